@@ -6,12 +6,16 @@ const BUILD_METADATA: &str = "SURREAL_BUILD_METADATA";
 fn main() {
 	println!("cargo:rerun-if-env-changed={BUILD_METADATA}");
 	println!("cargo:rerun-if-changed=lib");
+	println!("cargo:rerun-if-changed=proto");
 	println!("cargo:rerun-if-changed=src");
 	println!("cargo:rerun-if-changed=Cargo.toml");
 	println!("cargo:rerun-if-changed=Cargo.lock");
 	if let Some(metadata) = build_metadata() {
 		println!("cargo:rustc-env={BUILD_METADATA}={metadata}");
 	}
+	// The gRPC service requires protoc to compile its definition
+	#[cfg(feature = "grpc")]
+	tonic_build::compile_protos("proto/surrealdb.proto").expect("Failed to compile protobuf");
 }
 
 fn build_metadata() -> Option<String> {
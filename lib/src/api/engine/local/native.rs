@@ -25,6 +25,7 @@ use std::marker::PhantomData;
 use std::pin::Pin;
 use std::sync::atomic::AtomicI64;
 use std::sync::Arc;
+use std::time::Duration;
 
 impl crate::api::Connection for Db {}
 
@@ -133,7 +134,27 @@ pub(crate) fn router(
 			Session::for_kv()
 		};
 
-		while let Some(Some(route)) = stream.next().await {
+		// Periodically build any indexes of large tables in the background,
+		// one batch at a time in between the requests, until they are built
+		let mut tick = tokio::time::interval(Duration::from_secs(1));
+		let mut building = false;
+
+		loop {
+			let route = tokio::select! {
+				biased;
+				route = stream.next() => match route {
+					Some(Some(route)) => route,
+					_ => break,
+				},
+				_ = tick.tick(), if !building => {
+					building = matches!(kvs.build_indexes().await, Ok(1..));
+					continue;
+				}
+				_ = std::future::ready(()), if building => {
+					building = matches!(kvs.build_indexes().await, Ok(1..));
+					continue;
+				}
+			};
 			match super::router(
 				route.request,
 				&kvs,
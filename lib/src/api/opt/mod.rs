@@ -13,6 +13,8 @@ use crate::sql::constant::ConstantValue;
 use crate::sql::to_value;
 use crate::sql::Thing;
 use crate::sql::Value;
+use base64_lib::engine::general_purpose::STANDARD;
+use base64_lib::Engine;
 use dmp::Diff;
 use serde::de::DeserializeOwned;
 use serde::Serialize;
@@ -356,7 +358,10 @@ fn into_json(value: Value, simplify: bool) -> JsonValue {
 			true => Geometry::from(geo).0,
 			false => json!(geo),
 		},
-		Value::Bytes(bytes) => json!(bytes),
+		Value::Bytes(bytes) => match simplify {
+			true => STANDARD.encode(&*bytes).into(),
+			false => json!(bytes),
+		},
 		Value::Param(param) => json!(param),
 		Value::Idiom(idiom) => json!(idiom),
 		Value::Table(table) => json!(table),
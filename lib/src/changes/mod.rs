@@ -0,0 +1,242 @@
+//! A logical decoding of the changes made to records in the datastore.
+//!
+//! Every record which is created, updated, or deleted by a committed
+//! transaction is decoded into a [`Change`], which contains the namespace,
+//! database, table, and record id, along with the record content before
+//! and after the change. Changes are delivered to every subscriber in the
+//! order in which their transactions were committed, and each change is
+//! given a sequence number which increases monotonically for the lifetime
+//! of the datastore instance.
+//!
+//! The datastore can also retain a number of the most recent changes, so
+//! that a subscriber which reconnects can resume from the last change it
+//! received with [`Datastore::changes_since`](crate::kvs::Datastore::changes_since),
+//! without missing any changes.
+//!
+//! This module is intended as the building block for custom replication
+//! and change-data-capture tooling in applications which embed the
+//! datastore.
+//!
+//! ```rust,no_run
+//! use surrealdb::dbs::Session;
+//! use surrealdb::err::Error;
+//! use surrealdb::kvs::Datastore;
+//!
+//! #[tokio::main]
+//! async fn main() -> Result<(), Error> {
+//!     let ds = Datastore::new("memory").await?;
+//!     let changes = ds.changes().await;
+//!     let ses = Session::for_kv().with_ns("test").with_db("test");
+//!     ds.execute("CREATE person:tobie SET name = 'Tobie'", &ses, None, false).await?;
+//!     while let Ok(change) = changes.recv().await {
+//!         println!("{} {} {}", change.seq, change.action(), change.id);
+//!     }
+//!     Ok(())
+//! }
+//! ```
+use crate::sql::datetime::Datetime;
+use crate::sql::thing::Thing;
+use crate::sql::value::Value;
+use channel::Sender;
+use futures::lock::Mutex;
+use futures::lock::MutexGuard;
+use std::collections::VecDeque;
+use std::fmt;
+use std::sync::atomic::{AtomicU64, AtomicUsize, Ordering};
+
+pub use channel::Receiver;
+
+/// The number of changes which can be buffered for each subscriber.
+///
+/// A subscriber which falls further behind than this is disconnected,
+/// so that a slow consumer never blocks the datastore from committing
+/// transactions. Once disconnected, the receiver ends, and the consumer
+/// should resynchronise before subscribing again.
+pub const CAPACITY: usize = 10_000;
+
+/// The type of change made to a record
+#[derive(Clone, Copy, Debug, Eq, PartialEq)]
+pub enum Action {
+	Create,
+	Update,
+	Delete,
+}
+
+impl fmt::Display for Action {
+	fn fmt(&self, f: &mut fmt::Formatter) -> fmt::Result {
+		match self {
+			Action::Create => f.write_str("CREATE"),
+			Action::Update => f.write_str("UPDATE"),
+			Action::Delete => f.write_str("DELETE"),
+		}
+	}
+}
+
+/// A decoded change to a single record
+#[derive(Clone, Debug, PartialEq)]
+pub struct Change {
+	/// The position of this change in the change log
+	pub seq: u64,
+	/// The time at which the change was committed
+	pub at: Datetime,
+	/// The namespace containing the record
+	pub ns: String,
+	/// The database containing the record
+	pub db: String,
+	/// The table containing the record
+	pub tb: String,
+	/// The id of the changed record
+	pub id: Thing,
+	/// The record content before the change
+	pub before: Value,
+	/// The record content after the change
+	pub after: Value,
+}
+
+impl Change {
+	/// Get the type of change made to the record
+	pub fn action(&self) -> Action {
+		match (&self.before, &self.after) {
+			(Value::None, _) => Action::Create,
+			(_, Value::None) => Action::Delete,
+			_ => Action::Update,
+		}
+	}
+}
+
+/// The change log of a datastore, which fans
+/// committed changes out to every subscriber.
+#[derive(Default)]
+pub(crate) struct Feed {
+	seq: AtomicU64,
+	count: AtomicUsize,
+	lock: Mutex<()>,
+	subs: Mutex<Vec<Sender<Change>>>,
+	// The number of recent changes which are retained
+	retain: usize,
+	history: Mutex<VecDeque<Change>>,
+}
+
+impl Feed {
+	/// Create a change log which retains a number of the most recent changes
+	pub fn new(retain: usize) -> Self {
+		Feed {
+			retain,
+			..Default::default()
+		}
+	}
+	/// Subscribe to all subsequently committed changes
+	pub async fn subscribe(&self) -> Receiver<Change> {
+		let (snd, rcv) = channel::bounded(CAPACITY);
+		let mut subs = self.subs.lock().await;
+		subs.push(snd);
+		self.count.store(subs.len(), Ordering::Release);
+		rcv
+	}
+	/// Subscribe to all subsequently committed changes, returning any
+	/// retained changes after the specified sequence number. If changes
+	/// after the sequence number are no longer retained, then no changes
+	/// are returned, and the subscriber should resynchronise instead.
+	pub async fn subscribe_since(&self, seq: u64) -> (Option<Vec<Change>>, Receiver<Change>) {
+		let (snd, rcv) = channel::bounded(CAPACITY);
+		let mut subs = self.subs.lock().await;
+		let history = self.history.lock().await;
+		let changes = self.retained(&history, seq);
+		subs.push(snd);
+		self.count.store(subs.len(), Ordering::Release);
+		(changes, rcv)
+	}
+	/// Get any retained changes after the specified sequence number, or
+	/// `None` if changes after the sequence number are no longer retained
+	pub async fn since(&self, seq: u64) -> Option<Vec<Change>> {
+		let history = self.history.lock().await;
+		self.retained(&history, seq)
+	}
+	/// Check if every change after the sequence number is retained
+	fn retained(&self, history: &VecDeque<Change>, seq: u64) -> Option<Vec<Change>> {
+		let last = self.seq.load(Ordering::Acquire);
+		let first = history.front().map_or(last + 1, |v| v.seq);
+		match seq {
+			seq if seq > last || seq + 1 < first => None,
+			seq => Some(history.iter().filter(|v| v.seq > seq).cloned().collect()),
+		}
+	}
+	/// Get the sequence number of the last published change
+	pub fn sequence(&self) -> u64 {
+		self.seq.load(Ordering::Acquire)
+	}
+	/// Close every subscription, so that subscribers see the end of the
+	/// change log when the datastore is shut down
+	pub async fn close(&self) {
+		let mut subs = self.subs.lock().await;
+		subs.drain(..).for_each(|s| {
+			s.close();
+		});
+		self.count.store(0, Ordering::Release);
+	}
+	/// Check if there are any subscribers to the change log,
+	/// or if the recent changes to the datastore are retained
+	pub fn is_active(&self) -> bool {
+		self.retain > 0 || self.count.load(Ordering::Acquire) > 0
+	}
+	/// Acquire the commit lock, so that changes from concurrent
+	/// transactions are published in the order they commit
+	pub async fn lock(&self) -> MutexGuard<'_, ()> {
+		self.lock.lock().await
+	}
+	/// Publish the changes of a committed transaction, returning the
+	/// sequence number of the first change. This must be called while
+	/// holding the commit lock.
+	pub async fn publish(&self, changes: Vec<Change>) -> u64 {
+		let mut subs = self.subs.lock().await;
+		let mut history = self.history.lock().await;
+		let at = Datetime::default();
+		let first = self.seq.load(Ordering::Acquire) + 1;
+		for mut change in changes {
+			change.seq = self.seq.fetch_add(1, Ordering::AcqRel) + 1;
+			change.at = at.clone();
+			// Retain the most recent changes
+			if self.retain > 0 {
+				if history.len() == self.retain {
+					history.pop_front();
+				}
+				history.push_back(change.clone());
+			}
+			// Remove any closed or lagging subscribers
+			subs.retain(|s| match s.try_send(change.clone()) {
+				Ok(_) => true,
+				Err(_) => {
+					s.close();
+					false
+				}
+			});
+		}
+		self.count.store(subs.len(), Ordering::Release);
+		first
+	}
+}
+
+#[cfg(test)]
+mod tests {
+
+	use super::*;
+
+	#[test]
+	fn change_action() {
+		let mut change = Change {
+			seq: 1,
+			at: Datetime::default(),
+			ns: String::from("test"),
+			db: String::from("test"),
+			tb: String::from("person"),
+			id: Thing::from(("person", "tobie")),
+			before: Value::None,
+			after: Value::from(true),
+		};
+		assert_eq!(change.action(), Action::Create);
+		change.before = Value::from(false);
+		assert_eq!(change.action(), Action::Update);
+		change.after = Value::None;
+		assert_eq!(change.action(), Action::Delete);
+	}
+}
@@ -12,6 +12,22 @@ pub static MAX_COMPUTATION_DEPTH: Lazy<u8> = Lazy::new(|| {
 	option_env!("SURREAL_MAX_COMPUTATION_DEPTH").and_then(|s| s.parse::<u8>().ok()).unwrap_or(120)
 });
 
+/// Specifies the maximum size in bytes of a single bytes value.
+pub static MAX_BYTES_SIZE: Lazy<usize> = Lazy::new(|| {
+	option_env!("SURREAL_MAX_BYTES_SIZE")
+		.and_then(|s| s.parse::<usize>().ok())
+		.unwrap_or(16 * 1024 * 1024)
+});
+
+/// Specifies the default maximum size in bytes of the serialized data of a single record.
+pub const MAX_DOCUMENT_SIZE: usize = 64 * 1024 * 1024;
+
+/// Specifies the default size in bytes above which the data of a record is split across multiple keys.
+pub const DOCUMENT_CHUNK_SIZE: usize = 64 * 1024;
+
+/// Specifies the maximum number of missing rows which a FILL clause may create.
+pub const MAX_FILL_ROWS: usize = 100_000;
+
 /// Specifies the names of parameters which can not be specified in a query.
 pub const PROTECTED_PARAM_NAMES: &[&str] = &["auth", "scope", "token", "session"];
 
@@ -1,18 +1,31 @@
 use crate::ctx::canceller::Canceller;
+use crate::ctx::limits::Limits;
 use crate::ctx::reason::Reason;
+use crate::ctx::sandbox::{Kind, Sandbox};
+use crate::ctx::tracer::Tracer;
+use crate::dbs::Config;
+use crate::dbs::Page;
+use crate::dbs::Registry;
+use crate::dbs::Statistics;
 use crate::dbs::Transaction;
+use crate::dbs::Usage;
+use crate::doc::Reads;
 use crate::err::Error;
 use crate::idx::planner::executor::QueryExecutor;
 use crate::sql::value::Value;
 use crate::sql::Thing;
+use once_cell::sync::Lazy;
 use std::borrow::Cow;
-use std::collections::HashMap;
+use std::collections::{BTreeMap, HashMap};
 use std::fmt::{self, Debug};
 use std::sync::atomic::{AtomicBool, Ordering};
 use std::sync::Arc;
 use std::time::Duration;
 use trice::Instant;
 
+/// The configuration of any context which was not started by a datastore
+static CONFIG: Lazy<Config> = Lazy::new(Config::default);
+
 impl<'a> From<Value> for Cow<'a, Value> {
 	fn from(v: Value) -> Cow<'a, Value> {
 		Cow::Owned(v)
@@ -41,6 +54,24 @@ pub struct Context<'a> {
 	thing: Option<&'a Thing>,
 	// An optional cursor document
 	cursor_doc: Option<&'a Value>,
+	// An optional sandbox limiting the current invocation
+	sandbox: Option<Arc<Sandbox>>,
+	// Optional resource limits for the current statement
+	limits: Option<Arc<Limits>>,
+	// An optional tracer for the current statement
+	tracer: Option<Arc<Tracer>>,
+	// An optional page of the records which are output by the current statement
+	page: Option<Arc<Page>>,
+	// An optional set of the audit entries of the records which were read by the current statement
+	reads: Option<Arc<Reads>>,
+	// An optional registry of sessions, for sending live query notifications
+	registry: Option<Arc<Registry>>,
+	// An optional count of the queries which were run against each namespace
+	usage: Option<Arc<Usage>>,
+	// An optional set of statement statistics, for selecting from `system:statements`
+	statistics: Option<Arc<Statistics>>,
+	// An optional configuration of the datastore which runs the query
+	config: Option<Arc<Config>>,
 }
 
 impl<'a> Default for Context<'a> {
@@ -74,6 +105,15 @@ impl<'a> Context<'a> {
 			query_executors: None,
 			thing: None,
 			cursor_doc: None,
+			sandbox: None,
+			limits: None,
+			tracer: None,
+			page: None,
+			reads: None,
+			registry: None,
+			usage: None,
+			statistics: None,
+			config: None,
 		}
 	}
 
@@ -88,6 +128,15 @@ impl<'a> Context<'a> {
 			query_executors: parent.query_executors.clone(),
 			thing: parent.thing,
 			cursor_doc: parent.cursor_doc,
+			sandbox: parent.sandbox.clone(),
+			limits: parent.limits.clone(),
+			tracer: parent.tracer.clone(),
+			page: parent.page.clone(),
+			reads: parent.reads.clone(),
+			registry: parent.registry.clone(),
+			usage: parent.usage.clone(),
+			statistics: parent.statistics.clone(),
+			config: parent.config.clone(),
 		}
 	}
 
@@ -113,6 +162,70 @@ impl<'a> Context<'a> {
 		self.add_deadline(Instant::now() + timeout)
 	}
 
+	/// Run this context within a new sandbox, nested within any current
+	/// sandbox, and limit its deadline to the time limit of the sandbox.
+	pub fn add_sandbox(&mut self, kind: Kind, name: impl Into<String>) -> Arc<Sandbox> {
+		let limits = self.config().sandbox;
+		let sandbox = Arc::new(Sandbox::new(self.sandbox.take(), kind, name, limits));
+		self.add_deadline(sandbox.deadline());
+		self.sandbox = Some(sandbox.clone());
+		sandbox
+	}
+
+	/// Limit the resources which the current statement, and any subqueries
+	/// which it runs, may use. Limits which are not enabled are ignored.
+	pub fn add_limits(&mut self, limits: Limits) {
+		if limits.is_enabled() {
+			self.limits = Some(Arc::new(limits));
+		}
+	}
+
+	/// Add a tracer to the context, which collects the query plans of the
+	/// current statement and any of its subqueries.
+	pub fn add_tracer(&mut self, tracer: Arc<Tracer>) {
+		self.tracer = Some(tracer);
+	}
+
+	/// Add a page to the context, which is set to the cursor of the next page
+	/// and the total count of the records which are output by the current statement.
+	pub(crate) fn add_page(&mut self) -> Arc<Page> {
+		let page = Arc::new(Page::default());
+		self.page = Some(page.clone());
+		page
+	}
+
+	/// Add a set of audit entries to the context, which collects the reads of
+	/// audited records by the current statement and any of its subqueries.
+	pub(crate) fn add_reads(&mut self) -> Arc<Reads> {
+		let reads = Arc::new(Reads::default());
+		self.reads = Some(reads.clone());
+		reads
+	}
+
+	/// Add the registry of sessions to the context, so that live query
+	/// notifications can be sent to the sessions which subscribed to them.
+	pub fn add_registry(&mut self, registry: Arc<Registry>) {
+		self.registry = Some(registry);
+	}
+
+	/// Add the query counts of the datastore to the context, so that
+	/// `INFO FOR NAMESPACE` can return the usage of the namespace.
+	pub fn add_usage(&mut self, usage: Arc<Usage>) {
+		self.usage = Some(usage);
+	}
+
+	/// Add the statement statistics of the datastore to the context, so
+	/// that the statistics can be selected from `system:statements`.
+	pub fn add_statistics(&mut self, statistics: Arc<Statistics>) {
+		self.statistics = Some(statistics);
+	}
+
+	/// Add the configuration of the datastore to the context, so that
+	/// statements and functions apply the limits of the datastore.
+	pub(crate) fn add_config(&mut self, config: Arc<Config>) {
+		self.config = Some(config);
+	}
+
 	pub fn add_transaction(&mut self, txn: Option<&Transaction>) {
 		if let Some(txn) = txn {
 			self.transaction = Some(txn.clone());
@@ -146,6 +259,12 @@ impl<'a> Context<'a> {
 		self.values.insert(key.into(), value.into());
 	}
 
+	/// Remove a value which was added to this context, so that any
+	/// value with the same key in a parent context is visible again.
+	pub fn remove_value(&mut self, key: &str) {
+		self.values.remove(key);
+	}
+
 	/// Get the timeout for this operation, if any. This is useful for
 	/// checking if a long job should be started or not.
 	pub fn timeout(&self) -> Option<Duration> {
@@ -167,6 +286,51 @@ impl<'a> Context<'a> {
 		self.cursor_doc
 	}
 
+	/// Get the sandbox which limits the current invocation, if any
+	pub fn sandbox(&self) -> Option<&Sandbox> {
+		self.sandbox.as_deref()
+	}
+
+	/// Get the tracer of the current statement, if any
+	pub fn tracer(&self) -> Option<&Tracer> {
+		self.tracer.as_deref()
+	}
+
+	/// Get the registry of sessions, if any
+	pub fn registry(&self) -> Option<&Registry> {
+		self.registry.as_deref()
+	}
+
+	/// Get the query counts of the datastore, if any
+	pub fn usage(&self) -> Option<&Usage> {
+		self.usage.as_deref()
+	}
+
+	/// Get the statement statistics of the datastore, if any
+	pub fn statistics(&self) -> Option<&Statistics> {
+		self.statistics.as_deref()
+	}
+
+	/// Get the configuration of the datastore, or the default configuration
+	pub(crate) fn config(&self) -> &Config {
+		self.config.as_deref().unwrap_or(&CONFIG)
+	}
+
+	/// Get the resource limits of the current statement, if any
+	pub fn limits(&self) -> Option<&Limits> {
+		self.limits.as_deref()
+	}
+
+	/// Get the audit entries of the reads of the current statement, if any
+	pub(crate) fn reads(&self) -> Option<&Reads> {
+		self.reads.as_deref()
+	}
+
+	/// Get the page of the records of the current statement, if any
+	pub(crate) fn page(&self) -> Option<&Page> {
+		self.page.as_deref()
+	}
+
 	pub(crate) fn get_query_executor(&self, tb: &str) -> Option<&QueryExecutor> {
 		if let Some(qe) = &self.query_executors {
 			qe.get(tb)
@@ -218,6 +382,19 @@ impl<'a> Context<'a> {
 		}
 	}
 
+	/// Get all of the values which are visible in this context, where the
+	/// values of this context replace the values of any parent context
+	pub(crate) fn values(&self) -> BTreeMap<&str, &Value> {
+		let mut out = match self.parent {
+			Some(p) => p.values(),
+			None => BTreeMap::new(),
+		};
+		for (k, v) in self.values.iter() {
+			out.insert(k.as_ref(), v.as_ref());
+		}
+		out
+	}
+
 	/// Get a 'static view into the cancellation status.
 	#[cfg(feature = "scripting")]
 	pub fn cancellation(&self) -> crate::ctx::cancellation::Cancellation {
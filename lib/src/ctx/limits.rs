@@ -0,0 +1,110 @@
+//! Resource limits for a single statement.
+//!
+//! Each statement runs with a set of [`Limits`], which is shared with any
+//! subqueries which the statement runs. The limits count the number of
+//! records which are examined, and estimate the memory used by the records
+//! which are held in the output of the statement, so that a single statement
+//! can not scan or buffer an unbounded amount of data.
+use crate::err::Error;
+use crate::sql::value::Value;
+use std::mem::size_of;
+use std::sync::atomic::{AtomicUsize, Ordering};
+
+#[derive(Debug)]
+pub struct Limits {
+	// The maximum number of records which may be examined
+	max_rows: Option<usize>,
+	// The maximum number of bytes which may be held in memory
+	max_memory: Option<usize>,
+	// The number of records which have been examined
+	rows: AtomicUsize,
+	// The estimated number of bytes which are held in memory
+	memory: AtomicUsize,
+}
+
+impl Limits {
+	/// Create a new set of limits
+	pub fn new(max_rows: Option<usize>, max_memory: Option<usize>) -> Self {
+		Limits {
+			max_rows,
+			max_memory,
+			rows: AtomicUsize::new(0),
+			memory: AtomicUsize::new(0),
+		}
+	}
+
+	/// Check if any limits are configured
+	pub fn is_enabled(&self) -> bool {
+		self.max_rows.is_some() || self.max_memory.is_some()
+	}
+
+	/// Record that a record is being examined
+	pub fn examine(&self) -> Result<(), Error> {
+		let rows = self.rows.fetch_add(1, Ordering::Relaxed);
+		if let Some(limit) = self.max_rows {
+			if rows >= limit {
+				return Err(Error::QueryRowLimit {
+					limit,
+				});
+			}
+		}
+		Ok(())
+	}
+
+	/// Get the number of records which have been examined
+	pub fn examined(&self) -> usize {
+		self.rows.load(Ordering::Relaxed)
+	}
+
+	/// Record that a value is being held in the output of the statement
+	pub fn hold(&self, val: &Value) -> Result<(), Error> {
+		if let Some(limit) = self.max_memory {
+			let size = estimate(val);
+			if self.memory.fetch_add(size, Ordering::Relaxed) + size > limit {
+				return Err(Error::QueryMemoryLimit {
+					limit,
+				});
+			}
+		}
+		Ok(())
+	}
+}
+
+/// Estimate the number of bytes of memory which a value uses
+pub(crate) fn estimate(val: &Value) -> usize {
+	size_of::<Value>()
+		+ match val {
+			Value::Strand(v) => v.0.len(),
+			Value::Bytes(v) => v.len(),
+			Value::Thing(v) => v.tb.len() + v.id.to_raw().len(),
+			Value::Array(v) => v.iter().map(estimate).sum(),
+			Value::Object(v) => v.iter().map(|(k, v)| k.len() + estimate(v)).sum(),
+			_ => 0,
+		}
+}
+
+#[cfg(test)]
+mod tests {
+	use super::*;
+
+	#[test]
+	fn limits_rows() {
+		let limits = Limits::new(Some(2), None);
+		assert!(limits.examine().is_ok());
+		assert!(limits.examine().is_ok());
+		assert!(matches!(
+			limits.examine(),
+			Err(Error::QueryRowLimit {
+				limit: 2
+			})
+		));
+	}
+
+	#[test]
+	fn limits_memory() {
+		let limits = Limits::new(None, Some(size_of::<Value>() * 4));
+		let val = Value::from(vec![Value::from(1), Value::from(2)]);
+		assert!(limits.hold(&val).is_ok());
+		assert!(limits.hold(&val).is_err());
+	}
+}
@@ -27,4 +27,7 @@ pub use self::reason::*;
 pub mod cancellation;
 pub mod canceller;
 pub mod context;
+pub mod limits;
 pub mod reason;
+pub mod sandbox;
+pub mod tracer;
@@ -0,0 +1,157 @@
+//! Resource limits for events, futures, and stored functions.
+//!
+//! Each invocation of an event, a future, or a stored function runs within a
+//! [`Sandbox`], which limits the wall-clock time which it may take, the number
+//! of subqueries which it may run, and the number of outbound network calls
+//! which it may make. Any embedded script which runs within a sandbox is also
+//! limited to the configured memory size. The limits are specified with
+//! [`Datastore::with_sandbox_limits`](crate::kvs::Datastore::with_sandbox_limits).
+//! Sandboxes are nested, so that the limits of an event also apply to any
+//! functions which it calls.
+use crate::dbs::SandboxLimits;
+use crate::err::Error;
+use std::fmt;
+use std::sync::atomic::{AtomicUsize, Ordering};
+use std::sync::Arc;
+use trice::Instant;
+
+/// The kind of invocation which a sandbox limits
+#[derive(Clone, Copy, Debug, Eq, PartialEq)]
+pub enum Kind {
+	Event,
+	Future,
+	Function,
+}
+
+impl fmt::Display for Kind {
+	fn fmt(&self, f: &mut fmt::Formatter) -> fmt::Result {
+		match self {
+			Self::Event => f.write_str("event"),
+			Self::Future => f.write_str("future"),
+			Self::Function => f.write_str("function"),
+		}
+	}
+}
+
+#[derive(Debug)]
+pub struct Sandbox {
+	// The sandbox of the calling invocation
+	parent: Option<Arc<Sandbox>>,
+	// The kind of invocation
+	kind: Kind,
+	// The name of the invocation
+	name: String,
+	// The limits of the invocation
+	limits: SandboxLimits,
+	// The time at which the invocation must finish
+	deadline: Instant,
+	// The number of subqueries which have been run
+	subqueries: AtomicUsize,
+	// The number of outbound calls which have been made
+	calls: AtomicUsize,
+}
+
+impl Sandbox {
+	/// Create a new sandbox, within an optional parent sandbox
+	pub fn new(
+		parent: Option<Arc<Sandbox>>,
+		kind: Kind,
+		name: impl Into<String>,
+		limits: SandboxLimits,
+	) -> Self {
+		Sandbox {
+			parent,
+			kind,
+			name: name.into(),
+			limits,
+			deadline: Instant::now() + limits.time,
+			subqueries: AtomicUsize::new(0),
+			calls: AtomicUsize::new(0),
+		}
+	}
+
+	/// Get the time at which the invocation must finish
+	pub fn deadline(&self) -> Instant {
+		self.deadline
+	}
+
+	/// Get the limits of the invocation
+	pub fn limits(&self) -> &SandboxLimits {
+		&self.limits
+	}
+
+	/// Check if this invocation runs within an invocation of a kind
+	pub fn within(&self, kind: Kind) -> bool {
+		self.kind == kind || self.parent.as_ref().map_or(false, |v| v.within(kind))
+	}
+
+	/// Record that a subquery is being run
+	pub fn subquery(&self) -> Result<(), Error> {
+		if self.subqueries.fetch_add(1, Ordering::Relaxed) >= self.limits.subqueries {
+			return Err(self.violation(Violation::Subqueries));
+		}
+		match &self.parent {
+			Some(v) => v.subquery(),
+			None => Ok(()),
+		}
+	}
+
+	/// Record that an outbound call is being made
+	pub fn call(&self) -> Result<(), Error> {
+		if self.calls.fetch_add(1, Ordering::Relaxed) >= self.limits.calls {
+			return Err(self.violation(Violation::Calls));
+		}
+		match &self.parent {
+			Some(v) => v.call(),
+			None => Ok(()),
+		}
+	}
+
+	/// Check the result of the invocation, converting any error which was
+	/// caused by exceeding the time or memory limits into a sandbox error
+	pub fn finish<T>(&self, res: Result<T, Error>) -> Result<T, Error> {
+		match res {
+			// A violation in a nested sandbox has already been logged
+			Err(
+				e @ Error::SandboxLimit {
+					..
+				},
+			) => Err(e),
+			Err(Error::InvalidScript {
+				message,
+			}) if message.contains("out of memory") => Err(self.violation(Violation::Memory)),
+			_ if self.deadline <= Instant::now() => Err(self.violation(Violation::Time)),
+			res => res,
+		}
+	}
+
+	/// Log and return an error for a limit which has been exceeded
+	fn violation(&self, limit: Violation) -> Error {
+		warn!(target: "surrealdb::sandbox", "The {} '{}' exceeded the {limit} limit", self.kind, self.name);
+		Error::SandboxLimit {
+			kind: self.kind,
+			name: self.name.clone(),
+			limit,
+		}
+	}
+}
+
+/// The limit which a sandboxed invocation exceeded
+#[derive(Clone, Copy, Debug, Eq, PartialEq)]
+pub enum Violation {
+	Time,
+	Memory,
+	Subqueries,
+	Calls,
+}
+
+impl fmt::Display for Violation {
+	fn fmt(&self, f: &mut fmt::Formatter) -> fmt::Result {
+		match self {
+			Self::Time => f.write_str("time"),
+			Self::Memory => f.write_str("memory"),
+			Self::Subqueries => f.write_str("subquery"),
+			Self::Calls => f.write_str("outbound call"),
+		}
+	}
+}
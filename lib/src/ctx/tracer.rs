@@ -0,0 +1,26 @@
+//! Tracing of a single statement for a connection.
+//!
+//! When a connection enables statement tracing, each statement runs with a
+//! [`Tracer`], which is shared with any subqueries which the statement runs.
+//! The tracer collects the query plans of each iterator, so that they can be
+//! sent to the connection along with the timing of the statement.
+use crate::sql::value::Value;
+use std::sync::Mutex;
+
+#[derive(Debug, Default)]
+pub struct Tracer {
+	// The query plans of the iterators which have run
+	plans: Mutex<Vec<Value>>,
+}
+
+impl Tracer {
+	/// Record the query plan of an iterator
+	pub(crate) fn plan(&self, plan: Value) {
+		self.plans.lock().unwrap().push(plan);
+	}
+
+	/// Take the query plans which have been recorded
+	pub(crate) fn plans(&self) -> Vec<Value> {
+		std::mem::take(&mut *self.plans.lock().unwrap())
+	}
+}
@@ -0,0 +1,64 @@
+//! A batch of statements, which are sent as separate statements rather
+//! than as a single query string, so that the result of each statement
+//! can be matched to the statement which produced it.
+//!
+//! An `atomic` batch runs every statement in a single transaction, which
+//! is committed only if every statement succeeds, and is not run at all if
+//! any statement can not be parsed. A `continue-on-error` batch runs each
+//! statement in its own transaction, and runs the remaining statements
+//! when one of them fails.
+use crate::err::Error;
+use crate::sql;
+use crate::sql::Statement;
+use serde::{Deserialize, Serialize};
+
+/// How a batch of statements handles a statement which fails
+#[derive(Clone, Copy, Debug, Default, Eq, PartialEq, Serialize, Deserialize)]
+#[serde(rename_all = "kebab-case")]
+pub enum BatchPolicy {
+	/// Commit the statements only if every statement succeeds
+	#[default]
+	Atomic,
+	/// Run each statement in its own transaction
+	ContinueOnError,
+}
+
+/// Parse a statement of a batch, which must be a single statement which
+/// does not begin or end a transaction, or change the query options
+pub(crate) fn parse_statement(txt: &str) -> Result<Statement, Error> {
+	let mut ast = sql::parse(txt)?;
+	match ast.0 .0.len() {
+		1 => match ast.0 .0.remove(0) {
+			Statement::Begin(_)
+			| Statement::Cancel(_)
+			| Statement::Commit(_)
+			| Statement::Option(_) => Err(Error::InvalidBatchStatement),
+			stm => Ok(stm),
+		},
+		_ => Err(Error::InvalidBatchStatement),
+	}
+}
+
+#[cfg(test)]
+mod tests {
+	use super::*;
+
+	#[test]
+	fn batch_statement() {
+		assert!(parse_statement("CREATE person:tobie").is_ok());
+		assert!(matches!(
+			parse_statement("CREATE person:1; CREATE person:2"),
+			Err(Error::InvalidBatchStatement)
+		));
+		assert!(matches!(parse_statement("BEGIN TRANSACTION"), Err(Error::InvalidBatchStatement)));
+		assert!(parse_statement("CREATE person:").is_err());
+	}
+
+	#[test]
+	fn batch_policy() {
+		let val: BatchPolicy = serde_json::from_str(r#""continue-on-error""#).unwrap();
+		assert_eq!(val, BatchPolicy::ContinueOnError);
+		let val: BatchPolicy = serde_json::from_str(r#""atomic""#).unwrap();
+		assert_eq!(val, BatchPolicy::Atomic);
+	}
+}
@@ -0,0 +1,208 @@
+//! A cache of the results of SELECT statements.
+//!
+//! A statement is cached by the text of the statement, together with the
+//! options and the parameters which it is run with, so that identical
+//! queries from dashboards which poll the database are only computed once.
+//! The results of a statement are invalidated when any of the tables which
+//! it selects from is written to, both when the record is written and when
+//! the transaction is committed, and otherwise expire after a fixed time.
+//! Only the tables which a statement selects from are tracked, so records
+//! which are fetched through record links or graph edges from a cached
+//! result may be out of date until the result expires.
+use crate::ctx::Context;
+use crate::dbs::is_statements;
+use crate::dbs::Options;
+use crate::sql::statements::SelectStatement;
+use crate::sql::Value;
+use std::collections::HashMap;
+use std::sync::atomic::{AtomicU64, Ordering};
+use std::sync::Mutex;
+use std::time::Duration;
+use trice::Instant;
+
+/// The functions whose results change each time they are called
+const VOLATILE: [&str; 7] =
+	["rand::", "rand(", "time::now(", "http::", "sleep(", "crypto::", "function("];
+
+struct Entry {
+	ns: String,
+	db: String,
+	tables: Vec<String>,
+	value: Value,
+	expires: Instant,
+}
+
+/// The cached results of SELECT statements
+pub struct ResultCache {
+	size: usize,
+	ttl: Duration,
+	// Incremented whenever any result is invalidated
+	generation: AtomicU64,
+	entries: Mutex<HashMap<String, Entry>>,
+}
+
+impl ResultCache {
+	/// Create a cache which keeps up to `size` results for `ttl`, where a
+	/// size of 0 disables the cache
+	pub fn new(size: usize, ttl: Duration) -> Self {
+		ResultCache {
+			size,
+			ttl,
+			generation: AtomicU64::new(0),
+			entries: Mutex::new(HashMap::new()),
+		}
+	}
+	/// Check whether any results are cached
+	pub fn is_enabled(&self) -> bool {
+		self.size > 0 && !self.ttl.is_zero()
+	}
+	/// Get the number of results which are cached
+	pub fn len(&self) -> usize {
+		self.entries.lock().unwrap().len()
+	}
+	/// Check whether no results are cached
+	pub fn is_empty(&self) -> bool {
+		self.len() == 0
+	}
+	/// Get the key by which the results of a statement are cached, if the
+	/// results of the statement can be cached
+	pub(crate) fn key(
+		&self,
+		ctx: &Context<'_>,
+		opt: &Options,
+		stm: &SelectStatement,
+	) -> Option<String> {
+		// Check if the cache is enabled
+		if !self.is_enabled() {
+			return None;
+		}
+		// Only cache statements which select from tables or records
+		if stm.fetch.is_some()
+			|| !stm.what.iter().all(|v| v.is_table() || v.is_thing() || v.is_range())
+		{
+			return None;
+		}
+		// Don't cache statements which count their total records with the results
+		if stm.total {
+			return None;
+		}
+		// Don't cache the statement statistics, which change on every statement
+		if stm.what.iter().any(|v| matches!(v, Value::Thing(v) if is_statements(v))) {
+			return None;
+		}
+		// Don't cache statements which have different results each time
+		let sql = stm.to_string();
+		if stm.writeable() || VOLATILE.iter().any(|v| sql.contains(v)) {
+			return None;
+		}
+		Some(format!("{opt:?}\n{sql}\n{:?}", ctx.values()))
+	}
+	/// Get the current generation of the cache, which is checked when a
+	/// result is stored, so that a result which was computed while one of
+	/// its tables was written to is not cached
+	pub(crate) fn generation(&self) -> u64 {
+		self.generation.load(Ordering::Acquire)
+	}
+	/// Get the cached results of a statement
+	pub(crate) fn get(&self, key: &str) -> Option<Value> {
+		let mut entries = self.entries.lock().unwrap();
+		match entries.get(key) {
+			Some(v) if v.expires > Instant::now() => Some(v.value.clone()),
+			Some(_) => {
+				entries.remove(key);
+				None
+			}
+			None => None,
+		}
+	}
+	/// Cache the results of a statement, which selected from the tables of
+	/// a database, unless the cache was invalidated since the generation
+	pub(crate) fn set(
+		&self,
+		key: String,
+		ns: &str,
+		db: &str,
+		tables: Vec<String>,
+		value: Value,
+		generation: u64,
+	) {
+		let mut entries = self.entries.lock().unwrap();
+		// Check if the cache was invalidated
+		if self.generation() != generation {
+			return;
+		}
+		// Make space for the result
+		let now = Instant::now();
+		if entries.len() >= self.size {
+			entries.retain(|_, v| v.expires > now);
+		}
+		if entries.len() >= self.size {
+			if let Some(k) = entries.iter().min_by_key(|(_, v)| v.expires).map(|(k, _)| k.clone()) {
+				entries.remove(&k);
+			}
+		}
+		// Store the result
+		entries.insert(
+			key,
+			Entry {
+				ns: ns.to_owned(),
+				db: db.to_owned(),
+				tables,
+				value,
+				expires: now + self.ttl,
+			},
+		);
+	}
+	/// Remove all of the cached results, when the definitions which the
+	/// results depend on, such as the permissions of a table, are changed
+	pub(crate) fn clear(&self) {
+		let mut entries = self.entries.lock().unwrap();
+		self.generation.fetch_add(1, Ordering::AcqRel);
+		entries.clear();
+	}
+	/// Remove the cached results which selected from a table
+	pub(crate) fn invalidate(&self, ns: &str, db: &str, tb: &str) {
+		let mut entries = self.entries.lock().unwrap();
+		self.generation.fetch_add(1, Ordering::AcqRel);
+		entries.retain(|_, v| v.ns != ns || v.db != db || !v.tables.iter().any(|t| t == tb));
+	}
+}
+
+impl Default for ResultCache {
+	fn default() -> Self {
+		// The cache is disabled unless a size is specified
+		ResultCache::new(0, Duration::from_secs(60))
+	}
+}
+
+#[cfg(test)]
+mod tests {
+	use super::*;
+
+	#[test]
+	fn cache_invalidate() {
+		let cache = ResultCache::new(2, Duration::from_secs(60));
+		let gen = cache.generation();
+		cache.set("a".into(), "test", "test", vec!["person".into()], Value::from(1), gen);
+		cache.set("b".into(), "test", "test", vec!["other".into()], Value::from(2), gen);
+		assert_eq!(cache.get("a"), Some(Value::from(1)));
+		// Writing to a table removes its results
+		cache.invalidate("test", "test", "person");
+		assert_eq!(cache.get("a"), None);
+		assert_eq!(cache.get("b"), Some(Value::from(2)));
+		// Results from before the invalidation are not cached
+		cache.set("a".into(), "test", "test", vec!["person".into()], Value::from(1), gen);
+		assert_eq!(cache.get("a"), None);
+	}
+
+	#[test]
+	fn cache_size() {
+		let cache = ResultCache::new(1, Duration::from_secs(60));
+		let gen = cache.generation();
+		cache.set("a".into(), "test", "test", vec![], Value::from(1), gen);
+		cache.set("b".into(), "test", "test", vec![], Value::from(2), gen);
+		assert_eq!(cache.len(), 1);
+		assert_eq!(cache.get("b"), Some(Value::from(2)));
+		assert!(!ResultCache::new(0, Duration::from_secs(60)).is_enabled());
+	}
+}
@@ -0,0 +1,117 @@
+//! The configuration of a datastore which applies while queries are run.
+//!
+//! The settings are specified with the `with_*` builder methods of a
+//! [`Datastore`](crate::kvs::Datastore), and the datastore adds them to the
+//! context of every query, so that statements, functions, and sandboxes can
+//! read them without reading the environment of the process.
+use crate::iam::allowlist::Allowlist;
+use crate::iam::external::Authenticator;
+use crate::iam::oidc::Issuer;
+use std::time::Duration;
+
+/// The limits of each event, future, and stored function which is run
+#[derive(Clone, Copy, Debug, Eq, PartialEq)]
+pub struct SandboxLimits {
+	/// The maximum time which an invocation may take
+	pub time: Duration,
+	/// The maximum memory in bytes which an embedded script may use
+	pub memory: usize,
+	/// The maximum number of subqueries which an invocation may run
+	pub subqueries: usize,
+	/// The maximum number of outbound calls which an invocation may make
+	pub calls: usize,
+}
+
+impl Default for SandboxLimits {
+	fn default() -> Self {
+		SandboxLimits {
+			time: Duration::from_secs(5),
+			memory: 2_000_000,
+			subqueries: 1_000,
+			calls: 10,
+		}
+	}
+}
+
+/// The limits of the requests which are sent by the http functions
+#[derive(Clone, Debug, Eq, PartialEq)]
+pub struct HttpLimits {
+	/// The hosts to which requests can be sent, in which `*.example.com`
+	/// matches any subdomain. Requests can be sent to any host if empty.
+	pub allowed_hosts: Vec<String>,
+	/// The maximum time which a request may take
+	pub timeout: Duration,
+	/// The maximum size in bytes of the request and response bodies
+	pub max_body_size: usize,
+}
+
+impl Default for HttpLimits {
+	fn default() -> Self {
+		HttpLimits {
+			allowed_hosts: vec![],
+			timeout: Duration::from_secs(10),
+			max_body_size: 10 * 1024 * 1024,
+		}
+	}
+}
+
+/// The configuration which is added to the context of every query
+#[derive(Clone, Debug)]
+pub(crate) struct Config {
+	// The limits of events, futures, and stored functions
+	pub sandbox: SandboxLimits,
+	// The limits of the http functions
+	pub http: HttpLimits,
+	// Whether record counts are calculated by scanning every record
+	pub exact_count: bool,
+	// The timeout of any statement which has no TIMEOUT clause
+	pub statement_timeout: Option<Duration>,
+	// The maximum number of records which a statement may examine
+	pub max_query_rows: Option<usize>,
+	// The maximum estimated memory in bytes of the output of a statement
+	pub max_query_memory: Option<usize>,
+	// The number of records above which an index is built in the background
+	pub index_build_threshold: Option<u64>,
+	// The number of records which are indexed in each batch of a build
+	pub index_build_batch_size: u32,
+	// The number of webhooks which are delivered concurrently
+	pub webhook_workers: usize,
+	// The number of times the delivery of a webhook is retried
+	pub webhook_retries: u32,
+	// The time before the first retry of a webhook, which doubles on each retry
+	pub webhook_backoff: Duration,
+	// The external identity providers whose tokens are accepted
+	pub oidc_issuers: Vec<Issuer>,
+	// The external authenticator which is consulted during signin
+	pub authenticator: Option<Authenticator>,
+	// The maximum time which the external authenticator may take
+	pub authenticator_timeout: Duration,
+	// The networks from which clients may use each namespace
+	pub ns_allowlists: Vec<Allowlist>,
+}
+
+impl Default for Config {
+	fn default() -> Self {
+		Config {
+			sandbox: SandboxLimits::default(),
+			http: HttpLimits::default(),
+			exact_count: false,
+			statement_timeout: None,
+			max_query_rows: None,
+			max_query_memory: None,
+			// The background build is driven by the server, or the embedded router
+			index_build_threshold: match cfg!(target_arch = "wasm32") {
+				true => None,
+				false => Some(10_000),
+			},
+			index_build_batch_size: 1000,
+			webhook_workers: 4,
+			webhook_retries: 5,
+			webhook_backoff: Duration::from_millis(500),
+			oidc_issuers: vec![],
+			authenticator: None,
+			authenticator_timeout: Duration::from_secs(5),
+			ns_allowlists: vec![],
+		}
+	}
+}
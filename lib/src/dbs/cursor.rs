@@ -0,0 +1,140 @@
+//! Cursors for keyset pagination with `START AFTER`.
+//!
+//! A cursor holds the ordering key of the last record of a page: the value
+//! of each field in the ORDER clause, followed by the id of the record. The
+//! next page is selected with `START AFTER $cursor`, which skips every record
+//! which is ordered at or before the cursor, so that records which are
+//! created or deleted between pages do not shift the following pages. The
+//! cursor is returned to the client as an opaque URL-safe string.
+use crate::err::Error;
+use crate::sql::order::Orders;
+use crate::sql::paths::ID;
+use crate::sql::{Array, Value};
+use base64_lib::engine::general_purpose::URL_SAFE_NO_PAD;
+use base64_lib::Engine;
+use std::cmp::Ordering;
+use std::sync::Mutex;
+
+#[derive(Clone, Debug, Eq, PartialEq)]
+pub(crate) struct Cursor(Array);
+
+impl Cursor {
+	/// Get the cursor of a record in the output of a query, if it has an id
+	pub(crate) fn new(v: &Value, orders: Option<&Orders>) -> Option<Self> {
+		let id = match v.pick(ID.as_ref()) {
+			v @ Value::Thing(_) => v,
+			_ => return None,
+		};
+		let mut key = Array::new();
+		if let Some(orders) = orders {
+			for order in orders.iter() {
+				if order.random {
+					return None;
+				}
+				key.push(v.pick(order));
+			}
+		}
+		key.push(id);
+		Some(Self(key))
+	}
+	/// Decode a cursor which was returned to the client
+	pub(crate) fn decode(v: &str, orders: Option<&Orders>) -> Result<Self, Error> {
+		let invalid = || Error::InvalidCursor {
+			value: v.to_owned(),
+		};
+		let sql = URL_SAFE_NO_PAD.decode(v).map_err(|_| invalid())?;
+		let sql = String::from_utf8(sql).map_err(|_| invalid())?;
+		let key = match crate::sql::value(&sql) {
+			Ok(Value::Array(v)) => v,
+			_ => return Err(invalid()),
+		};
+		// The cursor must have a value for each field in the ORDER clause
+		let len = orders.map_or(0, |v| v.len());
+		match key.last() {
+			Some(Value::Thing(_)) if key.len() == len + 1 => Ok(Self(key)),
+			_ => Err(invalid()),
+		}
+	}
+	/// Encode the cursor so that it can be returned to the client
+	pub(crate) fn encode(&self) -> String {
+		URL_SAFE_NO_PAD.encode(self.0.to_string())
+	}
+	/// Get the id of the record at the cursor
+	pub(crate) fn id(&self) -> Option<&Value> {
+		self.0.last()
+	}
+	/// Check if the cursor is ordered before a record in the output of a query
+	pub(crate) fn precedes(&self, v: &Value, orders: Option<&Orders>) -> bool {
+		let mut key = self.0.iter();
+		if let Some(orders) = orders {
+			for (order, c) in orders.iter().zip(key.by_ref()) {
+				let o = match order.direction {
+					true => order.compare_at(&v.pick(order), c, &[]),
+					false => order.compare_at(c, &v.pick(order), &[]),
+				};
+				match o {
+					Some(Ordering::Greater) => return true,
+					Some(Ordering::Less) => return false,
+					_ => continue,
+				}
+			}
+		}
+		match key.next() {
+			Some(id) => v.pick(ID.as_ref()).partial_cmp(id) == Some(Ordering::Greater),
+			None => false,
+		}
+	}
+}
+
+/// The pagination details of the records which were output by a statement,
+/// which are returned with the response, so that the client can select the
+/// next page, and can display the number of pages
+#[derive(Debug, Default)]
+pub(crate) struct Page {
+	/// The cursor of the last record of a full page
+	cursor: Mutex<Option<String>>,
+	/// The number of records before the START and LIMIT clauses
+	total: Mutex<Option<usize>>,
+}
+
+impl Page {
+	/// Set the cursor of the last page which was output
+	pub(crate) fn set_cursor(&self, v: Option<String>) {
+		*self.cursor.lock().unwrap() = v;
+	}
+	/// Set the total number of records which were matched
+	pub(crate) fn set_total(&self, v: Option<usize>) {
+		*self.total.lock().unwrap() = v;
+	}
+	/// Take the cursor which was set by the statement
+	pub(crate) fn cursor(&self) -> Option<String> {
+		self.cursor.lock().unwrap().take()
+	}
+	/// Take the total which was set by the statement
+	pub(crate) fn total(&self) -> Option<usize> {
+		self.total.lock().unwrap().take()
+	}
+}
+
+#[cfg(test)]
+mod tests {
+	use super::*;
+	use crate::sql::test::Parse;
+
+	#[test]
+	fn cursor_round_trip() {
+		let val = Value::parse("{ id: post:two, rank: 2 }");
+		let orders = crate::sql::order::order("ORDER BY rank DESC").unwrap().1;
+		let cur = Cursor::new(&val, Some(&orders)).unwrap();
+		let dec = Cursor::decode(&cur.encode(), Some(&orders)).unwrap();
+		assert_eq!(cur, dec);
+		// Records with a lower rank, or the same rank and a later id, follow
+		assert!(dec.precedes(&Value::parse("{ id: post:one, rank: 1 }"), Some(&orders)));
+		assert!(dec.precedes(&Value::parse("{ id: post:zed, rank: 2 }"), Some(&orders)));
+		assert!(!dec.precedes(&Value::parse("{ id: post:two, rank: 2 }"), Some(&orders)));
+		assert!(!dec.precedes(&Value::parse("{ id: post:abc, rank: 3 }"), Some(&orders)));
+		// A cursor for a different ORDER clause is not valid
+		assert!(Cursor::decode(&cur.encode(), None).is_err());
+		assert!(Cursor::decode("not a cursor", None).is_err());
+	}
+}
@@ -1,5 +1,9 @@
 use crate::cnf::PROTECTED_PARAM_NAMES;
+use crate::ctx::limits::Limits;
+use crate::ctx::tracer::Tracer;
 use crate::ctx::Context;
+use crate::ctx::Reason;
+use crate::dbs::normalize;
 use crate::dbs::response::Response;
 use crate::dbs::Auth;
 use crate::dbs::Level;
@@ -8,31 +12,89 @@ use crate::dbs::Transaction;
 use crate::dbs::LOG;
 use crate::err::Error;
 use crate::kvs::Datastore;
+use crate::sql::object::Object;
 use crate::sql::paths::DB;
 use crate::sql::paths::NS;
 use crate::sql::query::Query;
 use crate::sql::statement::Statement;
 use crate::sql::value::Value;
 use futures::lock::Mutex;
+use std::collections::{BTreeMap, BTreeSet, VecDeque};
 use std::sync::Arc;
+use std::time::Duration;
 use tracing::instrument;
 use trice::Instant;
+use uuid::Uuid;
 
 pub(crate) struct Executor<'a> {
 	err: bool,
 	kvs: &'a Datastore,
 	txn: Option<Transaction>,
+	sid: Uuid,
+	idn: Option<String>,
+	// Whether every transaction is rolled back instead of committed
+	dry: bool,
+	// Whether the transaction is kept open after the query
+	held: bool,
+	// The previous values of the variables set in the current transaction
+	lets: Vec<(String, Option<Value>)>,
+	// The names of the variables set in the query
+	names: BTreeSet<String>,
+	// The variables which were set in the query, once finished
+	vars: BTreeMap<String, Value>,
+	// The id of the current transaction in the journal, once a statement is journaled
+	jid: Option<Uuid>,
 }
 
 impl<'a> Executor<'a> {
-	pub fn new(kvs: &'a Datastore) -> Executor<'a> {
+	pub fn new(kvs: &'a Datastore, sid: Uuid, idn: Option<String>) -> Executor<'a> {
 		Executor {
 			kvs,
 			txn: None,
 			err: false,
+			sid,
+			idn,
+			dry: false,
+			held: false,
+			lets: vec![],
+			names: BTreeSet::new(),
+			vars: BTreeMap::new(),
+			jid: None,
 		}
 	}
 
+	/// Run the statements of the query in a transaction which is kept open
+	/// after the query, and which is committed or cancelled separately
+	pub fn with_transaction(
+		mut self,
+		txn: Transaction,
+		err: bool,
+		jid: Option<Uuid>,
+	) -> Executor<'a> {
+		self.txn = Some(txn);
+		self.err = err;
+		self.jid = jid;
+		self.held = true;
+		self
+	}
+
+	/// Check if a statement failed in the transaction which is kept open
+	pub fn failed(&self) -> bool {
+		self.err
+	}
+
+	/// Get the id of the transaction which is kept open in the journal,
+	/// if any of its statements were journaled
+	pub fn journal_id(&self) -> Option<Uuid> {
+		self.jid
+	}
+
+	/// Take the variables which were set by LET statements in the
+	/// query, and which were not rolled back by a transaction
+	pub fn vars(&mut self) -> BTreeMap<String, Value> {
+		std::mem::take(&mut self.vars)
+	}
+
 	/// # Return
 	/// - true if a new transaction has begun
 	/// - false if
@@ -67,6 +129,17 @@ impl<'a> Executor<'a> {
 					// Cancel and ignore any error because the error flag was
 					// already set
 					let _ = txn.cancel().await;
+				} else if self.dry {
+					// Roll back the changes of a dry-run
+					if txn.cancel().await.is_err() {
+						self.err = true;
+					}
+				} else if let Err(e) = self.sync() {
+					// The journaled statements could not be synced to disk
+					let _ = txn.cancel().await;
+					self.err = true;
+					self.finish(false);
+					return Err(e);
 				} else if let Err(e) = txn.commit().await {
 					// Transaction failed to commit
 					//
@@ -74,8 +147,10 @@ impl<'a> Executor<'a> {
 					// the transaction didn't commit. Detect that and tell
 					// the user.
 					self.err = true;
+					self.finish(false);
 					return Err(e);
 				}
+				self.finish(!self.err && !self.dry);
 			}
 		}
 		Ok(())
@@ -89,6 +164,70 @@ impl<'a> Executor<'a> {
 				if txn.cancel().await.is_err() {
 					self.err = true;
 				}
+				self.finish(false);
+			}
+		}
+	}
+
+	/// Get the number of changes to records which the current transaction
+	/// has made, so that the changes of a statement can be journaled
+	async fn mark(&self) -> usize {
+		match (self.kvs.journal(), self.txn.as_ref()) {
+			(Some(_), Some(txn)) => txn.lock().await.changes_len(),
+			_ => 0,
+		}
+	}
+
+	/// Append a statement which changes the datastore to the journal,
+	/// before its transaction is committed. The changes which the statement
+	/// made to records since the mark are journaled with it, unless it only
+	/// changes definitions, so that it is replayed as it was made.
+	async fn journal(
+		&mut self,
+		ctx: &Context<'_>,
+		opt: &Options,
+		sql: &str,
+		writes: bool,
+		mark: usize,
+	) -> Result<(), Error> {
+		match (self.kvs.journal(), self.dry) {
+			(Some(journal), false) => {
+				let jid = *self.jid.get_or_insert_with(Uuid::new_v4);
+				let (ns, db) = (opt.ns.as_deref(), opt.db.as_deref());
+				match (writes, self.txn.as_ref()) {
+					(true, Some(txn)) => {
+						let txn = txn.lock().await;
+						journal.statement(ctx, &jid, ns, db, sql, Some(txn.changes_after(mark)))
+					}
+					_ => journal.statement(ctx, &jid, ns, db, sql, None),
+				}
+			}
+			_ => Ok(()),
+		}
+	}
+
+	/// Sync the journaled statements of the current transaction to disk
+	fn sync(&self) -> Result<(), Error> {
+		match (self.kvs.journal(), self.jid) {
+			(Some(journal), Some(_)) => journal.sync(),
+			_ => Ok(()),
+		}
+	}
+
+	/// Append the outcome of the current transaction to the journal
+	fn finish(&mut self, committed: bool) {
+		if let (Some(jid), Some(journal)) = (self.jid.take(), self.kvs.journal()) {
+			journal.finish(&jid, committed);
+		}
+	}
+
+	/// Restore the variables which were set in a transaction
+	/// which was cancelled, or which failed to commit
+	fn unset(&mut self, ctx: &mut Context<'_>) {
+		for (name, prev) in self.lets.drain(..).rev() {
+			match prev {
+				Some(v) => ctx.add_value(name, v),
+				None => ctx.remove_value(&name),
 			}
 		}
 	}
@@ -97,6 +236,9 @@ impl<'a> Executor<'a> {
 		Response {
 			time: v.time,
 			result: Err(Error::QueryCancelled),
+			retries: v.retries,
+			cursor: None,
+			total: None,
 		}
 	}
 
@@ -107,12 +249,13 @@ impl<'a> Executor<'a> {
 				result: match v.result {
 					Ok(_) => Err(commit_error
 						.as_ref()
-						.map(|e| Error::QueryNotExecutedDetail {
-							message: e.to_string(),
-						})
+						.map(not_committed)
 						.unwrap_or(Error::QueryNotExecuted)),
 					Err(e) => Err(e),
 				},
+				retries: v.retries,
+				cursor: None,
+				total: None,
 			},
 			_ => v,
 		}
@@ -139,12 +282,36 @@ impl<'a> Executor<'a> {
 		mut opt: Options,
 		qry: Query,
 	) -> Result<Vec<Response>, Error> {
+		// Serve read-only queries from the replica, if configured
+		let kvs = self.kvs;
+		if let (Some(replica), false) = (kvs.replica(), self.held) {
+			if qry.iter().all(|v| v.read_only()) {
+				self.kvs = replica;
+			}
+		}
+		// Roll back every transaction of a dry-run
+		self.dry = opt.dry;
+		// Send any live query notifications to the sessions of this datastore
+		ctx.add_registry(kvs.registry().clone());
+		// Count the queries which are run against each namespace
+		ctx.add_usage(kvs.usage().clone());
+		// Select the statement statistics from `system:statements`
+		ctx.add_statistics(kvs.statistics().clone());
+		// Apply the configuration of this datastore
+		ctx.add_config(kvs.config().clone());
 		// Initialise buffer of responses
 		let mut buf: Vec<Response> = vec![];
 		// Initialise array of responses
 		let mut out: Vec<Response> = vec![];
+		// The number of times a statement or transaction which conflicted is retried
+		let retries = kvs.retries();
+		// The statements of the current transaction, if it can be retried
+		let mut block: Option<Vec<Statement>> = None;
+		// The number of times the current statement or transaction was retried
+		let mut attempt = 0;
 		// Process all statements in query
-		for stm in qry.into_iter() {
+		let mut queue: VecDeque<Statement> = qry.into_iter().collect();
+		while let Some(stm) = queue.pop_front() {
 			// Log the statement
 			debug!(target: LOG, "Executing: {}", stm);
 			// Reset errors
@@ -153,8 +320,76 @@ impl<'a> Executor<'a> {
 			}
 			// Get the statement start time
 			let now = Instant::now();
+			// Keep the statements of a transaction which can be retried
+			if let (Some(v), true) = (&mut block, self.txn.is_some()) {
+				if !matches!(stm, Statement::Begin(_) | Statement::Cancel(_) | Statement::Commit(_))
+				{
+					v.push(stm.clone());
+				}
+			}
+			// Keep a statement which can be retried outside of a transaction
+			let retry = match retries > 0 && self.txn.is_none() && stm.writeable() {
+				true => Some(stm.clone()),
+				false => None,
+			};
 			// Check if this is a RETURN statement
 			let clr = matches!(stm, Statement::Output(_));
+			// Get the type of statement for the metrics
+			let kind = stm.kind();
+			// Get the tables for any latency targets
+			let tbs = match kvs.slo().is_enabled() {
+				true => Some(stm.tables()),
+				false => None,
+			};
+			// Get the statement for the slow query log
+			let slow = kvs.settings().slow_query_threshold().map(|v| (v, stm.to_string()));
+			// Get the normalized statement for the statement statistics
+			let normalized = kvs.statistics().is_enabled().then(|| normalize(&stm.to_string()));
+			// The number of records which the statement examined
+			let mut examined = 0;
+			// The cursor of the next page of a SELECT statement
+			let mut cursor = None;
+			// The total number of records matched by a SELECT statement
+			let mut total = None;
+			// Get any live query which is killed, for the quotas
+			let killed = match &stm {
+				Statement::Kill(v) => Some(v.id.0),
+				_ => None,
+			};
+			// Route the notifications of any live query to this session before
+			// it runs, so that any notifications which it replays are received
+			let live = match &stm {
+				Statement::Live(v) => {
+					kvs.registry().subscribe(&self.sid, v.id.0);
+					Some(v.id.0)
+				}
+				_ => None,
+			};
+			// Check the quotas of the identity
+			let quota = kvs.quotas().check(self.idn.as_deref(), &stm);
+			// Count the query against the selected namespace
+			if let Some(ns) = &opt.ns {
+				kvs.usage().query(ns, opt.db.as_deref());
+			}
+			// Get any tracer for the session
+			let tracer = kvs
+				.registry()
+				.tracer(&self.sid)
+				.map(|chn| (chn, stm.to_string(), Arc::new(Tracer::default())));
+			// Get the cache key of a SELECT statement outside of a transaction
+			let cached = match (&stm, self.txn.is_none(), &opt.ns, &opt.db) {
+				(Statement::Select(v), true, Some(_), Some(_)) => {
+					let cache = kvs.result_cache();
+					cache.key(&ctx, &opt, v).map(|k| (k, stm.tables(), cache.generation()))
+				}
+				_ => None,
+			};
+			// Get any cached results of the statement
+			let hit = cached.as_ref().and_then(|(k, _, _)| kvs.result_cache().get(k));
+			let from_cache = hit.is_some();
+			// Check if the statement changes any definitions
+			let schema =
+				matches!(stm, Statement::Apply(_) | Statement::Define(_) | Statement::Remove(_));
 			// Process a single statement
 			let res = match stm {
 				// Specify runtime options
@@ -172,27 +407,71 @@ impl<'a> Executor<'a> {
 						"TABLES" => opt.tables(stm.what),
 						"IMPORT" => opt.import(stm.what),
 						"FORCE" => opt.force(stm.what),
+						"SAFE" => opt.safe(stm.what),
+						"STRICT_TYPES" => opt.types(stm.what),
 						_ => break,
 					};
 					// Continue
 					continue;
 				}
+				// Show the sessions which are connected
+				Statement::Show(_) => opt.check(Level::Kv).map(|_| kvs.registry().show()),
+				// Kill a session which is connected
+				Statement::Kill(stm) if kvs.registry().contains(&stm.id.0) => {
+					opt.check(Level::Kv).map(|_| {
+						kvs.registry().kill(&stm.id.0);
+						Value::None
+					})
+				}
+				// Transactions which are kept open are finished separately
+				Statement::Begin(_) | Statement::Cancel(_) | Statement::Commit(_) if self.held => {
+					Err(Error::TxNested)
+				}
 				// Begin a new transaction
 				Statement::Begin(_) => {
-					self.begin(true).await;
+					if self.begin(true).await && retries > 0 {
+						block = Some(vec![]);
+					}
 					continue;
 				}
 				// Cancel a running transaction
 				Statement::Cancel(_) => {
 					self.cancel(true).await;
+					self.unset(&mut ctx);
+					block = None;
+					attempt = 0;
 					buf = buf.into_iter().map(|v| self.buf_cancel(v)).collect();
 					out.append(&mut buf);
 					debug_assert!(self.txn.is_none(), "cancel(true) should have unset txn");
 					continue;
 				}
 				// Commit a running transaction
-				Statement::Commit(_) => {
+				Statement::Commit(stm) => {
 					let commit_error = self.commit(true).await.err();
+					// Retry the transaction if it conflicted with a concurrent transaction
+					let conflicted = matches!(commit_error, Some(Error::TxConflict))
+						|| buf.iter().any(|v| matches!(v.result, Err(Error::TxConflict)));
+					if let (true, Some(stms)) = (conflicted && attempt < retries, block.take()) {
+						self.unset(&mut ctx);
+						buf.clear();
+						attempt += 1;
+						backoff(attempt).await;
+						queue.push_front(Statement::Commit(stm));
+						for v in stms.into_iter().rev() {
+							queue.push_front(v);
+						}
+						queue.push_front(Statement::Begin(Default::default()));
+						continue;
+					}
+					block = None;
+					for v in buf.iter_mut() {
+						v.retries = attempt;
+					}
+					attempt = 0;
+					match self.err {
+						true => self.unset(&mut ctx),
+						false => self.lets.clear(),
+					}
 					buf = buf.into_iter().map(|v| self.buf_commit(v, &commit_error)).collect();
 					out.append(&mut buf);
 					debug_assert!(self.txn.is_none(), "commit(true) should have unset txn");
@@ -230,10 +509,36 @@ impl<'a> Executor<'a> {
 					}
 					Ok(Value::None)
 				}
+				// Change a setting of the datastore, unless this is a dry-run
+				Statement::Global(stm) => opt.check(Level::Kv).and_then(|_| match self.dry {
+					true => Ok(Value::None),
+					false => kvs.set_global(&stm.name, &stm.what).map(|_| Value::None),
+				}),
+				// Reject statements which exceed the quotas of the identity
+				_ if quota.is_err() => quota.map(|_| Value::None),
+				// Reject writes on a read-only replica
+				_ if stm.writeable() && self.kvs.is_read_only() => Err(Error::ReplicaReadOnly),
+				// Serve the statement from the result cache
+				_ if from_cache => Ok(hit.unwrap()),
+				// Copy a database in batches, outside of a transaction
+				Statement::Copy(stm) if self.txn.is_none() && !self.dry => {
+					let sql = stm.to_string();
+					let res = match self.journal(&ctx, &opt, &sql, false, 0).await {
+						Ok(_) => match self.sync() {
+							Ok(_) => stm.copy(&ctx, &opt, kvs).await,
+							Err(e) => Err(e),
+						},
+						Err(e) => Err(e),
+					};
+					self.finish(res.is_ok());
+					res
+				}
 				// Process param definition statements
 				Statement::Set(mut stm) => {
 					// Create a transaction
 					let loc = self.begin(stm.writeable()).await;
+					// Mark the changes which were made before the statement
+					let mark = self.mark().await;
 					// Check the transaction
 					match self.err {
 						// We failed to create a transaction
@@ -253,19 +558,31 @@ impl<'a> Executor<'a> {
 									name: std::mem::take(&mut stm.name),
 								}),
 							};
+							// Journal the statement if it changed the datastore
+							let res = match res {
+								Ok(val) if stm.writeable() => {
+									let sql = stm.to_string();
+									self.journal(&ctx, &opt, &sql, !schema, mark).await.map(|_| val)
+								}
+								res => res,
+							};
 							// Check the statement
 							match res {
 								Ok(val) => {
 									// Check if writeable
 									let writeable = stm.writeable();
+									// Keep the previous value if this is in a transaction
+									if !loc && self.txn.is_some() {
+										let prev = ctx.value(&stm.name).cloned();
+										self.lets.push((stm.name.clone(), prev));
+									}
 									// Set the parameter
+									self.names.insert(stm.name.clone());
 									ctx.add_value(stm.name, val);
 									// Finalise transaction, returning nothing unless it couldn't commit
 									if writeable {
 										match self.commit(loc).await {
-											Err(e) => Err(Error::QueryNotExecutedDetail {
-												message: e.to_string(),
-											}),
+											Err(e) => Err(not_committed(&e)),
 											Ok(_) => Ok(Value::None),
 										}
 									} else {
@@ -291,6 +608,8 @@ impl<'a> Executor<'a> {
 					false => {
 						// Create a transaction
 						let loc = self.begin(stm.writeable()).await;
+						// Mark the changes which were made before the statement
+						let mark = self.mark().await;
 						// Check the transaction
 						match self.err {
 							// We failed to create a transaction
@@ -298,25 +617,55 @@ impl<'a> Executor<'a> {
 							// The transaction began successfully
 							false => {
 								// Process the statement
-								let res = match stm.timeout() {
-									// There is a timeout clause
-									Some(timeout) => {
-										// Set statement timeout
-										let mut ctx = Context::new(&ctx);
+								let res = {
+									let mut ctx = Context::new(&ctx);
+									// Set statement timeout, or the default statement timeout
+									let config = kvs.config();
+									if let Some(timeout) =
+										stm.timeout().or(config.statement_timeout)
+									{
 										ctx.add_timeout(timeout);
-										ctx.add_transaction(self.txn.as_ref());
-										// Process the statement
-										let res = stm.compute(&ctx, &opt).await;
-										// Catch statement timeout
-										match ctx.is_timedout() {
-											true => Err(Error::QueryTimedout),
-											false => res,
-										}
 									}
-									// There is no timeout clause
-									None => {
-										ctx.add_transaction(self.txn.as_ref());
-										stm.compute(&ctx, &opt).await
+									// Set statement resource limits
+									ctx.add_limits(Limits::new(
+										config.max_query_rows,
+										config.max_query_memory,
+									));
+									// Collect the cursor of the next page, and the total
+									let page = ctx.add_page();
+									// Collect the audit entries of any audited reads
+									let reads = ctx.add_reads();
+									// Collect the query plans for any tracer
+									if let Some((_, _, tracer)) = &tracer {
+										ctx.add_tracer(tracer.clone());
+									}
+									ctx.add_transaction(self.txn.as_ref());
+									// Record the running statement for the session
+									let canceller = ctx.add_cancel();
+									kvs.registry().running(&self.sid, stm.to_string(), canceller);
+									// Process the statement
+									let res = stm.compute(&ctx, &opt).await;
+									kvs.registry().finished(&self.sid);
+									// Write the audit entries of any audited reads, unless this is a dry-run
+									let res = match self.dry {
+										true => res,
+										false => match reads.write(kvs).await {
+											Ok(_) => res,
+											Err(e) => res.and(Err(e)),
+										},
+									};
+									// Count the records which the statement examined
+									examined = ctx.limits().map_or(0, Limits::examined);
+									// Keep the cursor of the next page of records
+									if let Statement::Select(_) = stm {
+										cursor = page.cursor();
+										total = page.total();
+									}
+									// Catch statement timeout, or a killed session
+									match ctx.done() {
+										Some(Reason::Timedout) => Err(Error::QueryTimedout),
+										Some(Reason::Canceled) => Err(Error::QueryKilled),
+										None => res,
 									}
 								};
 								// Catch global timeout
@@ -324,13 +673,21 @@ impl<'a> Executor<'a> {
 									true => Err(Error::QueryTimedout),
 									false => res,
 								};
+								// Journal the statement if it changed the datastore
+								let res = match res {
+									Ok(v) if stm.writeable() => {
+										let sql = stm.to_string();
+										self.journal(&ctx, &opt, &sql, !schema, mark)
+											.await
+											.map(|_| v)
+									}
+									res => res,
+								};
 								// Finalise transaction and return the result.
 								if res.is_ok() && stm.writeable() {
 									if let Err(e) = self.commit(loc).await {
 										// The commit failed
-										Err(Error::QueryNotExecutedDetail {
-											message: e.to_string(),
-										})
+										Err(not_committed(&e))
 									} else {
 										// Successful, committed result
 										res
@@ -346,6 +703,23 @@ impl<'a> Executor<'a> {
 					}
 				},
 			};
+			// Retry a statement which conflicted with a concurrent transaction
+			if let (Err(Error::TxConflict), Some(stm)) = (&res, retry) {
+				if attempt < retries {
+					attempt += 1;
+					backoff(attempt).await;
+					queue.push_front(stm);
+					continue;
+				}
+			}
+			// Clear the result cache if any definitions changed
+			if schema && res.is_ok() {
+				kvs.result_cache().clear();
+			}
+			// Cache the results of the statement
+			if let (Some((key, tables, generation)), Ok(v), false) = (cached, &res, from_cache) {
+				kvs.result_cache().set(key, opt.ns(), opt.db(), tables, v.clone(), generation);
+			}
 			// Produce the response
 			let res = Response {
 				// Get the statement end time
@@ -356,9 +730,54 @@ impl<'a> Executor<'a> {
 					self.err = true;
 					e
 				}),
+				// Get the number of times the statement was retried
+				retries: match self.txn.is_none() {
+					true => std::mem::take(&mut attempt),
+					false => 0,
+				},
+				// Get the cursor of the next page of records
+				cursor: cursor.take(),
+				// Get the total number of matched records
+				total: total.take(),
 			};
+			// Log the statement if it was slow
+			if let Some((threshold, sql)) = slow {
+				if res.time >= threshold {
+					warn!(target: LOG, "Slow query took {:?}: {}", res.time, sql);
+				}
+			}
+			// Record the statistics of the normalized statement
+			if let Some(sql) = normalized {
+				let ns = opt.ns.as_deref().unwrap_or_default();
+				let db = opt.db.as_deref().unwrap_or_default();
+				kvs.statistics().record(ns, db, sql, res.time, &res.result, examined);
+			}
+			// Count the statement against any latency targets
+			if let (Some(tbs), Some(ns)) = (&tbs, &opt.ns) {
+				let timedout = matches!(res.result, Err(Error::QueryTimedout));
+				kvs.slo().record(ns, opt.db.as_deref(), tbs, res.time, timedout);
+			}
+			// Count the statement and any live query subscriptions
+			kvs.metrics().statement(kind, res.time, res.result.is_ok());
+			match (kind, res.result.is_ok()) {
+				("live", true) => kvs.metrics().live(1),
+				("kill", true) => kvs.metrics().live(-1),
+				_ => (),
+			}
+			// Stop routing the notifications of any killed or failed live query
+			match (&res.result, killed, live) {
+				(Ok(_), Some(v), _) => kvs.registry().unsubscribe(&v),
+				(Err(_), _, Some(v)) => kvs.registry().unsubscribe(&v),
+				_ => (),
+			}
+			// Count the output and any live queries of the identity
+			kvs.quotas().finished(self.idn.as_deref(), kind, killed, &res.result);
+			// Send the trace of the statement to the session
+			if let Some((chn, sql, tracer)) = tracer {
+				let _ = chn.try_send(trace(sql, &res, tracer.plans()));
+			}
 			// Output the response
-			if self.txn.is_some() {
+			if self.txn.is_some() && !self.held {
 				if clr {
 					buf.clear();
 				}
@@ -367,7 +786,58 @@ impl<'a> Executor<'a> {
 				out.push(res)
 			}
 		}
+		// Restore the variables of any unfinished transaction
+		if self.txn.is_some() && !self.held {
+			self.unset(&mut ctx);
+			self.finish(false);
+		}
+		// Keep the variables which were set in the query
+		self.vars = self
+			.names
+			.iter()
+			.filter_map(|k| ctx.value(k).map(|v| (k.clone(), v.clone())))
+			.collect();
 		// Return responses
 		Ok(out)
 	}
 }
+
+/// Wait before retrying a statement or transaction which conflicted with
+/// a concurrent transaction, for longer after each attempt
+async fn backoff(attempt: u32) {
+	let delay = Duration::from_millis(1 << attempt.min(8));
+	#[cfg(target_arch = "wasm32")]
+	wasmtimer::tokio::sleep(delay).await;
+	#[cfg(not(target_arch = "wasm32"))]
+	tokio::time::sleep(delay).await;
+}
+
+/// Get the error for a statement whose transaction failed to commit,
+/// keeping any retryable error so that clients know to retry it
+fn not_committed(e: &Error) -> Error {
+	match e {
+		Error::TxConflict => Error::TxConflict,
+		e => Error::QueryNotExecutedDetail {
+			message: e.to_string(),
+		},
+	}
+}
+
+/// Describe the statement, timing, result status, and query plans of a
+/// statement which was traced
+fn trace(sql: String, res: &Response, plans: Vec<Value>) -> Value {
+	let mut obj = Object::default();
+	obj.insert("statement".to_owned(), sql.into());
+	obj.insert("time".to_owned(), res.speed().into());
+	match &res.result {
+		Ok(_) => {
+			obj.insert("status".to_owned(), "OK".into());
+		}
+		Err(e) => {
+			obj.insert("status".to_owned(), "ERR".into());
+			obj.insert("detail".to_owned(), e.to_string().into());
+		}
+	}
+	obj.insert("plans".to_owned(), plans.into());
+	obj.into()
+}
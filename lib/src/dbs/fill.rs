@@ -0,0 +1,99 @@
+use crate::cnf::MAX_FILL_ROWS;
+use crate::err::Error;
+use crate::sql::field::{Field, Fields};
+use crate::sql::fill::{bucket, Fill};
+use crate::sql::group::Groups;
+use crate::sql::idiom::Idiom;
+use crate::sql::number::Number;
+use crate::sql::value::Value;
+
+/// Create the rows for the time buckets which are missing between the grouped
+/// rows. The rows must be ordered by their group values, so that the rows of
+/// each series are adjacent, and ordered by their time bucket. The last group
+/// is the time bucket, and the other groups identify each series.
+pub(super) fn fill(
+	fill: Fill,
+	fields: &Fields,
+	groups: &Groups,
+	rows: Vec<Value>,
+) -> Result<Vec<Value>, Error> {
+	// Get the time bucket, and the groups which identify each series
+	let (last, keys) = match groups.split_last() {
+		Some(v) => v,
+		None => return Ok(rows),
+	};
+	// Get the duration of each time bucket
+	let step = match bucket(fields, last).and_then(|v| chrono::Duration::from_std(*v).ok()) {
+		Some(v) => v,
+		None => return Ok(rows),
+	};
+	// Get the fields which are filled
+	let values: Vec<Idiom> = fields
+		.other()
+		.filter_map(|field| match field {
+			Field::Single {
+				expr,
+				alias,
+			} => Some(alias.clone().unwrap_or_else(|| expr.to_idiom())),
+			_ => None,
+		})
+		.filter(|idiom| !groups.iter().any(|g| g.0 == *idiom))
+		.collect();
+	// Loop over each row
+	let mut out = Vec::with_capacity(rows.len());
+	let mut added = 0;
+	let mut rows = rows.into_iter().peekable();
+	while let Some(row) = rows.next() {
+		let mut missing = Vec::new();
+		// Check if the next row is in the same series
+		if let Some(next) = rows.peek() {
+			if keys.iter().all(|k| row.pick(k) == next.pick(k)) {
+				if let (Value::Datetime(a), Value::Datetime(b)) =
+					(row.pick(&last.0), next.pick(&last.0))
+				{
+					let span = (b.0 - a.0).num_nanoseconds().unwrap_or(i64::MAX) as f64;
+					let mut time = a.0 + step;
+					while time < b.0 {
+						// Check that the filled rows are within the limit
+						added += 1;
+						if added > MAX_FILL_ROWS {
+							return Err(Error::FillLimit {
+								limit: MAX_FILL_ROWS,
+							});
+						}
+						// Create the row for the missing time bucket
+						let mut obj = row.clone();
+						obj.put(&last.0, time.into());
+						match fill {
+							Fill::None => {
+								for idiom in values.iter() {
+									obj.put(idiom, Value::None);
+								}
+							}
+							Fill::Previous => (),
+							Fill::Linear => {
+								let frac =
+									(time - a.0).num_nanoseconds().unwrap_or(0) as f64 / span;
+								for idiom in values.iter() {
+									let val = match (row.pick(idiom), next.pick(idiom)) {
+										(Value::Number(x), Value::Number(y)) => {
+											let (x, y) = (x.to_float(), y.to_float());
+											Number::from(x + (y - x) * frac).into()
+										}
+										_ => Value::None,
+									};
+									obj.put(idiom, val);
+								}
+							}
+						}
+						missing.push(obj);
+						time = time + step;
+					}
+				}
+			}
+		}
+		out.push(row);
+		out.append(&mut missing);
+	}
+	Ok(out)
+}
@@ -9,6 +9,9 @@ use crate::idx::planner::plan::Plan;
 use crate::key::graph;
 use crate::key::thing;
 use crate::sql::dir::Dir;
+use crate::sql::idiom::Idiom;
+use crate::sql::paths::ID;
+use crate::sql::permission::Permission;
 use crate::sql::thing::Thing;
 use crate::sql::value::Value;
 use crate::sql::{Edges, Range, Table};
@@ -155,6 +158,8 @@ impl Iterable {
 		let txn = ctx.clone_transaction()?;
 		// Check that the table exists
 		txn.lock().await.check_ns_db_tb(opt.ns(), opt.db(), &v, opt.strict).await?;
+		// Check if the records need to be decoded
+		let skip = Self::covered(ctx, opt, stm, &v, &[]).await?;
 		// Prepare the start and end keys
 		let beg = thing::prefix(opt.ns(), opt.db(), &v);
 		let end = thing::suffix(opt.ns(), opt.db(), &v);
@@ -196,8 +201,12 @@ impl Iterable {
 					}
 					// Parse the data from the store
 					let key: crate::key::thing::Thing = (&k).into();
-					let val: crate::sql::value::Value = (&v).into();
 					let rid = Thing::from((key.tb, key.id));
+					// Skip decoding the record if only the id is read
+					let val: crate::sql::value::Value = match skip {
+						true => Value::from(map! { "id".to_string() => Value::from(rid.clone()) }),
+						false => (&v).into(),
+					};
 					// Create a new operable value
 					let val = Operable::Value(val);
 					let mut child_ctx = Context::new(&ctx);
@@ -223,6 +232,8 @@ impl Iterable {
 		let txn = ctx.clone_transaction()?;
 		// Check that the table exists
 		txn.lock().await.check_ns_db_tb(opt.ns(), opt.db(), &v.tb, opt.strict).await?;
+		// Check if the records need to be decoded
+		let skip = Self::covered(ctx, opt, stm, &v.tb, &[]).await?;
 		// Prepare the range start key
 		let beg = match &v.beg {
 			Bound::Unbounded => thing::prefix(opt.ns(), opt.db(), &v.tb),
@@ -281,8 +292,12 @@ impl Iterable {
 					}
 					// Parse the data from the store
 					let key: crate::key::thing::Thing = (&k).into();
-					let val: crate::sql::value::Value = (&v).into();
 					let rid = Thing::from((key.tb, key.id));
+					// Skip decoding the record if only the id is read
+					let val: crate::sql::value::Value = match skip {
+						true => Value::from(map! { "id".to_string() => Value::from(rid.clone()) }),
+						false => (&v).into(),
+					};
 					let mut ctx = Context::new(ctx);
 					ctx.add_thing(&rid);
 					// Create a new operable value
@@ -449,6 +464,11 @@ impl Iterable {
 		let txn = ctx.clone_transaction()?;
 		// Check that the table exists
 		txn.lock().await.check_ns_db_tb(opt.ns(), opt.db(), &table.0, opt.strict).await?;
+		// Check if the records need to be fetched
+		let covered = match plan.covered() {
+			Some((col, v)) if Self::covered(ctx, opt, stm, &table, &[col]).await? => Some((col, v)),
+			_ => None,
+		};
 		let mut iterator = plan.new_iterator(opt, &txn).await?;
 		let mut things = iterator.next_batch(&txn, 1000).await?;
 		while !things.is_empty() {
@@ -468,6 +488,17 @@ impl Iterable {
 					continue;
 				}
 
+				// Build the record from the index if it covers every read field
+				if let Some((col, v)) = covered {
+					let mut val =
+						Value::from(map! { "id".to_string() => Value::from(thing.clone()) });
+					val.put(col, v.clone());
+					let mut ctx = Context::new(&ctx);
+					ctx.add_thing(&thing);
+					// Process the document record
+					ite.process(&ctx, opt, stm, Operable::Value(val)).await;
+					continue;
+				}
 				// Fetch the data from the store
 				let key = thing::new(opt.ns(), opt.db(), &table.0, &thing.id);
 				let val = txn.lock().await.get(key.clone()).await?;
@@ -488,4 +519,52 @@ impl Iterable {
 		}
 		Ok(())
 	}
+
+	/// Check if a statement only reads the id of each record, and any
+	/// fields in the given list, so that the rest of each record can be
+	/// skipped. Permissions are computed from the whole record, and soft
+	/// deleted records are hidden using the whole record, so this is never
+	/// the case when they need to be checked.
+	async fn covered(
+		ctx: &Context<'_>,
+		opt: &Options,
+		stm: &Statement<'_>,
+		tb: &str,
+		cols: &[&Idiom],
+	) -> Result<bool, Error> {
+		// Get the fields read by the statement
+		let fields = match stm.projection() {
+			Some(v) => v,
+			None => return Ok(false),
+		};
+		// Check that every field is covered
+		if !fields
+			.iter()
+			.all(|v| v.starts_with(ID.as_ref()) || cols.iter().any(|c| v.starts_with(c)))
+		{
+			return Ok(false);
+		}
+		let txn = ctx.clone_transaction()?;
+		let mut run = txn.lock().await;
+		// Check that there are no soft deleted records to hide
+		if !opt.purge && !stm.deleted() {
+			if let Ok(v) = run.get_tb(opt.ns(), opt.db(), tb).await {
+				if v.soft.is_some() {
+					return Ok(false);
+				}
+			}
+		}
+		// Check that there are no permissions to process
+		if opt.perms && opt.auth.perms() {
+			match run.get_tb(opt.ns(), opt.db(), tb).await {
+				Ok(v) if v.permissions.select == Permission::Full => (),
+				_ => return Ok(false),
+			}
+			let fds = run.all_fd(opt.ns(), opt.db(), tb).await?;
+			if fds.iter().any(|v| v.permissions.select != Permission::Full) {
+				return Ok(false);
+			}
+		}
+		Ok(true)
+	}
 }
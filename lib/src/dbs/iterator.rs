@@ -1,5 +1,8 @@
 use crate::ctx::Canceller;
 use crate::ctx::Context;
+use crate::dbs::fill::fill;
+use crate::dbs::Auth;
+use crate::dbs::Cursor;
 use crate::dbs::Options;
 use crate::dbs::Statement;
 use crate::dbs::LOG;
@@ -9,6 +12,7 @@ use crate::idx::planner::plan::Plan;
 use crate::sql::array::Array;
 use crate::sql::edges::Edges;
 use crate::sql::field::Field;
+use crate::sql::function::Function;
 use crate::sql::range::Range;
 use crate::sql::table::Table;
 use crate::sql::thing::Thing;
@@ -19,6 +23,9 @@ use std::borrow::Cow;
 use std::cmp::Ordering;
 use std::collections::{BTreeMap, HashMap};
 use std::mem;
+use std::ops::Bound;
+use tracing::instrument;
+use tracing::Instrument;
 
 pub(crate) enum Iterable {
 	Value(Value),
@@ -51,6 +58,16 @@ pub(crate) struct Iterator {
 	limit: Option<usize>,
 	// Iterator start value
 	start: Option<usize>,
+	// Iterator cursor to start after
+	after: Option<Cursor>,
+	// Iterator records skipped before the cursor
+	skipped: usize,
+	// Iterator total from the record counter
+	total: Option<usize>,
+	// Iterator processed count
+	count: usize,
+	// Iterator output is already in the order of the ORDER clause
+	ordered: bool,
 	// Iterator runtime error
 	error: Option<Error>,
 	// Iterator output results
@@ -70,7 +87,13 @@ impl Iterator {
 		self.entries.push(val)
 	}
 
+	/// Sets the total number of records, if it is known before iterating
+	pub fn set_total(&mut self, val: Option<usize>) {
+		self.total = val
+	}
+
 	/// Process the records and output
+	#[instrument(name = "iterator", skip_all)]
 	pub async fn output(
 		&mut self,
 		ctx: &Context<'_>,
@@ -86,10 +109,20 @@ impl Iterator {
 		self.setup_limit(ctx, opt, stm).await?;
 		// Process the query START clause
 		self.setup_start(ctx, opt, stm).await?;
+		// Process the query START AFTER clause
+		self.setup_after(ctx, opt, stm).await?;
+		// Check any safe mode restrictions
+		self.check_safe(ctx, opt, stm).await?;
 		// Process any EXPLAIN clause
 		let explanation = self.output_explain(ctx, opt, stm)?;
+		// Record the query plan for any tracer
+		if let Some(tracer) = ctx.tracer() {
+			tracer.plan(self.explain());
+		}
+		// Check if the records are iterated in order
+		self.ordered = matches!(self.entries.as_slice(), [Iterable::Index(_, p)] if p.ordered());
 		// Process prepared values
-		self.iterate(ctx, opt, stm).await?;
+		self.iterate(&run, opt, stm).await?;
 		// Return any document errors
 		if let Some(e) = self.error.take() {
 			return Err(e);
@@ -98,14 +131,20 @@ impl Iterator {
 		self.output_split(ctx, opt, stm).await?;
 		// Process any GROUP clause
 		self.output_group(ctx, opt, stm).await?;
+		// Process any window functions
+		self.output_window(ctx, opt, stm).await?;
 		// Process any ORDER clause
 		self.output_order(ctx, opt, stm).await?;
+		// Return the total number of records
+		self.output_total(ctx, opt, stm);
 		// Process any START clause
 		self.output_start(ctx, opt, stm).await?;
 		// Process any LIMIT clause
 		self.output_limit(ctx, opt, stm).await?;
 		// Process any FETCH clause
 		self.output_fetch(ctx, opt, stm).await?;
+		// Return the cursor of the next page
+		self.output_cursor(ctx, opt, stm);
 		// Add the EXPLAIN clause to the result
 		if let Some(e) = explanation {
 			self.results.push(e);
@@ -140,6 +179,81 @@ impl Iterator {
 		Ok(())
 	}
 
+	#[inline]
+	async fn setup_after(
+		&mut self,
+		ctx: &Context<'_>,
+		opt: &Options,
+		stm: &Statement<'_>,
+	) -> Result<(), Error> {
+		if let Some(v) = stm.after() {
+			// Grouped records do not have a cursor
+			if stm.split().is_some() || stm.group().is_some() || stm.window() {
+				return Err(Error::InvalidCursor {
+					value: v.0.to_string(),
+				});
+			}
+			let cursor = v.process(ctx, opt, stm.order()).await?;
+			// Records in a table are iterated in the order of their ids, so
+			// the table is iterated from the record after the cursor, unless
+			// the records before the cursor must be counted in the total
+			if stm.order().is_none() && (!stm.total() || self.total.is_some()) {
+				if let Some(Value::Thing(id)) = cursor.id() {
+					for v in self.entries.iter_mut() {
+						if matches!(v, Iterable::Table(t) if t.0 == id.tb) {
+							*v = Iterable::Range(Range {
+								tb: id.tb.clone(),
+								beg: Bound::Excluded(id.id.clone()),
+								end: Bound::Unbounded,
+							});
+						}
+					}
+				}
+			}
+			self.after = Some(cursor);
+		}
+		Ok(())
+	}
+
+	#[inline]
+	async fn check_safe(
+		&self,
+		ctx: &Context<'_>,
+		opt: &Options,
+		stm: &Statement<'_>,
+	) -> Result<(), Error> {
+		// Only changes made to whole tables are restricted
+		if !stm.is_update() && !stm.is_delete() {
+			return Ok(());
+		}
+		if stm.conds().is_some() || stm.limit().is_some() {
+			return Ok(());
+		}
+		if !self.entries.iter().any(|v| matches!(v, Iterable::Table(_))) {
+			return Ok(());
+		}
+		// Check if safe mode is enabled for this query
+		let safe = match opt.auth.as_ref() {
+			_ if opt.safe => true,
+			// Scope safe mode only applies to queries run by the user
+			Auth::Sc(ns, db, sc) if opt.perms => {
+				let txn = ctx.clone_transaction()?;
+				let mut run = txn.lock().await;
+				run.get_sc(ns, db, sc).await?.safe
+			}
+			_ => false,
+		};
+		match safe {
+			true => Err(Error::UnsafeStatement {
+				statement: match stm.is_update() {
+					true => String::from("UPDATE"),
+					false => String::from("DELETE"),
+				},
+			}),
+			false => Ok(()),
+		}
+	}
+
 	#[inline]
 	async fn output_split(
 		&mut self,
@@ -215,46 +329,128 @@ impl Iterator {
 						}
 					}
 				}
-				// Loop over each grouped collection
-				for (_, vals) in grp {
-					// Create a new value
-					let mut obj = Value::base();
-					// Save the collected values
-					let vals = Value::from(vals);
-					// Loop over each group clause
-					for field in fields.other() {
-						// Process the field
-						if let Field::Single {
-							expr,
-							alias,
-						} = field
-						{
-							let idiom = alias
-								.as_ref()
-								.map(Cow::Borrowed)
-								.unwrap_or_else(|| Cow::Owned(expr.to_idiom()));
-							match expr {
-								Value::Function(f) if f.is_aggregate() => {
-									let x = vals.all().get(ctx, opt, idiom.as_ref()).await?;
-									let x = f.aggregate(x).compute(ctx, opt).await?;
-									obj.set(ctx, opt, idiom.as_ref(), x).await?;
+				// Create the subtotal collections for a ROLLUP clause,
+				// from the most specific down to the grand total
+				let mut sets = vec![(groups.len(), grp)];
+				if stm.rollup() {
+					for len in (0..groups.len()).rev() {
+						let mut sub: BTreeMap<Array, Array> = BTreeMap::new();
+						for (key, vals) in sets[0].1.iter() {
+							let key = Array::from(key[..len].to_vec());
+							match sub.get_mut(&key) {
+								Some(v) => v.extend(vals.iter().cloned()),
+								None => {
+									sub.insert(key, vals.clone());
 								}
-								_ => {
-									let x = vals.first();
-									let mut child_ctx = Context::new(ctx);
-									child_ctx.add_cursor_doc(&x);
-									let x = if let Some(alias) = alias {
-										alias.compute(&child_ctx, opt).await?
-									} else {
-										expr.compute(&child_ctx, opt).await?
-									};
-									obj.set(ctx, opt, idiom.as_ref(), x).await?;
+							}
+						}
+						sets.push((len, sub));
+					}
+				}
+				// Loop over each grouped collection
+				for (len, grp) in sets {
+					let mut rows = Vec::with_capacity(grp.len());
+					for (_, vals) in grp {
+						// Create a new value
+						let mut obj = Value::base();
+						// Save the collected values
+						let vals = Value::from(vals);
+						// Loop over each group clause
+						for field in fields.other() {
+							// Process the field
+							if let Field::Single {
+								expr,
+								alias,
+							} = field
+							{
+								let idiom = alias
+									.as_ref()
+									.map(Cow::Borrowed)
+									.unwrap_or_else(|| Cow::Owned(expr.to_idiom()));
+								match expr {
+									Value::Function(f) if f.is_aggregate() => {
+										let x = vals.all().get(ctx, opt, idiom.as_ref()).await?;
+										let x = f.aggregate(x).compute(ctx, opt).await?;
+										obj.set(ctx, opt, idiom.as_ref(), x).await?;
+									}
+									// Window functions use the arguments of the first grouped row
+									Value::Function(f) if f.is_window() => {
+										let x = vals.first().pick(idiom.as_ref());
+										obj.set(ctx, opt, idiom.as_ref(), x).await?;
+									}
+									// Subtotal rows have no value for the rolled up groups
+									_ if groups[len..].iter().any(|g| g.0 == *idiom) => {
+										obj.set(ctx, opt, idiom.as_ref(), Value::None).await?;
+									}
+									_ => {
+										let x = vals.first();
+										let mut child_ctx = Context::new(ctx);
+										child_ctx.add_cursor_doc(&x);
+										let x = if let Some(alias) = alias {
+											alias.compute(&child_ctx, opt).await?
+										} else {
+											expr.compute(&child_ctx, opt).await?
+										};
+										obj.set(ctx, opt, idiom.as_ref(), x).await?;
+									}
 								}
 							}
 						}
+						// Add the object to the grouped rows
+						rows.push(obj);
+					}
+					// Fill the missing time buckets of the most specific groups
+					if let (Some(v), true) = (stm.fill(), len == groups.len()) {
+						rows = fill(v, fields, groups, rows)?;
+					}
+					// Add the rows to the results
+					self.results.extend(rows);
+				}
+			}
+		}
+		Ok(())
+	}
+
+	#[inline]
+	async fn output_window(
+		&mut self,
+		ctx: &Context<'_>,
+		opt: &Options,
+		stm: &Statement<'_>,
+	) -> Result<(), Error> {
+		if let Some(fields) = stm.expr() {
+			// Loop over each window function
+			for field in fields.other() {
+				if let Field::Single {
+					expr: Value::Function(f),
+					alias,
+				} = field
+				{
+					if let Function::Window(_, _, window) = f.as_ref() {
+						let idiom = alias.clone().unwrap_or_else(|| f.to_idiom());
+						// Get the function arguments which were computed for each row
+						let args = self
+							.results
+							.iter()
+							.map(|obj| match fields.single() {
+								Some(_) => obj.clone(),
+								None => obj.pick(&idiom),
+							})
+							.map(|v| match v {
+								Value::Array(v) => v.0,
+								_ => vec![],
+							})
+							.collect();
+						// Compute the function over the whole result set
+						let vals = window.compute(ctx, opt, f, &self.results, args).await?;
+						// Set the value of the field on each row
+						for (obj, val) in self.results.iter_mut().zip(vals) {
+							match fields.single() {
+								Some(_) => *obj = val,
+								None => obj.set(ctx, opt, &idiom, val).await?,
+							}
+						}
 					}
-					// Add the object to the results
-					self.results.push(obj);
 				}
 			}
 		}
@@ -281,8 +477,8 @@ impl Iterator {
 							a.partial_cmp(&b)
 						}
 						false => match order.direction {
-							true => a.compare(b, order, order.collate, order.numeric),
-							false => b.compare(a, order, order.collate, order.numeric),
+							true => order.compare(a, b),
+							false => order.compare(b, a),
 						},
 					};
 					//
@@ -325,6 +521,37 @@ impl Iterator {
 		Ok(())
 	}
 
+	/// Set the total number of records before the START and LIMIT clauses
+	#[inline]
+	fn output_total(&self, ctx: &Context<'_>, _opt: &Options, stm: &Statement<'_>) {
+		if let Some(page) = ctx.page() {
+			let total = match stm.total() {
+				true => Some(self.total.unwrap_or(self.results.len() + self.skipped)),
+				false => None,
+			};
+			page.set_total(total);
+		}
+	}
+
+	/// Set the cursor of the last record, if the output is a full page
+	/// and there could be more records after it
+	#[inline]
+	fn output_cursor(&self, ctx: &Context<'_>, _opt: &Options, stm: &Statement<'_>) {
+		if let Some(page) = ctx.page() {
+			let full = matches!(self.limit, Some(l) if l > 0 && self.results.len() == l);
+			let cursor = match full
+				&& stm.is_select()
+				&& stm.split().is_none()
+				&& stm.group().is_none()
+				&& !stm.window()
+			{
+				true => self.results.last().and_then(|v| Cursor::new(v, stm.order())),
+				false => None,
+			};
+			page.set_cursor(cursor.map(|c| c.encode()));
+		}
+	}
+
 	#[inline]
 	async fn output_fetch(
 		&mut self,
@@ -352,54 +579,56 @@ impl Iterator {
 		stm: &Statement<'_>,
 	) -> Result<Option<Value>, Error> {
 		Ok(if stm.explain() {
-			let mut explains = Vec::with_capacity(self.entries.len());
-			for iter in &self.entries {
-				let (operation, detail) = match iter {
-					Iterable::Value(v) => ("Iterate Value", vec![("value", v.to_owned())]),
-					Iterable::Table(t) => {
-						("Iterate Table", vec![("table", Value::from(t.0.to_owned()))])
-					}
-					Iterable::Thing(t) => {
-						("Iterate Thing", vec![("thing", Value::Thing(t.to_owned()))])
-					}
-					Iterable::Range(r) => {
-						("Iterate Range", vec![("table", Value::from(r.tb.to_owned()))])
-					}
-					Iterable::Edges(e) => {
-						("Iterate Edges", vec![("from", Value::Thing(e.from.to_owned()))])
-					}
-					Iterable::Mergeable(t, v) => (
-						"Iterate Mergeable",
-						vec![("thing", Value::Thing(t.to_owned())), ("value", v.to_owned())],
-					),
-					Iterable::Relatable(t1, t2, t3) => (
-						"Iterate Relatable",
-						vec![
-							("thing-1", Value::Thing(t1.to_owned())),
-							("thing-2", Value::Thing(t2.to_owned())),
-							("thing-3", Value::Thing(t3.to_owned())),
-						],
-					),
-					Iterable::Index(t, p) => (
-						"Iterate Index",
-						vec![("table", Value::from(t.0.to_owned())), ("plan", p.explain())],
-					),
-				};
-				let explain = Object::from(HashMap::from([
-					("operation", Value::from(operation)),
-					("detail", Value::Object(Object::from(HashMap::from_iter(detail)))),
-				]));
-				explains.push(Value::Object(explain));
-			}
-			Some(Value::Object(Object::from(HashMap::from([(
-				"explain",
-				Value::Array(Array::from(explains)),
-			)]))))
+			Some(Value::Object(Object::from(HashMap::from([("explain", self.explain())]))))
 		} else {
 			None
 		})
 	}
 
+	/// Describe how each of the prepared values is iterated
+	fn explain(&self) -> Value {
+		let mut explains = Vec::with_capacity(self.entries.len());
+		for iter in &self.entries {
+			let (operation, detail) = match iter {
+				Iterable::Value(v) => ("Iterate Value", vec![("value", v.to_owned())]),
+				Iterable::Table(t) => {
+					("Iterate Table", vec![("table", Value::from(t.0.to_owned()))])
+				}
+				Iterable::Thing(t) => {
+					("Iterate Thing", vec![("thing", Value::Thing(t.to_owned()))])
+				}
+				Iterable::Range(r) => {
+					("Iterate Range", vec![("table", Value::from(r.tb.to_owned()))])
+				}
+				Iterable::Edges(e) => {
+					("Iterate Edges", vec![("from", Value::Thing(e.from.to_owned()))])
+				}
+				Iterable::Mergeable(t, v) => (
+					"Iterate Mergeable",
+					vec![("thing", Value::Thing(t.to_owned())), ("value", v.to_owned())],
+				),
+				Iterable::Relatable(t1, t2, t3) => (
+					"Iterate Relatable",
+					vec![
+						("thing-1", Value::Thing(t1.to_owned())),
+						("thing-2", Value::Thing(t2.to_owned())),
+						("thing-3", Value::Thing(t3.to_owned())),
+					],
+				),
+				Iterable::Index(t, p) => (
+					"Iterate Index",
+					vec![("table", Value::from(t.0.to_owned())), ("plan", p.explain())],
+				),
+			};
+			let explain = Object::from(HashMap::from([
+				("operation", Value::from(operation)),
+				("detail", Value::Object(Object::from(HashMap::from_iter(detail)))),
+			]));
+			explains.push(Value::Object(explain));
+		}
+		Value::Array(Array::from(explains))
+	}
+
 	#[cfg(target_arch = "wasm32")]
 	#[async_recursion(?Send)]
 	async fn iterate(
@@ -428,8 +657,10 @@ impl Iterator {
 	) -> Result<(), Error> {
 		// Prevent deep recursion
 		let opt = &opt.dive(4)?;
+		// Records are modified one after the other, up to any LIMIT
+		let limited = (stm.is_update() || stm.is_delete()) && self.limit.is_some();
 		// Check if iterating in parallel
-		match stm.parallel() {
+		match stm.parallel() && !limited {
 			// Run statements sequentially
 			false => {
 				// Process all prepared values
@@ -453,7 +684,7 @@ impl Iterator {
 				let adocs = async {
 					// Process all prepared values
 					for v in vals {
-						e.spawn(v.channel(ctx, opt, stm, chn.clone()))
+						e.spawn(v.channel(ctx, opt, stm, chn.clone()).in_current_span())
 							// Ensure we detach the spawned task
 							.detach();
 					}
@@ -466,9 +697,11 @@ impl Iterator {
 				let avals = async {
 					// Process all received values
 					while let Ok((k, v)) = docs.recv().await {
-						e.spawn(Document::compute(ctx, opt, stm, chn.clone(), k, v))
-							// Ensure we detach the spawned task
-							.detach();
+						e.spawn(
+							Document::compute(ctx, opt, stm, chn.clone(), k, v).in_current_span(),
+						)
+						// Ensure we detach the spawned task
+						.detach();
 					}
 					// Drop the uncloned channel instance
 					drop(chn);
@@ -495,6 +728,7 @@ impl Iterator {
 	}
 
 	/// Process a new record Thing and Value
+	#[instrument(name = "document", level = "debug", skip_all)]
 	pub async fn process(
 		&mut self,
 		ctx: &Context<'_>,
@@ -506,6 +740,12 @@ impl Iterator {
 		if ctx.is_done() {
 			return;
 		}
+		// Check the number of examined records
+		if let Some(limits) = ctx.limits() {
+			if let Err(e) = limits.examine() {
+				return self.result(Err(e), stm);
+			}
+		}
 		// Setup a new workable
 		let (val, ext) = match val {
 			Operable::Value(v) => (v, Workable::Normal),
@@ -524,6 +764,11 @@ impl Iterator {
 			Statement::Insert(_) => doc.insert(ctx, opt, stm).await,
 			_ => unreachable!(),
 		};
+		// Check the memory used by the output
+		let res = match (res, ctx.limits()) {
+			(Ok(v), Some(limits)) => limits.hold(&v).map(|_| v),
+			(res, _) => res,
+		};
 		// Process the result
 		self.result(res, stm);
 	}
@@ -535,15 +780,45 @@ impl Iterator {
 			Err(Error::Ignore) => {
 				return;
 			}
+			Err(Error::Omit) => self.count += 1,
 			Err(e) => {
 				self.error = Some(e);
 				self.run.cancel();
 				return;
 			}
-			Ok(v) => self.results.push(v),
+			Ok(v) => {
+				// Skip the records up to the cursor
+				if let Some(c) = &self.after {
+					if !c.precedes(&v, stm.order()) {
+						self.skipped += 1;
+						return;
+					}
+				}
+				self.count += 1;
+				self.results.push(v);
+			}
+		}
+		// Check if enough records were modified, or if a STRICT
+		// limit was exceeded, in which case the statement fails
+		if stm.is_update() || stm.is_delete() {
+			if let Some(l) = self.limit {
+				if stm.strict() && self.count > l {
+					self.error = Some(Error::LimitExceeded {
+						limit: l,
+					});
+					self.run.cancel();
+				} else if !stm.strict() && self.count >= l {
+					self.run.cancel();
+				}
+			}
+			return;
+		}
+		// Every record must be counted for the total
+		if stm.total() && self.total.is_none() {
+			return;
 		}
 		// Check if we can exit
-		if stm.group().is_none() && stm.order().is_none() {
+		if stm.group().is_none() && !stm.window() && (stm.order().is_none() || self.ordered) {
 			if let Some(l) = self.limit {
 				if let Some(s) = self.start {
 					if self.results.len() == l + s {
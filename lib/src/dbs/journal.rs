@@ -0,0 +1,213 @@
+//! The write-ahead journal of the statements which change the datastore.
+//!
+//! When a journal is configured with [`Datastore::with_journal`], every
+//! statement which changes the datastore is appended to the journal before
+//! its transaction is committed, along with the values of the parameters
+//! which it refers to, and the id of its transaction. The journal is synced
+//! to disk before the transaction is committed, and the outcome of the
+//! transaction is appended once it is committed or cancelled. The statements
+//! of a transaction without an outcome were running when the server stopped,
+//! which helps to diagnose a crash, and the statements of the committed
+//! transactions can be replayed onto a restored snapshot, using the `restore`
+//! command, to recover the changes which were made after the snapshot.
+//!
+//! Each line of the journal is a JSON object. The values of the parameters
+//! are written as SurrealQL, so that their types are kept when replayed. The
+//! protected `$auth`, `$scope`, `$session`, and `$token` parameters are not
+//! written, and take the values of the session which replays the journal.
+//!
+//! A statement may not make the same changes when it is run again, because
+//! it creates records with generated ids, calls random, time, or custom
+//! functions, or triggers the `VALUE` and `DEFAULT` clauses of fields, and
+//! the events, which do. So every statement which changes records is
+//! journaled along with the `writes` which it made to records, and these
+//! writes are replayed in its place, with fields, events, and tables
+//! disabled as they are when importing. Statements which only change
+//! definitions, and statements which did not change any records, are
+//! replayed as they were run.
+//!
+//! [`Datastore::with_journal`]: crate::kvs::Datastore::with_journal
+use crate::changes::{Action, Change};
+use crate::cnf::PROTECTED_PARAM_NAMES;
+use crate::ctx::Context;
+use crate::dbs::LOG;
+use crate::err::Error;
+use crate::sql::paths::{EDGE, IN, OUT};
+use crate::sql::value::Value;
+use crate::sql::{Datetime, Ident};
+use serde_json::{json, Map, Value as Json};
+use std::collections::BTreeSet;
+use std::fs::{File, OpenOptions};
+use std::io::Write;
+use std::path::Path;
+use std::sync::Mutex;
+use uuid::Uuid;
+
+/// An append-only file of the statements which change the datastore
+pub struct Journal {
+	file: Mutex<File>,
+}
+
+impl Journal {
+	/// Open a journal for appending, creating the file if it does not exist
+	pub fn open(path: &Path) -> Result<Self, Error> {
+		let file = OpenOptions::new()
+			.create(true)
+			.append(true)
+			.open(path)
+			.map_err(|e| Error::Journal(e.to_string()))?;
+		Ok(Self {
+			file: Mutex::new(file),
+		})
+	}
+
+	/// Append a statement which was run in a transaction, along with any
+	/// changes which it made to records
+	pub(crate) fn statement(
+		&self,
+		ctx: &Context<'_>,
+		txn: &Uuid,
+		ns: Option<&str>,
+		db: Option<&str>,
+		sql: &str,
+		changes: Option<&[Change]>,
+	) -> Result<(), Error> {
+		let vars: Map<String, Json> = params(sql)
+			.into_iter()
+			.filter_map(|k| ctx.value(k).map(|v| (k.to_owned(), Json::from(v.to_string()))))
+			.collect();
+		let mut entry = json!({
+			"txn": txn.to_string(),
+			"at": Datetime::default().to_raw(),
+			"ns": ns,
+			"db": db,
+			"sql": sql,
+			"vars": vars,
+		});
+		if let Some(changes) = changes.filter(|v| !v.is_empty()) {
+			entry["writes"] = Json::from(writes(changes, ns, db));
+		}
+		self.append(entry)
+	}
+
+	/// Sync the statements which were appended to disk
+	pub(crate) fn sync(&self) -> Result<(), Error> {
+		self.file.lock().unwrap().sync_data().map_err(|e| Error::Journal(e.to_string()))
+	}
+
+	/// Append the outcome of a transaction, once it is committed or cancelled
+	pub(crate) fn finish(&self, txn: &Uuid, committed: bool) {
+		let status = match committed {
+			true => "COMMIT",
+			false => "CANCEL",
+		};
+		let res = self.append(json!({
+			"txn": txn.to_string(),
+			"at": Datetime::default().to_raw(),
+			"status": status,
+		}));
+		if let Err(e) = res {
+			error!(target: LOG, "{}", e);
+		}
+	}
+
+	fn append(&self, entry: Json) -> Result<(), Error> {
+		let mut line = entry.to_string();
+		line.push('\n');
+		let mut file = self.file.lock().unwrap();
+		file.write_all(line.as_bytes()).map_err(|e| Error::Journal(e.to_string()))
+	}
+}
+
+/// Get the names of the parameters which a statement refers to
+fn params(sql: &str) -> BTreeSet<&str> {
+	sql.split('$')
+		.skip(1)
+		.map(|v| {
+			let end = v.find(|c: char| !(c.is_ascii_alphanumeric() || c == '_')).unwrap_or(v.len());
+			&v[..end]
+		})
+		.filter(|v| !v.is_empty() && !PROTECTED_PARAM_NAMES.contains(v))
+		.collect()
+}
+
+/// Encode the changes which a statement made to records as SurrealQL
+fn writes(changes: &[Change], ns: Option<&str>, db: Option<&str>) -> Vec<String> {
+	let mut loc = (ns.map(str::to_owned), db.map(str::to_owned));
+	let mut out = vec![];
+	for v in changes {
+		// The change was made in a different database to the statement
+		if loc.0.as_deref() != Some(v.ns.as_str()) || loc.1.as_deref() != Some(v.db.as_str()) {
+			out.push(format!(
+				"USE NS {} DB {}",
+				Ident::from(v.ns.as_str()),
+				Ident::from(v.db.as_str())
+			));
+			loc = (Some(v.ns.clone()), Some(v.db.clone()));
+		}
+		out.push(
+			match (v.action(), v.after.pick(&*EDGE), v.after.pick(&*IN), v.after.pick(&*OUT)) {
+				(Action::Delete, ..) => format!("DELETE {}", v.id),
+				(Action::Create, Value::Bool(true), Value::Thing(l), Value::Thing(r)) => {
+					format!("RELATE {l} -> {} -> {r} CONTENT {}", v.id, v.after)
+				}
+				_ => format!("UPDATE {} CONTENT {}", v.id, v.after),
+			},
+		);
+	}
+	out
+}
+
+#[cfg(test)]
+mod tests {
+	use super::*;
+	use crate::sql::test::Parse;
+
+	#[test]
+	fn statement_params() {
+		let sql = "UPDATE $id SET name = $name, author = $auth.id, tags += $name";
+		assert_eq!(params(sql).into_iter().collect::<Vec<_>>(), vec!["id", "name"]);
+	}
+
+	#[test]
+	fn journal_entries() {
+		let dir = temp_dir::TempDir::new().unwrap();
+		let path = dir.path().join("journal.log");
+		let journal = Journal::open(&path).unwrap();
+		let mut ctx = Context::default();
+		ctx.add_value("name", Value::from("Tobie"));
+		let txn = Uuid::new_v4();
+		let sql = "CREATE person:tobie SET name = $name, age = $age";
+		journal.statement(&ctx, &txn, Some("test"), Some("test"), sql, None).unwrap();
+		let change = Change {
+			seq: 0,
+			at: Datetime::default(),
+			ns: String::from("test"),
+			db: String::from("other"),
+			tb: String::from("person"),
+			id: crate::sql::thing("person:jaime").unwrap(),
+			before: Value::None,
+			after: Value::parse("{ id: person:jaime, name: 'Jaime' }"),
+		};
+		let sql = "CREATE person SET name = 'Jaime'";
+		journal.statement(&ctx, &txn, Some("test"), Some("test"), sql, Some(&[change])).unwrap();
+		journal.sync().unwrap();
+		journal.finish(&txn, true);
+		let out = std::fs::read_to_string(&path).unwrap();
+		let lines: Vec<Json> = out.lines().map(|v| serde_json::from_str(v).unwrap()).collect();
+		assert_eq!(lines.len(), 3);
+		assert_eq!(lines[0]["txn"], txn.to_string());
+		assert_eq!(lines[0]["ns"], "test");
+		assert_eq!(lines[0]["vars"], json!({ "name": "'Tobie'" }));
+		assert!(lines[0].get("writes").is_none());
+		assert_eq!(
+			lines[1]["writes"],
+			json!([
+				"USE NS test DB other",
+				"UPDATE person:jaime CONTENT { id: person:jaime, name: 'Jaime' }"
+			])
+		);
+		assert_eq!(lines[2]["txn"], txn.to_string());
+		assert_eq!(lines[2]["status"], "COMMIT");
+	}
+}
@@ -0,0 +1,218 @@
+//! Operational metrics for a datastore.
+//!
+//! The [`Metrics`] of a datastore count the statements which are run, and
+//! their latency, for each type of statement, along with the key-value
+//! operations which are performed, the transactions which conflicted, and
+//! the live queries which are subscribed. The metrics are output in the
+//! Prometheus text format by [`Metrics::output`].
+use std::collections::BTreeMap;
+use std::fmt::Write;
+use std::sync::atomic::{AtomicI64, AtomicU64, Ordering};
+use std::sync::Mutex;
+use std::time::Duration;
+
+/// The upper bounds in seconds of the statement latency histogram buckets
+const BUCKETS: [f64; 11] = [0.0005, 0.001, 0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1.0, 5.0];
+
+/// A key-value operation which is counted
+#[derive(Clone, Copy, Debug, Eq, PartialEq)]
+pub enum Op {
+	Get,
+	Set,
+	Put,
+	Del,
+	Exi,
+	Scan,
+	Putc,
+	Delc,
+	Commit,
+	Cancel,
+}
+
+impl Op {
+	const ALL: [Op; 10] = [
+		Op::Get,
+		Op::Set,
+		Op::Put,
+		Op::Del,
+		Op::Exi,
+		Op::Scan,
+		Op::Putc,
+		Op::Delc,
+		Op::Commit,
+		Op::Cancel,
+	];
+
+	fn as_str(&self) -> &'static str {
+		match self {
+			Op::Get => "get",
+			Op::Set => "set",
+			Op::Put => "put",
+			Op::Del => "del",
+			Op::Exi => "exi",
+			Op::Scan => "scan",
+			Op::Putc => "putc",
+			Op::Delc => "delc",
+			Op::Commit => "commit",
+			Op::Cancel => "cancel",
+		}
+	}
+}
+
+#[derive(Debug, Default)]
+struct Histogram {
+	// The number of statements within each latency bucket
+	buckets: [u64; BUCKETS.len()],
+	// The total latency of the statements, in seconds
+	sum: f64,
+	// The number of statements
+	count: u64,
+	// The number of statements which failed
+	errors: u64,
+}
+
+/// The counters and histograms of a datastore
+#[derive(Debug, Default)]
+pub struct Metrics {
+	// The latency histogram for each type of statement
+	statements: Mutex<BTreeMap<&'static str, Histogram>>,
+	// The number of each type of key-value operation
+	operations: [AtomicU64; Op::ALL.len()],
+	// The number of transactions which failed to commit due to a conflict
+	conflicts: AtomicU64,
+	// The number of live queries which are subscribed
+	live: AtomicI64,
+}
+
+impl Metrics {
+	/// Count a statement of the specified type, and its latency
+	pub(crate) fn statement(&self, kind: &'static str, time: Duration, ok: bool) {
+		let secs = time.as_secs_f64();
+		let mut statements = self.statements.lock().unwrap();
+		let v = statements.entry(kind).or_default();
+		for (i, b) in BUCKETS.iter().enumerate() {
+			if secs <= *b {
+				v.buckets[i] += 1;
+			}
+		}
+		v.sum += secs;
+		v.count += 1;
+		if !ok {
+			v.errors += 1;
+		}
+	}
+
+	/// Count a key-value operation
+	pub(crate) fn operation(&self, op: Op) {
+		self.operations[op as usize].fetch_add(1, Ordering::Relaxed);
+	}
+
+	/// Count a transaction which failed to commit due to a conflict
+	pub(crate) fn conflict(&self) {
+		self.conflicts.fetch_add(1, Ordering::Relaxed);
+	}
+
+	/// Adjust the number of live queries which are subscribed
+	pub(crate) fn live(&self, val: i64) {
+		self.live.fetch_add(val, Ordering::Relaxed);
+	}
+
+	/// Output the metrics in the Prometheus text format
+	pub fn output(&self) -> String {
+		let mut out = String::new();
+		// Output the statement counters and latency histograms
+		let statements = self.statements.lock().unwrap();
+		out.push_str("# HELP surrealdb_statements_total Statements which have been run\n");
+		out.push_str("# TYPE surrealdb_statements_total counter\n");
+		for (k, v) in statements.iter() {
+			let _ = writeln!(out, "surrealdb_statements_total{{type=\"{k}\"}} {}", v.count);
+		}
+		out.push_str("# HELP surrealdb_statement_errors_total Statements which have failed\n");
+		out.push_str("# TYPE surrealdb_statement_errors_total counter\n");
+		for (k, v) in statements.iter() {
+			let _ = writeln!(out, "surrealdb_statement_errors_total{{type=\"{k}\"}} {}", v.errors);
+		}
+		out.push_str("# HELP surrealdb_statement_duration_seconds The latency of statements\n");
+		out.push_str("# TYPE surrealdb_statement_duration_seconds histogram\n");
+		for (k, v) in statements.iter() {
+			for (i, b) in BUCKETS.iter().enumerate() {
+				let _ = writeln!(
+					out,
+					"surrealdb_statement_duration_seconds_bucket{{type=\"{k}\",le=\"{b}\"}} {}",
+					v.buckets[i]
+				);
+			}
+			let _ = writeln!(
+				out,
+				"surrealdb_statement_duration_seconds_bucket{{type=\"{k}\",le=\"+Inf\"}} {}",
+				v.count
+			);
+			let _ =
+				writeln!(out, "surrealdb_statement_duration_seconds_sum{{type=\"{k}\"}} {}", v.sum);
+			let _ = writeln!(
+				out,
+				"surrealdb_statement_duration_seconds_count{{type=\"{k}\"}} {}",
+				v.count
+			);
+		}
+		drop(statements);
+		// Output the key-value operation counters
+		out.push_str(
+			"# HELP surrealdb_kv_operations_total Key-value operations which have been performed\n",
+		);
+		out.push_str("# TYPE surrealdb_kv_operations_total counter\n");
+		for op in Op::ALL.iter() {
+			let _ = writeln!(
+				out,
+				"surrealdb_kv_operations_total{{op=\"{}\"}} {}",
+				op.as_str(),
+				self.operations[*op as usize].load(Ordering::Relaxed)
+			);
+		}
+		// Output the transaction conflict counter
+		out.push_str("# HELP surrealdb_transaction_conflicts_total Transactions which failed to commit due to a conflict\n");
+		out.push_str("# TYPE surrealdb_transaction_conflicts_total counter\n");
+		let _ = writeln!(
+			out,
+			"surrealdb_transaction_conflicts_total {}",
+			self.conflicts.load(Ordering::Relaxed)
+		);
+		// Output the live query gauge
+		out.push_str("# HELP surrealdb_live_queries Live queries which are subscribed\n");
+		out.push_str("# TYPE surrealdb_live_queries gauge\n");
+		let _ =
+			writeln!(out, "surrealdb_live_queries {}", self.live.load(Ordering::Relaxed).max(0));
+		out
+	}
+}
+
+#[cfg(test)]
+mod tests {
+	use super::*;
+
+	#[test]
+	fn metrics_output() {
+		let metrics = Metrics::default();
+		metrics.statement("select", Duration::from_millis(3), true);
+		metrics.statement("select", Duration::from_secs(2), false);
+		metrics.operation(Op::Get);
+		metrics.operation(Op::Get);
+		metrics.conflict();
+		metrics.live(1);
+		let out = metrics.output();
+		assert!(out.contains("surrealdb_statements_total{type=\"select\"} 2\n"));
+		assert!(out.contains("surrealdb_statement_errors_total{type=\"select\"} 1\n"));
+		assert!(out.contains(
+			"surrealdb_statement_duration_seconds_bucket{type=\"select\",le=\"0.001\"} 0\n"
+		));
+		assert!(out.contains(
+			"surrealdb_statement_duration_seconds_bucket{type=\"select\",le=\"0.005\"} 1\n"
+		));
+		assert!(out.contains(
+			"surrealdb_statement_duration_seconds_bucket{type=\"select\",le=\"+Inf\"} 2\n"
+		));
+		assert!(out.contains("surrealdb_kv_operations_total{op=\"get\"} 2\n"));
+		assert!(out.contains("surrealdb_transaction_conflicts_total 1\n"));
+		assert!(out.contains("surrealdb_live_queries 1\n"));
+	}
+}
@@ -3,21 +3,50 @@
 //! glue between the API and the response. In this module we use channels as a transport layer
 //! and executors to process the operations. This module also gives a `context` to the transaction.
 mod auth;
+mod batch;
+mod cache;
+mod config;
+mod cursor;
 mod executor;
+mod fill;
 mod iterate;
 mod iterator;
+mod journal;
+mod metrics;
 mod options;
+mod quota;
+mod registry;
 mod response;
 mod session;
+mod settings;
+mod slo;
 mod statement;
+mod statistics;
 mod transaction;
+mod transactions;
+mod usage;
 mod variables;
+mod webhook;
 
 pub use self::auth::*;
+pub use self::batch::*;
+pub use self::cache::*;
+pub use self::config::*;
+pub use self::journal::*;
+pub use self::metrics::*;
 pub use self::options::*;
+pub use self::quota::*;
+pub use self::registry::*;
 pub use self::response::*;
 pub use self::session::*;
+pub use self::settings::*;
+pub use self::slo::*;
+pub use self::statistics::*;
+pub use self::transactions::*;
+pub use self::usage::*;
+pub use self::webhook::*;
 
+pub(crate) use self::cursor::*;
 pub(crate) use self::executor::*;
 pub(crate) use self::iterator::*;
 pub(crate) use self::statement::*;
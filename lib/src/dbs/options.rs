@@ -39,6 +39,16 @@ pub struct Options {
 	pub indexes: bool,
 	/// Should we process function futures?
 	pub futures: bool,
+	/// Are we importing records which were already processed?
+	pub import: bool,
+	/// Should we prevent unfiltered whole-table changes?
+	pub safe: bool,
+	/// Should we permanently remove soft deleted records?
+	pub purge: bool,
+	/// Should we roll back the changes of every statement?
+	pub dry: bool,
+	/// Should we error when comparing values of different types?
+	pub types: bool,
 }
 
 impl Default for Options {
@@ -63,6 +73,11 @@ impl Options {
 			tables: true,
 			indexes: true,
 			futures: false,
+			import: false,
+			safe: false,
+			purge: false,
+			dry: false,
+			types: false,
 			auth: Arc::new(auth),
 		}
 	}
@@ -96,6 +111,16 @@ impl Options {
 		}
 	}
 
+	/// Create a new Options object for another database
+	pub fn database(&self, db: &str) -> Options {
+		Options {
+			auth: self.auth.clone(),
+			ns: self.ns.clone(),
+			db: Some(db.into()),
+			..*self
+		}
+	}
+
 	/// Create a new Options object for a subquery
 	pub fn force(&self, v: bool) -> Options {
 		Options {
@@ -171,6 +196,7 @@ impl Options {
 			fields: !v,
 			events: !v,
 			tables: !v,
+			import: v,
 			..*self
 		}
 	}
@@ -197,6 +223,39 @@ impl Options {
 		}
 	}
 
+	/// Create a new Options object for a subquery
+	pub fn safe(&self, v: bool) -> Options {
+		Options {
+			auth: self.auth.clone(),
+			ns: self.ns.clone(),
+			db: self.db.clone(),
+			safe: v,
+			..*self
+		}
+	}
+
+	/// Create a new Options object for a subquery
+	pub fn purge(&self, v: bool) -> Options {
+		Options {
+			auth: self.auth.clone(),
+			ns: self.ns.clone(),
+			db: self.db.clone(),
+			purge: v,
+			..*self
+		}
+	}
+
+	/// Create a new Options object for a subquery
+	pub fn types(&self, v: bool) -> Options {
+		Options {
+			auth: self.auth.clone(),
+			ns: self.ns.clone(),
+			db: self.db.clone(),
+			types: v,
+			..*self
+		}
+	}
+
 	/// Check whether realtime queries are supported
 	pub fn realtime(&self) -> Result<(), Error> {
 		if !self.live {
@@ -0,0 +1,365 @@
+//! Rate limits and quotas for each authenticated identity.
+//!
+//! The [`QuotaLimits`] of a datastore restrict the number of statements and
+//! writes which each identity may run per second, the number of live queries
+//! which it may have subscribed, and the number of bytes which its results may
+//! use per second, so that a single tenant can not monopolise a shared server.
+//! An identity is a scope user, or a database or namespace level session, and
+//! root sessions are never limited. Rates are enforced with a token bucket for
+//! each identity, which holds up to one second of capacity, and the number of
+//! statements which were rejected are exposed by [`Quotas::metrics`].
+use crate::ctx::limits::estimate;
+use crate::dbs::Auth;
+use crate::dbs::Session;
+use crate::dbs::LOG;
+use crate::err::Error;
+use crate::sql::statement::Statement;
+use crate::sql::Value;
+use std::collections::HashMap;
+use std::fmt::{self, Write};
+use std::sync::atomic::{AtomicU64, Ordering};
+use std::sync::{Mutex, RwLock};
+use std::time::Duration;
+use trice::Instant;
+use uuid::Uuid;
+
+/// The number of identities above which idle identities are forgotten
+const MAX_IDENTITIES: usize = 1024;
+
+/// How long an identity is idle before its usage can be forgotten
+const IDLE_DURATION: Duration = Duration::from_secs(1);
+
+/// The rate limits and quotas which apply to each identity
+#[derive(Clone, Copy, Debug, Default, Eq, PartialEq)]
+pub struct QuotaLimits {
+	/// The maximum number of statements which may be run per second
+	pub queries: Option<u64>,
+	/// The maximum number of writeable statements which may be run per second
+	pub writes: Option<u64>,
+	/// The maximum number of live queries which may be subscribed at once
+	pub live: Option<u64>,
+	/// The maximum estimated number of result bytes which may be output per second
+	pub bytes: Option<u64>,
+}
+
+/// The quota which an identity exceeded
+#[derive(Clone, Copy, Debug, Eq, PartialEq)]
+pub enum Quota {
+	Queries,
+	Writes,
+	Live,
+	Bytes,
+}
+
+impl Quota {
+	const ALL: [Quota; 4] = [Quota::Queries, Quota::Writes, Quota::Live, Quota::Bytes];
+
+	fn as_str(&self) -> &'static str {
+		match self {
+			Quota::Queries => "queries",
+			Quota::Writes => "writes",
+			Quota::Live => "live",
+			Quota::Bytes => "bytes",
+		}
+	}
+}
+
+impl fmt::Display for Quota {
+	fn fmt(&self, f: &mut fmt::Formatter) -> fmt::Result {
+		match self {
+			Self::Queries => f.write_str("queries per second"),
+			Self::Writes => f.write_str("writes per second"),
+			Self::Live => f.write_str("live queries"),
+			Self::Bytes => f.write_str("result bytes per second"),
+		}
+	}
+}
+
+#[derive(Debug, Default)]
+struct Bucket {
+	// The capacity which remains, which is negative once overspent
+	tokens: f64,
+	// When the bucket was last refilled, or none if it is full
+	updated: Option<Instant>,
+}
+
+impl Bucket {
+	/// Refill the bucket at the rate per second, up to one second of capacity
+	fn refill(&mut self, rate: u64) -> f64 {
+		let rate = rate as f64;
+		self.tokens = match self.updated {
+			Some(v) => (self.tokens + v.elapsed().as_secs_f64() * rate).min(rate),
+			None => rate,
+		};
+		self.updated = Some(Instant::now());
+		self.tokens
+	}
+
+	/// Check if the bucket has not been used recently
+	fn is_idle(&self) -> bool {
+		self.updated.map_or(true, |v| v.elapsed() >= IDLE_DURATION)
+	}
+}
+
+#[derive(Debug, Default)]
+struct Usage {
+	queries: Bucket,
+	writes: Bucket,
+	bytes: Bucket,
+	live: u64,
+}
+
+impl Usage {
+	/// Check if this usage can be forgotten without loosening any limits
+	fn is_idle(&self) -> bool {
+		self.live == 0 && self.queries.is_idle() && self.writes.is_idle() && self.bytes.is_idle()
+	}
+}
+
+/// The usage of each identity, which is checked against the quota limits
+#[derive(Debug, Default)]
+pub struct Quotas {
+	limits: RwLock<QuotaLimits>,
+	// The usage of each identity
+	usage: Mutex<HashMap<String, Usage>>,
+	// The identity which subscribed each live query
+	live: Mutex<HashMap<Uuid, String>>,
+	// The number of statements which were rejected for each quota
+	exceeded: [AtomicU64; Quota::ALL.len()],
+}
+
+impl Quotas {
+	/// Create a new set of quotas with the specified limits
+	pub fn new(limits: QuotaLimits) -> Self {
+		Quotas {
+			limits: RwLock::new(limits),
+			..Default::default()
+		}
+	}
+
+	/// Check if any limits are configured
+	pub fn is_enabled(&self) -> bool {
+		self.limits() != QuotaLimits::default()
+	}
+
+	/// Get the limits which are currently configured
+	pub fn limits(&self) -> QuotaLimits {
+		*self.limits.read().unwrap()
+	}
+
+	/// Change the limits while the datastore is running, keeping the
+	/// current usage of each identity
+	pub fn set_limits(&self, limits: QuotaLimits) {
+		*self.limits.write().unwrap() = limits;
+	}
+
+	/// Get the identity of a session which is limited, if any
+	pub(crate) fn identity(&self, sess: &Session) -> Option<String> {
+		if !self.is_enabled() {
+			return None;
+		}
+		match (&*sess.au, &sess.sd) {
+			(Auth::Sc(ns, db, sc), Some(sd)) => Some(format!("{ns}/{db}/{sc}/{sd}")),
+			(Auth::Sc(ns, db, sc), None) => Some(format!("{ns}/{db}/{sc}")),
+			(Auth::Db(ns, db), _) => Some(format!("{ns}/{db}")),
+			(Auth::Ns(ns), _) => Some(ns.to_owned()),
+			_ => None,
+		}
+	}
+
+	/// Reject a query before it is run, if the identity has no capacity left
+	pub(crate) fn admit(&self, idn: Option<&str>) -> Result<(), Error> {
+		if let Some(idn) = idn {
+			let limits = self.limits();
+			let mut usage = self.usage.lock().unwrap();
+			if let Some(v) = usage.get_mut(idn) {
+				if let Some(limit) = limits.queries {
+					if v.queries.refill(limit) < 1.0 {
+						return Err(self.exceeded(idn, Quota::Queries, limit));
+					}
+				}
+				if let Some(limit) = limits.bytes {
+					if v.bytes.refill(limit) <= 0.0 {
+						return Err(self.exceeded(idn, Quota::Bytes, limit));
+					}
+				}
+			}
+		}
+		Ok(())
+	}
+
+	/// Check and count a statement which is about to be run by the identity
+	pub(crate) fn check(&self, idn: Option<&str>, stm: &Statement) -> Result<(), Error> {
+		// Check if the identity is limited
+		let idn = match idn {
+			Some(idn) => idn,
+			None => return Ok(()),
+		};
+		// Transaction and session statements are not counted
+		let kind = stm.kind();
+		if matches!(kind, "begin" | "cancel" | "commit" | "use" | "option") {
+			return Ok(());
+		}
+		// Forget any idle identities
+		let limits = self.limits();
+		let mut usage = self.usage.lock().unwrap();
+		if usage.len() > MAX_IDENTITIES {
+			usage.retain(|_, v| !v.is_idle());
+		}
+		let v = usage.entry(idn.to_owned()).or_default();
+		// Check every limit before any capacity is used
+		if let Some(limit) = limits.queries {
+			if v.queries.refill(limit) < 1.0 {
+				return Err(self.exceeded(idn, Quota::Queries, limit));
+			}
+		}
+		if let Some(limit) = limits.writes.filter(|_| stm.writeable()) {
+			if v.writes.refill(limit) < 1.0 {
+				return Err(self.exceeded(idn, Quota::Writes, limit));
+			}
+		}
+		if let Some(limit) = limits.bytes {
+			if v.bytes.refill(limit) <= 0.0 {
+				return Err(self.exceeded(idn, Quota::Bytes, limit));
+			}
+		}
+		if let Some(limit) = limits.live.filter(|_| kind == "live") {
+			if v.live >= limit {
+				return Err(self.exceeded(idn, Quota::Live, limit));
+			}
+		}
+		// Use the capacity for this statement
+		if limits.queries.is_some() {
+			v.queries.tokens -= 1.0;
+		}
+		if limits.writes.is_some() && stm.writeable() {
+			v.writes.tokens -= 1.0;
+		}
+		Ok(())
+	}
+
+	/// Count the output of a statement which was run by the identity, and
+	/// any live query which it subscribed or killed
+	pub(crate) fn finished(
+		&self,
+		idn: Option<&str>,
+		kind: &str,
+		killed: Option<Uuid>,
+		res: &Result<Value, Error>,
+	) {
+		// Check if any limits are configured
+		if !self.is_enabled() {
+			return;
+		}
+		// Only successful statements are counted
+		let limits = self.limits();
+		let val = match res {
+			Ok(val) => val,
+			Err(_) => return,
+		};
+		// A killed live query no longer counts against its identity
+		if let Some(id) = killed.filter(|_| kind == "kill") {
+			if let Some(idn) = self.live.lock().unwrap().remove(&id) {
+				if let Some(v) = self.usage.lock().unwrap().get_mut(&idn) {
+					v.live = v.live.saturating_sub(1);
+				}
+			}
+		}
+		// Count the result bytes and live queries of the identity
+		if let Some(idn) = idn {
+			let mut usage = self.usage.lock().unwrap();
+			let v = usage.entry(idn.to_owned()).or_default();
+			if let Some(limit) = limits.bytes {
+				v.bytes.refill(limit);
+				v.bytes.tokens -= estimate(val) as f64;
+			}
+			if let (Value::Uuid(id), "live") = (val, kind) {
+				self.live.lock().unwrap().insert(id.0, idn.to_owned());
+				v.live += 1;
+			}
+		}
+	}
+
+	/// Log and count a quota which an identity exceeded
+	fn exceeded(&self, idn: &str, quota: Quota, limit: u64) -> Error {
+		debug!(target: LOG, "The identity '{idn}' exceeded the {quota} quota of {limit}");
+		self.exceeded[quota as usize].fetch_add(1, Ordering::Relaxed);
+		Error::QuotaExceeded {
+			quota,
+			limit,
+		}
+	}
+
+	/// Output the quota counters in the Prometheus text format
+	pub fn metrics(&self) -> String {
+		let mut out = String::new();
+		out.push_str("# HELP surrealdb_quota_exceeded_total Statements which were rejected because an identity exceeded a quota\n");
+		out.push_str("# TYPE surrealdb_quota_exceeded_total counter\n");
+		for quota in Quota::ALL.iter() {
+			let _ = writeln!(
+				out,
+				"surrealdb_quota_exceeded_total{{quota=\"{}\"}} {}",
+				quota.as_str(),
+				self.exceeded[*quota as usize].load(Ordering::Relaxed)
+			);
+		}
+		out
+	}
+}
+
+#[cfg(test)]
+mod tests {
+	use super::*;
+	use crate::sql::parse;
+
+	#[test]
+	fn quotas_queries_and_writes() {
+		let quotas = Quotas::new(QuotaLimits {
+			queries: Some(3),
+			writes: Some(1),
+			..Default::default()
+		});
+		let qry = parse("SELECT * FROM person; CREATE person; CREATE person").unwrap();
+		let idn = Some("test/test");
+		assert!(quotas.check(idn, &qry[0]).is_ok());
+		assert!(quotas.check(idn, &qry[1]).is_ok());
+		assert!(matches!(
+			quotas.check(idn, &qry[2]),
+			Err(Error::QuotaExceeded {
+				quota: Quota::Writes,
+				limit: 1
+			})
+		));
+		// The rejected write did not use any capacity
+		assert!(quotas.check(idn, &qry[0]).is_ok());
+		assert!(matches!(
+			quotas.check(idn, &qry[0]),
+			Err(Error::QuotaExceeded {
+				quota: Quota::Queries,
+				limit: 3
+			})
+		));
+		assert!(quotas.admit(idn).is_err());
+		// Other identities are limited separately
+		assert!(quotas.admit(Some("test")).is_ok());
+		assert!(quotas.check(Some("test"), &qry[1]).is_ok());
+		// Root sessions are never limited
+		assert!(quotas.check(None, &qry[1]).is_ok());
+		assert!(quotas.metrics().contains("surrealdb_quota_exceeded_total{quota=\"writes\"} 1\n"));
+	}
+
+	#[test]
+	fn quotas_identity() {
+		let quotas = Quotas::new(QuotaLimits {
+			live: Some(1),
+			..Default::default()
+		});
+		let mut sess = Session::for_sc("test", "test", "user");
+		assert_eq!(quotas.identity(&sess), Some("test/test/user".to_owned()));
+		sess.sd = Some(Value::from(crate::sql::Thing::from(("user", "one"))));
+		assert_eq!(quotas.identity(&sess), Some("test/test/user/user:one".to_owned()));
+		assert_eq!(quotas.identity(&Session::for_db("test", "test")), Some("test/test".to_owned()));
+		assert_eq!(quotas.identity(&Session::for_kv()), None);
+		assert_eq!(Quotas::default().identity(&sess), None);
+	}
+}
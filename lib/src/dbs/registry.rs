@@ -0,0 +1,346 @@
+//! A registry of the sessions which are connected to a datastore.
+//!
+//! Long-lived connections, such as WebSocket clients, are registered for as
+//! long as they are connected, and every other query is registered for as
+//! long as it is running. Each session records the statement which it is
+//! currently running, so that `SHOW SESSIONS` can list the sessions and their
+//! statements, and `KILL <session-id>` can cancel the running statement and
+//! close the connection. A connection can also enable statement tracing,
+//! so that the timings and query plans of its statements are sent to it,
+//! and can receive the notifications of the live queries which it started.
+//!
+//! Every query which a session is running can be cancelled, such as when
+//! the client of the connection disconnects, or asks for its queries to be
+//! cancelled. This cancels the context of the query, so that any statement
+//! and scan of the storage engine stops, and its transaction is rolled back.
+use crate::ctx::Canceller;
+use crate::dbs::Auth;
+use crate::dbs::Session;
+use crate::sql::Datetime;
+use crate::sql::Object;
+use crate::sql::Value;
+use channel::{Receiver, Sender};
+use std::collections::HashMap;
+use std::sync::atomic::{AtomicUsize, Ordering};
+use std::sync::{Arc, Mutex};
+use uuid::Uuid;
+
+struct Running {
+	sql: String,
+	started: Datetime,
+	canceller: Canceller,
+}
+
+struct Entry {
+	protocol: &'static str,
+	ip: Option<String>,
+	origin: Option<String>,
+	auth: Arc<Auth>,
+	connected: Datetime,
+	running: Option<Running>,
+	// Cancels each of the queries which the session is running
+	queries: HashMap<Uuid, Canceller>,
+	// Notifies the connection that it should be closed
+	exit: Option<Sender<()>>,
+	// Receives the traces of the statements of the session
+	trace: Option<Sender<Value>>,
+	// Receives the notifications of the live queries of the session
+	notify: Option<Sender<Value>>,
+}
+
+/// The sessions which are connected to a datastore
+#[derive(Default)]
+pub struct Registry {
+	sessions: Mutex<HashMap<Uuid, Entry>>,
+	// The session which started each live query
+	lives: Mutex<HashMap<Uuid, Uuid>>,
+	// The number of queries which are running
+	active: AtomicUsize,
+}
+
+impl Registry {
+	/// Register a connection, returning a channel which is closed when the
+	/// connection is killed. The connection must be unregistered with
+	/// [`Registry::disconnect`] once it has closed.
+	pub fn connect(&self, id: Uuid, protocol: &'static str, sess: &Session) -> Receiver<()> {
+		let (exit, recv) = channel::bounded(1);
+		self.sessions.lock().unwrap().insert(id, Entry::new(protocol, sess, Some(exit)));
+		recv
+	}
+
+	/// Unregister a connection
+	pub fn disconnect(&self, id: &Uuid) {
+		self.sessions.lock().unwrap().remove(id);
+		self.lives.lock().unwrap().retain(|_, v| v != id);
+	}
+
+	/// Enable or disable statement tracing for a connection. When enabled,
+	/// a trace of each statement which the connection runs is sent to the
+	/// channel, and traces are dropped if the channel is full.
+	pub fn trace(&self, id: &Uuid, chn: Option<Sender<Value>>) {
+		if let Some(v) = self.sessions.lock().unwrap().get_mut(id) {
+			v.trace = chn;
+		}
+	}
+
+	/// Get the channel which receives the statement traces of a session
+	pub(crate) fn tracer(&self, id: &Uuid) -> Option<Sender<Value>> {
+		self.sessions.lock().unwrap().get(id).and_then(|v| v.trace.clone())
+	}
+
+	/// Enable or disable live query notifications for a connection. When
+	/// enabled, the notifications of the live queries which the connection
+	/// started are sent to the channel, and are dropped if it is full.
+	pub fn notify(&self, id: &Uuid, chn: Option<Sender<Value>>) {
+		if let Some(v) = self.sessions.lock().unwrap().get_mut(id) {
+			v.notify = chn;
+		}
+	}
+
+	/// Record the session which started a live query
+	pub(crate) fn subscribe(&self, id: &Uuid, lq: Uuid) {
+		self.lives.lock().unwrap().insert(lq, *id);
+	}
+
+	/// Forget the session which started a live query
+	pub(crate) fn unsubscribe(&self, lq: &Uuid) {
+		self.lives.lock().unwrap().remove(lq);
+	}
+
+	/// Get the channel which receives the notifications of a live query
+	pub(crate) fn notifier(&self, lq: &Uuid) -> Option<Sender<Value>> {
+		let id = *self.lives.lock().unwrap().get(lq)?;
+		self.sessions.lock().unwrap().get(&id).and_then(|v| v.notify.clone())
+	}
+
+	/// Register a query for a session, returning the id of the session. If the
+	/// session belongs to a registered connection, the id of the connection is
+	/// used, otherwise the query is registered as a new session until it ends.
+	/// The canceller cancels the context of the query if the query is cancelled.
+	pub(crate) fn begin(self: &Arc<Self>, sess: &Session, canceller: Canceller) -> Registered {
+		let id = sess.id.as_deref().and_then(|v| Uuid::parse_str(v).ok());
+		let query = Uuid::new_v4();
+		let mut sessions = self.sessions.lock().unwrap();
+		self.active.fetch_add(1, Ordering::AcqRel);
+		match id {
+			Some(id) if sessions.contains_key(&id) => {
+				// The session may have signed in since it connected
+				if let Some(v) = sessions.get_mut(&id) {
+					v.auth = sess.au.clone();
+					v.queries.insert(query, canceller);
+				}
+				Registered {
+					id,
+					query,
+					owned: false,
+					registry: self.clone(),
+				}
+			}
+			_ => {
+				let id = Uuid::new_v4();
+				let mut entry = Entry::new("query", sess, None);
+				entry.queries.insert(query, canceller);
+				sessions.insert(id, entry);
+				Registered {
+					id,
+					query,
+					owned: true,
+					registry: self.clone(),
+				}
+			}
+		}
+	}
+
+	/// Record the statement which a session is running
+	pub(crate) fn running(&self, id: &Uuid, sql: String, canceller: Canceller) {
+		if let Some(v) = self.sessions.lock().unwrap().get_mut(id) {
+			v.running = Some(Running {
+				sql,
+				started: Datetime::default(),
+				canceller,
+			});
+		}
+	}
+
+	/// Record that a session has finished running a statement
+	pub(crate) fn finished(&self, id: &Uuid) {
+		if let Some(v) = self.sessions.lock().unwrap().get_mut(id) {
+			v.running = None;
+		}
+	}
+
+	/// Record that a session has finished running a query
+	fn ended(&self, id: &Uuid, query: &Uuid) {
+		if let Some(v) = self.sessions.lock().unwrap().get_mut(id) {
+			v.queries.remove(query);
+		}
+	}
+
+	/// Check if a session is registered
+	pub(crate) fn contains(&self, id: &Uuid) -> bool {
+		self.sessions.lock().unwrap().contains_key(id)
+	}
+
+	/// Check if a session belongs to a registered connection
+	pub(crate) fn is_connected(&self, sess: &Session) -> bool {
+		match sess.id.as_deref().and_then(|v| Uuid::parse_str(v).ok()) {
+			Some(id) => self.sessions.lock().unwrap().get(&id).map_or(false, |v| v.exit.is_some()),
+			None => false,
+		}
+	}
+
+	/// Get the number of queries which are running
+	pub fn active(&self) -> usize {
+		self.active.load(Ordering::Acquire)
+	}
+
+	/// Notify the subscriber of every live query that the datastore is
+	/// shutting down, with the sequence number of the last committed change,
+	/// from which the subscriber can resume once the datastore restarts
+	pub(crate) fn shutdown(&self, cursor: u64) {
+		let lives = self.lives.lock().unwrap();
+		let sessions = self.sessions.lock().unwrap();
+		for (lq, id) in lives.iter() {
+			if let Some(chn) = sessions.get(id).and_then(|v| v.notify.as_ref()) {
+				let _ = chn.try_send(Value::from(map! {
+					String::from("id") => Value::from(crate::sql::Uuid(*lq)),
+					String::from("action") => Value::from("SHUTDOWN"),
+					String::from("result") => Value::from(map! {
+						String::from("cursor") => Value::from(cursor),
+					}),
+				}));
+			}
+		}
+	}
+
+	/// Close every connection, so that no further queries are received
+	pub(crate) fn close(&self) {
+		for v in self.sessions.lock().unwrap().values() {
+			if let Some(exit) = &v.exit {
+				exit.close();
+			}
+		}
+	}
+
+	/// Cancel the running queries of every session
+	pub(crate) fn cancel(&self) {
+		for v in self.sessions.lock().unwrap().values_mut() {
+			v.interrupt();
+		}
+	}
+
+	/// Cancel the running queries of a session, such as when its client has
+	/// disconnected, returning the number of queries which were cancelled
+	pub fn interrupt(&self, id: &Uuid) -> usize {
+		match self.sessions.lock().unwrap().get_mut(id) {
+			Some(v) => v.interrupt(),
+			None => 0,
+		}
+	}
+
+	/// Cancel the running queries of a session, and close its connection
+	pub(crate) fn kill(&self, id: &Uuid) -> bool {
+		match self.sessions.lock().unwrap().get_mut(id) {
+			Some(v) => {
+				v.interrupt();
+				if let Some(exit) = v.exit.take() {
+					exit.close();
+				}
+				true
+			}
+			None => false,
+		}
+	}
+
+	/// Output the registered sessions
+	pub(crate) fn show(&self) -> Value {
+		let sessions = self.sessions.lock().unwrap();
+		let mut out: Vec<(&Uuid, &Entry)> = sessions.iter().collect();
+		out.sort_by(|a, b| a.1.connected.0.cmp(&b.1.connected.0));
+		out.into_iter().map(|(id, v)| v.output(id)).collect::<Vec<_>>().into()
+	}
+}
+
+impl Entry {
+	fn new(protocol: &'static str, sess: &Session, exit: Option<Sender<()>>) -> Self {
+		Entry {
+			protocol,
+			ip: sess.ip.clone(),
+			origin: sess.or.clone(),
+			auth: sess.au.clone(),
+			connected: Datetime::default(),
+			running: None,
+			queries: HashMap::new(),
+			exit,
+			trace: None,
+			notify: None,
+		}
+	}
+
+	/// Cancel the running statement and queries, returning the number of queries
+	fn interrupt(&mut self) -> usize {
+		if let Some(running) = self.running.take() {
+			running.canceller.cancel();
+		}
+		for canceller in self.queries.values() {
+			canceller.cancel();
+		}
+		self.queries.len()
+	}
+
+	fn output(&self, id: &Uuid) -> Value {
+		let mut obj = Object::default();
+		obj.insert("id".to_owned(), Value::from(crate::sql::Uuid(*id)));
+		obj.insert("protocol".to_owned(), self.protocol.into());
+		obj.insert("ip".to_owned(), self.ip.clone().map(Value::from).unwrap_or_default());
+		obj.insert("origin".to_owned(), self.origin.clone().map(Value::from).unwrap_or_default());
+		obj.insert(
+			"auth".to_owned(),
+			match &*self.auth {
+				Auth::No => "NO",
+				Auth::Kv => "KV",
+				Auth::Ns(_) => "NS",
+				Auth::Db(_, _) => "DB",
+				Auth::Sc(_, _, _) => "SC",
+			}
+			.into(),
+		);
+		obj.insert("connected".to_owned(), self.connected.clone().into());
+		obj.insert(
+			"statement".to_owned(),
+			match &self.running {
+				Some(v) => {
+					let mut stm = Object::default();
+					stm.insert("sql".to_owned(), v.sql.clone().into());
+					stm.insert("started".to_owned(), v.started.clone().into());
+					stm.into()
+				}
+				None => Value::None,
+			},
+		);
+		obj.into()
+	}
+}
+
+/// A query which is registered for a session
+pub(crate) struct Registered {
+	pub(crate) id: Uuid,
+	// The id of the query within the session
+	query: Uuid,
+	// Whether the session was registered for this query only
+	owned: bool,
+	registry: Arc<Registry>,
+}
+
+impl Drop for Registered {
+	fn drop(&mut self) {
+		self.registry.active.fetch_sub(1, Ordering::AcqRel);
+		match self.owned {
+			true => self.registry.disconnect(&self.id),
+			false => {
+				self.registry.finished(&self.id);
+				self.registry.ended(&self.id, &self.query);
+			}
+		}
+	}
+}
@@ -11,6 +11,15 @@ pub(crate) const TOKEN: &str = "$surrealdb::private::sql::Response";
 pub struct Response {
 	pub time: Duration,
 	pub result: Result<Value, Error>,
+	/// The number of times the statement was retried, as it conflicted
+	/// with a concurrent transaction
+	pub retries: u32,
+	/// The cursor for selecting the next page of records with START AFTER,
+	/// if the statement output a full page of records
+	pub cursor: Option<String>,
+	/// The total number of records which were matched by a SELECT statement
+	/// WITH TOTAL, before its START and LIMIT clauses were applied
+	pub total: Option<usize>,
 }
 
 impl Response {
@@ -31,17 +40,41 @@ impl Serialize for Response {
 	{
 		match &self.result {
 			Ok(v) => {
-				let mut val = serializer.serialize_struct(TOKEN, 3)?;
+				let len = 3
+					+ (self.retries > 0) as usize
+					+ self.cursor.is_some() as usize
+					+ self.total.is_some() as usize;
+				let mut val = serializer.serialize_struct(TOKEN, len)?;
 				val.serialize_field("time", self.speed().as_str())?;
 				val.serialize_field("status", "OK")?;
 				val.serialize_field("result", v)?;
+				if self.retries > 0 {
+					val.serialize_field("retries", &self.retries)?;
+				}
+				if let Some(cursor) = &self.cursor {
+					val.serialize_field("cursor", cursor)?;
+				}
+				if let Some(total) = &self.total {
+					val.serialize_field("total", total)?;
+				}
 				val.end()
 			}
 			Err(e) => {
-				let mut val = serializer.serialize_struct(TOKEN, 3)?;
+				let kind = e.kind();
+				let retry = e.retry();
+				let len = 5 + retry.is_some() as usize + (self.retries > 0) as usize;
+				let mut val = serializer.serialize_struct(TOKEN, len)?;
 				val.serialize_field("time", self.speed().as_str())?;
 				val.serialize_field("status", "ERR")?;
 				val.serialize_field("detail", e)?;
+				val.serialize_field("kind", &kind)?;
+				val.serialize_field("code", &kind.code())?;
+				if let Some(retry) = &retry {
+					val.serialize_field("retry", retry)?;
+				}
+				if self.retries > 0 {
+					val.serialize_field("retries", &self.retries)?;
+				}
 				val.end()
 			}
 		}
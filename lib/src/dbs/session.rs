@@ -26,6 +26,8 @@ pub struct Session {
 	pub tk: Option<Value>,
 	/// The current scope authentication data
 	pub sd: Option<Value>,
+	/// Whether the changes of the statements are rolled back
+	pub dr: bool,
 }
 
 impl Session {
@@ -82,6 +84,12 @@ impl Session {
 		self.db = Some(db.to_owned());
 		self
 	}
+	/// Roll back the changes of the statements of the session, returning
+	/// the changes which they would have made instead
+	pub fn with_dry_run(mut self, dr: bool) -> Session {
+		self.dr = dr;
+		self
+	}
 	/// Retrieves the selected namespace
 	pub(crate) fn ns(&self) -> Option<Arc<str>> {
 		self.ns.as_deref().map(Into::into)
@@ -0,0 +1,181 @@
+//! Settings which can be changed while the datastore is running.
+//!
+//! Only the settings which are safe to change without restarting are
+//! supported, which are the log level, the duration after which statements
+//! are logged as slow, and the rate limits and quotas of authenticated
+//! identities. A setting is changed by a root user with a `SET GLOBAL`
+//! statement, such as `SET GLOBAL slow_query_threshold = 500ms`, so that a
+//! server can be tuned without dropping its connections or live queries. A
+//! setting is given a literal value, and `NONE` or `NULL` removes a limit.
+use crate::dbs::Quotas;
+use crate::err::Error;
+use crate::sql::Value;
+use std::fmt;
+use std::str::FromStr;
+use std::sync::RwLock;
+use std::time::Duration;
+
+/// A function which changes the log level of the process
+type Logger = Box<dyn Fn(&str) -> Result<(), String> + Send + Sync>;
+
+/// A setting which can be changed with a `SET GLOBAL` statement
+#[derive(Clone, Copy, Debug, Eq, PartialEq)]
+pub enum Setting {
+	LogLevel,
+	SlowQueryThreshold,
+	RateLimitQueries,
+	RateLimitWrites,
+	RateLimitBytes,
+	MaxLiveQueries,
+}
+
+impl Setting {
+	const ALL: [Setting; 6] = [
+		Setting::LogLevel,
+		Setting::SlowQueryThreshold,
+		Setting::RateLimitQueries,
+		Setting::RateLimitWrites,
+		Setting::RateLimitBytes,
+		Setting::MaxLiveQueries,
+	];
+
+	fn as_str(&self) -> &'static str {
+		match self {
+			Setting::LogLevel => "log_level",
+			Setting::SlowQueryThreshold => "slow_query_threshold",
+			Setting::RateLimitQueries => "rate_limit_queries",
+			Setting::RateLimitWrites => "rate_limit_writes",
+			Setting::RateLimitBytes => "rate_limit_bytes",
+			Setting::MaxLiveQueries => "max_live_queries",
+		}
+	}
+}
+
+impl fmt::Display for Setting {
+	fn fmt(&self, f: &mut fmt::Formatter) -> fmt::Result {
+		f.write_str(self.as_str())
+	}
+}
+
+impl FromStr for Setting {
+	type Err = Error;
+	fn from_str(s: &str) -> Result<Self, Self::Err> {
+		Setting::ALL.into_iter().find(|v| v.as_str().eq_ignore_ascii_case(s)).ok_or_else(|| {
+			Error::InvalidSetting {
+				name: s.to_owned(),
+				message: "The setting does not exist, or can not be changed at runtime".to_owned(),
+			}
+		})
+	}
+}
+
+/// The settings of a datastore which can be changed at runtime
+pub struct Settings {
+	slow: RwLock<Option<Duration>>,
+	logger: RwLock<Option<Logger>>,
+}
+
+impl Default for Settings {
+	fn default() -> Self {
+		Settings::new(None)
+	}
+}
+
+impl Settings {
+	/// Create the settings, with a duration after which a statement is logged as slow
+	pub fn new(slow: Option<Duration>) -> Self {
+		Settings {
+			slow: RwLock::new(slow),
+			logger: RwLock::new(None),
+		}
+	}
+	/// Get the duration after which a statement is logged as slow
+	pub fn slow_query_threshold(&self) -> Option<Duration> {
+		*self.slow.read().unwrap()
+	}
+	/// Set the function which changes the log level of the process, so
+	/// that the log level can be changed with a `SET GLOBAL` statement
+	pub fn set_logger<F>(&self, logger: F)
+	where
+		F: Fn(&str) -> Result<(), String> + Send + Sync + 'static,
+	{
+		*self.logger.write().unwrap() = Some(Box::new(logger));
+	}
+	/// Change a setting to a literal value
+	pub(crate) fn set(&self, quotas: &Quotas, name: &str, value: &Value) -> Result<(), Error> {
+		let setting = Setting::from_str(name)?;
+		let err = |message: &str| Error::InvalidSetting {
+			name: setting.to_string(),
+			message: message.to_owned(),
+		};
+		match setting {
+			Setting::LogLevel => {
+				let level = match value {
+					Value::Strand(v) => v.as_str(),
+					_ => return Err(err("Expected a log level string")),
+				};
+				match &*self.logger.read().unwrap() {
+					Some(logger) => logger(level).map_err(|e| err(&e))?,
+					None => return Err(err("The log level can not be changed by this datastore")),
+				}
+			}
+			Setting::SlowQueryThreshold => {
+				*self.slow.write().unwrap() = match value {
+					Value::None | Value::Null => None,
+					Value::Duration(v) => Some(v.0).filter(|v| !v.is_zero()),
+					_ => return Err(err("Expected a duration, or NONE")),
+				}
+			}
+			_ => {
+				let limit = match value {
+					Value::None | Value::Null => None,
+					Value::Number(v) if v.is_integer() && v.to_int() >= 0 => {
+						Some(v.to_int() as u64)
+					}
+					_ => return Err(err("Expected a positive integer, or NONE")),
+				};
+				let mut limits = quotas.limits();
+				match setting {
+					Setting::RateLimitQueries => limits.queries = limit,
+					Setting::RateLimitWrites => limits.writes = limit,
+					Setting::RateLimitBytes => limits.bytes = limit,
+					_ => limits.live = limit,
+				}
+				quotas.set_limits(limits);
+			}
+		}
+		info!(target: crate::dbs::LOG, "The global setting '{setting}' was set to {value}");
+		Ok(())
+	}
+}
+
+#[cfg(test)]
+mod tests {
+	use super::*;
+	use crate::dbs::QuotaLimits;
+	use crate::sql::Duration as SqlDuration;
+
+	#[test]
+	fn settings_set() {
+		let settings = Settings::default();
+		let quotas = Quotas::new(QuotaLimits::default());
+		let val = Value::from(SqlDuration::from(Duration::from_millis(500)));
+		assert!(settings.set(&quotas, "SLOW_QUERY_THRESHOLD", &val).is_ok());
+		assert_eq!(settings.slow_query_threshold(), Some(Duration::from_millis(500)));
+		assert!(settings.set(&quotas, "rate_limit_writes", &Value::from(10)).is_ok());
+		assert_eq!(quotas.limits().writes, Some(10));
+		assert!(settings.set(&quotas, "rate_limit_writes", &Value::None).is_ok());
+		assert!(!quotas.is_enabled());
+		// Invalid settings and values are rejected
+		assert!(settings.set(&quotas, "max_live_queries", &Value::from(-1)).is_err());
+		assert!(settings.set(&quotas, "strict", &Value::from(true)).is_err());
+		assert!(settings.set(&quotas, "log_level", &Value::from("debug")).is_err());
+		// The log level is changed by the logger
+		settings.set_logger(|v| match v {
+			"debug" => Ok(()),
+			_ => Err(format!("Invalid log level '{v}'")),
+		});
+		assert!(settings.set(&quotas, "log_level", &Value::from("debug")).is_ok());
+		assert!(settings.set(&quotas, "log_level", &Value::from("loud")).is_err());
+	}
+}
@@ -0,0 +1,251 @@
+//! Latency service level objectives for namespaces, databases, and tables.
+//!
+//! Each [`SloTarget`] specifies the latency within which a statement against
+//! a namespace, a database, or a table should complete, and the proportion of
+//! statements which should meet that latency. Every statement which is run is
+//! counted as good or bad against the most specific matching target, and the
+//! counters are exposed in the Prometheus text format by [`Slo::metrics`], so
+//! that the rate at which the error budget is being burned can be alerted on.
+use crate::sql::duration::Duration as SqlDuration;
+use std::fmt::{self, Write};
+use std::str::FromStr;
+use std::sync::atomic::{AtomicU64, Ordering};
+use std::time::Duration;
+
+/// The default proportion of statements which should meet a latency target
+const DEFAULT_OBJECTIVE: f64 = 99.0;
+
+/// A latency objective for a namespace, a database, or a table
+#[derive(Clone, Debug, PartialEq)]
+pub struct SloTarget {
+	pub ns: String,
+	pub db: Option<String>,
+	pub tb: Option<String>,
+	pub latency: Duration,
+	pub objective: f64,
+}
+
+impl SloTarget {
+	/// Check if a statement against this table is covered by this target
+	fn matches(&self, ns: &str, db: Option<&str>, tb: Option<&str>) -> bool {
+		self.ns == ns
+			&& self.db.as_deref().map_or(true, |v| Some(v) == db)
+			&& self.tb.as_deref().map_or(true, |v| Some(v) == tb)
+	}
+	/// Get the specificity of this target, with table targets being the most specific
+	fn specificity(&self) -> usize {
+		match (&self.db, &self.tb) {
+			(_, Some(_)) => 2,
+			(Some(_), None) => 1,
+			(None, None) => 0,
+		}
+	}
+}
+
+impl FromStr for SloTarget {
+	type Err = String;
+	/// Parse a target in the form `ns[/db[/tb]]=latency[@objective]`
+	fn from_str(s: &str) -> Result<Self, Self::Err> {
+		let err =
+			|| format!("Invalid SLO target '{s}', expected 'ns[/db[/tb]]=latency[@objective]'");
+		let (path, target) = s.split_once('=').ok_or_else(err)?;
+		let mut path = path.trim().splitn(3, '/').map(str::to_owned);
+		let ns = path.next().filter(|v| !v.is_empty()).ok_or_else(err)?;
+		let db = path.next().filter(|v| !v.is_empty());
+		let tb = path.next().filter(|v| !v.is_empty());
+		let (latency, objective) = match target.split_once('@') {
+			Some((l, o)) => (l, o.trim().trim_end_matches('%').parse().map_err(|_| err())?),
+			None => (target, DEFAULT_OBJECTIVE),
+		};
+		let latency = SqlDuration::try_from(latency.trim()).map_err(|_| err())?.0;
+		if !(objective > 0.0 && objective < 100.0) {
+			return Err(format!(
+				"Invalid SLO target '{s}', the objective must be between 0 and 100"
+			));
+		}
+		if db.is_none() && tb.is_some() {
+			return Err(err());
+		}
+		Ok(SloTarget {
+			ns,
+			db,
+			tb,
+			latency,
+			objective,
+		})
+	}
+}
+
+impl fmt::Display for SloTarget {
+	fn fmt(&self, f: &mut fmt::Formatter) -> fmt::Result {
+		f.write_str(&self.ns)?;
+		if let Some(db) = &self.db {
+			write!(f, "/{db}")?;
+		}
+		if let Some(tb) = &self.tb {
+			write!(f, "/{tb}")?;
+		}
+		write!(f, "={}@{}", SqlDuration::from(self.latency), self.objective)
+	}
+}
+
+#[derive(Debug)]
+struct Counter {
+	target: SloTarget,
+	good: AtomicU64,
+	bad: AtomicU64,
+}
+
+/// The good and bad statement counters for a set of latency targets
+#[derive(Debug, Default)]
+pub struct Slo {
+	counters: Vec<Counter>,
+}
+
+impl Slo {
+	/// Create a new set of counters for the specified targets
+	pub fn new(targets: Vec<SloTarget>) -> Self {
+		Slo {
+			counters: targets
+				.into_iter()
+				.map(|target| Counter {
+					target,
+					good: AtomicU64::new(0),
+					bad: AtomicU64::new(0),
+				})
+				.collect(),
+		}
+	}
+
+	/// Check if any latency targets are configured
+	pub fn is_enabled(&self) -> bool {
+		!self.counters.is_empty()
+	}
+
+	/// Count a statement against the most specific target for each table
+	/// which it touches. A statement is bad if it took longer than the target
+	/// latency, or if it timed out. Each target counts a statement only once.
+	pub(crate) fn record(
+		&self,
+		ns: &str,
+		db: Option<&str>,
+		tbs: &[String],
+		elapsed: Duration,
+		timedout: bool,
+	) {
+		let mut seen: Vec<usize> = Vec::new();
+		let mut count = |tb: Option<&str>| {
+			let best = self
+				.counters
+				.iter()
+				.enumerate()
+				.filter(|(_, c)| c.target.matches(ns, db, tb))
+				.max_by_key(|(_, c)| c.target.specificity());
+			if let Some((i, c)) = best {
+				if !seen.contains(&i) {
+					seen.push(i);
+					match timedout || elapsed > c.target.latency {
+						true => c.bad.fetch_add(1, Ordering::Relaxed),
+						false => c.good.fetch_add(1, Ordering::Relaxed),
+					};
+				}
+			}
+		};
+		match tbs.is_empty() {
+			true => count(None),
+			false => tbs.iter().for_each(|tb| count(Some(tb))),
+		}
+	}
+
+	/// Output the counters and targets in the Prometheus text format
+	pub fn metrics(&self) -> String {
+		let mut out = String::new();
+		let labels = |t: &SloTarget| {
+			format!(
+				"ns=\"{}\",db=\"{}\",tb=\"{}\"",
+				escape(&t.ns),
+				escape(t.db.as_deref().unwrap_or_default()),
+				escape(t.tb.as_deref().unwrap_or_default())
+			)
+		};
+		out.push_str("# HELP surrealdb_slo_requests_total Statements which met or missed their latency target\n");
+		out.push_str("# TYPE surrealdb_slo_requests_total counter\n");
+		for c in self.counters.iter() {
+			let l = labels(&c.target);
+			let _ = writeln!(
+				out,
+				"surrealdb_slo_requests_total{{{l},result=\"good\"}} {}",
+				c.good.load(Ordering::Relaxed)
+			);
+			let _ = writeln!(
+				out,
+				"surrealdb_slo_requests_total{{{l},result=\"bad\"}} {}",
+				c.bad.load(Ordering::Relaxed)
+			);
+		}
+		out.push_str("# HELP surrealdb_slo_latency_seconds The latency target of each objective\n");
+		out.push_str("# TYPE surrealdb_slo_latency_seconds gauge\n");
+		for c in self.counters.iter() {
+			let l = labels(&c.target);
+			let _ = writeln!(
+				out,
+				"surrealdb_slo_latency_seconds{{{l}}} {}",
+				c.target.latency.as_secs_f64()
+			);
+		}
+		out.push_str("# HELP surrealdb_slo_objective_ratio The proportion of statements which should meet the latency target\n");
+		out.push_str("# TYPE surrealdb_slo_objective_ratio gauge\n");
+		for c in self.counters.iter() {
+			let l = labels(&c.target);
+			let _ = writeln!(
+				out,
+				"surrealdb_slo_objective_ratio{{{l}}} {}",
+				c.target.objective / 100.0
+			);
+		}
+		out
+	}
+}
+
+/// Escape a Prometheus label value
+fn escape(v: &str) -> String {
+	v.replace('\\', "\\\\").replace('"', "\\\"").replace('\n', "\\n")
+}
+
+#[cfg(test)]
+mod tests {
+	use super::*;
+
+	#[test]
+	fn slo_target_parse() {
+		let t: SloTarget = "test/test/person=50ms@99.9".parse().unwrap();
+		assert_eq!(t.ns, "test");
+		assert_eq!(t.db.as_deref(), Some("test"));
+		assert_eq!(t.tb.as_deref(), Some("person"));
+		assert_eq!(t.latency, Duration::from_millis(50));
+		assert_eq!(t.objective, 99.9);
+		assert_eq!(t.to_string(), "test/test/person=50ms@99.9");
+		let t: SloTarget = "test=1s".parse().unwrap();
+		assert_eq!(t.db, None);
+		assert_eq!(t.objective, DEFAULT_OBJECTIVE);
+		assert!("test".parse::<SloTarget>().is_err());
+		assert!("test=1s@100".parse::<SloTarget>().is_err());
+		assert!("test=soon".parse::<SloTarget>().is_err());
+	}
+
+	#[test]
+	fn slo_record_most_specific() {
+		let slo =
+			Slo::new(vec!["test=1s".parse().unwrap(), "test/test/person=10ms".parse().unwrap()]);
+		let tbs = vec!["person".to_owned()];
+		slo.record("test", Some("test"), &tbs, Duration::from_millis(5), false);
+		slo.record("test", Some("test"), &tbs, Duration::from_millis(50), false);
+		slo.record("test", Some("test"), &[], Duration::from_millis(50), false);
+		slo.record("test", Some("test"), &[], Duration::from_millis(5), true);
+		slo.record("other", Some("test"), &tbs, Duration::from_millis(5), false);
+		assert_eq!(slo.counters[0].good.load(Ordering::Relaxed), 1);
+		assert_eq!(slo.counters[0].bad.load(Ordering::Relaxed), 1);
+		assert_eq!(slo.counters[1].good.load(Ordering::Relaxed), 1);
+		assert_eq!(slo.counters[1].bad.load(Ordering::Relaxed), 1);
+	}
+}
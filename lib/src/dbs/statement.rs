@@ -1,13 +1,16 @@
 use crate::sql::cond::Cond;
 use crate::sql::data::Data;
 use crate::sql::fetch::Fetchs;
-use crate::sql::field::Fields;
+use crate::sql::field::{Field, Fields};
+use crate::sql::fill::Fill;
 use crate::sql::group::Groups;
+use crate::sql::idiom::Idiom;
 use crate::sql::limit::Limit;
 use crate::sql::order::Orders;
 use crate::sql::output::Output;
+use crate::sql::part::Part;
 use crate::sql::split::Splits;
-use crate::sql::start::Start;
+use crate::sql::start::{After, Start};
 use crate::sql::statements::create::CreateStatement;
 use crate::sql::statements::delete::DeleteStatement;
 use crate::sql::statements::insert::InsertStatement;
@@ -15,6 +18,7 @@ use crate::sql::statements::live::LiveStatement;
 use crate::sql::statements::relate::RelateStatement;
 use crate::sql::statements::select::SelectStatement;
 use crate::sql::statements::update::UpdateStatement;
+use crate::sql::value::Value;
 use std::fmt;
 
 #[derive(Clone, Debug)]
@@ -92,6 +96,11 @@ impl<'a> Statement<'a> {
 	}
 	/// Check the type of statement
 	#[inline]
+	pub fn is_update(&self) -> bool {
+		matches!(self, Statement::Update(_))
+	}
+	/// Check the type of statement
+	#[inline]
 	pub fn is_delete(&self) -> bool {
 		matches!(self, Statement::Delete(_))
 	}
@@ -126,6 +135,14 @@ impl<'a> Statement<'a> {
 			_ => None,
 		}
 	}
+	/// Returns whether soft deleted records are included
+	#[inline]
+	pub fn deleted(&self) -> bool {
+		match self {
+			Statement::Select(v) => v.deleted,
+			_ => false,
+		}
+	}
 	/// Returns any SPLIT clause if specified
 	#[inline]
 	pub fn split(&self) -> Option<&Splits> {
@@ -142,11 +159,39 @@ impl<'a> Statement<'a> {
 			_ => None,
 		}
 	}
+	/// Returns whether the GROUP clause includes subtotal rows
+	#[inline]
+	pub fn rollup(&self) -> bool {
+		match self {
+			Statement::Select(v) => v.rollup,
+			_ => false,
+		}
+	}
+	/// Returns whether any field is computed by a window function
+	#[inline]
+	pub fn window(&self) -> bool {
+		match self {
+			Statement::Select(v) => v.expr.other().any(
+				|f| matches!(f, Field::Single { expr: Value::Function(f), .. } if f.is_window()),
+			),
+			_ => false,
+		}
+	}
+	/// Returns any FILL clause if specified
+	#[inline]
+	pub fn fill(&self) -> Option<Fill> {
+		match self {
+			Statement::Select(v) => v.fill,
+			_ => None,
+		}
+	}
 	/// Returns any ORDER clause if specified
 	#[inline]
 	pub fn order(&self) -> Option<&Orders> {
 		match self {
 			Statement::Select(v) => v.order.as_ref(),
+			Statement::Update(v) => v.order.as_ref(),
+			Statement::Delete(v) => v.order.as_ref(),
 			_ => None,
 		}
 	}
@@ -166,14 +211,41 @@ impl<'a> Statement<'a> {
 			_ => None,
 		}
 	}
+	/// Returns any START AFTER clause if specified
+	#[inline]
+	pub fn after(&self) -> Option<&After> {
+		match self {
+			Statement::Select(v) => v.after.as_ref(),
+			_ => None,
+		}
+	}
+	/// Returns whether the total number of records is output WITH TOTAL
+	#[inline]
+	pub fn total(&self) -> bool {
+		match self {
+			Statement::Select(v) => v.total,
+			_ => false,
+		}
+	}
 	/// Returns any LIMIT clause if specified
 	#[inline]
 	pub fn limit(&self) -> Option<&Limit> {
 		match self {
 			Statement::Select(v) => v.limit.as_ref(),
+			Statement::Update(v) => v.limit.as_ref(),
+			Statement::Delete(v) => v.limit.as_ref(),
 			_ => None,
 		}
 	}
+	/// Check whether the LIMIT clause is STRICT
+	#[inline]
+	pub fn strict(&self) -> bool {
+		match self {
+			Statement::Update(v) => v.strict,
+			Statement::Delete(v) => v.strict,
+			_ => false,
+		}
+	}
 	/// Returns any RETURN clause if specified
 	#[inline]
 	pub fn output(&self) -> Option<&Output> {
@@ -208,4 +280,68 @@ impl<'a> Statement<'a> {
 			_ => false,
 		}
 	}
+	/// Returns the record fields which are read by this statement, if
+	/// it is a SELECT statement which only reads simple record fields
+	pub fn projection(&self) -> Option<Vec<&Idiom>> {
+		let stm = match self {
+			Statement::Select(v) => v,
+			_ => return None,
+		};
+		// Any of these clauses might need the whole record
+		if stm.expr.is_all()
+			|| stm.split.is_some()
+			|| stm.group.is_some()
+			|| stm.fetch.is_some()
+			|| stm.version.is_some()
+		{
+			return None;
+		}
+		let mut out = vec![];
+		for v in stm.expr.other() {
+			if let Field::Single {
+				expr,
+				..
+			} = v
+			{
+				if !idioms(expr, &mut out) {
+					return None;
+				}
+			}
+		}
+		if let Some(v) = &stm.cond {
+			if !idioms(&v.0, &mut out) {
+				return None;
+			}
+		}
+		if let Some(v) = &stm.order {
+			out.extend(v.iter().map(|v| &v.order));
+		}
+		Some(out)
+	}
+}
+
+/// Collects the record fields which are read by a simple expression,
+/// returning false if the expression might read anything else
+fn idioms<'a>(v: &'a Value, out: &mut Vec<&'a Idiom>) -> bool {
+	match v {
+		Value::Idiom(v) => {
+			let simple = v.iter().all(|v| {
+				matches!(v, Part::Field(_) | Part::Index(_) | Part::All | Part::First | Part::Last)
+			});
+			out.push(v);
+			simple
+		}
+		Value::Expression(v) => idioms(&v.l, out) && idioms(&v.r, out),
+		Value::None
+		| Value::Null
+		| Value::Bool(_)
+		| Value::Number(_)
+		| Value::Strand(_)
+		| Value::Duration(_)
+		| Value::Datetime(_)
+		| Value::Uuid(_)
+		| Value::Thing(_)
+		| Value::Constant(_) => true,
+		_ => false,
+	}
 }
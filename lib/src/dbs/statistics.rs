@@ -0,0 +1,271 @@
+//! Execution statistics for each normalized statement.
+//!
+//! Every statement which is run is normalized, by replacing its literal
+//! values and record ids with placeholders, so that statements which only
+//! differ in their arguments are counted together. For each normalized
+//! statement the [`Statistics`] count the number of executions and errors,
+//! the latency, and the number of records which were returned and examined.
+//! The statistics are only kept in memory, for as long as the datastore is
+//! running, and are returned by selecting from `system:statements`, so that
+//! they can be filtered, sorted, and grouped like any other records.
+use crate::err::Error;
+use crate::sql::duration::Duration as SqlDuration;
+use crate::sql::{Object, Thing, Value};
+use std::collections::{HashMap, VecDeque};
+use std::sync::Mutex;
+use std::time::Duration;
+
+/// The number of latencies which are kept for each statement, from which
+/// the latency percentiles are calculated
+const SAMPLES: usize = 1000;
+
+/// The namespace, database, and normalized text of a statement
+type Key = (String, String, String);
+
+#[derive(Debug, Default)]
+struct Entry {
+	// The number of times the statement was run
+	calls: u64,
+	// The number of times the statement failed
+	errors: u64,
+	// The total latency of the statement
+	total: Duration,
+	// The maximum latency of the statement
+	max: Duration,
+	// The most recent latencies of the statement
+	samples: VecDeque<Duration>,
+	// The number of records which were returned
+	returned: u64,
+	// The number of records which were examined
+	examined: u64,
+}
+
+impl Entry {
+	/// Get a latency percentile from the recent latencies
+	fn percentile(sorted: &[Duration], p: f64) -> Duration {
+		match sorted.len() {
+			0 => Duration::ZERO,
+			n => sorted[((n - 1) as f64 * p).round() as usize],
+		}
+	}
+
+	fn output(&self, (ns, db, sql): &Key) -> Value {
+		let mut sorted: Vec<Duration> = self.samples.iter().copied().collect();
+		sorted.sort_unstable();
+		let mean = Duration::from_nanos((self.total.as_nanos() / self.calls.max(1) as u128) as u64);
+		let time = |v: Duration| Value::from(SqlDuration::from(v));
+		let mut obj = Object::default();
+		obj.insert("ns".to_owned(), Value::from(ns.as_str()));
+		obj.insert("db".to_owned(), Value::from(db.as_str()));
+		obj.insert("query".to_owned(), Value::from(sql.as_str()));
+		obj.insert("calls".to_owned(), Value::from(self.calls));
+		obj.insert("errors".to_owned(), Value::from(self.errors));
+		obj.insert("total_time".to_owned(), time(self.total));
+		obj.insert("mean_time".to_owned(), time(mean));
+		obj.insert("p50_time".to_owned(), time(Entry::percentile(&sorted, 0.50)));
+		obj.insert("p95_time".to_owned(), time(Entry::percentile(&sorted, 0.95)));
+		obj.insert("p99_time".to_owned(), time(Entry::percentile(&sorted, 0.99)));
+		obj.insert("max_time".to_owned(), time(self.max));
+		obj.insert("rows_returned".to_owned(), Value::from(self.returned));
+		obj.insert("rows_examined".to_owned(), Value::from(self.examined));
+		Value::from(obj)
+	}
+}
+
+/// The execution statistics of the normalized statements of a datastore
+#[derive(Debug)]
+pub struct Statistics {
+	// The maximum number of normalized statements which are tracked
+	capacity: usize,
+	// The statistics of each normalized statement
+	entries: Mutex<HashMap<Key, Entry>>,
+}
+
+impl Default for Statistics {
+	fn default() -> Self {
+		Statistics::new(1000)
+	}
+}
+
+impl Statistics {
+	/// Create statistics which track up to a number of normalized statements,
+	/// where the least frequently run statement is replaced once full
+	pub fn new(capacity: usize) -> Self {
+		Statistics {
+			capacity,
+			entries: Mutex::new(HashMap::new()),
+		}
+	}
+	/// Check if any statements are tracked
+	pub fn is_enabled(&self) -> bool {
+		self.capacity > 0
+	}
+	/// Record the execution of a normalized statement
+	pub(crate) fn record(
+		&self,
+		ns: &str,
+		db: &str,
+		sql: String,
+		time: Duration,
+		res: &Result<Value, Error>,
+		examined: usize,
+	) {
+		let mut entries = self.entries.lock().unwrap();
+		let key = (ns.to_owned(), db.to_owned(), sql);
+		// Replace the least frequently run statement when full
+		if !entries.contains_key(&key) && entries.len() >= self.capacity {
+			let min = entries.iter().min_by_key(|(_, v)| v.calls).map(|(k, _)| k.clone());
+			if let Some(min) = min {
+				entries.remove(&min);
+			}
+		}
+		let entry = entries.entry(key).or_default();
+		entry.calls += 1;
+		entry.total += time;
+		entry.max = entry.max.max(time);
+		if entry.samples.len() >= SAMPLES {
+			entry.samples.pop_front();
+		}
+		entry.samples.push_back(time);
+		entry.examined += examined as u64;
+		match res {
+			Ok(Value::Array(v)) => entry.returned += v.len() as u64,
+			Ok(Value::None) => (),
+			Ok(_) => entry.returned += 1,
+			Err(_) => entry.errors += 1,
+		}
+	}
+	/// Get the statistics of each normalized statement, optionally only for
+	/// a namespace and database, with the longest running statements first
+	pub fn output(&self, ns: Option<&str>, db: Option<&str>) -> Vec<Value> {
+		let entries = self.entries.lock().unwrap();
+		let mut out: Vec<(&Key, &Entry)> = entries
+			.iter()
+			.filter(|((n, d, _), _)| ns.map_or(true, |v| v == n) && db.map_or(true, |v| v == d))
+			.collect();
+		out.sort_by(|a, b| b.1.total.cmp(&a.1.total).then_with(|| a.0.cmp(b.0)));
+		out.into_iter().map(|(k, v)| v.output(k)).collect()
+	}
+	/// Remove the statistics of every statement
+	pub fn clear(&self) {
+		self.entries.lock().unwrap().clear();
+	}
+}
+
+/// Check if a record id refers to the statement statistics
+pub(crate) fn is_statements(v: &Thing) -> bool {
+	v.tb == "system" && v.id.to_raw() == "statements"
+}
+
+/// Normalize the text of a statement, by replacing any strings, numbers,
+/// durations, datetimes, uuids, and the ids of any record ids with `?`
+pub(crate) fn normalize(sql: &str) -> String {
+	let ident = |c: char| c.is_alphanumeric() || c == '_';
+	let mut out = String::with_capacity(sql.len());
+	let mut chars = sql.chars().peekable();
+	while let Some(c) = chars.next() {
+		match c {
+			// Replace strings, and any datetime, uuid, or record prefix
+			'\'' | '"' => {
+				let mut it = out.chars().rev();
+				if let (Some('d' | 'r' | 'u' | 's'), None | Some(' ' | '(' | '[' | ',')) =
+					(it.next(), it.next())
+				{
+					out.pop();
+				}
+				let mut escaped = false;
+				for v in chars.by_ref() {
+					match v {
+						_ if escaped => escaped = false,
+						'\\' => escaped = true,
+						_ if v == c => break,
+						_ => (),
+					}
+				}
+				out.push('?');
+			}
+			// Replace the id of a record id
+			':' if out.ends_with(ident)
+				&& chars.peek().map_or(false, |v| ident(*v) || *v == '⟨') =>
+			{
+				out.push(':');
+				if chars.next_if_eq(&'⟨').is_some() {
+					chars.by_ref().find(|v| *v == '⟩');
+				} else {
+					while chars.next_if(|v| ident(*v)).is_some() {}
+				}
+				out.push('?');
+			}
+			// Replace numbers and durations
+			_ if c.is_ascii_digit() && !out.ends_with(ident) => {
+				while chars.next_if(|v| ident(*v) || *v == '.').is_some() {}
+				out.push('?');
+			}
+			_ => out.push(c),
+		}
+	}
+	out
+}
+
+#[cfg(test)]
+mod tests {
+	use super::*;
+
+	#[test]
+	fn normalize_statements() {
+		let tests = [
+			(
+				"SELECT * FROM person WHERE age > 18 AND name = 'Tobie'",
+				"SELECT * FROM person WHERE age > ? AND name = ?",
+			),
+			("SELECT * FROM person:tobie", "SELECT * FROM person:?"),
+			("SELECT * FROM person:⟨tobie morgan⟩", "SELECT * FROM person:?"),
+			("CREATE person SET born = d'2020-01-01T00:00:00Z'", "CREATE person SET born = ?"),
+			("SELECT * FROM sleep(500ms) TIMEOUT 1.5s", "SELECT * FROM sleep(?) TIMEOUT ?"),
+			("RETURN string::len('it\\'s')", "RETURN string::len(?)"),
+			("SELECT * FROM table2 WHERE v1 = $v1", "SELECT * FROM table2 WHERE v1 = $v1"),
+		];
+		for (sql, res) in tests {
+			assert_eq!(normalize(sql), res);
+		}
+	}
+
+	#[test]
+	fn statistics_record() {
+		let stats = Statistics::new(2);
+		let sql = || "SELECT * FROM person".to_owned();
+		let val = Ok(Value::from(vec![Value::from(1), Value::from(2)]));
+		stats.record("test", "test", sql(), Duration::from_millis(10), &val, 4);
+		stats.record("test", "test", sql(), Duration::from_millis(30), &val, 4);
+		stats.record(
+			"test",
+			"test",
+			sql(),
+			Duration::from_millis(20),
+			&Err(Error::QueryTimedout),
+			2,
+		);
+		stats.record("test", "other", sql(), Duration::from_millis(1), &val, 0);
+		let out = stats.output(None, None);
+		assert_eq!(out.len(), 2);
+		let obj = match &out[0] {
+			Value::Object(v) => v,
+			_ => unreachable!(),
+		};
+		assert_eq!(obj.get("db"), Some(&Value::from("test")));
+		assert_eq!(obj.get("calls"), Some(&Value::from(3u64)));
+		assert_eq!(obj.get("errors"), Some(&Value::from(1u64)));
+		assert_eq!(obj.get("rows_returned"), Some(&Value::from(4u64)));
+		assert_eq!(obj.get("rows_examined"), Some(&Value::from(10u64)));
+		let time = |v: u64| Value::from(SqlDuration::from(Duration::from_millis(v)));
+		assert_eq!(obj.get("mean_time"), Some(&time(20)));
+		assert_eq!(obj.get("p50_time"), Some(&time(20)));
+		assert_eq!(obj.get("max_time"), Some(&time(30)));
+		// The statistics can be filtered by database
+		assert_eq!(stats.output(Some("test"), Some("other")).len(), 1);
+		// The least frequently run statement is replaced once full
+		stats.record("test", "test", "RETURN ?".to_owned(), Duration::ZERO, &val, 0);
+		let out = stats.output(Some("test"), Some("other"));
+		assert!(out.is_empty());
+	}
+}
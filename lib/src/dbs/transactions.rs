@@ -0,0 +1,199 @@
+//! Transactions which span multiple queries.
+//!
+//! A transaction which is started with [`Datastore::begin`] is kept open on
+//! the datastore, and is referred to by its id, so that the statements of a
+//! transaction can be sent in separate queries, such as separate requests
+//! to the HTTP API, until the transaction is committed or cancelled. Only
+//! the session which started a transaction can use it, and a transaction
+//! can only be used by one query at a time. If a query is dropped before it
+//! finishes, such as when the client disconnects, the transaction is kept
+//! open, but as a statement may have been interrupted part of the way
+//! through, the transaction can then only be cancelled. A transaction which
+//! has not been used for longer than the idle timeout is cancelled by
+//! [`Datastore::expire_transactions`], so that abandoned transactions do
+//! not hold on to the resources of the datastore.
+//!
+//! [`Datastore::begin`]: crate::kvs::Datastore::begin
+//! [`Datastore::expire_transactions`]: crate::kvs::Datastore::expire_transactions
+use crate::dbs::Auth;
+use crate::dbs::Session;
+use crate::dbs::Transaction;
+use crate::err::Error;
+use crate::sql::Value;
+use std::collections::{BTreeMap, HashMap};
+use std::ops::{Deref, DerefMut};
+use std::sync::{Arc, Mutex};
+use std::time::Duration;
+use trice::Instant;
+use uuid::Uuid;
+
+/// The default time after which an idle transaction is cancelled
+const TIMEOUT: Duration = Duration::from_secs(30);
+
+/// A transaction which is kept open between queries
+pub(crate) struct Handle {
+	// The transaction which the statements are run in
+	pub(crate) txn: Transaction,
+	// The authentication of the session which started the transaction
+	pub(crate) auth: Arc<Auth>,
+	// The id of the session which started the transaction
+	sid: Option<String>,
+	// The scope record of the session which started the transaction
+	sd: Option<Value>,
+	// Whether a statement in the transaction failed
+	pub(crate) err: bool,
+	// Whether the transaction is rolled back instead of committed
+	pub(crate) dry: bool,
+	// The variables which were set by LET statements in the transaction
+	pub(crate) vars: BTreeMap<String, Value>,
+	// The id of the transaction in the journal, once a statement is journaled
+	pub(crate) jid: Option<Uuid>,
+	// When the transaction was last used
+	used: Instant,
+}
+
+impl Handle {
+	pub(crate) fn new(txn: Transaction, sess: &Session, dry: bool) -> Self {
+		Handle {
+			txn,
+			auth: sess.au.clone(),
+			sid: sess.id.clone(),
+			sd: sess.sd.clone(),
+			err: false,
+			dry,
+			vars: BTreeMap::new(),
+			jid: None,
+			used: Instant::now(),
+		}
+	}
+	/// Check whether a session is the session which started the transaction
+	fn started(&self, sess: &Session) -> bool {
+		self.auth == sess.au && self.sid == sess.id && self.sd == sess.sd
+	}
+}
+
+/// A transaction which has been taken to run a query in. The transaction
+/// is put back when this is dropped, so that it is not lost if the query
+/// is dropped before it finishes, in which case the transaction is marked
+/// as failed, as a statement may have been interrupted.
+pub(crate) struct Taken<'a> {
+	id: Uuid,
+	transactions: &'a Transactions,
+	handle: Option<Handle>,
+}
+
+impl<'a> Taken<'a> {
+	/// Put back the transaction once a query has finished with it
+	pub(crate) fn put(mut self) {
+		if let Some(mut handle) = self.handle.take() {
+			handle.used = Instant::now();
+			self.transactions.put(&self.id, handle);
+		}
+	}
+	/// Stop keeping the transaction open, once it is committed or cancelled
+	pub(crate) fn remove(mut self) -> Handle {
+		self.transactions.remove(&self.id);
+		self.handle.take().unwrap()
+	}
+}
+
+impl<'a> Deref for Taken<'a> {
+	type Target = Handle;
+	fn deref(&self) -> &Self::Target {
+		self.handle.as_ref().unwrap()
+	}
+}
+
+impl<'a> DerefMut for Taken<'a> {
+	fn deref_mut(&mut self) -> &mut Self::Target {
+		self.handle.as_mut().unwrap()
+	}
+}
+
+impl<'a> Drop for Taken<'a> {
+	fn drop(&mut self) {
+		if let Some(mut handle) = self.handle.take() {
+			handle.err = true;
+			self.transactions.put(&self.id, handle);
+		}
+	}
+}
+
+/// The transactions which are kept open between queries
+pub struct Transactions {
+	timeout: Duration,
+	handles: Mutex<HashMap<Uuid, Option<Handle>>>,
+}
+
+impl Default for Transactions {
+	fn default() -> Self {
+		Transactions::new(TIMEOUT)
+	}
+}
+
+impl Transactions {
+	/// Keep transactions open until they have not been used for a duration
+	pub fn new(timeout: Duration) -> Self {
+		Transactions {
+			timeout,
+			handles: Mutex::new(HashMap::new()),
+		}
+	}
+	/// Get the number of transactions which are open
+	pub fn len(&self) -> usize {
+		self.handles.lock().unwrap().len()
+	}
+	/// Check whether no transactions are open
+	pub fn is_empty(&self) -> bool {
+		self.len() == 0
+	}
+	/// Keep a transaction open, returning the id by which it is used
+	pub(crate) fn insert(&self, handle: Handle) -> Uuid {
+		let id = Uuid::new_v4();
+		self.handles.lock().unwrap().insert(id, Some(handle));
+		id
+	}
+	/// Take a transaction to run a query in, which is put back once the
+	/// query has finished, so that it can not be used by concurrent queries
+	pub(crate) fn take(&self, id: &Uuid, sess: &Session) -> Result<Taken<'_>, Error> {
+		let mut handles = self.handles.lock().unwrap();
+		let handle = match handles.get_mut(id) {
+			Some(v) if v.as_ref().map_or(false, |v| !v.started(sess)) => Err(Error::TxNotFound {
+				value: id.to_string(),
+			}),
+			Some(v) => v.take().ok_or(Error::TxInUse {
+				value: id.to_string(),
+			}),
+			None => Err(Error::TxNotFound {
+				value: id.to_string(),
+			}),
+		}?;
+		Ok(Taken {
+			id: *id,
+			transactions: self,
+			handle: Some(handle),
+		})
+	}
+	/// Put back a transaction which was taken
+	fn put(&self, id: &Uuid, handle: Handle) {
+		if let Some(v) = self.handles.lock().unwrap().get_mut(id) {
+			*v = Some(handle);
+		}
+	}
+	/// Stop keeping a transaction open, once it is committed or cancelled
+	fn remove(&self, id: &Uuid) {
+		self.handles.lock().unwrap().remove(id);
+	}
+	/// Remove the transactions which have not been used within the idle
+	/// timeout, or every transaction if `all` is set, so that they can be
+	/// cancelled. A transaction which is being used is never removed.
+	pub(crate) fn expired(&self, all: bool) -> Vec<Handle> {
+		let mut handles = self.handles.lock().unwrap();
+		let ids: Vec<Uuid> = handles
+			.iter()
+			.filter(|(_, v)| v.as_ref().map_or(false, |v| all || v.used.elapsed() > self.timeout))
+			.map(|(k, _)| *k)
+			.collect();
+		ids.iter().filter_map(|k| handles.remove(k).flatten()).collect()
+	}
+}
@@ -0,0 +1,55 @@
+//! The resource usage of the namespaces of a datastore.
+//!
+//! The number of records and the storage bytes of each table are stored
+//! alongside the table itself, so that they persist across restarts, but
+//! the number of queries which were run against each database is only
+//! counted in memory, for as long as the datastore is running. The usage
+//! of a namespace is returned by `INFO FOR NAMESPACE`.
+use std::collections::HashMap;
+use std::sync::Mutex;
+
+/// The number of queries which were run against each database
+#[derive(Debug, Default)]
+pub struct Usage {
+	queries: Mutex<HashMap<String, HashMap<String, u64>>>,
+}
+
+impl Usage {
+	/// Count a query which was run against a namespace and database
+	pub(crate) fn query(&self, ns: &str, db: Option<&str>) {
+		let mut queries = self.queries.lock().unwrap();
+		let dbs = match queries.get_mut(ns) {
+			Some(v) => v,
+			None => queries.entry(ns.to_owned()).or_default(),
+		};
+		match dbs.get_mut(db.unwrap_or_default()) {
+			Some(v) => *v += 1,
+			None => {
+				dbs.insert(db.unwrap_or_default().to_owned(), 1);
+			}
+		}
+	}
+	/// Get the number of queries which were run against each database of
+	/// a namespace, where queries without a database are counted under an
+	/// empty database name
+	pub fn queries(&self, ns: &str) -> HashMap<String, u64> {
+		self.queries.lock().unwrap().get(ns).cloned().unwrap_or_default()
+	}
+}
+
+#[cfg(test)]
+mod tests {
+	use super::*;
+
+	#[test]
+	fn usage_counts_queries() {
+		let usage = Usage::default();
+		usage.query("test", Some("test"));
+		usage.query("test", Some("test"));
+		usage.query("test", None);
+		let queries = usage.queries("test");
+		assert_eq!(queries.get("test"), Some(&2));
+		assert_eq!(queries.get(""), Some(&1));
+		assert!(usage.queries("other").is_empty());
+	}
+}
@@ -0,0 +1,179 @@
+//! Asynchronous delivery of the webhooks which are sent by events.
+//!
+//! When the `THEN` clause of an event calls `http::post`, `http::put`,
+//! `http::patch`, or `http::delete`, the request is not sent while the record
+//! is being written. Instead it is queued with the transaction, and once the
+//! transaction commits, it is handed to a pool of workers which deliver it in
+//! the background. Requests which fail are retried with exponential backoff,
+//! so that external systems are notified of writes without polling, without
+//! slowing the writes down, and without being notified of cancelled writes.
+use crate::dbs::Config;
+use crate::sql::object::Object;
+use crate::sql::value::Value;
+use channel::Sender;
+use once_cell::sync::OnceCell;
+use std::fmt;
+use std::sync::atomic::{AtomicU64, Ordering};
+use std::sync::Arc;
+
+const LOG: &str = "surrealdb::webhook";
+
+/// The number of webhooks which can be waiting to be delivered.
+///
+/// Webhooks which are sent while the queue is full are dropped, so
+/// that a slow external system never blocks transactions from
+/// committing.
+pub const CAPACITY: usize = 10_000;
+
+/// The HTTP method of a webhook
+#[derive(Clone, Copy, Debug, Eq, PartialEq)]
+pub enum Method {
+	Post,
+	Put,
+	Patch,
+	Delete,
+}
+
+impl fmt::Display for Method {
+	fn fmt(&self, f: &mut fmt::Formatter) -> fmt::Result {
+		match self {
+			Method::Post => f.write_str("POST"),
+			Method::Put => f.write_str("PUT"),
+			Method::Patch => f.write_str("PATCH"),
+			Method::Delete => f.write_str("DELETE"),
+		}
+	}
+}
+
+/// A request which is sent once its transaction commits
+#[derive(Clone, Debug, PartialEq)]
+pub struct Webhook {
+	pub method: Method,
+	pub uri: String,
+	pub body: Value,
+	pub headers: Object,
+}
+
+#[derive(Default)]
+struct Counters {
+	delivered: AtomicU64,
+	retried: AtomicU64,
+	failed: AtomicU64,
+}
+
+/// The delivery queue of the webhooks of a datastore
+pub struct Webhooks {
+	queue: OnceCell<Sender<Webhook>>,
+	counters: Arc<Counters>,
+	#[cfg_attr(not(all(feature = "http", not(target_arch = "wasm32"))), allow(dead_code))]
+	config: Arc<Config>,
+}
+
+impl Webhooks {
+	/// Create a delivery queue, which delivers webhooks with the workers,
+	/// retries, and limits of the configuration of a datastore
+	pub(crate) fn new(config: Arc<Config>) -> Self {
+		Webhooks {
+			queue: OnceCell::new(),
+			counters: Arc::default(),
+			config,
+		}
+	}
+
+	/// Queue the webhooks of a committed transaction for delivery
+	pub(crate) fn publish(&self, hooks: Vec<Webhook>) {
+		// Start the delivery workers when the first webhook is sent
+		let queue = self.queue.get_or_init(|| self.start());
+		for hook in hooks {
+			if queue.try_send(hook).is_err() {
+				warn!(target: LOG, "The webhook queue is full, so a webhook was dropped");
+				self.counters.failed.fetch_add(1, Ordering::Relaxed);
+			}
+		}
+	}
+
+	/// The number of webhooks which have been delivered
+	pub fn delivered(&self) -> u64 {
+		self.counters.delivered.load(Ordering::Relaxed)
+	}
+
+	/// The number of failed deliveries which have been retried
+	pub fn retried(&self) -> u64 {
+		self.counters.retried.load(Ordering::Relaxed)
+	}
+
+	/// The number of webhooks which could not be delivered
+	pub fn failed(&self) -> u64 {
+		self.counters.failed.load(Ordering::Relaxed)
+	}
+
+	/// The number of webhooks which are queued for delivery
+	pub fn pending(&self) -> usize {
+		self.queue.get().map_or(0, |v| v.len())
+	}
+
+	#[cfg(all(feature = "http", not(target_arch = "wasm32")))]
+	fn start(&self) -> Sender<Webhook> {
+		let (snd, rcv) = channel::bounded(CAPACITY);
+		for _ in 0..self.config.webhook_workers.max(1) {
+			tokio::spawn(worker(rcv.clone(), self.counters.clone(), self.config.clone()));
+		}
+		snd
+	}
+
+	#[cfg(not(all(feature = "http", not(target_arch = "wasm32"))))]
+	fn start(&self) -> Sender<Webhook> {
+		// Webhooks are only queued when they can be delivered in the background
+		channel::bounded(1).0
+	}
+}
+
+/// Get the time to wait before the specified retry of a webhook
+#[cfg(all(feature = "http", not(target_arch = "wasm32")))]
+fn backoff(base: std::time::Duration, attempt: u32) -> std::time::Duration {
+	base.saturating_mul(2u32.saturating_pow(attempt))
+}
+
+/// Deliver webhooks from the queue, retrying any failed deliveries
+#[cfg(all(feature = "http", not(target_arch = "wasm32")))]
+async fn worker(rcv: channel::Receiver<Webhook>, counters: Arc<Counters>, config: Arc<Config>) {
+	use crate::fnc::util::http::deliver;
+	while let Ok(hook) = rcv.recv().await {
+		let mut attempt = 0;
+		loop {
+			match deliver(&hook, &config.http, config.sandbox.time).await {
+				Ok(_) => {
+					counters.delivered.fetch_add(1, Ordering::Relaxed);
+					break;
+				}
+				Err(e) if attempt < config.webhook_retries => {
+					trace!(target: LOG, "Retrying the webhook {} {}: {}", hook.method, hook.uri, e);
+					counters.retried.fetch_add(1, Ordering::Relaxed);
+					tokio::time::sleep(backoff(config.webhook_backoff, attempt)).await;
+					attempt += 1;
+				}
+				Err(e) => {
+					warn!(target: LOG, "Unable to deliver the webhook {} {}: {}", hook.method, hook.uri, e);
+					counters.failed.fetch_add(1, Ordering::Relaxed);
+					break;
+				}
+			}
+		}
+	}
+}
+
+#[cfg(all(test, feature = "http", not(target_arch = "wasm32")))]
+mod tests {
+
+	use super::*;
+	use std::time::Duration;
+
+	#[test]
+	fn backoff_doubles() {
+		let base = Duration::from_millis(500);
+		assert_eq!(backoff(base, 0), Duration::from_millis(500));
+		assert_eq!(backoff(base, 1), Duration::from_secs(1));
+		assert_eq!(backoff(base, 3), Duration::from_secs(4));
+		assert_eq!(backoff(base, 64), Duration::MAX);
+	}
+}
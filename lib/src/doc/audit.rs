@@ -0,0 +1,194 @@
+use crate::ctx::Context;
+use crate::dbs::Auth;
+use crate::dbs::Options;
+use crate::dbs::Statement;
+use crate::doc::Document;
+use crate::err::Error;
+use crate::kvs::Datastore;
+use crate::sql::datetime::Datetime;
+use crate::sql::id::Id;
+use crate::sql::paths::ID;
+use crate::sql::paths::IP;
+use crate::sql::paths::SD;
+use crate::sql::thing::Thing;
+use crate::sql::value::Value;
+use std::sync::Mutex;
+
+const LOG: &str = "surrealdb::audit";
+
+impl<'a> Document<'a> {
+	pub async fn audit(
+		&self,
+		ctx: &Context<'_>,
+		opt: &Options,
+		stm: &Statement<'_>,
+	) -> Result<(), Error> {
+		// Imported records are already audited
+		if opt.import {
+			return Ok(());
+		}
+		// Check if this record exists
+		let rid = match self.id {
+			Some(rid) => rid,
+			None => return Ok(()),
+		};
+		// Clone transaction
+		let txn = ctx.clone_transaction()?;
+		// Get the table
+		let tb = self.tb(opt, &txn).await?;
+		// Check if the table is audited
+		let audit = match &tb.audit {
+			Some(v) => v,
+			None => return Ok(()),
+		};
+		// Get the audited action
+		let action = if stm.is_select() {
+			// Check if reads are audited
+			if !audit.reads {
+				return Ok(());
+			}
+			"SELECT"
+		} else {
+			// Check if forced
+			if !opt.force && !self.changed() {
+				return Ok(());
+			}
+			if stm.is_delete() {
+				"DELETE"
+			} else if self.is_new() {
+				"CREATE"
+			} else {
+				"UPDATE"
+			}
+		};
+		// Get the session details
+		let session = ctx.value("session").unwrap_or(&Value::None);
+		// Create the audit entry
+		let mut entry = Value::from(map! {
+			"action".to_string() => Value::from(action),
+			"at".to_string() => Value::from(Datetime::default()),
+			"auth".to_string() => identity(opt, session),
+			"ip".to_string() => session.pick(IP.as_ref()),
+			"session".to_string() => session.pick(ID.as_ref()),
+			"table".to_string() => Value::from(rid.tb.to_owned()),
+			"record".to_string() => Value::from(rid.clone()),
+		});
+		// Write the audit entry
+		match (&audit.into, ctx.reads()) {
+			// Append the entry of a read to the audit table after the statement,
+			// as a SELECT statement runs in a read-only transaction
+			(Some(into), Some(reads)) if stm.is_select() => {
+				let id = Thing {
+					tb: into.to_raw(),
+					id: Id::ulid(),
+				};
+				reads.push(opt, id, entry);
+			}
+			// Append the entry to the audit table
+			(Some(into), _) if !stm.is_select() => {
+				// Generate the audit entry id
+				let id = Thing {
+					tb: into.to_raw(),
+					id: Id::ulid(),
+				};
+				// Claim transaction
+				let mut run = txn.lock().await;
+				// Ensure the audit table exists
+				run.add_tb(opt.ns(), opt.db(), into, opt.strict).await?;
+				// Store the audit entry
+				let key = crate::key::thing::new(opt.ns(), opt.db(), &id.tb, &id.id);
+				entry.put(ID.as_ref(), id.into());
+				run.set(key, entry).await?;
+			}
+			// Send the entry to the audit log
+			_ => info!(target: LOG, "{}", entry),
+		}
+		// Carry on
+		Ok(())
+	}
+}
+
+/// The audit entries of the records which were read by a statement, which
+/// are written once the statement has finished, in their own transaction
+#[derive(Debug, Default)]
+pub(crate) struct Reads {
+	entries: Mutex<Vec<Read>>,
+}
+
+#[derive(Debug)]
+struct Read {
+	ns: String,
+	db: String,
+	strict: bool,
+	id: Thing,
+	entry: Value,
+}
+
+impl Reads {
+	fn push(&self, opt: &Options, id: Thing, entry: Value) {
+		self.entries.lock().unwrap().push(Read {
+			ns: opt.ns().to_owned(),
+			db: opt.db().to_owned(),
+			strict: opt.strict,
+			id,
+			entry,
+		});
+	}
+
+	/// Write the audit entries in a new write transaction, so that reads
+	/// are audited even if the transaction of the statement is cancelled
+	pub(crate) async fn write(&self, kvs: &Datastore) -> Result<(), Error> {
+		let entries = std::mem::take(&mut *self.entries.lock().unwrap());
+		if entries.is_empty() {
+			return Ok(());
+		}
+		let mut run = kvs.transaction(true, false).await?;
+		let res = async {
+			for Read {
+				ns,
+				db,
+				strict,
+				id,
+				mut entry,
+			} in entries
+			{
+				// Ensure the audit table exists
+				run.add_tb(&ns, &db, &id.tb, strict).await?;
+				// Store the audit entry
+				let key = crate::key::thing::new(&ns, &db, &id.tb, &id.id);
+				entry.put(ID.as_ref(), id.into());
+				run.set(key, entry).await?;
+			}
+			Ok::<(), Error>(())
+		}
+		.await;
+		match res {
+			Ok(_) => run.commit().await,
+			Err(e) => {
+				let _ = run.cancel().await;
+				Err(e)
+			}
+		}
+	}
+}
+
+/// Describe the identity which is running the current statement
+pub(super) fn identity(opt: &Options, session: &Value) -> Value {
+	// Get the authentication details
+	let (level, ns, db, sc) = match opt.auth.as_ref() {
+		Auth::No => ("NO", None, None, None),
+		Auth::Kv => ("KV", None, None, None),
+		Auth::Ns(ns) => ("NS", Some(ns.to_owned()), None, None),
+		Auth::Db(ns, db) => ("DB", Some(ns.to_owned()), Some(db.to_owned()), None),
+		Auth::Sc(ns, db, sc) => {
+			("SC", Some(ns.to_owned()), Some(db.to_owned()), Some(sc.to_owned()))
+		}
+	};
+	Value::from(map! {
+		"level".to_string() => Value::from(level),
+		"ns".to_string() => Value::from(ns),
+		"db".to_string() => Value::from(db),
+		"sc".to_string() => Value::from(sc),
+		"sd".to_string() => session.pick(SD.as_ref()),
+	})
+}
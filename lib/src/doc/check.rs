@@ -11,10 +11,22 @@ impl<'a> Document<'a> {
 		opt: &Options,
 		stm: &Statement<'_>,
 	) -> Result<(), Error> {
+		// Hide soft deleted records, unless requested
+		if self.is_deleted() && !opt.purge && !stm.deleted() {
+			// Clone transaction
+			let txn = ctx.clone_transaction()?;
+			// Check if the table keeps deleted records
+			if self.tb(opt, &txn).await?.soft.is_some() {
+				// Ignore this document
+				return Err(Error::Ignore);
+			}
+		}
 		// Check where condition
 		if let Some(cond) = stm.conds() {
+			// Hide the fields which can not be selected
+			let current = self.reduced(ctx, opt, &self.current).await?;
 			let mut ctx = Context::new(ctx);
-			ctx.add_cursor_doc(&self.current);
+			ctx.add_cursor_doc(&current);
 			// Check if the expression is truthy
 			if !cond.compute(&ctx, opt).await?.is_truthy() {
 				// Ignore this document
@@ -8,9 +8,11 @@ use crate::err::Error;
 use crate::sql::thing::Thing;
 use crate::sql::value::Value;
 use channel::Sender;
+use tracing::instrument;
 
 impl<'a> Document<'a> {
 	#[allow(dead_code)]
+	#[instrument(name = "document", level = "debug", skip_all)]
 	pub(crate) async fn compute(
 		ctx: &Context<'_>,
 		opt: &Options,
@@ -16,12 +16,28 @@ impl<'a> Document<'a> {
 		self.check(ctx, opt, stm).await?;
 		// Check if allowed
 		self.allow(ctx, opt, stm).await?;
-		// Erase document
-		self.erase(ctx, opt, stm).await?;
-		// Purge index data
-		self.index(ctx, opt, stm).await?;
-		// Purge record data
-		self.purge(ctx, opt, stm).await?;
+		// Check if the table keeps deleted records
+		if self.tombstone(ctx, opt, stm).await? {
+			// Update index data
+			self.index(ctx, opt, stm).await?;
+			// Update partition data
+			self.partition(ctx, opt, stm).await?;
+			// Store record data
+			self.store(ctx, opt, stm).await?;
+		} else {
+			// Erase document
+			self.erase(ctx, opt, stm).await?;
+			// Purge index data
+			self.index(ctx, opt, stm).await?;
+			// Purge partition data
+			self.partition(ctx, opt, stm).await?;
+			// Purge record data
+			self.purge(ctx, opt, stm).await?;
+		}
+		// Record audit entry
+		self.audit(ctx, opt, stm).await?;
+		// Record previous version
+		self.history(ctx, opt, stm).await?;
 		// Run table queries
 		self.table(ctx, opt, stm).await?;
 		// Run lives queries
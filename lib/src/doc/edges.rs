@@ -31,6 +31,21 @@ impl<'a> Document<'a> {
 		if let Workable::Relate(l, r) = &self.extras {
 			// Get temporary edge references
 			let (ref o, ref i) = (Dir::Out, Dir::In);
+			// Purge the edges of any previous relation of the record
+			if let (Value::Bool(true), Value::Thing(ref pl), Value::Thing(ref pr)) =
+				(self.initial.pick(&*EDGE), self.initial.pick(&*IN), self.initial.pick(&*OUT))
+			{
+				if pl != l || pr != r {
+					let key = crate::key::graph::new(opt.ns(), opt.db(), &pl.tb, &pl.id, o, rid);
+					run.del(key).await?;
+					let key = crate::key::graph::new(opt.ns(), opt.db(), &rid.tb, &rid.id, i, pl);
+					run.del(key).await?;
+					let key = crate::key::graph::new(opt.ns(), opt.db(), &rid.tb, &rid.id, o, pr);
+					run.del(key).await?;
+					let key = crate::key::graph::new(opt.ns(), opt.db(), &pr.tb, &pr.id, i, rid);
+					run.del(key).await?;
+				}
+			}
 			// Store the left pointer edge
 			let key = crate::key::graph::new(opt.ns(), opt.db(), &l.tb, &l.id, o, rid);
 			run.set(key, vec![]).await?;
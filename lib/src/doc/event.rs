@@ -1,3 +1,4 @@
+use crate::ctx::sandbox::Kind;
 use crate::ctx::Context;
 use crate::dbs::Options;
 use crate::dbs::Statement;
@@ -42,14 +43,23 @@ impl<'a> Document<'a> {
 			ctx.add_value("after", self.current.deref());
 			ctx.add_value("before", self.initial.deref());
 			ctx.add_cursor_doc(&self.current);
-			// Process conditional clause
-			let val = ev.when.compute(&ctx, opt).await?;
-			// Execute event if value is truthy
-			if val.is_truthy() {
-				for v in ev.then.iter() {
-					v.compute(&ctx, opt).await?;
+			// Limit the resources used by the event
+			let sandbox = ctx.add_sandbox(Kind::Event, ev.name.to_raw());
+			// Run the event within the sandbox
+			let res = async {
+				// Process conditional clause
+				let val = ev.when.compute(&ctx, opt).await?;
+				// Execute event if value is truthy
+				if val.is_truthy() {
+					for v in ev.then.iter() {
+						v.compute(&ctx, opt).await?;
+					}
 				}
+				Ok(())
 			}
+			.await;
+			// Check the sandbox limits
+			sandbox.finish(res)?;
 		}
 		// Carry on
 		Ok(())
@@ -23,6 +23,8 @@ impl<'a> Document<'a> {
 		let inp = self.initial.changed(self.current.as_ref());
 		// Clone transaction
 		let txn = ctx.clone_transaction()?;
+		// The fields which did not pass their ASSERT clause
+		let mut failed = Vec::new();
 		// Loop through all field statements
 		for fd in self.fd(opt, &txn).await?.iter() {
 			// Loop over each field in document
@@ -31,6 +33,14 @@ impl<'a> Document<'a> {
 				let old = self.initial.pick(&k);
 				// Get the input value
 				let inp = inp.pick(&k);
+				// Check that a computed field was not set
+				if fd.computed && !inp.is_none() {
+					return Err(Error::FieldComputed {
+						thing: rid.to_string(),
+						value: inp.to_string(),
+						field: fd.name.clone(),
+					});
+				}
 				// Check for a TYPE clause
 				if let Some(kind) = &fd.kind {
 					if !val.is_none() {
@@ -88,13 +98,20 @@ impl<'a> Document<'a> {
 					ctx.add_value("after", &val);
 					ctx.add_value("before", &old);
 					ctx.add_cursor_doc(&self.current);
-					// Process the ASSERT clause
+					// Process the ASSERT clause, and check every other field
 					if !expr.compute(&ctx, opt).await?.is_truthy() {
-						return Err(Error::FieldValue {
-							thing: rid.to_string(),
-							field: fd.name.clone(),
-							value: val.to_string(),
-							check: expr.to_string(),
+						failed.push(match &fd.message {
+							Some(message) => Error::FieldMessage {
+								thing: rid.to_string(),
+								field: fd.name.clone(),
+								message: message.as_str().to_owned(),
+							},
+							None => Error::FieldValue {
+								thing: rid.to_string(),
+								field: fd.name.clone(),
+								value: val.to_string(),
+								check: expr.to_string(),
+							},
 						});
 					}
 				}
@@ -134,7 +151,14 @@ impl<'a> Document<'a> {
 				};
 			}
 		}
-		// Carry on
-		Ok(())
+		// Check if any fields did not pass their ASSERT clause
+		match failed.len() {
+			0 => Ok(()),
+			1 => Err(failed.remove(0)),
+			_ => Err(Error::FieldAsserts {
+				thing: rid.to_string(),
+				errors: failed.iter().map(|e| e.to_string()).collect(),
+			}),
+		}
 	}
 }
@@ -0,0 +1,71 @@
+use crate::ctx::Context;
+use crate::dbs::Options;
+use crate::dbs::Statement;
+use crate::doc::audit::identity;
+use crate::doc::Document;
+use crate::err::Error;
+use crate::sql::datetime::Datetime;
+use crate::sql::id::Id;
+use crate::sql::thing::Thing;
+use crate::sql::value::Value;
+
+impl<'a> Document<'a> {
+	pub async fn history(
+		&self,
+		ctx: &Context<'_>,
+		opt: &Options,
+		stm: &Statement<'_>,
+	) -> Result<(), Error> {
+		// Check if this record exists
+		let rid = match self.id {
+			Some(rid) => rid,
+			None => return Ok(()),
+		};
+		// Imported records already have their history
+		if opt.import {
+			return Ok(());
+		}
+		// Only changes to existing records have a previous version
+		if self.is_new() || !self.changed() {
+			return Ok(());
+		}
+		// Clone transaction
+		let txn = ctx.clone_transaction()?;
+		// Check if the table keeps a history
+		if !self.tb(opt, &txn).await?.history {
+			return Ok(());
+		}
+		// Get the changed action
+		let action = match stm.is_delete() {
+			true => "DELETE",
+			false => "UPDATE",
+		};
+		// Get the session details
+		let session = ctx.value("session").unwrap_or(&Value::None);
+		// Generate the history entry id
+		let id = Thing {
+			tb: format!("{}_history", rid.tb),
+			id: Id::ulid(),
+		};
+		// Create the history entry
+		let entry = Value::from(map! {
+			"id".to_string() => Value::from(id.clone()),
+			"action".to_string() => Value::from(action),
+			"at".to_string() => Value::from(Datetime::default()),
+			"auth".to_string() => identity(opt, session),
+			"record".to_string() => Value::from(rid.clone()),
+			"version".to_string() => self.initial.as_ref().clone(),
+		});
+		// Claim transaction
+		let mut run = txn.lock().await;
+		// Ensure the history table exists
+		run.add_tb(opt.ns(), opt.db(), &id.tb, opt.strict).await?;
+		// Store the history entry
+		let key = crate::key::thing::new(opt.ns(), opt.db(), &id.tb, &id.id);
+		run.set(key, entry).await?;
+		// Count the history entry
+		run.add_cn(opt.ns(), opt.db(), &id.tb, 1).await?;
+		// Carry on
+		Ok(())
+	}
+}
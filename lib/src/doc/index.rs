@@ -3,10 +3,12 @@ use crate::dbs::Options;
 use crate::dbs::Statement;
 use crate::doc::Document;
 use crate::err::Error;
+use crate::idx::build::Build;
 use crate::idx::ft::FtIndex;
+use crate::idx::hnsw::HnswIndex;
 use crate::idx::IndexKeyBase;
 use crate::sql::array::Array;
-use crate::sql::index::Index;
+use crate::sql::index::{Distance, Index};
 use crate::sql::scoring::Scoring;
 use crate::sql::statements::DefineIndexStatement;
 use crate::sql::{Ident, Thing, Value};
@@ -35,8 +37,16 @@ impl<'a> Document<'a> {
 		}
 		// Get the record id
 		let rid = self.id.as_ref().unwrap();
+		// Get any indexes which are being built in the background
+		let builds = Build::all(&mut *txn.lock().await, opt.ns(), opt.db(), &rid.tb).await?;
 		// Loop through all index statements
 		for ix in self.ix(opt, &txn).await?.iter() {
+			// Skip any index whose build has not reached this record yet
+			if let Some(build) = builds.get(ix.name.as_str()) {
+				if !build.covers(opt, rid) {
+					continue;
+				}
+			}
 			// Calculate old values
 			let o = Self::build_opt_array(ctx, opt, ix, &self.initial).await?;
 
@@ -52,32 +62,37 @@ impl<'a> Document<'a> {
 				let mut ic = IndexOperation::new(opt, ix, o, n, rid);
 
 				// Index operation dispatching
-				match &ix.index {
-					Index::Uniq => ic.index_unique(&mut run).await?,
-					Index::Idx => ic.index_non_unique(&mut run).await?,
-					Index::Search {
-						az,
-						sc,
-						hl,
-						order,
-					} => match sc {
-						Scoring::Bm {
-							..
-						} => ic.index_best_matching_search(&mut run, az, *order, *hl).await?,
-						Scoring::Vs => ic.index_vector_search(az, *hl).await?,
-					},
-				};
+				ic.compute(&mut run).await?;
 			}
 		}
 		// Carry on
 		Ok(())
 	}
 
+	/// Add the entries of a record to an index which is being built in the
+	/// background, and which does not have any entries for the record yet
+	pub(crate) async fn build_index(
+		ctx: &Context<'_>,
+		opt: &Options,
+		ix: &DefineIndexStatement,
+		rid: &Thing,
+		val: &Value,
+	) -> Result<(), Error> {
+		// Calculate new values
+		let n = Self::build_opt_array(ctx, opt, ix, val).await?;
+		// Clone transaction
+		let txn = ctx.clone_transaction()?;
+		// Claim transaction
+		let mut run = txn.lock().await;
+		// Add the index entries
+		IndexOperation::new(opt, ix, None, n, rid).compute(&mut run).await
+	}
+
 	/// Extract from the given document, the values required by the index and put then in an array.
 	/// Eg. IF the index is composed of the columns `name` and `instrument`
 	/// Given this doc: { "id": 1, "instrument":"piano", "name":"Tobie" }
 	/// It will return: ["Tobie", "piano"]
-	async fn build_opt_array(
+	pub(crate) async fn build_opt_array(
 		ctx: &Context<'_>,
 		opt: &Options,
 		ix: &DefineIndexStatement,
@@ -124,13 +139,45 @@ impl<'a> IndexOperation<'a> {
 		}
 	}
 
+	/// Update the index entries, depending on the type of index
+	async fn compute(&mut self, run: &mut kvs::Transaction) -> Result<(), Error> {
+		let ix = self.ix;
+		match &ix.index {
+			Index::Uniq => {
+				self.index_unique(run).await?;
+				self.index_elements(run).await
+			}
+			Index::Idx => {
+				self.index_non_unique(run).await?;
+				self.index_elements(run).await
+			}
+			Index::Search {
+				az,
+				sc,
+				hl,
+				order,
+			} => match sc {
+				Scoring::Bm {
+					..
+				} => self.index_best_matching_search(run, az, *order, *hl).await,
+				Scoring::Vs => self.index_vector_search(az, *hl).await,
+			},
+			Index::Hnsw {
+				dimension,
+				dist,
+				m,
+				efc,
+			} => self.index_hnsw(run, *dimension, *dist, *m, *efc).await,
+		}
+	}
+
 	fn get_non_unique_index_key(&self, v: &Array) -> key::index::Index {
 		key::index::new(
 			self.opt.ns(),
 			self.opt.db(),
 			&self.ix.what,
 			&self.ix.name,
-			v,
+			&self.ix.collated(v.clone()),
 			Some(&self.rid.id),
 		)
 	}
@@ -152,7 +199,8 @@ impl<'a> IndexOperation<'a> {
 	}
 
 	fn get_unique_index_key(&self, v: &Array) -> key::index::Index {
-		key::index::new(self.opt.ns(), self.opt.db(), &self.ix.what, &self.ix.name, v, None)
+		let v = self.ix.collated(v.clone());
+		key::index::new(self.opt.ns(), self.opt.db(), &self.ix.what, &self.ix.name, &v, None)
 	}
 
 	async fn index_unique(&self, run: &mut kvs::Transaction) -> Result<(), Error> {
@@ -171,6 +219,64 @@ impl<'a> IndexOperation<'a> {
 		Ok(())
 	}
 
+	fn get_element_index_key(&self, v: &Array) -> key::ie::Ie {
+		let v = self.ix.collated(v.clone());
+		key::ie::new(self.opt.ns(), self.opt.db(), &self.ix.what, &self.ix.name, &v, &self.rid.id)
+	}
+
+	/// Eg. IF the index is composed of the columns `tags` and `lang`
+	/// Given these values: [["rust", "db"], "en"]
+	/// It will return: [["rust", "en"], ["db", "en"]]
+	fn elements(v: &Array) -> Vec<Array> {
+		// Only the values which contain an array have element entries
+		if !v.iter().any(Value::is_array) {
+			return vec![];
+		}
+		let mut out = vec![Array::with_capacity(v.len())];
+		for v in v.iter() {
+			let vals = match v {
+				Value::Array(v) => v.as_slice(),
+				v => std::slice::from_ref(v),
+			};
+			out = out
+				.iter()
+				.flat_map(|o| {
+					vals.iter().map(move |v| {
+						let mut o = o.clone();
+						o.push(v.clone());
+						o
+					})
+				})
+				.collect();
+		}
+		// An element which is repeated in an array has a single entry
+		let mut res: Vec<Array> = Vec::with_capacity(out.len());
+		for v in out {
+			if !res.contains(&v) {
+				res.push(v);
+			}
+		}
+		res
+	}
+
+	/// Add an entry for each element of an indexed array, so that records
+	/// can be found with the CONTAINS and INSIDE operators on the index
+	async fn index_elements(&self, run: &mut kvs::Transaction) -> Result<(), Error> {
+		// Delete the old element entries
+		if let Some(o) = &self.o {
+			for v in Self::elements(o) {
+				run.del(self.get_element_index_key(&v)).await?;
+			}
+		}
+		// Create the new element entries
+		if let Some(n) = &self.n {
+			for v in Self::elements(n) {
+				run.set(self.get_element_index_key(&v), self.rid).await?;
+			}
+		}
+		Ok(())
+	}
+
 	fn err_index_exists(&self, n: &Array) -> Result<(), Error> {
 		Err(Error::IndexExists {
 			thing: self.rid.to_string(),
@@ -200,6 +306,23 @@ impl<'a> IndexOperation<'a> {
 		}
 	}
 
+	async fn index_hnsw(
+		&self,
+		run: &mut kvs::Transaction,
+		dimension: u32,
+		dist: Distance,
+		m: u32,
+		efc: u32,
+	) -> Result<(), Error> {
+		let ikb = IndexKeyBase::new(self.opt, self.ix);
+		let mut hnsw = HnswIndex::new(run, ikb, dimension, dist, m, efc).await?;
+		if let Some(n) = &self.n {
+			hnsw.index_document(run, self.rid, n).await
+		} else {
+			hnsw.remove_document(run, self.rid).await
+		}
+	}
+
 	async fn index_vector_search(&mut self, _az: &Ident, _hl: bool) -> Result<(), Error> {
 		Err(Error::FeatureNotYetImplemented {
 			feature: "VectorSearch indexing",
@@ -3,40 +3,66 @@ use crate::dbs::Options;
 use crate::dbs::Statement;
 use crate::doc::Document;
 use crate::err::Error;
+use crate::sql::value::Value;
 
 impl<'a> Document<'a> {
 	pub async fn lives(
 		&self,
 		ctx: &Context<'_>,
 		opt: &Options,
-		_stm: &Statement<'_>,
+		stm: &Statement<'_>,
 	) -> Result<(), Error> {
 		// Check if forced
 		if !opt.force && !self.changed() {
 			return Ok(());
 		}
+		// Invalidate any cached results of the table
+		let rid = self.id.as_ref().unwrap();
+		ctx.clone_transaction()?.lock().await.invalidate(opt.ns(), opt.db(), &rid.tb);
+		// The changes of a dry-run are rolled back
+		if opt.dry {
+			return Ok(());
+		}
+		// Check if any session receives notifications
+		let reg = match ctx.registry() {
+			Some(v) => v,
+			None => return Ok(()),
+		};
 		// Clone transaction
 		let txn = ctx.clone_transaction()?;
-		// Get the record id
-		let _ = self.id.as_ref().unwrap();
-		// Loop through all index statements
+		// Loop through all live query statements
 		for lv in self.lv(opt, &txn).await?.iter() {
+			// Get the channel of the session which started the live query
+			let chn = match reg.notifier(&lv.id.0) {
+				Some(v) => v,
+				None => continue,
+			};
 			// Create a new statement
-			let stm = Statement::from(lv);
-			// Check LIVE SELECT where condition
-			if self.check(ctx, opt, &stm).await.is_err() {
-				continue;
-			}
+			let lq = Statement::from(lv);
 			// Check what type of data change this is
-			if stm.is_delete() {
-				// Send a DELETE notification to the WebSocket
-			} else if self.is_new() {
-				// Process the CREATE notification to send
-				let _ = self.pluck(ctx, opt, &stm).await?;
+			let (action, result) = if stm.is_delete() {
+				// Send a DELETE notification with the record id
+				("DELETE", Value::from((*rid).clone()))
 			} else {
-				// Process the CREATE notification to send
-				let _ = self.pluck(ctx, opt, &stm).await?;
+				// Check LIVE SELECT where condition
+				if self.check(ctx, opt, &lq).await.is_err() {
+					continue;
+				}
+				// Process the CREATE or UPDATE notification to send
+				let action = match self.is_new() {
+					true => "CREATE",
+					false => "UPDATE",
+				};
+				(action, self.pluck(ctx, opt, &lq).await?)
 			};
+			// Send the notification once the transaction commits
+			let msg = Value::from(map! {
+				String::from("id") => Value::from(lv.id.clone()),
+				String::from("action") => Value::from(action),
+				String::from("result") => result,
+			});
+			let mut run = txn.lock().await;
+			run.notify(chn, opt.ns(), opt.db(), rid, &self.initial, &self.current, msg);
 		}
 		// Carry on
 		Ok(())
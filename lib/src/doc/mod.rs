@@ -4,6 +4,7 @@
 //! - `current`: value after the transaction
 //! - `initial`: value before the transaction
 //! - `id`: traditionally an integer but can be an object or collection such as an array
+pub(crate) use self::audit::Reads;
 pub(crate) use self::document::*;
 
 #[cfg(not(target_arch = "wasm32"))]
@@ -20,6 +21,7 @@ mod update; // Processes a UPDATE statement for this document
 
 mod allow; // Checks whether the query can access this document
 mod alter; // Modifies and updates the fields in this document
+mod audit; // Records audit entries for any access to this document
 mod check; // Checks whether the WHERE clauses matches this document
 mod clean; // Ensures records adhere to the table schema
 mod edges; // Attempts to store the edge data for this document
@@ -28,11 +30,18 @@ mod erase; // Removes all content and field data for this document
 mod event; // Processes any table events relevant for this document
 mod exist; // Checks whether the specified document actually exists
 mod field; // Processes any schema-defined fields for this document
+mod history; // Records the previous version of this document in its history table
 mod index; // Attempts to store the index data for this document
 mod lives; // Processes any live queries relevant for this document
 mod merge; // Merges any field changes for an INSERT statement
+mod partition; // Stores the partition of this document, for partitioned tables
 mod pluck; // Pulls the projected expressions from the document
 mod purge; // Deletes this document, and any edges or indexes
+mod reduce; // Hides the fields which can not be selected from this document
+mod relation; // Checks whether this document can be stored in a relation table
 mod reset; // Resets internal fields which were set for this document
+mod schema; // Validates this document against the JSON Schema of the table
 mod store; // Writes the document content to the storage engine
 mod table; // Processes any foreign tables relevant for this document
+mod tombstone; // Marks this document as deleted, for tables which keep deleted records
+mod unique; // Checks whether the document content was recently created
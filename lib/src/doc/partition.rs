@@ -0,0 +1,118 @@
+use crate::ctx::Context;
+use crate::dbs::Options;
+use crate::dbs::Statement;
+use crate::doc::Document;
+use crate::err::Error;
+use crate::key;
+use crate::sql::array::Array;
+use crate::sql::statements::DefineTableStatement;
+use crate::sql::{Thing, Value};
+
+impl<'a> Document<'a> {
+	pub async fn partition(
+		&self,
+		ctx: &Context<'_>,
+		opt: &Options,
+		_stm: &Statement<'_>,
+	) -> Result<(), Error> {
+		// Check if forced
+		if !opt.force && !self.changed() {
+			return Ok(());
+		}
+		// Clone transaction
+		let txn = ctx.clone_transaction()?;
+		// Get the table definition
+		let tb = self.tb(opt, &txn).await?;
+		// Check if the table is a view
+		if tb.drop {
+			return Ok(());
+		}
+		// Check if the table is partitioned
+		let expr = match &tb.partition {
+			Some(v) => v,
+			None => return Ok(()),
+		};
+		// Get the record id
+		let rid = self.id.as_ref().unwrap();
+		// Calculate the old and new partitions
+		let o = Self::partition_of(ctx, opt, expr, &self.initial).await?;
+		let n = Self::partition_of(ctx, opt, expr, &self.current).await?;
+		// Move the record to its new partition
+		if opt.force || o != n {
+			// Claim transaction
+			let mut run = txn.lock().await;
+			// Remove the record from its old partition
+			if let Some(o) = &o {
+				let key = key::pt::new(opt.ns(), opt.db(), &rid.tb, o, &rid.id);
+				run.del(key).await?;
+			}
+			// Add the record to its new partition
+			if let Some(n) = &n {
+				let key = key::pt::new(opt.ns(), opt.db(), &rid.tb, n, &rid.id);
+				run.set(key, rid).await?;
+			}
+		}
+		// Carry on
+		Ok(())
+	}
+
+	/// Calculate the partition of a record, from the partition expression
+	/// of its table
+	pub(crate) async fn partition_of(
+		ctx: &Context<'_>,
+		opt: &Options,
+		expr: &Value,
+		value: &Value,
+	) -> Result<Option<Array>, Error> {
+		if !value.is_some() {
+			return Ok(None);
+		}
+		let mut ctx = Context::new(ctx);
+		ctx.add_cursor_doc(value);
+		let v = expr.compute(&ctx, opt).await?;
+		Ok(Some(Array::from(vec![v])))
+	}
+
+	/// Store the partition of every record in a table, which is done when
+	/// the partition expression of the table is defined or changed
+	pub(crate) async fn repartition(
+		ctx: &Context<'_>,
+		opt: &Options,
+		tb: &DefineTableStatement,
+	) -> Result<(), Error> {
+		// Clone transaction
+		let txn = ctx.clone_transaction()?;
+		// Remove the previous partitions of the records
+		let beg = key::pt::prefix(opt.ns(), opt.db(), &tb.name);
+		let end = key::pt::suffix(opt.ns(), opt.db(), &tb.name);
+		txn.lock().await.delr(beg..end, u32::MAX).await?;
+		// Check if the table is partitioned
+		let expr = match &tb.partition {
+			Some(v) => v,
+			None => return Ok(()),
+		};
+		// Partition each record in the table
+		let mut beg = key::thing::prefix(opt.ns(), opt.db(), &tb.name);
+		let end = key::thing::suffix(opt.ns(), opt.db(), &tb.name);
+		loop {
+			let res = txn.lock().await.scan(beg.clone()..end.clone(), 1000).await?;
+			// Exit when settled
+			let last = match res.last() {
+				Some((k, _)) => k.clone(),
+				None => break,
+			};
+			for (k, v) in res.into_iter() {
+				let key = key::thing::Thing::decode(&k)?;
+				let rid = Thing::from((key.tb, key.id));
+				if let Some(pt) = Self::partition_of(ctx, opt, expr, &Value::from(v)).await? {
+					let key = key::pt::new(opt.ns(), opt.db(), &tb.name, &pt, &rid.id);
+					txn.lock().await.set(key, &rid).await?;
+				}
+			}
+			// Continue after the last record
+			beg = last;
+			beg.push(0x00);
+		}
+		Ok(())
+	}
+}
@@ -6,7 +6,6 @@ use crate::err::Error;
 use crate::sql::idiom::Idiom;
 use crate::sql::output::Output;
 use crate::sql::paths::META;
-use crate::sql::permission::Permission;
 use crate::sql::value::Value;
 
 impl<'a> Document<'a> {
@@ -18,98 +17,83 @@ impl<'a> Document<'a> {
 	) -> Result<Value, Error> {
 		// Ensure futures are run
 		let opt = &opt.futures(true);
+		// Hide the fields which can not be selected
+		let current = self.reduced(ctx, opt, &self.current).await?;
+		// Return the changes which a dry-run would have made
+		if opt.dry && !stm.is_select() && !matches!(stm, Statement::Live(_)) {
+			let rid = self.id.as_ref().unwrap();
+			let initial = self.reduced(ctx, opt, &self.initial).await?;
+			return Ok(Value::from(map! {
+				String::from("id") => Value::from((*rid).clone()),
+				String::from("diff") => initial.diff(&current, Idiom::default()).into(),
+			}));
+		}
 		// Process the desired output
 		let mut out = match stm.output() {
 			Some(v) => match v {
-				Output::None => Err(Error::Ignore),
+				Output::None => Err(Error::Omit),
 				Output::Null => Ok(Value::Null),
-				Output::Diff => Ok(self.initial.diff(&self.current, Idiom::default()).into()),
+				Output::Diff => {
+					let initial = self.reduced(ctx, opt, &self.initial).await?;
+					Ok(initial.diff(&current, Idiom::default()).into())
+				}
 				Output::After => {
 					let mut ctx = Context::new(ctx);
-					ctx.add_cursor_doc(&self.current);
-					self.current.compute(&ctx, opt).await
+					ctx.add_cursor_doc(&current);
+					current.compute(&ctx, opt).await
 				}
 				Output::Before => {
+					let initial = self.reduced(ctx, opt, &self.initial).await?;
 					let mut ctx = Context::new(ctx);
-					ctx.add_cursor_doc(&self.initial);
-					self.initial.compute(&ctx, opt).await
+					ctx.add_cursor_doc(&initial);
+					initial.compute(&ctx, opt).await
 				}
 				Output::Fields(v) => {
 					let mut ctx = Context::new(ctx);
-					ctx.add_cursor_doc(&self.current);
+					ctx.add_cursor_doc(&current);
 					v.compute(&ctx, opt, false).await
 				}
 			},
 			None => match stm {
 				Statement::Live(s) => match s.expr.len() {
-					0 => Ok(self.initial.diff(&self.current, Idiom::default()).into()),
+					0 => {
+						let initial = self.reduced(ctx, opt, &self.initial).await?;
+						Ok(initial.diff(&current, Idiom::default()).into())
+					}
 					_ => {
 						let mut ctx = Context::new(ctx);
-						ctx.add_cursor_doc(&self.current);
+						ctx.add_cursor_doc(&current);
 						s.expr.compute(&ctx, opt, false).await
 					}
 				},
 				Statement::Select(s) => {
 					let mut ctx = Context::new(ctx);
-					ctx.add_cursor_doc(&self.current);
+					ctx.add_cursor_doc(&current);
 					s.expr.compute(&ctx, opt, s.group.is_some()).await
 				}
 				Statement::Create(_) => {
 					let mut ctx = Context::new(ctx);
-					ctx.add_cursor_doc(&self.current);
-					self.current.compute(&ctx, opt).await
+					ctx.add_cursor_doc(&current);
+					current.compute(&ctx, opt).await
 				}
 				Statement::Update(_) => {
 					let mut ctx = Context::new(ctx);
-					ctx.add_cursor_doc(&self.current);
-					self.current.compute(&ctx, opt).await
+					ctx.add_cursor_doc(&current);
+					current.compute(&ctx, opt).await
 				}
 				Statement::Relate(_) => {
 					let mut ctx = Context::new(ctx);
-					ctx.add_cursor_doc(&self.current);
-					self.current.compute(&ctx, opt).await
+					ctx.add_cursor_doc(&current);
+					current.compute(&ctx, opt).await
 				}
 				Statement::Insert(_) => {
 					let mut ctx = Context::new(ctx);
-					ctx.add_cursor_doc(&self.current);
-					self.current.compute(&ctx, opt).await
+					ctx.add_cursor_doc(&current);
+					current.compute(&ctx, opt).await
 				}
-				_ => Err(Error::Ignore),
+				Statement::Delete(_) => Err(Error::Omit),
 			},
 		}?;
-		// Check if this record exists
-		if self.id.is_some() {
-			// Should we run permissions checks?
-			if opt.perms && opt.auth.perms() {
-				// Clone transaction
-				let txn = ctx.clone_transaction()?;
-				// Loop through all field statements
-				for fd in self.fd(opt, &txn).await?.iter() {
-					// Loop over each field in document
-					for k in out.each(&fd.name).iter() {
-						// Process the field permissions
-						match &fd.permissions.select {
-							Permission::Full => (),
-							Permission::None => out.del(ctx, opt, k).await?,
-							Permission::Specific(e) => {
-								// Disable permissions
-								let opt = &opt.perms(false);
-								// Get the current value
-								let val = self.current.pick(k);
-								// Configure the context
-								let mut ctx = Context::new(ctx);
-								ctx.add_value("value", &val);
-								ctx.add_cursor_doc(&self.current);
-								// Process the PERMISSION clause
-								if !e.compute(&ctx, opt).await?.is_truthy() {
-									out.del(&ctx, opt, k).await?
-								}
-							}
-						}
-					}
-				}
-			}
-		}
 		// Remove metadata fields on output
 		out.del(ctx, opt, &*META).await?;
 		// Output result
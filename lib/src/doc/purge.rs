@@ -5,6 +5,7 @@ use crate::doc::Document;
 use crate::err::Error;
 use crate::sql::dir::Dir;
 use crate::sql::edges::Edges;
+use crate::sql::paths::DELETED;
 use crate::sql::paths::EDGE;
 use crate::sql::paths::IN;
 use crate::sql::paths::OUT;
@@ -25,19 +26,34 @@ impl<'a> Document<'a> {
 		}
 		// Clone transaction
 		let txn = ctx.clone_transaction()?;
+		// Get the table definition
+		let tb = self.tb(opt, &txn).await?;
 		// Check if the table is a view
-		if self.tb(opt, &txn).await?.drop {
+		if tb.drop {
 			return Ok(());
 		}
+		// Remove the content hash of the record
+		if self.id.is_some() && !self.is_new() {
+			self.unique_purge(ctx, opt).await?;
+		}
 		// Clone transaction
 		let run = txn.clone();
 		// Claim transaction
 		let mut run = run.lock().await;
 		// Get the record id
 		if let Some(rid) = self.id {
-			// Purge the record data
-			let key = crate::key::thing::new(opt.ns(), opt.db(), &rid.tb, &rid.id);
-			run.del(key).await?;
+			// Purge the record data, and any of its chunks
+			let bytes = run.del_record(opt.ns(), opt.db(), rid).await?;
+			// Uncount the record if it existed, unless it was
+			// already uncounted when it was soft deleted
+			if !self.is_new() {
+				if tb.soft.is_none() || !self.initial.pick(&*DELETED).is_datetime() {
+					run.add_cn(opt.ns(), opt.db(), &rid.tb, -1).await?;
+				}
+				run.add_by(opt.ns(), opt.db(), &rid.tb, -bytes).await?;
+			}
+			// Record the change to the record
+			run.record_change(opt.ns(), opt.db(), rid, &self.initial, &Value::None);
 			// Purge the record edges
 			match (self.initial.pick(&*EDGE), self.initial.pick(&*IN), self.initial.pick(&*OUT)) {
 				(Value::Bool(true), Value::Thing(ref l), Value::Thing(ref r)) => {
@@ -68,8 +84,9 @@ impl<'a> Document<'a> {
 						})]),
 						..DeleteStatement::default()
 					};
-					// Execute the delete statement
-					stm.compute(ctx, opt).await?;
+					// Delete the edges even if they can not be deleted by the user,
+					// so that no edge is left pointing at a record which is gone
+					stm.compute(ctx, &opt.perms(false)).await?;
 				}
 			}
 		}
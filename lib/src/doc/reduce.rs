@@ -0,0 +1,61 @@
+use crate::ctx::Context;
+use crate::dbs::Options;
+use crate::doc::Document;
+use crate::err::Error;
+use crate::sql::permission::Permission;
+use crate::sql::value::Value;
+use std::borrow::Cow;
+
+impl<'a> Document<'a> {
+	/// Get a version of the document without the fields which can not be
+	/// selected by the current user. Projections and conditions are computed
+	/// on this version, so that expressions, aliases, and WHERE clauses can
+	/// not reveal the values of fields which are hidden from the user.
+	pub async fn reduced<'b>(
+		&self,
+		ctx: &Context<'_>,
+		opt: &Options,
+		doc: &'b Value,
+	) -> Result<Cow<'b, Value>, Error> {
+		// Check if this record exists
+		if self.id.is_none() {
+			return Ok(Cow::Borrowed(doc));
+		}
+		// Should we run permissions checks?
+		if !opt.perms || !opt.auth.perms() {
+			return Ok(Cow::Borrowed(doc));
+		}
+		// Clone transaction
+		let txn = ctx.clone_transaction()?;
+		// Loop through all field statements
+		let mut out = Cow::Borrowed(doc);
+		for fd in self.fd(opt, &txn).await?.iter() {
+			// Loop over each field in document
+			for k in doc.each(&fd.name).iter() {
+				// Process the field permissions
+				let allowed = match &fd.permissions.select {
+					Permission::Full => true,
+					Permission::None => false,
+					Permission::Specific(e) => {
+						// Disable permissions
+						let opt = &opt.perms(false);
+						// Get the current value
+						let val = doc.pick(k);
+						// Configure the context
+						let mut ctx = Context::new(ctx);
+						ctx.add_value("value", &val);
+						ctx.add_cursor_doc(doc);
+						// Process the PERMISSION clause
+						e.compute(&ctx, opt).await?.is_truthy()
+					}
+				};
+				// Remove the field if it can not be selected
+				if !allowed {
+					out.to_mut().cut(k);
+				}
+			}
+		}
+		// Output the reduced document
+		Ok(out)
+	}
+}
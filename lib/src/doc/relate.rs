@@ -22,14 +22,24 @@ impl<'a> Document<'a> {
 		self.reset(ctx, opt, stm).await?;
 		// Clean fields data
 		self.clean(ctx, opt, stm).await?;
+		// Check table schema
+		self.schema(ctx, opt, stm).await?;
+		// Check relation table
+		self.relation(ctx, opt, stm).await?;
 		// Check if allowed
 		self.allow(ctx, opt, stm).await?;
 		// Store record edges
 		self.edges(ctx, opt, stm).await?;
 		// Store index data
 		self.index(ctx, opt, stm).await?;
+		// Store partition data
+		self.partition(ctx, opt, stm).await?;
 		// Store record data
 		self.store(ctx, opt, stm).await?;
+		// Record audit entry
+		self.audit(ctx, opt, stm).await?;
+		// Record previous version
+		self.history(ctx, opt, stm).await?;
 		// Run table queries
 		self.table(ctx, opt, stm).await?;
 		// Run lives queries
@@ -0,0 +1,61 @@
+use crate::ctx::Context;
+use crate::dbs::Options;
+use crate::dbs::Statement;
+use crate::dbs::Workable;
+use crate::doc::Document;
+use crate::err::Error;
+use crate::sql::paths::EDGE;
+use crate::sql::table::Tables;
+use crate::sql::thing::Thing;
+
+impl<'a> Document<'a> {
+	pub async fn relation(
+		&self,
+		ctx: &Context<'_>,
+		opt: &Options,
+		_stm: &Statement<'_>,
+	) -> Result<(), Error> {
+		// Imported edges were already checked
+		if opt.import {
+			return Ok(());
+		}
+		// Clone transaction
+		let txn = ctx.clone_transaction()?;
+		// Get the table
+		let tb = self.tb(opt, &txn).await?;
+		// Check if the table is a relation table
+		if let Some(relation) = &tb.relation {
+			// Get the record id
+			let rid = self.id.as_ref().unwrap();
+			match &self.extras {
+				// Edges must connect records of the specified tables
+				Workable::Relate(l, r) => {
+					check(&relation.from, l, "in", &tb.name)?;
+					check(&relation.with, r, "out", &tb.name)?;
+				}
+				// Records can only be created in a relation table with RELATE
+				_ if !self.initial.pick(&*EDGE).is_true() => {
+					return Err(Error::RelationTable {
+						thing: rid.to_string(),
+						table: tb.name.to_raw(),
+					});
+				}
+				_ => (),
+			}
+		}
+		// Carry on
+		Ok(())
+	}
+}
+
+/// Check if a record belongs to one of the tables which are allowed
+fn check(tables: &Option<Tables>, rid: &Thing, dir: &'static str, tb: &str) -> Result<(), Error> {
+	match tables {
+		Some(v) if !v.iter().any(|v| v.0 == rid.tb) => Err(Error::RelationRecord {
+			thing: rid.to_string(),
+			dir,
+			table: tb.to_owned(),
+		}),
+		_ => Ok(()),
+	}
+}
@@ -0,0 +1,230 @@
+use crate::ctx::Context;
+use crate::dbs::Options;
+use crate::dbs::Statement;
+use crate::doc::Document;
+use crate::err::Error;
+use crate::sql::object::Object;
+use crate::sql::value::Value;
+
+impl<'a> Document<'a> {
+	pub async fn schema(
+		&mut self,
+		ctx: &Context<'_>,
+		opt: &Options,
+		_stm: &Statement<'_>,
+	) -> Result<(), Error> {
+		// Clone transaction
+		let txn = ctx.clone_transaction()?;
+		// Get the table
+		let tb = self.tb(opt, &txn).await?;
+		// Check if the table has a JSON Schema
+		if let Some(schema) = &tb.schema {
+			// Validate the whole document
+			let mut errors = vec![];
+			validate(schema, &self.current, "", &mut errors);
+			// Return every validation error
+			if !errors.is_empty() {
+				return Err(Error::SchemaCheck {
+					thing: self.id.as_ref().unwrap().to_string(),
+					errors,
+				});
+			}
+		}
+		// Carry on
+		Ok(())
+	}
+}
+
+/// Check if a value is of a JSON Schema type
+fn is_type(kind: &str, val: &Value) -> bool {
+	match kind {
+		"null" => val.is_none_or_null(),
+		"boolean" => matches!(val, Value::Bool(_)),
+		"integer" => matches!(val, Value::Number(v) if v.is_integer()),
+		"number" => matches!(val, Value::Number(_)),
+		"string" => matches!(
+			val,
+			Value::Strand(_)
+				| Value::Datetime(_)
+				| Value::Duration(_)
+				| Value::Uuid(_)
+				| Value::Thing(_)
+		),
+		"array" => matches!(val, Value::Array(_)),
+		"object" => matches!(val, Value::Object(_)),
+		// Unknown types are not checked
+		_ => true,
+	}
+}
+
+/// Validate a value against a subset of JSON Schema, which supports the
+/// type, enum, const, numeric range, string length and pattern, array
+/// length and items, and object property keywords. Each error refers to
+/// the value with a JSON Pointer.
+fn validate(schema: &Object, val: &Value, path: &str, errors: &mut Vec<String>) {
+	let at = match path {
+		"" => "/",
+		path => path,
+	};
+	// Check the type of the value
+	if let Some(kind) = schema.get("type") {
+		let valid = match kind {
+			Value::Strand(v) => is_type(v, val),
+			Value::Array(v) => v.iter().any(|v| matches!(v, Value::Strand(v) if is_type(v, val))),
+			_ => true,
+		};
+		if !valid {
+			errors.push(format!("{at} must be of type {kind}"));
+			return;
+		}
+	}
+	// Check the allowed values
+	if let Some(Value::Array(v)) = schema.get("enum") {
+		if !v.contains(val) {
+			errors.push(format!("{at} must be one of {v}"));
+		}
+	}
+	if let Some(v) = schema.get("const") {
+		if v != val {
+			errors.push(format!("{at} must be {v}"));
+		}
+	}
+	match val {
+		Value::Number(v) => {
+			if let Some(Value::Number(min)) = schema.get("minimum") {
+				if v < min {
+					errors.push(format!("{at} must be at least {min}"));
+				}
+			}
+			if let Some(Value::Number(max)) = schema.get("maximum") {
+				if v > max {
+					errors.push(format!("{at} must be at most {max}"));
+				}
+			}
+			if let Some(Value::Number(min)) = schema.get("exclusiveMinimum") {
+				if v <= min {
+					errors.push(format!("{at} must be greater than {min}"));
+				}
+			}
+			if let Some(Value::Number(max)) = schema.get("exclusiveMaximum") {
+				if v >= max {
+					errors.push(format!("{at} must be less than {max}"));
+				}
+			}
+		}
+		Value::Strand(v) => {
+			let len = v.chars().count();
+			if let Some(Value::Number(min)) = schema.get("minLength") {
+				if len < min.to_usize() {
+					errors.push(format!("{at} must have at least {min} characters"));
+				}
+			}
+			if let Some(Value::Number(max)) = schema.get("maxLength") {
+				if len > max.to_usize() {
+					errors.push(format!("{at} must have at most {max} characters"));
+				}
+			}
+			if let Some(Value::Strand(pattern)) = schema.get("pattern") {
+				match regex::Regex::new(pattern.as_str()) {
+					Ok(r) if r.is_match(v.as_str()) => (),
+					_ => errors.push(format!("{at} must match the pattern {pattern}")),
+				}
+			}
+		}
+		Value::Array(v) => {
+			if let Some(Value::Number(min)) = schema.get("minItems") {
+				if v.len() < min.to_usize() {
+					errors.push(format!("{at} must have at least {min} items"));
+				}
+			}
+			if let Some(Value::Number(max)) = schema.get("maxItems") {
+				if v.len() > max.to_usize() {
+					errors.push(format!("{at} must have at most {max} items"));
+				}
+			}
+			if let Some(Value::Object(items)) = schema.get("items") {
+				for (i, v) in v.iter().enumerate() {
+					validate(items, v, &format!("{path}/{i}"), errors);
+				}
+			}
+		}
+		Value::Object(v) => {
+			if let Some(Value::Array(required)) = schema.get("required") {
+				for k in required.iter() {
+					if let Value::Strand(k) = k {
+						if v.get(k.as_str()).map_or(true, Value::is_none) {
+							errors.push(format!("{at} must have the property {k}"));
+						}
+					}
+				}
+			}
+			let properties = match schema.get("properties") {
+				Some(Value::Object(v)) => Some(v),
+				_ => None,
+			};
+			for (k, v) in v.iter() {
+				// Unset fields are not validated
+				if v.is_none() {
+					continue;
+				}
+				let path = format!("{path}/{k}");
+				let root = at == "/" && matches!(k.as_str(), "id" | "in" | "out" | "__");
+				match (properties.and_then(|p| p.get(k)), schema.get("additionalProperties")) {
+					// The property has a schema
+					(Some(Value::Object(s)), _) => validate(s, v, &path, errors),
+					(Some(_), _) => (),
+					// The record id and edge fields are always allowed
+					(None, _) if root => (),
+					// Any other properties are not allowed
+					(None, Some(Value::Bool(false))) => {
+						errors.push(format!("{path} is not an allowed property"))
+					}
+					// Any other properties must match the schema
+					(None, Some(Value::Object(s))) => validate(s, v, &path, errors),
+					(None, _) => (),
+				}
+			}
+		}
+		_ => (),
+	}
+}
+
+#[cfg(test)]
+mod tests {
+
+	use super::*;
+	use crate::sql::test::Parse;
+
+	#[test]
+	fn validate_schema() {
+		let Value::Object(schema) = Value::parse(
+			"{
+				type: 'object',
+				required: ['name', 'age'],
+				additionalProperties: false,
+				properties: {
+					name: { type: 'string', minLength: 1 },
+					age: { type: 'integer', minimum: 0 },
+					tags: { type: 'array', items: { enum: ['a', 'b'] } },
+				},
+			}",
+		) else {
+			unreachable!()
+		};
+		let mut errors = vec![];
+		let val = Value::parse("{ id: person:one, name: 'Tobie', age: 30, tags: ['a'] }");
+		validate(&schema, &val, "", &mut errors);
+		assert!(errors.is_empty(), "{errors:?}");
+		let val = Value::parse("{ id: person:one, name: '', age: 1.5, tags: ['c'], other: 1 }");
+		validate(&schema, &val, "", &mut errors);
+		assert_eq!(
+			errors,
+			vec![
+				"/age must be of type 'integer'",
+				"/name must have at least 1 characters",
+				"/other is not an allowed property",
+				"/tags/0 must be one of ['a', 'b']",
+			]
+		);
+	}
+}
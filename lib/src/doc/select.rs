@@ -18,6 +18,8 @@ impl<'a> Document<'a> {
 		self.check(ctx, opt, stm).await?;
 		// Check if allowed
 		self.allow(ctx, opt, stm).await?;
+		// Record audit entry
+		self.audit(ctx, opt, stm).await?;
 		// Yield document
 		self.pluck(ctx, opt, stm).await
 	}
@@ -3,6 +3,8 @@ use crate::dbs::Options;
 use crate::dbs::Statement;
 use crate::doc::Document;
 use crate::err::Error;
+use crate::sql::paths::DELETED;
+use crate::sql::value::Value;
 
 impl<'a> Document<'a> {
 	pub async fn store(
@@ -17,17 +19,36 @@ impl<'a> Document<'a> {
 		}
 		// Clone transaction
 		let txn = ctx.clone_transaction()?;
+		// Get the table definition
+		let tb = self.tb(opt, &txn).await?;
 		// Check if the table is a view
-		if self.tb(opt, &txn).await?.drop {
+		if tb.drop {
 			return Ok(());
 		}
 		// Claim transaction
 		let mut run = txn.lock().await;
 		// Get the record id
 		let rid = self.id.as_ref().unwrap();
-		// Store the record data
-		let key = crate::key::thing::new(opt.ns(), opt.db(), &rid.tb, &rid.id);
-		run.set(key, self).await?;
+		// Serialize the record data
+		let val: Vec<u8> = self.into();
+		// Store the record data, compressed, and in chunks if it is large
+		let compression = tb.compression.as_ref();
+		let bytes =
+			run.set_record(opt.ns(), opt.db(), rid, val, self.is_new(), compression).await?;
+		// Check the storage limits of the namespace
+		run.check_ns_limits(opt.ns(), self.is_new() as i64, bytes).await?;
+		// Count the record if it is new, and only count the records
+		// of a table which keeps deleted records until they are deleted
+		let live = |v: &Value| tb.soft.is_none() || !v.pick(&*DELETED).is_datetime();
+		let before = !self.is_new() && live(&self.initial);
+		let after = live(&self.current);
+		if before != after {
+			run.add_cn(opt.ns(), opt.db(), &rid.tb, after as i64 - before as i64).await?;
+		}
+		// Count the size of the record data
+		run.add_by(opt.ns(), opt.db(), &rid.tb, bytes).await?;
+		// Record the change to the record
+		run.record_change(opt.ns(), opt.db(), rid, &self.initial, &self.current);
 		// Carry on
 		Ok(())
 	}
@@ -0,0 +1,38 @@
+use crate::ctx::Context;
+use crate::dbs::Options;
+use crate::dbs::Statement;
+use crate::doc::Document;
+use crate::err::Error;
+use crate::sql::datetime::Datetime;
+use crate::sql::paths::DELETED;
+
+impl<'a> Document<'a> {
+	/// Check if this document was soft deleted
+	pub fn is_deleted(&self) -> bool {
+		self.id.is_some() && self.current.pick(&*DELETED).is_datetime()
+	}
+	/// Mark this document as deleted, if the table keeps deleted records
+	pub async fn tombstone(
+		&mut self,
+		ctx: &Context<'_>,
+		opt: &Options,
+		_stm: &Statement<'_>,
+	) -> Result<bool, Error> {
+		// Permanently remove records when purging
+		if opt.purge {
+			return Ok(false);
+		}
+		// Clone transaction
+		let txn = ctx.clone_transaction()?;
+		// Check if the table keeps deleted records
+		if self.tb(opt, &txn).await?.soft.is_none() {
+			return Ok(false);
+		}
+		// Set the time at which the record was deleted
+		if self.current.is_some() {
+			self.current.to_mut().put(&*DELETED, Datetime::default().into());
+		}
+		// Carry on
+		Ok(true)
+	}
+}
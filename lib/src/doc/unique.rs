@@ -0,0 +1,123 @@
+use crate::ctx::Context;
+use crate::dbs::Options;
+use crate::dbs::Statement;
+use crate::doc::Document;
+use crate::err::Error;
+use crate::sql::datetime::Datetime;
+use crate::sql::paths::ID;
+use crate::sql::value::Value;
+use sha2::{Digest, Sha256};
+
+// The number of expired content hashes which are removed by each statement
+const CLEANUP_BATCH_SIZE: u32 = 100;
+
+impl<'a> Document<'a> {
+	/// Check that no record with the same content was created within the
+	/// uniqueness window. This runs once the fields have been processed and
+	/// the permissions have been checked, so that the content is hashed as
+	/// it is stored, and so that the existing record is never revealed.
+	pub async fn unique(
+		&self,
+		ctx: &Context<'_>,
+		opt: &Options,
+		stm: &Statement<'_>,
+	) -> Result<(), Error> {
+		// Check if a uniqueness window is specified
+		let window = match stm {
+			Statement::Create(v) => match &v.unique {
+				Some(v) => v,
+				None => return Ok(()),
+			},
+			_ => return Ok(()),
+		};
+		// Get the record id
+		let rid = self.id.as_ref().unwrap();
+		// Hash the stored document content without the record id
+		let hash = hash(&self.current);
+		// Get the current time
+		let now = Datetime::default();
+		// Clone transaction
+		let txn = ctx.clone_transaction()?;
+		// Claim transaction
+		let mut run = txn.lock().await;
+		// Remove the content hashes which have expired
+		let beg = crate::key::de::prefix(opt.ns(), opt.db(), &rid.tb);
+		let end = crate::key::de::expired(opt.ns(), opt.db(), &rid.tb, nanos(&now));
+		for (k, _) in run.scan(beg..end, CLEANUP_BATCH_SIZE).await? {
+			let de = crate::key::de::De::decode(&k)?;
+			let key = crate::key::dh::new(opt.ns(), opt.db(), &rid.tb, de.dh);
+			// The content may have been created again since
+			if let Some(v) = run.get(key.clone()).await? {
+				match Value::from(&v).pick(&["expires".into()]) {
+					Value::Datetime(v) if v > now => (),
+					_ => run.del(key).await?,
+				}
+			}
+			run.del(k).await?;
+		}
+		// Check for a recent record with the same content
+		let key = crate::key::dh::new(opt.ns(), opt.db(), &rid.tb, &hash);
+		if let Some(v) = run.get(key).await? {
+			if let Value::Object(v) = Value::from(&v) {
+				if let (Some(Value::Thing(id)), Some(Value::Datetime(at))) =
+					(v.get("id"), v.get("at"))
+				{
+					// Check if the record was created within the window
+					if window.clone() + at.clone() > now {
+						// Check if the record still exists
+						let key = crate::key::thing::new(opt.ns(), opt.db(), &id.tb, &id.id);
+						if run.exi(key).await? {
+							return Err(Error::DuplicateRecord);
+						}
+					}
+				}
+			}
+		}
+		// Store the content hash for this record, until the window expires
+		let expires = window.clone() + now.clone();
+		let key = crate::key::dh::new(opt.ns(), opt.db(), &rid.tb, &hash);
+		let val = Value::from(map! {
+			"id".to_string() => Value::from(rid.clone()),
+			"at".to_string() => Value::from(now),
+			"expires".to_string() => Value::from(expires.clone()),
+		});
+		run.set(key, val).await?;
+		let key = crate::key::de::new(opt.ns(), opt.db(), &rid.tb, nanos(&expires), &hash);
+		run.set(key, vec![]).await?;
+		// Carry on
+		Ok(())
+	}
+
+	/// Remove the content hash of a record which is deleted
+	pub async fn unique_purge(&self, ctx: &Context<'_>, opt: &Options) -> Result<(), Error> {
+		// Get the record id
+		let rid = self.id.as_ref().unwrap();
+		// Hash the stored document content without the record id
+		let hash = hash(&self.initial);
+		// Clone transaction
+		let txn = ctx.clone_transaction()?;
+		// Claim transaction
+		let mut run = txn.lock().await;
+		// Remove the content hash if it belongs to this record
+		let key = crate::key::dh::new(opt.ns(), opt.db(), &rid.tb, &hash);
+		if let Some(v) = run.get(key.clone()).await? {
+			if Value::from(&v).pick(&["id".into()]) == Value::from(rid.clone()) {
+				run.del(key).await?;
+			}
+		}
+		// Carry on
+		Ok(())
+	}
+}
+
+/// Hash the content of a document, without its record id
+fn hash(doc: &Value) -> String {
+	let mut doc = doc.clone();
+	doc.cut(ID.as_ref());
+	format!("{:x}", Sha256::digest(doc.to_string().as_bytes()))
+}
+
+/// Get the number of nanoseconds since the epoch of a datetime
+fn nanos(v: &Datetime) -> u64 {
+	v.0.timestamp_nanos().max(0) as u64
+}
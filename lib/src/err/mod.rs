@@ -5,9 +5,12 @@ use bincode::Error as BincodeError;
 use bung::encode::Error as SerdeError;
 use fst::Error as FstError;
 use jsonwebtoken::errors::Error as JWTError;
+use serde::ser::SerializeStruct;
 use serde::Serialize;
 use std::borrow::Cow;
+use std::fmt;
 use std::string::FromUtf8Error;
+use std::time::Duration;
 use storekey::decode::Error as DecodeError;
 use storekey::encode::Error as EncodeError;
 use thiserror::Error;
@@ -21,10 +24,47 @@ pub enum Error {
 	#[error("Conditional clause is not truthy")]
 	Ignore,
 
+	/// This error is used for omitting a processed document from the query output
+	#[doc(hidden)]
+	#[error("Processed document is not output")]
+	Omit,
+
 	/// There was a problem with the underlying datastore
 	#[error("There was a problem with the underlying datastore: {0}")]
 	Ds(String),
 
+	/// There was a problem with the archive tier
+	#[error("There was a problem with the archive tier: {0}")]
+	Archive(String),
+
+	/// There was a problem with an S3-compatible object store
+	#[error("There was a problem with the object store: {0}")]
+	ObjectStore(String),
+
+	/// There was a problem writing to the statement journal
+	#[error("There was a problem writing to the statement journal: {0}")]
+	Journal(String),
+
+	/// There was a problem compressing or decompressing a record
+	#[error("There was a problem with the compression of a record: {0}")]
+	Compression(String),
+
+	/// The backup data could not be decoded
+	#[error("The backup data is invalid or corrupted")]
+	InvalidBackup,
+
+	/// The replicated write batch could not be decoded
+	#[error("The replicated write batch is invalid or corrupted")]
+	InvalidBatch,
+
+	/// The transaction was committed, but was not replicated in time
+	#[error("The transaction was committed but was not acknowledged by a majority of replicas")]
+	ReplicaUnacknowledged,
+
+	/// The datastore is a read-only replica
+	#[error("Unable to modify data on a read-only replica")]
+	ReplicaReadOnly,
+
 	/// There was a problem with a datastore transaction
 	#[error("There was a problem with a datastore transaction: {0}")]
 	Tx(String),
@@ -45,6 +85,10 @@ pub enum Error {
 	#[error("Value being checked was not correct")]
 	TxConditionNotMet,
 
+	/// The transaction conflicted with a concurrent transaction
+	#[error("The transaction conflicted with a concurrent transaction, and can be retried")]
+	TxConflict,
+
 	/// The key being inserted in the transaction already exists
 	#[error("The key being inserted already exists")]
 	TxKeyAlreadyExists,
@@ -65,6 +109,22 @@ pub enum Error {
 	#[error("No transaction")]
 	NoTx,
 
+	/// The requested transaction does not exist, or has expired
+	#[error("The transaction '{value}' does not exist, or has expired")]
+	TxNotFound {
+		value: String,
+	},
+
+	/// The requested transaction is being used by another query
+	#[error("The transaction '{value}' is being used by another query")]
+	TxInUse {
+		value: String,
+	},
+
+	/// A transaction can not be started or finished within an open transaction
+	#[error("Unable to begin, commit, or cancel a transaction within an open transaction")]
+	TxNested,
+
 	/// No namespace has been selected
 	#[error("Specify a namespace to use")]
 	NsEmpty,
@@ -81,10 +141,29 @@ pub enum Error {
 	#[error("The SQL query was not parsed fully")]
 	QueryRemaining,
 
+	/// A statement of a batch was not a single statement
+	#[error("Each statement of a batch must be a single statement, other than a transaction or OPTION statement")]
+	InvalidBatchStatement,
+
+	/// A runtime setting does not exist, or was given an invalid value
+	#[error("Unable to set the global setting '{name}': {message}")]
+	InvalidSetting {
+		name: String,
+		message: String,
+	},
+
+	/// The datastore is shutting down, and does not accept new queries
+	#[error("The datastore is shutting down")]
+	ShuttingDown,
+
 	/// There was an error with authentication
 	#[error("There was a problem with authentication")]
 	InvalidAuth,
 
+	/// The share link is not valid
+	#[error("A share link must be for a record id or a single SELECT query, and must expire within 30 days")]
+	InvalidShare,
+
 	/// There was an error with the SQL query
 	#[error("Parse error on line {line} at character {char} when parsing '{sql}'")]
 	InvalidQuery {
@@ -103,12 +182,30 @@ pub enum Error {
 	#[error("Remote HTTP request functions are not enabled")]
 	HttpDisabled,
 
+	/// The host of a remote HTTP request is not in the allowlist
+	#[error("Remote HTTP requests to the host '{0}' are not allowed")]
+	HttpHostNotAllowed(String),
+
+	/// The body of a remote HTTP request or response is too large
+	#[error("The HTTP body exceeded the maximum size of {0} bytes")]
+	HttpBodyTooLarge(usize),
+
 	/// it is not possible to set a variable with the specified name
 	#[error("Found '{name}' but it is not possible to set a variable with this name")]
 	InvalidParam {
 		name: String,
 	},
 
+	/// The FOR statement was given a value which is not an array
+	#[error("Found '{value}' but a FOR statement can only iterate over an array")]
+	InvalidForeach {
+		value: String,
+	},
+
+	/// An error was thrown by a THROW statement
+	#[error("An error occurred: {0}")]
+	Thrown(String),
+
 	#[error("Found '{field}' in SELECT clause on line {line}, but field is not an aggregate function, and is not present in GROUP BY expression")]
 	InvalidField {
 		line: usize,
@@ -133,18 +230,48 @@ pub enum Error {
 		field: String,
 	},
 
+	#[error("Found '{field}' as the last field in the GROUP BY clause on line {line}, but a FILL clause requires a time::bucket() field with a fixed duration")]
+	InvalidFill {
+		line: usize,
+		field: String,
+	},
+
+	/// A FILL clause would create too many missing rows
+	#[error("The FILL clause would create more than {limit} missing rows")]
+	FillLimit {
+		limit: usize,
+	},
+
 	/// The LIMIT clause must evaluate to a positive integer
 	#[error("Found {value} but the LIMIT clause must evaluate to a positive integer")]
 	InvalidLimit {
 		value: String,
 	},
 
+	/// The statement would modify more records than its STRICT limit allows
+	#[error("The statement would modify more records than the LIMIT of {limit} allows")]
+	LimitExceeded {
+		limit: usize,
+	},
+
+	/// The statement must be filtered when safe mode is enabled
+	#[error("Can not execute {statement} on a whole table without a WHERE or LIMIT clause")]
+	UnsafeStatement {
+		statement: String,
+	},
+
 	/// The START clause must evaluate to a positive integer
 	#[error("Found {value} but the START clause must evaluate to a positive integer")]
 	InvalidStart {
 		value: String,
 	},
 
+	/// The START AFTER clause must evaluate to a cursor for the query
+	#[error("Found {value} but the START AFTER clause must evaluate to a cursor which was returned for the same query")]
+	InvalidCursor {
+		value: String,
+	},
+
 	/// There was an error with the provided JavaScript code
 	#[error("Problem with embedded script function. {message}")]
 	InvalidScript {
@@ -169,6 +296,45 @@ pub enum Error {
 	#[error("The query was not executed because it exceeded the timeout")]
 	QueryTimedout,
 
+	/// The statement examined more records than the configured limit
+	#[error("The query was stopped because it examined more than {limit} records")]
+	QueryRowLimit {
+		limit: usize,
+	},
+
+	/// The output of the statement used more memory than the configured limit
+	#[error("The query was stopped because it used more than {limit} bytes of memory")]
+	QueryMemoryLimit {
+		limit: usize,
+	},
+
+	/// The identity of the session exceeded one of its rate limits or quotas
+	#[error("The query was rejected because the {quota} quota of {limit} was exceeded")]
+	QuotaExceeded {
+		quota: crate::dbs::Quota,
+		limit: u64,
+	},
+
+	/// The write would take the namespace over one of its storage limits
+	#[error("The write was rejected because the namespace '{ns}' is limited to {limit} {kind}")]
+	NsLimitExceeded {
+		ns: String,
+		kind: &'static str,
+		limit: u64,
+	},
+
+	/// An event, future, or stored function exceeded one of its sandbox limits
+	#[error("The {kind} '{name}' was stopped because it exceeded the {limit} limit")]
+	SandboxLimit {
+		kind: crate::ctx::sandbox::Kind,
+		name: String,
+		limit: crate::ctx::sandbox::Violation,
+	},
+
+	/// The query was killed by an administrator
+	#[error("The query was killed")]
+	QueryKilled,
+
 	/// The query did not execute, because the transaction was cancelled
 	#[error("The query was not executed due to a cancelled transaction")]
 	QueryCancelled,
@@ -187,6 +353,12 @@ pub enum Error {
 	#[error("You don't have permission to perform this query type")]
 	QueryPermissions,
 
+	/// The client address is not in the network allowlist of the namespace
+	#[error("Connections from this address are not allowed to use the {ns} namespace")]
+	IpNotAllowed {
+		ns: String,
+	},
+
 	/// The permissions do not allow for changing to the specified namespace
 	#[error("You don't have permission to change to the {ns} namespace")]
 	NsNotAllowed {
@@ -223,6 +395,12 @@ pub enum Error {
 		value: String,
 	},
 
+	/// The specified database already exists
+	#[error("The database '{value}' already exists")]
+	DbAlreadyExists {
+		value: String,
+	},
+
 	/// The requested database token does not exist
 	#[error("The database token '{value}' does not exist")]
 	DtNotFound {
@@ -259,12 +437,55 @@ pub enum Error {
 		value: String,
 	},
 
+	/// The requested sequence does not exist
+	#[error("The sequence '{value}' does not exist")]
+	SqNotFound {
+		value: String,
+	},
+
+	/// The requested sequence has no more values available
+	#[error("The sequence '{value}' has been exhausted")]
+	SqExhausted {
+		value: String,
+	},
+
+	/// The requested migration does not exist
+	#[error("The migration '{value}' does not exist")]
+	MgNotFound {
+		value: String,
+	},
+
+	/// The migration contains a statement which can not be run within a migration
+	#[error("The migration '{value}' can not contain {kind} statements")]
+	MgInvalid {
+		value: String,
+		kind: String,
+	},
+
+	/// The applied migration can not be reverted, as it has no DOWN script
+	#[error("The migration '{value}' can not be reverted, as it has no DOWN script")]
+	MgIrreversible {
+		value: String,
+	},
+
 	/// The requested table does not exist
 	#[error("The table '{value}' does not exist")]
 	TbNotFound {
 		value: String,
 	},
 
+	/// The table does not keep deleted records
+	#[error("The table '{value}' does not keep deleted records")]
+	TbNotSoftDelete {
+		value: String,
+	},
+
+	/// The table is not partitioned
+	#[error("The table '{value}' is not partitioned")]
+	TbNotPartitioned {
+		value: String,
+	},
+
 	/// The requested analyzer does not exist
 	#[error("The analyzer '{value}' does not exist")]
 	AzNotFound {
@@ -277,6 +498,12 @@ pub enum Error {
 		value: String,
 	},
 
+	/// The requested index is still being built in the background
+	#[error("The index '{value}' is still being built")]
+	IxBuilding {
+		value: String,
+	},
+
 	/// Unable to perform the realtime query
 	#[error("Unable to perform the realtime query")]
 	RealtimeDisabled,
@@ -315,12 +542,24 @@ pub enum Error {
 		value: String,
 	},
 
+	/// Can not execute a SELECT query with a JOIN using the specified value
+	#[error("Can not execute SELECT query with a JOIN using value '{value}'")]
+	JoinStatement {
+		value: String,
+	},
+
 	/// Can not execute LIVE query using the specified value
 	#[error("Can not execute LIVE query using value '{value}'")]
 	LiveStatement {
 		value: String,
 	},
 
+	/// The changes since the token of a live query are not retained
+	#[error("Unable to resume the LIVE query, as the changes since '{value}' are not retained")]
+	LiveResume {
+		value: String,
+	},
+
 	/// Can not execute KILL query using the specified id
 	#[error("Can not execute KILL query using id '{value}'")]
 	KillStatement {
@@ -345,6 +584,10 @@ pub enum Error {
 		thing: String,
 	},
 
+	/// A database record with the same content was recently created
+	#[error("A database record was recently created with the same content")]
+	DuplicateRecord,
+
 	/// A database index entry for the specified record already exists
 	#[error("Database index `{index}` already contains {value}, with record `{thing}`")]
 	IndexExists {
@@ -353,6 +596,13 @@ pub enum Error {
 		value: String,
 	},
 
+	/// The vector of a record does not have the dimension of the vector index
+	#[error("Incorrect vector dimension ({current}). Expected a vector of {expected} dimension.")]
+	InvalidVectorDimension {
+		current: usize,
+		expected: usize,
+	},
+
 	/// The specified field did not conform to the field type check
 	#[error("Found {value} for field `{field}`, with record `{thing}`, but expected a {check}")]
 	FieldCheck {
@@ -371,6 +621,51 @@ pub enum Error {
 		check: String,
 	},
 
+	/// The specified field did not conform to the field ASSERT clause, which has a custom message
+	#[error("Invalid value for field `{field}`, with record `{thing}`: {message}")]
+	FieldMessage {
+		thing: String,
+		field: Idiom,
+		message: String,
+	},
+
+	/// More than one field did not conform to its field ASSERT clause
+	#[error("Found {} invalid fields, with record `{thing}`: {}", .errors.len(), .errors.join("; "))]
+	FieldAsserts {
+		thing: String,
+		errors: Vec<String>,
+	},
+
+	/// The record did not conform to the JSON Schema of its table
+	#[error("Found record `{thing}` which does not conform to the table schema: {}", .errors.join("; "))]
+	SchemaCheck {
+		thing: String,
+		errors: Vec<String>,
+	},
+
+	/// The record is not an edge, but its table is a relation table
+	#[error("Found record `{thing}` which is not an edge, but the table `{table}` only contains edges created with RELATE")]
+	RelationTable {
+		thing: String,
+		table: String,
+	},
+
+	/// The record is related with a record of a table which the relation table does not allow
+	#[error("Found record `{thing}` which can not be the `{dir}` record of the relation table `{table}`")]
+	RelationRecord {
+		thing: String,
+		dir: &'static str,
+		table: String,
+	},
+
+	/// The specified field is computed, and can not be set directly
+	#[error("Found {value} for field `{field}`, with record `{thing}`, but field is computed and can not be set")]
+	FieldComputed {
+		thing: String,
+		value: String,
+		field: Idiom,
+	},
+
 	/// Found a record id for the record but this is not a valid id
 	#[error("Found '{value}' for the record ID but this is not a valid id")]
 	IdInvalid {
@@ -391,6 +686,21 @@ pub enum Error {
 		into: Cow<'static, str>,
 	},
 
+	/// The bytes value was larger than the maximum size
+	#[error("Expected bytes of at most {max} bytes but found {size} bytes")]
+	BytesTooLarge {
+		size: usize,
+		max: usize,
+	},
+
+	/// The data of a record was larger than the maximum document size
+	#[error("The record {thing} is {size} bytes, which is larger than the maximum document size of {max} bytes")]
+	DocumentTooLarge {
+		thing: String,
+		size: usize,
+		max: usize,
+	},
+
 	/// Unable to coerce to a value to another value
 	#[error("Expected a {kind} but the array had {size} items")]
 	LengthInvalid {
@@ -418,6 +728,10 @@ pub enum Error {
 	#[error("Cannot raise the value '{0}' with '{1}'")]
 	TryPow(String, String),
 
+	/// Cannot compare values of different types when strict types are enabled
+	#[error("Cannot compare '{1}' and '{2}' with '{0}', as they are of different types")]
+	TryCompare(String, String, String),
+
 	/// It's is not possible to convert between the two types
 	#[error("Cannot convert from '{0}' to '{1}'")]
 	TryFrom(String, &'static str),
@@ -522,6 +836,11 @@ impl From<tikv::Error> for Error {
 	fn from(e: tikv::Error) -> Error {
 		match e {
 			tikv::Error::DuplicateKeyInsertion => Error::TxKeyAlreadyExists,
+			tikv::Error::KeyError(tikv_client_proto::kvrpcpb::KeyError {
+				conflict,
+				retryable,
+				..
+			}) if conflict.is_some() || !retryable.is_empty() => Error::TxConflict,
 			tikv::Error::KeyError(tikv_client_proto::kvrpcpb::KeyError {
 				abort,
 				..
@@ -545,7 +864,10 @@ impl From<speedb::Error> for Error {
 #[cfg(feature = "kv-rocksdb")]
 impl From<rocksdb::Error> for Error {
 	fn from(e: rocksdb::Error) -> Error {
-		Error::Tx(e.to_string())
+		match e.kind() {
+			rocksdb::ErrorKind::Busy | rocksdb::ErrorKind::TryAgain => Error::TxConflict,
+			_ => Error::Tx(e.to_string()),
+		}
 	}
 }
 
@@ -568,6 +890,278 @@ impl From<reqwest::Error> for Error {
 	}
 }
 
+impl Error {
+	/// Get a hint as to whether, and after how long, the failed statement
+	/// can be retried, if the error was caused by a transient condition
+	pub fn retry(&self) -> Option<Retry> {
+		let (reason, backoff) = match self {
+			Error::TxConflict => (RetryReason::Conflict, 50),
+			Error::TxFailure => (RetryReason::Unavailable, 100),
+			Error::QueryTimedout => (RetryReason::Timeout, 500),
+			Error::ReplicaReadOnly => (RetryReason::Leader, 1000),
+			Error::QuotaExceeded {
+				quota,
+				..
+			} if *quota != crate::dbs::Quota::Live => (RetryReason::Quota, 1000),
+			_ => return None,
+		};
+		Some(Retry {
+			reason,
+			backoff: Duration::from_millis(backoff),
+		})
+	}
+	/// Get the category of the error, which is included in the error payload
+	/// so that clients can handle errors without matching the error message
+	pub fn kind(&self) -> ErrorKind {
+		match self {
+			Error::InvalidQuery {
+				..
+			}
+			| Error::QueryEmpty
+			| Error::QueryRemaining => ErrorKind::Parse,
+			Error::InvalidAuth
+			| Error::InvalidShare
+			| Error::QueryPermissions
+			| Error::TablePermissions {
+				..
+			}
+			| Error::IpNotAllowed {
+				..
+			}
+			| Error::NsNotAllowed {
+				..
+			}
+			| Error::DbNotAllowed {
+				..
+			}
+			| Error::HttpDisabled
+			| Error::HttpHostNotAllowed(_)
+			| Error::RealtimeDisabled => ErrorKind::PermissionDenied,
+			Error::NsNotFound {
+				..
+			}
+			| Error::NtNotFound {
+				..
+			}
+			| Error::NlNotFound {
+				..
+			}
+			| Error::DbNotFound {
+				..
+			}
+			| Error::DtNotFound {
+				..
+			}
+			| Error::DlNotFound {
+				..
+			}
+			| Error::FcNotFound {
+				..
+			}
+			| Error::ScNotFound {
+				..
+			}
+			| Error::StNotFound {
+				..
+			}
+			| Error::PaNotFound {
+				..
+			}
+			| Error::SqNotFound {
+				..
+			}
+			| Error::MgNotFound {
+				..
+			}
+			| Error::TbNotFound {
+				..
+			}
+			| Error::AzNotFound {
+				..
+			}
+			| Error::IxNotFound {
+				..
+			}
+			| Error::TxNotFound {
+				..
+			} => ErrorKind::NotFound,
+			Error::DbAlreadyExists {
+				..
+			}
+			| Error::RecordExists {
+				..
+			}
+			| Error::DuplicateRecord
+			| Error::IndexExists {
+				..
+			}
+			| Error::TxKeyAlreadyExists => ErrorKind::AlreadyExists,
+			Error::TxConflict
+			| Error::TxConditionNotMet
+			| Error::TxInUse {
+				..
+			} => ErrorKind::Conflict,
+			Error::QueryTimedout => ErrorKind::Timeout,
+			Error::QuotaExceeded {
+				..
+			}
+			| Error::NsLimitExceeded {
+				..
+			}
+			| Error::QueryRowLimit {
+				..
+			}
+			| Error::QueryMemoryLimit {
+				..
+			}
+			| Error::SandboxLimit {
+				..
+			} => ErrorKind::Quota,
+			Error::QueryKilled
+			| Error::QueryCancelled
+			| Error::QueryNotExecuted
+			| Error::QueryNotExecutedDetail {
+				..
+			} => ErrorKind::Cancelled,
+			Error::TxFailure
+			| Error::ReplicaReadOnly
+			| Error::ReplicaUnacknowledged
+			| Error::ShuttingDown => ErrorKind::Unavailable,
+			Error::Thrown(_) => ErrorKind::Thrown,
+			Error::Ignore
+			| Error::Omit
+			| Error::Ds(_)
+			| Error::Archive(_)
+			| Error::Journal(_)
+			| Error::Compression(_)
+			| Error::Tx(_)
+			| Error::Channel(_)
+			| Error::Serde(_)
+			| Error::Encode(_)
+			| Error::Decode(_)
+			| Error::CorruptedIndex
+			| Error::Bincode(_)
+			| Error::FstError(_)
+			| Error::Utf8Error(_) => ErrorKind::Internal,
+			_ => ErrorKind::Invalid,
+		}
+	}
+}
+
+/// The category of an error, which is stable across releases, unlike the
+/// error message. Each category has a string name and a numeric code, so
+/// that client SDKs can handle errors without matching on the message.
+#[derive(Clone, Copy, Debug, Eq, PartialEq, Serialize)]
+#[serde(rename_all = "snake_case")]
+pub enum ErrorKind {
+	/// An unexpected error within the datastore
+	Internal,
+	/// The query could not be parsed
+	Parse,
+	/// The query or its arguments were invalid
+	Invalid,
+	/// The session is not allowed to perform the operation
+	PermissionDenied,
+	/// The namespace, database, table, or other resource does not exist
+	NotFound,
+	/// The record, index entry, or other resource already exists
+	AlreadyExists,
+	/// The transaction conflicted with a concurrent transaction
+	Conflict,
+	/// The statement exceeded its timeout
+	Timeout,
+	/// The statement exceeded one of the rate limits, quotas, or limits
+	Quota,
+	/// The statement was cancelled, or was not executed
+	Cancelled,
+	/// The datastore is unavailable, or is unable to accept writes
+	Unavailable,
+	/// An error was thrown by a THROW statement
+	Thrown,
+}
+
+impl ErrorKind {
+	/// Get the numeric code of the error category
+	pub fn code(&self) -> u16 {
+		match self {
+			ErrorKind::Internal => 1000,
+			ErrorKind::Parse => 1001,
+			ErrorKind::Invalid => 1002,
+			ErrorKind::PermissionDenied => 1003,
+			ErrorKind::NotFound => 1004,
+			ErrorKind::AlreadyExists => 1005,
+			ErrorKind::Conflict => 1006,
+			ErrorKind::Timeout => 1007,
+			ErrorKind::Quota => 1008,
+			ErrorKind::Cancelled => 1009,
+			ErrorKind::Unavailable => 1010,
+			ErrorKind::Thrown => 1011,
+		}
+	}
+	/// Get the string name of the error category
+	pub fn as_str(&self) -> &'static str {
+		match self {
+			ErrorKind::Internal => "internal",
+			ErrorKind::Parse => "parse",
+			ErrorKind::Invalid => "invalid",
+			ErrorKind::PermissionDenied => "permission_denied",
+			ErrorKind::NotFound => "not_found",
+			ErrorKind::AlreadyExists => "already_exists",
+			ErrorKind::Conflict => "conflict",
+			ErrorKind::Timeout => "timeout",
+			ErrorKind::Quota => "quota",
+			ErrorKind::Cancelled => "cancelled",
+			ErrorKind::Unavailable => "unavailable",
+			ErrorKind::Thrown => "thrown",
+		}
+	}
+}
+
+impl fmt::Display for ErrorKind {
+	fn fmt(&self, f: &mut fmt::Formatter) -> fmt::Result {
+		f.write_str(self.as_str())
+	}
+}
+
+/// The transient condition which caused a retryable error
+#[derive(Clone, Copy, Debug, Eq, PartialEq, Serialize)]
+#[serde(rename_all = "lowercase")]
+pub enum RetryReason {
+	/// The transaction conflicted with a concurrent transaction
+	Conflict,
+	/// The statement exceeded its timeout
+	Timeout,
+	/// A datastore transaction could not be started
+	Unavailable,
+	/// The statement was sent to a node which is not the cluster leader
+	Leader,
+	/// The identity of the session exceeded one of its rate limits
+	Quota,
+}
+
+/// A hint that a failed statement can be retried, which is included in the
+/// error payload so that clients can implement uniform retry policies. The
+/// backoff is the suggested delay before the first retry, in milliseconds,
+/// which clients should increase exponentially on each subsequent retry.
+#[derive(Clone, Copy, Debug, Eq, PartialEq)]
+pub struct Retry {
+	pub reason: RetryReason,
+	pub backoff: Duration,
+}
+
+impl Serialize for Retry {
+	fn serialize<S>(&self, serializer: S) -> Result<S::Ok, S::Error>
+	where
+		S: serde::Serializer,
+	{
+		let mut val = serializer.serialize_struct("Retry", 3)?;
+		val.serialize_field("retryable", &true)?;
+		val.serialize_field("reason", &self.reason)?;
+		val.serialize_field("backoff", &(self.backoff.as_millis() as u64))?;
+		val.end()
+	}
+}
+
 impl Serialize for Error {
 	fn serialize<S>(&self, serializer: S) -> Result<S::Ok, S::Error>
 	where
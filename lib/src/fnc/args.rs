@@ -1,6 +1,6 @@
 use crate::err::Error;
 use crate::sql::value::Value;
-use crate::sql::{Array, Bytes, Datetime, Duration, Kind, Number, Strand, Thing};
+use crate::sql::{Array, Bytes, Datetime, Duration, Kind, Number, Object, Strand, Thing};
 
 /// Implemented by types that are commonly used, in a certain way, as arguments.
 pub trait FromArg: Sized {
@@ -55,6 +55,12 @@ impl FromArg for Array {
 	}
 }
 
+impl FromArg for Object {
+	fn from_arg(arg: Value) -> Result<Self, Error> {
+		arg.coerce_to_object()
+	}
+}
+
 impl FromArg for Bytes {
 	fn from_arg(arg: Value) -> Result<Self, Error> {
 		arg.coerce_to_bytes()
@@ -8,6 +8,7 @@ use crate::sql::array::Flatten;
 use crate::sql::array::Intersect;
 use crate::sql::array::Union;
 use crate::sql::array::Uniq;
+use crate::sql::object::Object;
 use crate::sql::value::Value;
 
 pub fn add((mut array, value): (Array, Value)) -> Result<Value, Error> {
@@ -113,6 +114,26 @@ pub fn min((array,): (Array,)) -> Result<Value, Error> {
 	Ok(array.into_iter().min().unwrap_or_default())
 }
 
+pub fn pivot((array, key, val): (Array, String, Option<String>)) -> Result<Value, Error> {
+	let mut out = Object::default();
+	for v in array.into_iter() {
+		if let Value::Object(mut v) = v {
+			// Get the category of this row
+			let k = match v.remove(&key) {
+				Some(k) => k.as_raw_string(),
+				None => continue,
+			};
+			// Get the value for this category
+			let v = match &val {
+				Some(val) => v.remove(val).unwrap_or_default(),
+				None => v.into(),
+			};
+			out.insert(k, v);
+		}
+	}
+	Ok(out.into())
+}
+
 pub fn pop((mut array,): (Array,)) -> Result<Value, Error> {
 	Ok(array.pop().into())
 }
@@ -142,6 +142,46 @@ pub mod bcrypt {
 	}
 }
 
+pub mod hmac {
+
+	use crate::err::Error;
+	use crate::sql::value::Value;
+	use hmac::digest::core_api::BlockSizeUser;
+	use hmac::digest::Digest;
+	use hmac::{Mac, SimpleHmac};
+	use sha1::Sha1;
+	use sha2::{Sha256, Sha512};
+
+	fn sign<D: Digest + BlockSizeUser>(
+		name: &str,
+		key: String,
+		msg: String,
+	) -> Result<Value, Error> {
+		let mut mac = SimpleHmac::<D>::new_from_slice(key.as_bytes()).map_err(|_| {
+			Error::InvalidArguments {
+				name: name.to_owned(),
+				message: String::from("The key could not be used to sign the message."),
+			}
+		})?;
+		mac.update(msg.as_bytes());
+		let val = mac.finalize().into_bytes();
+		let val = format!("{val:x}");
+		Ok(val.into())
+	}
+
+	pub fn sha1((key, msg): (String, String)) -> Result<Value, Error> {
+		sign::<Sha1>("crypto::hmac::sha1", key, msg)
+	}
+
+	pub fn sha256((key, msg): (String, String)) -> Result<Value, Error> {
+		sign::<Sha256>("crypto::hmac::sha256", key, msg)
+	}
+
+	pub fn sha512((key, msg): (String, String)) -> Result<Value, Error> {
+		sign::<Sha512>("crypto::hmac::sha512", key, msg)
+	}
+}
+
 pub mod pbkdf2 {
 
 	use super::COST_ALLOWANCE;
@@ -0,0 +1,10 @@
+use crate::err::Error;
+use crate::fnc::string::edits;
+use crate::fnc::util::string;
+use crate::sql::value::Value;
+
+/// Returns true if the edit distance between two strings is no more than the maximum distance.
+pub fn fuzzy((a, b, max): (String, String, usize)) -> Result<Value, Error> {
+	edits("fuzzy", &a, &b)?;
+	Ok((string::levenshtein(&a, &b) <= max).into())
+}
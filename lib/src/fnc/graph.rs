@@ -0,0 +1,205 @@
+use crate::ctx::Context;
+use crate::err::Error;
+use crate::sql::part::Part;
+use crate::sql::paths::DB;
+use crate::sql::paths::NS;
+use crate::sql::paths::OUT;
+use crate::sql::paths::SC;
+use crate::sql::thing::Thing;
+use crate::sql::value::Value;
+use crate::sql::{Array, Dir, Object};
+use std::cmp::Ordering;
+use std::collections::{BinaryHeap, HashMap};
+
+/// The default maximum number of edges in a path
+const MAX_DEPTH: usize = 16;
+
+/// The maximum number of records which are visited when searching for a path
+const MAX_VISITS: usize = 10_000;
+
+#[derive(Debug)]
+struct State {
+	cost: f64,
+	depth: usize,
+	node: Thing,
+}
+
+impl PartialEq for State {
+	fn eq(&self, other: &Self) -> bool {
+		self.cmp(other) == Ordering::Equal
+	}
+}
+
+impl Eq for State {}
+
+impl PartialOrd for State {
+	fn partial_cmp(&self, other: &Self) -> Option<Ordering> {
+		Some(self.cmp(other))
+	}
+}
+
+impl Ord for State {
+	// Order by lowest cost first, so that the binary heap is a min-heap
+	fn cmp(&self, other: &Self) -> Ordering {
+		other.cost.partial_cmp(&self.cost).unwrap_or(Ordering::Equal)
+	}
+}
+
+/// Find the lowest cost path from one record to another, following the outgoing
+/// edges of the specified edge table. When a weight field is specified, the cost
+/// of each edge is the numeric value of that field, or 1 if the field is not set,
+/// otherwise each edge costs 1. The search is limited to paths with at most the
+/// specified number of edges, and to a maximum number of visited records.
+///
+/// graph::shortest_path(from, to, edge, [weight], [depth])
+pub async fn shortest_path(ctx: &Context<'_>, args: Vec<Value>) -> Result<Value, Error> {
+	let name = "graph::shortest_path";
+	let err = |message: &str| Error::InvalidArguments {
+		name: name.to_owned(),
+		message: message.to_owned(),
+	};
+	// Process the function arguments
+	if !(3..=5).contains(&args.len()) {
+		return Err(err("Expected 3, 4, or 5 arguments."));
+	}
+	let mut args = args.into_iter();
+	let from = match args.next() {
+		Some(Value::Thing(v)) => v,
+		_ => return Err(err("Argument 1 was the wrong type. Expected a record.")),
+	};
+	let to = match args.next() {
+		Some(Value::Thing(v)) => v,
+		_ => return Err(err("Argument 2 was the wrong type. Expected a record.")),
+	};
+	let edge = match args.next() {
+		Some(Value::Strand(v)) => v.0,
+		Some(Value::Table(v)) => v.0,
+		_ => return Err(err("Argument 3 was the wrong type. Expected a table name.")),
+	};
+	let weight = match args.next() {
+		Some(Value::Strand(v)) => Some(Part::from(v.0)),
+		Some(Value::None | Value::Null) | None => None,
+		_ => return Err(err("Argument 4 was the wrong type. Expected a field name.")),
+	};
+	let limit = match args.next() {
+		Some(Value::Number(v)) if v.is_integer() && v.to_int() > 0 => v.to_usize(),
+		Some(Value::None | Value::Null) | None => MAX_DEPTH,
+		_ => return Err(err("Argument 5 was the wrong type. Expected a positive integer.")),
+	};
+	// Get the session details
+	let session = ctx.value("session").unwrap_or(&Value::None);
+	// Edges are read directly, so scope users can not search them
+	if session.pick(SC.as_ref()).is_some() {
+		return Err(Error::TablePermissions {
+			table: edge,
+		});
+	}
+	// Get the selected namespace
+	let ns = match session.pick(NS.as_ref()) {
+		Value::Strand(v) => v.0,
+		_ => return Err(Error::NsEmpty),
+	};
+	// Get the selected database
+	let db = match session.pick(DB.as_ref()) {
+		Value::Strand(v) => v.0,
+		_ => return Err(Error::DbEmpty),
+	};
+	// Clone transaction
+	let txn = ctx.clone_transaction()?;
+	// Claim transaction
+	let mut run = txn.lock().await;
+	// The lowest known cost to each record, and the edge by which it was reached
+	let mut best: HashMap<Thing, (f64, Option<(Thing, Thing)>)> = HashMap::new();
+	let mut heap = BinaryHeap::new();
+	best.insert(from.clone(), (0.0, None));
+	heap.push(State {
+		cost: 0.0,
+		depth: 0,
+		node: from.clone(),
+	});
+	let mut visits = 0;
+	while let Some(State {
+		cost,
+		depth,
+		node,
+	}) = heap.pop()
+	{
+		// Check if the context is finished
+		if ctx.is_done() {
+			break;
+		}
+		// Output the path once the target is reached
+		if node == to {
+			let mut path = vec![Value::from(to.clone())];
+			let mut edges = vec![];
+			let mut cur = to;
+			while let Some((_, Some((prev, via)))) = best.get(&cur) {
+				path.push(Value::from(prev.clone()));
+				edges.push(Value::from(via.clone()));
+				cur = prev.clone();
+			}
+			path.reverse();
+			edges.reverse();
+			let mut res = Object::default();
+			res.insert("cost".to_owned(), cost.into());
+			res.insert("edges".to_owned(), Value::from(Array::from(edges)));
+			res.insert("path".to_owned(), Value::from(Array::from(path)));
+			return Ok(res.into());
+		}
+		// Skip records which have since been reached more cheaply
+		if best.get(&node).map_or(false, |(c, _)| *c < cost) {
+			continue;
+		}
+		// Stop searching beyond the path limits
+		visits += 1;
+		if depth >= limit || visits > MAX_VISITS {
+			continue;
+		}
+		// Fetch the outgoing edges of this record
+		let beg = crate::key::graph::ftprefix(&ns, &db, &node.tb, &node.id, &Dir::Out, &edge);
+		let end = crate::key::graph::ftsuffix(&ns, &db, &node.tb, &node.id, &Dir::Out, &edge);
+		for (k, _) in run.scan(beg..end, u32::MAX).await? {
+			let gra: crate::key::graph::Graph = (&k).into();
+			let via = Thing::from((gra.ft, gra.fk));
+			// Fetch the edge record
+			let key = crate::key::thing::new(&ns, &db, &via.tb, &via.id);
+			let val = match run.get(key).await? {
+				Some(v) => Value::from(v),
+				None => continue,
+			};
+			// Get the record at the end of the edge
+			let next = match val.pick(OUT.as_ref()) {
+				Value::Thing(v) => v,
+				_ => continue,
+			};
+			// Get the cost of the edge
+			let step = match &weight {
+				Some(w) => match val.pick(std::slice::from_ref(w)) {
+					Value::Number(v) if v.to_float() >= 0.0 => v.to_float(),
+					Value::None | Value::Null => 1.0,
+					_ => {
+						return Err(Error::InvalidArguments {
+							name: name.to_owned(),
+							message: format!(
+								"The weight of edge {via} must be a number which is not negative."
+							),
+						})
+					}
+				},
+				None => 1.0,
+			};
+			// Keep the edge if it reaches the record more cheaply
+			let total = cost + step;
+			if best.get(&next).map_or(true, |(c, _)| total < *c) {
+				best.insert(next.clone(), (total, Some((node.clone(), via))));
+				heap.push(State {
+					cost: total,
+					depth: depth + 1,
+					node: next,
+				});
+			}
+		}
+	}
+	// There is no path within the limits
+	Ok(Value::None)
+}
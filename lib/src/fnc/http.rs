@@ -42,10 +42,13 @@ pub async fn delete(_: &Context<'_>, (_, _): (Value, Option<Value>)) -> Result<V
 }
 
 #[cfg(feature = "http")]
-fn try_as_uri(fn_name: &str, value: Value) -> Result<crate::sql::Strand, Error> {
+fn try_as_uri(ctx: &Context<'_>, fn_name: &str, value: Value) -> Result<crate::sql::Strand, Error> {
 	match value {
 		// Pre-check URI.
-		Value::Strand(uri) if crate::fnc::util::http::uri_is_valid(&uri) => Ok(uri),
+		Value::Strand(uri) if crate::fnc::util::http::uri_is_valid(&uri) => {
+			crate::fnc::util::http::check_host(&ctx.config().http, &uri)?;
+			Ok(uri)
+		}
 		_ => Err(Error::InvalidArguments {
 			name: fn_name.to_owned(),
 			// Assumption is that URI is first argument.
@@ -70,16 +73,40 @@ fn try_as_opts(
 	}
 }
 
+/// Queue a request which is made by an event, so that it is
+/// sent in the background once the transaction commits
+#[cfg(feature = "http")]
+#[cfg_attr(target_arch = "wasm32", allow(unused_variables))]
+async fn defer(
+	ctx: &Context<'_>,
+	method: crate::dbs::Method,
+	uri: &crate::sql::Strand,
+	body: &Value,
+	opts: &Option<crate::sql::Object>,
+) -> Result<bool, Error> {
+	#[cfg(not(target_arch = "wasm32"))]
+	if ctx.sandbox().map_or(false, |v| v.within(crate::ctx::sandbox::Kind::Event)) {
+		ctx.clone_transaction()?.lock().await.webhook(crate::dbs::Webhook {
+			method,
+			uri: uri.as_str().to_owned(),
+			body: body.clone(),
+			headers: opts.clone().unwrap_or_default(),
+		});
+		return Ok(true);
+	}
+	Ok(false)
+}
+
 #[cfg(feature = "http")]
 pub async fn head(ctx: &Context<'_>, (uri, opts): (Value, Option<Value>)) -> Result<Value, Error> {
-	let uri = try_as_uri("http::head", uri)?;
+	let uri = try_as_uri(ctx, "http::head", uri)?;
 	let opts = try_as_opts("http::head", "The second argument should be an object.", opts)?;
 	crate::fnc::util::http::head(ctx, uri, opts).await
 }
 
 #[cfg(feature = "http")]
 pub async fn get(ctx: &Context<'_>, (uri, opts): (Value, Option<Value>)) -> Result<Value, Error> {
-	let uri = try_as_uri("http::get", uri)?;
+	let uri = try_as_uri(ctx, "http::get", uri)?;
 	let opts = try_as_opts("http::get", "The second argument should be an object.", opts)?;
 	crate::fnc::util::http::get(ctx, uri, opts).await
 }
@@ -89,9 +116,13 @@ pub async fn put(
 	ctx: &Context<'_>,
 	(uri, body, opts): (Value, Option<Value>, Option<Value>),
 ) -> Result<Value, Error> {
-	let uri = try_as_uri("http::put", uri)?;
+	let uri = try_as_uri(ctx, "http::put", uri)?;
 	let opts = try_as_opts("http::put", "The third argument should be an object.", opts)?;
-	crate::fnc::util::http::put(ctx, uri, body.unwrap_or(Value::Null), opts).await
+	let body = body.unwrap_or(Value::Null);
+	if defer(ctx, crate::dbs::Method::Put, &uri, &body, &opts).await? {
+		return Ok(Value::None);
+	}
+	crate::fnc::util::http::put(ctx, uri, body, opts).await
 }
 
 #[cfg(feature = "http")]
@@ -99,9 +130,13 @@ pub async fn post(
 	ctx: &Context<'_>,
 	(uri, body, opts): (Value, Option<Value>, Option<Value>),
 ) -> Result<Value, Error> {
-	let uri = try_as_uri("http::post", uri)?;
+	let uri = try_as_uri(ctx, "http::post", uri)?;
 	let opts = try_as_opts("http::post", "The third argument should be an object.", opts)?;
-	crate::fnc::util::http::post(ctx, uri, body.unwrap_or(Value::Null), opts).await
+	let body = body.unwrap_or(Value::Null);
+	if defer(ctx, crate::dbs::Method::Post, &uri, &body, &opts).await? {
+		return Ok(Value::None);
+	}
+	crate::fnc::util::http::post(ctx, uri, body, opts).await
 }
 
 #[cfg(feature = "http")]
@@ -109,9 +144,13 @@ pub async fn patch(
 	ctx: &Context<'_>,
 	(uri, body, opts): (Value, Option<Value>, Option<Value>),
 ) -> Result<Value, Error> {
-	let uri = try_as_uri("http::patch", uri)?;
+	let uri = try_as_uri(ctx, "http::patch", uri)?;
 	let opts = try_as_opts("http::patch", "The third argument should be an object.", opts)?;
-	crate::fnc::util::http::patch(ctx, uri, body.unwrap_or(Value::Null), opts).await
+	let body = body.unwrap_or(Value::Null);
+	if defer(ctx, crate::dbs::Method::Patch, &uri, &body, &opts).await? {
+		return Ok(Value::None);
+	}
+	crate::fnc::util::http::patch(ctx, uri, body, opts).await
 }
 
 #[cfg(feature = "http")]
@@ -119,7 +158,10 @@ pub async fn delete(
 	ctx: &Context<'_>,
 	(uri, opts): (Value, Option<Value>),
 ) -> Result<Value, Error> {
-	let uri = try_as_uri("http::delete", uri)?;
+	let uri = try_as_uri(ctx, "http::delete", uri)?;
 	let opts = try_as_opts("http::delete", "The second argument should be an object.", opts)?;
+	if defer(ctx, crate::dbs::Method::Delete, &uri, &Value::None, &opts).await? {
+		return Ok(Value::None);
+	}
 	crate::fnc::util::http::delete(ctx, uri, opts).await
 }
@@ -19,6 +19,18 @@ pub fn abs((arg,): (Number,)) -> Result<Value, Error> {
 	Ok(arg.abs().into())
 }
 
+pub fn acos((arg,): (Number,)) -> Result<Value, Error> {
+	Ok(float(arg.as_float().acos()))
+}
+
+pub fn asin((arg,): (Number,)) -> Result<Value, Error> {
+	Ok(float(arg.as_float().asin()))
+}
+
+pub fn atan((arg,): (Number,)) -> Result<Value, Error> {
+	Ok(float(arg.as_float().atan()))
+}
+
 pub fn bottom((array, c): (Vec<Number>, i64)) -> Result<Value, Error> {
 	if c > 0 {
 		Ok(array.bottom(c).into())
@@ -34,6 +46,18 @@ pub fn ceil((arg,): (Number,)) -> Result<Value, Error> {
 	Ok(arg.ceil().into())
 }
 
+pub fn cos((arg,): (Number,)) -> Result<Value, Error> {
+	Ok(float(arg.as_float().cos()))
+}
+
+pub fn cot((arg,): (Number,)) -> Result<Value, Error> {
+	Ok(float(1.0 / arg.as_float().tan()))
+}
+
+pub fn deg2rad((arg,): (Number,)) -> Result<Value, Error> {
+	Ok(float(arg.as_float().to_radians()))
+}
+
 pub fn fixed((arg, p): (Number, i64)) -> Result<Value, Error> {
 	if p > 0 {
 		Ok(arg.fixed(p as usize).into())
@@ -53,6 +77,22 @@ pub fn interquartile((mut array,): (Vec<Number>,)) -> Result<Value, Error> {
 	Ok(array.sorted().interquartile().into())
 }
 
+pub fn ln((arg,): (Number,)) -> Result<Value, Error> {
+	Ok(float(arg.as_float().ln()))
+}
+
+pub fn log((arg, base): (Number, Number)) -> Result<Value, Error> {
+	Ok(float(arg.as_float().log(base.as_float())))
+}
+
+pub fn log10((arg,): (Number,)) -> Result<Value, Error> {
+	Ok(float(arg.as_float().log10()))
+}
+
+pub fn log2((arg,): (Number,)) -> Result<Value, Error> {
+	Ok(float(arg.as_float().log2()))
+}
+
 pub fn max((array,): (Vec<Number>,)) -> Result<Value, Error> {
 	Ok(match array.into_iter().max() {
 		Some(v) => v.into(),
@@ -61,7 +101,11 @@ pub fn max((array,): (Vec<Number>,)) -> Result<Value, Error> {
 }
 
 pub fn mean((array,): (Vec<Number>,)) -> Result<Value, Error> {
-	Ok(array.mean().into())
+	Ok(match array.iter().any(Number::is_decimal) {
+		// Decimals are averaged without conversion to floats
+		true => (array.iter().sum::<Number>() / Number::from(array.len())).into(),
+		false => array.mean().into(),
+	})
 }
 
 pub fn median((mut array,): (Vec<Number>,)) -> Result<Value, Error> {
@@ -102,10 +146,26 @@ pub fn product((array,): (Vec<Number>,)) -> Result<Value, Error> {
 	Ok(array.into_iter().product::<Number>().into())
 }
 
+pub fn rad2deg((arg,): (Number,)) -> Result<Value, Error> {
+	Ok(float(arg.as_float().to_degrees()))
+}
+
 pub fn round((arg,): (Number,)) -> Result<Value, Error> {
 	Ok(arg.round().into())
 }
 
+pub fn sign((arg,): (Number,)) -> Result<Value, Error> {
+	Ok(match arg {
+		v if v > Number::Int(0) => Value::from(1),
+		v if v < Number::Int(0) => Value::from(-1),
+		_ => Value::from(0),
+	})
+}
+
+pub fn sin((arg,): (Number,)) -> Result<Value, Error> {
+	Ok(float(arg.as_float().sin()))
+}
+
 pub fn spread((array,): (Vec<Number>,)) -> Result<Value, Error> {
 	Ok(array.spread().into())
 }
@@ -125,6 +185,10 @@ pub fn sum((array,): (Vec<Number>,)) -> Result<Value, Error> {
 	Ok(array.into_iter().sum::<Number>().into())
 }
 
+pub fn tan((arg,): (Number,)) -> Result<Value, Error> {
+	Ok(float(arg.as_float().tan()))
+}
+
 pub fn top((array, c): (Vec<Number>, i64)) -> Result<Value, Error> {
 	if c > 0 {
 		Ok(array.top(c).into())
@@ -143,3 +207,12 @@ pub fn trimean((mut array,): (Vec<Number>,)) -> Result<Value, Error> {
 pub fn variance((array,): (Vec<Number>,)) -> Result<Value, Error> {
 	Ok(array.variance(true).into())
 }
+
+/// Results which are not a real number, such as the
+/// logarithm of a negative number, are returned as NONE
+fn float(v: f64) -> Value {
+	match v.is_finite() {
+		true => v.into(),
+		false => Value::None,
+	}
+}
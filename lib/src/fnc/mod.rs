@@ -10,31 +10,46 @@ pub mod count;
 pub mod crypto;
 pub mod duration;
 pub mod encoding;
+pub mod fuzzy;
 pub mod geo;
+pub mod graph;
 pub mod http;
 pub mod is;
 pub mod math;
 pub mod meta;
 pub mod not;
+pub mod object;
 pub mod operate;
 pub mod parse;
 pub mod rand;
 pub mod script;
+pub mod search;
+pub mod sequence;
 pub mod session;
 pub mod sleep;
 pub mod string;
 pub mod time;
 pub mod r#type;
 pub mod util;
+pub mod vector;
 
 /// Attempts to run any function
 pub async fn run(ctx: &Context<'_>, name: &str, args: Vec<Value>) -> Result<Value, Error> {
+	// Check the sandbox limits for outbound calls
+	if name.starts_with("http") {
+		if let Some(sandbox) = ctx.sandbox() {
+			sandbox.call()?;
+		}
+	}
 	if name.eq("sleep")
 		|| name.starts_with("http")
 		|| name.starts_with("crypto::argon2")
 		|| name.starts_with("crypto::bcrypt")
 		|| name.starts_with("crypto::pbkdf2")
 		|| name.starts_with("crypto::scrypt")
+		|| name.starts_with("graph")
+		|| name.starts_with("search")
+		|| name.starts_with("sequence")
 	{
 		asynchronous(ctx, name, args).await
 	} else {
@@ -84,6 +99,7 @@ pub fn synchronous(ctx: &Context<'_>, name: &str, args: Vec<Value>) -> Result<Va
 		"array::len" => array::len,
 		"array::max" => array::max,
 		"array::min" => array::min,
+		"array::pivot" => array::pivot,
 		"array::pop" => array::pop,
 		"array::prepend" => array::prepend,
 		"array::push" => array::push,
@@ -99,6 +115,9 @@ pub fn synchronous(ctx: &Context<'_>, name: &str, args: Vec<Value>) -> Result<Va
 		//
 		"count" => count::count,
 		//
+		"crypto::hmac::sha1" => crypto::hmac::sha1,
+		"crypto::hmac::sha256" => crypto::hmac::sha256,
+		"crypto::hmac::sha512" => crypto::hmac::sha512,
 		"crypto::md5" => crypto::md5,
 		"crypto::sha1" => crypto::sha1,
 		"crypto::sha256" => crypto::sha256,
@@ -125,6 +144,8 @@ pub fn synchronous(ctx: &Context<'_>, name: &str, args: Vec<Value>) -> Result<Va
 		"encoding::base64::decode" => encoding::base64::decode,
 		"encoding::base64::encode" => encoding::base64::encode,
 		//
+		"fuzzy" => fuzzy::fuzzy,
+		//
 		"geo::area" => geo::area,
 		"geo::bearing" => geo::bearing,
 		"geo::centroid" => geo::centroid,
@@ -147,11 +168,21 @@ pub fn synchronous(ctx: &Context<'_>, name: &str, args: Vec<Value>) -> Result<Va
 		"is::uuid" => is::uuid,
 		//
 		"math::abs" => math::abs,
+		"math::acos" => math::acos,
+		"math::asin" => math::asin,
+		"math::atan" => math::atan,
 		"math::bottom" => math::bottom,
 		"math::ceil" => math::ceil,
+		"math::cos" => math::cos,
+		"math::cot" => math::cot,
+		"math::deg2rad" => math::deg2rad,
 		"math::fixed" => math::fixed,
 		"math::floor" => math::floor,
 		"math::interquartile" => math::interquartile,
+		"math::ln" => math::ln,
+		"math::log" => math::log,
+		"math::log10" => math::log10,
+		"math::log2" => math::log2,
 		"math::max" => math::max,
 		"math::mean" => math::mean,
 		"math::median" => math::median,
@@ -162,11 +193,15 @@ pub fn synchronous(ctx: &Context<'_>, name: &str, args: Vec<Value>) -> Result<Va
 		"math::percentile" => math::percentile,
 		"math::pow" => math::pow,
 		"math::product" => math::product,
+		"math::rad2deg" => math::rad2deg,
 		"math::round" => math::round,
+		"math::sign" => math::sign,
+		"math::sin" => math::sin,
 		"math::spread" => math::spread,
 		"math::sqrt" => math::sqrt,
 		"math::stddev" => math::stddev,
 		"math::sum" => math::sum,
+		"math::tan" => math::tan,
 		"math::top" => math::top,
 		"math::trimean" => math::trimean,
 		"math::variance" => math::variance,
@@ -177,6 +212,13 @@ pub fn synchronous(ctx: &Context<'_>, name: &str, args: Vec<Value>) -> Result<Va
 		//
 		"not" => not::not,
 		//
+		"object::entries" => object::entries,
+		"object::from_entries" => object::from_entries,
+		"object::keys" => object::keys,
+		"object::len" => object::len,
+		"object::merge" => object::merge,
+		"object::values" => object::values,
+		//
 		"parse::email::host" => parse::email::host,
 		"parse::email::user" => parse::email::user,
 		"parse::url::domain" => parse::url::domain,
@@ -211,6 +253,7 @@ pub fn synchronous(ctx: &Context<'_>, name: &str, args: Vec<Value>) -> Result<Va
 		//
 		"string::concat" => string::concat,
 		"string::contains" => string::contains,
+		"string::distance::levenshtein" => string::distance::levenshtein,
 		"string::endsWith" => string::ends_with,
 		"string::join" => string::join,
 		"string::len" => string::len,
@@ -218,6 +261,8 @@ pub fn synchronous(ctx: &Context<'_>, name: &str, args: Vec<Value>) -> Result<Va
 		"string::repeat" => string::repeat,
 		"string::replace" => string::replace,
 		"string::reverse" => string::reverse,
+		"string::similarity::fuzzy" => string::similarity::fuzzy,
+		"string::similarity::trigram" => string::similarity::trigram,
 		"string::slice" => string::slice,
 		"string::slug" => string::slug,
 		"string::split" => string::split,
@@ -226,6 +271,7 @@ pub fn synchronous(ctx: &Context<'_>, name: &str, args: Vec<Value>) -> Result<Va
 		"string::uppercase" => string::uppercase,
 		"string::words" => string::words,
 		//
+		"time::bucket" => time::bucket,
 		"time::ceil" => time::ceil,
 		"time::day" => time::day,
 		"time::floor" => time::floor,
@@ -248,6 +294,8 @@ pub fn synchronous(ctx: &Context<'_>, name: &str, args: Vec<Value>) -> Result<Va
 		"time::from::millis" => time::from::millis,
 		"time::from::secs" => time::from::secs,
 		"time::from::unix" => time::from::unix,
+		"time::tz::format" => time::tz::format,
+		"time::tz::group" => time::tz::group,
 		//
 		"type::bool" => r#type::bool,
 		"type::datetime" => r#type::datetime,
@@ -260,6 +308,39 @@ pub fn synchronous(ctx: &Context<'_>, name: &str, args: Vec<Value>) -> Result<Va
 		"type::string" => r#type::string,
 		"type::table" => r#type::table,
 		"type::thing" => r#type::thing,
+		"type::is::array" => r#type::is::array,
+		"type::is::bool" => r#type::is::bool,
+		"type::is::bytes" => r#type::is::bytes,
+		"type::is::datetime" => r#type::is::datetime,
+		"type::is::decimal" => r#type::is::decimal,
+		"type::is::duration" => r#type::is::duration,
+		"type::is::float" => r#type::is::float,
+		"type::is::geometry" => r#type::is::geometry,
+		"type::is::int" => r#type::is::int,
+		"type::is::none" => r#type::is::none,
+		"type::is::null" => r#type::is::null,
+		"type::is::number" => r#type::is::number,
+		"type::is::object" => r#type::is::object,
+		"type::is::point" => r#type::is::point,
+		"type::is::record" => r#type::is::record,
+		"type::is::string" => r#type::is::string,
+		"type::is::uuid" => r#type::is::uuid,
+		//
+		"vector::add" => vector::add,
+		"vector::angle" => vector::angle,
+		"vector::cross" => vector::cross,
+		"vector::distance::chebyshev" => vector::distance::chebyshev,
+		"vector::distance::euclidean" => vector::distance::euclidean,
+		"vector::distance::manhattan" => vector::distance::manhattan,
+		"vector::divide" => vector::divide,
+		"vector::dot" => vector::dot,
+		"vector::magnitude" => vector::magnitude,
+		"vector::multiply" => vector::multiply,
+		"vector::normalize" => vector::normalize,
+		"vector::project" => vector::project,
+		"vector::similarity::cosine" => vector::similarity::cosine,
+		"vector::similarity::euclidean" => vector::similarity::euclidean,
+		"vector::subtract" => vector::subtract,
 	)
 }
 
@@ -293,6 +374,8 @@ pub async fn asynchronous(ctx: &Context<'_>, name: &str, args: Vec<Value>) -> Re
 		"crypto::scrypt::compare" => (cpu_intensive) crypto::scrypt::cmp.await,
 		"crypto::scrypt::generate" => (cpu_intensive) crypto::scrypt::gen.await,
 		//
+		"graph::shortest_path" => graph::shortest_path(ctx).await,
+		//
 		"http::head" => http::head(ctx).await,
 		"http::get" => http::get(ctx).await,
 		"http::put" => http::put(ctx).await,
@@ -300,6 +383,10 @@ pub async fn asynchronous(ctx: &Context<'_>, name: &str, args: Vec<Value>) -> Re
 		"http::patch" => http::patch(ctx).await,
 		"http::delete" => http::delete(ctx).await,
 		//
+		"search::analyze" => search::analyze(ctx).await,
+		//
+		"sequence::next" => sequence::next(ctx).await,
+		//
 		"sleep" => sleep::sleep(ctx).await,
 	)
 }
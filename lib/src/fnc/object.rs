@@ -0,0 +1,47 @@
+use crate::err::Error;
+use crate::sql::array::Array;
+use crate::sql::object::Object;
+use crate::sql::value::Value;
+
+pub fn entries((object,): (Object,)) -> Result<Value, Error> {
+	Ok(Value::Array(Array(
+		object.0.into_iter().map(|(k, v)| Value::Array(Array(vec![k.into(), v]))).collect(),
+	)))
+}
+
+pub fn from_entries((array,): (Array,)) -> Result<Value, Error> {
+	let mut obj = Object::default();
+	for v in array {
+		match v {
+			Value::Array(Array(v)) if v.len() == 2 => {
+				let mut v = v.into_iter();
+				let k = v.next().unwrap_or_default().as_raw_string();
+				obj.insert(k, v.next().unwrap_or_default());
+			}
+			_ => {
+				return Err(Error::InvalidArguments {
+					name: String::from("object::from_entries"),
+					message: String::from("The argument must be an array of [key, value] pairs."),
+				})
+			}
+		}
+	}
+	Ok(obj.into())
+}
+
+pub fn keys((object,): (Object,)) -> Result<Value, Error> {
+	Ok(Value::Array(Array(object.0.into_keys().map(Value::from).collect())))
+}
+
+pub fn len((object,): (Object,)) -> Result<Value, Error> {
+	Ok(object.len().into())
+}
+
+pub fn merge((mut object, other): (Object, Object)) -> Result<Value, Error> {
+	object.0.extend(other.0);
+	Ok(object.into())
+}
+
+pub fn values((object,): (Object,)) -> Result<Value, Error> {
+	Ok(Value::Array(Array(object.0.into_values().collect())))
+}
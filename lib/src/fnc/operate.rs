@@ -7,6 +7,8 @@ use crate::sql::value::TryPow;
 use crate::sql::value::TrySub;
 use crate::sql::value::Value;
 use crate::sql::Expression;
+use crate::sql::Operator;
+use std::mem::discriminant;
 
 pub fn or(a: Value, b: Value) -> Result<Value, Error> {
 	Ok(match a.is_truthy() {
@@ -56,6 +58,19 @@ pub fn pow(a: Value, b: Value) -> Result<Value, Error> {
 	a.try_pow(b)
 }
 
+/// Check that two values can be compared when strict types are enabled,
+/// which is the case if they are of the same type, if either is missing,
+/// or if either is a regex, which is matched against the other value
+pub fn comparable(o: &Operator, a: &Value, b: &Value) -> Result<(), Error> {
+	match (a, b) {
+		(Value::None | Value::Null, _) | (_, Value::None | Value::Null) => Ok(()),
+		(Value::Regex(_), _) | (_, Value::Regex(_)) => Ok(()),
+		(Value::Number(_), Value::Number(_)) => Ok(()),
+		(a, b) if discriminant(a) == discriminant(b) => Ok(()),
+		(a, b) => Err(Error::TryCompare(o.to_string(), a.to_string(), b.to_string())),
+	}
+}
+
 pub fn exact(a: &Value, b: &Value) -> Result<Value, Error> {
 	Ok(Value::from(a == b))
 }
@@ -31,8 +31,11 @@ pub async fn run(
 	let run = js::AsyncRuntime::new().unwrap();
 	// Explicitly set max stack size to 256 KiB
 	run.set_max_stack_size(262_144).await;
-	// Explicitly set max memory size to 2 MB
-	run.set_memory_limit(2_000_000).await;
+	// Explicitly set max memory size, which can be configured for sandboxes
+	match ctx.sandbox() {
+		Some(v) => run.set_memory_limit(v.limits().memory).await,
+		None => run.set_memory_limit(2_000_000).await,
+	}
 	// Ensure scripts are cancelled with context
 	let cancellation = ctx.cancellation();
 	let handler = Box::new(move || cancellation.is_done());
@@ -24,6 +24,7 @@ impl_module_def!(
 	"len" => run,
 	"max" => run,
 	"min" => run,
+	"pivot" => run,
 	"pop" => run,
 	"push" => run,
 	"prepend" => run,
@@ -0,0 +1,12 @@
+use super::super::run;
+use crate::fnc::script::modules::impl_module_def;
+
+pub struct Package;
+
+impl_module_def!(
+	Package,
+	"crypto::hmac",
+	"sha1" => run,
+	"sha256" => run,
+	"sha512" => run
+);
@@ -7,11 +7,21 @@ impl_module_def!(
 	Package,
 	"math",
 	"abs" => run,
+	"acos" => run,
+	"asin" => run,
+	"atan" => run,
 	"bottom" => run,
 	"ceil" => run,
+	"cos" => run,
+	"cot" => run,
+	"deg2rad" => run,
 	"fixed" => run,
 	"floor" => run,
 	"interquartile" => run,
+	"ln" => run,
+	"log" => run,
+	"log10" => run,
+	"log2" => run,
 	"max" => run,
 	"mean" => run,
 	"median" => run,
@@ -22,11 +32,15 @@ impl_module_def!(
 	"percentile" => run,
 	"pow" => run,
 	"product" => run,
+	"rad2deg" => run,
 	"round" => run,
+	"sign" => run,
+	"sin" => run,
 	"spread" => run,
 	"sqrt" => run,
 	"stddev" => run,
 	"sum" => run,
+	"tan" => run,
 	"top" => run,
 	"trimean" => run,
 	"variance" => run
@@ -11,16 +11,21 @@ mod crypto;
 mod duration;
 mod encoding;
 mod geo;
+mod graph;
 mod http;
 mod is;
 mod math;
 mod meta;
+mod object;
 mod parse;
 mod rand;
+mod search;
+mod sequence;
 mod session;
 mod string;
 mod time;
 mod r#type;
+mod vector;
 
 pub struct Package;
 
@@ -33,20 +38,26 @@ impl_module_def!(
 	"crypto" => (crypto::Package),
 	"duration" => (duration::Package),
 	"encoding" => (encoding::Package),
+	"fuzzy" => run,
 	"geo" => (geo::Package),
+	"graph" => (graph::Package),
 	"http" => (http::Package),
 	"is" => (is::Package),
 	"math" => (math::Package),
 	"meta" => (meta::Package),
 	"not" => run,
+	"object" => (object::Package),
 	"parse" => (parse::Package),
 	"rand" => (rand::Package),
 	"array" => (array::Package),
+	"search" => (search::Package),
+	"sequence" => (sequence::Package),
 	"session" => (session::Package),
 	"sleep" => fut Async,
 	"string" => (string::Package),
 	"time" => (time::Package),
-	"type" => (r#type::Package)
+	"type" => (r#type::Package),
+	"vector" => (vector::Package)
 );
 
 fn run(js_ctx: js::Ctx<'_>, name: &str, args: Vec<Value>) -> Result<Value> {
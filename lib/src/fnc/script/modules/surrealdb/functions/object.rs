@@ -0,0 +1,15 @@
+use super::run;
+use crate::fnc::script::modules::impl_module_def;
+
+pub struct Package;
+
+impl_module_def!(
+	Package,
+	"object",
+	"entries" => run,
+	"from_entries" => run,
+	"keys" => run,
+	"len" => run,
+	"merge" => run,
+	"values" => run
+);
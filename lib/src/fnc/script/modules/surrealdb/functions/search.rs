@@ -0,0 +1,11 @@
+use super::fut;
+use crate::fnc::script::modules::impl_module_def;
+use js::prelude::Async;
+
+pub struct Package;
+
+impl_module_def!(
+	Package,
+	"search",
+	"analyze" => fut Async
+);
@@ -2,12 +2,14 @@ use super::run;
 use crate::fnc::script::modules::impl_module_def;
 
 mod from;
+mod tz;
 
 pub struct Package;
 
 impl_module_def!(
 	Package,
 	"time",
+	"bucket" => run,
 	"ceil" => run,
 	"day" => run,
 	"floor" => run,
@@ -28,5 +30,6 @@ impl_module_def!(
 	"week" => run,
 	"yday" => run,
 	"year" => run,
-	"from" => (from::Package)
+	"from" => (from::Package),
+	"tz" => (tz::Package)
 );
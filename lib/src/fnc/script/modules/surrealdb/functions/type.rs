@@ -1,6 +1,8 @@
 use super::run;
 use crate::fnc::script::modules::impl_module_def;
 
+mod is;
+
 pub struct Package;
 
 impl_module_def!(
@@ -17,5 +19,6 @@ impl_module_def!(
 	"regex" => run,
 	"string" => run,
 	"table" => run,
-	"thing" => run
+	"thing" => run,
+	"is" => (is::Package)
 );
@@ -0,0 +1,26 @@
+use super::run;
+use crate::fnc::script::modules::impl_module_def;
+
+pub struct Package;
+
+impl_module_def!(
+	Package,
+	"type::is",
+	"array" => run,
+	"bool" => run,
+	"bytes" => run,
+	"datetime" => run,
+	"decimal" => run,
+	"duration" => run,
+	"float" => run,
+	"geometry" => run,
+	"int" => run,
+	"none" => run,
+	"null" => run,
+	"number" => run,
+	"object" => run,
+	"point" => run,
+	"record" => run,
+	"string" => run,
+	"uuid" => run
+);
@@ -0,0 +1,24 @@
+use super::run;
+use crate::fnc::script::modules::impl_module_def;
+
+mod distance;
+mod similarity;
+
+pub struct Package;
+
+impl_module_def!(
+	Package,
+	"vector",
+	"add" => run,
+	"angle" => run,
+	"cross" => run,
+	"distance" => (distance::Package),
+	"divide" => run,
+	"dot" => run,
+	"magnitude" => run,
+	"multiply" => run,
+	"normalize" => run,
+	"project" => run,
+	"similarity" => (similarity::Package),
+	"subtract" => run
+);
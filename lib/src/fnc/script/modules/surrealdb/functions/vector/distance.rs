@@ -0,0 +1,12 @@
+use super::run;
+use crate::fnc::script::modules::impl_module_def;
+
+pub struct Package;
+
+impl_module_def!(
+	Package,
+	"vector::distance",
+	"chebyshev" => run,
+	"euclidean" => run,
+	"manhattan" => run
+);
@@ -0,0 +1,28 @@
+use crate::ctx::Context;
+use crate::err::Error;
+use crate::idx::ft::analyzer::Analyzer;
+use crate::sql::paths::DB;
+use crate::sql::paths::NS;
+use crate::sql::value::Value;
+
+pub async fn analyze(ctx: &Context<'_>, (az, val): (String, String)) -> Result<Value, Error> {
+	// Get the session details
+	let session = ctx.value("session").unwrap_or(&Value::None);
+	// Get the selected namespace
+	let ns = match session.pick(NS.as_ref()) {
+		Value::Strand(v) => v.0,
+		_ => return Err(Error::NsEmpty),
+	};
+	// Get the selected database
+	let db = match session.pick(DB.as_ref()) {
+		Value::Strand(v) => v.0,
+		_ => return Err(Error::DbEmpty),
+	};
+	// Clone transaction
+	let txn = ctx.clone_transaction()?;
+	// Fetch the analyzer definition
+	let az = txn.lock().await.get_az(&ns, &db, &az).await?;
+	// Output the terms of the analyzed value
+	let az: Analyzer = az.into();
+	Ok(az.terms(val).into_iter().map(Value::from).collect::<Vec<_>>().into())
+}
@@ -0,0 +1,26 @@
+use crate::ctx::Context;
+use crate::err::Error;
+use crate::sql::paths::DB;
+use crate::sql::paths::NS;
+use crate::sql::value::Value;
+
+pub async fn next(ctx: &Context<'_>, (name,): (String,)) -> Result<Value, Error> {
+	// Get the session details
+	let session = ctx.value("session").unwrap_or(&Value::None);
+	// Get the selected namespace
+	let ns = match session.pick(NS.as_ref()) {
+		Value::Strand(v) => v.0,
+		_ => return Err(Error::NsEmpty),
+	};
+	// Get the selected database
+	let db = match session.pick(DB.as_ref()) {
+		Value::Strand(v) => v.0,
+		_ => return Err(Error::DbEmpty),
+	};
+	// Clone transaction
+	let txn = ctx.clone_transaction()?;
+	// Claim transaction
+	let mut run = txn.lock().await;
+	// Take the next sequence value
+	run.next_sq(&ns, &db, &name).await.map(Value::from)
+}
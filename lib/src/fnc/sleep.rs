@@ -2,6 +2,10 @@ use crate::ctx::Context;
 use crate::err::Error;
 use crate::sql::Duration;
 use crate::sql::Value;
+use trice::Instant;
+
+/// How often a sleep checks if its query has been cancelled
+const INTERVAL: std::time::Duration = std::time::Duration::from_millis(10);
 
 /// Sleep during the provided duration parameter.
 pub async fn sleep(ctx: &Context<'_>, (dur,): (Duration,)) -> Result<Value, Error> {
@@ -10,11 +14,18 @@ pub async fn sleep(ctx: &Context<'_>, (dur,): (Duration,)) -> Result<Value, Erro
 		(Some(t), d) if t < d => t,
 		(_, d) => d,
 	};
-	// Sleep for the specified time
-	#[cfg(target_arch = "wasm32")]
-	wasmtimer::tokio::sleep(dur).await;
-	#[cfg(not(target_arch = "wasm32"))]
-	tokio::time::sleep(dur).await;
+	// Sleep for the specified time, or until the query is cancelled
+	let now = Instant::now();
+	while ctx.is_ok() {
+		let left = match dur.checked_sub(now.elapsed()) {
+			Some(v) if !v.is_zero() => v.min(INTERVAL),
+			_ => break,
+		};
+		#[cfg(target_arch = "wasm32")]
+		wasmtimer::tokio::sleep(left).await;
+		#[cfg(not(target_arch = "wasm32"))]
+		tokio::time::sleep(left).await;
+	}
 	// Ok all good
 	Ok(Value::None)
 }
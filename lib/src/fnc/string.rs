@@ -133,6 +133,51 @@ pub fn words((string,): (String,)) -> Result<Value, Error> {
 	Ok(string.split_whitespace().collect::<Vec<&str>>().into())
 }
 
+pub mod distance {
+
+	use crate::err::Error;
+	use crate::fnc::util::string;
+	use crate::sql::value::Value;
+
+	pub fn levenshtein((a, b): (String, String)) -> Result<Value, Error> {
+		super::edits("string::distance::levenshtein", &a, &b)?;
+		Ok((string::levenshtein(&a, &b) as i64).into())
+	}
+}
+
+pub mod similarity {
+
+	use crate::err::Error;
+	use crate::fnc::util::string;
+	use crate::sql::value::Value;
+	use fuzzy_matcher::skim::SkimMatcherV2;
+	use fuzzy_matcher::FuzzyMatcher;
+	use once_cell::sync::Lazy;
+
+	static MATCHER: Lazy<SkimMatcherV2> = Lazy::new(|| SkimMatcherV2::default().ignore_case());
+
+	pub fn fuzzy((a, b): (String, String)) -> Result<Value, Error> {
+		Ok(MATCHER.fuzzy_match(&a, &b).unwrap_or(0).into())
+	}
+
+	pub fn trigram((a, b): (String, String)) -> Result<Value, Error> {
+		Ok(string::similarity(&a, &b).into())
+	}
+}
+
+/// Returns an error if the edit distance between these strings is too much to compute.
+pub(crate) fn edits(name: &str, a: &str, b: &str) -> Result<(), Error> {
+	const LIMIT: usize = 2usize.pow(24);
+	if a.chars().count().saturating_mul(b.chars().count()) > LIMIT {
+		Err(Error::InvalidArguments {
+			name: name.to_owned(),
+			message: format!("The product of the string lengths must not exceed {LIMIT}."),
+		})
+	} else {
+		Ok(())
+	}
+}
+
 #[cfg(test)]
 mod tests {
 	use super::{contains, slice};
@@ -5,6 +5,22 @@ use crate::sql::value::Value;
 use chrono::offset::TimeZone;
 use chrono::{DateTime, Datelike, DurationRound, Local, Timelike, Utc};
 
+pub fn bucket((val, duration): (Datetime, Duration)) -> Result<Value, Error> {
+	match chrono::Duration::from_std(*duration) {
+		Ok(d) if !d.is_zero() => match val.duration_trunc(d) {
+			Ok(v) => Ok(v.into()),
+			_ => Err(Error::InvalidArguments {
+				name: String::from("time::bucket"),
+				message: String::from("The second argument must be a duration, and must be able to be represented as nanoseconds."),
+			}),
+		},
+		_ => Err(Error::InvalidArguments {
+			name: String::from("time::bucket"),
+			message: String::from("The second argument must be a duration which is greater than zero."),
+		}),
+	}
+}
+
 pub fn ceil((val, duration): (Datetime, Duration)) -> Result<Value, Error> {
 	match chrono::Duration::from_std(*duration) {
 		Ok(d) => {
@@ -282,3 +298,53 @@ pub mod from {
 		}
 	}
 }
+
+pub mod tz {
+
+	use crate::err::Error;
+	use crate::sql::datetime::{zone, Datetime};
+	use crate::sql::value::Value;
+	use chrono::{Datelike, FixedOffset, TimeZone, Timelike, Utc};
+
+	/// Parse a timezone offset in the form `Z`, `+hh:mm`, or `-hh:mm`
+	fn offset(name: &str, tz: &str) -> Result<FixedOffset, Error> {
+		match zone(tz) {
+			Ok(("", v)) => Ok(v),
+			_ => Err(Error::InvalidArguments {
+				name: name.to_owned(),
+				message: String::from("The second argument must be a timezone offset, such as 'Z', '+05:30', or '-08:00'."),
+			}),
+		}
+	}
+
+	pub fn format((val, tz, format): (Datetime, String, String)) -> Result<Value, Error> {
+		let tz = offset("time::tz::format", &tz)?;
+		Ok(val.with_timezone(&tz).format(&format).to_string().into())
+	}
+
+	pub fn group((val, tz, group): (Datetime, String, String)) -> Result<Value, Error> {
+		let tz = offset("time::tz::group", &tz)?;
+		// Group by the calendar of the timezone
+		let v = val.with_timezone(&tz);
+		let (y, mo, d, h, mi, s) = match group.as_str() {
+			"year" => (v.year(), 1, 1, 0, 0, 0),
+			"month" => (v.year(), v.month(), 1, 0, 0, 0),
+			"day" => (v.year(), v.month(), v.day(), 0, 0, 0),
+			"hour" => (v.year(), v.month(), v.day(), v.hour(), 0, 0),
+			"minute" => (v.year(), v.month(), v.day(), v.hour(), v.minute(), 0),
+			"second" => (v.year(), v.month(), v.day(), v.hour(), v.minute(), v.second()),
+			_ => return Err(Error::InvalidArguments {
+				name: String::from("time::tz::group"),
+				message: String::from("The third argument must be a string, and can be one of 'year', 'month', 'day', 'hour', 'minute', or 'second'."),
+			}),
+		};
+		// Output the start of the group as a UTC datetime
+		match tz.with_ymd_and_hms(y, mo, d, h, mi, s).earliest() {
+			Some(v) => Ok(Datetime::from(v.with_timezone(&Utc)).into()),
+			None => Err(Error::InvalidArguments {
+				name: String::from("time::tz::group"),
+				message: String::from("The first argument must be a datetime which can be represented in the timezone."),
+			}),
+		}
+	}
+}
@@ -65,3 +65,82 @@ pub fn thing((arg1, arg2): (Value, Option<Value>)) -> Result<Value, Error> {
 		}
 	})
 }
+
+pub mod is {
+
+	use crate::err::Error;
+	use crate::sql::table::Table;
+	use crate::sql::value::Value;
+
+	pub fn array((arg,): (Value,)) -> Result<Value, Error> {
+		Ok(arg.is_array().into())
+	}
+
+	pub fn bool((arg,): (Value,)) -> Result<Value, Error> {
+		Ok(arg.is_bool().into())
+	}
+
+	pub fn bytes((arg,): (Value,)) -> Result<Value, Error> {
+		Ok(arg.is_bytes().into())
+	}
+
+	pub fn datetime((arg,): (Value,)) -> Result<Value, Error> {
+		Ok(arg.is_datetime().into())
+	}
+
+	pub fn decimal((arg,): (Value,)) -> Result<Value, Error> {
+		Ok(arg.is_decimal().into())
+	}
+
+	pub fn duration((arg,): (Value,)) -> Result<Value, Error> {
+		Ok(arg.is_duration().into())
+	}
+
+	pub fn float((arg,): (Value,)) -> Result<Value, Error> {
+		Ok(arg.is_float().into())
+	}
+
+	pub fn geometry((arg,): (Value,)) -> Result<Value, Error> {
+		Ok(arg.is_geometry().into())
+	}
+
+	pub fn int((arg,): (Value,)) -> Result<Value, Error> {
+		Ok(arg.is_int().into())
+	}
+
+	pub fn none((arg,): (Value,)) -> Result<Value, Error> {
+		Ok(arg.is_none().into())
+	}
+
+	pub fn null((arg,): (Value,)) -> Result<Value, Error> {
+		Ok(arg.is_null().into())
+	}
+
+	pub fn number((arg,): (Value,)) -> Result<Value, Error> {
+		Ok(arg.is_number().into())
+	}
+
+	pub fn object((arg,): (Value,)) -> Result<Value, Error> {
+		Ok(arg.is_object().into())
+	}
+
+	pub fn point((arg,): (Value,)) -> Result<Value, Error> {
+		Ok(arg.is_geometry_type(&[String::from("point")]).into())
+	}
+
+	pub fn record((arg, table): (Value, Option<String>)) -> Result<Value, Error> {
+		Ok(match table {
+			Some(tb) => arg.is_record_type(&[Table(tb)]),
+			None => arg.is_record(),
+		}
+		.into())
+	}
+
+	pub fn string((arg,): (Value,)) -> Result<Value, Error> {
+		Ok(arg.is_strand().into())
+	}
+
+	pub fn uuid((arg,): (Value,)) -> Result<Value, Error> {
+		Ok(arg.is_uuid().into())
+	}
+}
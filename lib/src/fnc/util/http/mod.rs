@@ -1,40 +1,125 @@
 use crate::ctx::Context;
+use crate::dbs::HttpLimits;
 use crate::err::Error;
 use crate::sql::object::Object;
 use crate::sql::strand::Strand;
 use crate::sql::value::Value;
 use crate::sql::{json, Bytes};
 use reqwest::header::CONTENT_TYPE;
-use reqwest::{Client, RequestBuilder, Response};
+use reqwest::{Client, RequestBuilder, Response, Url};
 
 pub(crate) fn uri_is_valid(uri: &str) -> bool {
-	reqwest::Url::parse(uri).is_ok()
+	Url::parse(uri).is_ok()
 }
 
-fn encode_body(req: RequestBuilder, body: Value) -> RequestBuilder {
-	match body {
-		Value::Bytes(bytes) => req.header(CONTENT_TYPE, "application/octet-stream").body(bytes.0),
-		_ if body.is_some() => req.json(&body.into_json()),
-		_ => req,
+/// Check whether a host matches any of the allowed hosts
+fn host_matches(allowed: &[String], host: &str) -> bool {
+	let host = host.to_ascii_lowercase();
+	allowed.is_empty()
+		|| allowed.iter().any(|v| match v.strip_prefix("*.") {
+			Some(domain) => host.strip_suffix(domain).map_or(false, |v| v.ends_with('.')),
+			None => v == "*" || *v == host,
+		})
+}
+
+/// Check that requests can be sent to the host of a URI
+pub(crate) fn check_host(lim: &HttpLimits, uri: &str) -> Result<(), Error> {
+	let host =
+		Url::parse(uri).ok().and_then(|v| v.host_str().map(str::to_owned)).unwrap_or_default();
+	match host_matches(&lim.allowed_hosts, &host) {
+		true => Ok(()),
+		false => Err(Error::HttpHostNotAllowed(host)),
+	}
+}
+
+/// Create a client which only follows redirects to allowed hosts
+#[cfg_attr(target_arch = "wasm32", allow(unused_variables))]
+fn client(lim: &HttpLimits) -> Result<Client, Error> {
+	let cli = Client::builder();
+	#[cfg(not(target_arch = "wasm32"))]
+	let allowed = lim.allowed_hosts.clone();
+	#[cfg(not(target_arch = "wasm32"))]
+	let cli = cli.redirect(reqwest::redirect::Policy::custom(move |attempt| {
+		match attempt.url().host_str().map_or(false, |v| host_matches(&allowed, v)) {
+			true if attempt.previous().len() < 10 => attempt.follow(),
+			_ => attempt.stop(),
+		}
+	}));
+	Ok(cli.build()?)
+}
+
+fn encode_body(
+	lim: &HttpLimits,
+	req: RequestBuilder,
+	body: Value,
+) -> Result<RequestBuilder, Error> {
+	let (mime, body) = match body {
+		Value::Bytes(bytes) => ("application/octet-stream", bytes.0),
+		_ if body.is_some() => (
+			"application/json",
+			serde_json::to_vec(&body.into_json()).map_err(|e| Error::Http(e.to_string()))?,
+		),
+		_ => return Ok(req),
+	};
+	if body.len() > lim.max_body_size {
+		return Err(Error::HttpBodyTooLarge(lim.max_body_size));
+	}
+	Ok(req.header(CONTENT_TYPE, mime).body(body))
+}
+
+/// Send a request, limited to the remaining time of the query
+#[cfg_attr(target_arch = "wasm32", allow(unused_variables))]
+async fn send(ctx: &Context<'_>, req: RequestBuilder) -> Result<Response, Error> {
+	#[cfg(not(target_arch = "wasm32"))]
+	let req = req.timeout(match ctx.timeout() {
+		Some(d) => d.min(ctx.config().http.timeout),
+		None => ctx.config().http.timeout,
+	});
+	Ok(req.send().await?)
+}
+
+/// Read the body of a response, up to the maximum body size
+#[cfg_attr(target_arch = "wasm32", allow(unused_mut))]
+async fn read_body(lim: &HttpLimits, mut res: Response) -> Result<Vec<u8>, Error> {
+	let limit = lim.max_body_size;
+	if res.content_length().map_or(false, |v| v > limit as u64) {
+		return Err(Error::HttpBodyTooLarge(limit));
+	}
+	#[cfg(not(target_arch = "wasm32"))]
+	let body = {
+		let mut body = Vec::new();
+		while let Some(chunk) = res.chunk().await? {
+			if body.len() + chunk.len() > limit {
+				return Err(Error::HttpBodyTooLarge(limit));
+			}
+			body.extend_from_slice(&chunk);
+		}
+		body
+	};
+	#[cfg(target_arch = "wasm32")]
+	let body = res.bytes().await?.to_vec();
+	match body.len() > limit {
+		true => Err(Error::HttpBodyTooLarge(limit)),
+		false => Ok(body),
 	}
 }
 
-async fn decode_response(res: Response) -> Result<Value, Error> {
+async fn decode_response(lim: &HttpLimits, res: Response) -> Result<Value, Error> {
 	match res.status() {
 		s if s.is_success() => match res.headers().get(CONTENT_TYPE) {
-			Some(mime) => match mime.to_str() {
+			Some(mime) => match mime.to_str().map(str::to_owned) {
 				Ok(v) if v.starts_with("application/json") => {
-					let txt = res.text().await?;
-					let val = json(&txt)?;
+					let body = read_body(lim, res).await?;
+					let val = json(&String::from_utf8_lossy(&body))?;
 					Ok(val)
 				}
 				Ok(v) if v.starts_with("application/octet-stream") => {
-					let bytes = res.bytes().await?;
-					Ok(Value::Bytes(Bytes(bytes.into())))
+					let body = read_body(lim, res).await?;
+					Ok(Value::Bytes(Bytes(body)))
 				}
 				Ok(v) if v.starts_with("text") => {
-					let txt = res.text().await?;
-					let val = txt.into();
+					let body = read_body(lim, res).await?;
+					let val = String::from_utf8_lossy(&body).into_owned().into();
 					Ok(val)
 				}
 				_ => Ok(Value::None),
@@ -46,8 +131,10 @@ async fn decode_response(res: Response) -> Result<Value, Error> {
 }
 
 pub async fn head(ctx: &Context<'_>, uri: Strand, opts: impl Into<Object>) -> Result<Value, Error> {
-	// Set a default client with no timeout
-	let cli = Client::builder().build()?;
+	// Get the limits of the http functions
+	let lim = &ctx.config().http;
+	// Set a client which checks redirects
+	let cli = client(lim)?;
 	// Start a new HEAD request
 	let mut req = cli.head(uri.as_str());
 	// Add the User-Agent header
@@ -59,11 +146,7 @@ pub async fn head(ctx: &Context<'_>, uri: Strand, opts: impl Into<Object>) -> Re
 		req = req.header(k.as_str(), v.to_raw_string());
 	}
 	// Send the request and wait
-	let res = match ctx.timeout() {
-		#[cfg(not(target_arch = "wasm32"))]
-		Some(d) => req.timeout(d).send().await?,
-		_ => req.send().await?,
-	};
+	let res = send(ctx, req).await?;
 	// Check the response status
 	match res.status() {
 		s if s.is_success() => Ok(Value::None),
@@ -72,8 +155,10 @@ pub async fn head(ctx: &Context<'_>, uri: Strand, opts: impl Into<Object>) -> Re
 }
 
 pub async fn get(ctx: &Context<'_>, uri: Strand, opts: impl Into<Object>) -> Result<Value, Error> {
-	// Set a default client with no timeout
-	let cli = Client::builder().build()?;
+	// Get the limits of the http functions
+	let lim = &ctx.config().http;
+	// Set a client which checks redirects
+	let cli = client(lim)?;
 	// Start a new GET request
 	let mut req = cli.get(uri.as_str());
 	// Add the User-Agent header
@@ -85,13 +170,9 @@ pub async fn get(ctx: &Context<'_>, uri: Strand, opts: impl Into<Object>) -> Res
 		req = req.header(k.as_str(), v.to_raw_string());
 	}
 	// Send the request and wait
-	let res = match ctx.timeout() {
-		#[cfg(not(target_arch = "wasm32"))]
-		Some(d) => req.timeout(d).send().await?,
-		_ => req.send().await?,
-	};
+	let res = send(ctx, req).await?;
 	// Receive the response as a value
-	decode_response(res).await
+	decode_response(lim, res).await
 }
 
 pub async fn put(
@@ -100,9 +181,11 @@ pub async fn put(
 	body: Value,
 	opts: impl Into<Object>,
 ) -> Result<Value, Error> {
-	// Set a default client with no timeout
-	let cli = Client::builder().build()?;
-	// Start a new GET request
+	// Get the limits of the http functions
+	let lim = &ctx.config().http;
+	// Set a client which checks redirects
+	let cli = client(lim)?;
+	// Start a new PUT request
 	let mut req = cli.put(uri.as_str());
 	// Add the User-Agent header
 	if cfg!(not(target_arch = "wasm32")) {
@@ -113,15 +196,11 @@ pub async fn put(
 		req = req.header(k.as_str(), v.to_raw_string());
 	}
 	// Submit the request body
-	req = encode_body(req, body);
+	req = encode_body(lim, req, body)?;
 	// Send the request and wait
-	let res = match ctx.timeout() {
-		#[cfg(not(target_arch = "wasm32"))]
-		Some(d) => req.timeout(d).send().await?,
-		_ => req.send().await?,
-	};
+	let res = send(ctx, req).await?;
 	// Receive the response as a value
-	decode_response(res).await
+	decode_response(lim, res).await
 }
 
 pub async fn post(
@@ -130,9 +209,11 @@ pub async fn post(
 	body: Value,
 	opts: impl Into<Object>,
 ) -> Result<Value, Error> {
-	// Set a default client with no timeout
-	let cli = Client::builder().build()?;
-	// Start a new GET request
+	// Get the limits of the http functions
+	let lim = &ctx.config().http;
+	// Set a client which checks redirects
+	let cli = client(lim)?;
+	// Start a new POST request
 	let mut req = cli.post(uri.as_str());
 	// Add the User-Agent header
 	if cfg!(not(target_arch = "wasm32")) {
@@ -143,15 +224,11 @@ pub async fn post(
 		req = req.header(k.as_str(), v.to_raw_string());
 	}
 	// Submit the request body
-	req = encode_body(req, body);
+	req = encode_body(lim, req, body)?;
 	// Send the request and wait
-	let res = match ctx.timeout() {
-		#[cfg(not(target_arch = "wasm32"))]
-		Some(d) => req.timeout(d).send().await?,
-		_ => req.send().await?,
-	};
+	let res = send(ctx, req).await?;
 	// Receive the response as a value
-	decode_response(res).await
+	decode_response(lim, res).await
 }
 
 pub async fn patch(
@@ -160,9 +237,11 @@ pub async fn patch(
 	body: Value,
 	opts: impl Into<Object>,
 ) -> Result<Value, Error> {
-	// Set a default client with no timeout
-	let cli = Client::builder().build()?;
-	// Start a new GET request
+	// Get the limits of the http functions
+	let lim = &ctx.config().http;
+	// Set a client which checks redirects
+	let cli = client(lim)?;
+	// Start a new PATCH request
 	let mut req = cli.patch(uri.as_str());
 	// Add the User-Agent header
 	if cfg!(not(target_arch = "wasm32")) {
@@ -173,15 +252,11 @@ pub async fn patch(
 		req = req.header(k.as_str(), v.to_raw_string());
 	}
 	// Submit the request body
-	req = encode_body(req, body);
+	req = encode_body(lim, req, body)?;
 	// Send the request and wait
-	let res = match ctx.timeout() {
-		#[cfg(not(target_arch = "wasm32"))]
-		Some(d) => req.timeout(d).send().await?,
-		_ => req.send().await?,
-	};
+	let res = send(ctx, req).await?;
 	// Receive the response as a value
-	decode_response(res).await
+	decode_response(lim, res).await
 }
 
 pub async fn delete(
@@ -189,9 +264,11 @@ pub async fn delete(
 	uri: Strand,
 	opts: impl Into<Object>,
 ) -> Result<Value, Error> {
-	// Set a default client with no timeout
-	let cli = Client::builder().build()?;
-	// Start a new GET request
+	// Get the limits of the http functions
+	let lim = &ctx.config().http;
+	// Set a client which checks redirects
+	let cli = client(lim)?;
+	// Start a new DELETE request
 	let mut req = cli.delete(uri.as_str());
 	// Add the User-Agent header
 	if cfg!(not(target_arch = "wasm32")) {
@@ -202,11 +279,59 @@ pub async fn delete(
 		req = req.header(k.as_str(), v.to_raw_string());
 	}
 	// Send the request and wait
-	let res = match ctx.timeout() {
-		#[cfg(not(target_arch = "wasm32"))]
-		Some(d) => req.timeout(d).send().await?,
-		_ => req.send().await?,
-	};
+	let res = send(ctx, req).await?;
 	// Receive the response as a value
-	decode_response(res).await
+	decode_response(lim, res).await
+}
+
+/// Deliver a webhook which was sent by an event
+#[cfg(not(target_arch = "wasm32"))]
+pub(crate) async fn deliver(
+	hook: &crate::dbs::Webhook,
+	lim: &HttpLimits,
+	timeout: std::time::Duration,
+) -> Result<(), Error> {
+	use crate::dbs::Method;
+	// Set a client which checks redirects
+	let cli = client(lim)?;
+	// Start a new request
+	let mut req = match hook.method {
+		Method::Post => cli.post(hook.uri.as_str()),
+		Method::Put => cli.put(hook.uri.as_str()),
+		Method::Patch => cli.patch(hook.uri.as_str()),
+		Method::Delete => cli.delete(hook.uri.as_str()),
+	};
+	// Add the User-Agent header
+	req = req.header("User-Agent", "SurrealDB");
+	// Add specified header values
+	for (k, v) in hook.headers.iter() {
+		req = req.header(k.as_str(), v.to_raw_string());
+	}
+	// Submit the request body
+	req = encode_body(lim, req, hook.body.clone())?;
+	// Send the request and wait
+	let res = req.timeout(timeout).send().await?;
+	// Check the response status
+	match res.status() {
+		s if s.is_success() => Ok(()),
+		s => Err(Error::Http(s.canonical_reason().unwrap_or_default().to_owned())),
+	}
+}
+
+#[cfg(test)]
+mod tests {
+
+	use super::*;
+
+	#[test]
+	fn allowed_hosts() {
+		let allowed = vec![String::from("example.com"), String::from("*.surrealdb.com")];
+		assert!(host_matches(&[], "localhost"));
+		assert!(host_matches(&allowed, "Example.com"));
+		assert!(host_matches(&allowed, "api.surrealdb.com"));
+		assert!(!host_matches(&allowed, "surrealdb.com"));
+		assert!(!host_matches(&allowed, "evilsurrealdb.com"));
+		assert!(!host_matches(&allowed, "api.example.com"));
+		assert!(host_matches(&[String::from("*")], "localhost"));
+	}
 }
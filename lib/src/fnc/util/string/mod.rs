@@ -1,6 +1,7 @@
 use ascii::any_ascii as ascii;
 use once_cell::sync::Lazy;
 use regex::Regex;
+use std::collections::HashSet;
 
 static SIMPLES: Lazy<Regex> = Lazy::new(|| Regex::new("[^a-z0-9-_]").unwrap());
 static HYPHENS: Lazy<Regex> = Lazy::new(|| Regex::new("-+").unwrap());
@@ -21,3 +22,70 @@ pub fn slug<S: AsRef<str>>(s: S) -> String {
 	// Return the string
 	s.to_owned()
 }
+
+pub fn levenshtein(a: &str, b: &str) -> usize {
+	// Compare the strings by character
+	let a: Vec<char> = a.chars().collect();
+	let b: Vec<char> = b.chars().collect();
+	// Keep a single row of the distance matrix
+	let mut row: Vec<usize> = (0..=b.len()).collect();
+	for (i, ca) in a.iter().enumerate() {
+		let mut prev = row[0];
+		row[0] = i + 1;
+		for (j, cb) in b.iter().enumerate() {
+			let cost = if ca == cb {
+				prev
+			} else {
+				prev + 1
+			};
+			prev = row[j + 1];
+			row[j + 1] = cost.min(row[j] + 1).min(prev + 1);
+		}
+	}
+	row[b.len()]
+}
+
+pub fn trigrams(s: &str) -> HashSet<String> {
+	let mut out = HashSet::new();
+	// Each word is padded, so that its start and end are weighted
+	for word in s.to_lowercase().split(|c: char| !c.is_alphanumeric()).filter(|w| !w.is_empty()) {
+		let chars: Vec<char> = format!("  {word} ").chars().collect();
+		for w in chars.windows(3) {
+			out.insert(w.iter().collect());
+		}
+	}
+	out
+}
+
+pub fn similarity(a: &str, b: &str) -> f64 {
+	let a = trigrams(a);
+	let b = trigrams(b);
+	// Get the proportion of the trigrams which are shared
+	match a.union(&b).count() {
+		0 => 0.0,
+		n => a.intersection(&b).count() as f64 / n as f64,
+	}
+}
+
+#[cfg(test)]
+mod tests {
+	use super::{levenshtein, similarity};
+
+	#[test]
+	fn string_levenshtein() {
+		assert_eq!(levenshtein("", ""), 0);
+		assert_eq!(levenshtein("", "abc"), 3);
+		assert_eq!(levenshtein("kitten", "sitting"), 3);
+		assert_eq!(levenshtein("flaw", "lawn"), 2);
+		assert_eq!(levenshtein("你好世界", "你好"), 2);
+	}
+
+	#[test]
+	fn string_similarity() {
+		assert_eq!(similarity("", ""), 0.0);
+		assert_eq!(similarity("word", "word"), 1.0);
+		assert_eq!(similarity("Word", "word!"), 1.0);
+		assert_eq!(similarity("word", "two words"), 4.0 / 11.0);
+		assert_eq!(similarity("abc", "xyz"), 0.0);
+	}
+}
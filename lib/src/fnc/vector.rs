@@ -0,0 +1,176 @@
+use crate::err::Error;
+use crate::sql::number::Number;
+use crate::sql::value::Value;
+
+/// Check that two vectors have the same number of dimensions
+fn check(name: &str, a: &[Number], b: &[Number]) -> Result<(), Error> {
+	match a.len() == b.len() {
+		true => Ok(()),
+		false => Err(Error::InvalidArguments {
+			name: String::from(name),
+			message: String::from("The two vectors must be of the same dimension."),
+		}),
+	}
+}
+
+fn dot_product(a: &[Number], b: &[Number]) -> Number {
+	a.iter().zip(b.iter()).map(|(a, b)| a * b).sum()
+}
+
+fn norm(a: &[Number]) -> f64 {
+	a.iter().map(|v| v.to_float().powi(2)).sum::<f64>().sqrt()
+}
+
+pub fn add((a, b): (Vec<Number>, Vec<Number>)) -> Result<Value, Error> {
+	check("vector::add", &a, &b)?;
+	Ok(a.iter().zip(b.iter()).map(|(a, b)| a + b).collect::<Vec<_>>().into())
+}
+
+pub fn angle((a, b): (Vec<Number>, Vec<Number>)) -> Result<Value, Error> {
+	check("vector::angle", &a, &b)?;
+	let cos = dot_product(&a, &b).to_float() / (norm(&a) * norm(&b));
+	Ok(match cos.is_finite() {
+		true => cos.clamp(-1.0, 1.0).acos().into(),
+		false => Value::None,
+	})
+}
+
+pub fn cross((a, b): (Vec<Number>, Vec<Number>)) -> Result<Value, Error> {
+	match (a.as_slice(), b.as_slice()) {
+		([a1, a2, a3], [b1, b2, b3]) => {
+			Ok(vec![&(a2 * b3) - &(a3 * b2), &(a3 * b1) - &(a1 * b3), &(a1 * b2) - &(a2 * b1)]
+				.into())
+		}
+		_ => Err(Error::InvalidArguments {
+			name: String::from("vector::cross"),
+			message: String::from("Both vectors must have 3 dimensions."),
+		}),
+	}
+}
+
+pub fn divide((a, b): (Vec<Number>, Vec<Number>)) -> Result<Value, Error> {
+	check("vector::divide", &a, &b)?;
+	Ok(a.iter().zip(b.iter()).map(|(a, b)| a / b).collect::<Vec<_>>().into())
+}
+
+pub fn dot((a, b): (Vec<Number>, Vec<Number>)) -> Result<Value, Error> {
+	check("vector::dot", &a, &b)?;
+	Ok(dot_product(&a, &b).into())
+}
+
+pub fn magnitude((a,): (Vec<Number>,)) -> Result<Value, Error> {
+	Ok(norm(&a).into())
+}
+
+pub fn multiply((a, b): (Vec<Number>, Vec<Number>)) -> Result<Value, Error> {
+	check("vector::multiply", &a, &b)?;
+	Ok(a.iter().zip(b.iter()).map(|(a, b)| a * b).collect::<Vec<_>>().into())
+}
+
+pub fn normalize((a,): (Vec<Number>,)) -> Result<Value, Error> {
+	let n = norm(&a);
+	Ok(match n > 0.0 {
+		true => a.iter().map(|v| Number::from(v.to_float() / n)).collect::<Vec<_>>().into(),
+		false => Value::None,
+	})
+}
+
+pub fn project((a, b): (Vec<Number>, Vec<Number>)) -> Result<Value, Error> {
+	check("vector::project", &a, &b)?;
+	let s = dot_product(&a, &b).to_float() / dot_product(&b, &b).to_float();
+	Ok(match s.is_finite() {
+		true => b.iter().map(|v| Number::from(v.to_float() * s)).collect::<Vec<_>>().into(),
+		false => Value::None,
+	})
+}
+
+pub fn subtract((a, b): (Vec<Number>, Vec<Number>)) -> Result<Value, Error> {
+	check("vector::subtract", &a, &b)?;
+	Ok(a.iter().zip(b.iter()).map(|(a, b)| a - b).collect::<Vec<_>>().into())
+}
+
+pub mod distance {
+
+	use super::check;
+	use crate::err::Error;
+	use crate::sql::number::Number;
+	use crate::sql::value::Value;
+
+	pub fn chebyshev((a, b): (Vec<Number>, Vec<Number>)) -> Result<Value, Error> {
+		check("vector::distance::chebyshev", &a, &b)?;
+		Ok(a.iter()
+			.zip(b.iter())
+			.map(|(a, b)| (a.to_float() - b.to_float()).abs())
+			.fold(0.0, f64::max)
+			.into())
+	}
+
+	pub fn euclidean((a, b): (Vec<Number>, Vec<Number>)) -> Result<Value, Error> {
+		check("vector::distance::euclidean", &a, &b)?;
+		Ok(a.iter()
+			.zip(b.iter())
+			.map(|(a, b)| (a.to_float() - b.to_float()).powi(2))
+			.sum::<f64>()
+			.sqrt()
+			.into())
+	}
+
+	pub fn manhattan((a, b): (Vec<Number>, Vec<Number>)) -> Result<Value, Error> {
+		check("vector::distance::manhattan", &a, &b)?;
+		Ok(a.iter().zip(b.iter()).map(|(a, b)| (a - b).abs()).sum::<Number>().into())
+	}
+}
+
+pub mod similarity {
+
+	use super::{check, dot_product, norm};
+	use crate::err::Error;
+	use crate::sql::number::Number;
+	use crate::sql::value::Value;
+
+	pub fn cosine((a, b): (Vec<Number>, Vec<Number>)) -> Result<Value, Error> {
+		check("vector::similarity::cosine", &a, &b)?;
+		let v = dot_product(&a, &b).to_float() / (norm(&a) * norm(&b));
+		Ok(match v.is_finite() {
+			true => v.into(),
+			false => Value::None,
+		})
+	}
+
+	pub fn euclidean((a, b): (Vec<Number>, Vec<Number>)) -> Result<Value, Error> {
+		check("vector::similarity::euclidean", &a, &b)?;
+		let d =
+			a.iter().zip(b.iter()).map(|(a, b)| (a.to_float() - b.to_float()).powi(2)).sum::<f64>();
+		Ok((1.0 / (1.0 + d.sqrt())).into())
+	}
+}
+
+#[cfg(test)]
+mod tests {
+	use super::*;
+
+	fn vector(v: &[i64]) -> Vec<Number> {
+		v.iter().map(|v| Number::from(*v)).collect()
+	}
+
+	#[test]
+	fn vector_cross() {
+		let res = cross((vector(&[1, 0, 0]), vector(&[0, 1, 0]))).unwrap();
+		assert_eq!(res, Value::from(vector(&[0, 0, 1])));
+		assert!(cross((vector(&[1, 0]), vector(&[0, 1]))).is_err());
+	}
+
+	#[test]
+	fn vector_similarity() {
+		let res = similarity::euclidean((vector(&[0, 0]), vector(&[3, 4]))).unwrap();
+		assert_eq!(res, Value::from(1.0 / 6.0));
+		let res = similarity::cosine((vector(&[1, 0]), vector(&[2, 0]))).unwrap();
+		assert_eq!(res, Value::from(1.0));
+	}
+
+	#[test]
+	fn vector_dimensions() {
+		assert!(add((vector(&[1, 2]), vector(&[1, 2, 3]))).is_err());
+		assert_eq!(dot((vector(&[1, 2, 3]), vector(&[4, 5, 6]))).unwrap(), Value::from(32));
+	}
+}
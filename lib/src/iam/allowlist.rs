@@ -0,0 +1,166 @@
+//! Network allowlists for namespaces.
+//!
+//! A namespace can be restricted to clients which connect from specific
+//! networks with [`Datastore::with_ns_allowlists`](crate::kvs::Datastore::with_ns_allowlists),
+//! which are parsed from the form `ns=network,network;ns=network`, where each
+//! network is an IP address or a CIDR range. A query which uses a restricted namespace, either through its
+//! session or through a `USE` statement, is rejected before it is run unless
+//! the client IP address of the session is within one of the allowed networks.
+use crate::dbs::Session;
+use crate::err::Error;
+use crate::iam::LOG;
+use crate::sql::query::Query;
+use crate::sql::statement::Statement;
+use std::net::IpAddr;
+use std::str::FromStr;
+
+/// An IP address, or a range of IP addresses in CIDR notation
+#[derive(Clone, Copy, Debug, Eq, PartialEq)]
+pub struct Network {
+	addr: IpAddr,
+	prefix: u32,
+}
+
+impl Network {
+	/// Check if an IP address is within this network
+	pub fn contains(&self, ip: &IpAddr) -> bool {
+		// Compare IPv4-mapped IPv6 addresses as IPv4 addresses
+		let ip = match ip {
+			IpAddr::V6(v) => v.to_ipv4_mapped().map_or(*ip, IpAddr::V4),
+			_ => *ip,
+		};
+		let (a, b, bits) = match (self.addr, ip) {
+			(IpAddr::V4(a), IpAddr::V4(b)) => (u32::from(a) as u128, u32::from(b) as u128, 32),
+			(IpAddr::V6(a), IpAddr::V6(b)) => (u128::from(a), u128::from(b), 128),
+			_ => return false,
+		};
+		(a ^ b).checked_shr(bits - self.prefix).unwrap_or(0) == 0
+	}
+}
+
+impl FromStr for Network {
+	type Err = String;
+	/// Parse an IP address, or a range of IP addresses in the form `addr/prefix`
+	fn from_str(s: &str) -> Result<Self, Self::Err> {
+		let err = || format!("Invalid network '{s}', expected an IP address or a CIDR range");
+		let (addr, prefix) = match s.trim().split_once('/') {
+			Some((a, p)) => (a.parse::<IpAddr>().map_err(|_| err())?, Some(p)),
+			None => (s.trim().parse::<IpAddr>().map_err(|_| err())?, None),
+		};
+		let bits = if addr.is_ipv4() {
+			32
+		} else {
+			128
+		};
+		let prefix = match prefix {
+			Some(p) => p.parse::<u32>().ok().filter(|p| *p <= bits).ok_or_else(err)?,
+			None => bits,
+		};
+		Ok(Network {
+			addr,
+			prefix,
+		})
+	}
+}
+
+/// The networks from which clients may use a namespace
+#[derive(Clone, Debug, Eq, PartialEq)]
+pub struct Allowlist {
+	pub ns: String,
+	pub networks: Vec<Network>,
+}
+
+impl Allowlist {
+	/// Parse the allowlists of each namespace. Any network which is invalid
+	/// is ignored, so that its namespace remains restricted to the networks
+	/// which are valid rather than being unrestricted.
+	pub fn parse(v: &str) -> Vec<Allowlist> {
+		v.split(';')
+			.filter_map(|v| v.split_once('='))
+			.map(|(ns, networks)| Allowlist {
+				ns: ns.trim().to_owned(),
+				networks: networks
+					.split(',')
+					.filter(|v| !v.trim().is_empty())
+					.filter_map(|v| match v.parse() {
+						Ok(v) => Some(v),
+						Err(e) => {
+							warn!(target: LOG, "The namespace allowlist configuration is invalid: {}", e);
+							None
+						}
+					})
+					.collect(),
+			})
+			.collect()
+	}
+}
+
+/// Check if a client IP address may use a namespace
+fn allowed(allowlists: &[Allowlist], ns: &str, ip: Option<&str>) -> bool {
+	match allowlists.iter().find(|v| v.ns == ns) {
+		Some(v) => match ip.and_then(|ip| ip.parse::<IpAddr>().ok()) {
+			Some(ip) => v.networks.iter().any(|n| n.contains(&ip)),
+			None => false,
+		},
+		None => true,
+	}
+}
+
+/// Check that the session may use the namespaces of a query
+pub fn check(allowlists: &[Allowlist], sess: &Session, qry: Option<&Query>) -> Result<(), Error> {
+	// Check if any namespaces are restricted
+	if allowlists.is_empty() {
+		return Ok(());
+	}
+	// Get the namespace of the session, and of any USE statements
+	let mut namespaces = sess.ns.iter().chain(qry.into_iter().flat_map(|v| {
+		v.iter().filter_map(|v| match v {
+			Statement::Use(v) => v.ns.as_ref(),
+			_ => None,
+		})
+	}));
+	// Check that the client may use each namespace
+	match namespaces.find(|ns| !allowed(allowlists, ns, sess.ip.as_deref())) {
+		Some(ns) => {
+			debug!(target: LOG, "The client address {:?} is not allowed to use the '{}' namespace", sess.ip, ns);
+			Err(Error::IpNotAllowed {
+				ns: ns.to_owned(),
+			})
+		}
+		None => Ok(()),
+	}
+}
+
+#[cfg(test)]
+mod tests {
+	use super::*;
+
+	#[test]
+	fn network_contains() {
+		let net = "10.1.0.0/16".parse::<Network>().unwrap();
+		assert!(net.contains(&"10.1.2.3".parse().unwrap()));
+		assert!(!net.contains(&"10.2.0.1".parse().unwrap()));
+		assert!(net.contains(&"::ffff:10.1.0.1".parse().unwrap()));
+		let net = "192.168.1.1".parse::<Network>().unwrap();
+		assert!(net.contains(&"192.168.1.1".parse().unwrap()));
+		assert!(!net.contains(&"192.168.1.2".parse().unwrap()));
+		let net = "0.0.0.0/0".parse::<Network>().unwrap();
+		assert!(net.contains(&"8.8.8.8".parse().unwrap()));
+		assert!(!net.contains(&"::1".parse().unwrap()));
+		let net = "fd00::/8".parse::<Network>().unwrap();
+		assert!(net.contains(&"fd12::1".parse().unwrap()));
+		assert!("10.0.0.0/33".parse::<Network>().is_err());
+		assert!("localhost".parse::<Network>().is_err());
+	}
+
+	#[test]
+	fn allowlist_parse() {
+		let v = Allowlist::parse("test=10.0.0.0/8, 192.168.0.1;prod=;other=invalid");
+		assert_eq!(v.len(), 3);
+		assert_eq!(v[0].ns, "test");
+		assert_eq!(v[0].networks.len(), 2);
+		// A namespace without any valid networks can not be used by any client
+		assert!(v[1].networks.is_empty());
+		assert!(v[2].networks.is_empty());
+	}
+}
@@ -0,0 +1,322 @@
+//! Authentication of credentials by an external authenticator.
+//!
+//! An external authenticator is configured with
+//! [`Datastore::with_authenticator`](crate::kvs::Datastore::with_authenticator),
+//! as the URL of an HTTP endpoint, or as a command which is prefixed with `exec:`. When the credentials of a signin are not accepted
+//! by the database, they are sent to the authenticator as a JSON object, so
+//! that users can be authenticated against a directory such as LDAP or Active
+//! Directory. An HTTP authenticator receives the credentials as the body of a
+//! POST request, and a command receives them on its standard input.
+//!
+//! The authenticator accepts the credentials by responding with a JSON object
+//! of identity claims, in which the `ns`, `db`, and `sc` claims specify the
+//! level at which the session is authenticated, and the `id` claim specifies
+//! the record of a scope user. The claims are available to queries as the
+//! `$token` parameter. The authenticator rejects the credentials with an error
+//! status, or with a non-zero exit code. As with OIDC issuers, root
+//! authentication is never granted by an external authenticator.
+use crate::cnf::SERVER_NAME;
+use crate::dbs::Auth;
+use crate::dbs::Session;
+use crate::err::Error;
+use crate::iam::token::{Claims, HEADER};
+use crate::iam::LOG;
+use crate::kvs::Datastore;
+use crate::sql::{Object, Part, Value};
+use chrono::{Duration, Utc};
+use jsonwebtoken::{encode, EncodingKey};
+use std::fmt;
+use std::sync::Arc;
+
+/// An external authenticator which is consulted during signin
+#[derive(Clone, Debug, Eq, PartialEq)]
+pub enum Authenticator {
+	/// An HTTP endpoint which receives the credentials in a POST request
+	Http(String),
+	/// A command which receives the credentials on its standard input
+	Exec(Vec<String>),
+}
+
+impl Authenticator {
+	/// Parse the configured authenticator from a URL or an `exec:` command
+	pub fn parse(v: &str) -> Result<Self, String> {
+		if let Some(cmd) = v.strip_prefix("exec:") {
+			let args: Vec<String> = cmd.split_whitespace().map(str::to_owned).collect();
+			return match args.is_empty() {
+				true => Err(String::from("the command is empty")),
+				false => Ok(Self::Exec(args)),
+			};
+		}
+		match v.starts_with("http://") || v.starts_with("https://") {
+			true => Ok(Self::Http(v.to_owned())),
+			false => Err(format!("'{v}' is not an HTTP URL or an exec: command")),
+		}
+	}
+}
+
+impl fmt::Display for Authenticator {
+	fn fmt(&self, f: &mut fmt::Formatter) -> fmt::Result {
+		match self {
+			Self::Http(v) => f.write_str(v),
+			Self::Exec(v) => write!(f, "exec:{}", v.join(" ")),
+		}
+	}
+}
+
+/// Sign in with credentials which are accepted by the external authenticator,
+/// returning a token for scope users, which is signed with the scope key
+pub async fn signin(
+	kvs: &Datastore,
+	session: &mut Session,
+	vars: Object,
+) -> Result<Option<String>, Error> {
+	// Authenticate the credentials
+	let claims = authenticate(kvs, vars).await?;
+	let auth = identity(&claims)?;
+	// Scope users are issued a token for the scope
+	let tk = match &auth {
+		Auth::Sc(ns, db, sc) => {
+			// Create a new readonly transaction
+			let mut tx = kvs.transaction(false, false).await?;
+			// The scope must be defined
+			let sv = tx.get_sc(ns, db, sc).await.map_err(|_| Error::InvalidAuth)?;
+			// Create the authentication key
+			let key = EncodingKey::from_secret(sv.code.as_ref());
+			// Create the authentication claim
+			let val = Claims {
+				iss: Some(SERVER_NAME.to_owned()),
+				iat: Some(Utc::now().timestamp()),
+				nbf: Some(Utc::now().timestamp()),
+				exp: Some(
+					match sv.session {
+						Some(v) => Utc::now() + Duration::from_std(v.0).unwrap(),
+						_ => Utc::now() + Duration::hours(1),
+					}
+					.timestamp(),
+				),
+				ns: Some(ns.to_owned()),
+				db: Some(db.to_owned()),
+				sc: Some(sc.to_owned()),
+				id: Some(claims.pick(&[Part::from("id")]).as_raw_string()),
+				..Claims::default()
+			};
+			// Create the authentication token
+			Some(encode(&HEADER, &val, &key).map_err(|_| Error::InvalidAuth)?)
+		}
+		_ => None,
+	};
+	// Set the authentication on the session
+	session_auth(session, claims, auth)?;
+	Ok(tk)
+}
+
+/// Authenticate a session with a username and password, which are accepted by
+/// the external authenticator, at the namespace and database of the session
+pub async fn credentials(
+	kvs: &Datastore,
+	session: &mut Session,
+	user: &str,
+	pass: &str,
+) -> Result<(), Error> {
+	// Create the credentials
+	let mut vars = Object::default();
+	if let Some(ns) = &session.ns {
+		vars.insert(String::from("ns"), ns.to_owned().into());
+	}
+	if let Some(db) = &session.db {
+		vars.insert(String::from("db"), db.to_owned().into());
+	}
+	vars.insert(String::from("user"), user.into());
+	vars.insert(String::from("pass"), pass.into());
+	// Authenticate the credentials
+	let claims = authenticate(kvs, vars).await?;
+	let auth = identity(&claims)?;
+	// Set the authentication on the session
+	session_auth(session, claims, auth)
+}
+
+/// Authenticate a session with the identity claims of the authenticator
+fn session_auth(session: &mut Session, claims: Value, auth: Auth) -> Result<(), Error> {
+	match &auth {
+		Auth::Sc(ns, db, sc) => {
+			let id = claims.pick(&[Part::from("id")]).as_raw_string();
+			session.ns = Some(ns.to_owned());
+			session.db = Some(db.to_owned());
+			session.sc = Some(sc.to_owned());
+			session.sd = Some(crate::sql::thing(&id)?.into());
+		}
+		Auth::Db(ns, db) => {
+			session.ns = Some(ns.to_owned());
+			session.db = Some(db.to_owned());
+		}
+		Auth::Ns(ns) => session.ns = Some(ns.to_owned()),
+		_ => return Err(Error::InvalidAuth),
+	}
+	session.tk = Some(claims);
+	session.au = Arc::new(auth);
+	Ok(())
+}
+
+/// Get the authentication level of the identity claims of the authenticator
+fn identity(claims: &Value) -> Result<Auth, Error> {
+	let claim = |v: &str| match claims.pick(&[Part::from(v)]) {
+		Value::Strand(v) => Some(v.as_string()),
+		_ => None,
+	};
+	match (claim("ns"), claim("db"), claim("sc"), claim("id")) {
+		(Some(ns), Some(db), Some(sc), Some(_)) => Ok(Auth::Sc(ns, db, sc)),
+		(Some(ns), Some(db), None, _) => Ok(Auth::Db(ns, db)),
+		(Some(ns), None, None, _) => Ok(Auth::Ns(ns)),
+		_ => {
+			warn!(target: LOG, "The external authenticator returned claims without a valid level");
+			Err(Error::InvalidAuth)
+		}
+	}
+}
+
+/// Send the credentials to the external authenticator, returning the claims
+async fn authenticate(kvs: &Datastore, vars: Object) -> Result<Value, Error> {
+	// Check that an authenticator is configured
+	let authenticator = kvs.authenticator().ok_or(Error::InvalidAuth)?;
+	let timeout = kvs.config().authenticator_timeout;
+	// Log the authentication type
+	trace!(target: LOG, "Authenticating with external authenticator `{}`", authenticator);
+	// Send the credentials to the authenticator
+	let body = Value::from(vars).into_json().to_string();
+	let out = match authenticator {
+		Authenticator::Http(url) => post(url, body, timeout).await?,
+		Authenticator::Exec(args) => exec(args, body, timeout).await?,
+	};
+	// Parse the identity claims
+	match crate::sql::json(&out) {
+		Ok(v @ Value::Object(_)) => {
+			debug!(target: LOG, "Authenticated with external authenticator `{}`", authenticator);
+			Ok(v)
+		}
+		_ => {
+			warn!(target: LOG, "The external authenticator returned invalid claims");
+			Err(Error::InvalidAuth)
+		}
+	}
+}
+
+#[cfg(feature = "http")]
+#[cfg_attr(target_arch = "wasm32", allow(unused_mut, unused_variables))]
+async fn post(url: &str, body: String, timeout: std::time::Duration) -> Result<String, Error> {
+	let mut req = reqwest::Client::new()
+		.post(url)
+		.header(reqwest::header::CONTENT_TYPE, "application/json")
+		.body(body);
+	#[cfg(not(target_arch = "wasm32"))]
+	{
+		req = req.timeout(timeout);
+	}
+	match req.send().await {
+		Ok(res) if res.status().is_success() => res.text().await.map_err(|e| {
+			warn!(target: LOG, "The external authenticator response could not be read: {}", e);
+			Error::InvalidAuth
+		}),
+		Ok(res) => {
+			trace!(target: LOG, "The external authenticator rejected the credentials: {}", res.status());
+			Err(Error::InvalidAuth)
+		}
+		Err(e) => {
+			warn!(target: LOG, "The external authenticator could not be reached: {}", e);
+			Err(Error::InvalidAuth)
+		}
+	}
+}
+
+#[cfg(not(feature = "http"))]
+async fn post(url: &str, _: String, _: std::time::Duration) -> Result<String, Error> {
+	warn!(target: LOG, "The authenticator `{}` can not be used without the `http` feature", url);
+	Err(Error::InvalidAuth)
+}
+
+#[cfg(not(target_arch = "wasm32"))]
+async fn exec(
+	args: &[String],
+	body: String,
+	timeout: std::time::Duration,
+) -> Result<String, Error> {
+	use std::process::Stdio;
+	use tokio::io::AsyncWriteExt;
+	use tokio::process::Command;
+	// Spawn the command
+	let mut child = Command::new(&args[0])
+		.args(&args[1..])
+		.stdin(Stdio::piped())
+		.stdout(Stdio::piped())
+		.stderr(Stdio::null())
+		.kill_on_drop(true)
+		.spawn()
+		.map_err(|e| {
+			warn!(target: LOG, "The external authenticator `{}` could not be run: {}", args[0], e);
+			Error::InvalidAuth
+		})?;
+	// Write the credentials, and wait for the claims
+	let run = async {
+		if let Some(mut stdin) = child.stdin.take() {
+			stdin.write_all(body.as_bytes()).await?;
+		}
+		child.wait_with_output().await
+	};
+	match tokio::time::timeout(timeout, run).await {
+		Ok(Ok(out)) if out.status.success() => {
+			Ok(String::from_utf8_lossy(&out.stdout).into_owned())
+		}
+		Ok(Ok(out)) => {
+			trace!(target: LOG, "The external authenticator rejected the credentials: {}", out.status);
+			Err(Error::InvalidAuth)
+		}
+		Ok(Err(e)) => {
+			warn!(target: LOG, "The external authenticator `{}` failed: {}", args[0], e);
+			Err(Error::InvalidAuth)
+		}
+		Err(_) => {
+			warn!(target: LOG, "The external authenticator `{}` timed out", args[0]);
+			Err(Error::InvalidAuth)
+		}
+	}
+}
+
+#[cfg(target_arch = "wasm32")]
+async fn exec(args: &[String], _: String, _: std::time::Duration) -> Result<String, Error> {
+	warn!(target: LOG, "The authenticator `{}` can not be run in WebAssembly", args[0]);
+	Err(Error::InvalidAuth)
+}
+
+#[cfg(test)]
+mod tests {
+	use super::*;
+	use crate::sql::test::Parse;
+
+	#[test]
+	fn parse_authenticator() {
+		let v = Authenticator::parse("https://auth.example.com/signin").unwrap();
+		assert_eq!(v, Authenticator::Http("https://auth.example.com/signin".to_owned()));
+		let v = Authenticator::parse("exec:/usr/local/bin/ldap-auth --base dc=example").unwrap();
+		assert_eq!(v.to_string(), "exec:/usr/local/bin/ldap-auth --base dc=example");
+		assert!(Authenticator::parse("exec: ").is_err());
+		assert!(Authenticator::parse("ldap://directory.example.com").is_err());
+	}
+
+	#[test]
+	fn identity_claims() {
+		let v = Value::parse(
+			"{ ns: 'test', db: 'test', sc: 'user', id: 'user:tobie', groups: ['admin'] }",
+		);
+		assert_eq!(
+			identity(&v).unwrap(),
+			Auth::Sc("test".to_owned(), "test".to_owned(), "user".to_owned())
+		);
+		let v = Value::parse("{ ns: 'test', db: 'test' }");
+		assert_eq!(identity(&v).unwrap(), Auth::Db("test".to_owned(), "test".to_owned()));
+		// Scope users must have a record
+		let v = Value::parse("{ ns: 'test', db: 'test', sc: 'user' }");
+		assert!(identity(&v).is_err());
+		// Root authentication is never granted
+		let v = Value::parse("{ id: 'root' }");
+		assert!(identity(&v).is_err());
+	}
+}
@@ -1,6 +1,10 @@
+pub mod allowlist;
 pub mod base;
 pub mod clear;
+pub mod external;
+pub mod oidc;
 pub mod parse;
+pub mod share;
 pub mod signin;
 pub mod signup;
 pub mod token;
@@ -0,0 +1,161 @@
+//! Time-limited share links for records and query results.
+//!
+//! A share link is a signed token which grants read access to a single
+//! record, or to the result of a single `SELECT` query, until it expires.
+//! Share links are signed with a random secret which is stored for each
+//! database, and can be minted by any user with database level access.
+//! The record or query is read with the permissions of a database user.
+use crate::cnf::SERVER_NAME;
+use crate::dbs::Auth;
+use crate::dbs::Response;
+use crate::dbs::Session;
+use crate::err::Error;
+use crate::iam::token::HEADER;
+use crate::iam::LOG;
+use crate::kvs::Datastore;
+use crate::sql::statement::Statement;
+use crate::sql::Value;
+use chrono::Utc;
+use jsonwebtoken::{decode, encode, DecodingKey, EncodingKey, Validation};
+use serde::{Deserialize, Serialize};
+use std::collections::BTreeMap;
+use std::time::Duration;
+
+/// The longest time for which a share link can be valid
+pub const MAX_EXPIRY: Duration = Duration::from_secs(30 * 24 * 60 * 60);
+
+/// The data which a share link grants access to
+#[derive(Clone, Debug, Eq, PartialEq, Serialize, Deserialize)]
+#[serde(rename_all = "lowercase")]
+pub enum Share {
+	/// A single record
+	Record(String),
+	/// The result of a single SELECT query
+	Query(String),
+}
+
+#[derive(Debug, Serialize, Deserialize)]
+struct Claims {
+	iss: String,
+	iat: i64,
+	nbf: i64,
+	exp: i64,
+	#[serde(rename = "NS")]
+	ns: String,
+	#[serde(rename = "DB")]
+	db: String,
+	#[serde(rename = "SH")]
+	sh: Share,
+}
+
+/// Create a share link token for a record or query in the selected database
+/// of the session, which is valid for the specified duration
+pub async fn mint(
+	kvs: &Datastore,
+	session: &Session,
+	share: Share,
+	expiry: Duration,
+) -> Result<String, Error> {
+	// Check the selected namespace and database
+	let ns = session.ns.as_ref().ok_or(Error::NsEmpty)?;
+	let db = session.db.as_ref().ok_or(Error::DbEmpty)?;
+	// Check that the session has database level access
+	match &*session.au {
+		Auth::Kv => (),
+		Auth::Ns(v) if v == ns => (),
+		Auth::Db(v, d) if v == ns && d == db => (),
+		_ => return Err(Error::InvalidAuth),
+	};
+	// Check that the shared data can be read
+	check(&share)?;
+	// Check the expiry of the share link
+	if expiry.is_zero() || expiry > MAX_EXPIRY {
+		return Err(Error::InvalidShare);
+	}
+	// Get the secret for the database
+	let mut tx = kvs.transaction(true, false).await?;
+	let code = match tx.get_or_add_sh(ns, db).await {
+		Ok(v) => {
+			tx.commit().await?;
+			v
+		}
+		Err(e) => {
+			tx.cancel().await?;
+			return Err(e);
+		}
+	};
+	// Create the share link claims
+	let now = Utc::now().timestamp();
+	let val = Claims {
+		iss: SERVER_NAME.to_owned(),
+		iat: now,
+		nbf: now,
+		exp: now + expiry.as_secs() as i64,
+		ns: ns.to_owned(),
+		db: db.to_owned(),
+		sh: share,
+	};
+	// Log the share link
+	debug!(target: LOG, "Minted a share link for {:?} in database `{}`", val.sh, db);
+	// Create the share link token
+	encode(&HEADER, &val, &EncodingKey::from_secret(code.as_ref())).map_err(|_| Error::InvalidAuth)
+}
+
+/// Verify a share link token, and read the record or query which it shares
+pub async fn open(kvs: &Datastore, token: &str) -> Result<Value, Error> {
+	// Decode the token without verifying
+	let mut validation = Validation::new(jsonwebtoken::Algorithm::HS512);
+	validation.insecure_disable_signature_validation();
+	let claims = decode::<Claims>(token, &DecodingKey::from_secret(&[]), &validation)?.claims;
+	// Get the secret for the database
+	let mut tx = kvs.transaction(false, false).await?;
+	let code = tx.get_sh(&claims.ns, &claims.db).await?;
+	tx.cancel().await?;
+	// Verify the token, including its expiry
+	let code = code.ok_or(Error::InvalidAuth)?;
+	let validation = Validation::new(jsonwebtoken::Algorithm::HS512);
+	let claims =
+		decode::<Claims>(token, &DecodingKey::from_secret(code.as_ref()), &validation)?.claims;
+	if claims.nbf > Utc::now().timestamp() {
+		return Err(Error::InvalidAuth);
+	}
+	// Log the share link
+	trace!(target: LOG, "Opening a share link for {:?} in database `{}`", claims.sh, claims.db);
+	// Read the shared data as a database user
+	let sess = Session::for_db(claims.ns, claims.db);
+	match claims.sh {
+		Share::Record(id) => {
+			let id = crate::sql::thing(&id)?;
+			let vars = BTreeMap::from([("record".to_owned(), Value::from(id))]);
+			let res = kvs.execute("SELECT * FROM $record", &sess, Some(vars), false).await?;
+			// Output the record, if it exists
+			match output(res)? {
+				Value::Array(mut v) => Ok(v.0.pop().unwrap_or(Value::None)),
+				v => Ok(v),
+			}
+		}
+		Share::Query(sql) => output(kvs.execute(&sql, &sess, None, false).await?),
+	}
+}
+
+/// Get the result of the single statement which a share link runs
+fn output(mut res: Vec<Response>) -> Result<Value, Error> {
+	match res.pop() {
+		Some(v) => v.result,
+		None => Err(Error::QueryEmpty),
+	}
+}
+
+/// Check that the shared data is a valid record id, or a single SELECT query which does not write
+fn check(share: &Share) -> Result<(), Error> {
+	match share {
+		Share::Record(id) => crate::sql::thing(id).map(|_| ()),
+		Share::Query(sql) => {
+			let ast = crate::sql::parse(sql)?;
+			match ast.len() {
+				1 if matches!(ast[0], Statement::Select(_)) && !ast[0].writeable() => Ok(()),
+				_ => Err(Error::InvalidShare),
+			}
+		}
+	}
+}
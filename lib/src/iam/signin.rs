@@ -19,6 +19,24 @@ pub async fn signin(
 	strict: bool,
 	session: &mut Session,
 	vars: Object,
+) -> Result<Option<String>, Error> {
+	// Check the credentials which are defined in the database
+	let res = credentials(kvs, configured_root, strict, session, vars.clone()).await;
+	// Consult the external authenticator if they were not accepted
+	match res {
+		Err(Error::InvalidAuth) if kvs.authenticator().is_some() => {
+			super::external::signin(kvs, session, vars).await
+		}
+		res => res,
+	}
+}
+
+async fn credentials(
+	kvs: &Datastore,
+	configured_root: &Option<Root<'_>>,
+	strict: bool,
+	session: &mut Session,
+	vars: Object,
 ) -> Result<Option<String>, Error> {
 	// Parse the specified variables
 	let ns = vars.get("NS").or_else(|| vars.get("ns"));
@@ -88,6 +88,10 @@ pub async fn token(kvs: &Datastore, session: &mut Session, auth: String) -> Resu
 	let token = decode::<Claims>(auth, &KEY, &DUD)?;
 	// Parse the token and catch any errors
 	let value = super::parse::parse(auth)?;
+	// Check if the token was signed by an external identity provider
+	if let Some(issuer) = super::oidc::issuer(kvs, token.claims.iss.as_deref()) {
+		return super::oidc::verify(issuer, session, auth, value).await;
+	}
 	// Check if the auth token can be used
 	if let Some(nbf) = token.claims.nbf {
 		if nbf > Utc::now().timestamp() {
@@ -0,0 +1,240 @@
+//! Building the index of an existing table in the background.
+//!
+//! When an index is defined on a table with more records than the threshold
+//! of [`Datastore::with_index_build`], the defining statement does not index
+//! the records of the table. Instead the progress of the build is stored alongside
+//! the index, and [`Datastore::build_indexes`] indexes the records of the table
+//! in key order, in batches, each in its own transaction. While an index is
+//! being built, a write to a record only updates the index if the build has
+//! already reached the record, as the build indexes the latest value of any
+//! other record once it reaches it. Each batch rewrites the records which it
+//! indexes, so that it conflicts with any concurrent write to those records.
+//! The query planner does not use an index until it has been built.
+use crate::ctx::Context;
+use crate::dbs::{Auth, Options};
+use crate::doc::Document;
+use crate::err::Error;
+use crate::idx::SerdeState;
+use crate::key::ib::Ib;
+use crate::kvs::{Datastore, Key, Transaction};
+use crate::sql::{Object, Thing, Value};
+use futures::lock::Mutex;
+use serde::{Deserialize, Serialize};
+use std::collections::HashMap;
+use std::sync::Arc;
+
+const LOG: &str = "surrealdb::idx::build";
+
+/// The progress of an index which is being built in the background
+#[derive(Clone, Debug, Default, Eq, PartialEq, Serialize, Deserialize)]
+pub(crate) struct Build {
+	// The key of the last record which was indexed
+	cursor: Option<Key>,
+	// The number of records which have been indexed
+	indexed: u64,
+	// The number of records in the table when the build started
+	total: u64,
+	// The error which stopped the build, if it failed
+	error: Option<String>,
+}
+
+impl SerdeState for Build {}
+
+impl Build {
+	/// Start building an index of a table with a number of records
+	pub(crate) fn new(total: u64) -> Self {
+		Build {
+			total,
+			..Build::default()
+		}
+	}
+	/// Get the progress of an index, if it is being built
+	pub(crate) async fn get(
+		run: &mut Transaction,
+		ns: &str,
+		db: &str,
+		tb: &str,
+		ix: &str,
+	) -> Result<Option<Build>, Error> {
+		let key = crate::key::ib::new(ns, db, tb, ix);
+		match run.get(key).await? {
+			Some(v) => Ok(Some(Build::try_from_val(v)?)),
+			None => Ok(None),
+		}
+	}
+	/// Get the progress of every index of a table which is being built
+	pub(crate) async fn all(
+		run: &mut Transaction,
+		ns: &str,
+		db: &str,
+		tb: &str,
+	) -> Result<HashMap<String, Build>, Error> {
+		let beg = crate::key::ib::prefix(ns, db, tb);
+		let end = crate::key::ib::suffix(ns, db, tb);
+		let mut out = HashMap::new();
+		for (k, v) in run.scan(beg..end, u32::MAX).await? {
+			let key = Ib::decode(&k)?;
+			out.insert(key.ix.to_owned(), Build::try_from_val(v)?);
+		}
+		Ok(out)
+	}
+	/// Store the progress of an index
+	pub(crate) async fn set(
+		&self,
+		run: &mut Transaction,
+		ns: &str,
+		db: &str,
+		tb: &str,
+		ix: &str,
+	) -> Result<(), Error> {
+		let key = crate::key::ib::new(ns, db, tb, ix);
+		run.set(key, self.try_to_val()?).await
+	}
+	/// Check if a write to a record should update the index, which is only
+	/// the case once the build has reached the record, and has not failed
+	pub(crate) fn covers(&self, opt: &Options, rid: &Thing) -> bool {
+		let key: Key = crate::key::thing::new(opt.ns(), opt.db(), &rid.tb, &rid.id).into();
+		match (&self.cursor, &self.error) {
+			(Some(cursor), None) => key <= *cursor,
+			_ => false,
+		}
+	}
+	/// Describe the progress of the build
+	pub(crate) fn output(&self) -> Value {
+		let mut obj = Object::default();
+		let status = match self.error {
+			Some(_) => "failed",
+			None => "building",
+		};
+		obj.insert("status".to_owned(), status.into());
+		obj.insert("indexed".to_owned(), self.indexed.into());
+		obj.insert("total".to_owned(), self.total.into());
+		let progress = match self.total {
+			0 => 0.0,
+			n => (self.indexed as f64 / n as f64 * 100.0).min(100.0),
+		};
+		obj.insert("progress".to_owned(), progress.into());
+		if let Some(e) = &self.error {
+			obj.insert("error".to_owned(), e.as_str().into());
+		}
+		obj.into()
+	}
+}
+
+/// Find the indexes which are being built, and have not failed
+async fn building(ds: &Datastore) -> Result<Vec<(String, String, String, String)>, Error> {
+	let mut run = ds.transaction(false, false).await?;
+	let mut builds = vec![];
+	for ns in run.all_ns().await?.iter() {
+		for db in run.all_db(&ns.name).await?.iter() {
+			for tb in run.all_tb(&ns.name, &db.name).await?.iter() {
+				for (ix, v) in Build::all(&mut run, &ns.name, &db.name, &tb.name).await? {
+					if v.error.is_none() {
+						builds.push((ns.name.to_raw(), db.name.to_raw(), tb.name.to_raw(), ix));
+					}
+				}
+			}
+		}
+	}
+	run.cancel().await?;
+	Ok(builds)
+}
+
+/// Get the number of indexes which are being built
+pub(crate) async fn pending(ds: &Datastore) -> Result<usize, Error> {
+	Ok(building(ds).await?.len())
+}
+
+/// Index the next batch of records of every index which is being built,
+/// returning the number of records which were indexed
+pub(crate) async fn build(ds: &Datastore) -> Result<usize, Error> {
+	// Find the indexes which are being built
+	let builds = building(ds).await?;
+	// Index the next batch of records of each index
+	let mut count = 0;
+	for (ns, db, tb, ix) in builds.iter() {
+		match batch(ds, ns, db, tb, ix).await {
+			Ok(n) => count += n,
+			// The batch is retried the next time, such as when it conflicted
+			Err(e) => debug!(target: LOG, "Unable to build the index '{ix}' on table '{tb}': {e}"),
+		}
+	}
+	Ok(count)
+}
+
+/// Index the next batch of records of an index which is being built
+async fn batch(ds: &Datastore, ns: &str, db: &str, tb: &str, ix: &str) -> Result<usize, Error> {
+	let txn = Arc::new(Mutex::new(ds.transaction(true, false).await?));
+	let mut run = txn.lock().await;
+	// Check that the index is still being built
+	let mut build = match Build::get(&mut run, ns, db, tb, ix).await? {
+		Some(v) if v.error.is_none() => v,
+		_ => {
+			run.cancel().await?;
+			return Ok(0);
+		}
+	};
+	let def = run.get_ix(ns, db, tb, ix).await?;
+	// Fetch the next batch of records, after the last indexed record
+	let beg = match &build.cursor {
+		Some(v) => {
+			let mut v = v.clone();
+			v.push(0x00);
+			v
+		}
+		None => crate::key::thing::prefix(ns, db, tb),
+	};
+	let end = crate::key::thing::suffix(ns, db, tb);
+	let res = run.scan_and_touch(beg..end, ds.config().index_build_batch_size).await?;
+	drop(run);
+	// Index each of the records
+	let mut ctx = Context::background();
+	ctx.add_transaction(Some(&txn));
+	ctx.add_config(ds.config().clone());
+	let mut opt = Options::new(Auth::Kv);
+	opt.ns = Some(ns.into());
+	opt.db = Some(db.into());
+	let count = res.len();
+	let prev = build.clone();
+	let mut failed = None;
+	for (k, v) in res.into_iter() {
+		let key: crate::key::thing::Thing = (&k).into();
+		let rid = Thing::from((key.tb, key.id));
+		let val: Value = (&v).into();
+		if let Err(e) = Document::build_index(&ctx, &opt, &def, &rid, &val).await {
+			failed = Some(e);
+			break;
+		}
+		build.cursor = Some(k);
+		build.indexed += 1;
+	}
+	let mut run = txn.lock().await;
+	match failed {
+		// The index can not be built, so stop building it
+		Some(e) => {
+			run.cancel().await?;
+			warn!(target: LOG, "Unable to build the index '{ix}' on table '{tb}': {e}");
+			let mut run = ds.transaction(true, false).await?;
+			let build = Build {
+				error: Some(e.to_string()),
+				..prev
+			};
+			build.set(&mut run, ns, db, tb, ix).await?;
+			run.commit().await?;
+			Ok(0)
+		}
+		// Every record has been indexed
+		None if count == 0 => {
+			run.del(crate::key::ib::new(ns, db, tb, ix)).await?;
+			run.commit().await?;
+			info!(target: LOG, "Finished building the index '{ix}' on table '{tb}'");
+			Ok(0)
+		}
+		// Store the progress of the build
+		None => {
+			build.set(&mut run, ns, db, tb, ix).await?;
+			run.commit().await?;
+			Ok(count)
+		}
+	}
+}
@@ -98,7 +98,7 @@ impl Filter {
 
 	#[inline]
 	fn uppercase(c: &str) -> FilterResult {
-		Self::check_term(c, c.to_lowercase())
+		Self::check_term(c, c.to_uppercase())
 	}
 
 	#[inline]
@@ -722,6 +722,15 @@ mod tests {
 		);
 	}
 
+	#[test]
+	fn test_uppercase() {
+		test_analyser(
+			"DEFINE ANALYZER test TOKENIZERS blank,class FILTERS ascii,uppercase;",
+			"Ālea iacta est",
+			&vec!["ALEA", "IACTA", "EST"],
+		);
+	}
+
 	#[test]
 	fn test_edgengram() {
 		test_analyser(
@@ -20,7 +20,7 @@ impl Analyzers {
 	pub(crate) const LIKE: &'static str = "like";
 }
 
-pub(super) struct Analyzer {
+pub(crate) struct Analyzer {
 	t: Option<Vec<SqlTokenizer>>,
 	f: Option<Vec<Filter>>,
 }
@@ -97,6 +97,13 @@ impl Analyzer {
 		Ok((doc_length, res))
 	}
 
+	/// Get the terms which a string is analyzed into, in the order
+	/// in which they appear, without removing duplicates.
+	pub(crate) fn terms(&self, input: String) -> Vec<String> {
+		let tokens = self.analyse(input);
+		tokens.list().iter().map(|t| tokens.get_token_string(t).to_owned()).collect()
+	}
+
 	fn analyse(&self, input: String) -> Tokens {
 		if let Some(t) = &self.t {
 			if !input.is_empty() {
@@ -8,7 +8,7 @@ use serde::{Deserialize, Serialize};
 
 pub(crate) type DocId = u64;
 
-pub(super) struct DocIds {
+pub(crate) struct DocIds {
 	state_key: Key,
 	index_key_base: IndexKeyBase,
 	btree: BTree<DocIdsKeyProvider>,
@@ -18,7 +18,7 @@ pub(super) struct DocIds {
 }
 
 impl DocIds {
-	pub(super) async fn new(
+	pub(crate) async fn new(
 		tx: &mut Transaction,
 		index_key_base: IndexKeyBase,
 		default_btree_order: u32,
@@ -69,7 +69,7 @@ impl DocIds {
 
 	/// Returns the doc_id for the given doc_key.
 	/// If the doc_id does not exists, a new one is created, and associated to the given key.
-	pub(super) async fn resolve_doc_id(
+	pub(crate) async fn resolve_doc_id(
 		&mut self,
 		tx: &mut Transaction,
 		doc_key: Key,
@@ -85,7 +85,7 @@ impl DocIds {
 		}
 	}
 
-	pub(super) async fn remove_doc(
+	pub(crate) async fn remove_doc(
 		&mut self,
 		tx: &mut Transaction,
 		doc_key: Key,
@@ -106,7 +106,7 @@ impl DocIds {
 		}
 	}
 
-	pub(super) async fn get_doc_key(
+	pub(crate) async fn get_doc_key(
 		&self,
 		tx: &mut Transaction,
 		doc_id: DocId,
@@ -123,7 +123,7 @@ impl DocIds {
 		self.btree.statistics::<TrieKeys>(tx).await
 	}
 
-	pub(super) async fn finish(self, tx: &mut Transaction) -> Result<(), Error> {
+	pub(crate) async fn finish(self, tx: &mut Transaction) -> Result<(), Error> {
 		if self.updated || self.btree.is_updated() {
 			let state = State {
 				btree: self.btree.get_state().clone(),
@@ -156,20 +156,20 @@ impl State {
 }
 
 #[derive(Debug, PartialEq)]
-pub(super) enum Resolved {
+pub(crate) enum Resolved {
 	New(DocId),
 	Existing(DocId),
 }
 
 impl Resolved {
-	pub(super) fn doc_id(&self) -> &DocId {
+	pub(crate) fn doc_id(&self) -> &DocId {
 		match self {
 			Resolved::New(doc_id) => doc_id,
 			Resolved::Existing(doc_id) => doc_id,
 		}
 	}
 
-	pub(super) fn was_existing(&self) -> bool {
+	pub(crate) fn was_existing(&self) -> bool {
 		match self {
 			Resolved::New(_) => false,
 			Resolved::Existing(_) => true,
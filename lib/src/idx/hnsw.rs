@@ -0,0 +1,497 @@
+use crate::err::Error;
+use crate::idx::ft::docids::{DocId, DocIds};
+use crate::idx::{IndexKeyBase, SerdeState};
+use crate::key::hn::Hn;
+use crate::kvs::{Key, Transaction};
+use crate::sql::index::Distance;
+use crate::sql::{Array, Thing, Value};
+use rand::Rng;
+use serde::{Deserialize, Serialize};
+use std::cmp::{Ordering, Reverse};
+use std::collections::{BinaryHeap, HashMap, HashSet};
+
+/// The order of the BTree which maps the records to their doc ids
+const BTREE_ORDER: u32 = 100;
+
+/// An approximate nearest neighbour index, storing the vectors of
+/// the records in a Hierarchical Navigable Small World graph.
+pub(crate) struct HnswIndex {
+	state_key: Key,
+	index_key_base: IndexKeyBase,
+	state: State,
+	dimension: usize,
+	dist: Distance,
+	m: usize,
+	efc: usize,
+}
+
+#[derive(Default, Serialize, Deserialize)]
+struct State {
+	/// The node from which every search starts
+	entry: Option<DocId>,
+	/// The top layer of the graph
+	level: usize,
+	doc_count: u64,
+}
+
+impl SerdeState for State {}
+
+#[derive(Serialize, Deserialize)]
+struct Node {
+	vector: Vec<f64>,
+	/// The neighbours of the node, for each layer of the node
+	layers: Vec<Vec<DocId>>,
+}
+
+impl SerdeState for Node {}
+
+/// A node which has been reached by a search, and its distance to the searched vector
+#[derive(Clone, Copy, Debug)]
+struct Candidate(f64, DocId);
+
+impl PartialEq for Candidate {
+	fn eq(&self, other: &Self) -> bool {
+		self.cmp(other).is_eq()
+	}
+}
+
+impl Eq for Candidate {}
+
+impl PartialOrd for Candidate {
+	fn partial_cmp(&self, other: &Self) -> Option<Ordering> {
+		Some(self.cmp(other))
+	}
+}
+
+impl Ord for Candidate {
+	fn cmp(&self, other: &Self) -> Ordering {
+		self.0.total_cmp(&other.0).then(self.1.cmp(&other.1))
+	}
+}
+
+/// The nodes which have been read during a single operation
+type Nodes = HashMap<DocId, Option<Node>>;
+
+/// Keep the node with the most layers as the entry point of the graph
+fn elect(elected: &mut Option<(DocId, usize)>, doc_id: DocId, node: &Node) {
+	let level = node.layers.len().saturating_sub(1);
+	if elected.map_or(true, |(_, l)| level > l) {
+		*elected = Some((doc_id, level));
+	}
+}
+
+impl HnswIndex {
+	pub(crate) async fn new(
+		tx: &mut Transaction,
+		index_key_base: IndexKeyBase,
+		dimension: u32,
+		dist: Distance,
+		m: u32,
+		efc: u32,
+	) -> Result<Self, Error> {
+		let state_key: Key = index_key_base.new_hs_key();
+		let state: State = if let Some(val) = tx.get(state_key.clone()).await? {
+			State::try_from_val(val)?
+		} else {
+			State::default()
+		};
+		Ok(Self {
+			state_key,
+			index_key_base,
+			state,
+			dimension: dimension as usize,
+			dist,
+			m: m.max(2) as usize,
+			efc: efc.max(1) as usize,
+		})
+	}
+
+	async fn doc_ids(&self, tx: &mut Transaction) -> Result<DocIds, Error> {
+		DocIds::new(tx, self.index_key_base.clone(), BTREE_ORDER).await
+	}
+
+	/// Extract a vector from a value, checking its dimension.
+	/// Values which are not arrays of numbers are not indexed.
+	fn vector(&self, v: &Value) -> Result<Option<Vec<f64>>, Error> {
+		let v = match v {
+			Value::Array(v) => v,
+			_ => return Ok(None),
+		};
+		let mut vector = Vec::with_capacity(v.len());
+		for v in v.iter() {
+			match v {
+				Value::Number(v) => vector.push(v.to_float()),
+				_ => return Ok(None),
+			}
+		}
+		if vector.len() != self.dimension {
+			return Err(Error::InvalidVectorDimension {
+				current: vector.len(),
+				expected: self.dimension,
+			});
+		}
+		Ok(Some(vector))
+	}
+
+	fn distance(&self, a: &[f64], b: &[f64]) -> f64 {
+		match self.dist {
+			Distance::Euclidean => {
+				a.iter().zip(b.iter()).map(|(a, b)| (a - b).powi(2)).sum::<f64>().sqrt()
+			}
+			Distance::Manhattan => a.iter().zip(b.iter()).map(|(a, b)| (a - b).abs()).sum(),
+			Distance::Cosine => {
+				let dot = a.iter().zip(b.iter()).map(|(a, b)| a * b).sum::<f64>();
+				let norm = |v: &[f64]| v.iter().map(|v| v * v).sum::<f64>().sqrt();
+				match dot / (norm(a) * norm(b)) {
+					v if v.is_finite() => 1.0 - v,
+					_ => 1.0,
+				}
+			}
+		}
+	}
+
+	/// The maximum number of neighbours of a node on a layer
+	fn max_neighbours(&self, layer: usize) -> usize {
+		match layer {
+			0 => self.m * 2,
+			_ => self.m,
+		}
+	}
+
+	/// Pick the top layer of a new node, with an exponentially decaying probability
+	fn random_level(&self) -> usize {
+		let ml = 1.0 / (self.m as f64).ln();
+		let r: f64 = rand::thread_rng().gen_range(f64::MIN_POSITIVE..1.0);
+		(-r.ln() * ml).floor() as usize
+	}
+
+	async fn get_node<'a>(
+		&self,
+		tx: &mut Transaction,
+		nodes: &'a mut Nodes,
+		doc_id: DocId,
+	) -> Result<Option<&'a Node>, Error> {
+		if !nodes.contains_key(&doc_id) {
+			let node = match tx.get(self.index_key_base.new_hn_key(doc_id)).await? {
+				Some(val) => Some(Node::try_from_val(val)?),
+				None => None,
+			};
+			nodes.insert(doc_id, node);
+		}
+		Ok(nodes[&doc_id].as_ref())
+	}
+
+	async fn set_node(
+		&self,
+		tx: &mut Transaction,
+		nodes: &mut Nodes,
+		doc_id: DocId,
+		node: Node,
+	) -> Result<(), Error> {
+		tx.set(self.index_key_base.new_hn_key(doc_id), node.try_to_val()?).await?;
+		nodes.insert(doc_id, Some(node));
+		Ok(())
+	}
+
+	/// Find the nearest nodes to a vector on a layer, starting from the entry points.
+	/// The candidates are returned from the nearest to the furthest.
+	async fn search_layer(
+		&self,
+		tx: &mut Transaction,
+		nodes: &mut Nodes,
+		q: &[f64],
+		entries: Vec<Candidate>,
+		ef: usize,
+		layer: usize,
+	) -> Result<Vec<Candidate>, Error> {
+		let mut visited: HashSet<DocId> = entries.iter().map(|c| c.1).collect();
+		let mut candidates: BinaryHeap<Reverse<Candidate>> =
+			entries.iter().copied().map(Reverse).collect();
+		let mut results: BinaryHeap<Candidate> = entries.into_iter().collect();
+		while let Some(Reverse(c)) = candidates.pop() {
+			if results.len() >= ef && results.peek().map_or(false, |f| c.0 > f.0) {
+				break;
+			}
+			let neighbours = match self.get_node(tx, nodes, c.1).await? {
+				Some(node) => node.layers.get(layer).cloned().unwrap_or_default(),
+				None => continue,
+			};
+			for n in neighbours {
+				if !visited.insert(n) {
+					continue;
+				}
+				// Neighbours which have been removed are skipped
+				let d = match self.get_node(tx, nodes, n).await? {
+					Some(node) => self.distance(q, &node.vector),
+					None => continue,
+				};
+				if results.len() < ef || results.peek().map_or(true, |f| d < f.0) {
+					candidates.push(Reverse(Candidate(d, n)));
+					results.push(Candidate(d, n));
+					if results.len() > ef {
+						results.pop();
+					}
+				}
+			}
+		}
+		Ok(results.into_sorted_vec())
+	}
+
+	/// Add a neighbour to a node, keeping only the nearest neighbours of the node
+	async fn connect(
+		&self,
+		tx: &mut Transaction,
+		nodes: &mut Nodes,
+		doc_id: DocId,
+		neighbours: &[DocId],
+		layer: usize,
+	) -> Result<(), Error> {
+		let mut node = match nodes.remove(&doc_id) {
+			Some(Some(node)) => node,
+			_ => match tx.get(self.index_key_base.new_hn_key(doc_id)).await? {
+				Some(val) => Node::try_from_val(val)?,
+				None => return Ok(()),
+			},
+		};
+		if node.layers.len() <= layer {
+			nodes.insert(doc_id, Some(node));
+			return Ok(());
+		}
+		let mut links = node.layers[layer].clone();
+		for n in neighbours {
+			if *n != doc_id && !links.contains(n) {
+				links.push(*n);
+			}
+		}
+		if links.len() > self.max_neighbours(layer) {
+			let mut scored = Vec::with_capacity(links.len());
+			for n in links {
+				if let Some(other) = self.get_node(tx, nodes, n).await? {
+					scored.push(Candidate(self.distance(&node.vector, &other.vector), n));
+				}
+			}
+			scored.sort();
+			scored.truncate(self.max_neighbours(layer));
+			links = scored.into_iter().map(|c| c.1).collect();
+		}
+		node.layers[layer] = links;
+		self.set_node(tx, nodes, doc_id, node).await
+	}
+
+	pub(crate) async fn index_document(
+		&mut self,
+		tx: &mut Transaction,
+		rid: &Thing,
+		content: &Array,
+	) -> Result<(), Error> {
+		// Extract the vector, or remove the record if there is none
+		let q = match content.first() {
+			Some(v) => self.vector(v)?,
+			None => None,
+		};
+		let q = match q {
+			Some(q) => q,
+			None => return self.remove_document(tx, rid).await,
+		};
+		// Resolve the doc_id, removing the previous vector of the record
+		let mut d = self.doc_ids(tx).await?;
+		let resolved = d.resolve_doc_id(tx, rid.into()).await?;
+		let doc_id = *resolved.doc_id();
+		let mut nodes = Nodes::new();
+		if resolved.was_existing() {
+			self.remove_node(tx, &mut nodes, doc_id).await?;
+		}
+		d.finish(tx).await?;
+		// Insert the node in the graph
+		let level = self.random_level();
+		let mut node = Node {
+			vector: q.clone(),
+			layers: vec![vec![]; level + 1],
+		};
+		if let Some(entry) = self.state.entry {
+			let mut entries = match self.get_node(tx, &mut nodes, entry).await? {
+				Some(e) => vec![Candidate(self.distance(&q, &e.vector), entry)],
+				None => vec![],
+			};
+			// Descend greedily through the layers above the node
+			for layer in (level + 1..=self.state.level).rev() {
+				entries = self.search_layer(tx, &mut nodes, &q, entries, 1, layer).await?;
+			}
+			// Link the node to its nearest neighbours on its own layers
+			for layer in (0..=level.min(self.state.level)).rev() {
+				let found = self.search_layer(tx, &mut nodes, &q, entries, self.efc, layer).await?;
+				node.layers[layer] = found.iter().take(self.m).map(|c| c.1).collect();
+				entries = found;
+			}
+		}
+		let layers = node.layers.clone();
+		self.set_node(tx, &mut nodes, doc_id, node).await?;
+		for (layer, neighbours) in layers.iter().enumerate() {
+			for n in neighbours {
+				self.connect(tx, &mut nodes, *n, &[doc_id], layer).await?;
+			}
+		}
+		// Update the index state
+		if self.state.entry.is_none() || level > self.state.level {
+			self.state.entry = Some(doc_id);
+			self.state.level = level;
+		}
+		self.state.doc_count += 1;
+		tx.set(self.state_key.clone(), self.state.try_to_val()?).await?;
+		Ok(())
+	}
+
+	pub(crate) async fn remove_document(
+		&mut self,
+		tx: &mut Transaction,
+		rid: &Thing,
+	) -> Result<(), Error> {
+		let mut d = self.doc_ids(tx).await?;
+		if let Some(doc_id) = d.remove_doc(tx, rid.into()).await? {
+			self.remove_node(tx, &mut Nodes::new(), doc_id).await?;
+			d.finish(tx).await?;
+			tx.set(self.state_key.clone(), self.state.try_to_val()?).await?;
+		}
+		Ok(())
+	}
+
+	/// Remove a node from the graph, reconnecting its neighbours to each other
+	async fn remove_node(
+		&mut self,
+		tx: &mut Transaction,
+		nodes: &mut Nodes,
+		doc_id: DocId,
+	) -> Result<(), Error> {
+		let node = match tx.get(self.index_key_base.new_hn_key(doc_id)).await? {
+			Some(val) => Node::try_from_val(val)?,
+			None => return Ok(()),
+		};
+		tx.del(self.index_key_base.new_hn_key(doc_id)).await?;
+		nodes.insert(doc_id, None);
+		for (layer, neighbours) in node.layers.iter().enumerate() {
+			for n in neighbours {
+				let mut unlinked = match self.get_node(tx, nodes, *n).await? {
+					Some(other) => Node {
+						vector: other.vector.clone(),
+						layers: other.layers.clone(),
+					},
+					None => continue,
+				};
+				if let Some(links) = unlinked.layers.get_mut(layer) {
+					links.retain(|l| *l != doc_id);
+				}
+				self.set_node(tx, nodes, *n, unlinked).await?;
+				self.connect(tx, nodes, *n, neighbours, layer).await?;
+			}
+		}
+		self.state.doc_count = self.state.doc_count.saturating_sub(1);
+		// Elect a new entry point if the node was the entry point
+		if self.state.entry == Some(doc_id) {
+			let mut elected = None;
+			for n in node.layers.iter().rev().flatten() {
+				if let Some(other) = self.get_node(tx, nodes, *n).await? {
+					elect(&mut elected, *n, other);
+				}
+			}
+			// The node had no neighbours left, so scan the whole graph
+			if elected.is_none() && self.state.doc_count > 0 {
+				let beg = self.index_key_base.new_hn_key(0);
+				let end = self.index_key_base.new_hn_key(DocId::MAX);
+				for (k, v) in tx.getr(beg..end, u32::MAX).await? {
+					let key: Hn = (&k).into();
+					elect(&mut elected, key.doc_id, &Node::try_from_val(v)?);
+				}
+			}
+			self.state.entry = elected.map(|(id, _)| id);
+			self.state.level = elected.map_or(0, |(_, level)| level);
+		}
+		Ok(())
+	}
+
+	/// Find the approximate nearest records to a vector
+	pub(crate) async fn search(
+		&self,
+		tx: &mut Transaction,
+		v: &Value,
+		k: usize,
+	) -> Result<Vec<Thing>, Error> {
+		let q = match self.vector(v)? {
+			Some(q) => q,
+			None => return Ok(vec![]),
+		};
+		let entry = match self.state.entry {
+			Some(entry) if k > 0 => entry,
+			_ => return Ok(vec![]),
+		};
+		let mut nodes = Nodes::new();
+		let mut entries = match self.get_node(tx, &mut nodes, entry).await? {
+			Some(e) => vec![Candidate(self.distance(&q, &e.vector), entry)],
+			None => return Ok(vec![]),
+		};
+		for layer in (1..=self.state.level).rev() {
+			entries = self.search_layer(tx, &mut nodes, &q, entries, 1, layer).await?;
+		}
+		let found = self.search_layer(tx, &mut nodes, &q, entries, self.efc.max(k), 0).await?;
+		let d = self.doc_ids(tx).await?;
+		let mut res = Vec::with_capacity(k);
+		for c in found.into_iter().take(k) {
+			if let Some(doc_key) = d.get_doc_key(tx, c.1).await? {
+				res.push(doc_key.into());
+			}
+		}
+		Ok(res)
+	}
+}
+
+#[cfg(test)]
+mod tests {
+	use crate::idx::hnsw::HnswIndex;
+	use crate::idx::IndexKeyBase;
+	use crate::kvs::Datastore;
+	use crate::sql::index::Distance;
+	use crate::sql::{Array, Thing, Value};
+	use test_log::test;
+
+	fn vector(v: &[f64]) -> Value {
+		Value::from(v.iter().map(|v| Value::from(*v)).collect::<Vec<_>>())
+	}
+
+	#[test(tokio::test)]
+	async fn test_hnsw_index() {
+		let ds = Datastore::new("memory").await.unwrap();
+		let things: Vec<Thing> =
+			(0..50).map(|i| ("t", format!("doc{i}").as_str()).into()).collect();
+
+		{
+			// Index the points of a line
+			let mut tx = ds.transaction(true, false).await.unwrap();
+			let mut hnsw =
+				HnswIndex::new(&mut tx, IndexKeyBase::default(), 2, Distance::Euclidean, 4, 20)
+					.await
+					.unwrap();
+			for (i, t) in things.iter().enumerate() {
+				let v = Array::from(vec![vector(&[i as f64, 0.0])]);
+				hnsw.index_document(&mut tx, t, &v).await.unwrap();
+			}
+			let v = Array::from(vec![vector(&[1.0, 2.0, 3.0])]);
+			assert!(hnsw.index_document(&mut tx, &things[0], &v).await.is_err());
+			tx.commit().await.unwrap();
+		}
+
+		{
+			// Search the nearest points
+			let mut tx = ds.transaction(true, false).await.unwrap();
+			let mut hnsw =
+				HnswIndex::new(&mut tx, IndexKeyBase::default(), 2, Distance::Euclidean, 4, 20)
+					.await
+					.unwrap();
+			let res = hnsw.search(&mut tx, &vector(&[20.2, 1.0]), 3).await.unwrap();
+			assert_eq!(res, vec![things[20].clone(), things[21].clone(), things[19].clone()]);
+			// Remove a point
+			hnsw.remove_document(&mut tx, &things[20]).await.unwrap();
+			let res = hnsw.search(&mut tx, &vector(&[20.2, 1.0]), 2).await.unwrap();
+			assert_eq!(res, vec![things[21].clone(), things[19].clone()]);
+			tx.commit().await.unwrap();
+		}
+	}
+}
@@ -1,6 +1,8 @@
 mod bkeys;
 pub(crate) mod btree;
+pub(crate) mod build;
 pub(crate) mod ft;
+pub(crate) mod hnsw;
 pub(crate) mod planner;
 
 use crate::dbs::Options;
@@ -18,6 +20,8 @@ use crate::key::bp::Bp;
 use crate::key::bs::Bs;
 use crate::key::bt::Bt;
 use crate::key::bu::Bu;
+use crate::key::hn::Hn;
+use crate::key::hs::Hs;
 use crate::kvs::{Key, Val};
 use crate::sql::statements::DefineIndexStatement;
 use roaring::RoaringTreemap;
@@ -159,6 +163,27 @@ impl IndexKeyBase {
 		)
 		.into()
 	}
+
+	fn new_hn_key(&self, doc_id: DocId) -> Key {
+		Hn::new(
+			self.inner.ns.as_str(),
+			self.inner.db.as_str(),
+			self.inner.tb.as_str(),
+			self.inner.ix.as_str(),
+			doc_id,
+		)
+		.into()
+	}
+
+	fn new_hs_key(&self) -> Key {
+		Hs::new(
+			self.inner.ns.as_str(),
+			self.inner.db.as_str(),
+			self.inner.tb.as_str(),
+			self.inner.ix.as_str(),
+		)
+		.into()
+	}
 }
 
 /// This trait provides `bincode` based default implementations for serialization/deserialization
@@ -3,29 +3,55 @@ pub(crate) mod plan;
 mod tree;
 
 use crate::ctx::Context;
-use crate::dbs::{Iterable, Options};
+use crate::dbs::{Iterable, Options, Transaction};
 use crate::err::Error;
 use crate::idx::planner::executor::QueryExecutor;
 use crate::idx::planner::plan::{Plan, PlanBuilder};
 use crate::idx::planner::tree::{Node, Tree};
-use crate::sql::{Cond, Operator, Table};
+use crate::sql::index::{Distance, Index};
+use crate::sql::statements::DefineIndexStatement;
+use crate::sql::{Array, Cond, Idiom, Limit, Operator, Order, Start, Subquery, Table, Value};
 use std::collections::HashMap;
+use tracing::instrument;
 
 pub(crate) struct QueryPlanner<'a> {
 	opt: &'a Options,
 	cond: &'a Option<Cond>,
+	order: Option<&'a Order>,
+	knn: Option<Knn<'a>>,
 	executors: HashMap<String, QueryExecutor>,
 }
 
+/// A search for the records whose field is nearest to a vector,
+/// which may be satisfied by a vector index on the field
+pub(crate) struct Knn<'a> {
+	pub(crate) field: &'a Idiom,
+	pub(crate) vector: &'a Value,
+	pub(crate) dist: Distance,
+	pub(crate) limit: &'a Limit,
+	pub(crate) start: Option<&'a Start>,
+}
+
 impl<'a> QueryPlanner<'a> {
-	pub(crate) fn new(opt: &'a Options, cond: &'a Option<Cond>) -> Self {
+	/// Create a query planner for a condition, for an optional ORDER clause
+	/// which may be satisfied by iterating in the order of an index, and
+	/// for an optional nearest neighbour search over a vector index
+	pub(crate) fn new(
+		opt: &'a Options,
+		cond: &'a Option<Cond>,
+		order: Option<&'a Order>,
+		knn: Option<Knn<'a>>,
+	) -> Self {
 		Self {
 			opt,
 			cond,
+			order,
+			knn,
 			executors: HashMap::default(),
 		}
 	}
 
+	#[instrument(name = "planner", skip_all, fields(table = %t))]
 	pub(crate) async fn get_iterable(
 		&mut self,
 		ctx: &Context<'_>,
@@ -33,19 +59,176 @@ impl<'a> QueryPlanner<'a> {
 		t: Table,
 	) -> Result<Iterable, Error> {
 		let txn = ctx.clone_transaction()?;
-		let res = Tree::build(self.opt, &txn, &t, self.cond).await?;
+		let res = Tree::build(ctx, self.opt, &txn, &t, self.cond).await?;
 		if let Some((node, im)) = res {
 			if let Some(plan) = AllAndStrategy::build(&node)? {
-				let e = plan.i.new_query_executor(opt, &txn, &t, im).await?;
+				let e = plan.new_query_executor(opt, &txn, &t, im).await?;
 				self.executors.insert(t.0.clone(), e);
 				return Ok(Iterable::Index(t, plan));
 			}
 			let e = QueryExecutor::new(opt, &txn, &t, im, None).await?;
 			self.executors.insert(t.0.clone(), e);
 		}
+		if let Some(plan) = self.knn_index(ctx, &txn, &t).await? {
+			return Ok(Iterable::Index(t, plan));
+		}
+		if let Some(pt) = self.partition(ctx, &txn, &t).await? {
+			return Ok(Iterable::Index(t.clone(), Plan::Partition(t, pt)));
+		}
+		if let Some(ix) = self.order_index(&txn, &t).await? {
+			return Ok(Iterable::Index(t, Plan::Order(ix)));
+		}
 		Ok(Iterable::Table(t))
 	}
 
+	/// Find a vector index on the field of the nearest neighbour search,
+	/// which uses the same distance function as the search
+	async fn knn_index(
+		&self,
+		ctx: &Context<'_>,
+		txn: &Transaction,
+		t: &Table,
+	) -> Result<Option<Plan>, Error> {
+		let knn = match &self.knn {
+			Some(v) => v,
+			None => return Ok(None),
+		};
+		// Field permissions could hide the value of the field
+		if self.opt.perms && self.opt.auth.perms() {
+			return Ok(None);
+		}
+		let ixs = txn.lock().await.all_built_ix(self.opt.ns(), self.opt.db(), &t.0).await?;
+		let found = ixs.iter().find_map(|ix| match ix.index {
+			Index::Hnsw {
+				dimension,
+				dist,
+				..
+			} if dist == knn.dist && matches!(ix.cols.as_slice(), [col] if col.eq(knn.field)) => {
+				Some((ix, dimension as usize))
+			}
+			_ => None,
+		});
+		let (ix, dimension) = match found {
+			Some(v) => v,
+			None => return Ok(None),
+		};
+		// Only vectors which can be searched in the index are planned
+		let vector = knn.vector.compute(ctx, self.opt).await?;
+		match &vector {
+			Value::Array(v) if v.len() == dimension && v.iter().all(Value::is_number) => (),
+			_ => return Ok(None),
+		}
+		let mut k = knn.limit.process(ctx, self.opt).await?;
+		if let Some(start) = knn.start {
+			k = k.saturating_add(start.process(ctx, self.opt).await?);
+		}
+		Ok(Some(Plan::Knn(ix.clone(), vector, k)))
+	}
+
+	/// Find the partition of a partitioned table which every record that
+	/// matches the condition is in, if the condition requires the partition
+	/// expression of the table to be equal to a value
+	async fn partition(
+		&self,
+		ctx: &Context<'_>,
+		txn: &Transaction,
+		t: &Table,
+	) -> Result<Option<Array>, Error> {
+		let cond = match self.cond {
+			Some(v) => v,
+			None => return Ok(None),
+		};
+		// Field permissions could hide the value of the partitioned fields
+		if self.opt.perms && self.opt.auth.perms() {
+			return Ok(None);
+		}
+		let tb = match txn.lock().await.get_tb(self.opt.ns(), self.opt.db(), &t.0).await {
+			Ok(v) => v,
+			Err(Error::TbNotFound {
+				..
+			}) => return Ok(None),
+			Err(e) => return Err(e),
+		};
+		let expr = match &tb.partition {
+			Some(v) => v,
+			None => return Ok(None),
+		};
+		let v = match Self::partition_value(&cond.0, expr) {
+			Some(v) => v.compute(ctx, self.opt).await?,
+			None => return Ok(None),
+		};
+		// Only values which are only equal to values of the same type are planned
+		match v {
+			Value::Number(_)
+			| Value::Strand(_)
+			| Value::Bool(_)
+			| Value::Datetime(_)
+			| Value::Duration(_)
+			| Value::Uuid(_) => Ok(Some(Array::from(vec![v]))),
+			_ => Ok(None),
+		}
+	}
+
+	/// Find a value which the partition expression is compared with in a
+	/// condition, where every other part of the condition is joined by AND
+	fn partition_value<'b>(cond: &'b Value, expr: &Value) -> Option<&'b Value> {
+		match cond {
+			Value::Expression(e) => match e.o {
+				Operator::And => {
+					Self::partition_value(&e.l, expr).or_else(|| Self::partition_value(&e.r, expr))
+				}
+				Operator::Equal if e.l.eq(expr) && Self::is_constant(&e.r) => Some(&e.r),
+				Operator::Equal if e.r.eq(expr) && Self::is_constant(&e.l) => Some(&e.l),
+				_ => None,
+			},
+			Value::Subquery(s) => match s.as_ref() {
+				Subquery::Value(v) => Self::partition_value(v, expr),
+				_ => None,
+			},
+			_ => None,
+		}
+	}
+
+	/// Check if a value is the same for every record of a table
+	fn is_constant(v: &Value) -> bool {
+		match v {
+			Value::Param(p) => !matches!(p.as_str(), "this" | "value"),
+			v => matches!(
+				v,
+				Value::Number(_)
+					| Value::Strand(_)
+					| Value::Bool(_)
+					| Value::Datetime(_)
+					| Value::Duration(_)
+					| Value::Uuid(_)
+			),
+		}
+	}
+
+	/// Find an index on the field of the ORDER clause, in whose key
+	/// order the records of the table can be iterated without sorting
+	async fn order_index(
+		&self,
+		txn: &Transaction,
+		t: &Table,
+	) -> Result<Option<DefineIndexStatement>, Error> {
+		let order = match self.order {
+			Some(v) => v,
+			None => return Ok(None),
+		};
+		// Field permissions could hide the value of the field
+		if self.opt.perms && self.opt.auth.perms() {
+			return Ok(None);
+		}
+		let ixs = txn.lock().await.all_built_ix(self.opt.ns(), self.opt.db(), &t.0).await?;
+		let ix = ixs.iter().find(|ix| {
+			matches!(ix.index, Index::Idx | Index::Uniq)
+				&& matches!(ix.cols.as_slice(), [col] if col.eq(&order.order))
+				&& ix.collation == order.collation
+		});
+		Ok(ix.cloned())
+	}
+
 	pub(crate) fn finish(self) -> Option<HashMap<String, QueryExecutor>> {
 		if self.executors.is_empty() {
 			None
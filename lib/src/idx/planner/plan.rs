@@ -1,6 +1,7 @@
 use crate::dbs::{Options, Transaction};
 use crate::err::Error;
 use crate::idx::ft::{FtIndex, HitsIterator};
+use crate::idx::hnsw::HnswIndex;
 use crate::idx::planner::executor::QueryExecutor;
 use crate::idx::planner::tree::{IndexMap, Node};
 use crate::idx::IndexKeyBase;
@@ -9,7 +10,7 @@ use crate::kvs::Key;
 use crate::sql::index::Index;
 use crate::sql::scoring::Scoring;
 use crate::sql::statements::DefineIndexStatement;
-use crate::sql::{Array, Expression, Ident, Object, Operator, Table, Thing, Value};
+use crate::sql::{Array, Expression, Ident, Idiom, Object, Operator, Table, Thing, Value};
 use async_trait::async_trait;
 use std::collections::HashMap;
 
@@ -33,40 +34,99 @@ impl PlanBuilder {
 	}
 }
 
-pub(crate) struct Plan {
-	pub(super) i: IndexOption,
+pub(crate) enum Plan {
+	/// Iterate over the records which match a condition on an index
+	Condition(IndexOption),
+	/// Iterate over every record, in the order of an index
+	Order(DefineIndexStatement),
+	/// Iterate over the approximate nearest records to a vector
+	Knn(DefineIndexStatement, Value, usize),
+	/// Iterate over the records in a single partition of a table
+	Partition(Table, Array),
 }
 
 impl Plan {
+	pub(super) async fn new_query_executor(
+		&self,
+		opt: &Options,
+		txn: &Transaction,
+		t: &Table,
+		i: IndexMap,
+	) -> Result<QueryExecutor, Error> {
+		match self {
+			Self::Condition(io) => io.new_query_executor(opt, txn, t, i).await,
+			Self::Order(_) | Self::Knn(..) | Self::Partition(..) => {
+				QueryExecutor::new(opt, txn, t, i, None).await
+			}
+		}
+	}
+
 	pub(crate) async fn new_iterator(
 		&self,
 		opt: &Options,
 		txn: &Transaction,
 	) -> Result<Box<dyn ThingIterator>, Error> {
-		self.i.new_iterator(opt, txn).await
+		match self {
+			Self::Condition(io) => io.new_iterator(opt, txn).await,
+			Self::Order(ix) => Ok(Box::new(OrderThingIterator::new(opt, ix))),
+			Self::Knn(ix, v, k) => Ok(Box::new(KnnThingIterator::new(opt, txn, ix, v, *k).await?)),
+			Self::Partition(t, pt) => Ok(Box::new(PartitionThingIterator::new(opt, t, pt))),
+		}
+	}
+
+	/// Returns true if this plan returns records in the order of
+	/// the ORDER clause which the query planner was given
+	pub(crate) fn ordered(&self) -> bool {
+		matches!(self, Self::Order(_))
+	}
+
+	/// Returns the indexed field and its value, if every record which is
+	/// matched by this plan is known to contain that value for the field
+	pub(crate) fn covered(&self) -> Option<(&Idiom, &Value)> {
+		match self {
+			Self::Condition(io) => match (&io.ix.index, &io.op, io.ix.cols.as_slice()) {
+				// A collated index may match values which differ from the value
+				(Index::Idx | Index::Uniq, Operator::Equal, [col]) if io.ix.collation.is_none() => {
+					Some((col, &io.v))
+				}
+				_ => None,
+			},
+			Self::Order(_) | Self::Knn(..) | Self::Partition(..) => None,
+		}
 	}
 
 	pub(crate) fn explain(&self) -> Value {
-		match &self.i {
-			IndexOption {
+		match self {
+			Self::Condition(IndexOption {
 				ix,
 				v,
 				op,
 				..
-			} => Value::Object(Object::from(HashMap::from([
+			}) => Value::Object(Object::from(HashMap::from([
 				("index", Value::from(ix.name.0.to_owned())),
 				("operator", Value::from(op.to_string())),
 				("value", v.clone()),
 			]))),
+			Self::Order(ix) => Value::Object(Object::from(HashMap::from([
+				("index", Value::from(ix.name.0.to_owned())),
+				("order", Value::from(ix.cols[0].to_string())),
+			]))),
+			Self::Knn(ix, v, k) => Value::Object(Object::from(HashMap::from([
+				("index", Value::from(ix.name.0.to_owned())),
+				("vector", v.clone()),
+				("limit", Value::from(*k)),
+			]))),
+			Self::Partition(_, pt) => Value::Object(Object::from(HashMap::from([(
+				"partition",
+				pt.first().cloned().unwrap_or_default(),
+			)]))),
 		}
 	}
 }
 
 impl From<IndexOption> for Plan {
 	fn from(i: IndexOption) -> Self {
-		Self {
-			i,
-		}
+		Self::Condition(i)
 	}
 }
 
@@ -113,6 +173,9 @@ impl IndexOption {
 				} => {
 					matches!(op, Operator::Matches(_))
 				}
+				Index::Hnsw {
+					..
+				} => false,
 			} {
 				return Some(IndexOption::new(ix.clone(), op.to_owned(), v.clone(), ep.clone()));
 			}
@@ -120,6 +183,22 @@ impl IndexOption {
 		None
 	}
 
+	/// Get the option for finding the records whose indexed array contains
+	/// a value, with the entries of the elements of the indexed arrays
+	pub(super) fn found_element(
+		ix: &DefineIndexStatement,
+		op: &Operator,
+		v: &Node,
+		ep: &Expression,
+	) -> Option<Self> {
+		if let Some(v) = v.is_scalar() {
+			if matches!(ix.index, Index::Idx | Index::Uniq) {
+				return Some(IndexOption::new(ix.clone(), op.to_owned(), v.clone(), ep.clone()));
+			}
+		}
+		None
+	}
+
 	async fn new_iterator(
 		&self,
 		opt: &Options,
@@ -130,12 +209,18 @@ impl IndexOption {
 				Operator::Equal => {
 					Ok(Box::new(NonUniqueEqualThingIterator::new(opt, &self.ix, &self.v)?))
 				}
+				Operator::Contain | Operator::Inside => {
+					Ok(Box::new(ElementThingIterator::new(opt, &self.ix, &self.v)))
+				}
 				_ => Err(Error::BypassQueryPlanner),
 			},
 			Index::Uniq => match self.op {
 				Operator::Equal => {
 					Ok(Box::new(UniqueEqualThingIterator::new(opt, &self.ix, &self.v)?))
 				}
+				Operator::Contain | Operator::Inside => {
+					Ok(Box::new(ElementThingIterator::new(opt, &self.ix, &self.v)))
+				}
 				_ => Err(Error::BypassQueryPlanner),
 			},
 			Index::Search {
@@ -150,6 +235,9 @@ impl IndexOption {
 				)),
 				_ => Err(Error::BypassQueryPlanner),
 			},
+			Index::Hnsw {
+				..
+			} => Err(Error::BypassQueryPlanner),
 		}
 	}
 }
@@ -167,7 +255,7 @@ struct NonUniqueEqualThingIterator {
 
 impl NonUniqueEqualThingIterator {
 	fn new(opt: &Options, ix: &DefineIndexStatement, v: &Value) -> Result<Self, Error> {
-		let v = Array::from(v.clone());
+		let v = ix.collated(Array::from(v.clone()));
 		let beg = key::index::prefix_all_ids(opt.ns(), opt.db(), &ix.what, &ix.name, &v);
 		let end = key::index::suffix_all_ids(opt.ns(), opt.db(), &ix.what, &ix.name, &v);
 		Ok(Self {
@@ -193,13 +281,107 @@ impl ThingIterator for NonUniqueEqualThingIterator {
 	}
 }
 
+/// Iterates over every record id whose indexed array contains a value
+struct ElementThingIterator {
+	beg: Vec<u8>,
+	end: Vec<u8>,
+}
+
+impl ElementThingIterator {
+	fn new(opt: &Options, ix: &DefineIndexStatement, v: &Value) -> Self {
+		let v = ix.collated(Array::from(v.clone()));
+		Self {
+			beg: key::ie::prefix_all_ids(opt.ns(), opt.db(), &ix.what, &ix.name, &v),
+			end: key::ie::suffix_all_ids(opt.ns(), opt.db(), &ix.what, &ix.name, &v),
+		}
+	}
+}
+
+#[cfg_attr(not(target_arch = "wasm32"), async_trait)]
+#[cfg_attr(target_arch = "wasm32", async_trait(?Send))]
+impl ThingIterator for ElementThingIterator {
+	async fn next_batch(&mut self, txn: &Transaction, limit: u32) -> Result<Vec<Thing>, Error> {
+		let min = self.beg.clone();
+		let max = self.end.clone();
+		let res = txn.lock().await.scan(min..max, limit).await?;
+		if let Some((key, _)) = res.last() {
+			self.beg = key.clone();
+			self.beg.push(0x00);
+		}
+		let res = res.iter().map(|(_, val)| val.into()).collect();
+		Ok(res)
+	}
+}
+
+/// Iterates over every record id in a partition of a table
+struct PartitionThingIterator {
+	beg: Vec<u8>,
+	end: Vec<u8>,
+}
+
+impl PartitionThingIterator {
+	fn new(opt: &Options, t: &Table, pt: &Array) -> Self {
+		Self {
+			beg: key::pt::prefix_all_ids(opt.ns(), opt.db(), &t.0, pt),
+			end: key::pt::suffix_all_ids(opt.ns(), opt.db(), &t.0, pt),
+		}
+	}
+}
+
+#[cfg_attr(not(target_arch = "wasm32"), async_trait)]
+#[cfg_attr(target_arch = "wasm32", async_trait(?Send))]
+impl ThingIterator for PartitionThingIterator {
+	async fn next_batch(&mut self, txn: &Transaction, limit: u32) -> Result<Vec<Thing>, Error> {
+		let min = self.beg.clone();
+		let max = self.end.clone();
+		let res = txn.lock().await.scan(min..max, limit).await?;
+		if let Some((key, _)) = res.last() {
+			self.beg = key.clone();
+			self.beg.push(0x00);
+		}
+		let res = res.iter().map(|(_, val)| val.into()).collect();
+		Ok(res)
+	}
+}
+
+/// Iterates over every record id in an index, in the order of the index keys
+struct OrderThingIterator {
+	beg: Vec<u8>,
+	end: Vec<u8>,
+}
+
+impl OrderThingIterator {
+	fn new(opt: &Options, ix: &DefineIndexStatement) -> Self {
+		Self {
+			beg: key::index::prefix(opt.ns(), opt.db(), &ix.what, &ix.name),
+			end: key::index::suffix(opt.ns(), opt.db(), &ix.what, &ix.name),
+		}
+	}
+}
+
+#[cfg_attr(not(target_arch = "wasm32"), async_trait)]
+#[cfg_attr(target_arch = "wasm32", async_trait(?Send))]
+impl ThingIterator for OrderThingIterator {
+	async fn next_batch(&mut self, txn: &Transaction, limit: u32) -> Result<Vec<Thing>, Error> {
+		let min = self.beg.clone();
+		let max = self.end.clone();
+		let res = txn.lock().await.scan(min..max, limit).await?;
+		if let Some((key, _)) = res.last() {
+			self.beg = key.clone();
+			self.beg.push(0x00);
+		}
+		let res = res.iter().map(|(_, val)| val.into()).collect();
+		Ok(res)
+	}
+}
+
 struct UniqueEqualThingIterator {
 	key: Option<Key>,
 }
 
 impl UniqueEqualThingIterator {
 	fn new(opt: &Options, ix: &DefineIndexStatement, v: &Value) -> Result<Self, Error> {
-		let v = Array::from(v.clone());
+		let v = ix.collated(Array::from(v.clone()));
 		let key = key::index::new(opt.ns(), opt.db(), &ix.what, &ix.name, &v, None).into();
 		Ok(Self {
 			key: Some(key),
@@ -275,3 +457,44 @@ impl ThingIterator for MatchesThingIterator {
 		Ok(res)
 	}
 }
+
+/// Iterates over the approximate nearest records to a vector, from the nearest
+struct KnnThingIterator {
+	res: std::vec::IntoIter<Thing>,
+}
+
+impl KnnThingIterator {
+	async fn new(
+		opt: &Options,
+		txn: &Transaction,
+		ix: &DefineIndexStatement,
+		v: &Value,
+		k: usize,
+	) -> Result<Self, Error> {
+		let res = match &ix.index {
+			Index::Hnsw {
+				dimension,
+				dist,
+				m,
+				efc,
+			} => {
+				let ikb = IndexKeyBase::new(opt, ix);
+				let mut run = txn.lock().await;
+				let hnsw = HnswIndex::new(&mut run, ikb, *dimension, *dist, *m, *efc).await?;
+				hnsw.search(&mut run, v, k).await?
+			}
+			_ => return Err(Error::BypassQueryPlanner),
+		};
+		Ok(Self {
+			res: res.into_iter(),
+		})
+	}
+}
+
+#[cfg_attr(not(target_arch = "wasm32"), async_trait)]
+#[cfg_attr(target_arch = "wasm32", async_trait(?Send))]
+impl ThingIterator for KnnThingIterator {
+	async fn next_batch(&mut self, _txn: &Transaction, limit: u32) -> Result<Vec<Thing>, Error> {
+		Ok(self.res.by_ref().take(limit as usize).collect())
+	}
+}
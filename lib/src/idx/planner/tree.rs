@@ -1,8 +1,9 @@
+use crate::ctx::Context;
 use crate::dbs::{Options, Transaction};
 use crate::err::Error;
 use crate::idx::planner::plan::IndexOption;
-use crate::sql::statements::DefineIndexStatement;
-use crate::sql::{Cond, Expression, Idiom, Operator, Subquery, Table, Value};
+use crate::sql::statements::{DefineFieldStatement, DefineIndexStatement};
+use crate::sql::{Cond, Expression, Idiom, Kind, Operator, Param, Part, Subquery, Table, Value};
 use async_recursion::async_recursion;
 use std::collections::hash_map::Entry;
 use std::collections::{HashMap, HashSet};
@@ -14,16 +15,19 @@ pub(super) type IndexMap = HashMap<Expression, HashSet<IndexOption>>;
 
 impl Tree {
 	pub(super) async fn build<'a>(
+		ctx: &'a Context<'a>,
 		opt: &'a Options,
 		txn: &'a Transaction,
 		table: &'a Table,
 		cond: &Option<Cond>,
 	) -> Result<Option<(Node, IndexMap)>, Error> {
 		let mut b = TreeBuilder {
+			ctx,
 			opt,
 			txn,
 			table,
 			indexes: None,
+			fields: None,
 			index_map: IndexMap::default(),
 		};
 		let mut res = None;
@@ -35,10 +39,12 @@ impl Tree {
 }
 
 struct TreeBuilder<'a> {
+	ctx: &'a Context<'a>,
 	opt: &'a Options,
 	txn: &'a Transaction,
 	table: &'a Table,
 	indexes: Option<Arc<[DefineIndexStatement]>>,
+	fields: Option<Arc<[DefineFieldStatement]>>,
 	index_map: IndexMap,
 }
 
@@ -50,7 +56,7 @@ impl<'a> TreeBuilder<'a> {
 				.clone()
 				.lock()
 				.await
-				.all_ix(self.opt.ns(), self.opt.db(), &self.table.0)
+				.all_built_ix(self.opt.ns(), self.opt.db(), &self.table.0)
 				.await?;
 			self.indexes = Some(indexes);
 		}
@@ -64,6 +70,27 @@ impl<'a> TreeBuilder<'a> {
 		Ok(None)
 	}
 
+	/// Check if the field is defined with a type which only allows arrays,
+	/// so that CONTAINS and INSIDE can only match an element of the array
+	async fn is_array_field(&mut self, i: &Idiom) -> Result<bool, Error> {
+		if self.fields.is_none() {
+			let fields = self
+				.txn
+				.clone()
+				.lock()
+				.await
+				.all_fd(self.opt.ns(), self.opt.db(), &self.table.0)
+				.await?;
+			self.fields = Some(fields);
+		}
+		if let Some(fields) = &self.fields {
+			if let Some(fd) = fields.iter().find(|fd| fd.name.eq(i)) {
+				return Ok(fd.kind.as_ref().map_or(false, Kind::is_array));
+			}
+		}
+		Ok(false)
+	}
+
 	#[cfg_attr(not(target_arch = "wasm32"), async_recursion)]
 	#[cfg_attr(target_arch = "wasm32", async_recursion(?Send))]
 	async fn eval_value(&mut self, v: &Value) -> Result<Node, Error> {
@@ -74,13 +101,26 @@ impl<'a> TreeBuilder<'a> {
 			Value::Number(_) => Node::Scalar(v.to_owned()),
 			Value::Bool(_) => Node::Scalar(v.to_owned()),
 			Value::Subquery(s) => self.eval_subquery(s).await?,
+			Value::Param(p) => self.eval_param(p).await?,
 			_ => Node::Unsupported,
 		})
 	}
 
+	/// Parameters are computed before the table is iterated, so that a
+	/// subquery which compares an indexed field with a field of `$parent`
+	/// is planned as an index lookup for each parent record
+	async fn eval_param(&mut self, p: &Param) -> Result<Node, Error> {
+		Ok(Node::computed(p.compute(self.ctx, self.opt).await?))
+	}
+
 	async fn eval_idiom(&mut self, i: &Idiom) -> Result<Node, Error> {
+		// An idiom such as `$parent.company` does not refer to this table
+		if let Some(Part::Value(Value::Param(_))) = i.first() {
+			return Ok(Node::computed(i.compute(self.ctx, self.opt).await?));
+		}
 		Ok(if let Some(ix) = self.find_index(i).await? {
-			Node::IndexedField(ix)
+			let array = self.is_array_field(i).await?;
+			Node::IndexedField(ix, array)
 		} else {
 			Node::NonIndexedField
 		})
@@ -102,6 +142,19 @@ impl<'a> TreeBuilder<'a> {
 				self.add_index(e, io);
 			}
 		}
+		// An array field can be searched for one of its elements
+		if let (Some(ix), Operator::Contain) = (left.is_indexed_array(), &e.o) {
+			if let Some(io) = IndexOption::found_element(ix, &e.o, &right, e) {
+				index_option = Some(io.clone());
+				self.add_index(e, io);
+			}
+		}
+		if let (Some(ix), Operator::Inside) = (right.is_indexed_array(), &e.o) {
+			if let Some(io) = IndexOption::found_element(ix, &e.o, &left, e) {
+				index_option = Some(io.clone());
+				self.add_index(e, io);
+			}
+		}
 		Ok(Node::Expression {
 			index_option,
 			left: Box::new(left),
@@ -137,13 +190,24 @@ pub(super) enum Node {
 		right: Box<Node>,
 		operator: Operator,
 	},
-	IndexedField(DefineIndexStatement),
+	/// A field with an index, and whether the field is always an array
+	IndexedField(DefineIndexStatement, bool),
 	NonIndexedField,
 	Scalar(Value),
 	Unsupported,
 }
 
 impl Node {
+	/// Get the node for a value which was computed while planning
+	fn computed(v: Value) -> Self {
+		match v {
+			Value::Strand(_) | Value::Number(_) | Value::Bool(_) | Value::Thing(_) => {
+				Node::Scalar(v)
+			}
+			_ => Node::Unsupported,
+		}
+	}
+
 	pub(super) fn is_scalar(&self) -> Option<&Value> {
 		if let Node::Scalar(v) = self {
 			Some(v)
@@ -153,7 +217,15 @@ impl Node {
 	}
 
 	pub(super) fn is_indexed_field(&self) -> Option<&DefineIndexStatement> {
-		if let Node::IndexedField(ix) = self {
+		if let Node::IndexedField(ix, _) = self {
+			Some(ix)
+		} else {
+			None
+		}
+	}
+
+	pub(super) fn is_indexed_array(&self) -> Option<&DefineIndexStatement> {
+		if let Node::IndexedField(ix, true) = self {
 			Some(ix)
 		} else {
 			None
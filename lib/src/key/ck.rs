@@ -0,0 +1,61 @@
+use crate::sql::id::Id;
+use derive::Key;
+use serde::{Deserialize, Serialize};
+
+#[derive(Clone, Debug, Eq, PartialEq, PartialOrd, Serialize, Deserialize, Key)]
+pub struct Ck<'a> {
+	__: u8,
+	_a: u8,
+	pub ns: &'a str,
+	_b: u8,
+	pub db: &'a str,
+	_c: u8,
+	pub tb: &'a str,
+	_d: u8,
+	_e: u8,
+	_f: u8,
+	pub id: Id,
+	pub nr: u32,
+}
+
+pub fn new<'a>(ns: &'a str, db: &'a str, tb: &'a str, id: &Id, nr: u32) -> Ck<'a> {
+	Ck::new(ns, db, tb, id.to_owned(), nr)
+}
+
+impl<'a> Ck<'a> {
+	pub fn new(ns: &'a str, db: &'a str, tb: &'a str, id: Id, nr: u32) -> Self {
+		Self {
+			__: b'/',
+			_a: b'*',
+			ns,
+			_b: b'*',
+			db,
+			_c: b'*',
+			tb,
+			_d: b'!',
+			_e: b'c',
+			_f: b'k',
+			id,
+			nr,
+		}
+	}
+}
+
+#[cfg(test)]
+mod tests {
+	#[test]
+	fn key() {
+		use super::*;
+		#[rustfmt::skip]
+		let val = Ck::new(
+			"test",
+			"test",
+			"test",
+			"test".into(),
+			7,
+		);
+		let enc = Ck::encode(&val).unwrap();
+		let dec = Ck::decode(&enc).unwrap();
+		assert_eq!(val, dec);
+	}
+}
@@ -0,0 +1,73 @@
+use derive::Key;
+use serde::{Deserialize, Serialize};
+
+#[derive(Clone, Debug, Eq, PartialEq, PartialOrd, Serialize, Deserialize, Key)]
+pub struct Cn<'a> {
+	__: u8,
+	_a: u8,
+	pub ns: &'a str,
+	_b: u8,
+	pub db: &'a str,
+	_c: u8,
+	pub tb: &'a str,
+	_d: u8,
+	_e: u8,
+	_f: u8,
+}
+
+pub fn new<'a>(ns: &'a str, db: &'a str, tb: &'a str) -> Cn<'a> {
+	Cn::new(ns, db, tb)
+}
+
+/// Get the key of a shard of the counter
+pub fn shard(ns: &str, db: &str, tb: &str, shard: u8) -> Vec<u8> {
+	let mut k = Cn::new(ns, db, tb).encode().unwrap();
+	k.push(shard);
+	k
+}
+
+pub fn prefix(ns: &str, db: &str, tb: &str) -> Vec<u8> {
+	let mut k = Cn::new(ns, db, tb).encode().unwrap();
+	k.push(0x00);
+	k
+}
+
+pub fn suffix(ns: &str, db: &str, tb: &str) -> Vec<u8> {
+	let mut k = Cn::new(ns, db, tb).encode().unwrap();
+	k.push(0xff);
+	k
+}
+
+impl<'a> Cn<'a> {
+	pub fn new(ns: &'a str, db: &'a str, tb: &'a str) -> Self {
+		Self {
+			__: b'/',
+			_a: b'*',
+			ns,
+			_b: b'*',
+			db,
+			_c: b'*',
+			tb,
+			_d: b'!',
+			_e: b'c',
+			_f: b'n',
+		}
+	}
+}
+
+#[cfg(test)]
+mod tests {
+	#[test]
+	fn key() {
+		use super::*;
+		#[rustfmt::skip]
+		let val = Cn::new(
+			"test",
+			"test",
+			"test",
+		);
+		let enc = Cn::encode(&val).unwrap();
+		let dec = Cn::decode(&enc).unwrap();
+		assert_eq!(val, dec);
+	}
+}
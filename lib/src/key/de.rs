@@ -0,0 +1,81 @@
+use derive::Key;
+use serde::{Deserialize, Serialize};
+
+#[derive(Clone, Debug, Eq, PartialEq, PartialOrd, Serialize, Deserialize, Key)]
+pub struct De<'a> {
+	__: u8,
+	_a: u8,
+	pub ns: &'a str,
+	_b: u8,
+	pub db: &'a str,
+	_c: u8,
+	pub tb: &'a str,
+	_d: u8,
+	_e: u8,
+	_f: u8,
+	pub at: u64,
+	pub dh: &'a str,
+}
+
+pub fn new<'a>(ns: &'a str, db: &'a str, tb: &'a str, at: u64, dh: &'a str) -> De<'a> {
+	De::new(ns, db, tb, at, dh)
+}
+
+pub fn prefix(ns: &str, db: &str, tb: &str) -> Vec<u8> {
+	let mut k = super::table::new(ns, db, tb).encode().unwrap();
+	k.extend_from_slice(&[b'!', b'd', b'e']);
+	k
+}
+
+/// The end of the range of the content hashes which expired before a time
+pub fn expired(ns: &str, db: &str, tb: &str, at: u64) -> Vec<u8> {
+	De::new(ns, db, tb, at, "").encode().unwrap()
+}
+
+impl<'a> De<'a> {
+	pub fn new(ns: &'a str, db: &'a str, tb: &'a str, at: u64, dh: &'a str) -> Self {
+		Self {
+			__: b'/',
+			_a: b'*',
+			ns,
+			_b: b'*',
+			db,
+			_c: b'*',
+			tb,
+			_d: b'!',
+			_e: b'd',
+			_f: b'e',
+			at,
+			dh,
+		}
+	}
+}
+
+#[cfg(test)]
+mod tests {
+	#[test]
+	fn key() {
+		use super::*;
+		#[rustfmt::skip]
+		let val = De::new(
+			"test",
+			"test",
+			"test",
+			1_000,
+			"test",
+		);
+		let enc = De::encode(&val).unwrap();
+		let dec = De::decode(&enc).unwrap();
+		assert_eq!(val, dec);
+	}
+
+	#[test]
+	fn order() {
+		use super::*;
+		let a = De::new("test", "test", "test", 1_000, "b").encode().unwrap();
+		let b = De::new("test", "test", "test", 2_000, "a").encode().unwrap();
+		assert!(prefix("test", "test", "test") < a);
+		assert!(a < expired("test", "test", "test", 2_000));
+		assert!(b > expired("test", "test", "test", 2_000));
+	}
+}
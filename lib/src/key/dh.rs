@@ -0,0 +1,57 @@
+use derive::Key;
+use serde::{Deserialize, Serialize};
+
+#[derive(Clone, Debug, Eq, PartialEq, PartialOrd, Serialize, Deserialize, Key)]
+pub struct Dh<'a> {
+	__: u8,
+	_a: u8,
+	pub ns: &'a str,
+	_b: u8,
+	pub db: &'a str,
+	_c: u8,
+	pub tb: &'a str,
+	_d: u8,
+	_e: u8,
+	_f: u8,
+	pub dh: &'a str,
+}
+
+pub fn new<'a>(ns: &'a str, db: &'a str, tb: &'a str, dh: &'a str) -> Dh<'a> {
+	Dh::new(ns, db, tb, dh)
+}
+
+impl<'a> Dh<'a> {
+	pub fn new(ns: &'a str, db: &'a str, tb: &'a str, dh: &'a str) -> Self {
+		Self {
+			__: b'/',
+			_a: b'*',
+			ns,
+			_b: b'*',
+			db,
+			_c: b'*',
+			tb,
+			_d: b'!',
+			_e: b'd',
+			_f: b'h',
+			dh,
+		}
+	}
+}
+
+#[cfg(test)]
+mod tests {
+	#[test]
+	fn key() {
+		use super::*;
+		#[rustfmt::skip]
+		let val = Dh::new(
+			"test",
+			"test",
+			"test",
+			"test",
+		);
+		let enc = Dh::encode(&val).unwrap();
+		let dec = Dh::decode(&enc).unwrap();
+		assert_eq!(val, dec);
+	}
+}
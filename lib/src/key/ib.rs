@@ -0,0 +1,69 @@
+use derive::Key;
+use serde::{Deserialize, Serialize};
+
+#[derive(Clone, Debug, Eq, PartialEq, PartialOrd, Serialize, Deserialize, Key)]
+pub struct Ib<'a> {
+	__: u8,
+	_a: u8,
+	pub ns: &'a str,
+	_b: u8,
+	pub db: &'a str,
+	_c: u8,
+	pub tb: &'a str,
+	_d: u8,
+	_e: u8,
+	_f: u8,
+	pub ix: &'a str,
+}
+
+pub fn new<'a>(ns: &'a str, db: &'a str, tb: &'a str, ix: &'a str) -> Ib<'a> {
+	Ib::new(ns, db, tb, ix)
+}
+
+pub fn prefix(ns: &str, db: &str, tb: &str) -> Vec<u8> {
+	let mut k = super::table::new(ns, db, tb).encode().unwrap();
+	k.extend_from_slice(&[b'!', b'i', b'b', 0x00]);
+	k
+}
+
+pub fn suffix(ns: &str, db: &str, tb: &str) -> Vec<u8> {
+	let mut k = super::table::new(ns, db, tb).encode().unwrap();
+	k.extend_from_slice(&[b'!', b'i', b'b', 0xff]);
+	k
+}
+
+impl<'a> Ib<'a> {
+	pub fn new(ns: &'a str, db: &'a str, tb: &'a str, ix: &'a str) -> Self {
+		Self {
+			__: b'/',
+			_a: b'*',
+			ns,
+			_b: b'*',
+			db,
+			_c: b'*',
+			tb,
+			_d: b'!',
+			_e: b'i',
+			_f: b'b',
+			ix,
+		}
+	}
+}
+
+#[cfg(test)]
+mod tests {
+	#[test]
+	fn key() {
+		use super::*;
+		#[rustfmt::skip]
+		let val = Ib::new(
+			"test",
+			"test",
+			"test",
+			"test",
+		);
+		let enc = Ib::encode(&val).unwrap();
+		let dec = Ib::decode(&enc).unwrap();
+		assert_eq!(val, dec);
+	}
+}
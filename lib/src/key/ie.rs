@@ -0,0 +1,169 @@
+use crate::sql::array::Array;
+use crate::sql::id::Id;
+use derive::Key;
+use serde::{Deserialize, Serialize};
+
+#[derive(Clone, Debug, Eq, PartialEq, PartialOrd, Serialize, Deserialize, Key)]
+struct Prefix<'a> {
+	__: u8,
+	_a: u8,
+	pub ns: &'a str,
+	_b: u8,
+	pub db: &'a str,
+	_c: u8,
+	pub tb: &'a str,
+	_d: u8,
+	_e: u8,
+	_f: u8,
+	pub ix: &'a str,
+	_g: u8,
+}
+
+impl<'a> Prefix<'a> {
+	fn new(ns: &'a str, db: &'a str, tb: &'a str, ix: &'a str) -> Self {
+		Self {
+			__: b'/',
+			_a: b'*',
+			ns,
+			_b: b'*',
+			db,
+			_c: b'*',
+			tb,
+			_d: b'!',
+			_e: b'i',
+			_f: b'e',
+			ix,
+			_g: b'*',
+		}
+	}
+}
+
+#[derive(Clone, Debug, Eq, PartialEq, PartialOrd, Serialize, Deserialize, Key)]
+struct PrefixIds<'a> {
+	__: u8,
+	_a: u8,
+	pub ns: &'a str,
+	_b: u8,
+	pub db: &'a str,
+	_c: u8,
+	pub tb: &'a str,
+	_d: u8,
+	_e: u8,
+	_f: u8,
+	pub ix: &'a str,
+	_g: u8,
+	#[serde(with = "super::index::lexical")]
+	pub fd: Array,
+	_h: u8,
+}
+
+impl<'a> PrefixIds<'a> {
+	fn new(ns: &'a str, db: &'a str, tb: &'a str, ix: &'a str, fd: &Array) -> Self {
+		Self {
+			__: b'/',
+			_a: b'*',
+			ns,
+			_b: b'*',
+			db,
+			_c: b'*',
+			tb,
+			_d: b'!',
+			_e: b'i',
+			_f: b'e',
+			ix,
+			_g: b'*',
+			fd: fd.to_owned(),
+			_h: b'*',
+		}
+	}
+}
+
+#[derive(Clone, Debug, Eq, PartialEq, PartialOrd, Serialize, Deserialize, Key)]
+pub struct Ie<'a> {
+	__: u8,
+	_a: u8,
+	pub ns: &'a str,
+	_b: u8,
+	pub db: &'a str,
+	_c: u8,
+	pub tb: &'a str,
+	_d: u8,
+	_e: u8,
+	_f: u8,
+	pub ix: &'a str,
+	_g: u8,
+	#[serde(with = "super::index::lexical")]
+	pub fd: Array,
+	_h: u8,
+	pub id: Id,
+}
+
+pub fn new<'a>(ns: &'a str, db: &'a str, tb: &'a str, ix: &'a str, fd: &Array, id: &Id) -> Ie<'a> {
+	Ie::new(ns, db, tb, ix, fd.to_owned(), id.to_owned())
+}
+
+pub fn prefix(ns: &str, db: &str, tb: &str, ix: &str) -> Vec<u8> {
+	let mut k = Prefix::new(ns, db, tb, ix).encode().unwrap();
+	k.extend_from_slice(&[0x00]);
+	k
+}
+
+pub fn suffix(ns: &str, db: &str, tb: &str, ix: &str) -> Vec<u8> {
+	let mut k = Prefix::new(ns, db, tb, ix).encode().unwrap();
+	k.extend_from_slice(&[0xff]);
+	k
+}
+
+pub fn prefix_all_ids(ns: &str, db: &str, tb: &str, ix: &str, fd: &Array) -> Vec<u8> {
+	let mut k = PrefixIds::new(ns, db, tb, ix, fd).encode().unwrap();
+	k.extend_from_slice(&[0x00]);
+	k
+}
+
+pub fn suffix_all_ids(ns: &str, db: &str, tb: &str, ix: &str, fd: &Array) -> Vec<u8> {
+	let mut k = PrefixIds::new(ns, db, tb, ix, fd).encode().unwrap();
+	k.extend_from_slice(&[0xff]);
+	k
+}
+
+impl<'a> Ie<'a> {
+	pub fn new(ns: &'a str, db: &'a str, tb: &'a str, ix: &'a str, fd: Array, id: Id) -> Self {
+		Self {
+			__: b'/',
+			_a: b'*',
+			ns,
+			_b: b'*',
+			db,
+			_c: b'*',
+			tb,
+			_d: b'!',
+			_e: b'i',
+			_f: b'e',
+			ix,
+			_g: b'*',
+			fd,
+			_h: b'*',
+			id,
+		}
+	}
+}
+
+#[cfg(test)]
+mod tests {
+	#[test]
+	fn key() {
+		use super::*;
+		#[rustfmt::skip]
+		let val = Ie::new(
+			"test",
+			"test",
+			"test",
+			"test",
+			Array::from(vec!["tag"]),
+			"test".into(),
+		);
+		let enc = Ie::encode(&val).unwrap();
+		let dec = Ie::decode(&enc).unwrap();
+		assert_eq!(val, dec);
+	}
+}
@@ -4,6 +4,12 @@ use crate::sql::id::Id;
 use derive::Key;
 use serde::{Deserialize, Serialize};
 
+/// The format version of the index entries. Version 2 encodes numbers so
+/// that they sort by their numeric value, including numbers which are nested
+/// within arrays and objects. The indexes of a datastore whose entries have
+/// an older format are rebuilt when the datastore is opened.
+pub const VERSION: u8 = 2;
+
 #[derive(Clone, Debug, Eq, PartialEq, PartialOrd, Serialize, Deserialize, Key)]
 struct Prefix<'a> {
 	__: u8,
@@ -47,6 +53,7 @@ struct PrefixIds<'a> {
 	_d: u8,
 	pub ix: &'a str,
 	_e: u8,
+	#[serde(with = "lexical")]
 	pub fd: Array,
 	_f: u8,
 }
@@ -82,6 +89,7 @@ pub struct Index<'a> {
 	_d: u8,
 	pub ix: &'a str,
 	_e: u8,
+	#[serde(with = "lexical")]
 	pub fd: Array,
 	_f: u8,
 	pub id: Option<Id>,
@@ -149,6 +157,189 @@ impl<'a> Index<'a> {
 	}
 }
 
+// serde(with = lexical) will (de)serialize the indexed values, encoding any
+// numbers so that they sort by their numeric value, whatever their type, and
+// however deeply they are nested within arrays and objects.
+pub(super) mod lexical {
+	use crate::sql::array::{self, Array};
+	use crate::sql::number::Number;
+	use crate::sql::object::{self, Object};
+	use crate::sql::value::{self, Value};
+	use serde::de::{self, EnumAccess, MapAccess, SeqAccess, VariantAccess, Visitor};
+	use serde::{Deserialize, Deserializer, Serialize, Serializer};
+	use std::collections::BTreeMap;
+	use std::fmt;
+
+	/// The variants of the indexable values, in the order of the Value enum
+	const VARIANTS: &[&str] = &[
+		"None", "Null", "Bool", "Number", "Strand", "Duration", "Datetime", "Uuid", "Array",
+		"Object", "Geometry", "Bytes", "Thing",
+	];
+
+	struct Values<'a>(&'a [Value]);
+
+	impl Serialize for Values<'_> {
+		fn serialize<S: Serializer>(&self, s: S) -> Result<S::Ok, S::Error> {
+			s.collect_seq(self.0.iter().map(Lexical))
+		}
+	}
+
+	struct LexicalArray<'a>(&'a [Value]);
+
+	impl Serialize for LexicalArray<'_> {
+		fn serialize<S: Serializer>(&self, s: S) -> Result<S::Ok, S::Error> {
+			s.serialize_newtype_struct(array::TOKEN, &Values(self.0))
+		}
+	}
+
+	struct Entries<'a>(&'a BTreeMap<String, Value>);
+
+	impl Serialize for Entries<'_> {
+		fn serialize<S: Serializer>(&self, s: S) -> Result<S::Ok, S::Error> {
+			s.collect_map(self.0.iter().map(|(k, v)| (k, Lexical(v))))
+		}
+	}
+
+	struct LexicalObject<'a>(&'a BTreeMap<String, Value>);
+
+	impl Serialize for LexicalObject<'_> {
+		fn serialize<S: Serializer>(&self, s: S) -> Result<S::Ok, S::Error> {
+			s.serialize_newtype_struct(object::TOKEN, &Entries(self.0))
+		}
+	}
+
+	struct Lexical<'a>(&'a Value);
+
+	impl Serialize for Lexical<'_> {
+		fn serialize<S: Serializer>(&self, s: S) -> Result<S::Ok, S::Error> {
+			match self.0 {
+				Value::Number(v) => {
+					s.serialize_newtype_variant(value::TOKEN, 3, "Number", &v.to_lexical())
+				}
+				Value::Array(v) => {
+					s.serialize_newtype_variant(value::TOKEN, 8, "Array", &LexicalArray(&v.0))
+				}
+				Value::Object(v) => {
+					s.serialize_newtype_variant(value::TOKEN, 9, "Object", &LexicalObject(&v.0))
+				}
+				v => v.serialize(s),
+			}
+		}
+	}
+
+	pub(super) fn serialize<S: Serializer>(fd: &Array, s: S) -> Result<S::Ok, S::Error> {
+		s.serialize_newtype_struct(array::TOKEN, &Values(&fd.0))
+	}
+
+	struct ArrayVisitor;
+
+	impl<'de> Visitor<'de> for ArrayVisitor {
+		type Value = Array;
+
+		fn expecting(&self, f: &mut fmt::Formatter) -> fmt::Result {
+			f.write_str("an array of indexed values")
+		}
+
+		fn visit_newtype_struct<D: Deserializer<'de>>(self, d: D) -> Result<Array, D::Error> {
+			d.deserialize_seq(self)
+		}
+
+		fn visit_seq<A: SeqAccess<'de>>(self, mut seq: A) -> Result<Array, A::Error> {
+			let mut out = Vec::new();
+			while let Some(LexicalValue(v)) = seq.next_element()? {
+				out.push(v);
+			}
+			Ok(Array(out))
+		}
+	}
+
+	struct LexicalArrayValue(Array);
+
+	impl<'de> Deserialize<'de> for LexicalArrayValue {
+		fn deserialize<D: Deserializer<'de>>(d: D) -> Result<Self, D::Error> {
+			d.deserialize_newtype_struct(array::TOKEN, ArrayVisitor).map(LexicalArrayValue)
+		}
+	}
+
+	struct ObjectVisitor;
+
+	impl<'de> Visitor<'de> for ObjectVisitor {
+		type Value = Object;
+
+		fn expecting(&self, f: &mut fmt::Formatter) -> fmt::Result {
+			f.write_str("an object of indexed values")
+		}
+
+		fn visit_newtype_struct<D: Deserializer<'de>>(self, d: D) -> Result<Object, D::Error> {
+			d.deserialize_map(self)
+		}
+
+		fn visit_map<A: MapAccess<'de>>(self, mut map: A) -> Result<Object, A::Error> {
+			let mut out = BTreeMap::new();
+			while let Some((k, LexicalValue(v))) = map.next_entry()? {
+				out.insert(k, v);
+			}
+			Ok(Object(out))
+		}
+	}
+
+	struct LexicalObjectValue(Object);
+
+	impl<'de> Deserialize<'de> for LexicalObjectValue {
+		fn deserialize<D: Deserializer<'de>>(d: D) -> Result<Self, D::Error> {
+			d.deserialize_newtype_struct(object::TOKEN, ObjectVisitor).map(LexicalObjectValue)
+		}
+	}
+
+	struct LexicalValue(Value);
+
+	impl<'de> Deserialize<'de> for LexicalValue {
+		fn deserialize<D: Deserializer<'de>>(d: D) -> Result<Self, D::Error> {
+			d.deserialize_enum(value::TOKEN, VARIANTS, ValueVisitor).map(LexicalValue)
+		}
+	}
+
+	struct ValueVisitor;
+
+	impl<'de> Visitor<'de> for ValueVisitor {
+		type Value = Value;
+
+		fn expecting(&self, f: &mut fmt::Formatter) -> fmt::Result {
+			f.write_str("an indexed value")
+		}
+
+		fn visit_enum<A: EnumAccess<'de>>(self, data: A) -> Result<Value, A::Error> {
+			let (i, v) = data.variant::<u32>()?;
+			Ok(match i {
+				0 => v.unit_variant().map(|_| Value::None)?,
+				1 => v.unit_variant().map(|_| Value::Null)?,
+				2 => Value::Bool(v.newtype_variant()?),
+				3 => {
+					let v: String = v.newtype_variant()?;
+					match Number::from_lexical(&v) {
+						Some(v) => Value::Number(v),
+						None => return Err(de::Error::custom("invalid number in index key")),
+					}
+				}
+				4 => Value::Strand(v.newtype_variant()?),
+				5 => Value::Duration(v.newtype_variant()?),
+				6 => Value::Datetime(v.newtype_variant()?),
+				7 => Value::Uuid(v.newtype_variant()?),
+				8 => Value::Array(v.newtype_variant::<LexicalArrayValue>()?.0),
+				9 => Value::Object(v.newtype_variant::<LexicalObjectValue>()?.0),
+				10 => Value::Geometry(v.newtype_variant()?),
+				11 => Value::Bytes(v.newtype_variant()?),
+				12 => Value::Thing(v.newtype_variant()?),
+				_ => return Err(de::Error::custom("unexpected value in index key")),
+			})
+		}
+	}
+
+	pub(super) fn deserialize<'de, D: Deserializer<'de>>(d: D) -> Result<Array, D::Error> {
+		d.deserialize_newtype_struct(array::TOKEN, ArrayVisitor)
+	}
+}
+
 #[cfg(test)]
 mod tests {
 	#[test]
@@ -167,4 +358,53 @@ mod tests {
 		let dec = Index::decode(&enc).unwrap();
 		assert_eq!(val, dec);
 	}
+
+	#[test]
+	fn key_numbers() {
+		use super::*;
+		use crate::sql::number::Number;
+		use crate::sql::value::Value;
+		let key = |v: Number| {
+			let fd = vec![Value::Number(v), Value::from("test")].into();
+			Index::new("test", "test", "test", "test", fd, None).encode().unwrap()
+		};
+		// Numbers are ordered by value, whatever their type
+		let ordered = [
+			key(Number::Int(-10)),
+			key(Number::Float(-1.5)),
+			key(Number::Decimal("-0.15".parse().unwrap())),
+			key(Number::Int(0)),
+			key(Number::Decimal("0.5".parse().unwrap())),
+			key(Number::Int(2)),
+			key(Number::Float(10.25)),
+			key(Number::Decimal("100".parse().unwrap())),
+		];
+		assert!(ordered.windows(2).all(|v| v[0] < v[1]));
+		// Equal numbers of different types have the same key
+		assert_eq!(key(Number::Int(1)), key(Number::Float(1.0)));
+		assert_eq!(key(Number::Int(1)), key(Number::Decimal("1.00".parse().unwrap())));
+		// The key can be decoded
+		let dec = Index::decode(&key(Number::Float(1.5))).unwrap();
+		assert_eq!(dec.fd, vec![Value::from(1.5), Value::from("test")].into());
+	}
+
+	#[test]
+	fn key_nested_numbers() {
+		use super::*;
+		let key = |v: &str| {
+			let fd = vec![crate::sql::value(v).unwrap()].into();
+			Index::new("test", "test", "test", "test", fd, None).encode().unwrap()
+		};
+		// Numbers within arrays and objects are ordered by value
+		assert!(key("[1, 2]") < key("[1.5, 0]"));
+		assert!(key("[1.5, 0]") < key("[10, 0]"));
+		assert!(key("{ a: -1.5 }") < key("{ a: 1 }"));
+		assert!(key("{ a: [2] }") < key("{ a: [10dec] }"));
+		// Equal nested numbers of different types have the same key
+		assert_eq!(key("[1, { a: 2 }]"), key("[1.0, { a: 2dec }]"));
+		// The key can be decoded
+		let val = crate::sql::value("[1.5, { a: [2, 'test'] }]").unwrap();
+		let dec = Index::decode(&key("[1.5, { a: [2, 'test'] }]")).unwrap();
+		assert_eq!(dec.fd, vec![val].into());
+	}
 }
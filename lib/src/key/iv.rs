@@ -0,0 +1,44 @@
+use derive::Key;
+use serde::{Deserialize, Serialize};
+
+#[derive(Clone, Debug, Eq, PartialEq, PartialOrd, Serialize, Deserialize, Key)]
+pub struct Iv {
+	__: u8,
+	_a: u8,
+	_b: u8,
+	_c: u8,
+}
+
+pub fn new() -> Iv {
+	Iv::new()
+}
+
+impl Default for Iv {
+	fn default() -> Self {
+		Self::new()
+	}
+}
+
+impl Iv {
+	pub fn new() -> Iv {
+		Iv {
+			__: b'/',
+			_a: b'!',
+			_b: b'i',
+			_c: b'v',
+		}
+	}
+}
+
+#[cfg(test)]
+mod tests {
+	#[test]
+	fn key() {
+		use super::*;
+		#[rustfmt::skip]
+		let val = Iv::new();
+		let enc = Iv::encode(&val).unwrap();
+		let dec = Iv::decode(&enc).unwrap();
+		assert_eq!(val, dec);
+	}
+}
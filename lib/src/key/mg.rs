@@ -0,0 +1,64 @@
+use derive::Key;
+use serde::{Deserialize, Serialize};
+
+#[derive(Clone, Debug, Eq, PartialEq, PartialOrd, Serialize, Deserialize, Key)]
+pub struct Mg<'a> {
+	__: u8,
+	_a: u8,
+	pub ns: &'a str,
+	_b: u8,
+	pub db: &'a str,
+	_c: u8,
+	_d: u8,
+	_e: u8,
+	pub mg: &'a str,
+}
+
+pub fn new<'a>(ns: &'a str, db: &'a str, mg: &'a str) -> Mg<'a> {
+	Mg::new(ns, db, mg)
+}
+
+pub fn prefix(ns: &str, db: &str) -> Vec<u8> {
+	let mut k = super::database::new(ns, db).encode().unwrap();
+	k.extend_from_slice(&[b'!', b'm', b'g', 0x00]);
+	k
+}
+
+pub fn suffix(ns: &str, db: &str) -> Vec<u8> {
+	let mut k = super::database::new(ns, db).encode().unwrap();
+	k.extend_from_slice(&[b'!', b'm', b'g', 0xff]);
+	k
+}
+
+impl<'a> Mg<'a> {
+	pub fn new(ns: &'a str, db: &'a str, mg: &'a str) -> Self {
+		Self {
+			__: b'/',
+			_a: b'*',
+			ns,
+			_b: b'*',
+			db,
+			_c: b'!',
+			_d: b'm',
+			_e: b'g',
+			mg,
+		}
+	}
+}
+
+#[cfg(test)]
+mod tests {
+	#[test]
+	fn key() {
+		use super::*;
+		#[rustfmt::skip]
+		let val = Mg::new(
+			"test",
+			"test",
+			"test",
+		);
+		let enc = Mg::encode(&val).unwrap();
+		let dec = Mg::decode(&enc).unwrap();
+		assert_eq!(val, dec);
+	}
+}
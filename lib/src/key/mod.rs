@@ -1,6 +1,8 @@
 //! How the keys are structured in the key value store
 ///
 /// KV              /
+/// IV              /!iv
+/// RS              /!rs
 /// NS              /!ns{ns}
 ///
 /// Namespace       /*{ns}
@@ -12,8 +14,13 @@
 /// AZ              /*{ns}*{db}!az{az}
 /// DL              /*{ns}*{db}!dl{us}
 /// DT              /*{ns}*{db}!dt{tk}
+/// MA              /*{ns}*{db}!ma{mg}
+/// MG              /*{ns}*{db}!mg{mg}
 /// PA              /*{ns}*{db}!pa{pa}
 /// SC              /*{ns}*{db}!sc{sc}
+/// SH              /*{ns}*{db}!sh
+/// SQ              /*{ns}*{db}!sq{sq}
+/// SV              /*{ns}*{db}!sv{sq}
 /// TB              /*{ns}*{db}!tb{tb}
 /// LQ              /*{ns}*{db}!lq{lq}
 ///
@@ -23,11 +30,19 @@
 /// AZ              /*{ns}*{db}!az{az}
 ///
 /// Table           /*{ns}*{db}*{tb}
+/// BY              /*{ns}*{db}*{tb}!by{shard}
+/// CK              /*{ns}*{db}*{tb}!ck{id}{nr}
+/// CN              /*{ns}*{db}*{tb}!cn{shard}
+/// DE              /*{ns}*{db}*{tb}!de{at}{dh}
+/// DH              /*{ns}*{db}*{tb}!dh{dh}
 /// EV              /*{ns}*{db}*{tb}!ev{ev}
 /// FD              /*{ns}*{db}*{tb}!fd{fd}
 /// FT              /*{ns}*{db}*{tb}!ft{ft}
+/// IB              /*{ns}*{db}*{tb}!ib{ix}
+/// IE              /*{ns}*{db}*{tb}!ie{ix}*{fd}*{id}
 /// IX              /*{ns}*{db}*{tb}!ix{ix}
 /// LV              /*{ns}*{db}*{tb}!lv{lv}
+/// PT              /*{ns}*{db}*{tb}!pt{pt}{id}
 ///
 /// Thing           /*{ns}*{db}*{tb}*{id}
 ///
@@ -45,6 +60,8 @@
 /// BS              /*{ns}*{db}*{tb}!bs{ix}
 /// BT              /*{ns}*{db}*{tb}!bt{ix}*{id}
 /// BU              /*{ns}*{db}*{tb}!bu{ix}*{id}
+/// HN              /*{ns}*{db}*{tb}!hn{ix}*{id}
+/// HS              /*{ns}*{db}*{tb}!hs{ix}
 pub mod az; // Stores a DEFINE ANALYZER config definition
 pub mod bc; // Stores Doc list for each term
 pub mod bd; // Stores BTree nodes for doc ids
@@ -56,8 +73,13 @@ pub mod bp; // Stores BTree nodes for postings
 pub mod bs; // Stores FullText index states
 pub mod bt; // Stores BTree nodes for terms
 pub mod bu; // Stores terms for term_ids
+pub mod by; // Stores the number of bytes of record data in a table
+pub mod ck; // Stores a chunk of the data of a large record
+pub mod cn; // Stores the number of records in a table
 pub mod database; // Stores the key prefix for all keys under a database
 pub mod db; // Stores a DEFINE DATABASE config definition
+pub mod de; // Stores the expiry time of the content hash of a recently created record
+pub mod dh; // Stores the content hash of a recently created record
 pub mod dl; // Stores a DEFINE LOGIN ON DATABASE config definition
 pub mod dt; // Stores a DEFINE LOGIN ON DATABASE config definition
 pub mod ev; // Stores a DEFINE EVENT config definition
@@ -65,19 +87,31 @@ pub mod fc; // Stores a DEFINE FUNCTION config definition
 pub mod fd; // Stores a DEFINE FIELD config definition
 pub mod ft; // Stores a DEFINE TABLE AS config definition
 pub mod graph; // Stores a graph edge pointer
+pub mod hn; // Stores HNSW graph nodes for doc ids
+pub mod hs; // Stores HNSW index states
+pub mod ib; // Stores the progress of an index which is built in the background
+pub mod ie; // Stores an index entry for an element of an indexed array
 pub mod index; // Stores an index entry
+pub mod iv; // Stores the format version of the index entries
 pub mod ix; // Stores a DEFINE INDEX config definition
 pub mod kv; // Stores the key prefix for all keys
 pub mod lq; // Stores a LIVE SELECT query definition on the database
 pub mod lv; // Stores a LIVE SELECT query definition on the table
+pub mod ma; // Stores the time at which a migration was applied
+pub mod mg; // Stores a DEFINE MIGRATION config definition
 pub mod namespace; // Stores the key prefix for all keys under a namespace
 pub mod nl; // Stores a DEFINE LOGIN ON NAMESPACE config definition
 pub mod ns; // Stores a DEFINE NAMESPACE config definition
 pub mod nt; // Stores a DEFINE TOKEN ON NAMESPACE config definition
 pub mod pa; // Stores a DEFINE PARAM config definition
+pub mod pt; // Stores the membership of a record in a table partition
+pub mod rs; // Stores the location of a restore which has not completed
 pub mod sc; // Stores a DEFINE SCOPE config definition
 pub mod scope; // Stores the key prefix for all keys under a scope
+pub mod sh; // Stores the secret which signs record share links
+pub mod sq; // Stores a DEFINE SEQUENCE config definition
 pub mod st; // Stores a DEFINE TOKEN ON SCOPE config definition
+pub mod sv; // Stores the current value of a sequence
 pub mod table; // Stores the key prefix for all keys under a table
 pub mod tb; // Stores a DEFINE TABLE config definition
 pub mod thing;
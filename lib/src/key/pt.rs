@@ -0,0 +1,161 @@
+use crate::sql::array::Array;
+use crate::sql::id::Id;
+use derive::Key;
+use serde::{Deserialize, Serialize};
+
+#[derive(Clone, Debug, Eq, PartialEq, PartialOrd, Serialize, Deserialize, Key)]
+struct Prefix<'a> {
+	__: u8,
+	_a: u8,
+	pub ns: &'a str,
+	_b: u8,
+	pub db: &'a str,
+	_c: u8,
+	pub tb: &'a str,
+	_d: u8,
+	_e: u8,
+	_f: u8,
+}
+
+impl<'a> Prefix<'a> {
+	fn new(ns: &'a str, db: &'a str, tb: &'a str) -> Self {
+		Self {
+			__: b'/',
+			_a: b'*',
+			ns,
+			_b: b'*',
+			db,
+			_c: b'*',
+			tb,
+			_d: b'!',
+			_e: b'p',
+			_f: b't',
+		}
+	}
+}
+
+#[derive(Clone, Debug, Eq, PartialEq, PartialOrd, Serialize, Deserialize, Key)]
+struct PrefixPt<'a> {
+	__: u8,
+	_a: u8,
+	pub ns: &'a str,
+	_b: u8,
+	pub db: &'a str,
+	_c: u8,
+	pub tb: &'a str,
+	_d: u8,
+	_e: u8,
+	_f: u8,
+	#[serde(with = "super::index::lexical")]
+	pub pt: Array,
+}
+
+impl<'a> PrefixPt<'a> {
+	fn new(ns: &'a str, db: &'a str, tb: &'a str, pt: &Array) -> Self {
+		Self {
+			__: b'/',
+			_a: b'*',
+			ns,
+			_b: b'*',
+			db,
+			_c: b'*',
+			tb,
+			_d: b'!',
+			_e: b'p',
+			_f: b't',
+			pt: pt.to_owned(),
+		}
+	}
+}
+
+#[derive(Clone, Debug, Eq, PartialEq, PartialOrd, Serialize, Deserialize, Key)]
+pub struct Pt<'a> {
+	__: u8,
+	_a: u8,
+	pub ns: &'a str,
+	_b: u8,
+	pub db: &'a str,
+	_c: u8,
+	pub tb: &'a str,
+	_d: u8,
+	_e: u8,
+	_f: u8,
+	#[serde(with = "super::index::lexical")]
+	pub pt: Array,
+	pub id: Id,
+}
+
+pub fn new<'a>(ns: &'a str, db: &'a str, tb: &'a str, pt: &Array, id: &Id) -> Pt<'a> {
+	Pt::new(ns, db, tb, pt.to_owned(), id.to_owned())
+}
+
+pub fn prefix(ns: &str, db: &str, tb: &str) -> Vec<u8> {
+	let mut k = Prefix::new(ns, db, tb).encode().unwrap();
+	k.extend_from_slice(&[0x00]);
+	k
+}
+
+pub fn suffix(ns: &str, db: &str, tb: &str) -> Vec<u8> {
+	let mut k = Prefix::new(ns, db, tb).encode().unwrap();
+	k.extend_from_slice(&[0xff]);
+	k
+}
+
+pub fn prefix_all_ids(ns: &str, db: &str, tb: &str, pt: &Array) -> Vec<u8> {
+	let mut k = PrefixPt::new(ns, db, tb, pt).encode().unwrap();
+	k.extend_from_slice(&[0x00]);
+	k
+}
+
+pub fn suffix_all_ids(ns: &str, db: &str, tb: &str, pt: &Array) -> Vec<u8> {
+	let mut k = PrefixPt::new(ns, db, tb, pt).encode().unwrap();
+	k.extend_from_slice(&[0xff]);
+	k
+}
+
+impl<'a> Pt<'a> {
+	pub fn new(ns: &'a str, db: &'a str, tb: &'a str, pt: Array, id: Id) -> Self {
+		Self {
+			__: b'/',
+			_a: b'*',
+			ns,
+			_b: b'*',
+			db,
+			_c: b'*',
+			tb,
+			_d: b'!',
+			_e: b'p',
+			_f: b't',
+			pt,
+			id,
+		}
+	}
+}
+
+#[cfg(test)]
+mod tests {
+	#[test]
+	fn key() {
+		use super::*;
+		#[rustfmt::skip]
+		let val = Pt::new(
+			"test",
+			"test",
+			"test",
+			Array::from(vec![3]),
+			"test".into(),
+		);
+		let enc = Pt::encode(&val).unwrap();
+		let dec = Pt::decode(&enc).unwrap();
+		assert_eq!(val, dec);
+	}
+
+	#[test]
+	fn numbers_share_a_partition() {
+		use super::*;
+		use crate::sql::Number;
+		let int = prefix_all_ids("test", "test", "test", &Array::from(vec![3]));
+		let float = prefix_all_ids("test", "test", "test", &Array::from(vec![Number::Float(3.0)]));
+		assert_eq!(int, float);
+	}
+}
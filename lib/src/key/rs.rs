@@ -0,0 +1,44 @@
+use derive::Key;
+use serde::{Deserialize, Serialize};
+
+#[derive(Clone, Debug, Eq, PartialEq, PartialOrd, Serialize, Deserialize, Key)]
+pub struct Rs {
+	__: u8,
+	_a: u8,
+	_b: u8,
+	_c: u8,
+}
+
+pub fn new() -> Rs {
+	Rs::new()
+}
+
+impl Default for Rs {
+	fn default() -> Self {
+		Self::new()
+	}
+}
+
+impl Rs {
+	pub fn new() -> Rs {
+		Rs {
+			__: b'/',
+			_a: b'!',
+			_b: b'r',
+			_c: b's',
+		}
+	}
+}
+
+#[cfg(test)]
+mod tests {
+	#[test]
+	fn key() {
+		use super::*;
+		#[rustfmt::skip]
+		let val = Rs::new();
+		let enc = Rs::encode(&val).unwrap();
+		let dec = Rs::decode(&enc).unwrap();
+		assert_eq!(val, dec);
+	}
+}
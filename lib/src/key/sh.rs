@@ -0,0 +1,49 @@
+use derive::Key;
+use serde::{Deserialize, Serialize};
+
+#[derive(Clone, Debug, Eq, PartialEq, PartialOrd, Serialize, Deserialize, Key)]
+pub struct Sh<'a> {
+	__: u8,
+	_a: u8,
+	pub ns: &'a str,
+	_b: u8,
+	pub db: &'a str,
+	_c: u8,
+	_d: u8,
+	_e: u8,
+}
+
+pub fn new<'a>(ns: &'a str, db: &'a str) -> Sh<'a> {
+	Sh::new(ns, db)
+}
+
+impl<'a> Sh<'a> {
+	pub fn new(ns: &'a str, db: &'a str) -> Self {
+		Self {
+			__: b'/',
+			_a: b'*',
+			ns,
+			_b: b'*',
+			db,
+			_c: b'!',
+			_d: b's',
+			_e: b'h',
+		}
+	}
+}
+
+#[cfg(test)]
+mod tests {
+	#[test]
+	fn key() {
+		use super::*;
+		#[rustfmt::skip]
+		let val = Sh::new(
+			"test",
+			"test",
+		);
+		let enc = Sh::encode(&val).unwrap();
+		let dec = Sh::decode(&enc).unwrap();
+		assert_eq!(val, dec);
+	}
+}
@@ -0,0 +1,64 @@
+use derive::Key;
+use serde::{Deserialize, Serialize};
+
+#[derive(Clone, Debug, Eq, PartialEq, PartialOrd, Serialize, Deserialize, Key)]
+pub struct Sv<'a> {
+	__: u8,
+	_a: u8,
+	pub ns: &'a str,
+	_b: u8,
+	pub db: &'a str,
+	_c: u8,
+	_d: u8,
+	_e: u8,
+	pub sq: &'a str,
+}
+
+pub fn new<'a>(ns: &'a str, db: &'a str, sq: &'a str) -> Sv<'a> {
+	Sv::new(ns, db, sq)
+}
+
+pub fn prefix(ns: &str, db: &str) -> Vec<u8> {
+	let mut k = super::database::new(ns, db).encode().unwrap();
+	k.extend_from_slice(&[b'!', b's', b'v', 0x00]);
+	k
+}
+
+pub fn suffix(ns: &str, db: &str) -> Vec<u8> {
+	let mut k = super::database::new(ns, db).encode().unwrap();
+	k.extend_from_slice(&[b'!', b's', b'v', 0xff]);
+	k
+}
+
+impl<'a> Sv<'a> {
+	pub fn new(ns: &'a str, db: &'a str, sq: &'a str) -> Self {
+		Self {
+			__: b'/',
+			_a: b'*',
+			ns,
+			_b: b'*',
+			db,
+			_c: b'!',
+			_d: b's',
+			_e: b'v',
+			sq,
+		}
+	}
+}
+
+#[cfg(test)]
+mod tests {
+	#[test]
+	fn key() {
+		use super::*;
+		#[rustfmt::skip]
+		let val = Sv::new(
+			"test",
+			"test",
+			"test",
+		);
+		let enc = Sv::encode(&val).unwrap();
+		let dec = Sv::decode(&enc).unwrap();
+		assert_eq!(val, dec);
+	}
+}
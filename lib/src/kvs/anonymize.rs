@@ -0,0 +1,249 @@
+//! The anonymization of sensitive fields in exported records.
+//!
+//! Fields which are defined as `SENSITIVE` are anonymized in the records
+//! of an anonymized export, so that a production database can be exported
+//! into a non-production environment. A masked value keeps its shape, with
+//! each letter and digit replaced. A hashed or faked value is derived from
+//! a hash of the original value alone, so that equal values remain equal
+//! across records and tables, and values can still be joined and grouped.
+use crate::sql::number::Number;
+use crate::sql::object::Object;
+use crate::sql::part::{Next, Part};
+use crate::sql::statements::define::Sensitive;
+use crate::sql::statements::DefineFieldStatement;
+use crate::sql::value::Value;
+use crate::sql::{Datetime, Uuid};
+use chrono::{Duration, TimeZone, Utc};
+use rust_decimal::Decimal;
+use sha2::{Digest, Sha256};
+use std::collections::BTreeMap;
+
+/// The words which are used for faked strings
+const WORDS: [&str; 16] = [
+	"alex", "blair", "casey", "drew", "ellis", "frankie", "gray", "harper", "jordan", "kai",
+	"logan", "morgan", "quinn", "riley", "sage", "taylor",
+];
+
+/// Anonymize the sensitive fields of a record
+pub(super) fn record(v: &mut Value, fds: &[DefineFieldStatement]) {
+	for fd in fds.iter() {
+		if let Some(s) = fd.sensitive {
+			field(v, &fd.name, s);
+		}
+	}
+}
+
+/// Anonymize the value at a field path, without adding any missing fields
+fn field(v: &mut Value, path: &[Part], s: Sensitive) {
+	match path.first() {
+		Some(p) => match (v, p) {
+			(Value::Object(v), Part::Field(f)) => {
+				if let Some(v) = v.get_mut(f.to_raw().as_str()) {
+					field(v, path.next(), s)
+				}
+			}
+			(Value::Array(v), Part::All) => {
+				let path = path.next();
+				v.iter_mut().for_each(|v| field(v, path, s));
+			}
+			_ => (),
+		},
+		None => *v = anonymize(v, s),
+	}
+}
+
+/// Anonymize a value. Values which can not be hashed or faked into
+/// a value of the same type are masked, and records are kept, so
+/// that the links between records are not broken.
+pub(super) fn anonymize(v: &Value, s: Sensitive) -> Value {
+	match v {
+		Value::None | Value::Null | Value::Bool(_) | Value::Thing(_) => v.clone(),
+		Value::Array(v) => Value::Array(v.iter().map(|v| anonymize(v, s)).collect()),
+		Value::Object(v) => Value::Object(Object::from(
+			v.iter().map(|(k, v)| (k.clone(), anonymize(v, s))).collect::<BTreeMap<_, _>>(),
+		)),
+		v => match s {
+			Sensitive::Mask => mask(v),
+			Sensitive::Hash => hash(v),
+			Sensitive::Fake => fake(v),
+		},
+	}
+}
+
+/// Replace each letter and digit of a value
+fn mask(v: &Value) -> Value {
+	match v {
+		Value::Strand(v) => Value::from(
+			v.chars()
+				.map(|c| match c.is_alphanumeric() {
+					true => '*',
+					false => c,
+				})
+				.collect::<String>(),
+		),
+		Value::Number(Number::Int(_)) => Value::from(0),
+		Value::Number(Number::Float(_)) => Value::from(0.0),
+		Value::Number(Number::Decimal(_)) => Value::from(Decimal::ZERO),
+		Value::Datetime(_) => Value::from(Datetime::from(Utc.timestamp_opt(0, 0).unwrap())),
+		Value::Uuid(_) => Value::from(Uuid::from(uuid::Uuid::nil())),
+		_ => Value::None,
+	}
+}
+
+/// Replace a value with a hash of the value
+fn hash(v: &Value) -> Value {
+	let h = digest(v);
+	match v {
+		Value::Strand(_) => Value::from(h.iter().map(|v| format!("{v:02x}")).collect::<String>()),
+		Value::Number(Number::Int(_)) => Value::from(seed(&h) as i64 & i64::MAX),
+		Value::Number(Number::Decimal(_)) => Value::from(Decimal::from(seed(&h) & i64::MAX as u64)),
+		Value::Uuid(_) => {
+			Value::from(Uuid::from(uuid::Builder::from_random_bytes(bytes(&h)).into_uuid()))
+		}
+		v => mask(v),
+	}
+}
+
+/// Replace a value with a realistic value of the same type
+fn fake(v: &Value) -> Value {
+	let h = digest(v);
+	let mut n = seed(&h);
+	match v {
+		// Replace email addresses with a unique address on a reserved domain
+		Value::Strand(v) if v.contains('@') => {
+			let word = WORDS[(n % WORDS.len() as u64) as usize];
+			Value::from(format!("{word}.{:012x}@example.com", n >> 16))
+		}
+		// Replace the digits of numbers, such as phone numbers, keeping their format
+		Value::Strand(v) if v.chars().any(|c| c.is_ascii_digit()) => Value::from(digits(v, &mut n)),
+		// Replace each word with a word of the same case
+		Value::Strand(v) => Value::from(
+			v.split(' ')
+				.enumerate()
+				.map(|(i, w)| {
+					let word = WORDS[(h[i % h.len()] as usize) % WORDS.len()];
+					match w.chars().next().map_or(false, char::is_uppercase) {
+						true => word[..1].to_uppercase() + &word[1..],
+						false => word.to_owned(),
+					}
+				})
+				.collect::<Vec<_>>()
+				.join(" "),
+		),
+		// Replace numbers with a number of the same sign and number of digits
+		Value::Number(Number::Int(v)) => {
+			Value::from(digits(&v.to_string(), &mut n).parse::<i64>().unwrap_or_default())
+		}
+		Value::Number(Number::Decimal(v)) => {
+			Value::from(digits(&v.to_string(), &mut n).parse::<Decimal>().unwrap_or_default())
+		}
+		// Replace floats with a float of the same sign and magnitude
+		Value::Number(Number::Float(v)) => {
+			let scale = 0.5 + (n % 1000) as f64 / 1000.0;
+			Value::from(v * scale)
+		}
+		// Move datetimes by up to a year in either direction
+		Value::Datetime(v) => {
+			let secs = (n % (2 * 365 * 86400)) as i64 - 365 * 86400;
+			Value::from(Datetime::from(v.0 + Duration::seconds(secs)))
+		}
+		Value::Uuid(_) => {
+			Value::from(Uuid::from(uuid::Builder::from_random_bytes(bytes(&h)).into_uuid()))
+		}
+		v => mask(v),
+	}
+}
+
+/// Replace each digit of a string, keeping a leading non-zero digit
+fn digits(v: &str, n: &mut u64) -> String {
+	let mut first = true;
+	v.chars()
+		.map(|c| match c.to_digit(10) {
+			Some(_) => {
+				let d = match first {
+					true => 1 + *n % 9,
+					false => *n % 10,
+				};
+				*n = n.rotate_right(7) ^ 0x9e37_79b9_7f4a_7c15;
+				first = false;
+				char::from_digit(d as u32, 10).unwrap()
+			}
+			None => c,
+		})
+		.collect()
+}
+
+/// Get the hash of a value
+fn digest(v: &Value) -> [u8; 32] {
+	Sha256::digest(v.to_string().as_bytes()).into()
+}
+
+/// Get a number from the start of a hash
+fn seed(h: &[u8; 32]) -> u64 {
+	u64::from_be_bytes(h[..8].try_into().unwrap())
+}
+
+/// Get the bytes of a uuid from the start of a hash
+fn bytes(h: &[u8; 32]) -> [u8; 16] {
+	h[..16].try_into().unwrap()
+}
+
+#[cfg(test)]
+mod tests {
+	use super::*;
+	use crate::sql::test::Parse;
+	use crate::sql::Idiom;
+
+	#[test]
+	fn anonymize_mask() {
+		let val = Value::parse("{ name: 'Tobie Morgan', phone: '+44 7700 900123', age: 33 }");
+		let out = anonymize(&val, Sensitive::Mask);
+		let res = Value::parse("{ name: '***** ******', phone: '+** **** ******', age: 0 }");
+		assert_eq!(out, res);
+	}
+
+	#[test]
+	fn anonymize_hash() {
+		let val = Value::from("tobie@surrealdb.com");
+		let out = anonymize(&val, Sensitive::Hash);
+		assert_ne!(out, val);
+		assert_eq!(out, anonymize(&val, Sensitive::Hash));
+		assert_eq!(out.as_string().len(), 64);
+	}
+
+	#[test]
+	fn anonymize_fake() {
+		let val = Value::parse("{ email: 'tobie@surrealdb.com', phone: '+44 7700 900123', name: 'Tobie Morgan', age: 33 }");
+		let out = anonymize(&val, Sensitive::Fake);
+		assert_eq!(out, anonymize(&val, Sensitive::Fake));
+		let email = out.pick(&[Part::from("email")]).as_string();
+		assert!(email.ends_with("@example.com"));
+		let phone = out.pick(&[Part::from("phone")]).as_string();
+		assert_eq!(phone.len(), 15);
+		assert!(phone.starts_with("+"));
+		assert_ne!(phone, "+44 7700 900123");
+		let name = out.pick(&[Part::from("name")]).as_string();
+		assert_eq!(name.split(' ').count(), 2);
+		assert!(name.chars().next().unwrap().is_uppercase());
+		let age = out.pick(&[Part::from("age")]);
+		assert!(matches!(age, Value::Number(Number::Int(10..=99))));
+	}
+
+	#[test]
+	fn anonymize_record() {
+		let fd = |name: &str, sensitive: Option<Sensitive>| DefineFieldStatement {
+			name: Idiom::parse(name),
+			sensitive,
+			..Default::default()
+		};
+		let fds = [
+			fd("tags[*]", Some(Sensitive::Mask)),
+			fd("name", None),
+			fd("address.city", Some(Sensitive::Mask)),
+		];
+		let mut val = Value::parse("{ id: user:tobie, name: 'Tobie', tags: ['admin', 'ops'], address: { street: 'Main' } }");
+		record(&mut val, &fds);
+		let res = Value::parse("{ id: user:tobie, name: 'Tobie', tags: ['*****', '***'], address: { street: 'Main' } }");
+		assert_eq!(val, res);
+	}
+}
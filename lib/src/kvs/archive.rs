@@ -0,0 +1,155 @@
+//! The cold storage tier for archived tables.
+//!
+//! The records in any table which is defined with `ARCHIVE` are moved out of
+//! the key-value store and into an object store by [`Datastore::archive`].
+//! Each archived value is replaced in the key-value store with a small marker
+//! containing the name of its object, so that reads within a transaction fall
+//! through to the archive tier transparently. Updating an archived record
+//! writes it to the key-value store again, returning it to the hot tier.
+//!
+//! [`Datastore::archive`]: super::Datastore::archive
+use super::Val;
+use crate::err::Error;
+use async_trait::async_trait;
+use std::sync::Arc;
+
+/// The prefix of a value which has been moved to the archive tier
+const MARKER: &[u8] = b"\x00SURREALDB-ARCHIVE\x00";
+
+/// An object store which holds archived values
+#[async_trait]
+pub(super) trait Archive: Send + Sync {
+	/// Fetch an archived value
+	async fn get(&self, name: &str) -> Result<Option<Val>, Error>;
+	/// Store an archived value
+	async fn put(&self, name: &str, val: Val) -> Result<(), Error>;
+}
+
+/// Open the archive tier at a storage path
+pub(super) async fn open(path: &str) -> Result<Arc<dyn Archive>, Error> {
+	match path {
+		"memory" => Ok(Arc::new(Memory::default())),
+		#[cfg(not(target_arch = "wasm32"))]
+		s if s.starts_with("file:") => {
+			let s = s.trim_start_matches("file://");
+			let s = s.trim_start_matches("file:");
+			tokio::fs::create_dir_all(s).await.map_err(|e| Error::Archive(e.to_string()))?;
+			Ok(Arc::new(Folder(s.into())))
+		}
+		#[cfg(any(feature = "http", feature = "protocol-http"))]
+		s if super::s3::is_s3(s) => Ok(Arc::new(S3::new(s)?)),
+		_ => Err(Error::Archive(format!("Unable to load the archive tier at {path}"))),
+	}
+}
+
+/// Get the object name under which a key is archived
+pub(super) fn name(key: &[u8]) -> String {
+	key.iter().map(|v| format!("{v:02x}")).collect()
+}
+
+/// Get the marker which replaces an archived value
+pub(super) fn marker(name: &str) -> Val {
+	[MARKER, name.as_bytes()].concat()
+}
+
+/// Get the object name from a marker, if this value has been archived
+pub(super) fn archived(val: &[u8]) -> Option<&str> {
+	val.strip_prefix(MARKER).and_then(|v| std::str::from_utf8(v).ok())
+}
+
+/// Fetch the value which a marker refers to
+pub(super) async fn fetch(archive: Option<&Arc<dyn Archive>>, name: &str) -> Result<Val, Error> {
+	let archive = archive.ok_or_else(|| {
+		Error::Archive("A record has been archived, but no archive tier is configured".into())
+	})?;
+	match archive.get(name).await? {
+		Some(v) => Ok(v),
+		None => Err(Error::Archive(format!("The archived object {name} does not exist"))),
+	}
+}
+
+/// An in-memory archive tier, which is useful for testing
+#[derive(Default)]
+struct Memory(std::sync::Mutex<std::collections::HashMap<String, Val>>);
+
+#[async_trait]
+impl Archive for Memory {
+	async fn get(&self, name: &str) -> Result<Option<Val>, Error> {
+		Ok(self.0.lock().unwrap().get(name).cloned())
+	}
+	async fn put(&self, name: &str, val: Val) -> Result<(), Error> {
+		self.0.lock().unwrap().insert(name.to_owned(), val);
+		Ok(())
+	}
+}
+
+/// An archive tier which stores each object as a file in a folder
+#[cfg(not(target_arch = "wasm32"))]
+struct Folder(std::path::PathBuf);
+
+#[cfg(not(target_arch = "wasm32"))]
+#[async_trait]
+impl Archive for Folder {
+	async fn get(&self, name: &str) -> Result<Option<Val>, Error> {
+		match tokio::fs::read(self.0.join(name)).await {
+			Ok(v) => Ok(Some(v)),
+			Err(e) if e.kind() == std::io::ErrorKind::NotFound => Ok(None),
+			Err(e) => Err(Error::Archive(e.to_string())),
+		}
+	}
+	async fn put(&self, name: &str, val: Val) -> Result<(), Error> {
+		// Write to a temporary file, so that objects are never partially written
+		let tmp = self.0.join(format!("{name}.tmp"));
+		tokio::fs::write(&tmp, val).await.map_err(|e| Error::Archive(e.to_string()))?;
+		tokio::fs::rename(&tmp, self.0.join(name)).await.map_err(|e| Error::Archive(e.to_string()))
+	}
+}
+
+/// An archive tier which stores each object in an S3-compatible bucket.
+///
+/// The storage path is specified as `s3://bucket/prefix`, and the client is
+/// configured from the environment, as described in [`super::s3`].
+#[cfg(any(feature = "http", feature = "protocol-http"))]
+struct S3 {
+	client: super::s3::Client,
+	path: String,
+}
+
+#[cfg(any(feature = "http", feature = "protocol-http"))]
+impl S3 {
+	fn new(path: &str) -> Result<Self, Error> {
+		let (bucket, prefix) = super::s3::parse(path)
+			.ok_or_else(|| Error::Archive("Specify an S3 path as s3://bucket/prefix".into()))?;
+		let path = match prefix.trim_matches('/') {
+			"" => format!("/{bucket}"),
+			v => format!("/{bucket}/{v}"),
+		};
+		Ok(S3 {
+			client: super::s3::Client::from_env()?,
+			path,
+		})
+	}
+}
+
+#[cfg(any(feature = "http", feature = "protocol-http"))]
+#[async_trait]
+impl Archive for S3 {
+	async fn get(&self, name: &str) -> Result<Option<Val>, Error> {
+		let path = format!("{}/{name}", self.path);
+		let res = self.client.request(reqwest::Method::GET, &path, &[]).send().await?;
+		match res.status() {
+			reqwest::StatusCode::NOT_FOUND => Ok(None),
+			s if s.is_success() => Ok(Some(res.bytes().await?.to_vec())),
+			s => Err(Error::Archive(format!("The object store responded with {s}"))),
+		}
+	}
+	async fn put(&self, name: &str, val: Val) -> Result<(), Error> {
+		let path = format!("{}/{name}", self.path);
+		let req = self.client.request(reqwest::Method::PUT, &path, &val);
+		let res = req.body(val).send().await?;
+		match res.status() {
+			s if s.is_success() => Ok(()),
+			s => Err(Error::Archive(format!("The object store responded with {s}"))),
+		}
+	}
+}
@@ -0,0 +1,149 @@
+//! The binary format used for datastore snapshots.
+//!
+//! A snapshot begins with a header line, containing the format version and
+//! the time at which the snapshot was taken, and is followed by a sequence
+//! of key-value frames. Each frame contains a big-endian `u32` key length,
+//! the key, a big-endian `u32` value length, and the value. Keys are stored
+//! relative to the namespace or database which was snapshotted, so that they
+//! can be restored into the same location.
+use super::Key;
+use crate::err::Error;
+use crate::sql::datetime::Datetime;
+
+/// The header which precedes every snapshot
+const MAGIC: &[u8] = b"SURREALDB-BACKUP 1 ";
+
+/// The number of keys which are deleted or written in each transaction
+/// of a restore
+pub(super) const BATCH_SIZE: u32 = 1000;
+
+/// The number of bytes which are written in each transaction of a restore
+pub(super) const BATCH_BYTES: usize = 1 << 20;
+
+/// Get the key prefix for a namespace, database, or the entire datastore
+pub(super) fn prefix(ns: Option<&str>, db: Option<&str>) -> Result<Key, Error> {
+	match (ns, db) {
+		(Some(ns), Some(db)) => Ok(crate::key::database::new(ns, db).into()),
+		(Some(ns), None) => Ok(crate::key::namespace::new(ns).into()),
+		(None, None) => Ok(crate::key::kv::new().into()),
+		(None, Some(_)) => Err(Error::NsEmpty),
+	}
+}
+
+/// Encode the snapshot header
+pub(super) fn header(at: &Datetime) -> Vec<u8> {
+	let mut out = MAGIC.to_vec();
+	out.extend(at.to_raw().as_bytes());
+	out.push(b'\n');
+	out
+}
+
+/// Encode a key-value frame
+pub(super) fn frame(out: &mut Vec<u8>, k: &[u8], v: &[u8]) {
+	out.extend((k.len() as u32).to_be_bytes());
+	out.extend(k);
+	out.extend((v.len() as u32).to_be_bytes());
+	out.extend(v);
+}
+
+/// Decode a snapshot into its time and key-value frames
+pub(super) fn decode(data: &[u8]) -> Result<(Datetime, Vec<(&[u8], &[u8])>), Error> {
+	// Check the snapshot header
+	let data = data.strip_prefix(MAGIC).ok_or(Error::InvalidBackup)?;
+	let end = data.iter().position(|&v| v == b'\n').ok_or(Error::InvalidBackup)?;
+	let at = std::str::from_utf8(&data[..end]).map_err(|_| Error::InvalidBackup)?;
+	let at = Datetime::try_from(at).map_err(|_| Error::InvalidBackup)?;
+	// Decode each key-value frame
+	let mut out = vec![];
+	let mut data = &data[end + 1..];
+	while !data.is_empty() {
+		let (k, rest) = chunk(data)?;
+		let (v, rest) = chunk(rest)?;
+		out.push((k, v));
+		data = rest;
+	}
+	Ok((at, out))
+}
+
+/// Decode a length-prefixed chunk of bytes
+fn chunk(data: &[u8]) -> Result<(&[u8], &[u8]), Error> {
+	if data.len() < 4 {
+		return Err(Error::InvalidBackup);
+	}
+	let (len, data) = data.split_at(4);
+	let len = u32::from_be_bytes([len[0], len[1], len[2], len[3]]) as usize;
+	if data.len() < len {
+		return Err(Error::InvalidBackup);
+	}
+	Ok(data.split_at(len))
+}
+
+/// Check if a key belongs to a live query definition.
+/// Live queries are tied to a connection, so they are
+/// never included in a snapshot.
+pub(super) fn is_live(k: &[u8]) -> bool {
+	// Skip the namespace and database prefix
+	let k = match k.strip_prefix(b"/*") {
+		Some(k) => k,
+		None => return false,
+	};
+	let k = match k.iter().position(|&v| v == 0x00) {
+		Some(pos) => &k[pos + 1..],
+		None => return false,
+	};
+	let k = match k.strip_prefix(b"*") {
+		Some(k) => k,
+		None => return false,
+	};
+	let k = match k.iter().position(|&v| v == 0x00) {
+		Some(pos) => &k[pos + 1..],
+		None => return false,
+	};
+	// A live query on the database
+	if k.starts_with(b"!lq") || k.starts_with(b"!lv") {
+		return true;
+	}
+	// A live query on a table
+	if let Some(k) = k.strip_prefix(b"*") {
+		if let Some(pos) = k.iter().position(|&v| v == 0x00) {
+			return k[pos + 1..].starts_with(b"!lv");
+		}
+	}
+	false
+}
+
+#[cfg(test)]
+mod tests {
+
+	use super::*;
+
+	#[test]
+	fn encode_decode() {
+		let at = Datetime::try_from("2023-05-01T10:00:00Z").unwrap();
+		let mut out = header(&at);
+		frame(&mut out, b"one", b"1");
+		frame(&mut out, b"two", b"");
+		let (tim, res) = decode(&out).unwrap();
+		assert_eq!(tim, at);
+		assert_eq!(res, vec![(&b"one"[..], &b"1"[..]), (&b"two"[..], &b""[..])]);
+	}
+
+	#[test]
+	fn decode_truncated() {
+		let at = Datetime::try_from("2023-05-01T10:00:00Z").unwrap();
+		let mut out = header(&at);
+		frame(&mut out, b"one", b"1");
+		out.pop();
+		assert!(decode(&out).is_err());
+		assert!(decode(b"SELECT * FROM person").is_err());
+	}
+
+	#[test]
+	fn live_keys() {
+		assert!(is_live(b"/*test\x00*test\x00!lvabc"));
+		assert!(is_live(b"/*test\x00*test\x00*person\x00!lvabc"));
+		assert!(!is_live(b"/*test\x00*test\x00!tbperson\x00"));
+		assert!(!is_live(b"/*test\x00*test\x00*person\x00*\x00tobie"));
+		assert!(!is_live(b"/!nstest\x00"));
+	}
+}
@@ -6,9 +6,11 @@ use crate::sql::statements::DefineFieldStatement;
 use crate::sql::statements::DefineFunctionStatement;
 use crate::sql::statements::DefineIndexStatement;
 use crate::sql::statements::DefineLoginStatement;
+use crate::sql::statements::DefineMigrationStatement;
 use crate::sql::statements::DefineNamespaceStatement;
 use crate::sql::statements::DefineParamStatement;
 use crate::sql::statements::DefineScopeStatement;
+use crate::sql::statements::DefineSequenceStatement;
 use crate::sql::statements::DefineTableStatement;
 use crate::sql::statements::DefineTokenStatement;
 use crate::sql::statements::LiveStatement;
@@ -20,6 +22,7 @@ pub enum Entry {
 	// Single definitions
 	Db(Arc<DefineDatabaseStatement>),
 	Ns(Arc<DefineNamespaceStatement>),
+	Sq(Arc<DefineSequenceStatement>),
 	Tb(Arc<DefineTableStatement>),
 	// Multi definitions
 	Azs(Arc<[DefineAnalyzerStatement]>),
@@ -32,11 +35,13 @@ pub enum Entry {
 	Fts(Arc<[DefineTableStatement]>),
 	Ixs(Arc<[DefineIndexStatement]>),
 	Lvs(Arc<[LiveStatement]>),
+	Mgs(Arc<[DefineMigrationStatement]>),
 	Nls(Arc<[DefineLoginStatement]>),
 	Nss(Arc<[DefineNamespaceStatement]>),
 	Nts(Arc<[DefineTokenStatement]>),
 	Pas(Arc<[DefineParamStatement]>),
 	Scs(Arc<[DefineScopeStatement]>),
+	Sqs(Arc<[DefineSequenceStatement]>),
 	Sts(Arc<[DefineTokenStatement]>),
 	Tbs(Arc<[DefineTableStatement]>),
 }
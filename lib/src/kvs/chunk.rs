@@ -0,0 +1,35 @@
+//! The chunked storage of large records.
+//!
+//! Many storage engines limit the size of a single value, so the data of a
+//! record which is larger than the chunk size of the datastore is split into
+//! chunks, which are stored under separate keys within the table. The value
+//! of the record itself is replaced with a small marker containing the number
+//! of chunks, so that reads within a transaction reassemble the record
+//! transparently. Storing the record again, deleting it, or archiving it
+//! removes any chunks which are no longer needed.
+use super::Val;
+
+/// The prefix of a value which has been split into chunks
+const MARKER: &[u8] = b"\x00SURREALDB-CHUNKED\x00";
+
+/// Get the marker which replaces a value split into a number of chunks
+pub(super) fn marker(count: u32) -> Val {
+	[MARKER, &count.to_be_bytes()].concat()
+}
+
+/// Get the number of chunks from a marker, if this value has been chunked
+pub(super) fn chunked(val: &[u8]) -> Option<u32> {
+	val.strip_prefix(MARKER).and_then(|v| v.try_into().ok()).map(u32::from_be_bytes)
+}
+
+#[cfg(test)]
+mod tests {
+	use super::*;
+
+	#[test]
+	fn chunked_marker() {
+		assert_eq!(chunked(&marker(3)), Some(3));
+		assert_eq!(chunked(b"\x00SURREALDB-CHUNKED\x00"), None);
+		assert_eq!(chunked(b"value"), None);
+	}
+}
@@ -0,0 +1,93 @@
+//! The compression of stored records.
+//!
+//! The data of the records in a table which is defined with `COMPRESSION` is
+//! compressed before it is stored. Each compressed value begins with a marker
+//! which is followed by the version of the stored format and the algorithm
+//! which compressed it, so that values which were stored before compression
+//! was enabled, or with a different algorithm, remain readable, and reads
+//! within a transaction decompress values transparently. A value is stored
+//! uncompressed if compressing it would not make it any smaller.
+use super::Val;
+use crate::err::Error;
+use crate::sql::statements::define::Compression;
+
+/// The prefix of a value which has been compressed
+const MARKER: &[u8] = b"\x00SURREALDB-COMPRESSED\x00";
+
+/// The version of the format of compressed values
+const VERSION: u8 = 1;
+
+/// The compression level which is used for zstd
+#[cfg(not(target_arch = "wasm32"))]
+const ZSTD_LEVEL: i32 = 3;
+
+/// Get the tag which identifies the algorithm of a compressed value
+fn tag(with: &Compression) -> u8 {
+	match with {
+		Compression::Snappy => 1,
+		Compression::Zstd => 2,
+	}
+}
+
+/// Compress a value, unless compressing it would not make it smaller
+pub(super) fn compress(with: &Compression, val: Val) -> Result<Val, Error> {
+	let data = match with {
+		Compression::Snappy => snap::raw::Encoder::new()
+			.compress_vec(&val)
+			.map_err(|e| Error::Compression(e.to_string()))?,
+		#[cfg(not(target_arch = "wasm32"))]
+		Compression::Zstd => zstd::stream::encode_all(val.as_slice(), ZSTD_LEVEL)
+			.map_err(|e| Error::Compression(e.to_string()))?,
+		#[cfg(target_arch = "wasm32")]
+		Compression::Zstd => {
+			return Err(Error::Compression("zstd is not supported on this platform".into()))
+		}
+	};
+	match MARKER.len() + 2 + data.len() < val.len() {
+		true => Ok([MARKER, &[VERSION, tag(with)], &data].concat()),
+		false => Ok(val),
+	}
+}
+
+/// Check if a value has been compressed
+pub(super) fn compressed(val: &[u8]) -> bool {
+	val.starts_with(MARKER)
+}
+
+/// Decompress a value which has been compressed
+pub(super) fn decompress(val: &[u8]) -> Result<Val, Error> {
+	match val.strip_prefix(MARKER) {
+		Some([VERSION, 1, data @ ..]) => snap::raw::Decoder::new()
+			.decompress_vec(data)
+			.map_err(|e| Error::Compression(e.to_string())),
+		#[cfg(not(target_arch = "wasm32"))]
+		Some([VERSION, 2, data @ ..]) => {
+			zstd::stream::decode_all(data).map_err(|e| Error::Compression(e.to_string()))
+		}
+		Some([v, t, ..]) => Err(Error::Compression(format!(
+			"Unable to decompress a value with version {v} and algorithm {t}"
+		))),
+		_ => Err(Error::Compression("The compressed value is truncated".into())),
+	}
+}
+
+#[cfg(test)]
+mod tests {
+	use super::*;
+
+	#[test]
+	fn compress_and_decompress() {
+		let val = "surrealdb ".repeat(100).into_bytes();
+		for with in [Compression::Snappy, Compression::Zstd] {
+			let tmp = compress(&with, val.clone()).unwrap();
+			assert!(compressed(&tmp));
+			assert!(tmp.len() < val.len());
+			assert_eq!(decompress(&tmp).unwrap(), val);
+		}
+		// Values which do not get smaller are stored uncompressed
+		let val = b"surrealdb".to_vec();
+		assert_eq!(compress(&Compression::Snappy, val.clone()).unwrap(), val);
+		// Values with an unknown format are rejected
+		assert!(decompress(b"\x00SURREALDB-COMPRESSED\x00\x02\x01").is_err());
+	}
+}
@@ -0,0 +1,143 @@
+//! Conflict detection for storage engines which do not detect conflicts.
+//!
+//! The built-in storage engines either detect conflicting transactions
+//! themselves, or only run one writeable transaction at a time. A custom
+//! [`Engine`](super::Engine) may do neither, in which case two concurrent
+//! transactions which read and then write the same key would both commit,
+//! with one of the writes being lost. For these engines the datastore keeps
+//! the keys which are written by each transaction, and the keys which were
+//! written by the transactions which committed while it was running. A
+//! transaction which writes to any key which was written by a transaction
+//! which committed after it started can not be committed, and fails with
+//! [`Error::TxConflict`](crate::err::Error::TxConflict), so that the first
+//! transaction to commit wins, and the other can be retried.
+use super::Key;
+use futures::lock::{Mutex, MutexGuard};
+use std::collections::{BTreeMap, HashSet, VecDeque};
+use std::sync::{Arc, Mutex as SyncMutex};
+
+#[derive(Default)]
+struct State {
+	// The version of the last committed transaction
+	version: u64,
+	// The number of running transactions which started at each version
+	running: BTreeMap<u64, usize>,
+	// The keys written by each committed transaction, by its version
+	committed: VecDeque<(u64, HashSet<Key>)>,
+}
+
+/// The keys written by the recently committed transactions of a datastore
+#[derive(Default)]
+pub(super) struct Conflicts {
+	// Ensures that transactions are checked and committed one at a time
+	lock: Mutex<()>,
+	state: SyncMutex<State>,
+}
+
+impl Conflicts {
+	/// Start tracking the keys which a transaction writes
+	pub(super) fn begin(self: &Arc<Self>) -> Tracked {
+		let mut state = self.state.lock().unwrap();
+		let start = state.version;
+		*state.running.entry(start).or_default() += 1;
+		Tracked {
+			conflicts: self.clone(),
+			start,
+			keys: HashSet::new(),
+		}
+	}
+	/// Wait until no other transaction is being committed
+	pub(super) async fn lock(&self) -> MutexGuard<'_, ()> {
+		self.lock.lock().await
+	}
+	/// Check if any key which a transaction wrote was written by another
+	/// transaction which committed after the transaction started
+	pub(super) fn conflicts(&self, tx: &Tracked) -> bool {
+		let state = self.state.lock().unwrap();
+		state
+			.committed
+			.iter()
+			.filter(|(v, _)| *v > tx.start)
+			.any(|(_, keys)| keys.iter().any(|k| tx.keys.contains(k)))
+	}
+	/// Keep the keys which a transaction wrote once it has committed
+	pub(super) fn committed(&self, tx: &mut Tracked) {
+		if !tx.keys.is_empty() {
+			let mut state = self.state.lock().unwrap();
+			state.version += 1;
+			let version = state.version;
+			state.committed.push_back((version, std::mem::take(&mut tx.keys)));
+		}
+	}
+	/// Stop tracking a transaction which has finished, and forget the keys
+	/// of any committed transactions which no running transaction overlaps
+	fn finished(&self, start: u64) {
+		let mut state = self.state.lock().unwrap();
+		if let Some(n) = state.running.get_mut(&start) {
+			*n -= 1;
+			if *n == 0 {
+				state.running.remove(&start);
+			}
+		}
+		let oldest = state.running.keys().next().copied().unwrap_or(state.version);
+		while state.committed.front().map_or(false, |(v, _)| *v <= oldest) {
+			state.committed.pop_front();
+		}
+	}
+}
+
+/// The keys which are written by a running transaction
+pub(super) struct Tracked {
+	conflicts: Arc<Conflicts>,
+	// The version of the last transaction committed before this one started
+	start: u64,
+	// The keys which this transaction has written
+	keys: HashSet<Key>,
+}
+
+impl Tracked {
+	/// Record a key which the transaction wrote
+	pub(super) fn write(&mut self, key: &Key) {
+		if !self.keys.contains(key) {
+			self.keys.insert(key.clone());
+		}
+	}
+	/// Get the conflicts which the transaction is tracked by
+	pub(super) fn conflicts(&self) -> Arc<Conflicts> {
+		self.conflicts.clone()
+	}
+}
+
+impl Drop for Tracked {
+	fn drop(&mut self) {
+		self.conflicts.finished(self.start);
+	}
+}
+
+#[cfg(test)]
+mod tests {
+	use super::*;
+
+	#[test]
+	fn first_commit_wins() {
+		let conflicts = Arc::new(Conflicts::default());
+		let mut a = conflicts.begin();
+		let mut b = conflicts.begin();
+		a.write(&b"counter".to_vec());
+		b.write(&b"counter".to_vec());
+		b.write(&b"other".to_vec());
+		// The first transaction to commit wins
+		assert!(!conflicts.conflicts(&a));
+		conflicts.committed(&mut a);
+		drop(a);
+		assert!(conflicts.conflicts(&b));
+		// A transaction which started later does not conflict
+		let mut c = conflicts.begin();
+		c.write(&b"counter".to_vec());
+		assert!(!conflicts.conflicts(&c));
+		// The keys are forgotten once no transaction overlaps them
+		drop(b);
+		drop(c);
+		assert!(conflicts.state.lock().unwrap().committed.is_empty());
+	}
+}
@@ -1,28 +1,103 @@
+use super::archive::Archive;
+use super::conflict::Conflicts;
+use super::stream::{Batch, Stream, Write};
 use super::tx::Transaction;
+use super::Key;
+use crate::changes::{Action, Change, Feed, Receiver};
+use crate::cnf::{DOCUMENT_CHUNK_SIZE, MAX_DOCUMENT_SIZE};
 use crate::ctx::Context;
+use crate::ctx::Reason;
+use crate::dbs::parse_statement;
 use crate::dbs::Attach;
+use crate::dbs::BatchPolicy;
+use crate::dbs::Config;
 use crate::dbs::Executor;
+use crate::dbs::Handle;
+use crate::dbs::HttpLimits;
+use crate::dbs::Journal;
+use crate::dbs::Metrics;
 use crate::dbs::Options;
+use crate::dbs::QuotaLimits;
+use crate::dbs::Quotas;
+use crate::dbs::Registry;
 use crate::dbs::Response;
+use crate::dbs::ResultCache;
+use crate::dbs::SandboxLimits;
 use crate::dbs::Session;
+use crate::dbs::Settings;
+use crate::dbs::Slo;
+use crate::dbs::SloTarget;
+use crate::dbs::Statistics;
+use crate::dbs::Transactions;
+use crate::dbs::Usage;
 use crate::dbs::Variables;
+use crate::dbs::Webhooks;
+use crate::dbs::Workable;
+use crate::doc::Document;
 use crate::err::Error;
+use crate::iam::allowlist;
+use crate::iam::allowlist::Allowlist;
+use crate::iam::external::Authenticator;
+use crate::iam::oidc::Issuer;
 use crate::kvs::LOG;
 use crate::sql;
+use crate::sql::statements::SelectStatement;
+use crate::sql::Cond;
+use crate::sql::Datetime;
+use crate::sql::Fields;
 use crate::sql::Query;
+use crate::sql::Statement;
+use crate::sql::Statements;
+use crate::sql::Table;
 use crate::sql::Value;
+use crate::sql::Values;
 use channel::Sender;
 use futures::lock::Mutex;
+use std::collections::BTreeMap;
+use std::collections::HashMap;
 use std::fmt;
+use std::sync::atomic::{AtomicBool, Ordering};
 use std::sync::Arc;
 use std::time::Duration;
 use tracing::instrument;
+use trice::Instant;
+use uuid::Uuid;
 
 /// The underlying datastore instance which stores the dataset.
 #[allow(dead_code)]
 pub struct Datastore {
 	pub(super) inner: Inner,
 	query_timeout: Option<Duration>,
+	// The number of times a statement which conflicted is retried
+	retries: u32,
+	// The written keys of any engine which does not detect conflicts
+	conflicts: Option<Arc<Conflicts>>,
+	// The maximum size in bytes of the data of a record
+	max_document_size: usize,
+	// The size in bytes above which the data of a record is split into chunks
+	chunk_size: usize,
+	// The number of shards of the record and byte counters of each table
+	counter_shards: u8,
+	// The configuration which is added to the context of every query
+	config: Arc<Config>,
+	feed: Arc<Feed>,
+	stream: Arc<Stream>,
+	read_only: AtomicBool,
+	closing: AtomicBool,
+	dry_run: bool,
+	replica: Option<Box<Datastore>>,
+	archive: Option<Arc<dyn Archive>>,
+	journal: Option<Arc<Journal>>,
+	slo: Arc<Slo>,
+	metrics: Arc<Metrics>,
+	registry: Arc<Registry>,
+	usage: Arc<Usage>,
+	statistics: Arc<Statistics>,
+	transactions: Arc<Transactions>,
+	results: Arc<ResultCache>,
+	hooks: Arc<Webhooks>,
+	quotas: Arc<Quotas>,
+	settings: Arc<Settings>,
 }
 
 #[allow(clippy::large_enum_variant)]
@@ -39,6 +114,7 @@ pub(super) enum Inner {
 	TiKV(super::tikv::Datastore),
 	#[cfg(feature = "kv-fdb")]
 	FDB(super::fdb::Datastore),
+	Engine(Box<dyn super::engine::Engine>),
 }
 
 impl fmt::Display for Datastore {
@@ -57,6 +133,7 @@ impl fmt::Display for Datastore {
 			Inner::TiKV(_) => write!(f, "tikv"),
 			#[cfg(feature = "kv-fdb")]
 			Inner::FDB(_) => write!(f, "fdb"),
+			Inner::Engine(_) => write!(f, "engine"),
 			#[allow(unreachable_patterns)]
 			_ => unreachable!(),
 		}
@@ -198,18 +275,582 @@ impl Datastore {
 				#[cfg(not(feature = "kv-fdb"))]
 				return Err(Error::Ds("Cannot connect to the `foundationdb` storage engine as it is not enabled in this build of SurrealDB".to_owned()));
 			}
-			// The datastore path is not valid
-			_ => {
-				info!(target: LOG, "Unable to load the specified datastore {}", path);
-				Err(Error::Ds("Unable to load the specified datastore".into()))
-			}
+			// Parse and initiate a registered storage engine
+			_ => match super::engine::open(path).await {
+				Some(v) => {
+					info!(target: LOG, "Started kvs store at {}", path);
+					v.map(Inner::Engine)
+				}
+				// The datastore path is not valid
+				None => {
+					info!(target: LOG, "Unable to load the specified datastore {}", path);
+					Err(Error::Ds("Unable to load the specified datastore".into()))
+				}
+			},
 		};
-		inner.map(|inner| Self {
+		// Check for conflicts with engines which do not detect them
+		let conflicts = match &inner {
+			Ok(Inner::Engine(v)) if !v.detects_conflicts() => Some(Arc::default()),
+			_ => None,
+		};
+		let config = Arc::new(Config::default());
+		let ds = inner.map(|inner| Self {
 			inner,
 			query_timeout: None,
+			retries: 0,
+			conflicts,
+			max_document_size: MAX_DOCUMENT_SIZE,
+			chunk_size: DOCUMENT_CHUNK_SIZE,
+			counter_shards: 16,
+			hooks: Arc::new(Webhooks::new(config.clone())),
+			config,
+			feed: Arc::new(Feed::default()),
+			stream: Arc::new(Stream::default()),
+			read_only: AtomicBool::new(false),
+			closing: AtomicBool::new(false),
+			dry_run: false,
+			replica: None,
+			archive: None,
+			journal: None,
+			slo: Arc::new(Slo::default()),
+			metrics: Arc::new(Metrics::default()),
+			registry: Arc::new(Registry::default()),
+			usage: Arc::new(Usage::default()),
+			statistics: Arc::new(Statistics::default()),
+			transactions: Arc::new(Transactions::default()),
+			results: Arc::new(ResultCache::default()),
+			quotas: Arc::new(Quotas::default()),
+			settings: Arc::new(Settings::default()),
+		})?;
+		// Publish the writes which other processes commit to the engine
+		#[cfg(not(target_arch = "wasm32"))]
+		if let Inner::Engine(v) = &ds.inner {
+			if let Some(rcv) = v.watch() {
+				let feed = ds.feed.clone();
+				let stream = ds.stream.clone();
+				crate::exe::spawn(async move {
+					while let Ok(writes) = rcv.recv().await {
+						let _lock = feed.lock().await;
+						stream.publish(writes, None).await;
+					}
+				})
+				.detach();
+			}
+		}
+		// Rebuild any indexes whose entries have an older format
+		ds.check_index_format().await?;
+		// Warn about any restore which did not complete
+		if ds.restoring().await? {
+			warn!(target: LOG, "A restore did not complete, and the snapshot should be restored again");
+		}
+		Ok(ds)
+	}
+
+	/// Rebuild the indexes whose entries were written with an older format,
+	/// and record the format of the index entries of this datastore
+	async fn check_index_format(&self) -> Result<(), Error> {
+		let mut txn = self.transaction(true, false).await?;
+		let key = crate::key::iv::new();
+		let version = txn.get(key.clone()).await?.and_then(|v| v.first().copied());
+		if version == Some(crate::key::index::VERSION) {
+			return txn.cancel().await;
+		}
+		// Find the indexes which are stored as index entries
+		let mut indexes = vec![];
+		for ns in txn.all_ns().await?.iter() {
+			let ns = ns.name.to_raw();
+			for db in txn.all_db(&ns).await?.iter() {
+				let db = db.name.to_raw();
+				for tb in txn.all_tb(&ns, &db).await?.iter() {
+					for ix in txn.all_ix(&ns, &db, &tb.name).await?.iter() {
+						if matches!(ix.index, sql::index::Index::Idx | sql::index::Index::Uniq) {
+							indexes.push((ns.clone(), db.clone(), ix.to_string()));
+						}
+					}
+				}
+			}
+		}
+		txn.cancel().await?;
+		// Defining each index again rebuilds its entries
+		for (ns, db, sql) in indexes {
+			info!(target: LOG, "Rebuilding an index with an older format: {}", sql);
+			let ses = Session::for_kv().with_ns(&ns).with_db(&db);
+			for res in self.execute(&sql, &ses, None, false).await? {
+				res.result?;
+			}
+		}
+		// Record the format of the index entries
+		let mut txn = self.transaction(true, false).await?;
+		txn.set(key, vec![crate::key::index::VERSION]).await?;
+		txn.commit().await
+	}
+
+	/// Serve any query which does not modify data from a read-only replica
+	///
+	/// ```rust,no_run
+	/// use surrealdb::kvs::Datastore;
+	/// use surrealdb::err::Error;
+	///
+	/// #[tokio::main]
+	/// async fn main() -> Result<(), Error> {
+	///     let ds = Datastore::new("tikv://leader:2379")
+	///         .await?
+	///         .with_replica("tikv://follower:2379")
+	///         .await?;
+	///     Ok(())
+	/// }
+	/// ```
+	pub async fn with_replica(mut self, path: &str) -> Result<Self, Error> {
+		let mut replica = Self::new(path).await?;
+		replica.set_read_only(true);
+		replica.metrics = self.metrics.clone();
+		self.replica = Some(Box::new(replica));
+		Ok(self)
+	}
+
+	/// Move the records of any table defined with `ARCHIVE` to an object
+	/// store, which reads fall through to when a record is not in this
+	/// datastore. The archive tier is specified as `memory`, as a local
+	/// folder using `file://path`, or as an S3 bucket using `s3://bucket/prefix`.
+	///
+	/// ```rust,no_run
+	/// use surrealdb::kvs::Datastore;
+	/// use surrealdb::err::Error;
+	///
+	/// #[tokio::main]
+	/// async fn main() -> Result<(), Error> {
+	///     let ds = Datastore::new("file://database.db")
+	///         .await?
+	///         .with_archive("s3://bucket/archive")
+	///         .await?;
+	///     ds.archive().await?;
+	///     Ok(())
+	/// }
+	/// ```
+	pub async fn with_archive(mut self, path: &str) -> Result<Self, Error> {
+		let archive = super::archive::open(path).await?;
+		if let Some(replica) = &mut self.replica {
+			replica.archive = Some(archive.clone());
+		}
+		self.archive = Some(archive);
+		Ok(self)
+	}
+
+	/// Append every statement which changes this datastore to a journal file,
+	/// before its transaction is committed, so that the statements which were
+	/// committed after a snapshot can be replayed onto the restored snapshot
+	///
+	/// ```rust,no_run
+	/// use surrealdb::kvs::Datastore;
+	/// use surrealdb::err::Error;
+	///
+	/// #[tokio::main]
+	/// async fn main() -> Result<(), Error> {
+	///     let ds = Datastore::new("file://database.db").await?.with_journal("journal.log")?;
+	///     Ok(())
+	/// }
+	/// ```
+	pub fn with_journal(mut self, path: impl AsRef<std::path::Path>) -> Result<Self, Error> {
+		self.journal = Some(Arc::new(Journal::open(path.as_ref())?));
+		Ok(self)
+	}
+
+	/// Get the journal of the statements which change this datastore, if any
+	pub(crate) fn journal(&self) -> Option<&Journal> {
+		self.journal.as_deref()
+	}
+
+	/// Append the outcome of a transaction which was kept open to the journal
+	fn finish(&self, handle: &Handle, committed: bool) {
+		if let (Some(jid), Some(journal)) = (handle.jid, self.journal()) {
+			journal.finish(&jid, committed);
+		}
+	}
+
+	/// Move the records of every table defined with `ARCHIVE` to the archive
+	/// tier, returning the number of records which were moved
+	#[instrument(skip(self))]
+	pub async fn archive(&self) -> Result<usize, Error> {
+		// Start a new transaction
+		let mut txn = self.transaction(true, false).await?;
+		// Process the archive
+		match txn.archive().await {
+			Ok(v) => {
+				txn.commit().await?;
+				Ok(v)
+			}
+			Err(e) => {
+				txn.cancel().await?;
+				Err(e)
+			}
+		}
+	}
+
+	/// Index the next batch of records of every index which is being built in
+	/// the background, returning the number of records which were indexed
+	#[instrument(skip(self))]
+	pub async fn build_indexes(&self) -> Result<usize, Error> {
+		crate::idx::build::build(self).await
+	}
+
+	/// Get the number of indexes which are being built in the background
+	pub async fn pending_indexes(&self) -> Result<usize, Error> {
+		crate::idx::build::pending(self).await
+	}
+
+	/// Get the read-only replica of this datastore, if one is configured
+	pub(crate) fn replica(&self) -> Option<&Datastore> {
+		self.replica.as_deref()
+	}
+
+	/// Count every statement as good or bad against a set of latency targets,
+	/// which are exposed in the Prometheus text format by [`Slo::metrics`]
+	///
+	/// ```rust,no_run
+	/// use surrealdb::kvs::Datastore;
+	/// use surrealdb::err::Error;
+	///
+	/// #[tokio::main]
+	/// async fn main() -> Result<(), Error> {
+	///     let ds = Datastore::new("memory")
+	///         .await?
+	///         .with_slo(vec!["test/test/person=50ms@99.9".parse().unwrap()]);
+	///     println!("{}", ds.slo().metrics());
+	///     Ok(())
+	/// }
+	/// ```
+	pub fn with_slo(mut self, targets: Vec<SloTarget>) -> Self {
+		let slo = Arc::new(Slo::new(targets));
+		if let Some(replica) = &mut self.replica {
+			replica.slo = slo.clone();
+		}
+		self.slo = slo;
+		self
+	}
+
+	/// Get the latency targets and counters of this datastore
+	pub fn slo(&self) -> &Slo {
+		&self.slo
+	}
+
+	/// Limit the rate of statements, writes, and result bytes, and the number of
+	/// live queries, of each authenticated identity which runs queries against
+	/// this datastore
+	///
+	/// ```rust,no_run
+	/// use surrealdb::kvs::Datastore;
+	/// use surrealdb::err::Error;
+	/// use surrealdb::dbs::QuotaLimits;
+	///
+	/// #[tokio::main]
+	/// async fn main() -> Result<(), Error> {
+	///     let ds = Datastore::new("memory").await?.with_quotas(QuotaLimits {
+	///         queries: Some(100),
+	///         live: Some(10),
+	///         ..Default::default()
+	///     });
+	///     println!("{}", ds.quotas().metrics());
+	///     Ok(())
+	/// }
+	/// ```
+	pub fn with_quotas(mut self, limits: QuotaLimits) -> Self {
+		self.quotas = Arc::new(Quotas::new(limits));
+		self
+	}
+
+	/// Retain a number of the most recent changes committed to this datastore,
+	/// so that subscribers can resume from the last change which they received
+	/// using [`Datastore::changes_since`]
+	pub fn with_history(mut self, retain: usize) -> Self {
+		self.feed = Arc::new(Feed::new(retain));
+		self
+	}
+
+	/// Cancel any transaction which is kept open between queries, once it
+	/// has not been used for a duration
+	pub fn with_transaction_timeout(mut self, timeout: Duration) -> Self {
+		self.transactions = Arc::new(Transactions::new(timeout));
+		self
+	}
+
+	/// Retry any statement, or any transaction, which fails to commit as it
+	/// conflicted with a concurrent transaction, up to a number of times
+	pub fn with_retries(mut self, retries: u32) -> Self {
+		self.retries = retries;
+		self
+	}
+
+	/// Reject any record whose data is larger than a number of bytes
+	pub fn with_max_document_size(mut self, size: usize) -> Self {
+		self.max_document_size = size;
+		self
+	}
+
+	/// Split the data of any record which is larger than a number of bytes
+	/// into chunks, which are stored under separate keys, so that records
+	/// which are larger than the value size limit of the storage engine
+	/// can be stored
+	pub fn with_chunk_size(mut self, size: usize) -> Self {
+		self.chunk_size = size.max(1);
+		self
+	}
+
+	/// Split the record and byte counters of each table into a number of
+	/// shards, so that concurrent writes to a table rarely conflict
+	pub fn with_counter_shards(mut self, shards: u8) -> Self {
+		self.counter_shards = shards.clamp(1, 128);
+		self
+	}
+
+	/// Connect a number of clients to a TiKV cluster, which are health
+	/// checked at an interval, and retry any read which fails with a
+	/// transient error up to a number of times. Any other storage engine
+	/// ignores these settings.
+	#[cfg_attr(not(feature = "kv-tikv"), allow(unused_mut, unused_variables))]
+	pub async fn with_tikv(
+		mut self,
+		size: usize,
+		interval: Duration,
+		retries: u32,
+	) -> Result<Self, Error> {
+		#[cfg(feature = "kv-tikv")]
+		{
+			if let Inner::TiKV(v) = &mut self.inner {
+				v.configure(size, interval, retries).await?;
+			}
+			if let Some(Inner::TiKV(v)) = self.replica.as_deref_mut().map(|v| &mut v.inner) {
+				v.configure(size, interval, retries).await?;
+			}
+		}
+		Ok(self)
+	}
+
+	/// Change the configuration which is added to the context of every query
+	fn configure(mut self, f: impl FnOnce(&mut Config)) -> Self {
+		f(Arc::make_mut(&mut self.config));
+		// Webhooks are delivered with the limits of the configuration
+		self.hooks = Arc::new(Webhooks::new(self.config.clone()));
+		self
+	}
+
+	/// Limit the time, the embedded script memory, the subqueries, and the
+	/// outbound calls of each event, future, and stored function
+	///
+	/// ```rust,no_run
+	/// use std::time::Duration;
+	/// use surrealdb::kvs::Datastore;
+	/// use surrealdb::err::Error;
+	/// use surrealdb::dbs::SandboxLimits;
+	///
+	/// #[tokio::main]
+	/// async fn main() -> Result<(), Error> {
+	///     let ds = Datastore::new("memory").await?.with_sandbox_limits(SandboxLimits {
+	///         time: Duration::from_secs(1),
+	///         ..Default::default()
+	///     });
+	///     Ok(())
+	/// }
+	/// ```
+	pub fn with_sandbox_limits(self, limits: SandboxLimits) -> Self {
+		self.configure(|v| v.sandbox = limits)
+	}
+
+	/// Limit the hosts, the time, and the body size of the requests which
+	/// are sent by the http functions
+	pub fn with_http_limits(self, mut limits: HttpLimits) -> Self {
+		// The hosts of requests are matched case-insensitively
+		limits.allowed_hosts = limits
+			.allowed_hosts
+			.iter()
+			.map(|v| v.trim().to_ascii_lowercase())
+			.filter(|v| !v.is_empty())
+			.collect();
+		self.configure(|v| v.http = limits)
+	}
+
+	/// Calculate record counts by scanning every record in a table, rather
+	/// than taking them from the record counter which is kept for each table
+	pub fn with_exact_count(self, exact: bool) -> Self {
+		self.configure(|v| v.exact_count = exact)
+	}
+
+	/// Cancel any statement which has no `TIMEOUT` clause once it has run
+	/// for a duration
+	pub fn with_statement_timeout(self, timeout: Option<Duration>) -> Self {
+		self.configure(|v| v.statement_timeout = timeout)
+	}
+
+	/// Limit the number of records which a single statement may examine, and
+	/// the estimated memory in bytes which the output of a statement may use
+	pub fn with_query_limits(self, rows: Option<usize>, memory: Option<usize>) -> Self {
+		self.configure(|v| {
+			v.max_query_rows = rows;
+			v.max_query_memory = memory;
+		})
+	}
+
+	/// Build any new index of a table with more records than a threshold in
+	/// the background, indexing a number of records in each batch
+	pub fn with_index_build(self, threshold: Option<u64>, batch_size: u32) -> Self {
+		self.configure(|v| {
+			v.index_build_threshold = threshold.filter(|v| *v > 0);
+			v.index_build_batch_size = batch_size.max(1);
+		})
+	}
+
+	/// Deliver the webhooks which are sent by events with a number of
+	/// workers, retrying each failed delivery up to a number of times, after
+	/// a backoff which doubles on each retry
+	pub fn with_webhooks(self, workers: usize, retries: u32, backoff: Duration) -> Self {
+		self.configure(|v| {
+			v.webhook_workers = workers.max(1);
+			v.webhook_retries = retries;
+			v.webhook_backoff = backoff;
+		})
+	}
+
+	/// Accept the tokens which are signed by external identity providers
+	pub fn with_oidc_issuers(self, issuers: Vec<Issuer>) -> Self {
+		self.configure(|v| v.oidc_issuers = issuers)
+	}
+
+	/// Consult an external authenticator when signin credentials are not
+	/// accepted by the database, waiting up to a timeout for its response
+	pub fn with_authenticator(
+		self,
+		authenticator: Option<Authenticator>,
+		timeout: Duration,
+	) -> Self {
+		self.configure(|v| {
+			v.authenticator = authenticator;
+			v.authenticator_timeout = timeout;
 		})
 	}
 
+	/// Restrict the namespaces of the allowlists to clients which connect
+	/// from the allowed networks of each namespace
+	pub fn with_ns_allowlists(self, allowlists: Vec<Allowlist>) -> Self {
+		self.configure(|v| v.ns_allowlists = allowlists)
+	}
+
+	/// Get the external identity providers whose tokens are accepted
+	pub fn oidc_issuers(&self) -> &[Issuer] {
+		&self.config.oidc_issuers
+	}
+
+	/// Get the external authenticator which is consulted during signin, if any
+	pub fn authenticator(&self) -> Option<&Authenticator> {
+		self.config.authenticator.as_ref()
+	}
+
+	/// Get the networks from which clients may use each restricted namespace
+	pub fn ns_allowlists(&self) -> &[Allowlist] {
+		&self.config.ns_allowlists
+	}
+
+	/// Get the configuration which is added to the context of every query
+	pub(crate) fn config(&self) -> &Arc<Config> {
+		&self.config
+	}
+
+	/// Get the number of times a statement which conflicted is retried
+	pub(crate) fn retries(&self) -> u32 {
+		self.retries
+	}
+
+	/// Get the transactions which are kept open between queries
+	pub fn transactions(&self) -> &Transactions {
+		&self.transactions
+	}
+
+	/// Get the rate limits and quotas of this datastore
+	pub fn quotas(&self) -> &Quotas {
+		&self.quotas
+	}
+
+	/// Get the settings of this datastore which can be changed at runtime
+	pub fn settings(&self) -> &Settings {
+		&self.settings
+	}
+
+	/// Change a setting of this datastore while it is running, as is done
+	/// by a `SET GLOBAL` statement
+	///
+	/// ```rust,no_run
+	/// use surrealdb::kvs::Datastore;
+	/// use surrealdb::err::Error;
+	/// use surrealdb::sql::Value;
+	///
+	/// #[tokio::main]
+	/// async fn main() -> Result<(), Error> {
+	///     let ds = Datastore::new("memory").await?;
+	///     ds.set_global("rate_limit_queries", &Value::from(100))?;
+	///     Ok(())
+	/// }
+	/// ```
+	pub fn set_global(&self, name: &str, value: &Value) -> Result<(), Error> {
+		self.settings.set(&self.quotas, name, value)
+	}
+
+	/// Get the operational metrics of this datastore
+	pub fn metrics(&self) -> &Metrics {
+		&self.metrics
+	}
+
+	/// Get the delivery queue of the webhooks which are sent by events
+	pub fn webhooks(&self) -> &Webhooks {
+		&self.hooks
+	}
+
+	/// Get the registry of the sessions which are connected to this datastore
+	pub fn registry(&self) -> &Arc<Registry> {
+		&self.registry
+	}
+
+	/// Get the number of queries which were run against each namespace
+	pub fn usage(&self) -> &Arc<Usage> {
+		&self.usage
+	}
+
+	/// Get the execution statistics of each normalized statement, which are
+	/// also returned by selecting from `system:statements`
+	pub fn statistics(&self) -> &Arc<Statistics> {
+		&self.statistics
+	}
+
+	/// Keep the execution statistics of up to a number of normalized statements
+	pub fn with_statistics(mut self, size: usize) -> Self {
+		self.statistics = Arc::new(Statistics::new(size));
+		self
+	}
+
+	/// Log any statement which takes longer than a duration as slow, until
+	/// the threshold is changed with a `SET GLOBAL` statement
+	pub fn with_slow_query_threshold(mut self, threshold: Option<Duration>) -> Self {
+		self.settings = Arc::new(Settings::new(threshold));
+		self
+	}
+
+	/// Cache the results of up to `size` SELECT statements for `ttl`, so
+	/// that repeated queries are not computed again until a table which
+	/// they select from is written to
+	pub fn with_result_cache(mut self, size: usize, ttl: Duration) -> Self {
+		self.results = Arc::new(ResultCache::new(size, ttl));
+		self
+	}
+
+	/// Get the cached results of SELECT statements
+	pub fn result_cache(&self) -> &ResultCache {
+		&self.results
+	}
+
+	/// Roll back the changes of every query, returning the changes which
+	/// each statement would have made instead of committing them
+	pub fn with_dry_run(mut self, dry_run: bool) -> Self {
+		self.dry_run = dry_run;
+		self
+	}
+
 	/// Set global query timeout
 	pub fn query_timeout(mut self, duration: Option<Duration>) -> Self {
 		self.query_timeout = duration;
@@ -263,6 +904,10 @@ impl Datastore {
 				let tx = v.transaction(write, lock).await?;
 				super::tx::Inner::FDB(tx)
 			}
+			Inner::Engine(v) => {
+				let tx = v.transaction(write, lock).await?;
+				super::tx::Inner::Engine(tx)
+			}
 			#[allow(unreachable_patterns)]
 			_ => unreachable!(),
 		};
@@ -271,9 +916,257 @@ impl Datastore {
 		Ok(Transaction {
 			inner,
 			cache: super::cache::Cache::default(),
+			feed: self.feed.clone(),
+			changes: vec![],
+			stream: self.stream.clone(),
+			writes: vec![],
+			replay: None,
+			archive: self.archive.clone(),
+			metrics: self.metrics.clone(),
+			hooks: self.hooks.clone(),
+			webhooks: vec![],
+			notifications: vec![],
+			results: self.results.clone(),
+			invalidated: vec![],
+			tracked: match (&self.conflicts, write) {
+				(Some(v), true) => Some(v.begin()),
+				_ => None,
+			},
+			max_document_size: self.max_document_size,
+			chunk_size: self.chunk_size,
+			shard: rand::random::<u8>() % self.counter_shards,
+			usage: HashMap::new(),
+			journaled: self.journal.is_some(),
 		})
 	}
 
+	/// Subscribe to the decoded changes committed to this datastore
+	///
+	/// ```rust,no_run
+	/// use surrealdb::kvs::Datastore;
+	/// use surrealdb::err::Error;
+	///
+	/// #[tokio::main]
+	/// async fn main() -> Result<(), Error> {
+	///     let ds = Datastore::new("memory").await?;
+	///     let changes = ds.changes().await;
+	///     while let Ok(change) = changes.recv().await {
+	///         println!("{} {}", change.action(), change.id);
+	///     }
+	///     Ok(())
+	/// }
+	/// ```
+	pub async fn changes(&self) -> Receiver<Change> {
+		self.feed.subscribe().await
+	}
+
+	/// Subscribe to the decoded changes committed to this datastore, after
+	/// the change with the specified sequence number. Any retained changes
+	/// after the sequence number are returned, or `None` if some of those
+	/// changes are no longer retained. See [`Datastore::with_history`].
+	pub async fn changes_since(&self, seq: u64) -> (Option<Vec<Change>>, Receiver<Change>) {
+		self.feed.subscribe_since(seq).await
+	}
+
+	/// Select the record of a change with the permissions of a session
+	///
+	/// Returns `None` if the session can not select the record, or if the
+	/// record does not match the condition. A deleted record is checked with
+	/// its content before the deletion, and only its record id is returned.
+	pub async fn select_change(
+		&self,
+		sess: &Session,
+		change: &Change,
+		cond: Option<&Cond>,
+	) -> Result<Option<Value>, Error> {
+		// Check that the change is to the selected database
+		if sess.ns.as_deref() != Some(change.ns.as_str())
+			|| sess.db.as_deref() != Some(change.db.as_str())
+		{
+			return Ok(None);
+		}
+		// Start a new read-only transaction
+		let txn = self.transaction(false, false).await?;
+		//
+		let txn = Arc::new(Mutex::new(txn));
+		// Create a default context
+		let mut ctx = Context::default();
+		// Add the transaction
+		ctx.add_transaction(Some(&txn));
+		// Apply the configuration of this datastore
+		ctx.add_config(self.config.clone());
+		// Start an execution context
+		let ctx = sess.context(ctx);
+		// Create a new query options
+		let mut opt = Options::default();
+		// Setup the auth options
+		opt.auth = sess.au.clone();
+		// Set current NS and DB
+		opt.ns = sess.ns();
+		opt.db = sess.db();
+		// Select the record from its table
+		let stm = SelectStatement {
+			expr: Fields::all(),
+			what: Values(vec![Value::from(Table(change.tb.clone()))]),
+			cond: cond.cloned(),
+			..SelectStatement::default()
+		};
+		let stm = crate::dbs::Statement::from(&stm);
+		// Check a deleted record with its content before the deletion
+		let action = change.action();
+		let val = match action {
+			Action::Delete => &change.before,
+			_ => &change.after,
+		};
+		let doc = Document::new(Some(&change.id), val, Workable::Normal);
+		let res = async {
+			// Check the table permissions
+			doc.allow(&ctx, &opt, &stm).await?;
+			// Check the where condition
+			doc.check(&ctx, &opt, &stm).await?;
+			// Hide the fields which can not be selected
+			match action {
+				Action::Delete => Ok(Value::from(change.id.clone())),
+				_ => doc.pluck(&ctx, &opt, &stm).await,
+			}
+		}
+		.await;
+		// Nothing is written by the selection
+		txn.lock().await.cancel().await?;
+		match res {
+			Ok(v) => Ok(Some(v)),
+			Err(Error::Ignore) => Ok(None),
+			Err(e) => Err(e),
+		}
+	}
+
+	/// Subscribe to the raw key-value writes committed to this datastore
+	///
+	/// Each committed transaction is received as a single [`Batch`], which
+	/// can be applied to a replica of this datastore using [`Datastore::apply`].
+	pub async fn writes(&self) -> channel::Receiver<Batch> {
+		self.stream.subscribe().await
+	}
+
+	/// Get the sequence number of the last batch committed to this datastore
+	pub fn sequence(&self) -> u64 {
+		self.stream.sequence()
+	}
+
+	/// Apply a batch of writes received from the write stream of another datastore
+	#[instrument(skip_all, fields(seq = batch.seq))]
+	pub async fn apply(&self, batch: &Batch) -> Result<(), Error> {
+		// Start a new transaction
+		let mut txn = self.transaction(true, false).await?;
+		// Keep the sequence number of the batch
+		txn.replay = Some(batch.seq);
+		// Process the writes
+		let res = async {
+			for w in batch.writes.iter() {
+				match w {
+					Write::Set(k, v) => txn.set(k.clone(), v.clone()).await?,
+					Write::Del(k) => txn.del(k.clone()).await?,
+				}
+			}
+			Ok::<(), Error>(())
+		}
+		.await;
+		// Commit or cancel the transaction
+		match res {
+			Ok(_) => txn.commit().await,
+			Err(e) => {
+				txn.cancel().await?;
+				Err(e)
+			}
+		}
+	}
+
+	/// Specify whether this datastore rejects queries which modify data
+	pub fn set_read_only(&self, v: bool) {
+		self.read_only.store(v, Ordering::Release);
+	}
+
+	/// Check if this datastore rejects queries which modify data
+	pub fn is_read_only(&self) -> bool {
+		self.read_only.load(Ordering::Acquire)
+	}
+
+	/// Check if this datastore is shutting down
+	pub fn is_closing(&self) -> bool {
+		self.closing.load(Ordering::Acquire)
+	}
+
+	/// Shut down this datastore gracefully
+	///
+	/// New queries are rejected, other than those from connections which are
+	/// already registered, and the subscriber of every live query is sent a
+	/// `SHUTDOWN` notification, containing the sequence number of the last
+	/// committed change, from which it can resume with [`Datastore::changes_since`]
+	/// once the datastore has restarted. Every connection is then closed, and
+	/// the running queries are given until the timeout to complete, after
+	/// which they are cancelled, so that their transactions are rolled back
+	/// rather than being left in an unknown state. Finally any buffered
+	/// writes are flushed to the storage engine.
+	pub async fn shutdown(&self, timeout: Duration) -> Result<(), Error> {
+		// Reject any new queries
+		self.closing.store(true, Ordering::Release);
+		// Notify the subscribers of any live queries
+		self.registry.shutdown(self.feed.sequence());
+		// Stop receiving queries from any connections
+		self.registry.close();
+		// Wait for the running queries to complete
+		let now = Instant::now();
+		while self.registry.active() > 0 && now.elapsed() < timeout {
+			#[cfg(target_arch = "wasm32")]
+			wasmtimer::tokio::sleep(Duration::from_millis(10)).await;
+			#[cfg(not(target_arch = "wasm32"))]
+			tokio::time::sleep(Duration::from_millis(10)).await;
+		}
+		// Cancel any queries which are still running
+		if self.registry.active() > 0 {
+			warn!(target: LOG, "Cancelling {} queries which did not complete", self.registry.active());
+			self.registry.cancel();
+		}
+		// Roll back any transactions which are still open
+		for v in self.transactions.expired(true) {
+			let _ = v.txn.lock().await.cancel().await;
+			self.finish(&v, false);
+		}
+		// End the change log for any subscribers
+		self.feed.close().await;
+		// Flush any buffered writes to storage
+		self.flush().await
+	}
+
+	/// Flush any buffered writes to the storage engine
+	async fn flush(&self) -> Result<(), Error> {
+		match &self.inner {
+			#[cfg(feature = "kv-rocksdb")]
+			Inner::RocksDB(v) => v.flush().await,
+			#[cfg(feature = "kv-speedb")]
+			Inner::SpeeDB(v) => v.flush().await,
+			Inner::Engine(v) => v.flush().await,
+			#[allow(unreachable_patterns)]
+			_ => Ok(()),
+		}
+	}
+
+	/// Specify whether committed transactions wait to be acknowledged by replicas
+	pub fn set_synchronous(&self, v: bool) {
+		self.stream.set_synchronous(v);
+	}
+
+	/// Acknowledge that all batches up to and including a sequence number
+	/// have been replicated, releasing any transactions waiting on them
+	pub async fn acknowledge(&self, seq: u64) {
+		self.stream.acknowledge(seq).await;
+	}
+
+	/// Fail any transactions waiting for their batches to be replicated
+	pub async fn unacknowledge(&self) {
+		self.stream.unacknowledge().await;
+	}
+
 	/// Parse and execute an SQL query
 	///
 	/// ```rust,no_run
@@ -304,6 +1197,38 @@ impl Datastore {
 		self.process(ast, sess, vars, strict).await
 	}
 
+	/// Execute a query, returning the variables which were set with
+	/// LET statements, so that they can be kept for the next query
+	/// on the same connection
+	///
+	/// ```rust,no_run
+	/// use surrealdb::kvs::Datastore;
+	/// use surrealdb::err::Error;
+	/// use surrealdb::dbs::Session;
+	///
+	/// #[tokio::main]
+	/// async fn main() -> Result<(), Error> {
+	///     let ds = Datastore::new("memory").await?;
+	///     let ses = Session::for_kv();
+	///     let ast = "USE NS test DB test; LET $tenant = 'acme';";
+	///     let (res, vars) = ds.execute_with_lets(ast, &ses, None, false).await?;
+	///     Ok(())
+	/// }
+	/// ```
+	#[instrument(skip_all)]
+	pub async fn execute_with_lets(
+		&self,
+		txt: &str,
+		sess: &Session,
+		vars: Variables,
+		strict: bool,
+	) -> Result<(Vec<Response>, BTreeMap<String, Value>), Error> {
+		// Parse the SQL query text
+		let ast = sql::parse(txt)?;
+		// Process the AST
+		self.process_with_lets(ast, sess, vars, strict).await
+	}
+
 	/// Execute a pre-parsed SQL query
 	///
 	/// ```rust,no_run
@@ -329,12 +1254,54 @@ impl Datastore {
 		vars: Variables,
 		strict: bool,
 	) -> Result<Vec<Response>, Error> {
+		self.process_with_lets(ast, sess, vars, strict).await.map(|(res, _)| res)
+	}
+
+	/// Execute a pre-parsed SQL query, returning the variables which
+	/// were set with LET statements
+	#[instrument(skip_all)]
+	pub async fn process_with_lets(
+		&self,
+		ast: Query,
+		sess: &Session,
+		vars: Variables,
+		strict: bool,
+	) -> Result<(Vec<Response>, BTreeMap<String, Value>), Error> {
+		self.run(ast, sess, vars, strict, None).await
+	}
+
+	/// Execute a pre-parsed SQL query, optionally in a transaction which
+	/// is kept open between queries
+	async fn run(
+		&self,
+		ast: Query,
+		sess: &Session,
+		vars: Variables,
+		strict: bool,
+		handle: Option<&mut Handle>,
+	) -> Result<(Vec<Response>, BTreeMap<String, Value>), Error> {
 		// Create a new query options
 		let mut opt = Options::default();
-		// Create a new query executor
-		let mut exe = Executor::new(self);
+		// Reject new queries while shutting down
+		if self.is_closing() && !self.registry.is_connected(sess) {
+			return Err(Error::ShuttingDown);
+		}
+		// Check the network allowlists of the namespaces
+		allowlist::check(&self.config.ns_allowlists, sess, Some(&ast))?;
+		// Get the identity of the session for any quotas
+		let idn = self.quotas.identity(sess);
+		// Reject the query if the identity has no capacity left
+		self.quotas.admit(idn.as_deref())?;
 		// Create a default context
 		let mut ctx = Context::default();
+		// Register the query for the session, so that it can be cancelled
+		let reg = self.registry.begin(sess, ctx.add_cancel());
+		// Create a new query executor
+		let mut exe = Executor::new(self, reg.id, idn);
+		// Run the query in any transaction which is kept open
+		if let Some(v) = &handle {
+			exe = exe.with_transaction(v.txn.clone(), v.err, v.jid);
+		}
 		// Set the global query timeout
 		if let Some(timeout) = self.query_timeout {
 			ctx.add_timeout(timeout);
@@ -352,8 +1319,227 @@ impl Datastore {
 		opt.db = sess.db();
 		// Set strict config
 		opt.strict = strict;
+		// Roll back the changes of a dry-run
+		opt.dry = self.dry_run || sess.dr;
 		// Process all statements
-		exe.execute(ctx, opt, ast).await
+		let res = exe.execute(ctx, opt, ast).await;
+		// Mark any transaction which is kept open as failed
+		if let Some(v) = handle {
+			v.err = exe.failed() || res.is_err();
+			v.jid = exe.journal_id();
+		}
+		// Return the responses and the variables
+		Ok((res?, exe.vars()))
+	}
+
+	/// Begin a transaction which is kept open between queries, returning
+	/// the id with which queries are run in the transaction using
+	/// [`Datastore::execute_in`], until the transaction is finished with
+	/// [`Datastore::commit`] or [`Datastore::cancel`]. Only the session
+	/// which began the transaction can use it, and the transaction is
+	/// cancelled once it has not been used for the transaction timeout.
+	///
+	/// ```rust,no_run
+	/// use surrealdb::kvs::Datastore;
+	/// use surrealdb::err::Error;
+	/// use surrealdb::dbs::Session;
+	///
+	/// #[tokio::main]
+	/// async fn main() -> Result<(), Error> {
+	///     let ds = Datastore::new("memory").await?;
+	///     let ses = Session::for_kv().with_ns("test").with_db("test");
+	///     let id = ds.begin(&ses).await?;
+	///     ds.execute_in(&id, "CREATE person:tobie", &ses, None, false).await?;
+	///     ds.execute_in(&id, "CREATE person:jaime", &ses, None, false).await?;
+	///     ds.commit(&id, &ses).await?;
+	///     Ok(())
+	/// }
+	/// ```
+	pub async fn begin(&self, sess: &Session) -> Result<Uuid, Error> {
+		// Reject new transactions while shutting down
+		if self.is_closing() {
+			return Err(Error::ShuttingDown);
+		}
+		// Start a new write transaction
+		let txn = Arc::new(Mutex::new(self.transaction(true, false).await?));
+		// Keep the transaction open
+		let dry = self.dry_run || sess.dr;
+		Ok(self.transactions.insert(Handle::new(txn, sess, dry)))
+	}
+
+	/// Parse and execute an SQL query in a transaction which was started
+	/// with [`Datastore::begin`]. The variables which are set with LET
+	/// statements are kept for the next query in the transaction. Once a
+	/// statement fails, no further statements are run, and the transaction
+	/// can only be cancelled.
+	#[instrument(skip_all)]
+	pub async fn execute_in(
+		&self,
+		id: &Uuid,
+		txt: &str,
+		sess: &Session,
+		vars: Variables,
+		strict: bool,
+	) -> Result<Vec<Response>, Error> {
+		// Parse the SQL query text
+		let ast = sql::parse(txt)?;
+		// Process the AST
+		self.process_in(id, ast, sess, vars, strict).await
+	}
+
+	/// Execute a pre-parsed SQL query in a transaction which was started
+	/// with [`Datastore::begin`], in the same way as [`Datastore::execute_in`]
+	#[instrument(skip_all)]
+	pub async fn process_in(
+		&self,
+		id: &Uuid,
+		ast: Query,
+		sess: &Session,
+		vars: Variables,
+		strict: bool,
+	) -> Result<Vec<Response>, Error> {
+		// Take the transaction for this query
+		let mut handle = self.transactions.take(id, sess)?;
+		// Add the variables which were set in the transaction
+		let mut all = handle.vars.clone();
+		all.extend(vars.unwrap_or_default());
+		// Process the AST
+		let res = self.run(ast, sess, Some(all), strict, Some(&mut *handle)).await;
+		// Keep the variables which were set
+		let res = res.map(|(res, lets)| {
+			handle.vars.extend(lets);
+			res
+		});
+		// Put the transaction back for the next query
+		handle.put();
+		res
+	}
+
+	/// Commit a transaction which was started with [`Datastore::begin`],
+	/// which is cancelled instead if any of its statements failed
+	pub async fn commit(&self, id: &Uuid, sess: &Session) -> Result<(), Error> {
+		let handle = self.transactions.take(id, sess)?.remove();
+		let mut txn = handle.txn.lock().await;
+		let res = match (handle.err, handle.dry) {
+			// A statement in the transaction failed
+			(true, _) => {
+				let _ = txn.cancel().await;
+				Err(Error::QueryCancelled)
+			}
+			// Roll back the changes of a dry-run
+			(false, true) => txn.cancel().await,
+			// Sync any journaled statements to disk, and commit the changes
+			(false, false) => match (handle.jid, self.journal()) {
+				(Some(_), Some(journal)) => match journal.sync() {
+					Ok(_) => txn.commit().await,
+					Err(e) => {
+						let _ = txn.cancel().await;
+						Err(e)
+					}
+				},
+				_ => txn.commit().await,
+			},
+		};
+		// Append the outcome of the transaction to the journal
+		self.finish(&handle, res.is_ok() && !handle.dry);
+		res
+	}
+
+	/// Cancel a transaction which was started with [`Datastore::begin`]
+	pub async fn cancel(&self, id: &Uuid, sess: &Session) -> Result<(), Error> {
+		let handle = self.transactions.take(id, sess)?.remove();
+		let mut txn = handle.txn.lock().await;
+		let res = txn.cancel().await;
+		self.finish(&handle, false);
+		res
+	}
+
+	/// Cancel the transactions which have not been used within the
+	/// transaction timeout, returning the number which were cancelled
+	pub async fn expire_transactions(&self) -> usize {
+		let handles = self.transactions.expired(false);
+		for v in handles.iter() {
+			let _ = v.txn.lock().await.cancel().await;
+			self.finish(v, false);
+		}
+		handles.len()
+	}
+
+	/// Execute a batch of statements, returning a response for each
+	/// statement, in the order in which the statements were specified
+	///
+	/// ```rust,no_run
+	/// use surrealdb::kvs::Datastore;
+	/// use surrealdb::err::Error;
+	/// use surrealdb::dbs::BatchPolicy;
+	/// use surrealdb::dbs::Session;
+	///
+	/// #[tokio::main]
+	/// async fn main() -> Result<(), Error> {
+	///     let ds = Datastore::new("memory").await?;
+	///     let ses = Session::for_kv().with_ns("test").with_db("test");
+	///     let stms = vec!["CREATE person:tobie".to_owned(), "CREATE person:jaime".to_owned()];
+	///     let res = ds.batch(&stms, BatchPolicy::Atomic, &ses, None, false).await?;
+	///     Ok(())
+	/// }
+	/// ```
+	#[instrument(skip_all)]
+	pub async fn batch(
+		&self,
+		txts: &[String],
+		policy: BatchPolicy,
+		sess: &Session,
+		vars: Variables,
+		strict: bool,
+	) -> Result<Vec<Response>, Error> {
+		// Parse each of the statements
+		let stms = txts.iter().map(|v| parse_statement(v)).collect::<Vec<_>>();
+		// An atomic batch is not run if any statement is invalid
+		if policy == BatchPolicy::Atomic && stms.iter().any(|v| v.is_err()) {
+			return Ok(stms
+				.into_iter()
+				.map(|v| Response {
+					time: Duration::ZERO,
+					result: match v {
+						Ok(_) => Err(Error::QueryNotExecuted),
+						Err(e) => Err(e),
+					},
+					retries: 0,
+					cursor: None,
+					total: None,
+				})
+				.collect());
+		}
+		// Keep the errors of any invalid statements
+		let mut ast = vec![];
+		let mut errs = vec![];
+		for (i, v) in stms.into_iter().enumerate() {
+			match v {
+				Ok(v) => ast.push(v),
+				Err(e) => errs.push((i, e)),
+			}
+		}
+		// Run an atomic batch in a single transaction
+		if policy == BatchPolicy::Atomic {
+			ast.insert(0, Statement::Begin(Default::default()));
+			ast.push(Statement::Commit(Default::default()));
+		}
+		// Process the valid statements
+		let mut res = self.process(Query(Statements(ast)), sess, vars, strict).await?;
+		// Put the errors back in the order of the statements
+		for (i, e) in errs {
+			res.insert(
+				i,
+				Response {
+					time: Duration::ZERO,
+					result: Err(e),
+					retries: 0,
+					cursor: None,
+					total: None,
+				},
+			);
+		}
+		Ok(res)
 	}
 
 	/// Ensure a SQL [`Value`] is fully computed
@@ -382,16 +1568,35 @@ impl Datastore {
 		vars: Variables,
 		strict: bool,
 	) -> Result<Value, Error> {
+		// Check if the datastore is read-only
+		if val.writeable() && self.is_read_only() {
+			return Err(Error::ReplicaReadOnly);
+		}
+		// Reject new queries while shutting down
+		if self.is_closing() && !self.registry.is_connected(sess) {
+			return Err(Error::ShuttingDown);
+		}
+		// Check the network allowlist of the namespace
+		allowlist::check(&self.config.ns_allowlists, sess, None)?;
+		// Create a default context
+		let mut ctx = Context::default();
+		// Register the computation for the session, so that it can be cancelled
+		let _reg = self.registry.begin(sess, ctx.add_cancel());
+		// Serve read-only values from the replica, if configured
+		let kvs = match (val.writeable(), self.replica()) {
+			(false, Some(v)) => v,
+			_ => self,
+		};
 		// Start a new transaction
-		let txn = self.transaction(val.writeable(), false).await?;
+		let txn = kvs.transaction(val.writeable(), false).await?;
 		//
 		let txn = Arc::new(Mutex::new(txn));
 		// Create a new query options
 		let mut opt = Options::default();
-		// Create a default context
-		let mut ctx = Context::default();
 		// Add the transaction
 		ctx.add_transaction(Some(&txn));
+		// Apply the configuration of this datastore
+		ctx.add_config(self.config.clone());
 		// Set the global query timeout
 		if let Some(timeout) = self.query_timeout {
 			ctx.add_timeout(timeout);
@@ -407,10 +1612,24 @@ impl Datastore {
 		opt.db = sess.db();
 		// Set strict config
 		opt.strict = strict;
+		// Roll back the changes of a dry-run
+		opt.dry = self.dry_run || sess.dr;
 		// Compute the value
-		let res = val.compute(&ctx, &opt).await?;
+		let res = val.compute(&ctx, &opt).await;
+		// Roll back a computation which failed, or which was cancelled
+		let res = match (res, ctx.done()) {
+			(Ok(v), None) => v,
+			(res, reason) => {
+				txn.lock().await.cancel().await?;
+				return match reason {
+					Some(Reason::Timedout) => Err(Error::QueryTimedout),
+					Some(Reason::Canceled) => Err(Error::QueryKilled),
+					None => res,
+				};
+			}
+		};
 		// Store any data
-		match val.writeable() {
+		match val.writeable() && !opt.dry {
 			true => txn.lock().await.commit().await?,
 			false => txn.lock().await.cancel().await?,
 		};
@@ -428,4 +1647,228 @@ impl Datastore {
 		// Everything ok
 		Ok(())
 	}
+
+	/// Performs a full database export as SQL, in read-committed mode
+	///
+	/// Each batch of records is read from a new snapshot, so that a long
+	/// export does not prevent the storage engine from releasing old data.
+	/// The export is no longer a consistent snapshot, as changes which are
+	/// committed during the export may be included in later batches.
+	#[instrument(skip(self, chn))]
+	pub async fn export_committed(
+		&self,
+		ns: String,
+		db: String,
+		chn: Sender<Vec<u8>>,
+	) -> Result<(), Error> {
+		// Start a new transaction
+		let mut txn = self.transaction(false, false).await?;
+		// Process the export
+		txn.export_with(&ns, &db, chn, Some(self)).await?;
+		// Everything ok
+		txn.cancel().await
+	}
+
+	/// Performs a full database export as SQL, with the fields which are
+	/// defined as `SENSITIVE` masked, hashed, or faked in every record
+	#[instrument(skip(self, chn))]
+	pub async fn export_anonymized(
+		&self,
+		ns: String,
+		db: String,
+		chn: Sender<Vec<u8>>,
+	) -> Result<(), Error> {
+		// Start a new transaction
+		let mut txn = self.transaction(false, false).await?;
+		// Process the export
+		txn.export_anonymized(&ns, &db, chn).await?;
+		// Everything ok
+		txn.cancel().await
+	}
+
+	/// Performs an export of the definitions of a database as SQL, without any table data
+	#[instrument(skip(self, chn))]
+	pub async fn export_schema(
+		&self,
+		ns: String,
+		db: String,
+		chn: Sender<Vec<u8>>,
+	) -> Result<(), Error> {
+		// Start a new transaction
+		let mut txn = self.transaction(false, false).await?;
+		// Process the export
+		txn.export_schema(&ns, &db, chn).await?;
+		// Everything ok
+		txn.cancel().await
+	}
+
+	/// Performs a binary snapshot of a namespace, a database, or the entire datastore
+	///
+	/// The snapshot is taken within a single read-only transaction, so it
+	/// is consistent without blocking any concurrent writes.
+	#[instrument(skip(self, chn))]
+	pub async fn backup(
+		&self,
+		ns: Option<String>,
+		db: Option<String>,
+		chn: Sender<Vec<u8>>,
+	) -> Result<(), Error> {
+		// Get the snapshot key prefix
+		let pre = super::backup::prefix(ns.as_deref(), db.as_deref())?;
+		// Output the snapshot header
+		chn.send(super::backup::header(&Datetime::default())).await?;
+		// Start a new transaction
+		let mut txn = self.transaction(false, false).await?;
+		// Process the snapshot
+		txn.backup(&pre, chn).await?;
+		// Everything ok
+		txn.cancel().await
+	}
+
+	/// Restores a binary snapshot of a namespace, a database, or the entire
+	/// datastore, replacing any existing data. This returns the time at which
+	/// the snapshot was taken.
+	///
+	/// The existing data is removed, and the snapshot is written, in batches
+	/// which are each committed in their own transaction, so that a large
+	/// snapshot can be restored within the transaction limits of the storage
+	/// engine. A marker is stored until the restore completes, so that a
+	/// restore which fails part of the way through can be detected with
+	/// [`Datastore::restoring`], and the snapshot restored again.
+	#[instrument(skip(self, data))]
+	pub async fn restore(
+		&self,
+		ns: Option<String>,
+		db: Option<String>,
+		data: &[u8],
+	) -> Result<Datetime, Error> {
+		// Get the snapshot key prefix
+		let pre = super::backup::prefix(ns.as_deref(), db.as_deref())?;
+		// Decode the snapshot
+		let (at, kvs) = super::backup::decode(data)?;
+		// Mark the restore as started
+		let rs: Key = crate::key::rs::new().into();
+		let mut txn = self.transaction(true, false).await?;
+		txn.set(rs.clone(), pre.clone()).await?;
+		txn.commit().await?;
+		// Remove any existing data, except for the marker
+		let mut end = pre.clone();
+		end.push(0xff);
+		let mut nxt: Option<Key> = None;
+		loop {
+			let min = match nxt.take() {
+				Some(mut v) => {
+					v.push(0x00);
+					v
+				}
+				None => pre.clone(),
+			};
+			let mut txn = self.transaction(true, false).await?;
+			let res = txn.scan_raw(min..end.clone(), super::backup::BATCH_SIZE).await?;
+			// Exit when settled
+			if res.is_empty() {
+				txn.cancel().await?;
+				break;
+			}
+			for (k, _) in res.into_iter() {
+				if k != rs {
+					txn.del(k.clone()).await?;
+				}
+				nxt = Some(k);
+			}
+			txn.commit().await?;
+		}
+		// Write the snapshot data
+		let mut kvs = kvs.into_iter().peekable();
+		while kvs.peek().is_some() {
+			let mut txn = self.transaction(true, false).await?;
+			let (mut num, mut size) = (0, 0);
+			while num < super::backup::BATCH_SIZE && size < super::backup::BATCH_BYTES {
+				let Some((k, v)) = kvs.next() else {
+					break;
+				};
+				let key: Key = pre.iter().chain(k.iter()).copied().collect();
+				txn.set(key, v.to_vec()).await?;
+				num += 1;
+				size += v.len();
+			}
+			txn.commit().await?;
+		}
+		// Ensure the containing definitions exist,
+		// and mark the restore as complete
+		let mut txn = self.transaction(true, false).await?;
+		if let Some(ns) = &ns {
+			txn.add_ns(ns, false).await?;
+		}
+		if let (Some(ns), Some(db)) = (&ns, &db) {
+			txn.add_db(ns, db, false).await?;
+		}
+		txn.del(rs).await?;
+		txn.commit().await?;
+		// Everything ok
+		Ok(at)
+	}
+
+	/// Checks whether a restore was started and did not complete, in which
+	/// case the restored location holds part of the existing data and part
+	/// of the snapshot, until the snapshot is restored again
+	pub async fn restoring(&self) -> Result<bool, Error> {
+		let mut txn = self.transaction(false, false).await?;
+		let res = txn.exi(crate::key::rs::new()).await?;
+		txn.cancel().await?;
+		Ok(res)
+	}
+
+	/// Performs a streaming export of the records in a table
+	#[instrument(skip(self, chn))]
+	pub async fn export_table(
+		&self,
+		ns: String,
+		db: String,
+		tb: String,
+		chn: Sender<Value>,
+	) -> Result<(), Error> {
+		// Start a new transaction
+		let mut txn = self.transaction(false, false).await?;
+		// Process the export
+		txn.export_table(&ns, &db, &tb, chn).await?;
+		// Everything ok
+		Ok(())
+	}
+
+	/// Performs a streaming export of the records in a table, with the
+	/// fields which are defined as `SENSITIVE` anonymized in every record
+	#[instrument(skip(self, chn))]
+	pub async fn export_table_anonymized(
+		&self,
+		ns: String,
+		db: String,
+		tb: String,
+		chn: Sender<Value>,
+	) -> Result<(), Error> {
+		// Start a new transaction
+		let mut txn = self.transaction(false, false).await?;
+		// Process the export
+		txn.export_table_anonymized(&ns, &db, &tb, chn).await?;
+		// Everything ok
+		txn.cancel().await
+	}
+
+	/// Performs a streaming export of the records in a table, in
+	/// read-committed mode, as with [`Datastore::export_committed`]
+	#[instrument(skip(self, chn))]
+	pub async fn export_table_committed(
+		&self,
+		ns: String,
+		db: String,
+		tb: String,
+		chn: Sender<Value>,
+	) -> Result<(), Error> {
+		// Start a new transaction
+		let mut txn = self.transaction(false, false).await?;
+		// Process the export
+		txn.export_table_with(&ns, &db, &tb, chn, Some(self), false).await?;
+		// Everything ok
+		txn.cancel().await
+	}
 }
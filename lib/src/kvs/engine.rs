@@ -0,0 +1,129 @@
+//! The interface which a pluggable storage engine implements.
+//!
+//! The built-in storage engines are selected using the datastore path.
+//! A custom storage engine implements the [`Engine`] and [`EngineTransaction`]
+//! traits, and is registered for a path scheme using [`register`], after
+//! which any datastore path beginning with that scheme opens the engine.
+//!
+//! An engine only needs to provide transactional reads and writes of raw
+//! keys and values, and ordered range scans. Caching, change feeds, and the
+//! write stream which is used for watching and replicating the datastore,
+//! are all provided on top of every engine by the datastore itself. An
+//! engine whose storage is shared with other processes can also be watched,
+//! so that the writes which those processes commit are published on the
+//! write stream of the datastore.
+use super::{Key, Val, Write};
+use crate::err::Error;
+use async_trait::async_trait;
+use futures::future::BoxFuture;
+use once_cell::sync::Lazy;
+use std::future::Future;
+use std::ops::Range;
+use std::sync::{Arc, RwLock};
+
+/// A storage engine which can be used by a datastore
+#[async_trait]
+pub trait Engine: Send + Sync + 'static {
+	/// Start a new read-only or writeable transaction. A pessimistic
+	/// transaction locks any keys which it reads until it completes.
+	async fn transaction(
+		&self,
+		write: bool,
+		lock: bool,
+	) -> Result<Box<dyn EngineTransaction>, Error>;
+	/// Check if the engine fails to commit any transaction which conflicts
+	/// with a concurrent transaction. Otherwise the datastore checks for
+	/// transactions which write to the same keys itself. By default the
+	/// engine does not detect conflicts.
+	fn detects_conflicts(&self) -> bool {
+		false
+	}
+	/// Write any buffered changes to durable storage, when the datastore
+	/// is shut down. By default nothing is flushed.
+	async fn flush(&self) -> Result<(), Error> {
+		Ok(())
+	}
+	/// Watch for the writes which other processes commit to the engine.
+	/// Each message holds the writes of a single transaction, in commit
+	/// order, and is published on the write stream of the datastore. The
+	/// writes which this datastore commits should not be sent, as they are
+	/// already published. By default the engine is not watched.
+	fn watch(&self) -> Option<channel::Receiver<Vec<Write>>> {
+		None
+	}
+}
+
+/// A transaction on a storage engine. A transaction which has been cancelled
+/// or committed should return [`Error::TxFinished`] from any further calls,
+/// and a read-only transaction should return [`Error::TxReadonly`] from any
+/// call which modifies data.
+#[async_trait]
+pub trait EngineTransaction: Send {
+	/// Check if the transaction has been cancelled or committed
+	fn closed(&self) -> bool;
+	/// Cancel the transaction, discarding any changes
+	async fn cancel(&mut self) -> Result<(), Error>;
+	/// Commit the transaction, making all changes visible atomically
+	async fn commit(&mut self) -> Result<(), Error>;
+	/// Check if a key exists
+	async fn exi(&mut self, key: Key) -> Result<bool, Error>;
+	/// Fetch the value of a key
+	async fn get(&mut self, key: Key) -> Result<Option<Val>, Error>;
+	/// Insert or update a key
+	async fn set(&mut self, key: Key, val: Val) -> Result<(), Error>;
+	/// Insert a key, failing with [`Error::TxKeyAlreadyExists`] if it exists
+	async fn put(&mut self, key: Key, val: Val) -> Result<(), Error>;
+	/// Insert or update a key, failing with [`Error::TxConditionNotMet`]
+	/// if its current value does not match the check value
+	async fn putc(&mut self, key: Key, val: Val, chk: Option<Val>) -> Result<(), Error>;
+	/// Delete a key
+	async fn del(&mut self, key: Key) -> Result<(), Error>;
+	/// Delete a key, failing with [`Error::TxConditionNotMet`]
+	/// if its current value does not match the check value
+	async fn delc(&mut self, key: Key, chk: Option<Val>) -> Result<(), Error>;
+	/// Fetch up to `limit` keys within a range, in ascending key order
+	async fn scan(&mut self, rng: Range<Key>, limit: u32) -> Result<Vec<(Key, Val)>, Error>;
+}
+
+/// A function which opens a storage engine at a path
+type Opener =
+	Arc<dyn Fn(String) -> BoxFuture<'static, Result<Box<dyn Engine>, Error>> + Send + Sync>;
+
+/// The storage engines which have been registered
+static ENGINES: Lazy<RwLock<Vec<(String, Opener)>>> = Lazy::new(Default::default);
+
+/// Register a storage engine for a datastore path scheme
+///
+/// ```rust,ignore
+/// use surrealdb::kvs::{register, Datastore};
+///
+/// register("custom", |path| async move { CustomEngine::open(&path).await });
+/// let ds = Datastore::new("custom://path/to/data").await?;
+/// ```
+pub fn register<F, T, E>(scheme: &str, open: F)
+where
+	F: Fn(String) -> T + Send + Sync + 'static,
+	T: Future<Output = Result<E, Error>> + Send + 'static,
+	E: Engine,
+{
+	let open: Opener = Arc::new(move |path| {
+		let fut = open(path);
+		Box::pin(async move { Ok(Box::new(fut.await?) as Box<dyn Engine>) })
+	});
+	let mut engines = ENGINES.write().unwrap();
+	engines.retain(|(v, _)| v != scheme);
+	engines.push((scheme.to_owned(), open));
+}
+
+/// Open a registered storage engine, if one matches the path scheme
+pub(super) async fn open(path: &str) -> Option<Result<Box<dyn Engine>, Error>> {
+	let (open, path) = {
+		let engines = ENGINES.read().unwrap();
+		engines.iter().find_map(|(scheme, open)| {
+			let path = path.strip_prefix(scheme.as_str())?.strip_prefix(':')?;
+			let path = path.strip_prefix("//").unwrap_or(path);
+			Some((open.clone(), path.to_owned()))
+		})?
+	};
+	Some(open(path).await)
+}
@@ -138,6 +138,8 @@ impl Transaction {
 		};
 		match r {
 			Ok(_r) => {}
+			// The transaction was not committed due to a conflict
+			Err(e) if e.code() == 1020 => return Err(Error::TxConflict),
 			Err(e) => {
 				return Err(Error::Tx(format!("Transaction commit error: {}", e).to_string()));
 			}
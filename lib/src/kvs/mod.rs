@@ -12,14 +12,24 @@
 //! - `speedb`: [SpeedyDB](https://github.com/speedb-io/speedb) fork of rocksDB making it faster (Redis is using speedb but this is not acid transactions)
 //! - `tikv`: [TiKV](https://github.com/tikv/tikv) a distributed, and transactional key-value database
 //! - `mem`: in-memory database
+mod anonymize;
+mod archive;
+mod backup;
 mod cache;
+mod chunk;
+mod compress;
+mod conflict;
 mod ds;
+mod engine;
 mod fdb;
 mod indxdb;
 mod kv;
 mod mem;
 mod rocksdb;
+#[cfg(any(feature = "http", feature = "protocol-http"))]
+pub mod s3;
 mod speedb;
+mod stream;
 mod tikv;
 mod tx;
 
@@ -27,7 +37,9 @@ mod tx;
 mod tests;
 
 pub use self::ds::*;
+pub use self::engine::{register, Engine, EngineTransaction};
 pub use self::kv::*;
+pub use self::stream::{Batch, Write};
 pub use self::tx::*;
 
 pub(crate) const LOG: &str = "surrealdb::kvs";
@@ -0,0 +1,161 @@
+//! A minimal client for S3-compatible object stores, which is shared by the
+//! archive tier and the backup commands. Every request is signed with AWS
+//! Signature Version 4, and objects are addressed using path-style urls.
+//! Credentials are read from the standard `AWS_ACCESS_KEY_ID`,
+//! `AWS_SECRET_ACCESS_KEY`, `AWS_SESSION_TOKEN`, and `AWS_REGION` environment
+//! variables, and the `AWS_ENDPOINT_URL` environment variable can be used to
+//! connect to an S3-compatible service other than Amazon S3.
+use crate::err::Error;
+use chrono::Utc;
+use hmac::{Hmac, Mac};
+use reqwest::{Method, RequestBuilder};
+use sha2::{Digest, Sha256};
+use std::env;
+
+/// The scheme of a path which refers to an S3-compatible object store
+const SCHEME: &str = "s3://";
+
+/// Check if a path refers to an S3-compatible object store
+pub fn is_s3(v: &str) -> bool {
+	v.starts_with(SCHEME)
+}
+
+/// Split an `s3://bucket/key` path into the bucket and the object key,
+/// which is empty if the path only specifies a bucket
+pub fn parse(v: &str) -> Option<(&str, &str)> {
+	let v = v.strip_prefix(SCHEME)?;
+	let (bucket, key) = v.split_once('/').unwrap_or((v, ""));
+	match bucket.is_empty() {
+		true => None,
+		false => Some((bucket, key)),
+	}
+}
+
+/// A client for an S3-compatible object store
+pub struct Client {
+	client: reqwest::Client,
+	endpoint: String,
+	host: String,
+	region: String,
+	access: String,
+	secret: String,
+	token: Option<String>,
+}
+
+impl Client {
+	/// Create a client, using the credentials in the environment
+	pub fn from_env() -> Result<Self, Error> {
+		let var = |k: &str| env::var(k).ok().filter(|v| !v.is_empty());
+		let missing =
+			|k: &str| Error::ObjectStore(format!("The {k} environment variable is not set"));
+		let access = var("AWS_ACCESS_KEY_ID").ok_or_else(|| missing("AWS_ACCESS_KEY_ID"))?;
+		let secret =
+			var("AWS_SECRET_ACCESS_KEY").ok_or_else(|| missing("AWS_SECRET_ACCESS_KEY"))?;
+		let region = var("AWS_REGION").unwrap_or_else(|| String::from("us-east-1"));
+		let endpoint = var("AWS_ENDPOINT_URL")
+			.map(|v| v.trim_end_matches('/').to_owned())
+			.unwrap_or_else(|| format!("https://s3.{region}.amazonaws.com"));
+		let host = match endpoint.split_once("://") {
+			Some((_, v)) => v.to_owned(),
+			None => {
+				return Err(Error::ObjectStore(format!("The S3 endpoint {endpoint} is invalid")))
+			}
+		};
+		Ok(Client {
+			client: reqwest::Client::new(),
+			endpoint,
+			host,
+			region,
+			access,
+			secret,
+			token: var("AWS_SESSION_TOKEN"),
+		})
+	}
+
+	/// Create a request for an object, signed with AWS Signature Version 4.
+	/// The path is the url-encoded `/bucket/key` of the object.
+	pub fn request(&self, method: Method, path: &str, body: &[u8]) -> RequestBuilder {
+		let now = Utc::now();
+		let time = now.format("%Y%m%dT%H%M%SZ").to_string();
+		let date = now.format("%Y%m%d").to_string();
+		let hash = hex(&Sha256::digest(body));
+		// Build the canonical request
+		let mut headers = vec![
+			("host", self.host.clone()),
+			("x-amz-content-sha256", hash.clone()),
+			("x-amz-date", time.clone()),
+		];
+		if let Some(token) = &self.token {
+			headers.push(("x-amz-security-token", token.clone()));
+		}
+		let signed = headers.iter().map(|(k, _)| *k).collect::<Vec<_>>().join(";");
+		let canonical = format!(
+			"{}\n{}\n\n{}\n{}\n{}",
+			method,
+			path,
+			headers.iter().map(|(k, v)| format!("{k}:{v}\n")).collect::<String>(),
+			signed,
+			hash,
+		);
+		// Sign the request
+		let scope = format!("{date}/{}/s3/aws4_request", self.region);
+		let string = format!(
+			"AWS4-HMAC-SHA256\n{time}\n{scope}\n{}",
+			hex(&Sha256::digest(canonical.as_bytes()))
+		);
+		let key = format!("AWS4{}", self.secret);
+		let key = hmac(key.as_bytes(), date.as_bytes());
+		let key = hmac(&key, self.region.as_bytes());
+		let key = hmac(&key, b"s3");
+		let key = hmac(&key, b"aws4_request");
+		let signature = hex(&hmac(&key, string.as_bytes()));
+		let auth = format!(
+			"AWS4-HMAC-SHA256 Credential={}/{scope}, SignedHeaders={signed}, Signature={signature}",
+			self.access
+		);
+		// Create the request
+		let mut req = self
+			.client
+			.request(method, format!("{}{}", self.endpoint, path))
+			.header("authorization", auth);
+		for (k, v) in headers.into_iter().filter(|(k, _)| *k != "host") {
+			req = req.header(k, v);
+		}
+		req
+	}
+}
+
+/// Compute an HMAC-SHA256 message authentication code
+fn hmac(key: &[u8], msg: &[u8]) -> Vec<u8> {
+	let mut mac = Hmac::<Sha256>::new_from_slice(key).expect("HMAC can take a key of any size");
+	mac.update(msg);
+	mac.finalize().into_bytes().to_vec()
+}
+
+/// Encode bytes as lowercase hexadecimal
+fn hex(v: &[u8]) -> String {
+	v.iter().map(|b| format!("{b:02x}")).collect()
+}
+
+#[cfg(test)]
+mod tests {
+
+	use super::*;
+
+	#[test]
+	fn hmac_sha256() {
+		// Test case 2 from RFC 4231
+		let out = hmac(b"Jefe", b"what do ya want for nothing?");
+		assert_eq!(hex(&out), "5bdcc146bf60754e6a042426089575c75a003f089d2739839dec58b964ec3843");
+	}
+
+	#[test]
+	fn parse_path() {
+		assert!(is_s3("s3://backups/prod.db"));
+		assert!(!is_s3("s3:backups/prod.db"));
+		assert_eq!(parse("s3://backups/2023/prod.db"), Some(("backups", "2023/prod.db")));
+		assert_eq!(parse("s3://archive"), Some(("archive", "")));
+		assert_eq!(parse("s3://"), None);
+		assert_eq!(parse("file://archive"), None);
+	}
+}
@@ -35,6 +35,10 @@ impl Datastore {
 			db: Arc::pin(OptimisticTransactionDB::open_default(path)?),
 		})
 	}
+	/// Flush the memtables to disk
+	pub async fn flush(&self) -> Result<(), Error> {
+		Ok(self.db.flush()?)
+	}
 	/// Start a new transaction
 	pub async fn transaction(&self, write: bool, _: bool) -> Result<Transaction, Error> {
 		// Activate the snapshot options
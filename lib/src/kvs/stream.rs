@@ -0,0 +1,250 @@
+//! The stream of key-value writes committed to a datastore.
+//!
+//! Every transaction which modifies the datastore is published as a single
+//! [`Batch`] of raw key-value writes, in the order in which the transactions
+//! were committed. Each batch is given a sequence number which increases by
+//! one for every committed transaction. The stream is used to replicate a
+//! datastore to follower nodes, which apply each batch in sequence order.
+//!
+//! When synchronous replication is enabled, a committing transaction waits
+//! until its batch has been acknowledged by the replication layer before
+//! returning to the caller.
+use super::{Key, Val};
+use crate::err::Error;
+use channel::{Receiver, Sender};
+use futures::lock::Mutex;
+use std::sync::atomic::{AtomicBool, AtomicU64, AtomicUsize, Ordering};
+
+/// The number of batches which can be buffered for each subscriber.
+///
+/// A subscriber which falls further behind than this is disconnected,
+/// so that a slow follower never blocks the datastore from committing
+/// transactions. Once disconnected, the follower should resynchronise
+/// from a snapshot before subscribing again.
+pub const CAPACITY: usize = 10_000;
+
+/// A single key-value write
+#[derive(Clone, Debug, Eq, PartialEq)]
+pub enum Write {
+	/// A key was inserted or updated
+	Set(Key, Val),
+	/// A key was deleted
+	Del(Key),
+}
+
+/// The writes made by a single committed transaction
+#[derive(Clone, Debug, Default, Eq, PartialEq)]
+pub struct Batch {
+	/// The position of this batch in the write stream
+	pub seq: u64,
+	/// The writes made by the transaction, in order
+	pub writes: Vec<Write>,
+}
+
+impl Batch {
+	/// Encode this batch as a length-prefixed binary frame
+	pub fn encode(&self) -> Vec<u8> {
+		let mut out = vec![0; 4];
+		out.extend(self.seq.to_be_bytes());
+		out.extend((self.writes.len() as u32).to_be_bytes());
+		for w in self.writes.iter() {
+			match w {
+				Write::Set(k, v) => {
+					out.push(b'S');
+					out.extend((k.len() as u32).to_be_bytes());
+					out.extend(k);
+					out.extend((v.len() as u32).to_be_bytes());
+					out.extend(v);
+				}
+				Write::Del(k) => {
+					out.push(b'D');
+					out.extend((k.len() as u32).to_be_bytes());
+					out.extend(k);
+				}
+			}
+		}
+		let len = (out.len() - 4) as u32;
+		out[..4].copy_from_slice(&len.to_be_bytes());
+		out
+	}
+
+	/// Decode a batch from the start of a buffer, returning the batch and
+	/// the number of bytes which were consumed. If the buffer does not yet
+	/// contain a complete frame, then this returns `None`.
+	pub fn decode(data: &[u8]) -> Result<Option<(Batch, usize)>, Error> {
+		// Check if the frame is complete
+		let len = match data.get(..4) {
+			Some(v) => u32::from_be_bytes([v[0], v[1], v[2], v[3]]) as usize,
+			None => return Ok(None),
+		};
+		let mut data = match data.get(4..4 + len) {
+			Some(v) => v,
+			None => return Ok(None),
+		};
+		// Decode the batch header
+		let seq = u64::from_be_bytes(take(&mut data, 8)?.try_into().unwrap());
+		let num = u32::from_be_bytes(take(&mut data, 4)?.try_into().unwrap());
+		// Decode each write
+		let mut writes = Vec::with_capacity(num as usize);
+		for _ in 0..num {
+			let tag = take(&mut data, 1)?[0];
+			let key = chunk(&mut data)?.to_vec();
+			match tag {
+				b'S' => writes.push(Write::Set(key, chunk(&mut data)?.to_vec())),
+				b'D' => writes.push(Write::Del(key)),
+				_ => return Err(Error::InvalidBatch),
+			}
+		}
+		// Check that the whole frame was used
+		if !data.is_empty() {
+			return Err(Error::InvalidBatch);
+		}
+		Ok(Some((
+			Batch {
+				seq,
+				writes,
+			},
+			4 + len,
+		)))
+	}
+}
+
+/// Take a number of bytes from the start of a buffer
+fn take<'a>(data: &mut &'a [u8], len: usize) -> Result<&'a [u8], Error> {
+	if data.len() < len {
+		return Err(Error::InvalidBatch);
+	}
+	let (v, rest) = data.split_at(len);
+	*data = rest;
+	Ok(v)
+}
+
+/// Take a length-prefixed chunk of bytes from the start of a buffer
+fn chunk<'a>(data: &mut &'a [u8]) -> Result<&'a [u8], Error> {
+	let len = take(data, 4)?;
+	let len = u32::from_be_bytes([len[0], len[1], len[2], len[3]]) as usize;
+	take(data, len)
+}
+
+/// The write stream of a datastore, which fans
+/// committed batches out to every subscriber.
+#[derive(Default)]
+pub(crate) struct Stream {
+	seq: AtomicU64,
+	count: AtomicUsize,
+	subs: Mutex<Vec<Sender<Batch>>>,
+	synchronous: AtomicBool,
+	acked: AtomicU64,
+	waiters: Mutex<Vec<(u64, Sender<bool>)>>,
+}
+
+impl Stream {
+	/// Subscribe to all subsequently committed batches
+	pub async fn subscribe(&self) -> Receiver<Batch> {
+		let (snd, rcv) = channel::bounded(CAPACITY);
+		let mut subs = self.subs.lock().await;
+		subs.push(snd);
+		self.count.store(subs.len(), Ordering::Release);
+		rcv
+	}
+	/// Check if writes need to be recorded for the write stream
+	pub fn is_active(&self) -> bool {
+		self.count.load(Ordering::Acquire) > 0 || self.synchronous.load(Ordering::Acquire)
+	}
+	/// Get the sequence number of the last committed batch
+	pub fn sequence(&self) -> u64 {
+		self.seq.load(Ordering::Acquire)
+	}
+	/// Publish the writes of a committed transaction, returning the
+	/// sequence number of the batch. A batch which is being replayed
+	/// from another datastore keeps its original sequence number. This
+	/// must be called while holding the commit lock.
+	pub async fn publish(&self, writes: Vec<Write>, replay: Option<u64>) -> u64 {
+		let seq = match replay {
+			Some(seq) => {
+				self.seq.store(seq, Ordering::Release);
+				seq
+			}
+			None => self.seq.fetch_add(1, Ordering::AcqRel) + 1,
+		};
+		let batch = Batch {
+			seq,
+			writes,
+		};
+		// Remove any closed or lagging subscribers
+		let mut subs = self.subs.lock().await;
+		subs.retain(|s| match s.try_send(batch.clone()) {
+			Ok(_) => true,
+			Err(_) => {
+				s.close();
+				false
+			}
+		});
+		self.count.store(subs.len(), Ordering::Release);
+		seq
+	}
+	/// Enable or disable synchronous replication
+	pub fn set_synchronous(&self, v: bool) {
+		self.synchronous.store(v, Ordering::Release);
+	}
+	/// Wait until a committed batch has been acknowledged,
+	/// if synchronous replication is enabled
+	pub async fn wait(&self, seq: u64) -> Result<(), Error> {
+		if !self.synchronous.load(Ordering::Acquire) {
+			return Ok(());
+		}
+		let rcv = {
+			let mut waiters = self.waiters.lock().await;
+			if self.acked.load(Ordering::Acquire) >= seq {
+				return Ok(());
+			}
+			let (snd, rcv) = channel::bounded(1);
+			waiters.push((seq, snd));
+			rcv
+		};
+		match rcv.recv().await {
+			Ok(true) => Ok(()),
+			_ => Err(Error::ReplicaUnacknowledged),
+		}
+	}
+	/// Acknowledge all batches up to and including a sequence number
+	pub async fn acknowledge(&self, seq: u64) {
+		let mut waiters = self.waiters.lock().await;
+		self.acked.fetch_max(seq, Ordering::AcqRel);
+		waiters.retain(|(v, s)| match *v <= seq {
+			true => {
+				let _ = s.try_send(true);
+				false
+			}
+			false => true,
+		});
+	}
+	/// Fail all batches which are waiting to be acknowledged
+	pub async fn unacknowledge(&self) {
+		let mut waiters = self.waiters.lock().await;
+		for (_, s) in waiters.drain(..) {
+			let _ = s.try_send(false);
+		}
+	}
+}
+
+#[cfg(test)]
+mod tests {
+
+	use super::*;
+
+	#[test]
+	fn encode_decode() {
+		let batch = Batch {
+			seq: 7,
+			writes: vec![Write::Set(b"one".to_vec(), b"1".to_vec()), Write::Del(b"two".to_vec())],
+		};
+		let mut out = batch.encode();
+		out.extend(batch.encode());
+		let (dec, len) = Batch::decode(&out).unwrap().unwrap();
+		assert_eq!(dec, batch);
+		assert_eq!(len, out.len() / 2);
+		assert!(Batch::decode(&out[..len - 1]).unwrap().is_none());
+		assert!(Batch::decode(&[0, 0, 0, 1, b'X']).is_err());
+	}
+}
@@ -40,6 +40,7 @@ mod rocksdb {
 	include!("multireader.rs");
 	include!("multiwriter_different_keys.rs");
 	include!("multiwriter_same_keys_conflict.rs");
+	include!("multiwriter_counters.rs");
 }
 
 #[cfg(feature = "kv-speedb")]
@@ -64,6 +65,7 @@ mod speedb {
 	include!("multireader.rs");
 	include!("multiwriter_different_keys.rs");
 	include!("multiwriter_same_keys_conflict.rs");
+	include!("multiwriter_counters.rs");
 }
 
 #[cfg(feature = "kv-tikv")]
@@ -92,6 +94,7 @@ mod tikv {
 	include!("multireader.rs");
 	include!("multiwriter_different_keys.rs");
 	include!("multiwriter_same_keys_conflict.rs");
+	include!("multiwriter_counters.rs");
 }
 
 #[cfg(feature = "kv-fdb")]
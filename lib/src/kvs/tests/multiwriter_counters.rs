@@ -0,0 +1,30 @@
+#[tokio::test]
+#[serial]
+async fn multiwriter_counters_do_not_conflict() {
+	// Create a new datastore
+	let ds = new_ds().await;
+	// Start counting the records of a table
+	let mut tx = ds.transaction(true, false).await.unwrap();
+	tx.set_cn("test", "test", "person", 1).await.unwrap();
+	tx.commit().await.unwrap();
+	// Create two writeable transactions, which change different shards
+	let mut tx1 = ds.transaction(true, false).await.unwrap();
+	tx1.shard = 0;
+	tx1.add_cn("test", "test", "person", 1).await.unwrap();
+	tx1.add_by("test", "test", "person", 10).await.unwrap();
+	let mut tx2 = ds.transaction(true, false).await.unwrap();
+	tx2.shard = 1;
+	tx2.add_cn("test", "test", "person", 1).await.unwrap();
+	tx2.add_by("test", "test", "person", 10).await.unwrap();
+	// Commit both writeable transactions
+	assert!(tx1.commit().await.is_ok());
+	assert!(tx2.commit().await.is_ok());
+	// Check that the counters were updated ok
+	let mut tx = ds.transaction(true, false).await.unwrap();
+	assert_eq!(tx.get_cn("test", "test", "person").await.unwrap(), Some(3));
+	assert_eq!(tx.get_by("test", "test", "person").await.unwrap(), 20);
+	// Check that setting the counter clears the shards
+	tx.set_cn("test", "test", "person", 0).await.unwrap();
+	assert_eq!(tx.get_cn("test", "test", "person").await.unwrap(), Some(0));
+	tx.cancel().await.unwrap();
+}
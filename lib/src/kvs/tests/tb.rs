@@ -40,7 +40,17 @@ pub(crate) mod table {
 			drop: false,
 			full: false,
 			view: None,
+			audit: None,
+			archive: false,
+			history: false,
+			soft: None,
+			id: None,
+			compression: None,
+			partition: None,
+			schema: None,
 			permissions: Default::default(),
+			comment: None,
+			relation: None,
 		};
 		match tx.set(&key, &value).await {
 			Ok(_) => {}
@@ -80,7 +90,17 @@ pub(crate) mod table {
 			drop: false,
 			full: false,
 			view: None,
+			audit: None,
+			archive: false,
+			history: false,
+			soft: None,
+			id: None,
+			compression: None,
+			partition: None,
+			schema: None,
 			permissions: Default::default(),
+			comment: None,
+			relation: None,
 		};
 		match tx.set(&key, &value).await {
 			Ok(_) => {}
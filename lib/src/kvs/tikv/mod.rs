@@ -1,14 +1,64 @@
 #![cfg(feature = "kv-tikv")]
 
+mod pool;
+
 use crate::err::Error;
 use crate::kvs::Key;
 use crate::kvs::Val;
+use pool::Pool;
 use std::ops::Range;
+use std::sync::Arc;
+use std::time::Duration;
 use tikv::CheckLevel;
 use tikv::TransactionOptions;
 
+/// The default number of clients which are connected to the cluster
+const POOL_SIZE: usize = 4;
+
+/// The default interval at which each client of the cluster is health checked
+const HEALTH_INTERVAL: Duration = Duration::from_secs(10);
+
+/// The default number of times a read is retried after a transient error
+const READ_RETRIES: u32 = 3;
+
+/// Retry a read which failed with a transient error, such as a lost
+/// connection or a stale region, as reads are safe to repeat
+macro_rules! retry {
+	($self:ident, $req:expr) => {{
+		let mut n = 0;
+		loop {
+			match $req.await {
+				Err(e) if transient(&e) && n < $self.retries => {
+					n += 1;
+					tokio::time::sleep(backoff(n)).await;
+				}
+				Err(e) if transient(&e) => {
+					$self.pool.failed(&$self.client);
+					break Err(e);
+				}
+				res => break res,
+			}
+		}
+	}};
+}
+
+/// Get the time to wait before the specified retry of a read
+fn backoff(attempt: u32) -> Duration {
+	Duration::from_millis(10 << attempt.min(8))
+}
+
+/// Check whether a request failed with an error which may not occur again
+fn transient(e: &tikv::Error) -> bool {
+	matches!(e, tikv::Error::Grpc(_) | tikv::Error::RegionError(_))
+}
+
 pub struct Datastore {
-	db: tikv::TransactionClient,
+	// The address of the cluster
+	path: String,
+	// The clients which are connected to the cluster
+	pool: Arc<Pool>,
+	// The number of times a read is retried after a transient error
+	retries: u32,
 }
 
 pub struct Transaction {
@@ -18,17 +68,34 @@ pub struct Transaction {
 	rw: bool,
 	// The distributed datastore transaction
 	tx: tikv::Transaction,
+	// The pool of the client which the transaction is pinned to
+	pool: Arc<Pool>,
+	// The client which started the transaction
+	client: Arc<tikv::TransactionClient>,
+	// The number of times a read is retried after a transient error
+	retries: u32,
 }
 
 impl Datastore {
 	/// Open a new database
 	pub async fn new(path: &str) -> Result<Datastore, Error> {
-		match tikv::TransactionClient::new(vec![path]).await {
-			Ok(db) => Ok(Datastore {
-				db,
-			}),
-			Err(e) => Err(Error::Ds(e.to_string())),
-		}
+		Ok(Datastore {
+			path: path.to_owned(),
+			pool: Arc::new(Pool::with(path, POOL_SIZE, HEALTH_INTERVAL).await?),
+			retries: READ_RETRIES,
+		})
+	}
+	/// Connect a number of clients to the cluster, which are health checked
+	/// at an interval, in place of the clients which are connected
+	pub async fn configure(
+		&mut self,
+		size: usize,
+		interval: Duration,
+		retries: u32,
+	) -> Result<(), Error> {
+		self.pool = Arc::new(Pool::with(&self.path, size, interval).await?);
+		self.retries = retries;
+		Ok(())
 	}
 	/// Start a new transaction
 	pub async fn transaction(&self, write: bool, lock: bool) -> Result<Transaction, Error> {
@@ -44,14 +111,22 @@ impl Datastore {
 		if !write {
 			opt = opt.read_only();
 		}
+		// Pin the transaction to a client of the pool
+		let client = self.pool.client().await?;
 		// Create a new distributed transaction
-		match self.db.begin_with_options(opt).await {
+		match client.begin_with_options(opt).await {
 			Ok(tx) => Ok(Transaction {
 				ok: false,
 				rw: write,
 				tx,
+				pool: self.pool.clone(),
+				client,
+				retries: self.retries,
 			}),
-			Err(e) => Err(Error::Tx(e.to_string())),
+			Err(e) => {
+				self.pool.failed(&client);
+				Err(Error::Tx(e.to_string()))
+			}
 		}
 	}
 }
@@ -103,7 +178,8 @@ impl Transaction {
 			return Err(Error::TxFinished);
 		}
 		// Check the key
-		let res = self.tx.key_exists(key.into()).await?;
+		let key = key.into();
+		let res = retry!(self, self.tx.key_exists(key.clone()))?;
 		// Return result
 		Ok(res)
 	}
@@ -117,7 +193,8 @@ impl Transaction {
 			return Err(Error::TxFinished);
 		}
 		// Get the key
-		let res = self.tx.get(key.into()).await?;
+		let key = key.into();
+		let res = retry!(self, self.tx.get(key.clone()))?;
 		// Return result
 		Ok(res)
 	}
@@ -159,7 +236,7 @@ impl Transaction {
 		// Get the val
 		let val = val.into();
 		// Set the key if empty
-		match self.tx.key_exists(key.clone()).await? {
+		match retry!(self, self.tx.key_exists(key.clone()))? {
 			false => self.tx.put(key, val).await?,
 			_ => return Err(Error::TxKeyAlreadyExists),
 		};
@@ -187,7 +264,7 @@ impl Transaction {
 		// Get the check
 		let chk = chk.map(Into::into);
 		// Delete the key
-		match (self.tx.get(key.clone()).await?, chk) {
+		match (retry!(self, self.tx.get(key.clone()))?, chk) {
 			(Some(v), Some(w)) if v == w => self.tx.put(key, val).await?,
 			(None, None) => self.tx.put(key, val).await?,
 			_ => return Err(Error::TxConditionNotMet),
@@ -232,7 +309,7 @@ impl Transaction {
 		// Get the check
 		let chk = chk.map(Into::into);
 		// Delete the key
-		match (self.tx.get(key.clone()).await?, chk) {
+		match (retry!(self, self.tx.get(key.clone()))?, chk) {
 			(Some(v), Some(w)) if v == w => self.tx.delete(key).await?,
 			(None, None) => self.tx.delete(key).await?,
 			_ => return Err(Error::TxConditionNotMet),
@@ -255,9 +332,81 @@ impl Transaction {
 			end: rng.end.into(),
 		};
 		// Scan the keys
-		let res = self.tx.scan(rng, limit).await?;
+		let res = retry!(self, self.tx.scan(rng.clone(), limit))?;
 		let res = res.map(|kv| (Key::from(kv.0), kv.1)).collect();
 		// Return result
 		Ok(res)
 	}
 }
+
+#[cfg(test)]
+mod tests {
+
+	use super::pool::tests::Fake;
+	use super::*;
+	use std::sync::atomic::{AtomicU32, Ordering};
+
+	/// A transaction which is pinned to a client of the pool
+	struct Pinned {
+		pool: Arc<Pool<Fake>>,
+		client: Arc<Fake>,
+		retries: u32,
+	}
+
+	async fn pinned() -> Pinned {
+		let pool = Pool::<Fake>::with("up", 1, Duration::from_secs(3600)).await.unwrap();
+		let client = pool.client().await.unwrap();
+		Pinned {
+			pool: Arc::new(pool),
+			client,
+			retries: READ_RETRIES,
+		}
+	}
+
+	/// A read which fails with a transient error a number of times
+	async fn read(count: &AtomicU32, fails: u32) -> Result<u32, tikv::Error> {
+		match count.fetch_add(1, Ordering::Relaxed) {
+			n if n < fails => Err(tikv::Error::RegionError(Default::default())),
+			n => Ok(n),
+		}
+	}
+
+	#[tokio::test]
+	async fn transient_errors_are_retried() {
+		let tx = pinned().await;
+		let count = AtomicU32::new(0);
+		let res = retry!(tx, read(&count, 2));
+		assert_eq!(res.unwrap(), 2);
+	}
+
+	#[tokio::test]
+	async fn persistent_errors_fail_the_client() {
+		let tx = pinned().await;
+		let count = AtomicU32::new(0);
+		let res = retry!(tx, read(&count, u32::MAX));
+		assert!(matches!(res, Err(tikv::Error::RegionError(_))));
+		assert_eq!(count.load(Ordering::Relaxed), READ_RETRIES + 1);
+		// The client is checked, and replaced, before it is next used
+		tx.client.up.store(false, Ordering::Release);
+		assert!(!Arc::ptr_eq(&tx.client, &tx.pool.client().await.unwrap()));
+	}
+
+	#[tokio::test]
+	async fn other_errors_are_not_retried() {
+		let tx = pinned().await;
+		let count = AtomicU32::new(0);
+		let res = retry!(tx, async {
+			count.fetch_add(1, Ordering::Relaxed);
+			Err::<(), _>(tikv::Error::DuplicateKeyInsertion)
+		});
+		assert!(matches!(res, Err(tikv::Error::DuplicateKeyInsertion)));
+		assert_eq!(count.load(Ordering::Relaxed), 1);
+	}
+
+	#[test]
+	fn backoff_is_clamped() {
+		assert_eq!(backoff(1), Duration::from_millis(20));
+		assert_eq!(backoff(3), Duration::from_millis(80));
+		assert_eq!(backoff(u32::MAX), Duration::from_millis(2560));
+	}
+}
@@ -0,0 +1,215 @@
+//! A pool of clients which are connected to a TiKV cluster.
+//!
+//! Transactions are spread across the clients of the pool in turn, and each
+//! transaction stays pinned to the client which started it, so that a single
+//! SurrealQL transaction always maps to a single TiKV transaction. A client
+//! is health checked before it is used, if it was last checked longer ago
+//! than the health check interval, or if one of its requests failed, and it
+//! is reconnected if the check fails.
+use crate::err::Error;
+use async_trait::async_trait;
+use std::sync::atomic::{AtomicBool, AtomicUsize, Ordering};
+use std::sync::{Arc, Mutex};
+use std::time::{Duration, Instant};
+use tikv::TransactionClient;
+
+/// A client which can be kept in the pool
+#[async_trait]
+pub(super) trait Client: Send + Sync + Sized {
+	/// Connect a new client to the cluster
+	async fn connect(path: &str) -> Result<Self, Error>;
+	/// Check that the client can reach the cluster
+	async fn ping(&self) -> bool;
+}
+
+#[async_trait]
+impl Client for TransactionClient {
+	async fn connect(path: &str) -> Result<Self, Error> {
+		match TransactionClient::new(vec![path]).await {
+			Ok(v) => Ok(v),
+			Err(e) => Err(Error::Ds(e.to_string())),
+		}
+	}
+	async fn ping(&self) -> bool {
+		self.current_timestamp().await.is_ok()
+	}
+}
+
+struct Slot<C> {
+	client: Mutex<Arc<C>>,
+	healthy: AtomicBool,
+	checked: Mutex<Instant>,
+}
+
+pub(super) struct Pool<C = TransactionClient> {
+	path: String,
+	slots: Vec<Slot<C>>,
+	next: AtomicUsize,
+	interval: Duration,
+}
+
+impl<C: Client> Pool<C> {
+	/// Connect a number of clients to the cluster, which are health
+	/// checked at the specified interval
+	pub(super) async fn with(
+		path: &str,
+		size: usize,
+		interval: Duration,
+	) -> Result<Pool<C>, Error> {
+		let mut slots = vec![];
+		for _ in 0..size.max(1) {
+			slots.push(Slot {
+				client: Mutex::new(Arc::new(C::connect(path).await?)),
+				healthy: AtomicBool::new(true),
+				checked: Mutex::new(Instant::now()),
+			});
+		}
+		Ok(Pool {
+			path: path.to_owned(),
+			slots,
+			next: AtomicUsize::new(0),
+			interval,
+		})
+	}
+	/// Get the next healthy client of the pool
+	pub(super) async fn client(&self) -> Result<Arc<C>, Error> {
+		let start = self.next.fetch_add(1, Ordering::Relaxed);
+		let mut err = None;
+		for i in 0..self.slots.len() {
+			match self.check(&self.slots[(start + i) % self.slots.len()]).await {
+				Ok(v) => return Ok(v),
+				Err(e) => err = Some(e),
+			}
+		}
+		Err(err.unwrap_or_else(|| Error::Ds("The connection pool is empty".to_owned())))
+	}
+	/// Mark the client of a failed request as unhealthy, so that it is
+	/// checked again before it is next used
+	pub(super) fn failed(&self, client: &Arc<C>) {
+		for slot in self.slots.iter() {
+			if Arc::ptr_eq(&slot.client.lock().unwrap(), client) {
+				slot.healthy.store(false, Ordering::Release);
+			}
+		}
+	}
+	/// Check the health of a client if it is due a check, reconnecting
+	/// the client if the check fails
+	async fn check(&self, slot: &Slot<C>) -> Result<Arc<C>, Error> {
+		let client = slot.client.lock().unwrap().clone();
+		// Check if the client is due a health check
+		let due = {
+			let mut checked = slot.checked.lock().unwrap();
+			match slot.healthy.load(Ordering::Acquire) && checked.elapsed() < self.interval {
+				true => false,
+				false => {
+					*checked = Instant::now();
+					true
+				}
+			}
+		};
+		if !due {
+			return Ok(client);
+		}
+		// Check that the client can reach the cluster
+		if client.ping().await {
+			slot.healthy.store(true, Ordering::Release);
+			return Ok(client);
+		}
+		slot.healthy.store(false, Ordering::Release);
+		warn!(target: crate::kvs::LOG, "Reconnecting an unhealthy TiKV client");
+		// Reconnect the client
+		let client = Arc::new(C::connect(&self.path).await?);
+		if !client.ping().await {
+			return Err(Error::Ds("Unable to reach the TiKV cluster".to_owned()));
+		}
+		*slot.client.lock().unwrap() = client.clone();
+		slot.healthy.store(true, Ordering::Release);
+		Ok(client)
+	}
+}
+
+#[cfg(test)]
+pub(super) mod tests {
+
+	use super::*;
+
+	/// A client which is either able or unable to reach the cluster
+	pub(in super::super) struct Fake {
+		pub(in super::super) up: AtomicBool,
+	}
+
+	#[async_trait]
+	impl Client for Fake {
+		async fn connect(path: &str) -> Result<Self, Error> {
+			match path {
+				"down" => Err(Error::Ds("Unable to connect".to_owned())),
+				_ => Ok(Fake {
+					up: AtomicBool::new(true),
+				}),
+			}
+		}
+		async fn ping(&self) -> bool {
+			self.up.load(Ordering::Acquire)
+		}
+	}
+
+	const HOUR: Duration = Duration::from_secs(3600);
+
+	#[tokio::test]
+	async fn clients_are_used_in_turn() {
+		let pool = Pool::<Fake>::with("up", 2, HOUR).await.unwrap();
+		let one = pool.client().await.unwrap();
+		let two = pool.client().await.unwrap();
+		assert!(!Arc::ptr_eq(&one, &two));
+		assert!(Arc::ptr_eq(&one, &pool.client().await.unwrap()));
+	}
+
+	#[tokio::test]
+	async fn healthy_clients_are_checked_when_due() {
+		let pool = Pool::<Fake>::with("up", 1, HOUR).await.unwrap();
+		let one = pool.client().await.unwrap();
+		one.up.store(false, Ordering::Release);
+		// The client is not checked until the interval has passed
+		assert!(Arc::ptr_eq(&one, &pool.client().await.unwrap()));
+		// The client is checked once it is due, and is reconnected
+		let pool = Pool::<Fake>::with("up", 1, Duration::ZERO).await.unwrap();
+		let one = pool.client().await.unwrap();
+		one.up.store(false, Ordering::Release);
+		let two = pool.client().await.unwrap();
+		assert!(!Arc::ptr_eq(&one, &two));
+		assert!(Arc::ptr_eq(&two, &pool.client().await.unwrap()));
+	}
+
+	#[tokio::test]
+	async fn failed_clients_are_reconnected() {
+		let pool = Pool::<Fake>::with("up", 1, HOUR).await.unwrap();
+		let one = pool.client().await.unwrap();
+		// A failed client which can still reach the cluster is kept
+		pool.failed(&one);
+		assert!(Arc::ptr_eq(&one, &pool.client().await.unwrap()));
+		// A failed client which can not reach the cluster is replaced
+		one.up.store(false, Ordering::Release);
+		pool.failed(&one);
+		let two = pool.client().await.unwrap();
+		assert!(!Arc::ptr_eq(&one, &two));
+		assert!(two.ping().await);
+	}
+
+	#[tokio::test]
+	async fn unreachable_cluster_fails() {
+		assert!(Pool::<Fake>::with("down", 1, HOUR).await.is_err());
+		// A client which can not be reconnected is skipped
+		let mut pool = Pool::<Fake>::with("up", 2, HOUR).await.unwrap();
+		let one = pool.client().await.unwrap();
+		let two = pool.client().await.unwrap();
+		one.up.store(false, Ordering::Release);
+		pool.failed(&one);
+		pool.path = "down".to_owned();
+		assert!(Arc::ptr_eq(&two, &pool.client().await.unwrap()));
+		assert!(Arc::ptr_eq(&two, &pool.client().await.unwrap()));
+		// The pool fails once no client can reach the cluster
+		two.up.store(false, Ordering::Release);
+		pool.failed(&two);
+		assert!(pool.client().await.is_err());
+	}
+}
@@ -1,7 +1,14 @@
+use super::archive::Archive;
+use super::conflict::Tracked;
 use super::kv::Add;
 use super::kv::Convert;
+use super::stream::{Stream, Write};
+use super::Datastore;
 use super::Key;
 use super::Val;
+use crate::changes::{Change, Feed};
+use crate::dbs::{Metrics, Op, ResultCache};
+use crate::dbs::{Webhook, Webhooks};
 use crate::err::Error;
 use crate::key::thing;
 use crate::kvs::cache::Cache;
@@ -14,6 +21,7 @@ use crate::sql::thing::Thing;
 use crate::sql::Value;
 use channel::Sender;
 use sql::permission::Permissions;
+use sql::statements::define::Compression;
 use sql::statements::DefineAnalyzerStatement;
 use sql::statements::DefineDatabaseStatement;
 use sql::statements::DefineEventStatement;
@@ -21,16 +29,20 @@ use sql::statements::DefineFieldStatement;
 use sql::statements::DefineFunctionStatement;
 use sql::statements::DefineIndexStatement;
 use sql::statements::DefineLoginStatement;
+use sql::statements::DefineMigrationStatement;
 use sql::statements::DefineNamespaceStatement;
 use sql::statements::DefineParamStatement;
 use sql::statements::DefineScopeStatement;
+use sql::statements::DefineSequenceStatement;
 use sql::statements::DefineTableStatement;
 use sql::statements::DefineTokenStatement;
 use sql::statements::LiveStatement;
+use std::collections::HashMap;
 use std::fmt;
 use std::fmt::Debug;
 use std::ops::Range;
 use std::sync::Arc;
+use tracing::instrument;
 
 #[cfg(debug_assertions)]
 const LOG: &str = "surrealdb::txn";
@@ -40,6 +52,34 @@ const LOG: &str = "surrealdb::txn";
 pub struct Transaction {
 	pub(super) inner: Inner,
 	pub(super) cache: Cache,
+	pub(super) feed: Arc<Feed>,
+	pub(super) changes: Vec<Change>,
+	pub(super) stream: Arc<Stream>,
+	pub(super) writes: Vec<Write>,
+	pub(super) replay: Option<u64>,
+	pub(super) archive: Option<Arc<dyn Archive>>,
+	pub(super) metrics: Arc<Metrics>,
+	pub(super) hooks: Arc<Webhooks>,
+	pub(super) webhooks: Vec<Webhook>,
+	pub(super) notifications: Vec<Notification>,
+	pub(super) results: Arc<ResultCache>,
+	pub(super) invalidated: Vec<(String, String, String)>,
+	pub(super) tracked: Option<Tracked>,
+	pub(super) max_document_size: usize,
+	pub(super) chunk_size: usize,
+	pub(super) shard: u8,
+	// Whether the changes to records are recorded for the journal
+	pub(super) journaled: bool,
+	// The records and bytes of record data counted in each namespace
+	pub(super) usage: HashMap<String, (i64, i64)>,
+}
+
+/// A live query notification, which is sent once the transaction commits
+pub(super) struct Notification {
+	chn: Sender<Value>,
+	// The position of the change which caused the notification
+	change: Option<usize>,
+	msg: Value,
 }
 
 #[allow(clippy::large_enum_variant)]
@@ -56,6 +96,7 @@ pub(super) enum Inner {
 	TiKV(super::tikv::Transaction),
 	#[cfg(feature = "kv-fdb")]
 	FDB(super::fdb::Transaction),
+	Engine(Box<dyn super::engine::EngineTransaction>),
 }
 
 impl fmt::Display for Transaction {
@@ -74,6 +115,7 @@ impl fmt::Display for Transaction {
 			Inner::TiKV(_) => write!(f, "tikv"),
 			#[cfg(feature = "kv-fdb")]
 			Inner::FDB(_) => write!(f, "fdb"),
+			Inner::Engine(_) => write!(f, "engine"),
 			#[allow(unreachable_patterns)]
 			_ => unreachable!(),
 		}
@@ -125,6 +167,10 @@ impl Transaction {
 				inner: Inner::FDB(v),
 				..
 			} => v.closed(),
+			Transaction {
+				inner: Inner::Engine(v),
+				..
+			} => v.closed(),
 			#[allow(unreachable_patterns)]
 			_ => unreachable!(),
 		}
@@ -136,6 +182,14 @@ impl Transaction {
 	pub async fn cancel(&mut self) -> Result<(), Error> {
 		#[cfg(debug_assertions)]
 		trace!(target: LOG, "Cancel");
+		self.metrics.operation(Op::Cancel);
+		// Discard any recorded changes
+		self.changes.clear();
+		self.writes.clear();
+		self.webhooks.clear();
+		self.notifications.clear();
+		self.invalidated.clear();
+		self.tracked = None;
 		match self {
 			#[cfg(feature = "kv-mem")]
 			Transaction {
@@ -167,6 +221,10 @@ impl Transaction {
 				inner: Inner::FDB(v),
 				..
 			} => v.cancel().await,
+			Transaction {
+				inner: Inner::Engine(v),
+				..
+			} => v.cancel().await,
 			#[allow(unreachable_patterns)]
 			_ => unreachable!(),
 		}
@@ -175,9 +233,152 @@ impl Transaction {
 	/// Commit a transaction.
 	///
 	/// This attempts to commit all changes made within the transaction.
+	#[instrument(name = "commit", level = "debug", skip_all)]
 	pub async fn commit(&mut self) -> Result<(), Error> {
 		#[cfg(debug_assertions)]
 		trace!(target: LOG, "Commit");
+		self.metrics.operation(Op::Commit);
+		let res = self.commit_changes().await;
+		if let Err(Error::TxConflict) = res {
+			self.metrics.conflict();
+		}
+		// Deliver any webhooks once the transaction has committed
+		if res.is_ok() && !self.webhooks.is_empty() {
+			self.hooks.publish(std::mem::take(&mut self.webhooks));
+		}
+		// Send any live query notifications once the transaction has committed
+		for v in std::mem::take(&mut self.notifications) {
+			if res.is_ok() {
+				// Notifications are dropped if the session is not keeping up
+				let _ = v.chn.try_send(v.msg);
+			}
+		}
+		// Invalidate the cached results of any written tables once more, in
+		// case the results were cached again before the transaction committed
+		for (ns, db, tb) in std::mem::take(&mut self.invalidated) {
+			self.results.invalidate(&ns, &db, &tb);
+		}
+		res
+	}
+
+	/// Send a webhook once this transaction commits
+	pub(crate) fn webhook(&mut self, hook: Webhook) {
+		self.webhooks.push(hook);
+	}
+
+	/// Send a live query notification for a change to a record once this
+	/// transaction commits. The notification is given a `token`, which is
+	/// the sequence number of the change in the change log, so that the
+	/// subscriber can resume the live query from the last notification
+	/// which it received.
+	#[allow(clippy::too_many_arguments)]
+	pub(crate) fn notify(
+		&mut self,
+		chn: Sender<Value>,
+		ns: &str,
+		db: &str,
+		id: &Thing,
+		before: &Value,
+		after: &Value,
+		msg: Value,
+	) {
+		// The change is recorded even if the change log has no subscribers
+		let change =
+			match self.changes.iter().rposition(|v| v.ns == ns && v.db == db && v.id == *id) {
+				Some(v) => v,
+				None => {
+					self.push_change(ns, db, id, before, after);
+					self.changes.len() - 1
+				}
+			};
+		self.notifications.push(Notification {
+			chn,
+			change: Some(change),
+			msg,
+		});
+	}
+
+	/// Send a live query notification once this transaction commits
+	pub(crate) fn notify_replay(&mut self, chn: Sender<Value>, msg: Value) {
+		self.notifications.push(Notification {
+			chn,
+			change: None,
+			msg,
+		});
+	}
+
+	/// Get any changes in the change log after the specified sequence number,
+	/// or `None` if some of those changes are no longer retained
+	pub(crate) async fn changes_since(&self, seq: u64) -> Option<Vec<Change>> {
+		self.feed.since(seq).await
+	}
+
+	/// Invalidate the cached results which selected from a table, both now
+	/// and once this transaction commits
+	pub(crate) fn invalidate(&mut self, ns: &str, db: &str, tb: &str) {
+		if self.results.is_enabled() {
+			self.results.invalidate(ns, db, tb);
+			if !self.invalidated.iter().any(|(n, d, t)| n == ns && d == db && t == tb) {
+				self.invalidated.push((ns.to_owned(), db.to_owned(), tb.to_owned()));
+			}
+		}
+	}
+
+	/// Commit the transaction, publishing any recorded changes and writes.
+	async fn commit_changes(&mut self) -> Result<(), Error> {
+		// Check if any changes or writes were recorded
+		if self.changes.is_empty() && self.writes.is_empty() && self.replay.is_none() {
+			return self.commit_inner().await;
+		}
+		// Publish the changes and writes in commit order
+		let seq = {
+			let feed = self.feed.clone();
+			let _lock = feed.lock().await;
+			self.commit_inner().await?;
+			if !self.changes.is_empty() {
+				let first = feed.publish(std::mem::take(&mut self.changes)).await;
+				// Give each notification the sequence number of its change
+				for v in self.notifications.iter_mut() {
+					if let (Some(i), Value::Object(msg)) = (v.change, &mut v.msg) {
+						msg.insert("token".to_owned(), Value::from(first + i as u64));
+					}
+				}
+			}
+			match self.writes.is_empty() && self.replay.is_none() {
+				true => None,
+				false => {
+					Some(self.stream.publish(std::mem::take(&mut self.writes), self.replay).await)
+				}
+			}
+		};
+		// Wait for the writes to be replicated
+		match (seq, self.replay) {
+			(Some(seq), None) => self.stream.wait(seq).await,
+			_ => Ok(()),
+		}
+	}
+
+	/// Commit the underlying storage engine transaction, unless it conflicts
+	/// with a transaction which committed since it started, for any engine
+	/// which does not detect conflicts.
+	async fn commit_inner(&mut self) -> Result<(), Error> {
+		let mut tracked = match self.tracked.take() {
+			Some(v) => v,
+			None => return self.commit_engine().await,
+		};
+		let conflicts = tracked.conflicts();
+		let _lock = conflicts.lock().await;
+		if conflicts.conflicts(&tracked) {
+			let _ = self.cancel().await;
+			return Err(Error::TxConflict);
+		}
+		self.commit_engine().await?;
+		conflicts.committed(&mut tracked);
+		Ok(())
+	}
+
+	/// Commit the transaction of the underlying storage engine.
+	async fn commit_engine(&mut self) -> Result<(), Error> {
 		match self {
 			#[cfg(feature = "kv-mem")]
 			Transaction {
@@ -209,6 +410,10 @@ impl Transaction {
 				inner: Inner::FDB(v),
 				..
 			} => v.commit().await,
+			Transaction {
+				inner: Inner::Engine(v),
+				..
+			} => v.commit().await,
 			#[allow(unreachable_patterns)]
 			_ => unreachable!(),
 		}
@@ -222,7 +427,13 @@ impl Transaction {
 	{
 		#[cfg(debug_assertions)]
 		trace!(target: LOG, "Del {:?}", key);
-		match self {
+		self.metrics.operation(Op::Del);
+		let key: Key = key.into();
+		let rec = self.stream.is_active().then(|| key.clone());
+		if let Some(v) = &mut self.tracked {
+			v.write(&key);
+		}
+		let res = match self {
 			#[cfg(feature = "kv-mem")]
 			Transaction {
 				inner: Inner::Mem(v),
@@ -253,9 +464,18 @@ impl Transaction {
 				inner: Inner::FDB(v),
 				..
 			} => v.del(key).await,
+			Transaction {
+				inner: Inner::Engine(v),
+				..
+			} => v.del(key).await,
 			#[allow(unreachable_patterns)]
 			_ => unreachable!(),
+		};
+		// Record the write for replication
+		if let (Ok(_), Some(key)) = (&res, rec) {
+			self.record_write(Write::Del(key));
 		}
+		res
 	}
 
 	/// Check if a key exists in the datastore.
@@ -266,6 +486,7 @@ impl Transaction {
 	{
 		#[cfg(debug_assertions)]
 		trace!(target: LOG, "Exi {:?}", key);
+		self.metrics.operation(Op::Exi);
 		match self {
 			#[cfg(feature = "kv-mem")]
 			Transaction {
@@ -297,19 +518,37 @@ impl Transaction {
 				inner: Inner::FDB(v),
 				..
 			} => v.exi(key).await,
+			Transaction {
+				inner: Inner::Engine(v),
+				..
+			} => v.exi(key.into()).await,
 			#[allow(unreachable_patterns)]
 			_ => unreachable!(),
 		}
 	}
 
 	/// Fetch a key from the datastore.
-	#[allow(unused_variables)]
 	pub async fn get<K>(&mut self, key: K) -> Result<Option<Val>, Error>
+	where
+		K: Into<Key> + Debug,
+	{
+		let key: Key = key.into();
+		match self.get_raw(key.clone()).await? {
+			Some(v) => self.fall_through(&key, v).await.map(Some),
+			None => Ok(None),
+		}
+	}
+
+	/// Fetch a key from the datastore, without reassembling the chunks of
+	/// a large record, or fetching its value from the archive tier.
+	#[allow(unused_variables)]
+	async fn get_raw<K>(&mut self, key: K) -> Result<Option<Val>, Error>
 	where
 		K: Into<Key> + Debug,
 	{
 		#[cfg(debug_assertions)]
 		trace!(target: LOG, "Get {:?}", key);
+		self.metrics.operation(Op::Get);
 		match self {
 			#[cfg(feature = "kv-mem")]
 			Transaction {
@@ -341,11 +580,164 @@ impl Transaction {
 				inner: Inner::FDB(v),
 				..
 			} => v.get(key).await,
+			Transaction {
+				inner: Inner::Engine(v),
+				..
+			} => v.get(key.into()).await,
 			#[allow(unreachable_patterns)]
 			_ => unreachable!(),
 		}
 	}
 
+	/// Get the value of a key which was fetched from the datastore, by
+	/// falling through to the archive tier if the record has been archived,
+	/// reassembling the chunks of a large record, and decompressing it.
+	async fn fall_through(&mut self, key: &[u8], val: Val) -> Result<Val, Error> {
+		// Fall through to the archive tier
+		let val = match super::archive::archived(&val) {
+			Some(name) => super::archive::fetch(self.archive.as_ref(), name).await?,
+			None => val,
+		};
+		// Reassemble the chunks of a large record
+		let val = match super::chunk::chunked(&val) {
+			Some(count) => self.get_chunks(key, count).await?,
+			None => val,
+		};
+		// Decompress a compressed record
+		match super::compress::compressed(&val) {
+			true => super::compress::decompress(&val),
+			false => Ok(val),
+		}
+	}
+
+	/// Get the values of a set of keys which were fetched from the datastore
+	async fn fall_through_all(&mut self, res: Vec<(Key, Val)>) -> Result<Vec<(Key, Val)>, Error> {
+		let mut out = Vec::with_capacity(res.len());
+		for (k, v) in res.into_iter() {
+			let v = self.fall_through(&k, v).await?;
+			out.push((k, v));
+		}
+		Ok(out)
+	}
+
+	/// Reassemble the data of a record which was split into chunks
+	async fn get_chunks(&mut self, key: &[u8], count: u32) -> Result<Val, Error> {
+		let key = thing::Thing::decode(key)?;
+		let beg = crate::key::ck::new(key.ns, key.db, key.tb, &key.id, 0);
+		let end = crate::key::ck::new(key.ns, key.db, key.tb, &key.id, count);
+		let res = self.scan_raw(beg..end, count).await?;
+		if res.len() != count as usize {
+			return Err(Error::Tx(format!(
+				"The record {} is missing {} of its {} chunks",
+				Thing::from((key.tb, key.id.clone())),
+				count as usize - res.len(),
+				count
+			)));
+		}
+		Ok(res.into_iter().flat_map(|(_, v)| v).collect())
+	}
+
+	/// Delete the chunks of a record, from one chunk number up to another
+	async fn del_chunks(
+		&mut self,
+		ns: &str,
+		db: &str,
+		rid: &Thing,
+		rng: Range<u32>,
+	) -> Result<(), Error> {
+		for nr in rng {
+			self.del(crate::key::ck::new(ns, db, &rid.tb, &rid.id, nr)).await?;
+		}
+		Ok(())
+	}
+
+	/// Store the data of a record, compressing the data if a compression
+	/// algorithm is specified, splitting the data into chunks if it is
+	/// larger than the chunk size of the datastore, and removing any chunks
+	/// of the previous data of the record which are no longer needed. The
+	/// change in the size of the record data is returned.
+	pub async fn set_record(
+		&mut self,
+		ns: &str,
+		db: &str,
+		rid: &Thing,
+		val: Val,
+		new: bool,
+		compression: Option<&Compression>,
+	) -> Result<i64, Error> {
+		// Check the size of the record data
+		if val.len() > self.max_document_size {
+			return Err(Error::DocumentTooLarge {
+				thing: rid.to_string(),
+				size: val.len(),
+				max: self.max_document_size,
+			});
+		}
+		// Get the size of the record data
+		let size = val.len() as i64;
+		// Compress the record data
+		let val = match compression {
+			Some(v) => super::compress::compress(v, val)?,
+			None => val,
+		};
+		// Get the number of chunks, and the size, of the previous data
+		let key = thing::new(ns, db, &rid.tb, &rid.id);
+		let prev = match new {
+			true => None,
+			false => self.get_raw(key.clone()).await?,
+		};
+		let (old, size) = match prev {
+			Some(v) => {
+				(super::chunk::chunked(&v).unwrap_or(0), size - self.size_of(&key, v).await?)
+			}
+			None => (0, size),
+		};
+		// Store small records in a single key
+		if val.len() <= self.chunk_size {
+			self.del_chunks(ns, db, rid, 0..old).await?;
+			self.set(key, val).await?;
+			return Ok(size);
+		}
+		// Split large records into chunks
+		let mut count = 0;
+		for chunk in val.chunks(self.chunk_size) {
+			self.set(crate::key::ck::new(ns, db, &rid.tb, &rid.id, count), chunk).await?;
+			count += 1;
+		}
+		self.del_chunks(ns, db, rid, count..old).await?;
+		self.set(key, super::chunk::marker(count)).await?;
+		Ok(size)
+	}
+
+	/// Delete the data of a record, along with any of its chunks. The
+	/// size of the record data which was deleted is returned.
+	pub async fn del_record(&mut self, ns: &str, db: &str, rid: &Thing) -> Result<i64, Error> {
+		let key = thing::new(ns, db, &rid.tb, &rid.id);
+		let size = match self.get_raw(key.clone()).await? {
+			Some(v) => {
+				if let Some(count) = super::chunk::chunked(&v) {
+					self.del_chunks(ns, db, rid, 0..count).await?;
+				}
+				self.size_of(&key, v).await?
+			}
+			None => 0,
+		};
+		self.del(key).await?;
+		Ok(size)
+	}
+
+	/// Get the size of the data of a record from its stored value. Only
+	/// records which are archived, chunked, or compressed are read in full.
+	async fn size_of(&mut self, key: &[u8], val: Val) -> Result<i64, Error> {
+		let stored = super::archive::archived(&val).is_some()
+			|| super::chunk::chunked(&val).is_some()
+			|| super::compress::compressed(&val);
+		match stored {
+			true => Ok(self.fall_through(key, val).await?.len() as i64),
+			false => Ok(val.len() as i64),
+		}
+	}
+
 	/// Insert or update a key in the datastore.
 	#[allow(unused_variables)]
 	pub async fn set<K, V>(&mut self, key: K, val: V) -> Result<(), Error>
@@ -355,7 +747,14 @@ impl Transaction {
 	{
 		#[cfg(debug_assertions)]
 		trace!(target: LOG, "Set {:?} => {:?}", key, val);
-		match self {
+		self.metrics.operation(Op::Set);
+		let key: Key = key.into();
+		let val: Val = val.into();
+		let rec = self.stream.is_active().then(|| (key.clone(), val.clone()));
+		if let Some(v) = &mut self.tracked {
+			v.write(&key);
+		}
+		let res = match self {
 			#[cfg(feature = "kv-mem")]
 			Transaction {
 				inner: Inner::Mem(v),
@@ -386,9 +785,18 @@ impl Transaction {
 				inner: Inner::FDB(v),
 				..
 			} => v.set(key, val).await,
+			Transaction {
+				inner: Inner::Engine(v),
+				..
+			} => v.set(key, val).await,
 			#[allow(unreachable_patterns)]
 			_ => unreachable!(),
+		};
+		// Record the write for replication
+		if let (Ok(_), Some((key, val))) = (&res, rec) {
+			self.record_write(Write::Set(key, val));
 		}
+		res
 	}
 
 	/// Insert a key if it doesn't exist in the datastore.
@@ -400,7 +808,14 @@ impl Transaction {
 	{
 		#[cfg(debug_assertions)]
 		trace!(target: LOG, "Put {:?} => {:?}", key, val);
-		match self {
+		self.metrics.operation(Op::Put);
+		let key: Key = key.into();
+		let val: Val = val.into();
+		let rec = self.stream.is_active().then(|| (key.clone(), val.clone()));
+		if let Some(v) = &mut self.tracked {
+			v.write(&key);
+		}
+		let res = match self {
 			#[cfg(feature = "kv-mem")]
 			Transaction {
 				inner: Inner::Mem(v),
@@ -431,21 +846,65 @@ impl Transaction {
 				inner: Inner::FDB(v),
 				..
 			} => v.put(key, val).await,
+			Transaction {
+				inner: Inner::Engine(v),
+				..
+			} => v.put(key, val).await,
 			#[allow(unreachable_patterns)]
 			_ => unreachable!(),
+		};
+		// Record the write for replication
+		if let (Ok(_), Some((key, val))) = (&res, rec) {
+			self.record_write(Write::Set(key, val));
 		}
+		res
 	}
 
 	/// Retrieve a specific range of keys from the datastore.
 	///
 	/// This function fetches the full range of key-value pairs, in a single request to the underlying datastore.
-	#[allow(unused_variables)]
 	pub async fn scan<K>(&mut self, rng: Range<K>, limit: u32) -> Result<Vec<(Key, Val)>, Error>
+	where
+		K: Into<Key> + Debug,
+	{
+		let res = self.scan_raw(rng, limit).await?;
+		// Reassemble chunks, and fall through to the archive tier
+		self.fall_through_all(res).await
+	}
+
+	/// Retrieve a specific range of keys from the datastore, and rewrite
+	/// the value of each key which is not archived, so that the transaction
+	/// conflicts with any concurrent transaction which writes to the keys.
+	pub(crate) async fn scan_and_touch(
+		&mut self,
+		rng: Range<Key>,
+		limit: u32,
+	) -> Result<Vec<(Key, Val)>, Error> {
+		let res = self.scan_raw(rng, limit).await?;
+		for (k, v) in res.iter() {
+			if super::archive::archived(v).is_none() {
+				self.set(k.clone(), v.clone()).await?;
+			}
+		}
+		// Reassemble chunks, and fall through to the archive tier
+		self.fall_through_all(res).await
+	}
+
+	/// Retrieve a specific range of keys from the datastore, without
+	/// reassembling the chunks of any large records, or fetching the
+	/// values of any archived keys from the archive tier.
+	#[allow(unused_variables)]
+	pub(crate) async fn scan_raw<K>(
+		&mut self,
+		rng: Range<K>,
+		limit: u32,
+	) -> Result<Vec<(Key, Val)>, Error>
 	where
 		K: Into<Key> + Debug,
 	{
 		#[cfg(debug_assertions)]
 		trace!(target: LOG, "Scan {:?} - {:?}", rng.start, rng.end);
+		self.metrics.operation(Op::Scan);
 		match self {
 			#[cfg(feature = "kv-mem")]
 			Transaction {
@@ -477,6 +936,10 @@ impl Transaction {
 				inner: Inner::FDB(v),
 				..
 			} => v.scan(rng, limit).await,
+			Transaction {
+				inner: Inner::Engine(v),
+				..
+			} => v.scan(rng.start.into()..rng.end.into(), limit).await,
 			#[allow(unreachable_patterns)]
 			_ => unreachable!(),
 		}
@@ -491,7 +954,15 @@ impl Transaction {
 	{
 		#[cfg(debug_assertions)]
 		trace!(target: LOG, "Putc {:?} if {:?} => {:?}", key, chk, val);
-		match self {
+		self.metrics.operation(Op::Putc);
+		let key: Key = key.into();
+		let val: Val = val.into();
+		let chk: Option<Val> = chk.map(Into::into);
+		let rec = self.stream.is_active().then(|| (key.clone(), val.clone()));
+		if let Some(v) = &mut self.tracked {
+			v.write(&key);
+		}
+		let res = match self {
 			#[cfg(feature = "kv-mem")]
 			Transaction {
 				inner: Inner::Mem(v),
@@ -522,9 +993,18 @@ impl Transaction {
 				inner: Inner::FDB(v),
 				..
 			} => v.putc(key, val, chk).await,
+			Transaction {
+				inner: Inner::Engine(v),
+				..
+			} => v.putc(key, val, chk).await,
 			#[allow(unreachable_patterns)]
 			_ => unreachable!(),
+		};
+		// Record the write for replication
+		if let (Ok(_), Some((key, val))) = (&res, rec) {
+			self.record_write(Write::Set(key, val));
 		}
+		res
 	}
 
 	/// Delete a key from the datastore if the current value matches a condition.
@@ -536,7 +1016,14 @@ impl Transaction {
 	{
 		#[cfg(debug_assertions)]
 		trace!(target: LOG, "Delc {:?} if {:?}", key, chk);
-		match self {
+		self.metrics.operation(Op::Delc);
+		let key: Key = key.into();
+		let chk: Option<Val> = chk.map(Into::into);
+		let rec = self.stream.is_active().then(|| key.clone());
+		if let Some(v) = &mut self.tracked {
+			v.write(&key);
+		}
+		let res = match self {
 			#[cfg(feature = "kv-mem")]
 			Transaction {
 				inner: Inner::Mem(v),
@@ -567,9 +1054,18 @@ impl Transaction {
 				inner: Inner::FDB(v),
 				..
 			} => v.delc(key, chk).await,
+			Transaction {
+				inner: Inner::Engine(v),
+				..
+			} => v.delc(key, chk).await,
 			#[allow(unreachable_patterns)]
 			_ => unreachable!(),
+		};
+		// Record the write for replication
+		if let (Ok(_), Some(key)) = (&res, rec) {
+			self.record_write(Write::Del(key));
 		}
+		res
 	}
 
 	// --------------------------------------------------
@@ -1004,6 +1500,52 @@ impl Transaction {
 		})
 	}
 
+	/// Retrieve all sequence definitions for a specific database.
+	pub async fn all_sq(
+		&mut self,
+		ns: &str,
+		db: &str,
+	) -> Result<Arc<[DefineSequenceStatement]>, Error> {
+		let key = crate::key::sq::prefix(ns, db);
+		Ok(if let Some(e) = self.cache.get(&key) {
+			if let Entry::Sqs(v) = e {
+				v
+			} else {
+				unreachable!();
+			}
+		} else {
+			let beg = crate::key::sq::prefix(ns, db);
+			let end = crate::key::sq::suffix(ns, db);
+			let val = self.getr(beg..end, u32::MAX).await?;
+			let val = val.convert().into();
+			self.cache.set(key, Entry::Sqs(Arc::clone(&val)));
+			val
+		})
+	}
+
+	/// Retrieve all migration definitions for a specific database, in the order of their names.
+	pub async fn all_mg(
+		&mut self,
+		ns: &str,
+		db: &str,
+	) -> Result<Arc<[DefineMigrationStatement]>, Error> {
+		let key = crate::key::mg::prefix(ns, db);
+		Ok(if let Some(e) = self.cache.get(&key) {
+			if let Entry::Mgs(v) = e {
+				v
+			} else {
+				unreachable!();
+			}
+		} else {
+			let beg = crate::key::mg::prefix(ns, db);
+			let end = crate::key::mg::suffix(ns, db);
+			let val = self.getr(beg..end, u32::MAX).await?;
+			let val = val.convert().into();
+			self.cache.set(key, Entry::Mgs(Arc::clone(&val)));
+			val
+		})
+	}
+
 	/// Retrieve all table definitions for a specific database.
 	pub async fn all_tb(
 		&mut self,
@@ -1099,6 +1641,28 @@ impl Transaction {
 		})
 	}
 
+	/// Retrieve all index definitions for a specific table, except for any
+	/// index which is still being built in the background.
+	pub async fn all_built_ix(
+		&mut self,
+		ns: &str,
+		db: &str,
+		tb: &str,
+	) -> Result<Arc<[DefineIndexStatement]>, Error> {
+		let ixs = self.all_ix(ns, db, tb).await?;
+		let beg = crate::key::ib::prefix(ns, db, tb);
+		let end = crate::key::ib::suffix(ns, db, tb);
+		let res = self.getr(beg..end, u32::MAX).await?;
+		if res.is_empty() {
+			return Ok(ixs);
+		}
+		let mut building = Vec::with_capacity(res.len());
+		for (k, _) in res.iter() {
+			building.push(crate::key::ib::Ib::decode(k)?.ix.to_owned());
+		}
+		Ok(ixs.iter().filter(|v| !building.contains(&v.name.0)).cloned().collect())
+	}
+
 	/// Retrieve all view definitions for a specific table.
 	pub async fn all_ft(
 		&mut self,
@@ -1290,6 +1854,20 @@ impl Transaction {
 		Ok(val.into())
 	}
 
+	/// Retrieve a specific sequence definition.
+	pub async fn get_sq(
+		&mut self,
+		ns: &str,
+		db: &str,
+		sq: &str,
+	) -> Result<DefineSequenceStatement, Error> {
+		let key = crate::key::sq::new(ns, db, sq);
+		let val = self.get(key).await?.ok_or(Error::SqNotFound {
+			value: sq.to_owned(),
+		})?;
+		Ok(val.into())
+	}
+
 	/// Retrieve a specific table definition.
 	pub async fn get_tb(
 		&mut self,
@@ -1344,6 +1922,7 @@ impl Transaction {
 					let key = crate::key::ns::new(ns);
 					let val = DefineNamespaceStatement {
 						name: ns.to_owned().into(),
+						..DefineNamespaceStatement::default()
 					};
 					self.put(key, &val).await?;
 					Ok(val)
@@ -1435,6 +2014,7 @@ impl Transaction {
 						..DefineTableStatement::default()
 					};
 					self.put(key, &val).await?;
+					self.set_cn(ns, db, tb, 0).await?;
 					Ok(val)
 				}
 				true => Err(Error::TbNotFound {
@@ -1515,6 +2095,281 @@ impl Transaction {
 		})
 	}
 
+	/// Retrieve and cache a specific sequence definition.
+	pub async fn get_and_cache_sq(
+		&mut self,
+		ns: &str,
+		db: &str,
+		sq: &str,
+	) -> Result<Arc<DefineSequenceStatement>, Error> {
+		let key = crate::key::sq::new(ns, db, sq).encode()?;
+		Ok(if let Some(e) = self.cache.get(&key) {
+			if let Entry::Sq(v) = e {
+				v
+			} else {
+				unreachable!();
+			}
+		} else {
+			let val = self.get(key.clone()).await?.ok_or(Error::SqNotFound {
+				value: sq.to_owned(),
+			})?;
+			let val: Arc<DefineSequenceStatement> = Arc::new(val.into());
+			self.cache.set(key, Entry::Sq(Arc::clone(&val)));
+			val
+		})
+	}
+
+	/// Retrieve the last value which was taken from a sequence, if any.
+	pub async fn get_sv(&mut self, ns: &str, db: &str, sq: &str) -> Result<Option<i64>, Error> {
+		let key = crate::key::sv::new(ns, db, sq);
+		Ok(match self.get(key).await? {
+			Some(v) => match Value::from(v) {
+				Value::Number(v) => Some(v.to_int()),
+				_ => None,
+			},
+			None => None,
+		})
+	}
+
+	/// Retrieve the time at which a migration was applied, if it has been applied.
+	pub async fn get_ma(&mut self, ns: &str, db: &str, mg: &str) -> Result<Option<Value>, Error> {
+		let key = crate::key::ma::new(ns, db, mg);
+		Ok(self.get(key).await?.map(Value::from))
+	}
+
+	/// Take the next value from a sequence within this transaction.
+	/// As the value is only persisted when this transaction commits,
+	/// a cancelled transaction does not leave any gaps in the sequence.
+	pub async fn next_sq(&mut self, ns: &str, db: &str, sq: &str) -> Result<i64, Error> {
+		// Fetch the sequence definition
+		let def = self.get_and_cache_sq(ns, db, sq).await?;
+		// Calculate the next sequence value
+		let val = match self.get_sv(ns, db, sq).await? {
+			Some(v) => v.checked_add(def.step).ok_or(Error::SqExhausted {
+				value: sq.to_owned(),
+			})?,
+			None => def.start,
+		};
+		// Store the new sequence value
+		let key = crate::key::sv::new(ns, db, sq);
+		self.set(key, Value::from(val)).await?;
+		// Return the sequence value
+		Ok(val)
+	}
+
+	/// Retrieve the number of records in a table, if the table has a record counter.
+	/// Tables which were created before record counters existed do not have one.
+	pub async fn get_cn(&mut self, ns: &str, db: &str, tb: &str) -> Result<Option<i64>, Error> {
+		let key = crate::key::cn::new(ns, db, tb);
+		let val = match self.get(key).await? {
+			Some(v) => match Value::from(v) {
+				Value::Number(v) => v.to_int(),
+				_ => return Ok(None),
+			},
+			None => return Ok(None),
+		};
+		let beg = crate::key::cn::prefix(ns, db, tb);
+		let end = crate::key::cn::suffix(ns, db, tb);
+		Ok(Some(val + self.sum_shards(beg..end).await?))
+	}
+
+	/// Set the record counter of a table, which starts counting the records in the table.
+	pub async fn set_cn(&mut self, ns: &str, db: &str, tb: &str, val: i64) -> Result<(), Error> {
+		let beg = crate::key::cn::prefix(ns, db, tb);
+		let end = crate::key::cn::suffix(ns, db, tb);
+		self.delr(beg..end, u8::MAX as u32).await?;
+		self.usage.remove(ns);
+		let key = crate::key::cn::new(ns, db, tb);
+		self.set(key, Value::from(val)).await
+	}
+
+	/// Retrieve the secret which signs the share links of a database,
+	/// creating a new random secret if the database does not have one.
+	pub async fn get_or_add_sh(&mut self, ns: &str, db: &str) -> Result<String, Error> {
+		let key = crate::key::sh::new(ns, db);
+		match self.get_sh(ns, db).await? {
+			Some(v) => Ok(v),
+			None => {
+				use rand::distributions::Alphanumeric;
+				use rand::Rng;
+				let val = rand::thread_rng()
+					.sample_iter(&Alphanumeric)
+					.take(128)
+					.map(char::from)
+					.collect::<String>();
+				self.put(key, val.clone()).await?;
+				Ok(val)
+			}
+		}
+	}
+
+	/// Retrieve the secret which signs the share links of a database, if any.
+	pub async fn get_sh(&mut self, ns: &str, db: &str) -> Result<Option<String>, Error> {
+		let key = crate::key::sh::new(ns, db);
+		match self.get(key).await? {
+			Some(v) => Ok(Some(String::from_utf8(v)?)),
+			None => Ok(None),
+		}
+	}
+
+	/// Adjust the record counter of a table, if the table has a record counter.
+	/// Only the shard of this transaction is changed, so that concurrent
+	/// transactions which write to the same table rarely conflict.
+	pub async fn add_cn(&mut self, ns: &str, db: &str, tb: &str, val: i64) -> Result<(), Error> {
+		let key = crate::key::cn::new(ns, db, tb);
+		if self.exi(key).await? {
+			let key = crate::key::cn::shard(ns, db, tb, self.shard);
+			self.add_shard(key, val).await?;
+			if let Some(v) = self.usage.get_mut(ns) {
+				v.0 += val;
+			}
+		}
+		Ok(())
+	}
+
+	/// Retrieve the number of bytes of record data in a table.
+	pub async fn get_by(&mut self, ns: &str, db: &str, tb: &str) -> Result<i64, Error> {
+		// Tables which were written before the counter was sharded keep a total
+		let key = crate::key::by::new(ns, db, tb);
+		let val = match self.get(key).await? {
+			Some(v) => match Value::from(v) {
+				Value::Number(v) => v.to_int(),
+				_ => 0,
+			},
+			None => 0,
+		};
+		let beg = crate::key::by::prefix(ns, db, tb);
+		let end = crate::key::by::suffix(ns, db, tb);
+		Ok(val + self.sum_shards(beg..end).await?)
+	}
+
+	/// Adjust the number of bytes of record data in a table. Only the
+	/// shard of this transaction is changed, as with the record counter.
+	pub async fn add_by(&mut self, ns: &str, db: &str, tb: &str, val: i64) -> Result<(), Error> {
+		let key = crate::key::by::shard(ns, db, tb, self.shard);
+		self.add_shard(key, val).await?;
+		if let Some(v) = self.usage.get_mut(ns) {
+			v.1 += val;
+		}
+		Ok(())
+	}
+
+	/// Sum the shards of a counter
+	async fn sum_shards(&mut self, rng: Range<Vec<u8>>) -> Result<i64, Error> {
+		let mut out = 0;
+		for (_, v) in self.scan(rng, u8::MAX as u32).await? {
+			if let Value::Number(v) = Value::from(v) {
+				out += v.to_int();
+			}
+		}
+		Ok(out)
+	}
+
+	/// Adjust one shard of a counter
+	async fn add_shard(&mut self, key: Vec<u8>, val: i64) -> Result<(), Error> {
+		let v = match self.get(key.clone()).await? {
+			Some(v) => match Value::from(v) {
+				Value::Number(v) => v.to_int(),
+				_ => 0,
+			},
+			None => 0,
+		};
+		self.set(key, Value::from(v + val)).await
+	}
+
+	/// Count the records, and the bytes of record data, in a database.
+	pub async fn usage_db(&mut self, ns: &str, db: &str) -> Result<(i64, i64), Error> {
+		let mut out = (0, 0);
+		for tb in self.all_tb(ns, db).await?.iter() {
+			out.0 += self.get_cn(ns, db, &tb.name).await?.unwrap_or_default();
+			out.1 += self.get_by(ns, db, &tb.name).await?;
+		}
+		Ok(out)
+	}
+
+	/// Check that adding records, or bytes of record data, to a namespace
+	/// keeps the namespace within its storage limits, if it has any. The
+	/// usage of the namespace is counted once in each transaction, and is
+	/// then kept up to date as records are counted.
+	pub async fn check_ns_limits(
+		&mut self,
+		ns: &str,
+		records: i64,
+		bytes: i64,
+	) -> Result<(), Error> {
+		let def = self.get_and_cache_ns(ns).await?;
+		// Check if the namespace is limited
+		let limits = [("records", def.records, records), ("bytes", def.bytes, bytes)];
+		if limits.iter().all(|(_, limit, add)| limit.is_none() || *add <= 0) {
+			return Ok(());
+		}
+		// Count the usage of every database, if not yet counted
+		let usage = match self.usage.get(ns) {
+			Some(v) => *v,
+			None => {
+				let mut usage = (0, 0);
+				for db in self.all_db(ns).await?.iter() {
+					let (r, b) = self.usage_db(ns, &db.name).await?;
+					usage.0 += r;
+					usage.1 += b;
+				}
+				self.usage.insert(ns.to_owned(), usage);
+				usage
+			}
+		};
+		let usage = (usage.0 + records, usage.1 + bytes);
+		// Reject the write if it exceeds any limit
+		for ((kind, limit, add), used) in limits.into_iter().zip([usage.0, usage.1]) {
+			if let Some(limit) = limit {
+				if add > 0 && used > limit as i64 {
+					return Err(Error::NsLimitExceeded {
+						ns: ns.to_owned(),
+						kind,
+						limit,
+					});
+				}
+			}
+		}
+		Ok(())
+	}
+
+	/// Count the records in a table. The record counter of the table is used,
+	/// unless the table does not have one, or an exact count is requested, in
+	/// which case every record in the table is scanned.
+	pub async fn count_tb(
+		&mut self,
+		ns: &str,
+		db: &str,
+		tb: &str,
+		exact: bool,
+	) -> Result<i64, Error> {
+		if !exact {
+			if let Some(v) = self.get_cn(ns, db, tb).await? {
+				return Ok(v);
+			}
+		}
+		let beg = crate::key::thing::prefix(ns, db, tb);
+		let end = crate::key::thing::suffix(ns, db, tb);
+		let mut nxt: Option<Key> = None;
+		let mut num = 0;
+		loop {
+			let res = match nxt.take() {
+				None => self.scan_raw(beg.clone()..end.clone(), 1000).await?,
+				Some(k) => self.scan_raw(k..end.clone(), 1000).await?,
+			};
+			if let Some((k, _)) = res.last() {
+				let mut k = k.clone();
+				k.push(0x00);
+				nxt = Some(k);
+			}
+			num += res.len() as i64;
+			if res.len() < 1000 {
+				break;
+			}
+		}
+		Ok(num)
+	}
+
 	/// Add a namespace with a default configuration, only if we are in dynamic mode.
 	pub async fn add_and_cache_ns(
 		&mut self,
@@ -1529,6 +2384,7 @@ impl Transaction {
 					let key = crate::key::ns::new(ns);
 					let val = DefineNamespaceStatement {
 						name: ns.to_owned().into(),
+						..DefineNamespaceStatement::default()
 					};
 					self.put(key, &val).await?;
 					Ok(Arc::new(val))
@@ -1590,6 +2446,7 @@ impl Transaction {
 						..DefineTableStatement::default()
 					};
 					self.put(key, &val).await?;
+					self.set_cn(ns, db, tb, 0).await?;
 					Ok(Arc::new(val))
 				}
 				true => Err(Error::TbNotFound {
@@ -1622,12 +2479,115 @@ impl Transaction {
 		}
 	}
 
+	/// Record a change to a record, which is published to the
+	/// change log if and when this transaction is committed.
+	pub(crate) fn record_change(
+		&mut self,
+		ns: &str,
+		db: &str,
+		id: &Thing,
+		before: &Value,
+		after: &Value,
+	) {
+		// Only decode changes when there are subscribers, or a journal
+		if self.feed.is_active() || self.journaled {
+			self.push_change(ns, db, id, before, after);
+		}
+	}
+
+	/// Get the number of changes to records which have been recorded
+	pub(crate) fn changes_len(&self) -> usize {
+		self.changes.len()
+	}
+
+	/// Get the changes to records which were recorded after a number of changes
+	pub(crate) fn changes_after(&self, len: usize) -> &[Change] {
+		self.changes.get(len..).unwrap_or_default()
+	}
+
+	fn push_change(&mut self, ns: &str, db: &str, id: &Thing, before: &Value, after: &Value) {
+		self.changes.push(Change {
+			seq: 0,
+			at: Default::default(),
+			ns: ns.to_owned(),
+			db: db.to_owned(),
+			tb: id.tb.to_owned(),
+			id: id.clone(),
+			before: before.clone(),
+			after: after.clone(),
+		});
+	}
+
+	/// Record a raw key-value write, which is published to
+	/// the write stream if and when this transaction is committed.
+	fn record_write(&mut self, write: Write) {
+		// Live queries are local to each node
+		let key = match &write {
+			Write::Set(k, _) => k,
+			Write::Del(k) => k,
+		};
+		if !super::backup::is_live(key) {
+			self.writes.push(write);
+		}
+	}
+
 	// --------------------------------------------------
 	// Additional methods
 	// --------------------------------------------------
 
 	/// Writes the full database contents as binary SQL.
 	pub async fn export(&mut self, ns: &str, db: &str, chn: Sender<Vec<u8>>) -> Result<(), Error> {
+		self.export_with(ns, db, chn, None).await
+	}
+
+	/// Writes the full database contents as binary SQL. If a datastore is
+	/// specified, each batch of records is read from a new snapshot, so
+	/// that a long export does not hold a single snapshot open throughout.
+	pub(super) async fn export_with(
+		&mut self,
+		ns: &str,
+		db: &str,
+		chn: Sender<Vec<u8>>,
+		renew: Option<&Datastore>,
+	) -> Result<(), Error> {
+		self.export_sql(ns, db, chn, renew, true, false).await
+	}
+
+	/// Writes the full database contents as binary SQL, with the fields
+	/// which are defined as `SENSITIVE` anonymized in every record, so
+	/// that the export can be imported into a non-production environment.
+	pub async fn export_anonymized(
+		&mut self,
+		ns: &str,
+		db: &str,
+		chn: Sender<Vec<u8>>,
+	) -> Result<(), Error> {
+		self.export_sql(ns, db, chn, None, true, true).await
+	}
+
+	/// Writes the definitions of a database as binary SQL, without any of
+	/// the table data. Definitions are written in a fixed order, so that
+	/// the schema can be kept under version control and replayed onto a
+	/// new database. Sequences are written with their defined start value.
+	pub async fn export_schema(
+		&mut self,
+		ns: &str,
+		db: &str,
+		chn: Sender<Vec<u8>>,
+	) -> Result<(), Error> {
+		self.export_sql(ns, db, chn, None, false, false).await
+	}
+
+	/// Writes the definitions of a database, and the table data if requested, as binary SQL
+	async fn export_sql(
+		&mut self,
+		ns: &str,
+		db: &str,
+		chn: Sender<Vec<u8>>,
+		renew: Option<&Datastore>,
+		data: bool,
+		anonymize: bool,
+	) -> Result<(), Error> {
 		// Output OPTIONS
 		{
 			chn.send(bytes!("-- ------------------------------")).await?;
@@ -1637,6 +2597,15 @@ impl Transaction {
 			chn.send(bytes!("OPTION IMPORT;")).await?;
 			chn.send(bytes!("")).await?;
 		}
+		// Start transaction, so that the database is imported atomically
+		{
+			chn.send(bytes!("-- ------------------------------")).await?;
+			chn.send(bytes!("-- TRANSACTION")).await?;
+			chn.send(bytes!("-- ------------------------------")).await?;
+			chn.send(bytes!("")).await?;
+			chn.send(bytes!("BEGIN TRANSACTION;")).await?;
+			chn.send(bytes!("")).await?;
+		}
 		// Output FUNCTIONS
 		{
 			let fcs = self.all_fc(ns, db).await?;
@@ -1693,6 +2662,28 @@ impl Transaction {
 				chn.send(bytes!("")).await?;
 			}
 		}
+		// Output SEQUENCES
+		{
+			let sqs = self.all_sq(ns, db).await?;
+			if !sqs.is_empty() {
+				chn.send(bytes!("-- ------------------------------")).await?;
+				chn.send(bytes!("-- SEQUENCES")).await?;
+				chn.send(bytes!("-- ------------------------------")).await?;
+				chn.send(bytes!("")).await?;
+				for sq in sqs.iter() {
+					// Continue from the last value which was taken
+					let sq = match self.get_sv(ns, db, &sq.name).await?.filter(|_| data) {
+						Some(v) => DefineSequenceStatement {
+							start: v.saturating_add(sq.step),
+							..sq.clone()
+						},
+						None => sq.clone(),
+					};
+					chn.send(bytes!(format!("{sq};"))).await?;
+				}
+				chn.send(bytes!("")).await?;
+			}
+		}
 		// Output SCOPES
 		{
 			let scs = self.all_sc(ns, db).await?;
@@ -1734,7 +2725,9 @@ impl Transaction {
 		}
 		// Output TABLES
 		{
+			// Output source tables before any views which select from them
 			let tbs = self.all_tb(ns, db).await?;
+			let tbs = ordered(&tbs);
 			if !tbs.is_empty() {
 				for tb in tbs.iter() {
 					// Output TABLE
@@ -1771,85 +2764,313 @@ impl Transaction {
 						chn.send(bytes!("")).await?;
 					}
 				}
-				// Start transaction
-				chn.send(bytes!("-- ------------------------------")).await?;
-				chn.send(bytes!("-- TRANSACTION")).await?;
-				chn.send(bytes!("-- ------------------------------")).await?;
-				chn.send(bytes!("")).await?;
-				chn.send(bytes!("BEGIN TRANSACTION;")).await?;
-				chn.send(bytes!("")).await?;
 				// Output TABLE data
-				for tb in tbs.iter() {
-					// Start records
-					chn.send(bytes!("-- ------------------------------")).await?;
-					chn.send(bytes!(format!("-- TABLE DATA: {}", tb.name))).await?;
-					chn.send(bytes!("-- ------------------------------")).await?;
-					chn.send(bytes!("")).await?;
-					// Fetch records
-					let beg = thing::prefix(ns, db, &tb.name);
-					let end = thing::suffix(ns, db, &tb.name);
-					let mut nxt: Option<Vec<u8>> = None;
-					loop {
-						let res = match nxt {
-							None => {
-								let min = beg.clone();
-								let max = end.clone();
-								self.scan(min..max, 1000).await?
-							}
-							Some(ref mut beg) => {
-								beg.push(0x00);
-								let min = beg.clone();
-								let max = end.clone();
-								self.scan(min..max, 1000).await?
-							}
+				if data {
+					for tb in tbs.iter() {
+						// Start records
+						chn.send(bytes!("-- ------------------------------")).await?;
+						chn.send(bytes!(format!("-- TABLE DATA: {}", tb.name))).await?;
+						chn.send(bytes!("-- ------------------------------")).await?;
+						chn.send(bytes!("")).await?;
+						// Fetch the sensitive fields, if anonymized
+						let fds = match anonymize {
+							true => Some(self.all_fd(ns, db, &tb.name).await?),
+							false => None,
 						};
-						if !res.is_empty() {
-							// Get total results
-							let n = res.len();
-							// Exit when settled
-							if n == 0 {
-								break;
+						// Fetch records
+						let beg = thing::prefix(ns, db, &tb.name);
+						let end = thing::suffix(ns, db, &tb.name);
+						let mut nxt: Option<Vec<u8>> = None;
+						loop {
+							// Read each batch from a new snapshot, if requested
+							if let (Some(ds), Some(_)) = (renew, &nxt) {
+								self.renew(ds).await?;
 							}
-							// Loop over results
-							for (i, (k, v)) in res.into_iter().enumerate() {
-								// Ready the next
-								if n == i + 1 {
-									nxt = Some(k.clone());
+							let res = match nxt {
+								None => {
+									let min = beg.clone();
+									let max = end.clone();
+									self.scan(min..max, 1000).await?
+								}
+								Some(ref mut beg) => {
+									beg.push(0x00);
+									let min = beg.clone();
+									let max = end.clone();
+									self.scan(min..max, 1000).await?
+								}
+							};
+							if !res.is_empty() {
+								// Get total results
+								let n = res.len();
+								// Exit when settled
+								if n == 0 {
+									break;
 								}
-								// Parse the key and the value
-								let k: crate::key::thing::Thing = (&k).into();
-								let v: crate::sql::value::Value = (&v).into();
-								let t = Thing::from((k.tb, k.id));
-								// Check if this is a graph edge
-								match (v.pick(&*EDGE), v.pick(&*IN), v.pick(&*OUT)) {
-									// This is a graph edge record
-									(Value::Bool(true), Value::Thing(l), Value::Thing(r)) => {
-										let sql = format!("RELATE {l} -> {t} -> {r} CONTENT {v};",);
-										chn.send(bytes!(sql)).await?;
+								// Loop over results
+								for (i, (k, v)) in res.into_iter().enumerate() {
+									// Ready the next
+									if n == i + 1 {
+										nxt = Some(k.clone());
 									}
-									// This is a normal record
-									_ => {
-										let sql = format!("UPDATE {t} CONTENT {v};");
-										chn.send(bytes!(sql)).await?;
+									// Parse the key and the value
+									let k: crate::key::thing::Thing = (&k).into();
+									let mut v: crate::sql::value::Value = (&v).into();
+									let t = Thing::from((k.tb, k.id));
+									// Anonymize the sensitive fields
+									if let Some(fds) = &fds {
+										super::anonymize::record(&mut v, fds);
+									}
+									// Check if this is a graph edge
+									match (v.pick(&*EDGE), v.pick(&*IN), v.pick(&*OUT)) {
+										// This is a graph edge record
+										(Value::Bool(true), Value::Thing(l), Value::Thing(r)) => {
+											let sql =
+												format!("RELATE {l} -> {t} -> {r} CONTENT {v};",);
+											chn.send(bytes!(sql)).await?;
+										}
+										// This is a normal record
+										_ => {
+											let sql = format!("UPDATE {t} CONTENT {v};");
+											chn.send(bytes!(sql)).await?;
+										}
 									}
 								}
+								continue;
 							}
-							continue;
+							break;
 						}
-						break;
+						chn.send(bytes!("")).await?;
 					}
-					chn.send(bytes!("")).await?;
 				}
-				// Commit transaction
-				chn.send(bytes!("-- ------------------------------")).await?;
-				chn.send(bytes!("-- TRANSACTION")).await?;
-				chn.send(bytes!("-- ------------------------------")).await?;
-				chn.send(bytes!("")).await?;
-				chn.send(bytes!("COMMIT TRANSACTION;")).await?;
-				chn.send(bytes!("")).await?;
 			}
 		}
+		// Commit transaction
+		{
+			chn.send(bytes!("-- ------------------------------")).await?;
+			chn.send(bytes!("-- TRANSACTION")).await?;
+			chn.send(bytes!("-- ------------------------------")).await?;
+			chn.send(bytes!("")).await?;
+			chn.send(bytes!("COMMIT TRANSACTION;")).await?;
+			chn.send(bytes!("")).await?;
+		}
 		// Everything exported
 		Ok(())
 	}
+
+	/// Replaces this transaction with a new read-only transaction, so that
+	/// any further reads see the latest committed data, and the snapshot
+	/// held by this transaction can be released by the storage engine.
+	async fn renew(&mut self, ds: &Datastore) -> Result<(), Error> {
+		let mut txn = ds.transaction(false, false).await?;
+		std::mem::swap(self, &mut txn);
+		txn.cancel().await
+	}
+
+	/// Writes the keys under a prefix as a binary snapshot, in key order
+	pub async fn backup(&mut self, pre: &[u8], chn: Sender<Vec<u8>>) -> Result<(), Error> {
+		let beg: Key = pre.to_vec();
+		let end: Key = beg.clone().add(0xff);
+		let mut nxt: Option<Key> = None;
+		loop {
+			// Get the next batch of keys
+			let min = match nxt.take() {
+				Some(v) => v.add(0x00),
+				None => beg.clone(),
+			};
+			// The stored values are kept as they are, with any chunks
+			let res = self.scan_raw(min..end.clone(), 1000).await?;
+			// Exit when settled
+			if res.is_empty() {
+				break;
+			}
+			// Encode each key-value frame
+			let mut out = vec![];
+			for (k, v) in res.into_iter() {
+				if !super::backup::is_live(&k) {
+					super::backup::frame(&mut out, &k[beg.len()..], &v);
+				}
+				nxt = Some(k);
+			}
+			chn.send(out).await?;
+		}
+		Ok(())
+	}
+
+	/// Moves the records in every table defined with `ARCHIVE` to the
+	/// archive tier, returning the number of records which were moved
+	pub async fn archive(&mut self) -> Result<usize, Error> {
+		// Check that an archive tier is configured
+		let archive = match self.archive.clone() {
+			Some(v) => v,
+			None => return Err(Error::Archive("No archive tier is configured".into())),
+		};
+		let mut count = 0;
+		for ns in self.all_ns().await?.iter() {
+			for db in self.all_db(&ns.name).await?.iter() {
+				for tb in self.all_tb(&ns.name, &db.name).await?.iter() {
+					// Only archive tables defined with ARCHIVE
+					if !tb.archive {
+						continue;
+					}
+					// Fetch records
+					let beg = thing::prefix(&ns.name, &db.name, &tb.name);
+					let end = thing::suffix(&ns.name, &db.name, &tb.name);
+					let mut nxt: Option<Key> = None;
+					loop {
+						let min = match nxt.take() {
+							Some(v) => v.add(0x00),
+							None => beg.clone(),
+						};
+						let res = self.scan_raw(min..end.clone(), 1000).await?;
+						// Exit when settled
+						if res.is_empty() {
+							break;
+						}
+						// Move each record which is not yet archived
+						for (k, v) in res.into_iter() {
+							if super::archive::archived(&v).is_none() {
+								// Archive large records as a single object
+								let v = match super::chunk::chunked(&v) {
+									Some(n) => {
+										let v = self.get_chunks(&k, n).await?;
+										let key = thing::Thing::decode(&k)?;
+										let rid = Thing::from((key.tb, key.id.clone()));
+										self.del_chunks(key.ns, key.db, &rid, 0..n).await?;
+										v
+									}
+									None => v,
+								};
+								let name = super::archive::name(&k);
+								archive.put(&name, v).await?;
+								self.set(k.clone(), super::archive::marker(&name)).await?;
+								count += 1;
+							}
+							nxt = Some(k);
+						}
+					}
+				}
+			}
+		}
+		Ok(count)
+	}
+
+	/// Writes the records in a table to a channel, in key order
+	pub async fn export_table(
+		&mut self,
+		ns: &str,
+		db: &str,
+		tb: &str,
+		chn: Sender<Value>,
+	) -> Result<(), Error> {
+		self.export_table_with(ns, db, tb, chn, None, false).await
+	}
+
+	/// Writes the records in a table to a channel, in key order, with the
+	/// fields which are defined as `SENSITIVE` anonymized in every record
+	pub async fn export_table_anonymized(
+		&mut self,
+		ns: &str,
+		db: &str,
+		tb: &str,
+		chn: Sender<Value>,
+	) -> Result<(), Error> {
+		self.export_table_with(ns, db, tb, chn, None, true).await
+	}
+
+	/// Writes the records in a table to a channel, in key order. If a
+	/// datastore is specified, each batch of records is read from a new
+	/// snapshot, as with [`Transaction::export_with`].
+	pub(super) async fn export_table_with(
+		&mut self,
+		ns: &str,
+		db: &str,
+		tb: &str,
+		chn: Sender<Value>,
+		renew: Option<&Datastore>,
+		anonymize: bool,
+	) -> Result<(), Error> {
+		// Check that the table exists
+		self.get_tb(ns, db, tb).await?;
+		// Fetch the sensitive fields, if anonymized
+		let fds = match anonymize {
+			true => Some(self.all_fd(ns, db, tb).await?),
+			false => None,
+		};
+		// Fetch records
+		let beg = thing::prefix(ns, db, tb);
+		let end = thing::suffix(ns, db, tb);
+		let mut nxt: Option<Vec<u8>> = None;
+		loop {
+			// Read each batch from a new snapshot, if requested
+			if let (Some(ds), Some(_)) = (renew, &nxt) {
+				self.renew(ds).await?;
+			}
+			let res = match nxt {
+				None => {
+					let min = beg.clone();
+					let max = end.clone();
+					self.scan(min..max, 1000).await?
+				}
+				Some(ref mut beg) => {
+					beg.push(0x00);
+					let min = beg.clone();
+					let max = end.clone();
+					self.scan(min..max, 1000).await?
+				}
+			};
+			// Get total results
+			let n = res.len();
+			// Exit when settled
+			if n == 0 {
+				break;
+			}
+			// Loop over results
+			for (i, (k, v)) in res.into_iter().enumerate() {
+				// Ready the next
+				if n == i + 1 {
+					nxt = Some(k);
+				}
+				// Anonymize the sensitive fields
+				let mut v: Value = (&v).into();
+				if let Some(fds) = &fds {
+					super::anonymize::record(&mut v, fds);
+				}
+				// Output the record
+				chn.send(v).await?;
+			}
+		}
+		// Everything exported
+		Ok(())
+	}
+}
+
+/// Orders table definitions so that every table is defined after
+/// any tables which it is a view of. Tables are otherwise kept in
+/// their original order, and any cycles are left as they are.
+fn ordered(tbs: &[DefineTableStatement]) -> Vec<&DefineTableStatement> {
+	fn visit<'a>(
+		tb: &'a DefineTableStatement,
+		tbs: &'a [DefineTableStatement],
+		seen: &mut Vec<&'a str>,
+		out: &mut Vec<&'a DefineTableStatement>,
+	) {
+		if seen.contains(&tb.name.as_str()) {
+			return;
+		}
+		seen.push(&tb.name);
+		if let Some(view) = &tb.view {
+			for what in view.what.iter() {
+				if let Some(v) = tbs.iter().find(|v| v.name.as_str() == what.as_str()) {
+					visit(v, tbs, seen, out);
+				}
+			}
+		}
+		out.push(tb);
+	}
+	let mut seen = Vec::with_capacity(tbs.len());
+	let mut out = Vec::with_capacity(tbs.len());
+	for tb in tbs.iter() {
+		visit(tb, tbs, &mut seen, &mut out);
+	}
+	out
 }
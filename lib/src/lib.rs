@@ -113,6 +113,7 @@ mod exe;
 mod fnc;
 mod key;
 
+pub mod changes;
 pub mod sql;
 
 #[doc(hidden)]
@@ -0,0 +1,80 @@
+use crate::sql::comment::shouldbespace;
+use crate::sql::error::IResult;
+use crate::sql::ident::{ident, Ident};
+use nom::bytes::complete::tag_no_case;
+use nom::combinator::opt;
+use nom::sequence::preceded;
+use serde::{Deserialize, Serialize};
+use std::fmt;
+
+#[derive(Clone, Debug, Default, Eq, PartialEq, PartialOrd, Serialize, Deserialize, Hash)]
+pub struct Audit {
+	/// Should SELECT access be recorded as well as writes?
+	pub reads: bool,
+	/// The table to write the audit entries into, if any
+	pub into: Option<Ident>,
+}
+
+impl fmt::Display for Audit {
+	fn fmt(&self, f: &mut fmt::Formatter) -> fmt::Result {
+		f.write_str("AUDIT")?;
+		if self.reads {
+			f.write_str(" ALL")?
+		}
+		if let Some(ref v) = self.into {
+			write!(f, " INTO {v}")?
+		}
+		Ok(())
+	}
+}
+
+pub fn audit(i: &str) -> IResult<&str, Audit> {
+	let (i, _) = tag_no_case("AUDIT")(i)?;
+	let (i, reads) = opt(preceded(shouldbespace, tag_no_case("ALL")))(i)?;
+	let (i, into) = opt(|i| {
+		let (i, _) = shouldbespace(i)?;
+		let (i, _) = tag_no_case("INTO")(i)?;
+		let (i, _) = shouldbespace(i)?;
+		let (i, v) = ident(i)?;
+		Ok((i, v))
+	})(i)?;
+	Ok((
+		i,
+		Audit {
+			reads: reads.is_some(),
+			into,
+		},
+	))
+}
+
+#[cfg(test)]
+mod tests {
+
+	use super::*;
+
+	#[test]
+	fn audit_writes() {
+		let sql = "AUDIT";
+		let res = audit(sql);
+		assert!(res.is_ok());
+		let out = res.unwrap().1;
+		assert_eq!(out, Audit::default());
+		assert_eq!("AUDIT", format!("{}", out));
+	}
+
+	#[test]
+	fn audit_reads_into() {
+		let sql = "AUDIT ALL INTO audit_log";
+		let res = audit(sql);
+		assert!(res.is_ok());
+		let out = res.unwrap().1;
+		assert_eq!(
+			out,
+			Audit {
+				reads: true,
+				into: Some(Ident::from("audit_log")),
+			}
+		);
+		assert_eq!("AUDIT ALL INTO audit_log", format!("{}", out));
+	}
+}
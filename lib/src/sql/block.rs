@@ -8,12 +8,14 @@ use crate::sql::error::IResult;
 use crate::sql::fmt::{is_pretty, pretty_indent, Fmt, Pretty};
 use crate::sql::statements::create::{create, CreateStatement};
 use crate::sql::statements::delete::{delete, DeleteStatement};
+use crate::sql::statements::foreach::{foreach, ForeachStatement};
 use crate::sql::statements::ifelse::{ifelse, IfelseStatement};
 use crate::sql::statements::insert::{insert, InsertStatement};
 use crate::sql::statements::output::{output, OutputStatement};
 use crate::sql::statements::relate::{relate, RelateStatement};
 use crate::sql::statements::select::{select, SelectStatement};
 use crate::sql::statements::set::{set, SetStatement};
+use crate::sql::statements::throw::{throw, ThrowStatement};
 use crate::sql::statements::update::{update, UpdateStatement};
 use crate::sql::value::{value, Value};
 use nom::branch::alt;
@@ -75,6 +77,9 @@ impl Block {
 				Entry::Ifelse(v) => {
 					v.compute(&ctx, opt).await?;
 				}
+				Entry::Foreach(v) => {
+					v.compute(&ctx, opt).await?;
+				}
 				Entry::Select(v) => {
 					v.compute(&ctx, opt).await?;
 				}
@@ -93,6 +98,9 @@ impl Block {
 				Entry::Insert(v) => {
 					v.compute(&ctx, opt).await?;
 				}
+				Entry::Throw(v) => {
+					return v.compute(&ctx, opt).await;
+				}
 				Entry::Output(v) => {
 					return v.compute(&ctx, opt).await;
 				}
@@ -171,6 +179,8 @@ pub enum Entry {
 	Relate(RelateStatement),
 	Insert(InsertStatement),
 	Output(OutputStatement),
+	Foreach(ForeachStatement),
+	Throw(ThrowStatement),
 }
 
 impl PartialOrd for Entry {
@@ -194,6 +204,8 @@ impl Entry {
 			Self::Relate(v) => v.writeable(),
 			Self::Insert(v) => v.writeable(),
 			Self::Output(v) => v.writeable(),
+			Self::Foreach(v) => v.writeable(),
+			Self::Throw(v) => v.writeable(),
 		}
 	}
 }
@@ -211,6 +223,8 @@ impl Display for Entry {
 			Self::Relate(v) => write!(f, "{v}"),
 			Self::Insert(v) => write!(f, "{v}"),
 			Self::Output(v) => write!(f, "{v}"),
+			Self::Foreach(v) => write!(f, "{v}"),
+			Self::Throw(v) => write!(f, "{v}"),
 		}
 	}
 }
@@ -228,6 +242,8 @@ pub fn entry(i: &str) -> IResult<&str, Entry> {
 			map(relate, Entry::Relate),
 			map(delete, Entry::Delete),
 			map(insert, Entry::Insert),
+			map(foreach, Entry::Foreach),
+			map(throw, Entry::Throw),
 			map(value, Entry::Value),
 		)),
 		mightbespace,
@@ -266,6 +282,15 @@ mod tests {
 		assert_eq!(sql, format!("{}", out))
 	}
 
+	#[test]
+	fn block_foreach_throw() {
+		let sql = "{ FOR $test IN $tests { IF $test.id THEN { THROW 'Exists'; } END; }; }";
+		let res = block(sql);
+		assert!(res.is_ok());
+		let out = res.unwrap().1;
+		assert_eq!(sql, format!("{}", out))
+	}
+
 	#[test]
 	fn block_multiple() {
 		let sql = r#"{
@@ -1,4 +1,6 @@
-use base64_lib::{engine::general_purpose::STANDARD_NO_PAD, Engine};
+use base64_lib::engine::general_purpose::{GeneralPurpose, GeneralPurposeConfig, STANDARD_NO_PAD};
+use base64_lib::engine::DecodePaddingMode;
+use base64_lib::{alphabet, Engine};
 use serde::{
 	de::{self, Visitor},
 	Deserialize, Serialize,
@@ -6,6 +8,15 @@ use serde::{
 use std::fmt::{self, Display, Formatter};
 use std::ops::Deref;
 
+/// Decodes base64 with or without padding, so that bytes which were encoded
+/// by any client can be accepted
+pub(crate) const BASE64: GeneralPurpose = GeneralPurpose::new(
+	&alphabet::STANDARD,
+	GeneralPurposeConfig::new()
+		.with_encode_padding(false)
+		.with_decode_padding_mode(DecodePaddingMode::Indifferent),
+);
+
 #[derive(Clone, Debug, Default, Eq, PartialEq, PartialOrd, Hash)]
 pub struct Bytes(pub(crate) Vec<u8>);
 
@@ -13,6 +24,11 @@ impl Bytes {
 	pub fn into_inner(self) -> Vec<u8> {
 		self.0
 	}
+
+	/// Decode bytes from a base64 string, with or without padding
+	pub fn from_base64(v: &str) -> Option<Self> {
+		BASE64.decode(v.trim()).ok().map(Bytes)
+	}
 }
 
 impl From<Vec<u8>> for Bytes {
@@ -89,4 +105,12 @@ mod tests {
 		let deserialized = Value::from(serialized);
 		assert_eq!(val, deserialized);
 	}
+
+	#[test]
+	fn from_base64() {
+		assert_eq!(Bytes::from_base64("AQID"), Some(Bytes(vec![1, 2, 3])));
+		assert_eq!(Bytes::from_base64("AQI="), Some(Bytes(vec![1, 2])));
+		assert_eq!(Bytes::from_base64("AQI"), Some(Bytes(vec![1, 2])));
+		assert_eq!(Bytes::from_base64("AQ!D"), None);
+	}
 }
@@ -0,0 +1,272 @@
+//! Locale-aware string collation for `ORDER BY` clauses and indexes.
+//!
+//! Strings are compared by a sort key with three levels, in the manner of
+//! the Unicode Collation Algorithm. The primary level holds the base letters,
+//! with accents and case removed, and with the letters which a locale sorts
+//! separately from their base letter (such as `å`, `ä` and `ö` in Swedish)
+//! placed after it. The secondary level distinguishes accents, and the
+//! tertiary level distinguishes case, unless the collation is case
+//! insensitive. A numeric collation compares runs of digits by their value.
+use crate::sql::comment::shouldbespace;
+use crate::sql::error::IResult;
+use crate::sql::escape::quote_str;
+use crate::sql::strand::strand;
+use crate::sql::value::Value;
+use deunicode::deunicode_char;
+use nom::bytes::complete::tag_no_case;
+use nom::combinator::{map, opt};
+use nom::sequence::{preceded, tuple};
+use serde::{Deserialize, Serialize};
+use std::cmp::Ordering;
+use std::fmt;
+
+/// The character which separates the levels of a sort key, which sorts
+/// before any character of a level so that shorter strings sort first
+const LEVEL: char = '\u{1}';
+
+/// The first of the private use characters which are used to place the
+/// tailored letters of a locale after their base letter
+const TAILORED: u32 = 0xE000;
+
+#[derive(Clone, Debug, Default, Eq, PartialEq, PartialOrd, Serialize, Deserialize, Hash)]
+pub struct Collation {
+	pub locale: Option<String>,
+	pub insensitive: bool,
+	pub numeric: bool,
+}
+
+impl Collation {
+	/// Compare two values, comparing strings by their sort keys
+	pub(crate) fn compare(&self, a: &Value, b: &Value) -> Option<Ordering> {
+		match (a, b) {
+			(Value::Strand(a), Value::Strand(b)) => Some(self.key(a).cmp(&self.key(b))),
+			_ => a.partial_cmp(b),
+		}
+	}
+
+	/// Get the value which is stored in an index for a value, so that
+	/// strings are stored by their sort keys, in the order of the collation
+	pub(crate) fn index(&self, v: Value) -> Value {
+		match v {
+			Value::Strand(v) => Value::from(self.key(&v)),
+			Value::Array(v) => Value::Array(v.into_iter().map(|v| self.index(v)).collect()),
+			v => v,
+		}
+	}
+
+	/// Get the sort key of a string
+	pub(crate) fn key(&self, s: &str) -> String {
+		let lang = self.language();
+		let tailoring = tailoring(lang);
+		let lower: String = s.chars().flat_map(|c| lowercase(lang, c)).collect();
+		// The primary level holds the base letters
+		let mut primary = String::with_capacity(lower.len());
+		for c in lower.chars() {
+			match tailoring.iter().find(|(t, ..)| *t == c) {
+				Some((_, base, rank)) => {
+					primary.push(*base);
+					primary.push(char::from_u32(TAILORED + rank).unwrap());
+				}
+				None if c.is_ascii() => primary.push(c),
+				None => match deunicode_char(c) {
+					Some(v) => primary.extend(v.chars().flat_map(char::to_lowercase)),
+					None => primary.push(c),
+				},
+			}
+		}
+		let mut key = match self.numeric {
+			true => numeric(&primary),
+			false => primary,
+		};
+		// The secondary level distinguishes accents
+		key.push(LEVEL);
+		key.push_str(&lower);
+		// The tertiary level distinguishes case, with lower case first
+		if !self.insensitive {
+			key.push(LEVEL);
+			key.extend(s.chars().map(|c| match c.is_uppercase() {
+				true => 'u',
+				false => 'l',
+			}));
+		}
+		key
+	}
+
+	/// Get the language of the locale, such as `sv` for `sv-SE`
+	fn language(&self) -> &str {
+		match &self.locale {
+			Some(v) => v.split(['-', '_']).next().unwrap_or_default(),
+			None => "",
+		}
+	}
+}
+
+impl fmt::Display for Collation {
+	fn fmt(&self, f: &mut fmt::Formatter) -> fmt::Result {
+		write!(f, "COLLATE")?;
+		if let Some(ref v) = self.locale {
+			write!(f, " {}", quote_str(v))?;
+		}
+		if self.insensitive {
+			write!(f, " CI")?;
+		}
+		if self.numeric {
+			write!(f, " NUMERIC")?;
+		}
+		Ok(())
+	}
+}
+
+/// Get the letters which a language sorts after a base letter, with the
+/// base letter and the position of the letter after it
+fn tailoring(lang: &str) -> &'static [(char, char, u32)] {
+	match lang.to_ascii_lowercase().as_str() {
+		"sv" | "fi" => &[('å', 'z', 1), ('ä', 'z', 2), ('æ', 'z', 2), ('ö', 'z', 3), ('ø', 'z', 3)],
+		"da" | "nb" | "nn" | "no" => {
+			&[('æ', 'z', 1), ('ä', 'z', 1), ('ø', 'z', 2), ('ö', 'z', 2), ('å', 'z', 3)]
+		}
+		"es" => &[('ñ', 'n', 1)],
+		"tr" | "az" => &[
+			('ç', 'c', 1),
+			('ğ', 'g', 1),
+			('ı', 'h', 1),
+			('ö', 'o', 1),
+			('ş', 's', 1),
+			('ü', 'u', 1),
+		],
+		"pl" => &[
+			('ą', 'a', 1),
+			('ć', 'c', 1),
+			('ę', 'e', 1),
+			('ł', 'l', 1),
+			('ń', 'n', 1),
+			('ó', 'o', 1),
+			('ś', 's', 1),
+			('ź', 'z', 1),
+			('ż', 'z', 2),
+		],
+		"cs" | "sk" => &[('č', 'c', 1), ('ř', 'r', 1), ('š', 's', 1), ('ž', 'z', 1)],
+		_ => &[],
+	}
+}
+
+/// Get the lower case of a character, in the conventions of a language
+fn lowercase(lang: &str, c: char) -> impl Iterator<Item = char> {
+	let turkic = matches!(lang.to_ascii_lowercase().as_str(), "tr" | "az");
+	let (a, b) = match (turkic, c) {
+		(true, 'I') => (Some('ı'), None),
+		(true, 'İ') => (Some('i'), None),
+		_ => (None, Some(c.to_lowercase())),
+	};
+	a.into_iter().chain(b.into_iter().flatten())
+}
+
+/// Prefix each run of digits with its length, without any leading
+/// zeros, so that numbers are compared by their value
+fn numeric(s: &str) -> String {
+	let mut out = String::with_capacity(s.len());
+	let mut chars = s.chars().peekable();
+	while let Some(c) = chars.next() {
+		if !c.is_ascii_digit() {
+			out.push(c);
+			continue;
+		}
+		let mut digits = String::from(c);
+		while let Some(c) = chars.next_if(char::is_ascii_digit) {
+			digits.push(c);
+		}
+		let digits = digits.trim_start_matches('0');
+		out.push(char::from_u32('0' as u32 + digits.len() as u32).unwrap_or(char::MAX));
+		out.push_str(digits);
+	}
+	out
+}
+
+pub fn collation(i: &str) -> IResult<&str, Collation> {
+	let (i, _) = tag_no_case("COLLATE")(i)?;
+	let (i, locale) = opt(preceded(shouldbespace, map(strand, |v| v.0)))(i)?;
+	let (i, insensitive) = opt(tuple((shouldbespace, tag_no_case("CI"))))(i)?;
+	let (i, numeric) = opt(tuple((shouldbespace, tag_no_case("NUMERIC"))))(i)?;
+	Ok((
+		i,
+		Collation {
+			locale,
+			insensitive: insensitive.is_some(),
+			numeric: numeric.is_some(),
+		},
+	))
+}
+
+#[cfg(test)]
+mod tests {
+
+	use super::*;
+
+	fn sorted(c: &Collation, v: &[&str]) -> Vec<String> {
+		let mut v: Vec<String> = v.iter().map(|v| v.to_string()).collect();
+		v.sort_by_key(|v| c.key(v));
+		v
+	}
+
+	#[test]
+	fn collation_statement() {
+		let sql = "COLLATE 'sv-SE' CI NUMERIC";
+		let res = collation(sql);
+		assert!(res.is_ok());
+		let out = res.unwrap().1;
+		assert_eq!(
+			out,
+			Collation {
+				locale: Some("sv-SE".to_owned()),
+				insensitive: true,
+				numeric: true,
+			}
+		);
+		assert_eq!("COLLATE 'sv-SE' CI NUMERIC", format!("{}", out));
+	}
+
+	#[test]
+	fn collation_root() {
+		let c = Collation::default();
+		let v = sorted(&c, &["zebra", "Émile", "apple", "Apple", "émile", "emile"]);
+		assert_eq!(v, vec!["apple", "Apple", "emile", "émile", "Émile", "zebra"]);
+	}
+
+	#[test]
+	fn collation_locale() {
+		let c = Collation {
+			locale: Some("sv".to_owned()),
+			..Default::default()
+		};
+		let v = sorted(&c, &["öl", "zon", "åsna", "ära", "apa"]);
+		assert_eq!(v, vec!["apa", "zon", "åsna", "ära", "öl"]);
+		let c = Collation {
+			locale: Some("de".to_owned()),
+			..Default::default()
+		};
+		let v = sorted(&c, &["öl", "zon", "ofen"]);
+		assert_eq!(v, vec!["ofen", "öl", "zon"]);
+	}
+
+	#[test]
+	fn collation_insensitive() {
+		let c = Collation {
+			insensitive: true,
+			..Default::default()
+		};
+		assert_eq!(c.key("Tobie"), c.key("tobie"));
+		assert_ne!(c.key("Tobie"), c.key("Töbie"));
+		let c = Collation::default();
+		assert_ne!(c.key("Tobie"), c.key("tobie"));
+	}
+
+	#[test]
+	fn collation_numeric() {
+		let c = Collation {
+			numeric: true,
+			..Default::default()
+		};
+		let v = sorted(&c, &["file10", "file9", "file010", "file1"]);
+		assert_eq!(v, vec!["file1", "file9", "file010", "file10"]);
+	}
+}
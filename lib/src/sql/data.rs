@@ -48,15 +48,30 @@ impl Data {
 		match self {
 			Self::MergeExpression(v) => {
 				// This MERGE expression has an 'id' field
-				v.compute(ctx, opt).await?.rid().generate(tb, false)
+				match v.compute(ctx, opt).await?.rid() {
+					// There is no 'id' field so generate a record id
+					Value::None => tb.generate_with(ctx, opt).await,
+					// Use the specified record id
+					v => v.generate(tb, false),
+				}
 			}
 			Self::ReplaceExpression(v) => {
 				// This REPLACE expression has an 'id' field
-				v.compute(ctx, opt).await?.rid().generate(tb, false)
+				match v.compute(ctx, opt).await?.rid() {
+					// There is no 'id' field so generate a record id
+					Value::None => tb.generate_with(ctx, opt).await,
+					// Use the specified record id
+					v => v.generate(tb, false),
+				}
 			}
 			Self::ContentExpression(v) => {
 				// This CONTENT expression has an 'id' field
-				v.compute(ctx, opt).await?.rid().generate(tb, false)
+				match v.compute(ctx, opt).await?.rid() {
+					// There is no 'id' field so generate a record id
+					Value::None => tb.generate_with(ctx, opt).await,
+					// Use the specified record id
+					v => v.generate(tb, false),
+				}
 			}
 			Self::SetExpression(v) => match v.iter().find(|f| f.0.is_id()) {
 				Some((_, _, v)) => {
@@ -64,10 +79,10 @@ impl Data {
 					v.compute(ctx, opt).await?.generate(tb, false)
 				}
 				// This SET expression had no 'id' field
-				_ => Ok(tb.generate()),
+				_ => tb.generate_with(ctx, opt).await,
 			},
-			// Generate a random id for all other data clauses
-			_ => Ok(tb.generate()),
+			// Generate a record id for all other data clauses
+			_ => tb.generate_with(ctx, opt).await,
 		}
 	}
 }
@@ -87,6 +87,16 @@ impl Datetime {
 	pub fn to_raw(&self) -> String {
 		self.0.to_rfc3339_opts(SecondsFormat::AutoSi, true)
 	}
+	/// Add a Duration, returning None if the result is out of range
+	pub fn checked_add(&self, d: &Duration) -> Option<Datetime> {
+		let d = chrono::Duration::from_std(d.0).ok()?;
+		self.0.checked_add_signed(d).map(Datetime)
+	}
+	/// Subtract a Duration, returning None if the result is out of range
+	pub fn checked_sub(&self, d: &Duration) -> Option<Datetime> {
+		let d = chrono::Duration::from_std(d.0).ok()?;
+		self.0.checked_sub_signed(d).map(Datetime)
+	}
 }
 
 impl Display for Datetime {
@@ -234,7 +244,7 @@ fn nanosecond(i: &str) -> IResult<&str, u32> {
 	Ok((i, v))
 }
 
-fn zone(i: &str) -> IResult<&str, FixedOffset> {
+pub(crate) fn zone(i: &str) -> IResult<&str, FixedOffset> {
 	alt((zone_utc, zone_all))(i)
 }
 
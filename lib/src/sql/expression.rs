@@ -86,6 +86,19 @@ impl Expression {
 			_ => {} // Continue
 		}
 		let r = self.r.compute(ctx, opt).await?;
+		// Check that the values are of the same type, if strict types are enabled
+		if opt.types {
+			match self.o {
+				Operator::Equal
+				| Operator::Exact
+				| Operator::NotEqual
+				| Operator::LessThan
+				| Operator::LessThanOrEqual
+				| Operator::MoreThan
+				| Operator::MoreThanOrEqual => fnc::operate::comparable(&self.o, &l, &r)?,
+				_ => (),
+			}
+		}
 		match self.o {
 			Operator::Or => fnc::operate::or(l, r),
 			Operator::And => fnc::operate::and(l, r),
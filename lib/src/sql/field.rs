@@ -9,6 +9,7 @@ use crate::sql::fmt::Fmt;
 use crate::sql::idiom::{plain as idiom, Idiom};
 use crate::sql::part::Part;
 use crate::sql::value::{value, Value};
+use futures::future::try_join_all;
 use nom::branch::alt;
 use nom::bytes::complete::tag_no_case;
 use nom::multi::separated_list1;
@@ -114,6 +115,18 @@ impl Fields {
 								true => out = x,
 							}
 						}
+						// This expression is a window function, which is computed
+						// over the result set using the arguments of each row
+						Value::Function(f) if f.is_window() => {
+							let x = try_join_all(f.args().iter().map(|v| v.compute(&ctx, opt)))
+								.await?
+								.into();
+							// Check if this is a single VALUE field expression
+							match self.single().is_some() {
+								false => out.set(&ctx, opt, idiom.as_ref(), x).await?,
+								true => out = x,
+							}
+						}
 						// This expression is a multi-output graph traversal
 						Value::Idiom(v) if v.is_multi_yield() => {
 							// Store the different output yields here
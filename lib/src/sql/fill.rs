@@ -0,0 +1,98 @@
+use crate::sql::comment::shouldbespace;
+use crate::sql::error::IResult;
+use crate::sql::field::{Field, Fields};
+use crate::sql::function::Function;
+use crate::sql::idiom::Idiom;
+use crate::sql::value::Value;
+use crate::sql::Duration;
+use nom::branch::alt;
+use nom::bytes::complete::tag_no_case;
+use nom::combinator::map;
+use serde::{Deserialize, Serialize};
+use std::fmt::{self, Display, Formatter};
+
+/// How the missing time buckets of a grouped query are filled
+#[derive(Clone, Copy, Debug, Default, Eq, PartialEq, PartialOrd, Serialize, Deserialize, Hash)]
+pub enum Fill {
+	/// Missing buckets have no values
+	#[default]
+	None,
+	/// Missing buckets have the values of the previous bucket
+	Previous,
+	/// Missing buckets have values interpolated between the surrounding buckets
+	Linear,
+}
+
+impl Display for Fill {
+	fn fmt(&self, f: &mut Formatter) -> fmt::Result {
+		match self {
+			Self::None => f.write_str("FILL NONE"),
+			Self::Previous => f.write_str("FILL PREVIOUS"),
+			Self::Linear => f.write_str("FILL LINEAR"),
+		}
+	}
+}
+
+/// Get the duration of the `time::bucket()` field which is grouped by the
+/// specified idiom, if the grouped field is a `time::bucket()` expression
+pub(crate) fn bucket(fields: &Fields, group: &Idiom) -> Option<Duration> {
+	fields.iter().find_map(|field| match field {
+		Field::Single {
+			expr: Value::Function(f),
+			alias,
+		} if alias.as_ref().map_or_else(|| f.to_idiom() == *group, |a| a == group) => match &**f {
+			Function::Normal(name, args) if name == "time::bucket" => match args.get(1) {
+				Some(Value::Duration(v)) if !v.is_zero() => Some(v.clone()),
+				_ => None,
+			},
+			_ => None,
+		},
+		_ => None,
+	})
+}
+
+pub fn fill(i: &str) -> IResult<&str, Fill> {
+	let (i, _) = tag_no_case("FILL")(i)?;
+	let (i, _) = shouldbespace(i)?;
+	alt((
+		map(tag_no_case("NONE"), |_| Fill::None),
+		map(tag_no_case("PREVIOUS"), |_| Fill::Previous),
+		map(tag_no_case("LINEAR"), |_| Fill::Linear),
+	))(i)
+}
+
+#[cfg(test)]
+mod tests {
+
+	use super::*;
+
+	#[test]
+	fn fill_none() {
+		let sql = "FILL none";
+		let res = fill(sql);
+		assert!(res.is_ok());
+		let out = res.unwrap().1;
+		assert_eq!(out, Fill::None);
+		assert_eq!("FILL NONE", format!("{}", out));
+	}
+
+	#[test]
+	fn fill_previous() {
+		let sql = "FILL previous";
+		let res = fill(sql);
+		assert!(res.is_ok());
+		let out = res.unwrap().1;
+		assert_eq!(out, Fill::Previous);
+		assert_eq!("FILL PREVIOUS", format!("{}", out));
+	}
+
+	#[test]
+	fn fill_linear() {
+		let sql = "FILL LINEAR";
+		let res = fill(sql);
+		assert!(res.is_ok());
+		let out = res.unwrap().1;
+		assert_eq!(out, Fill::Linear);
+		assert_eq!("FILL LINEAR", format!("{}", out));
+	}
+}
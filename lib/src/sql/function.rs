@@ -1,8 +1,9 @@
+use crate::ctx::sandbox::Kind;
 use crate::ctx::Context;
-use crate::dbs::Options;
+use crate::dbs::{Level, Options};
 use crate::err::Error;
 use crate::fnc;
-use crate::sql::comment::mightbespace;
+use crate::sql::comment::{mightbespace, shouldbespace};
 use crate::sql::common::val_char;
 use crate::sql::common::{closeparentheses, commas, openparentheses};
 use crate::sql::error::IResult;
@@ -10,13 +11,14 @@ use crate::sql::fmt::Fmt;
 use crate::sql::idiom::Idiom;
 use crate::sql::script::{script as func, Script};
 use crate::sql::value::{value, Value};
+use crate::sql::window::{window, Window};
 use async_recursion::async_recursion;
 use futures::future::try_join_all;
 use nom::branch::alt;
 use nom::bytes::complete::tag;
 use nom::bytes::complete::take_while1;
 use nom::character::complete::char;
-use nom::combinator::recognize;
+use nom::combinator::{opt, recognize};
 use nom::multi::separated_list0;
 use nom::multi::separated_list1;
 use nom::sequence::preceded;
@@ -32,6 +34,7 @@ pub enum Function {
 	Normal(String, Vec<Value>),
 	Custom(String, Vec<Value>),
 	Script(Script, Vec<Value>),
+	Window(String, Vec<Value>, Window),
 	// Add new variants here
 }
 
@@ -48,6 +51,7 @@ impl Function {
 		match self {
 			Self::Normal(n, _) => n.as_str(),
 			Self::Custom(n, _) => n.as_str(),
+			Self::Window(n, _, _) => n.as_str(),
 			_ => unreachable!(),
 		}
 	}
@@ -56,6 +60,7 @@ impl Function {
 		match self {
 			Self::Normal(_, a) => a,
 			Self::Custom(_, a) => a,
+			Self::Window(_, a, _) => a,
 			_ => &[],
 		}
 	}
@@ -65,6 +70,7 @@ impl Function {
 			Self::Script(_, _) => "function".to_string().into(),
 			Self::Normal(f, _) => f.to_owned().into(),
 			Self::Custom(f, _) => format!("fn::{f}").into(),
+			Self::Window(f, _, _) => f.to_owned().into(),
 		}
 	}
 	/// Convert this function to an aggregate
@@ -88,6 +94,21 @@ impl Function {
 	pub fn is_custom(&self) -> bool {
 		matches!(self, Self::Custom(_, _))
 	}
+	/// Check if this function may write to the datastore, as custom
+	/// functions can run any statement, and taking the next value from
+	/// a sequence stores the value. Embedded scripts are read-only, so
+	/// they can not write unless the statement is already writeable.
+	pub(crate) fn is_writeable(&self) -> bool {
+		match self {
+			Self::Normal(name, _) => name == "sequence::next",
+			Self::Custom(_, _) => true,
+			Self::Script(_, _) | Self::Window(_, _, _) => false,
+		}
+	}
+	/// Check if this function is a window function
+	pub fn is_window(&self) -> bool {
+		matches!(self, Self::Window(_, _, _))
+	}
 	/// Check if this function is a rolling function
 	pub fn is_rolling(&self) -> bool {
 		match self {
@@ -139,6 +160,12 @@ impl Function {
 		// Process the function type
 		match self {
 			Self::Normal(s, x) => {
+				// Outbound requests can only be made by database users, or
+				// by the events, futures, and functions which they define
+				if s.starts_with("http::") && !opt.auth.check(Level::Db) && ctx.sandbox().is_none()
+				{
+					return Err(Error::QueryPermissions);
+				}
 				// Compute the function arguments
 				let a = try_join_all(x.iter().map(|v| v.compute(ctx, opt))).await?;
 				// Run the normal function
@@ -172,8 +199,11 @@ impl Function {
 				for (val, (name, kind)) in a.into_iter().zip(val.args) {
 					ctx.add_value(name.to_raw(), val.coerce_to(&kind)?);
 				}
+				// Limit the resources used by the function
+				let sandbox = ctx.add_sandbox(Kind::Function, format!("fn::{}", val.name));
 				// Run the custom function
-				val.block.compute(&ctx, opt).await
+				let res = val.block.compute(&ctx, opt).await;
+				sandbox.finish(res)
 			}
 			#[allow(unused_variables)]
 			Self::Script(s, x) => {
@@ -191,6 +221,8 @@ impl Function {
 					})
 				}
 			}
+			// Window functions are computed over the whole result set
+			Self::Window(_, _, _) => Ok(Value::None),
 		}
 	}
 }
@@ -201,12 +233,13 @@ impl fmt::Display for Function {
 			Self::Normal(s, e) => write!(f, "{s}({})", Fmt::comma_separated(e)),
 			Self::Custom(s, e) => write!(f, "fn::{s}({})", Fmt::comma_separated(e)),
 			Self::Script(s, e) => write!(f, "function({}) {{{s}}}", Fmt::comma_separated(e)),
+			Self::Window(s, e, w) => write!(f, "{s}({}) {w}", Fmt::comma_separated(e)),
 		}
 	}
 }
 
 pub fn function(i: &str) -> IResult<&str, Function> {
-	alt((normal, custom, script))(i)
+	alt((normal, windowed, custom, script))(i)
 }
 
 pub fn normal(i: &str) -> IResult<&str, Function> {
@@ -214,7 +247,21 @@ pub fn normal(i: &str) -> IResult<&str, Function> {
 	let (i, _) = openparentheses(i)?;
 	let (i, a) = separated_list0(commas, value)(i)?;
 	let (i, _) = closeparentheses(i)?;
-	Ok((i, Function::Normal(s.to_string(), a)))
+	let (i, w) = opt(preceded(shouldbespace, window))(i)?;
+	match w {
+		Some(w) => Ok((i, Function::Window(s.to_string(), a, w))),
+		None => Ok((i, Function::Normal(s.to_string(), a))),
+	}
+}
+
+fn windowed(i: &str) -> IResult<&str, Function> {
+	let (i, s) = recognize(preceded(tag("window::"), function_window))(i)?;
+	let (i, _) = openparentheses(i)?;
+	let (i, a) = separated_list0(commas, value)(i)?;
+	let (i, _) = closeparentheses(i)?;
+	let (i, _) = shouldbespace(i)?;
+	let (i, w) = window(i)?;
+	Ok((i, Function::Window(s.to_string(), a, w)))
 }
 
 pub fn custom(i: &str) -> IResult<&str, Function> {
@@ -243,26 +290,36 @@ fn script(i: &str) -> IResult<&str, Function> {
 
 pub(crate) fn function_names(i: &str) -> IResult<&str, &str> {
 	recognize(alt((
-		preceded(tag("array::"), function_array),
-		preceded(tag("bytes::"), function_bytes),
-		preceded(tag("crypto::"), function_crypto),
-		preceded(tag("duration::"), function_duration),
-		preceded(tag("encoding::"), function_encoding),
-		preceded(tag("geo::"), function_geo),
-		preceded(tag("http::"), function_http),
-		preceded(tag("is::"), function_is),
-		preceded(tag("math::"), function_math),
-		preceded(tag("meta::"), function_meta),
-		preceded(tag("parse::"), function_parse),
-		preceded(tag("rand::"), function_rand),
-		preceded(tag("session::"), function_session),
-		preceded(tag("string::"), function_string),
-		preceded(tag("time::"), function_time),
-		preceded(tag("type::"), function_type),
-		tag("count"),
-		tag("not"),
-		tag("rand"),
-		tag("sleep"),
+		alt((
+			preceded(tag("array::"), function_array),
+			preceded(tag("bytes::"), function_bytes),
+			preceded(tag("crypto::"), function_crypto),
+			preceded(tag("duration::"), function_duration),
+			preceded(tag("encoding::"), function_encoding),
+			preceded(tag("geo::"), function_geo),
+			preceded(tag("graph::"), function_graph),
+			preceded(tag("http::"), function_http),
+			preceded(tag("is::"), function_is),
+			preceded(tag("math::"), function_math),
+			preceded(tag("meta::"), function_meta),
+		)),
+		alt((
+			preceded(tag("object::"), function_object),
+			preceded(tag("parse::"), function_parse),
+			preceded(tag("rand::"), function_rand),
+			preceded(tag("search::"), function_search),
+			preceded(tag("sequence::"), function_sequence),
+			preceded(tag("session::"), function_session),
+			preceded(tag("string::"), function_string),
+			preceded(tag("time::"), function_time),
+			preceded(tag("type::"), function_type),
+			preceded(tag("vector::"), function_vector),
+			tag("count"),
+			tag("fuzzy"),
+			tag("not"),
+			tag("rand"),
+			tag("sleep"),
+		)),
 	)))(i)
 }
 
@@ -288,6 +345,7 @@ fn function_array(i: &str) -> IResult<&str, &str> {
 			tag("len"),
 			tag("max"),
 			tag("min"),
+			tag("pivot"),
 			tag("pop"),
 			tag("prepend"),
 			tag("push"),
@@ -310,6 +368,7 @@ fn function_crypto(i: &str) -> IResult<&str, &str> {
 	alt((
 		preceded(tag("argon2::"), alt((tag("compare"), tag("generate")))),
 		preceded(tag("bcrypt::"), alt((tag("compare"), tag("generate")))),
+		preceded(tag("hmac::"), alt((tag("sha1"), tag("sha256"), tag("sha512")))),
 		preceded(tag("pbkdf2::"), alt((tag("compare"), tag("generate")))),
 		preceded(tag("scrypt::"), alt((tag("compare"), tag("generate")))),
 		tag("md5"),
@@ -360,6 +419,10 @@ fn function_geo(i: &str) -> IResult<&str, &str> {
 	))(i)
 }
 
+fn function_graph(i: &str) -> IResult<&str, &str> {
+	alt((tag("shortest_path"),))(i)
+}
+
 fn function_http(i: &str) -> IResult<&str, &str> {
 	alt((tag("head"), tag("get"), tag("put"), tag("post"), tag("patch"), tag("delete")))(i)
 }
@@ -386,28 +449,42 @@ fn function_math(i: &str) -> IResult<&str, &str> {
 	alt((
 		alt((
 			tag("abs"),
+			tag("acos"),
+			tag("asin"),
+			tag("atan"),
 			tag("bottom"),
 			tag("ceil"),
+			tag("cos"),
+			tag("cot"),
+			tag("deg2rad"),
 			tag("fixed"),
 			tag("floor"),
 			tag("interquartile"),
+			tag("ln"),
+			tag("log10"),
+			tag("log2"),
+			tag("log"),
 			tag("max"),
 			tag("mean"),
 			tag("median"),
 			tag("midhinge"),
-			tag("min"),
-			tag("mode"),
 		)),
 		alt((
+			tag("min"),
+			tag("mode"),
 			tag("nearestrank"),
 			tag("percentile"),
 			tag("pow"),
 			tag("product"),
+			tag("rad2deg"),
 			tag("round"),
+			tag("sign"),
+			tag("sin"),
 			tag("spread"),
 			tag("sqrt"),
 			tag("stddev"),
 			tag("sum"),
+			tag("tan"),
 			tag("top"),
 			tag("trimean"),
 			tag("variance"),
@@ -419,6 +496,12 @@ fn function_meta(i: &str) -> IResult<&str, &str> {
 	alt((tag("id"), tag("table"), tag("tb")))(i)
 }
 
+fn function_object(i: &str) -> IResult<&str, &str> {
+	alt((tag("entries"), tag("from_entries"), tag("keys"), tag("len"), tag("merge"), tag("values")))(
+		i,
+	)
+}
+
 fn function_parse(i: &str) -> IResult<&str, &str> {
 	alt((
 		preceded(tag("email::"), alt((tag("host"), tag("user")))),
@@ -453,6 +536,14 @@ fn function_rand(i: &str) -> IResult<&str, &str> {
 	))(i)
 }
 
+fn function_search(i: &str) -> IResult<&str, &str> {
+	alt((tag("analyze"),))(i)
+}
+
+fn function_sequence(i: &str) -> IResult<&str, &str> {
+	alt((tag("next"),))(i)
+}
+
 fn function_session(i: &str) -> IResult<&str, &str> {
 	alt((
 		tag("db"),
@@ -470,6 +561,7 @@ fn function_string(i: &str) -> IResult<&str, &str> {
 	alt((
 		tag("concat"),
 		tag("contains"),
+		tag("distance::levenshtein"),
 		tag("endsWith"),
 		tag("join"),
 		tag("len"),
@@ -477,6 +569,8 @@ fn function_string(i: &str) -> IResult<&str, &str> {
 		tag("repeat"),
 		tag("replace"),
 		tag("reverse"),
+		tag("similarity::fuzzy"),
+		tag("similarity::trigram"),
 		tag("slice"),
 		tag("slug"),
 		tag("split"),
@@ -489,6 +583,7 @@ fn function_string(i: &str) -> IResult<&str, &str> {
 
 fn function_time(i: &str) -> IResult<&str, &str> {
 	alt((
+		tag("bucket"),
 		tag("ceil"),
 		tag("day"),
 		tag("floor"),
@@ -508,11 +603,13 @@ fn function_time(i: &str) -> IResult<&str, &str> {
 		tag("yday"),
 		tag("year"),
 		preceded(tag("from::"), alt((tag("micros"), tag("millis"), tag("secs"), tag("unix")))),
+		preceded(tag("tz::"), alt((tag("format"), tag("group")))),
 	))(i)
 }
 
 fn function_type(i: &str) -> IResult<&str, &str> {
 	alt((
+		preceded(tag("is::"), function_type_is),
 		tag("bool"),
 		tag("datetime"),
 		tag("decimal"),
@@ -527,6 +624,49 @@ fn function_type(i: &str) -> IResult<&str, &str> {
 	))(i)
 }
 
+fn function_type_is(i: &str) -> IResult<&str, &str> {
+	alt((
+		tag("array"),
+		tag("bool"),
+		tag("bytes"),
+		tag("datetime"),
+		tag("decimal"),
+		tag("duration"),
+		tag("float"),
+		tag("geometry"),
+		tag("int"),
+		tag("none"),
+		tag("null"),
+		tag("number"),
+		tag("object"),
+		tag("point"),
+		tag("record"),
+		tag("string"),
+		tag("uuid"),
+	))(i)
+}
+
+fn function_vector(i: &str) -> IResult<&str, &str> {
+	alt((
+		tag("add"),
+		tag("angle"),
+		tag("cross"),
+		preceded(tag("distance::"), alt((tag("chebyshev"), tag("euclidean"), tag("manhattan")))),
+		tag("divide"),
+		tag("dot"),
+		tag("magnitude"),
+		tag("multiply"),
+		tag("normalize"),
+		tag("project"),
+		preceded(tag("similarity::"), alt((tag("cosine"), tag("euclidean")))),
+		tag("subtract"),
+	))(i)
+}
+
+fn function_window(i: &str) -> IResult<&str, &str> {
+	alt((tag("dense_rank"), tag("lag"), tag("lead"), tag("rank"), tag("row_number")))(i)
+}
+
 #[cfg(test)]
 mod tests {
 
@@ -591,4 +731,45 @@ mod tests {
 			)
 		);
 	}
+
+	#[test]
+	fn function_window() {
+		let sql = "window::rank() OVER (PARTITION BY country ORDER BY score DESC)";
+		let res = function(sql);
+		assert!(res.is_ok());
+		let out = res.unwrap().1;
+		assert_eq!(
+			"window::rank() OVER (PARTITION BY country ORDER BY score DESC)",
+			format!("{}", out)
+		);
+		assert!(out.is_window());
+		assert_eq!(out.name(), "window::rank");
+	}
+
+	#[test]
+	fn function_window_requires_over() {
+		let sql = "window::row_number()";
+		let res = function(sql);
+		assert!(res.is_err());
+	}
+
+	#[test]
+	fn function_window_aggregate() {
+		let sql = "math::sum(total) OVER (ORDER BY time)";
+		let res = function(sql);
+		assert!(res.is_ok());
+		let out = res.unwrap().1;
+		assert_eq!("math::sum(total) OVER (ORDER BY time)", format!("{}", out));
+		assert_eq!(
+			out,
+			Function::Window(
+				String::from("math::sum"),
+				vec![Value::parse("total")],
+				Window {
+					partition: None,
+					order: Some(crate::sql::order::order("ORDER BY time").unwrap().1),
+				}
+			)
+		);
+	}
 }
@@ -1,3 +1,4 @@
+use crate::ctx::sandbox::Kind;
 use crate::ctx::Context;
 use crate::dbs::Options;
 use crate::err::Error;
@@ -29,7 +30,15 @@ impl Future {
 		let opt = &opt.dive(1)?;
 		// Process the future if enabled
 		match opt.futures {
-			true => self.0.compute(ctx, opt).await?.ok(),
+			true => {
+				// Limit the resources used by the future
+				let mut ctx = Context::new(ctx);
+				let name = ctx.thing().map(|v| v.to_string()).unwrap_or_default();
+				let sandbox = ctx.add_sandbox(Kind::Future, name);
+				// Run the future within the sandbox
+				let res = self.0.compute(&ctx, opt).await;
+				sandbox.finish(res)?.ok()
+			}
 			false => Ok(self.clone().into()),
 		}
 	}
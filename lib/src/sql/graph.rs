@@ -12,10 +12,11 @@ use crate::sql::split::Splits;
 use crate::sql::start::Start;
 use crate::sql::table::{table, tables, Tables};
 use nom::branch::alt;
-use nom::bytes::complete::tag_no_case;
-use nom::character::complete::char;
+use nom::bytes::complete::{tag, tag_no_case};
+use nom::character::complete::{char, u64};
 use nom::combinator::map;
 use nom::combinator::opt;
+use nom::combinator::verify;
 use serde::{Deserialize, Serialize};
 use std::fmt::{self, Display, Formatter, Write};
 
@@ -31,6 +32,8 @@ pub struct Graph {
 	pub limit: Option<Limit>,
 	pub start: Option<Start>,
 	pub alias: Option<Idiom>,
+	/// The minimum and maximum number of edges of a recursive traversal
+	pub recurse: Option<(u64, u64)>,
 }
 
 impl Graph {
@@ -47,7 +50,7 @@ impl Display for Graph {
 			match self.what.len() {
 				0 => f.write_char('?'),
 				_ => Display::fmt(&self.what, f),
-			}
+			}?;
 		} else {
 			write!(f, "{}(", self.dir)?;
 			match self.what.len() {
@@ -75,7 +78,12 @@ impl Display for Graph {
 			if let Some(ref v) = self.alias {
 				write!(f, " AS {v}")?
 			}
-			f.write_char(')')
+			f.write_char(')')?;
+		}
+		match self.recurse {
+			Some((min, max)) if min == max => write!(f, "*{min}"),
+			Some((min, max)) => write!(f, "*{min}..{max}"),
+			None => Ok(()),
 		}
 	}
 }
@@ -83,6 +91,7 @@ impl Display for Graph {
 pub fn graph(i: &str) -> IResult<&str, Graph> {
 	let (i, dir) = dir(i)?;
 	let (i, (what, cond, alias)) = alt((simple, custom))(i)?;
+	let (i, recurse) = opt(recurse)(i)?;
 	Ok((
 		i,
 		Graph {
@@ -96,10 +105,24 @@ pub fn graph(i: &str) -> IResult<&str, Graph> {
 			order: None,
 			limit: None,
 			start: None,
+			recurse,
 		},
 	))
 }
 
+fn recurse(i: &str) -> IResult<&str, (u64, u64)> {
+	let (i, _) = char('*')(i)?;
+	alt((
+		|i| {
+			let (i, min) = opt(u64)(i)?;
+			let (i, _) = tag("..")(i)?;
+			let (i, max) = verify(u64, |v| *v >= min.unwrap_or(1))(i)?;
+			Ok((i, (min.unwrap_or(1), max)))
+		},
+		map(u64, |v| (v, v)),
+	))(i)
+}
+
 fn simple(i: &str) -> IResult<&str, (Tables, Option<Cond>, Option<Idiom>)> {
 	let (i, w) = alt((any, one))(i)?;
 	Ok((i, (w, None, None)))
@@ -200,4 +223,33 @@ mod tests {
 		let out = res.unwrap().1;
 		assert_eq!("->(likes, follows WHERE influencer = true AS connections)", format!("{}", out));
 	}
+
+	#[test]
+	fn graph_recurse() {
+		let sql = "->parent*1..5";
+		let res = graph(sql);
+		assert!(res.is_ok());
+		let out = res.unwrap().1;
+		assert_eq!(out.recurse, Some((1, 5)));
+		assert_eq!("->parent*1..5", format!("{}", out));
+	}
+
+	#[test]
+	fn graph_recurse_exact() {
+		let sql = "<-(manages WHERE active = true)*3";
+		let res = graph(sql);
+		assert!(res.is_ok());
+		let out = res.unwrap().1;
+		assert_eq!(out.recurse, Some((3, 3)));
+		assert_eq!("<-(manages WHERE active = true)*3", format!("{}", out));
+	}
+
+	#[test]
+	fn graph_recurse_max() {
+		let sql = "->parent*..4";
+		let res = graph(sql);
+		assert!(res.is_ok());
+		let out = res.unwrap().1;
+		assert_eq!("->parent*1..4", format!("{}", out));
+	}
 }
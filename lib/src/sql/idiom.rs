@@ -403,6 +403,7 @@ mod tests {
 					order: None,
 					limit: None,
 					start: None,
+					recurse: None,
 				}),
 				Part::Graph(Graph {
 					dir: Dir::Out,
@@ -415,6 +416,7 @@ mod tests {
 					order: None,
 					limit: None,
 					start: None,
+					recurse: None,
 				}),
 			])
 		);
@@ -23,6 +23,32 @@ pub enum Index {
 		sc: Scoring,
 		order: u32,
 	},
+	/// Index with approximate nearest neighbour search over vectors
+	Hnsw {
+		dimension: u32,
+		dist: Distance,
+		m: u32,
+		efc: u32,
+	},
+}
+
+/// The distance function used by a vector index
+#[derive(Clone, Copy, Debug, Default, Eq, PartialEq, Serialize, Deserialize, Hash)]
+pub enum Distance {
+	#[default]
+	Euclidean,
+	Cosine,
+	Manhattan,
+}
+
+impl fmt::Display for Distance {
+	fn fmt(&self, f: &mut fmt::Formatter) -> fmt::Result {
+		match self {
+			Self::Euclidean => f.write_str("EUCLIDEAN"),
+			Self::Cosine => f.write_str("COSINE"),
+			Self::Manhattan => f.write_str("MANHATTAN"),
+		}
+	}
 }
 
 impl Default for Index {
@@ -48,12 +74,18 @@ impl fmt::Display for Index {
 				}
 				Ok(())
 			}
+			Self::Hnsw {
+				dimension,
+				dist,
+				m,
+				efc,
+			} => write!(f, "HNSW DIMENSION {} DIST {} M {} EFC {}", dimension, dist, m, efc),
 		}
 	}
 }
 
 pub fn index(i: &str) -> IResult<&str, Index> {
-	alt((unique, search, non_unique))(i)
+	alt((unique, search, hnsw, non_unique))(i)
 }
 
 pub fn non_unique(i: &str) -> IResult<&str, Index> {
@@ -105,3 +137,40 @@ pub fn search(i: &str) -> IResult<&str, Index> {
 		},
 	))
 }
+
+pub fn distance(i: &str) -> IResult<&str, Distance> {
+	let (i, _) = mightbespace(i)?;
+	let (i, _) = tag_no_case("DIST")(i)?;
+	let (i, _) = shouldbespace(i)?;
+	alt((
+		map(tag_no_case("EUCLIDEAN"), |_| Distance::Euclidean),
+		map(tag_no_case("COSINE"), |_| Distance::Cosine),
+		map(tag_no_case("MANHATTAN"), |_| Distance::Manhattan),
+	))(i)
+}
+
+fn hnsw_param<'a>(name: &'static str) -> impl FnMut(&'a str) -> IResult<&'a str, u32> {
+	move |i| {
+		let (i, _) = mightbespace(i)?;
+		let (i, _) = tag_no_case(name)(i)?;
+		let (i, _) = shouldbespace(i)?;
+		u32(i)
+	}
+}
+
+pub fn hnsw(i: &str) -> IResult<&str, Index> {
+	let (i, _) = tag_no_case("HNSW")(i)?;
+	let (i, dimension) = hnsw_param("DIMENSION")(i)?;
+	let (i, dist) = opt(distance)(i)?;
+	let (i, m) = opt(hnsw_param("M"))(i)?;
+	let (i, efc) = opt(hnsw_param("EFC"))(i)?;
+	Ok((
+		i,
+		Index::Hnsw {
+			dimension,
+			dist: dist.unwrap_or_default(),
+			m: m.unwrap_or(12),
+			efc: efc.unwrap_or(150),
+		},
+	))
+}
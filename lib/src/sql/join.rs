@@ -0,0 +1,131 @@
+use crate::sql::comment::shouldbespace;
+use crate::sql::error::IResult;
+use crate::sql::expression::Expression;
+use crate::sql::function::Function;
+use crate::sql::idiom::Idiom;
+use crate::sql::param::Param;
+use crate::sql::part::Part;
+use crate::sql::table::{table, Table};
+use crate::sql::value::{value, Value};
+use nom::bytes::complete::tag_no_case;
+use nom::combinator::opt;
+use nom::sequence::terminated;
+use serde::{Deserialize, Serialize};
+use std::fmt;
+
+/// The parameter which holds the record of the selected table,
+/// while the records of the joined table are matched with it
+pub(crate) const LEFT: &str = "join";
+
+/// The JOIN clause of a SELECT statement, which matches each record of the
+/// selected table with the records of another table, for which the ON
+/// condition is truthy. Within the condition, and within the rest of the
+/// statement, the fields of each record are accessed by its table name.
+#[derive(Clone, Debug, Default, Eq, PartialEq, PartialOrd, Serialize, Deserialize, Hash)]
+pub struct Join {
+	pub what: Table,
+	pub cond: Value,
+}
+
+impl Join {
+	/// Get the condition which selects the records of the joined table for
+	/// a record of the selected table. The fields of the selected table are
+	/// read from the `$join` parameter, and the fields of the joined table
+	/// from each of its records, so that a comparison of an indexed field of
+	/// the joined table is planned as an index lookup for each record.
+	pub(crate) fn condition(&self, left: &Table) -> Value {
+		rewrite(&self.cond, &left.0, &self.what.0)
+	}
+}
+
+/// Rewrite the idioms of a condition which start with a table name
+fn rewrite(v: &Value, left: &str, right: &str) -> Value {
+	match v {
+		Value::Idiom(i) => match i.first() {
+			Some(Part::Field(f)) if f.0 == left => match i.len() {
+				1 => Value::from(Param::from(LEFT)),
+				_ => {
+					let mut parts = vec![Part::Value(Value::from(Param::from(LEFT)))];
+					parts.extend(i[1..].iter().cloned());
+					Value::from(Idiom(parts))
+				}
+			},
+			Some(Part::Field(f)) if f.0 == right => match i.len() {
+				1 => Value::from(Param::from("this")),
+				_ => Value::from(Idiom(i[1..].to_vec())),
+			},
+			_ => v.clone(),
+		},
+		Value::Expression(e) => Value::from(Expression::new(
+			rewrite(&e.l, left, right),
+			e.o.clone(),
+			rewrite(&e.r, left, right),
+		)),
+		Value::Array(a) => {
+			Value::from(a.iter().map(|v| rewrite(v, left, right)).collect::<Vec<_>>())
+		}
+		Value::Function(f) => match f.as_ref() {
+			Function::Normal(name, args) => Value::from(Function::Normal(
+				name.clone(),
+				args.iter().map(|v| rewrite(v, left, right)).collect(),
+			)),
+			_ => v.clone(),
+		},
+		_ => v.clone(),
+	}
+}
+
+impl fmt::Display for Join {
+	fn fmt(&self, f: &mut fmt::Formatter) -> fmt::Result {
+		write!(f, "JOIN {} ON {}", self.what, self.cond)
+	}
+}
+
+pub fn join(i: &str) -> IResult<&str, Join> {
+	let (i, _) = opt(terminated(tag_no_case("INNER"), shouldbespace))(i)?;
+	let (i, _) = tag_no_case("JOIN")(i)?;
+	let (i, _) = shouldbespace(i)?;
+	let (i, what) = table(i)?;
+	let (i, _) = shouldbespace(i)?;
+	let (i, _) = tag_no_case("ON")(i)?;
+	let (i, _) = shouldbespace(i)?;
+	let (i, cond) = value(i)?;
+	Ok((
+		i,
+		Join {
+			what,
+			cond,
+		},
+	))
+}
+
+#[cfg(test)]
+mod tests {
+
+	use super::*;
+
+	#[test]
+	fn join_statement() {
+		let sql = "JOIN company ON company.code = person.company";
+		let res = join(sql);
+		assert!(res.is_ok());
+		let out = res.unwrap().1;
+		assert_eq!("JOIN company ON company.code = person.company", format!("{}", out));
+	}
+
+	#[test]
+	fn join_statement_inner() {
+		let sql = "INNER JOIN company ON company.code = person.company";
+		let res = join(sql);
+		assert!(res.is_ok());
+		let out = res.unwrap().1;
+		assert_eq!("JOIN company ON company.code = person.company", format!("{}", out));
+	}
+
+	#[test]
+	fn join_condition() {
+		let (_, out) = join("JOIN company ON company.code = person.company AND person").unwrap();
+		let cond = out.condition(&Table::from(String::from("person")));
+		assert_eq!("code = $join.company AND $join", format!("{}", cond));
+	}
+}
@@ -35,6 +35,7 @@ pub enum Kind {
 	Either(Vec<Kind>),
 	Set(Box<Kind>, Option<u64>),
 	Array(Box<Kind>, Option<u64>),
+	Vector(u64),
 }
 
 impl Default for Kind {
@@ -47,6 +48,15 @@ impl Kind {
 	fn is_any(&self) -> bool {
 		matches!(self, Kind::Any)
 	}
+	/// Check if every value of this kind is either an array or NONE
+	pub(crate) fn is_array(&self) -> bool {
+		match self {
+			Kind::Array(..) | Kind::Set(..) | Kind::Vector(_) => true,
+			Kind::Option(k) => k.is_array(),
+			Kind::Either(k) => !k.is_empty() && k.iter().all(Kind::is_array),
+			_ => false,
+		}
+	}
 }
 
 impl From<&Kind> for Box<Kind> {
@@ -91,6 +101,7 @@ impl Display for Kind {
 				(k, None) => write!(f, "array<{k}>"),
 				(k, Some(l)) => write!(f, "array<{k}, {l}>"),
 			},
+			Kind::Vector(l) => write!(f, "vector<{l}>"),
 			Kind::Either(k) => write!(f, "{}", Fmt::verbar_separated(k)),
 		}
 	}
@@ -122,7 +133,8 @@ pub fn simple(i: &str) -> IResult<&str, Kind> {
 }
 
 fn either(i: &str) -> IResult<&str, Kind> {
-	let (i, mut v) = separated_list1(verbar, alt((simple, geometry, record, array, set)))(i)?;
+	let (i, mut v) =
+		separated_list1(verbar, alt((simple, geometry, record, array, set, vector)))(i)?;
 	match v.len() {
 		1 => Ok((i, v.remove(0))),
 		_ => Ok((i, Kind::Either(v))),
@@ -133,7 +145,7 @@ fn option(i: &str) -> IResult<&str, Kind> {
 	let (i, _) = tag("option")(i)?;
 	let (i, _) = mightbespace(i)?;
 	let (i, _) = char('<')(i)?;
-	let (i, v) = map(alt((either, simple, geometry, record, array, set)), Box::new)(i)?;
+	let (i, v) = map(alt((either, simple, geometry, record, array, set, vector)), Box::new)(i)?;
 	let (i, _) = char('>')(i)?;
 	Ok((i, Kind::Option(v)))
 }
@@ -232,6 +244,16 @@ fn set(i: &str) -> IResult<&str, Kind> {
 	))
 }
 
+fn vector(i: &str) -> IResult<&str, Kind> {
+	let (i, _) = tag("vector")(i)?;
+	let (i, _) = char('<')(i)?;
+	let (i, _) = mightbespace(i)?;
+	let (i, l) = u64(i)?;
+	let (i, _) = mightbespace(i)?;
+	let (i, _) = char('>')(i)?;
+	Ok((i, Kind::Vector(l)))
+}
+
 fn geo(i: &str) -> IResult<&str, String> {
 	map(
 		alt((
@@ -523,4 +545,14 @@ mod tests {
 		assert_eq!("set<float, 10>", format!("{}", out));
 		assert_eq!(out, Kind::Set(Box::new(Kind::Float), Some(10)));
 	}
+
+	#[test]
+	fn kind_vector() {
+		let sql = "vector< 3 >";
+		let res = kind(sql);
+		assert!(res.is_ok());
+		let out = res.unwrap().1;
+		assert_eq!("vector<3>", format!("{}", out));
+		assert_eq!(out, Kind::Vector(3));
+	}
 }
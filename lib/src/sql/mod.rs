@@ -2,10 +2,12 @@
 
 pub(crate) mod algorithm;
 pub(crate) mod array;
+pub(crate) mod audit;
 pub(crate) mod base;
 pub(crate) mod block;
 pub(crate) mod bytes;
 pub(crate) mod cast;
+pub(crate) mod collation;
 pub(crate) mod comment;
 pub(crate) mod common;
 pub(crate) mod cond;
@@ -21,6 +23,7 @@ pub(crate) mod escape;
 pub(crate) mod expression;
 pub(crate) mod fetch;
 pub(crate) mod field;
+pub(crate) mod fill;
 pub(crate) mod filter;
 pub(crate) mod fmt;
 pub(crate) mod function;
@@ -32,6 +35,7 @@ pub(crate) mod id;
 pub(crate) mod ident;
 pub(crate) mod idiom;
 pub(crate) mod index;
+pub(crate) mod join;
 pub(crate) mod kind;
 pub(crate) mod language;
 pub(crate) mod limit;
@@ -66,6 +70,7 @@ pub(crate) mod uuid;
 pub(crate) mod value;
 pub(crate) mod version;
 pub(crate) mod view;
+pub(crate) mod window;
 
 #[cfg(test)]
 pub(crate) mod test;
@@ -76,10 +81,12 @@ pub use self::parser::*;
 
 pub use self::algorithm::Algorithm;
 pub use self::array::Array;
+pub use self::audit::Audit;
 pub use self::base::Base;
 pub use self::block::Block;
 pub use self::bytes::Bytes;
 pub use self::cast::Cast;
+pub use self::collation::Collation;
 pub use self::cond::Cond;
 pub use self::data::Data;
 pub use self::datetime::Datetime;
@@ -92,6 +99,7 @@ pub use self::fetch::Fetch;
 pub use self::fetch::Fetchs;
 pub use self::field::Field;
 pub use self::field::Fields;
+pub use self::fill::Fill;
 pub use self::function::Function;
 pub use self::future::Future;
 pub use self::geometry::Geometry;
@@ -102,6 +110,7 @@ pub use self::id::Id;
 pub use self::ident::Ident;
 pub use self::idiom::Idiom;
 pub use self::idiom::Idioms;
+pub use self::join::Join;
 pub use self::kind::Kind;
 pub use self::limit::Limit;
 pub use self::model::Model;
@@ -123,6 +132,7 @@ pub use self::regex::Regex;
 pub use self::script::Script;
 pub use self::split::Split;
 pub use self::split::Splits;
+pub use self::start::After;
 pub use self::start::Start;
 pub use self::statement::Statement;
 pub use self::statement::Statements;
@@ -137,5 +147,6 @@ pub use self::value::Value;
 pub use self::value::Values;
 pub use self::version::Version;
 pub use self::view::View;
+pub use self::window::Window;
 
 pub use self::value::serde::to_value;
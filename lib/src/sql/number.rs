@@ -322,6 +322,75 @@ impl Number {
 		}
 	}
 
+	// -----------------------------------
+	// Lexical encoding of number
+	// -----------------------------------
+
+	/// Encode this number as a string which sorts in numeric order, so that
+	/// numbers of every type are ordered and compared by value in index keys
+	pub(crate) fn to_lexical(&self) -> String {
+		// Get the sign and the digits of the number
+		let (neg, repr) = match self {
+			Number::Int(v) => (*v < 0, v.unsigned_abs().to_string()),
+			Number::Float(v) if v.is_nan() => return String::from("3"),
+			Number::Float(v) if v.is_infinite() && v.is_sign_positive() => {
+				return String::from("2~")
+			}
+			Number::Float(v) if v.is_infinite() => return String::from("0!"),
+			Number::Float(v) => (v.is_sign_negative(), format!("{:e}", v.abs())),
+			Number::Decimal(v) => (v.is_sign_negative(), v.abs().to_string()),
+		};
+		match significant(&repr) {
+			// Zero sorts between the negative and the positive numbers
+			(digits, _) if digits.is_empty() => String::from("1"),
+			// Positive numbers sort by their exponent, and then their digits
+			(digits, exp) if !neg => format!("2{:03}{digits}", exp + 500),
+			// Negative numbers sort in the reverse order of their magnitude
+			(digits, exp) => {
+				let digits: String = digits.chars().map(complement).collect();
+				format!("0{:03}{digits}~", 499 - exp)
+			}
+		}
+	}
+
+	/// Decode a number which was encoded with [`Number::to_lexical`]
+	pub(crate) fn from_lexical(v: &str) -> Option<Number> {
+		let (neg, v) = match v {
+			"0!" => return Some(Number::Float(f64::NEG_INFINITY)),
+			"1" => return Some(Number::Int(0)),
+			"2~" => return Some(Number::Float(f64::INFINITY)),
+			"3" => return Some(Number::NAN),
+			v if v.starts_with('0') => (true, v.get(1..)?.strip_suffix('~')?),
+			v if v.starts_with('2') => (false, v.get(1..)?),
+			_ => return None,
+		};
+		let exp = v.get(..3)?.parse::<i32>().ok()?;
+		let (exp, digits) = match neg {
+			true => (499 - exp, v.get(3..)?.chars().map(complement).collect::<String>()),
+			false => (exp - 500, v.get(3..)?.to_owned()),
+		};
+		let sign = if neg {
+			"-"
+		} else {
+			""
+		};
+		// Integers are decoded as integers
+		if let Some(zeros) = exp.checked_sub(digits.len() as i32).filter(|v| (0..=19).contains(v)) {
+			let v = format!("{sign}{digits}{}", "0".repeat(zeros as usize));
+			if let Ok(v) = v.parse::<i64>() {
+				return Some(Number::Int(v));
+			}
+		}
+		// Other numbers are decoded as decimals where they can be represented
+		let v = format!("{sign}0.{digits}e{exp}");
+		if digits.len() as i32 - exp <= 28 && exp <= 28 {
+			if let Ok(v) = Decimal::from_scientific(&v) {
+				return Some(Number::Decimal(v));
+			}
+		}
+		v.parse::<f64>().ok().map(Number::Float)
+	}
+
 	// -----------------------------------
 	//
 	// -----------------------------------
@@ -656,6 +725,24 @@ pub fn integer(i: &str) -> IResult<&str, i64> {
 	Ok((i, v))
 }
 
+/// Get the significant digits of an unsigned number, and the exponent
+/// for which the number is equal to `0.digits × 10^exp`
+fn significant(v: &str) -> (String, i32) {
+	let (v, exp) = match v.split_once('e') {
+		Some((v, e)) => (v, e.parse::<i32>().unwrap_or_default()),
+		None => (v, 0),
+	};
+	let (int, frac) = v.split_once('.').unwrap_or((v, ""));
+	let digits = format!("{int}{frac}");
+	let lead = digits.len() - digits.trim_start_matches('0').len();
+	(digits.trim_matches('0').to_owned(), int.len() as i32 - lead as i32 + exp)
+}
+
+/// Complement a digit, so that digits sort in reverse
+fn complement(c: char) -> char {
+	(b'0' + b'9' - c as u8) as char
+}
+
 /// TODO: This slow but temporary (awaiting https://docs.rs/rust_decimal/latest/rust_decimal/ version >1.29.1)
 pub(crate) fn decimal_is_integer(decimal: &Decimal) -> bool {
 	decimal.fract().is_zero()
@@ -887,4 +974,61 @@ mod tests {
 			}
 		}
 	}
+
+	#[test]
+	fn lexical() {
+		let ordering = &[
+			f64::NEG_INFINITY,
+			-1e300,
+			-10.5,
+			-10.0,
+			-1.5,
+			-1.0,
+			-0.15,
+			-0.1,
+			-f64::MIN_POSITIVE,
+			0.0,
+			f64::MIN_POSITIVE,
+			0.1,
+			0.15,
+			1.0,
+			1.5,
+			10.0,
+			10.5,
+			1e300,
+			f64::INFINITY,
+			f64::NAN,
+		];
+
+		fn permutations(n: f64) -> Vec<Number> {
+			let mut ret = vec![Number::Float(n)];
+			if n.is_finite() && (n == 0.0 || n.abs() > 1e-10) && n.abs() < 1e20 {
+				ret.push(Number::Decimal(n.to_string().parse().unwrap()));
+			}
+			if n.is_finite() && n.fract() == 0.0 && n.abs() < 1e18 {
+				ret.push(Number::Int(n as i64));
+			}
+			ret
+		}
+
+		for (ai, a) in ordering.iter().enumerate() {
+			for (bi, b) in ordering.iter().enumerate() {
+				for a in permutations(*a) {
+					for b in permutations(*b) {
+						assert_eq!(a.to_lexical().cmp(&b.to_lexical()), ai.cmp(&bi), "{a} {b}");
+					}
+				}
+			}
+			for a in permutations(*a) {
+				let v = Number::from_lexical(&a.to_lexical()).unwrap();
+				assert_eq!(v.to_lexical(), a.to_lexical(), "{a}");
+			}
+		}
+		assert_eq!(Number::from_lexical("2501"), Some(Number::Int(1)));
+		assert_eq!(
+			Number::from_lexical("049984~"),
+			Some(Number::Decimal("-0.15".parse().unwrap()))
+		);
+		assert_eq!(Number::from_lexical("invalid"), None);
+	}
 }
@@ -1,14 +1,18 @@
+use crate::sql::collation::{collation, Collation};
 use crate::sql::comment::shouldbespace;
 use crate::sql::common::commas;
 use crate::sql::error::IResult;
 use crate::sql::fmt::Fmt;
 use crate::sql::idiom::{basic, Idiom};
+use crate::sql::part::Part;
+use crate::sql::value::Value;
 use nom::branch::alt;
 use nom::bytes::complete::tag_no_case;
 use nom::combinator::{map, opt};
 use nom::multi::separated_list1;
-use nom::sequence::tuple;
+use nom::sequence::{preceded, tuple};
 use serde::{Deserialize, Serialize};
+use std::cmp::Ordering;
 use std::fmt;
 use std::ops::Deref;
 
@@ -42,9 +46,25 @@ pub struct Order {
 	pub random: bool,
 	pub collate: bool,
 	pub numeric: bool,
+	pub collation: Option<Collation>,
 	pub direction: bool,
 }
 
+impl Order {
+	/// Compare two records by the value of the ordered field
+	pub(crate) fn compare(&self, a: &Value, b: &Value) -> Option<Ordering> {
+		self.compare_at(a, b, &self.order)
+	}
+
+	/// Compare two values at a path, with the collation of this ordering
+	pub(crate) fn compare_at(&self, a: &Value, b: &Value, path: &[Part]) -> Option<Ordering> {
+		match &self.collation {
+			Some(c) => a.compare_by(b, path, &|a, b| c.compare(a, b)),
+			None => a.compare(b, path, self.collate, self.numeric),
+		}
+	}
+}
+
 impl Deref for Order {
 	type Target = Idiom;
 	fn deref(&self) -> &Self::Target {
@@ -58,11 +78,15 @@ impl fmt::Display for Order {
 		if self.random {
 			write!(f, "RAND()")?;
 		}
-		if self.collate {
-			write!(f, " COLLATE")?;
-		}
-		if self.numeric {
-			write!(f, " NUMERIC")?;
+		if let Some(ref v) = self.collation {
+			write!(f, " {v}")?;
+		} else {
+			if self.collate {
+				write!(f, " COLLATE")?;
+			}
+			if self.numeric {
+				write!(f, " NUMERIC")?;
+			}
 		}
 		match self.direction {
 			false => write!(f, " DESC")?,
@@ -89,6 +113,7 @@ fn order_rand(i: &str) -> IResult<&str, Vec<Order>> {
 			random: true,
 			collate: false,
 			numeric: false,
+			collation: None,
 			direction: true,
 		}],
 	))
@@ -96,8 +121,11 @@ fn order_rand(i: &str) -> IResult<&str, Vec<Order>> {
 
 fn order_raw(i: &str) -> IResult<&str, Order> {
 	let (i, v) = basic(i)?;
-	let (i, c) = opt(tuple((shouldbespace, tag_no_case("COLLATE"))))(i)?;
-	let (i, n) = opt(tuple((shouldbespace, tag_no_case("NUMERIC"))))(i)?;
+	let (i, c) = opt(preceded(shouldbespace, collation))(i)?;
+	let (i, n) = match c {
+		Some(ref c) => (i, c.numeric),
+		None => map(opt(tuple((shouldbespace, tag_no_case("NUMERIC")))), |v| v.is_some())(i)?,
+	};
 	let (i, d) = opt(alt((
 		map(tuple((shouldbespace, tag_no_case("ASC"))), |_| true),
 		map(tuple((shouldbespace, tag_no_case("DESC"))), |_| false),
@@ -108,7 +136,9 @@ fn order_raw(i: &str) -> IResult<&str, Order> {
 			order: v,
 			random: false,
 			collate: c.is_some(),
-			numeric: n.is_some(),
+			numeric: n,
+			// A plain COLLATE clause keeps the lexical ordering of strings
+			collation: c.filter(|c| c.locale.is_some() || c.insensitive),
 			direction: d.unwrap_or(true),
 		},
 	))
@@ -133,6 +163,7 @@ mod tests {
 				random: false,
 				collate: false,
 				numeric: false,
+				collation: None,
 				direction: true,
 			}])
 		);
@@ -152,6 +183,7 @@ mod tests {
 				random: false,
 				collate: false,
 				numeric: false,
+				collation: None,
 				direction: true,
 			}])
 		);
@@ -171,6 +203,7 @@ mod tests {
 				random: true,
 				collate: false,
 				numeric: false,
+				collation: None,
 				direction: true,
 			}])
 		);
@@ -191,6 +224,7 @@ mod tests {
 					random: false,
 					collate: false,
 					numeric: false,
+					collation: None,
 					direction: true,
 				},
 				Order {
@@ -198,6 +232,7 @@ mod tests {
 					random: false,
 					collate: false,
 					numeric: false,
+					collation: None,
 					direction: true,
 				},
 			])
@@ -218,6 +253,7 @@ mod tests {
 				random: false,
 				collate: true,
 				numeric: false,
+				collation: None,
 				direction: true,
 			}])
 		);
@@ -237,6 +273,7 @@ mod tests {
 				random: false,
 				collate: false,
 				numeric: true,
+				collation: None,
 				direction: true,
 			}])
 		);
@@ -256,6 +293,7 @@ mod tests {
 				random: false,
 				collate: false,
 				numeric: false,
+				collation: None,
 				direction: false,
 			}])
 		);
@@ -275,9 +313,34 @@ mod tests {
 				random: false,
 				collate: true,
 				numeric: true,
+				collation: None,
 				direction: false,
 			}])
 		);
 		assert_eq!("ORDER BY field COLLATE NUMERIC DESC", format!("{}", out));
 	}
+
+	#[test]
+	fn order_statement_collation() {
+		let sql = "ORDER field COLLATE 'sv' CI NUMERIC DESC";
+		let res = order(sql);
+		assert!(res.is_ok());
+		let out = res.unwrap().1;
+		assert_eq!(
+			out,
+			Orders(vec![Order {
+				order: Idiom::parse("field"),
+				random: false,
+				collate: true,
+				numeric: true,
+				collation: Some(Collation {
+					locale: Some("sv".to_owned()),
+					insensitive: true,
+					numeric: true,
+				}),
+				direction: false,
+			}])
+		);
+		assert_eq!("ORDER BY field COLLATE 'sv' CI NUMERIC DESC", format!("{}", out));
+	}
 }
@@ -76,6 +76,11 @@ fn parse_impl<O>(input: &str, parser: impl Fn(&str) -> IResult<&str, O>) -> Resu
 					line: locate(input, e).1,
 					field: f,
 				},
+				// There was a FILL error
+				Fill(e, f) => Error::InvalidFill {
+					line: locate(input, e).1,
+					field: f,
+				},
 			}),
 			_ => unreachable!(),
 		},
@@ -24,3 +24,5 @@ pub static OUT: Lazy<[Part; 1]> = Lazy::new(|| [Part::from("out")]);
 pub static META: Lazy<[Part; 1]> = Lazy::new(|| [Part::from("__")]);
 
 pub static EDGE: Lazy<[Part; 1]> = Lazy::new(|| [Part::from("__")]);
+
+pub static DELETED: Lazy<[Part; 1]> = Lazy::new(|| [Part::from("deleted_at")]);
@@ -55,6 +55,22 @@ impl Permissions {
 			&& self.update == Permission::Full
 			&& self.delete == Permission::Full
 	}
+
+	/// Describe these permissions as a structured value, where each
+	/// permission is true, false, or the clause which must match
+	pub(crate) fn structure(&self) -> Value {
+		let v = |v: &Permission| match v {
+			Permission::None => Value::from(false),
+			Permission::Full => Value::from(true),
+			Permission::Specific(v) => Value::from(v.to_string()),
+		};
+		Value::from(map! {
+			String::from("select") => v(&self.select),
+			String::from("create") => v(&self.create),
+			String::from("update") => v(&self.update),
+			String::from("delete") => v(&self.delete),
+		})
+	}
 }
 
 impl Display for Permissions {
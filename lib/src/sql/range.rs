@@ -1,15 +1,21 @@
 use crate::ctx::Context;
 use crate::dbs::Options;
 use crate::err::Error;
+use crate::sql::array::Array;
+use crate::sql::comment::mightbespace;
+use crate::sql::common::{closebracket, commas, openbracket};
 use crate::sql::error::IResult;
 use crate::sql::id::{id, Id};
 use crate::sql::ident::ident_raw;
+use crate::sql::number::integer;
 use crate::sql::strand::no_nul_bytes;
-use crate::sql::value::Value;
+use crate::sql::value::{value, Value};
 use nom::branch::alt;
+use nom::bytes::complete::tag;
 use nom::character::complete::char;
 use nom::combinator::map;
 use nom::combinator::opt;
+use nom::multi::many0;
 use nom::sequence::preceded;
 use nom::sequence::terminated;
 use serde::{Deserialize, Serialize};
@@ -140,6 +146,10 @@ impl fmt::Display for Range {
 }
 
 pub fn range(i: &str) -> IResult<&str, Range> {
+	alt((range_element, range_bounds))(i)
+}
+
+fn range_bounds(i: &str) -> IResult<&str, Range> {
 	let (i, tb) = ident_raw(i)?;
 	let (i, _) = char(':')(i)?;
 	let (i, beg) =
@@ -158,6 +168,41 @@ pub fn range(i: &str) -> IResult<&str, Range> {
 	))
 }
 
+/// Parse a range of the last element of an array record id, such as
+/// `temperature:['London', '2023-01-01'..'2023-02-01']`, which selects the
+/// same records as `temperature:['London', '2023-01-01']..['London', '2023-02-01']`
+fn range_element(i: &str) -> IResult<&str, Range> {
+	let (i, tb) = ident_raw(i)?;
+	let (i, _) = char(':')(i)?;
+	let (i, _) = openbracket(i)?;
+	let (i, prefix) = many0(terminated(value, commas))(i)?;
+	let (i, beg) = alt((map(integer, Value::from), value))(i)?;
+	let (i, _) = mightbespace(i)?;
+	let (i, _) = tag("..")(i)?;
+	let (i, inclusive) = opt(char('='))(i)?;
+	let (i, _) = mightbespace(i)?;
+	let (i, end) = alt((map(integer, Value::from), value))(i)?;
+	let (i, _) = opt(commas)(i)?;
+	let (i, _) = closebracket(i)?;
+	// Add each bound to the shared elements
+	let bound = |v: Value| {
+		let mut prefix = prefix.clone();
+		prefix.push(v);
+		Id::Array(Array(prefix))
+	};
+	Ok((
+		i,
+		Range {
+			tb,
+			beg: Bound::Included(bound(beg)),
+			end: match inclusive {
+				Some(_) => Bound::Included(bound(end)),
+				None => Bound::Excluded(bound(end)),
+			},
+		},
+	))
+}
+
 #[cfg(test)]
 mod tests {
 
@@ -181,6 +226,23 @@ mod tests {
 		assert_eq!("person:['USA', 10]..['USA', 100]", format!("{}", out));
 	}
 
+	#[test]
+	fn range_array_element() {
+		let sql = "temperature:['London', '2023-01-01'..'2023-02-01']";
+		let res = range(sql);
+		assert!(res.is_ok());
+		let out = res.unwrap().1;
+		assert_eq!(
+			"temperature:['London', '2023-01-01']..['London', '2023-02-01']",
+			format!("{}", out)
+		);
+		let sql = "person:[1..=10]";
+		let res = range(sql);
+		assert!(res.is_ok());
+		let out = res.unwrap().1;
+		assert_eq!("person:[1]..=[10]", format!("{}", out));
+	}
+
 	#[test]
 	fn range_object() {
 		let sql = "person:{ country: 'USA', position: 10 }..{ country: 'USA', position: 100 }";
@@ -1,5 +1,6 @@
 use crate::sql::error::Error;
 use crate::sql::field::{Field, Fields};
+use crate::sql::fill::{bucket, Fill};
 use crate::sql::group::Groups;
 use crate::sql::order::Orders;
 use crate::sql::split::Splits;
@@ -110,6 +111,8 @@ pub fn check_group_by_fields<'a>(
 									Value::Idiom(i) if i == &group.0 => continue 'outer,
 									// If the expression in the SELECT clause is a function, check to see if it is an aggregate function
 									Value::Function(f) if f.is_aggregate() => continue 'outer,
+									// Window functions are computed over the grouped rows
+									Value::Function(f) if f.is_window() => continue 'outer,
 									// Otherwise check if the expression itself exists in the GROUP BY clause
 									v if v.to_idiom() == group.0 => continue 'outer,
 									// Check if this is a static value which can be used in the GROUP BY clause
@@ -130,3 +133,22 @@ pub fn check_group_by_fields<'a>(
 	// This query is ok to run
 	Ok(())
 }
+
+pub fn check_fill_fields<'a>(
+	i: &'a str,
+	fields: &Fields,
+	groups: &Option<Groups>,
+	fill: &Option<Fill>,
+) -> Result<(), Err<Error<&'a str>>> {
+	// Check to see if a FILL clause has been defined
+	if fill.is_some() {
+		// The last expression in the GROUP BY clause must be a time bucket
+		if let Some(group) = groups.as_ref().and_then(|v| v.last()) {
+			if bucket(fields, &group.0).is_none() {
+				return Err(Failure(Error::Fill(i, group.to_string())));
+			}
+		}
+	}
+	// This query is ok to run
+	Ok(())
+}
@@ -1,9 +1,11 @@
 use crate::ctx::Context;
+use crate::dbs::Cursor;
 use crate::dbs::Options;
 use crate::err::Error;
 use crate::sql::comment::shouldbespace;
 use crate::sql::error::IResult;
 use crate::sql::number::Number;
+use crate::sql::order::Orders;
 use crate::sql::value::{value, Value};
 use nom::bytes::complete::tag_no_case;
 use nom::combinator::opt;
@@ -35,6 +37,44 @@ impl fmt::Display for Start {
 	}
 }
 
+#[derive(Clone, Debug, Default, Eq, PartialEq, PartialOrd, Serialize, Deserialize, Hash)]
+pub struct After(pub Value);
+
+impl After {
+	pub(crate) async fn process(
+		&self,
+		ctx: &Context<'_>,
+		opt: &Options,
+		orders: Option<&Orders>,
+	) -> Result<Cursor, Error> {
+		match self.0.compute(ctx, opt).await {
+			// This is a cursor which was returned by a previous page
+			Ok(Value::Strand(v)) => Cursor::decode(v.as_str(), orders),
+			// An invalid value was specified
+			Ok(v) => Err(Error::InvalidCursor {
+				value: v.as_string(),
+			}),
+			// A different error occured
+			Err(e) => Err(e),
+		}
+	}
+}
+
+impl fmt::Display for After {
+	fn fmt(&self, f: &mut fmt::Formatter) -> fmt::Result {
+		write!(f, "START AFTER {}", self.0)
+	}
+}
+
+pub fn after(i: &str) -> IResult<&str, After> {
+	let (i, _) = tag_no_case("START")(i)?;
+	let (i, _) = shouldbespace(i)?;
+	let (i, _) = tag_no_case("AFTER")(i)?;
+	let (i, _) = shouldbespace(i)?;
+	let (i, v) = value(i)?;
+	Ok((i, After(v)))
+}
+
 pub fn start(i: &str) -> IResult<&str, Start> {
 	let (i, _) = tag_no_case("START")(i)?;
 	let (i, _) = opt(tuple((shouldbespace, tag_no_case("AT"))))(i)?;
@@ -67,4 +107,13 @@ mod tests {
 		assert_eq!(out, Start(Value::from(100)));
 		assert_eq!("START 100", format!("{}", out));
 	}
+
+	#[test]
+	fn start_statement_after() {
+		let sql = "START AFTER $cursor";
+		let res = after(sql);
+		assert!(res.is_ok());
+		let out = res.unwrap().1;
+		assert_eq!("START AFTER $cursor", format!("{}", out));
+	}
 }
@@ -7,12 +7,18 @@ use crate::sql::error::IResult;
 use crate::sql::fmt::Fmt;
 use crate::sql::fmt::Pretty;
 use crate::sql::statements::analyze::{analyze, AnalyzeStatement};
+use crate::sql::statements::apply::{apply, ApplyStatement};
 use crate::sql::statements::begin::{begin, BeginStatement};
 use crate::sql::statements::cancel::{cancel, CancelStatement};
+use crate::sql::statements::check::{check, CheckStatement};
 use crate::sql::statements::commit::{commit, CommitStatement};
+use crate::sql::statements::copy::{copy, CopyStatement};
 use crate::sql::statements::create::{create, CreateStatement};
 use crate::sql::statements::define::{define, DefineStatement};
 use crate::sql::statements::delete::{delete, DeleteStatement};
+use crate::sql::statements::export::{export, ExportStatement};
+use crate::sql::statements::foreach::{foreach, ForeachStatement};
+use crate::sql::statements::global::{global, GlobalStatement};
 use crate::sql::statements::ifelse::{ifelse, IfelseStatement};
 use crate::sql::statements::info::{info, InfoStatement};
 use crate::sql::statements::insert::{insert, InsertStatement};
@@ -20,11 +26,15 @@ use crate::sql::statements::kill::{kill, KillStatement};
 use crate::sql::statements::live::{live, LiveStatement};
 use crate::sql::statements::option::{option, OptionStatement};
 use crate::sql::statements::output::{output, OutputStatement};
+use crate::sql::statements::purge::{purge, PurgeStatement};
+use crate::sql::statements::rebuild::{rebuild, RebuildStatement};
 use crate::sql::statements::relate::{relate, RelateStatement};
 use crate::sql::statements::remove::{remove, RemoveStatement};
 use crate::sql::statements::select::{select, SelectStatement};
 use crate::sql::statements::set::{set, SetStatement};
+use crate::sql::statements::show::{show, ShowStatement};
 use crate::sql::statements::sleep::{sleep, SleepStatement};
+use crate::sql::statements::throw::{throw, ThrowStatement};
 use crate::sql::statements::update::{update, UpdateStatement};
 use crate::sql::statements::yuse::{yuse, UseStatement};
 use crate::sql::value::Value;
@@ -38,6 +48,7 @@ use serde::{Deserialize, Serialize};
 use std::fmt::{self, Display, Formatter, Write};
 use std::ops::Deref;
 use std::time::Duration;
+use tracing::instrument;
 
 #[derive(Clone, Debug, Default, Eq, PartialEq, Serialize, Deserialize, Store, Hash)]
 pub struct Statements(pub Vec<Statement>);
@@ -75,12 +86,18 @@ pub fn statements(i: &str) -> IResult<&str, Statements> {
 #[derive(Clone, Debug, Eq, PartialEq, Serialize, Deserialize, Store, Hash)]
 pub enum Statement {
 	Analyze(AnalyzeStatement),
+	Apply(ApplyStatement),
 	Begin(BeginStatement),
 	Cancel(CancelStatement),
+	Check(CheckStatement),
 	Commit(CommitStatement),
+	Copy(CopyStatement),
 	Create(CreateStatement),
 	Define(DefineStatement),
 	Delete(DeleteStatement),
+	Export(ExportStatement),
+	Foreach(ForeachStatement),
+	Global(GlobalStatement),
 	Ifelse(IfelseStatement),
 	Info(InfoStatement),
 	Insert(InsertStatement),
@@ -88,11 +105,15 @@ pub enum Statement {
 	Live(LiveStatement),
 	Option(OptionStatement),
 	Output(OutputStatement),
+	Purge(PurgeStatement),
+	Rebuild(RebuildStatement),
 	Relate(RelateStatement),
 	Remove(RemoveStatement),
 	Select(SelectStatement),
 	Set(SetStatement),
+	Show(ShowStatement),
 	Sleep(SleepStatement),
+	Throw(ThrowStatement),
 	Update(UpdateStatement),
 	Use(UseStatement),
 }
@@ -114,9 +135,15 @@ impl Statement {
 	pub(crate) fn writeable(&self) -> bool {
 		match self {
 			Self::Analyze(_) => false,
+			Self::Apply(_) => true,
+			Self::Check(_) => false,
+			Self::Copy(_) => true,
 			Self::Create(v) => v.writeable(),
 			Self::Define(_) => true,
 			Self::Delete(v) => v.writeable(),
+			Self::Export(_) => false,
+			Self::Foreach(v) => v.writeable(),
+			Self::Global(_) => false,
 			Self::Ifelse(v) => v.writeable(),
 			Self::Info(_) => false,
 			Self::Insert(v) => v.writeable(),
@@ -124,34 +151,119 @@ impl Statement {
 			Self::Live(_) => true,
 			Self::Output(v) => v.writeable(),
 			Self::Option(_) => false,
+			Self::Purge(_) => true,
+			Self::Rebuild(_) => true,
 			Self::Relate(v) => v.writeable(),
 			Self::Remove(_) => true,
 			Self::Select(v) => v.writeable(),
 			Self::Set(v) => v.writeable(),
+			Self::Show(_) => false,
 			Self::Sleep(_) => false,
+			Self::Throw(v) => v.writeable(),
 			Self::Update(v) => v.writeable(),
 			Self::Use(_) => false,
 			_ => unreachable!(),
 		}
 	}
+	/// Check if this statement can be served from a read-only replica
+	pub(crate) fn read_only(&self) -> bool {
+		match self {
+			Self::Begin(_) => true,
+			Self::Cancel(_) => true,
+			Self::Commit(_) => true,
+			_ => !self.writeable(),
+		}
+	}
+	/// Get the type of this statement, for use in metrics
+	pub(crate) fn kind(&self) -> &'static str {
+		match self {
+			Self::Analyze(_) => "analyze",
+			Self::Apply(_) => "apply",
+			Self::Begin(_) => "begin",
+			Self::Cancel(_) => "cancel",
+			Self::Check(_) => "check",
+			Self::Commit(_) => "commit",
+			Self::Copy(_) => "copy",
+			Self::Create(_) => "create",
+			Self::Define(_) => "define",
+			Self::Delete(_) => "delete",
+			Self::Export(_) => "export",
+			Self::Foreach(_) => "foreach",
+			Self::Global(_) => "global",
+			Self::Ifelse(_) => "ifelse",
+			Self::Info(_) => "info",
+			Self::Insert(_) => "insert",
+			Self::Kill(_) => "kill",
+			Self::Live(_) => "live",
+			Self::Option(_) => "option",
+			Self::Output(_) => "output",
+			Self::Purge(_) => "purge",
+			Self::Rebuild(_) => "rebuild",
+			Self::Relate(_) => "relate",
+			Self::Remove(_) => "remove",
+			Self::Select(_) => "select",
+			Self::Set(_) => "set",
+			Self::Show(_) => "show",
+			Self::Sleep(_) => "sleep",
+			Self::Throw(_) => "throw",
+			Self::Update(_) => "update",
+			Self::Use(_) => "use",
+		}
+	}
+	/// Get the names of the tables which this statement reads or writes
+	pub(crate) fn tables(&self) -> Vec<String> {
+		let tb = |v: &Value| match v {
+			Value::Table(v) => Some(v.0.clone()),
+			Value::Thing(v) => Some(v.tb.clone()),
+			Value::Range(v) => Some(v.tb.clone()),
+			_ => None,
+		};
+		let mut out: Vec<String> = match self {
+			Self::Create(v) => v.what.iter().filter_map(tb).collect(),
+			Self::Delete(v) => v.what.iter().filter_map(tb).collect(),
+			Self::Insert(v) => vec![v.into.0.clone()],
+			Self::Purge(v) => vec![v.what.to_raw()],
+			Self::Relate(v) => tb(&v.kind).into_iter().collect(),
+			Self::Select(v) => v
+				.what
+				.iter()
+				.filter_map(tb)
+				.chain(v.join.iter().map(|v| v.what.0.clone()))
+				.collect(),
+			Self::Update(v) => v.what.iter().filter_map(tb).collect(),
+			_ => vec![],
+		};
+		out.sort_unstable();
+		out.dedup();
+		out
+	}
 	/// Process this type returning a computed simple Value
+	#[instrument(name = "statement", skip_all, fields(kind = self.kind()))]
 	pub(crate) async fn compute(&self, ctx: &Context<'_>, opt: &Options) -> Result<Value, Error> {
 		match self {
 			Self::Analyze(v) => v.compute(ctx, opt).await,
+			Self::Apply(v) => v.compute(ctx, opt).await,
+			Self::Check(v) => v.compute(ctx, opt).await,
+			Self::Copy(v) => v.compute(ctx, opt).await,
 			Self::Create(v) => v.compute(ctx, opt).await,
 			Self::Delete(v) => v.compute(ctx, opt).await,
+			Self::Export(v) => v.compute(ctx, opt).await,
 			Self::Define(v) => v.compute(ctx, opt).await,
+			Self::Foreach(v) => v.compute(ctx, opt).await,
 			Self::Ifelse(v) => v.compute(ctx, opt).await,
 			Self::Info(v) => v.compute(ctx, opt).await,
 			Self::Insert(v) => v.compute(ctx, opt).await,
 			Self::Kill(v) => v.compute(ctx, opt).await,
 			Self::Live(v) => v.compute(ctx, opt).await,
 			Self::Output(v) => v.compute(ctx, opt).await,
+			Self::Purge(v) => v.compute(ctx, opt).await,
+			Self::Rebuild(v) => v.compute(ctx, opt).await,
 			Self::Relate(v) => v.compute(ctx, opt).await,
 			Self::Remove(v) => v.compute(ctx, opt).await,
 			Self::Select(v) => v.compute(ctx, opt).await,
 			Self::Set(v) => v.compute(ctx, opt).await,
 			Self::Sleep(v) => v.compute(ctx, opt).await,
+			Self::Throw(v) => v.compute(ctx, opt).await,
 			Self::Update(v) => v.compute(ctx, opt).await,
 			_ => unreachable!(),
 		}
@@ -162,12 +274,18 @@ impl Display for Statement {
 	fn fmt(&self, f: &mut Formatter) -> fmt::Result {
 		match self {
 			Self::Analyze(v) => write!(Pretty::from(f), "{v}"),
+			Self::Apply(v) => write!(Pretty::from(f), "{v}"),
 			Self::Begin(v) => write!(Pretty::from(f), "{v}"),
 			Self::Cancel(v) => write!(Pretty::from(f), "{v}"),
+			Self::Check(v) => write!(Pretty::from(f), "{v}"),
 			Self::Commit(v) => write!(Pretty::from(f), "{v}"),
+			Self::Copy(v) => write!(Pretty::from(f), "{v}"),
 			Self::Create(v) => write!(Pretty::from(f), "{v}"),
 			Self::Define(v) => write!(Pretty::from(f), "{v}"),
 			Self::Delete(v) => write!(Pretty::from(f), "{v}"),
+			Self::Export(v) => write!(Pretty::from(f), "{v}"),
+			Self::Foreach(v) => write!(Pretty::from(f), "{v}"),
+			Self::Global(v) => write!(Pretty::from(f), "{v}"),
 			Self::Insert(v) => write!(Pretty::from(f), "{v}"),
 			Self::Ifelse(v) => write!(Pretty::from(f), "{v}"),
 			Self::Info(v) => write!(Pretty::from(f), "{v}"),
@@ -175,11 +293,15 @@ impl Display for Statement {
 			Self::Live(v) => write!(Pretty::from(f), "{v}"),
 			Self::Option(v) => write!(Pretty::from(f), "{v}"),
 			Self::Output(v) => write!(Pretty::from(f), "{v}"),
+			Self::Purge(v) => write!(Pretty::from(f), "{v}"),
+			Self::Rebuild(v) => write!(Pretty::from(f), "{v}"),
 			Self::Relate(v) => write!(Pretty::from(f), "{v}"),
 			Self::Remove(v) => write!(Pretty::from(f), "{v}"),
 			Self::Select(v) => write!(Pretty::from(f), "{v}"),
 			Self::Set(v) => write!(Pretty::from(f), "{v}"),
+			Self::Show(v) => write!(Pretty::from(f), "{v}"),
 			Self::Sleep(v) => write!(Pretty::from(f), "{v}"),
+			Self::Throw(v) => write!(Pretty::from(f), "{v}"),
 			Self::Update(v) => write!(Pretty::from(f), "{v}"),
 			Self::Use(v) => write!(Pretty::from(f), "{v}"),
 		}
@@ -190,27 +312,41 @@ pub fn statement(i: &str) -> IResult<&str, Statement> {
 	delimited(
 		mightbespace,
 		alt((
-			map(analyze, Statement::Analyze),
-			map(begin, Statement::Begin),
-			map(cancel, Statement::Cancel),
-			map(commit, Statement::Commit),
-			map(create, Statement::Create),
-			map(define, Statement::Define),
-			map(delete, Statement::Delete),
-			map(ifelse, Statement::Ifelse),
-			map(info, Statement::Info),
-			map(insert, Statement::Insert),
-			map(kill, Statement::Kill),
-			map(live, Statement::Live),
-			map(option, Statement::Option),
-			map(output, Statement::Output),
-			map(relate, Statement::Relate),
-			map(remove, Statement::Remove),
-			map(select, Statement::Select),
-			map(set, Statement::Set),
-			map(sleep, Statement::Sleep),
-			map(update, Statement::Update),
-			map(yuse, Statement::Use),
+			alt((
+				map(analyze, Statement::Analyze),
+				map(apply, Statement::Apply),
+				map(begin, Statement::Begin),
+				map(cancel, Statement::Cancel),
+				map(check, Statement::Check),
+				map(commit, Statement::Commit),
+				map(copy, Statement::Copy),
+				map(create, Statement::Create),
+				map(define, Statement::Define),
+				map(delete, Statement::Delete),
+				map(export, Statement::Export),
+				map(foreach, Statement::Foreach),
+				map(global, Statement::Global),
+				map(ifelse, Statement::Ifelse),
+				map(info, Statement::Info),
+				map(insert, Statement::Insert),
+			)),
+			alt((
+				map(kill, Statement::Kill),
+				map(live, Statement::Live),
+				map(option, Statement::Option),
+				map(output, Statement::Output),
+				map(purge, Statement::Purge),
+				map(rebuild, Statement::Rebuild),
+				map(relate, Statement::Relate),
+				map(remove, Statement::Remove),
+				map(select, Statement::Select),
+				map(set, Statement::Set),
+				map(show, Statement::Show),
+				map(sleep, Statement::Sleep),
+				map(throw, Statement::Throw),
+				map(update, Statement::Update),
+				map(yuse, Statement::Use),
+			)),
 		)),
 		mightbespace,
 	)(i)
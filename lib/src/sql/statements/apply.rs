@@ -0,0 +1,157 @@
+use crate::cnf::PROTECTED_PARAM_NAMES;
+use crate::ctx::Context;
+use crate::dbs::Level;
+use crate::dbs::Options;
+use crate::err::Error;
+use crate::sql::comment::shouldbespace;
+use crate::sql::datetime::Datetime;
+use crate::sql::error::IResult;
+use crate::sql::ident::{ident, Ident};
+use crate::sql::statement::{Statement, Statements};
+use crate::sql::value::Value;
+use async_recursion::async_recursion;
+use derive::Store;
+use nom::bytes::complete::tag_no_case;
+use nom::combinator::opt;
+use nom::sequence::{preceded, tuple};
+use serde::{Deserialize, Serialize};
+use std::fmt;
+
+#[derive(Clone, Debug, Default, Eq, PartialEq, Serialize, Deserialize, Store, Hash)]
+pub struct ApplyStatement {
+	pub to: Option<Ident>,
+}
+
+impl ApplyStatement {
+	/// Process this type returning a computed simple Value
+	#[cfg_attr(not(target_arch = "wasm32"), async_recursion)]
+	#[cfg_attr(target_arch = "wasm32", async_recursion(?Send))]
+	pub(crate) async fn compute(&self, ctx: &Context<'_>, opt: &Options) -> Result<Value, Error> {
+		// Selected DB?
+		opt.needs(Level::Db)?;
+		// Allowed to run?
+		opt.check(Level::Db)?;
+		// Clone transaction
+		let txn = ctx.clone_transaction()?;
+		// Fetch the migrations, and whether each one has been applied
+		let mut mgs = vec![];
+		{
+			let mut run = txn.lock().await;
+			for mg in run.all_mg(opt.ns(), opt.db()).await?.iter() {
+				let applied = run.get_ma(opt.ns(), opt.db(), &mg.name).await?.is_some();
+				mgs.push((mg.clone(), applied));
+			}
+		}
+		// Find the last migration which should be applied
+		let end = match &self.to {
+			Some(to) => match mgs.iter().position(|(v, _)| v.name == *to) {
+				Some(v) => v + 1,
+				None => {
+					return Err(Error::MgNotFound {
+						value: to.to_raw(),
+					})
+				}
+			},
+			None => mgs.len(),
+		};
+		// Revert any applied migrations after the target, newest first
+		let mut reverted = vec![];
+		for (mg, _) in mgs[end..].iter().rev().filter(|(_, v)| *v) {
+			let down = mg.down.as_ref().ok_or_else(|| Error::MgIrreversible {
+				value: mg.name.to_raw(),
+			})?;
+			script(ctx, opt, down).await?;
+			let key = crate::key::ma::new(opt.ns(), opt.db(), &mg.name);
+			txn.lock().await.del(key).await?;
+			reverted.push(Value::from(mg.name.to_raw()));
+		}
+		// Apply any outstanding migrations up to the target, oldest first
+		let mut applied = vec![];
+		for (mg, _) in mgs[..end].iter().filter(|(_, v)| !*v) {
+			script(ctx, opt, &mg.up).await?;
+			let key = crate::key::ma::new(opt.ns(), opt.db(), &mg.name);
+			txn.lock().await.set(key, Value::from(Datetime::default())).await?;
+			applied.push(Value::from(mg.name.to_raw()));
+		}
+		// Ok all good
+		Ok(Value::from(map! {
+			String::from("applied") => Value::from(applied),
+			String::from("reverted") => Value::from(reverted),
+		}))
+	}
+}
+
+/// Run the statements of a migration script within the current transaction
+async fn script(ctx: &Context<'_>, opt: &Options, stms: &Statements) -> Result<(), Error> {
+	// Duplicate context
+	let mut ctx = Context::new(ctx);
+	// Loop over the statements
+	for v in stms.iter() {
+		match v {
+			Statement::Set(v) => {
+				// Check if the variable is a protected variable
+				if PROTECTED_PARAM_NAMES.contains(&v.name.as_str()) {
+					return Err(Error::InvalidParam {
+						name: v.name.to_owned(),
+					});
+				}
+				// Set the parameter
+				let val = v.compute(&ctx, opt).await?;
+				ctx.add_value(v.name.to_owned(), val);
+			}
+			v => {
+				v.compute(&ctx, opt).await?;
+			}
+		}
+	}
+	Ok(())
+}
+
+impl fmt::Display for ApplyStatement {
+	fn fmt(&self, f: &mut fmt::Formatter) -> fmt::Result {
+		f.write_str("APPLY MIGRATIONS")?;
+		if let Some(ref v) = self.to {
+			write!(f, " TO {v}")?
+		}
+		Ok(())
+	}
+}
+
+pub fn apply(i: &str) -> IResult<&str, ApplyStatement> {
+	let (i, _) = tag_no_case("APPLY")(i)?;
+	let (i, _) = shouldbespace(i)?;
+	let (i, _) = tag_no_case("MIGRATIONS")(i)?;
+	let (i, to) =
+		opt(preceded(tuple((shouldbespace, tag_no_case("TO"), shouldbespace)), ident))(i)?;
+	Ok((
+		i,
+		ApplyStatement {
+			to,
+		},
+	))
+}
+
+#[cfg(test)]
+mod tests {
+
+	use super::*;
+
+	#[test]
+	fn apply_migrations() {
+		let sql = "APPLY MIGRATIONS";
+		let res = apply(sql);
+		assert!(res.is_ok());
+		let out = res.unwrap().1;
+		assert_eq!("APPLY MIGRATIONS", format!("{}", out))
+	}
+
+	#[test]
+	fn apply_migrations_to() {
+		let sql = "APPLY MIGRATIONS TO v2";
+		let res = apply(sql);
+		assert!(res.is_ok());
+		let out = res.unwrap().1;
+		assert_eq!(out.to, Some(Ident::from("v2")));
+		assert_eq!("APPLY MIGRATIONS TO v2", format!("{}", out))
+	}
+}
@@ -0,0 +1,157 @@
+use crate::ctx::Context;
+use crate::dbs::Level;
+use crate::dbs::Options;
+use crate::doc::Document;
+use crate::err::Error;
+use crate::idx::build::Build;
+use crate::sql::array::Array;
+use crate::sql::comment::shouldbespace;
+use crate::sql::error::IResult;
+use crate::sql::ident::{ident, Ident};
+use crate::sql::index::Index;
+use crate::sql::object::Object;
+use crate::sql::thing::Thing;
+use crate::sql::value::Value;
+use derive::Store;
+use nom::bytes::complete::tag_no_case;
+use serde::{Deserialize, Serialize};
+use std::collections::BTreeSet;
+use std::fmt;
+use std::fmt::{Display, Formatter};
+
+#[derive(Clone, Debug, Eq, PartialEq, Serialize, Deserialize, Store, Hash)]
+pub enum CheckStatement {
+	Idx(Ident, Ident),
+}
+
+impl CheckStatement {
+	/// Process this type returning a computed simple Value
+	pub(crate) async fn compute(&self, ctx: &Context<'_>, opt: &Options) -> Result<Value, Error> {
+		match self {
+			CheckStatement::Idx(tb, idx) => {
+				// Selected DB?
+				opt.needs(Level::Db)?;
+				// Allowed to run?
+				opt.check(Level::Db)?;
+				// Clone transaction
+				let txn = ctx.clone_transaction()?;
+				// Claim transaction
+				let mut run = txn.lock().await;
+				// Read the index
+				let ix = run.get_ix(opt.ns(), opt.db(), tb.as_str(), idx.as_str()).await?;
+				match &ix.index {
+					Index::Idx | Index::Uniq => (),
+					_ => {
+						return Err(Error::FeatureNotYetImplemented {
+							feature: "Checking full-text and vector indexes.",
+						})
+					}
+				};
+				// An index which is being built has not indexed every record yet
+				if Build::get(&mut run, opt.ns(), opt.db(), tb, idx).await?.is_some() {
+					return Err(Error::IxBuilding {
+						value: idx.to_raw(),
+					});
+				}
+				// Fetch the entries of the index
+				let beg = crate::key::index::prefix(opt.ns(), opt.db(), tb, idx);
+				let end = crate::key::index::suffix(opt.ns(), opt.db(), tb, idx);
+				let mut entries = BTreeSet::new();
+				for (k, v) in run.getr(beg..end, u32::MAX).await? {
+					let key = crate::key::index::Index::decode(&k)?;
+					entries.insert((key.fd, Thing::from(v)));
+				}
+				let total = entries.len();
+				// Check that every record has its index entry
+				let mut records: usize = 0;
+				let mut missing = vec![];
+				let mut nxt = crate::key::thing::prefix(opt.ns(), opt.db(), tb);
+				let end = crate::key::thing::suffix(opt.ns(), opt.db(), tb);
+				loop {
+					let res = run.scan(nxt.clone()..end.clone(), 1000).await?;
+					let last = match res.last() {
+						Some((k, _)) => k.clone(),
+						None => break,
+					};
+					// Release the transaction while the index values are computed
+					drop(run);
+					for (k, v) in res.into_iter() {
+						let key: crate::key::thing::Thing = (&k).into();
+						let rid = Thing::from((key.tb, key.id));
+						let val: Value = (&v).into();
+						if let Some(fd) = Document::build_opt_array(ctx, opt, &ix, &val).await? {
+							let entry = (ix.collated(fd), rid);
+							if !entries.remove(&entry) {
+								missing.push(entry);
+							}
+						}
+						records += 1;
+					}
+					run = txn.lock().await;
+					nxt = last;
+					nxt.push(0x00);
+				}
+				// Any remaining entries do not belong to a record
+				let orphans = entries.into_iter().collect();
+				// Return the result object
+				let mut res = Object::default();
+				res.insert("records".to_owned(), records.into());
+				res.insert("entries".to_owned(), total.into());
+				res.insert("missing".to_owned(), output(missing));
+				res.insert("orphans".to_owned(), output(orphans));
+				Value::from(res).ok()
+			}
+		}
+	}
+}
+
+/// Describe each of the index entries as a record id and its indexed values
+fn output(entries: Vec<(Array, Thing)>) -> Value {
+	entries
+		.into_iter()
+		.map(|(fd, rid)| {
+			let mut obj = Object::default();
+			obj.insert("id".to_owned(), rid.into());
+			obj.insert("value".to_owned(), fd.into());
+			Value::from(obj)
+		})
+		.collect::<Vec<_>>()
+		.into()
+}
+
+pub fn check(i: &str) -> IResult<&str, CheckStatement> {
+	let (i, _) = tag_no_case("CHECK")(i)?;
+	let (i, _) = shouldbespace(i)?;
+	let (i, _) = tag_no_case("INDEX")(i)?;
+	let (i, _) = shouldbespace(i)?;
+	let (i, idx) = ident(i)?;
+	let (i, _) = shouldbespace(i)?;
+	let (i, _) = tag_no_case("ON")(i)?;
+	let (i, _) = shouldbespace(i)?;
+	let (i, tb) = ident(i)?;
+	Ok((i, CheckStatement::Idx(tb, idx)))
+}
+
+impl Display for CheckStatement {
+	fn fmt(&self, f: &mut Formatter) -> fmt::Result {
+		match self {
+			Self::Idx(tb, idx) => write!(f, "CHECK INDEX {idx} ON {tb}"),
+		}
+	}
+}
+
+#[cfg(test)]
+mod tests {
+
+	use super::*;
+
+	#[test]
+	fn check_index() {
+		let sql = "CHECK INDEX my_index ON my_table";
+		let res = check(sql);
+		assert!(res.is_ok());
+		let out = res.unwrap().1;
+		assert_eq!(out, CheckStatement::Idx(Ident::from("my_table"), Ident::from("my_index")));
+		assert_eq!("CHECK INDEX my_index ON my_table", format!("{}", out));
+	}
+}
@@ -0,0 +1,355 @@
+use crate::ctx::Context;
+use crate::ctx::Reason;
+use crate::dbs::Level;
+use crate::dbs::Options;
+use crate::dbs::LOG;
+use crate::err::Error;
+use crate::key::database;
+use crate::kvs::Datastore;
+use crate::kvs::Key;
+use crate::kvs::Transaction;
+use crate::sql::comment::shouldbespace;
+use crate::sql::error::IResult;
+use crate::sql::ident::{ident, Ident};
+use crate::sql::statements::DefineDatabaseStatement;
+use crate::sql::value::Value;
+use derive::Store;
+use nom::branch::alt;
+use nom::bytes::complete::tag_no_case;
+use nom::combinator::opt;
+use nom::sequence::tuple;
+use serde::{Deserialize, Serialize};
+use std::fmt;
+
+/// The number of keys which are read at once while copying a database
+const COPY_BATCH_SIZE: u32 = 1000;
+
+/// The number of bytes which are copied in each transaction, when a
+/// database is copied outside of a transaction
+const COPY_BATCH_BYTES: usize = 1 << 20;
+
+#[derive(Clone, Debug, Default, Eq, PartialEq, Serialize, Deserialize, Store, Hash)]
+pub struct CopyStatement {
+	pub from: Ident,
+	pub into: Ident,
+	pub snapshot: bool,
+}
+
+impl CopyStatement {
+	/// Process this type returning a computed simple Value
+	pub(crate) async fn compute(&self, ctx: &Context<'_>, opt: &Options) -> Result<Value, Error> {
+		// Selected NS?
+		opt.needs(Level::Ns)?;
+		// Allowed to run?
+		opt.check(Level::Ns)?;
+		// Clone transaction
+		let txn = ctx.clone_transaction()?;
+		// Claim transaction
+		let mut run = txn.lock().await;
+		// Check the databases, and the limits of the namespace
+		self.check(&mut run, opt).await?;
+		// Copy every key within this transaction, so
+		// the copy is a consistent snapshot of the database
+		let mut nxt = None;
+		while let Some(k) = self.batch(&mut run, opt, nxt.take(), usize::MAX).await? {
+			nxt = Some(k);
+		}
+		// Define the target database
+		self.define(&mut run, opt).await?;
+		// Ok all good
+		Ok(Value::None)
+	}
+
+	/// Process this statement outside of a transaction, copying the keys
+	/// in batches which are each committed in their own transaction, so
+	/// that a large database can be copied within the transaction size
+	/// and time limits of the storage engine. The target database is only
+	/// defined once every key has been copied. Unlike a copy within a
+	/// transaction, this is not a consistent snapshot of a database which
+	/// is written to while it is copied.
+	pub(crate) async fn copy(
+		&self,
+		ctx: &Context<'_>,
+		opt: &Options,
+		kvs: &Datastore,
+	) -> Result<Value, Error> {
+		// Selected NS?
+		opt.needs(Level::Ns)?;
+		// Allowed to run?
+		opt.check(Level::Ns)?;
+		// Check the databases, and the limits of the namespace
+		let mut run = kvs.transaction(false, false).await?;
+		let res = self.check(&mut run, opt).await;
+		run.cancel().await?;
+		res?;
+		// Copy each batch of keys in its own transaction
+		let res = async {
+			let mut nxt = None;
+			loop {
+				// Stop copying once the statement is cancelled
+				match ctx.done() {
+					Some(Reason::Timedout) => return Err(Error::QueryTimedout),
+					Some(Reason::Canceled) => return Err(Error::QueryKilled),
+					None => (),
+				}
+				let mut run = kvs.transaction(true, false).await?;
+				let res = match self.batch(&mut run, opt, nxt.take(), COPY_BATCH_BYTES).await {
+					Ok(Some(k)) => Ok(Some(k)),
+					// Define the target database with the last batch
+					Ok(None) => self.define(&mut run, opt).await.map(|_| None),
+					Err(e) => Err(e),
+				};
+				match res {
+					Ok(v) => {
+						run.commit().await?;
+						match v {
+							Some(k) => nxt = Some(k),
+							None => return Ok(()),
+						}
+					}
+					Err(e) => {
+						let _ = run.cancel().await;
+						return Err(e);
+					}
+				}
+			}
+		}
+		.await;
+		// Remove any keys which were copied before the copy failed
+		if let Err(e) = res {
+			if let Err(e) = self.clean(kvs, opt).await {
+				warn!(target: LOG, "Unable to remove a partial copy of a database: {}", e);
+			}
+			return Err(e);
+		}
+		// Ok all good
+		Ok(Value::None)
+	}
+
+	/// Check that the source database exists, that the target database
+	/// does not exist, and that the copied records and bytes keep the
+	/// namespace within its storage limits
+	async fn check(&self, run: &mut Transaction, opt: &Options) -> Result<(), Error> {
+		// Check that the source database exists
+		run.get_db(opt.ns(), &self.from).await?;
+		// Check that the target database does not exist
+		if run.get_db(opt.ns(), &self.into).await.is_ok() {
+			return Err(Error::DbAlreadyExists {
+				value: self.into.to_string(),
+			});
+		}
+		// Only a snapshot copies the records
+		if self.snapshot {
+			let (records, bytes) = run.usage_db(opt.ns(), &self.from).await?;
+			run.check_ns_limits(opt.ns(), records, bytes).await?;
+		}
+		Ok(())
+	}
+
+	/// Define the target database, once its keys have been copied
+	async fn define(&self, run: &mut Transaction, opt: &Options) -> Result<(), Error> {
+		// Check that the target database was not defined meanwhile
+		if run.get_db(opt.ns(), &self.into).await.is_ok() {
+			return Err(Error::DbAlreadyExists {
+				value: self.into.to_string(),
+			});
+		}
+		let key = crate::key::db::new(opt.ns(), &self.into);
+		let val = DefineDatabaseStatement {
+			name: self.into.clone(),
+		};
+		run.set(key, &val).await
+	}
+
+	/// Copy the keys which follow a key, until at least a number of bytes
+	/// have been copied, returning the last key which was copied, or `None`
+	/// once every key of the source database has been copied
+	async fn batch(
+		&self,
+		run: &mut Transaction,
+		opt: &Options,
+		after: Option<Key>,
+		max: usize,
+	) -> Result<Option<Key>, Error> {
+		// Copy the database definitions, and with a
+		// snapshot the database resource data
+		let beg: Key = database::new(opt.ns(), &self.from).into();
+		let pre: Key = database::new(opt.ns(), &self.into).into();
+		let mut end = beg.clone();
+		end.push(0xff);
+		let mut nxt = after;
+		let mut size = 0;
+		loop {
+			// Get the next batch of keys
+			let min = match nxt.clone() {
+				Some(mut v) => {
+					v.push(0x00);
+					v
+				}
+				None => beg.clone(),
+			};
+			// The stored values are copied as they are, with any chunks
+			let res = run.scan_raw(min..end.clone(), COPY_BATCH_SIZE).await?;
+			// Exit when settled
+			if res.is_empty() {
+				return Ok(None);
+			}
+			// Write each key under the target database
+			for (k, v) in res.into_iter() {
+				let key: Key = pre.iter().chain(k[beg.len()..].iter()).copied().collect();
+				let copy = match self.snapshot {
+					true => !is_live(&k[beg.len()..]),
+					false => is_schema(&k[beg.len()..]),
+				};
+				nxt = Some(k);
+				// Live queries are not copied
+				if copy {
+					size += v.len();
+					run.set(key, v).await?;
+				}
+			}
+			// Stop once the batch is large enough
+			if size >= max {
+				return Ok(nxt);
+			}
+		}
+	}
+
+	/// Remove the keys of the target database, in batches
+	async fn clean(&self, kvs: &Datastore, opt: &Options) -> Result<(), Error> {
+		let beg: Key = database::new(opt.ns(), &self.into).into();
+		let mut end = beg.clone();
+		end.push(0xff);
+		loop {
+			let mut run = kvs.transaction(true, false).await?;
+			let res = run.scan_raw(beg.clone()..end.clone(), COPY_BATCH_SIZE).await?;
+			if res.is_empty() {
+				run.cancel().await?;
+				return Ok(());
+			}
+			for (k, _) in res.into_iter() {
+				run.del(k).await?;
+			}
+			run.commit().await?;
+		}
+	}
+}
+
+/// Check if a key suffix, following the database
+/// key prefix, belongs to a live query definition
+fn is_live(k: &[u8]) -> bool {
+	// A live query on the database
+	if k.starts_with(b"!lq") {
+		return true;
+	}
+	// A live query on a table
+	if k.starts_with(b"*") {
+		if let Some(pos) = k.iter().position(|&v| v == 0x00) {
+			return k[pos + 1..].starts_with(b"!lv");
+		}
+	}
+	false
+}
+
+/// Check if a key suffix, following the database key prefix, belongs
+/// to a definition, which is copied from a template database without
+/// the records, the index data, and the state of the database
+fn is_schema(k: &[u8]) -> bool {
+	// A definition on the database
+	if k.starts_with(b"!") {
+		return [b"!az", b"!dl", b"!dt", b"!fc", b"!ma", b"!mg", b"!pa", b"!sc", b"!sq", b"!tb"]
+			.iter()
+			.any(|v| k.starts_with(*v));
+	}
+	// A definition on a scope
+	if k.starts_with(&[0xb1]) {
+		return true;
+	}
+	// A definition on a table
+	if k.starts_with(b"*") {
+		if let Some(pos) = k.iter().position(|&v| v == 0x00) {
+			let k = &k[pos + 1..];
+			return [b"!ev", b"!fd", b"!ft", b"!ix"].iter().any(|v| k.starts_with(*v));
+		}
+	}
+	false
+}
+
+impl fmt::Display for CopyStatement {
+	fn fmt(&self, f: &mut fmt::Formatter) -> fmt::Result {
+		write!(f, "COPY DATABASE {} TO {}", self.from, self.into)?;
+		if self.snapshot {
+			write!(f, " AS SNAPSHOT")?;
+		}
+		Ok(())
+	}
+}
+
+pub fn copy(i: &str) -> IResult<&str, CopyStatement> {
+	let (i, _) = tag_no_case("COPY")(i)?;
+	let (i, _) = shouldbespace(i)?;
+	let (i, _) = alt((tag_no_case("DB"), tag_no_case("DATABASE")))(i)?;
+	let (i, _) = shouldbespace(i)?;
+	let (i, from) = ident(i)?;
+	let (i, _) = shouldbespace(i)?;
+	let (i, _) = tag_no_case("TO")(i)?;
+	let (i, _) = shouldbespace(i)?;
+	let (i, into) = ident(i)?;
+	let (i, snapshot) =
+		opt(tuple((shouldbespace, tag_no_case("AS"), shouldbespace, tag_no_case("SNAPSHOT"))))(i)?;
+	Ok((
+		i,
+		CopyStatement {
+			from,
+			into,
+			snapshot: snapshot.is_some(),
+		},
+	))
+}
+
+#[cfg(test)]
+mod tests {
+
+	use super::*;
+
+	#[test]
+	fn copy_statement() {
+		let sql = "COPY DATABASE prod TO preview_123 AS SNAPSHOT";
+		let res = copy(sql);
+		assert!(res.is_ok());
+		let out = res.unwrap().1;
+		assert_eq!("COPY DATABASE prod TO preview_123 AS SNAPSHOT", format!("{}", out))
+	}
+
+	#[test]
+	fn copy_statement_schema() {
+		let sql = "COPY DB prod TO tenant_123";
+		let res = copy(sql);
+		assert!(res.is_ok());
+		let out = res.unwrap().1;
+		assert!(!out.snapshot);
+		assert_eq!("COPY DATABASE prod TO tenant_123", format!("{}", out))
+	}
+
+	#[test]
+	fn copy_statement_live_keys() {
+		assert!(is_live(b"!lqabc"));
+		assert!(is_live(b"*person\x00!lvabc"));
+		assert!(!is_live(b"!tbperson\x00"));
+		assert!(!is_live(b"*person\x00*\x00tobie"));
+	}
+
+	#[test]
+	fn copy_statement_schema_keys() {
+		assert!(is_schema(b"!tbperson\x00"));
+		assert!(is_schema(b"!pamaximum\x00"));
+		assert!(is_schema(b"*person\x00!fdname\x00"));
+		assert!(is_schema(b"*person\x00!ixname\x00"));
+		assert!(!is_schema(b"!lqabc"));
+		assert!(!is_schema(b"!sh"));
+		assert!(!is_schema(b"!svcounter\x00"));
+		assert!(!is_schema(b"*person\x00!cn"));
+		assert!(!is_schema(b"*person\x00*\x00tobie"));
+		assert!(!is_schema(b"*person\x00!lvabc"));
+	}
+}
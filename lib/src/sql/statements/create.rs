@@ -7,6 +7,7 @@ use crate::dbs::Statement;
 use crate::err::Error;
 use crate::sql::comment::shouldbespace;
 use crate::sql::data::{data, Data};
+use crate::sql::duration::{duration, Duration};
 use crate::sql::error::IResult;
 use crate::sql::output::{output, Output};
 use crate::sql::timeout::{timeout, Timeout};
@@ -22,6 +23,7 @@ use std::fmt;
 pub struct CreateStatement {
 	pub what: Values,
 	pub data: Option<Data>,
+	pub unique: Option<Duration>,
 	pub output: Option<Output>,
 	pub timeout: Option<Timeout>,
 	pub parallel: bool,
@@ -64,7 +66,7 @@ impl CreateStatement {
 						Ok(v) => i.ingest(Iterable::Thing(v)),
 					},
 					// There is no data clause so create a record id
-					None => i.ingest(Iterable::Thing(v.generate())),
+					None => i.ingest(Iterable::Thing(v.generate_with(ctx, opt).await?)),
 				},
 				Value::Thing(v) => i.ingest(Iterable::Thing(v)),
 				Value::Model(v) => {
@@ -75,7 +77,9 @@ impl CreateStatement {
 				Value::Array(v) => {
 					for v in v {
 						match v {
-							Value::Table(v) => i.ingest(Iterable::Thing(v.generate())),
+							Value::Table(v) => {
+								i.ingest(Iterable::Thing(v.generate_with(ctx, opt).await?))
+							}
 							Value::Thing(v) => i.ingest(Iterable::Thing(v)),
 							Value::Model(v) => {
 								for v in v {
@@ -126,6 +130,9 @@ impl fmt::Display for CreateStatement {
 		if let Some(ref v) = self.data {
 			write!(f, " {v}")?
 		}
+		if let Some(ref v) = self.unique {
+			write!(f, " UNIQUE WITHIN {v}")?
+		}
 		if let Some(ref v) = self.output {
 			write!(f, " {v}")?
 		}
@@ -144,6 +151,7 @@ pub fn create(i: &str) -> IResult<&str, CreateStatement> {
 	let (i, _) = shouldbespace(i)?;
 	let (i, what) = whats(i)?;
 	let (i, data) = opt(preceded(shouldbespace, data))(i)?;
+	let (i, unique) = opt(preceded(shouldbespace, unique))(i)?;
 	let (i, output) = opt(preceded(shouldbespace, output))(i)?;
 	let (i, timeout) = opt(preceded(shouldbespace, timeout))(i)?;
 	let (i, parallel) = opt(preceded(shouldbespace, tag_no_case("PARALLEL")))(i)?;
@@ -152,6 +160,7 @@ pub fn create(i: &str) -> IResult<&str, CreateStatement> {
 		CreateStatement {
 			what,
 			data,
+			unique,
 			output,
 			timeout,
 			parallel: parallel.is_some(),
@@ -159,6 +168,15 @@ pub fn create(i: &str) -> IResult<&str, CreateStatement> {
 	))
 }
 
+fn unique(i: &str) -> IResult<&str, Duration> {
+	let (i, _) = tag_no_case("UNIQUE")(i)?;
+	let (i, _) = shouldbespace(i)?;
+	let (i, _) = tag_no_case("WITHIN")(i)?;
+	let (i, _) = shouldbespace(i)?;
+	let (i, v) = duration(i)?;
+	Ok((i, v))
+}
+
 #[cfg(test)]
 mod tests {
 
@@ -172,4 +190,16 @@ mod tests {
 		let out = res.unwrap().1;
 		assert_eq!("CREATE test", format!("{}", out))
 	}
+
+	#[test]
+	fn create_statement_unique() {
+		let sql = "CREATE test CONTENT { name: 'Tobie' } UNIQUE WITHIN 1h RETURN NONE";
+		let res = create(sql);
+		assert!(res.is_ok());
+		let out = res.unwrap().1;
+		assert_eq!(
+			"CREATE test CONTENT { name: 'Tobie' } UNIQUE WITHIN 1h RETURN NONE",
+			format!("{}", out)
+		)
+	}
 }
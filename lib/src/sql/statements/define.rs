@@ -1,26 +1,36 @@
 use crate::ctx::Context;
 use crate::dbs::Level;
 use crate::dbs::Options;
+use crate::doc::Document;
 use crate::err::Error;
+use crate::idx::build::Build;
 use crate::sql::algorithm::{algorithm, Algorithm};
+use crate::sql::array::Array;
+use crate::sql::audit::{audit, Audit};
 use crate::sql::base::{base, base_or_scope, Base};
 use crate::sql::block::{block, Block};
+use crate::sql::collation::{collation, Collation};
 use crate::sql::comment::{mightbespace, shouldbespace};
-use crate::sql::common::commas;
+use crate::sql::common::{closebraces, colons, commas, openbraces, verbar};
 use crate::sql::duration::{duration, Duration};
 use crate::sql::error::IResult;
 use crate::sql::escape::quote_str;
 use crate::sql::filter::{filters, Filter};
 use crate::sql::fmt::is_pretty;
 use crate::sql::fmt::pretty_indent;
+use crate::sql::fmt::Fmt;
 use crate::sql::ident::{ident, Ident};
 use crate::sql::idiom;
 use crate::sql::idiom::{Idiom, Idioms};
 use crate::sql::index::Index;
 use crate::sql::kind::{kind, Kind};
+use crate::sql::number::integer;
+use crate::sql::object::{object, Object};
 use crate::sql::permission::{permissions, Permissions};
+use crate::sql::statement::{statement, Statement, Statements};
 use crate::sql::statements::UpdateStatement;
-use crate::sql::strand::strand_raw;
+use crate::sql::strand::{strand, strand_raw, Strand};
+use crate::sql::table::Tables;
 use crate::sql::tokenizer::{tokenizers, Tokenizer};
 use crate::sql::value::{value, values, Value, Values};
 use crate::sql::view::{view, View};
@@ -31,11 +41,12 @@ use derive::Store;
 use nom::branch::alt;
 use nom::bytes::complete::tag;
 use nom::bytes::complete::tag_no_case;
-use nom::character::complete::char;
+use nom::character::complete::{char, u64};
 use nom::combinator::{map, opt};
 use nom::multi::many0;
 use nom::multi::separated_list0;
-use nom::sequence::tuple;
+use nom::multi::separated_list1;
+use nom::sequence::{preceded, tuple};
 use rand::distributions::Alphanumeric;
 use rand::rngs::OsRng;
 use rand::Rng;
@@ -53,6 +64,8 @@ pub enum DefineStatement {
 	Token(DefineTokenStatement),
 	Scope(DefineScopeStatement),
 	Param(DefineParamStatement),
+	Sequence(DefineSequenceStatement),
+	Migration(DefineMigrationStatement),
 	Table(DefineTableStatement),
 	Event(DefineEventStatement),
 	Field(DefineFieldStatement),
@@ -70,6 +83,8 @@ impl DefineStatement {
 			Self::Token(ref v) => v.compute(ctx, opt).await,
 			Self::Scope(ref v) => v.compute(ctx, opt).await,
 			Self::Param(ref v) => v.compute(ctx, opt).await,
+			Self::Sequence(ref v) => v.compute(ctx, opt).await,
+			Self::Migration(ref v) => v.compute(ctx, opt).await,
 			Self::Table(ref v) => v.compute(ctx, opt).await,
 			Self::Event(ref v) => v.compute(ctx, opt).await,
 			Self::Field(ref v) => v.compute(ctx, opt).await,
@@ -89,6 +104,8 @@ impl Display for DefineStatement {
 			Self::Token(v) => Display::fmt(v, f),
 			Self::Scope(v) => Display::fmt(v, f),
 			Self::Param(v) => Display::fmt(v, f),
+			Self::Sequence(v) => Display::fmt(v, f),
+			Self::Migration(v) => Display::fmt(v, f),
 			Self::Table(v) => Display::fmt(v, f),
 			Self::Event(v) => Display::fmt(v, f),
 			Self::Field(v) => Display::fmt(v, f),
@@ -107,6 +124,8 @@ pub fn define(i: &str) -> IResult<&str, DefineStatement> {
 		map(token, DefineStatement::Token),
 		map(scope, DefineStatement::Scope),
 		map(param, DefineStatement::Param),
+		map(sequence, DefineStatement::Sequence),
+		map(migration, DefineStatement::Migration),
 		map(table, DefineStatement::Table),
 		map(event, DefineStatement::Event),
 		map(field, DefineStatement::Field),
@@ -123,9 +142,22 @@ pub fn define(i: &str) -> IResult<&str, DefineStatement> {
 #[format(Named)]
 pub struct DefineNamespaceStatement {
 	pub name: Ident,
+	/// The maximum number of records which may be stored in the namespace
+	pub records: Option<u64>,
+	/// The maximum number of bytes of record data which may be stored in the namespace
+	pub bytes: Option<u64>,
 }
 
 impl DefineNamespaceStatement {
+	/// Describe this definition as a structured value
+	pub(crate) fn structure(&self) -> Value {
+		Value::from(map! {
+			String::from("name") => self.name.to_raw().into(),
+			String::from("records") => self.records.map(Value::from).into(),
+			String::from("bytes") => self.bytes.map(Value::from).into(),
+		})
+	}
+
 	/// Process this type returning a computed simple Value
 	pub(crate) async fn compute(&self, ctx: &Context<'_>, opt: &Options) -> Result<Value, Error> {
 		// No need for NS/DB
@@ -146,7 +178,17 @@ impl DefineNamespaceStatement {
 
 impl Display for DefineNamespaceStatement {
 	fn fmt(&self, f: &mut fmt::Formatter) -> fmt::Result {
-		write!(f, "DEFINE NAMESPACE {}", self.name)
+		write!(f, "DEFINE NAMESPACE {}", self.name)?;
+		if self.records.is_some() || self.bytes.is_some() {
+			f.write_str(" LIMIT")?;
+			if let Some(v) = self.records {
+				write!(f, " RECORDS {v}")?
+			}
+			if let Some(v) = self.bytes {
+				write!(f, " BYTES {v}")?
+			}
+		}
+		Ok(())
 	}
 }
 
@@ -156,14 +198,32 @@ fn namespace(i: &str) -> IResult<&str, DefineNamespaceStatement> {
 	let (i, _) = alt((tag_no_case("NS"), tag_no_case("NAMESPACE")))(i)?;
 	let (i, _) = shouldbespace(i)?;
 	let (i, name) = ident(i)?;
+	let (i, limits) = opt(namespace_limits)(i)?;
+	let (records, bytes) = limits.unwrap_or_default();
 	Ok((
 		i,
 		DefineNamespaceStatement {
 			name,
+			records,
+			bytes,
 		},
 	))
 }
 
+fn namespace_limits(i: &str) -> IResult<&str, (Option<u64>, Option<u64>)> {
+	let (i, _) = shouldbespace(i)?;
+	let (i, _) = tag_no_case("LIMIT")(i)?;
+	let (i, records) =
+		opt(preceded(tuple((shouldbespace, tag_no_case("RECORDS"), shouldbespace)), u64))(i)?;
+	let (i, bytes) =
+		opt(preceded(tuple((shouldbespace, tag_no_case("BYTES"), shouldbespace)), u64))(i)?;
+	// At least one limit must be specified
+	if records.is_none() && bytes.is_none() {
+		return Err(nom::Err::Error(crate::sql::error::Error::Parser(i)));
+	}
+	Ok((i, (records, bytes)))
+}
+
 // --------------------------------------------------
 // --------------------------------------------------
 // --------------------------------------------------
@@ -175,6 +235,13 @@ pub struct DefineDatabaseStatement {
 }
 
 impl DefineDatabaseStatement {
+	/// Describe this definition as a structured value
+	pub(crate) fn structure(&self) -> Value {
+		Value::from(map! {
+			String::from("name") => self.name.to_raw().into(),
+		})
+	}
+
 	/// Process this type returning a computed simple Value
 	pub(crate) async fn compute(&self, ctx: &Context<'_>, opt: &Options) -> Result<Value, Error> {
 		// Selected NS?
@@ -227,6 +294,25 @@ pub struct DefineFunctionStatement {
 }
 
 impl DefineFunctionStatement {
+	/// Describe this definition as a structured value
+	pub(crate) fn structure(&self) -> Value {
+		Value::from(map! {
+			String::from("name") => self.name.to_raw().into(),
+			String::from("args") => self
+				.args
+				.iter()
+				.map(|(n, k)| {
+					Value::from(map! {
+						String::from("name") => n.to_raw().into(),
+						String::from("kind") => k.to_string().into(),
+					})
+				})
+				.collect::<Vec<_>>()
+				.into(),
+			String::from("block") => self.block.to_string().into(),
+		})
+	}
+
 	/// Process this type returning a computed simple Value
 	pub(crate) async fn compute(&self, ctx: &Context<'_>, opt: &Options) -> Result<Value, Error> {
 		// Selected DB?
@@ -306,6 +392,22 @@ pub struct DefineAnalyzerStatement {
 }
 
 impl DefineAnalyzerStatement {
+	/// Describe this definition as a structured value
+	pub(crate) fn structure(&self) -> Value {
+		let list = |v: Vec<String>| Value::from(v);
+		Value::from(map! {
+			String::from("name") => self.name.to_raw().into(),
+			String::from("tokenizers") => self
+				.tokenizers
+				.as_ref()
+				.map_or(Value::None, |v| list(v.iter().map(ToString::to_string).collect())),
+			String::from("filters") => self
+				.filters
+				.as_ref()
+				.map_or(Value::None, |v| list(v.iter().map(ToString::to_string).collect())),
+		})
+	}
+
 	pub(crate) async fn compute(&self, ctx: &Context<'_>, opt: &Options) -> Result<Value, Error> {
 		// Selected DB?
 		opt.needs(Level::Db)?;
@@ -376,6 +478,15 @@ pub struct DefineLoginStatement {
 }
 
 impl DefineLoginStatement {
+	/// Describe this definition as a structured value
+	pub(crate) fn structure(&self) -> Value {
+		Value::from(map! {
+			String::from("name") => self.name.to_raw().into(),
+			String::from("base") => self.base.to_string().into(),
+			String::from("passhash") => self.hash.clone().into(),
+		})
+	}
+
 	/// Process this type returning a computed simple Value
 	pub(crate) async fn compute(&self, ctx: &Context<'_>, opt: &Options) -> Result<Value, Error> {
 		match self.base {
@@ -495,6 +606,16 @@ pub struct DefineTokenStatement {
 }
 
 impl DefineTokenStatement {
+	/// Describe this definition as a structured value
+	pub(crate) fn structure(&self) -> Value {
+		Value::from(map! {
+			String::from("name") => self.name.to_raw().into(),
+			String::from("base") => self.base.to_string().into(),
+			String::from("kind") => self.kind.to_string().into(),
+			String::from("value") => self.code.clone().into(),
+		})
+	}
+
 	/// Process this type returning a computed simple Value
 	pub(crate) async fn compute(&self, ctx: &Context<'_>, opt: &Options) -> Result<Value, Error> {
 		match &self.base {
@@ -608,9 +729,21 @@ pub struct DefineScopeStatement {
 	pub session: Option<Duration>,
 	pub signup: Option<Value>,
 	pub signin: Option<Value>,
+	pub safe: bool,
 }
 
 impl DefineScopeStatement {
+	/// Describe this definition as a structured value
+	pub(crate) fn structure(&self) -> Value {
+		Value::from(map! {
+			String::from("name") => self.name.to_raw().into(),
+			String::from("session") => self.session.clone().map_or(Value::None, Value::from),
+			String::from("signup") => self.signup.as_ref().map(ToString::to_string).into(),
+			String::from("signin") => self.signin.as_ref().map(ToString::to_string).into(),
+			String::from("safe") => self.safe.into(),
+		})
+	}
+
 	/// Process this type returning a computed simple Value
 	pub(crate) async fn compute(&self, ctx: &Context<'_>, opt: &Options) -> Result<Value, Error> {
 		// Selected DB?
@@ -643,6 +776,9 @@ impl Display for DefineScopeStatement {
 		if let Some(ref v) = self.signin {
 			write!(f, " SIGNIN {v}")?
 		}
+		if self.safe {
+			f.write_str(" SAFE")?
+		}
 		Ok(())
 	}
 }
@@ -675,6 +811,7 @@ fn scope(i: &str) -> IResult<&str, DefineScopeStatement> {
 				DefineScopeOption::Signin(ref v) => Some(v.to_owned()),
 				_ => None,
 			}),
+			safe: opts.iter().any(|x| matches!(x, DefineScopeOption::Safe)),
 		},
 	))
 }
@@ -684,10 +821,11 @@ pub enum DefineScopeOption {
 	Session(Duration),
 	Signup(Value),
 	Signin(Value),
+	Safe,
 }
 
 fn scope_opts(i: &str) -> IResult<&str, DefineScopeOption> {
-	alt((scope_session, scope_signup, scope_signin))(i)
+	alt((scope_session, scope_signup, scope_signin, scope_safe))(i)
 }
 
 fn scope_session(i: &str) -> IResult<&str, DefineScopeOption> {
@@ -714,6 +852,12 @@ fn scope_signin(i: &str) -> IResult<&str, DefineScopeOption> {
 	Ok((i, DefineScopeOption::Signin(v)))
 }
 
+fn scope_safe(i: &str) -> IResult<&str, DefineScopeOption> {
+	let (i, _) = shouldbespace(i)?;
+	let (i, _) = tag_no_case("SAFE")(i)?;
+	Ok((i, DefineScopeOption::Safe))
+}
+
 // --------------------------------------------------
 // --------------------------------------------------
 // --------------------------------------------------
@@ -726,6 +870,14 @@ pub struct DefineParamStatement {
 }
 
 impl DefineParamStatement {
+	/// Describe this definition as a structured value
+	pub(crate) fn structure(&self) -> Value {
+		Value::from(map! {
+			String::from("name") => self.name.to_raw().into(),
+			String::from("value") => self.value.clone(),
+		})
+	}
+
 	/// Process this type returning a computed simple Value
 	pub(crate) async fn compute(&self, ctx: &Context<'_>, opt: &Options) -> Result<Value, Error> {
 		// Selected DB?
@@ -776,6 +928,234 @@ fn param(i: &str) -> IResult<&str, DefineParamStatement> {
 // --------------------------------------------------
 // --------------------------------------------------
 
+#[derive(Clone, Debug, Default, Eq, PartialEq, Serialize, Deserialize, Store, Hash)]
+#[format(Named)]
+pub struct DefineSequenceStatement {
+	pub name: Ident,
+	pub start: i64,
+	pub step: i64,
+}
+
+impl DefineSequenceStatement {
+	/// Describe this definition as a structured value
+	pub(crate) fn structure(&self) -> Value {
+		Value::from(map! {
+			String::from("name") => self.name.to_raw().into(),
+			String::from("start") => self.start.into(),
+			String::from("step") => self.step.into(),
+		})
+	}
+
+	/// Process this type returning a computed simple Value
+	pub(crate) async fn compute(&self, ctx: &Context<'_>, opt: &Options) -> Result<Value, Error> {
+		// Selected DB?
+		opt.needs(Level::Db)?;
+		// Allowed to run?
+		opt.check(Level::Db)?;
+		// Clone transaction
+		let txn = ctx.clone_transaction()?;
+		// Claim transaction
+		let mut run = txn.lock().await;
+		// Process the statement
+		let key = crate::key::sq::new(opt.ns(), opt.db(), &self.name);
+		run.add_ns(opt.ns(), opt.strict).await?;
+		run.add_db(opt.ns(), opt.db(), opt.strict).await?;
+		run.set(key, self).await?;
+		// Clear the cache
+		let key = crate::key::sq::new(opt.ns(), opt.db(), &self.name);
+		run.clr(key).await?;
+		let key = crate::key::sq::prefix(opt.ns(), opt.db());
+		run.clr(key).await?;
+		// Ok all good
+		Ok(Value::None)
+	}
+}
+
+impl Display for DefineSequenceStatement {
+	fn fmt(&self, f: &mut fmt::Formatter) -> fmt::Result {
+		write!(f, "DEFINE SEQUENCE {} START {} INCREMENT {}", self.name, self.start, self.step)
+	}
+}
+
+fn sequence(i: &str) -> IResult<&str, DefineSequenceStatement> {
+	let (i, _) = tag_no_case("DEFINE")(i)?;
+	let (i, _) = shouldbespace(i)?;
+	let (i, _) = tag_no_case("SEQUENCE")(i)?;
+	let (i, _) = shouldbespace(i)?;
+	let (i, name) = ident(i)?;
+	let (i, opts) = many0(sequence_opts)(i)?;
+	Ok((
+		i,
+		DefineSequenceStatement {
+			name,
+			start: opts
+				.iter()
+				.find_map(|x| match x {
+					DefineSequenceOption::Start(ref v) => Some(*v),
+					_ => None,
+				})
+				.unwrap_or(1),
+			step: opts
+				.iter()
+				.find_map(|x| match x {
+					DefineSequenceOption::Step(ref v) => Some(*v),
+					_ => None,
+				})
+				.unwrap_or(1),
+		},
+	))
+}
+
+#[derive(Clone, Debug, Eq, PartialEq, Serialize, Deserialize)]
+pub enum DefineSequenceOption {
+	Start(i64),
+	Step(i64),
+}
+
+fn sequence_opts(i: &str) -> IResult<&str, DefineSequenceOption> {
+	alt((sequence_start, sequence_step))(i)
+}
+
+fn sequence_start(i: &str) -> IResult<&str, DefineSequenceOption> {
+	let (i, _) = shouldbespace(i)?;
+	let (i, _) = tag_no_case("START")(i)?;
+	let (i, _) = shouldbespace(i)?;
+	let (i, v) = integer(i)?;
+	Ok((i, DefineSequenceOption::Start(v)))
+}
+
+fn sequence_step(i: &str) -> IResult<&str, DefineSequenceOption> {
+	let (i, _) = shouldbespace(i)?;
+	let (i, _) = tag_no_case("INCREMENT")(i)?;
+	let (i, _) = shouldbespace(i)?;
+	let (i, _) = opt(tuple((tag_no_case("BY"), shouldbespace)))(i)?;
+	let (i, v) = integer(i)?;
+	// A sequence must increase or decrease with each value
+	if v == 0 {
+		return Err(nom::Err::Failure(crate::sql::error::Error::Parser(i)));
+	}
+	Ok((i, DefineSequenceOption::Step(v)))
+}
+
+// --------------------------------------------------
+// --------------------------------------------------
+// --------------------------------------------------
+
+#[derive(Clone, Debug, Default, Eq, PartialEq, Serialize, Deserialize, Store, Hash)]
+#[format(Named)]
+pub struct DefineMigrationStatement {
+	pub name: Ident,
+	pub up: Statements,
+	pub down: Option<Statements>,
+	pub comment: Option<Strand>,
+}
+
+impl DefineMigrationStatement {
+	/// Process this type returning a computed simple Value
+	pub(crate) async fn compute(&self, ctx: &Context<'_>, opt: &Options) -> Result<Value, Error> {
+		// Selected DB?
+		opt.needs(Level::Db)?;
+		// Allowed to run?
+		opt.check(Level::Db)?;
+		// Check the migration scripts
+		for v in self.up.iter().chain(self.down.iter().flat_map(|v| v.iter())) {
+			if let Statement::Apply(_)
+			| Statement::Begin(_)
+			| Statement::Cancel(_)
+			| Statement::Commit(_)
+			| Statement::Global(_)
+			| Statement::Option(_)
+			| Statement::Show(_)
+			| Statement::Use(_) = v
+			{
+				return Err(Error::MgInvalid {
+					value: self.name.to_raw(),
+					kind: v.kind().to_uppercase(),
+				});
+			}
+		}
+		// Clone transaction
+		let txn = ctx.clone_transaction()?;
+		// Claim transaction
+		let mut run = txn.lock().await;
+		// Process the statement
+		let key = crate::key::mg::new(opt.ns(), opt.db(), &self.name);
+		run.add_ns(opt.ns(), opt.strict).await?;
+		run.add_db(opt.ns(), opt.db(), opt.strict).await?;
+		run.set(key, self).await?;
+		// Clear the cache
+		let key = crate::key::mg::prefix(opt.ns(), opt.db());
+		run.clr(key).await?;
+		// Ok all good
+		Ok(Value::None)
+	}
+}
+
+/// Write the statements of a migration script within braces
+fn script(f: &mut fmt::Formatter, v: &Statements) -> fmt::Result {
+	f.write_char('{')?;
+	for v in v.iter() {
+		write!(f, " {v};")?;
+	}
+	if !v.is_empty() {
+		f.write_char(' ')?;
+	}
+	f.write_char('}')
+}
+
+impl Display for DefineMigrationStatement {
+	fn fmt(&self, f: &mut fmt::Formatter) -> fmt::Result {
+		write!(f, "DEFINE MIGRATION {} UP ", self.name)?;
+		script(f, &self.up)?;
+		if let Some(ref v) = self.down {
+			f.write_str(" DOWN ")?;
+			script(f, v)?;
+		}
+		if let Some(ref v) = self.comment {
+			write!(f, " COMMENT {v}")?
+		}
+		Ok(())
+	}
+}
+
+fn migration(i: &str) -> IResult<&str, DefineMigrationStatement> {
+	let (i, _) = tag_no_case("DEFINE")(i)?;
+	let (i, _) = shouldbespace(i)?;
+	let (i, _) = tag_no_case("MIGRATION")(i)?;
+	let (i, _) = shouldbespace(i)?;
+	let (i, name) = ident(i)?;
+	let (i, _) = mightbespace(i)?;
+	let (i, _) = opt(tuple((tag_no_case("UP"), mightbespace)))(i)?;
+	let (i, up) = migration_script(i)?;
+	let (i, down) = opt(preceded(
+		tuple((shouldbespace, tag_no_case("DOWN"), mightbespace)),
+		migration_script,
+	))(i)?;
+	let (i, comment) =
+		opt(preceded(tuple((shouldbespace, tag_no_case("COMMENT"), shouldbespace)), strand))(i)?;
+	Ok((
+		i,
+		DefineMigrationStatement {
+			name,
+			up,
+			down,
+			comment,
+		},
+	))
+}
+
+fn migration_script(i: &str) -> IResult<&str, Statements> {
+	let (i, _) = openbraces(i)?;
+	let (i, v) = separated_list0(colons, statement)(i)?;
+	let (i, _) = opt(colons)(i)?;
+	let (i, _) = closebraces(i)?;
+	Ok((i, Statements(v)))
+}
+
+// --------------------------------------------------
+// --------------------------------------------------
+// --------------------------------------------------
+
 #[derive(Clone, Debug, Default, Eq, PartialEq, Serialize, Deserialize, Store, Hash)]
 #[format(Named)]
 pub struct DefineTableStatement {
@@ -783,10 +1163,41 @@ pub struct DefineTableStatement {
 	pub drop: bool,
 	pub full: bool,
 	pub view: Option<View>,
+	pub audit: Option<Audit>,
+	pub archive: bool,
+	pub history: bool,
+	pub soft: Option<Duration>,
+	pub id: Option<IdGenerator>,
+	pub compression: Option<Compression>,
+	pub partition: Option<Value>,
+	pub schema: Option<Object>,
 	pub permissions: Permissions,
+	pub comment: Option<Strand>,
+	pub relation: Option<Relation>,
 }
 
 impl DefineTableStatement {
+	/// Describe this definition as a structured value
+	pub(crate) fn structure(&self) -> Value {
+		Value::from(map! {
+			String::from("name") => self.name.to_raw().into(),
+			String::from("drop") => self.drop.into(),
+			String::from("full") => self.full.into(),
+			String::from("view") => self.view.as_ref().map(ToString::to_string).into(),
+			String::from("audit") => self.audit.as_ref().map(ToString::to_string).into(),
+			String::from("archive") => self.archive.into(),
+			String::from("history") => self.history.into(),
+			String::from("soft") => self.soft.clone().map_or(Value::None, Value::from),
+			String::from("id") => self.id.as_ref().map(ToString::to_string).into(),
+			String::from("compression") => self.compression.as_ref().map(ToString::to_string).into(),
+			String::from("partition") => self.partition.as_ref().map(ToString::to_string).into(),
+			String::from("schema") => self.schema.clone().map_or(Value::None, Value::from),
+			String::from("permissions") => self.permissions.structure(),
+			String::from("comment") => self.comment.as_ref().map(|v| v.as_str().to_owned()).into(),
+			String::from("relation") => self.relation.as_ref().map(ToString::to_string).into(),
+		})
+	}
+
 	pub(crate) async fn compute(&self, ctx: &Context<'_>, opt: &Options) -> Result<Value, Error> {
 		// Selected DB?
 		opt.needs(Level::Db)?;
@@ -800,12 +1211,20 @@ impl DefineTableStatement {
 		let key = crate::key::tb::new(opt.ns(), opt.db(), &self.name);
 		run.add_ns(opt.ns(), opt.strict).await?;
 		run.add_db(opt.ns(), opt.db(), opt.strict).await?;
+		// Get the previous definition of the table
+		let prev = run.get_tb(opt.ns(), opt.db(), &self.name).await.ok();
+		// Start counting the records of a new table
+		if prev.is_none() {
+			run.set_cn(opt.ns(), opt.db(), &self.name, 0).await?;
+		}
 		run.set(key, self).await?;
 		// Check if table is a view
 		if let Some(view) = &self.view {
 			// Remove the table data
 			let key = crate::key::table::new(opt.ns(), opt.db(), &self.name);
 			run.delp(key, u32::MAX).await?;
+			// Count the records of the view from scratch
+			run.set_cn(opt.ns(), opt.db(), &self.name, 0).await?;
 			// Process each foreign table
 			for v in view.what.0.iter() {
 				// Save the view config
@@ -825,6 +1244,8 @@ impl DefineTableStatement {
 			let opt = &opt.events(false);
 			// Don't process index queries
 			let opt = &opt.indexes(false);
+			// Don't restrict whole table changes
+			let opt = &opt.safe(false);
 			// Process each foreign table
 			for v in view.what.0.iter() {
 				// Process the view data
@@ -834,6 +1255,11 @@ impl DefineTableStatement {
 				};
 				stm.compute(ctx, opt).await?;
 			}
+		} else if prev.map_or(self.partition.is_some(), |v| v.partition != self.partition) {
+			// Release the transaction
+			drop(run);
+			// Partition the existing records of the table
+			Document::repartition(ctx, opt, self).await?;
 		}
 		// Ok all good
 		Ok(Value::None)
@@ -851,9 +1277,42 @@ impl Display for DefineTableStatement {
 		} else {
 			" SCHEMALESS"
 		})?;
+		if self.archive {
+			f.write_str(" ARCHIVE")?;
+		}
+		if self.history {
+			f.write_str(" HISTORY")?;
+		}
+		if let Some(ref v) = self.soft {
+			f.write_str(" SOFTDELETE")?;
+			if !v.is_zero() {
+				write!(f, " RETAIN {v}")?
+			}
+		}
+		if let Some(ref v) = self.id {
+			write!(f, " ID {v}")?
+		}
+		if let Some(ref v) = self.compression {
+			write!(f, " COMPRESSION {v}")?
+		}
+		if let Some(ref v) = self.partition {
+			write!(f, " PARTITION BY {v}")?
+		}
+		if let Some(ref v) = self.schema {
+			write!(f, " SCHEMA {v}")?
+		}
+		if let Some(ref v) = self.relation {
+			write!(f, " {v}")?
+		}
 		if let Some(ref v) = self.view {
 			write!(f, " {v}")?
 		}
+		if let Some(ref v) = self.audit {
+			write!(f, " {v}")?
+		}
+		if let Some(ref v) = self.comment {
+			write!(f, " COMMENT {v}")?
+		}
 		if !self.permissions.is_full() {
 			let _indent = if is_pretty() {
 				Some(pretty_indent())
@@ -897,6 +1356,44 @@ fn table(i: &str) -> IResult<&str, DefineTableStatement> {
 				DefineTableOption::View(ref v) => Some(v.to_owned()),
 				_ => None,
 			}),
+			audit: opts.iter().find_map(|x| match x {
+				DefineTableOption::Audit(ref v) => Some(v.to_owned()),
+				_ => None,
+			}),
+			archive: opts
+				.iter()
+				.find_map(|x| match x {
+					DefineTableOption::Archive => Some(true),
+					_ => None,
+				})
+				.unwrap_or_default(),
+			history: opts
+				.iter()
+				.find_map(|x| match x {
+					DefineTableOption::History => Some(true),
+					_ => None,
+				})
+				.unwrap_or_default(),
+			soft: opts.iter().find_map(|x| match x {
+				DefineTableOption::Soft(ref v) => Some(v.to_owned()),
+				_ => None,
+			}),
+			id: opts.iter().find_map(|x| match x {
+				DefineTableOption::Id(ref v) => Some(v.to_owned()),
+				_ => None,
+			}),
+			compression: opts.iter().find_map(|x| match x {
+				DefineTableOption::Compression(ref v) => Some(v.to_owned()),
+				_ => None,
+			}),
+			partition: opts.iter().find_map(|x| match x {
+				DefineTableOption::Partition(ref v) => Some(v.to_owned()),
+				_ => None,
+			}),
+			schema: opts.iter().find_map(|x| match x {
+				DefineTableOption::Schema(ref v) => Some(v.to_owned()),
+				_ => None,
+			}),
 			permissions: opts
 				.iter()
 				.find_map(|x| match x {
@@ -904,6 +1401,14 @@ fn table(i: &str) -> IResult<&str, DefineTableStatement> {
 					_ => None,
 				})
 				.unwrap_or_default(),
+			comment: opts.iter().find_map(|x| match x {
+				DefineTableOption::Comment(ref v) => Some(v.to_owned()),
+				_ => None,
+			}),
+			relation: opts.iter().find_map(|x| match x {
+				DefineTableOption::Relation(ref v) => Some(v.to_owned()),
+				_ => None,
+			}),
 		},
 	))
 }
@@ -912,13 +1417,104 @@ fn table(i: &str) -> IResult<&str, DefineTableStatement> {
 pub enum DefineTableOption {
 	Drop,
 	View(View),
+	Audit(Audit),
+	Archive,
+	History,
+	Soft(Duration),
+	Id(IdGenerator),
+	Compression(Compression),
+	Partition(Value),
 	Schemaless,
 	Schemafull,
+	Schema(Object),
 	Permissions(Permissions),
+	Comment(Strand),
+	Relation(Relation),
+}
+
+/// How the ids of records which are created without an id are generated
+#[derive(Clone, Debug, Eq, PartialEq, Serialize, Deserialize, Hash)]
+pub enum IdGenerator {
+	/// A random 20 character id, which is the default
+	Rand,
+	/// A time-sortable ULID
+	Ulid,
+	/// A time-sortable version 7 UUID
+	Uuid,
+	/// The next value of a sequence, as a number
+	Sequence(Ident),
+}
+
+impl Display for IdGenerator {
+	fn fmt(&self, f: &mut fmt::Formatter) -> fmt::Result {
+		match self {
+			Self::Rand => f.write_str("RAND"),
+			Self::Ulid => f.write_str("ULID"),
+			Self::Uuid => f.write_str("UUID"),
+			Self::Sequence(v) => write!(f, "SEQUENCE {v}"),
+		}
+	}
+}
+
+/// How the data of the records in a table is compressed when it is stored
+#[derive(Clone, Copy, Debug, Eq, PartialEq, Serialize, Deserialize, Hash)]
+pub enum Compression {
+	/// Fast compression, with a moderate compression ratio
+	Snappy,
+	/// Slower compression, with a higher compression ratio
+	Zstd,
+}
+
+impl Display for Compression {
+	fn fmt(&self, f: &mut fmt::Formatter) -> fmt::Result {
+		match self {
+			Self::Snappy => f.write_str("SNAPPY"),
+			Self::Zstd => f.write_str("ZSTD"),
+		}
+	}
+}
+
+/// The tables of the records which the edges of a relation table connect,
+/// where any table is allowed if none are specified
+#[derive(Clone, Debug, Default, Eq, PartialEq, Serialize, Deserialize, Hash)]
+pub struct Relation {
+	/// The tables of the `in` records
+	pub from: Option<Tables>,
+	/// The tables of the `out` records
+	pub with: Option<Tables>,
+}
+
+impl Display for Relation {
+	fn fmt(&self, f: &mut fmt::Formatter) -> fmt::Result {
+		f.write_str("RELATION")?;
+		if let Some(ref v) = self.from {
+			write!(f, " IN {}", Fmt::verbar_separated(v.iter()))?
+		}
+		if let Some(ref v) = self.with {
+			write!(f, " OUT {}", Fmt::verbar_separated(v.iter()))?
+		}
+		Ok(())
+	}
 }
 
 fn table_opts(i: &str) -> IResult<&str, DefineTableOption> {
-	alt((table_drop, table_view, table_schemaless, table_schemafull, table_permissions))(i)
+	alt((
+		table_drop,
+		table_view,
+		table_audit,
+		table_archive,
+		table_history,
+		table_soft,
+		table_id,
+		table_compression,
+		table_partition,
+		table_schemaless,
+		table_schemafull,
+		table_schema,
+		table_permissions,
+		table_comment,
+		table_relation,
+	))(i)
 }
 
 fn table_drop(i: &str) -> IResult<&str, DefineTableOption> {
@@ -933,6 +1529,68 @@ fn table_view(i: &str) -> IResult<&str, DefineTableOption> {
 	Ok((i, DefineTableOption::View(v)))
 }
 
+fn table_audit(i: &str) -> IResult<&str, DefineTableOption> {
+	let (i, _) = shouldbespace(i)?;
+	let (i, v) = audit(i)?;
+	Ok((i, DefineTableOption::Audit(v)))
+}
+
+fn table_archive(i: &str) -> IResult<&str, DefineTableOption> {
+	let (i, _) = shouldbespace(i)?;
+	let (i, _) = tag_no_case("ARCHIVE")(i)?;
+	Ok((i, DefineTableOption::Archive))
+}
+
+fn table_history(i: &str) -> IResult<&str, DefineTableOption> {
+	let (i, _) = shouldbespace(i)?;
+	let (i, _) = tag_no_case("HISTORY")(i)?;
+	Ok((i, DefineTableOption::History))
+}
+
+fn table_soft(i: &str) -> IResult<&str, DefineTableOption> {
+	let (i, _) = shouldbespace(i)?;
+	let (i, _) = tag_no_case("SOFTDELETE")(i)?;
+	let (i, v) =
+		opt(preceded(tuple((shouldbespace, tag_no_case("RETAIN"), shouldbespace)), duration))(i)?;
+	Ok((i, DefineTableOption::Soft(v.unwrap_or_default())))
+}
+
+fn table_id(i: &str) -> IResult<&str, DefineTableOption> {
+	let (i, _) = shouldbespace(i)?;
+	let (i, _) = tag_no_case("ID")(i)?;
+	let (i, _) = shouldbespace(i)?;
+	let (i, v) = alt((
+		map(tag_no_case("RAND"), |_| IdGenerator::Rand),
+		map(tag_no_case("ULID"), |_| IdGenerator::Ulid),
+		map(tag_no_case("UUID"), |_| IdGenerator::Uuid),
+		map(tuple((tag_no_case("SEQUENCE"), shouldbespace, ident)), |(_, _, v)| {
+			IdGenerator::Sequence(v)
+		}),
+	))(i)?;
+	Ok((i, DefineTableOption::Id(v)))
+}
+
+fn table_compression(i: &str) -> IResult<&str, DefineTableOption> {
+	let (i, _) = shouldbespace(i)?;
+	let (i, _) = tag_no_case("COMPRESSION")(i)?;
+	let (i, _) = shouldbespace(i)?;
+	let (i, v) = alt((
+		map(tag_no_case("SNAPPY"), |_| Compression::Snappy),
+		map(tag_no_case("ZSTD"), |_| Compression::Zstd),
+	))(i)?;
+	Ok((i, DefineTableOption::Compression(v)))
+}
+
+fn table_partition(i: &str) -> IResult<&str, DefineTableOption> {
+	let (i, _) = shouldbespace(i)?;
+	let (i, _) = tag_no_case("PARTITION")(i)?;
+	let (i, _) = shouldbespace(i)?;
+	let (i, _) = tag_no_case("BY")(i)?;
+	let (i, _) = shouldbespace(i)?;
+	let (i, v) = value(i)?;
+	Ok((i, DefineTableOption::Partition(v)))
+}
+
 fn table_schemaless(i: &str) -> IResult<&str, DefineTableOption> {
 	let (i, _) = shouldbespace(i)?;
 	let (i, _) = tag_no_case("SCHEMALESS")(i)?;
@@ -945,12 +1603,53 @@ fn table_schemafull(i: &str) -> IResult<&str, DefineTableOption> {
 	Ok((i, DefineTableOption::Schemafull))
 }
 
+fn table_schema(i: &str) -> IResult<&str, DefineTableOption> {
+	let (i, _) = shouldbespace(i)?;
+	let (i, _) = tag_no_case("SCHEMA")(i)?;
+	let (i, _) = shouldbespace(i)?;
+	let (i, v) = object(i)?;
+	Ok((i, DefineTableOption::Schema(v)))
+}
+
 fn table_permissions(i: &str) -> IResult<&str, DefineTableOption> {
 	let (i, _) = shouldbespace(i)?;
 	let (i, v) = permissions(i)?;
 	Ok((i, DefineTableOption::Permissions(v)))
 }
 
+fn table_relation(i: &str) -> IResult<&str, DefineTableOption> {
+	let (i, _) = shouldbespace(i)?;
+	let (i, _) = tag_no_case("RELATION")(i)?;
+	let (i, from) = opt(preceded(
+		tuple((shouldbespace, tag_no_case("IN"), shouldbespace)),
+		relation_tables,
+	))(i)?;
+	let (i, with) = opt(preceded(
+		tuple((shouldbespace, tag_no_case("OUT"), shouldbespace)),
+		relation_tables,
+	))(i)?;
+	Ok((
+		i,
+		DefineTableOption::Relation(Relation {
+			from,
+			with,
+		}),
+	))
+}
+
+fn relation_tables(i: &str) -> IResult<&str, Tables> {
+	let (i, v) = separated_list1(verbar, crate::sql::table::table)(i)?;
+	Ok((i, Tables(v)))
+}
+
+fn table_comment(i: &str) -> IResult<&str, DefineTableOption> {
+	let (i, _) = shouldbespace(i)?;
+	let (i, _) = tag_no_case("COMMENT")(i)?;
+	let (i, _) = shouldbespace(i)?;
+	let (i, v) = strand(i)?;
+	Ok((i, DefineTableOption::Comment(v)))
+}
+
 // --------------------------------------------------
 // --------------------------------------------------
 // --------------------------------------------------
@@ -965,6 +1664,21 @@ pub struct DefineEventStatement {
 }
 
 impl DefineEventStatement {
+	/// Describe this definition as a structured value
+	pub(crate) fn structure(&self) -> Value {
+		Value::from(map! {
+			String::from("name") => self.name.to_raw().into(),
+			String::from("what") => self.what.to_raw().into(),
+			String::from("when") => self.when.to_string().into(),
+			String::from("then") => self
+				.then
+				.iter()
+				.map(ToString::to_string)
+				.collect::<Vec<_>>()
+				.into(),
+		})
+	}
+
 	/// Process this type returning a computed simple Value
 	pub(crate) async fn compute(&self, ctx: &Context<'_>, opt: &Options) -> Result<Value, Error> {
 		// Selected DB?
@@ -1041,11 +1755,32 @@ pub struct DefineFieldStatement {
 	pub flex: bool,
 	pub kind: Option<Kind>,
 	pub value: Option<Value>,
+	pub computed: bool,
 	pub assert: Option<Value>,
+	pub message: Option<Strand>,
 	pub permissions: Permissions,
+	pub comment: Option<Strand>,
+	pub sensitive: Option<Sensitive>,
 }
 
 impl DefineFieldStatement {
+	/// Describe this definition as a structured value
+	pub(crate) fn structure(&self) -> Value {
+		Value::from(map! {
+			String::from("name") => self.name.to_string().into(),
+			String::from("what") => self.what.to_raw().into(),
+			String::from("flex") => self.flex.into(),
+			String::from("kind") => self.kind.as_ref().map(ToString::to_string).into(),
+			String::from("value") => self.value.as_ref().map(ToString::to_string).into(),
+			String::from("computed") => self.computed.into(),
+			String::from("assert") => self.assert.as_ref().map(ToString::to_string).into(),
+			String::from("message") => self.message.as_ref().map(|v| v.as_str().to_owned()).into(),
+			String::from("permissions") => self.permissions.structure(),
+			String::from("comment") => self.comment.as_ref().map(|v| v.as_str().to_owned()).into(),
+			String::from("sensitive") => self.sensitive.as_ref().map(ToString::to_string).into(),
+		})
+	}
+
 	/// Process this type returning a computed simple Value
 	pub(crate) async fn compute(&self, ctx: &Context<'_>, opt: &Options) -> Result<Value, Error> {
 		// Selected DB?
@@ -1083,9 +1818,21 @@ impl Display for DefineFieldStatement {
 		if let Some(ref v) = self.value {
 			write!(f, " VALUE {v}")?
 		}
+		if self.computed {
+			write!(f, " COMPUTED")?
+		}
 		if let Some(ref v) = self.assert {
 			write!(f, " ASSERT {v}")?
 		}
+		if let Some(ref v) = self.message {
+			write!(f, " MESSAGE {v}")?
+		}
+		if let Some(ref v) = self.sensitive {
+			write!(f, " SENSITIVE {v}")?
+		}
+		if let Some(ref v) = self.comment {
+			write!(f, " COMMENT {v}")?
+		}
 		if !self.permissions.is_full() {
 			write!(f, " {}", self.permissions)?;
 		}
@@ -1093,6 +1840,29 @@ impl Display for DefineFieldStatement {
 	}
 }
 
+/// How the value of a sensitive field is anonymized, when the records
+/// of a database are exported for a non-production environment
+#[derive(Clone, Copy, Debug, Default, Eq, PartialEq, Serialize, Deserialize, Hash)]
+pub enum Sensitive {
+	/// Replace each letter and digit, keeping the shape of the value
+	#[default]
+	Mask,
+	/// Replace the value with a hash, so that equal values remain equal
+	Hash,
+	/// Replace the value with a realistic value of the same type
+	Fake,
+}
+
+impl Display for Sensitive {
+	fn fmt(&self, f: &mut fmt::Formatter) -> fmt::Result {
+		match self {
+			Self::Mask => f.write_str("MASK"),
+			Self::Hash => f.write_str("HASH"),
+			Self::Fake => f.write_str("FAKE"),
+		}
+	}
+}
+
 fn field(i: &str) -> IResult<&str, DefineFieldStatement> {
 	let (i, _) = tag_no_case("DEFINE")(i)?;
 	let (i, _) = shouldbespace(i)?;
@@ -1125,8 +1895,19 @@ fn field(i: &str) -> IResult<&str, DefineFieldStatement> {
 				DefineFieldOption::Value(ref v) => Some(v.to_owned()),
 				_ => None,
 			}),
+			computed: opts
+				.iter()
+				.find_map(|x| match x {
+					DefineFieldOption::Computed => Some(true),
+					_ => None,
+				})
+				.unwrap_or_default(),
 			assert: opts.iter().find_map(|x| match x {
-				DefineFieldOption::Assert(ref v) => Some(v.to_owned()),
+				DefineFieldOption::Assert(ref v, _) => Some(v.to_owned()),
+				_ => None,
+			}),
+			message: opts.iter().find_map(|x| match x {
+				DefineFieldOption::Assert(_, ref v) => v.to_owned(),
 				_ => None,
 			}),
 			permissions: opts
@@ -1136,6 +1917,14 @@ fn field(i: &str) -> IResult<&str, DefineFieldStatement> {
 					_ => None,
 				})
 				.unwrap_or_default(),
+			comment: opts.iter().find_map(|x| match x {
+				DefineFieldOption::Comment(ref v) => Some(v.to_owned()),
+				_ => None,
+			}),
+			sensitive: opts.iter().find_map(|x| match x {
+				DefineFieldOption::Sensitive(v) => Some(*v),
+				_ => None,
+			}),
 		},
 	))
 }
@@ -1145,12 +1934,24 @@ pub enum DefineFieldOption {
 	Flex,
 	Kind(Kind),
 	Value(Value),
-	Assert(Value),
+	Computed,
+	Assert(Value, Option<Strand>),
 	Permissions(Permissions),
+	Comment(Strand),
+	Sensitive(Sensitive),
 }
 
 fn field_opts(i: &str) -> IResult<&str, DefineFieldOption> {
-	alt((field_flex, field_kind, field_value, field_assert, field_permissions))(i)
+	alt((
+		field_flex,
+		field_kind,
+		field_value,
+		field_computed,
+		field_assert,
+		field_permissions,
+		field_comment,
+		field_sensitive,
+	))(i)
 }
 
 fn field_flex(i: &str) -> IResult<&str, DefineFieldOption> {
@@ -1175,12 +1976,20 @@ fn field_value(i: &str) -> IResult<&str, DefineFieldOption> {
 	Ok((i, DefineFieldOption::Value(v)))
 }
 
+fn field_computed(i: &str) -> IResult<&str, DefineFieldOption> {
+	let (i, _) = shouldbespace(i)?;
+	let (i, _) = tag_no_case("COMPUTED")(i)?;
+	Ok((i, DefineFieldOption::Computed))
+}
+
 fn field_assert(i: &str) -> IResult<&str, DefineFieldOption> {
 	let (i, _) = shouldbespace(i)?;
 	let (i, _) = tag_no_case("ASSERT")(i)?;
 	let (i, _) = shouldbespace(i)?;
 	let (i, v) = value(i)?;
-	Ok((i, DefineFieldOption::Assert(v)))
+	let (i, m) =
+		opt(preceded(tuple((shouldbespace, tag_no_case("MESSAGE"), shouldbespace)), strand))(i)?;
+	Ok((i, DefineFieldOption::Assert(v, m)))
 }
 
 fn field_permissions(i: &str) -> IResult<&str, DefineFieldOption> {
@@ -1189,6 +1998,28 @@ fn field_permissions(i: &str) -> IResult<&str, DefineFieldOption> {
 	Ok((i, DefineFieldOption::Permissions(v)))
 }
 
+fn field_comment(i: &str) -> IResult<&str, DefineFieldOption> {
+	let (i, _) = shouldbespace(i)?;
+	let (i, _) = tag_no_case("COMMENT")(i)?;
+	let (i, _) = shouldbespace(i)?;
+	let (i, v) = strand(i)?;
+	Ok((i, DefineFieldOption::Comment(v)))
+}
+
+fn field_sensitive(i: &str) -> IResult<&str, DefineFieldOption> {
+	let (i, _) = shouldbespace(i)?;
+	let (i, _) = tag_no_case("SENSITIVE")(i)?;
+	let (i, v) = opt(preceded(
+		shouldbespace,
+		alt((
+			map(tag_no_case("MASK"), |_| Sensitive::Mask),
+			map(tag_no_case("HASH"), |_| Sensitive::Hash),
+			map(tag_no_case("FAKE"), |_| Sensitive::Fake),
+		)),
+	))(i)?;
+	Ok((i, DefineFieldOption::Sensitive(v.unwrap_or_default())))
+}
+
 // --------------------------------------------------
 // --------------------------------------------------
 // --------------------------------------------------
@@ -1200,9 +2031,46 @@ pub struct DefineIndexStatement {
 	pub what: Ident,
 	pub cols: Idioms,
 	pub index: Index,
+	pub collation: Option<Collation>,
 }
 
 impl DefineIndexStatement {
+	/// Describe this definition as a structured value
+	pub(crate) fn structure(&self) -> Value {
+		Value::from(map! {
+			String::from("name") => self.name.to_raw().into(),
+			String::from("what") => self.what.to_raw().into(),
+			String::from("cols") => self
+				.cols
+				.iter()
+				.map(ToString::to_string)
+				.collect::<Vec<_>>()
+				.into(),
+			String::from("unique") => matches!(self.index, Index::Uniq).into(),
+			String::from("search") => match self.index {
+				Index::Search { .. } => Value::from(self.index.to_string()),
+				_ => Value::None,
+			},
+			String::from("vector") => match self.index {
+				Index::Hnsw { .. } => Value::from(self.index.to_string()),
+				_ => Value::None,
+			},
+			String::from("collation") => match self.collation {
+				Some(ref v) => Value::from(v.to_string()),
+				None => Value::None,
+			},
+		})
+	}
+
+	/// Get the values which are stored in the index for the values of the
+	/// indexed fields, which are the sort keys of strings with a collation
+	pub(crate) fn collated(&self, v: Array) -> Array {
+		match self.collation {
+			Some(ref c) => v.into_iter().map(|v| c.index(v)).collect(),
+			None => v,
+		}
+	}
+
 	/// Process this type returning a computed simple Value
 	pub(crate) async fn compute(&self, ctx: &Context<'_>, opt: &Options) -> Result<Value, Error> {
 		// Selected DB?
@@ -1226,6 +2094,22 @@ impl DefineIndexStatement {
 		let beg = crate::key::index::prefix(opt.ns(), opt.db(), &self.what, &self.name);
 		let end = crate::key::index::suffix(opt.ns(), opt.db(), &self.what, &self.name);
 		run.delr(beg..end, u32::MAX).await?;
+		let beg = crate::key::ie::prefix(opt.ns(), opt.db(), &self.what, &self.name);
+		let end = crate::key::ie::suffix(opt.ns(), opt.db(), &self.what, &self.name);
+		run.delr(beg..end, u32::MAX).await?;
+		// Build the index of a large table in the background
+		if let Some(threshold) = ctx.config().index_build_threshold {
+			if let Some(count) = run.get_cn(opt.ns(), opt.db(), &self.what).await? {
+				if count as u64 > threshold {
+					let build = Build::new(count as u64);
+					build.set(&mut run, opt.ns(), opt.db(), &self.what, &self.name).await?;
+					return Ok(Value::None);
+				}
+			}
+		}
+		// Stop any build of a previous definition
+		let key = crate::key::ib::new(opt.ns(), opt.db(), &self.what, &self.name);
+		run.del(key).await?;
 		// Release the transaction
 		drop(run);
 		// Force queries to run
@@ -1236,6 +2120,8 @@ impl DefineIndexStatement {
 		let opt = &opt.events(false);
 		// Don't process table queries
 		let opt = &opt.tables(false);
+		// Don't restrict whole table changes
+		let opt = &opt.safe(false);
 		// Update the index data
 		let stm = UpdateStatement {
 			what: Values(vec![Value::Table(self.what.clone().into())]),
@@ -1253,6 +2139,9 @@ impl Display for DefineIndexStatement {
 		if Index::Idx != self.index {
 			write!(f, " {}", self.index)?;
 		}
+		if let Some(ref v) = self.collation {
+			write!(f, " {v}")?;
+		}
 		Ok(())
 	}
 }
@@ -1274,6 +2163,12 @@ fn index(i: &str) -> IResult<&str, DefineIndexStatement> {
 	let (i, cols) = idiom::locals(i)?;
 	let (i, _) = mightbespace(i)?;
 	let (i, index) = index::index(i)?;
+	// Only the keys of plain and unique indexes can be collated
+	let (i, collation) = match index {
+		Index::Idx => opt(collation)(i)?,
+		Index::Uniq => opt(preceded(shouldbespace, collation))(i)?,
+		_ => (i, None),
+	};
 	Ok((
 		i,
 		DefineIndexStatement {
@@ -1281,6 +2176,7 @@ fn index(i: &str) -> IResult<&str, DefineIndexStatement> {
 			what,
 			cols,
 			index,
+			collation,
 		},
 	))
 }
@@ -1288,6 +2184,7 @@ fn index(i: &str) -> IResult<&str, DefineIndexStatement> {
 #[cfg(test)]
 mod tests {
 	use super::*;
+	use crate::sql::index::Distance;
 	use crate::sql::scoring::Scoring;
 	use crate::sql::Part;
 
@@ -1295,8 +2192,142 @@ mod tests {
 	fn check_define_serialize() {
 		let stm = DefineStatement::Namespace(DefineNamespaceStatement {
 			name: Ident::from("test"),
+			..Default::default()
 		});
-		assert_eq!(22, stm.to_vec().len());
+		assert_eq!(38, stm.to_vec().len());
+	}
+
+	#[test]
+	fn check_define_namespace_limits() {
+		let sql = "DEFINE NAMESPACE acme LIMIT RECORDS 1000 BYTES 1048576";
+		let (_, ns) = namespace(sql).unwrap();
+		assert_eq!(ns.records, Some(1000));
+		assert_eq!(ns.bytes, Some(1048576));
+		assert_eq!(ns.to_string(), sql);
+		assert!(namespace("DEFINE NAMESPACE acme LIMIT").is_err());
+	}
+
+	#[test]
+	fn check_define_sequence_step() {
+		let sql = "DEFINE SEQUENCE countdown START 100 INCREMENT -1";
+		let (_, sq) = sequence(sql).unwrap();
+		assert_eq!(sq.start, 100);
+		assert_eq!(sq.step, -1);
+		assert_eq!(sq.to_string(), sql);
+		assert!(sequence("DEFINE SEQUENCE invoice INCREMENT 0").is_err());
+		assert!(sequence("DEFINE SEQUENCE invoice START 1 INCREMENT BY 0").is_err());
+	}
+
+	#[test]
+	fn check_define_safe_scope() {
+		let sql = "DEFINE SCOPE account SESSION 1h SAFE";
+		let (_, sc) = scope(sql).unwrap();
+		assert!(sc.safe);
+		assert_eq!(sc.to_string(), "DEFINE SCOPE account SESSION 1h SAFE");
+	}
+
+	#[test]
+	fn check_define_comment() {
+		let sql = "DEFINE TABLE person SCHEMALESS COMMENT 'A person'";
+		let (_, tb) = table(sql).unwrap();
+		assert_eq!(tb.comment, Some(Strand::from("A person")));
+		assert_eq!(tb.to_string(), sql);
+		let sql = "DEFINE FIELD name ON person TYPE string COMMENT 'The name' PERMISSIONS NONE";
+		let (_, fd) = field(sql).unwrap();
+		assert_eq!(fd.comment, Some(Strand::from("The name")));
+		assert_eq!(fd.to_string(), sql);
+	}
+
+	#[test]
+	fn check_define_field_sensitive() {
+		let sql = "DEFINE FIELD email ON user TYPE string SENSITIVE FAKE";
+		let (_, fd) = field(sql).unwrap();
+		assert_eq!(fd.sensitive, Some(Sensitive::Fake));
+		assert_eq!(fd.to_string(), sql);
+		let (_, fd) = field("DEFINE FIELD ssn ON user sensitive").unwrap();
+		assert_eq!(fd.sensitive, Some(Sensitive::Mask));
+		assert_eq!(fd.to_string(), "DEFINE FIELD ssn ON user SENSITIVE MASK");
+	}
+
+	#[test]
+	fn check_define_table_id() {
+		let sql = "DEFINE TABLE invoice SCHEMALESS ID SEQUENCE invoice";
+		let (_, tb) = table(sql).unwrap();
+		assert_eq!(tb.id, Some(IdGenerator::Sequence(Ident::from("invoice"))));
+		assert_eq!(tb.to_string(), sql);
+		let (_, tb) = table("DEFINE TABLE event id ulid").unwrap();
+		assert_eq!(tb.id, Some(IdGenerator::Ulid));
+		assert_eq!(tb.to_string(), "DEFINE TABLE event SCHEMALESS ID ULID");
+	}
+
+	#[test]
+	fn check_define_table_compression() {
+		let sql = "DEFINE TABLE article SCHEMALESS COMPRESSION ZSTD";
+		let (_, tb) = table(sql).unwrap();
+		assert_eq!(tb.compression, Some(Compression::Zstd));
+		assert_eq!(tb.to_string(), sql);
+		let (_, tb) = table("DEFINE TABLE article compression snappy").unwrap();
+		assert_eq!(tb.compression, Some(Compression::Snappy));
+		assert_eq!(tb.to_string(), "DEFINE TABLE article SCHEMALESS COMPRESSION SNAPPY");
+	}
+
+	#[test]
+	fn check_define_table_partition() {
+		let sql = "DEFINE TABLE events SCHEMALESS PARTITION BY time::month(at) PERMISSIONS NONE";
+		let (_, tb) = table(sql).unwrap();
+		assert_eq!(tb.to_string(), sql);
+		assert_eq!(tb.partition.unwrap().to_string(), "time::month(at)");
+	}
+
+	#[test]
+	fn check_define_table_history() {
+		let sql = "DEFINE TABLE person SCHEMALESS HISTORY";
+		let (_, tb) = table(sql).unwrap();
+		assert!(tb.history);
+		assert_eq!(tb.to_string(), sql);
+	}
+
+	#[test]
+	fn check_define_table_soft() {
+		let sql = "DEFINE TABLE person SCHEMALESS SOFTDELETE RETAIN 1w";
+		let (_, tb) = table(sql).unwrap();
+		assert_eq!(tb.soft, Some(Duration::try_from("1w").unwrap()));
+		assert_eq!(tb.to_string(), sql);
+		let (_, tb) = table("DEFINE TABLE person SOFTDELETE").unwrap();
+		assert_eq!(tb.soft, Some(Duration::default()));
+		assert_eq!(tb.to_string(), "DEFINE TABLE person SCHEMALESS SOFTDELETE");
+	}
+
+	#[test]
+	fn check_define_table_relation() {
+		let sql = "DEFINE TABLE likes SCHEMALESS RELATION IN person | team OUT post";
+		let (_, tb) = table(sql).unwrap();
+		let rel = tb.relation.as_ref().unwrap();
+		assert_eq!(rel.from.as_ref().map(|v| v.len()), Some(2));
+		assert_eq!(rel.with.as_ref().map(|v| v.len()), Some(1));
+		assert_eq!(tb.to_string(), sql);
+		let (_, tb) = table("DEFINE TABLE likes RELATION OUT post").unwrap();
+		assert_eq!(tb.relation.as_ref().unwrap().from, None);
+		assert_eq!(tb.to_string(), "DEFINE TABLE likes SCHEMALESS RELATION OUT post");
+	}
+
+	#[test]
+	fn check_define_migration() {
+		let sql = "DEFINE MIGRATION v1 UP { DEFINE TABLE person SCHEMALESS; UPDATE person SET age = 18; } DOWN { REMOVE TABLE person; } COMMENT 'People'";
+		let (_, mg) = migration(sql).unwrap();
+		assert_eq!(mg.up.len(), 2);
+		assert_eq!(mg.to_string(), sql);
+		let (_, mg) = migration("DEFINE MIGRATION v2 {}").unwrap();
+		assert!(mg.down.is_none());
+		assert_eq!(mg.to_string(), "DEFINE MIGRATION v2 UP {}");
+	}
+
+	#[test]
+	fn check_define_table_schema() {
+		let sql = "DEFINE TABLE person SCHEMALESS SCHEMA { required: ['name'], type: 'object' }";
+		let (_, tb) = table(sql).unwrap();
+		assert!(tb.schema.is_some());
+		assert_eq!(tb.to_string(), sql);
 	}
 
 	#[test]
@@ -1310,6 +2341,7 @@ mod tests {
 				what: Ident("my_table".to_string()),
 				cols: Idioms(vec![Idiom(vec![Part::Field(Ident("my_col".to_string()))])]),
 				index: Index::Idx,
+				collation: None,
 			}
 		);
 		assert_eq!(idx.to_string(), "DEFINE INDEX my_index ON my_table FIELDS my_col");
@@ -1326,6 +2358,7 @@ mod tests {
 				what: Ident("my_table".to_string()),
 				cols: Idioms(vec![Idiom(vec![Part::Field(Ident("my_col".to_string()))])]),
 				index: Index::Uniq,
+				collation: None,
 			}
 		);
 		assert_eq!(idx.to_string(), "DEFINE INDEX my_index ON my_table FIELDS my_col UNIQUE");
@@ -1350,6 +2383,7 @@ mod tests {
 					},
 					order: 1000
 				},
+				collation: None,
 			}
 		);
 		assert_eq!(idx.to_string(), "DEFINE INDEX my_index ON my_table FIELDS my_col SEARCH ANALYZER my_analyzer BM25(1.2,0.75) ORDER 1000 HIGHLIGHTS");
@@ -1372,6 +2406,7 @@ mod tests {
 					sc: Scoring::Vs,
 					order: 100
 				},
+				collation: None,
 			}
 		);
 		assert_eq!(
@@ -1379,4 +2414,29 @@ mod tests {
 			"DEFINE INDEX my_index ON my_table FIELDS my_col SEARCH ANALYZER my_analyzer VS ORDER 100"
 		);
 	}
+
+	#[test]
+	fn check_create_hnsw_index() {
+		let sql = "DEFINE INDEX my_index ON TABLE my_table COLUMNS my_col HNSW DIMENSION 3 DIST COSINE";
+		let (_, idx) = index(sql).unwrap();
+		assert_eq!(
+			idx,
+			DefineIndexStatement {
+				name: Ident("my_index".to_string()),
+				what: Ident("my_table".to_string()),
+				cols: Idioms(vec![Idiom(vec![Part::Field(Ident("my_col".to_string()))])]),
+				index: Index::Hnsw {
+					dimension: 3,
+					dist: Distance::Cosine,
+					m: 12,
+					efc: 150,
+				},
+				collation: None,
+			}
+		);
+		assert_eq!(
+			idx.to_string(),
+			"DEFINE INDEX my_index ON my_table FIELDS my_col HNSW DIMENSION 3 DIST COSINE M 12 EFC 150"
+		);
+	}
 }
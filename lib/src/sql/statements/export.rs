@@ -0,0 +1,71 @@
+use crate::ctx::Context;
+use crate::dbs::Level;
+use crate::dbs::Options;
+use crate::err::Error;
+use crate::sql::comment::shouldbespace;
+use crate::sql::error::IResult;
+use crate::sql::value::Value;
+use derive::Store;
+use nom::bytes::complete::tag_no_case;
+use nom::combinator::map;
+use serde::{Deserialize, Serialize};
+use std::fmt;
+
+#[derive(Clone, Debug, Default, Eq, PartialEq, Serialize, Deserialize, Store, Hash)]
+pub enum ExportStatement {
+	#[default]
+	Schema,
+}
+
+impl ExportStatement {
+	/// Process this type returning a computed simple Value
+	pub(crate) async fn compute(&self, ctx: &Context<'_>, opt: &Options) -> Result<Value, Error> {
+		// Selected DB?
+		opt.needs(Level::Db)?;
+		// Allowed to run?
+		opt.check(Level::Db)?;
+		// Clone transaction
+		let txn = ctx.clone_transaction()?;
+		// Claim transaction
+		let mut run = txn.lock().await;
+		// Export the definitions
+		let (snd, rcv) = channel::unbounded();
+		run.export_schema(opt.ns(), opt.db(), snd).await?;
+		// Collect the exported statements
+		let mut out = Vec::new();
+		while let Ok(v) = rcv.try_recv() {
+			out.extend(v);
+		}
+		// Ok all good
+		Ok(String::from_utf8_lossy(&out).into_owned().into())
+	}
+}
+
+impl fmt::Display for ExportStatement {
+	fn fmt(&self, f: &mut fmt::Formatter) -> fmt::Result {
+		match self {
+			Self::Schema => f.write_str("EXPORT SCHEMA"),
+		}
+	}
+}
+
+pub fn export(i: &str) -> IResult<&str, ExportStatement> {
+	let (i, _) = tag_no_case("EXPORT")(i)?;
+	let (i, _) = shouldbespace(i)?;
+	map(tag_no_case("SCHEMA"), |_| ExportStatement::Schema)(i)
+}
+
+#[cfg(test)]
+mod tests {
+
+	use super::*;
+
+	#[test]
+	fn export_schema() {
+		let sql = "EXPORT SCHEMA";
+		let res = export(sql);
+		assert!(res.is_ok());
+		let out = res.unwrap().1;
+		assert_eq!("EXPORT SCHEMA", format!("{}", out))
+	}
+}
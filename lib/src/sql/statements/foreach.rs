@@ -0,0 +1,109 @@
+use crate::cnf::PROTECTED_PARAM_NAMES;
+use crate::ctx::Context;
+use crate::dbs::Options;
+use crate::err::Error;
+use crate::sql::block::{block, Block};
+use crate::sql::comment::{mightbespace, shouldbespace};
+use crate::sql::error::IResult;
+use crate::sql::param::{param, Param};
+use crate::sql::value::{value, Value};
+use derive::Store;
+use nom::bytes::complete::tag_no_case;
+use serde::{Deserialize, Serialize};
+use std::fmt::{self, Display};
+
+#[derive(Clone, Debug, Default, Eq, PartialEq, Serialize, Deserialize, Store, Hash)]
+pub struct ForeachStatement {
+	pub param: Param,
+	pub range: Value,
+	pub block: Block,
+}
+
+impl ForeachStatement {
+	/// Check if we require a writeable transaction
+	pub(crate) fn writeable(&self) -> bool {
+		self.range.writeable() || self.block.writeable()
+	}
+	/// Process this type returning a computed simple Value
+	pub(crate) async fn compute(&self, ctx: &Context<'_>, opt: &Options) -> Result<Value, Error> {
+		// Check if the variable is a protected variable
+		if PROTECTED_PARAM_NAMES.contains(&self.param.as_str()) {
+			return Err(Error::InvalidParam {
+				name: self.param.to_raw(),
+			});
+		}
+		// Check that the range is an array
+		let range = match self.range.compute(ctx, opt).await? {
+			Value::Array(v) => v,
+			v => {
+				return Err(Error::InvalidForeach {
+					value: v.to_string(),
+				})
+			}
+		};
+		// Loop over the values
+		for v in range {
+			// Check if the context is finished
+			if ctx.is_done() {
+				break;
+			}
+			// Set the loop variable for this iteration
+			let mut ctx = Context::new(ctx);
+			ctx.add_value(self.param.to_raw(), v);
+			// Process the block
+			self.block.compute(&ctx, opt).await?;
+		}
+		// Ok all good
+		Ok(Value::None)
+	}
+}
+
+impl Display for ForeachStatement {
+	fn fmt(&self, f: &mut fmt::Formatter) -> fmt::Result {
+		write!(f, "FOR {} IN {} {}", self.param, self.range, self.block)
+	}
+}
+
+pub fn foreach(i: &str) -> IResult<&str, ForeachStatement> {
+	let (i, _) = tag_no_case("FOR")(i)?;
+	let (i, _) = shouldbespace(i)?;
+	let (i, param) = param(i)?;
+	let (i, _) = shouldbespace(i)?;
+	let (i, _) = tag_no_case("IN")(i)?;
+	let (i, _) = shouldbespace(i)?;
+	let (i, range) = value(i)?;
+	let (i, _) = mightbespace(i)?;
+	let (i, block) = block(i)?;
+	Ok((
+		i,
+		ForeachStatement {
+			param,
+			range,
+			block,
+		},
+	))
+}
+
+#[cfg(test)]
+mod tests {
+
+	use super::*;
+
+	#[test]
+	fn foreach_statement_first() {
+		let sql = "FOR $test IN [1, 2, 3, 4, 5] { UPDATE person:test SET scores += $test; }";
+		let res = foreach(sql);
+		assert!(res.is_ok());
+		let out = res.unwrap().1;
+		assert_eq!(sql, format!("{}", out))
+	}
+
+	#[test]
+	fn foreach_statement_param() {
+		let sql = "FOR $person IN $people { CREATE person CONTENT $person; }";
+		let res = foreach(sql);
+		assert!(res.is_ok());
+		let out = res.unwrap().1;
+		assert_eq!(sql, format!("{}", out))
+	}
+}
@@ -0,0 +1,65 @@
+use crate::sql::comment::mightbespace;
+use crate::sql::comment::shouldbespace;
+use crate::sql::error::IResult;
+use crate::sql::ident::{ident, Ident};
+use crate::sql::value::{value, Value};
+use derive::Store;
+use nom::bytes::complete::tag_no_case;
+use nom::character::complete::char;
+use serde::{Deserialize, Serialize};
+use std::fmt;
+
+#[derive(Clone, Debug, Default, Eq, PartialEq, Serialize, Deserialize, Store, Hash)]
+pub struct GlobalStatement {
+	pub name: Ident,
+	pub what: Value,
+}
+
+impl fmt::Display for GlobalStatement {
+	fn fmt(&self, f: &mut fmt::Formatter) -> fmt::Result {
+		write!(f, "SET GLOBAL {} = {}", self.name, self.what)
+	}
+}
+
+pub fn global(i: &str) -> IResult<&str, GlobalStatement> {
+	let (i, _) = tag_no_case("SET")(i)?;
+	let (i, _) = shouldbespace(i)?;
+	let (i, _) = tag_no_case("GLOBAL")(i)?;
+	let (i, _) = shouldbespace(i)?;
+	let (i, n) = ident(i)?;
+	let (i, _) = mightbespace(i)?;
+	let (i, _) = char('=')(i)?;
+	let (i, _) = mightbespace(i)?;
+	let (i, w) = value(i)?;
+	Ok((
+		i,
+		GlobalStatement {
+			name: n,
+			what: w,
+		},
+	))
+}
+
+#[cfg(test)]
+mod tests {
+
+	use super::*;
+
+	#[test]
+	fn global_statement() {
+		let sql = "SET GLOBAL slow_query_threshold = 500ms";
+		let res = global(sql);
+		assert!(res.is_ok());
+		let out = res.unwrap().1;
+		assert_eq!("SET GLOBAL slow_query_threshold = 500ms", format!("{}", out));
+	}
+
+	#[test]
+	fn global_statement_lowercase() {
+		let sql = "set global log_level='debug'";
+		let res = global(sql);
+		assert!(res.is_ok());
+		let out = res.unwrap().1;
+		assert_eq!("SET GLOBAL log_level = 'debug'", format!("{}", out));
+	}
+}
@@ -2,24 +2,87 @@ use crate::ctx::Context;
 use crate::dbs::Level;
 use crate::dbs::Options;
 use crate::err::Error;
+use crate::idx::build::Build;
 use crate::sql::comment::shouldbespace;
 use crate::sql::error::IResult;
 use crate::sql::ident::{ident, Ident};
 use crate::sql::object::Object;
+use crate::sql::statements::{
+	DefineAnalyzerStatement, DefineDatabaseStatement, DefineEventStatement, DefineFieldStatement,
+	DefineFunctionStatement, DefineIndexStatement, DefineLoginStatement, DefineNamespaceStatement,
+	DefineParamStatement, DefineScopeStatement, DefineSequenceStatement, DefineTableStatement,
+	DefineTokenStatement,
+};
 use crate::sql::value::Value;
 use derive::Store;
 use nom::branch::alt;
 use nom::bytes::complete::tag_no_case;
+use nom::combinator::opt;
+use nom::sequence::tuple;
 use serde::{Deserialize, Serialize};
 use std::fmt;
 
 #[derive(Clone, Debug, Eq, PartialEq, Serialize, Deserialize, Store, Hash)]
 pub enum InfoStatement {
-	Kv,
-	Ns,
-	Db,
-	Sc(Ident),
-	Tb(Ident),
+	Kv(bool),
+	Ns(bool),
+	Db(bool),
+	Sc(Ident, bool),
+	Tb(Ident, bool),
+	Mg,
+	Ix(Ident, Ident),
+}
+
+/// A definition which can be described by an INFO statement
+trait Describe: fmt::Display {
+	/// The name of this definition
+	fn name(&self) -> String;
+	/// Describe this definition as a structured value
+	fn structure(&self) -> Value;
+}
+
+macro_rules! describe {
+	($($t:ty),+) => {
+		$(impl Describe for $t {
+			fn name(&self) -> String {
+				self.name.to_string()
+			}
+			fn structure(&self) -> Value {
+				<$t>::structure(self)
+			}
+		})+
+	};
+}
+
+describe!(
+	DefineAnalyzerStatement,
+	DefineDatabaseStatement,
+	DefineEventStatement,
+	DefineFieldStatement,
+	DefineFunctionStatement,
+	DefineIndexStatement,
+	DefineLoginStatement,
+	DefineNamespaceStatement,
+	DefineParamStatement,
+	DefineScopeStatement,
+	DefineSequenceStatement,
+	DefineTableStatement,
+	DefineTokenStatement
+);
+
+/// Describe a set of definitions, either as an object of DEFINE statements
+/// by name, or as an array of structured values
+fn describe<T: Describe>(defs: &[T], structured: bool) -> Value {
+	match structured {
+		true => defs.iter().map(Describe::structure).collect::<Vec<_>>().into(),
+		false => {
+			let mut tmp = Object::default();
+			for v in defs.iter() {
+				tmp.insert(v.name(), v.to_string().into());
+			}
+			tmp.into()
+		}
+	}
 }
 
 impl InfoStatement {
@@ -27,7 +90,7 @@ impl InfoStatement {
 	pub(crate) async fn compute(&self, ctx: &Context<'_>, opt: &Options) -> Result<Value, Error> {
 		// Allowed to run?
 		match self {
-			InfoStatement::Kv => {
+			InfoStatement::Kv(structured) => {
 				// No need for NS/DB
 				opt.needs(Level::Kv)?;
 				// Allowed to run?
@@ -39,15 +102,12 @@ impl InfoStatement {
 				// Claim transaction
 				let mut run = txn.lock().await;
 				// Process the statement
-				let mut tmp = Object::default();
-				for v in run.all_ns().await?.iter() {
-					tmp.insert(v.name.to_string(), v.to_string().into());
-				}
-				res.insert("namespaces".to_owned(), tmp.into());
+				let tmp = run.all_ns().await?;
+				res.insert("namespaces".to_owned(), describe(&tmp, *structured));
 				// Ok all good
 				Value::from(res).ok()
 			}
-			InfoStatement::Ns => {
+			InfoStatement::Ns(structured) => {
 				// Selected NS?
 				opt.needs(Level::Ns)?;
 				// Allowed to run?
@@ -59,27 +119,46 @@ impl InfoStatement {
 				// Create the result set
 				let mut res = Object::default();
 				// Process the databases
-				let mut tmp = Object::default();
-				for v in run.all_db(opt.ns()).await?.iter() {
-					tmp.insert(v.name.to_string(), v.to_string().into());
-				}
-				res.insert("databases".to_owned(), tmp.into());
+				let tmp = run.all_db(opt.ns()).await?;
+				res.insert("databases".to_owned(), describe(&tmp, *structured));
 				// Process the logins
-				let mut tmp = Object::default();
-				for v in run.all_nl(opt.ns()).await?.iter() {
-					tmp.insert(v.name.to_string(), v.to_string().into());
-				}
-				res.insert("logins".to_owned(), tmp.into());
+				let tmp = run.all_nl(opt.ns()).await?;
+				res.insert("logins".to_owned(), describe(&tmp, *structured));
 				// Process the tokens
-				let mut tmp = Object::default();
-				for v in run.all_nt(opt.ns()).await?.iter() {
-					tmp.insert(v.name.to_string(), v.to_string().into());
+				let tmp = run.all_nt(opt.ns()).await?;
+				res.insert("tokens".to_owned(), describe(&tmp, *structured));
+				// Process the usage
+				let queries = ctx.usage().map(|v| v.queries(opt.ns())).unwrap_or_default();
+				let mut total = (0, 0);
+				let mut dbs = Object::default();
+				for db in run.all_db(opt.ns()).await?.iter() {
+					let (records, bytes) = run.usage_db(opt.ns(), &db.name).await?;
+					let count = queries.get(db.name.as_str()).copied().unwrap_or_default();
+					total = (total.0 + records, total.1 + bytes);
+					dbs.insert(
+						db.name.to_string(),
+						map! {
+							"bytes".to_string() => bytes.into(),
+							"queries".to_string() => count.into(),
+							"records".to_string() => records.into(),
+						}
+						.into(),
+					);
 				}
-				res.insert("tokens".to_owned(), tmp.into());
+				res.insert(
+					"usage".to_owned(),
+					map! {
+						"bytes".to_string() => total.1.into(),
+						"databases".to_string() => dbs.into(),
+						"queries".to_string() => queries.values().sum::<u64>().into(),
+						"records".to_string() => total.0.into(),
+					}
+					.into(),
+				);
 				// Ok all good
 				Value::from(res).ok()
 			}
-			InfoStatement::Db => {
+			InfoStatement::Db(structured) => {
 				// Selected DB?
 				opt.needs(Level::Db)?;
 				// Allowed to run?
@@ -91,51 +170,33 @@ impl InfoStatement {
 				// Create the result set
 				let mut res = Object::default();
 				// Process the logins
-				let mut tmp = Object::default();
-				for v in run.all_dl(opt.ns(), opt.db()).await?.iter() {
-					tmp.insert(v.name.to_string(), v.to_string().into());
-				}
-				res.insert("logins".to_owned(), tmp.into());
+				let tmp = run.all_dl(opt.ns(), opt.db()).await?;
+				res.insert("logins".to_owned(), describe(&tmp, *structured));
 				// Process the tokens
-				let mut tmp = Object::default();
-				for v in run.all_dt(opt.ns(), opt.db()).await?.iter() {
-					tmp.insert(v.name.to_string(), v.to_string().into());
-				}
-				res.insert("tokens".to_owned(), tmp.into());
+				let tmp = run.all_dt(opt.ns(), opt.db()).await?;
+				res.insert("tokens".to_owned(), describe(&tmp, *structured));
 				// Process the functions
-				let mut tmp = Object::default();
-				for v in run.all_fc(opt.ns(), opt.db()).await?.iter() {
-					tmp.insert(v.name.to_string(), v.to_string().into());
-				}
-				res.insert("functions".to_owned(), tmp.into());
+				let tmp = run.all_fc(opt.ns(), opt.db()).await?;
+				res.insert("functions".to_owned(), describe(&tmp, *structured));
 				// Process the params
-				let mut tmp = Object::default();
-				for v in run.all_pa(opt.ns(), opt.db()).await?.iter() {
-					tmp.insert(v.name.to_string(), v.to_string().into());
-				}
-				res.insert("params".to_owned(), tmp.into());
+				let tmp = run.all_pa(opt.ns(), opt.db()).await?;
+				res.insert("params".to_owned(), describe(&tmp, *structured));
+				// Process the sequences
+				let tmp = run.all_sq(opt.ns(), opt.db()).await?;
+				res.insert("sequences".to_owned(), describe(&tmp, *structured));
 				// Process the scopes
-				let mut tmp = Object::default();
-				for v in run.all_sc(opt.ns(), opt.db()).await?.iter() {
-					tmp.insert(v.name.to_string(), v.to_string().into());
-				}
-				res.insert("scopes".to_owned(), tmp.into());
+				let tmp = run.all_sc(opt.ns(), opt.db()).await?;
+				res.insert("scopes".to_owned(), describe(&tmp, *structured));
 				// Process the tables
-				let mut tmp = Object::default();
-				for v in run.all_tb(opt.ns(), opt.db()).await?.iter() {
-					tmp.insert(v.name.to_string(), v.to_string().into());
-				}
-				res.insert("tables".to_owned(), tmp.into());
+				let tmp = run.all_tb(opt.ns(), opt.db()).await?;
+				res.insert("tables".to_owned(), describe(&tmp, *structured));
 				// Process the analyzers
-				let mut tmp = Object::default();
-				for v in run.all_az(opt.ns(), opt.db()).await?.iter() {
-					tmp.insert(v.name.to_string(), v.to_string().into());
-				}
-				res.insert("analyzers".to_owned(), tmp.into());
+				let tmp = run.all_az(opt.ns(), opt.db()).await?;
+				res.insert("analyzers".to_owned(), describe(&tmp, *structured));
 				// Ok all good
 				Value::from(res).ok()
 			}
-			InfoStatement::Sc(sc) => {
+			InfoStatement::Sc(sc, structured) => {
 				// Selected DB?
 				opt.needs(Level::Db)?;
 				// Allowed to run?
@@ -147,15 +208,12 @@ impl InfoStatement {
 				// Create the result set
 				let mut res = Object::default();
 				// Process the tokens
-				let mut tmp = Object::default();
-				for v in run.all_st(opt.ns(), opt.db(), sc).await?.iter() {
-					tmp.insert(v.name.to_string(), v.to_string().into());
-				}
-				res.insert("tokens".to_owned(), tmp.into());
+				let tmp = run.all_st(opt.ns(), opt.db(), sc).await?;
+				res.insert("tokens".to_owned(), describe(&tmp, *structured));
 				// Ok all good
 				Value::from(res).ok()
 			}
-			InfoStatement::Tb(tb) => {
+			InfoStatement::Tb(tb, structured) => {
 				// Selected DB?
 				opt.needs(Level::Db)?;
 				// Allowed to run?
@@ -167,32 +225,68 @@ impl InfoStatement {
 				// Create the result set
 				let mut res = Object::default();
 				// Process the events
-				let mut tmp = Object::default();
-				for v in run.all_ev(opt.ns(), opt.db(), tb).await?.iter() {
-					tmp.insert(v.name.to_string(), v.to_string().into());
-				}
-				res.insert("events".to_owned(), tmp.into());
+				let tmp = run.all_ev(opt.ns(), opt.db(), tb).await?;
+				res.insert("events".to_owned(), describe(&tmp, *structured));
 				// Process the fields
-				let mut tmp = Object::default();
-				for v in run.all_fd(opt.ns(), opt.db(), tb).await?.iter() {
-					tmp.insert(v.name.to_string(), v.to_string().into());
-				}
-				res.insert("fields".to_owned(), tmp.into());
+				let tmp = run.all_fd(opt.ns(), opt.db(), tb).await?;
+				res.insert("fields".to_owned(), describe(&tmp, *structured));
 				// Process the tables
-				let mut tmp = Object::default();
-				for v in run.all_ft(opt.ns(), opt.db(), tb).await?.iter() {
-					tmp.insert(v.name.to_string(), v.to_string().into());
-				}
-				res.insert("tables".to_owned(), tmp.into());
+				let tmp = run.all_ft(opt.ns(), opt.db(), tb).await?;
+				res.insert("tables".to_owned(), describe(&tmp, *structured));
 				// Process the indexes
+				let tmp = run.all_ix(opt.ns(), opt.db(), tb).await?;
+				res.insert("indexes".to_owned(), describe(&tmp, *structured));
+				// Process the record count
+				let tmp = run.count_tb(opt.ns(), opt.db(), tb, ctx.config().exact_count).await?;
+				res.insert("count".to_owned(), tmp.into());
+				// Ok all good
+				Value::from(res).ok()
+			}
+			InfoStatement::Mg => {
+				// Selected DB?
+				opt.needs(Level::Db)?;
+				// Allowed to run?
+				opt.check(Level::Db)?;
+				// Clone transaction
+				let txn = ctx.clone_transaction()?;
+				// Claim transaction
+				let mut run = txn.lock().await;
+				// Create the result set
+				let mut res = Object::default();
+				// Process the migrations
 				let mut tmp = Object::default();
-				for v in run.all_ix(opt.ns(), opt.db(), tb).await?.iter() {
-					tmp.insert(v.name.to_string(), v.to_string().into());
+				for v in run.all_mg(opt.ns(), opt.db()).await?.iter() {
+					let applied = run.get_ma(opt.ns(), opt.db(), &v.name).await?;
+					let mut mg = Object::default();
+					mg.insert("applied".to_owned(), applied.unwrap_or_default());
+					mg.insert("definition".to_owned(), v.to_string().into());
+					tmp.insert(v.name.to_string(), mg.into());
 				}
-				res.insert("indexes".to_owned(), tmp.into());
+				res.insert("migrations".to_owned(), tmp.into());
 				// Ok all good
 				Value::from(res).ok()
 			}
+			InfoStatement::Ix(ix, tb) => {
+				// Selected DB?
+				opt.needs(Level::Db)?;
+				// Allowed to run?
+				opt.check(Level::Db)?;
+				// Clone transaction
+				let txn = ctx.clone_transaction()?;
+				// Claim transaction
+				let mut run = txn.lock().await;
+				// Check that the index exists
+				run.get_ix(opt.ns(), opt.db(), tb, ix).await?;
+				// Process the progress of any background build
+				match Build::get(&mut run, opt.ns(), opt.db(), tb, ix).await? {
+					Some(v) => v.output().ok(),
+					None => {
+						let mut res = Object::default();
+						res.insert("status".to_owned(), "ready".into());
+						Value::from(res).ok()
+					}
+				}
+			}
 		}
 	}
 }
@@ -200,11 +294,18 @@ impl InfoStatement {
 impl fmt::Display for InfoStatement {
 	fn fmt(&self, f: &mut fmt::Formatter) -> fmt::Result {
 		match self {
-			Self::Kv => f.write_str("INFO FOR KV"),
-			Self::Ns => f.write_str("INFO FOR NAMESPACE"),
-			Self::Db => f.write_str("INFO FOR DATABASE"),
-			Self::Sc(ref s) => write!(f, "INFO FOR SCOPE {s}"),
-			Self::Tb(ref t) => write!(f, "INFO FOR TABLE {t}"),
+			Self::Kv(_) => f.write_str("INFO FOR KV")?,
+			Self::Ns(_) => f.write_str("INFO FOR NAMESPACE")?,
+			Self::Db(_) => f.write_str("INFO FOR DATABASE")?,
+			Self::Sc(ref s, _) => write!(f, "INFO FOR SCOPE {s}")?,
+			Self::Tb(ref t, _) => write!(f, "INFO FOR TABLE {t}")?,
+			Self::Mg => f.write_str("INFO FOR MIGRATIONS")?,
+			Self::Ix(ref i, ref t) => write!(f, "INFO FOR INDEX {i} ON {t}")?,
+		}
+		match self {
+			Self::Kv(true) | Self::Ns(true) | Self::Db(true) => f.write_str(" STRUCTURE"),
+			Self::Sc(_, true) | Self::Tb(_, true) => f.write_str(" STRUCTURE"),
+			_ => Ok(()),
 		}
 	}
 }
@@ -214,36 +315,63 @@ pub fn info(i: &str) -> IResult<&str, InfoStatement> {
 	let (i, _) = shouldbespace(i)?;
 	let (i, _) = tag_no_case("FOR")(i)?;
 	let (i, _) = shouldbespace(i)?;
-	alt((kv, ns, db, sc, tb))(i)
+	alt((kv, ns, db, sc, tb, mg, ix))(i)
+}
+
+fn structure(i: &str) -> IResult<&str, bool> {
+	let (i, v) = opt(tuple((shouldbespace, tag_no_case("STRUCTURE"))))(i)?;
+	Ok((i, v.is_some()))
 }
 
 fn kv(i: &str) -> IResult<&str, InfoStatement> {
 	let (i, _) = tag_no_case("KV")(i)?;
-	Ok((i, InfoStatement::Kv))
+	let (i, v) = structure(i)?;
+	Ok((i, InfoStatement::Kv(v)))
 }
 
 fn ns(i: &str) -> IResult<&str, InfoStatement> {
 	let (i, _) = alt((tag_no_case("NAMESPACE"), tag_no_case("NS")))(i)?;
-	Ok((i, InfoStatement::Ns))
+	let (i, v) = structure(i)?;
+	Ok((i, InfoStatement::Ns(v)))
 }
 
 fn db(i: &str) -> IResult<&str, InfoStatement> {
 	let (i, _) = alt((tag_no_case("DATABASE"), tag_no_case("DB")))(i)?;
-	Ok((i, InfoStatement::Db))
+	let (i, v) = structure(i)?;
+	Ok((i, InfoStatement::Db(v)))
 }
 
 fn sc(i: &str) -> IResult<&str, InfoStatement> {
 	let (i, _) = alt((tag_no_case("SCOPE"), tag_no_case("SC")))(i)?;
 	let (i, _) = shouldbespace(i)?;
 	let (i, scope) = ident(i)?;
-	Ok((i, InfoStatement::Sc(scope)))
+	let (i, v) = structure(i)?;
+	Ok((i, InfoStatement::Sc(scope, v)))
 }
 
 fn tb(i: &str) -> IResult<&str, InfoStatement> {
 	let (i, _) = alt((tag_no_case("TABLE"), tag_no_case("TB")))(i)?;
 	let (i, _) = shouldbespace(i)?;
 	let (i, table) = ident(i)?;
-	Ok((i, InfoStatement::Tb(table)))
+	let (i, v) = structure(i)?;
+	Ok((i, InfoStatement::Tb(table, v)))
+}
+
+fn mg(i: &str) -> IResult<&str, InfoStatement> {
+	let (i, _) = tag_no_case("MIGRATIONS")(i)?;
+	Ok((i, InfoStatement::Mg))
+}
+
+fn ix(i: &str) -> IResult<&str, InfoStatement> {
+	let (i, _) = alt((tag_no_case("INDEX"), tag_no_case("IX")))(i)?;
+	let (i, _) = shouldbespace(i)?;
+	let (i, index) = ident(i)?;
+	let (i, _) = shouldbespace(i)?;
+	let (i, _) = tag_no_case("ON")(i)?;
+	let (i, _) = opt(tuple((shouldbespace, tag_no_case("TABLE"))))(i)?;
+	let (i, _) = shouldbespace(i)?;
+	let (i, table) = ident(i)?;
+	Ok((i, InfoStatement::Ix(index, table)))
 }
 
 #[cfg(test)]
@@ -257,7 +385,7 @@ mod tests {
 		let res = info(sql);
 		assert!(res.is_ok());
 		let out = res.unwrap().1;
-		assert_eq!(out, InfoStatement::Ns);
+		assert_eq!(out, InfoStatement::Ns(false));
 		assert_eq!("INFO FOR NAMESPACE", format!("{}", out));
 	}
 
@@ -267,7 +395,7 @@ mod tests {
 		let res = info(sql);
 		assert!(res.is_ok());
 		let out = res.unwrap().1;
-		assert_eq!(out, InfoStatement::Db);
+		assert_eq!(out, InfoStatement::Db(false));
 		assert_eq!("INFO FOR DATABASE", format!("{}", out));
 	}
 
@@ -277,7 +405,7 @@ mod tests {
 		let res = info(sql);
 		assert!(res.is_ok());
 		let out = res.unwrap().1;
-		assert_eq!(out, InfoStatement::Sc(Ident::from("test")));
+		assert_eq!(out, InfoStatement::Sc(Ident::from("test"), false));
 		assert_eq!("INFO FOR SCOPE test", format!("{}", out));
 	}
 
@@ -287,7 +415,27 @@ mod tests {
 		let res = info(sql);
 		assert!(res.is_ok());
 		let out = res.unwrap().1;
-		assert_eq!(out, InfoStatement::Tb(Ident::from("test")));
+		assert_eq!(out, InfoStatement::Tb(Ident::from("test"), false));
 		assert_eq!("INFO FOR TABLE test", format!("{}", out));
 	}
+
+	#[test]
+	fn info_query_ix() {
+		let sql = "INFO FOR INDEX idx ON TABLE test";
+		let res = info(sql);
+		assert!(res.is_ok());
+		let out = res.unwrap().1;
+		assert_eq!(out, InfoStatement::Ix(Ident::from("idx"), Ident::from("test")));
+		assert_eq!("INFO FOR INDEX idx ON test", format!("{}", out));
+	}
+
+	#[test]
+	fn info_query_tb_structure() {
+		let sql = "INFO FOR TABLE test STRUCTURE";
+		let res = info(sql);
+		assert!(res.is_ok());
+		let out = res.unwrap().1;
+		assert_eq!(out, InfoStatement::Tb(Ident::from("test"), true));
+		assert_eq!("INFO FOR TABLE test STRUCTURE", format!("{}", out));
+	}
 }
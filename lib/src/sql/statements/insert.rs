@@ -67,7 +67,10 @@ impl InsertStatement {
 						o.set(ctx, opt, k, v).await?;
 					}
 					// Specify the new table record id
-					let id = o.rid().generate(&self.into, true)?;
+					let id = match o.rid() {
+						Value::None => self.into.generate_with(ctx, opt).await?,
+						v => v.generate(&self.into, true)?,
+					};
 					// Pass the mergeable to the iterator
 					i.ingest(Iterable::Mergeable(id, o));
 				}
@@ -79,14 +82,20 @@ impl InsertStatement {
 					Value::Array(v) => {
 						for v in v {
 							// Specify the new table record id
-							let id = v.rid().generate(&self.into, true)?;
+							let id = match v.rid() {
+								Value::None => self.into.generate_with(ctx, opt).await?,
+								v => v.generate(&self.into, true)?,
+							};
 							// Pass the mergeable to the iterator
 							i.ingest(Iterable::Mergeable(id, v));
 						}
 					}
 					Value::Object(_) => {
 						// Specify the new table record id
-						let id = v.rid().generate(&self.into, true)?;
+						let id = match v.rid() {
+							Value::None => self.into.generate_with(ctx, opt).await?,
+							v => v.generate(&self.into, true)?,
+						};
 						// Pass the mergeable to the iterator
 						i.ingest(Iterable::Mergeable(id, v));
 					}
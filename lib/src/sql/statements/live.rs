@@ -1,6 +1,10 @@
+use crate::changes::Action;
 use crate::ctx::Context;
 use crate::dbs::Level;
 use crate::dbs::Options;
+use crate::dbs::Statement;
+use crate::dbs::Workable;
+use crate::doc::Document;
 use crate::err::Error;
 use crate::sql::comment::shouldbespace;
 use crate::sql::cond::{cond, Cond};
@@ -8,9 +12,9 @@ use crate::sql::error::IResult;
 use crate::sql::fetch::{fetch, Fetchs};
 use crate::sql::field::{fields, Fields};
 use crate::sql::param::param;
-use crate::sql::table::table;
+use crate::sql::table::{table, Table};
 use crate::sql::uuid::Uuid;
-use crate::sql::value::Value;
+use crate::sql::value::{value, Value};
 use derive::Store;
 use nom::branch::alt;
 use nom::bytes::complete::tag_no_case;
@@ -27,6 +31,7 @@ pub struct LiveStatement {
 	pub what: Value,
 	pub cond: Option<Cond>,
 	pub fetch: Option<Fetchs>,
+	pub since: Option<Value>,
 }
 
 impl LiveStatement {
@@ -40,17 +45,23 @@ impl LiveStatement {
 		opt.check(Level::No)?;
 		// Clone transaction
 		let txn = ctx.clone_transaction()?;
-		// Claim transaction
-		let mut run = txn.lock().await;
 		// Process the live query table
 		match self.what.compute(ctx, opt).await? {
 			Value::Table(tb) => {
+				// Claim transaction
+				let mut run = txn.lock().await;
 				// Insert the live query
 				let key = crate::key::lq::new(opt.ns(), opt.db(), &self.id);
 				run.putc(key, tb.as_str(), None).await?;
 				// Insert the table live query
 				let key = crate::key::lv::new(opt.ns(), opt.db(), &tb, &self.id);
 				run.putc(key, self.clone(), None).await?;
+				// Release the transaction
+				drop(run);
+				// Replay any changes which were missed
+				if let Some(since) = &self.since {
+					self.replay(ctx, opt, &tb, since).await?;
+				}
 			}
 			v => {
 				return Err(Error::LiveStatement {
@@ -61,6 +72,72 @@ impl LiveStatement {
 		// Return the query id
 		Ok(self.id.clone().into())
 	}
+	/// Send a notification for each change to the table since the token
+	/// of the last notification which the subscriber received
+	async fn replay(
+		&self,
+		ctx: &Context<'_>,
+		opt: &Options,
+		tb: &Table,
+		since: &Value,
+	) -> Result<(), Error> {
+		// Compute the token to resume from
+		let token = match since.compute(ctx, opt).await? {
+			Value::Number(v) if v.is_integer() && v.to_int() >= 0 => v.to_int() as u64,
+			v => {
+				return Err(Error::LiveResume {
+					value: v.to_string(),
+				})
+			}
+		};
+		// Clone transaction
+		let txn = ctx.clone_transaction()?;
+		// Get the changes which were missed
+		let changes = match txn.lock().await.changes_since(token).await {
+			Some(v) => v,
+			None => {
+				return Err(Error::LiveResume {
+					value: token.to_string(),
+				})
+			}
+		};
+		// Get the channel of the session which started the live query
+		let chn = match ctx.registry().and_then(|v| v.notifier(&self.id.0)) {
+			Some(v) => v,
+			None => return Ok(()),
+		};
+		// Create a new statement
+		let lq = Statement::from(self);
+		// Loop through the changes to the table
+		for change in changes.iter() {
+			if change.ns != opt.ns() || change.db != opt.db() || change.tb != tb.0 {
+				continue;
+			}
+			let result = match change.action() {
+				// Send a DELETE notification with the record id
+				Action::Delete => Value::from(change.id.clone()),
+				// Process the CREATE or UPDATE notification to send
+				_ => {
+					let doc = Document::new(Some(&change.id), &change.after, Workable::Normal);
+					// Check LIVE SELECT where condition
+					if doc.check(ctx, opt, &lq).await.is_err() {
+						continue;
+					}
+					doc.pluck(ctx, opt, &lq).await?
+				}
+			};
+			// Send the notification once the transaction commits
+			let msg = Value::from(map! {
+				String::from("id") => Value::from(self.id.clone()),
+				String::from("action") => Value::from(change.action().to_string()),
+				String::from("result") => result,
+				String::from("token") => Value::from(change.seq),
+			});
+			txn.lock().await.notify_replay(chn.clone(), msg);
+		}
+		// Carry on
+		Ok(())
+	}
 }
 
 impl fmt::Display for LiveStatement {
@@ -72,6 +149,9 @@ impl fmt::Display for LiveStatement {
 		if let Some(ref v) = self.fetch {
 			write!(f, " {v}")?
 		}
+		if let Some(ref v) = self.since {
+			write!(f, " SINCE {v}")?
+		}
 		Ok(())
 	}
 }
@@ -86,6 +166,7 @@ pub fn live(i: &str) -> IResult<&str, LiveStatement> {
 	let (i, what) = alt((map(param, Value::from), map(table, Value::from)))(i)?;
 	let (i, cond) = opt(preceded(shouldbespace, cond))(i)?;
 	let (i, fetch) = opt(preceded(shouldbespace, fetch))(i)?;
+	let (i, since) = opt(preceded(shouldbespace, since))(i)?;
 	Ok((
 		i,
 		LiveStatement {
@@ -94,6 +175,13 @@ pub fn live(i: &str) -> IResult<&str, LiveStatement> {
 			what,
 			cond,
 			fetch,
+			since,
 		},
 	))
 }
+
+fn since(i: &str) -> IResult<&str, Value> {
+	let (i, _) = tag_no_case("SINCE")(i)?;
+	let (i, _) = shouldbespace(i)?;
+	value(i)
+}
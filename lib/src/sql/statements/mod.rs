@@ -1,10 +1,16 @@
 pub(crate) mod analyze;
+pub(crate) mod apply;
 pub(crate) mod begin;
 pub(crate) mod cancel;
+pub(crate) mod check;
 pub(crate) mod commit;
+pub(crate) mod copy;
 pub(crate) mod create;
 pub(crate) mod define;
 pub(crate) mod delete;
+pub(crate) mod export;
+pub(crate) mod foreach;
+pub(crate) mod global;
 pub(crate) mod ifelse;
 pub(crate) mod info;
 pub(crate) mod insert;
@@ -12,19 +18,29 @@ pub(crate) mod kill;
 pub(crate) mod live;
 pub(crate) mod option;
 pub(crate) mod output;
+pub(crate) mod purge;
+pub(crate) mod rebuild;
 pub(crate) mod relate;
 pub(crate) mod remove;
 pub(crate) mod select;
 pub(crate) mod set;
+pub(crate) mod show;
 pub(crate) mod sleep;
+pub(crate) mod throw;
 pub(crate) mod update;
 pub(crate) mod yuse;
 
+pub use self::apply::ApplyStatement;
 pub use self::begin::BeginStatement;
 pub use self::cancel::CancelStatement;
+pub use self::check::CheckStatement;
 pub use self::commit::CommitStatement;
+pub use self::copy::CopyStatement;
 pub use self::create::CreateStatement;
 pub use self::delete::DeleteStatement;
+pub use self::export::ExportStatement;
+pub use self::foreach::ForeachStatement;
+pub use self::global::GlobalStatement;
 pub use self::ifelse::IfelseStatement;
 pub use self::info::InfoStatement;
 pub use self::insert::InsertStatement;
@@ -32,9 +48,13 @@ pub use self::kill::KillStatement;
 pub use self::live::LiveStatement;
 pub use self::option::OptionStatement;
 pub use self::output::OutputStatement;
+pub use self::purge::PurgeStatement;
+pub use self::rebuild::RebuildStatement;
 pub use self::relate::RelateStatement;
 pub use self::select::SelectStatement;
 pub use self::set::SetStatement;
+pub use self::show::ShowStatement;
+pub use self::throw::ThrowStatement;
 pub use self::update::UpdateStatement;
 pub use self::yuse::UseStatement;
 
@@ -45,9 +65,11 @@ pub use self::define::DefineFieldStatement;
 pub use self::define::DefineFunctionStatement;
 pub use self::define::DefineIndexStatement;
 pub use self::define::DefineLoginStatement;
+pub use self::define::DefineMigrationStatement;
 pub use self::define::DefineNamespaceStatement;
 pub use self::define::DefineParamStatement;
 pub use self::define::DefineScopeStatement;
+pub use self::define::DefineSequenceStatement;
 pub use self::define::DefineStatement;
 pub use self::define::DefineTableStatement;
 pub use self::define::DefineTokenStatement;
@@ -58,9 +80,11 @@ pub use self::remove::RemoveFieldStatement;
 pub use self::remove::RemoveFunctionStatement;
 pub use self::remove::RemoveIndexStatement;
 pub use self::remove::RemoveLoginStatement;
+pub use self::remove::RemoveMigrationStatement;
 pub use self::remove::RemoveNamespaceStatement;
 pub use self::remove::RemoveParamStatement;
 pub use self::remove::RemoveScopeStatement;
+pub use self::remove::RemoveSequenceStatement;
 pub use self::remove::RemoveStatement;
 pub use self::remove::RemoveTableStatement;
 pub use self::remove::RemoveTokenStatement;
@@ -0,0 +1,102 @@
+use crate::ctx::Context;
+use crate::dbs::Level;
+use crate::dbs::Options;
+use crate::err::Error;
+use crate::sql::comment::shouldbespace;
+use crate::sql::cond::Cond;
+use crate::sql::datetime::Datetime;
+use crate::sql::error::IResult;
+use crate::sql::expression::Expression;
+use crate::sql::ident::{ident, Ident};
+use crate::sql::idiom::Idiom;
+use crate::sql::operator::Operator;
+use crate::sql::output::Output;
+use crate::sql::paths::DELETED;
+use crate::sql::statements::DeleteStatement;
+use crate::sql::table::Table;
+use crate::sql::value::{Value, Values};
+use derive::Store;
+use nom::bytes::complete::tag_no_case;
+use serde::{Deserialize, Serialize};
+use std::fmt;
+
+#[derive(Clone, Debug, Default, Eq, PartialEq, Serialize, Deserialize, Store, Hash)]
+pub struct PurgeStatement {
+	pub what: Ident,
+}
+
+impl PurgeStatement {
+	/// Process this type returning a computed simple Value
+	pub(crate) async fn compute(&self, ctx: &Context<'_>, opt: &Options) -> Result<Value, Error> {
+		// Selected DB?
+		opt.needs(Level::Db)?;
+		// Allowed to run?
+		opt.check(Level::Db)?;
+		// Clone transaction
+		let txn = ctx.clone_transaction()?;
+		// Get the table definition
+		let tb = txn.lock().await.get_tb(opt.ns(), opt.db(), self.what.as_str()).await?;
+		// Check if the table keeps deleted records
+		let retain = match tb.soft {
+			Some(ref v) => v,
+			None => {
+				return Err(Error::TbNotSoftDelete {
+					value: self.what.to_raw(),
+				})
+			}
+		};
+		// Records which were deleted before this time are removed
+		let before = match Datetime::default().checked_sub(retain) {
+			Some(v) => v,
+			None => return Ok(Value::from(Vec::<Value>::new())),
+		};
+		// Only select records which were soft deleted
+		let field = Value::Idiom(Idiom::from(DELETED.as_slice()));
+		let cond = Expression::new(
+			Value::from(Expression::new(field.clone(), Operator::NotEqual, Value::None)),
+			Operator::And,
+			Value::from(Expression::new(field, Operator::LessThanOrEqual, before.into())),
+		);
+		// Permanently remove the records
+		let stm = DeleteStatement {
+			what: Values(vec![Value::Table(Table(self.what.to_raw()))]),
+			cond: Some(Cond(Value::from(cond))),
+			output: Some(Output::Before),
+			..DeleteStatement::default()
+		};
+		stm.compute(ctx, &opt.purge(true)).await
+	}
+}
+
+impl fmt::Display for PurgeStatement {
+	fn fmt(&self, f: &mut fmt::Formatter) -> fmt::Result {
+		write!(f, "PURGE {}", self.what)
+	}
+}
+
+pub fn purge(i: &str) -> IResult<&str, PurgeStatement> {
+	let (i, _) = tag_no_case("PURGE")(i)?;
+	let (i, _) = shouldbespace(i)?;
+	let (i, what) = ident(i)?;
+	Ok((
+		i,
+		PurgeStatement {
+			what,
+		},
+	))
+}
+
+#[cfg(test)]
+mod tests {
+
+	use super::*;
+
+	#[test]
+	fn purge_statement() {
+		let sql = "PURGE person";
+		let res = purge(sql);
+		assert!(res.is_ok());
+		let out = res.unwrap().1;
+		assert_eq!("PURGE person", format!("{}", out));
+	}
+}
@@ -0,0 +1,80 @@
+use crate::ctx::Context;
+use crate::dbs::Level;
+use crate::dbs::Options;
+use crate::err::Error;
+use crate::sql::comment::shouldbespace;
+use crate::sql::error::IResult;
+use crate::sql::ident::{ident, Ident};
+use crate::sql::value::Value;
+use derive::Store;
+use nom::bytes::complete::tag_no_case;
+use serde::{Deserialize, Serialize};
+use std::fmt;
+use std::fmt::{Display, Formatter};
+
+#[derive(Clone, Debug, Eq, PartialEq, Serialize, Deserialize, Store, Hash)]
+pub enum RebuildStatement {
+	Idx(Ident, Ident),
+}
+
+impl RebuildStatement {
+	/// Process this type returning a computed simple Value
+	pub(crate) async fn compute(&self, ctx: &Context<'_>, opt: &Options) -> Result<Value, Error> {
+		match self {
+			RebuildStatement::Idx(tb, idx) => {
+				// Selected DB?
+				opt.needs(Level::Db)?;
+				// Allowed to run?
+				opt.check(Level::Db)?;
+				// Clone transaction
+				let txn = ctx.clone_transaction()?;
+				// Claim transaction
+				let mut run = txn.lock().await;
+				// Read the index
+				let ix = run.get_ix(opt.ns(), opt.db(), tb.as_str(), idx.as_str()).await?;
+				// Release the transaction
+				drop(run);
+				// Define the index again, which removes the index data, and
+				// indexes every record of the table from scratch
+				ix.compute(ctx, opt).await
+			}
+		}
+	}
+}
+
+pub fn rebuild(i: &str) -> IResult<&str, RebuildStatement> {
+	let (i, _) = tag_no_case("REBUILD")(i)?;
+	let (i, _) = shouldbespace(i)?;
+	let (i, _) = tag_no_case("INDEX")(i)?;
+	let (i, _) = shouldbespace(i)?;
+	let (i, idx) = ident(i)?;
+	let (i, _) = shouldbespace(i)?;
+	let (i, _) = tag_no_case("ON")(i)?;
+	let (i, _) = shouldbespace(i)?;
+	let (i, tb) = ident(i)?;
+	Ok((i, RebuildStatement::Idx(tb, idx)))
+}
+
+impl Display for RebuildStatement {
+	fn fmt(&self, f: &mut Formatter) -> fmt::Result {
+		match self {
+			Self::Idx(tb, idx) => write!(f, "REBUILD INDEX {idx} ON {tb}"),
+		}
+	}
+}
+
+#[cfg(test)]
+mod tests {
+
+	use super::*;
+
+	#[test]
+	fn rebuild_index() {
+		let sql = "REBUILD INDEX my_index ON my_table";
+		let res = rebuild(sql);
+		assert!(res.is_ok());
+		let out = res.unwrap().1;
+		assert_eq!(out, RebuildStatement::Idx(Ident::from("my_table"), Ident::from("my_index")));
+		assert_eq!("REBUILD INDEX my_index ON my_table", format!("{}", out));
+	}
+}
@@ -165,7 +165,9 @@ impl RelateStatement {
 							Ok(t) => i.ingest(Iterable::Relatable(f, t, w)),
 						},
 						// There is no data clause so create a record id
-						None => i.ingest(Iterable::Relatable(f, tb.generate(), w)),
+						None => {
+							i.ingest(Iterable::Relatable(f, tb.generate_with(ctx, opt).await?, w))
+						}
 					},
 					// The relation can not be any other type
 					_ => unreachable!(),
@@ -2,6 +2,7 @@ use crate::ctx::Context;
 use crate::dbs::Level;
 use crate::dbs::Options;
 use crate::err::Error;
+use crate::sql::array::Array;
 use crate::sql::base::{base, base_or_scope, Base};
 use crate::sql::comment::{mightbespace, shouldbespace};
 use crate::sql::error::IResult;
@@ -9,7 +10,8 @@ use crate::sql::ident;
 use crate::sql::ident::{ident, Ident};
 use crate::sql::idiom;
 use crate::sql::idiom::Idiom;
-use crate::sql::value::Value;
+use crate::sql::statements::DeleteStatement;
+use crate::sql::value::{value, Value, Values};
 use derive::Store;
 use nom::branch::alt;
 use nom::bytes::complete::tag;
@@ -31,10 +33,13 @@ pub enum RemoveStatement {
 	Token(RemoveTokenStatement),
 	Scope(RemoveScopeStatement),
 	Param(RemoveParamStatement),
+	Sequence(RemoveSequenceStatement),
+	Migration(RemoveMigrationStatement),
 	Table(RemoveTableStatement),
 	Event(RemoveEventStatement),
 	Field(RemoveFieldStatement),
 	Index(RemoveIndexStatement),
+	Partition(RemovePartitionStatement),
 }
 
 impl RemoveStatement {
@@ -48,10 +53,13 @@ impl RemoveStatement {
 			Self::Token(ref v) => v.compute(ctx, opt).await,
 			Self::Scope(ref v) => v.compute(ctx, opt).await,
 			Self::Param(ref v) => v.compute(ctx, opt).await,
+			Self::Sequence(ref v) => v.compute(ctx, opt).await,
+			Self::Migration(ref v) => v.compute(ctx, opt).await,
 			Self::Table(ref v) => v.compute(ctx, opt).await,
 			Self::Event(ref v) => v.compute(ctx, opt).await,
 			Self::Field(ref v) => v.compute(ctx, opt).await,
 			Self::Index(ref v) => v.compute(ctx, opt).await,
+			Self::Partition(ref v) => v.compute(ctx, opt).await,
 			Self::Analyzer(ref v) => v.compute(ctx, opt).await,
 		}
 	}
@@ -67,10 +75,13 @@ impl Display for RemoveStatement {
 			Self::Token(v) => Display::fmt(v, f),
 			Self::Scope(v) => Display::fmt(v, f),
 			Self::Param(v) => Display::fmt(v, f),
+			Self::Sequence(v) => Display::fmt(v, f),
+			Self::Migration(v) => Display::fmt(v, f),
 			Self::Table(v) => Display::fmt(v, f),
 			Self::Event(v) => Display::fmt(v, f),
 			Self::Field(v) => Display::fmt(v, f),
 			Self::Index(v) => Display::fmt(v, f),
+			Self::Partition(v) => Display::fmt(v, f),
 			Self::Analyzer(v) => Display::fmt(v, f),
 		}
 	}
@@ -85,10 +96,13 @@ pub fn remove(i: &str) -> IResult<&str, RemoveStatement> {
 		map(token, RemoveStatement::Token),
 		map(scope, RemoveStatement::Scope),
 		map(param, RemoveStatement::Param),
+		map(sequence, RemoveStatement::Sequence),
+		map(migration, RemoveStatement::Migration),
 		map(table, RemoveStatement::Table),
 		map(event, RemoveStatement::Event),
 		map(field, RemoveStatement::Field),
 		map(index, RemoveStatement::Index),
+		map(partition, RemoveStatement::Partition),
 		map(analyzer, RemoveStatement::Analyzer),
 	))(i)
 }
@@ -573,6 +587,118 @@ fn param(i: &str) -> IResult<&str, RemoveParamStatement> {
 // --------------------------------------------------
 // --------------------------------------------------
 
+#[derive(Clone, Debug, Default, Eq, PartialEq, Serialize, Deserialize, Store, Hash)]
+#[format(Named)]
+pub struct RemoveSequenceStatement {
+	pub name: Ident,
+}
+
+impl RemoveSequenceStatement {
+	/// Process this type returning a computed simple Value
+	pub(crate) async fn compute(&self, ctx: &Context<'_>, opt: &Options) -> Result<Value, Error> {
+		// Selected DB?
+		opt.needs(Level::Db)?;
+		// Allowed to run?
+		opt.check(Level::Db)?;
+		// Clone transaction
+		let txn = ctx.clone_transaction()?;
+		// Claim transaction
+		let mut run = txn.lock().await;
+		// Delete the definition
+		let key = crate::key::sq::new(opt.ns(), opt.db(), &self.name);
+		run.del(key).await?;
+		// Delete the sequence value
+		let key = crate::key::sv::new(opt.ns(), opt.db(), &self.name);
+		run.del(key).await?;
+		// Clear the cache
+		let key = crate::key::sq::new(opt.ns(), opt.db(), &self.name);
+		run.clr(key).await?;
+		let key = crate::key::sq::prefix(opt.ns(), opt.db());
+		run.clr(key).await?;
+		// Ok all good
+		Ok(Value::None)
+	}
+}
+
+impl Display for RemoveSequenceStatement {
+	fn fmt(&self, f: &mut Formatter) -> fmt::Result {
+		write!(f, "REMOVE SEQUENCE {}", self.name)
+	}
+}
+
+fn sequence(i: &str) -> IResult<&str, RemoveSequenceStatement> {
+	let (i, _) = tag_no_case("REMOVE")(i)?;
+	let (i, _) = shouldbespace(i)?;
+	let (i, _) = tag_no_case("SEQUENCE")(i)?;
+	let (i, _) = shouldbespace(i)?;
+	let (i, name) = ident(i)?;
+	Ok((
+		i,
+		RemoveSequenceStatement {
+			name,
+		},
+	))
+}
+
+// --------------------------------------------------
+// --------------------------------------------------
+// --------------------------------------------------
+
+#[derive(Clone, Debug, Default, Eq, PartialEq, Serialize, Deserialize, Store, Hash)]
+#[format(Named)]
+pub struct RemoveMigrationStatement {
+	pub name: Ident,
+}
+
+impl RemoveMigrationStatement {
+	/// Process this type returning a computed simple Value
+	pub(crate) async fn compute(&self, ctx: &Context<'_>, opt: &Options) -> Result<Value, Error> {
+		// Selected DB?
+		opt.needs(Level::Db)?;
+		// Allowed to run?
+		opt.check(Level::Db)?;
+		// Clone transaction
+		let txn = ctx.clone_transaction()?;
+		// Claim transaction
+		let mut run = txn.lock().await;
+		// Delete the definition
+		let key = crate::key::mg::new(opt.ns(), opt.db(), &self.name);
+		run.del(key).await?;
+		// Delete the applied state
+		let key = crate::key::ma::new(opt.ns(), opt.db(), &self.name);
+		run.del(key).await?;
+		// Clear the cache
+		let key = crate::key::mg::prefix(opt.ns(), opt.db());
+		run.clr(key).await?;
+		// Ok all good
+		Ok(Value::None)
+	}
+}
+
+impl Display for RemoveMigrationStatement {
+	fn fmt(&self, f: &mut Formatter) -> fmt::Result {
+		write!(f, "REMOVE MIGRATION {}", self.name)
+	}
+}
+
+fn migration(i: &str) -> IResult<&str, RemoveMigrationStatement> {
+	let (i, _) = tag_no_case("REMOVE")(i)?;
+	let (i, _) = shouldbespace(i)?;
+	let (i, _) = tag_no_case("MIGRATION")(i)?;
+	let (i, _) = shouldbespace(i)?;
+	let (i, name) = ident(i)?;
+	Ok((
+		i,
+		RemoveMigrationStatement {
+			name,
+		},
+	))
+}
+
+// --------------------------------------------------
+// --------------------------------------------------
+// --------------------------------------------------
+
 #[derive(Clone, Debug, Default, Eq, PartialEq, Serialize, Deserialize, Store, Hash)]
 #[format(Named)]
 pub struct RemoveTableStatement {
@@ -765,6 +891,9 @@ impl RemoveIndexStatement {
 		// Delete the definition
 		let key = crate::key::ix::new(opt.ns(), opt.db(), &self.what, &self.name);
 		run.del(key).await?;
+		// Stop any build of the index
+		let key = crate::key::ib::new(opt.ns(), opt.db(), &self.what, &self.name);
+		run.del(key).await?;
 		// Clear the cache
 		let key = crate::key::ix::prefix(opt.ns(), opt.db(), &self.what);
 		run.clr(key).await?;
@@ -772,6 +901,9 @@ impl RemoveIndexStatement {
 		let beg = crate::key::index::prefix(opt.ns(), opt.db(), &self.what, &self.name);
 		let end = crate::key::index::suffix(opt.ns(), opt.db(), &self.what, &self.name);
 		run.delr(beg..end, u32::MAX).await?;
+		let beg = crate::key::ie::prefix(opt.ns(), opt.db(), &self.what, &self.name);
+		let end = crate::key::ie::suffix(opt.ns(), opt.db(), &self.what, &self.name);
+		run.delr(beg..end, u32::MAX).await?;
 		// Ok all good
 		Ok(Value::None)
 	}
@@ -803,6 +935,87 @@ fn index(i: &str) -> IResult<&str, RemoveIndexStatement> {
 	))
 }
 
+// --------------------------------------------------
+// --------------------------------------------------
+// --------------------------------------------------
+
+#[derive(Clone, Debug, Default, Eq, PartialEq, Serialize, Deserialize, Store, Hash)]
+#[format(Named)]
+pub struct RemovePartitionStatement {
+	pub value: Value,
+	pub what: Ident,
+}
+
+impl RemovePartitionStatement {
+	/// Process this type returning a computed simple Value
+	pub(crate) async fn compute(&self, ctx: &Context<'_>, opt: &Options) -> Result<Value, Error> {
+		// Selected DB?
+		opt.needs(Level::Db)?;
+		// Allowed to run?
+		opt.check(Level::Db)?;
+		// Clone transaction
+		let txn = ctx.clone_transaction()?;
+		// Get the table definition
+		let tb = txn.lock().await.get_tb(opt.ns(), opt.db(), &self.what).await?;
+		// Check if the table is partitioned
+		if tb.partition.is_none() {
+			return Err(Error::TbNotPartitioned {
+				value: self.what.to_raw(),
+			});
+		}
+		// Compute the partition to remove
+		let pt = Array::from(vec![self.value.compute(ctx, opt).await?]);
+		// Delete the records in the partition
+		let mut beg = crate::key::pt::prefix_all_ids(opt.ns(), opt.db(), &self.what, &pt);
+		let end = crate::key::pt::suffix_all_ids(opt.ns(), opt.db(), &self.what, &pt);
+		loop {
+			let res = txn.lock().await.scan(beg.clone()..end.clone(), 1000).await?;
+			// Exit when settled
+			let last = match res.last() {
+				Some((k, _)) => k.clone(),
+				None => break,
+			};
+			// Delete this batch of records
+			let stm = DeleteStatement {
+				what: Values(res.iter().map(|(_, v)| Value::Thing(v.into())).collect()),
+				..DeleteStatement::default()
+			};
+			stm.compute(ctx, opt).await?;
+			// Continue after the last record
+			beg = last;
+			beg.push(0x00);
+		}
+		// Ok all good
+		Ok(Value::None)
+	}
+}
+
+impl Display for RemovePartitionStatement {
+	fn fmt(&self, f: &mut Formatter) -> fmt::Result {
+		write!(f, "REMOVE PARTITION {} ON {}", self.value, self.what)
+	}
+}
+
+fn partition(i: &str) -> IResult<&str, RemovePartitionStatement> {
+	let (i, _) = tag_no_case("REMOVE")(i)?;
+	let (i, _) = shouldbespace(i)?;
+	let (i, _) = tag_no_case("PARTITION")(i)?;
+	let (i, _) = shouldbespace(i)?;
+	let (i, value) = value(i)?;
+	let (i, _) = shouldbespace(i)?;
+	let (i, _) = tag_no_case("ON")(i)?;
+	let (i, _) = opt(tuple((shouldbespace, tag_no_case("TABLE"))))(i)?;
+	let (i, _) = shouldbespace(i)?;
+	let (i, what) = ident(i)?;
+	Ok((
+		i,
+		RemovePartitionStatement {
+			value,
+			what,
+		},
+	))
+}
+
 #[cfg(test)]
 mod tests {
 
@@ -815,4 +1028,13 @@ mod tests {
 		});
 		assert_eq!(22, stm.to_vec().len());
 	}
+
+	#[test]
+	fn check_remove_partition() {
+		let sql = "REMOVE PARTITION 1 ON TABLE events";
+		let res = remove(sql);
+		assert!(res.is_ok());
+		let out = res.unwrap().1;
+		assert_eq!("REMOVE PARTITION 1 ON events", format!("{}", out));
+	}
 }
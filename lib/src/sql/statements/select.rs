@@ -1,31 +1,51 @@
 use crate::ctx::Context;
+use crate::dbs::is_statements;
 use crate::dbs::Iterable;
 use crate::dbs::Iterator;
 use crate::dbs::Level;
 use crate::dbs::Options;
 use crate::dbs::Statement;
 use crate::err::Error;
-use crate::idx::planner::QueryPlanner;
+use crate::idx::planner::{Knn, QueryPlanner};
+use crate::sql::array::Array;
 use crate::sql::comment::shouldbespace;
+use crate::sql::common::commas;
 use crate::sql::cond::{cond, Cond};
+use crate::sql::error::Error::Parser;
 use crate::sql::error::IResult;
 use crate::sql::fetch::{fetch, Fetchs};
 use crate::sql::field::{fields, Field, Fields};
+use crate::sql::fill::{fill, Fill};
+use crate::sql::fmt::Fmt;
+use crate::sql::function::Function;
 use crate::sql::group::{group, Groups};
+use crate::sql::ident::{ident, Ident};
+use crate::sql::idiom::Idiom;
+use crate::sql::index::Distance;
+use crate::sql::join::{join, Join, LEFT};
 use crate::sql::limit::{limit, Limit};
-use crate::sql::order::{order, Orders};
+use crate::sql::order::{order, Order, Orders};
+use crate::sql::permission::Permission;
+use crate::sql::special::check_fill_fields;
 use crate::sql::special::check_group_by_fields;
 use crate::sql::special::check_order_by_fields;
 use crate::sql::special::check_split_on_fields;
 use crate::sql::split::{split, Splits};
-use crate::sql::start::{start, Start};
+use crate::sql::start::{after, start, After, Start};
+use crate::sql::table::{table, Table};
 use crate::sql::timeout::{timeout, Timeout};
 use crate::sql::value::{selects, Value, Values};
 use crate::sql::version::{version, Version};
+use async_recursion::async_recursion;
 use derive::Store;
+use nom::branch::alt;
+use nom::bytes::complete::tag;
 use nom::bytes::complete::tag_no_case;
-use nom::combinator::opt;
+use nom::character::complete::one_of;
+use nom::combinator::{map, not, opt, peek};
+use nom::multi::separated_list1;
 use nom::sequence::preceded;
+use nom::Err::Failure;
 use serde::{Deserialize, Serialize};
 use std::fmt;
 
@@ -33,17 +53,24 @@ use std::fmt;
 pub struct SelectStatement {
 	pub expr: Fields,
 	pub what: Values,
+	pub join: Option<Join>,
+	pub deleted: bool,
 	pub cond: Option<Cond>,
 	pub split: Option<Splits>,
 	pub group: Option<Groups>,
+	pub rollup: bool,
+	pub fill: Option<Fill>,
 	pub order: Option<Orders>,
 	pub limit: Option<Limit>,
 	pub start: Option<Start>,
+	pub after: Option<After>,
+	pub total: bool,
 	pub fetch: Option<Fetchs>,
 	pub version: Option<Version>,
 	pub timeout: Option<Timeout>,
 	pub parallel: bool,
 	pub explain: bool,
+	pub database: Option<Ident>,
 }
 
 impl SelectStatement {
@@ -61,6 +88,9 @@ impl SelectStatement {
 		if self.what.iter().any(|v| v.writeable()) {
 			return true;
 		}
+		if self.join.as_ref().map_or(false, |v| v.cond.writeable()) {
+			return true;
+		}
 		self.cond.as_ref().map_or(false, |v| v.writeable())
 	}
 	/// Check if this statement is for a single record
@@ -71,19 +101,307 @@ impl SelectStatement {
 			_ => false,
 		}
 	}
+	/// Get the ORDER clause, if it can be satisfied by iterating over the
+	/// records in the order of an index, so that the iteration can stop
+	/// as soon as the records up to the LIMIT clause have been found
+	fn index_order(&self) -> Option<&Order> {
+		// The results must not be reshaped before they are ordered
+		if self.limit.is_none() || self.group.is_some() || self.split.is_some() || self.parallel {
+			return None;
+		}
+		let order = match self.order.as_deref().map(Vec::as_slice) {
+			Some([v]) if v.direction && !v.random => v,
+			_ => return None,
+		};
+		// Strings must be compared in the order of the keys of an index
+		if order.collation.is_none() && (order.collate || order.numeric) {
+			return None;
+		}
+		// The ordered field must be output unchanged
+		if self.expr.single().is_some() {
+			return None;
+		}
+		let mut output = self.expr.is_all();
+		for v in self.expr.other() {
+			if let Field::Single {
+				expr,
+				alias,
+			} = v
+			{
+				let idiom = alias.clone().unwrap_or_else(|| expr.to_idiom());
+				if idiom.starts_with(&order.order) || order.order.starts_with(&idiom) {
+					match (expr, alias) {
+						(Value::Idiom(v), None) if v.eq(&order.order) => output = true,
+						_ => return None,
+					}
+				}
+			}
+		}
+		match output {
+			true => Some(order),
+			false => None,
+		}
+	}
+	/// Get the nearest neighbour search of this statement, if the records
+	/// are ordered by their similarity or distance to a vector, and limited
+	fn index_knn(&self) -> Option<Knn> {
+		if self.cond.is_some() || self.group.is_some() || self.split.is_some() || self.parallel {
+			return None;
+		}
+		let limit = self.limit.as_ref()?;
+		let order = match self.order.as_deref().map(Vec::as_slice) {
+			Some([v]) if !v.random => v,
+			_ => return None,
+		};
+		// The ordered field must be a similarity or distance function
+		for v in self.expr.other() {
+			if let Field::Single {
+				expr,
+				alias,
+			} = v
+			{
+				let idiom = alias.clone().unwrap_or_else(|| expr.to_idiom());
+				if !idiom.eq(&order.order) {
+					continue;
+				}
+				let (name, args) = match expr {
+					Value::Function(f) => match f.as_ref() {
+						Function::Normal(name, args) => (name.as_str(), args.as_slice()),
+						_ => return None,
+					},
+					_ => return None,
+				};
+				let dist = match (name, order.direction) {
+					("vector::similarity::cosine", false) => Distance::Cosine,
+					("vector::similarity::euclidean", false) => Distance::Euclidean,
+					("vector::distance::euclidean", true) => Distance::Euclidean,
+					("vector::distance::manhattan", true) => Distance::Manhattan,
+					_ => return None,
+				};
+				let (field, vector) = match args {
+					[Value::Idiom(i), v] | [v, Value::Idiom(i)]
+						if v.is_static() || matches!(v, Value::Param(_)) =>
+					{
+						(i, v)
+					}
+					_ => return None,
+				};
+				return Some(Knn {
+					field,
+					vector,
+					dist,
+					limit,
+					start: self.start.as_ref(),
+				});
+			}
+		}
+		None
+	}
+	/// Count the records in a table using the record counter of the table,
+	/// if this statement only counts every record within a single table
+	async fn count(&self, ctx: &Context<'_>, opt: &Options) -> Result<Option<Value>, Error> {
+		// Every record must be counted within a single group
+		if !matches!(&self.group, Some(v) if v.is_empty())
+			|| self.join.is_some()
+			|| self.cond.is_some()
+			|| self.split.is_some()
+			|| self.limit.is_some()
+			|| self.start.is_some()
+			|| self.after.is_some()
+			|| self.fetch.is_some()
+			|| self.version.is_some()
+			|| self.explain
+			|| self.expr.single().is_some()
+		{
+			return Ok(None);
+		}
+		let tb = match self.what.0.as_slice() {
+			[Value::Table(v)] => v,
+			_ => return Ok(None),
+		};
+		// Every field must be a count of the records
+		let mut fields: Vec<Idiom> = Vec::with_capacity(self.expr.len());
+		for v in self.expr.iter() {
+			let (f, alias) = match v {
+				Field::Single {
+					expr: Value::Function(f),
+					alias,
+				} => (f, alias),
+				_ => return Ok(None),
+			};
+			match f.as_ref() {
+				Function::Normal(name, args) if name == "count" && args.is_empty() => {
+					fields.push(alias.clone().unwrap_or_else(|| f.to_idiom()))
+				}
+				_ => return Ok(None),
+			}
+		}
+		// Fetch the record counter of the table
+		let num = match Self::counter(ctx, opt, tb, self.deleted).await? {
+			Some(v) => v,
+			None => return Ok(None),
+		};
+		// An empty table has no group
+		if num == 0 {
+			return Ok(Some(Value::from(Array::new())));
+		}
+		// Output the count in each field
+		let mut obj = Value::base();
+		for v in fields.iter() {
+			obj.set(ctx, opt, v, Value::from(num)).await?;
+		}
+		Ok(Some(Value::from(Array::from(obj))))
+	}
+	/// Count the records which match this statement using the record counter
+	/// of the table, for the total WITH TOTAL, if every record is matched
+	async fn total(&self, ctx: &Context<'_>, opt: &Options) -> Result<Option<usize>, Error> {
+		if self.join.is_some()
+			|| self.cond.is_some()
+			|| self.split.is_some()
+			|| self.group.is_some()
+			|| self.version.is_some()
+		{
+			return Ok(None);
+		}
+		match self.what.0.as_slice() {
+			[Value::Table(tb)] => {
+				Ok(Self::counter(ctx, opt, tb, self.deleted).await?.map(|v| v.max(0) as usize))
+			}
+			_ => Ok(None),
+		}
+	}
+	/// Fetch the record counter of a table, if the counter can be used
+	/// in place of counting the records which can be selected. The counter
+	/// of a table which keeps deleted records does not count those records.
+	async fn counter(
+		ctx: &Context<'_>,
+		opt: &Options,
+		tb: &str,
+		deleted: bool,
+	) -> Result<Option<i64>, Error> {
+		// Check if exact counts are required
+		if ctx.config().exact_count {
+			return Ok(None);
+		}
+		// Clone transaction
+		let txn = ctx.clone_transaction()?;
+		// Claim transaction
+		let mut run = txn.lock().await;
+		// Deleted records are not counted by the counter
+		if deleted {
+			match run.get_tb(opt.ns(), opt.db(), tb).await {
+				Ok(v) if v.soft.is_none() => (),
+				_ => return Ok(None),
+			}
+		}
+		// Permissions could exclude some of the records
+		if opt.perms && opt.auth.perms() {
+			match run.get_tb(opt.ns(), opt.db(), tb).await {
+				Ok(v) if v.permissions.select == Permission::Full => (),
+				_ => return Ok(None),
+			}
+		}
+		// Fetch the record counter of the table
+		run.get_cn(opt.ns(), opt.db(), tb).await
+	}
+	/// Match each record of the selected table with the records of the
+	/// joined table for which the ON condition is truthy, returning each
+	/// pair of records as an object which is keyed by their table names
+	#[cfg_attr(not(target_arch = "wasm32"), async_recursion)]
+	#[cfg_attr(target_arch = "wasm32", async_recursion(?Send))]
+	async fn join(
+		&self,
+		ctx: &Context<'_>,
+		opt: &Options,
+		join: &Join,
+	) -> Result<Vec<Value>, Error> {
+		// A join is from a single table
+		let tb = match self.what.0.as_slice() {
+			[Value::Table(v)] => v,
+			_ => {
+				return Err(Error::JoinStatement {
+					value: self.what.to_string(),
+				})
+			}
+		};
+		// Select the records of the selected table
+		let stm = SelectStatement {
+			expr: Fields::all(),
+			what: Values(vec![Value::Table(tb.clone())]),
+			deleted: self.deleted,
+			version: self.version.clone(),
+			..SelectStatement::default()
+		};
+		let Value::Array(left) = stm.compute(ctx, opt).await? else {
+			return Ok(vec![]);
+		};
+		// Select the matching records of the joined table for each record
+		let stm = SelectStatement {
+			expr: Fields::all(),
+			what: Values(vec![Value::Table(join.what.clone())]),
+			deleted: self.deleted,
+			cond: Some(Cond(join.condition(tb))),
+			version: self.version.clone(),
+			..SelectStatement::default()
+		};
+		let mut out = vec![];
+		for l in left {
+			let mut ctx = Context::new(ctx);
+			ctx.add_value(LEFT, &l);
+			if let Value::Array(right) = stm.compute(&ctx, opt).await? {
+				for r in right {
+					out.push(Value::from(map! {
+						tb.0.clone() => l.clone(),
+						join.what.0.clone() => r,
+					}));
+				}
+			}
+		}
+		Ok(out)
+	}
 	/// Process this type returning a computed simple Value
 	pub(crate) async fn compute(&self, ctx: &Context<'_>, opt: &Options) -> Result<Value, Error> {
 		// Selected DB?
 		opt.needs(Level::Db)?;
 		// Allowed to run?
 		opt.check(Level::No)?;
+		// Check if another database is selected
+		let opt = &match &self.database {
+			Some(db) => {
+				// Allowed to query across databases?
+				opt.check(Level::Ns)?;
+				// Clone transaction
+				let txn = ctx.clone_transaction()?;
+				// Check that the database exists
+				txn.lock().await.get_db(opt.ns(), db).await?;
+				// Process within the database
+				opt.database(db)
+			}
+			None => opt.clone(),
+		};
 		// Create a new iterator
 		let mut i = Iterator::new();
 		// Ensure futures are stored
 		let opt = &opt.futures(false);
 
+		// Check if the record counter can be used
+		if let Some(v) = self.count(ctx, opt).await? {
+			return Ok(v);
+		}
+		// Check if the record counter can be used for the total
+		if self.total {
+			i.set_total(self.total(ctx, opt).await?);
+		}
+		// Join the records of the selected table with the joined table
+		if let Some(join) = &self.join {
+			for v in self.join(ctx, opt, join).await? {
+				i.ingest(Iterable::Value(v));
+			}
+			let stm = Statement::from(self);
+			return i.output(ctx, opt, &stm).await;
+		}
 		// Get a query planner
-		let mut planner = QueryPlanner::new(opt, &self.cond);
+		let mut planner = QueryPlanner::new(opt, &self.cond, self.index_order(), self.index_knn());
 		// Loop over the select targets
 		for w in self.what.0.iter() {
 			let v = w.compute(ctx, opt).await?;
@@ -91,6 +409,16 @@ impl SelectStatement {
 				Value::Table(t) => {
 					i.ingest(planner.get_iterable(ctx, opt, t).await?);
 				}
+				// Select the statement statistics of the datastore
+				Value::Thing(v) if is_statements(&v) => {
+					opt.check(Level::Db)?;
+					// Only root users can see the statements of every namespace
+					let ns = (!opt.auth.check(Level::Kv)).then(|| opt.ns());
+					let db = (!opt.auth.check(Level::Ns)).then(|| opt.db());
+					for v in ctx.statistics().map(|s| s.output(ns, db)).unwrap_or_default() {
+						i.ingest(Iterable::Value(v));
+					}
+				}
 				Value::Thing(v) => i.ingest(Iterable::Thing(v)),
 				Value::Range(v) => i.ingest(Iterable::Range(*v)),
 				Value::Edges(v) => i.ingest(Iterable::Edges(*v)),
@@ -134,7 +462,23 @@ impl SelectStatement {
 
 impl fmt::Display for SelectStatement {
 	fn fmt(&self, f: &mut fmt::Formatter) -> fmt::Result {
-		write!(f, "SELECT {} FROM {}", self.expr, self.what)?;
+		match self.database {
+			Some(ref db) => write!(
+				f,
+				"SELECT {} FROM {}",
+				self.expr,
+				Fmt::comma_separated(
+					self.what.iter().map(|v| Fmt::new(v, |v, f| write!(f, "{db}::{v}")))
+				)
+			)?,
+			None => write!(f, "SELECT {} FROM {}", self.expr, self.what)?,
+		}
+		if let Some(ref v) = self.join {
+			write!(f, " {v}")?
+		}
+		if self.deleted {
+			f.write_str(" WITH DELETED")?
+		}
 		if let Some(ref v) = self.cond {
 			write!(f, " {v}")?
 		}
@@ -144,6 +488,12 @@ impl fmt::Display for SelectStatement {
 		if let Some(ref v) = self.group {
 			write!(f, " {v}")?
 		}
+		if self.rollup {
+			f.write_str(" ROLLUP")?
+		}
+		if let Some(ref v) = self.fill {
+			write!(f, " {v}")?
+		}
 		if let Some(ref v) = self.order {
 			write!(f, " {v}")?
 		}
@@ -153,6 +503,12 @@ impl fmt::Display for SelectStatement {
 		if let Some(ref v) = self.start {
 			write!(f, " {v}")?
 		}
+		if let Some(ref v) = self.after {
+			write!(f, " {v}")?
+		}
+		if self.total {
+			f.write_str(" WITH TOTAL")?
+		}
 		if let Some(ref v) = self.fetch {
 			write!(f, " {v}")?
 		}
@@ -176,16 +532,36 @@ pub fn select(i: &str) -> IResult<&str, SelectStatement> {
 	let (i, _) = shouldbespace(i)?;
 	let (i, _) = tag_no_case("FROM")(i)?;
 	let (i, _) = shouldbespace(i)?;
-	let (i, what) = selects(i)?;
+	let (i, (database, what)) = alt((qualified, map(selects, |v| (None, v))))(i)?;
+	let (i, join) = opt(preceded(shouldbespace, join))(i)?;
+	// A join is from a single table
+	if join.is_some() && !matches!(what.0.as_slice(), [Value::Table(_)]) {
+		return Err(Failure(Parser(i)));
+	}
+	let (i, deleted) = opt(preceded(shouldbespace, with_deleted))(i)?;
 	let (i, cond) = opt(preceded(shouldbespace, cond))(i)?;
 	let (i, split) = opt(preceded(shouldbespace, split))(i)?;
 	check_split_on_fields(i, &expr, &split)?;
 	let (i, group) = opt(preceded(shouldbespace, group))(i)?;
 	check_group_by_fields(i, &expr, &group)?;
+	let (i, rollup) = match group {
+		Some(ref v) if !v.is_empty() => opt(preceded(shouldbespace, tag_no_case("ROLLUP")))(i)?,
+		_ => (i, None),
+	};
+	let (i, fill) = match (&group, &rollup) {
+		(Some(v), None) if !v.is_empty() => opt(preceded(shouldbespace, fill))(i)?,
+		_ => (i, None),
+	};
+	check_fill_fields(i, &expr, &group, &fill)?;
 	let (i, order) = opt(preceded(shouldbespace, order))(i)?;
 	check_order_by_fields(i, &expr, &order)?;
 	let (i, limit) = opt(preceded(shouldbespace, limit))(i)?;
-	let (i, start) = opt(preceded(shouldbespace, start))(i)?;
+	let (i, after) = opt(preceded(shouldbespace, after))(i)?;
+	let (i, start) = match after {
+		Some(_) => (i, None),
+		None => opt(preceded(shouldbespace, start))(i)?,
+	};
+	let (i, total) = opt(preceded(shouldbespace, with_total))(i)?;
 	let (i, fetch) = opt(preceded(shouldbespace, fetch))(i)?;
 	let (i, version) = opt(preceded(shouldbespace, version))(i)?;
 	let (i, timeout) = opt(preceded(shouldbespace, timeout))(i)?;
@@ -196,21 +572,61 @@ pub fn select(i: &str) -> IResult<&str, SelectStatement> {
 		SelectStatement {
 			expr,
 			what,
+			join,
+			deleted: deleted.is_some(),
 			cond,
 			split,
 			group,
+			rollup: rollup.is_some(),
+			fill,
 			order,
 			limit,
 			start,
+			after,
+			total: total.is_some(),
 			fetch,
 			version,
 			timeout,
 			parallel: parallel.is_some(),
 			explain: explain.is_some(),
+			database,
 		},
 	))
 }
 
+fn with_deleted(i: &str) -> IResult<&str, ()> {
+	let (i, _) = tag_no_case("WITH")(i)?;
+	let (i, _) = shouldbespace(i)?;
+	let (i, _) = tag_no_case("DELETED")(i)?;
+	Ok((i, ()))
+}
+
+fn with_total(i: &str) -> IResult<&str, ()> {
+	let (i, _) = tag_no_case("WITH")(i)?;
+	let (i, _) = shouldbespace(i)?;
+	let (i, _) = tag_no_case("TOTAL")(i)?;
+	Ok((i, ()))
+}
+
+fn qualified(i: &str) -> IResult<&str, (Option<Ident>, Values)> {
+	let (i, v) = separated_list1(commas, qualified_table)(i)?;
+	// All tables must be within the same database
+	let db = v[0].0.clone();
+	if v.iter().any(|(v, _)| v != &db) {
+		return Err(Failure(Parser(i)));
+	}
+	let v = v.into_iter().map(|(_, v)| Value::Table(v)).collect();
+	Ok((i, (Some(db), Values(v))))
+}
+
+fn qualified_table(i: &str) -> IResult<&str, (Ident, Table)> {
+	let (i, db) = ident(i)?;
+	let (i, _) = tag("::")(i)?;
+	let (i, tb) = table(i)?;
+	let (i, _) = peek(not(one_of("(:")))(i)?;
+	Ok((i, (db, tb)))
+}
+
 #[cfg(test)]
 mod tests {
 
@@ -234,6 +650,53 @@ mod tests {
 		assert_eq!(sql, format!("{}", out));
 	}
 
+	#[test]
+	fn select_statement_with_deleted() {
+		let sql = "SELECT * FROM test WITH DELETED WHERE age > 18";
+		let res = select(sql);
+		assert!(res.is_ok());
+		let out = res.unwrap().1;
+		assert_eq!(sql, format!("{}", out));
+		assert!(out.deleted);
+	}
+
+	#[test]
+	fn select_statement_join() {
+		let sql = "SELECT person.name, company.name AS company FROM person JOIN company ON company.code = person.company WHERE person.age > 18";
+		let res = select(sql);
+		assert!(res.is_ok());
+		let out = res.unwrap().1;
+		assert_eq!(sql, format!("{}", out));
+		assert_eq!(out.join.unwrap().what.0, "company");
+	}
+
+	#[test]
+	fn select_statement_join_multiple_tables() {
+		let sql = "SELECT * FROM person, user JOIN company ON company.code = person.company";
+		let res = select(sql);
+		assert!(res.is_err());
+	}
+
+	#[test]
+	fn select_statement_multiple_tables() {
+		let sql = "SELECT * FROM person, company WHERE company.code = person.company";
+		let res = select(sql);
+		assert!(res.is_ok());
+		let out = res.unwrap().1;
+		assert_eq!(sql, format!("{}", out));
+		assert!(out.join.is_none());
+	}
+
+	#[test]
+	fn select_statement_with_total() {
+		let sql = "SELECT * FROM test WHERE age > 18 LIMIT 10 START 20 WITH TOTAL";
+		let res = select(sql);
+		assert!(res.is_ok());
+		let out = res.unwrap().1;
+		assert_eq!(sql, format!("{}", out));
+		assert!(out.total);
+	}
+
 	#[test]
 	fn select_statement_thing() {
 		let sql = "SELECT * FROM test:thingy ORDER BY name";
@@ -252,6 +715,59 @@ mod tests {
 		assert_eq!(sql, format!("{}", out))
 	}
 
+	#[test]
+	fn select_statement_database_table() {
+		let sql = "SELECT * FROM other::person, other::post WHERE age > 18";
+		let res = select(sql);
+		assert!(res.is_ok());
+		let out = res.unwrap().1;
+		assert_eq!(out.database, Some(Ident::from("other")));
+		assert_eq!(sql, format!("{}", out))
+	}
+
+	#[test]
+	fn select_statement_database_table_mixed() {
+		let sql = "SELECT * FROM other::person, test::post";
+		let res = select(sql);
+		assert!(res.is_err());
+	}
+
+	#[test]
+	fn select_statement_database_function() {
+		let sql = "SELECT * FROM fn::test()";
+		let res = select(sql);
+		assert!(res.is_ok());
+		let out = res.unwrap().1;
+		assert_eq!(out.database, None);
+		assert_eq!(sql, format!("{}", out))
+	}
+
+	#[test]
+	fn select_statement_group_rollup() {
+		let sql = "SELECT count(), country FROM test GROUP BY country ROLLUP";
+		let res = select(sql);
+		assert!(res.is_ok());
+		let out = res.unwrap().1;
+		assert!(out.rollup);
+		assert_eq!(sql, format!("{}", out));
+		let sql = "SELECT count() FROM test GROUP ALL ROLLUP";
+		let res = select(sql);
+		assert_eq!(res.unwrap().0, " ROLLUP");
+	}
+
+	#[test]
+	fn select_statement_group_fill() {
+		let sql = "SELECT time::bucket(time, 1h) AS hour, math::mean(value) AS value FROM test GROUP BY hour FILL LINEAR";
+		let res = select(sql);
+		assert!(res.is_ok());
+		let out = res.unwrap().1;
+		assert_eq!(out.fill, Some(Fill::Linear));
+		assert_eq!(sql, format!("{}", out));
+		let sql = "SELECT time::group(time, 'hour') AS hour FROM test GROUP BY hour FILL LINEAR";
+		let res = select(sql);
+		assert!(res.is_err());
+	}
+
 	#[test]
 	fn select_statement_table_thing() {
 		let sql = "SELECT *, ((1 + 3) / 4), 1.3999f AS tester FROM test, test:thingy";
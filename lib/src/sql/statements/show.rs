@@ -0,0 +1,42 @@
+use crate::sql::comment::shouldbespace;
+use crate::sql::error::IResult;
+use derive::Store;
+use nom::bytes::complete::tag_no_case;
+use nom::combinator::map;
+use serde::{Deserialize, Serialize};
+use std::fmt;
+
+#[derive(Clone, Debug, Default, Eq, PartialEq, Serialize, Deserialize, Store, Hash)]
+pub enum ShowStatement {
+	#[default]
+	Sessions,
+}
+
+impl fmt::Display for ShowStatement {
+	fn fmt(&self, f: &mut fmt::Formatter) -> fmt::Result {
+		match self {
+			Self::Sessions => f.write_str("SHOW SESSIONS"),
+		}
+	}
+}
+
+pub fn show(i: &str) -> IResult<&str, ShowStatement> {
+	let (i, _) = tag_no_case("SHOW")(i)?;
+	let (i, _) = shouldbespace(i)?;
+	map(tag_no_case("SESSIONS"), |_| ShowStatement::Sessions)(i)
+}
+
+#[cfg(test)]
+mod tests {
+
+	use super::*;
+
+	#[test]
+	fn show_sessions() {
+		let sql = "SHOW SESSIONS";
+		let res = show(sql);
+		assert!(res.is_ok());
+		let out = res.unwrap().1;
+		assert_eq!("SHOW SESSIONS", format!("{}", out))
+	}
+}
@@ -0,0 +1,59 @@
+use crate::ctx::Context;
+use crate::dbs::Options;
+use crate::err::Error;
+use crate::sql::comment::shouldbespace;
+use crate::sql::error::IResult;
+use crate::sql::value::{value, Value};
+use derive::Store;
+use nom::bytes::complete::tag_no_case;
+use serde::{Deserialize, Serialize};
+use std::fmt;
+
+#[derive(Clone, Debug, Default, Eq, PartialEq, Serialize, Deserialize, Store, Hash)]
+pub struct ThrowStatement {
+	pub error: Value,
+}
+
+impl ThrowStatement {
+	/// Check if we require a writeable transaction
+	pub(crate) fn writeable(&self) -> bool {
+		self.error.writeable()
+	}
+	/// Process this type returning a computed simple Value
+	pub(crate) async fn compute(&self, ctx: &Context<'_>, opt: &Options) -> Result<Value, Error> {
+		Err(Error::Thrown(self.error.compute(ctx, opt).await?.as_raw_string()))
+	}
+}
+
+impl fmt::Display for ThrowStatement {
+	fn fmt(&self, f: &mut fmt::Formatter) -> fmt::Result {
+		write!(f, "THROW {}", self.error)
+	}
+}
+
+pub fn throw(i: &str) -> IResult<&str, ThrowStatement> {
+	let (i, _) = tag_no_case("THROW")(i)?;
+	let (i, _) = shouldbespace(i)?;
+	let (i, error) = value(i)?;
+	Ok((
+		i,
+		ThrowStatement {
+			error,
+		},
+	))
+}
+
+#[cfg(test)]
+mod tests {
+
+	use super::*;
+
+	#[test]
+	fn throw_statement() {
+		let sql = "THROW 'Record does not exist'";
+		let res = throw(sql);
+		assert!(res.is_ok());
+		let out = res.unwrap().1;
+		assert_eq!("THROW 'Record does not exist'", format!("{}", out))
+	}
+}
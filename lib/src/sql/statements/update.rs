@@ -9,7 +9,11 @@ use crate::sql::comment::shouldbespace;
 use crate::sql::cond::{cond, Cond};
 use crate::sql::data::{data, Data};
 use crate::sql::error::IResult;
+use crate::sql::field::Fields;
+use crate::sql::limit::{limit, Limit};
+use crate::sql::order::{order, Orders};
 use crate::sql::output::{output, Output};
+use crate::sql::statements::SelectStatement;
 use crate::sql::timeout::{timeout, Timeout};
 use crate::sql::value::{whats, Value, Values};
 use derive::Store;
@@ -17,6 +21,7 @@ use nom::bytes::complete::tag_no_case;
 use nom::combinator::opt;
 use nom::sequence::preceded;
 use serde::{Deserialize, Serialize};
+use std::borrow::Cow;
 use std::fmt;
 
 #[derive(Clone, Debug, Default, Eq, PartialEq, Serialize, Deserialize, Store, Hash)]
@@ -24,6 +29,9 @@ pub struct UpdateStatement {
 	pub what: Values,
 	pub data: Option<Data>,
 	pub cond: Option<Cond>,
+	pub order: Option<Orders>,
+	pub limit: Option<Limit>,
+	pub strict: bool,
 	pub output: Option<Output>,
 	pub timeout: Option<Timeout>,
 	pub parallel: bool,
@@ -42,6 +50,29 @@ impl UpdateStatement {
 			_ => false,
 		}
 	}
+	/// Select the records to modify, in the order of the ORDER clause,
+	/// and limited to the number of records of any LIMIT clause. With a
+	/// STRICT limit, every matching record is selected, so that the
+	/// statement fails if more records match than the limit allows.
+	async fn ordered(&self, ctx: &Context<'_>, opt: &Options) -> Result<Values, Error> {
+		let stm = SelectStatement {
+			expr: Fields::all(),
+			what: self.what.clone(),
+			cond: self.cond.clone(),
+			order: self.order.clone(),
+			limit: match self.strict {
+				true => None,
+				false => self.limit.clone(),
+			},
+			..SelectStatement::default()
+		};
+		match stm.compute(ctx, opt).await? {
+			Value::Array(v) => {
+				Ok(Values(v.into_iter().map(|v| v.rid()).filter(Value::is_thing).collect()))
+			}
+			_ => Ok(Values::default()),
+		}
+	}
 	/// Process this type returning a computed simple Value
 	pub(crate) async fn compute(&self, ctx: &Context<'_>, opt: &Options) -> Result<Value, Error> {
 		// Selected DB?
@@ -52,8 +83,13 @@ impl UpdateStatement {
 		let mut i = Iterator::new();
 		// Ensure futures are stored
 		let opt = &opt.futures(false);
+		// Select the records to modify first, if they are ordered
+		let what = match self.order {
+			Some(_) => Cow::Owned(self.ordered(ctx, opt).await?),
+			None => Cow::Borrowed(&self.what),
+		};
 		// Loop over the update targets
-		for w in self.what.0.iter() {
+		for w in what.0.iter() {
 			let v = w.compute(ctx, opt).await?;
 			match v {
 				Value::Table(v) => i.ingest(Iterable::Table(v)),
@@ -123,6 +159,15 @@ impl fmt::Display for UpdateStatement {
 		if let Some(ref v) = self.cond {
 			write!(f, " {v}")?
 		}
+		if let Some(ref v) = self.order {
+			write!(f, " {v}")?
+		}
+		if let Some(ref v) = self.limit {
+			write!(f, " {v}")?
+		}
+		if self.strict {
+			f.write_str(" STRICT")?
+		}
 		if let Some(ref v) = self.output {
 			write!(f, " {v}")?
 		}
@@ -142,6 +187,12 @@ pub fn update(i: &str) -> IResult<&str, UpdateStatement> {
 	let (i, what) = whats(i)?;
 	let (i, data) = opt(preceded(shouldbespace, data))(i)?;
 	let (i, cond) = opt(preceded(shouldbespace, cond))(i)?;
+	let (i, order) = opt(preceded(shouldbespace, order))(i)?;
+	let (i, limit) = opt(preceded(shouldbespace, limit))(i)?;
+	let (i, strict) = match limit {
+		Some(_) => opt(preceded(shouldbespace, tag_no_case("STRICT")))(i)?,
+		None => (i, None),
+	};
 	let (i, output) = opt(preceded(shouldbespace, output))(i)?;
 	let (i, timeout) = opt(preceded(shouldbespace, timeout))(i)?;
 	let (i, parallel) = opt(preceded(shouldbespace, tag_no_case("PARALLEL")))(i)?;
@@ -151,6 +202,9 @@ pub fn update(i: &str) -> IResult<&str, UpdateStatement> {
 			what,
 			data,
 			cond,
+			order,
+			limit,
+			strict: strict.is_some(),
 			output,
 			timeout,
 			parallel: parallel.is_some(),
@@ -171,4 +225,35 @@ mod tests {
 		let out = res.unwrap().1;
 		assert_eq!("UPDATE test", format!("{}", out))
 	}
+
+	#[test]
+	fn update_statement_limit() {
+		let sql = "UPDATE test WHERE age > 10 LIMIT 100 RETURN NONE";
+		let res = update(sql);
+		assert!(res.is_ok());
+		let out = res.unwrap().1;
+		assert_eq!("UPDATE test WHERE age > 10 LIMIT 100 RETURN NONE", format!("{}", out))
+	}
+
+	#[test]
+	fn update_statement_order() {
+		let sql = "UPDATE test WHERE age > 10 ORDER BY age DESC LIMIT 100 RETURN NONE";
+		let res = update(sql);
+		assert!(res.is_ok());
+		let out = res.unwrap().1;
+		assert_eq!(
+			"UPDATE test WHERE age > 10 ORDER BY age DESC LIMIT 100 RETURN NONE",
+			format!("{}", out)
+		)
+	}
+
+	#[test]
+	fn update_statement_strict() {
+		let sql = "UPDATE test WHERE age > 10 LIMIT 100 STRICT RETURN NONE";
+		let res = update(sql);
+		assert!(res.is_ok());
+		let out = res.unwrap().1;
+		assert!(out.strict);
+		assert_eq!("UPDATE test WHERE age > 10 LIMIT 100 STRICT RETURN NONE", format!("{}", out))
+	}
 }
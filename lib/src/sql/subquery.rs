@@ -62,6 +62,12 @@ impl Subquery {
 	pub(crate) async fn compute(&self, ctx: &Context<'_>, opt: &Options) -> Result<Value, Error> {
 		// Prevent deep recursion
 		let opt = &opt.dive(2)?;
+		// Check the sandbox limits
+		if let Some(sandbox) = ctx.sandbox() {
+			if !matches!(self, Self::Value(_)) {
+				sandbox.subquery()?;
+			}
+		}
 		// Process the subquery
 		match self {
 			Self::Value(ref v) => v.compute(ctx, opt).await,
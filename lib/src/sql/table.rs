@@ -1,11 +1,16 @@
+use crate::ctx::Context;
+use crate::dbs::Options;
+use crate::err::Error;
 use crate::sql::common::commas;
 use crate::sql::error::IResult;
 use crate::sql::escape::escape_ident;
 use crate::sql::fmt::Fmt;
 use crate::sql::id::Id;
 use crate::sql::ident::{ident_raw, Ident};
+use crate::sql::statements::define::IdGenerator;
 use crate::sql::strand::no_nul_bytes;
 use crate::sql::thing::Thing;
+use crate::sql::uuid::Uuid;
 use nom::multi::separated_list1;
 use serde::{Deserialize, Serialize};
 use std::fmt::{self, Display, Formatter};
@@ -77,6 +82,38 @@ impl Table {
 			id: Id::rand(),
 		}
 	}
+	/// Generate a record id with the id generator of the table definition
+	pub(crate) async fn generate_with(
+		&self,
+		ctx: &Context<'_>,
+		opt: &Options,
+	) -> Result<Thing, Error> {
+		// Clone transaction
+		let txn = ctx.clone_transaction()?;
+		// Claim transaction
+		let mut run = txn.lock().await;
+		// Get the id generator of the table, if it is defined
+		let generator = match run.get_and_cache_tb(opt.ns(), opt.db(), &self.0).await {
+			Ok(tb) => tb.id.clone(),
+			Err(Error::TbNotFound {
+				..
+			}) => None,
+			Err(e) => return Err(e),
+		};
+		// Generate the record id
+		let id = match generator {
+			Some(IdGenerator::Ulid) => Id::ulid(),
+			Some(IdGenerator::Uuid) => Id::String(Uuid::new_v7().to_raw()),
+			Some(IdGenerator::Sequence(sq)) => {
+				Id::Number(run.next_sq(opt.ns(), opt.db(), &sq).await?)
+			}
+			Some(IdGenerator::Rand) | None => Id::rand(),
+		};
+		Ok(Thing {
+			tb: self.0.to_owned(),
+			id,
+		})
+	}
 }
 
 impl Display for Table {
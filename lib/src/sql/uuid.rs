@@ -91,6 +91,18 @@ impl Uuid {
 	pub fn new_v7() -> Self {
 		Self(uuid::Uuid::now_v7())
 	}
+	/// Generate a new V7 UUID
+	#[cfg(not(uuid_unstable))]
+	pub fn new_v7() -> Self {
+		let mut bytes: [u8; 16] = rand::random();
+		// The first 48 bits are the milliseconds since the Unix epoch
+		let millis = chrono::Utc::now().timestamp_millis() as u64;
+		bytes[..6].copy_from_slice(&millis.to_be_bytes()[2..]);
+		// Set the version and the variant
+		bytes[6] = 0x70 | (bytes[6] & 0x0f);
+		bytes[8] = 0x80 | (bytes[8] & 0x3f);
+		Self(uuid::Uuid::from_bytes(bytes))
+	}
 	/// Convert the Uuid to a raw String
 	pub fn to_raw(&self) -> String {
 		self.0.to_string()
@@ -164,4 +176,11 @@ mod tests {
 		assert_eq!("'b19bc00b-aa98-486c-ae37-c8e1c54295b1'", format!("{}", out));
 		assert_eq!(out, Uuid::try_from("b19bc00b-aa98-486c-ae37-c8e1c54295b1").unwrap());
 	}
+
+	#[test]
+	fn uuid_v7_generated() {
+		let out = Uuid::new_v7();
+		assert_eq!(out.get_version_num(), 7);
+		assert!(uuid_raw(&out.to_raw()).is_ok());
+	}
 }
@@ -11,13 +11,27 @@ impl Value {
 		collate: bool,
 		numeric: bool,
 	) -> Option<Ordering> {
+		self.compare_by(other, path, &|a, b| match (collate, numeric) {
+			(true, true) => a.natural_lexical_cmp(b),
+			(true, false) => a.lexical_cmp(b),
+			(false, true) => a.natural_cmp(b),
+			_ => a.partial_cmp(b),
+		})
+	}
+
+	/// Compare this Value to another Value at a path, comparing
+	/// the values at the end of the path with a custom function
+	pub(crate) fn compare_by<F>(&self, other: &Self, path: &[Part], cmp: &F) -> Option<Ordering>
+	where
+		F: Fn(&Value, &Value) -> Option<Ordering>,
+	{
 		match path.first() {
 			// Get the current path part
 			Some(p) => match (self, other) {
 				// Current path part is an object
 				(Value::Object(a), Value::Object(b)) => match p {
 					Part::Field(f) => match (a.get(f.as_str()), b.get(f.as_str())) {
-						(Some(a), Some(b)) => a.compare(b, path.next(), collate, numeric),
+						(Some(a), Some(b)) => a.compare_by(b, path.next(), cmp),
 						(Some(_), None) => Some(Ordering::Greater),
 						(None, Some(_)) => Some(Ordering::Less),
 						(_, _) => Some(Ordering::Equal),
@@ -28,7 +42,7 @@ impl Value {
 				(Value::Array(a), Value::Array(b)) => match p {
 					Part::All => {
 						for (a, b) in a.iter().zip(b.iter()) {
-							match a.compare(b, path.next(), collate, numeric) {
+							match a.compare_by(b, path.next(), cmp) {
 								Some(Ordering::Equal) => continue,
 								None => continue,
 								o => return o,
@@ -41,26 +55,26 @@ impl Value {
 						}
 					}
 					Part::First => match (a.first(), b.first()) {
-						(Some(a), Some(b)) => a.compare(b, path.next(), collate, numeric),
+						(Some(a), Some(b)) => a.compare_by(b, path.next(), cmp),
 						(Some(_), None) => Some(Ordering::Greater),
 						(None, Some(_)) => Some(Ordering::Less),
 						(_, _) => Some(Ordering::Equal),
 					},
 					Part::Last => match (a.last(), b.last()) {
-						(Some(a), Some(b)) => a.compare(b, path.next(), collate, numeric),
+						(Some(a), Some(b)) => a.compare_by(b, path.next(), cmp),
 						(Some(_), None) => Some(Ordering::Greater),
 						(None, Some(_)) => Some(Ordering::Less),
 						(_, _) => Some(Ordering::Equal),
 					},
 					Part::Index(i) => match (a.get(i.to_usize()), b.get(i.to_usize())) {
-						(Some(a), Some(b)) => a.compare(b, path.next(), collate, numeric),
+						(Some(a), Some(b)) => a.compare_by(b, path.next(), cmp),
 						(Some(_), None) => Some(Ordering::Greater),
 						(None, Some(_)) => Some(Ordering::Less),
 						(_, _) => Some(Ordering::Equal),
 					},
 					_ => {
 						for (a, b) in a.iter().zip(b.iter()) {
-							match a.compare(b, path, collate, numeric) {
+							match a.compare_by(b, path, cmp) {
 								Some(Ordering::Equal) => continue,
 								None => continue,
 								o => return o,
@@ -74,15 +88,10 @@ impl Value {
 					}
 				},
 				// Ignore everything else
-				(a, b) => a.compare(b, path.next(), collate, numeric),
+				(a, b) => a.compare_by(b, path.next(), cmp),
 			},
 			// No more parts so get the value
-			None => match (collate, numeric) {
-				(true, true) => self.natural_lexical_cmp(other),
-				(true, false) => self.lexical_cmp(other),
-				(false, true) => self.natural_cmp(other),
-				_ => self.partial_cmp(other),
-			},
+			None => cmp(self, other),
 		}
 	}
 }
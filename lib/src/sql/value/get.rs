@@ -4,14 +4,17 @@ use crate::err::Error;
 use crate::exe::try_join_all_buffered;
 use crate::sql::edges::Edges;
 use crate::sql::field::{Field, Fields};
+use crate::sql::graph::Graph;
 use crate::sql::id::Id;
 use crate::sql::part::Next;
 use crate::sql::part::Part;
-use crate::sql::paths::ID;
+use crate::sql::paths::{ID, IN, OUT};
 use crate::sql::statements::select::SelectStatement;
 use crate::sql::thing::Thing;
 use crate::sql::value::{Value, Values};
+use crate::sql::Dir;
 use async_recursion::async_recursion;
+use std::collections::HashSet;
 
 impl Value {
 	/// Asynchronous method for getting a local or remote field from a `Value`
@@ -135,6 +138,14 @@ impl Value {
 						// Remote embedded field, so fetch the thing
 						_ => match p {
 							// This is a graph traversal expression
+							// This is a recursive graph traversal expression
+							Part::Graph(g) if g.recurse.is_some() => {
+								let val = Value::from(Value::recurse(ctx, opt, val, g).await?);
+								match path.len() {
+									1 => Ok(val),
+									_ => val.get(ctx, opt, path.next()).await?.flatten().ok(),
+								}
+							}
 							Part::Graph(g) => {
 								let stm = SelectStatement {
 									expr: Fields(vec![Field::All], false),
@@ -184,6 +195,71 @@ impl Value {
 			None => Ok(self.clone()),
 		}
 	}
+
+	/// Follow the edges of a recursive graph traversal breadth first,
+	/// returning each distinct record which is reached within the depth
+	/// range. Records which have already been reached are not followed
+	/// again, so that cycles in the graph are not traversed.
+	async fn recurse(
+		ctx: &Context<'_>,
+		opt: &Options,
+		from: Thing,
+		g: &Graph,
+	) -> Result<Vec<Value>, Error> {
+		let (min, max) = g.recurse.unwrap_or((1, 1));
+		let mut out = vec![];
+		if min == 0 {
+			out.push(Value::from(from.clone()));
+		}
+		let mut seen = HashSet::from([from.clone()]);
+		let mut next = vec![from];
+		for depth in 1..=max {
+			// Check if there is anything left to traverse
+			if next.is_empty() || ctx.is_done() {
+				break;
+			}
+			// Select the edges of all the records at this depth
+			let stm = SelectStatement {
+				expr: Fields(vec![Field::All], false),
+				what: Values(
+					next.drain(..)
+						.map(|from| {
+							Value::from(Edges {
+								from,
+								dir: g.dir.clone(),
+								what: g.what.clone(),
+							})
+						})
+						.collect(),
+				),
+				cond: g.cond.clone(),
+				..SelectStatement::default()
+			};
+			let edges = match stm.compute(ctx, opt).await? {
+				Value::Array(v) => v,
+				_ => break,
+			};
+			for edge in edges.iter() {
+				// Get the records at the other end of the edge
+				let ends = match g.dir {
+					Dir::In => vec![edge.pick(IN.as_ref())],
+					Dir::Out => vec![edge.pick(OUT.as_ref())],
+					Dir::Both => vec![edge.pick(IN.as_ref()), edge.pick(OUT.as_ref())],
+				};
+				for end in ends {
+					if let Value::Thing(v) = end {
+						if seen.insert(v.clone()) {
+							if depth >= min {
+								out.push(Value::from(v.clone()));
+							}
+							next.push(v);
+						}
+					}
+				}
+			}
+		}
+		Ok(out)
+	}
 }
 
 #[cfg(test)]
@@ -61,6 +61,10 @@ impl ser::Serializer for Serializer {
 			"Output" => {
 				Ok(Entry::Output(value.serialize(ser::statement::output::Serializer.wrap())?))
 			}
+			"Foreach" => {
+				Ok(Entry::Foreach(value.serialize(ser::statement::foreach::Serializer.wrap())?))
+			}
+			"Throw" => Ok(Entry::Throw(value.serialize(ser::statement::throw::Serializer.wrap())?)),
 			variant => Err(Error::custom(format!("unexpected variant `{name}::{variant}`"))),
 		}
 	}
@@ -141,4 +145,18 @@ mod tests {
 		let serialized = entry.serialize(Serializer.wrap()).unwrap();
 		assert_eq!(entry, serialized);
 	}
+
+	#[test]
+	fn foreach() {
+		let entry = Entry::Foreach(Default::default());
+		let serialized = entry.serialize(Serializer.wrap()).unwrap();
+		assert_eq!(entry, serialized);
+	}
+
+	#[test]
+	fn throw() {
+		let entry = Entry::Throw(Default::default());
+		let serialized = entry.serialize(Serializer.wrap()).unwrap();
+		assert_eq!(entry, serialized);
+	}
 }
@@ -0,0 +1,103 @@
+pub(super) mod opt;
+
+use crate::err::Error;
+use crate::sql::value::serde::ser;
+use crate::sql::Collation;
+use ser::Serializer as _;
+use serde::ser::Error as _;
+use serde::ser::Impossible;
+use serde::ser::Serialize;
+
+pub(super) struct Serializer;
+
+impl ser::Serializer for Serializer {
+	type Ok = Collation;
+	type Error = Error;
+
+	type SerializeSeq = Impossible<Collation, Error>;
+	type SerializeTuple = Impossible<Collation, Error>;
+	type SerializeTupleStruct = Impossible<Collation, Error>;
+	type SerializeTupleVariant = Impossible<Collation, Error>;
+	type SerializeMap = Impossible<Collation, Error>;
+	type SerializeStruct = SerializeCollation;
+	type SerializeStructVariant = Impossible<Collation, Error>;
+
+	const EXPECTED: &'static str = "a struct `Collation`";
+
+	#[inline]
+	fn serialize_struct(
+		self,
+		_name: &'static str,
+		_len: usize,
+	) -> Result<Self::SerializeStruct, Error> {
+		Ok(SerializeCollation::default())
+	}
+}
+
+#[derive(Default)]
+pub(super) struct SerializeCollation {
+	locale: Option<String>,
+	insensitive: Option<bool>,
+	numeric: Option<bool>,
+}
+
+impl serde::ser::SerializeStruct for SerializeCollation {
+	type Ok = Collation;
+	type Error = Error;
+
+	fn serialize_field<T>(&mut self, key: &'static str, value: &T) -> Result<(), Error>
+	where
+		T: ?Sized + Serialize,
+	{
+		match key {
+			"locale" => {
+				self.locale = value.serialize(ser::string::opt::Serializer.wrap())?;
+			}
+			"insensitive" => {
+				self.insensitive = Some(value.serialize(ser::primitive::bool::Serializer.wrap())?);
+			}
+			"numeric" => {
+				self.numeric = Some(value.serialize(ser::primitive::bool::Serializer.wrap())?);
+			}
+			key => {
+				return Err(Error::custom(format!("unexpected field `Collation::{key}`")));
+			}
+		}
+		Ok(())
+	}
+
+	fn end(self) -> Result<Self::Ok, Error> {
+		match (self.insensitive, self.numeric) {
+			(Some(insensitive), Some(numeric)) => Ok(Collation {
+				locale: self.locale,
+				insensitive,
+				numeric,
+			}),
+			_ => Err(Error::custom("`Collation` missing required field(s)")),
+		}
+	}
+}
+
+#[cfg(test)]
+mod tests {
+	use super::*;
+	use serde::Serialize;
+
+	#[test]
+	fn default() {
+		let collation = Collation::default();
+		let serialized = collation.serialize(Serializer.wrap()).unwrap();
+		assert_eq!(collation, serialized);
+	}
+
+	#[test]
+	fn with_locale() {
+		let collation = Collation {
+			locale: Some("sv".to_owned()),
+			insensitive: true,
+			numeric: true,
+		};
+		let serialized = collation.serialize(Serializer.wrap()).unwrap();
+		assert_eq!(collation, serialized);
+	}
+}
@@ -0,0 +1,55 @@
+use crate::err::Error;
+use crate::sql::value::serde::ser;
+use crate::sql::Collation;
+use serde::ser::Impossible;
+use serde::ser::Serialize;
+
+pub struct Serializer;
+
+impl ser::Serializer for Serializer {
+	type Ok = Option<Collation>;
+	type Error = Error;
+
+	type SerializeSeq = Impossible<Option<Collation>, Error>;
+	type SerializeTuple = Impossible<Option<Collation>, Error>;
+	type SerializeTupleStruct = Impossible<Option<Collation>, Error>;
+	type SerializeTupleVariant = Impossible<Option<Collation>, Error>;
+	type SerializeMap = Impossible<Option<Collation>, Error>;
+	type SerializeStruct = Impossible<Option<Collation>, Error>;
+	type SerializeStructVariant = Impossible<Option<Collation>, Error>;
+
+	const EXPECTED: &'static str = "an `Option<Collation>`";
+
+	#[inline]
+	fn serialize_none(self) -> Result<Self::Ok, Self::Error> {
+		Ok(None)
+	}
+
+	#[inline]
+	fn serialize_some<T>(self, value: &T) -> Result<Self::Ok, Self::Error>
+	where
+		T: ?Sized + Serialize,
+	{
+		Ok(Some(value.serialize(ser::collation::Serializer.wrap())?))
+	}
+}
+
+#[cfg(test)]
+mod tests {
+	use super::*;
+	use ser::Serializer as _;
+
+	#[test]
+	fn none() {
+		let option: Option<Collation> = None;
+		let serialized = option.serialize(Serializer.wrap()).unwrap();
+		assert_eq!(option, serialized);
+	}
+
+	#[test]
+	fn some() {
+		let option = Some(Collation::default());
+		let serialized = option.serialize(Serializer.wrap()).unwrap();
+		assert_eq!(option, serialized);
+	}
+}
@@ -0,0 +1,67 @@
+pub(super) mod opt;
+
+use crate::err::Error;
+use crate::sql::value::serde::ser;
+use crate::sql::Fill;
+use serde::ser::Error as _;
+use serde::ser::Impossible;
+
+pub(super) struct Serializer;
+
+impl ser::Serializer for Serializer {
+	type Ok = Fill;
+	type Error = Error;
+
+	type SerializeSeq = Impossible<Fill, Error>;
+	type SerializeTuple = Impossible<Fill, Error>;
+	type SerializeTupleStruct = Impossible<Fill, Error>;
+	type SerializeTupleVariant = Impossible<Fill, Error>;
+	type SerializeMap = Impossible<Fill, Error>;
+	type SerializeStruct = Impossible<Fill, Error>;
+	type SerializeStructVariant = Impossible<Fill, Error>;
+
+	const EXPECTED: &'static str = "an enum `Fill`";
+
+	#[inline]
+	fn serialize_unit_variant(
+		self,
+		name: &'static str,
+		_variant_index: u32,
+		variant: &'static str,
+	) -> Result<Self::Ok, Error> {
+		match variant {
+			"None" => Ok(Fill::None),
+			"Previous" => Ok(Fill::Previous),
+			"Linear" => Ok(Fill::Linear),
+			variant => Err(Error::custom(format!("unexpected unit variant `{name}::{variant}`"))),
+		}
+	}
+}
+
+#[cfg(test)]
+mod tests {
+	use super::*;
+	use ser::Serializer as _;
+	use serde::Serialize;
+
+	#[test]
+	fn none() {
+		let fill = Fill::None;
+		let serialized = fill.serialize(Serializer.wrap()).unwrap();
+		assert_eq!(fill, serialized);
+	}
+
+	#[test]
+	fn previous() {
+		let fill = Fill::Previous;
+		let serialized = fill.serialize(Serializer.wrap()).unwrap();
+		assert_eq!(fill, serialized);
+	}
+
+	#[test]
+	fn linear() {
+		let fill = Fill::Linear;
+		let serialized = fill.serialize(Serializer.wrap()).unwrap();
+		assert_eq!(fill, serialized);
+	}
+}
@@ -0,0 +1,55 @@
+use crate::err::Error;
+use crate::sql::value::serde::ser;
+use crate::sql::Fill;
+use serde::ser::Impossible;
+use serde::ser::Serialize;
+
+pub struct Serializer;
+
+impl ser::Serializer for Serializer {
+	type Ok = Option<Fill>;
+	type Error = Error;
+
+	type SerializeSeq = Impossible<Option<Fill>, Error>;
+	type SerializeTuple = Impossible<Option<Fill>, Error>;
+	type SerializeTupleStruct = Impossible<Option<Fill>, Error>;
+	type SerializeTupleVariant = Impossible<Option<Fill>, Error>;
+	type SerializeMap = Impossible<Option<Fill>, Error>;
+	type SerializeStruct = Impossible<Option<Fill>, Error>;
+	type SerializeStructVariant = Impossible<Option<Fill>, Error>;
+
+	const EXPECTED: &'static str = "an `Option<Fill>`";
+
+	#[inline]
+	fn serialize_none(self) -> Result<Self::Ok, Self::Error> {
+		Ok(None)
+	}
+
+	#[inline]
+	fn serialize_some<T>(self, value: &T) -> Result<Self::Ok, Self::Error>
+	where
+		T: ?Sized + Serialize,
+	{
+		Ok(Some(value.serialize(ser::fill::Serializer.wrap())?))
+	}
+}
+
+#[cfg(test)]
+mod tests {
+	use super::*;
+	use ser::Serializer as _;
+
+	#[test]
+	fn none() {
+		let option: Option<Fill> = None;
+		let serialized = option.serialize(Serializer.wrap()).unwrap();
+		assert_eq!(option, serialized);
+	}
+
+	#[test]
+	fn some() {
+		let option = Some(Fill::Linear);
+		let serialized = option.serialize(Serializer.wrap()).unwrap();
+		assert_eq!(option, serialized);
+	}
+}
@@ -3,6 +3,7 @@ use crate::sql::value::serde::ser;
 use crate::sql::Function;
 use crate::sql::Script;
 use crate::sql::Value;
+use crate::sql::Window;
 use ser::Serializer as _;
 use serde::ser::Error as _;
 use serde::ser::Impossible;
@@ -35,6 +36,7 @@ impl ser::Serializer for Serializer {
 			"Normal" => Inner::Normal(None, None),
 			"Custom" => Inner::Custom(None, None),
 			"Script" => Inner::Script(None, None),
+			"Window" => Inner::Window(None, None, None),
 			variant => {
 				return Err(Error::custom(format!("unexpected tuple variant `{name}::{variant}`")));
 			}
@@ -55,6 +57,7 @@ enum Inner {
 	Normal(Option<String>, Option<Vec<Value>>),
 	Custom(Option<String>, Option<Vec<Value>>),
 	Script(Option<Script>, Option<Vec<Value>>),
+	Window(Option<String>, Option<Vec<Value>>, Option<Window>),
 }
 
 impl serde::ser::SerializeTupleVariant for SerializeFunction {
@@ -66,7 +69,12 @@ impl serde::ser::SerializeTupleVariant for SerializeFunction {
 		T: Serialize + ?Sized,
 	{
 		match (self.index, &mut self.inner) {
-			(0, Inner::Normal(ref mut var, _) | Inner::Custom(ref mut var, _)) => {
+			(
+				0,
+				Inner::Normal(ref mut var, _)
+				| Inner::Custom(ref mut var, _)
+				| Inner::Window(ref mut var, _, _),
+			) => {
 				*var = Some(value.serialize(ser::string::Serializer.wrap())?);
 			}
 			(0, Inner::Script(ref mut var, _)) => {
@@ -76,15 +84,20 @@ impl serde::ser::SerializeTupleVariant for SerializeFunction {
 				1,
 				Inner::Normal(_, ref mut var)
 				| Inner::Custom(_, ref mut var)
-				| Inner::Script(_, ref mut var),
+				| Inner::Script(_, ref mut var)
+				| Inner::Window(_, ref mut var, _),
 			) => {
 				*var = Some(value.serialize(ser::value::vec::Serializer.wrap())?);
 			}
+			(2, Inner::Window(_, _, ref mut var)) => {
+				*var = Some(value.serialize(ser::window::Serializer.wrap())?);
+			}
 			(index, inner) => {
 				let variant = match inner {
 					Inner::Normal(..) => "Normal",
 					Inner::Custom(..) => "Custom",
 					Inner::Script(..) => "Script",
+					Inner::Window(..) => "Window",
 				};
 				return Err(Error::custom(format!(
 					"unexpected `Function::{variant}` index `{index}`"
@@ -100,6 +113,9 @@ impl serde::ser::SerializeTupleVariant for SerializeFunction {
 			Inner::Normal(Some(one), Some(two)) => Ok(Function::Normal(one, two)),
 			Inner::Custom(Some(one), Some(two)) => Ok(Function::Custom(one, two)),
 			Inner::Script(Some(one), Some(two)) => Ok(Function::Script(one, two)),
+			Inner::Window(Some(one), Some(two), Some(three)) => {
+				Ok(Function::Window(one, two, three))
+			}
 			_ => Err(Error::custom("`Function` missing required value(s)")),
 		}
 	}
@@ -130,4 +146,12 @@ mod tests {
 		let serialized = function.serialize(Serializer.wrap()).unwrap();
 		assert_eq!(function, serialized);
 	}
+
+	#[test]
+	fn window() {
+		let function =
+			Function::Window(Default::default(), vec![Default::default()], Default::default());
+		let serialized = function.serialize(Serializer.wrap()).unwrap();
+		assert_eq!(function, serialized);
+	}
 }
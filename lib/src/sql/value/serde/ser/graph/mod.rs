@@ -11,6 +11,7 @@ use crate::sql::Dir;
 use crate::sql::Graph;
 use crate::sql::Idiom;
 use crate::sql::Tables;
+use crate::sql::Value;
 use ser::Serializer as _;
 use serde::ser::Error as _;
 use serde::ser::Impossible;
@@ -54,6 +55,7 @@ pub(super) struct SerializeGraph {
 	limit: Option<Limit>,
 	start: Option<Start>,
 	alias: Option<Idiom>,
+	recurse: Option<(u64, u64)>,
 }
 
 impl serde::ser::SerializeStruct for SerializeGraph {
@@ -95,6 +97,17 @@ impl serde::ser::SerializeStruct for SerializeGraph {
 			"alias" => {
 				self.alias = value.serialize(ser::part::vec::opt::Serializer.wrap())?.map(Idiom);
 			}
+			"recurse" => {
+				self.recurse = match value.serialize(ser::value::Serializer.wrap())? {
+					Value::Array(v) => match v.as_slice() {
+						[Value::Number(min), Value::Number(max)] => {
+							Some((min.to_int() as u64, max.to_int() as u64))
+						}
+						_ => return Err(Error::custom("expected a `(u64, u64)`")),
+					},
+					_ => None,
+				};
+			}
 			key => {
 				return Err(Error::custom(format!("unexpected field `Graph::{key}`")));
 			}
@@ -115,6 +128,7 @@ impl serde::ser::SerializeStruct for SerializeGraph {
 				limit: self.limit,
 				start: self.start,
 				alias: self.alias,
+				recurse: self.recurse,
 			}),
 			_ => Err(Error::custom("`Graph` missing required field(s)")),
 		}
@@ -152,4 +166,14 @@ mod tests {
 		let serialized = graph.serialize(Serializer.wrap()).unwrap();
 		assert_eq!(graph, serialized);
 	}
+
+	#[test]
+	fn with_recurse() {
+		let graph = Graph {
+			recurse: Some((1, 5)),
+			..Default::default()
+		};
+		let serialized = graph.serialize(Serializer.wrap()).unwrap();
+		assert_eq!(graph, serialized);
+	}
 }
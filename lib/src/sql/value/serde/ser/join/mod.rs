@@ -0,0 +1,88 @@
+pub(super) mod opt;
+
+use crate::err::Error;
+use crate::sql::value::serde::ser;
+use crate::sql::Join;
+use crate::sql::Table;
+use crate::sql::Value;
+use ser::Serializer as _;
+use serde::ser::Error as _;
+use serde::ser::Impossible;
+use serde::ser::Serialize;
+
+pub(super) struct Serializer;
+
+impl ser::Serializer for Serializer {
+	type Ok = Join;
+	type Error = Error;
+
+	type SerializeSeq = Impossible<Join, Error>;
+	type SerializeTuple = Impossible<Join, Error>;
+	type SerializeTupleStruct = Impossible<Join, Error>;
+	type SerializeTupleVariant = Impossible<Join, Error>;
+	type SerializeMap = Impossible<Join, Error>;
+	type SerializeStruct = SerializeJoin;
+	type SerializeStructVariant = Impossible<Join, Error>;
+
+	const EXPECTED: &'static str = "a struct `Join`";
+
+	#[inline]
+	fn serialize_struct(
+		self,
+		_name: &'static str,
+		_len: usize,
+	) -> Result<Self::SerializeStruct, Error> {
+		Ok(SerializeJoin::default())
+	}
+}
+
+#[derive(Default)]
+pub(super) struct SerializeJoin {
+	what: Option<Table>,
+	cond: Option<Value>,
+}
+
+impl serde::ser::SerializeStruct for SerializeJoin {
+	type Ok = Join;
+	type Error = Error;
+
+	fn serialize_field<T>(&mut self, key: &'static str, value: &T) -> Result<(), Error>
+	where
+		T: ?Sized + Serialize,
+	{
+		match key {
+			"what" => {
+				self.what = Some(Table(value.serialize(ser::string::Serializer.wrap())?));
+			}
+			"cond" => {
+				self.cond = Some(value.serialize(ser::value::Serializer.wrap())?);
+			}
+			key => {
+				return Err(Error::custom(format!("unexpected field `Join::{key}`")));
+			}
+		}
+		Ok(())
+	}
+
+	fn end(self) -> Result<Self::Ok, Error> {
+		match (self.what, self.cond) {
+			(Some(what), Some(cond)) => Ok(Join {
+				what,
+				cond,
+			}),
+			_ => Err(Error::custom("`Join` missing required field(s)")),
+		}
+	}
+}
+
+#[cfg(test)]
+mod tests {
+	use super::*;
+
+	#[test]
+	fn default() {
+		let join = Join::default();
+		let serialized = join.serialize(Serializer.wrap()).unwrap();
+		assert_eq!(join, serialized);
+	}
+}
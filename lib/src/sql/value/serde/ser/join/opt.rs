@@ -0,0 +1,55 @@
+use crate::err::Error;
+use crate::sql::value::serde::ser;
+use crate::sql::Join;
+use serde::ser::Impossible;
+use serde::ser::Serialize;
+
+pub struct Serializer;
+
+impl ser::Serializer for Serializer {
+	type Ok = Option<Join>;
+	type Error = Error;
+
+	type SerializeSeq = Impossible<Option<Join>, Error>;
+	type SerializeTuple = Impossible<Option<Join>, Error>;
+	type SerializeTupleStruct = Impossible<Option<Join>, Error>;
+	type SerializeTupleVariant = Impossible<Option<Join>, Error>;
+	type SerializeMap = Impossible<Option<Join>, Error>;
+	type SerializeStruct = Impossible<Option<Join>, Error>;
+	type SerializeStructVariant = Impossible<Option<Join>, Error>;
+
+	const EXPECTED: &'static str = "an `Option<Join>`";
+
+	#[inline]
+	fn serialize_none(self) -> Result<Self::Ok, Self::Error> {
+		Ok(None)
+	}
+
+	#[inline]
+	fn serialize_some<T>(self, value: &T) -> Result<Self::Ok, Self::Error>
+	where
+		T: ?Sized + Serialize,
+	{
+		Ok(Some(value.serialize(ser::join::Serializer.wrap())?))
+	}
+}
+
+#[cfg(test)]
+mod tests {
+	use super::*;
+	use ser::Serializer as _;
+
+	#[test]
+	fn none() {
+		let option: Option<Join> = None;
+		let serialized = option.serialize(Serializer.wrap()).unwrap();
+		assert_eq!(option, serialized);
+	}
+
+	#[test]
+	fn some() {
+		let option = Some(Join::default());
+		let serialized = option.serialize(Serializer.wrap()).unwrap();
+		assert_eq!(option, serialized);
+	}
+}
@@ -65,6 +65,7 @@ impl ser::Serializer for Serializer {
 			"Geometry" => Ok(Kind::Geometry(value.serialize(ser::string::vec::Serializer.wrap())?)),
 			"Option" => Ok(Kind::Option(Box::new(value.serialize(Serializer.wrap())?))),
 			"Either" => Ok(Kind::Either(value.serialize(vec::Serializer.wrap())?)),
+			"Vector" => Ok(Kind::Vector(value.serialize(ser::primitive::u64::Serializer.wrap())?)),
 			variant => {
 				Err(Error::custom(format!("unexpected newtype variant `{name}::{variant}`")))
 			}
@@ -297,4 +298,11 @@ mod tests {
 		let serialized = kind.serialize(Serializer.wrap()).unwrap();
 		assert_eq!(kind, serialized);
 	}
+
+	#[test]
+	fn vector() {
+		let kind = Kind::Vector(3);
+		let serialized = kind.serialize(Serializer.wrap()).unwrap();
+		assert_eq!(kind, serialized);
+	}
 }
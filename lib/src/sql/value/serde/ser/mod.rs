@@ -1,5 +1,6 @@
 mod block;
 mod cast;
+mod collation;
 mod cond;
 mod constant;
 mod data;
@@ -12,11 +13,13 @@ mod expression;
 mod fetch;
 mod field;
 mod fields;
+mod fill;
 mod function;
 mod geometry;
 mod graph;
 mod group;
 mod id;
+mod join;
 mod kind;
 mod limit;
 mod model;
@@ -38,6 +41,7 @@ mod timeout;
 mod uuid;
 mod value;
 mod version;
+mod window;
 
 use serde::ser::Error;
 use serde::ser::Serialize;
@@ -2,6 +2,7 @@ pub(super) mod vec;
 
 use crate::err::Error;
 use crate::sql::value::serde::ser;
+use crate::sql::Collation;
 use crate::sql::Idiom;
 use crate::sql::Order;
 use ser::Serializer as _;
@@ -41,6 +42,7 @@ pub(super) struct SerializeOrder {
 	random: Option<bool>,
 	collate: Option<bool>,
 	numeric: Option<bool>,
+	collation: Option<Collation>,
 	direction: Option<bool>,
 }
 
@@ -65,6 +67,9 @@ impl serde::ser::SerializeStruct for SerializeOrder {
 			"numeric" => {
 				self.numeric = Some(value.serialize(ser::primitive::bool::Serializer.wrap())?);
 			}
+			"collation" => {
+				self.collation = value.serialize(ser::collation::opt::Serializer.wrap())?;
+			}
 			"direction" => {
 				self.direction = Some(value.serialize(ser::primitive::bool::Serializer.wrap())?);
 			}
@@ -83,6 +88,7 @@ impl serde::ser::SerializeStruct for SerializeOrder {
 					random,
 					collate,
 					numeric,
+					collation: self.collation,
 					direction,
 				})
 			}
@@ -102,4 +108,14 @@ mod tests {
 		let serialized = order.serialize(Serializer.wrap()).unwrap();
 		assert_eq!(order, serialized);
 	}
+
+	#[test]
+	fn with_collation() {
+		let order = Order {
+			collation: Some(Default::default()),
+			..Default::default()
+		};
+		let serialized = order.serialize(Serializer.wrap()).unwrap();
+		assert_eq!(order, serialized);
+	}
 }
@@ -41,6 +41,7 @@ impl ser::Serializer for Serializer {
 pub struct SerializeCreateStatement {
 	what: Option<Values>,
 	data: Option<Data>,
+	unique: Option<Duration>,
 	output: Option<Output>,
 	timeout: Option<Timeout>,
 	parallel: Option<bool>,
@@ -61,6 +62,9 @@ impl serde::ser::SerializeStruct for SerializeCreateStatement {
 			"data" => {
 				self.data = value.serialize(ser::data::opt::Serializer.wrap())?;
 			}
+			"unique" => {
+				self.unique = value.serialize(ser::duration::opt::Serializer.wrap())?.map(Duration);
+			}
 			"output" => {
 				self.output = value.serialize(ser::output::opt::Serializer.wrap())?;
 			}
@@ -85,6 +89,7 @@ impl serde::ser::SerializeStruct for SerializeCreateStatement {
 				what,
 				parallel,
 				data: self.data,
+				unique: self.unique,
 				output: self.output,
 				timeout: self.timeout,
 			}),
@@ -114,6 +119,16 @@ mod tests {
 		assert_eq!(value, stmt);
 	}
 
+	#[test]
+	fn with_unique() {
+		let stmt = CreateStatement {
+			unique: Some(Default::default()),
+			..Default::default()
+		};
+		let value: CreateStatement = stmt.serialize(Serializer.wrap()).unwrap();
+		assert_eq!(value, stmt);
+	}
+
 	#[test]
 	fn with_output() {
 		let stmt = CreateStatement {
@@ -2,6 +2,8 @@ use crate::err::Error;
 use crate::sql::statements::DeleteStatement;
 use crate::sql::value::serde::ser;
 use crate::sql::Cond;
+use crate::sql::Limit;
+use crate::sql::Orders;
 use crate::sql::Output;
 use crate::sql::Timeout;
 use crate::sql::Values;
@@ -40,6 +42,9 @@ impl ser::Serializer for Serializer {
 pub struct SerializeDeleteStatement {
 	what: Option<Values>,
 	cond: Option<Cond>,
+	order: Option<Orders>,
+	limit: Option<Limit>,
+	strict: bool,
 	output: Option<Output>,
 	timeout: Option<Timeout>,
 	parallel: Option<bool>,
@@ -60,6 +65,15 @@ impl serde::ser::SerializeStruct for SerializeDeleteStatement {
 			"cond" => {
 				self.cond = value.serialize(ser::cond::opt::Serializer.wrap())?;
 			}
+			"order" => {
+				self.order = value.serialize(ser::order::vec::opt::Serializer.wrap())?.map(Orders);
+			}
+			"limit" => {
+				self.limit = value.serialize(ser::limit::opt::Serializer.wrap())?;
+			}
+			"strict" => {
+				self.strict = value.serialize(ser::primitive::bool::Serializer.wrap())?;
+			}
 			"output" => {
 				self.output = value.serialize(ser::output::opt::Serializer.wrap())?;
 			}
@@ -82,6 +96,9 @@ impl serde::ser::SerializeStruct for SerializeDeleteStatement {
 				what,
 				parallel,
 				cond: self.cond,
+				order: self.order,
+				limit: self.limit,
+				strict: self.strict,
 				output: self.output,
 				timeout: self.timeout,
 			}),
@@ -111,6 +128,37 @@ mod tests {
 		assert_eq!(value, stmt);
 	}
 
+	#[test]
+	fn with_order() {
+		let stmt = DeleteStatement {
+			order: Some(Default::default()),
+			..Default::default()
+		};
+		let value: DeleteStatement = stmt.serialize(Serializer.wrap()).unwrap();
+		assert_eq!(value, stmt);
+	}
+
+	#[test]
+	fn with_limit() {
+		let stmt = DeleteStatement {
+			limit: Some(Default::default()),
+			..Default::default()
+		};
+		let value: DeleteStatement = stmt.serialize(Serializer.wrap()).unwrap();
+		assert_eq!(value, stmt);
+	}
+
+	#[test]
+	fn with_strict() {
+		let stmt = DeleteStatement {
+			limit: Some(Default::default()),
+			strict: true,
+			..Default::default()
+		};
+		let value: DeleteStatement = stmt.serialize(Serializer.wrap()).unwrap();
+		assert_eq!(value, stmt);
+	}
+
 	#[test]
 	fn with_output() {
 		let stmt = DeleteStatement {
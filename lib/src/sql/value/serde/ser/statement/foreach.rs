@@ -0,0 +1,92 @@
+use crate::err::Error;
+use crate::sql::statements::ForeachStatement;
+use crate::sql::value::serde::ser;
+use crate::sql::{Block, Param, Value};
+use ser::Serializer as _;
+use serde::ser::Error as _;
+use serde::ser::Impossible;
+use serde::ser::Serialize;
+
+pub struct Serializer;
+
+impl ser::Serializer for Serializer {
+	type Ok = ForeachStatement;
+	type Error = Error;
+
+	type SerializeSeq = Impossible<ForeachStatement, Error>;
+	type SerializeTuple = Impossible<ForeachStatement, Error>;
+	type SerializeTupleStruct = Impossible<ForeachStatement, Error>;
+	type SerializeTupleVariant = Impossible<ForeachStatement, Error>;
+	type SerializeMap = Impossible<ForeachStatement, Error>;
+	type SerializeStruct = SerializeForeachStatement;
+	type SerializeStructVariant = Impossible<ForeachStatement, Error>;
+
+	const EXPECTED: &'static str = "a struct `ForeachStatement`";
+
+	#[inline]
+	fn serialize_struct(
+		self,
+		_name: &'static str,
+		_len: usize,
+	) -> Result<Self::SerializeStruct, Error> {
+		Ok(SerializeForeachStatement::default())
+	}
+}
+
+#[derive(Default)]
+pub struct SerializeForeachStatement {
+	param: Option<Param>,
+	range: Option<Value>,
+	block: Option<Block>,
+}
+
+impl serde::ser::SerializeStruct for SerializeForeachStatement {
+	type Ok = ForeachStatement;
+	type Error = Error;
+
+	fn serialize_field<T>(&mut self, key: &'static str, value: &T) -> Result<(), Error>
+	where
+		T: ?Sized + Serialize,
+	{
+		match key {
+			"param" => match value.serialize(ser::value::Serializer.wrap())? {
+				Value::Param(v) => self.param = Some(v),
+				_ => return Err(Error::custom("expected a `Param`")),
+			},
+			"range" => {
+				self.range = Some(value.serialize(ser::value::Serializer.wrap())?);
+			}
+			"block" => match value.serialize(ser::value::Serializer.wrap())? {
+				Value::Block(v) => self.block = Some(*v),
+				_ => return Err(Error::custom("expected a `Block`")),
+			},
+			key => {
+				return Err(Error::custom(format!("unexpected field `ForeachStatement::{key}`")));
+			}
+		}
+		Ok(())
+	}
+
+	fn end(self) -> Result<Self::Ok, Error> {
+		match (self.param, self.range, self.block) {
+			(Some(param), Some(range), Some(block)) => Ok(ForeachStatement {
+				param,
+				range,
+				block,
+			}),
+			_ => Err(Error::custom("`ForeachStatement` missing required field(s)")),
+		}
+	}
+}
+
+#[cfg(test)]
+mod tests {
+	use super::*;
+
+	#[test]
+	fn default() {
+		let stmt = ForeachStatement::default();
+		let value: ForeachStatement = stmt.serialize(Serializer.wrap()).unwrap();
+		assert_eq!(value, stmt);
+	}
+}
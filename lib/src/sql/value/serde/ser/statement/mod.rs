@@ -1,9 +1,11 @@
 pub mod create;
 pub mod delete;
+pub mod foreach;
 pub mod ifelse;
 pub mod insert;
 pub mod output;
 pub mod relate;
 pub mod select;
 pub mod set;
+pub mod throw;
 pub mod update;
@@ -1,10 +1,14 @@
 use crate::err::Error;
 use crate::sql::statements::SelectStatement;
 use crate::sql::value::serde::ser;
+use crate::sql::After;
 use crate::sql::Cond;
 use crate::sql::Fetchs;
 use crate::sql::Fields;
+use crate::sql::Fill;
 use crate::sql::Groups;
+use crate::sql::Ident;
+use crate::sql::Join;
 use crate::sql::Limit;
 use crate::sql::Orders;
 use crate::sql::Splits;
@@ -47,16 +51,23 @@ impl ser::Serializer for Serializer {
 pub struct SerializeSelectStatement {
 	expr: Option<Fields>,
 	what: Option<Values>,
+	join: Option<Join>,
+	deleted: Option<bool>,
 	cond: Option<Cond>,
 	split: Option<Splits>,
 	group: Option<Groups>,
+	rollup: Option<bool>,
+	fill: Option<Fill>,
 	order: Option<Orders>,
 	limit: Option<Limit>,
 	start: Option<Start>,
+	after: Option<After>,
+	total: Option<bool>,
 	fetch: Option<Fetchs>,
 	version: Option<Version>,
 	timeout: Option<Timeout>,
 	parallel: Option<bool>,
+	database: Option<Ident>,
 	explain: Option<bool>,
 }
 
@@ -75,6 +86,12 @@ impl serde::ser::SerializeStruct for SerializeSelectStatement {
 			"what" => {
 				self.what = Some(Values(value.serialize(ser::value::vec::Serializer.wrap())?));
 			}
+			"join" => {
+				self.join = value.serialize(ser::join::opt::Serializer.wrap())?;
+			}
+			"deleted" => {
+				self.deleted = Some(value.serialize(ser::primitive::bool::Serializer.wrap())?);
+			}
 			"cond" => {
 				self.cond = value.serialize(ser::cond::opt::Serializer.wrap())?;
 			}
@@ -84,6 +101,12 @@ impl serde::ser::SerializeStruct for SerializeSelectStatement {
 			"group" => {
 				self.group = value.serialize(ser::group::vec::opt::Serializer.wrap())?.map(Groups);
 			}
+			"rollup" => {
+				self.rollup = Some(value.serialize(ser::primitive::bool::Serializer.wrap())?);
+			}
+			"fill" => {
+				self.fill = value.serialize(ser::fill::opt::Serializer.wrap())?;
+			}
 			"order" => {
 				self.order = value.serialize(ser::order::vec::opt::Serializer.wrap())?.map(Orders);
 			}
@@ -93,6 +116,12 @@ impl serde::ser::SerializeStruct for SerializeSelectStatement {
 			"start" => {
 				self.start = value.serialize(ser::start::opt::Serializer.wrap())?;
 			}
+			"after" => {
+				self.after = value.serialize(ser::value::opt::Serializer.wrap())?.map(After);
+			}
+			"total" => {
+				self.total = Some(value.serialize(ser::primitive::bool::Serializer.wrap())?);
+			}
 			"fetch" => {
 				self.fetch = value.serialize(ser::fetch::vec::opt::Serializer.wrap())?.map(Fetchs);
 			}
@@ -108,6 +137,9 @@ impl serde::ser::SerializeStruct for SerializeSelectStatement {
 			"explain" => {
 				self.explain = Some(value.serialize(ser::primitive::bool::Serializer.wrap())?);
 			}
+			"database" => {
+				self.database = value.serialize(ser::string::opt::Serializer.wrap())?.map(Ident);
+			}
 			key => {
 				return Err(Error::custom(format!("unexpected field `SelectStatement::{key}`")));
 			}
@@ -116,21 +148,44 @@ impl serde::ser::SerializeStruct for SerializeSelectStatement {
 	}
 
 	fn end(self) -> Result<Self::Ok, Error> {
-		match (self.expr, self.what, self.parallel, self.explain) {
-			(Some(expr), Some(what), Some(parallel), Some(explain)) => Ok(SelectStatement {
+		match (
+			self.expr,
+			self.what,
+			self.deleted,
+			self.rollup,
+			self.total,
+			self.parallel,
+			self.explain,
+		) {
+			(
+				Some(expr),
+				Some(what),
+				Some(deleted),
+				Some(rollup),
+				Some(total),
+				Some(parallel),
+				Some(explain),
+			) => Ok(SelectStatement {
 				expr,
 				what,
+				deleted,
+				rollup,
+				total,
 				parallel,
 				explain,
+				join: self.join,
 				cond: self.cond,
 				split: self.split,
 				group: self.group,
+				fill: self.fill,
 				order: self.order,
 				limit: self.limit,
 				start: self.start,
+				after: self.after,
 				fetch: self.fetch,
 				version: self.version,
 				timeout: self.timeout,
+				database: self.database,
 			}),
 			_ => Err(Error::custom("`SelectStatement` missing required field(s)")),
 		}
@@ -148,6 +203,26 @@ mod tests {
 		assert_eq!(value, stmt);
 	}
 
+	#[test]
+	fn with_database() {
+		let stmt = SelectStatement {
+			database: Some(Default::default()),
+			..Default::default()
+		};
+		let value: SelectStatement = stmt.serialize(Serializer.wrap()).unwrap();
+		assert_eq!(value, stmt);
+	}
+
+	#[test]
+	fn with_deleted() {
+		let stmt = SelectStatement {
+			deleted: true,
+			..Default::default()
+		};
+		let value: SelectStatement = stmt.serialize(Serializer.wrap()).unwrap();
+		assert_eq!(value, stmt);
+	}
+
 	#[test]
 	fn with_cond() {
 		let stmt = SelectStatement {
@@ -178,6 +253,28 @@ mod tests {
 		assert_eq!(value, stmt);
 	}
 
+	#[test]
+	fn with_rollup() {
+		let stmt = SelectStatement {
+			group: Some(Default::default()),
+			rollup: true,
+			..Default::default()
+		};
+		let value: SelectStatement = stmt.serialize(Serializer.wrap()).unwrap();
+		assert_eq!(value, stmt);
+	}
+
+	#[test]
+	fn with_fill() {
+		let stmt = SelectStatement {
+			group: Some(Default::default()),
+			fill: Some(Default::default()),
+			..Default::default()
+		};
+		let value: SelectStatement = stmt.serialize(Serializer.wrap()).unwrap();
+		assert_eq!(value, stmt);
+	}
+
 	#[test]
 	fn with_order() {
 		let stmt = SelectStatement {
@@ -208,6 +305,26 @@ mod tests {
 		assert_eq!(value, stmt);
 	}
 
+	#[test]
+	fn with_after() {
+		let stmt = SelectStatement {
+			after: Some(After("cursor".into())),
+			..Default::default()
+		};
+		let value: SelectStatement = stmt.serialize(Serializer.wrap()).unwrap();
+		assert_eq!(value, stmt);
+	}
+
+	#[test]
+	fn with_total() {
+		let stmt = SelectStatement {
+			total: true,
+			..Default::default()
+		};
+		let value: SelectStatement = stmt.serialize(Serializer.wrap()).unwrap();
+		assert_eq!(value, stmt);
+	}
+
 	#[test]
 	fn with_fetch() {
 		let stmt = SelectStatement {
@@ -4,6 +4,8 @@ use crate::sql::value::serde::ser;
 use crate::sql::Cond;
 use crate::sql::Data;
 use crate::sql::Duration;
+use crate::sql::Limit;
+use crate::sql::Orders;
 use crate::sql::Output;
 use crate::sql::Timeout;
 use crate::sql::Values;
@@ -43,6 +45,9 @@ pub struct SerializeUpdateStatement {
 	what: Option<Values>,
 	data: Option<Data>,
 	cond: Option<Cond>,
+	order: Option<Orders>,
+	limit: Option<Limit>,
+	strict: bool,
 	output: Option<Output>,
 	timeout: Option<Timeout>,
 	parallel: Option<bool>,
@@ -66,6 +71,15 @@ impl serde::ser::SerializeStruct for SerializeUpdateStatement {
 			"cond" => {
 				self.cond = value.serialize(ser::cond::opt::Serializer.wrap())?;
 			}
+			"order" => {
+				self.order = value.serialize(ser::order::vec::opt::Serializer.wrap())?.map(Orders);
+			}
+			"limit" => {
+				self.limit = value.serialize(ser::limit::opt::Serializer.wrap())?;
+			}
+			"strict" => {
+				self.strict = value.serialize(ser::primitive::bool::Serializer.wrap())?;
+			}
 			"output" => {
 				self.output = value.serialize(ser::output::opt::Serializer.wrap())?;
 			}
@@ -91,6 +105,9 @@ impl serde::ser::SerializeStruct for SerializeUpdateStatement {
 				parallel,
 				data: self.data,
 				cond: self.cond,
+				order: self.order,
+				limit: self.limit,
+				strict: self.strict,
 				output: self.output,
 				timeout: self.timeout,
 			}),
@@ -130,6 +147,37 @@ mod tests {
 		assert_eq!(value, stmt);
 	}
 
+	#[test]
+	fn with_order() {
+		let stmt = UpdateStatement {
+			order: Some(Default::default()),
+			..Default::default()
+		};
+		let value: UpdateStatement = stmt.serialize(Serializer.wrap()).unwrap();
+		assert_eq!(value, stmt);
+	}
+
+	#[test]
+	fn with_limit() {
+		let stmt = UpdateStatement {
+			limit: Some(Default::default()),
+			..Default::default()
+		};
+		let value: UpdateStatement = stmt.serialize(Serializer.wrap()).unwrap();
+		assert_eq!(value, stmt);
+	}
+
+	#[test]
+	fn with_strict() {
+		let stmt = UpdateStatement {
+			limit: Some(Default::default()),
+			strict: true,
+			..Default::default()
+		};
+		let value: UpdateStatement = stmt.serialize(Serializer.wrap()).unwrap();
+		assert_eq!(value, stmt);
+	}
+
 	#[test]
 	fn with_output() {
 		let stmt = UpdateStatement {
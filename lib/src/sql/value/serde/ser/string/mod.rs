@@ -1,3 +1,4 @@
+pub(super) mod opt;
 pub(super) mod vec;
 
 use crate::err::Error;
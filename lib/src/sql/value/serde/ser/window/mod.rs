@@ -0,0 +1,95 @@
+use crate::err::Error;
+use crate::sql::group::Groups;
+use crate::sql::order::Orders;
+use crate::sql::value::serde::ser;
+use crate::sql::Window;
+use ser::Serializer as _;
+use serde::ser::Error as _;
+use serde::ser::Impossible;
+use serde::ser::Serialize;
+
+pub(super) struct Serializer;
+
+impl ser::Serializer for Serializer {
+	type Ok = Window;
+	type Error = Error;
+
+	type SerializeSeq = Impossible<Window, Error>;
+	type SerializeTuple = Impossible<Window, Error>;
+	type SerializeTupleStruct = Impossible<Window, Error>;
+	type SerializeTupleVariant = Impossible<Window, Error>;
+	type SerializeMap = Impossible<Window, Error>;
+	type SerializeStruct = SerializeWindow;
+	type SerializeStructVariant = Impossible<Window, Error>;
+
+	const EXPECTED: &'static str = "a struct `Window`";
+
+	#[inline]
+	fn serialize_struct(
+		self,
+		_name: &'static str,
+		_len: usize,
+	) -> Result<Self::SerializeStruct, Error> {
+		Ok(SerializeWindow::default())
+	}
+}
+
+#[derive(Default)]
+pub(super) struct SerializeWindow {
+	partition: Option<Groups>,
+	order: Option<Orders>,
+}
+
+impl serde::ser::SerializeStruct for SerializeWindow {
+	type Ok = Window;
+	type Error = Error;
+
+	fn serialize_field<T>(&mut self, key: &'static str, value: &T) -> Result<(), Error>
+	where
+		T: ?Sized + Serialize,
+	{
+		match key {
+			"partition" => {
+				self.partition =
+					value.serialize(ser::group::vec::opt::Serializer.wrap())?.map(Groups);
+			}
+			"order" => {
+				self.order = value.serialize(ser::order::vec::opt::Serializer.wrap())?.map(Orders);
+			}
+			key => {
+				return Err(Error::custom(format!("unexpected field `Window::{key}`")));
+			}
+		}
+		Ok(())
+	}
+
+	fn end(self) -> Result<Self::Ok, Error> {
+		Ok(Window {
+			partition: self.partition,
+			order: self.order,
+		})
+	}
+}
+
+#[cfg(test)]
+mod tests {
+	use super::*;
+	use serde::Serialize;
+
+	#[test]
+	fn default() {
+		let window = Window::default();
+		let serialized = window.serialize(Serializer.wrap()).unwrap();
+		assert_eq!(window, serialized);
+	}
+
+	#[test]
+	fn with_partition_and_order() {
+		let window = Window {
+			partition: Some(Default::default()),
+			order: Some(Default::default()),
+		};
+		let serialized = window.serialize(Serializer.wrap()).unwrap();
+		assert_eq!(window, serialized);
+	}
+}
@@ -1,5 +1,6 @@
 #![allow(clippy::derive_ord_xor_partial_ord)]
 
+use crate::cnf::MAX_BYTES_SIZE;
 use crate::ctx::Context;
 use crate::dbs::Options;
 use crate::err::Error;
@@ -1117,6 +1118,7 @@ impl Value {
 				Some(l) => self.coerce_to_array_type_len(t, l).map(Value::from),
 				None => self.coerce_to_array_type(t).map(Value::from),
 			},
+			Kind::Vector(l) => self.coerce_to_vector_len(l).map(Value::from),
 			Kind::Record(t) => match t.is_empty() {
 				true => self.coerce_to_record().map(Value::from),
 				false => self.coerce_to_record_type(t).map(Value::from),
@@ -1426,7 +1428,24 @@ impl Value {
 	pub(crate) fn coerce_to_bytes(self) -> Result<Bytes, Error> {
 		match self {
 			// Bytes are allowed
-			Value::Bytes(v) => Ok(v),
+			Value::Bytes(v) if v.len() <= *MAX_BYTES_SIZE => Ok(v),
+			// Base64 encoded strings are allowed
+			Value::Strand(ref v) => match Bytes::from_base64(v.as_str()) {
+				Some(v) if v.len() <= *MAX_BYTES_SIZE => Ok(v),
+				Some(v) => Err(Error::BytesTooLarge {
+					size: v.len(),
+					max: *MAX_BYTES_SIZE,
+				}),
+				None => Err(Error::CoerceTo {
+					from: self,
+					into: "bytes".into(),
+				}),
+			},
+			// Bytes which are too large raise an error
+			Value::Bytes(v) => Err(Error::BytesTooLarge {
+				size: v.len(),
+				max: *MAX_BYTES_SIZE,
+			}),
 			// Anything else raises an error
 			_ => Err(Error::CoerceTo {
 				from: self,
@@ -1569,6 +1588,31 @@ impl Value {
 			})
 	}
 
+	/// Try to coerce this value to an `Array` of numbers, of an exact length
+	pub(crate) fn coerce_to_vector_len(self, len: &u64) -> Result<Array, Error> {
+		self.coerce_to_array()?
+			.into_iter()
+			.map(|value| value.coerce_to_number().map(Value::from))
+			.collect::<Result<Array, Error>>()
+			.map_err(|e| match e {
+				Error::CoerceTo {
+					from,
+					..
+				} => Error::CoerceTo {
+					from,
+					into: format!("vector<{len}>").into(),
+				},
+				e => e,
+			})
+			.and_then(|v| match v.len() {
+				v if v != *len as usize => Err(Error::LengthInvalid {
+					kind: format!("vector<{len}>").into(),
+					size: v,
+				}),
+				_ => Ok(v),
+			})
+	}
+
 	/// Try to coerce this value to an `Array` of a certain type, unique values
 	pub(crate) fn coerce_to_set_type(self, kind: &Kind) -> Result<Array, Error> {
 		self.coerce_to_array()?
@@ -1643,6 +1687,7 @@ impl Value {
 				Some(l) => self.convert_to_array_type_len(t, l).map(Value::from),
 				None => self.convert_to_array_type(t).map(Value::from),
 			},
+			Kind::Vector(l) => self.convert_to_vector_len(l).map(Value::from),
 			Kind::Record(t) => match t.is_empty() {
 				true => self.convert_to_record().map(Value::from),
 				false => self.convert_to_record_type(t).map(Value::from),
@@ -2130,6 +2175,31 @@ impl Value {
 			})
 	}
 
+	/// Try to convert this value to an `Array` of numbers, of an exact length
+	pub(crate) fn convert_to_vector_len(self, len: &u64) -> Result<Array, Error> {
+		self.convert_to_array()?
+			.into_iter()
+			.map(|value| value.convert_to_number().map(Value::from))
+			.collect::<Result<Array, Error>>()
+			.map_err(|e| match e {
+				Error::ConvertTo {
+					from,
+					..
+				} => Error::ConvertTo {
+					from,
+					into: format!("vector<{len}>").into(),
+				},
+				e => e,
+			})
+			.and_then(|v| match v.len() {
+				v if v != *len as usize => Err(Error::LengthInvalid {
+					kind: format!("vector<{len}>").into(),
+					size: v,
+				}),
+				_ => Ok(v),
+			})
+	}
+
 	/// Try to convert this value to an `Array` of a certain type, unique values
 	pub(crate) fn convert_to_set_type(self, kind: &Kind) -> Result<Array, Error> {
 		self.convert_to_array()?
@@ -2322,10 +2392,12 @@ impl Value {
 		match self {
 			Value::Uuid(v) => match other {
 				Value::Strand(w) => MATCHER.fuzzy_match(v.to_raw().as_str(), w.as_str()).is_some(),
+				Value::Regex(w) => w.regex().is_match(v.to_raw().as_str()),
 				_ => false,
 			},
 			Value::Strand(v) => match other {
 				Value::Strand(w) => MATCHER.fuzzy_match(v.as_str(), w.as_str()).is_some(),
+				Value::Regex(w) => w.regex().is_match(v.as_str()),
 				_ => false,
 			},
 			_ => self.equal(other),
@@ -2475,7 +2547,7 @@ impl Value {
 			Value::Idiom(v) => v.writeable(),
 			Value::Array(v) => v.iter().any(Value::writeable),
 			Value::Object(v) => v.iter().any(|(_, v)| v.writeable()),
-			Value::Function(v) => v.is_custom() || v.args().iter().any(Value::writeable),
+			Value::Function(v) => v.is_writeable() || v.args().iter().any(Value::writeable),
 			Value::Subquery(v) => v.writeable(),
 			Value::Expression(v) => v.l.writeable() || v.r.writeable(),
 			_ => false,
@@ -2531,8 +2603,14 @@ impl TryAdd for Value {
 				(v, w) => Ok(Value::Number(v + w)),
 			},
 			(Value::Strand(v), Value::Strand(w)) => Ok(Value::Strand(v + w)),
-			(Value::Datetime(v), Value::Duration(w)) => Ok(Value::Datetime(w + v)),
-			(Value::Duration(v), Value::Datetime(w)) => Ok(Value::Datetime(v + w)),
+			(Value::Datetime(v), Value::Duration(w)) => match v.checked_add(&w) {
+				Some(v) => Ok(Value::Datetime(v)),
+				None => Err(Error::TryAdd(v.to_raw(), w.to_raw())),
+			},
+			(Value::Duration(v), Value::Datetime(w)) => match w.checked_add(&v) {
+				Some(w) => Ok(Value::Datetime(w)),
+				None => Err(Error::TryAdd(v.to_raw(), w.to_raw())),
+			},
 			(Value::Duration(v), Value::Duration(w)) => Ok(Value::Duration(v + w)),
 			(v, w) => Err(Error::TryAdd(v.to_raw_string(), w.to_raw_string())),
 		}
@@ -2566,8 +2644,14 @@ impl TrySub for Value {
 				(v, w) => Ok(Value::Number(v - w)),
 			},
 			(Value::Datetime(v), Value::Datetime(w)) => Ok(Value::Duration(v - w)),
-			(Value::Datetime(v), Value::Duration(w)) => Ok(Value::Datetime(w - v)),
-			(Value::Duration(v), Value::Datetime(w)) => Ok(Value::Datetime(v - w)),
+			(Value::Datetime(v), Value::Duration(w)) => match v.checked_sub(&w) {
+				Some(v) => Ok(Value::Datetime(v)),
+				None => Err(Error::TrySub(v.to_raw(), w.to_raw())),
+			},
+			(Value::Duration(v), Value::Datetime(w)) => match w.checked_sub(&v) {
+				Some(w) => Ok(Value::Datetime(w)),
+				None => Err(Error::TrySub(v.to_raw(), w.to_raw())),
+			},
 			(Value::Duration(v), Value::Duration(w)) => Ok(Value::Duration(v - w)),
 			(v, w) => Err(Error::TrySub(v.to_raw_string(), w.to_raw_string())),
 		}
@@ -0,0 +1,245 @@
+use crate::ctx::Context;
+use crate::dbs::Options;
+use crate::err::Error;
+use crate::sql::comment::{mightbespace, shouldbespace};
+use crate::sql::common::commas;
+use crate::sql::error::IResult;
+use crate::sql::fmt::Fmt;
+use crate::sql::function::Function;
+use crate::sql::group::{Group, Groups};
+use crate::sql::idiom::basic;
+use crate::sql::order::{order, Orders};
+use crate::sql::value::Value;
+use nom::bytes::complete::tag_no_case;
+use nom::character::complete::char;
+use nom::combinator::{map, opt};
+use nom::multi::separated_list1;
+use nom::sequence::{terminated, tuple};
+use serde::{Deserialize, Serialize};
+use std::cmp::Ordering;
+use std::collections::BTreeMap;
+use std::fmt::{self, Display, Formatter};
+use std::mem;
+
+/// The OVER clause of a window function
+#[derive(Clone, Debug, Default, Eq, PartialEq, PartialOrd, Serialize, Deserialize, Hash)]
+pub struct Window {
+	pub partition: Option<Groups>,
+	pub order: Option<Orders>,
+}
+
+impl Window {
+	/// Compare two rows using the ORDER BY clause of this window
+	fn compare(&self, a: &Value, b: &Value) -> Ordering {
+		if let Some(orders) = &self.order {
+			// Loop over each order clause
+			for order in orders.iter() {
+				// Reverse the ordering if DESC
+				let o = match order.direction {
+					true => order.compare(a, b),
+					false => order.compare(b, a),
+				};
+				//
+				match o {
+					Some(Ordering::Greater) => return Ordering::Greater,
+					Some(Ordering::Equal) => continue,
+					Some(Ordering::Less) => return Ordering::Less,
+					None => continue,
+				}
+			}
+		}
+		Ordering::Equal
+	}
+	/// Compute a window function over the rows of a result set, using
+	/// the function arguments which were computed for each row, and
+	/// returning the value of the function for each row in turn
+	pub(crate) async fn compute(
+		&self,
+		ctx: &Context<'_>,
+		opt: &Options,
+		func: &Function,
+		rows: &[Value],
+		mut args: Vec<Vec<Value>>,
+	) -> Result<Vec<Value>, Error> {
+		let name = func.name();
+		// Create the aggregate function for any running aggregate
+		let agg = match name {
+			"window::row_number" | "window::rank" | "window::dense_rank" => None,
+			"window::lag" | "window::lead" => None,
+			_ => match Function::Normal(name.to_owned(), func.args().to_vec()) {
+				f if f.is_aggregate() => Some(f),
+				_ => {
+					return Err(Error::InvalidArguments {
+						name: name.to_owned(),
+						message: String::from("The function can not be used as a window function."),
+					})
+				}
+			},
+		};
+		// Split the rows into partitions, keeping their order
+		let mut parts: BTreeMap<Vec<Value>, Vec<usize>> = BTreeMap::new();
+		for (i, row) in rows.iter().enumerate() {
+			let key = match &self.partition {
+				Some(groups) => groups.iter().map(|g| row.pick(g)).collect(),
+				None => vec![],
+			};
+			parts.entry(key).or_default().push(i);
+		}
+		// Compute the function for each partition
+		let mut out = vec![Value::None; rows.len()];
+		for (_, mut idx) in parts {
+			// Sort the partition, keeping the order of equal rows
+			idx.sort_by(|a, b| self.compare(&rows[*a], &rows[*b]));
+			// Check if two rows of the partition are peers
+			let peer = |a: usize, b: usize| self.compare(&rows[idx[a]], &rows[idx[b]]).is_eq();
+			// Get the function arguments of each row
+			let vals = idx.iter().map(|i| mem::take(&mut args[*i])).collect::<Vec<_>>();
+			match name {
+				"window::row_number" => {
+					for (p, i) in idx.iter().enumerate() {
+						out[*i] = Value::from(p + 1);
+					}
+				}
+				"window::rank" => {
+					let mut rank = 0;
+					for (p, i) in idx.iter().enumerate() {
+						if p == 0 || !peer(p - 1, p) {
+							rank = p + 1;
+						}
+						out[*i] = Value::from(rank);
+					}
+				}
+				"window::dense_rank" => {
+					let mut rank = 0;
+					for (p, i) in idx.iter().enumerate() {
+						if p == 0 || !peer(p - 1, p) {
+							rank += 1;
+						}
+						out[*i] = Value::from(rank);
+					}
+				}
+				"window::lag" | "window::lead" => {
+					for (p, i) in idx.iter().enumerate() {
+						let offset = match vals[p].get(1) {
+							Some(Value::Number(v)) if v.to_int() >= 0 => v.to_usize(),
+							Some(v) => {
+								return Err(Error::InvalidArguments {
+									name: name.to_owned(),
+									message: format!("Expected a positive offset, but got '{v}'."),
+								})
+							}
+							None => 1,
+						};
+						let target = match name {
+							"window::lag" => p.checked_sub(offset),
+							_ => p.checked_add(offset).filter(|t| *t < idx.len()),
+						};
+						out[*i] = match target {
+							Some(t) => vals[t].first().cloned().unwrap_or_default(),
+							None => vals[p].get(2).cloned().unwrap_or_default(),
+						};
+					}
+				}
+				_ => {
+					let agg = agg.as_ref().unwrap();
+					let mut end = None;
+					let mut val = Value::None;
+					for (p, i) in idx.iter().enumerate() {
+						// A running aggregate includes the peers of the current row,
+						// and an unordered window aggregates the whole partition
+						let mut last = p;
+						while last + 1 < idx.len() && peer(p, last + 1) {
+							last += 1;
+						}
+						if end != Some(last) {
+							let set = vals[..=last]
+								.iter()
+								.map(|a| a.first().cloned().unwrap_or(Value::Bool(true)))
+								.collect::<Vec<_>>();
+							val = agg.aggregate(Value::from(set)).compute(ctx, opt).await?;
+							end = Some(last);
+						}
+						out[*i] = val.clone();
+					}
+				}
+			}
+		}
+		Ok(out)
+	}
+}
+
+impl Display for Window {
+	fn fmt(&self, f: &mut Formatter) -> fmt::Result {
+		f.write_str("OVER (")?;
+		if let Some(ref v) = self.partition {
+			write!(f, "PARTITION BY {}", Fmt::comma_separated(&v.0))?;
+			if self.order.is_some() {
+				f.write_str(" ")?;
+			}
+		}
+		if let Some(ref v) = self.order {
+			write!(f, "{v}")?;
+		}
+		f.write_str(")")
+	}
+}
+
+pub fn window(i: &str) -> IResult<&str, Window> {
+	let (i, _) = tag_no_case("OVER")(i)?;
+	let (i, _) = mightbespace(i)?;
+	let (i, _) = char('(')(i)?;
+	let (i, _) = mightbespace(i)?;
+	let (i, partition) = opt(terminated(partition, mightbespace))(i)?;
+	let (i, order) = opt(terminated(order, mightbespace))(i)?;
+	let (i, _) = char(')')(i)?;
+	Ok((
+		i,
+		Window {
+			partition,
+			order,
+		},
+	))
+}
+
+fn partition(i: &str) -> IResult<&str, Groups> {
+	let (i, _) = tag_no_case("PARTITION")(i)?;
+	let (i, _) = tuple((shouldbespace, tag_no_case("BY")))(i)?;
+	let (i, _) = shouldbespace(i)?;
+	let (i, v) = separated_list1(commas, map(basic, Group))(i)?;
+	Ok((i, Groups(v)))
+}
+
+#[cfg(test)]
+mod tests {
+
+	use super::*;
+
+	#[test]
+	fn window_empty() {
+		let sql = "OVER ()";
+		let res = window(sql);
+		assert!(res.is_ok());
+		let out = res.unwrap().1;
+		assert_eq!("OVER ()", format!("{}", out));
+		assert_eq!(out, Window::default());
+	}
+
+	#[test]
+	fn window_partition_order() {
+		let sql = "OVER ( PARTITION BY country, city ORDER BY age DESC )";
+		let res = window(sql);
+		assert!(res.is_ok());
+		let out = res.unwrap().1;
+		assert_eq!("OVER (PARTITION BY country, city ORDER BY age DESC)", format!("{}", out));
+	}
+
+	#[test]
+	fn window_order() {
+		let sql = "OVER (ORDER BY time)";
+		let res = window(sql);
+		assert!(res.is_ok());
+		let out = res.unwrap().1;
+		assert_eq!("OVER (ORDER BY time)", format!("{}", out));
+		assert!(out.partition.is_none());
+	}
+}
@@ -0,0 +1,45 @@
+mod parse;
+use parse::Parse;
+use surrealdb::dbs::Session;
+use surrealdb::err::Error;
+use surrealdb::iam::allowlist::Allowlist;
+use surrealdb::kvs::Datastore;
+use surrealdb::sql::Value;
+
+#[tokio::test]
+async fn namespace_ip_allowlist() -> Result<(), Error> {
+	let allowlists = Allowlist::parse("test=10.0.0.0/8,192.168.1.1");
+	let dbs = Datastore::new("memory").await?.with_ns_allowlists(allowlists);
+	// A client within an allowed network can use the namespace
+	let mut ses = Session::for_kv().with_ns("test").with_db("test");
+	ses.ip = Some("10.1.2.3".to_owned());
+	let res = &mut dbs.execute("CREATE person:tobie", &ses, None, false).await?;
+	assert_eq!(res.len(), 1);
+	//
+	let tmp = res.remove(0).result?;
+	let val = Value::parse("[{ id: person:tobie }]");
+	assert_eq!(tmp, val);
+	// A client outside of the allowed networks is rejected
+	ses.ip = Some("172.16.0.1".to_owned());
+	let res = dbs.execute("SELECT * FROM person", &ses, None, false).await;
+	assert!(matches!(res, Err(Error::IpNotAllowed { .. })));
+	// A client without an address is rejected
+	ses.ip = None;
+	let res = dbs.execute("SELECT * FROM person", &ses, None, false).await;
+	assert!(matches!(res, Err(Error::IpNotAllowed { .. })));
+	// The namespace can not be selected with a USE statement
+	let mut ses = Session::for_kv();
+	ses.ip = Some("172.16.0.1".to_owned());
+	let res = dbs.execute("USE NS test DB test; SELECT * FROM person", &ses, None, false).await;
+	assert!(matches!(res, Err(Error::IpNotAllowed { .. })));
+	// Other namespaces are not restricted
+	let res =
+		&mut dbs.execute("USE NS other DB test; SELECT * FROM person", &ses, None, false).await?;
+	assert_eq!(res.len(), 2);
+	//
+	let tmp = res.remove(1).result?;
+	let val = Value::parse("[]");
+	assert_eq!(tmp, val);
+	//
+	Ok(())
+}
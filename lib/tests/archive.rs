@@ -0,0 +1,94 @@
+mod parse;
+use parse::Parse;
+use surrealdb::dbs::Session;
+use surrealdb::err::Error;
+use surrealdb::kvs::Datastore;
+use surrealdb::sql::Value;
+
+#[tokio::test]
+async fn archive_table_records() -> Result<(), Error> {
+	let sql = "
+		DEFINE TABLE person ARCHIVE;
+		CREATE person:tobie SET name = 'Tobie';
+		CREATE person:jaime SET name = 'Jaime';
+		CREATE other:test SET name = 'Test';
+	";
+	let dbs = Datastore::new("memory").await?.with_archive("memory").await?;
+	let ses = Session::for_kv().with_ns("test").with_db("test");
+	dbs.execute(sql, &ses, None, false).await?;
+	// Move the records to the archive tier
+	assert_eq!(dbs.archive().await?, 2);
+	assert_eq!(dbs.archive().await?, 0);
+	//
+	let sql = "
+		SELECT * FROM person;
+		SELECT * FROM person:tobie;
+		UPDATE person:jaime SET age = 12;
+	";
+	let res = &mut dbs.execute(sql, &ses, None, false).await?;
+	assert_eq!(res.len(), 3);
+	//
+	let tmp = res.remove(0).result?;
+	let val = Value::parse(
+		"[
+			{ id: person:jaime, name: 'Jaime' },
+			{ id: person:tobie, name: 'Tobie' }
+		]",
+	);
+	assert_eq!(tmp, val);
+	//
+	let tmp = res.remove(0).result?;
+	let val = Value::parse("[{ id: person:tobie, name: 'Tobie' }]");
+	assert_eq!(tmp, val);
+	//
+	let tmp = res.remove(0).result?;
+	let val = Value::parse("[{ id: person:jaime, name: 'Jaime', age: 12 }]");
+	assert_eq!(tmp, val);
+	// The updated record was returned to the hot tier
+	assert_eq!(dbs.archive().await?, 1);
+	//
+	Ok(())
+}
+
+#[tokio::test]
+async fn archive_table_to_folder() -> Result<(), Error> {
+	let dir = temp_dir::TempDir::new().unwrap();
+	let path = format!("file://{}", dir.path().display());
+	let sql = "
+		DEFINE TABLE person SCHEMALESS ARCHIVE;
+		CREATE person:tobie SET name = 'Tobie';
+	";
+	let dbs = Datastore::new("memory").await?.with_archive(&path).await?;
+	let ses = Session::for_kv().with_ns("test").with_db("test");
+	dbs.execute(sql, &ses, None, false).await?;
+	assert_eq!(dbs.archive().await?, 1);
+	assert_eq!(std::fs::read_dir(dir.path()).unwrap().count(), 1);
+	//
+	let sql = "
+		INFO FOR DB;
+		SELECT * FROM person;
+	";
+	let res = &mut dbs.execute(sql, &ses, None, false).await?;
+	assert_eq!(res.len(), 2);
+	//
+	let tmp = res.remove(0).result?;
+	let val = Value::parse(
+		"{
+			analyzers: {},
+			logins: {},
+			tokens: {},
+			functions: {},
+			params: {},
+			scopes: {},
+			sequences: {},
+			tables: { person: 'DEFINE TABLE person SCHEMALESS ARCHIVE' },
+		}",
+	);
+	assert_eq!(tmp, val);
+	//
+	let tmp = res.remove(0).result?;
+	let val = Value::parse("[{ id: person:tobie, name: 'Tobie' }]");
+	assert_eq!(tmp, val);
+	//
+	Ok(())
+}
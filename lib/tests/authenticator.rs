@@ -0,0 +1,55 @@
+mod parse;
+use parse::Parse;
+use std::time::Duration;
+use surrealdb::dbs::{Auth, Session};
+use surrealdb::err::Error;
+use surrealdb::iam::external::Authenticator;
+use surrealdb::iam::signin::signin;
+use surrealdb::kvs::Datastore;
+use surrealdb::sql::{Object, Value};
+
+const SCRIPT: &str = r#"read input
+case "$input" in
+	*'"pass":"secret"'*) echo '{"ns":"test","db":"test","sc":"user","id":"user:tobie","groups":["admin"]}' ;;
+	*) exit 1 ;;
+esac
+"#;
+
+#[cfg(unix)]
+#[tokio::test]
+async fn external_authenticator_signin() -> Result<(), Error> {
+	let dir = temp_dir::TempDir::new().unwrap();
+	let script = dir.path().join("authenticator.sh");
+	std::fs::write(&script, SCRIPT).unwrap();
+	let authenticator = Authenticator::parse(&format!("exec:sh {}", script.display())).unwrap();
+	let dbs = Datastore::new("memory")
+		.await?
+		.with_authenticator(Some(authenticator), Duration::from_secs(5));
+	let ses = Session::for_kv().with_ns("test").with_db("test");
+	dbs.execute("DEFINE SCOPE user SESSION 1h", &ses, None, false).await?;
+	// The credentials are accepted by the authenticator
+	let vars = "{ ns: 'test', db: 'test', sc: 'user', user: 'tobie', pass: 'secret' }";
+	let vars = Object::try_from(Value::parse(vars)).unwrap();
+	let mut ses = Session::default();
+	let tk = signin(&dbs, &None, false, &mut ses, vars).await?;
+	assert!(tk.is_some());
+	assert_eq!(*ses.au, Auth::Sc("test".to_owned(), "test".to_owned(), "user".to_owned()));
+	// The claims of the authenticator are available to queries
+	let res = &mut dbs.execute("RETURN [$auth, $token.groups]", &ses, None, false).await?;
+	let tmp = res.remove(0).result?;
+	let val = Value::parse("[user:tobie, ['admin']]");
+	assert_eq!(tmp, val);
+	// The issued token authenticates to the scope
+	let mut ses = Session::default();
+	surrealdb::iam::verify::token(&dbs, &mut ses, tk.unwrap()).await?;
+	assert_eq!(*ses.au, Auth::Sc("test".to_owned(), "test".to_owned(), "user".to_owned()));
+	// The credentials are rejected by the authenticator
+	let vars = "{ ns: 'test', db: 'test', sc: 'user', user: 'tobie', pass: 'wrong' }";
+	let vars = Object::try_from(Value::parse(vars)).unwrap();
+	let mut ses = Session::default();
+	let res = signin(&dbs, &None, false, &mut ses, vars).await;
+	assert!(matches!(res, Err(Error::InvalidAuth)));
+	assert!(ses.au.is_no());
+	//
+	Ok(())
+}
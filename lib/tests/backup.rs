@@ -0,0 +1,196 @@
+mod parse;
+use parse::Parse;
+use surrealdb::dbs::Session;
+use surrealdb::err::Error;
+use surrealdb::kvs::Datastore;
+use surrealdb::sql::Value;
+
+async fn backup(dbs: &Datastore, ns: Option<&str>, db: Option<&str>) -> Result<Vec<u8>, Error> {
+	let (snd, rcv) = surrealdb::channel::new(1);
+	let out = tokio::spawn(async move {
+		let mut out = vec![];
+		while let Ok(v) = rcv.recv().await {
+			out.extend(v);
+		}
+		out
+	});
+	dbs.backup(ns.map(String::from), db.map(String::from), snd).await?;
+	Ok(out.await.unwrap())
+}
+
+#[tokio::test]
+async fn backup_and_restore_database() -> Result<(), Error> {
+	let sql = "
+		CREATE person:tobie SET name = 'Tobie';
+		CREATE person:jaime SET name = 'Jaime';
+		DEFINE INDEX name ON person FIELDS name;
+	";
+	let dbs = Datastore::new("memory").await?;
+	let ses = Session::for_kv().with_ns("test").with_db("test");
+	dbs.execute(sql, &ses, None, false).await?;
+	// Take a snapshot of the database
+	let data = backup(&dbs, Some("test"), Some("test")).await?;
+	// Modify the database after the snapshot
+	let sql = "
+		DELETE person:tobie;
+		CREATE person:other SET name = 'Other';
+	";
+	dbs.execute(sql, &ses, None, false).await?;
+	// Restore the snapshot
+	dbs.restore(Some(String::from("test")), Some(String::from("test")), &data).await?;
+	//
+	let sql = "
+		SELECT * FROM person;
+		SELECT * FROM person WHERE name = 'Tobie';
+	";
+	let res = &mut dbs.execute(sql, &ses, None, false).await?;
+	assert_eq!(res.len(), 2);
+	//
+	let tmp = res.remove(0).result?;
+	let val = Value::parse(
+		"[
+			{ id: person:jaime, name: 'Jaime' },
+			{ id: person:tobie, name: 'Tobie' }
+		]",
+	);
+	assert_eq!(tmp, val);
+	//
+	let tmp = res.remove(0).result?;
+	let val = Value::parse("[{ id: person:tobie, name: 'Tobie' }]");
+	assert_eq!(tmp, val);
+	//
+	Ok(())
+}
+
+async fn chunks(dbs: &Datastore, db: &str) -> Result<usize, Error> {
+	let mut tx = dbs.transaction(false, false).await?;
+	let beg = format!("/*test\x00*{db}\x00*person\x00!ck").into_bytes();
+	let end = format!("/*test\x00*{db}\x00*person\x00!cl").into_bytes();
+	let res = tx.scan(beg..end, 1000).await?;
+	tx.cancel().await?;
+	Ok(res.len())
+}
+
+#[tokio::test]
+async fn backup_and_copy_chunked_records() -> Result<(), Error> {
+	let sql = "CREATE person:tobie SET bio = string::repeat('a', 1000)";
+	let dbs = Datastore::new("memory").await?.with_chunk_size(128);
+	let ses = Session::for_kv().with_ns("test").with_db("test");
+	dbs.execute(sql, &ses, None, false).await?;
+	let count = chunks(&dbs, "test").await?;
+	assert!(count >= 8);
+	// The chunks are restored as they were stored
+	let data = backup(&dbs, Some("test"), Some("test")).await?;
+	let dbs = Datastore::new("memory").await?.with_chunk_size(128);
+	dbs.restore(Some(String::from("test")), Some(String::from("test")), &data).await?;
+	assert_eq!(chunks(&dbs, "test").await?, count);
+	// The chunks are copied as they were stored
+	let sql = "
+		COPY DATABASE test TO other AS SNAPSHOT;
+		SELECT VALUE string::len(bio) FROM person:tobie;
+		DELETE person:tobie;
+		USE DB other;
+		SELECT VALUE string::len(bio) FROM person:tobie;
+		DELETE person:tobie;
+	";
+	let res = &mut dbs.execute(sql, &ses, None, false).await?;
+	assert_eq!(res.len(), 6);
+	//
+	res.remove(0).result?;
+	assert_eq!(res.remove(0).result?, Value::parse("[1000]"));
+	res.remove(0).result?;
+	res.remove(0).result?;
+	assert_eq!(res.remove(0).result?, Value::parse("[1000]"));
+	res.remove(0).result?;
+	// No chunks are left behind once the records are deleted
+	assert_eq!(chunks(&dbs, "test").await?, 0);
+	assert_eq!(chunks(&dbs, "other").await?, 0);
+	//
+	Ok(())
+}
+
+#[tokio::test]
+async fn restore_into_empty_datastore() -> Result<(), Error> {
+	let sql = "
+		USE NS test DB one; CREATE person:tobie SET name = 'Tobie';
+		USE NS test DB two; CREATE person:jaime SET name = 'Jaime';
+	";
+	let dbs = Datastore::new("memory").await?;
+	let ses = Session::for_kv();
+	dbs.execute(sql, &ses, None, false).await?;
+	// Take a snapshot of the namespace
+	let data = backup(&dbs, Some("test"), None).await?;
+	// Restore the snapshot into a new datastore
+	let dbs = Datastore::new("memory").await?;
+	dbs.restore(Some(String::from("test")), None, &data).await?;
+	//
+	let sql = "
+		USE NS test DB one; SELECT * FROM person;
+		USE NS test DB two; SELECT * FROM person;
+	";
+	let res = &mut dbs.execute(sql, &ses, None, false).await?;
+	assert_eq!(res.len(), 4);
+	//
+	res.remove(0);
+	let tmp = res.remove(0).result?;
+	let val = Value::parse("[{ id: person:tobie, name: 'Tobie' }]");
+	assert_eq!(tmp, val);
+	//
+	res.remove(0);
+	let tmp = res.remove(0).result?;
+	let val = Value::parse("[{ id: person:jaime, name: 'Jaime' }]");
+	assert_eq!(tmp, val);
+	//
+	Ok(())
+}
+
+#[tokio::test]
+async fn restore_invalid_backup() -> Result<(), Error> {
+	let dbs = Datastore::new("memory").await?;
+	let res = dbs.restore(Some(String::from("test")), None, b"SELECT * FROM person").await;
+	assert!(matches!(res, Err(Error::InvalidBackup)));
+	let res = dbs.restore(None, Some(String::from("test")), b"").await;
+	assert!(matches!(res, Err(Error::NsEmpty)));
+	Ok(())
+}
+
+#[tokio::test]
+async fn restore_in_batches() -> Result<(), Error> {
+	let sql = "CREATE |person:1..3000| SET data = string::repeat('x', 1000);";
+	let dbs = Datastore::new("memory").await?;
+	let ses = Session::for_kv().with_ns("test").with_db("test");
+	dbs.execute(sql, &ses, None, false).await?;
+	// Take a snapshot of the database
+	let data = backup(&dbs, Some("test"), Some("test")).await?;
+	// Modify the database after the snapshot
+	let sql = "
+		DELETE person:1;
+		CREATE other:1;
+	";
+	dbs.execute(sql, &ses, None, false).await?;
+	// Mark a restore which did not complete
+	let mut tx = dbs.transaction(true, false).await?;
+	tx.set(b"/!rs".to_vec(), b"/*test\x00*test\x00".to_vec()).await?;
+	tx.commit().await?;
+	assert!(dbs.restoring().await?);
+	// Restoring the snapshot again completes the restore
+	dbs.restore(Some(String::from("test")), Some(String::from("test")), &data).await?;
+	assert!(!dbs.restoring().await?);
+	//
+	let sql = "
+		SELECT count() FROM person GROUP ALL;
+		SELECT * FROM other;
+	";
+	let res = &mut dbs.execute(sql, &ses, None, false).await?;
+	assert_eq!(res.len(), 2);
+	//
+	let tmp = res.remove(0).result?;
+	let val = Value::parse("[{ count: 3000 }]");
+	assert_eq!(tmp, val);
+	//
+	let tmp = res.remove(0).result?;
+	let val = Value::parse("[]");
+	assert_eq!(tmp, val);
+	//
+	Ok(())
+}
@@ -0,0 +1,90 @@
+mod parse;
+use parse::Parse;
+use surrealdb::dbs::BatchPolicy;
+use surrealdb::dbs::Session;
+use surrealdb::err::Error;
+use surrealdb::kvs::Datastore;
+use surrealdb::sql::Value;
+
+fn statements(v: &[&str]) -> Vec<String> {
+	v.iter().map(|v| v.to_string()).collect()
+}
+
+#[tokio::test]
+async fn batch_atomic_is_rolled_back_on_error() -> Result<(), Error> {
+	let stms = statements(&["CREATE person:1", "THROW 'failed'", "CREATE person:2"]);
+	let dbs = Datastore::new("memory").await?;
+	let ses = Session::for_kv().with_ns("test").with_db("test");
+	let res = &mut dbs.batch(&stms, BatchPolicy::Atomic, &ses, None, false).await?;
+	assert_eq!(res.len(), 3);
+	//
+	let tmp = res.remove(0).result;
+	assert!(matches!(tmp, Err(Error::QueryNotExecuted)));
+	//
+	let tmp = res.remove(0).result;
+	assert!(matches!(tmp, Err(Error::Thrown(_))));
+	//
+	let tmp = res.remove(0).result;
+	assert!(matches!(tmp, Err(Error::QueryNotExecuted)));
+	//
+	let res = &mut dbs.execute("SELECT * FROM person", &ses, None, false).await?;
+	let tmp = res.remove(0).result?;
+	let val = Value::parse("[]");
+	assert_eq!(tmp, val);
+	//
+	Ok(())
+}
+
+#[tokio::test]
+async fn batch_atomic_is_not_run_with_invalid_statements() -> Result<(), Error> {
+	let stms = statements(&["CREATE person:1", "CREATE person:", "BEGIN"]);
+	let dbs = Datastore::new("memory").await?;
+	let ses = Session::for_kv().with_ns("test").with_db("test");
+	let res = &mut dbs.batch(&stms, BatchPolicy::Atomic, &ses, None, false).await?;
+	assert_eq!(res.len(), 3);
+	//
+	let tmp = res.remove(0).result;
+	assert!(matches!(tmp, Err(Error::QueryNotExecuted)));
+	//
+	let tmp = res.remove(0).result;
+	assert!(matches!(tmp, Err(Error::InvalidQuery { .. })));
+	//
+	let tmp = res.remove(0).result;
+	assert!(matches!(tmp, Err(Error::InvalidBatchStatement)));
+	//
+	Ok(())
+}
+
+#[tokio::test]
+async fn batch_continue_on_error_runs_remaining_statements() -> Result<(), Error> {
+	let stms = statements(&[
+		"CREATE person:1",
+		"CREATE person:",
+		"THROW 'failed'",
+		"LET $name = 'Tobie'",
+		"CREATE person:2 SET name = $name",
+	]);
+	let dbs = Datastore::new("memory").await?;
+	let ses = Session::for_kv().with_ns("test").with_db("test");
+	let res = &mut dbs.batch(&stms, BatchPolicy::ContinueOnError, &ses, None, false).await?;
+	assert_eq!(res.len(), 5);
+	//
+	let tmp = res.remove(0).result?;
+	let val = Value::parse("[{ id: person:1 }]");
+	assert_eq!(tmp, val);
+	//
+	let tmp = res.remove(0).result;
+	assert!(matches!(tmp, Err(Error::InvalidQuery { .. })));
+	//
+	let tmp = res.remove(0).result;
+	assert!(matches!(tmp, Err(Error::Thrown(_))));
+	//
+	let tmp = res.remove(0).result?;
+	assert_eq!(tmp, Value::None);
+	//
+	let tmp = res.remove(0).result?;
+	let val = Value::parse("[{ id: person:2, name: 'Tobie' }]");
+	assert_eq!(tmp, val);
+	//
+	Ok(())
+}
@@ -0,0 +1,82 @@
+mod parse;
+use parse::Parse;
+use surrealdb::dbs::Session;
+use surrealdb::err::Error;
+use surrealdb::kvs::Datastore;
+use surrealdb::sql::{Part, Value};
+
+#[tokio::test]
+async fn define_index_builds_in_background() -> Result<(), Error> {
+	let sql = "
+		CREATE person:tobie SET name = 'Tobie';
+		CREATE person:jaime SET name = 'Jaime';
+		CREATE person:lizzie SET name = 'Lizzie';
+		DEFINE INDEX person_name ON TABLE person COLUMNS name;
+		INFO FOR INDEX person_name ON person;
+	";
+	// Build the indexes of any table with more than one record in the background
+	let dbs = Datastore::new("memory").await?.with_index_build(Some(1), 1000);
+	let ses = Session::for_kv().with_ns("test").with_db("test");
+	let res = &mut dbs.execute(sql, &ses, None, false).await?;
+	assert_eq!(res.len(), 5);
+	//
+	for _ in 0..4 {
+		res.remove(0).result?;
+	}
+	let tmp = res.remove(0).result?;
+	assert_eq!(tmp.pick(&[Part::from("status")]), Value::from("building"));
+	assert_eq!(tmp.pick(&[Part::from("indexed")]), Value::from(0));
+	assert_eq!(tmp.pick(&[Part::from("total")]), Value::from(3));
+	// Records which are written during the build are indexed by the build
+	let sql = "
+		CREATE person:jamie SET name = 'Jamie';
+		SELECT name FROM person WHERE name = 'Jamie';
+	";
+	let res = &mut dbs.execute(sql, &ses, None, false).await?;
+	res.remove(0).result?;
+	let tmp = res.remove(0).result?;
+	assert_eq!(tmp, Value::parse("[{ name: 'Jamie' }]"));
+	// Build the index until every record has been indexed
+	let mut count = 0;
+	loop {
+		match dbs.build_indexes().await? {
+			0 => break,
+			n => count += n,
+		}
+	}
+	assert_eq!(count, 4);
+	let sql = "
+		INFO FOR INDEX person_name ON person;
+		SELECT name FROM person WHERE name = 'Jamie' EXPLAIN;
+	";
+	let res = &mut dbs.execute(sql, &ses, None, false).await?;
+	let tmp = res.remove(0).result?;
+	assert_eq!(tmp, Value::parse("{ status: 'ready' }"));
+	let tmp = res.remove(0).result?;
+	let val = Value::parse(
+		"[
+			{
+				name: 'Jamie'
+			},
+			{
+				explain:
+				[
+					{
+						detail: {
+							plan: {
+								index: 'person_name',
+								operator: '=',
+								value: 'Jamie'
+							},
+							table: 'person',
+						},
+						operation: 'Iterate Index'
+					}
+				]
+			}
+		]",
+	);
+	assert_eq!(tmp, val);
+	//
+	Ok(())
+}
@@ -1,5 +1,6 @@
 mod parse;
 use parse::Parse;
+use std::time::Duration;
 use surrealdb::dbs::Session;
 use surrealdb::err::Error;
 use surrealdb::kvs::Datastore;
@@ -135,3 +136,50 @@ async fn clear_transaction_cache_field() -> Result<(), Error> {
 	//
 	Ok(())
 }
+
+#[tokio::test]
+async fn select_results_are_cached_until_written() -> Result<(), Error> {
+	let dbs = Datastore::new("memory").await?.with_result_cache(100, Duration::from_secs(60));
+	let ses = Session::for_kv().with_ns("test").with_db("test");
+	let sql = "
+		CREATE person:one SET x = 0;
+		SELECT * FROM person;
+		SELECT * FROM person;
+	";
+	let res = &mut dbs.execute(sql, &ses, None, false).await?;
+	assert_eq!(res.len(), 3);
+	assert_eq!(dbs.result_cache().len(), 1);
+	//
+	let _ = res.remove(0).result?;
+	for _ in 0..2 {
+		let tmp = res.remove(0).result?;
+		let val = Value::parse("[{ id: person:one, x: 0 }]");
+		assert_eq!(tmp, val);
+	}
+	// Writing to the table invalidates the results
+	let sql = "UPDATE person:one SET x = 1;";
+	dbs.execute(sql, &ses, None, false).await?;
+	assert!(dbs.result_cache().is_empty());
+	//
+	let sql = "
+		SELECT * FROM person;
+		SELECT *, rand::float() AS r FROM person;
+		BEGIN;
+		SELECT x FROM person;
+		COMMIT;
+	";
+	let res = &mut dbs.execute(sql, &ses, None, false).await?;
+	assert_eq!(res.len(), 3);
+	// Only the first statement is cached
+	assert_eq!(dbs.result_cache().len(), 1);
+	//
+	let tmp = res.remove(0).result?;
+	let val = Value::parse("[{ id: person:one, x: 1 }]");
+	assert_eq!(tmp, val);
+	// Changing the definitions clears the cache
+	let sql = "DEFINE TABLE person PERMISSIONS NONE;";
+	dbs.execute(sql, &ses, None, false).await?;
+	assert!(dbs.result_cache().is_empty());
+	//
+	Ok(())
+}
@@ -0,0 +1,153 @@
+mod parse;
+use parse::Parse;
+use surrealdb::changes::Action;
+use surrealdb::dbs::Session;
+use surrealdb::err::Error;
+use surrealdb::kvs::Datastore;
+use surrealdb::sql::Cond;
+use surrealdb::sql::Value;
+
+#[tokio::test]
+async fn changes_are_decoded_in_order() -> Result<(), Error> {
+	let sql = "
+		CREATE person:tobie SET name = 'Tobie';
+		UPDATE person:tobie SET age = 18;
+		DELETE person:tobie;
+	";
+	let dbs = Datastore::new("memory").await?;
+	let chn = dbs.changes().await;
+	let ses = Session::for_kv().with_ns("test").with_db("test");
+	let res = &mut dbs.execute(sql, &ses, None, false).await?;
+	assert_eq!(res.len(), 3);
+	//
+	let tmp = chn.recv().await.unwrap();
+	assert_eq!(tmp.seq, 1);
+	assert_eq!(tmp.action(), Action::Create);
+	assert_eq!(tmp.ns, "test");
+	assert_eq!(tmp.db, "test");
+	assert_eq!(tmp.tb, "person");
+	assert_eq!(tmp.id.to_string(), "person:tobie");
+	assert_eq!(tmp.before, Value::None);
+	assert_eq!(tmp.after, Value::parse("{ id: person:tobie, name: 'Tobie' }"));
+	//
+	let tmp = chn.recv().await.unwrap();
+	assert_eq!(tmp.seq, 2);
+	assert_eq!(tmp.action(), Action::Update);
+	assert_eq!(tmp.before, Value::parse("{ id: person:tobie, name: 'Tobie' }"));
+	assert_eq!(tmp.after, Value::parse("{ age: 18, id: person:tobie, name: 'Tobie' }"));
+	//
+	let tmp = chn.recv().await.unwrap();
+	assert_eq!(tmp.seq, 3);
+	assert_eq!(tmp.action(), Action::Delete);
+	assert_eq!(tmp.before, Value::parse("{ age: 18, id: person:tobie, name: 'Tobie' }"));
+	assert_eq!(tmp.after, Value::None);
+	//
+	assert!(chn.is_empty());
+	//
+	Ok(())
+}
+
+#[tokio::test]
+async fn changes_are_not_published_on_cancel() -> Result<(), Error> {
+	let sql = "
+		BEGIN TRANSACTION;
+		CREATE person:tobie SET name = 'Tobie';
+		CANCEL TRANSACTION;
+		CREATE person:jaime SET name = 'Jaime';
+	";
+	let dbs = Datastore::new("memory").await?;
+	let chn = dbs.changes().await;
+	let ses = Session::for_kv().with_ns("test").with_db("test");
+	dbs.execute(sql, &ses, None, false).await?;
+	//
+	let tmp = chn.recv().await.unwrap();
+	assert_eq!(tmp.seq, 1);
+	assert_eq!(tmp.id.to_string(), "person:jaime");
+	//
+	assert!(chn.is_empty());
+	//
+	Ok(())
+}
+
+#[tokio::test]
+async fn changes_are_resumed_from_history() -> Result<(), Error> {
+	let sql = "
+		CREATE person:tobie SET name = 'Tobie';
+		CREATE person:jaime SET name = 'Jaime';
+		CREATE person:john SET name = 'John';
+	";
+	let dbs = Datastore::new("memory").await?.with_history(2);
+	let ses = Session::for_kv().with_ns("test").with_db("test");
+	dbs.execute(sql, &ses, None, false).await?;
+	// The changes after the second change are retained
+	let (tmp, _) = dbs.changes_since(2).await;
+	let tmp = tmp.unwrap();
+	assert_eq!(tmp.len(), 1);
+	assert_eq!(tmp[0].seq, 3);
+	assert_eq!(tmp[0].id.to_string(), "person:john");
+	// The changes after the first change are retained
+	let (tmp, _) = dbs.changes_since(1).await;
+	assert_eq!(tmp.unwrap().len(), 2);
+	// The first change is no longer retained
+	let (tmp, _) = dbs.changes_since(0).await;
+	assert!(tmp.is_none());
+	// Changes which have not happened yet can not be resumed from
+	let (tmp, _) = dbs.changes_since(4).await;
+	assert!(tmp.is_none());
+	// Subsequent changes are received after the retained changes
+	let (tmp, chn) = dbs.changes_since(3).await;
+	assert_eq!(tmp.unwrap().len(), 0);
+	dbs.execute("DELETE person:tobie", &ses, None, false).await?;
+	let tmp = chn.recv().await.unwrap();
+	assert_eq!(tmp.seq, 4);
+	assert_eq!(tmp.action(), Action::Delete);
+	//
+	Ok(())
+}
+
+#[tokio::test]
+async fn changes_are_selected_with_permissions() -> Result<(), Error> {
+	let sql = "
+		DEFINE TABLE person SCHEMALESS PERMISSIONS FOR select WHERE public = true;
+		CREATE person:tobie SET name = 'Tobie', public = true, age = 18;
+		CREATE person:jaime SET name = 'Jaime', public = false, age = 18;
+		CREATE person:john SET name = 'John', public = true, age = 12;
+		DELETE person:tobie, person:jaime;
+	";
+	let dbs = Datastore::new("memory").await?;
+	let chn = dbs.changes().await;
+	let ses = Session::for_kv().with_ns("test").with_db("test");
+	let res = &mut dbs.execute(sql, &ses, None, false).await?;
+	assert_eq!(res.len(), 5);
+	//
+	let mut changes = vec![];
+	while let Ok(v) = chn.try_recv() {
+		changes.push(v);
+	}
+	assert_eq!(changes.len(), 5);
+	// Only the selectable records which match the condition are returned
+	let ses = Session::for_sc("test", "test", "test");
+	let cond = Cond(Value::parse("age >= 18"));
+	let mut out = vec![];
+	for change in changes.iter() {
+		if let Some(v) = dbs.select_change(&ses, change, Some(&cond)).await? {
+			out.push((change.action(), v));
+		}
+	}
+	assert_eq!(
+		out,
+		vec![
+			(
+				Action::Create,
+				Value::parse("{ age: 18, id: person:tobie, name: 'Tobie', public: true }")
+			),
+			(Action::Delete, Value::parse("person:tobie")),
+		]
+	);
+	// The changes to other databases are not returned
+	let ses = Session::for_kv().with_ns("test").with_db("other");
+	let tmp = dbs.select_change(&ses, &changes[0], None).await?;
+	assert_eq!(tmp, None);
+	//
+	Ok(())
+}
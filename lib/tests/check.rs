@@ -0,0 +1,84 @@
+mod parse;
+use parse::Parse;
+use surrealdb::dbs::Session;
+use surrealdb::err::Error;
+use surrealdb::kvs::Datastore;
+use surrealdb::sql::{Thing, Value};
+
+#[tokio::test]
+async fn check_and_rebuild_index() -> Result<(), Error> {
+	let sql = "
+		CREATE person:tobie SET name = 'Tobie';
+		CREATE person:jaime SET name = 'Jaime';
+		CREATE person:lizzie SET name = 'Lizzie';
+		DEFINE INDEX person_name ON TABLE person COLUMNS name;
+		CHECK INDEX person_name ON person;
+	";
+	let dbs = Datastore::new("memory").await?;
+	let ses = Session::for_kv().with_ns("test").with_db("test");
+	let res = &mut dbs.execute(sql, &ses, None, false).await?;
+	assert_eq!(res.len(), 5);
+	//
+	for _ in 0..4 {
+		res.remove(0).result?;
+	}
+	let tmp = res.remove(0).result?;
+	let val = Value::parse("{ records: 3, entries: 3, missing: [], orphans: [] }");
+	assert_eq!(tmp, val);
+	// Corrupt the index entries, which are ordered by the indexed values
+	let mut tx = dbs.transaction(true, false).await?;
+	let beg = b"/*test\x00*test\x00*person\x00\xa4person_name\x00*".to_vec();
+	let end = b"/*test\x00*test\x00*person\x00\xa4person_name\x00*\xff".to_vec();
+	let res = tx.scan(beg..end, 10).await?;
+	assert_eq!(res.len(), 3);
+	tx.del(res[0].0.clone()).await?;
+	tx.set(res[1].0.clone(), Vec::<u8>::from(Thing::from(("person", "missing")))).await?;
+	tx.commit().await?;
+	// The corrupted entries are reported
+	let sql = "
+		CHECK INDEX person_name ON person;
+		REBUILD INDEX person_name ON person;
+		CHECK INDEX person_name ON person;
+		SELECT id FROM person WHERE name = 'Jaime';
+	";
+	let res = &mut dbs.execute(sql, &ses, None, false).await?;
+	assert_eq!(res.len(), 4);
+	//
+	let tmp = res.remove(0).result?;
+	let val = Value::parse(
+		"{
+			records: 3,
+			entries: 2,
+			missing: [
+				{ id: person:jaime, value: ['Jaime'] },
+				{ id: person:lizzie, value: ['Lizzie'] },
+			],
+			orphans: [
+				{ id: person:missing, value: ['Lizzie'] },
+			],
+		}",
+	);
+	assert_eq!(tmp, val);
+	// The rebuilt index is consistent again
+	res.remove(0).result?;
+	let tmp = res.remove(0).result?;
+	let val = Value::parse("{ records: 3, entries: 3, missing: [], orphans: [] }");
+	assert_eq!(tmp, val);
+	let tmp = res.remove(0).result?;
+	let val = Value::parse("[{ id: person:jaime }]");
+	assert_eq!(tmp, val);
+	//
+	Ok(())
+}
+
+#[tokio::test]
+async fn check_missing_index() -> Result<(), Error> {
+	let dbs = Datastore::new("memory").await?;
+	let ses = Session::for_kv().with_ns("test").with_db("test");
+	let sql = "CHECK INDEX person_name ON person; REBUILD INDEX person_name ON person;";
+	let res = &mut dbs.execute(sql, &ses, None, false).await?;
+	assert!(matches!(res.remove(0).result, Err(Error::IxNotFound { .. })));
+	assert!(matches!(res.remove(0).result, Err(Error::IxNotFound { .. })));
+	//
+	Ok(())
+}
@@ -60,3 +60,39 @@ async fn compare_empty() -> Result<(), Error> {
 	//
 	Ok(())
 }
+
+#[tokio::test]
+async fn compare_strict_types() -> Result<(), Error> {
+	let sql = "
+		CREATE person:tobie SET age = 30;
+		SELECT VALUE id FROM person WHERE age = '30';
+		OPTION STRICT_TYPES;
+		SELECT VALUE id FROM person WHERE age = '30';
+		SELECT VALUE id FROM person WHERE age < 30.5;
+		SELECT VALUE id FROM person WHERE name = NONE;
+		RETURN <int> '30' = 30;
+	";
+	let dbs = Datastore::new("memory").await?;
+	let ses = Session::for_kv().with_ns("test").with_db("test");
+	let res = &mut dbs.execute(sql, &ses, None, false).await?;
+	assert_eq!(res.len(), 6);
+	//
+	res.remove(0).result?;
+	// Values of different types are compared as unequal by default
+	let tmp = res.remove(0).result?;
+	assert_eq!(tmp, Value::from(Vec::<Value>::new()));
+	// Comparing values of different types fails with strict types
+	let tmp = res.remove(0).result;
+	assert!(matches!(tmp, Err(Error::TryCompare(..))));
+	// Numbers and missing values can still be compared
+	let tmp = res.remove(0).result?;
+	assert_eq!(tmp.to_string(), "[person:tobie]");
+	let tmp = res.remove(0).result?;
+	assert_eq!(tmp.to_string(), "[person:tobie]");
+	//
+	let tmp = res.remove(0).result?;
+	let val = Value::Bool(true);
+	assert_eq!(tmp, val);
+	//
+	Ok(())
+}
@@ -0,0 +1,76 @@
+mod parse;
+use parse::Parse;
+use surrealdb::dbs::Session;
+use surrealdb::err::Error;
+use surrealdb::kvs::Datastore;
+use surrealdb::sql::Value;
+
+async fn chunks(dbs: &Datastore, tb: &str) -> Result<usize, Error> {
+	let mut tx = dbs.transaction(false, false).await?;
+	let key = [&b"/*test\x00*test\x00*"[..], tb.as_bytes(), b"\x00!c"].concat();
+	let beg = [&key[..], b"k"].concat();
+	let end = [&key[..], b"l"].concat();
+	let res = tx.scan(beg..end, 1000).await?;
+	tx.cancel().await?;
+	Ok(res.len())
+}
+
+#[tokio::test]
+async fn compressed_tables() -> Result<(), Error> {
+	let sql = "
+		DEFINE TABLE snappy COMPRESSION SNAPPY;
+		DEFINE TABLE zstd COMPRESSION ZSTD;
+		CREATE plain:test SET text = string::repeat('surrealdb ', 100);
+		CREATE snappy:test SET text = string::repeat('surrealdb ', 100);
+		CREATE zstd:test SET text = string::repeat('surrealdb ', 100);
+		SELECT VALUE string::len(text) FROM plain, snappy, zstd;
+	";
+	let dbs = Datastore::new("memory").await?.with_chunk_size(256);
+	let ses = Session::for_kv().with_ns("test").with_db("test");
+	let res = &mut dbs.execute(sql, &ses, None, false).await?;
+	assert_eq!(res.len(), 6);
+	//
+	for _ in 0..5 {
+		res.remove(0).result?;
+	}
+	let tmp = res.remove(0).result?;
+	assert_eq!(tmp, Value::parse("[1000, 1000, 1000]"));
+	// The compressed records are small enough to be stored in a single key
+	assert!(chunks(&dbs, "plain").await? > 0);
+	assert_eq!(chunks(&dbs, "snappy").await?, 0);
+	assert_eq!(chunks(&dbs, "zstd").await?, 0);
+	//
+	Ok(())
+}
+
+#[tokio::test]
+async fn compressed_tables_read_existing_records() -> Result<(), Error> {
+	let sql = "
+		CREATE article:one SET text = string::repeat('a', 1000);
+		DEFINE TABLE article COMPRESSION SNAPPY;
+		CREATE article:two SET text = string::repeat('b', 1000);
+		DEFINE TABLE article COMPRESSION ZSTD;
+		CREATE article:three SET text = string::repeat('c', 1000);
+		UPDATE article:one SET text = string::repeat('d', 100);
+		SELECT id, string::len(text) AS len FROM article;
+	";
+	let dbs = Datastore::new("memory").await?;
+	let ses = Session::for_kv().with_ns("test").with_db("test");
+	let res = &mut dbs.execute(sql, &ses, None, false).await?;
+	assert_eq!(res.len(), 7);
+	//
+	for _ in 0..6 {
+		res.remove(0).result?;
+	}
+	let tmp = res.remove(0).result?;
+	let val = Value::parse(
+		"[
+			{ id: article:one, len: 100 },
+			{ id: article:three, len: 1000 },
+			{ id: article:two, len: 1000 }
+		]",
+	);
+	assert_eq!(tmp, val);
+	//
+	Ok(())
+}
@@ -0,0 +1,221 @@
+mod parse;
+use parse::Parse;
+use surrealdb::dbs::Session;
+use surrealdb::err::Error;
+use surrealdb::kvs::Datastore;
+use surrealdb::sql::Value;
+
+#[tokio::test]
+async fn copy_database_snapshot() -> Result<(), Error> {
+	let sql = "
+		DEFINE TABLE person SCHEMALESS;
+		DEFINE INDEX name ON person FIELDS name UNIQUE;
+		CREATE person:tobie SET name = 'Tobie';
+		COPY DATABASE test TO preview AS SNAPSHOT;
+		CREATE person:jaime SET name = 'Jaime';
+	";
+	let dbs = Datastore::new("memory").await?;
+	let ses = Session::for_kv().with_ns("test").with_db("test");
+	let res = &mut dbs.execute(&sql, &ses, None, false).await?;
+	assert_eq!(res.len(), 5);
+	//
+	for _ in 0..5 {
+		let tmp = res.remove(0).result;
+		assert!(tmp.is_ok());
+	}
+	// The copy contains the data at the time of the snapshot
+	let sql = "
+		SELECT name FROM person;
+		CREATE person:other SET name = 'Tobie';
+		INFO FOR TABLE person;
+	";
+	let ses = Session::for_kv().with_ns("test").with_db("preview");
+	let res = &mut dbs.execute(&sql, &ses, None, false).await?;
+	assert_eq!(res.len(), 3);
+	//
+	let tmp = res.remove(0).result?;
+	let val = Value::parse(
+		"[
+			{
+				name: 'Tobie'
+			}
+		]",
+	);
+	assert_eq!(tmp, val);
+	//
+	let tmp = res.remove(0).result;
+	assert!(matches!(tmp, Err(Error::IndexExists { .. })));
+	//
+	let tmp = res.remove(0).result?;
+	let val = Value::parse(
+		"{
+			count: 1,
+			events: {},
+			fields: {},
+			tables: {},
+			indexes: { name: 'DEFINE INDEX name ON person FIELDS name UNIQUE' },
+		}",
+	);
+	assert_eq!(tmp, val);
+	//
+	Ok(())
+}
+
+#[tokio::test]
+async fn copy_database_schema() -> Result<(), Error> {
+	let sql = "
+		DEFINE TABLE person SCHEMAFULL;
+		DEFINE FIELD name ON person TYPE string;
+		DEFINE INDEX name ON person FIELDS name UNIQUE;
+		DEFINE PARAM $plan VALUE 'free';
+		CREATE person:tobie SET name = 'Tobie';
+		COPY DATABASE test TO tenant;
+	";
+	let dbs = Datastore::new("memory").await?;
+	let ses = Session::for_kv().with_ns("test").with_db("test");
+	let res = &mut dbs.execute(&sql, &ses, None, false).await?;
+	assert_eq!(res.len(), 6);
+	//
+	for _ in 0..6 {
+		let tmp = res.remove(0).result;
+		assert!(tmp.is_ok());
+	}
+	// The copy contains the definitions without the data
+	let sql = "
+		SELECT name FROM person;
+		RETURN $plan;
+		CREATE person:tobie SET name = 'Tobie';
+		CREATE person:other SET name = 'Tobie';
+		CREATE person:jaime SET name = 100;
+	";
+	let ses = Session::for_kv().with_ns("test").with_db("tenant");
+	let res = &mut dbs.execute(&sql, &ses, None, false).await?;
+	assert_eq!(res.len(), 5);
+	//
+	let tmp = res.remove(0).result?;
+	let val = Value::parse("[]");
+	assert_eq!(tmp, val);
+	//
+	let tmp = res.remove(0).result?;
+	let val = Value::from("free");
+	assert_eq!(tmp, val);
+	//
+	let tmp = res.remove(0).result;
+	assert!(tmp.is_ok());
+	//
+	let tmp = res.remove(0).result;
+	assert!(matches!(tmp, Err(Error::IndexExists { .. })));
+	//
+	let tmp = res.remove(0).result;
+	assert!(matches!(tmp, Err(Error::FieldCheck { .. })));
+	//
+	Ok(())
+}
+
+#[tokio::test]
+async fn copy_database_errors() -> Result<(), Error> {
+	let sql = "
+		DEFINE DATABASE preview;
+		COPY DATABASE missing TO other AS SNAPSHOT;
+		COPY DATABASE test TO preview AS SNAPSHOT;
+	";
+	let dbs = Datastore::new("memory").await?;
+	let ses = Session::for_kv().with_ns("test").with_db("test");
+	let res = &mut dbs.execute(&sql, &ses, None, false).await?;
+	assert_eq!(res.len(), 3);
+	//
+	let tmp = res.remove(0).result;
+	assert!(tmp.is_ok());
+	//
+	let tmp = res.remove(0).result;
+	assert!(matches!(
+		tmp.err(),
+		Some(e) if e.to_string() == "The database 'missing' does not exist"
+	));
+	//
+	let tmp = res.remove(0).result;
+	assert!(matches!(
+		tmp.err(),
+		Some(e) if e.to_string() == "The database 'preview' already exists"
+	));
+	// Database users can not copy databases
+	let sql = "COPY DATABASE test TO branch AS SNAPSHOT;";
+	let ses = Session::for_db("test", "test");
+	let res = &mut dbs.execute(&sql, &ses, None, false).await?;
+	assert_eq!(res.len(), 1);
+	let tmp = res.remove(0).result;
+	assert!(matches!(tmp, Err(Error::QueryPermissions)));
+	//
+	Ok(())
+}
+
+#[tokio::test]
+async fn copy_database_in_batches() -> Result<(), Error> {
+	let sql = "
+		CREATE |person:1..3000| SET data = string::repeat('x', 1000);
+		COPY DATABASE test TO preview AS SNAPSHOT;
+	";
+	let dbs = Datastore::new("memory").await?;
+	let ses = Session::for_kv().with_ns("test").with_db("test");
+	let res = &mut dbs.execute(&sql, &ses, None, false).await?;
+	assert_eq!(res.len(), 2);
+	//
+	for _ in 0..2 {
+		let _ = res.remove(0).result?;
+	}
+	// The copy contains every record, across several batches
+	let sql = "SELECT count() FROM person GROUP ALL;";
+	let ses = Session::for_kv().with_ns("test").with_db("preview");
+	let res = &mut dbs.execute(&sql, &ses, None, false).await?;
+	assert_eq!(res.len(), 1);
+	//
+	let tmp = res.remove(0).result?;
+	let val = Value::parse("[{ count: 3000 }]");
+	assert_eq!(tmp, val);
+	//
+	Ok(())
+}
+
+#[tokio::test]
+async fn copy_database_namespace_limits() -> Result<(), Error> {
+	let sql = "
+		DEFINE NAMESPACE test LIMIT RECORDS 3;
+		CREATE person:1;
+		CREATE person:2;
+		COPY DATABASE test TO preview AS SNAPSHOT;
+		COPY DATABASE test TO tenant;
+		INFO FOR NS;
+	";
+	let dbs = Datastore::new("memory").await?;
+	let ses = Session::for_kv().with_ns("test").with_db("test");
+	let res = &mut dbs.execute(&sql, &ses, None, false).await?;
+	assert_eq!(res.len(), 6);
+	//
+	for _ in 0..3 {
+		let _ = res.remove(0).result?;
+	}
+	// The copied records would exceed the limits of the namespace
+	let tmp = res.remove(0).result;
+	assert!(matches!(
+		tmp,
+		Err(Error::NsLimitExceeded {
+			kind: "records",
+			limit: 3,
+			..
+		})
+	));
+	// Copying the definitions does not add any records
+	let _ = res.remove(0).result?;
+	//
+	let tmp = res.remove(0).result?;
+	let Value::Object(info) = tmp else {
+		panic!("INFO FOR NS should return an object");
+	};
+	let Some(Value::Object(dbs)) = info.get("databases") else {
+		panic!("INFO FOR NS should return the databases");
+	};
+	assert!(dbs.contains_key("tenant"));
+	assert!(!dbs.contains_key("preview"));
+	//
+	Ok(())
+}
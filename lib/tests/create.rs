@@ -100,3 +100,177 @@ async fn create_with_id() -> Result<(), Error> {
 	//
 	Ok(())
 }
+
+#[tokio::test]
+async fn create_unique_within_window() -> Result<(), Error> {
+	let sql = "
+		CREATE person:one CONTENT { name: 'Tobie' } UNIQUE WITHIN 1h;
+		CREATE person:two CONTENT { name: 'Tobie' } UNIQUE WITHIN 1h;
+		CREATE person:three CONTENT { name: 'Jaime' } UNIQUE WITHIN 1h;
+		CREATE person:four CONTENT { name: 'Tobie' };
+		DELETE person:one;
+		CREATE person:five CONTENT { name: 'Tobie' } UNIQUE WITHIN 1h;
+		SELECT * FROM person;
+	";
+	let dbs = Datastore::new("memory").await?;
+	let ses = Session::for_kv().with_ns("test").with_db("test");
+	let res = &mut dbs.execute(sql, &ses, None, false).await?;
+	assert_eq!(res.len(), 7);
+	//
+	let tmp = res.remove(0).result?;
+	let val = Value::parse("[{ id: person:one, name: 'Tobie' }]");
+	assert_eq!(tmp, val);
+	//
+	let tmp = res.remove(0).result;
+	assert!(matches!(tmp, Err(Error::DuplicateRecord)));
+	//
+	let tmp = res.remove(0).result?;
+	let val = Value::parse("[{ id: person:three, name: 'Jaime' }]");
+	assert_eq!(tmp, val);
+	//
+	let tmp = res.remove(0).result?;
+	let val = Value::parse("[{ id: person:four, name: 'Tobie' }]");
+	assert_eq!(tmp, val);
+	//
+	let tmp = res.remove(0).result;
+	assert!(tmp.is_ok());
+	//
+	let tmp = res.remove(0).result?;
+	let val = Value::parse("[{ id: person:five, name: 'Tobie' }]");
+	assert_eq!(tmp, val);
+	//
+	let tmp = res.remove(0).result?;
+	let val = Value::parse(
+		"[
+			{ id: person:five, name: 'Tobie' },
+			{ id: person:four, name: 'Tobie' },
+			{ id: person:three, name: 'Jaime' }
+		]",
+	);
+	assert_eq!(tmp, val);
+	//
+	Ok(())
+}
+
+#[tokio::test]
+async fn create_unique_hashes_are_removed() -> Result<(), Error> {
+	let dbs = Datastore::new("memory").await?;
+	let ses = Session::for_kv().with_ns("test").with_db("test");
+	let sql = "CREATE person:one CONTENT { name: 'Tobie' } UNIQUE WITHIN 1ms";
+	dbs.execute(sql, &ses, None, false).await?;
+	tokio::time::sleep(std::time::Duration::from_millis(10)).await;
+	// The expired hash is removed when another record is created,
+	// and the hash of a deleted record is removed with the record,
+	// even if its content was changed by the fields of the table
+	let sql = "
+		DEFINE FIELD name ON person VALUE string::lowercase($value);
+		CREATE person:two CONTENT { name: 'Jaime' } UNIQUE WITHIN 1h;
+		DELETE person:two;
+	";
+	let res = &mut dbs.execute(sql, &ses, None, false).await?;
+	assert_eq!(res.len(), 3);
+	assert!(res.remove(0).result.is_ok());
+	assert!(res.remove(0).result.is_ok());
+	assert!(res.remove(0).result.is_ok());
+	// Only the expiry of the deleted hash is left, until it expires
+	let mut tx = dbs.transaction(false, false).await?;
+	let keys = tx.scan(vec![0u8]..vec![0xffu8], 1000).await?;
+	tx.cancel().await?;
+	let count = |tag: &[u8]| keys.iter().filter(|(k, _)| k.windows(3).any(|v| v == tag)).count();
+	assert_eq!(count(b"!dh"), 0);
+	assert_eq!(count(b"!de"), 1);
+	//
+	Ok(())
+}
+
+#[tokio::test]
+async fn create_unique_within_window_permissions() -> Result<(), Error> {
+	let sql = "
+		DEFINE TABLE person SCHEMALESS PERMISSIONS FOR create FULL, FOR select NONE;
+		DEFINE TABLE secret SCHEMALESS PERMISSIONS NONE;
+		CREATE person:one CONTENT { name: 'Tobie' };
+		CREATE secret:one CONTENT { name: 'Tobie' } UNIQUE WITHIN 1h;
+	";
+	let dbs = Datastore::new("memory").await?;
+	let ses = Session::for_kv().with_ns("test").with_db("test");
+	let res = &mut dbs.execute(sql, &ses, None, false).await?;
+	assert_eq!(res.len(), 4);
+	//
+	for _ in 0..4 {
+		let tmp = res.remove(0).result;
+		assert!(tmp.is_ok());
+	}
+	// Permissions are checked before the content is compared
+	let sql = "
+		CREATE secret:two CONTENT { name: 'Tobie' } UNIQUE WITHIN 1h;
+		CREATE person:two CONTENT { name: 'Tobie' } UNIQUE WITHIN 1h RETURN NONE;
+		CREATE person:three CONTENT { name: 'Tobie' } UNIQUE WITHIN 1h RETURN NONE;
+	";
+	let ses = Session::for_sc("test", "test", "test");
+	let res = &mut dbs.execute(sql, &ses, None, false).await?;
+	assert_eq!(res.len(), 3);
+	//
+	let tmp = res.remove(0).result?;
+	let val = Value::parse("[]");
+	assert_eq!(tmp, val);
+	//
+	let tmp = res.remove(0).result?;
+	let val = Value::parse("[]");
+	assert_eq!(tmp, val);
+	// The error does not reveal the existing record
+	let tmp = res.remove(0).result;
+	assert!(matches!(tmp, Err(Error::DuplicateRecord)));
+	assert!(!tmp.unwrap_err().to_string().contains("person:two"));
+	//
+	Ok(())
+}
+
+#[tokio::test]
+async fn create_with_table_id_generator() -> Result<(), Error> {
+	let sql = "
+		DEFINE SEQUENCE invoice START 1000;
+		DEFINE TABLE invoice ID SEQUENCE invoice;
+		DEFINE TABLE event ID ULID;
+		DEFINE TABLE session ID UUID;
+		CREATE invoice SET total = 10;
+		CREATE invoice CONTENT { total: 20 };
+		INSERT INTO invoice { total: 30 };
+		CREATE invoice:manual SET total = 40;
+		CREATE event, session;
+		SELECT VALUE string::len(meta::id(id)) FROM event, session;
+	";
+	let dbs = Datastore::new("memory").await?;
+	let ses = Session::for_kv().with_ns("test").with_db("test");
+	let res = &mut dbs.execute(sql, &ses, None, false).await?;
+	assert_eq!(res.len(), 10);
+	//
+	for _ in 0..4 {
+		let tmp = res.remove(0).result;
+		assert!(tmp.is_ok());
+	}
+	//
+	let tmp = res.remove(0).result?;
+	let val = Value::parse("[{ id: invoice:1000, total: 10 }]");
+	assert_eq!(tmp, val);
+	//
+	let tmp = res.remove(0).result?;
+	let val = Value::parse("[{ id: invoice:1001, total: 20 }]");
+	assert_eq!(tmp, val);
+	//
+	let tmp = res.remove(0).result?;
+	let val = Value::parse("[{ id: invoice:1002, total: 30 }]");
+	assert_eq!(tmp, val);
+	// A specified record id does not use the sequence
+	let tmp = res.remove(0).result?;
+	let val = Value::parse("[{ id: invoice:manual, total: 40 }]");
+	assert_eq!(tmp, val);
+	//
+	let tmp = res.remove(0).result;
+	assert!(tmp.is_ok());
+	//
+	let tmp = res.remove(0).result?;
+	let val = Value::parse("[26, 36]");
+	assert_eq!(tmp, val);
+	//
+	Ok(())
+}
@@ -43,3 +43,45 @@ async fn datetimes_conversion() -> Result<(), Error> {
 	//
 	Ok(())
 }
+
+#[tokio::test]
+async fn datetimes_arithmetic() -> Result<(), Error> {
+	let sql = r#"
+		RETURN <datetime> "2012-01-01T08:00:00Z" + 1d;
+		RETURN 1w + <datetime> "2012-01-01T08:00:00Z";
+		RETURN <datetime> "2012-01-01T08:00:00Z" - 1h;
+		RETURN <datetime> "2012-01-02T08:00:00Z" - <datetime> "2012-01-01T08:00:00Z";
+		RETURN <datetime> "2012-01-01T08:00:00Z" + 10000000y;
+	"#;
+	let dbs = Datastore::new("memory").await?;
+	let ses = Session::for_kv().with_ns("test").with_db("test");
+	let res = &mut dbs.execute(&sql, &ses, None, false).await?;
+	assert_eq!(res.len(), 5);
+	//
+	let tmp = res.remove(0).result?;
+	let val = Value::parse("'2012-01-02T08:00:00Z'");
+	assert_eq!(tmp, val);
+	//
+	let tmp = res.remove(0).result?;
+	let val = Value::parse("'2012-01-08T08:00:00Z'");
+	assert_eq!(tmp, val);
+	//
+	let tmp = res.remove(0).result?;
+	let val = Value::parse("'2012-01-01T07:00:00Z'");
+	assert_eq!(tmp, val);
+	//
+	let tmp = res.remove(0).result?;
+	let val = Value::parse("1d");
+	assert_eq!(tmp, val);
+	//
+	let tmp = res.remove(0).result;
+	assert!(
+		matches!(
+			&tmp,
+			Err(Error::TryAdd(a, b)) if a == "2012-01-01T08:00:00Z" && b == "10000000y"
+		),
+		"{tmp:?}"
+	);
+	//
+	Ok(())
+}
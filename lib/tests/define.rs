@@ -51,6 +51,12 @@ async fn define_statement_database() -> Result<(), Error> {
 			databases: { test: 'DEFINE DATABASE test' },
 			logins: {},
 			tokens: {},
+			usage: {
+				bytes: 0,
+				databases: { test: { bytes: 0, queries: 2, records: 0 } },
+				queries: 2,
+				records: 0,
+			},
 		}",
 	);
 	assert_eq!(tmp, val);
@@ -85,6 +91,7 @@ async fn define_statement_function() -> Result<(), Error> {
 			scopes: {},
 			params: {},
 			scopes: {},
+			sequences: {},
 			tables: {},
 		}",
 	);
@@ -116,6 +123,7 @@ async fn define_statement_table_drop() -> Result<(), Error> {
 			functions: {},
 			params: {},
 			scopes: {},
+			sequences: {},
 			tables: { test: 'DEFINE TABLE test DROP SCHEMALESS' },
 		}",
 	);
@@ -147,6 +155,7 @@ async fn define_statement_table_schemaless() -> Result<(), Error> {
 			functions: {},
 			params: {},
 			scopes: {},
+			sequences: {},
 			tables: { test: 'DEFINE TABLE test SCHEMALESS' },
 		}",
 	);
@@ -182,6 +191,7 @@ async fn define_statement_table_schemafull() -> Result<(), Error> {
 			functions: {},
 			params: {},
 			scopes: {},
+			sequences: {},
 			tables: { test: 'DEFINE TABLE test SCHEMAFULL' },
 		}",
 	);
@@ -213,6 +223,7 @@ async fn define_statement_table_schemaful() -> Result<(), Error> {
 			functions: {},
 			params: {},
 			scopes: {},
+			sequences: {},
 			tables: { test: 'DEFINE TABLE test SCHEMAFULL' },
 		}",
 	);
@@ -221,6 +232,138 @@ async fn define_statement_table_schemaful() -> Result<(), Error> {
 	Ok(())
 }
 
+#[tokio::test]
+async fn define_statement_table_audit() -> Result<(), Error> {
+	let sql = "
+		DEFINE TABLE test AUDIT ALL INTO audit_log;
+		CREATE test:tobie SET name = 'Tobie';
+		SELECT * FROM test;
+		DELETE test:tobie;
+		SELECT action, record, table FROM audit_log ORDER BY at;
+		INFO FOR DB;
+	";
+	let dbs = Datastore::new("memory").await?;
+	let ses = Session::for_kv().with_ns("test").with_db("test");
+	let res = &mut dbs.execute(&sql, &ses, None, false).await?;
+	assert_eq!(res.len(), 6);
+	//
+	let tmp = res.remove(0).result;
+	assert!(tmp.is_ok());
+	//
+	let tmp = res.remove(0).result;
+	assert!(tmp.is_ok());
+	//
+	let tmp = res.remove(0).result;
+	assert!(tmp.is_ok());
+	//
+	let tmp = res.remove(0).result;
+	assert!(tmp.is_ok());
+	//
+	let tmp = res.remove(0).result?;
+	let val = Value::parse(
+		"[
+			{ action: 'CREATE', record: test:tobie, table: 'test' },
+			{ action: 'SELECT', record: test:tobie, table: 'test' },
+			{ action: 'DELETE', record: test:tobie, table: 'test' },
+		]",
+	);
+	assert_eq!(tmp, val);
+	//
+	let tmp = res.remove(0).result?;
+	let val = Value::parse(
+		"{
+			analyzers: {},
+			logins: {},
+			tokens: {},
+			functions: {},
+			params: {},
+			scopes: {},
+			sequences: {},
+			tables: {
+				audit_log: 'DEFINE TABLE audit_log SCHEMALESS',
+				test: 'DEFINE TABLE test SCHEMALESS AUDIT ALL INTO audit_log',
+			},
+		}",
+	);
+	assert_eq!(tmp, val);
+	//
+	Ok(())
+}
+
+#[tokio::test]
+async fn define_statement_table_audit_reads() -> Result<(), Error> {
+	let sql = "
+		DEFINE TABLE test AUDIT ALL INTO audit_log;
+		CREATE test:tobie SET name = 'Tobie';
+	";
+	let dbs = Datastore::new("memory").await?;
+	let ses = Session::for_kv().with_ns("test").with_db("test");
+	dbs.execute(sql, &ses, None, false).await?;
+	// Reads are audited in their own transaction, even if the
+	// transaction of the statement is cancelled
+	let sql = "
+		SELECT name FROM test:tobie;
+		BEGIN;
+		SELECT name FROM test;
+		CANCEL;
+		SELECT action, record FROM audit_log WHERE action = 'SELECT';
+	";
+	let res = &mut dbs.execute(sql, &ses, None, false).await?;
+	assert_eq!(res.len(), 3);
+	//
+	let tmp = res.remove(0).result?;
+	let val = Value::parse("[{ name: 'Tobie' }]");
+	assert_eq!(tmp, val);
+	//
+	let tmp = res.remove(0).result;
+	assert!(matches!(tmp, Err(Error::QueryCancelled)));
+	//
+	let tmp = res.remove(0).result?;
+	let val = Value::parse(
+		"[
+			{ action: 'SELECT', record: test:tobie },
+			{ action: 'SELECT', record: test:tobie },
+		]",
+	);
+	assert_eq!(tmp, val);
+	//
+	Ok(())
+}
+
+#[tokio::test]
+async fn define_statement_table_audit_import() -> Result<(), Error> {
+	let sql = "
+		DEFINE TABLE test AUDIT ALL INTO audit_log;
+		DEFINE TABLE likes RELATION IN person OUT person;
+		OPTION IMPORT;
+		UPDATE test:tobie CONTENT { id: test:tobie, name: 'Tobie' };
+		UPDATE likes:one CONTENT { id: likes:one, in: person:tobie, out: person:jaime };
+		SELECT * FROM audit_log;
+	";
+	let dbs = Datastore::new("memory").await?;
+	let ses = Session::for_kv().with_ns("test").with_db("test");
+	let res = &mut dbs.execute(sql, &ses, None, false).await?;
+	assert_eq!(res.len(), 5);
+	//
+	let tmp = res.remove(0).result;
+	assert!(tmp.is_ok());
+	//
+	let tmp = res.remove(0).result;
+	assert!(tmp.is_ok());
+	//
+	let tmp = res.remove(0).result;
+	assert!(tmp.is_ok());
+	// Imported edges are not checked again
+	let tmp = res.remove(0).result;
+	assert!(tmp.is_ok());
+	// Imported records are not audited again
+	let tmp = res.remove(0).result?;
+	let val = Value::parse("[]");
+	assert_eq!(tmp, val);
+	//
+	Ok(())
+}
+
 #[tokio::test]
 async fn define_statement_event() -> Result<(), Error> {
 	let sql = "
@@ -250,6 +393,7 @@ async fn define_statement_event() -> Result<(), Error> {
 	let tmp = res.remove(0).result?;
 	let val = Value::parse(
 		"{
+			count: 0,
 			events: { test: 'DEFINE EVENT test ON user WHEN true THEN (CREATE activity SET user = $this, value = $after.email, action = $event)' },
 			fields: {},
 			tables: {},
@@ -307,6 +451,7 @@ async fn define_statement_event_when_event() -> Result<(), Error> {
 	let tmp = res.remove(0).result?;
 	let val = Value::parse(
 		r#"{
+			count: 0,
 			events: { test: "DEFINE EVENT test ON user WHEN $event = 'CREATE' THEN (CREATE activity SET user = $this, value = $after.email, action = $event)" },
 			fields: {},
 			tables: {},
@@ -364,6 +509,7 @@ async fn define_statement_event_when_logic() -> Result<(), Error> {
 	let tmp = res.remove(0).result?;
 	let val = Value::parse(
 		"{
+			count: 0,
 			events: { test: 'DEFINE EVENT test ON user WHEN $before.email != $after.email THEN (CREATE activity SET user = $this, value = $after.email, action = $event)' },
 			fields: {},
 			tables: {},
@@ -413,6 +559,7 @@ async fn define_statement_field() -> Result<(), Error> {
 	let tmp = res.remove(0).result?;
 	let val = Value::parse(
 		"{
+			count: 0,
 			events: {},
 			fields: { test: 'DEFINE FIELD test ON user' },
 			tables: {},
@@ -445,6 +592,7 @@ async fn define_statement_field_type() -> Result<(), Error> {
 	let tmp = res.remove(0).result?;
 	let val = Value::parse(
 		"{
+			count: 0,
 			events: {},
 			fields: { test: 'DEFINE FIELD test ON user TYPE string' },
 			tables: {},
@@ -477,6 +625,7 @@ async fn define_statement_field_value() -> Result<(), Error> {
 	let tmp = res.remove(0).result?;
 	let val = Value::parse(
 		r#"{
+			count: 0,
 			events: {},
 			fields: { test: "DEFINE FIELD test ON user VALUE $value OR 'GBR'" },
 			tables: {},
@@ -509,6 +658,7 @@ async fn define_statement_field_assert() -> Result<(), Error> {
 	let tmp = res.remove(0).result?;
 	let val = Value::parse(
 		"{
+			count: 0,
 			events: {},
 			fields: { test: 'DEFINE FIELD test ON user ASSERT $value != NONE AND $value = /[A-Z]{3}/' },
 			tables: {},
@@ -541,6 +691,7 @@ async fn define_statement_field_type_value_assert() -> Result<(), Error> {
 	let tmp = res.remove(0).result?;
 	let val = Value::parse(
 		r#"{
+			count: 0,
 			events: {},
 			fields: { test: "DEFINE FIELD test ON user TYPE string VALUE $value OR 'GBR' ASSERT $value != NONE AND $value = /[A-Z]{3}/" },
 			tables: {},
@@ -552,6 +703,55 @@ async fn define_statement_field_type_value_assert() -> Result<(), Error> {
 	Ok(())
 }
 
+#[tokio::test]
+async fn define_statement_comment() -> Result<(), Error> {
+	let sql = "
+		DEFINE TABLE user SCHEMAFULL COMMENT 'The users of the app';
+		DEFINE FIELD name ON user TYPE string COMMENT 'The full name of the user';
+		INFO FOR DB;
+		INFO FOR TABLE user;
+	";
+	let dbs = Datastore::new("memory").await?;
+	let ses = Session::for_kv().with_ns("test").with_db("test");
+	let res = &mut dbs.execute(&sql, &ses, None, false).await?;
+	assert_eq!(res.len(), 4);
+	//
+	let tmp = res.remove(0).result;
+	assert!(tmp.is_ok());
+	//
+	let tmp = res.remove(0).result;
+	assert!(tmp.is_ok());
+	//
+	let tmp = res.remove(0).result?;
+	let val = Value::parse(
+		r#"{
+			analyzers: {},
+			logins: {},
+			tokens: {},
+			functions: {},
+			params: {},
+			scopes: {},
+			sequences: {},
+			tables: { user: "DEFINE TABLE user SCHEMAFULL COMMENT 'The users of the app'" },
+		}"#,
+	);
+	assert_eq!(tmp, val);
+	//
+	let tmp = res.remove(0).result?;
+	let val = Value::parse(
+		r#"{
+			count: 0,
+			events: {},
+			fields: { name: "DEFINE FIELD name ON user TYPE string COMMENT 'The full name of the user'" },
+			tables: {},
+			indexes: {},
+		}"#,
+	);
+	assert_eq!(tmp, val);
+	//
+	Ok(())
+}
+
 #[tokio::test]
 async fn define_statement_index_single_simple() -> Result<(), Error> {
 	let sql = "
@@ -583,6 +783,7 @@ async fn define_statement_index_single_simple() -> Result<(), Error> {
 	let tmp = res.remove(0).result?;
 	let val = Value::parse(
 		"{
+			count: 2,
 			events: {},
 			fields: {},
 			tables: {},
@@ -625,6 +826,7 @@ async fn define_statement_index_single() -> Result<(), Error> {
 	let tmp = res.remove(0).result?;
 	let val = Value::parse(
 		"{
+			count: 0,
 			events: {},
 			fields: {},
 			tables: {},
@@ -669,6 +871,7 @@ async fn define_statement_index_multiple() -> Result<(), Error> {
 	let tmp = res.remove(0).result?;
 	let val = Value::parse(
 		"{
+			count: 0,
 			events: {},
 			fields: {},
 			tables: {},
@@ -721,6 +924,7 @@ async fn define_statement_index_single_unique() -> Result<(), Error> {
 	let tmp = res.remove(0).result?;
 	let val = Value::parse(
 		"{
+			count: 0,
 			events: {},
 			fields: {},
 			tables: {},
@@ -749,6 +953,50 @@ async fn define_statement_index_single_unique() -> Result<(), Error> {
 	Ok(())
 }
 
+#[tokio::test]
+async fn define_statement_index_unique_collated() -> Result<(), Error> {
+	let sql = "
+		DEFINE INDEX test ON user FIELDS email UNIQUE COLLATE CI;
+		CREATE user:1 SET email = 'test@surrealdb.com';
+		CREATE user:2 SET email = 'Test@SurrealDB.com';
+		SELECT * FROM user WHERE email = 'Test@SurrealDB.com';
+		INFO FOR TABLE user;
+	";
+	let dbs = Datastore::new("memory").await?;
+	let ses = Session::for_kv().with_ns("test").with_db("test");
+	let res = &mut dbs.execute(&sql, &ses, None, false).await?;
+	assert_eq!(res.len(), 5);
+	//
+	for _ in 0..2 {
+		let tmp = res.remove(0).result;
+		assert!(tmp.is_ok());
+	}
+	//
+	let tmp = res.remove(0).result;
+	assert!(matches!(
+		tmp.err(),
+		Some(e) if e.to_string() == r#"Database index `test` already contains 'Test@SurrealDB.com', with record `user:2`"#
+	));
+	// The index only finds the records which match the condition
+	let tmp = res.remove(0).result?;
+	let val = Value::parse("[]");
+	assert_eq!(tmp, val);
+	//
+	let tmp = res.remove(0).result?;
+	let val = Value::parse(
+		"{
+			count: 0,
+			events: {},
+			fields: {},
+			tables: {},
+			indexes: { test: 'DEFINE INDEX test ON user FIELDS email UNIQUE COLLATE CI' },
+		}",
+	);
+	assert_eq!(tmp, val);
+	//
+	Ok(())
+}
+
 #[tokio::test]
 async fn define_statement_index_multiple_unique() -> Result<(), Error> {
 	let sql = "
@@ -779,6 +1027,7 @@ async fn define_statement_index_multiple_unique() -> Result<(), Error> {
 	let tmp = res.remove(0).result?;
 	let val = Value::parse(
 		"{
+			count: 0,
 			events: {},
 			fields: {},
 			tables: {},
@@ -865,6 +1114,7 @@ async fn define_statement_index_single_unique_existing() -> Result<(), Error> {
 	let tmp = res.remove(0).result?;
 	let val = Value::parse(
 		"{
+			count: 3,
 			events: {},
 			fields: {},
 			tables: {},
@@ -913,6 +1163,7 @@ async fn define_statement_index_multiple_unique_existing() -> Result<(), Error>
 	let tmp = res.remove(0).result?;
 	let val = Value::parse(
 		"{
+			count: 4,
 			events: {},
 			fields: {},
 			tables: {},
@@ -955,6 +1206,7 @@ async fn define_statement_analyzer() -> Result<(), Error> {
 			functions: {},
 			params: {},
 			scopes: {},
+			sequences: {},
 			tables: {}
 		}",
 	);
@@ -988,6 +1240,7 @@ async fn define_statement_search_index() -> Result<(), Error> {
 	let tmp = res.remove(0).result?;
 	let val = Value::parse(
 		"{
+			count: 2,
 			events: {},
 			fields: {},
 			tables: {},
@@ -1021,6 +1274,91 @@ async fn define_statement_search_index() -> Result<(), Error> {
 	Ok(())
 }
 
+#[tokio::test]
+async fn define_statement_info_structure() -> Result<(), Error> {
+	let sql = "
+		DEFINE PARAM $limit VALUE 10;
+		DEFINE SEQUENCE invoice;
+		DEFINE TABLE person SCHEMAFULL;
+		DEFINE FIELD name ON person TYPE string ASSERT $value != NONE;
+		DEFINE INDEX person_name ON person FIELDS name UNIQUE;
+		INFO FOR DB STRUCTURE;
+		INFO FOR TABLE person STRUCTURE;
+	";
+	let dbs = Datastore::new("memory").await?;
+	let ses = Session::for_kv().with_ns("test").with_db("test");
+	let res = &mut dbs.execute(sql, &ses, None, false).await?;
+	assert_eq!(res.len(), 7);
+	//
+	for _ in 0..5 {
+		let tmp = res.remove(0).result;
+		assert!(tmp.is_ok());
+	}
+	//
+	let tmp = res.remove(0).result?;
+	let val = Value::parse("[{ name: 'limit', value: 10 }]");
+	assert_eq!(tmp.pick(&[Part::from("params")]), val);
+	let val = Value::parse("[{ name: 'invoice', start: 1, step: 1 }]");
+	assert_eq!(tmp.pick(&[Part::from("sequences")]), val);
+	let val = Value::parse(
+		"[
+			{
+				archive: false,
+				audit: NONE,
+				comment: NONE,
+				drop: false,
+				full: true,
+				history: false,
+				id: NONE,
+				name: 'person',
+				permissions: { create: true, delete: true, select: true, update: true },
+				schema: NONE,
+				soft: NONE,
+				view: NONE,
+			}
+		]",
+	);
+	assert_eq!(tmp.pick(&[Part::from("tables")]), val);
+	//
+	let tmp = res.remove(0).result?;
+	let val = Value::parse(
+		"{
+			count: 0,
+			events: [],
+			fields: [
+				{
+					assert: '$value != NONE',
+					comment: NONE,
+					computed: false,
+					flex: false,
+					kind: 'string',
+					message: NONE,
+					name: 'name',
+					permissions: { create: true, delete: true, select: true, update: true },
+					sensitive: NONE,
+					value: NONE,
+					what: 'person',
+				}
+			],
+			tables: [],
+			indexes: [
+				{
+					collation: NONE,
+					cols: ['name'],
+					name: 'person_name',
+					search: NONE,
+					unique: true,
+					vector: NONE,
+					what: 'person',
+				}
+			],
+		}",
+	);
+	assert_eq!(tmp, val);
+	//
+	Ok(())
+}
+
 fn check_path<F>(val: &Value, path: &[&str], check: F)
 where
 	F: Fn(Value),
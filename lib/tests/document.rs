@@ -0,0 +1,95 @@
+mod parse;
+use parse::Parse;
+use surrealdb::dbs::Session;
+use surrealdb::err::Error;
+use surrealdb::kvs::Datastore;
+use surrealdb::sql::Value;
+
+async fn chunks(dbs: &Datastore) -> Result<usize, Error> {
+	let mut tx = dbs.transaction(false, false).await?;
+	let beg = b"/*test\x00*test\x00*person\x00!ck".to_vec();
+	let end = b"/*test\x00*test\x00*person\x00!cl".to_vec();
+	let res = tx.scan(beg..end, 1000).await?;
+	tx.cancel().await?;
+	Ok(res.len())
+}
+
+#[tokio::test]
+async fn large_documents_are_chunked() -> Result<(), Error> {
+	let sql = "
+		CREATE person:tobie SET name = 'Tobie', bio = string::repeat('a', 1000);
+		CREATE person:jaime SET name = 'Jaime';
+		SELECT VALUE string::len(bio) FROM person:tobie;
+	";
+	let dbs = Datastore::new("memory").await?.with_chunk_size(128);
+	let ses = Session::for_kv().with_ns("test").with_db("test");
+	let res = &mut dbs.execute(sql, &ses, None, false).await?;
+	assert_eq!(res.len(), 3);
+	//
+	res.remove(0).result?;
+	res.remove(0).result?;
+	let tmp = res.remove(0).result?;
+	assert_eq!(tmp, Value::parse("[1000]"));
+	// Only the large record is split into chunks
+	let count = chunks(&dbs).await?;
+	assert!(count >= 8);
+	// Chunks which are no longer needed are removed
+	let sql = "
+		UPDATE person:tobie SET bio = string::repeat('b', 500);
+		SELECT VALUE string::len(bio) FROM person:tobie;
+	";
+	let res = &mut dbs.execute(sql, &ses, None, false).await?;
+	res.remove(0).result?;
+	assert_eq!(res.remove(0).result?, Value::parse("[500]"));
+	assert!(chunks(&dbs).await? < count);
+	//
+	let sql = "
+		UPDATE person:tobie SET bio = NONE;
+		SELECT * FROM person:tobie;
+	";
+	let res = &mut dbs.execute(sql, &ses, None, false).await?;
+	res.remove(0).result?;
+	assert_eq!(res.remove(0).result?, Value::parse("[{ id: person:tobie, name: 'Tobie' }]"));
+	assert_eq!(chunks(&dbs).await?, 0);
+	// Deleting a large record removes its chunks
+	let sql = "
+		UPDATE person:jaime SET bio = string::repeat('c', 1000);
+		DELETE person:jaime;
+		SELECT * FROM person;
+	";
+	let res = &mut dbs.execute(sql, &ses, None, false).await?;
+	res.remove(0).result?;
+	res.remove(0).result?;
+	assert_eq!(res.remove(0).result?, Value::parse("[{ id: person:tobie, name: 'Tobie' }]"));
+	assert_eq!(chunks(&dbs).await?, 0);
+	//
+	Ok(())
+}
+
+#[tokio::test]
+async fn documents_larger_than_the_limit_are_rejected() -> Result<(), Error> {
+	let sql = "
+		CREATE person:tobie SET bio = string::repeat('a', 1000);
+		CREATE person:jaime SET bio = string::repeat('a', 100);
+		UPDATE person:jaime SET bio = string::repeat('a', 1000);
+		SELECT VALUE string::len(bio) FROM person;
+	";
+	let dbs = Datastore::new("memory").await?.with_max_document_size(512);
+	let ses = Session::for_kv().with_ns("test").with_db("test");
+	let res = &mut dbs.execute(sql, &ses, None, false).await?;
+	assert_eq!(res.len(), 4);
+	//
+	let tmp = res.remove(0).result;
+	assert!(matches!(
+		tmp,
+		Err(Error::DocumentTooLarge { ref thing, max: 512, .. }) if thing == "person:tobie"
+	));
+	res.remove(0).result?;
+	let tmp = res.remove(0).result;
+	assert!(matches!(tmp, Err(Error::DocumentTooLarge { .. })));
+	//
+	let tmp = res.remove(0).result?;
+	assert_eq!(tmp, Value::parse("[100]"));
+	//
+	Ok(())
+}
@@ -0,0 +1,77 @@
+mod parse;
+use parse::Parse;
+use surrealdb::dbs::Session;
+use surrealdb::err::Error;
+use surrealdb::kvs::Datastore;
+use surrealdb::sql::Value;
+
+#[tokio::test]
+async fn dry_run_returns_changes_and_rolls_back() -> Result<(), Error> {
+	let dbs = Datastore::new("memory").await?;
+	let ses = Session::for_kv().with_ns("test").with_db("test");
+	dbs.execute("CREATE person:1 SET age = 20;", &ses, None, false).await?;
+	//
+	let sql = "
+		UPDATE person:1 SET age = 30;
+		BEGIN;
+		CREATE person:2 SET age = 1;
+		UPDATE person:2 SET age = 2;
+		COMMIT;
+	";
+	let dry = ses.clone().with_dry_run(true);
+	let res = &mut dbs.execute(sql, &dry, None, false).await?;
+	assert_eq!(res.len(), 3);
+	//
+	let tmp = res.remove(0).result?;
+	let val = Value::parse(
+		"[
+			{
+				id: person:1,
+				diff: [{ op: 'replace', path: '/age', value: 30 }],
+			}
+		]",
+	);
+	assert_eq!(tmp, val);
+	// The statements of a transaction see the changes of each other
+	let _ = res.remove(0).result?;
+	let tmp = res.remove(0).result?;
+	let val = Value::parse(
+		"[
+			{
+				id: person:2,
+				diff: [{ op: 'replace', path: '/age', value: 2 }],
+			}
+		]",
+	);
+	assert_eq!(tmp, val);
+	// None of the changes were committed
+	let res = &mut dbs.execute("SELECT * FROM person;", &ses, None, false).await?;
+	assert_eq!(res.len(), 1);
+	//
+	let tmp = res.remove(0).result?;
+	let val = Value::parse("[{ id: person:1, age: 20 }]");
+	assert_eq!(tmp, val);
+	//
+	Ok(())
+}
+
+#[tokio::test]
+async fn dry_run_datastore_rolls_back_every_query() -> Result<(), Error> {
+	let dbs = Datastore::new("memory").await?.with_dry_run(true);
+	let ses = Session::for_kv().with_ns("test").with_db("test");
+	let sql = "
+		CREATE person:1 SET age = 20;
+		SELECT * FROM person;
+	";
+	let res = &mut dbs.execute(sql, &ses, None, false).await?;
+	assert_eq!(res.len(), 2);
+	//
+	let tmp = res.remove(0).result?;
+	assert!(tmp.is_array());
+	//
+	let tmp = res.remove(0).result?;
+	let val = Value::parse("[]");
+	assert_eq!(tmp, val);
+	//
+	Ok(())
+}
@@ -0,0 +1,276 @@
+mod parse;
+use async_trait::async_trait;
+use parse::Parse;
+use std::collections::BTreeMap;
+use std::ops::Range;
+use std::sync::{Arc, Mutex};
+use std::time::Duration;
+use surrealdb::dbs::Session;
+use surrealdb::err::Error;
+use surrealdb::kvs::{register, Datastore, Engine, EngineTransaction, Key, Val, Write};
+use surrealdb::sql::Value;
+
+type Data = Arc<Mutex<BTreeMap<Key, Val>>>;
+
+#[derive(Default)]
+struct Store(Data);
+
+struct Transaction {
+	ok: bool,
+	rw: bool,
+	db: Data,
+	data: BTreeMap<Key, Val>,
+	writes: BTreeMap<Key, Option<Val>>,
+}
+
+impl Transaction {
+	fn check(&self, write: bool) -> Result<(), Error> {
+		match (self.ok, write && !self.rw) {
+			(true, _) => Err(Error::TxFinished),
+			(_, true) => Err(Error::TxReadonly),
+			_ => Ok(()),
+		}
+	}
+}
+
+#[async_trait]
+impl Engine for Store {
+	async fn transaction(&self, write: bool, _: bool) -> Result<Box<dyn EngineTransaction>, Error> {
+		Ok(Box::new(Transaction {
+			ok: false,
+			rw: write,
+			db: self.0.clone(),
+			data: self.0.lock().unwrap().clone(),
+			writes: BTreeMap::new(),
+		}))
+	}
+}
+
+/// A store which is shared with other processes
+struct Watched(Store, channel::Receiver<Vec<Write>>);
+
+#[async_trait]
+impl Engine for Watched {
+	async fn transaction(
+		&self,
+		write: bool,
+		lock: bool,
+	) -> Result<Box<dyn EngineTransaction>, Error> {
+		self.0.transaction(write, lock).await
+	}
+	fn watch(&self) -> Option<channel::Receiver<Vec<Write>>> {
+		Some(self.1.clone())
+	}
+}
+
+#[async_trait]
+impl EngineTransaction for Transaction {
+	fn closed(&self) -> bool {
+		self.ok
+	}
+	async fn cancel(&mut self) -> Result<(), Error> {
+		self.check(false)?;
+		self.ok = true;
+		Ok(())
+	}
+	async fn commit(&mut self) -> Result<(), Error> {
+		self.check(true)?;
+		self.ok = true;
+		let mut db = self.db.lock().unwrap();
+		for (k, v) in std::mem::take(&mut self.writes) {
+			match v {
+				Some(v) => db.insert(k, v),
+				None => db.remove(&k),
+			};
+		}
+		Ok(())
+	}
+	async fn exi(&mut self, key: Key) -> Result<bool, Error> {
+		self.check(false)?;
+		Ok(self.data.contains_key(&key))
+	}
+	async fn get(&mut self, key: Key) -> Result<Option<Val>, Error> {
+		self.check(false)?;
+		Ok(self.data.get(&key).cloned())
+	}
+	async fn set(&mut self, key: Key, val: Val) -> Result<(), Error> {
+		self.check(true)?;
+		self.data.insert(key.clone(), val.clone());
+		self.writes.insert(key, Some(val));
+		Ok(())
+	}
+	async fn put(&mut self, key: Key, val: Val) -> Result<(), Error> {
+		match self.data.contains_key(&key) {
+			true => Err(Error::TxKeyAlreadyExists),
+			false => self.set(key, val).await,
+		}
+	}
+	async fn putc(&mut self, key: Key, val: Val, chk: Option<Val>) -> Result<(), Error> {
+		match self.data.get(&key) == chk.as_ref() {
+			true => self.set(key, val).await,
+			false => Err(Error::TxConditionNotMet),
+		}
+	}
+	async fn del(&mut self, key: Key) -> Result<(), Error> {
+		self.check(true)?;
+		self.data.remove(&key);
+		self.writes.insert(key, None);
+		Ok(())
+	}
+	async fn delc(&mut self, key: Key, chk: Option<Val>) -> Result<(), Error> {
+		match self.data.get(&key) == chk.as_ref() {
+			true => self.del(key).await,
+			false => Err(Error::TxConditionNotMet),
+		}
+	}
+	async fn scan(&mut self, rng: Range<Key>, limit: u32) -> Result<Vec<(Key, Val)>, Error> {
+		self.check(false)?;
+		Ok(self.data.range(rng).take(limit as usize).map(|(k, v)| (k.clone(), v.clone())).collect())
+	}
+}
+
+#[tokio::test]
+async fn custom_storage_engine() -> Result<(), Error> {
+	register("custom", |_| async { Ok(Store::default()) });
+	let dbs = Datastore::new("custom://test").await?;
+	assert_eq!(dbs.to_string(), "engine");
+	//
+	let sql = "
+		CREATE person:tobie SET name = 'Tobie';
+		CREATE person:jaime SET name = 'Jaime';
+		DELETE person:jaime;
+		SELECT * FROM person;
+	";
+	let ses = Session::for_kv().with_ns("test").with_db("test");
+	let res = &mut dbs.execute(sql, &ses, None, false).await?;
+	assert_eq!(res.len(), 4);
+	//
+	let tmp = res.remove(3).result?;
+	let val = Value::parse("[{ id: person:tobie, name: 'Tobie' }]");
+	assert_eq!(tmp, val);
+	//
+	let tmp = Datastore::new("unknown://test").await;
+	assert!(matches!(tmp, Err(Error::Ds(_))));
+	//
+	Ok(())
+}
+
+#[tokio::test]
+async fn conflicting_transactions() -> Result<(), Error> {
+	register("conflict", |_| async { Ok(Store::default()) });
+	let dbs = Datastore::new("conflict://test").await?;
+	let ses = Session::for_kv().with_ns("test").with_db("test");
+	let res = &mut dbs.execute("CREATE counter:one SET n = 0", &ses, None, false).await?;
+	res.remove(0).result?;
+	// The first transaction to commit wins
+	let id = dbs.begin(&ses).await?;
+	let res = &mut dbs.execute_in(&id, "UPDATE counter:one SET n += 1", &ses, None, false).await?;
+	res.remove(0).result?;
+	let res = &mut dbs.execute("UPDATE counter:one SET n += 1", &ses, None, false).await?;
+	res.remove(0).result?;
+	let tmp = dbs.commit(&id, &ses).await;
+	assert!(matches!(tmp, Err(Error::TxConflict)));
+	// Transactions which write to different tables do not conflict
+	let id = dbs.begin(&ses).await?;
+	let res = &mut dbs.execute_in(&id, "CREATE person:tobie", &ses, None, false).await?;
+	res.remove(0).result?;
+	let res = &mut dbs.execute("UPDATE counter:one SET n += 1", &ses, None, false).await?;
+	res.remove(0).result?;
+	dbs.commit(&id, &ses).await?;
+	//
+	let sql = "SELECT * FROM counter, person";
+	let res = &mut dbs.execute(sql, &ses, None, false).await?;
+	let tmp = res.remove(0).result?;
+	let val = Value::parse("[{ id: counter:one, n: 2 }, { id: person:tobie }]");
+	assert_eq!(tmp, val);
+	//
+	Ok(())
+}
+
+#[tokio::test]
+async fn conflicting_transactions_are_retried() -> Result<(), Error> {
+	register("retry", |_| async { Ok(Store::default()) });
+	let dbs = Arc::new(Datastore::new("retry://test").await?.with_retries(3));
+	let ses = Session::for_kv().with_ns("test").with_db("test");
+	let res = &mut dbs.execute("CREATE counter:one SET n = 0", &ses, None, false).await?;
+	res.remove(0).result?;
+	// The transaction commits after a concurrent update to the same record
+	let sql = "BEGIN; UPDATE counter:one SET n += 1; SELECT * FROM sleep(100ms); COMMIT;";
+	let txn = {
+		let dbs = dbs.clone();
+		let ses = ses.clone();
+		tokio::spawn(async move { dbs.execute(sql, &ses, None, false).await })
+	};
+	tokio::time::sleep(Duration::from_millis(20)).await;
+	let res = &mut dbs.execute("UPDATE counter:one SET n += 1", &ses, None, false).await?;
+	let tmp = res.remove(0);
+	assert_eq!(tmp.retries, 0);
+	assert_eq!(tmp.result?, Value::parse("[{ id: counter:one, n: 1 }]"));
+	// The transaction is retried, so neither update is lost
+	let res = &mut txn.await.unwrap()?;
+	assert_eq!(res.len(), 2);
+	let tmp = res.remove(0);
+	assert_eq!(tmp.retries, 1);
+	assert_eq!(tmp.result?, Value::parse("[{ id: counter:one, n: 2 }]"));
+	let tmp = serde_json::to_value(&res.remove(0)).unwrap();
+	assert_eq!(tmp["retries"], 1);
+	//
+	Ok(())
+}
+
+#[tokio::test]
+async fn watched_storage_engine() -> Result<(), Error> {
+	let (snd, rcv) = channel::unbounded();
+	register("watch", move |_| {
+		let rcv = rcv.clone();
+		async move { Ok(Watched(Store::default(), rcv)) }
+	});
+	let dbs = Datastore::new("watch://test").await?;
+	let writes = dbs.writes().await;
+	// The writes committed by another process are published
+	let tmp = vec![Write::Set(b"key".to_vec(), b"val".to_vec()), Write::Del(b"old".to_vec())];
+	snd.send(tmp.clone()).await.unwrap();
+	let batch = tokio::time::timeout(Duration::from_secs(1), writes.recv()).await.unwrap().unwrap();
+	assert_eq!(batch.seq, 1);
+	assert_eq!(batch.writes, tmp);
+	// The writes committed by this datastore follow in sequence
+	let ses = Session::for_kv().with_ns("test").with_db("test");
+	let res = &mut dbs.execute("CREATE person:tobie", &ses, None, false).await?;
+	res.remove(0).result?;
+	let batch = tokio::time::timeout(Duration::from_secs(1), writes.recv()).await.unwrap().unwrap();
+	assert_eq!(batch.seq, 2);
+	//
+	Ok(())
+}
+
+#[tokio::test]
+async fn index_format_is_upgraded_on_open() -> Result<(), Error> {
+	let data = Data::default();
+	let store = data.clone();
+	register("upgrade", move |_| {
+		let store = store.clone();
+		async move { Ok(Store(store)) }
+	});
+	let dbs = Datastore::new("upgrade://test").await?;
+	let sql = "
+		DEFINE INDEX email ON person FIELDS email UNIQUE;
+		CREATE person:tobie SET email = 'tobie@surrealdb.com';
+	";
+	let ses = Session::for_kv().with_ns("test").with_db("test");
+	let res = &mut dbs.execute(sql, &ses, None, false).await?;
+	assert_eq!(res.len(), 2);
+	for v in res.drain(..) {
+		v.result?;
+	}
+	drop(dbs);
+	// Remove the index entries and the format version, as in an older datastore
+	data.lock().unwrap().retain(|k, _| k != b"/!iv" && !k.windows(6).any(|v| v == b"\xa4email"));
+	// The index is rebuilt when the datastore is opened
+	let dbs = Datastore::new("upgrade://test").await?;
+	let sql = "CREATE person:jaime SET email = 'tobie@surrealdb.com'";
+	let res = &mut dbs.execute(sql, &ses, None, false).await?;
+	let tmp = res.remove(0).result;
+	assert!(matches!(tmp, Err(Error::IndexExists { .. })));
+	//
+	Ok(())
+}
@@ -0,0 +1,68 @@
+use surrealdb::dbs::Session;
+use surrealdb::err::{Error, ErrorKind};
+use surrealdb::kvs::Datastore;
+
+#[tokio::test]
+async fn error_kinds_for_failed_statements() -> Result<(), Error> {
+	let sql = "
+		CREATE person:tobie;
+		CREATE person:tobie;
+		SELECT * FROM sleep(500ms) TIMEOUT 10ms;
+		RETURN fn::missing();
+		THROW 'some error';
+		RETURN 'valid';
+	";
+	let dbs = Datastore::new("memory").await?;
+	let ses = Session::for_kv().with_ns("test").with_db("test");
+	let res = &mut dbs.execute(sql, &ses, None, false).await?;
+	assert_eq!(res.len(), 6);
+	//
+	let tmp = res.remove(0);
+	assert!(tmp.result.is_ok());
+	let tmp = serde_json::to_value(&tmp).unwrap();
+	assert!(tmp.get("kind").is_none());
+	assert!(tmp.get("code").is_none());
+	//
+	let tmp = res.remove(0);
+	assert_eq!(tmp.result.as_ref().unwrap_err().kind(), ErrorKind::AlreadyExists);
+	let tmp = serde_json::to_value(&tmp).unwrap();
+	assert_eq!(tmp["kind"], "already_exists");
+	assert_eq!(tmp["code"], 1005);
+	assert!(tmp.get("retry").is_none());
+	//
+	let tmp = res.remove(0);
+	assert_eq!(tmp.result.as_ref().unwrap_err().kind(), ErrorKind::Timeout);
+	let tmp = serde_json::to_value(&tmp).unwrap();
+	assert_eq!(tmp["kind"], "timeout");
+	assert_eq!(tmp["code"], 1007);
+	assert_eq!(tmp["retry"]["reason"], "timeout");
+	//
+	let tmp = res.remove(0);
+	assert_eq!(tmp.result.as_ref().unwrap_err().kind(), ErrorKind::NotFound);
+	//
+	let tmp = res.remove(0);
+	assert_eq!(tmp.result.as_ref().unwrap_err().kind(), ErrorKind::Thrown);
+	//
+	let tmp = res.remove(0);
+	assert!(tmp.result.is_ok());
+	//
+	Ok(())
+}
+
+#[tokio::test]
+async fn error_kinds_for_invalid_queries() -> Result<(), Error> {
+	let dbs = Datastore::new("memory").await?;
+	// Parse errors are returned before any statement is executed
+	let ses = Session::for_kv().with_ns("test").with_db("test");
+	let res = dbs.execute("SELECT * FROM person WHERE", &ses, None, false).await;
+	assert_eq!(res.unwrap_err().kind(), ErrorKind::Parse);
+	// Statements which are not allowed for the session
+	let ses = Session::for_db("test", "test");
+	let res = &mut dbs.execute("DEFINE NAMESPACE other", &ses, None, false).await?;
+	let tmp = res.remove(0).result.unwrap_err();
+	assert_eq!(tmp.kind(), ErrorKind::PermissionDenied);
+	assert_eq!(tmp.kind().code(), 1003);
+	assert_eq!(tmp.kind().to_string(), "permission_denied");
+	//
+	Ok(())
+}
@@ -0,0 +1,240 @@
+mod parse;
+use parse::Parse;
+use surrealdb::dbs::Session;
+use surrealdb::err::Error;
+use surrealdb::kvs::Datastore;
+use surrealdb::sql::Value;
+
+async fn export(dbs: &Datastore, ns: &str, db: &str) -> Result<String, Error> {
+	let (snd, rcv) = surrealdb::channel::new(1);
+	let out = tokio::spawn(async move {
+		let mut out = vec![];
+		while let Ok(v) = rcv.recv().await {
+			out.extend(v);
+		}
+		out
+	});
+	dbs.export(ns.to_owned(), db.to_owned(), snd).await?;
+	Ok(String::from_utf8(out.await.unwrap()).unwrap())
+}
+
+async fn export_anonymized(dbs: &Datastore, ns: &str, db: &str) -> Result<String, Error> {
+	let (snd, rcv) = surrealdb::channel::new(1);
+	let out = tokio::spawn(async move {
+		let mut out = vec![];
+		while let Ok(v) = rcv.recv().await {
+			out.extend(v);
+		}
+		out
+	});
+	dbs.export_anonymized(ns.to_owned(), db.to_owned(), snd).await?;
+	Ok(String::from_utf8(out.await.unwrap()).unwrap())
+}
+
+#[tokio::test]
+async fn export_in_dependency_order() -> Result<(), Error> {
+	let sql = "
+		DEFINE TABLE person SCHEMALESS;
+		DEFINE TABLE adult AS SELECT * FROM person WHERE age >= 18;
+		CREATE person:tobie SET name = 'Tobie', age = 30;
+		CREATE person:jaime SET name = 'Jaime', age = 12;
+	";
+	let dbs = Datastore::new("memory").await?;
+	let ses = Session::for_kv().with_ns("test").with_db("test");
+	dbs.execute(sql, &ses, None, false).await?;
+	// Export the database
+	let sql = export(&dbs, "test", "test").await?;
+	let beg = sql.find("BEGIN TRANSACTION;").unwrap();
+	let src = sql.find("DEFINE TABLE person").unwrap();
+	let view = sql.find("DEFINE TABLE adult").unwrap();
+	assert!(beg < src && src < view);
+	// Import the export into a new datastore
+	let dbs = Datastore::new("memory").await?;
+	let res = dbs.execute(&sql, &ses, None, false).await?;
+	assert!(res.into_iter().all(|v| v.result.is_ok()));
+	//
+	let sql = "
+		SELECT name FROM person;
+		SELECT name FROM adult;
+	";
+	let res = &mut dbs.execute(sql, &ses, None, false).await?;
+	assert_eq!(res.len(), 2);
+	//
+	let tmp = res.remove(0).result?;
+	let val = Value::parse("[{ name: 'Jaime' }, { name: 'Tobie' }]");
+	assert_eq!(tmp, val);
+	//
+	let tmp = res.remove(0).result?;
+	let val = Value::parse("[{ name: 'Tobie' }]");
+	assert_eq!(tmp, val);
+	//
+	Ok(())
+}
+
+#[tokio::test]
+async fn import_is_atomic() -> Result<(), Error> {
+	let sql = "
+		CREATE person:tobie SET name = 'Tobie';
+		DEFINE FIELD name ON person ASSERT $value = 'Tobie';
+	";
+	let dbs = Datastore::new("memory").await?;
+	let ses = Session::for_kv().with_ns("test").with_db("test");
+	dbs.execute(sql, &ses, None, false).await?;
+	// Make the import fail after the definitions
+	let sql = export(&dbs, "test", "test").await?;
+	let sql = sql.replace("COMMIT TRANSACTION;", "CREATE person:tobie; COMMIT TRANSACTION;");
+	let dbs = Datastore::new("memory").await?;
+	let res = dbs.execute(&sql, &ses, None, false).await?;
+	assert!(res.into_iter().all(|v| v.result.is_err()));
+	// None of the definitions or records were imported
+	let sql = "
+		CREATE person:jaime SET name = 'Jaime';
+		SELECT * FROM person;
+	";
+	let res = &mut dbs.execute(sql, &ses, None, false).await?;
+	assert_eq!(res.len(), 2);
+	//
+	let tmp = res.remove(0).result;
+	assert!(tmp.is_ok());
+	//
+	let tmp = res.remove(0).result?;
+	let val = Value::parse("[{ id: person:jaime, name: 'Jaime' }]");
+	assert_eq!(tmp, val);
+	//
+	Ok(())
+}
+
+async fn export_while_writing(dbs: &Datastore, committed: bool) -> Result<String, Error> {
+	let (snd, rcv) = surrealdb::channel::new(1);
+	let ses = Session::for_kv().with_ns("test").with_db("test");
+	let export = async {
+		match committed {
+			true => dbs.export_committed("test".to_owned(), "test".to_owned(), snd).await,
+			false => dbs.export("test".to_owned(), "test".to_owned(), snd).await,
+		}
+	};
+	let output = async {
+		let mut out = String::new();
+		while let Ok(v) = rcv.recv().await {
+			let v = String::from_utf8(v).unwrap();
+			// Write a record while the first batch of records is exported
+			if v.starts_with("UPDATE person:500 ") {
+				let sql = "CREATE person:2000, person:0";
+				dbs.execute(sql, &ses, None, false).await?;
+			}
+			out.push_str(&v);
+		}
+		Ok::<String, Error>(out)
+	};
+	let (res, out) = tokio::join!(export, output);
+	res?;
+	out
+}
+
+#[tokio::test]
+async fn export_read_committed() -> Result<(), Error> {
+	let sql: String = (1..=1500).map(|i| format!("CREATE person:{i};")).collect();
+	let dbs = Datastore::new("memory").await?;
+	let ses = Session::for_kv().with_ns("test").with_db("test");
+	dbs.execute(&sql, &ses, None, false).await?;
+	// A snapshot export does not see the write
+	let sql = export_while_writing(&dbs, false).await?;
+	assert!(!sql.contains("UPDATE person:2000 "));
+	assert!(!sql.contains("UPDATE person:0 "));
+	assert_eq!(sql.matches("UPDATE person:").count(), 1500);
+	// A read-committed export sees the write in later batches
+	let dbs = Datastore::new("memory").await?;
+	let sql: String = (1..=1500).map(|i| format!("CREATE person:{i};")).collect();
+	dbs.execute(&sql, &ses, None, false).await?;
+	let sql = export_while_writing(&dbs, true).await?;
+	assert!(sql.contains("UPDATE person:2000 "));
+	assert!(!sql.contains("UPDATE person:0 "));
+	assert_eq!(sql.matches("UPDATE person:").count(), 1501);
+	//
+	Ok(())
+}
+
+#[tokio::test]
+async fn export_schema_without_data() -> Result<(), Error> {
+	let sql = "
+		DEFINE SEQUENCE invoice START 100;
+		DEFINE TABLE person SCHEMAFULL;
+		DEFINE FIELD name ON person TYPE string;
+		DEFINE INDEX person_name ON person FIELDS name UNIQUE;
+		CREATE person:tobie SET name = 'Tobie';
+		RETURN sequence::next('invoice');
+	";
+	let dbs = Datastore::new("memory").await?;
+	let ses = Session::for_kv().with_ns("test").with_db("test");
+	dbs.execute(sql, &ses, None, false).await?;
+	// Export the schema twice
+	let res = &mut dbs.execute("EXPORT SCHEMA; EXPORT SCHEMA;", &ses, None, false).await?;
+	let one = res.remove(0).result?.as_string();
+	let two = res.remove(0).result?.as_string();
+	assert_eq!(one, two);
+	assert!(one.contains("DEFINE SEQUENCE invoice START 100 INCREMENT 1;"));
+	assert!(one.contains("DEFINE FIELD name ON person TYPE string;"));
+	assert!(one.contains("DEFINE INDEX person_name ON person FIELDS name UNIQUE;"));
+	assert!(!one.contains("person:tobie"));
+	// Replay the schema onto a new datastore
+	let dbs = Datastore::new("memory").await?;
+	let res = dbs.execute(&one, &ses, None, false).await?;
+	assert!(res.into_iter().all(|v| v.result.is_ok()));
+	let res = &mut dbs.execute("EXPORT SCHEMA", &ses, None, false).await?;
+	assert_eq!(res.remove(0).result?.as_string(), one);
+	//
+	Ok(())
+}
+
+#[tokio::test]
+async fn export_anonymizes_sensitive_fields() -> Result<(), Error> {
+	let sql = "
+		DEFINE TABLE user SCHEMAFULL;
+		DEFINE FIELD name ON user TYPE string;
+		DEFINE FIELD email ON user TYPE string SENSITIVE FAKE;
+		DEFINE FIELD phone ON user TYPE string SENSITIVE;
+		DEFINE FIELD token ON user TYPE string SENSITIVE HASH;
+		DEFINE INDEX email ON user FIELDS email UNIQUE;
+		CREATE user:tobie SET name = 'Tobie', email = 'tobie@surrealdb.com', phone = '+44 7700 900123', token = 'secret';
+		CREATE user:jaime SET name = 'Jaime', email = 'jaime@surrealdb.com', phone = '+44 7700 900456', token = 'secret';
+	";
+	let dbs = Datastore::new("memory").await?;
+	let ses = Session::for_kv().with_ns("test").with_db("test");
+	dbs.execute(sql, &ses, None, false).await?;
+	// Export the database
+	let sql = export_anonymized(&dbs, "test", "test").await?;
+	assert!(sql.contains("DEFINE FIELD email ON user TYPE string SENSITIVE FAKE"));
+	assert!(!sql.contains("surrealdb.com"));
+	assert!(!sql.contains("secret"));
+	assert!(!sql.contains("7700"));
+	// Import the export into a new datastore
+	let dbs = Datastore::new("memory").await?;
+	let res = dbs.execute(&sql, &ses, None, false).await?;
+	assert!(res.into_iter().all(|v| v.result.is_ok()));
+	//
+	let sql = "
+		SELECT name, phone FROM user;
+		SELECT count() FROM user WHERE email CONTAINS '@example.com' GROUP ALL;
+		SELECT token FROM user GROUP BY token;
+	";
+	let res = &mut dbs.execute(sql, &ses, None, false).await?;
+	assert_eq!(res.len(), 3);
+	//
+	let tmp = res.remove(0).result?;
+	let val = Value::parse(
+		"[
+			{ name: 'Jaime', phone: '+** **** ******' },
+			{ name: 'Tobie', phone: '+** **** ******' }
+		]",
+	);
+	assert_eq!(tmp, val);
+	//
+	let tmp = res.remove(0).result?;
+	let val = Value::parse("[{ count: 2 }]");
+	assert_eq!(tmp, val);
+	// Equal values are hashed to equal values
+	let tmp = res.remove(0).result?;
+	assert!(matches!(tmp, Value::Array(v) if v.len() == 1));
+	//
+	Ok(())
+}
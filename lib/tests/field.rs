@@ -303,3 +303,199 @@ async fn field_definition_empty_nested_flexible() -> Result<(), Error> {
 	//
 	Ok(())
 }
+
+#[tokio::test]
+async fn field_permissions_hide_and_protect_fields() -> Result<(), Error> {
+	let sql = "
+		DEFINE TABLE employee SCHEMALESS PERMISSIONS FULL;
+		DEFINE FIELD salary ON employee PERMISSIONS FOR select WHERE $scope = 'admin' FOR create, update NONE;
+		DEFINE FIELD name ON employee PERMISSIONS FOR select FULL FOR update WHERE $before = NONE;
+		CREATE employee:one SET name = 'Tobie', salary = 100;
+	";
+	let dbs = Datastore::new("memory").await?;
+	let ses = Session::for_kv().with_ns("test").with_db("test");
+	let res = &mut dbs.execute(sql, &ses, None, false).await?;
+	assert_eq!(res.len(), 4);
+	//
+	for _ in 0..4 {
+		let tmp = res.remove(0).result;
+		assert!(tmp.is_ok());
+	}
+	// Hidden fields can not be selected, or used in expressions and conditions
+	let sql = "
+		SELECT * FROM employee;
+		SELECT name, salary AS pay FROM employee;
+		SELECT name FROM employee WHERE salary = 100;
+		UPDATE employee:one SET name = 'Jaime', salary = 200;
+	";
+	let ses = Session::for_sc("test", "test", "user");
+	let res = &mut dbs.execute(sql, &ses, None, false).await?;
+	assert_eq!(res.len(), 4);
+	//
+	let tmp = res.remove(0).result?;
+	let val = Value::parse("[{ id: employee:one, name: 'Tobie' }]");
+	assert_eq!(tmp, val);
+	//
+	let tmp = res.remove(0).result?;
+	let val = Value::parse("[{ name: 'Tobie', pay: NONE }]");
+	assert_eq!(tmp, val);
+	//
+	let tmp = res.remove(0).result?;
+	let val = Value::parse("[]");
+	assert_eq!(tmp, val);
+	//
+	let tmp = res.remove(0).result?;
+	let val = Value::parse("[{ id: employee:one, name: 'Tobie' }]");
+	assert_eq!(tmp, val);
+	// The fields are visible when the permissions allow it
+	let sql = "SELECT * FROM employee WHERE salary = 100";
+	let ses = Session::for_sc("test", "test", "admin");
+	let res = &mut dbs.execute(sql, &ses, None, false).await?;
+	assert_eq!(res.len(), 1);
+	//
+	let tmp = res.remove(0).result?;
+	let val = Value::parse("[{ id: employee:one, name: 'Tobie', salary: 100 }]");
+	assert_eq!(tmp, val);
+	//
+	Ok(())
+}
+
+#[tokio::test]
+async fn field_definition_bytes_from_base64() -> Result<(), Error> {
+	let sql = "
+		DEFINE FIELD data ON file TYPE bytes;
+		CREATE file:one SET data = 'AQID';
+		CREATE file:two SET data = 'AQI=';
+		CREATE file:three SET data = 'not base64!';
+		CREATE file:four SET data = 123;
+		SELECT VALUE encoding::base64::encode(data) FROM file:one, file:two;
+	";
+	let dbs = Datastore::new("memory").await?;
+	let ses = Session::for_kv().with_ns("test").with_db("test");
+	let res = &mut dbs.execute(sql, &ses, None, false).await?;
+	assert_eq!(res.len(), 6);
+	//
+	for _ in 0..3 {
+		let tmp = res.remove(0).result;
+		assert!(tmp.is_ok());
+	}
+	//
+	let tmp = res.remove(0).result;
+	assert!(
+		matches!(
+			&tmp,
+			Err(e) if e.to_string() == "Found 'not base64!' for field `data`, with record `file:three`, but expected a bytes"
+		),
+		"{tmp:?}"
+	);
+	//
+	let tmp = res.remove(0).result;
+	assert!(
+		matches!(
+			&tmp,
+			Err(e) if e.to_string() == "Found 123 for field `data`, with record `file:four`, but expected a bytes"
+		),
+		"{tmp:?}"
+	);
+	//
+	let tmp = res.remove(0).result?;
+	let val = Value::parse("['AQID', 'AQI']");
+	assert_eq!(tmp, val);
+	//
+	Ok(())
+}
+
+#[tokio::test]
+async fn field_definition_computed_value() -> Result<(), Error> {
+	let sql = "
+		DEFINE FIELD total ON purchase VALUE price * quantity COMPUTED;
+		CREATE purchase:one SET price = 5, quantity = 2;
+		UPDATE purchase:one SET quantity = 3;
+		UPDATE purchase:one SET total = 100;
+		CREATE purchase:two SET price = 5, quantity = 2, total = 1;
+		UPDATE purchase:one CONTENT { price: 2, quantity: 2 };
+	";
+	let dbs = Datastore::new("memory").await?;
+	let ses = Session::for_kv().with_ns("test").with_db("test");
+	let res = &mut dbs.execute(sql, &ses, None, false).await?;
+	assert_eq!(res.len(), 6);
+	//
+	let tmp = res.remove(0).result;
+	assert!(tmp.is_ok());
+	//
+	let tmp = res.remove(0).result?;
+	let val = Value::parse("[{ id: purchase:one, price: 5, quantity: 2, total: 10 }]");
+	assert_eq!(tmp, val);
+	// The value is recalculated on every write
+	let tmp = res.remove(0).result?;
+	let val = Value::parse("[{ id: purchase:one, price: 5, quantity: 3, total: 15 }]");
+	assert_eq!(tmp, val);
+	// The value can not be set directly
+	let tmp = res.remove(0).result;
+	assert!(
+		matches!(
+			&tmp,
+			Err(e) if e.to_string() == "Found 100 for field `total`, with record `purchase:one`, but field is computed and can not be set"
+		),
+		"{tmp:?}"
+	);
+	//
+	let tmp = res.remove(0).result;
+	assert!(
+		matches!(
+			&tmp,
+			Err(e) if e.to_string() == "Found 1 for field `total`, with record `purchase:two`, but field is computed and can not be set"
+		),
+		"{tmp:?}"
+	);
+	// Replacing the record without the field recalculates it
+	let tmp = res.remove(0).result?;
+	let val = Value::parse("[{ id: purchase:one, price: 2, quantity: 2, total: 4 }]");
+	assert_eq!(tmp, val);
+	//
+	Ok(())
+}
+
+#[tokio::test]
+async fn field_definition_assert_messages() -> Result<(), Error> {
+	let sql = "
+		DEFINE FIELD price ON product ASSERT $value > 0 MESSAGE 'The price must be positive';
+		DEFINE FIELD stock ON product ASSERT $value >= 0;
+		CREATE product:one SET price = 0, stock = 1;
+		CREATE product:two SET price = -1, stock = -1;
+		CREATE product:three SET price = 10, stock = 1;
+	";
+	let dbs = Datastore::new("memory").await?;
+	let ses = Session::for_kv().with_ns("test").with_db("test");
+	let res = &mut dbs.execute(sql, &ses, None, false).await?;
+	assert_eq!(res.len(), 5);
+	//
+	for _ in 0..2 {
+		let tmp = res.remove(0).result;
+		assert!(tmp.is_ok());
+	}
+	//
+	let tmp = res.remove(0).result;
+	assert!(
+		matches!(
+			&tmp,
+			Err(e) if e.to_string() == "Invalid value for field `price`, with record `product:one`: The price must be positive"
+		),
+		"{tmp:?}"
+	);
+	// Every failed assertion is reported
+	let tmp = res.remove(0).result;
+	assert!(
+		matches!(
+			&tmp,
+			Err(e) if e.to_string() == "Found 2 invalid fields, with record `product:two`: Invalid value for field `price`, with record `product:two`: The price must be positive; Found -1 for field `stock`, with record `product:two`, but field must conform to: $value >= 0"
+		),
+		"{tmp:?}"
+	);
+	//
+	let tmp = res.remove(0).result?;
+	let val = Value::parse("[{ id: product:three, price: 10, stock: 1 }]");
+	assert_eq!(tmp, val);
+	//
+	Ok(())
+}
@@ -0,0 +1,99 @@
+mod parse;
+use parse::Parse;
+use surrealdb::dbs::Session;
+use surrealdb::err::Error;
+use surrealdb::kvs::Datastore;
+use surrealdb::sql::Value;
+
+#[tokio::test]
+async fn foreach_with_ifelse() -> Result<(), Error> {
+	let sql = "
+		FOR $name IN ['Tobie', 'Jaime', 'Tobie'] {
+			LET $person = (SELECT * FROM person WHERE name = $name);
+			IF $person[0].id THEN
+				(UPDATE $person[0].id SET visits += 1)
+			ELSE
+				(CREATE person SET name = $name, visits = 1)
+			END;
+		};
+		SELECT name, visits FROM person ORDER BY name;
+	";
+	let dbs = Datastore::new("memory").await?;
+	let ses = Session::for_kv().with_ns("test").with_db("test");
+	let res = &mut dbs.execute(sql, &ses, None, false).await?;
+	assert_eq!(res.len(), 2);
+	//
+	let tmp = res.remove(0).result?;
+	let val = Value::None;
+	assert_eq!(tmp, val);
+	//
+	let tmp = res.remove(0).result?;
+	let val = Value::parse(
+		"[
+			{
+				name: 'Jaime',
+				visits: 1
+			},
+			{
+				name: 'Tobie',
+				visits: 2
+			}
+		]",
+	);
+	assert_eq!(tmp, val);
+	//
+	Ok(())
+}
+
+#[tokio::test]
+async fn foreach_requires_an_array() -> Result<(), Error> {
+	let sql = "
+		FOR $test IN 'test' { CREATE person; };
+	";
+	let dbs = Datastore::new("memory").await?;
+	let ses = Session::for_kv().with_ns("test").with_db("test");
+	let res = &mut dbs.execute(sql, &ses, None, false).await?;
+	assert_eq!(res.len(), 1);
+	//
+	let tmp = res.remove(0).result;
+	assert!(matches!(
+		tmp,
+		Err(Error::InvalidForeach {
+			value,
+		}) if value == "'test'"
+	));
+	//
+	Ok(())
+}
+
+#[tokio::test]
+async fn throw_cancels_transaction() -> Result<(), Error> {
+	let sql = "
+		BEGIN TRANSACTION;
+		LET $account = (CREATE account:one SET balance = 10);
+		IF $account[0].balance < 20 THEN {
+			THROW 'Insufficient balance: ' + <string> $account[0].balance;
+		} END;
+		COMMIT TRANSACTION;
+		SELECT * FROM account;
+	";
+	let dbs = Datastore::new("memory").await?;
+	let ses = Session::for_kv().with_ns("test").with_db("test");
+	let res = &mut dbs.execute(sql, &ses, None, false).await?;
+	assert_eq!(res.len(), 3);
+	//
+	let tmp = res.remove(0).result;
+	assert!(matches!(tmp, Err(Error::QueryNotExecuted)));
+	//
+	let tmp = res.remove(0).result;
+	assert!(matches!(
+		tmp,
+		Err(Error::Thrown(msg)) if msg == "Insufficient balance: 10"
+	));
+	//
+	let tmp = res.remove(0).result?;
+	let val = Value::parse("[]");
+	assert_eq!(tmp, val);
+	//
+	Ok(())
+}
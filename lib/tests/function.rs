@@ -3,7 +3,7 @@ use parse::Parse;
 use surrealdb::dbs::Session;
 use surrealdb::err::Error;
 use surrealdb::kvs::Datastore;
-use surrealdb::sql::{Number, Value};
+use surrealdb::sql::{Number, Part, Value};
 
 // --------------------------------------------------
 // array
@@ -567,6 +567,43 @@ async fn function_array_min() -> Result<(), Error> {
 	Ok(())
 }
 
+#[tokio::test]
+async fn function_array_pivot() -> Result<(), Error> {
+	let sql = r#"
+		RETURN array::pivot([], "country", "total");
+		RETURN array::pivot("some text", "country", "total");
+		RETURN array::pivot([{ country: "GBP", total: 60 }, { country: "EUR", total: 40 }, 10], "country", "total");
+		RETURN array::pivot([{ country: "GBP", total: 60, count: 3 }, { total: 40 }], "country");
+	"#;
+	let dbs = Datastore::new("memory").await?;
+	let ses = Session::for_kv().with_ns("test").with_db("test");
+	let res = &mut dbs.execute(&sql, &ses, None, false).await?;
+	assert_eq!(res.len(), 4);
+	//
+	let tmp = res.remove(0).result?;
+	let val = Value::parse("{}");
+	assert_eq!(tmp, val);
+	//
+	let tmp = res.remove(0).result;
+	assert!(
+		matches!(
+			&tmp,
+			Err(e) if e.to_string() == "Incorrect arguments for function array::pivot(). Argument 1 was the wrong type. Expected a array but found 'some text'"
+		),
+		"{tmp:?}"
+	);
+	//
+	let tmp = res.remove(0).result?;
+	let val = Value::parse("{ EUR: 40, GBP: 60 }");
+	assert_eq!(tmp, val);
+	//
+	let tmp = res.remove(0).result?;
+	let val = Value::parse("{ GBP: { count: 3, total: 60 } }");
+	assert_eq!(tmp, val);
+	//
+	Ok(())
+}
+
 #[tokio::test]
 async fn function_array_pop() -> Result<(), Error> {
 	let sql = r#"
@@ -1013,6 +1050,28 @@ async fn function_count() -> Result<(), Error> {
 // crypto
 // --------------------------------------------------
 
+#[tokio::test]
+async fn function_crypto_hmac() -> Result<(), Error> {
+	let sql = r#"
+		RETURN crypto::hmac::sha1('secret', 'tobie');
+		RETURN crypto::hmac::sha256('secret', 'tobie');
+	"#;
+	let dbs = Datastore::new("memory").await?;
+	let ses = Session::for_kv().with_ns("test").with_db("test");
+	let res = &mut dbs.execute(&sql, &ses, None, false).await?;
+	assert_eq!(res.len(), 2);
+	//
+	let tmp = res.remove(0).result?;
+	let val = Value::from("29cb276697c7558f6438e0efc82ab72cff85a425");
+	assert_eq!(tmp, val);
+	//
+	let tmp = res.remove(0).result?;
+	let val = Value::from("05fc8eddf42086ffc86b5f9e5d0d46a73f055129e5b4f96bfe4f9200a4021eb7");
+	assert_eq!(tmp, val);
+	//
+	Ok(())
+}
+
 #[tokio::test]
 async fn function_crypto_md5() -> Result<(), Error> {
 	let sql = r#"
@@ -1552,6 +1611,37 @@ async fn function_encoding_base64_encode() -> Result<(), Error> {
 	Ok(())
 }
 
+// --------------------------------------------------
+// fuzzy
+// --------------------------------------------------
+
+#[tokio::test]
+async fn function_fuzzy() -> Result<(), Error> {
+	let sql = r#"
+		RETURN fuzzy("kitten", "sitting", 3);
+		RETURN fuzzy("kitten", "sitting", 2);
+		RETURN fuzzy("", "", 0);
+	"#;
+	let dbs = Datastore::new("memory").await?;
+	let ses = Session::for_kv().with_ns("test").with_db("test");
+	let res = &mut dbs.execute(&sql, &ses, None, false).await?;
+	assert_eq!(res.len(), 3);
+	//
+	let tmp = res.remove(0).result?;
+	let val = Value::Bool(true);
+	assert_eq!(tmp, val);
+	//
+	let tmp = res.remove(0).result?;
+	let val = Value::Bool(false);
+	assert_eq!(tmp, val);
+	//
+	let tmp = res.remove(0).result?;
+	let val = Value::Bool(true);
+	assert_eq!(tmp, val);
+	//
+	Ok(())
+}
+
 // --------------------------------------------------
 // geo
 // --------------------------------------------------
@@ -1709,6 +1799,56 @@ async fn function_parse_geo_hash_decode() -> Result<(), Error> {
 	Ok(())
 }
 
+// --------------------------------------------------
+// graph
+// --------------------------------------------------
+
+#[tokio::test]
+async fn function_graph_shortest_path() -> Result<(), Error> {
+	let sql = r#"
+		RELATE city:london->road:a->city:paris SET distance = 450;
+		RELATE city:paris->road:b->city:berlin SET distance = 1050;
+		RELATE city:london->road:c->city:amsterdam SET distance = 360;
+		RELATE city:amsterdam->road:d->city:berlin SET distance = 650;
+		RETURN graph::shortest_path(city:london, city:berlin, 'road');
+		RETURN graph::shortest_path(city:london, city:berlin, 'road', 'distance');
+		RETURN graph::shortest_path(city:london, city:berlin, 'road', 'distance', 1);
+		RETURN graph::shortest_path(city:berlin, city:london, 'road', 'distance');
+	"#;
+	let dbs = Datastore::new("memory").await?;
+	let ses = Session::for_kv().with_ns("test").with_db("test");
+	let res = &mut dbs.execute(&sql, &ses, None, false).await?;
+	assert_eq!(res.len(), 8);
+	//
+	for _ in 0..4 {
+		let tmp = res.remove(0).result;
+		assert!(tmp.is_ok());
+	}
+	//
+	let tmp = res.remove(0).result?;
+	assert_eq!(tmp.pick(&[Part::from("cost")]), Value::from(2));
+	//
+	let tmp = res.remove(0).result?;
+	let val = Value::parse(
+		"{
+			cost: 1010,
+			edges: [road:c, road:d],
+			path: [city:london, city:amsterdam, city:berlin],
+		}",
+	);
+	assert_eq!(tmp, val);
+	//
+	let tmp = res.remove(0).result?;
+	let val = Value::None;
+	assert_eq!(tmp, val);
+	//
+	let tmp = res.remove(0).result?;
+	let val = Value::None;
+	assert_eq!(tmp, val);
+	//
+	Ok(())
+}
+
 // --------------------------------------------------
 // is
 // --------------------------------------------------
@@ -2164,6 +2304,43 @@ async fn function_math_interquartile() -> Result<(), Error> {
 	Ok(())
 }
 
+#[tokio::test]
+async fn function_math_log() -> Result<(), Error> {
+	let sql = r#"
+		RETURN math::ln(1);
+		RETURN math::log(8, 2);
+		RETURN math::log10(1000);
+		RETURN math::log2(0.25);
+		RETURN math::log10(0);
+	"#;
+	let dbs = Datastore::new("memory").await?;
+	let ses = Session::for_kv().with_ns("test").with_db("test");
+	let res = &mut dbs.execute(&sql, &ses, None, false).await?;
+	assert_eq!(res.len(), 5);
+	//
+	let tmp = res.remove(0).result?;
+	let val = Value::from(0.0);
+	assert_eq!(tmp, val);
+	//
+	let tmp = res.remove(0).result?;
+	let val = Value::from(3.0);
+	assert_eq!(tmp, val);
+	//
+	let tmp = res.remove(0).result?;
+	let val = Value::from(3.0);
+	assert_eq!(tmp, val);
+	//
+	let tmp = res.remove(0).result?;
+	let val = Value::from(-2.0);
+	assert_eq!(tmp, val);
+	//
+	let tmp = res.remove(0).result?;
+	let val = Value::None;
+	assert_eq!(tmp, val);
+	//
+	Ok(())
+}
+
 #[tokio::test]
 async fn function_math_max() -> Result<(), Error> {
 	let sql = r#"
@@ -2197,11 +2374,12 @@ async fn function_math_mean() -> Result<(), Error> {
 		RETURN math::mean([]);
 		RETURN math::mean([101, 213, 202]);
 		RETURN math::mean([101.5, 213.5, 202.5]);
+		RETURN math::mean([0.1dec, 0.2dec]);
 	"#;
 	let dbs = Datastore::new("memory").await?;
 	let ses = Session::for_kv().with_ns("test").with_db("test");
 	let res = &mut dbs.execute(&sql, &ses, None, false).await?;
-	assert_eq!(res.len(), 3);
+	assert_eq!(res.len(), 4);
 	//
 	let tmp = res.remove(0).result?;
 	assert!(tmp.is_nan());
@@ -2214,6 +2392,11 @@ async fn function_math_mean() -> Result<(), Error> {
 	let val = Value::from(172.5);
 	assert_eq!(tmp, val);
 	//
+	let tmp = res.remove(0).result?;
+	let val = Value::parse("0.15dec");
+	assert!(tmp.is_decimal());
+	assert_eq!(tmp, val);
+	//
 	Ok(())
 }
 
@@ -2446,6 +2629,48 @@ async fn function_math_round() -> Result<(), Error> {
 	Ok(())
 }
 
+#[tokio::test]
+async fn function_math_trigonometry() -> Result<(), Error> {
+	let sql = r#"
+		RETURN math::sin(0);
+		RETURN math::cos(0);
+		RETURN math::atan(1) * 4;
+		RETURN math::rad2deg(math::acos(0));
+		RETURN math::asin(2);
+		RETURN math::sign(-12.5);
+	"#;
+	let dbs = Datastore::new("memory").await?;
+	let ses = Session::for_kv().with_ns("test").with_db("test");
+	let res = &mut dbs.execute(&sql, &ses, None, false).await?;
+	assert_eq!(res.len(), 6);
+	//
+	let tmp = res.remove(0).result?;
+	let val = Value::from(0.0);
+	assert_eq!(tmp, val);
+	//
+	let tmp = res.remove(0).result?;
+	let val = Value::from(1.0);
+	assert_eq!(tmp, val);
+	//
+	let tmp = res.remove(0).result?;
+	let val = Value::from(std::f64::consts::PI);
+	assert_eq!(tmp, val);
+	//
+	let tmp = res.remove(0).result?;
+	let val = Value::from(std::f64::consts::FRAC_PI_2.to_degrees());
+	assert_eq!(tmp, val);
+	//
+	let tmp = res.remove(0).result?;
+	let val = Value::None;
+	assert_eq!(tmp, val);
+	//
+	let tmp = res.remove(0).result?;
+	let val = Value::from(-1);
+	assert_eq!(tmp, val);
+	//
+	Ok(())
+}
+
 #[tokio::test]
 async fn function_math_spread() -> Result<(), Error> {
 	let sql = r#"
@@ -2737,6 +2962,162 @@ async fn function_not() -> Result<(), Error> {
 	Ok(())
 }
 
+// --------------------------------------------------
+// object
+// --------------------------------------------------
+
+#[tokio::test]
+async fn function_object_entries() -> Result<(), Error> {
+	let sql = r#"
+		RETURN object::entries({});
+		RETURN object::entries({ a: 1, b: true });
+		RETURN object::entries([]);
+	"#;
+	let dbs = Datastore::new("memory").await?;
+	let ses = Session::for_kv().with_ns("test").with_db("test");
+	let res = &mut dbs.execute(&sql, &ses, None, false).await?;
+	assert_eq!(res.len(), 3);
+	//
+	let tmp = res.remove(0).result?;
+	let val = Value::parse("[]");
+	assert_eq!(tmp, val);
+	//
+	let tmp = res.remove(0).result?;
+	let val = Value::parse("[['a', 1], ['b', true]]");
+	assert_eq!(tmp, val);
+	//
+	let tmp = res.remove(0).result;
+	assert!(
+		matches!(
+			&tmp,
+			Err(e) if e.to_string() == "Incorrect arguments for function object::entries(). Argument 1 was the wrong type. Expected a object but found []"
+		),
+		"{tmp:?}"
+	);
+	//
+	Ok(())
+}
+
+#[tokio::test]
+async fn function_object_from_entries() -> Result<(), Error> {
+	let sql = r#"
+		RETURN object::from_entries([]);
+		RETURN object::from_entries([['a', 1], ['b', true]]);
+		RETURN object::from_entries([['a', 1, 2]]);
+	"#;
+	let dbs = Datastore::new("memory").await?;
+	let ses = Session::for_kv().with_ns("test").with_db("test");
+	let res = &mut dbs.execute(&sql, &ses, None, false).await?;
+	assert_eq!(res.len(), 3);
+	//
+	let tmp = res.remove(0).result?;
+	let val = Value::parse("{}");
+	assert_eq!(tmp, val);
+	//
+	let tmp = res.remove(0).result?;
+	let val = Value::parse("{ a: 1, b: true }");
+	assert_eq!(tmp, val);
+	//
+	let tmp = res.remove(0).result;
+	assert!(
+		matches!(
+			&tmp,
+			Err(e) if e.to_string() == "Incorrect arguments for function object::from_entries(). The argument must be an array of [key, value] pairs."
+		),
+		"{tmp:?}"
+	);
+	//
+	Ok(())
+}
+
+#[tokio::test]
+async fn function_object_keys() -> Result<(), Error> {
+	let sql = r#"
+		RETURN object::keys({});
+		RETURN object::keys({ b: 2, a: 1 });
+	"#;
+	let dbs = Datastore::new("memory").await?;
+	let ses = Session::for_kv().with_ns("test").with_db("test");
+	let res = &mut dbs.execute(&sql, &ses, None, false).await?;
+	assert_eq!(res.len(), 2);
+	//
+	let tmp = res.remove(0).result?;
+	let val = Value::parse("[]");
+	assert_eq!(tmp, val);
+	//
+	let tmp = res.remove(0).result?;
+	let val = Value::parse("['a', 'b']");
+	assert_eq!(tmp, val);
+	//
+	Ok(())
+}
+
+#[tokio::test]
+async fn function_object_len() -> Result<(), Error> {
+	let sql = r#"
+		RETURN object::len({});
+		RETURN object::len({ a: 1, b: { c: 2 } });
+	"#;
+	let dbs = Datastore::new("memory").await?;
+	let ses = Session::for_kv().with_ns("test").with_db("test");
+	let res = &mut dbs.execute(&sql, &ses, None, false).await?;
+	assert_eq!(res.len(), 2);
+	//
+	let tmp = res.remove(0).result?;
+	let val = Value::from(0);
+	assert_eq!(tmp, val);
+	//
+	let tmp = res.remove(0).result?;
+	let val = Value::from(2);
+	assert_eq!(tmp, val);
+	//
+	Ok(())
+}
+
+#[tokio::test]
+async fn function_object_merge() -> Result<(), Error> {
+	let sql = r#"
+		RETURN object::merge({}, {});
+		RETURN object::merge({ a: 1, b: { c: 2 } }, { b: 3, d: 4 });
+	"#;
+	let dbs = Datastore::new("memory").await?;
+	let ses = Session::for_kv().with_ns("test").with_db("test");
+	let res = &mut dbs.execute(&sql, &ses, None, false).await?;
+	assert_eq!(res.len(), 2);
+	//
+	let tmp = res.remove(0).result?;
+	let val = Value::parse("{}");
+	assert_eq!(tmp, val);
+	//
+	let tmp = res.remove(0).result?;
+	let val = Value::parse("{ a: 1, b: 3, d: 4 }");
+	assert_eq!(tmp, val);
+	//
+	Ok(())
+}
+
+#[tokio::test]
+async fn function_object_values() -> Result<(), Error> {
+	let sql = r#"
+		RETURN object::values({});
+		RETURN object::values({ b: 2, a: 1 });
+	"#;
+	let dbs = Datastore::new("memory").await?;
+	let ses = Session::for_kv().with_ns("test").with_db("test");
+	let res = &mut dbs.execute(&sql, &ses, None, false).await?;
+	assert_eq!(res.len(), 2);
+	//
+	let tmp = res.remove(0).result?;
+	let val = Value::parse("[]");
+	assert_eq!(tmp, val);
+	//
+	let tmp = res.remove(0).result?;
+	let val = Value::parse("[1, 2]");
+	assert_eq!(tmp, val);
+	//
+	Ok(())
+}
+
 // --------------------------------------------------
 // parse
 // --------------------------------------------------
@@ -3118,6 +3499,44 @@ async fn function_rand_uuid_v7() -> Result<(), Error> {
 	Ok(())
 }
 
+// --------------------------------------------------
+// search
+// --------------------------------------------------
+
+#[tokio::test]
+async fn function_search_analyze() -> Result<(), Error> {
+	let sql = r#"
+		DEFINE ANALYZER simple TOKENIZERS blank,class FILTERS lowercase;
+		DEFINE ANALYZER english TOKENIZERS blank,class FILTERS ascii,lowercase,snowball(english);
+		RETURN search::analyze('simple', "Teachers are teaching!");
+		RETURN search::analyze('english', "Teachers are teaching!");
+		RETURN search::analyze('unknown', "Teachers are teaching!");
+	"#;
+	let dbs = Datastore::new("memory").await?;
+	let ses = Session::for_kv().with_ns("test").with_db("test");
+	let res = &mut dbs.execute(&sql, &ses, None, false).await?;
+	assert_eq!(res.len(), 5);
+	//
+	let tmp = res.remove(0).result;
+	assert!(tmp.is_ok());
+	//
+	let tmp = res.remove(0).result;
+	assert!(tmp.is_ok());
+	//
+	let tmp = res.remove(0).result?;
+	let val = Value::parse("['teachers', 'are', 'teaching', '!']");
+	assert_eq!(tmp, val);
+	//
+	let tmp = res.remove(0).result?;
+	let val = Value::parse("['teacher', 'are', 'teach', '!']");
+	assert_eq!(tmp, val);
+	//
+	let tmp = res.remove(0).result;
+	assert!(matches!(tmp, Err(Error::AzNotFound { .. })));
+	//
+	Ok(())
+}
+
 // --------------------------------------------------
 // string
 // --------------------------------------------------
@@ -3236,6 +3655,33 @@ async fn function_string_contains() -> Result<(), Error> {
 	Ok(())
 }
 
+#[tokio::test]
+async fn function_string_distance_levenshtein() -> Result<(), Error> {
+	let sql = r#"
+		RETURN string::distance::levenshtein("", "");
+		RETURN string::distance::levenshtein("kitten", "sitting");
+		RETURN string::distance::levenshtein("ประเทศไทย", "ประเทศ");
+	"#;
+	let dbs = Datastore::new("memory").await?;
+	let ses = Session::for_kv().with_ns("test").with_db("test");
+	let res = &mut dbs.execute(&sql, &ses, None, false).await?;
+	assert_eq!(res.len(), 3);
+	//
+	let tmp = res.remove(0).result?;
+	let val = Value::from(0);
+	assert_eq!(tmp, val);
+	//
+	let tmp = res.remove(0).result?;
+	let val = Value::from(3);
+	assert_eq!(tmp, val);
+	//
+	let tmp = res.remove(0).result?;
+	let val = Value::from(3);
+	assert_eq!(tmp, val);
+	//
+	Ok(())
+}
+
 #[tokio::test]
 async fn function_string_ends_with() -> Result<(), Error> {
 	let sql = r#"
@@ -3425,6 +3871,55 @@ async fn function_string_reverse() -> Result<(), Error> {
 	Ok(())
 }
 
+#[tokio::test]
+async fn function_string_similarity_fuzzy() -> Result<(), Error> {
+	let sql = r#"
+		RETURN string::similarity::fuzzy("hello world", "hlw") > 0;
+		RETURN string::similarity::fuzzy("hello world", "xyz");
+	"#;
+	let dbs = Datastore::new("memory").await?;
+	let ses = Session::for_kv().with_ns("test").with_db("test");
+	let res = &mut dbs.execute(&sql, &ses, None, false).await?;
+	assert_eq!(res.len(), 2);
+	//
+	let tmp = res.remove(0).result?;
+	let val = Value::Bool(true);
+	assert_eq!(tmp, val);
+	//
+	let tmp = res.remove(0).result?;
+	let val = Value::from(0);
+	assert_eq!(tmp, val);
+	//
+	Ok(())
+}
+
+#[tokio::test]
+async fn function_string_similarity_trigram() -> Result<(), Error> {
+	let sql = r#"
+		RETURN string::similarity::trigram("Word", "word!");
+		RETURN string::similarity::trigram("word", "two words") > 0.3;
+		RETURN string::similarity::trigram("abc", "xyz");
+	"#;
+	let dbs = Datastore::new("memory").await?;
+	let ses = Session::for_kv().with_ns("test").with_db("test");
+	let res = &mut dbs.execute(&sql, &ses, None, false).await?;
+	assert_eq!(res.len(), 3);
+	//
+	let tmp = res.remove(0).result?;
+	let val = Value::from(1.0);
+	assert_eq!(tmp, val);
+	//
+	let tmp = res.remove(0).result?;
+	let val = Value::Bool(true);
+	assert_eq!(tmp, val);
+	//
+	let tmp = res.remove(0).result?;
+	let val = Value::from(0.0);
+	assert_eq!(tmp, val);
+	//
+	Ok(())
+}
+
 #[tokio::test]
 async fn function_string_slice() -> Result<(), Error> {
 	let sql = r#"
@@ -3638,6 +4133,32 @@ async fn function_string_words() -> Result<(), Error> {
 // time
 // --------------------------------------------------
 
+#[tokio::test]
+async fn function_time_bucket() -> Result<(), Error> {
+	let sql = r#"
+		RETURN time::bucket("1987-06-22T08:30:45Z", 1h);
+		RETURN time::bucket("1987-06-22T08:30:45Z", 15m);
+		RETURN time::bucket("1987-06-22T08:30:45Z", 0s);
+	"#;
+	let dbs = Datastore::new("memory").await?;
+	let ses = Session::for_kv().with_ns("test").with_db("test");
+	let res = &mut dbs.execute(&sql, &ses, None, false).await?;
+	assert_eq!(res.len(), 3);
+	//
+	let tmp = res.remove(0).result?;
+	let val = Value::parse("'1987-06-22T08:00:00Z'");
+	assert_eq!(tmp, val);
+	//
+	let tmp = res.remove(0).result?;
+	let val = Value::parse("'1987-06-22T08:30:00Z'");
+	assert_eq!(tmp, val);
+	//
+	let tmp = res.remove(0).result;
+	assert!(matches!(tmp, Err(Error::InvalidArguments { .. })));
+	//
+	Ok(())
+}
+
 #[tokio::test]
 async fn function_time_ceil() -> Result<(), Error> {
 	let sql = r#"
@@ -4093,6 +4614,65 @@ async fn function_time_from_unix() -> Result<(), Error> {
 	Ok(())
 }
 
+#[tokio::test]
+async fn function_time_tz_format() -> Result<(), Error> {
+	let sql = r#"
+		RETURN time::tz::format("1987-06-22T22:30:00Z", "+02:00", "%Y-%m-%d %H:%M");
+		RETURN time::tz::format("1987-06-22T22:30:00Z", "-05:30", "%Y-%m-%d %H:%M %z");
+		RETURN time::tz::format("1987-06-22T22:30:00Z", "Europe/London", "%Y-%m-%d");
+	"#;
+	let dbs = Datastore::new("memory").await?;
+	let ses = Session::for_kv().with_ns("test").with_db("test");
+	let res = &mut dbs.execute(&sql, &ses, None, false).await?;
+	assert_eq!(res.len(), 3);
+	//
+	let tmp = res.remove(0).result?;
+	let val = Value::from("1987-06-23 00:30");
+	assert_eq!(tmp, val);
+	//
+	let tmp = res.remove(0).result?;
+	let val = Value::from("1987-06-22 17:00 -0530");
+	assert_eq!(tmp, val);
+	//
+	let tmp = res.remove(0).result;
+	assert!(
+		matches!(
+			&tmp,
+			Err(e) if e.to_string() == "Incorrect arguments for function time::tz::format(). The second argument must be a timezone offset, such as 'Z', '+05:30', or '-08:00'."
+		),
+		"{tmp:?}"
+	);
+	//
+	Ok(())
+}
+
+#[tokio::test]
+async fn function_time_tz_group() -> Result<(), Error> {
+	let sql = r#"
+		RETURN time::tz::group("1987-06-22T22:30:00Z", "+02:00", "day");
+		RETURN time::tz::group("1987-06-22T22:30:00Z", "-05:30", "month");
+		RETURN time::tz::group("1987-06-22T22:30:00Z", "Z", "hour");
+	"#;
+	let dbs = Datastore::new("memory").await?;
+	let ses = Session::for_kv().with_ns("test").with_db("test");
+	let res = &mut dbs.execute(&sql, &ses, None, false).await?;
+	assert_eq!(res.len(), 3);
+	//
+	let tmp = res.remove(0).result?;
+	let val = Value::parse("'1987-06-22T22:00:00Z'");
+	assert_eq!(tmp, val);
+	//
+	let tmp = res.remove(0).result?;
+	let val = Value::parse("'1987-06-01T05:30:00Z'");
+	assert_eq!(tmp, val);
+	//
+	let tmp = res.remove(0).result?;
+	let val = Value::parse("'1987-06-22T22:00:00Z'");
+	assert_eq!(tmp, val);
+	//
+	Ok(())
+}
+
 // --------------------------------------------------
 // type
 // --------------------------------------------------
@@ -4390,3 +4970,88 @@ async fn function_type_thing() -> Result<(), Error> {
 	//
 	Ok(())
 }
+
+#[tokio::test]
+async fn function_type_is() -> Result<(), Error> {
+	let sql = r#"
+		RETURN type::is::int(1);
+		RETURN type::is::int(1.5);
+		RETURN type::is::number(1.5);
+		RETURN type::is::string("1");
+		RETURN type::is::datetime(time::now());
+		RETURN type::is::record(person:tobie);
+		RETURN type::is::record(person:tobie, "city");
+		RETURN type::is::none(NONE);
+		RETURN type::is::null(NONE);
+	"#;
+	let dbs = Datastore::new("memory").await?;
+	let ses = Session::for_kv().with_ns("test").with_db("test");
+	let res = &mut dbs.execute(&sql, &ses, None, false).await?;
+	assert_eq!(res.len(), 9);
+	//
+	for val in [true, false, true, true, true, true, false, true, false] {
+		let tmp = res.remove(0).result?;
+		assert_eq!(tmp, Value::Bool(val));
+	}
+	//
+	Ok(())
+}
+
+#[tokio::test]
+async fn function_vector_operations() -> Result<(), Error> {
+	let sql = r#"
+		RETURN vector::add([1, 2, 3], [4, 5, 6]);
+		RETURN vector::dot([1, 2, 3], [4, 5, 6]);
+		RETURN vector::cross([1, 0, 0], [0, 1, 0]);
+		RETURN vector::magnitude([3, 4]);
+		RETURN vector::normalize([3, 4]);
+		RETURN vector::distance::euclidean([0, 0], [3, 4]);
+		RETURN vector::distance::manhattan([0, 0], [3, -4]);
+		RETURN vector::similarity::cosine([1, 0], [2, 0]);
+		RETURN vector::add([1, 2], [1, 2, 3]);
+	"#;
+	let dbs = Datastore::new("memory").await?;
+	let ses = Session::for_kv().with_ns("test").with_db("test");
+	let res = &mut dbs.execute(&sql, &ses, None, false).await?;
+	assert_eq!(res.len(), 9);
+	//
+	let tmp = res.remove(0).result?;
+	let val = Value::parse("[5, 7, 9]");
+	assert_eq!(tmp, val);
+	//
+	let tmp = res.remove(0).result?;
+	let val = Value::from(32);
+	assert_eq!(tmp, val);
+	//
+	let tmp = res.remove(0).result?;
+	let val = Value::parse("[0, 0, 1]");
+	assert_eq!(tmp, val);
+	//
+	let tmp = res.remove(0).result?;
+	let val = Value::from(5.0);
+	assert_eq!(tmp, val);
+	//
+	let tmp = res.remove(0).result?;
+	let val = Value::parse("[0.6, 0.8]");
+	assert_eq!(tmp, val);
+	//
+	let tmp = res.remove(0).result?;
+	let val = Value::from(5.0);
+	assert_eq!(tmp, val);
+	//
+	let tmp = res.remove(0).result?;
+	let val = Value::from(7);
+	assert_eq!(tmp, val);
+	//
+	let tmp = res.remove(0).result?;
+	let val = Value::from(1.0);
+	assert_eq!(tmp, val);
+	//
+	let tmp = res.remove(0);
+	assert!(matches!(
+		tmp.result,
+		Err(Error::InvalidArguments { name, .. }) if name == "vector::add"
+	));
+	//
+	Ok(())
+}
@@ -0,0 +1,51 @@
+mod parse;
+use parse::Parse;
+use surrealdb::dbs::Session;
+use surrealdb::err::Error;
+use surrealdb::kvs::Datastore;
+use surrealdb::sql::Value;
+
+#[tokio::test]
+async fn graph_recursive_traversal() -> Result<(), Error> {
+	let sql = "
+		CREATE person:a SET name = 'A';
+		CREATE person:b SET name = 'B';
+		CREATE person:c SET name = 'C';
+		CREATE person:d SET name = 'D';
+		RELATE person:a->reports_to->person:b;
+		RELATE person:b->reports_to->person:c;
+		RELATE person:c->reports_to->person:d;
+		RELATE person:d->reports_to->person:a;
+		SELECT VALUE ->reports_to*1..5 FROM person:a;
+		SELECT VALUE ->reports_to*2 FROM person:a;
+		SELECT VALUE <-reports_to*..2 FROM person:d;
+		SELECT VALUE ->reports_to*0..3.name FROM person:b;
+	";
+	let dbs = Datastore::new("memory").await?;
+	let ses = Session::for_kv().with_ns("test").with_db("test");
+	let res = &mut dbs.execute(sql, &ses, None, false).await?;
+	assert_eq!(res.len(), 12);
+	//
+	for _ in 0..8 {
+		let tmp = res.remove(0).result;
+		assert!(tmp.is_ok());
+	}
+	// The cycle back to the first record is not followed
+	let tmp = res.remove(0).result?;
+	let val = Value::parse("[[person:b, person:c, person:d]]");
+	assert_eq!(tmp, val);
+	//
+	let tmp = res.remove(0).result?;
+	let val = Value::parse("[[person:c]]");
+	assert_eq!(tmp, val);
+	//
+	let tmp = res.remove(0).result?;
+	let val = Value::parse("[[person:c, person:b]]");
+	assert_eq!(tmp, val);
+	//
+	let tmp = res.remove(0).result?;
+	let val = Value::parse("[['B', 'C', 'D', 'A']]");
+	assert_eq!(tmp, val);
+	//
+	Ok(())
+}
@@ -459,3 +459,154 @@ async fn select_multi_aggregate_composed() -> Result<(), Error> {
 	//
 	Ok(())
 }
+
+#[tokio::test]
+async fn select_group_rollup() -> Result<(), Error> {
+	let sql = "
+		CREATE temperature:1 SET country = 'GBP', time = '2020-01-01T08:00:00Z', value = 10;
+		CREATE temperature:2 SET country = 'GBP', time = '2020-02-01T08:00:00Z', value = 20;
+		CREATE temperature:3 SET country = 'GBP', time = '2021-01-01T08:00:00Z', value = 30;
+		CREATE temperature:4 SET country = 'EUR', time = '2021-01-01T08:00:00Z', value = 40;
+		SELECT count(), math::sum(value) AS total, country, time::year(time) AS year FROM temperature GROUP BY country, year ROLLUP;
+		SELECT count(), math::sum(value) AS total FROM temperature GROUP ALL;
+	";
+	let dbs = Datastore::new("memory").await?;
+	let ses = Session::for_kv().with_ns("test").with_db("test");
+	let res = &mut dbs.execute(&sql, &ses, None, false).await?;
+	assert_eq!(res.len(), 6);
+	//
+	for _ in 0..4 {
+		let tmp = res.remove(0).result;
+		assert!(tmp.is_ok());
+	}
+	//
+	let tmp = res.remove(0).result?;
+	let val = Value::parse(
+		"[
+			{
+				count: 1,
+				country: 'EUR',
+				total: 40,
+				year: 2021
+			},
+			{
+				count: 2,
+				country: 'GBP',
+				total: 30,
+				year: 2020
+			},
+			{
+				count: 1,
+				country: 'GBP',
+				total: 30,
+				year: 2021
+			},
+			{
+				count: 1,
+				country: 'EUR',
+				total: 40,
+				year: NONE
+			},
+			{
+				count: 3,
+				country: 'GBP',
+				total: 60,
+				year: NONE
+			},
+			{
+				count: 4,
+				country: NONE,
+				total: 100,
+				year: NONE
+			}
+		]",
+	);
+	assert_eq!(tmp, val);
+	//
+	let tmp = res.remove(0).result?;
+	let val = Value::parse(
+		"[
+			{
+				count: 4,
+				total: 100
+			}
+		]",
+	);
+	assert_eq!(tmp, val);
+	//
+	Ok(())
+}
+
+#[tokio::test]
+async fn select_group_fill() -> Result<(), Error> {
+	let sql = "
+		CREATE reading:1 SET sensor = 'a', time = '2020-01-01T08:15:00Z', value = 10;
+		CREATE reading:2 SET sensor = 'a', time = '2020-01-01T11:45:00Z', value = 40;
+		CREATE reading:3 SET sensor = 'b', time = '2020-01-01T08:30:00Z', value = 5;
+		CREATE reading:4 SET sensor = 'b', time = '2020-01-01T09:30:00Z', value = 7;
+		SELECT sensor, time::bucket(time, 1h) AS hour, math::sum(value) AS value FROM reading GROUP BY sensor, hour FILL none;
+		SELECT sensor, time::bucket(time, 1h) AS hour, math::sum(value) AS value FROM reading GROUP BY sensor, hour FILL previous;
+		SELECT sensor, time::bucket(time, 1h) AS hour, math::sum(value) AS value FROM reading GROUP BY sensor, hour FILL linear;
+	";
+	let dbs = Datastore::new("memory").await?;
+	let ses = Session::for_kv().with_ns("test").with_db("test");
+	let res = &mut dbs.execute(&sql, &ses, None, false).await?;
+	assert_eq!(res.len(), 7);
+	//
+	for _ in 0..4 {
+		let tmp = res.remove(0).result;
+		assert!(tmp.is_ok());
+	}
+	//
+	let tmp = res.remove(0).result?;
+	let val = Value::parse(
+		"[
+			{ hour: '2020-01-01T08:00:00Z', sensor: 'a', value: 10 },
+			{ hour: '2020-01-01T09:00:00Z', sensor: 'a', value: NONE },
+			{ hour: '2020-01-01T10:00:00Z', sensor: 'a', value: NONE },
+			{ hour: '2020-01-01T11:00:00Z', sensor: 'a', value: 40 },
+			{ hour: '2020-01-01T08:00:00Z', sensor: 'b', value: 5 },
+			{ hour: '2020-01-01T09:00:00Z', sensor: 'b', value: 7 }
+		]",
+	);
+	assert_eq!(tmp, val);
+	//
+	let tmp = res.remove(0).result?;
+	let val = Value::parse(
+		"[
+			{ hour: '2020-01-01T08:00:00Z', sensor: 'a', value: 10 },
+			{ hour: '2020-01-01T09:00:00Z', sensor: 'a', value: 10 },
+			{ hour: '2020-01-01T10:00:00Z', sensor: 'a', value: 10 },
+			{ hour: '2020-01-01T11:00:00Z', sensor: 'a', value: 40 },
+			{ hour: '2020-01-01T08:00:00Z', sensor: 'b', value: 5 },
+			{ hour: '2020-01-01T09:00:00Z', sensor: 'b', value: 7 }
+		]",
+	);
+	assert_eq!(tmp, val);
+	//
+	let tmp = res.remove(0).result?;
+	let val = Value::parse(
+		"[
+			{ hour: '2020-01-01T08:00:00Z', sensor: 'a', value: 10 },
+			{ hour: '2020-01-01T09:00:00Z', sensor: 'a', value: 20.0 },
+			{ hour: '2020-01-01T10:00:00Z', sensor: 'a', value: 30.0 },
+			{ hour: '2020-01-01T11:00:00Z', sensor: 'a', value: 40 },
+			{ hour: '2020-01-01T08:00:00Z', sensor: 'b', value: 5 },
+			{ hour: '2020-01-01T09:00:00Z', sensor: 'b', value: 7 }
+		]",
+	);
+	assert_eq!(tmp, val);
+	//
+	Ok(())
+}
+
+#[tokio::test]
+async fn select_group_fill_requires_time_bucket() -> Result<(), Error> {
+	let sql = "SELECT sensor, time::group(time, 'hour') AS hour FROM reading GROUP BY sensor, hour FILL linear";
+	let dbs = Datastore::new("memory").await?;
+	let ses = Session::for_kv().with_ns("test").with_db("test");
+	let res = dbs.execute(&sql, &ses, None, false).await;
+	assert!(matches!(res, Err(Error::InvalidFill { .. })));
+	//
+	Ok(())
+}
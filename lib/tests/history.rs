@@ -0,0 +1,48 @@
+mod parse;
+use parse::Parse;
+use surrealdb::dbs::Session;
+use surrealdb::err::Error;
+use surrealdb::kvs::Datastore;
+use surrealdb::sql::Value;
+
+#[tokio::test]
+async fn history_table_records_previous_versions() -> Result<(), Error> {
+	let sql = "
+		DEFINE TABLE person HISTORY;
+		CREATE person:tobie SET name = 'Tobie';
+		UPDATE person:tobie SET name = 'Jaime';
+		UPDATE person:tobie SET name = 'Jaime';
+		DELETE person:tobie;
+		SELECT action, auth.level AS level, record, version FROM person_history ORDER BY at;
+	";
+	let dbs = Datastore::new("memory").await?;
+	let ses = Session::for_kv().with_ns("test").with_db("test");
+	let res = &mut dbs.execute(&sql, &ses, None, false).await?;
+	assert_eq!(res.len(), 6);
+	//
+	for _ in 0..5 {
+		let tmp = res.remove(0).result;
+		assert!(tmp.is_ok());
+	}
+	// Unchanged records do not write a new version
+	let tmp = res.remove(0).result?;
+	let val = Value::parse(
+		"[
+			{
+				action: 'UPDATE',
+				level: 'KV',
+				record: person:tobie,
+				version: { id: person:tobie, name: 'Tobie' }
+			},
+			{
+				action: 'DELETE',
+				level: 'KV',
+				record: person:tobie,
+				version: { id: person:tobie, name: 'Jaime' }
+			}
+		]",
+	);
+	assert_eq!(tmp, val);
+	//
+	Ok(())
+}
@@ -0,0 +1,49 @@
+mod parse;
+use parse::Parse;
+use std::io::{Read, Write};
+use std::net::TcpListener;
+use surrealdb::dbs::Session;
+use surrealdb::err::Error;
+use surrealdb::kvs::Datastore;
+use surrealdb::sql::Value;
+
+/// Start a server which replies to a single request with a JSON body
+fn server(body: &'static str) -> String {
+	let listener = TcpListener::bind("127.0.0.1:0").unwrap();
+	let addr = format!("http://{}", listener.local_addr().unwrap());
+	std::thread::spawn(move || {
+		if let Some(Ok(mut stream)) = listener.incoming().next() {
+			let _ = stream.read(&mut [0; 1024]);
+			let res = format!(
+				"HTTP/1.1 200 OK\r\ncontent-type: application/json\r\ncontent-length: {}\r\nconnection: close\r\n\r\n{body}",
+				body.len()
+			);
+			let _ = stream.write_all(res.as_bytes());
+		}
+	});
+	addr
+}
+
+#[tokio::test]
+async fn http_functions_require_database_users() -> Result<(), Error> {
+	let addr = server(r#"{"status":"ok"}"#);
+	let sql = format!("RETURN http::get('{addr}/health');");
+	let dbs = Datastore::new("memory").await?;
+	// Scope users can not send requests
+	let ses = Session::for_sc("test", "test", "account");
+	let res = &mut dbs.execute(&sql, &ses, None, false).await?;
+	assert_eq!(res.len(), 1);
+	//
+	let tmp = res.remove(0).result;
+	assert!(matches!(tmp, Err(Error::QueryPermissions)));
+	// Database users can send requests
+	let ses = Session::for_kv().with_ns("test").with_db("test");
+	let res = &mut dbs.execute(&sql, &ses, None, false).await?;
+	assert_eq!(res.len(), 1);
+	//
+	let tmp = res.remove(0).result?;
+	let val = Value::parse("{ status: 'ok' }");
+	assert_eq!(tmp, val);
+	//
+	Ok(())
+}
@@ -0,0 +1,151 @@
+use serde_json::Value as Json;
+use surrealdb::dbs::Session;
+use surrealdb::err::Error;
+use surrealdb::kvs::Datastore;
+
+fn entries(path: &std::path::Path) -> Vec<Json> {
+	let out = std::fs::read_to_string(path).unwrap();
+	out.lines().map(|v| serde_json::from_str(v).unwrap()).collect()
+}
+
+#[tokio::test]
+async fn journal_mutating_statements() -> Result<(), Error> {
+	let dir = temp_dir::TempDir::new().unwrap();
+	let path = dir.path().join("journal.log");
+	let sql = "
+		LET $name = 'Tobie';
+		CREATE person:tobie SET name = $name;
+		SELECT * FROM person;
+		BEGIN;
+		CREATE person:jaime;
+		CANCEL;
+	";
+	let dbs = Datastore::new("memory").await?.with_journal(&path)?;
+	let ses = Session::for_kv().with_ns("test").with_db("test");
+	let res = &mut dbs.execute(sql, &ses, None, false).await?;
+	assert_eq!(res.len(), 4);
+	//
+	let out = entries(&path);
+	assert_eq!(out.len(), 4);
+	// The statement is journaled with its parameters
+	assert_eq!(out[0]["ns"], "test");
+	assert_eq!(out[0]["db"], "test");
+	assert_eq!(out[0]["sql"], "CREATE person:tobie SET name = $name");
+	assert_eq!(out[0]["vars"]["name"], "'Tobie'");
+	assert_eq!(out[1]["txn"], out[0]["txn"]);
+	assert_eq!(out[1]["status"], "COMMIT");
+	// The statements of a cancelled transaction are marked as cancelled
+	assert_eq!(out[2]["sql"], "CREATE person:jaime");
+	assert_ne!(out[2]["txn"], out[0]["txn"]);
+	assert_eq!(out[3]["txn"], out[2]["txn"]);
+	assert_eq!(out[3]["status"], "CANCEL");
+	//
+	Ok(())
+}
+
+#[tokio::test]
+async fn journal_transaction_kept_open() -> Result<(), Error> {
+	let dir = temp_dir::TempDir::new().unwrap();
+	let path = dir.path().join("journal.log");
+	let dbs = Datastore::new("memory").await?.with_journal(&path)?;
+	let ses = Session::for_kv().with_ns("test").with_db("test");
+	let id = dbs.begin(&ses).await?;
+	dbs.execute_in(&id, "CREATE person:tobie", &ses, None, false).await?;
+	dbs.execute_in(&id, "CREATE person:jaime", &ses, None, false).await?;
+	// The statements are journaled before the transaction is committed
+	let out = entries(&path);
+	assert_eq!(out.len(), 2);
+	assert_eq!(out[0]["txn"], out[1]["txn"]);
+	//
+	dbs.commit(&id, &ses).await?;
+	let out = entries(&path);
+	assert_eq!(out.len(), 3);
+	assert_eq!(out[2]["txn"], out[0]["txn"]);
+	assert_eq!(out[2]["status"], "COMMIT");
+	//
+	Ok(())
+}
+
+#[tokio::test]
+async fn journal_statement_writes() -> Result<(), Error> {
+	let dir = temp_dir::TempDir::new().unwrap();
+	let path = dir.path().join("journal.log");
+	let sql = "
+		DEFINE TABLE person SCHEMALESS;
+		CREATE person:tobie SET name = 'Tobie';
+		CREATE person SET name = 'Jaime', seed = rand();
+	";
+	let dbs = Datastore::new("memory").await?.with_journal(&path)?;
+	let ses = Session::for_kv().with_ns("test").with_db("test");
+	let res = &mut dbs.execute(sql, &ses, None, false).await?;
+	assert_eq!(res.len(), 3);
+	res.remove(0).result?;
+	res.remove(0).result?;
+	let tmp = res.remove(0).result?;
+	//
+	let out = entries(&path);
+	assert_eq!(out.len(), 6);
+	// Definitions are journaled without any writes
+	assert!(out[0].get("writes").is_none());
+	// Every statement which changes records is journaled with its writes
+	let writes = out[2]["writes"].as_array().unwrap();
+	assert_eq!(writes.len(), 1);
+	assert!(writes[0].as_str().unwrap().starts_with("UPDATE person:tobie CONTENT {"));
+	let id = tmp.first().pick(&["id".into()]).to_string();
+	let writes = out[4]["writes"].as_array().unwrap();
+	assert_eq!(writes.len(), 1);
+	assert!(writes[0].as_str().unwrap().starts_with(&format!("UPDATE {id} CONTENT {{")));
+	//
+	Ok(())
+}
+
+/// Get the SurrealQL which replays the committed statements of a journal
+fn replay(path: &std::path::Path) -> String {
+	let out = entries(path);
+	let mut sql = String::new();
+	for v in out.iter().filter(|v| v.get("status").is_none()) {
+		let committed = out.iter().any(|e| e["txn"] == v["txn"] && e["status"] == "COMMIT");
+		if !committed {
+			continue;
+		}
+		match v["writes"].as_array() {
+			Some(writes) => {
+				sql.push_str("OPTION IMPORT;\n");
+				for w in writes {
+					sql.push_str(&format!("{};\n", w.as_str().unwrap()));
+				}
+				sql.push_str("OPTION IMPORT = false;\n");
+			}
+			None => sql.push_str(&format!("{};\n", v["sql"].as_str().unwrap())),
+		}
+	}
+	sql
+}
+
+#[tokio::test]
+async fn journal_replay_field_values() -> Result<(), Error> {
+	let dir = temp_dir::TempDir::new().unwrap();
+	let path = dir.path().join("journal.log");
+	let sql = "
+		DEFINE FIELD token ON person VALUE $value OR rand::uuid();
+		CREATE person:tobie SET name = 'Tobie';
+		UPDATE person:tobie SET age = 33;
+	";
+	let dbs = Datastore::new("memory").await?.with_journal(&path)?;
+	let ses = Session::for_kv().with_ns("test").with_db("test");
+	for res in dbs.execute(sql, &ses, None, false).await? {
+		res.result?;
+	}
+	let sql = "SELECT * FROM person";
+	let one = dbs.execute(sql, &ses, None, false).await?.remove(0).result?;
+	// Replaying the journal gives the same field values
+	let other = Datastore::new("memory").await?;
+	for res in other.execute(&replay(&path), &ses, None, false).await? {
+		res.result?;
+	}
+	let two = other.execute(sql, &ses, None, false).await?.remove(0).result?;
+	assert_eq!(one, two);
+	assert!(one.first().pick(&["token".into()]).is_uuid());
+	//
+	Ok(())
+}
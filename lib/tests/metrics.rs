@@ -0,0 +1,27 @@
+use surrealdb::dbs::Session;
+use surrealdb::err::Error;
+use surrealdb::kvs::Datastore;
+
+#[tokio::test]
+async fn metrics_count_statements_and_operations() -> Result<(), Error> {
+	let sql = "
+		CREATE person:tobie;
+		SELECT * FROM person;
+		SELECT * FROM person;
+		CREATE person:tobie;
+	";
+	let dbs = Datastore::new("memory").await?;
+	let ses = Session::for_kv().with_ns("test").with_db("test");
+	let res = &mut dbs.execute(sql, &ses, None, false).await?;
+	assert_eq!(res.len(), 4);
+	//
+	let out = dbs.metrics().output();
+	assert!(out.contains("surrealdb_statements_total{type=\"create\"} 2\n"));
+	assert!(out.contains("surrealdb_statements_total{type=\"select\"} 2\n"));
+	assert!(out.contains("surrealdb_statement_errors_total{type=\"create\"} 1\n"));
+	assert!(out.contains("surrealdb_statement_errors_total{type=\"select\"} 0\n"));
+	assert!(out.contains("surrealdb_statement_duration_seconds_count{type=\"select\"} 2\n"));
+	assert!(!out.contains("surrealdb_kv_operations_total{op=\"commit\"} 0\n"));
+	//
+	Ok(())
+}
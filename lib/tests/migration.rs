@@ -0,0 +1,144 @@
+mod parse;
+use parse::Parse;
+use surrealdb::dbs::Session;
+use surrealdb::err::Error;
+use surrealdb::kvs::Datastore;
+use surrealdb::sql::Part;
+use surrealdb::sql::Value;
+
+#[tokio::test]
+async fn migrations_apply_and_revert() -> Result<(), Error> {
+	let sql = "
+		DEFINE MIGRATION v1 UP {
+			DEFINE TABLE person SCHEMAFULL;
+			DEFINE FIELD name ON person TYPE string;
+		} DOWN {
+			REMOVE TABLE person;
+		};
+		DEFINE MIGRATION v2 {
+			DEFINE FIELD age ON person TYPE int;
+			UPDATE person SET age = 18;
+		} DOWN {
+			REMOVE FIELD age ON person;
+			UPDATE person UNSET age;
+		};
+		APPLY MIGRATIONS TO v1;
+		CREATE person:tobie SET name = 'Tobie';
+		APPLY MIGRATIONS;
+		APPLY MIGRATIONS;
+		SELECT * FROM person;
+		APPLY MIGRATIONS TO v1;
+		SELECT * FROM person;
+		APPLY MIGRATIONS TO v3;
+	";
+	let dbs = Datastore::new("memory").await?;
+	let ses = Session::for_kv().with_ns("test").with_db("test");
+	let res = &mut dbs.execute(sql, &ses, None, false).await?;
+	assert_eq!(res.len(), 10);
+	//
+	let tmp = res.remove(0).result;
+	assert!(tmp.is_ok());
+	//
+	let tmp = res.remove(0).result;
+	assert!(tmp.is_ok());
+	//
+	let tmp = res.remove(0).result?;
+	let val = Value::parse("{ applied: ['v1'], reverted: [] }");
+	assert_eq!(tmp, val);
+	//
+	let tmp = res.remove(0).result;
+	assert!(tmp.is_ok());
+	//
+	let tmp = res.remove(0).result?;
+	let val = Value::parse("{ applied: ['v2'], reverted: [] }");
+	assert_eq!(tmp, val);
+	//
+	let tmp = res.remove(0).result?;
+	let val = Value::parse("{ applied: [], reverted: [] }");
+	assert_eq!(tmp, val);
+	//
+	let tmp = res.remove(0).result?;
+	let val = Value::parse("[{ id: person:tobie, name: 'Tobie', age: 18 }]");
+	assert_eq!(tmp, val);
+	//
+	let tmp = res.remove(0).result?;
+	let val = Value::parse("{ applied: [], reverted: ['v2'] }");
+	assert_eq!(tmp, val);
+	//
+	let tmp = res.remove(0).result?;
+	let val = Value::parse("[{ id: person:tobie, name: 'Tobie' }]");
+	assert_eq!(tmp, val);
+	//
+	let tmp = res.remove(0).result;
+	assert!(
+		matches!(&tmp, Err(e) if e.to_string() == "The migration 'v3' does not exist"),
+		"{tmp:?}"
+	);
+	//
+	Ok(())
+}
+
+#[tokio::test]
+async fn migrations_info_and_errors() -> Result<(), Error> {
+	let sql = "
+		DEFINE MIGRATION v1 { DEFINE TABLE person };
+		DEFINE MIGRATION v2 { DEFINE TABLE animal } COMMENT 'No way back';
+		DEFINE MIGRATION v3 { BEGIN; DEFINE TABLE plant; COMMIT };
+		APPLY MIGRATIONS TO v1;
+		INFO FOR MIGRATIONS;
+		APPLY MIGRATIONS;
+		APPLY MIGRATIONS TO v1;
+		REMOVE MIGRATION v2;
+		INFO FOR MIGRATIONS;
+	";
+	let dbs = Datastore::new("memory").await?;
+	let ses = Session::for_kv().with_ns("test").with_db("test");
+	let res = &mut dbs.execute(sql, &ses, None, false).await?;
+	assert_eq!(res.len(), 9);
+	//
+	let tmp = res.remove(0).result;
+	assert!(tmp.is_ok());
+	//
+	let tmp = res.remove(0).result;
+	assert!(tmp.is_ok());
+	//
+	let tmp = res.remove(0).result;
+	assert!(
+		matches!(&tmp, Err(e) if e.to_string() == "The migration 'v3' can not contain BEGIN statements"),
+		"{tmp:?}"
+	);
+	//
+	let tmp = res.remove(0).result;
+	assert!(tmp.is_ok());
+	//
+	let tmp = res.remove(0).result?;
+	let v1 = tmp.pick(&[Part::from("migrations"), Part::from("v1")]);
+	assert!(v1.pick(&[Part::from("applied")]).is_datetime());
+	let v2 = tmp.pick(&[Part::from("migrations"), Part::from("v2")]);
+	assert!(v2.pick(&[Part::from("applied")]).is_none());
+	assert_eq!(
+		v2.pick(&[Part::from("definition")]),
+		Value::from(
+			"DEFINE MIGRATION v2 UP { DEFINE TABLE animal SCHEMALESS; } COMMENT 'No way back'"
+		)
+	);
+	//
+	let tmp = res.remove(0).result?;
+	let val = Value::parse("{ applied: ['v2'], reverted: [] }");
+	assert_eq!(tmp, val);
+	//
+	let tmp = res.remove(0).result;
+	assert!(
+		matches!(&tmp, Err(e) if e.to_string() == "The migration 'v2' can not be reverted, as it has no DOWN script"),
+		"{tmp:?}"
+	);
+	//
+	let tmp = res.remove(0).result;
+	assert!(tmp.is_ok());
+	//
+	let tmp = res.remove(0).result?;
+	assert!(tmp.pick(&[Part::from("migrations"), Part::from("v1")]).is_some());
+	assert!(tmp.pick(&[Part::from("migrations"), Part::from("v2")]).is_none());
+	//
+	Ok(())
+}
@@ -31,6 +31,7 @@ async fn define_global_param() -> Result<(), Error> {
 			functions: {},
 			params: { test: 'DEFINE PARAM $test VALUE 12345' },
 			scopes: {},
+			sequences: {},
 			tables: {},
 		}",
 	);
@@ -84,3 +85,88 @@ async fn define_protected_param() -> Result<(), Error> {
 	//
 	Ok(())
 }
+
+#[tokio::test]
+async fn transaction_scoped_param() -> Result<(), Error> {
+	let sql = "
+		CREATE person:one;
+		LET $people = (SELECT id FROM person);
+		CREATE person:two;
+		RETURN $people;
+		LET $test = 1;
+		BEGIN TRANSACTION;
+		LET $test = 2;
+		LET $other = (CREATE person:three);
+		CANCEL TRANSACTION;
+		RETURN [$test, $other];
+		BEGIN TRANSACTION;
+		LET $test = 3;
+		COMMIT TRANSACTION;
+		RETURN $test;
+	";
+	let dbs = Datastore::new("memory").await?;
+	let ses = Session::for_kv().with_ns("test").with_db("test");
+	let res = &mut dbs.execute(&sql, &ses, None, false).await?;
+	assert_eq!(res.len(), 10);
+	//
+	for _ in 0..3 {
+		let tmp = res.remove(0).result;
+		assert!(tmp.is_ok());
+	}
+	// The result set is not selected again when it is used
+	let tmp = res.remove(0).result?;
+	let val = Value::parse("[{ id: person:one }]");
+	assert_eq!(tmp, val);
+	//
+	let tmp = res.remove(0).result;
+	assert!(tmp.is_ok());
+	//
+	for _ in 0..2 {
+		let tmp = res.remove(0).result;
+		assert!(matches!(tmp, Err(Error::QueryCancelled)));
+	}
+	// The variables set in the cancelled transaction are discarded
+	let tmp = res.remove(0).result?;
+	let val = Value::parse("[1, NONE]");
+	assert_eq!(tmp, val);
+	//
+	let tmp = res.remove(0).result;
+	assert!(tmp.is_ok());
+	//
+	let tmp = res.remove(0).result?;
+	let val = Value::parse("3");
+	assert_eq!(tmp, val);
+	//
+	Ok(())
+}
+
+#[tokio::test]
+async fn session_params_are_kept_between_queries() -> Result<(), Error> {
+	let sql = "
+		LET $tenant = 'acme';
+		BEGIN;
+		LET $locale = 'en-GB';
+		CANCEL;
+		BEGIN;
+		LET $region = 'eu';
+		COMMIT;
+	";
+	let dbs = Datastore::new("memory").await?;
+	let ses = Session::for_kv().with_ns("test").with_db("test");
+	let (res, vars) = dbs.execute_with_lets(&sql, &ses, None, false).await?;
+	assert_eq!(res.len(), 3);
+	// Variables of a cancelled transaction are not kept
+	assert_eq!(vars.len(), 2);
+	assert_eq!(vars.get("tenant"), Some(&Value::from("acme")));
+	assert_eq!(vars.get("region"), Some(&Value::from("eu")));
+	// The variables are available to the next query
+	let sql = "RETURN [$tenant, $region, $locale];";
+	let res = &mut dbs.execute(&sql, &ses, Some(vars), false).await?;
+	assert_eq!(res.len(), 1);
+	//
+	let tmp = res.remove(0).result?;
+	let val = Value::parse("['acme', 'eu', NONE]");
+	assert_eq!(tmp, val);
+	//
+	Ok(())
+}
@@ -0,0 +1,83 @@
+mod parse;
+use parse::Parse;
+use surrealdb::dbs::Session;
+use surrealdb::err::Error;
+use surrealdb::kvs::Datastore;
+use surrealdb::sql::Value;
+
+#[tokio::test]
+async fn partitioned_table_prunes_partitions() -> Result<(), Error> {
+	let sql = "
+		DEFINE TABLE events PARTITION BY time::month(created);
+		CREATE events:1 SET created = '2023-01-10T00:00:00Z';
+		CREATE events:2 SET created = '2023-02-10T00:00:00Z';
+		CREATE events:3 SET created = '2023-02-20T00:00:00Z';
+		SELECT id FROM events WHERE time::month(created) = 2;
+		SELECT id FROM events WHERE time::month(created) = 2 EXPLAIN;
+		UPDATE events:3 SET created = '2023-03-01T00:00:00Z';
+		SELECT id FROM events WHERE time::month(created) = 2;
+	";
+	let dbs = Datastore::new("memory").await?;
+	let ses = Session::for_kv().with_ns("test").with_db("test");
+	let res = &mut dbs.execute(sql, &ses, None, false).await?;
+	assert_eq!(res.len(), 8);
+	//
+	for _ in 0..4 {
+		res.remove(0).result?;
+	}
+	let tmp = res.remove(0).result?;
+	let val = Value::parse("[{ id: events:2 }, { id: events:3 }]");
+	assert_eq!(tmp, val);
+	//
+	let tmp = res.remove(0).result?;
+	let val = Value::parse(
+		"[
+			{
+				detail: {
+					plan: {
+						partition: 2
+					},
+					table: 'events',
+				},
+				operation: 'Iterate Index'
+			}
+		]",
+	);
+	assert_eq!(tmp, val);
+	//
+	res.remove(0).result?;
+	let tmp = res.remove(0).result?;
+	let val = Value::parse("[{ id: events:2 }]");
+	assert_eq!(tmp, val);
+	//
+	Ok(())
+}
+
+#[tokio::test]
+async fn remove_partition_deletes_its_records() -> Result<(), Error> {
+	let sql = "
+		CREATE events:1 SET created = '2023-01-10T00:00:00Z';
+		CREATE events:2 SET created = '2023-02-10T00:00:00Z';
+		DEFINE TABLE events PARTITION BY time::month(created);
+		CREATE events:3 SET created = '2023-01-20T00:00:00Z';
+		REMOVE PARTITION 1 ON events;
+		SELECT id FROM events;
+		REMOVE PARTITION 1 ON person;
+	";
+	let dbs = Datastore::new("memory").await?;
+	let ses = Session::for_kv().with_ns("test").with_db("test");
+	let res = &mut dbs.execute(sql, &ses, None, false).await?;
+	assert_eq!(res.len(), 7);
+	//
+	for _ in 0..5 {
+		res.remove(0).result?;
+	}
+	let tmp = res.remove(0).result?;
+	let val = Value::parse("[{ id: events:2 }]");
+	assert_eq!(tmp, val);
+	//
+	let tmp = res.remove(0).result;
+	assert!(matches!(tmp, Err(Error::TbNotFound { .. })));
+	//
+	Ok(())
+}
@@ -0,0 +1,104 @@
+mod parse;
+use parse::Parse;
+use surrealdb::dbs::QuotaLimits;
+use surrealdb::dbs::Session;
+use surrealdb::err::Error;
+use surrealdb::kvs::Datastore;
+use surrealdb::sql::Value;
+
+#[tokio::test]
+async fn quota_queries_per_identity() -> Result<(), Error> {
+	let sql = "
+		CREATE person:tobie;
+		SELECT * FROM person;
+		SELECT * FROM person;
+	";
+	let dbs = Datastore::new("memory").await?.with_quotas(QuotaLimits {
+		queries: Some(2),
+		..Default::default()
+	});
+	let ses = Session::for_db("test", "test");
+	let res = &mut dbs.execute(sql, &ses, None, false).await?;
+	assert_eq!(res.len(), 3);
+	//
+	let tmp = res.remove(0).result?;
+	let val = Value::parse("[{ id: person:tobie }]");
+	assert_eq!(tmp, val);
+	//
+	let tmp = res.remove(0).result?;
+	let val = Value::parse("[{ id: person:tobie }]");
+	assert_eq!(tmp, val);
+	//
+	let tmp = res.remove(0).result;
+	assert!(matches!(
+		tmp,
+		Err(Error::QuotaExceeded {
+			limit: 2,
+			..
+		})
+	));
+	// Further queries are rejected until the identity has capacity
+	let res = dbs.execute("SELECT * FROM person", &ses, None, false).await;
+	assert!(matches!(res, Err(Error::QuotaExceeded { .. })));
+	// Other identities have their own capacity
+	let ses = Session::for_db("test", "other");
+	let res = &mut dbs.execute("SELECT * FROM person", &ses, None, false).await?;
+	assert_eq!(res.len(), 1);
+	assert!(res.remove(0).result.is_ok());
+	// Root sessions are not limited
+	let ses = Session::for_kv().with_ns("test").with_db("test");
+	let res = &mut dbs.execute(sql, &ses, None, false).await?;
+	assert_eq!(res.len(), 3);
+	for _ in 0..3 {
+		assert!(res.remove(0).result.is_ok());
+	}
+	//
+	assert!(dbs
+		.quotas()
+		.metrics()
+		.contains("surrealdb_quota_exceeded_total{quota=\"queries\"} 2\n"));
+	//
+	Ok(())
+}
+
+#[tokio::test]
+async fn quota_live_queries_per_identity() -> Result<(), Error> {
+	let sql = "
+		LIVE SELECT * FROM person;
+		LIVE SELECT * FROM person;
+	";
+	let dbs = Datastore::new("memory").await?.with_quotas(QuotaLimits {
+		live: Some(1),
+		..Default::default()
+	});
+	let mut ses = Session::for_db("test", "test");
+	ses.rt = true;
+	let res = &mut dbs.execute(sql, &ses, None, false).await?;
+	assert_eq!(res.len(), 2);
+	//
+	let id = match res.remove(0).result? {
+		Value::Uuid(id) => id.0,
+		v => panic!("Expected a live query id, but found {v}"),
+	};
+	//
+	let tmp = res.remove(0).result;
+	assert!(matches!(
+		tmp,
+		Err(Error::QuotaExceeded {
+			limit: 1,
+			..
+		})
+	));
+	// Killing a live query releases its quota
+	let sql = format!("KILL '{id}'; LIVE SELECT * FROM person;");
+	let res = &mut dbs.execute(&sql, &ses, None, false).await?;
+	assert_eq!(res.len(), 2);
+	//
+	let tmp = res.remove(0).result?;
+	assert_eq!(tmp, Value::None);
+	//
+	let tmp = res.remove(0).result?;
+	assert!(matches!(tmp, Value::Uuid(_)));
+	//
+	Ok(())
+}
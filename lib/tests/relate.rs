@@ -102,3 +102,84 @@ async fn relate_and_overwrite() -> Result<(), Error> {
 	//
 	Ok(())
 }
+
+#[tokio::test]
+async fn relate_into_relation_table() -> Result<(), Error> {
+	let sql = "
+		DEFINE TABLE knows RELATION IN person OUT person | team;
+		RELATE person:tobie->knows->person:jaime SET id = knows:test;
+		RELATE person:tobie->knows->company:surreal;
+		CREATE knows:other;
+		UPDATE knows:test SET since = 2020;
+	";
+	let dbs = Datastore::new("memory").await?;
+	let ses = Session::for_kv().with_ns("test").with_db("test");
+	let res = &mut dbs.execute(&sql, &ses, None, false).await?;
+	assert_eq!(res.len(), 5);
+	//
+	let tmp = res.remove(0).result?;
+	let val = Value::None;
+	assert_eq!(tmp, val);
+	//
+	let tmp = res.remove(0).result?;
+	let val = Value::parse("[{ id: knows:test, in: person:tobie, out: person:jaime }]");
+	assert_eq!(tmp, val);
+	//
+	let tmp = res.remove(0).result;
+	assert!(matches!(
+		tmp,
+		Err(Error::RelationRecord {
+			dir: "out",
+			..
+		})
+	));
+	//
+	let tmp = res.remove(0).result;
+	assert!(matches!(tmp, Err(Error::RelationTable { .. })));
+	//
+	let tmp = res.remove(0).result?;
+	let val =
+		Value::parse("[{ id: knows:test, in: person:tobie, out: person:jaime, since: 2020 }]");
+	assert_eq!(tmp, val);
+	//
+	Ok(())
+}
+
+#[tokio::test]
+async fn relate_again_and_delete_endpoint() -> Result<(), Error> {
+	let sql = "
+		RELATE person:tobie->knows:test->person:jaime;
+		RELATE person:tobie->knows:test->person:lizzie;
+		SELECT ->knows->person AS out FROM person:tobie;
+		SELECT <-knows<-person AS from FROM person:jaime;
+		DELETE person:tobie;
+		SELECT * FROM knows;
+		SELECT <-knows<-person AS from FROM person:lizzie;
+	";
+	let dbs = Datastore::new("memory").await?;
+	let ses = Session::for_kv().with_ns("test").with_db("test");
+	let res = &mut dbs.execute(&sql, &ses, None, false).await?;
+	assert_eq!(res.len(), 7);
+	//
+	res.remove(0).result?;
+	res.remove(0).result?;
+	// The edges of the previous relation are removed
+	let tmp = res.remove(0).result?;
+	let val = Value::parse("[{ out: [person:lizzie] }]");
+	assert_eq!(tmp, val);
+	//
+	let tmp = res.remove(0).result?;
+	let val = Value::parse("[{ from: [] }]");
+	assert_eq!(tmp, val);
+	// The edges of a deleted record are removed
+	res.remove(0).result?;
+	let tmp = res.remove(0).result?;
+	let val = Value::parse("[]");
+	assert_eq!(tmp, val);
+	//
+	let tmp = res.remove(0).result?;
+	let val = Value::parse("[{ from: [] }]");
+	assert_eq!(tmp, val);
+	//
+	Ok(())
+}
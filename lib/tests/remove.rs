@@ -33,6 +33,7 @@ async fn remove_statement_table() -> Result<(), Error> {
 			functions: {},
 			params: {},
 			scopes: {},
+			sequences: {},
 			tables: {}
 		}",
 	);
@@ -67,6 +68,7 @@ async fn remove_statement_analyzer() -> Result<(), Error> {
 			functions: {},
 			params: {},
 			scopes: {},
+			sequences: {},
 			tables: {}
 		}",
 	);
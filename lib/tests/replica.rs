@@ -0,0 +1,99 @@
+mod parse;
+use parse::Parse;
+use surrealdb::dbs::Session;
+use surrealdb::err::Error;
+use surrealdb::kvs::Batch;
+use surrealdb::kvs::Datastore;
+use surrealdb::sql::Value;
+
+#[tokio::test]
+async fn replicate_write_stream() -> Result<(), Error> {
+	let leader = Datastore::new("memory").await?;
+	let follower = Datastore::new("memory").await?;
+	follower.set_read_only(true);
+	let writes = leader.writes().await;
+	//
+	let sql = "
+		CREATE person:tobie SET name = 'Tobie';
+		CREATE person:jaime SET name = 'Jaime';
+		DEFINE INDEX name ON person FIELDS name;
+		DELETE person:jaime;
+		SELECT * FROM person;
+	";
+	let ses = Session::for_kv().with_ns("test").with_db("test");
+	leader.execute(sql, &ses, None, false).await?;
+	assert_eq!(leader.sequence(), 4);
+	// Apply the batches to the follower
+	while let Ok(batch) = writes.try_recv() {
+		let (batch, _) = Batch::decode(&batch.encode())?.unwrap();
+		follower.apply(&batch).await?;
+	}
+	assert_eq!(follower.sequence(), 4);
+	//
+	let sql = "
+		SELECT * FROM person WHERE name = 'Tobie';
+		CREATE person:other SET name = 'Other';
+		SELECT * FROM person;
+	";
+	let res = &mut follower.execute(sql, &ses, None, false).await?;
+	assert_eq!(res.len(), 3);
+	//
+	let tmp = res.remove(0).result?;
+	let val = Value::parse("[{ id: person:tobie, name: 'Tobie' }]");
+	assert_eq!(tmp, val);
+	//
+	let tmp = res.remove(0).result;
+	assert!(matches!(tmp, Err(Error::ReplicaReadOnly)));
+	//
+	let tmp = res.remove(0).result?;
+	let val = Value::parse("[{ id: person:tobie, name: 'Tobie' }]");
+	assert_eq!(tmp, val);
+	//
+	Ok(())
+}
+
+#[tokio::test]
+async fn synchronous_write_unacknowledged() -> Result<(), Error> {
+	let dbs = Datastore::new("memory").await?;
+	dbs.set_synchronous(true);
+	let ses = Session::for_kv().with_ns("test").with_db("test");
+	let (res, _) = tokio::join!(dbs.execute("CREATE person:tobie", &ses, None, false), async {
+		tokio::time::sleep(std::time::Duration::from_millis(100)).await;
+		dbs.unacknowledge().await;
+	});
+	let tmp = res?.remove(0).result;
+	assert!(matches!(tmp, Err(Error::QueryNotExecutedDetail { .. })));
+	//
+	dbs.acknowledge(2).await;
+	let res = &mut dbs.execute("CREATE person:jaime", &ses, None, false).await?;
+	assert!(res.remove(0).result.is_ok());
+	//
+	Ok(())
+}
+
+#[tokio::test]
+async fn route_read_only_queries() -> Result<(), Error> {
+	let dbs = Datastore::new("memory").await?.with_replica("memory").await?;
+	let ses = Session::for_kv().with_ns("test").with_db("test");
+	//
+	let sql = "CREATE person:tobie SET name = 'Tobie'";
+	let res = &mut dbs.execute(sql, &ses, None, false).await?;
+	assert!(res.remove(0).result.is_ok());
+	// Read-only queries are served by the replica, which has not received the write
+	let sql = "SELECT * FROM person";
+	let res = &mut dbs.execute(sql, &ses, None, false).await?;
+	let tmp = res.remove(0).result?;
+	let val = Value::parse("[]");
+	assert_eq!(tmp, val);
+	// Queries which modify data are served by the primary
+	let sql = "
+		SELECT * FROM person;
+		CREATE person:jaime SET name = 'Jaime';
+	";
+	let res = &mut dbs.execute(sql, &ses, None, false).await?;
+	let tmp = res.remove(0).result?;
+	let val = Value::parse("[{ id: person:tobie, name: 'Tobie' }]");
+	assert_eq!(tmp, val);
+	//
+	Ok(())
+}
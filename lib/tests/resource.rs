@@ -0,0 +1,103 @@
+mod parse;
+use parse::Parse;
+use std::time::Duration;
+use surrealdb::dbs::Session;
+use surrealdb::err::Error;
+use surrealdb::kvs::Datastore;
+use surrealdb::sql::Value;
+
+/// Create a datastore with the limits which every test in this file uses
+async fn limited() -> Result<Datastore, Error> {
+	Ok(Datastore::new("memory")
+		.await?
+		.with_query_limits(Some(10), Some(100000))
+		.with_statement_timeout(Some(Duration::from_millis(200))))
+}
+
+#[tokio::test]
+async fn select_exceeds_max_query_rows() -> Result<(), Error> {
+	let sql: String = (0..20).map(|i| format!("CREATE person:{i};")).collect();
+	let dbs = limited().await?;
+	let ses = Session::for_kv().with_ns("test").with_db("test");
+	let res = &mut dbs.execute(&sql, &ses, None, false).await?;
+	assert_eq!(res.len(), 20);
+	for v in res.drain(..) {
+		v.result?;
+	}
+	//
+	let sql = "
+		SELECT * FROM person:1, person:2;
+		SELECT * FROM person;
+	";
+	let res = &mut dbs.execute(sql, &ses, None, false).await?;
+	assert_eq!(res.len(), 2);
+	//
+	let tmp = res.remove(0).result?;
+	let val = Value::parse("[{ id: person:1 }, { id: person:2 }]");
+	assert_eq!(tmp, val);
+	//
+	let tmp = res.remove(0).result;
+	assert!(matches!(
+		tmp,
+		Err(Error::QueryRowLimit {
+			limit: 10
+		})
+	));
+	//
+	Ok(())
+}
+
+#[tokio::test]
+async fn select_exceeds_max_query_memory() -> Result<(), Error> {
+	let text = "a".repeat(30000);
+	let sql: String = (0..5).map(|i| format!("CREATE doc:{i} SET text = '{text}';")).collect();
+	let dbs = limited().await?;
+	let ses = Session::for_kv().with_ns("test").with_db("test");
+	let res = &mut dbs.execute(&sql, &ses, None, false).await?;
+	assert_eq!(res.len(), 5);
+	for v in res.drain(..) {
+		v.result?;
+	}
+	//
+	let sql = "
+		SELECT id FROM doc;
+		SELECT * FROM doc;
+	";
+	let res = &mut dbs.execute(sql, &ses, None, false).await?;
+	assert_eq!(res.len(), 2);
+	//
+	let tmp = res.remove(0).result?;
+	let val =
+		Value::parse("[{ id: doc:0 }, { id: doc:1 }, { id: doc:2 }, { id: doc:3 }, { id: doc:4 }]");
+	assert_eq!(tmp, val);
+	//
+	let tmp = res.remove(0).result;
+	assert!(matches!(
+		tmp,
+		Err(Error::QueryMemoryLimit {
+			limit: 100000
+		})
+	));
+	//
+	Ok(())
+}
+
+#[tokio::test]
+async fn select_exceeds_statement_timeout() -> Result<(), Error> {
+	let sql = "
+		SELECT * FROM sleep(500ms);
+		SELECT * FROM sleep(500ms) TIMEOUT 1s;
+	";
+	let dbs = limited().await?;
+	let ses = Session::for_kv().with_ns("test").with_db("test");
+	let res = &mut dbs.execute(sql, &ses, None, false).await?;
+	assert_eq!(res.len(), 2);
+	// The default statement timeout applies without a TIMEOUT clause
+	let tmp = res.remove(0).result;
+	assert!(matches!(tmp, Err(Error::QueryTimedout)));
+	// A TIMEOUT clause overrides the default statement timeout
+	let tmp = res.remove(0).result;
+	assert!(tmp.is_ok());
+	//
+	Ok(())
+}
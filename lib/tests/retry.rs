@@ -0,0 +1,32 @@
+use std::time::Duration;
+use surrealdb::dbs::Session;
+use surrealdb::err::{Error, RetryReason};
+use surrealdb::kvs::Datastore;
+
+#[tokio::test]
+async fn retry_hint_for_timed_out_statement() -> Result<(), Error> {
+	let sql = "
+		SELECT * FROM sleep(500ms) TIMEOUT 10ms;
+		RETURN fn::missing();
+	";
+	let dbs = Datastore::new("memory").await?;
+	let ses = Session::for_kv().with_ns("test").with_db("test");
+	let res = &mut dbs.execute(sql, &ses, None, false).await?;
+	assert_eq!(res.len(), 2);
+	//
+	let tmp = res.remove(0);
+	let retry = tmp.result.as_ref().unwrap_err().retry().unwrap();
+	assert_eq!(retry.reason, RetryReason::Timeout);
+	assert_eq!(retry.backoff, Duration::from_millis(500));
+	let tmp = serde_json::to_value(&tmp).unwrap();
+	assert_eq!(tmp["retry"]["retryable"], true);
+	assert_eq!(tmp["retry"]["reason"], "timeout");
+	assert_eq!(tmp["retry"]["backoff"], 500);
+	//
+	let tmp = res.remove(0);
+	assert!(tmp.result.as_ref().unwrap_err().retry().is_none());
+	let tmp = serde_json::to_value(&tmp).unwrap();
+	assert!(tmp.get("retry").is_none());
+	//
+	Ok(())
+}
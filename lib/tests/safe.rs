@@ -0,0 +1,181 @@
+mod parse;
+use parse::Parse;
+use surrealdb::dbs::Session;
+use surrealdb::err::Error;
+use surrealdb::kvs::Datastore;
+use surrealdb::sql::Value;
+
+#[tokio::test]
+async fn update_and_delete_limit() -> Result<(), Error> {
+	let sql = "
+		CREATE person:a SET age = 40;
+		CREATE person:b SET age = 10;
+		CREATE person:c SET age = 30;
+		CREATE person:d SET age = 20;
+		UPDATE person SET age += 1 LIMIT 2 RETURN id;
+		DELETE person WHERE age < 35 ORDER BY age LIMIT 2 RETURN BEFORE;
+		UPDATE person SET old = true ORDER BY age DESC LIMIT 1 RETURN id;
+		SELECT * FROM person;
+	";
+	let dbs = Datastore::new("memory").await?;
+	let ses = Session::for_kv().with_ns("test").with_db("test");
+	let res = &mut dbs.execute(sql, &ses, None, false).await?;
+	assert_eq!(res.len(), 8);
+	//
+	for _ in 0..4 {
+		res.remove(0).result?;
+	}
+	// Only the LIMIT of records are modified
+	let tmp = res.remove(0).result?;
+	let val = Value::parse("[{ id: person:a }, { id: person:b }]");
+	assert_eq!(tmp, val);
+	// The records are modified in the order of the ORDER clause
+	let tmp = res.remove(0).result?;
+	let val = Value::parse(
+		"[
+			{ id: person:b, age: 11 },
+			{ id: person:d, age: 20 }
+		]",
+	);
+	assert_eq!(tmp, val);
+	//
+	let tmp = res.remove(0).result?;
+	let val = Value::parse("[{ id: person:a }]");
+	assert_eq!(tmp, val);
+	//
+	let tmp = res.remove(0).result?;
+	let val = Value::parse(
+		"[
+			{ id: person:a, age: 41, old: true },
+			{ id: person:c, age: 30 }
+		]",
+	);
+	assert_eq!(tmp, val);
+	//
+	Ok(())
+}
+
+#[tokio::test]
+async fn update_and_delete_strict_limit() -> Result<(), Error> {
+	let sql = "
+		CREATE person:tobie SET age = 30;
+		CREATE person:jaime SET age = 40;
+		UPDATE person SET age += 1 LIMIT 1 STRICT;
+		DELETE person WHERE age > 10 ORDER BY age LIMIT 1 STRICT;
+		UPDATE person SET age += 1 WHERE age > 35 LIMIT 1 STRICT RETURN NONE;
+		SELECT * FROM person;
+	";
+	let dbs = Datastore::new("memory").await?;
+	let ses = Session::for_kv().with_ns("test").with_db("test");
+	let res = &mut dbs.execute(sql, &ses, None, false).await?;
+	assert_eq!(res.len(), 6);
+	//
+	for _ in 0..2 {
+		res.remove(0).result?;
+	}
+	// A STRICT limit fails if more records would be modified
+	let tmp = res.remove(0).result;
+	assert!(matches!(
+		tmp,
+		Err(Error::LimitExceeded {
+			limit: 1
+		})
+	));
+	//
+	let tmp = res.remove(0).result;
+	assert!(matches!(
+		tmp,
+		Err(Error::LimitExceeded {
+			limit: 1
+		})
+	));
+	//
+	let tmp = res.remove(0).result?;
+	let val = Value::parse("[]");
+	assert_eq!(tmp, val);
+	// The records are left unchanged by the failed statements
+	let tmp = res.remove(0).result?;
+	let val = Value::parse(
+		"[
+			{ id: person:jaime, age: 41 },
+			{ id: person:tobie, age: 30 }
+		]",
+	);
+	assert_eq!(tmp, val);
+	//
+	Ok(())
+}
+
+#[tokio::test]
+async fn option_safe_mode() -> Result<(), Error> {
+	let sql = "
+		CREATE person:tobie SET age = 30;
+		OPTION SAFE;
+		UPDATE person SET age += 1;
+		DELETE person;
+		UPDATE person SET age += 1 WHERE age > 10;
+		UPDATE person:tobie SET age += 1;
+		DELETE person LIMIT 10;
+		SELECT * FROM person;
+	";
+	let dbs = Datastore::new("memory").await?;
+	let ses = Session::for_kv().with_ns("test").with_db("test");
+	let res = &mut dbs.execute(sql, &ses, None, false).await?;
+	assert_eq!(res.len(), 7);
+	//
+	let tmp = res.remove(0).result;
+	assert!(tmp.is_ok());
+	//
+	let tmp = res.remove(0).result;
+	assert!(matches!(tmp, Err(Error::UnsafeStatement { .. })));
+	//
+	let tmp = res.remove(0).result;
+	assert!(matches!(tmp, Err(Error::UnsafeStatement { .. })));
+	//
+	let tmp = res.remove(0).result?;
+	let val = Value::parse("[{ id: person:tobie, age: 31 }]");
+	assert_eq!(tmp, val);
+	//
+	let tmp = res.remove(0).result?;
+	let val = Value::parse("[{ id: person:tobie, age: 32 }]");
+	assert_eq!(tmp, val);
+	//
+	let tmp = res.remove(0).result?;
+	let val = Value::parse("[]");
+	assert_eq!(tmp, val);
+	//
+	let tmp = res.remove(0).result?;
+	let val = Value::parse("[]");
+	assert_eq!(tmp, val);
+	//
+	Ok(())
+}
+
+#[tokio::test]
+async fn scope_safe_mode() -> Result<(), Error> {
+	let sql = "
+		DEFINE SCOPE account SAFE;
+		DEFINE TABLE person SCHEMALESS PERMISSIONS FULL;
+		CREATE person:tobie SET age = 30;
+	";
+	let dbs = Datastore::new("memory").await?;
+	let ses = Session::for_kv().with_ns("test").with_db("test");
+	dbs.execute(sql, &ses, None, false).await?;
+	//
+	let sql = "
+		UPDATE person SET age += 1;
+		UPDATE person SET age += 1 WHERE age > 10;
+	";
+	let ses = Session::for_sc("test", "test", "account");
+	let res = &mut dbs.execute(sql, &ses, None, false).await?;
+	assert_eq!(res.len(), 2);
+	//
+	let tmp = res.remove(0).result;
+	assert!(matches!(tmp, Err(Error::UnsafeStatement { .. })));
+	//
+	let tmp = res.remove(0).result?;
+	let val = Value::parse("[{ id: person:tobie, age: 31 }]");
+	assert_eq!(tmp, val);
+	//
+	Ok(())
+}
@@ -0,0 +1,80 @@
+mod parse;
+use parse::Parse;
+use surrealdb::dbs::Session;
+use surrealdb::err::Error;
+use surrealdb::kvs::Datastore;
+use surrealdb::sql::Value;
+
+#[tokio::test]
+async fn sandbox_function_subquery_limit() -> Result<(), Error> {
+	let sql = "
+		CREATE |test:1100|;
+		DEFINE FUNCTION fn::few() {
+			RETURN (SELECT VALUE id FROM test:1, test:2 WHERE (SELECT * FROM test:1));
+		};
+		DEFINE FUNCTION fn::many() {
+			RETURN (SELECT VALUE id FROM test WHERE (SELECT * FROM test:1));
+		};
+		RETURN fn::few();
+		RETURN fn::many();
+	";
+	let dbs = Datastore::new("memory").await?;
+	let ses = Session::for_kv().with_ns("test").with_db("test");
+	let res = &mut dbs.execute(sql, &ses, None, false).await?;
+	assert_eq!(res.len(), 5);
+	//
+	let tmp = res.remove(0).result;
+	assert!(tmp.is_ok());
+	//
+	let tmp = res.remove(0).result;
+	assert!(tmp.is_ok());
+	//
+	let tmp = res.remove(0).result;
+	assert!(tmp.is_ok());
+	//
+	let tmp = res.remove(0).result?;
+	let val = Value::parse("[test:1, test:2]");
+	assert_eq!(tmp, val);
+	//
+	let tmp = res.remove(0).result.unwrap_err();
+	assert_eq!(
+		tmp.to_string(),
+		"The function 'fn::many' was stopped because it exceeded the subquery limit"
+	);
+	//
+	Ok(())
+}
+
+#[tokio::test]
+async fn sandbox_event_subquery_limit() -> Result<(), Error> {
+	let sql = "
+		CREATE |test:1100|;
+		DEFINE EVENT audit ON person THEN (
+			SELECT * FROM test WHERE (SELECT * FROM test:1)
+		);
+		CREATE person:tobie;
+		SELECT * FROM person;
+	";
+	let dbs = Datastore::new("memory").await?;
+	let ses = Session::for_kv().with_ns("test").with_db("test");
+	let res = &mut dbs.execute(sql, &ses, None, false).await?;
+	assert_eq!(res.len(), 4);
+	//
+	let tmp = res.remove(0).result;
+	assert!(tmp.is_ok());
+	//
+	let tmp = res.remove(0).result;
+	assert!(tmp.is_ok());
+	//
+	let tmp = res.remove(0).result.unwrap_err();
+	assert_eq!(
+		tmp.to_string(),
+		"The event 'audit' was stopped because it exceeded the subquery limit"
+	);
+	//
+	let tmp = res.remove(0).result?;
+	let val = Value::parse("[]");
+	assert_eq!(tmp, val);
+	//
+	Ok(())
+}
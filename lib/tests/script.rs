@@ -190,3 +190,44 @@ async fn script_function_module_os() -> Result<(), Error> {
 	//
 	Ok(())
 }
+
+#[tokio::test]
+async fn script_function_read_only() -> Result<(), Error> {
+	let sql = "
+		DEFINE SEQUENCE ticket;
+		RETURN function() {
+			const { functions } = await import('surrealdb');
+			return await functions.sequence.next('ticket');
+		};
+		RETURN sequence::next('ticket');
+	";
+	let dbs = Datastore::new("memory").await?;
+	let ses = Session::for_kv().with_ns("test").with_db("test");
+	let res = &mut dbs.execute(&sql, &ses, None, false).await?;
+	assert_eq!(res.len(), 3);
+	//
+	let tmp = res.remove(0).result;
+	assert!(tmp.is_ok());
+	// Scripts can not write in a read-only statement
+	let tmp = res.remove(0).result;
+	assert!(tmp.is_err());
+	//
+	let tmp = res.remove(0).result?;
+	let val = Value::from(1);
+	assert_eq!(tmp, val);
+	// Scripts can run on a read-only datastore
+	dbs.set_read_only(true);
+	let sql = "
+		RETURN function() {
+			return 1 + 1;
+		};
+	";
+	let res = &mut dbs.execute(&sql, &ses, None, false).await?;
+	assert_eq!(res.len(), 1);
+	//
+	let tmp = res.remove(0).result?;
+	let val = Value::from(2);
+	assert_eq!(tmp, val);
+	//
+	Ok(())
+}
@@ -333,3 +333,766 @@ async fn select_where_and_with_fulltext_index() -> Result<(), Error> {
 	assert_eq!(tmp, val);
 	Ok(())
 }
+
+#[tokio::test]
+async fn select_from_other_database() -> Result<(), Error> {
+	let dbs = Datastore::new("memory").await?;
+	// Create some data in another database
+	let sql = "CREATE person:tobie SET name = 'Tobie';";
+	let ses = Session::for_kv().with_ns("test").with_db("other");
+	let res = &mut dbs.execute(&sql, &ses, None, false).await?;
+	assert_eq!(res.len(), 1);
+	let tmp = res.remove(0).result;
+	assert!(tmp.is_ok());
+	// Query the data using namespace credentials
+	let sql = "
+		SELECT name FROM other::person;
+		SELECT name FROM person;
+		SELECT name FROM missing::person;
+	";
+	let ses = Session::for_ns("test").with_db("test");
+	let res = &mut dbs.execute(&sql, &ses, None, false).await?;
+	assert_eq!(res.len(), 3);
+	//
+	let tmp = res.remove(0).result?;
+	let val = Value::parse(
+		"[
+			{
+				name: 'Tobie'
+			}
+		]",
+	);
+	assert_eq!(tmp, val);
+	//
+	let tmp = res.remove(0).result?;
+	let val = Value::parse("[]");
+	assert_eq!(tmp, val);
+	//
+	let tmp = res.remove(0).result;
+	assert!(matches!(
+		tmp.err(),
+		Some(e) if e.to_string() == "The database 'missing' does not exist"
+	));
+	// Database credentials can not query across databases
+	let sql = "SELECT name FROM other::person;";
+	let ses = Session::for_db("test", "test");
+	let res = &mut dbs.execute(&sql, &ses, None, false).await?;
+	assert_eq!(res.len(), 1);
+	let tmp = res.remove(0).result;
+	assert!(matches!(tmp, Err(Error::QueryPermissions)));
+	//
+	Ok(())
+}
+
+#[tokio::test]
+async fn select_projection_pushdown() -> Result<(), Error> {
+	let sql = "
+		DEFINE INDEX name ON person FIELDS name;
+		CREATE person:tobie SET name = 'Tobie', age = 30, tags = ['a', 'b'];
+		CREATE person:jaime SET name = 'Jaime', age = 12, tags = ['c'];
+		SELECT id FROM person;
+		SELECT id FROM person:jaime..;
+		SELECT VALUE id FROM person WHERE id = person:tobie;
+		SELECT id, name FROM person WHERE name = 'Tobie';
+		SELECT name, age FROM person WHERE name = 'Tobie';
+	";
+	let dbs = Datastore::new("memory").await?;
+	let ses = Session::for_kv().with_ns("test").with_db("test");
+	let res = &mut dbs.execute(sql, &ses, None, false).await?;
+	assert_eq!(res.len(), 8);
+	//
+	for _ in 0..3 {
+		let tmp = res.remove(0).result;
+		assert!(tmp.is_ok());
+	}
+	//
+	let tmp = res.remove(0).result?;
+	let val = Value::parse("[{ id: person:jaime }, { id: person:tobie }]");
+	assert_eq!(tmp, val);
+	//
+	let tmp = res.remove(0).result?;
+	let val = Value::parse("[{ id: person:jaime }, { id: person:tobie }]");
+	assert_eq!(tmp, val);
+	//
+	let tmp = res.remove(0).result?;
+	let val = Value::parse("[person:tobie]");
+	assert_eq!(tmp, val);
+	//
+	let tmp = res.remove(0).result?;
+	let val = Value::parse("[{ id: person:tobie, name: 'Tobie' }]");
+	assert_eq!(tmp, val);
+	//
+	let tmp = res.remove(0).result?;
+	let val = Value::parse("[{ name: 'Tobie', age: 30 }]");
+	assert_eq!(tmp, val);
+	// Permissions are still checked against the whole record
+	let sql = "
+		DEFINE TABLE user SCHEMALESS PERMISSIONS FOR select WHERE public = true;
+		CREATE user:one SET public = true;
+		CREATE user:two SET public = false;
+	";
+	dbs.execute(sql, &ses, None, false).await?;
+	let sql = "SELECT id FROM user";
+	let ses = Session::for_sc("test", "test", "test");
+	let res = &mut dbs.execute(sql, &ses, None, false).await?;
+	let tmp = res.remove(0).result?;
+	let val = Value::parse("[{ id: user:one }]");
+	assert_eq!(tmp, val);
+	//
+	Ok(())
+}
+
+#[tokio::test]
+async fn select_order_by_index() -> Result<(), Error> {
+	let sql = "
+		DEFINE INDEX age ON person FIELDS age;
+		CREATE person:1 SET name = 'Tobie', age = 30;
+		CREATE person:2 SET name = 'Jaime', age = 12;
+		CREATE person:3 SET name = 'Lizzie', age = 25;
+		CREATE person:4 SET name = 'Daniel', age = 41;
+		SELECT name, age FROM person ORDER BY age LIMIT 2 START 1;
+		SELECT name, age FROM person ORDER BY age DESC LIMIT 2;
+		SELECT * FROM person ORDER BY age LIMIT 1 EXPLAIN;
+	";
+	let dbs = Datastore::new("memory").await?;
+	let ses = Session::for_kv().with_ns("test").with_db("test");
+	let res = &mut dbs.execute(sql, &ses, None, false).await?;
+	assert_eq!(res.len(), 8);
+	//
+	for _ in 0..5 {
+		let tmp = res.remove(0).result;
+		assert!(tmp.is_ok());
+	}
+	//
+	let tmp = res.remove(0).result?;
+	let val = Value::parse("[{ name: 'Lizzie', age: 25 }, { name: 'Tobie', age: 30 }]");
+	assert_eq!(tmp, val);
+	//
+	let tmp = res.remove(0).result?;
+	let val = Value::parse("[{ name: 'Daniel', age: 41 }, { name: 'Tobie', age: 30 }]");
+	assert_eq!(tmp, val);
+	//
+	let tmp = res.remove(0).result?;
+	let val = Value::parse(
+		"[
+			{
+				id: person:2,
+				name: 'Jaime',
+				age: 12
+			},
+			{
+				explain:
+				[
+					{
+						detail: {
+							plan: {
+								index: 'age',
+								order: 'age'
+							},
+							table: 'person',
+						},
+						operation: 'Iterate Index'
+					}
+				]
+			}
+		]",
+	);
+	assert_eq!(tmp, val);
+	//
+	Ok(())
+}
+
+#[tokio::test]
+async fn select_order_by_collation() -> Result<(), Error> {
+	let sql = "
+		CREATE word:1 SET name = 'Örn';
+		CREATE word:2 SET name = 'zebra';
+		CREATE word:3 SET name = 'Åsa';
+		CREATE word:4 SET name = 'apple';
+		CREATE word:5 SET name = 'Banana';
+		SELECT name FROM word ORDER BY name;
+		SELECT name FROM word ORDER BY name COLLATE 'sv';
+		SELECT name FROM word ORDER BY name COLLATE CI DESC;
+		DEFINE INDEX name ON word FIELDS name COLLATE 'sv';
+		SELECT name FROM word ORDER BY name COLLATE 'sv' LIMIT 3;
+	";
+	let dbs = Datastore::new("memory").await?;
+	let ses = Session::for_kv().with_ns("test").with_db("test");
+	let res = &mut dbs.execute(sql, &ses, None, false).await?;
+	assert_eq!(res.len(), 10);
+	//
+	for _ in 0..5 {
+		let tmp = res.remove(0).result;
+		assert!(tmp.is_ok());
+	}
+	//
+	let tmp = res.remove(0).result?;
+	let val = Value::parse(
+		"[{ name: 'Banana' }, { name: 'apple' }, { name: 'zebra' }, { name: 'Åsa' }, { name: 'Örn' }]",
+	);
+	assert_eq!(tmp, val);
+	//
+	let tmp = res.remove(0).result?;
+	let val = Value::parse(
+		"[{ name: 'apple' }, { name: 'Banana' }, { name: 'zebra' }, { name: 'Åsa' }, { name: 'Örn' }]",
+	);
+	assert_eq!(tmp, val);
+	//
+	let tmp = res.remove(0).result?;
+	let val = Value::parse(
+		"[{ name: 'zebra' }, { name: 'Örn' }, { name: 'Banana' }, { name: 'Åsa' }, { name: 'apple' }]",
+	);
+	assert_eq!(tmp, val);
+	// The index is iterated in the order of its collation
+	res.remove(0).result?;
+	let tmp = res.remove(0).result?;
+	let val = Value::parse("[{ name: 'apple' }, { name: 'Banana' }, { name: 'zebra' }]");
+	assert_eq!(tmp, val);
+	//
+	Ok(())
+}
+
+#[tokio::test]
+async fn select_count_with_record_counter() -> Result<(), Error> {
+	let sql = "
+		SELECT count() FROM person GROUP ALL;
+		CREATE person:1, person:2, person:3;
+		UPDATE person:3, person:4 SET name = 'Tobie';
+		DELETE person:1, person:5;
+		BEGIN;
+		CREATE person:6;
+		CANCEL;
+		SELECT count() FROM person GROUP ALL;
+		SELECT count() AS total, count() AS num FROM person GROUP ALL;
+		INFO FOR TABLE person;
+	";
+	let dbs = Datastore::new("memory").await?;
+	let ses = Session::for_kv().with_ns("test").with_db("test");
+	let res = &mut dbs.execute(sql, &ses, None, false).await?;
+	assert_eq!(res.len(), 8);
+	//
+	let tmp = res.remove(0).result?;
+	let val = Value::parse("[]");
+	assert_eq!(tmp, val);
+	//
+	for _ in 0..3 {
+		let tmp = res.remove(0).result;
+		assert!(tmp.is_ok());
+	}
+	//
+	let tmp = res.remove(0).result;
+	assert!(matches!(tmp, Err(Error::QueryCancelled)));
+	//
+	let tmp = res.remove(0).result?;
+	let val = Value::parse("[{ count: 3 }]");
+	assert_eq!(tmp, val);
+	//
+	let tmp = res.remove(0).result?;
+	let val = Value::parse("[{ total: 3, num: 3 }]");
+	assert_eq!(tmp, val);
+	//
+	let tmp = res.remove(0).result?;
+	let val = Value::parse("{ count: 3, events: {}, fields: {}, tables: {}, indexes: {} }");
+	assert_eq!(tmp, val);
+	//
+	Ok(())
+}
+
+#[tokio::test]
+async fn select_row_level_permissions() -> Result<(), Error> {
+	let sql = "
+		DEFINE TABLE user SCHEMALESS PERMISSIONS NONE;
+		DEFINE TABLE invoice SCHEMALESS PERMISSIONS
+			FOR select WHERE tenant = $auth.tenant OR tenant = $token.tenant
+			FOR create, update, delete WHERE tenant = $auth.tenant;
+		CREATE user:one SET tenant = 'acme';
+		CREATE user:two SET tenant = 'initech';
+		CREATE invoice:1 SET tenant = 'acme', total = 10;
+		CREATE invoice:2 SET tenant = 'initech', total = 20;
+		CREATE invoice:3 SET tenant = 'globex', total = 30;
+	";
+	let dbs = Datastore::new("memory").await?;
+	let ses = Session::for_kv().with_ns("test").with_db("test");
+	let res = &mut dbs.execute(sql, &ses, None, false).await?;
+	assert_eq!(res.len(), 7);
+	//
+	for _ in 0..7 {
+		let tmp = res.remove(0).result;
+		assert!(tmp.is_ok());
+	}
+	// Rows are filtered by the record of the authenticated user
+	let sql = "
+		SELECT id, total FROM invoice;
+		UPDATE invoice SET total = 0;
+		SELECT id, total FROM invoice:2;
+	";
+	let mut ses = Session::for_sc("test", "test", "user");
+	ses.sd = Some(Value::parse("user:one"));
+	let res = &mut dbs.execute(sql, &ses, None, false).await?;
+	assert_eq!(res.len(), 3);
+	//
+	let tmp = res.remove(0).result?;
+	let val = Value::parse("[{ id: invoice:1, total: 10 }]");
+	assert_eq!(tmp, val);
+	//
+	let tmp = res.remove(0).result?;
+	let val = Value::parse("[{ id: invoice:1, tenant: 'acme', total: 0 }]");
+	assert_eq!(tmp, val);
+	//
+	let tmp = res.remove(0).result?;
+	let val = Value::parse("[]");
+	assert_eq!(tmp, val);
+	// Rows are filtered by the claims of the session token
+	let sql = "SELECT id, total FROM invoice";
+	let mut ses = Session::for_sc("test", "test", "user");
+	ses.tk = Some(Value::parse("{ tenant: 'globex' }"));
+	let res = &mut dbs.execute(sql, &ses, None, false).await?;
+	assert_eq!(res.len(), 1);
+	//
+	let tmp = res.remove(0).result?;
+	let val = Value::parse("[{ id: invoice:3, total: 30 }]");
+	assert_eq!(tmp, val);
+	// Protected session parameters can not be overridden by the user
+	let sql = "
+		LET $auth = user:two;
+		SELECT id, total FROM invoice;
+	";
+	let mut ses = Session::for_sc("test", "test", "user");
+	ses.sd = Some(Value::parse("user:one"));
+	let res = &mut dbs.execute(sql, &ses, None, false).await?;
+	assert_eq!(res.len(), 2);
+	//
+	let tmp = res.remove(0).result;
+	assert!(matches!(tmp, Err(Error::InvalidParam { .. })));
+	//
+	let tmp = res.remove(0).result?;
+	let val = Value::parse("[{ id: invoice:1, total: 0 }]");
+	assert_eq!(tmp, val);
+	//
+	Ok(())
+}
+
+#[tokio::test]
+async fn select_where_regex_and_fuzzy_match() -> Result<(), Error> {
+	let sql = "
+		CREATE person:tobie SET name = 'Tobie', email = 'tobie@surrealdb.com';
+		CREATE person:jaime SET name = 'Jaime', email = 'jaime@example.com';
+		SELECT id FROM person WHERE email ~ /@surrealdb\\.com$/;
+		SELECT id FROM person WHERE email !~ /@surrealdb\\.com$/;
+		SELECT id FROM person WHERE name ~ 'tbe';
+	";
+	let dbs = Datastore::new("memory").await?;
+	let ses = Session::for_kv().with_ns("test").with_db("test");
+	let res = &mut dbs.execute(&sql, &ses, None, false).await?;
+	assert_eq!(res.len(), 5);
+	//
+	let _ = res.remove(0).result?;
+	let _ = res.remove(0).result?;
+	//
+	let tmp = res.remove(0).result?;
+	let val = Value::parse("[{ id: person:tobie }]");
+	assert_eq!(tmp, val);
+	//
+	let tmp = res.remove(0).result?;
+	let val = Value::parse("[{ id: person:jaime }]");
+	assert_eq!(tmp, val);
+	//
+	let tmp = res.remove(0).result?;
+	let val = Value::parse("[{ id: person:tobie }]");
+	assert_eq!(tmp, val);
+	//
+	Ok(())
+}
+
+#[tokio::test]
+async fn select_order_by_index_with_decimals() -> Result<(), Error> {
+	let sql = "
+		DEFINE INDEX price ON product FIELDS price;
+		DEFINE INDEX code ON product FIELDS code UNIQUE;
+		CREATE product:1 SET price = 10.50dec, code = 1;
+		CREATE product:2 SET price = 9.99dec, code = 2;
+		CREATE product:3 SET price = 100, code = 3;
+		CREATE product:4 SET price = 0.5f, code = 4;
+		SELECT VALUE price FROM product ORDER BY price;
+		SELECT VALUE id FROM product WHERE price = 10.5;
+		CREATE product:5 SET code = 1.0dec;
+	";
+	let dbs = Datastore::new("memory").await?;
+	let ses = Session::for_kv().with_ns("test").with_db("test");
+	let res = &mut dbs.execute(sql, &ses, None, false).await?;
+	assert_eq!(res.len(), 9);
+	//
+	for _ in 0..6 {
+		let tmp = res.remove(0).result;
+		assert!(tmp.is_ok());
+	}
+	// Numbers of every type are ordered by value in the index
+	let tmp = res.remove(0).result?;
+	let val = Value::parse("[0.5f, 9.99dec, 10.50dec, 100]");
+	assert_eq!(tmp, val);
+	// Equal numbers of different types are found in the index
+	let tmp = res.remove(0).result?;
+	let val = Value::parse("[product:1]");
+	assert_eq!(tmp, val);
+	// Equal numbers of different types are unique in the index
+	let tmp = res.remove(0).result;
+	assert!(matches!(tmp, Err(Error::IndexExists { .. })), "{tmp:?}");
+	//
+	Ok(())
+}
+
+#[tokio::test]
+async fn select_array_id_element_range() -> Result<(), Error> {
+	let sql = "
+		CREATE temperature:['London', '2022-12-31'] SET celsius = 9;
+		CREATE temperature:['London', '2023-01-01'] SET celsius = 5;
+		CREATE temperature:['London', '2023-01-15'] SET celsius = 2;
+		CREATE temperature:['London', '2023-02-01'] SET celsius = 7;
+		CREATE temperature:['Paris', '2023-01-10'] SET celsius = 4;
+		SELECT VALUE celsius FROM temperature:['London', '2023-01-01'..'2023-02-01'];
+		SELECT VALUE celsius FROM temperature:['London', '2023-01-01'..='2023-02-01'];
+		SELECT VALUE celsius FROM temperature:['London', '2023-01-01']..['London', '2023-02-01'];
+	";
+	let dbs = Datastore::new("memory").await?;
+	let ses = Session::for_kv().with_ns("test").with_db("test");
+	let res = &mut dbs.execute(sql, &ses, None, false).await?;
+	assert_eq!(res.len(), 8);
+	//
+	for _ in 0..5 {
+		let tmp = res.remove(0).result;
+		assert!(tmp.is_ok());
+	}
+	//
+	let tmp = res.remove(0).result?;
+	let val = Value::parse("[5, 2]");
+	assert_eq!(tmp, val);
+	//
+	let tmp = res.remove(0).result?;
+	let val = Value::parse("[5, 2, 7]");
+	assert_eq!(tmp, val);
+	// Both forms of the range select the same records
+	let tmp = res.remove(0).result?;
+	let val = Value::parse("[5, 2]");
+	assert_eq!(tmp, val);
+	//
+	Ok(())
+}
+
+#[tokio::test]
+async fn select_where_param_with_index() -> Result<(), Error> {
+	let sql = "
+		CREATE company:one SET code = 'ACME', name = 'Acme';
+		CREATE company:two SET code = 'INIT', name = 'Initech';
+		CREATE person:tobie SET name = 'Tobie', company = 'INIT';
+		DEFINE INDEX company_code ON TABLE company COLUMNS code UNIQUE;
+		LET $code = 'ACME';
+		SELECT name FROM company WHERE code = $code EXPLAIN;
+		SELECT name, (SELECT VALUE name FROM company WHERE code = $parent.company) AS company FROM person;";
+	let dbs = Datastore::new("memory").await?;
+	let ses = Session::for_kv().with_ns("test").with_db("test");
+	let res = &mut dbs.execute(&sql, &ses, None, false).await?;
+	assert_eq!(res.len(), 7);
+	//
+	for _ in 0..5 {
+		let _ = res.remove(0).result?;
+	}
+	//
+	let tmp = res.remove(0).result?;
+	let val = Value::parse(
+		"[
+			{
+				name: 'Acme'
+			},
+			{
+				explain:
+				[
+					{
+						detail: {
+							plan: {
+								index: 'company_code',
+								operator: '=',
+								value: 'ACME'
+							},
+							table: 'company',
+						},
+						operation: 'Iterate Index'
+					}
+				]
+			}
+		]",
+	);
+	assert_eq!(tmp, val);
+	// The subquery is planned with the field of each parent record
+	let tmp = res.remove(0).result?;
+	let val = Value::parse(
+		"[
+			{
+				company: ['Initech'],
+				name: 'Tobie'
+			}
+		]",
+	);
+	assert_eq!(tmp, val);
+	Ok(())
+}
+
+#[tokio::test]
+async fn select_where_contains_with_array_index() -> Result<(), Error> {
+	let sql = "
+		DEFINE FIELD tags ON TABLE post TYPE array;
+		DEFINE INDEX post_tags ON TABLE post COLUMNS tags;
+		CREATE post:one SET title = 'One', tags = ['rust', 'db'];
+		CREATE post:two SET title = 'Two', tags = ['go', 'db', 'db'];
+		SELECT title FROM post WHERE tags CONTAINS 'rust' EXPLAIN;
+		UPDATE post:one SET tags = ['go'];
+		SELECT title FROM post WHERE 'db' INSIDE tags;
+		DELETE post:two;
+		SELECT title FROM post WHERE tags CONTAINS 'go';";
+	let dbs = Datastore::new("memory").await?;
+	let ses = Session::for_kv().with_ns("test").with_db("test");
+	let res = &mut dbs.execute(&sql, &ses, None, false).await?;
+	assert_eq!(res.len(), 9);
+	//
+	for _ in 0..4 {
+		let _ = res.remove(0).result?;
+	}
+	//
+	let tmp = res.remove(0).result?;
+	let val = Value::parse(
+		"[
+			{
+				title: 'One'
+			},
+			{
+				explain:
+				[
+					{
+						detail: {
+							plan: {
+								index: 'post_tags',
+								operator: 'CONTAINS',
+								value: 'rust'
+							},
+							table: 'post',
+						},
+						operation: 'Iterate Index'
+					}
+				]
+			}
+		]",
+	);
+	assert_eq!(tmp, val);
+	// The entries of the removed elements are deleted on update
+	let _ = res.remove(0).result?;
+	let tmp = res.remove(0).result?;
+	let val = Value::parse("[{ title: 'Two' }]");
+	assert_eq!(tmp, val);
+	// The entries of a deleted record are deleted
+	let _ = res.remove(0).result?;
+	let tmp = res.remove(0).result?;
+	let val = Value::parse("[{ title: 'One' }]");
+	assert_eq!(tmp, val);
+	Ok(())
+}
+
+#[tokio::test]
+async fn select_start_after_cursor() -> Result<(), Error> {
+	let sql = "
+		CREATE post:a SET rank = 1;
+		CREATE post:b SET rank = 2;
+		CREATE post:c SET rank = 2;
+		CREATE post:d SET rank = 3;
+		CREATE post:e SET rank = 4;
+		SELECT id, rank FROM post ORDER BY rank LIMIT 2;
+		DELETE post:a;";
+	let dbs = Datastore::new("memory").await?;
+	let ses = Session::for_kv().with_ns("test").with_db("test");
+	let res = &mut dbs.execute(sql, &ses, None, false).await?;
+	assert_eq!(res.len(), 7);
+	//
+	for _ in 0..5 {
+		let _ = res.remove(0).result?;
+	}
+	let tmp = res.remove(0);
+	let cursor = tmp.cursor.expect("a full page has a cursor");
+	let val = Value::parse("[{ id: post:a, rank: 1 }, { id: post:b, rank: 2 }]");
+	assert_eq!(tmp.result?, val);
+	assert!(res.remove(0).cursor.is_none());
+	// The next page is not shifted by the deleted record
+	let sql = format!("SELECT id, rank FROM post ORDER BY rank LIMIT 2 START AFTER '{cursor}'");
+	let res = &mut dbs.execute(&sql, &ses, None, false).await?;
+	let tmp = res.remove(0);
+	let cursor = tmp.cursor.expect("a full page has a cursor");
+	let val = Value::parse("[{ id: post:c, rank: 2 }, { id: post:d, rank: 3 }]");
+	assert_eq!(tmp.result?, val);
+	// The last page does not have a cursor
+	let sql = format!("SELECT id, rank FROM post ORDER BY rank LIMIT 2 START AFTER '{cursor}'");
+	let res = &mut dbs.execute(&sql, &ses, None, false).await?;
+	let tmp = res.remove(0);
+	assert!(tmp.cursor.is_none());
+	let val = Value::parse("[{ id: post:e, rank: 4 }]");
+	assert_eq!(tmp.result?, val);
+	// A cursor for a different ORDER clause is not valid
+	let sql = format!("SELECT id FROM post LIMIT 2 START AFTER '{cursor}'");
+	let res = &mut dbs.execute(&sql, &ses, None, false).await?;
+	let tmp = res.remove(0).result;
+	assert!(matches!(tmp, Err(Error::InvalidCursor { .. })));
+	// Without an ORDER clause the table is iterated from the cursor
+	let sql = "SELECT id FROM post LIMIT 2";
+	let res = &mut dbs.execute(sql, &ses, None, false).await?;
+	let cursor = res.remove(0).cursor.expect("a full page has a cursor");
+	let sql = format!("SELECT id FROM post LIMIT 2 START AFTER '{cursor}'");
+	let res = &mut dbs.execute(&sql, &ses, None, false).await?;
+	let tmp = res.remove(0).result?;
+	let val = Value::parse("[{ id: post:d }, { id: post:e }]");
+	assert_eq!(tmp, val);
+	Ok(())
+}
+
+#[tokio::test]
+async fn select_with_total() -> Result<(), Error> {
+	let sql = "
+		CREATE post:a SET rank = 1;
+		CREATE post:b SET rank = 2;
+		CREATE post:c SET rank = 2;
+		CREATE post:d SET rank = 3;
+		CREATE post:e SET rank = 4;
+		SELECT id FROM post WHERE rank > 1 ORDER BY rank LIMIT 2 WITH TOTAL;
+		SELECT id FROM post LIMIT 2 START 4 WITH TOTAL;
+		SELECT rank FROM post GROUP BY rank LIMIT 1 WITH TOTAL;
+		SELECT id FROM post LIMIT 2;
+	";
+	let dbs = Datastore::new("memory").await?;
+	let ses = Session::for_kv().with_ns("test").with_db("test");
+	let res = &mut dbs.execute(sql, &ses, None, false).await?;
+	assert_eq!(res.len(), 9);
+	//
+	for _ in 0..5 {
+		let _ = res.remove(0).result?;
+	}
+	let tmp = res.remove(0);
+	assert_eq!(tmp.total, Some(4));
+	let val = Value::parse("[{ id: post:b }, { id: post:c }]");
+	assert_eq!(tmp.result?, val);
+	// The total counts every record, and not only those of the page
+	let tmp = res.remove(0);
+	assert_eq!(tmp.total, Some(5));
+	let val = Value::parse("[{ id: post:e }]");
+	assert_eq!(tmp.result?, val);
+	// The total of grouped records is the number of groups
+	let tmp = res.remove(0);
+	assert_eq!(tmp.total, Some(4));
+	let val = Value::parse("[{ rank: 1 }]");
+	assert_eq!(tmp.result?, val);
+	// The total is only output when requested
+	let tmp = res.remove(0);
+	assert!(tmp.total.is_none());
+	let tmp = serde_json::to_value(&tmp).unwrap();
+	assert!(tmp.get("total").is_none());
+	// The records before a cursor are counted in the total
+	let sql = "SELECT id FROM post LIMIT 2";
+	let res = &mut dbs.execute(sql, &ses, None, false).await?;
+	let cursor = res.remove(0).cursor.expect("a full page has a cursor");
+	let sql =
+		format!("SELECT id FROM post WHERE rank < 4 LIMIT 2 START AFTER '{cursor}' WITH TOTAL");
+	let res = &mut dbs.execute(&sql, &ses, None, false).await?;
+	let tmp = serde_json::to_value(&res[0]).unwrap();
+	assert_eq!(tmp["total"], 4);
+	let val = Value::parse("[{ id: post:c }, { id: post:d }]");
+	assert_eq!(res.remove(0).result?, val);
+	Ok(())
+}
+
+#[tokio::test]
+async fn select_with_total_of_soft_deleted_records() -> Result<(), Error> {
+	let sql = "
+		DEFINE TABLE note SOFTDELETE;
+		CREATE note:a;
+		CREATE note:b;
+		CREATE note:c;
+		DELETE note:a;
+		SELECT id FROM note LIMIT 1 WITH TOTAL;
+		SELECT id FROM note WITH DELETED LIMIT 1 WITH TOTAL;
+	";
+	let dbs = Datastore::new("memory").await?;
+	let ses = Session::for_kv().with_ns("test").with_db("test");
+	let res = &mut dbs.execute(sql, &ses, None, false).await?;
+	assert_eq!(res.len(), 7);
+	//
+	for _ in 0..5 {
+		let _ = res.remove(0).result?;
+	}
+	// Deleted records are not counted in the total
+	let tmp = res.remove(0);
+	assert_eq!(tmp.total, Some(2));
+	let val = Value::parse("[{ id: note:b }]");
+	assert_eq!(tmp.result?, val);
+	// Unless they are selected
+	let tmp = res.remove(0);
+	assert_eq!(tmp.total, Some(3));
+	let val = Value::parse("[{ id: note:a }]");
+	assert_eq!(tmp.result?, val);
+	Ok(())
+}
+
+#[tokio::test]
+async fn select_join_on_condition() -> Result<(), Error> {
+	let sql = "
+		DEFINE INDEX company_code ON TABLE company COLUMNS code UNIQUE;
+		CREATE company:one SET code = 'ACME', name = 'Acme';
+		CREATE company:two SET code = 'INIT', name = 'Initech';
+		CREATE person:tobie SET name = 'Tobie', company = 'INIT', age = 30;
+		CREATE person:jaime SET name = 'Jaime', company = 'ACME', age = 17;
+		CREATE person:other SET name = 'Other', company = 'NONE', age = 40;
+		SELECT person.name AS name, company.name AS company FROM person JOIN company ON company.code = person.company ORDER BY name;
+		SELECT person.name AS name FROM person JOIN company ON company.code = person.company WHERE person.age > 18;
+		SELECT * FROM person, company WHERE name = 'Acme';
+	";
+	let dbs = Datastore::new("memory").await?;
+	let ses = Session::for_kv().with_ns("test").with_db("test");
+	let res = &mut dbs.execute(&sql, &ses, None, false).await?;
+	assert_eq!(res.len(), 9);
+	//
+	for _ in 0..6 {
+		let _ = res.remove(0).result?;
+	}
+	// Each person is matched with their company
+	let tmp = res.remove(0).result?;
+	let val = Value::parse(
+		"[
+			{
+				company: 'Acme',
+				name: 'Jaime'
+			},
+			{
+				company: 'Initech',
+				name: 'Tobie'
+			}
+		]",
+	);
+	assert_eq!(tmp, val);
+	// The WHERE clause filters the joined records
+	let tmp = res.remove(0).result?;
+	let val = Value::parse(
+		"[
+			{
+				name: 'Tobie'
+			}
+		]",
+	);
+	assert_eq!(tmp, val);
+	// Selecting from several tables without a JOIN is unchanged
+	let tmp = res.remove(0).result?;
+	let val = Value::parse(
+		"[
+			{
+				code: 'ACME',
+				id: company:one,
+				name: 'Acme'
+			}
+		]",
+	);
+	assert_eq!(tmp, val);
+	//
+	Ok(())
+}
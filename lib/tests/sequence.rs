@@ -0,0 +1,214 @@
+mod parse;
+use parse::Parse;
+use surrealdb::dbs::Session;
+use surrealdb::err::Error;
+use surrealdb::kvs::Datastore;
+use surrealdb::sql::Value;
+
+#[tokio::test]
+async fn define_statement_sequence() -> Result<(), Error> {
+	let sql = "
+		DEFINE SEQUENCE invoice;
+		DEFINE SEQUENCE ticket START 100 INCREMENT BY 10;
+		INFO FOR DB;
+	";
+	let dbs = Datastore::new("memory").await?;
+	let ses = Session::for_kv().with_ns("test").with_db("test");
+	let res = &mut dbs.execute(&sql, &ses, None, false).await?;
+	assert_eq!(res.len(), 3);
+	//
+	let tmp = res.remove(0).result;
+	assert!(tmp.is_ok());
+	//
+	let tmp = res.remove(0).result;
+	assert!(tmp.is_ok());
+	//
+	let tmp = res.remove(0).result?;
+	let val = Value::parse(
+		"{
+			analyzers: {},
+			logins: {},
+			tokens: {},
+			functions: {},
+			params: {},
+			scopes: {},
+			sequences: {
+				invoice: 'DEFINE SEQUENCE invoice START 1 INCREMENT 1',
+				ticket: 'DEFINE SEQUENCE ticket START 100 INCREMENT 10',
+			},
+			tables: {},
+		}",
+	);
+	assert_eq!(tmp, val);
+	//
+	Ok(())
+}
+
+#[tokio::test]
+async fn sequence_next_values() -> Result<(), Error> {
+	let sql = "
+		DEFINE SEQUENCE ticket START 100 INCREMENT 10;
+		RETURN sequence::next('ticket');
+		RETURN sequence::next('ticket');
+		RETURN sequence::next('ticket');
+		RETURN sequence::next('unknown');
+	";
+	let dbs = Datastore::new("memory").await?;
+	let ses = Session::for_kv().with_ns("test").with_db("test");
+	let res = &mut dbs.execute(&sql, &ses, None, false).await?;
+	assert_eq!(res.len(), 5);
+	//
+	let tmp = res.remove(0).result;
+	assert!(tmp.is_ok());
+	//
+	let tmp = res.remove(0).result?;
+	let val = Value::from(100);
+	assert_eq!(tmp, val);
+	//
+	let tmp = res.remove(0).result?;
+	let val = Value::from(110);
+	assert_eq!(tmp, val);
+	//
+	let tmp = res.remove(0).result?;
+	let val = Value::from(120);
+	assert_eq!(tmp, val);
+	//
+	let tmp = res.remove(0).result;
+	assert!(matches!(
+		tmp.err(),
+		Some(e) if e.to_string() == "The sequence 'unknown' does not exist"
+	));
+	//
+	Ok(())
+}
+
+#[tokio::test]
+async fn sequence_cancelled_transaction_is_gapless() -> Result<(), Error> {
+	let sql = "
+		DEFINE SEQUENCE invoice;
+		RETURN sequence::next('invoice');
+		BEGIN TRANSACTION;
+		RETURN sequence::next('invoice');
+		CANCEL TRANSACTION;
+		RETURN sequence::next('invoice');
+	";
+	let dbs = Datastore::new("memory").await?;
+	let ses = Session::for_kv().with_ns("test").with_db("test");
+	let res = &mut dbs.execute(&sql, &ses, None, false).await?;
+	assert_eq!(res.len(), 4);
+	//
+	let tmp = res.remove(0).result;
+	assert!(tmp.is_ok());
+	//
+	let tmp = res.remove(0).result?;
+	let val = Value::from(1);
+	assert_eq!(tmp, val);
+	//
+	let tmp = res.remove(0).result;
+	assert!(tmp.is_err());
+	//
+	let tmp = res.remove(0).result?;
+	let val = Value::from(2);
+	assert_eq!(tmp, val);
+	//
+	Ok(())
+}
+
+#[tokio::test]
+async fn sequence_used_for_record_ids_and_fields() -> Result<(), Error> {
+	let sql = "
+		DEFINE SEQUENCE invoice;
+		DEFINE FIELD number ON invoice VALUE $value OR sequence::next('invoice');
+		CREATE type::thing('invoice', sequence::next('invoice'));
+		CREATE invoice:custom;
+		SELECT id, number FROM invoice ORDER BY number;
+	";
+	let dbs = Datastore::new("memory").await?;
+	let ses = Session::for_kv().with_ns("test").with_db("test");
+	let res = &mut dbs.execute(&sql, &ses, None, false).await?;
+	assert_eq!(res.len(), 5);
+	//
+	let tmp = res.remove(0).result;
+	assert!(tmp.is_ok());
+	//
+	let tmp = res.remove(0).result;
+	assert!(tmp.is_ok());
+	//
+	let tmp = res.remove(0).result?;
+	let val = Value::parse(
+		"[
+			{
+				id: invoice:1,
+				number: 2,
+			}
+		]",
+	);
+	assert_eq!(tmp, val);
+	//
+	let tmp = res.remove(0).result?;
+	let val = Value::parse(
+		"[
+			{
+				id: invoice:custom,
+				number: 3,
+			}
+		]",
+	);
+	assert_eq!(tmp, val);
+	//
+	let tmp = res.remove(0).result?;
+	let val = Value::parse(
+		"[
+			{
+				id: invoice:1,
+				number: 2,
+			},
+			{
+				id: invoice:custom,
+				number: 3,
+			}
+		]",
+	);
+	assert_eq!(tmp, val);
+	//
+	Ok(())
+}
+
+#[tokio::test]
+async fn sequence_next_in_read_statements() -> Result<(), Error> {
+	let sql = "
+		DEFINE SEQUENCE ticket;
+		CREATE person:tobie;
+		SELECT sequence::next('ticket') AS ticket FROM person;
+		LET $ticket = sequence::next('ticket');
+		RETURN $ticket;
+		RETURN sequence::next('ticket');
+	";
+	let dbs = Datastore::new("memory").await?;
+	let ses = Session::for_kv().with_ns("test").with_db("test");
+	let res = &mut dbs.execute(&sql, &ses, None, false).await?;
+	assert_eq!(res.len(), 6);
+	//
+	let tmp = res.remove(0).result;
+	assert!(tmp.is_ok());
+	//
+	let tmp = res.remove(0).result;
+	assert!(tmp.is_ok());
+	//
+	let tmp = res.remove(0).result?;
+	let val = Value::parse("[{ ticket: 1 }]");
+	assert_eq!(tmp, val);
+	//
+	let tmp = res.remove(0).result;
+	assert!(tmp.is_ok());
+	//
+	let tmp = res.remove(0).result?;
+	let val = Value::from(2);
+	assert_eq!(tmp, val);
+	//
+	let tmp = res.remove(0).result?;
+	let val = Value::from(3);
+	assert_eq!(tmp, val);
+	//
+	Ok(())
+}
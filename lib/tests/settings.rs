@@ -0,0 +1,85 @@
+mod parse;
+use parse::Parse;
+use surrealdb::dbs::Session;
+use surrealdb::err::Error;
+use surrealdb::kvs::Datastore;
+use surrealdb::sql::Value;
+
+#[tokio::test]
+async fn set_global_changes_quotas_at_runtime() -> Result<(), Error> {
+	let dbs = Datastore::new("memory").await?;
+	let ses = Session::for_db("test", "test");
+	let sql = "
+		SELECT * FROM person;
+		SELECT * FROM person;
+	";
+	let res = &mut dbs.execute(sql, &ses, None, false).await?;
+	assert_eq!(res.len(), 2);
+	assert!(res.remove(0).result.is_ok());
+	assert!(res.remove(0).result.is_ok());
+	// Only root users can change the settings
+	let res = &mut dbs.execute("SET GLOBAL rate_limit_queries = 1", &ses, None, false).await?;
+	assert_eq!(res.len(), 1);
+	let tmp = res.remove(0).result;
+	assert!(matches!(tmp, Err(Error::QueryPermissions)));
+	//
+	let root = Session::for_kv();
+	let res = &mut dbs.execute("SET GLOBAL rate_limit_queries = 1", &root, None, false).await?;
+	assert_eq!(res.len(), 1);
+	let tmp = res.remove(0).result?;
+	assert_eq!(tmp, Value::None);
+	assert_eq!(dbs.quotas().limits().queries, Some(1));
+	// The new limit applies to the identity
+	let res = &mut dbs.execute(sql, &ses, None, false).await?;
+	assert_eq!(res.len(), 2);
+	let tmp = res.remove(0).result?;
+	let val = Value::parse("[]");
+	assert_eq!(tmp, val);
+	let tmp = res.remove(0).result;
+	assert!(matches!(
+		tmp,
+		Err(Error::QuotaExceeded {
+			limit: 1,
+			..
+		})
+	));
+	// The limit is removed again
+	let res = &mut dbs.execute("SET GLOBAL rate_limit_queries = NONE", &root, None, false).await?;
+	assert!(res.remove(0).result.is_ok());
+	assert!(!dbs.quotas().is_enabled());
+	//
+	Ok(())
+}
+
+#[tokio::test]
+async fn set_global_rejects_invalid_settings() -> Result<(), Error> {
+	let sql = "
+		SET GLOBAL slow_query_threshold = 250ms;
+		SET GLOBAL slow_query_threshold = 'fast';
+		SET GLOBAL strict = true;
+		SET GLOBAL log_level = 'debug';
+	";
+	let dbs = Datastore::new("memory").await?;
+	let ses = Session::for_kv();
+	let res = &mut dbs.execute(sql, &ses, None, false).await?;
+	assert_eq!(res.len(), 4);
+	//
+	let tmp = res.remove(0).result?;
+	assert_eq!(tmp, Value::None);
+	assert_eq!(dbs.settings().slow_query_threshold(), Some(std::time::Duration::from_millis(250)));
+	//
+	let tmp = res.remove(0).result;
+	assert!(matches!(tmp, Err(Error::InvalidSetting { .. })));
+	//
+	let tmp = res.remove(0).result;
+	assert!(matches!(tmp, Err(Error::InvalidSetting { .. })));
+	// The log level can only be changed when a logger is set
+	let tmp = res.remove(0).result;
+	assert!(matches!(tmp, Err(Error::InvalidSetting { .. })));
+	//
+	dbs.settings().set_logger(|_| Ok(()));
+	let res = &mut dbs.execute("SET GLOBAL log_level = 'debug'", &ses, None, false).await?;
+	assert!(res.remove(0).result.is_ok());
+	//
+	Ok(())
+}
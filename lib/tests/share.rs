@@ -0,0 +1,63 @@
+mod parse;
+use parse::Parse;
+use std::time::Duration;
+use surrealdb::dbs::Session;
+use surrealdb::err::Error;
+use surrealdb::iam::share::{mint, open, Share};
+use surrealdb::kvs::Datastore;
+use surrealdb::sql::Value;
+
+#[tokio::test]
+async fn share_record_and_query() -> Result<(), Error> {
+	let sql = "
+		CREATE person:tobie SET name = 'Tobie';
+		CREATE person:jaime SET name = 'Jaime';
+	";
+	let dbs = Datastore::new("memory").await?;
+	let ses = Session::for_kv().with_ns("test").with_db("test");
+	let res = &mut dbs.execute(sql, &ses, None, false).await?;
+	assert_eq!(res.len(), 2);
+	//
+	let share = Share::Record("person:tobie".to_owned());
+	let tk = mint(&dbs, &ses, share, Duration::from_secs(60)).await?;
+	let tmp = open(&dbs, &tk).await?;
+	let val = Value::parse("{ id: person:tobie, name: 'Tobie' }");
+	assert_eq!(tmp, val);
+	//
+	let share = Share::Query("SELECT VALUE name FROM person ORDER BY name".to_owned());
+	let tk = mint(&dbs, &ses, share, Duration::from_secs(60)).await?;
+	let tmp = open(&dbs, &tk).await?;
+	let val = Value::parse("['Jaime', 'Tobie']");
+	assert_eq!(tmp, val);
+	//
+	let share = Share::Record("person:unknown".to_owned());
+	let tk = mint(&dbs, &ses, share, Duration::from_secs(60)).await?;
+	let tmp = open(&dbs, &tk).await?;
+	assert_eq!(tmp, Value::None);
+	//
+	Ok(())
+}
+
+#[tokio::test]
+async fn share_invalid() -> Result<(), Error> {
+	let dbs = Datastore::new("memory").await?;
+	let ses = Session::for_kv().with_ns("test").with_db("test");
+	//
+	let share = Share::Query("DELETE person".to_owned());
+	let tmp = mint(&dbs, &ses, share, Duration::from_secs(60)).await;
+	assert!(matches!(tmp, Err(Error::InvalidShare)));
+	//
+	let share = Share::Record("person:tobie".to_owned());
+	let tmp =
+		mint(&dbs, &Session::for_sc("test", "test", "user"), share, Duration::from_secs(60)).await;
+	assert!(matches!(tmp, Err(Error::InvalidAuth)));
+	//
+	let share = Share::Record("person:tobie".to_owned());
+	let tk = mint(&dbs, &ses, share, Duration::from_secs(60)).await?;
+	let (head, body) = tk.rsplit_once('.').unwrap();
+	let tk = format!("{head}.{}", body.chars().rev().collect::<String>());
+	let tmp = open(&dbs, &tk).await;
+	assert!(matches!(tmp, Err(Error::InvalidAuth)));
+	//
+	Ok(())
+}
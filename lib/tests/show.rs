@@ -0,0 +1,244 @@
+use surrealdb::dbs::Session;
+use surrealdb::err::Error;
+use surrealdb::kvs::Datastore;
+use surrealdb::sql::Part;
+use surrealdb::sql::Value;
+use uuid::Uuid;
+
+#[tokio::test]
+async fn show_sessions_and_kill_session() -> Result<(), Error> {
+	let dbs = Datastore::new("memory").await?;
+	let ses = Session::for_kv().with_ns("test").with_db("test");
+	// Register a connection
+	let id = Uuid::new_v4();
+	let exit = dbs.registry().connect(id, "websocket", &Session::for_db("test", "test"));
+	//
+	let sql = "SHOW SESSIONS";
+	let res = &mut dbs.execute(sql, &ses, None, false).await?;
+	assert_eq!(res.len(), 1);
+	//
+	let tmp = res.remove(0).result?;
+	let Value::Array(v) = tmp else {
+		panic!("expected an array of sessions")
+	};
+	assert_eq!(v.len(), 2);
+	let val = Value::from(surrealdb::sql::Uuid(id));
+	let con = v.iter().find(|v| v.pick(&[Part::from("id")]) == val).unwrap();
+	assert_eq!(con.pick(&[Part::from("protocol")]), Value::from("websocket"));
+	assert_eq!(con.pick(&[Part::from("auth")]), Value::from("DB"));
+	//
+	let sql = format!("KILL '{id}'");
+	let res = &mut dbs.execute(&sql, &Session::for_db("test", "test"), None, false).await?;
+	assert_eq!(res.len(), 1);
+	//
+	let tmp = res.remove(0).result;
+	assert!(matches!(tmp, Err(Error::QueryPermissions)));
+	assert!(!exit.is_closed());
+	//
+	let res = &mut dbs.execute(&sql, &ses, None, false).await?;
+	assert_eq!(res.len(), 1);
+	//
+	let tmp = res.remove(0).result?;
+	assert_eq!(tmp, Value::None);
+	assert!(exit.is_closed());
+	//
+	dbs.registry().disconnect(&id);
+	let sql = "SHOW SESSIONS";
+	let res = &mut dbs.execute(sql, &ses, None, false).await?;
+	let tmp = res.remove(0).result?;
+	let Value::Array(v) = tmp else {
+		panic!("expected an array of sessions")
+	};
+	assert_eq!(v.len(), 1);
+	//
+	Ok(())
+}
+
+#[tokio::test]
+async fn trace_session_statements() -> Result<(), Error> {
+	let dbs = Datastore::new("memory").await?;
+	let mut ses = Session::for_kv().with_ns("test").with_db("test");
+	// Register a connection which traces its statements
+	let id = Uuid::new_v4();
+	ses.id = Some(id.to_string());
+	let _exit = dbs.registry().connect(id, "websocket", &ses);
+	let (tx, rx) = surrealdb::channel::new(10);
+	dbs.registry().trace(&id, Some(tx));
+	//
+	let sql = "
+		DEFINE INDEX name ON person FIELDS name;
+		SELECT * FROM person WHERE name = 'Tobie';
+		SELECT * FROM person:unknown.name.first;
+	";
+	let res = &mut dbs.execute(sql, &ses, None, false).await?;
+	assert_eq!(res.len(), 3);
+	//
+	let tmp = rx.recv().await.unwrap();
+	assert_eq!(
+		tmp.pick(&[Part::from("statement")]),
+		Value::from("DEFINE INDEX name ON person FIELDS name")
+	);
+	assert_eq!(tmp.pick(&[Part::from("status")]), Value::from("OK"));
+	//
+	let tmp = rx.recv().await.unwrap();
+	assert_eq!(tmp.pick(&[Part::from("status")]), Value::from("OK"));
+	let val = Value::from("Iterate Index");
+	assert_eq!(
+		tmp.pick(&[Part::from("plans"), Part::from(0), Part::from(0), Part::from("operation")]),
+		val
+	);
+	//
+	let tmp = rx.recv().await.unwrap();
+	assert!(tmp.pick(&[Part::from("time")]).is_strand());
+	//
+	dbs.registry().trace(&id, None);
+	let res = &mut dbs.execute("RETURN true", &ses, None, false).await?;
+	assert_eq!(res.len(), 1);
+	assert!(rx.recv().await.is_err());
+	//
+	Ok(())
+}
+
+#[tokio::test]
+async fn notify_live_query_subscribers() -> Result<(), Error> {
+	let dbs = Datastore::new("memory").await?;
+	let mut ses = Session::for_kv().with_ns("test").with_db("test");
+	ses.rt = true;
+	// Register a connection which receives live query notifications
+	let id = Uuid::new_v4();
+	ses.id = Some(id.to_string());
+	let _exit = dbs.registry().connect(id, "websocket", &ses);
+	let (tx, rx) = surrealdb::channel::new(10);
+	dbs.registry().notify(&id, Some(tx));
+	//
+	let sql = "LIVE SELECT * FROM person WHERE age > 18";
+	let res = &mut dbs.execute(sql, &ses, None, false).await?;
+	let lq = res.remove(0).result?;
+	//
+	let sql = "
+		CREATE person:tobie SET age = 30;
+		CREATE person:jaime SET age = 15;
+		UPDATE person:tobie SET age = 31;
+		DELETE person:tobie;
+	";
+	let wri = Session::for_kv().with_ns("test").with_db("test");
+	let res = &mut dbs.execute(sql, &wri, None, false).await?;
+	assert_eq!(res.len(), 4);
+	//
+	let tmp = rx.recv().await.unwrap();
+	assert_eq!(tmp.pick(&[Part::from("id")]), lq);
+	assert_eq!(tmp.pick(&[Part::from("action")]), Value::from("CREATE"));
+	assert_eq!(tmp.pick(&[Part::from("result"), Part::from("age")]), Value::from(30));
+	let tmp = rx.recv().await.unwrap();
+	assert_eq!(tmp.pick(&[Part::from("action")]), Value::from("UPDATE"));
+	assert_eq!(tmp.pick(&[Part::from("result"), Part::from("age")]), Value::from(31));
+	let tmp = rx.recv().await.unwrap();
+	assert_eq!(tmp.pick(&[Part::from("action")]), Value::from("DELETE"));
+	assert_eq!(tmp.pick(&[Part::from("result")]).to_string(), "person:tobie");
+	assert!(rx.is_empty());
+	// Killed live queries are no longer sent
+	let sql = format!("KILL {lq}");
+	let res = &mut dbs.execute(&sql, &ses, None, false).await?;
+	res.remove(0).result?;
+	let res = &mut dbs.execute("CREATE person:jane SET age = 40", &wri, None, false).await?;
+	res.remove(0).result?;
+	assert!(rx.is_empty());
+	//
+	Ok(())
+}
+
+#[tokio::test]
+async fn resume_live_query_since_token() -> Result<(), Error> {
+	let dbs = Datastore::new("memory").await?.with_history(10);
+	let mut ses = Session::for_kv().with_ns("test").with_db("test");
+	ses.rt = true;
+	// Register a connection which receives live query notifications
+	let id = Uuid::new_v4();
+	ses.id = Some(id.to_string());
+	let _exit = dbs.registry().connect(id, "websocket", &ses);
+	let (tx, rx) = surrealdb::channel::new(10);
+	dbs.registry().notify(&id, Some(tx));
+	//
+	let sql = "LIVE SELECT * FROM person WHERE age > 18";
+	let res = &mut dbs.execute(sql, &ses, None, false).await?;
+	let lq = res.remove(0).result?;
+	let wri = Session::for_kv().with_ns("test").with_db("test");
+	let res = &mut dbs.execute("CREATE person:tobie SET age = 30", &wri, None, false).await?;
+	res.remove(0).result?;
+	let tmp = rx.recv().await.unwrap();
+	let token = tmp.pick(&[Part::from("token")]);
+	assert_eq!(token, Value::from(1));
+	// The subscriber disconnects, and misses some changes
+	let sql = format!("KILL {lq}");
+	let res = &mut dbs.execute(&sql, &ses, None, false).await?;
+	res.remove(0).result?;
+	let sql = "
+		CREATE person:jaime SET age = 15;
+		UPDATE person:tobie SET age = 31;
+		CREATE animal:dog SET age = 20;
+	";
+	let res = &mut dbs.execute(sql, &wri, None, false).await?;
+	assert_eq!(res.len(), 3);
+	assert!(rx.is_empty());
+	// The missed changes are replayed when the subscriber resumes
+	let sql = format!("LIVE SELECT * FROM person WHERE age > 18 SINCE {token}");
+	let res = &mut dbs.execute(&sql, &ses, None, false).await?;
+	let lq = res.remove(0).result?;
+	let tmp = rx.recv().await.unwrap();
+	assert_eq!(tmp.pick(&[Part::from("id")]), lq);
+	assert_eq!(tmp.pick(&[Part::from("action")]), Value::from("UPDATE"));
+	assert_eq!(tmp.pick(&[Part::from("result"), Part::from("age")]), Value::from(31));
+	assert_eq!(tmp.pick(&[Part::from("token")]), Value::from(3));
+	assert!(rx.is_empty());
+	// Subsequent notifications follow on from the replayed changes
+	let res = &mut dbs.execute("DELETE person:tobie", &wri, None, false).await?;
+	res.remove(0).result?;
+	let tmp = rx.recv().await.unwrap();
+	assert_eq!(tmp.pick(&[Part::from("action")]), Value::from("DELETE"));
+	assert_eq!(tmp.pick(&[Part::from("token")]), Value::from(5));
+	// Changes which are no longer retained can not be replayed
+	let sql = "LIVE SELECT * FROM person SINCE 100";
+	let res = &mut dbs.execute(sql, &ses, None, false).await?;
+	let tmp = res.remove(0).result;
+	assert!(matches!(tmp, Err(Error::LiveResume { .. })));
+	//
+	Ok(())
+}
+
+#[tokio::test]
+async fn interrupt_session_cancels_running_queries() -> Result<(), Error> {
+	let dbs = std::sync::Arc::new(Datastore::new("memory").await?);
+	let mut ses = Session::for_kv().with_ns("test").with_db("test");
+	// Register a connection
+	let id = Uuid::new_v4();
+	ses.id = Some(id.to_string());
+	let exit = dbs.registry().connect(id, "websocket", &ses);
+	assert_eq!(dbs.registry().interrupt(&id), 0);
+	// Run a long query on the connection
+	let sql = "CREATE person:tobie; SELECT * FROM sleep(10s); CREATE person:jaime;";
+	let run = {
+		let (dbs, ses) = (dbs.clone(), ses.clone());
+		tokio::spawn(async move { dbs.execute(sql, &ses, None, false).await })
+	};
+	tokio::time::sleep(std::time::Duration::from_millis(100)).await;
+	assert_eq!(dbs.registry().interrupt(&id), 1);
+	// The query stops running, and the connection stays open
+	let res = &mut tokio::time::timeout(std::time::Duration::from_secs(1), run)
+		.await
+		.expect("the query was not cancelled")
+		.unwrap()?;
+	assert_eq!(res.len(), 3);
+	res.remove(0).result?;
+	let tmp = res.remove(0).result;
+	assert!(matches!(tmp, Err(Error::QueryKilled)));
+	let tmp = res.remove(0).result;
+	assert!(matches!(tmp, Err(Error::QueryKilled)));
+	assert!(!exit.is_closed());
+	assert_eq!(dbs.registry().active(), 0);
+	//
+	let res = &mut dbs.execute("SELECT * FROM person", &ses, None, false).await?;
+	let tmp = res.remove(0).result?;
+	assert_eq!(tmp, surrealdb::sql::value("[{ id: person:tobie }]").unwrap());
+	//
+	Ok(())
+}
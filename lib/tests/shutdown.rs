@@ -0,0 +1,50 @@
+use std::time::Duration;
+use surrealdb::dbs::Session;
+use surrealdb::err::Error;
+use surrealdb::kvs::Datastore;
+use surrealdb::sql::{Part, Value};
+use uuid::Uuid;
+
+#[tokio::test]
+async fn shutdown_notifies_live_queries_with_cursor() -> Result<(), Error> {
+	let dbs = Datastore::new("memory").await?.with_history(10);
+	let mut ses = Session::for_kv().with_ns("test").with_db("test");
+	ses.rt = true;
+	// Register a connection which receives live query notifications
+	let id = Uuid::new_v4();
+	ses.id = Some(id.to_string());
+	let exit = dbs.registry().connect(id, "websocket", &ses);
+	let (tx, rx) = surrealdb::channel::new(10);
+	dbs.registry().notify(&id, Some(tx));
+	//
+	let res = &mut dbs.execute("LIVE SELECT * FROM person", &ses, None, false).await?;
+	let lq = res.remove(0).result?;
+	let wri = Session::for_kv().with_ns("test").with_db("test");
+	let res = &mut dbs.execute("CREATE person:tobie", &wri, None, false).await?;
+	res.remove(0).result?;
+	let tmp = rx.recv().await.unwrap();
+	assert_eq!(tmp.pick(&[Part::from("action")]), Value::from("CREATE"));
+	//
+	dbs.shutdown(Duration::from_secs(1)).await?;
+	assert!(dbs.is_closing());
+	// The subscriber can resume from the last committed change
+	let tmp = rx.recv().await.unwrap();
+	assert_eq!(tmp.pick(&[Part::from("id")]), lq);
+	assert_eq!(tmp.pick(&[Part::from("action")]), Value::from("SHUTDOWN"));
+	let cursor = tmp.pick(&[Part::from("result"), Part::from("cursor")]);
+	assert_eq!(cursor, Value::from(1));
+	let (replay, _) = dbs.changes_since(0).await;
+	assert_eq!(replay.unwrap().last().map(|v| v.seq), Some(1));
+	// The connection is closed
+	assert!(exit.is_closed());
+	// The connection can still clean up its live queries
+	let sql = format!("KILL {lq}");
+	let res = &mut dbs.execute(&sql, &ses, None, false).await?;
+	res.remove(0).result?;
+	// New queries are rejected
+	let res = dbs.execute("SELECT * FROM person", &wri, None, false).await;
+	assert!(matches!(res, Err(Error::ShuttingDown)));
+	assert_eq!(dbs.registry().active(), 0);
+	//
+	Ok(())
+}
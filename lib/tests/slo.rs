@@ -0,0 +1,35 @@
+use surrealdb::dbs::Session;
+use surrealdb::err::Error;
+use surrealdb::kvs::Datastore;
+
+#[tokio::test]
+async fn slo_counts_good_and_bad_statements() -> Result<(), Error> {
+	let sql = "
+		CREATE person:tobie;
+		SELECT * FROM person;
+		SELECT * FROM person WHERE sleep(100ms) = NONE;
+		CREATE user:jaime;
+	";
+	let dbs = Datastore::new("memory")
+		.await?
+		.with_slo(vec!["test=1s".parse().unwrap(), "test/test/person=50ms@99".parse().unwrap()]);
+	let ses = Session::for_kv().with_ns("test").with_db("test");
+	let res = &mut dbs.execute(sql, &ses, None, false).await?;
+	assert_eq!(res.len(), 4);
+	//
+	let out = dbs.slo().metrics();
+	assert!(out.contains(
+		"surrealdb_slo_requests_total{ns=\"test\",db=\"test\",tb=\"person\",result=\"good\"} 2"
+	));
+	assert!(out.contains(
+		"surrealdb_slo_requests_total{ns=\"test\",db=\"test\",tb=\"person\",result=\"bad\"} 1"
+	));
+	assert!(
+		out.contains("surrealdb_slo_requests_total{ns=\"test\",db=\"\",tb=\"\",result=\"good\"} 1")
+	);
+	assert!(
+		out.contains("surrealdb_slo_objective_ratio{ns=\"test\",db=\"test\",tb=\"person\"} 0.99")
+	);
+	//
+	Ok(())
+}
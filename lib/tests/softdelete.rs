@@ -0,0 +1,109 @@
+mod parse;
+use parse::Parse;
+use surrealdb::dbs::Session;
+use surrealdb::err::Error;
+use surrealdb::kvs::Datastore;
+use surrealdb::sql::Value;
+
+#[tokio::test]
+async fn soft_delete_hides_and_purges_records() -> Result<(), Error> {
+	let sql = "
+		DEFINE TABLE person SOFTDELETE;
+		CREATE person:one SET name = 'one';
+		CREATE person:two SET name = 'two';
+		DELETE person:one;
+		SELECT VALUE id FROM person;
+		SELECT id FROM person;
+		SELECT count() FROM person GROUP ALL;
+		SELECT count() FROM person WITH DELETED GROUP ALL;
+		SELECT VALUE deleted_at != NONE FROM person WITH DELETED;
+		UPDATE person SET active = true RETURN id;
+		PURGE person;
+		SELECT VALUE id FROM person WITH DELETED;
+		SELECT count() FROM person GROUP ALL;
+	";
+	let dbs = Datastore::new("memory").await?;
+	let ses = Session::for_kv().with_ns("test").with_db("test");
+	let res = &mut dbs.execute(&sql, &ses, None, false).await?;
+	assert_eq!(res.len(), 13);
+	//
+	for _ in 0..4 {
+		let tmp = res.remove(0).result;
+		assert!(tmp.is_ok());
+	}
+	//
+	let tmp = res.remove(0).result?;
+	let val = Value::parse("[person:two]");
+	assert_eq!(tmp, val);
+	// Deleted records are hidden when only the id is selected
+	let tmp = res.remove(0).result?;
+	let val = Value::parse("[{ id: person:two }]");
+	assert_eq!(tmp, val);
+	// Deleted records are not counted
+	let tmp = res.remove(0).result?;
+	let val = Value::parse("[{ count: 1 }]");
+	assert_eq!(tmp, val);
+	//
+	let tmp = res.remove(0).result?;
+	let val = Value::parse("[{ count: 2 }]");
+	assert_eq!(tmp, val);
+	//
+	let tmp = res.remove(0).result?;
+	let val = Value::parse("[true, false]");
+	assert_eq!(tmp, val);
+	// Deleted records are not updated
+	let tmp = res.remove(0).result?;
+	let val = Value::parse("[{ id: person:two }]");
+	assert_eq!(tmp, val);
+	//
+	let tmp = res.remove(0).result?;
+	assert!(matches!(tmp, Value::Array(v) if v.len() == 1));
+	//
+	let tmp = res.remove(0).result?;
+	let val = Value::parse("[person:two]");
+	assert_eq!(tmp, val);
+	// Purged records are not uncounted twice
+	let tmp = res.remove(0).result?;
+	let val = Value::parse("[{ count: 1 }]");
+	assert_eq!(tmp, val);
+	//
+	Ok(())
+}
+
+#[tokio::test]
+async fn soft_delete_keeps_records_for_retention_period() -> Result<(), Error> {
+	let sql = "
+		DEFINE TABLE person SOFTDELETE RETAIN 1d;
+		CREATE person:one;
+		DELETE person:one;
+		PURGE person;
+		SELECT VALUE id FROM person WITH DELETED;
+		CREATE user:one;
+		PURGE user;
+	";
+	let dbs = Datastore::new("memory").await?;
+	let ses = Session::for_kv().with_ns("test").with_db("test");
+	let res = &mut dbs.execute(&sql, &ses, None, false).await?;
+	assert_eq!(res.len(), 7);
+	//
+	for _ in 0..3 {
+		let tmp = res.remove(0).result;
+		assert!(tmp.is_ok());
+	}
+	//
+	let tmp = res.remove(0).result?;
+	let val = Value::parse("[]");
+	assert_eq!(tmp, val);
+	//
+	let tmp = res.remove(0).result?;
+	let val = Value::parse("[person:one]");
+	assert_eq!(tmp, val);
+	//
+	let tmp = res.remove(0).result;
+	assert!(tmp.is_ok());
+	//
+	let tmp = res.remove(0).result;
+	assert!(matches!(tmp, Err(Error::TbNotSoftDelete { .. })));
+	//
+	Ok(())
+}
@@ -0,0 +1,61 @@
+mod parse;
+use parse::Parse;
+use surrealdb::dbs::Session;
+use surrealdb::err::Error;
+use surrealdb::kvs::Datastore;
+use surrealdb::sql::{Part, Value};
+
+#[tokio::test]
+async fn select_statement_statistics() -> Result<(), Error> {
+	let sql = "
+		CREATE person:tobie SET age = 30;
+		CREATE person:jaime SET age = 20;
+		SELECT * FROM person WHERE age > 10;
+		SELECT * FROM person WHERE age > 25;
+	";
+	let dbs = Datastore::new("memory").await?;
+	let ses = Session::for_kv().with_ns("test").with_db("test");
+	let res = &mut dbs.execute(sql, &ses, None, false).await?;
+	assert_eq!(res.len(), 4);
+	// Statements which only differ in their arguments are counted together
+	let sql = "
+		SELECT query, calls, rows_returned, rows_examined FROM system:statements
+		WHERE query = 'SELECT * FROM person WHERE age > ?';
+		SELECT count() AS count FROM system:statements WHERE query CONTAINS 'CREATE' GROUP ALL;
+	";
+	let res = &mut dbs.execute(sql, &ses, None, false).await?;
+	assert_eq!(res.len(), 2);
+	let tmp = res.remove(0).result?;
+	let val = Value::parse(
+		"[
+			{
+				query: 'SELECT * FROM person WHERE age > ?',
+				calls: 2,
+				rows_returned: 3,
+				rows_examined: 4,
+			}
+		]",
+	);
+	assert_eq!(tmp, val);
+	let tmp = res.remove(0).result?;
+	let val = Value::parse("[{ count: 1 }]");
+	assert_eq!(tmp, val);
+	// The statistics are kept for each database
+	let ses = Session::for_kv().with_ns("test").with_db("other");
+	let res = &mut dbs.execute("RETURN 1", &ses, None, false).await?;
+	res.remove(0).result?;
+	let ses = Session::for_db("test", "other");
+	let sql = "SELECT db, query, calls FROM system:statements WHERE query = 'RETURN ?'";
+	let res = &mut dbs.execute(sql, &ses, None, false).await?;
+	let tmp = res.remove(0).result?;
+	let val = Value::parse("[{ db: 'other', query: 'RETURN ?', calls: 1 }]");
+	assert_eq!(tmp, val);
+	let res = &mut dbs.execute("SELECT * FROM system:statements", &ses, None, false).await?;
+	let tmp = res.remove(0).result?;
+	assert!(match tmp {
+		Value::Array(v) => v.iter().all(|v| v.pick(&[Part::from("db")]) == Value::from("other")),
+		_ => false,
+	});
+	//
+	Ok(())
+}
@@ -3,6 +3,7 @@ use parse::Parse;
 use surrealdb::dbs::Session;
 use surrealdb::err::Error;
 use surrealdb::kvs::Datastore;
+use surrealdb::sql::Part;
 use surrealdb::sql::Value;
 
 #[tokio::test]
@@ -237,7 +238,13 @@ async fn loose_mode_all_ok() -> Result<(), Error> {
 	);
 	assert_eq!(tmp, val);
 	//
-	let tmp = res.remove(0).result?;
+	let mut tmp = res.remove(0).result?;
+	let usage = tmp.pick(&[Part::from("usage")]);
+	assert_eq!(usage.pick(&[Part::from("records")]), Value::from(1));
+	assert_eq!(usage.pick(&[Part::from("queries")]), Value::from(5));
+	if let Value::Object(v) = &mut tmp {
+		v.remove("usage");
+	}
 	let val = Value::parse(
 		"{
 			databases: { test: 'DEFINE DATABASE test' },
@@ -256,6 +263,7 @@ async fn loose_mode_all_ok() -> Result<(), Error> {
 			functions: {},
 			params: {},
 			scopes: {},
+			sequences: {},
 			tables: { test: 'DEFINE TABLE test SCHEMALESS PERMISSIONS NONE' },
 		}",
 	);
@@ -264,6 +272,7 @@ async fn loose_mode_all_ok() -> Result<(), Error> {
 	let tmp = res.remove(0).result?;
 	let val = Value::parse(
 		"{
+			count: 1,
 			events: {},
 			fields: { extra: 'DEFINE FIELD extra ON test VALUE true' },
 			tables: {},
@@ -40,6 +40,7 @@ async fn define_foreign_table() -> Result<(), Error> {
 	let tmp = res.remove(0).result?;
 	let val = Value::parse(
 		"{
+			count: 0,
 			events: {},
 			fields: {},
 			tables: { person_by_age: 'DEFINE TABLE person_by_age SCHEMALESS AS SELECT count(), age, math::sum(age) AS total, math::mean(score) AS average FROM person GROUP BY age' },
@@ -104,3 +105,57 @@ async fn define_foreign_table() -> Result<(), Error> {
 	//
 	Ok(())
 }
+
+#[tokio::test]
+async fn define_table_with_json_schema() -> Result<(), Error> {
+	let sql = "
+		DEFINE TABLE person SCHEMALESS SCHEMA {
+			type: 'object',
+			required: ['name'],
+			additionalProperties: false,
+			properties: {
+				name: { type: 'string', minLength: 1 },
+				age: { type: 'integer', minimum: 0 },
+			},
+		};
+		CREATE person:one SET name = 'Tobie', age = 30;
+		CREATE person:two SET name = '', age = -1;
+		CREATE person:three SET age = 30, other = true;
+		UPDATE person:one SET age = 31;
+	";
+	let dbs = Datastore::new("memory").await?;
+	let ses = Session::for_kv().with_ns("test").with_db("test");
+	let res = &mut dbs.execute(sql, &ses, None, false).await?;
+	assert_eq!(res.len(), 5);
+	//
+	let tmp = res.remove(0).result;
+	assert!(tmp.is_ok());
+	//
+	let tmp = res.remove(0).result?;
+	let val = Value::parse("[{ id: person:one, name: 'Tobie', age: 30 }]");
+	assert_eq!(tmp, val);
+	//
+	let tmp = res.remove(0).result;
+	assert!(
+		matches!(
+			&tmp,
+			Err(e) if e.to_string() == "Found record `person:two` which does not conform to the table schema: /age must be at least 0; /name must have at least 1 characters"
+		),
+		"{tmp:?}"
+	);
+	//
+	let tmp = res.remove(0).result;
+	assert!(
+		matches!(
+			&tmp,
+			Err(e) if e.to_string() == "Found record `person:three` which does not conform to the table schema: / must have the property 'name'; /other is not an allowed property"
+		),
+		"{tmp:?}"
+	);
+	//
+	let tmp = res.remove(0).result?;
+	let val = Value::parse("[{ id: person:one, name: 'Tobie', age: 31 }]");
+	assert_eq!(tmp, val);
+	//
+	Ok(())
+}
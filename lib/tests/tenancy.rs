@@ -0,0 +1,148 @@
+mod parse;
+use parse::Parse;
+use surrealdb::dbs::Session;
+use surrealdb::err::Error;
+use surrealdb::kvs::Datastore;
+use surrealdb::sql::{Part, Value};
+
+#[tokio::test]
+async fn namespace_record_limit_rejects_writes() -> Result<(), Error> {
+	let sql = "
+		DEFINE NAMESPACE test LIMIT RECORDS 2;
+		CREATE person:1;
+		CREATE person:2;
+		CREATE person:3;
+		UPDATE person:1 SET name = 'Tobie';
+		DELETE person:2;
+		CREATE person:3;
+	";
+	let dbs = Datastore::new("memory").await?;
+	let ses = Session::for_kv().with_ns("test").with_db("test");
+	let res = &mut dbs.execute(&sql, &ses, None, false).await?;
+	assert_eq!(res.len(), 7);
+	//
+	for _ in 0..3 {
+		let _ = res.remove(0).result?;
+	}
+	//
+	let tmp = res.remove(0).result;
+	assert!(matches!(
+		tmp,
+		Err(Error::NsLimitExceeded {
+			kind: "records",
+			limit: 2,
+			..
+		})
+	));
+	// Updating an existing record does not add a record
+	let tmp = res.remove(0).result?;
+	let val = Value::parse("[{ id: person:1, name: 'Tobie' }]");
+	assert_eq!(tmp, val);
+	// Deleting a record frees up space in the namespace
+	let _ = res.remove(0).result?;
+	let tmp = res.remove(0).result?;
+	let val = Value::parse("[{ id: person:3 }]");
+	assert_eq!(tmp, val);
+	//
+	Ok(())
+}
+
+#[tokio::test]
+async fn namespace_byte_limit_rejects_writes() -> Result<(), Error> {
+	let sql = "
+		DEFINE NAMESPACE test LIMIT BYTES 100;
+		CREATE person:1 SET name = 'Tobie';
+		CREATE person:2 SET bio = string::repeat('a', 200);
+	";
+	let dbs = Datastore::new("memory").await?;
+	let ses = Session::for_kv().with_ns("test").with_db("test");
+	let res = &mut dbs.execute(&sql, &ses, None, false).await?;
+	assert_eq!(res.len(), 3);
+	//
+	let _ = res.remove(0).result?;
+	let _ = res.remove(0).result?;
+	//
+	let tmp = res.remove(0).result;
+	assert!(matches!(
+		tmp,
+		Err(Error::NsLimitExceeded {
+			kind: "bytes",
+			limit: 100,
+			..
+		})
+	));
+	//
+	Ok(())
+}
+
+#[tokio::test]
+async fn namespace_record_limit_within_transaction() -> Result<(), Error> {
+	let sql = "
+		DEFINE NAMESPACE test LIMIT RECORDS 2;
+		BEGIN;
+		CREATE person:1;
+		CREATE person:2;
+		DELETE person:1;
+		CREATE person:3;
+		COMMIT;
+		BEGIN;
+		CREATE person:4;
+		COMMIT;
+		SELECT * FROM person;
+	";
+	let dbs = Datastore::new("memory").await?;
+	let ses = Session::for_kv().with_ns("test").with_db("test");
+	let res = &mut dbs.execute(&sql, &ses, None, false).await?;
+	assert_eq!(res.len(), 7);
+	// The records of the transaction are counted as they are written
+	for _ in 0..5 {
+		let _ = res.remove(0).result?;
+	}
+	//
+	let tmp = res.remove(0).result;
+	assert!(matches!(
+		tmp,
+		Err(Error::NsLimitExceeded {
+			kind: "records",
+			limit: 2,
+			..
+		})
+	));
+	//
+	let tmp = res.remove(0).result?;
+	let val = Value::parse("[{ id: person:2 }, { id: person:3 }]");
+	assert_eq!(tmp, val);
+	//
+	Ok(())
+}
+
+#[tokio::test]
+async fn namespace_usage_is_accounted_per_database() -> Result<(), Error> {
+	let dbs = Datastore::new("memory").await?;
+	let sql = "CREATE person:1; CREATE person:2;";
+	let ses = Session::for_kv().with_ns("test").with_db("one");
+	dbs.execute(sql, &ses, None, false).await?;
+	let sql = "CREATE person:3;";
+	let ses = Session::for_kv().with_ns("test").with_db("two");
+	dbs.execute(sql, &ses, None, false).await?;
+	//
+	let res = &mut dbs.execute("INFO FOR NS;", &ses, None, false).await?;
+	assert_eq!(res.len(), 1);
+	//
+	let tmp = res.remove(0).result?;
+	let usage = tmp.pick(&[Part::from("usage")]);
+	assert_eq!(usage.pick(&[Part::from("records")]), Value::from(3));
+	assert_eq!(usage.pick(&[Part::from("queries")]), Value::from(4));
+	let one = usage.pick(&[Part::from("databases"), Part::from("one")]);
+	assert_eq!(one.pick(&[Part::from("records")]), Value::from(2));
+	assert_eq!(one.pick(&[Part::from("queries")]), Value::from(2));
+	let two = usage.pick(&[Part::from("databases"), Part::from("two")]);
+	assert_eq!(two.pick(&[Part::from("records")]), Value::from(1));
+	assert_eq!(two.pick(&[Part::from("queries")]), Value::from(2));
+	//
+	let one = one.pick(&[Part::from("bytes")]);
+	let two = two.pick(&[Part::from("bytes")]);
+	assert!(one > two && two > Value::from(0));
+	//
+	Ok(())
+}
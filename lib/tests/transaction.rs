@@ -0,0 +1,117 @@
+mod parse;
+use parse::Parse;
+use std::time::Duration;
+use surrealdb::dbs::Session;
+use surrealdb::err::Error;
+use surrealdb::kvs::Datastore;
+use surrealdb::sql::Value;
+
+#[tokio::test]
+async fn transaction_across_queries() -> Result<(), Error> {
+	let dbs = Datastore::new("memory").await?;
+	let ses = Session::for_kv().with_ns("test").with_db("test");
+	let id = dbs.begin(&ses).await?;
+	//
+	let res = &mut dbs.execute_in(&id, "LET $name = 'Tobie'", &ses, None, false).await?;
+	res.remove(0).result?;
+	let sql = "CREATE person:tobie SET name = $name";
+	let res = &mut dbs.execute_in(&id, sql, &ses, None, false).await?;
+	let tmp = res.remove(0).result?;
+	let val = Value::parse("[{ id: person:tobie, name: 'Tobie' }]");
+	assert_eq!(tmp, val);
+	// The changes are not visible outside of the transaction
+	let res = &mut dbs.execute("SELECT * FROM person", &ses, None, false).await?;
+	let tmp = res.remove(0).result?;
+	assert_eq!(tmp, Value::parse("[]"));
+	// Transactions can not be nested
+	let res = &mut dbs.execute_in(&id, "COMMIT", &ses, None, false).await?;
+	assert!(matches!(res.remove(0).result, Err(Error::TxNested)));
+	// The transaction can only be used by the session which began it
+	let other = Session::for_db("test", "test");
+	let res = dbs.execute_in(&id, "SELECT * FROM person", &other, None, false).await;
+	assert!(matches!(res, Err(Error::TxNotFound { .. })));
+	// The failed statement cancels the transaction
+	let res = dbs.commit(&id, &ses).await;
+	assert!(matches!(res, Err(Error::QueryCancelled)));
+	let res = dbs.execute_in(&id, "SELECT * FROM person", &ses, None, false).await;
+	assert!(matches!(res, Err(Error::TxNotFound { .. })));
+	let res = &mut dbs.execute("SELECT * FROM person", &ses, None, false).await?;
+	let tmp = res.remove(0).result?;
+	assert_eq!(tmp, Value::parse("[]"));
+	//
+	let id = dbs.begin(&ses).await?;
+	let res = &mut dbs.execute_in(&id, "CREATE person:tobie", &ses, None, false).await?;
+	res.remove(0).result?;
+	let res = &mut dbs.execute_in(&id, "CREATE person:jaime", &ses, None, false).await?;
+	res.remove(0).result?;
+	dbs.commit(&id, &ses).await?;
+	let res = &mut dbs.execute("SELECT * FROM person", &ses, None, false).await?;
+	let tmp = res.remove(0).result?;
+	assert_eq!(tmp, Value::parse("[{ id: person:jaime }, { id: person:tobie }]"));
+	assert!(dbs.transactions().is_empty());
+	//
+	Ok(())
+}
+
+#[tokio::test]
+async fn transaction_idle_timeout() -> Result<(), Error> {
+	let dbs = Datastore::new("memory").await?.with_transaction_timeout(Duration::from_millis(50));
+	let ses = Session::for_kv().with_ns("test").with_db("test");
+	let id = dbs.begin(&ses).await?;
+	let res = &mut dbs.execute_in(&id, "CREATE person:tobie", &ses, None, false).await?;
+	res.remove(0).result?;
+	assert_eq!(dbs.expire_transactions().await, 0);
+	// The idle transaction is cancelled
+	tokio::time::sleep(Duration::from_millis(100)).await;
+	assert_eq!(dbs.expire_transactions().await, 1);
+	let res = dbs.commit(&id, &ses).await;
+	assert!(matches!(res, Err(Error::TxNotFound { .. })));
+	let res = &mut dbs.execute("SELECT * FROM person", &ses, None, false).await?;
+	let tmp = res.remove(0).result?;
+	assert_eq!(tmp, Value::parse("[]"));
+	//
+	Ok(())
+}
+
+#[tokio::test]
+async fn transaction_kept_when_query_is_dropped() -> Result<(), Error> {
+	let dbs = Datastore::new("memory").await?;
+	let ses = Session::for_kv().with_ns("test").with_db("test");
+	let id = dbs.begin(&ses).await?;
+	let res = &mut dbs.execute_in(&id, "CREATE person:tobie", &ses, None, false).await?;
+	res.remove(0).result?;
+	// The query is dropped before it finishes
+	let sql = "CREATE person:jaime; SELECT * FROM sleep(500ms)";
+	let run = dbs.execute_in(&id, sql, &ses, None, false);
+	assert!(tokio::time::timeout(Duration::from_millis(50), run).await.is_err());
+	assert_eq!(dbs.transactions().len(), 1);
+	// The interrupted transaction can only be cancelled
+	let res = dbs.commit(&id, &ses).await;
+	assert!(matches!(res, Err(Error::QueryCancelled)));
+	assert!(dbs.transactions().is_empty());
+	let res = &mut dbs.execute("SELECT * FROM person", &ses, None, false).await?;
+	let tmp = res.remove(0).result?;
+	assert_eq!(tmp, Value::parse("[]"));
+	//
+	Ok(())
+}
+
+#[tokio::test]
+async fn transaction_used_by_its_scope_user_only() -> Result<(), Error> {
+	let dbs = Datastore::new("memory").await?;
+	let mut ses = Session::for_sc("test", "test", "account");
+	ses.sd = Some(Value::parse("user:tobie"));
+	let id = dbs.begin(&ses).await?;
+	// Another user of the same scope can not use the transaction
+	let mut other = Session::for_sc("test", "test", "account");
+	other.sd = Some(Value::parse("user:jaime"));
+	let res = dbs.execute_in(&id, "SELECT * FROM person", &other, None, false).await;
+	assert!(matches!(res, Err(Error::TxNotFound { .. })));
+	let res = dbs.cancel(&id, &other).await;
+	assert!(matches!(res, Err(Error::TxNotFound { .. })));
+	//
+	dbs.cancel(&id, &ses).await?;
+	assert!(dbs.transactions().is_empty());
+	//
+	Ok(())
+}
@@ -0,0 +1,91 @@
+mod parse;
+use parse::Parse;
+use surrealdb::dbs::Session;
+use surrealdb::err::Error;
+use surrealdb::kvs::Datastore;
+use surrealdb::sql::Value;
+
+#[tokio::test]
+async fn select_nearest_neighbours_with_vector_index() -> Result<(), Error> {
+	let sql = "
+		DEFINE FIELD embedding ON doc TYPE vector<3>;
+		DEFINE INDEX doc_embedding ON doc FIELDS embedding HNSW DIMENSION 3;
+		CREATE doc:1 SET embedding = [3, 4, 0];
+		CREATE doc:2 SET embedding = [0, 0, 1];
+		CREATE doc:3 SET embedding = [0, 2, 0];
+		CREATE doc:4 SET embedding = [6, 8, 0];
+		CREATE doc:5 SET embedding = [1, 2];
+		LET $q = [0, 0, 0];
+		SELECT VALUE id FROM (SELECT id, vector::distance::euclidean(embedding, $q) AS dist FROM doc ORDER BY dist LIMIT 2);
+		SELECT id, vector::similarity::euclidean(embedding, $q) AS score FROM doc ORDER BY score DESC LIMIT 1 EXPLAIN;
+		SELECT id, vector::similarity::cosine(embedding, [0, 0, 5]) AS score FROM doc ORDER BY score DESC LIMIT 1 EXPLAIN;
+	";
+	let dbs = Datastore::new("memory").await?;
+	let ses = Session::for_kv().with_ns("test").with_db("test");
+	let res = &mut dbs.execute(&sql, &ses, None, false).await?;
+	assert_eq!(res.len(), 11);
+	//
+	for _ in 0..6 {
+		let _ = res.remove(0).result?;
+	}
+	//
+	let tmp = res.remove(0).result;
+	assert!(matches!(tmp, Err(Error::LengthInvalid { .. })));
+	//
+	let _ = res.remove(0).result?;
+	//
+	let tmp = res.remove(0).result?;
+	let val = Value::parse("[doc:2, doc:3]");
+	assert_eq!(tmp, val);
+	//
+	let tmp = res.remove(0).result?;
+	let val = Value::parse(
+		"[
+			{
+				id: doc:2,
+				score: 0.5
+			},
+			{
+				explain:
+				[
+					{
+						detail: {
+							plan: {
+								index: 'doc_embedding',
+								limit: 1,
+								vector: [0, 0, 0]
+							},
+							table: 'doc',
+						},
+						operation: 'Iterate Index'
+					}
+				]
+			}
+		]",
+	);
+	assert_eq!(tmp, val);
+	// The index does not use the cosine distance
+	let tmp = res.remove(0).result?;
+	let val = Value::parse(
+		"[
+			{
+				id: doc:2,
+				score: 1.0
+			},
+			{
+				explain:
+				[
+					{
+						detail: {
+							table: 'doc',
+						},
+						operation: 'Iterate Table'
+					}
+				]
+			}
+		]",
+	);
+	assert_eq!(tmp, val);
+	//
+	Ok(())
+}
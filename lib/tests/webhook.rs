@@ -0,0 +1,99 @@
+use std::io::{Read, Write};
+use std::net::TcpListener;
+use std::sync::mpsc;
+use std::time::Duration;
+use surrealdb::dbs::Session;
+use surrealdb::err::Error;
+use surrealdb::kvs::Datastore;
+
+/// Start a server which replies to each request with the next status,
+/// returning its address, and a channel which receives each request
+fn server(statuses: Vec<&'static str>) -> (String, mpsc::Receiver<String>) {
+	let listener = TcpListener::bind("127.0.0.1:0").unwrap();
+	let addr = format!("http://{}", listener.local_addr().unwrap());
+	let (tx, rx) = mpsc::channel();
+	std::thread::spawn(move || {
+		for (stream, status) in listener.incoming().zip(statuses) {
+			let mut stream = stream.unwrap();
+			let _ = tx.send(request(&mut stream));
+			let res =
+				format!("HTTP/1.1 {status}\r\ncontent-length: 0\r\nconnection: close\r\n\r\n");
+			let _ = stream.write_all(res.as_bytes());
+		}
+	});
+	(addr, rx)
+}
+
+/// Read a whole request from a connection
+fn request(stream: &mut impl Read) -> String {
+	let mut buf = vec![];
+	let mut chunk = [0; 1024];
+	loop {
+		let n = stream.read(&mut chunk).unwrap();
+		if n == 0 {
+			break;
+		}
+		buf.extend_from_slice(&chunk[..n]);
+		let txt = String::from_utf8_lossy(&buf).into_owned();
+		if let Some(end) = txt.find("\r\n\r\n") {
+			let len = txt[..end]
+				.lines()
+				.find_map(|l| {
+					l.to_ascii_lowercase().strip_prefix("content-length:").map(str::to_owned)
+				})
+				.and_then(|v| v.trim().parse::<usize>().ok())
+				.unwrap_or(0);
+			if buf.len() >= end + 4 + len {
+				break;
+			}
+		}
+	}
+	String::from_utf8_lossy(&buf).into_owned()
+}
+
+#[tokio::test]
+async fn event_webhook_is_retried_after_commit() -> Result<(), Error> {
+	let (addr, rx) = server(vec!["500 Internal Server Error", "200 OK"]);
+	let sql = format!(
+		"
+		DEFINE EVENT hook ON person THEN http::post('{addr}/hook', {{ event: $event, id: $after.id }});
+		BEGIN TRANSACTION;
+		CREATE person:jaime;
+		CANCEL TRANSACTION;
+		CREATE person:tobie;
+	"
+	);
+	let dbs = Datastore::new("memory").await?;
+	let ses = Session::for_kv().with_ns("test").with_db("test");
+	let res = &mut dbs.execute(&sql, &ses, None, false).await?;
+	assert_eq!(res.len(), 3);
+	//
+	let tmp = res.remove(0).result;
+	assert!(tmp.is_ok());
+	//
+	let tmp = res.remove(0).result;
+	assert!(matches!(tmp, Err(Error::QueryCancelled)));
+	//
+	let tmp = res.remove(0).result;
+	assert!(tmp.is_ok());
+	// The failed delivery is retried, and the cancelled write is never sent
+	let mut reqs = vec![];
+	for _ in 0..1000 {
+		reqs.extend(rx.try_iter());
+		if dbs.webhooks().delivered() == 1 {
+			break;
+		}
+		tokio::time::sleep(Duration::from_millis(10)).await;
+	}
+	reqs.extend(rx.try_iter());
+	assert_eq!(reqs.len(), 2);
+	for req in reqs {
+		assert!(req.starts_with("POST /hook HTTP/1.1"), "{req}");
+		assert!(req.ends_with(r#"{"event":"CREATE","id":"person:tobie"}"#), "{req}");
+	}
+	assert_eq!(dbs.webhooks().delivered(), 1);
+	assert_eq!(dbs.webhooks().retried(), 1);
+	assert_eq!(dbs.webhooks().failed(), 0);
+	//
+	Ok(())
+}
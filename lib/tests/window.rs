@@ -0,0 +1,69 @@
+mod parse;
+use parse::Parse;
+use surrealdb::dbs::Session;
+use surrealdb::err::Error;
+use surrealdb::kvs::Datastore;
+use surrealdb::sql::Value;
+
+#[tokio::test]
+async fn window_functions_over_partitions() -> Result<(), Error> {
+	let sql = "
+		CREATE sale:1 SET region = 'eu', amount = 10, day = 1;
+		CREATE sale:2 SET region = 'eu', amount = 30, day = 2;
+		CREATE sale:3 SET region = 'us', amount = 20, day = 1;
+		CREATE sale:4 SET region = 'eu', amount = 30, day = 3;
+		SELECT
+			id,
+			region,
+			day,
+			window::row_number() OVER (PARTITION BY region ORDER BY day) AS num,
+			math::sum(amount) OVER (PARTITION BY region ORDER BY day) AS running,
+			window::lag(amount, 1, 0) OVER (PARTITION BY region ORDER BY day) AS previous
+		FROM sale ORDER BY id;
+		SELECT
+			id,
+			region,
+			amount,
+			window::rank() OVER (ORDER BY amount DESC) AS rank,
+			window::dense_rank() OVER (ORDER BY amount DESC) AS dense,
+			math::sum(amount) OVER (PARTITION BY region) AS total
+		FROM sale ORDER BY id;
+		SELECT string::len(region) OVER () AS len FROM sale;
+	";
+	let dbs = Datastore::new("memory").await?;
+	let ses = Session::for_kv().with_ns("test").with_db("test");
+	let res = &mut dbs.execute(sql, &ses, None, false).await?;
+	assert_eq!(res.len(), 7);
+	//
+	for _ in 0..4 {
+		let tmp = res.remove(0).result;
+		assert!(tmp.is_ok());
+	}
+	//
+	let tmp = res.remove(0).result?;
+	let val = Value::parse(
+		"[
+			{ id: sale:1, region: 'eu', day: 1, num: 1, running: 10, previous: 0 },
+			{ id: sale:2, region: 'eu', day: 2, num: 2, running: 40, previous: 10 },
+			{ id: sale:3, region: 'us', day: 1, num: 1, running: 20, previous: 0 },
+			{ id: sale:4, region: 'eu', day: 3, num: 3, running: 70, previous: 30 }
+		]",
+	);
+	assert_eq!(tmp, val);
+	//
+	let tmp = res.remove(0).result?;
+	let val = Value::parse(
+		"[
+			{ id: sale:1, region: 'eu', amount: 10, rank: 4, dense: 3, total: 70 },
+			{ id: sale:2, region: 'eu', amount: 30, rank: 1, dense: 1, total: 70 },
+			{ id: sale:3, region: 'us', amount: 20, rank: 3, dense: 2, total: 20 },
+			{ id: sale:4, region: 'eu', amount: 30, rank: 1, dense: 1, total: 70 }
+		]",
+	);
+	assert_eq!(tmp, val);
+	//
+	let tmp = res.remove(0).result;
+	assert!(matches!(tmp, Err(Error::InvalidArguments { .. })));
+	//
+	Ok(())
+}
@@ -0,0 +1,35 @@
+use crate::err::Error;
+use clap::ValueEnum;
+
+/// The file format used when importing or exporting data
+#[derive(ValueEnum, Clone, Copy, Debug, Default, Eq, PartialEq)]
+pub enum Format {
+	/// A SurrealQL script of the whole database
+	#[default]
+	Sql,
+	/// One JSON object per line, for a single table
+	Jsonl,
+	/// Comma-separated values with a header row, for a single table
+	Csv,
+}
+
+impl Format {
+	/// The media type used for this format on the HTTP endpoints
+	pub fn mime(&self) -> &'static str {
+		match self {
+			Format::Sql => "application/octet-stream",
+			Format::Jsonl => "application/x-ndjson",
+			Format::Csv => "text/csv",
+		}
+	}
+}
+
+/// Convert a remote endpoint into an HTTP url
+pub fn http_endpoint(endpoint: &str) -> Result<String, Error> {
+	match endpoint.split_once("://") {
+		Some(("http" | "https", _)) => Ok(endpoint.trim_end_matches('/').to_owned()),
+		Some(("ws", v)) => Ok(format!("http://{}", v.trim_end_matches('/'))),
+		Some(("wss", v)) => Ok(format!("https://{}", v.trim_end_matches('/'))),
+		_ => Err(Error::OperationUnsupported),
+	}
+}
@@ -1,4 +1,8 @@
+mod format;
+pub(crate) mod s3;
+
 use clap::Args;
+pub(crate) use format::{http_endpoint, Format};
 
 #[derive(Args, Debug)]
 pub(crate) struct AuthArguments {
@@ -45,3 +49,14 @@ pub struct DatabaseConnectionArguments {
 	#[arg(value_parser = super::validator::endpoint_valid)]
 	pub(crate) endpoint: String,
 }
+
+#[derive(Args, Debug)]
+pub struct TableFormatArguments {
+	#[arg(help = "The format of the data file")]
+	#[arg(long = "format", value_enum, default_value_t = Format::Sql)]
+	#[arg(requires_if("jsonl", "table"), requires_if("csv", "table"))]
+	pub(crate) format: Format,
+	#[arg(help = "The table to import or export, when using the jsonl or csv format")]
+	#[arg(long = "table")]
+	pub(crate) table: Option<String>,
+}
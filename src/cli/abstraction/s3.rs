@@ -0,0 +1,75 @@
+//! The snapshots which are stored in and fetched from S3-compatible object
+//! stores, using the client which is shared with the archive tier. Objects
+//! are specified as `s3://bucket/key`, and the client is configured from the
+//! standard `AWS_*` environment variables.
+use crate::cnf::SERVER_AGENT;
+use crate::err::Error;
+use bytes::Bytes;
+use reqwest::header::USER_AGENT;
+use reqwest::Method;
+use surrealdb::kvs::s3::{self, Client};
+
+/// Check if a path refers to an object in an S3-compatible store
+pub fn is_s3(v: &str) -> bool {
+	s3::is_s3(v)
+}
+
+/// An object in an S3-compatible object store
+pub struct Object {
+	client: Client,
+	path: String,
+}
+
+impl Object {
+	/// Parse an `s3://bucket/key` path, using the environment credentials
+	pub fn parse(v: &str) -> Result<Self, Error> {
+		// Parse the bucket and object key
+		let (bucket, key) = s3::parse(v)
+			.filter(|(_, k)| !k.is_empty())
+			.ok_or_else(|| Error::Backup(String::from("Specify an S3 path as s3://bucket/key")))?;
+		// Objects are addressed using path-style urls
+		let path = std::iter::once(bucket)
+			.chain(key.split('/'))
+			.map(|v| format!("/{}", urlencoding::encode(v)))
+			.collect();
+		Ok(Object {
+			client: Client::from_env()?,
+			path,
+		})
+	}
+
+	/// Upload data to this object
+	pub async fn put(&self, body: Vec<u8>) -> Result<(), Error> {
+		let req = self.client.request(Method::PUT, &self.path, &body);
+		req.header(USER_AGENT, SERVER_AGENT).body(body).send().await?.error_for_status()?;
+		Ok(())
+	}
+
+	/// Download the data in this object
+	pub async fn get(&self) -> Result<Bytes, Error> {
+		let req = self.client.request(Method::GET, &self.path, &[]);
+		Ok(req.header(USER_AGENT, SERVER_AGENT).send().await?.error_for_status()?.bytes().await?)
+	}
+}
+
+#[cfg(test)]
+mod tests {
+
+	use super::*;
+
+	#[test]
+	fn parse_path() {
+		assert!(is_s3("s3://backups/prod.db"));
+		assert!(!is_s3("backup.db"));
+		let vars = [
+			("AWS_ACCESS_KEY_ID", Some("access")),
+			("AWS_SECRET_ACCESS_KEY", Some("secret")),
+			("AWS_ENDPOINT_URL", Some("http://localhost:9000/")),
+		];
+		temp_env::with_vars(vars, || {
+			let obj = Object::parse("s3://backups/2023/prod snapshot.db").unwrap();
+			assert_eq!(obj.path, "/backups/2023/prod%20snapshot.db");
+			assert!(Object::parse("s3://backups").is_err());
+		});
+	}
+}
@@ -1,11 +1,12 @@
-use crate::cli::abstraction::AuthArguments;
+use crate::cli::abstraction::s3::{is_s3, Object};
+use crate::cli::abstraction::{AuthArguments, DatabaseSelectionOptionalArguments};
 use crate::cnf::SERVER_AGENT;
 use crate::err::Error;
 use clap::Args;
 use futures::TryStreamExt;
 use reqwest::header::CONTENT_TYPE;
 use reqwest::header::USER_AGENT;
-use reqwest::{Body, Client, Response};
+use reqwest::{Body, Client, RequestBuilder, Response};
 use std::io::ErrorKind;
 use tokio::fs::OpenOptions;
 use tokio::io::{copy, stdin, stdout, AsyncWrite, AsyncWriteExt};
@@ -24,6 +25,8 @@ pub struct BackupCommandArguments {
 	into: String,
 	#[command(flatten)]
 	auth: AuthArguments,
+	#[command(flatten)]
+	sel: DatabaseSelectionOptionalArguments,
 }
 
 pub async fn init(
@@ -34,6 +37,10 @@ pub async fn init(
 			username: user,
 			password: pass,
 		},
+		sel: DatabaseSelectionOptionalArguments {
+			namespace: ns,
+			database: db,
+		},
 	}: BackupCommandArguments,
 ) -> Result<(), Error> {
 	// Initialize opentelemetry and logging
@@ -42,56 +49,97 @@ pub async fn init(
 	// Process the source->destination response
 	let into_local = into.ends_with(".db");
 	let from_local = from.ends_with(".db");
+	let into_s3 = is_s3(&into);
+	let from_s3 = is_s3(&from);
+	let sel = (ns.as_deref(), db.as_deref());
 	match (from.as_str(), into.as_str()) {
 		// From Stdin -> Into Stdout (are you trying to make an ouroboros?)
 		("-", "-") => Err(Error::OperationUnsupported),
 		// From Stdin -> Into File (possible but meaningless)
-		("-", _) if into_local => Err(Error::OperationUnsupported),
+		("-", _) if into_local || into_s3 => Err(Error::OperationUnsupported),
 		// From File -> Into Stdout (possible but meaningless, could be useful for source validation but not for now)
-		(_, "-") if from_local => Err(Error::OperationUnsupported),
+		(_, "-") if from_local || from_s3 => Err(Error::OperationUnsupported),
 		// From File -> Into File (also possible but meaningless,
 		// but since the original function had this, I would choose to keep it as of now)
 		(from, into) if from_local && into_local => {
 			tokio::fs::copy(from, into).await?;
 			Ok(())
 		}
+		// From File or S3 -> Into File or S3
+		(from, into) if (from_local || from_s3) && (into_local || into_s3) => {
+			let data = match from_s3 {
+				true => Object::parse(from)?.get().await?.to_vec(),
+				false => tokio::fs::read(from).await?,
+			};
+			match into_s3 {
+				true => Object::parse(into)?.put(data).await,
+				false => Ok(tokio::fs::write(into, data).await?),
+			}
+		}
 		// From File -> Into HTTP
 		(from, into) if from_local => {
 			// Copy the data to the destination
 			let from = OpenOptions::new().read(true).open(from).await?;
-			post_http_sync_body(from, into, &user, &pass).await
+			post_http_sync_body(from, into, &user, &pass, sel).await
+		}
+		// From S3 -> Into HTTP
+		(from, into) if from_s3 => {
+			// Copy the data to the destination
+			let from = Object::parse(from)?.get().await?;
+			post_http_sync_body(from, into, &user, &pass, sel).await
 		}
 		// From HTTP -> Into File
 		(from, into) if into_local => {
 			// Try to open the output file
 			let into =
 				OpenOptions::new().write(true).create(true).truncate(true).open(into).await?;
-			backup_http_to_file(from, into, &user, &pass).await
+			backup_http_to_file(from, into, &user, &pass, sel).await
+		}
+		// From HTTP -> Into S3
+		(from, into) if into_s3 => {
+			// Parse the destination before taking the snapshot
+			let into = Object::parse(into)?;
+			let data = get_http_sync_body(from, &user, &pass, sel).await?.bytes().await?;
+			into.put(data.to_vec()).await
 		}
 		// From HTTP -> Into Stdout
-		(from, "-") => backup_http_to_file(from, stdout(), &user, &pass).await,
+		(from, "-") => backup_http_to_file(from, stdout(), &user, &pass, sel).await,
 		// From Stdin -> Into File
 		("-", into) => {
 			let from = Body::wrap_stream(ReaderStream::new(stdin()));
-			post_http_sync_body(from, into, &user, &pass).await
+			post_http_sync_body(from, into, &user, &pass, sel).await
 		}
 		// From HTTP -> Into HTTP
 		(from, into) => {
 			// Copy the data to the destination
-			let from = get_http_sync_body(from, &user, &pass).await?;
-			post_http_sync_body(from, into, &user, &pass).await
+			let from = get_http_sync_body(from, &user, &pass, sel).await?;
+			post_http_sync_body(from, into, &user, &pass, sel).await
 		}
 	}
 }
 
+/// The namespace and database selected for a backup
+pub(super) type Selection<'a> = (Option<&'a str>, Option<&'a str>);
+
+/// Add the selected namespace and database headers to a request
+pub(super) fn select(mut req: RequestBuilder, (ns, db): Selection) -> RequestBuilder {
+	if let Some(ns) = ns {
+		req = req.header("NS", ns);
+	}
+	if let Some(db) = db {
+		req = req.header("DB", db);
+	}
+	req
+}
+
 async fn post_http_sync_body<B: Into<Body>>(
 	from: B,
 	into: &str,
 	user: &str,
 	pass: &str,
+	sel: Selection<'_>,
 ) -> Result<(), Error> {
-	Client::new()
-		.post(format!("{into}/sync"))
+	select(Client::new().post(format!("{into}/sync")), sel)
 		.basic_auth(user, Some(pass))
 		.header(USER_AGENT, SERVER_AGENT)
 		.header(CONTENT_TYPE, TYPE)
@@ -102,9 +150,13 @@ async fn post_http_sync_body<B: Into<Body>>(
 	Ok(())
 }
 
-async fn get_http_sync_body(from: &str, user: &str, pass: &str) -> Result<Response, Error> {
-	Ok(Client::new()
-		.get(format!("{from}/sync"))
+async fn get_http_sync_body(
+	from: &str,
+	user: &str,
+	pass: &str,
+	sel: Selection<'_>,
+) -> Result<Response, Error> {
+	Ok(select(Client::new().get(format!("{from}/sync")), sel)
 		.basic_auth(user, Some(pass))
 		.header(USER_AGENT, SERVER_AGENT)
 		.header(CONTENT_TYPE, TYPE)
@@ -118,9 +170,10 @@ async fn backup_http_to_file<W: AsyncWrite + Unpin>(
 	mut into: W,
 	user: &str,
 	pass: &str,
+	sel: Selection<'_>,
 ) -> Result<(), Error> {
 	let mut from = StreamReader::new(
-		get_http_sync_body(from, user, pass)
+		get_http_sync_body(from, user, pass, sel)
 			.await?
 			.bytes_stream()
 			.map_err(|x| std::io::Error::new(ErrorKind::Other, x)),
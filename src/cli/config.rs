@@ -1,3 +1,4 @@
+use crate::iam::cert::CertAuth;
 use crate::net::client_ip::ClientIp;
 use once_cell::sync::OnceCell;
 use std::{net::SocketAddr, path::PathBuf};
@@ -8,10 +9,15 @@ pub static CF: OnceCell<Config> = OnceCell::new();
 pub struct Config {
 	pub strict: bool,
 	pub bind: SocketAddr,
+	#[cfg(feature = "grpc")]
+	pub grpc: Option<SocketAddr>,
+	pub pg: Option<SocketAddr>,
 	pub path: String,
 	pub client_ip: ClientIp,
 	pub user: String,
 	pub pass: Option<String>,
 	pub crt: Option<PathBuf>,
 	pub key: Option<PathBuf>,
+	pub ca: Option<PathBuf>,
+	pub certs: Vec<CertAuth>,
 }
@@ -1,15 +1,24 @@
 use crate::cli::abstraction::{
-	AuthArguments, DatabaseConnectionArguments, DatabaseSelectionArguments,
+	http_endpoint, AuthArguments, DatabaseConnectionArguments, DatabaseSelectionArguments, Format,
+	TableFormatArguments,
 };
 use crate::cli::LOG;
+use crate::cnf::SERVER_AGENT;
 use crate::err::Error;
 use clap::Args;
+use futures::TryStreamExt;
+use reqwest::header::{ACCEPT, USER_AGENT};
+use reqwest::Client;
+use std::io::ErrorKind;
 use surrealdb::engine::any::connect;
 use surrealdb::opt::auth::Root;
+use tokio::fs::OpenOptions;
+use tokio::io::{copy, stdout, AsyncWrite, AsyncWriteExt};
+use tokio_util::io::StreamReader;
 
 #[derive(Args, Debug)]
 pub struct ExportCommandArguments {
-	#[arg(help = "Path to the sql file to export. Use dash - to write into stdout.")]
+	#[arg(help = "Path to the file to export. Use dash - to write into stdout.")]
 	#[arg(default_value = "-")]
 	#[arg(index = 1)]
 	file: String,
@@ -20,6 +29,11 @@ pub struct ExportCommandArguments {
 	auth: AuthArguments,
 	#[command(flatten)]
 	sel: DatabaseSelectionArguments,
+	#[command(flatten)]
+	fmt: TableFormatArguments,
+	/// Whether the fields which are defined as sensitive are anonymized
+	#[arg(long)]
+	anonymize: bool,
 }
 
 pub async fn init(
@@ -36,10 +50,54 @@ pub async fn init(
 			namespace: ns,
 			database: db,
 		},
+		fmt: TableFormatArguments {
+			format,
+			table,
+		},
+		anonymize,
 	}: ExportCommandArguments,
 ) -> Result<(), Error> {
 	// Initialize opentelemetry and logging
 	crate::o11y::builder().with_log_level("error").init();
+	// Export table data using the HTTP endpoint
+	if let (Format::Jsonl | Format::Csv, Some(table)) = (format, table) {
+		// Request the table data from the server
+		let res = Client::new()
+			.get(format!("{}/export/{}", http_endpoint(&endpoint)?, urlencoding::encode(&table)))
+			.query(&[("anonymize", anonymize)])
+			.basic_auth(&username, Some(&password))
+			.header(USER_AGENT, SERVER_AGENT)
+			.header(ACCEPT, format.mime())
+			.header("NS", ns)
+			.header("DB", db)
+			.send()
+			.await?
+			.error_for_status()?;
+		// Copy the data to the destination
+		let num = save(res, &file).await?;
+		info!(target: LOG, "Exported {} bytes from table {}", num, table);
+		// Everything OK
+		return Ok(());
+	}
+	// Export anonymized data using the HTTP endpoint
+	if anonymize {
+		// Request the database export from the server
+		let res = Client::new()
+			.get(format!("{}/export", http_endpoint(&endpoint)?))
+			.query(&[("anonymize", anonymize)])
+			.basic_auth(&username, Some(&password))
+			.header(USER_AGENT, SERVER_AGENT)
+			.header("NS", ns)
+			.header("DB", db)
+			.send()
+			.await?
+			.error_for_status()?;
+		// Copy the data to the destination
+		save(res, &file).await?;
+		info!(target: LOG, "The anonymized SQL file was exported successfully");
+		// Everything OK
+		return Ok(());
+	}
 
 	let root = Root {
 		username: &username,
@@ -57,3 +115,26 @@ pub async fn init(
 	// Everything OK
 	Ok(())
 }
+
+async fn save(res: reqwest::Response, file: &str) -> Result<u64, Error> {
+	let mut from =
+		StreamReader::new(res.bytes_stream().map_err(|x| std::io::Error::new(ErrorKind::Other, x)));
+	match file {
+		"-" => write(&mut from, stdout()).await,
+		file => {
+			let into =
+				OpenOptions::new().write(true).create(true).truncate(true).open(file).await?;
+			write(&mut from, into).await
+		}
+	}
+}
+
+async fn write<R, W>(from: &mut R, mut into: W) -> Result<u64, Error>
+where
+	R: tokio::io::AsyncRead + Unpin,
+	W: AsyncWrite + Unpin,
+{
+	let num = copy(from, &mut into).await?;
+	into.flush().await?;
+	Ok(num)
+}
@@ -1,15 +1,22 @@
 use crate::cli::abstraction::{
-	AuthArguments, DatabaseConnectionArguments, DatabaseSelectionArguments,
+	http_endpoint, AuthArguments, DatabaseConnectionArguments, DatabaseSelectionArguments, Format,
+	TableFormatArguments,
 };
 use crate::cli::LOG;
+use crate::cnf::SERVER_AGENT;
 use crate::err::Error;
 use clap::Args;
+use reqwest::header::{ACCEPT, CONTENT_TYPE, USER_AGENT};
+use reqwest::{Body, Client};
+use serde_json::Value as Json;
 use surrealdb::engine::any::connect;
 use surrealdb::opt::auth::Root;
+use tokio::fs::OpenOptions;
+use tokio_util::io::ReaderStream;
 
 #[derive(Args, Debug)]
 pub struct ImportCommandArguments {
-	#[arg(help = "Path to the sql file to import")]
+	#[arg(help = "Path to the file to import")]
 	#[arg(index = 1)]
 	file: String,
 	#[command(flatten)]
@@ -18,6 +25,8 @@ pub struct ImportCommandArguments {
 	auth: AuthArguments,
 	#[command(flatten)]
 	sel: DatabaseSelectionArguments,
+	#[command(flatten)]
+	fmt: TableFormatArguments,
 }
 
 pub async fn init(
@@ -34,10 +43,40 @@ pub async fn init(
 			namespace: ns,
 			database: db,
 		},
+		fmt: TableFormatArguments {
+			format,
+			table,
+		},
 	}: ImportCommandArguments,
 ) -> Result<(), Error> {
 	// Initialize opentelemetry and logging
 	crate::o11y::builder().with_log_level("error").init();
+	// Import table data using the HTTP endpoint
+	if let (Format::Jsonl | Format::Csv, Some(table)) = (format, table) {
+		// Open the file to import
+		let file = OpenOptions::new().read(true).open(file).await?;
+		// Stream the file to the server
+		let res: Json = Client::new()
+			.post(format!("{}/import/{}", http_endpoint(&endpoint)?, urlencoding::encode(&table)))
+			.basic_auth(&username, Some(&password))
+			.header(USER_AGENT, SERVER_AGENT)
+			.header(CONTENT_TYPE, format.mime())
+			.header(ACCEPT, "application/json")
+			.header("NS", ns)
+			.header("DB", db)
+			.body(Body::wrap_stream(ReaderStream::new(file)))
+			.send()
+			.await?
+			.error_for_status()?
+			.json()
+			.await?;
+		info!(
+			target: LOG,
+			"Imported {} records into table {} in {} batches", res["records"], table, res["batches"]
+		);
+		// Everything OK
+		return Ok(());
+	}
 
 	let root = Root {
 		username: &username,
@@ -1,9 +1,10 @@
 pub(crate) mod abstraction;
 mod backup;
-mod config;
+pub(crate) mod config;
 mod export;
 mod import;
 mod isready;
+mod restore;
 mod sql;
 mod start;
 mod upgrade;
@@ -18,6 +19,7 @@ pub use config::CF;
 use export::ExportCommandArguments;
 use import::ImportCommandArguments;
 use isready::IsReadyCommandArguments;
+use restore::RestoreCommandArguments;
 use sql::SqlCommandArguments;
 use start::StartCommandArguments;
 use std::process::ExitCode;
@@ -53,6 +55,8 @@ enum Commands {
 	Start(StartCommandArguments),
 	#[command(about = "Backup data to or from an existing database")]
 	Backup(BackupCommandArguments),
+	#[command(about = "Restore a backup snapshot, optionally to a point in time")]
+	Restore(RestoreCommandArguments),
 	#[command(about = "Import a SurrealQL script into an existing database")]
 	Import(ImportCommandArguments),
 	#[command(about = "Export an existing database as a SurrealQL script")]
@@ -75,6 +79,7 @@ pub async fn init() -> ExitCode {
 	let output = match args.command {
 		Commands::Start(args) => start::init(args).await,
 		Commands::Backup(args) => backup::init(args).await,
+		Commands::Restore(args) => restore::init(args).await,
 		Commands::Import(args) => import::init(args).await,
 		Commands::Export(args) => export::init(args).await,
 		Commands::Version => version::init(),
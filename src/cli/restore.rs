@@ -0,0 +1,408 @@
+use crate::cli::abstraction::s3::{is_s3, Object};
+use crate::cli::abstraction::{
+	http_endpoint, AuthArguments, DatabaseConnectionArguments, DatabaseSelectionOptionalArguments,
+};
+use crate::cli::backup::select;
+use crate::cli::LOG;
+use crate::cnf::SERVER_AGENT;
+use crate::err::Error;
+use chrono::{DateTime, Utc};
+use clap::{ArgGroup, Args};
+use reqwest::header::{ACCEPT, CONTENT_TYPE, USER_AGENT};
+use reqwest::Client;
+use serde_json::Value as Json;
+use std::collections::HashMap;
+use std::path::PathBuf;
+use surrealdb::sql::Ident;
+
+#[derive(Args, Debug)]
+#[command(group(ArgGroup::new("replay").args(["log", "journal"])))]
+pub struct RestoreCommandArguments {
+	#[arg(help = "Path to the snapshot file or S3 object to restore")]
+	#[arg(index = 1)]
+	from: String,
+	#[command(flatten)]
+	conn: DatabaseConnectionArguments,
+	#[command(flatten)]
+	auth: AuthArguments,
+	#[command(flatten)]
+	sel: DatabaseSelectionOptionalArguments,
+	#[arg(help = "Path to an incremental backup log to replay on top of the snapshot")]
+	#[arg(long = "log")]
+	log: Option<PathBuf>,
+	#[arg(help = "Path to a statement journal to replay on top of the snapshot")]
+	#[arg(long = "journal")]
+	journal: Option<PathBuf>,
+	#[arg(
+		help = "Replay the incremental backup log or statement journal up to and including this point in time"
+	)]
+	#[arg(long = "until", requires = "replay")]
+	until: Option<DateTime<Utc>>,
+}
+
+pub async fn init(
+	RestoreCommandArguments {
+		from,
+		conn: DatabaseConnectionArguments {
+			endpoint,
+		},
+		auth: AuthArguments {
+			username: user,
+			password: pass,
+		},
+		sel: DatabaseSelectionOptionalArguments {
+			namespace: ns,
+			database: db,
+		},
+		log,
+		journal,
+		until,
+	}: RestoreCommandArguments,
+) -> Result<(), Error> {
+	// Initialize opentelemetry and logging
+	crate::o11y::builder().with_log_level("info").init();
+	// Get the server endpoint
+	let endpoint = http_endpoint(&endpoint)?;
+	let sel = (ns.as_deref(), db.as_deref());
+	// Read the snapshot data
+	let data = match is_s3(&from) {
+		true => Object::parse(&from)?.get().await?.to_vec(),
+		false => tokio::fs::read(&from).await?,
+	};
+	// Restore the snapshot
+	let res: Json = select(Client::new().post(format!("{endpoint}/sync")), sel)
+		.basic_auth(&user, Some(&pass))
+		.header(USER_AGENT, SERVER_AGENT)
+		.header(CONTENT_TYPE, "application/octet-stream")
+		.body(data)
+		.send()
+		.await?
+		.error_for_status()?
+		.json()
+		.await?;
+	let time = res["time"]
+		.as_str()
+		.and_then(|v| DateTime::parse_from_rfc3339(v).ok())
+		.map(|v| v.with_timezone(&Utc))
+		.ok_or_else(|| {
+			Error::Backup(String::from("The server did not return the snapshot time"))
+		})?;
+	info!(target: LOG, "Restored the snapshot taken at {}", time.to_rfc3339());
+	// Replay the incremental backup log
+	if let Some(log) = log {
+		let log = tokio::fs::read_to_string(log).await?;
+		let (sql, num) = entries(&log, time, until, sel)?;
+		if num > 0 {
+			replay(&endpoint, &user, &pass, sql, "backup log").await?;
+		}
+		info!(target: LOG, "Replayed {} changes from the incremental backup log", num);
+	}
+	// Replay the statement journal
+	if let Some(journal) = journal {
+		let journal = tokio::fs::read_to_string(journal).await?;
+		let (sql, num, running) = transactions(&journal, time, until, sel)?;
+		if !running.is_empty() {
+			warn!(
+				target: LOG,
+				"Skipped {} transactions which were running when the journal ended: {}",
+				running.len(),
+				running.join(", ")
+			);
+		}
+		if num > 0 {
+			replay(&endpoint, &user, &pass, sql, "statement journal").await?;
+		}
+		info!(target: LOG, "Replayed {} transactions from the statement journal", num);
+	}
+	// Everything OK
+	Ok(())
+}
+
+/// Run the SurrealQL of a backup log or statement journal on the server,
+/// checking that every statement succeeded
+async fn replay(
+	endpoint: &str,
+	user: &str,
+	pass: &str,
+	sql: String,
+	what: &str,
+) -> Result<(), Error> {
+	let res: Json = Client::new()
+		.post(format!("{endpoint}/import"))
+		.basic_auth(user, Some(pass))
+		.header(USER_AGENT, SERVER_AGENT)
+		.header(CONTENT_TYPE, "application/octet-stream")
+		.header(ACCEPT, "application/json")
+		.body(sql)
+		.send()
+		.await?
+		.error_for_status()?
+		.json()
+		.await?;
+	// Check that every statement succeeded
+	let failed = res.as_array().into_iter().flatten().find(|v| v["status"] != "OK");
+	match failed {
+		Some(v) => Err(Error::Backup(format!("Unable to replay the {what}: {}", v["detail"]))),
+		None => Ok(()),
+	}
+}
+
+/// Select the backup log entries which were committed at or after the
+/// snapshot time, and before the recovery point, returning the SurrealQL
+/// to replay along with the number of selected entries. The log can not be
+/// replayed if it has a gap between the snapshot and the recovery point.
+fn entries(
+	log: &str,
+	after: DateTime<Utc>,
+	until: Option<DateTime<Utc>>,
+	(ns, db): (Option<&str>, Option<&str>),
+) -> Result<(String, usize), Error> {
+	let time = |v: &str| DateTime::parse_from_rfc3339(v).ok().map(|v| v.with_timezone(&Utc));
+	// Only replay changes within the restored location
+	let location = match (ns, db) {
+		(Some(ns), Some(db)) => Some(format!(" NS {} DB {}", Ident::from(ns), Ident::from(db))),
+		(Some(ns), None) => Some(format!(" NS {} DB ", Ident::from(ns))),
+		_ => None,
+	};
+	let mut out = String::from("OPTION IMPORT;\n");
+	let mut num = 0;
+	let mut keep = false;
+	// The commit time of the last entry
+	let mut last: Option<DateTime<Utc>> = None;
+	for line in log.split_inclusive('\n') {
+		// Changes were missed between the last entry and this marker
+		if let Some(head) = line.strip_prefix("-- BROKEN AT ") {
+			let at = time(head.trim_end());
+			let gap = at.map_or(true, |at| at >= after)
+				&& until.map_or(true, |until| last.map_or(true, |last| last <= until));
+			if gap {
+				return Err(Error::Backup(format!(
+					"The backup log is missing the changes before {}, so it can not be replayed on this snapshot",
+					head.trim_end()
+				)));
+			}
+			keep = false;
+			continue;
+		}
+		// Each entry starts with a comment containing its commit time
+		if let Some(head) = line.strip_prefix("-- AT ") {
+			let at = head.split(' ').next().and_then(time);
+			last = at.or(last);
+			keep = match at {
+				Some(at) => {
+					at >= after
+						&& until.map_or(true, |until| at <= until)
+						&& location.as_ref().map_or(true, |v| match db {
+							Some(_) => head.trim_end().ends_with(v.as_str()),
+							None => head.contains(v.as_str()),
+						})
+				}
+				None => false,
+			};
+			if keep {
+				num += 1;
+			}
+			continue;
+		}
+		if keep {
+			out.push_str(line);
+		}
+	}
+	Ok((out, num))
+}
+
+/// Select the journaled transactions which were committed at or after the
+/// snapshot time, and before the recovery point, returning the SurrealQL to
+/// replay along with the number of selected transactions, and the ids of any
+/// transactions which were still running when the journal ended.
+fn transactions(
+	journal: &str,
+	after: DateTime<Utc>,
+	until: Option<DateTime<Utc>>,
+	(ns, db): (Option<&str>, Option<&str>),
+) -> Result<(String, usize, Vec<String>), Error> {
+	let mut out = String::new();
+	let mut num = 0;
+	// The ids of the transactions which have not finished, in order
+	let mut order: Vec<String> = vec![];
+	// The statements of each transaction which has not finished
+	let mut pending: HashMap<String, Vec<Json>> = HashMap::new();
+	let lines: Vec<&str> = journal.lines().filter(|v| !v.trim().is_empty()).collect();
+	for (i, line) in lines.iter().enumerate() {
+		let entry: Json = match serde_json::from_str(line) {
+			Ok(v) => v,
+			// The last entry may be incomplete if the server stopped while writing it
+			Err(_) if i + 1 == lines.len() => break,
+			Err(e) => return Err(Error::Backup(format!("Invalid statement journal entry: {e}"))),
+		};
+		let txn = entry["txn"].as_str().unwrap_or_default().to_owned();
+		match entry["status"].as_str() {
+			// A statement which was run in the transaction
+			None => {
+				if !pending.contains_key(&txn) {
+					order.push(txn.clone());
+				}
+				pending.entry(txn).or_default().push(entry);
+			}
+			// The transaction was committed
+			Some("COMMIT") => {
+				order.retain(|v| v != &txn);
+				let stms = pending.remove(&txn).unwrap_or_default();
+				let at = entry["at"]
+					.as_str()
+					.and_then(|v| DateTime::parse_from_rfc3339(v).ok())
+					.map(|v| v.with_timezone(&Utc));
+				let keep = match at {
+					Some(at) => at >= after && until.map_or(true, |until| at <= until),
+					None => false,
+				};
+				// Only replay statements within the restored location
+				let stms: Vec<Json> = stms
+					.into_iter()
+					.filter(|v| {
+						ns.map_or(true, |ns| v["ns"] == ns) && db.map_or(true, |db| v["db"] == db)
+					})
+					.collect();
+				if keep && !stms.is_empty() {
+					let at = entry["at"].as_str().unwrap_or_default();
+					out.push_str(&format!("-- TXN {txn} AT {at}\nBEGIN TRANSACTION;\n"));
+					stms.iter().for_each(|v| out.push_str(&statement(v)));
+					out.push_str("COMMIT TRANSACTION;\n");
+					num += 1;
+				}
+			}
+			// The transaction was cancelled
+			Some(_) => {
+				order.retain(|v| v != &txn);
+				pending.remove(&txn);
+			}
+		}
+	}
+	Ok((out, num, order))
+}
+
+/// Encode a journaled statement as SurrealQL, preceded by its location,
+/// and by the values of the parameters which it refers to. A statement
+/// which was journaled with its writes is replaced by those writes.
+fn statement(v: &Json) -> String {
+	let mut out = match (v["ns"].as_str(), v["db"].as_str()) {
+		(Some(ns), Some(db)) => format!("USE NS {} DB {};\n", Ident::from(ns), Ident::from(db)),
+		(Some(ns), None) => format!("USE NS {};\n", Ident::from(ns)),
+		_ => String::new(),
+	};
+	if let Some(writes) = v["writes"].as_array() {
+		out.push_str("OPTION IMPORT;\n");
+		for v in writes {
+			out.push_str(&format!("{};\n", v.as_str().unwrap_or_default()));
+		}
+		out.push_str("OPTION IMPORT = false;\n");
+		return out;
+	}
+	for (k, v) in v["vars"].as_object().into_iter().flatten() {
+		out.push_str(&format!("LET ${k} = {};\n", v.as_str().unwrap_or("NONE")));
+	}
+	out.push_str(&format!("{};\n", v["sql"].as_str().unwrap_or_default()));
+	out
+}
+
+#[cfg(test)]
+mod tests {
+
+	use super::*;
+
+	const LOG: &str = "-- AT 2023-05-01T10:00:00Z NS test DB test
+USE NS test DB test; UPDATE person:one CONTENT { id: person:one };
+-- AT 2023-05-01T11:00:00Z NS test DB other
+USE NS test DB other; UPDATE person:two CONTENT { id: person:two };
+-- AT 2023-05-01T12:00:00Z NS test DB test
+USE NS test DB test; DELETE person:one;
+";
+
+	const JOURNAL: &str = r#"{"txn":"a","at":"2023-05-01T10:00:00Z","ns":"test","db":"test","sql":"CREATE person:one SET name = $name","vars":{"name":"'Tobie'"}}
+{"txn":"a","at":"2023-05-01T10:00:01Z","status":"COMMIT"}
+{"txn":"b","at":"2023-05-01T11:00:00Z","ns":"test","db":"other","sql":"CREATE person:two","vars":{}}
+{"txn":"c","at":"2023-05-01T11:00:00Z","ns":"test","db":"test","sql":"DELETE person:one","vars":{}}
+{"txn":"b","at":"2023-05-01T11:00:01Z","status":"COMMIT"}
+{"txn":"c","at":"2023-05-01T11:00:01Z","status":"CANCEL"}
+{"txn":"d","at":"2023-05-01T12:00:00Z","ns":"test","db":"test","sql":"UPDATE person:one SET age = 33","vars":{}}
+{"txn":"e","at":"2023-05-01T12:00:00Z","ns":"test","db":"test","sql":"CREATE person","vars":{},"writes":["UPDATE person:xyz CONTENT { id: person:xyz }"]}
+{"txn":"e","at":"2023-05-01T12:00:01Z","status":"COMMIT"}
+{"txn":"d","at":"2023-05-01T1"#;
+
+	fn time(v: &str) -> DateTime<Utc> {
+		DateTime::parse_from_rfc3339(v).unwrap().with_timezone(&Utc)
+	}
+
+	#[test]
+	fn entries_after_snapshot() {
+		let (sql, num) = entries(LOG, time("2023-05-01T10:30:00Z"), None, (None, None)).unwrap();
+		assert_eq!(num, 2);
+		assert!(!sql.contains("person:one CONTENT"));
+		assert!(sql.contains("person:two"));
+		assert!(sql.contains("DELETE person:one"));
+	}
+
+	#[test]
+	fn entries_until_recovery_point() {
+		let until = Some(time("2023-05-01T11:30:00Z"));
+		let (sql, num) = entries(LOG, time("2023-05-01T09:00:00Z"), until, (None, None)).unwrap();
+		assert_eq!(num, 2);
+		assert!(!sql.contains("DELETE"));
+	}
+
+	#[test]
+	fn entries_for_database() {
+		let sel = (Some("test"), Some("test"));
+		let (sql, num) = entries(LOG, time("2023-05-01T09:00:00Z"), None, sel).unwrap();
+		assert_eq!(num, 2);
+		assert!(!sql.contains("person:two"));
+	}
+
+	#[test]
+	fn entries_across_gap() {
+		let log = format!("{LOG}-- BROKEN AT 2023-05-01T13:00:00Z\n");
+		// A snapshot taken after the gap can be replayed
+		let (_, num) = entries(&log, time("2023-05-01T14:00:00Z"), None, (None, None)).unwrap();
+		assert_eq!(num, 0);
+		// A recovery point before the missing changes can be replayed
+		let until = Some(time("2023-05-01T11:30:00Z"));
+		let (_, num) = entries(&log, time("2023-05-01T09:00:00Z"), until, (None, None)).unwrap();
+		assert_eq!(num, 2);
+		// Otherwise the log can not be replayed across the gap
+		assert!(entries(&log, time("2023-05-01T09:00:00Z"), None, (None, None)).is_err());
+	}
+
+	#[test]
+	fn transactions_after_snapshot() {
+		let (sql, num, running) =
+			transactions(JOURNAL, time("2023-05-01T09:00:00Z"), None, (None, None)).unwrap();
+		assert_eq!(num, 3);
+		assert!(sql.contains("USE NS test DB test;\nLET $name = 'Tobie';\nCREATE person:one"));
+		assert!(sql.contains("CREATE person:two"));
+		// The writes of statements which changed records are replayed in their place
+		assert!(sql.contains("OPTION IMPORT;\nUPDATE person:xyz CONTENT { id: person:xyz };\n"));
+		assert!(!sql.contains("CREATE person;"));
+		// Cancelled transactions are not replayed
+		assert!(!sql.contains("DELETE"));
+		// Transactions which never finished are reported
+		assert!(!sql.contains("age = 33"));
+		assert_eq!(running, vec!["d"]);
+	}
+
+	#[test]
+	fn transactions_until_recovery_point() {
+		let until = Some(time("2023-05-01T10:30:00Z"));
+		let (sql, num, _) =
+			transactions(JOURNAL, time("2023-05-01T09:00:00Z"), until, (None, None)).unwrap();
+		assert_eq!(num, 1);
+		assert!(!sql.contains("person:two"));
+	}
+
+	#[test]
+	fn transactions_for_database() {
+		let sel = (Some("test"), Some("test"));
+		let (sql, num, _) = transactions(JOURNAL, time("2023-05-01T09:00:00Z"), None, sel).unwrap();
+		assert_eq!(num, 2);
+		assert!(!sql.contains("person:two"));
+	}
+}
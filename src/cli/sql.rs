@@ -2,16 +2,38 @@ use crate::cli::abstraction::{
 	AuthArguments, DatabaseConnectionArguments, DatabaseSelectionOptionalArguments,
 };
 use crate::err::Error;
-use clap::Args;
+use crate::net::csv;
+use clap::{Args, ValueEnum};
 use rustyline::error::ReadlineError;
 use rustyline::validate::{ValidationContext, ValidationResult, Validator};
 use rustyline::{Completer, Editor, Helper, Highlighter, Hinter};
 use serde::Serialize;
 use serde_json::ser::PrettyFormatter;
-use surrealdb::engine::any::connect;
+use surrealdb::engine::any::{connect, Any};
 use surrealdb::opt::auth::Root;
-use surrealdb::sql::{self, Statement, Value};
-use surrealdb::Response;
+use surrealdb::sql::{self, Statement, Table, Value};
+use surrealdb::{Response, Surreal};
+
+const HELP: &str = "\\q              Quit the REPL
+\\?              Show this help
+\\l              List the databases in the namespace
+\\dt             List the tables in the database
+\\d <table>      Describe the fields, indexes, and events of a table
+\\f <format>     Output results as sql, json, table, or csv";
+
+/// The format in which query results are output
+#[derive(ValueEnum, Clone, Copy, Debug, Default, Eq, PartialEq)]
+pub enum OutputFormat {
+	/// SurrealQL values
+	#[default]
+	Sql,
+	/// JSON values
+	Json,
+	/// An aligned table of the records of each statement
+	Table,
+	/// Comma-separated values with a header row, for each statement
+	Csv,
+}
 
 #[derive(Args, Debug)]
 pub struct SqlCommandArguments {
@@ -27,6 +49,9 @@ pub struct SqlCommandArguments {
 	/// Whether to emit results in JSON
 	#[arg(long)]
 	json: bool,
+	/// The format in which results are output
+	#[arg(long, value_enum, conflicts_with = "json")]
+	format: Option<OutputFormat>,
 	/// Whether omitting semicolon causes a newline
 	#[arg(long)]
 	multi: bool,
@@ -44,6 +69,7 @@ pub async fn init(
 		sel,
 		pretty,
 		json,
+		format,
 		multi,
 		..
 	}: SqlCommandArguments,
@@ -77,6 +103,11 @@ pub async fn init(
 	} else {
 		(None, None)
 	};
+	// Configure the output format
+	let mut format = match json {
+		true => OutputFormat::Json,
+		false => format.unwrap_or_default(),
+	};
 	// Configure the prompt
 	let mut prompt = "> ".to_owned();
 	// Loop over each command-line input
@@ -127,6 +158,17 @@ pub async fn init(
 				break;
 			}
 		};
+		// Run any meta command
+		if let Some(cmd) = line.trim().strip_prefix('\\') {
+			match cmd.trim() {
+				"q" => break,
+				cmd => match meta(&client, cmd, &mut format).await {
+					Ok(v) => println!("{v}\n"),
+					Err(e) => eprintln!("{e}\n"),
+				},
+			}
+			continue;
+		}
 		// Complete the request
 		match sql::parse(&line) {
 			Ok(query) => {
@@ -150,7 +192,7 @@ pub async fn init(
 				}
 				let res = client.query(query).await;
 				// Get the request response
-				match process(pretty, json, res) {
+				match process(pretty, format, res) {
 					Ok(v) => {
 						println!("{v}\n");
 					}
@@ -170,11 +212,89 @@ pub async fn init(
 	Ok(())
 }
 
-fn process(pretty: bool, json: bool, res: surrealdb::Result<Response>) -> Result<String, Error> {
+/// Run a meta command, returning the output
+async fn meta(
+	client: &Surreal<Any>,
+	cmd: &str,
+	format: &mut OutputFormat,
+) -> Result<String, Error> {
+	let (cmd, arg) = cmd.split_once(char::is_whitespace).unwrap_or((cmd, ""));
+	match (cmd, arg.trim()) {
+		("?", _) => Ok(HELP.to_owned()),
+		("f", arg) => match OutputFormat::from_str(arg, true) {
+			Ok(v) => {
+				*format = v;
+				Ok(format!("Output format is {arg}"))
+			}
+			Err(_) => Ok(format!("Unknown output format '{arg}'")),
+		},
+		("l", "") => {
+			let info: Value = client.query("INFO FOR NS").await?.take(0)?;
+			Ok(table(&definitions(&info, &["databases"])))
+		}
+		("dt", "") => {
+			let info: Value = client.query("INFO FOR DB").await?.take(0)?;
+			Ok(table(&definitions(&info, &["tables"])))
+		}
+		("d", tb) if !tb.is_empty() => {
+			let sql = format!("INFO FOR TABLE {}", Table::from(tb));
+			let info: Value = client.query(sql).await?.take(0)?;
+			let kinds = ["fields", "indexes", "events", "tables"];
+			let count = object(&info).and_then(|v| v.get("count")).cloned().unwrap_or_default();
+			Ok(format!("{}\n{count} records", table(&definitions(&info, &kinds))))
+		}
+		_ => Ok(format!("Unknown command '\\{cmd}', use \\? for help")),
+	}
+}
+
+/// Collect the definitions of the specified kinds from an INFO statement
+fn definitions(info: &Value, kinds: &[&str]) -> Value {
+	let mut out = Vec::new();
+	for kind in kinds {
+		if let Some(Value::Object(defs)) = object(info).and_then(|v| v.get(*kind)) {
+			for (name, def) in defs.iter() {
+				out.push(Value::from(map! {
+					String::from("kind") => Value::from(match *kind {
+						"indexes" => "index",
+						kind => kind.trim_end_matches('s'),
+					}),
+					String::from("name") => Value::from(name.as_str()),
+					String::from("definition") => def.clone(),
+				}));
+			}
+		}
+	}
+	Value::from(out)
+}
+
+fn process(
+	pretty: bool,
+	format: OutputFormat,
+	res: surrealdb::Result<Response>,
+) -> Result<String, Error> {
 	// Check query response for an error
 	let mut response = res?;
 	// Get the number of statements the query contained
 	let num_statements = response.num_statements();
+	// Output the records of each statement separately
+	if let OutputFormat::Table | OutputFormat::Csv = format {
+		let render = match format {
+			OutputFormat::Csv => records_csv,
+			_ => table,
+		};
+		if num_statements == 1 {
+			return Ok(render(&response.take(0)?));
+		}
+		let mut output = Vec::with_capacity(num_statements);
+		for index in 0..num_statements {
+			output.push(match response.take(index) {
+				Ok(v) => render(&v),
+				Err(e) => e.to_string(),
+			});
+		}
+		return Ok(output.join("\n\n"));
+	}
+	let json = format == OutputFormat::Json;
 	// Prepare a single value from the query response
 	let value = if num_statements > 1 {
 		let mut output = Vec::<Value>::with_capacity(num_statements);
@@ -209,6 +329,83 @@ fn process(pretty: bool, json: bool, res: surrealdb::Result<Response>) -> Result
 	})
 }
 
+/// Get the records of a statement, or `None` if the value is not a list of objects
+fn records(value: &Value) -> Option<(Vec<String>, Vec<&sql::Object>)> {
+	let rows: Vec<&sql::Object> = match value {
+		Value::Array(v) => v.iter().map(object).collect::<Option<_>>()?,
+		v => vec![object(v)?],
+	};
+	// Output the columns in the order that they are first seen, with the id first
+	let mut cols: Vec<String> = Vec::new();
+	for row in rows.iter() {
+		for k in row.keys() {
+			if !cols.contains(k) {
+				cols.push(k.to_owned());
+			}
+		}
+	}
+	if let Some(i) = cols.iter().position(|v| v == "id") {
+		let id = cols.remove(i);
+		cols.insert(0, id);
+	}
+	Some((cols, rows))
+}
+
+fn object(value: &Value) -> Option<&sql::Object> {
+	match value {
+		Value::Object(v) => Some(v),
+		_ => None,
+	}
+}
+
+/// Output a list of records as an aligned table
+fn table(value: &Value) -> String {
+	let (cols, rows) = match records(value) {
+		Some(v) => v,
+		None => return value.to_string(),
+	};
+	let cell = |row: &sql::Object, col: &str| match row.get(col) {
+		None | Some(Value::None | Value::Null) => String::new(),
+		Some(Value::Strand(v)) => v.as_str().replace('\n', "\\n"),
+		Some(v) => v.to_string().replace('\n', "\\n"),
+	};
+	let cells: Vec<Vec<String>> =
+		rows.iter().map(|row| cols.iter().map(|c| cell(row, c)).collect()).collect();
+	// Size each column to fit its widest cell
+	let widths: Vec<usize> = cols
+		.iter()
+		.enumerate()
+		.map(|(i, c)| {
+			cells.iter().map(|r| r[i].chars().count()).fold(c.chars().count(), usize::max)
+		})
+		.collect();
+	let line = |row: &[String]| {
+		let cells = row.iter().zip(&widths).map(|(v, w)| format!(" {v:<w$} "));
+		cells.collect::<Vec<_>>().join("|").trim_end().to_owned()
+	};
+	let mut out = vec![line(&cols)];
+	out.push(widths.iter().map(|w| "-".repeat(w + 2)).collect::<Vec<_>>().join("+"));
+	out.extend(cells.iter().map(|r| line(r)));
+	out.push(match rows.len() {
+		1 => "(1 row)".to_owned(),
+		n => format!("({n} rows)"),
+	});
+	out.join("\n")
+}
+
+/// Output a list of records as comma-separated values with a header row
+fn records_csv(value: &Value) -> String {
+	let (cols, rows) = match records(value) {
+		Some(v) => v,
+		None => return value.to_string(),
+	};
+	let mut out = csv::row(&cols);
+	for row in rows {
+		out.push_str(&csv::row(cols.iter().map(|c| csv::cell(row.get(c).unwrap_or(&Value::None)))));
+	}
+	out.trim_end().to_owned()
+}
+
 #[derive(Completer, Helper, Highlighter, Hinter)]
 struct InputValidator {
 	/// If omitting semicolon causes newline.
@@ -224,12 +421,16 @@ impl Validator for InputValidator {
 		// Trim all whitespace from the user input
 		let input = input.trim();
 		// Process the input to check if we can send the query
-		let result = if self.multi && !input.ends_with(';') {
+		let result = if input.starts_with('\\') {
+			Valid(None) // The line is a meta command
+		} else if self.multi && !input.ends_with(';') {
 			Incomplete // The line doesn't end with a ; and we are in multi mode
 		} else if self.multi && input.is_empty() {
 			Incomplete // The line was empty and we are in multi mode
 		} else if input.ends_with('\\') {
 			Incomplete // The line ends with a backslash
+		} else if unterminated(input) {
+			Incomplete // The line has an unclosed quote, bracket, or brace
 		} else if let Err(e) = sql::parse(input) {
 			Invalid(Some(format!(" --< {e}")))
 		} else {
@@ -243,3 +444,67 @@ impl Validator for InputValidator {
 fn filter_line_continuations(line: &str) -> String {
 	line.replace("\\\n", "").replace("\\\r\n", "")
 }
+
+/// Check if the input has an unclosed quote, bracket, or brace, in
+/// which case the statement is continued on the next line
+fn unterminated(input: &str) -> bool {
+	let mut depth = 0;
+	let mut quote = None;
+	let mut chars = input.chars().peekable();
+	while let Some(c) = chars.next() {
+		match (quote, c) {
+			// Skip any escaped character in a string
+			(Some(_), '\\') => {
+				chars.next();
+			}
+			(Some(q), c) if c == q => quote = None,
+			(Some(_), _) => (),
+			// Skip any comment until the end of the line
+			(None, '#') => while chars.next_if(|c| *c != '\n').is_some() {},
+			(None, '-' | '/') if chars.peek() == Some(&c) => {
+				while chars.next_if(|c| *c != '\n').is_some() {}
+			}
+			(None, '"' | '\'' | '`') => quote = Some(c),
+			(None, '(' | '[' | '{') => depth += 1,
+			(None, ')' | ']' | '}') => depth -= 1,
+			_ => (),
+		}
+	}
+	quote.is_some() || depth > 0
+}
+
+#[cfg(test)]
+mod tests {
+
+	use super::*;
+
+	#[test]
+	fn unterminated_input() {
+		assert!(unterminated("CREATE person CONTENT {"));
+		assert!(unterminated("CREATE person SET name = 'Tobie"));
+		assert!(!unterminated("CREATE person SET name = 'To{bie'"));
+		assert!(!unterminated("SELECT * FROM person -- it's {"));
+		assert!(!unterminated("CREATE person CONTENT { tags: ['a'] }"));
+	}
+
+	#[test]
+	fn output_table() {
+		let val = Value::from(vec![
+			Value::from(map! {
+				String::from("id") => Value::from("person:one"),
+				String::from("age") => Value::from(30),
+			}),
+			Value::from(map! {
+				String::from("id") => Value::from("person:two"),
+				String::from("name") => Value::from("Jaime"),
+			}),
+		]);
+		let out = table(&val);
+		assert_eq!(
+			out,
+			" id         | age | name\n------------+-----+-------\n person:one | 30  |\n person:two |     | Jaime\n(2 rows)"
+		);
+		let out = records_csv(&val);
+		assert_eq!(out, "id,age,name\nperson:one,30,\nperson:two,,Jaime");
+	}
+}
@@ -7,8 +7,12 @@ use crate::dbs;
 use crate::dbs::StartCommandDbsOptions;
 use crate::env;
 use crate::err::Error;
+#[cfg(feature = "grpc")]
+use crate::grpc;
 use crate::iam;
+use crate::iam::cert::CertAuth;
 use crate::net::{self, client_ip::ClientIp};
+use crate::pgwire;
 use clap::Args;
 use ipnet::IpNet;
 use std::net::SocketAddr;
@@ -32,6 +36,12 @@ pub struct StartCommandArguments {
 	#[arg(env = "SURREAL_ADDR", long = "addr")]
 	#[arg(default_value = "127.0.0.1/32")]
 	allowed_networks: Vec<IpNet>,
+	#[arg(
+		help = "The comma-separated authentication levels of client certificates, in the form cn=root, cn=ns, or cn=ns/db"
+	)]
+	#[arg(env = "SURREAL_CLIENT_CERT_AUTH", long = "client-cert-auth", value_delimiter = ',')]
+	#[arg(requires = "web_ca")]
+	client_cert_auth: Vec<CertAuth>,
 	#[arg(help = "The method of detecting the client's IP address")]
 	#[arg(env = "SURREAL_CLIENT_IP", long)]
 	#[arg(default_value = "socket", value_enum)]
@@ -40,6 +50,13 @@ pub struct StartCommandArguments {
 	#[arg(env = "SURREAL_BIND", short = 'b', long = "bind")]
 	#[arg(default_value = "0.0.0.0:8000")]
 	listen_addresses: Vec<SocketAddr>,
+	#[cfg(feature = "grpc")]
+	#[arg(help = "The hostname or ip address to listen for gRPC connections on")]
+	#[arg(env = "SURREAL_GRPC_BIND", long = "grpc-bind")]
+	grpc_address: Option<SocketAddr>,
+	#[arg(help = "The hostname or ip address to listen for PostgreSQL connections on")]
+	#[arg(env = "SURREAL_PG_BIND", long = "pg-bind")]
+	pg_address: Option<SocketAddr>,
 	#[command(flatten)]
 	dbs: StartCommandDbsOptions,
 	#[arg(help = "Encryption key to use for on-disk encryption")]
@@ -88,6 +105,9 @@ struct StartCommandWebTlsOptions {
 	#[arg(help = "Path to the private key file for encrypted client connections")]
 	#[arg(env = "SURREAL_WEB_KEY", long = "web-key", value_parser = super::validator::file_exists)]
 	web_key: Option<PathBuf>,
+	#[arg(help = "Path to the CA file used to verify the certificates of clients, for mutual TLS")]
+	#[arg(env = "SURREAL_WEB_CA", long = "web-ca", value_parser = super::validator::file_exists)]
+	web_ca: Option<PathBuf>,
 }
 
 pub async fn init(
@@ -96,7 +116,11 @@ pub async fn init(
 		username: user,
 		password: pass,
 		client_ip,
+		client_cert_auth,
 		listen_addresses,
+		#[cfg(feature = "grpc")]
+		grpc_address,
+		pg_address,
 		dbs,
 		web,
 		strict,
@@ -117,12 +141,17 @@ pub async fn init(
 	let _ = config::CF.set(Config {
 		strict,
 		bind: listen_addresses.first().cloned().unwrap(),
+		#[cfg(feature = "grpc")]
+		grpc: grpc_address,
+		pg: pg_address,
 		client_ip,
 		path,
 		user,
 		pass,
 		crt: web.as_ref().and_then(|x| x.web_crt.clone()),
 		key: web.as_ref().and_then(|x| x.web_key.clone()),
+		ca: web.as_ref().and_then(|x| x.web_ca.clone()),
+		certs: client_cert_auth,
 	});
 	// Initiate environment
 	env::init().await?;
@@ -130,8 +159,15 @@ pub async fn init(
 	iam::init().await?;
 	// Start the kvs server
 	dbs::init(dbs).await?;
+	// Start the gRPC server
+	#[cfg(feature = "grpc")]
+	grpc::init().await?;
+	// Start the PostgreSQL listener
+	pgwire::init().await?;
 	// Start the web server
 	net::init().await?;
+	// Drain and flush the datastore
+	dbs::shutdown().await;
 	// All ok
 	Ok(())
 }
@@ -1,6 +1,7 @@
 use clap::builder::{NonEmptyStringValueParser, PossibleValue, TypedValueParser};
 use clap::error::{ContextKind, ContextValue, ErrorKind};
 use tracing::Level;
+use tracing_subscriber::filter::ParseError;
 use tracing_subscriber::EnvFilter;
 
 #[derive(Debug)]
@@ -12,6 +13,23 @@ impl Clone for CustomEnvFilter {
 	}
 }
 
+/// Parse a log level, or a custom log filter configuration string
+pub fn filter(v: &str) -> Result<EnvFilter, ParseError> {
+	match v {
+		// Don't show any logs at all
+		"none" => Ok(EnvFilter::default()),
+		// Check if we should show all log levels
+		"full" => Ok(EnvFilter::default().add_directive(Level::TRACE.into())),
+		// Otherwise, let's only show errors
+		"error" => Ok(EnvFilter::default().add_directive(Level::ERROR.into())),
+		// Specify the log level for each code area
+		"warn" | "info" | "debug" | "trace" => EnvFilter::builder()
+			.parse(format!("error,surreal={v},surrealdb={v},surrealdb::txn=error")),
+		// Let's try to parse the custom log level
+		_ => EnvFilter::builder().parse(v),
+	}
+}
+
 #[derive(Clone)]
 pub struct CustomEnvFilterParser;
 
@@ -32,20 +50,7 @@ impl TypedValueParser for CustomEnvFilterParser {
 	) -> Result<Self::Value, clap::Error> {
 		let inner = NonEmptyStringValueParser::new();
 		let v = inner.parse_ref(cmd, arg, value)?;
-		let filter = (match v.as_str() {
-			// Don't show any logs at all
-			"none" => Ok(EnvFilter::default()),
-			// Check if we should show all log levels
-			"full" => Ok(EnvFilter::default().add_directive(Level::TRACE.into())),
-			// Otherwise, let's only show errors
-			"error" => Ok(EnvFilter::default().add_directive(Level::ERROR.into())),
-			// Specify the log level for each code area
-			"warn" | "info" | "debug" | "trace" => EnvFilter::builder()
-				.parse(format!("error,surreal={v},surrealdb={v},surrealdb::txn=error")),
-			// Let's try to parse the custom log level
-			_ => EnvFilter::builder().parse(v),
-		})
-		.map_err(|e| {
+		let filter = filter(&v).map_err(|e| {
 			let mut err = clap::Error::new(ErrorKind::ValueValidation).with_cmd(cmd);
 			err.insert(ContextKind::Custom, ContextValue::String(e.to_string()));
 			err.insert(
@@ -25,9 +25,16 @@ pub const APP_ENDPOINT: &str = "https://surrealdb.com/app";
 /// How many concurrent tasks can be handled in a WebSocket
 pub const MAX_CONCURRENT_CALLS: usize = 24;
 
+/// How many live query notifications can be sent to a WebSocket before they
+/// must be acknowledged, once the client has started acknowledging them
+pub const WEBSOCKET_NOTIFICATION_WINDOW: u64 = 64;
+
 /// Specifies the frequency with which ping messages should be sent to the client
 pub const WEBSOCKET_PING_FREQUENCY: Duration = Duration::from_secs(5);
 
+/// How many batches a replica can be behind its leader and still be ready to serve queries
+pub const REPLICA_READY_LAG: u64 = 1000;
+
 /// The version identifier of this build
 pub static PKG_VERSION: Lazy<String> = Lazy::new(|| match option_env!("SURREAL_BUILD_METADATA") {
 	Some(metadata) if !metadata.trim().is_empty() => {
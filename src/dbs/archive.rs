@@ -0,0 +1,24 @@
+use crate::dbs::DB;
+use std::time::Duration;
+
+const LOG: &str = "surrealdb::dbs::archive";
+
+/// Start periodically moving the records in tables defined with `ARCHIVE`
+/// from the datastore to the archive tier.
+pub fn init(interval: Duration) {
+	// Get a database reference
+	let dbs = DB.get().unwrap();
+	info!(target: LOG, "Archiving records every {:?}", interval);
+	// Archive the records in the background
+	tokio::spawn(async move {
+		let mut tick = tokio::time::interval(interval);
+		loop {
+			tick.tick().await;
+			match dbs.archive().await {
+				Ok(0) => (),
+				Ok(n) => info!(target: LOG, "Moved {} records to the archive tier", n),
+				Err(e) => error!(target: LOG, "Unable to archive records: {}", e),
+			}
+		}
+	});
+}
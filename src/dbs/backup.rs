@@ -0,0 +1,80 @@
+use crate::dbs::DB;
+use crate::err::Error;
+use std::path::PathBuf;
+use surrealdb::changes::{Action, Change};
+use surrealdb::sql::{Datetime, Ident};
+use tokio::fs::OpenOptions;
+use tokio::io::{AsyncWriteExt, BufWriter};
+
+const LOG: &str = "surrealdb::dbs::backup";
+
+/// Start appending committed changes to the incremental backup log.
+///
+/// Each change is written as a SurrealQL statement, preceded by a comment
+/// line containing the commit time, namespace, and database of the change,
+/// so that the log can be replayed on top of a snapshot up to any point in
+/// time, using the `restore` command.
+///
+/// If the log falls too far behind the change log, the changes which were
+/// missed can not be recovered, so a `-- BROKEN AT` line is written with the
+/// time of the gap, and the `restore` command refuses to replay the log on
+/// top of any snapshot which was taken before the gap.
+pub async fn init(path: PathBuf) -> Result<(), Error> {
+	// Get a database reference
+	let dbs = DB.get().unwrap();
+	// Open the log file for appending
+	let file = OpenOptions::new().create(true).append(true).open(&path).await?;
+	let mut file = BufWriter::new(file);
+	info!(target: LOG, "Writing incremental backup log to {}", path.display());
+	// Subscribe to the change log
+	let mut chn = dbs.changes().await;
+	// Write the changes in the background
+	tokio::spawn(async move {
+		loop {
+			while let Ok(change) = chn.recv().await {
+				let res = async {
+					file.write_all(entry(&change).as_bytes()).await?;
+					// Flush once all pending changes are written
+					if chn.is_empty() {
+						file.flush().await?;
+					}
+					Ok::<(), std::io::Error>(())
+				};
+				if let Err(e) = res.await {
+					error!(target: LOG, "Unable to write to the backup log: {}", e);
+				}
+			}
+			// The subscriber fell too far behind the change log
+			error!(
+				target: LOG,
+				"The backup log fell behind, so a new snapshot is required for point-in-time recovery"
+			);
+			// Mark the gap in the log, so that it is not replayed across it
+			let res = async {
+				file.write_all(broken(&Datetime::default()).as_bytes()).await?;
+				file.flush().await
+			};
+			if let Err(e) = res.await {
+				error!(target: LOG, "Unable to write to the backup log: {}", e);
+			}
+			chn = dbs.changes().await;
+		}
+	});
+	Ok(())
+}
+
+/// Encode the marker of a gap in the backup log
+fn broken(at: &Datetime) -> String {
+	format!("-- BROKEN AT {}\n", at.to_raw())
+}
+
+/// Encode a change as a backup log entry
+fn entry(change: &Change) -> String {
+	let ns = Ident::from(change.ns.as_str());
+	let db = Ident::from(change.db.as_str());
+	let stm = match change.action() {
+		Action::Delete => format!("DELETE {}", change.id),
+		_ => format!("UPDATE {} CONTENT {}", change.id, change.after),
+	};
+	format!("-- AT {} NS {} DB {}\nUSE NS {} DB {}; {};\n", change.at.to_raw(), ns, db, ns, db, stm)
+}
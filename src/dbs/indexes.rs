@@ -0,0 +1,27 @@
+use crate::dbs::DB;
+use std::time::Duration;
+
+const LOG: &str = "surrealdb::dbs::indexes";
+
+/// How long to wait before checking for indexes to build again
+const INTERVAL: Duration = Duration::from_secs(1);
+
+/// Start building the indexes of large tables in the background, which are
+/// built in batches, one after the other, until every index has been built.
+pub fn init() {
+	// Get a database reference
+	let dbs = DB.get().unwrap();
+	// Build the indexes in the background
+	tokio::spawn(async move {
+		loop {
+			match dbs.build_indexes().await {
+				Ok(0) => tokio::time::sleep(INTERVAL).await,
+				Ok(n) => debug!(target: LOG, "Indexed {} records in the background", n),
+				Err(e) => {
+					error!(target: LOG, "Unable to build indexes: {}", e);
+					tokio::time::sleep(INTERVAL).await
+				}
+			}
+		}
+	});
+}
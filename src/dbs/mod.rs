@@ -1,26 +1,349 @@
+mod archive;
+mod backup;
+mod indexes;
+mod publish;
+pub mod replica;
+mod settings;
+mod transactions;
+
+use std::path::PathBuf;
 use std::time::Duration;
 
 use crate::cli::CF;
+use crate::dbs::publish::PublishFormat;
 use crate::err::Error;
 use clap::Args;
-use once_cell::sync::OnceCell;
+use once_cell::sync::{Lazy, OnceCell};
+use surrealdb::cnf::{DOCUMENT_CHUNK_SIZE, MAX_DOCUMENT_SIZE};
+use surrealdb::dbs::HttpLimits;
+use surrealdb::dbs::QuotaLimits;
+use surrealdb::dbs::SandboxLimits;
+use surrealdb::dbs::SloTarget;
+use surrealdb::iam::allowlist::Allowlist;
+use surrealdb::iam::external::Authenticator;
+use surrealdb::iam::oidc::Issuer;
 use surrealdb::kvs::Datastore;
 
 pub static DB: OnceCell<Datastore> = OnceCell::new();
 
+static SHUTDOWN_TIMEOUT: OnceCell<Duration> = OnceCell::new();
+
+static SHUTDOWN: Lazy<tokio::sync::OnceCell<()>> = Lazy::new(tokio::sync::OnceCell::new);
+
 const LOG: &str = "surrealdb::dbs";
 
 #[derive(Args, Debug)]
 pub struct StartCommandDbsOptions {
+	#[arg(help = "How long running queries are given to complete when the server shuts down")]
+	#[arg(env = "SURREAL_SHUTDOWN_TIMEOUT", long)]
+	#[arg(value_parser = super::cli::validator::duration)]
+	#[arg(default_value = "10s")]
+	shutdown_timeout: Duration,
+	#[arg(
+		help = "How long a transaction which is kept open between requests can be idle before it is cancelled"
+	)]
+	#[arg(env = "SURREAL_TRANSACTION_TIMEOUT", long)]
+	#[arg(value_parser = super::cli::validator::duration)]
+	#[arg(default_value = "30s")]
+	transaction_timeout: Duration,
+	#[arg(
+		help = "How many times a statement or transaction which conflicts with a concurrent transaction is retried"
+	)]
+	#[arg(env = "SURREAL_TRANSACTION_RETRIES", long)]
+	#[arg(default_value_t = 3)]
+	transaction_retries: u32,
 	#[arg(help = "The maximum duration of any query")]
 	#[arg(env = "SURREAL_QUERY_TIMEOUT", long)]
 	#[arg(value_parser = super::cli::validator::duration)]
 	query_timeout: Option<Duration>,
+	#[arg(
+		help = "Whether the changes of every query are rolled back, returning the changes which they would have made"
+	)]
+	#[arg(env = "SURREAL_READONLY", long)]
+	#[arg(default_value_t = false)]
+	readonly: bool,
+	#[arg(
+		help = "The file to which committed changes are continuously appended, for point-in-time recovery"
+	)]
+	#[arg(env = "SURREAL_BACKUP_LOG", long)]
+	backup_log: Option<PathBuf>,
+	#[arg(
+		help = "The file to which every statement which changes the datastore is appended before it is committed"
+	)]
+	#[arg(env = "SURREAL_JOURNAL", long)]
+	journal: Option<PathBuf>,
+	#[arg(help = "The url at which other nodes can reach this node, to enable replication")]
+	#[arg(env = "SURREAL_REPLICA_NODE", long)]
+	replica_node: Option<String>,
+	#[arg(help = "The comma-separated urls of the other nodes in the cluster")]
+	#[arg(env = "SURREAL_REPLICA_PEERS", long, value_delimiter = ',', requires = "replica_node")]
+	replica_peers: Vec<String>,
+	#[arg(help = "Whether commits wait until they are replicated to a majority of the cluster")]
+	#[arg(env = "SURREAL_REPLICA_SYNC", long, requires = "replica_node")]
+	#[arg(default_value_t = false)]
+	replica_sync: bool,
+	#[arg(help = "The read-only replica from which queries which do not modify data are served")]
+	#[arg(env = "SURREAL_REPLICA_PATH", long)]
+	replica_path: Option<String>,
+	#[arg(help = "The object store to which records in ARCHIVE tables are moved")]
+	#[arg(env = "SURREAL_ARCHIVE_PATH", long)]
+	archive_path: Option<String>,
+	#[arg(help = "How often records in ARCHIVE tables are moved to the object store")]
+	#[arg(env = "SURREAL_ARCHIVE_INTERVAL", long, requires = "archive_path")]
+	#[arg(value_parser = super::cli::validator::duration)]
+	#[arg(default_value = "1h")]
+	archive_interval: Duration,
+	#[arg(
+		help = "The comma-separated latency targets, in the form ns[/db[/tb]]=latency[@objective], which are exposed at /metrics"
+	)]
+	#[arg(env = "SURREAL_SLO_TARGETS", long = "slo-target", value_delimiter = ',')]
+	slo_targets: Vec<SloTarget>,
+	#[arg(help = "The maximum number of statements per second for each authenticated identity")]
+	#[arg(env = "SURREAL_RATE_LIMIT_QUERIES", long)]
+	rate_limit_queries: Option<u64>,
+	#[arg(help = "The maximum number of writes per second for each authenticated identity")]
+	#[arg(env = "SURREAL_RATE_LIMIT_WRITES", long)]
+	rate_limit_writes: Option<u64>,
+	#[arg(help = "The maximum result bytes per second for each authenticated identity")]
+	#[arg(env = "SURREAL_RATE_LIMIT_BYTES", long)]
+	rate_limit_bytes: Option<u64>,
+	#[arg(help = "The maximum number of live queries for each authenticated identity")]
+	#[arg(env = "SURREAL_MAX_LIVE_QUERIES", long)]
+	max_live_queries: Option<u64>,
+	#[arg(
+		help = "The file of runtime settings, in the form name = value, which is applied again on SIGHUP"
+	)]
+	#[arg(env = "SURREAL_SETTINGS", long)]
+	settings: Option<PathBuf>,
+	#[arg(
+		help = "The number of recent changes which are kept, so that live query event streams can resume"
+	)]
+	#[arg(env = "SURREAL_LIVE_HISTORY", long)]
+	#[arg(default_value_t = 1000)]
+	live_history: usize,
+	#[arg(
+		help = "The Kafka brokers (kafka://host:port,...) or NATS server (nats://host:port) to which committed changes are published"
+	)]
+	#[arg(env = "SURREAL_PUBLISH_URL", long)]
+	publish_url: Option<String>,
+	#[arg(help = "The prefix of the topic to which the changes to each table are published")]
+	#[arg(env = "SURREAL_PUBLISH_PREFIX", long, requires = "publish_url")]
+	#[arg(default_value = "surrealdb")]
+	publish_prefix: String,
+	#[arg(help = "The format in which published changes are serialized")]
+	#[arg(env = "SURREAL_PUBLISH_FORMAT", long, requires = "publish_url")]
+	#[arg(value_enum, default_value_t = PublishFormat::Json)]
+	publish_format: PublishFormat,
+	#[arg(help = "The maximum size in bytes of the data of a single record")]
+	#[arg(env = "SURREAL_MAX_DOCUMENT_SIZE", long)]
+	#[arg(default_value_t = MAX_DOCUMENT_SIZE)]
+	max_document_size: usize,
+	#[arg(
+		help = "The size in bytes above which the data of a record is split across multiple keys"
+	)]
+	#[arg(env = "SURREAL_DOCUMENT_CHUNK_SIZE", long)]
+	#[arg(default_value_t = DOCUMENT_CHUNK_SIZE)]
+	document_chunk_size: usize,
+	#[arg(
+		help = "The number of shards of the record and byte counters of each table, so that concurrent writes rarely conflict"
+	)]
+	#[arg(env = "SURREAL_COUNTER_SHARDS", long)]
+	#[arg(default_value_t = 16)]
+	counter_shards: u8,
+	#[arg(
+		help = "Whether record counts are calculated by scanning every record, rather than taken from the record counter of each table"
+	)]
+	#[arg(env = "SURREAL_EXACT_COUNT", long)]
+	#[arg(default_value_t = false)]
+	exact_count: bool,
+	#[arg(help = "The maximum number of SELECT statement results which are cached")]
+	#[arg(env = "SURREAL_RESULT_CACHE_SIZE", long)]
+	#[arg(default_value_t = 0)]
+	result_cache_size: usize,
+	#[arg(help = "How long a cached SELECT statement result is kept")]
+	#[arg(env = "SURREAL_RESULT_CACHE_TTL", long)]
+	#[arg(value_parser = super::cli::validator::duration)]
+	#[arg(default_value = "1m")]
+	result_cache_ttl: Duration,
+	#[arg(help = "The maximum duration of any statement which has no TIMEOUT clause")]
+	#[arg(env = "SURREAL_STATEMENT_TIMEOUT", long)]
+	#[arg(value_parser = super::cli::validator::duration)]
+	statement_timeout: Option<Duration>,
+	#[arg(help = "The duration after which a statement is logged as slow")]
+	#[arg(env = "SURREAL_SLOW_QUERY_THRESHOLD", long)]
+	#[arg(value_parser = super::cli::validator::duration)]
+	slow_query_threshold: Option<Duration>,
+	#[arg(help = "The number of normalized statements for which execution statistics are kept")]
+	#[arg(env = "SURREAL_STATEMENT_STATISTICS", long)]
+	#[arg(default_value_t = 1000)]
+	statement_statistics: usize,
+	#[arg(help = "The maximum number of records which a single statement may examine")]
+	#[arg(env = "SURREAL_MAX_QUERY_ROWS", long)]
+	max_query_rows: Option<usize>,
+	#[arg(
+		help = "The maximum estimated memory in bytes which the output of a single statement may use"
+	)]
+	#[arg(env = "SURREAL_MAX_QUERY_MEMORY", long)]
+	max_query_memory: Option<usize>,
+	#[arg(
+		help = "The number of records above which a new index is built in the background, or 0 to always build indexes immediately"
+	)]
+	#[arg(env = "SURREAL_INDEX_BUILD_THRESHOLD", long)]
+	#[arg(default_value_t = 10_000)]
+	index_build_threshold: u64,
+	#[arg(
+		help = "The number of records which are indexed in each batch of a background index build"
+	)]
+	#[arg(env = "SURREAL_INDEX_BUILD_BATCH_SIZE", long)]
+	#[arg(default_value_t = 1000)]
+	index_build_batch_size: u32,
+	#[arg(help = "The maximum duration of any event, future, or stored function")]
+	#[arg(env = "SURREAL_SANDBOX_TIME_LIMIT", long)]
+	#[arg(value_parser = super::cli::validator::duration)]
+	#[arg(default_value = "5s")]
+	sandbox_time_limit: Duration,
+	#[arg(
+		help = "The maximum memory in bytes which an embedded script within an event, future, or stored function may use"
+	)]
+	#[arg(env = "SURREAL_SANDBOX_MEMORY_LIMIT", long)]
+	#[arg(default_value_t = 2_000_000)]
+	sandbox_memory_limit: usize,
+	#[arg(
+		help = "The maximum number of subqueries which an event, future, or stored function may run"
+	)]
+	#[arg(env = "SURREAL_SANDBOX_SUBQUERY_LIMIT", long)]
+	#[arg(default_value_t = 1_000)]
+	sandbox_subquery_limit: usize,
+	#[arg(
+		help = "The maximum number of outbound calls which an event, future, or stored function may make"
+	)]
+	#[arg(env = "SURREAL_SANDBOX_CALL_LIMIT", long)]
+	#[arg(default_value_t = 10)]
+	sandbox_call_limit: usize,
+	#[arg(
+		help = "The comma-separated hosts to which the http functions can send requests, in which *.example.com matches any subdomain"
+	)]
+	#[arg(env = "SURREAL_HTTP_ALLOWED_HOSTS", long = "http-allowed-host", value_delimiter = ',')]
+	http_allowed_hosts: Vec<String>,
+	#[arg(help = "The maximum duration of any request from the http functions")]
+	#[arg(env = "SURREAL_HTTP_TIMEOUT", long)]
+	#[arg(value_parser = super::cli::validator::duration)]
+	#[arg(default_value = "10s")]
+	http_timeout: Duration,
+	#[arg(
+		help = "The maximum size in bytes of the request and response bodies of the http functions"
+	)]
+	#[arg(env = "SURREAL_HTTP_MAX_BODY_SIZE", long)]
+	#[arg(default_value_t = 10 * 1024 * 1024)]
+	http_max_body_size: usize,
+	#[arg(
+		help = "The number of webhooks, which are sent by events, which are delivered concurrently"
+	)]
+	#[arg(env = "SURREAL_WEBHOOK_WORKERS", long)]
+	#[arg(default_value_t = 4)]
+	webhook_workers: usize,
+	#[arg(help = "How many times the delivery of a webhook is retried before it is dropped")]
+	#[arg(env = "SURREAL_WEBHOOK_RETRIES", long)]
+	#[arg(default_value_t = 5)]
+	webhook_retries: u32,
+	#[arg(
+		help = "How long to wait before the first retry of a webhook, which doubles on each retry"
+	)]
+	#[arg(env = "SURREAL_WEBHOOK_BACKOFF", long)]
+	#[arg(value_parser = super::cli::validator::duration)]
+	#[arg(default_value = "500ms")]
+	webhook_backoff: Duration,
+	#[arg(
+		help = "The external identity providers whose tokens are accepted, as a JSON array of issuers"
+	)]
+	#[arg(env = "SURREAL_OIDC_ISSUERS", long)]
+	oidc_issuers: Option<String>,
+	#[arg(
+		help = "The external authenticator which is consulted when signin credentials are not accepted, as an HTTP URL or an exec: command"
+	)]
+	#[arg(env = "SURREAL_AUTHENTICATOR", long)]
+	#[arg(value_parser = Authenticator::parse)]
+	authenticator: Option<Authenticator>,
+	#[arg(help = "The maximum duration of a response from the external authenticator")]
+	#[arg(env = "SURREAL_AUTHENTICATOR_TIMEOUT", long)]
+	#[arg(value_parser = super::cli::validator::duration)]
+	#[arg(default_value = "5s")]
+	authenticator_timeout: Duration,
+	#[arg(
+		help = "The networks from which clients may use each namespace, in the form ns=network,network;ns=network"
+	)]
+	#[arg(env = "SURREAL_NS_ALLOWLIST", long)]
+	ns_allowlist: Option<String>,
+	#[arg(help = "The number of clients which are connected to a TiKV cluster")]
+	#[arg(env = "SURREAL_TIKV_POOL_SIZE", long)]
+	#[arg(default_value_t = 4)]
+	tikv_pool_size: usize,
+	#[arg(help = "How often each client of a TiKV cluster is health checked")]
+	#[arg(env = "SURREAL_TIKV_HEALTH_INTERVAL", long)]
+	#[arg(value_parser = super::cli::validator::duration)]
+	#[arg(default_value = "10s")]
+	tikv_health_interval: Duration,
+	#[arg(help = "How many times a read from a TiKV cluster is retried after a transient error")]
+	#[arg(env = "SURREAL_TIKV_READ_RETRIES", long)]
+	#[arg(default_value_t = 3)]
+	tikv_read_retries: u32,
 }
 
 pub async fn init(
 	StartCommandDbsOptions {
+		shutdown_timeout,
+		transaction_timeout,
+		transaction_retries,
 		query_timeout,
+		readonly,
+		backup_log,
+		journal,
+		replica_node,
+		replica_peers,
+		replica_sync,
+		replica_path,
+		archive_path,
+		archive_interval,
+		slo_targets,
+		rate_limit_queries,
+		rate_limit_writes,
+		rate_limit_bytes,
+		max_live_queries,
+		settings,
+		live_history,
+		publish_url,
+		publish_prefix,
+		publish_format,
+		max_document_size,
+		document_chunk_size,
+		counter_shards,
+		exact_count,
+		result_cache_size,
+		result_cache_ttl,
+		statement_timeout,
+		slow_query_threshold,
+		statement_statistics,
+		max_query_rows,
+		max_query_memory,
+		index_build_threshold,
+		index_build_batch_size,
+		sandbox_time_limit,
+		sandbox_memory_limit,
+		sandbox_subquery_limit,
+		sandbox_call_limit,
+		http_allowed_hosts,
+		http_timeout,
+		http_max_body_size,
+		webhook_workers,
+		webhook_retries,
+		webhook_backoff,
+		oidc_issuers,
+		authenticator,
+		authenticator_timeout,
+		ns_allowlist,
+		tikv_pool_size,
+		tikv_health_interval,
+		tikv_read_retries,
 	}: StartCommandDbsOptions,
 ) -> Result<(), Error> {
 	// Get local copy of options
@@ -30,10 +353,165 @@ pub async fn init(
 		true => info!(target: LOG, "Database strict mode is enabled"),
 		false => info!(target: LOG, "Database strict mode is disabled"),
 	};
+	// Log read-only options
+	if readonly {
+		info!(target: LOG, "Database read-only mode is enabled, and all changes are rolled back");
+	}
+	// Parse the external identity providers
+	let oidc_issuers = match oidc_issuers {
+		Some(v) => Issuer::parse(&v)
+			.map_err(|e| Error::Config(format!("The OIDC issuers are invalid: {e}")))?,
+		None => vec![],
+	};
 	// Parse and setup the desired kv datastore
-	let dbs = Datastore::new(&opt.path).await?.query_timeout(query_timeout);
+	let dbs = Datastore::new(&opt.path).await?;
+	// Serve the queries which do not modify data from a read-only replica
+	let dbs = match &replica_path {
+		Some(path) => {
+			info!(target: LOG, "Serving read-only queries from the replica at {}", path);
+			dbs.with_replica(path).await?
+		}
+		None => dbs,
+	};
+	// Connect the clients of any TiKV cluster
+	let dbs = dbs.with_tikv(tikv_pool_size, tikv_health_interval, tikv_read_retries).await?;
+	let dbs = dbs
+		.query_timeout(query_timeout)
+		.with_transaction_timeout(transaction_timeout)
+		.with_retries(transaction_retries)
+		.with_dry_run(readonly)
+		.with_history(live_history)
+		.with_slo(slo_targets)
+		.with_quotas(QuotaLimits {
+			queries: rate_limit_queries,
+			writes: rate_limit_writes,
+			live: max_live_queries,
+			bytes: rate_limit_bytes,
+		})
+		.with_max_document_size(max_document_size)
+		.with_chunk_size(document_chunk_size)
+		.with_counter_shards(counter_shards)
+		.with_exact_count(exact_count)
+		.with_result_cache(result_cache_size, result_cache_ttl)
+		.with_statement_timeout(statement_timeout)
+		.with_slow_query_threshold(slow_query_threshold)
+		.with_statistics(statement_statistics)
+		.with_query_limits(max_query_rows, max_query_memory)
+		.with_index_build(Some(index_build_threshold), index_build_batch_size)
+		.with_sandbox_limits(SandboxLimits {
+			time: sandbox_time_limit,
+			memory: sandbox_memory_limit,
+			subqueries: sandbox_subquery_limit,
+			calls: sandbox_call_limit,
+		})
+		.with_http_limits(HttpLimits {
+			allowed_hosts: http_allowed_hosts,
+			timeout: http_timeout,
+			max_body_size: http_max_body_size,
+		})
+		.with_webhooks(webhook_workers, webhook_retries, webhook_backoff)
+		.with_oidc_issuers(oidc_issuers)
+		.with_authenticator(authenticator, authenticator_timeout)
+		.with_ns_allowlists(ns_allowlist.as_deref().map(Allowlist::parse).unwrap_or_default());
+	// Log the rate limits and quotas
+	if dbs.quotas().is_enabled() {
+		info!(target: LOG, "Rate limits and quotas are enabled for authenticated identities");
+	}
+	// Log any namespaces which are restricted to specific networks
+	for v in dbs.ns_allowlists() {
+		info!(target: surrealdb::iam::LOG, "Namespace '{}' is restricted to {} allowed networks", v.ns, v.networks.len());
+	}
+	// Log any external identity providers
+	for v in dbs.oidc_issuers() {
+		info!(target: surrealdb::iam::LOG, "Token authentication is enabled for OIDC issuer '{}'", v.issuer);
+	}
+	// Log any external authenticator
+	if let Some(v) = dbs.authenticator() {
+		info!(target: surrealdb::iam::LOG, "External authentication is enabled with '{}'", v);
+	}
+	// Allow the log level to be changed at runtime
+	dbs.settings().set_logger(crate::o11y::reload);
+	// Journal the statements which change the datastore
+	let dbs = match &journal {
+		Some(path) => {
+			info!(target: LOG, "Writing the statement journal to {}", path.display());
+			dbs.with_journal(path)?
+		}
+		None => dbs,
+	};
+	// Setup the archive tier
+	let dbs = match &archive_path {
+		Some(path) => dbs.with_archive(path).await?,
+		None => dbs,
+	};
 	// Store database instance
 	let _ = DB.set(dbs);
+	let _ = SHUTDOWN_TIMEOUT.set(shutdown_timeout);
+	// Apply the runtime settings, and reload them on SIGHUP
+	settings::init(settings).await?;
+	// Start the incremental backup log
+	if let Some(path) = backup_log {
+		backup::init(path).await?;
+	}
+	// Join the replicated cluster
+	if let Some(url) = replica_node {
+		replica::init(url, replica_peers, replica_sync).await?;
+	}
+	// Publish committed changes to a message broker
+	if let Some(url) = publish_url {
+		publish::init(url, publish_prefix, publish_format).await?;
+	}
+	// Periodically move records to the archive tier
+	if archive_path.is_some() {
+		archive::init(archive_interval);
+	}
+	// Build any indexes of large tables in the background
+	indexes::init();
+	// Cancel any transactions which have been idle for too long
+	transactions::init();
 	// All ok
 	Ok(())
 }
+
+/// Shut down the datastore gracefully, once the server has stopped accepting
+/// connections. Every call waits until the datastore has been shut down once.
+pub async fn shutdown() {
+	SHUTDOWN
+		.get_or_init(|| async {
+			// Get a database reference
+			let dbs = DB.get().unwrap();
+			// Give the running queries time to complete
+			let timeout = SHUTDOWN_TIMEOUT.get().copied().unwrap_or_default();
+			info!(target: LOG, "Waiting up to {:?} for running queries to complete", timeout);
+			if let Err(e) = dbs.shutdown(timeout).await {
+				error!(target: LOG, "Unable to shut down the datastore cleanly: {}", e);
+			}
+		})
+		.await;
+}
+
+/// Set up an in-memory datastore, and the options of the server, for the
+/// tests of the endpoints, with a root user of `root` and password `root`
+#[cfg(test)]
+pub(crate) async fn test() {
+	use crate::cli::config::Config;
+	use crate::net::client_ip::ClientIp;
+	if DB.get().is_none() {
+		let _ = DB.set(Datastore::new("memory").await.unwrap());
+	}
+	let _ = CF.set(Config {
+		strict: false,
+		bind: "127.0.0.1:8000".parse().unwrap(),
+		#[cfg(feature = "grpc")]
+		grpc: None,
+		pg: None,
+		path: String::from("memory"),
+		client_ip: ClientIp::None,
+		user: String::from("root"),
+		pass: Some(String::from("root")),
+		crt: None,
+		key: None,
+		ca: None,
+		certs: Vec::new(),
+	});
+}
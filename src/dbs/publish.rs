@@ -0,0 +1,281 @@
+//! Publishing of committed changes to a message broker.
+//!
+//! Every change which is committed to the datastore is published to a topic
+//! for its table, named `<prefix>.<ns>.<db>.<tb>`, on either a Kafka cluster
+//! or a NATS server, so that downstream data pipelines can consume changes
+//! without a bespoke connector. Each message is keyed by the record id, and
+//! contains the sequence number, commit time, action, and the content of the
+//! record before and after the change. Changes are published in commit
+//! order, and the changes to each table are sent to the first partition of
+//! its Kafka topic, so that consumers receive them in the same order.
+//! Publishing is retried until the broker accepts the changes, and if the
+//! publisher falls behind, it resumes from the last change it published,
+//! so changes are published at least once while they are retained.
+use crate::dbs::DB;
+use crate::err::Error;
+use crate::net::output;
+use clap::ValueEnum;
+use rskafka::client::partition::{Compression, PartitionClient, UnknownTopicHandling};
+use rskafka::record::Record;
+use serde_json::Value as Json;
+use std::collections::{BTreeMap, HashMap};
+use std::time::Duration;
+use surrealdb::changes::Change;
+use surrealdb::sql::Value;
+use tokio::time::sleep;
+
+const LOG: &str = "surrealdb::dbs::publish";
+
+/// The maximum number of changes which are published in a single batch
+const BATCH: usize = 1000;
+
+/// The initial delay before publishing to the broker is retried
+const RETRY_MIN: Duration = Duration::from_millis(100);
+
+/// The maximum delay before publishing to the broker is retried
+const RETRY_MAX: Duration = Duration::from_secs(30);
+
+/// The serialization format of published changes
+#[derive(ValueEnum, Clone, Copy, Debug, Default, Eq, PartialEq)]
+pub enum PublishFormat {
+	/// JSON
+	#[default]
+	Json,
+	/// CBOR
+	Cbor,
+	/// MessagePack
+	Pack,
+}
+
+/// A message broker to which changes are published
+enum Broker {
+	Kafka(rskafka::client::Client, HashMap<String, PartitionClient>),
+	Nats(async_nats::Client),
+}
+
+impl Broker {
+	/// Connect to the Kafka brokers or NATS server at a url
+	async fn connect(url: &str) -> Result<Self, Error> {
+		match url.split_once("://") {
+			Some(("kafka", v)) => {
+				let brokers = v.split(',').map(str::to_owned).collect();
+				let client = rskafka::client::ClientBuilder::new(brokers)
+					.build()
+					.await
+					.map_err(|e| Error::Publish(e.to_string()))?;
+				Ok(Broker::Kafka(client, HashMap::new()))
+			}
+			Some(("nats", _)) => {
+				let client =
+					async_nats::connect(url).await.map_err(|e| Error::Publish(e.to_string()))?;
+				Ok(Broker::Nats(client))
+			}
+			_ => Err(Error::Publish(format!(
+				"Expected a kafka:// or nats:// url, but found '{url}'"
+			))),
+		}
+	}
+
+	/// Publish keyed messages to a topic, in order
+	async fn publish(&mut self, topic: &str, msgs: Vec<(String, Vec<u8>)>) -> Result<(), Error> {
+		match self {
+			Broker::Kafka(client, partitions) => {
+				if !partitions.contains_key(topic) {
+					let partition = partition(client, topic).await?;
+					partitions.insert(topic.to_owned(), partition);
+				}
+				let records = msgs
+					.into_iter()
+					.map(|(key, value)| Record {
+						key: Some(key.into_bytes()),
+						value: Some(value),
+						headers: BTreeMap::new(),
+						timestamp: chrono::Utc::now(),
+					})
+					.collect();
+				let res = partitions[topic].produce(records, Compression::NoCompression).await;
+				// Reconnect to the partition when publishing is retried
+				if let Err(e) = res {
+					partitions.remove(topic);
+					return Err(Error::Publish(e.to_string()));
+				}
+			}
+			Broker::Nats(client) => {
+				for (_, value) in msgs {
+					client
+						.publish(topic.to_owned(), value.into())
+						.await
+						.map_err(|e| Error::Publish(e.to_string()))?;
+				}
+				client.flush().await.map_err(|e| Error::Publish(e.to_string()))?;
+			}
+		}
+		Ok(())
+	}
+}
+
+/// Get the first partition of a Kafka topic, creating the topic if it does not exist
+async fn partition(
+	client: &rskafka::client::Client,
+	topic: &str,
+) -> Result<PartitionClient, Error> {
+	let res = client.partition_client(topic, 0, UnknownTopicHandling::Error).await;
+	if res.is_err() {
+		if let Ok(controller) = client.controller_client() {
+			let _ = controller.create_topic(topic, 1, 1, 5_000).await;
+		}
+	}
+	match res {
+		Ok(v) => Ok(v),
+		Err(_) => client.partition_client(topic, 0, UnknownTopicHandling::Retry).await,
+	}
+	.map_err(|e| Error::Publish(e.to_string()))
+}
+
+/// Start publishing committed changes to a message broker
+pub async fn init(url: String, prefix: String, format: PublishFormat) -> Result<(), Error> {
+	// Get a database reference
+	let dbs = DB.get().unwrap();
+	// Connect to the message broker
+	let mut broker = Broker::connect(&url).await?;
+	info!(target: LOG, "Publishing committed changes to {}", url);
+	// Subscribe to the change log
+	let mut chn = dbs.changes().await;
+	// Publish the changes in the background
+	tokio::spawn(async move {
+		// The sequence number of the last change which was published
+		let mut last: Option<u64> = None;
+		loop {
+			while let Ok(change) = chn.recv().await {
+				// Publish any pending changes in the same batch
+				let mut changes = vec![change];
+				while changes.len() < BATCH {
+					match chn.try_recv() {
+						Ok(v) => changes.push(v),
+						Err(_) => break,
+					}
+				}
+				send(&mut broker, &prefix, format, changes, &mut last).await;
+			}
+			// The publisher fell too far behind the change log,
+			// so resume from the last change which was published
+			let Some(seq) = last else {
+				chn = dbs.changes().await;
+				continue;
+			};
+			let (missed, rcv) = dbs.changes_since(seq).await;
+			chn = rcv;
+			match missed {
+				Some(missed) => {
+					for changes in missed.chunks(BATCH) {
+						send(&mut broker, &prefix, format, changes.to_vec(), &mut last).await;
+					}
+				}
+				None => {
+					warn!(target: LOG, "The change publisher fell behind, and the changes after {} are no longer retained, so some changes were not published", seq);
+				}
+			}
+		}
+	});
+	Ok(())
+}
+
+/// Publish a batch of changes, keeping the sequence number of the last
+/// change which was published. Changes are retried with a backoff until
+/// the broker accepts them, so that no changes are skipped while the
+/// broker is unavailable, although a change may then be published twice.
+async fn send(
+	broker: &mut Broker,
+	prefix: &str,
+	format: PublishFormat,
+	changes: Vec<Change>,
+	last: &mut Option<u64>,
+) {
+	// Skip any changes which were already published
+	let changes: Vec<Change> =
+		changes.into_iter().filter(|v| last.map_or(true, |seq| v.seq > seq)).collect();
+	let seq = match changes.last() {
+		Some(v) => v.seq,
+		None => return,
+	};
+	// Group the changes by topic, keeping their order
+	let mut topics: Vec<(String, Vec<(String, Vec<u8>)>)> = vec![];
+	for change in changes {
+		let topic = topic(prefix, &change);
+		let msg = match encode(format, &change) {
+			Ok(v) => (change.id.to_string(), v),
+			Err(e) => {
+				error!(target: LOG, "Unable to encode a change to {}: {}", topic, e);
+				continue;
+			}
+		};
+		match topics.iter_mut().find(|(t, _)| *t == topic) {
+			Some((_, msgs)) => msgs.push(msg),
+			None => topics.push((topic, vec![msg])),
+		}
+	}
+	for (topic, msgs) in topics {
+		let mut delay = RETRY_MIN;
+		while let Err(e) = broker.publish(&topic, msgs.clone()).await {
+			error!(target: LOG, "Unable to publish changes to {}, retrying in {:?}: {}", topic, delay, e);
+			sleep(delay).await;
+			delay = (delay * 2).min(RETRY_MAX);
+		}
+	}
+	*last = Some(seq);
+}
+
+/// Get the topic to which a change is published
+fn topic(prefix: &str, change: &Change) -> String {
+	format!("{prefix}.{}.{}.{}", change.ns, change.db, change.tb)
+}
+
+/// Encode a change as a message
+fn encode(format: PublishFormat, change: &Change) -> Result<Vec<u8>, Error> {
+	let val = Value::from(map! {
+		String::from("seq") => Value::from(change.seq),
+		String::from("at") => Value::from(change.at.clone()),
+		String::from("action") => Value::from(change.action().to_string()),
+		String::from("ns") => Value::from(change.ns.as_str()),
+		String::from("db") => Value::from(change.db.as_str()),
+		String::from("tb") => Value::from(change.tb.as_str()),
+		String::from("id") => Value::from(change.id.clone()),
+		String::from("before") => change.before.clone(),
+		String::from("after") => change.after.clone(),
+	});
+	Ok(match format {
+		PublishFormat::Json => serde_json::to_vec(&Json::from(val))?,
+		PublishFormat::Cbor => serde_cbor::to_vec(&output::binary(val))?,
+		PublishFormat::Pack => serde_pack::to_vec(&output::binary(val))?,
+	})
+}
+
+#[cfg(test)]
+mod tests {
+	use super::*;
+	use surrealdb::sql::{Datetime, Thing};
+
+	#[test]
+	fn change_message() {
+		let change = Change {
+			seq: 7,
+			at: Datetime::default(),
+			ns: String::from("test"),
+			db: String::from("test"),
+			tb: String::from("person"),
+			id: Thing::from(("person", "tobie")),
+			before: Value::None,
+			after: Value::from(map! {
+				String::from("name") => Value::from("Tobie"),
+			}),
+		};
+		assert_eq!(topic("surrealdb", &change), "surrealdb.test.test.person");
+		let msg = encode(PublishFormat::Json, &change).unwrap();
+		let msg: Json = serde_json::from_slice(&msg).unwrap();
+		assert_eq!(msg["seq"], 7);
+		assert_eq!(msg["action"], "CREATE");
+		assert_eq!(msg["id"], "person:tobie");
+		assert_eq!(msg["after"]["name"], "Tobie");
+		assert!(encode(PublishFormat::Cbor, &change).is_ok());
+	}
+}
@@ -0,0 +1,435 @@
+//! Replication of the datastore between the nodes of a cluster.
+//!
+//! One node in the cluster is elected as the leader, and is the only node
+//! which accepts queries that modify data. Every other node is a read-only
+//! follower, which streams the raw key-value writes committed on the leader
+//! and applies them to its own datastore, in commit order.
+//!
+//! Leaders are elected using terms and majority votes, in the same way as
+//! the Raft consensus algorithm. A follower which receives no heartbeat from
+//! the leader within a randomised election timeout stands as a candidate for
+//! a new term, and becomes the leader once a majority of the cluster votes
+//! for it. A node only votes for a candidate which has applied at least as
+//! many writes as itself, and only votes once in each term.
+//!
+//! Replication is asynchronous by default. When synchronous replication is
+//! enabled, each transaction on the leader waits until its writes have been
+//! applied by a majority of the cluster before returning a response.
+use crate::cli::CF;
+use crate::cnf::SERVER_AGENT;
+use crate::dbs::DB;
+use crate::err::Error;
+use futures::future::join_all;
+use once_cell::sync::OnceCell;
+use rand::Rng;
+use reqwest::header::{CONTENT_TYPE, USER_AGENT};
+use reqwest::{Client, Method, RequestBuilder};
+use serde::de::DeserializeOwned;
+use serde::{Deserialize, Serialize};
+use std::sync::Mutex;
+use std::time::{Duration, Instant};
+use surrealdb::kvs::Batch;
+use tokio::time::{sleep, timeout};
+
+const LOG: &str = "surrealdb::dbs::replica";
+
+/// The interval at which the leader sends heartbeats to the cluster
+const HEARTBEAT: Duration = Duration::from_millis(500);
+
+/// The minimum time to wait for a heartbeat before standing for election
+const ELECTION: Duration = Duration::from_millis(1500);
+
+/// The maximum time to wait for a vote or heartbeat response
+const TIMEOUT: Duration = Duration::from_millis(1000);
+
+/// The interval at which a follower checks for a new leader
+const POLL: Duration = Duration::from_millis(100);
+
+/// The replication state of this node, if replication is enabled
+pub static NODE: OnceCell<Node> = OnceCell::new();
+
+#[derive(Clone, Copy, Debug, Eq, PartialEq)]
+pub enum Role {
+	Follower,
+	Candidate,
+	Leader,
+}
+
+struct State {
+	/// The current election term
+	term: u64,
+	/// The current role of this node
+	role: Role,
+	/// The node which was voted for in the current term
+	voted: Option<String>,
+	/// The leader for the current term
+	leader: Option<String>,
+	/// The last time the leader or a majority of the cluster was heard from
+	contact: Instant,
+	/// The randomised election timeout for this term
+	timeout: Duration,
+	/// The number of batches which this node, or the furthest behind
+	/// follower if this node is the leader, has yet to apply
+	lag: u64,
+}
+
+/// A node in a replicated cluster
+pub struct Node {
+	/// The url at which this node can be reached
+	url: String,
+	/// The urls of the other nodes in the cluster
+	peers: Vec<String>,
+	/// Whether commits wait to be replicated
+	sync: bool,
+	client: Client,
+	state: Mutex<State>,
+}
+
+#[derive(Serialize, Deserialize)]
+pub struct VoteRequest {
+	pub term: u64,
+	pub node: String,
+	pub seq: u64,
+}
+
+#[derive(Serialize, Deserialize)]
+pub struct VoteResponse {
+	pub term: u64,
+	pub granted: bool,
+}
+
+#[derive(Serialize, Deserialize)]
+pub struct HeartbeatRequest {
+	pub term: u64,
+	pub node: String,
+	#[serde(default)]
+	pub seq: u64,
+}
+
+#[derive(Serialize, Deserialize)]
+pub struct HeartbeatResponse {
+	pub term: u64,
+	pub seq: u64,
+}
+
+/// Join a replicated cluster, and start electing leaders and replicating writes
+pub async fn init(url: String, peers: Vec<String>, sync: bool) -> Result<(), Error> {
+	// Get local copy of options
+	let opt = CF.get().unwrap();
+	// Nodes authenticate with each other as the root user
+	if opt.pass.is_none() {
+		return Err(Error::Replica(String::from(
+			"A root password must be specified to enable replication",
+		)));
+	}
+	// Every node starts as a read-only follower
+	DB.get().unwrap().set_read_only(true);
+	info!(target: LOG, "Joining cluster as {} with {} other nodes", url, peers.len());
+	let node = Node {
+		url: url.trim_end_matches('/').to_owned(),
+		peers: peers.iter().map(|v| v.trim_end_matches('/').to_owned()).collect(),
+		sync,
+		client: Client::new(),
+		state: Mutex::new(State {
+			term: 0,
+			role: Role::Follower,
+			voted: None,
+			leader: None,
+			contact: Instant::now(),
+			timeout: election(),
+			lag: 0,
+		}),
+	};
+	let _ = NODE.set(node);
+	// Run the election and replication loops
+	let node = NODE.get().unwrap();
+	tokio::spawn(node.elect());
+	tokio::spawn(node.follow());
+	// All ok
+	Ok(())
+}
+
+/// Get a randomised election timeout
+fn election() -> Duration {
+	ELECTION + rand::thread_rng().gen_range(Duration::ZERO..ELECTION)
+}
+
+impl Node {
+	/// Get the url of the current leader, if one has been elected
+	pub fn leader(&self) -> Option<String> {
+		self.state.lock().unwrap().leader.clone()
+	}
+
+	/// Get the current role of this node
+	pub fn role(&self) -> Role {
+		self.state.lock().unwrap().role
+	}
+
+	/// Get the number of batches which this node has yet to apply, or which
+	/// the furthest behind follower has yet to apply if this node is the leader
+	pub fn lag(&self) -> u64 {
+		self.state.lock().unwrap().lag
+	}
+
+	/// Respond to a vote request from a candidate
+	pub fn vote(&self, req: VoteRequest) -> VoteResponse {
+		let dbs = DB.get().unwrap();
+		let mut st = self.state.lock().unwrap();
+		// A newer term always replaces the current term
+		if req.term > st.term {
+			self.step_down(&mut st, req.term);
+		}
+		// Only vote once per term, for an up to date candidate
+		let granted = req.term == st.term
+			&& st.voted.as_ref().map_or(true, |v| v == &req.node)
+			&& req.seq >= dbs.sequence();
+		if granted {
+			st.voted = Some(req.node);
+			st.contact = Instant::now();
+		}
+		VoteResponse {
+			term: st.term,
+			granted,
+		}
+	}
+
+	/// Respond to a heartbeat from the leader
+	pub fn heartbeat(&self, req: HeartbeatRequest) -> HeartbeatResponse {
+		let dbs = DB.get().unwrap();
+		let mut st = self.state.lock().unwrap();
+		// Ignore heartbeats from a previous term
+		if req.term >= st.term {
+			if req.term > st.term || st.role != Role::Follower {
+				self.step_down(&mut st, req.term);
+			}
+			if st.leader.as_ref() != Some(&req.node) {
+				info!(target: LOG, "Following leader {} for term {}", req.node, req.term);
+				st.leader = Some(req.node);
+			}
+			st.contact = Instant::now();
+			st.lag = req.seq.saturating_sub(dbs.sequence());
+		}
+		HeartbeatResponse {
+			term: st.term,
+			seq: dbs.sequence(),
+		}
+	}
+
+	/// Become a read-only follower
+	fn step_down(&self, st: &mut State, term: u64) {
+		let dbs = DB.get().unwrap();
+		if term > st.term {
+			st.term = term;
+			st.voted = None;
+		}
+		if st.role == Role::Leader {
+			warn!(target: LOG, "Stepping down as leader for term {}", st.term);
+			dbs.set_read_only(true);
+			dbs.set_synchronous(false);
+			tokio::spawn(dbs.unacknowledge());
+		}
+		st.role = Role::Follower;
+		st.leader = None;
+		st.contact = Instant::now();
+		st.timeout = election();
+	}
+
+	/// Run elections, and send heartbeats while this node is the leader
+	async fn elect(&'static self) {
+		loop {
+			let (role, term, expired) = {
+				let st = self.state.lock().unwrap();
+				(st.role, st.term, st.contact.elapsed() > st.timeout)
+			};
+			match role {
+				Role::Leader => {
+					self.lead(term).await;
+					sleep(HEARTBEAT).await;
+				}
+				_ if expired => self.stand(term + 1).await,
+				_ => sleep(POLL).await,
+			}
+		}
+	}
+
+	/// Stand as a candidate for leader in a new term
+	async fn stand(&self, term: u64) {
+		let dbs = DB.get().unwrap();
+		{
+			let mut st = self.state.lock().unwrap();
+			st.term = term;
+			st.role = Role::Candidate;
+			st.voted = Some(self.url.clone());
+			st.leader = None;
+			st.contact = Instant::now();
+			st.timeout = election();
+		}
+		debug!(target: LOG, "Standing for election as leader for term {}", term);
+		// Request votes from the rest of the cluster
+		let req = VoteRequest {
+			term,
+			node: self.url.clone(),
+			seq: dbs.sequence(),
+		};
+		let res =
+			join_all(self.peers.iter().map(|p| self.post::<_, VoteResponse>(p, "vote", &req)));
+		let mut votes = 1;
+		for res in res.await.into_iter().flatten() {
+			if res.term > term {
+				self.step_down(&mut self.state.lock().unwrap(), res.term);
+				return;
+			}
+			if res.granted {
+				votes += 1;
+			}
+		}
+		// Check if a majority voted for this node
+		let mut st = self.state.lock().unwrap();
+		if st.term == term && st.role == Role::Candidate && votes >= self.majority() {
+			info!(target: LOG, "Elected as leader for term {}", term);
+			st.role = Role::Leader;
+			st.leader = Some(self.url.clone());
+			st.contact = Instant::now();
+			dbs.set_synchronous(self.sync);
+			dbs.set_read_only(false);
+		}
+	}
+
+	/// Send heartbeats to the cluster, and acknowledge replicated writes
+	async fn lead(&self, term: u64) {
+		let dbs = DB.get().unwrap();
+		let req = HeartbeatRequest {
+			term,
+			node: self.url.clone(),
+			seq: dbs.sequence(),
+		};
+		let res = join_all(
+			self.peers.iter().map(|p| self.post::<_, HeartbeatResponse>(p, "heartbeat", &req)),
+		);
+		let mut seqs = vec![dbs.sequence()];
+		for res in res.await.into_iter().flatten() {
+			if res.term > term {
+				self.step_down(&mut self.state.lock().unwrap(), res.term);
+				return;
+			}
+			seqs.push(res.seq);
+		}
+		let majority = self.majority();
+		{
+			let mut st = self.state.lock().unwrap();
+			if st.term != term || st.role != Role::Leader {
+				return;
+			}
+			if seqs.len() < majority {
+				// Stop accepting writes once a majority is unreachable
+				if st.contact.elapsed() > st.timeout {
+					warn!(target: LOG, "Unable to reach a majority of the cluster");
+					self.step_down(&mut st, term);
+				}
+				return;
+			}
+			st.contact = Instant::now();
+			st.lag = seqs.iter().map(|v| req.seq.saturating_sub(*v)).max().unwrap_or(0);
+		}
+		// Acknowledge the writes applied by a majority of the cluster
+		seqs.sort_unstable_by(|a, b| b.cmp(a));
+		dbs.acknowledge(seqs[majority - 1]).await;
+	}
+
+	/// Replicate the writes from the current leader while this node is a follower
+	async fn follow(&'static self) {
+		loop {
+			let leader = match self.leader() {
+				Some(v) if v != self.url => v,
+				_ => {
+					sleep(POLL).await;
+					continue;
+				}
+			};
+			if let Err(e) = self.replicate(&leader).await {
+				warn!(target: LOG, "Replication from leader {} failed: {}", leader, e);
+				sleep(HEARTBEAT).await;
+			}
+		}
+	}
+
+	/// Synchronise with a leader, and apply its writes until it is replaced
+	async fn replicate(&self, leader: &str) -> Result<(), Error> {
+		let dbs = DB.get().unwrap();
+		// Subscribe to the write stream before taking a snapshot, so
+		// that no writes are missed. Any writes which are already
+		// included in the snapshot are applied again, in order, which
+		// leaves the datastore in the same state as the leader.
+		let mut res = self
+			.request(Method::GET, format!("{leader}/replica/stream"))
+			.send()
+			.await?
+			.error_for_status()?;
+		let data = self
+			.request(Method::GET, format!("{leader}/sync"))
+			.send()
+			.await?
+			.error_for_status()?
+			.bytes()
+			.await?;
+		dbs.restore(None, None, &data).await?;
+		info!(target: LOG, "Synchronised with leader {}", leader);
+		// Apply the writes in commit order
+		let mut buf = Vec::new();
+		loop {
+			// Stop following a leader which has been replaced
+			if self.leader().as_deref() != Some(leader) {
+				return Ok(());
+			}
+			let chunk = match timeout(HEARTBEAT, res.chunk()).await {
+				Ok(v) => match v? {
+					Some(v) => v,
+					None => {
+						return Err(Error::Replica(String::from("The write stream was closed")))
+					}
+				},
+				Err(_) => continue,
+			};
+			buf.extend_from_slice(&chunk);
+			let mut pos = 0;
+			while let Some((batch, len)) = Batch::decode(&buf[pos..])? {
+				dbs.apply(&batch).await?;
+				pos += len;
+			}
+			buf.drain(..pos);
+		}
+	}
+
+	/// The number of nodes which make up a majority of the cluster
+	fn majority(&self) -> usize {
+		(self.peers.len() + 1) / 2 + 1
+	}
+
+	/// Create an authenticated request to another node
+	fn request(&self, method: Method, url: String) -> RequestBuilder {
+		let opt = CF.get().unwrap();
+		self.client
+			.request(method, url)
+			.basic_auth(&opt.user, opt.pass.as_ref())
+			.header(USER_AGENT, SERVER_AGENT)
+	}
+
+	/// Send a replication message to another node
+	async fn post<T, R>(&self, peer: &str, path: &str, body: &T) -> Result<R, Error>
+	where
+		T: Serialize,
+		R: DeserializeOwned,
+	{
+		let res = self
+			.request(Method::POST, format!("{peer}/replica/{path}"))
+			.header(CONTENT_TYPE, "application/json")
+			.timeout(TIMEOUT)
+			.body(serde_json::to_vec(body)?)
+			.send()
+			.await?
+			.error_for_status()?
+			.bytes()
+			.await?;
+		Ok(serde_json::from_slice(&res)?)
+	}
+}
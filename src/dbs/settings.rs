@@ -0,0 +1,58 @@
+use crate::dbs::DB;
+use crate::err::Error;
+use std::path::{Path, PathBuf};
+use surrealdb::dbs::Session;
+
+const LOG: &str = "surrealdb::dbs::settings";
+
+/// Apply the runtime settings in any settings file, and apply them again
+/// whenever the server receives a SIGHUP signal, so that the settings can
+/// be changed without restarting the server.
+///
+/// Each line of the file sets a single setting in the form `name = value`,
+/// as with a `SET GLOBAL` statement, and lines starting with `#` are ignored.
+pub async fn init(path: Option<PathBuf>) -> Result<(), Error> {
+	// Apply the settings when starting
+	if let Some(path) = &path {
+		info!(target: LOG, "Applying runtime settings from {}", path.display());
+		apply(path).await?;
+	}
+	// Apply the settings again on every SIGHUP signal
+	#[cfg(unix)]
+	{
+		use tokio::signal::unix::{signal, SignalKind};
+		let mut sighup = signal(SignalKind::hangup())?;
+		tokio::spawn(async move {
+			while sighup.recv().await.is_some() {
+				match &path {
+					Some(path) => {
+						info!(target: LOG, "Received SIGHUP, reloading runtime settings");
+						if let Err(e) = apply(path).await {
+							error!(target: LOG, "Unable to reload runtime settings: {}", e);
+						}
+					}
+					None => info!(target: LOG, "Received SIGHUP, but there is no settings file"),
+				}
+			}
+		});
+	}
+	Ok(())
+}
+
+/// Apply each of the settings in the settings file
+async fn apply(path: &Path) -> Result<(), Error> {
+	// Get a database reference
+	let dbs = DB.get().unwrap();
+	// Read the settings file
+	let txt = tokio::fs::read_to_string(path).await?;
+	// Apply each of the settings as a root user
+	let ses = Session::for_kv();
+	for line in txt.lines().map(str::trim).filter(|v| !v.is_empty() && !v.starts_with('#')) {
+		let sql = format!("SET GLOBAL {line}");
+		let res = dbs.execute(&sql, &ses, None, false).await.and_then(|mut v| v.remove(0).result);
+		if let Err(e) = res {
+			warn!(target: LOG, "Unable to apply setting '{}': {}", line, e);
+		}
+	}
+	Ok(())
+}
@@ -0,0 +1,25 @@
+use crate::dbs::DB;
+use std::time::Duration;
+
+const LOG: &str = "surrealdb::dbs::transactions";
+
+/// How often to check for idle transactions
+const INTERVAL: Duration = Duration::from_secs(1);
+
+/// Periodically cancel the transactions which are kept open between
+/// requests, once they have been idle for longer than the timeout
+pub fn init() {
+	// Get a database reference
+	let dbs = DB.get().unwrap();
+	// Check for idle transactions in the background
+	tokio::spawn(async move {
+		let mut interval = tokio::time::interval(INTERVAL);
+		loop {
+			interval.tick().await;
+			match dbs.expire_transactions().await {
+				0 => (),
+				n => info!(target: LOG, "Cancelled {} transactions which were idle", n),
+			}
+		}
+	});
+}
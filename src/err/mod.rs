@@ -6,6 +6,7 @@ use serde_json::error::Error as JsonError;
 use serde_pack::encode::Error as PackError;
 use std::io::Error as IoError;
 use std::string::FromUtf8Error as Utf8Error;
+use surrealdb::err::{ErrorKind, Retry};
 use surrealdb::Error as SurrealError;
 use thiserror::Error;
 
@@ -26,12 +27,27 @@ pub enum Error {
 	#[error("The specified media type is unsupported")]
 	InvalidType,
 
-	#[error("There was a problem connecting with the storage engine")]
-	InvalidStorage,
-
 	#[error("The operation is unsupported")]
 	OperationUnsupported,
 
+	#[error("There was a problem with the backup: {0}")]
+	Backup(String),
+
+	#[error("There was a problem with replication: {0}")]
+	Replica(String),
+
+	#[error("There was a problem publishing changes: {0}")]
+	Publish(String),
+
+	#[error("There was a problem with the GraphQL request: {0}")]
+	Graphql(String),
+
+	#[error("There was a problem with the TLS configuration: {0}")]
+	Tls(String),
+
+	#[error("There was a problem with the configuration: {0}")]
+	Config(String),
+
 	#[error("There was a problem with the database: {0}")]
 	Db(#[from] SurrealError),
 
@@ -77,6 +93,35 @@ impl From<surrealdb::error::Db> for Error {
 	}
 }
 
+impl Error {
+	/// Get a hint as to whether, and after how long, the request can be retried
+	pub fn retry(&self) -> Option<Retry> {
+		match self {
+			Error::Db(SurrealError::Db(e)) => e.retry(),
+			_ => None,
+		}
+	}
+	/// Get the category of the error, so that clients can handle the error
+	/// without matching the error message
+	pub fn kind(&self) -> ErrorKind {
+		match self {
+			Error::Db(SurrealError::Db(e)) => e.kind(),
+			Error::InvalidAuth => ErrorKind::PermissionDenied,
+			Error::Request
+			| Error::NoNsHeader
+			| Error::NoDbHeader
+			| Error::InvalidType
+			| Error::OperationUnsupported
+			| Error::Graphql(_)
+			| Error::Json(_)
+			| Error::Cbor(_)
+			| Error::Pack(_)
+			| Error::Db(_) => ErrorKind::Invalid,
+			_ => ErrorKind::Internal,
+		}
+	}
+}
+
 impl Serialize for Error {
 	fn serialize<S>(&self, serializer: S) -> Result<S::Ok, S::Error>
 	where
@@ -0,0 +1,321 @@
+//! Translates GraphQL requests into SurrealQL statements. The schema is
+//! generated from the tables and fields defined on the selected database,
+//! with each table exposed as a query field, a set of create, update, and
+//! delete mutations, and a subscription backed by a LIVE SELECT query.
+//! Subscriptions are served over a WebSocket with the GraphQL over WebSocket
+//! protocol, with an event for each notification of their live queries.
+mod parser;
+pub mod schema;
+
+use crate::cli::CF;
+use crate::dbs::DB;
+use crate::err::Error;
+use parser::{Document, Operation, Selection};
+use std::collections::BTreeMap;
+use surrealdb::dbs::Session;
+use surrealdb::sql::statements::{
+	CreateStatement, DeleteStatement, LiveStatement, SelectStatement, UpdateStatement,
+};
+use surrealdb::sql::{
+	Cond, Data, Expression, Fetch, Fetchs, Fields, Idiom, Limit, Operator, Order, Orders, Output,
+	Part, Query, Start, Statement, Statements, Table, Thing, Uuid, Value, Values,
+};
+
+/// Execute a GraphQL request, returning a GraphQL response object
+pub async fn execute(
+	query: &str,
+	vars: BTreeMap<String, Value>,
+	session: &Session,
+) -> Result<Value, Error> {
+	// Parse the GraphQL document
+	let doc = parser::parse(query, &vars)?;
+	// Convert the selections into statements
+	let ast = translate(&doc)?;
+	// Get a database reference
+	let kvs = DB.get().unwrap();
+	// Get local copy of options
+	let opt = CF.get().unwrap();
+	// Execute the statements on the database
+	let res = kvs.process(ast, session, None, opt.strict).await?;
+	// Build the response object
+	let mut data = BTreeMap::new();
+	let mut errors = Vec::new();
+	for (sel, res) in doc.fields.iter().zip(res.into_iter()) {
+		match res.result {
+			Ok(v) => {
+				// Single record lookups return a single object
+				let v = match doc.kind {
+					Operation::Query if !sel.args.contains_key("id") => v,
+					Operation::Subscription => v,
+					_ => v.first(),
+				};
+				data.insert(sel.key().to_owned(), project(v, &sel.fields));
+			}
+			Err(e) => {
+				data.insert(sel.key().to_owned(), Value::Null);
+				errors.push(Value::from(map! {
+					String::from("message") => Value::from(e.to_string()),
+					String::from("path") => Value::from(vec![Value::from(sel.key())]),
+				}));
+			}
+		}
+	}
+	// Return the response object
+	let mut out = map! {
+		String::from("data") => Value::from(data),
+	};
+	if !errors.is_empty() {
+		out.insert(String::from("errors"), errors.into());
+	}
+	Ok(out.into())
+}
+
+/// The live queries which were started by a GraphQL subscription
+pub struct Subscription {
+	// The root field selection which each live query delivers
+	lives: Vec<(Uuid, Selection)>,
+}
+
+impl Subscription {
+	/// Convert a live query notification into a GraphQL response object,
+	/// if the live query was started by this subscription
+	pub fn response(&self, msg: &Value) -> Option<Value> {
+		let (id, result) = match msg {
+			Value::Object(v) => match v.get("id") {
+				Some(Value::Uuid(id)) => (id, v.get("result").cloned().unwrap_or_default()),
+				_ => return None,
+			},
+			_ => return None,
+		};
+		let (_, sel) = self.lives.iter().find(|(lv, _)| lv == id)?;
+		let data = map! {
+			sel.key().to_owned() => project(result, &sel.fields),
+		};
+		Some(Value::from(map! {
+			String::from("data") => Value::from(data),
+		}))
+	}
+
+	/// Kill the live queries of the subscription
+	pub async fn kill(self, session: &Session) -> Result<(), Error> {
+		// Get a database reference
+		let kvs = DB.get().unwrap();
+		// Get local copy of options
+		let opt = CF.get().unwrap();
+		// Kill each of the live queries
+		for (id, _) in self.lives {
+			let var = Some(map! {
+				String::from("id") => Value::from(id),
+			});
+			kvs.execute("KILL $id", session, var, opt.strict).await?.remove(0).result?;
+		}
+		Ok(())
+	}
+}
+
+/// Check whether a GraphQL request is a subscription
+pub fn is_subscription(query: &str, vars: &BTreeMap<String, Value>) -> Result<bool, Error> {
+	Ok(parser::parse(query, vars)?.kind == Operation::Subscription)
+}
+
+/// Start a GraphQL subscription, returning the live queries which deliver
+/// its events. The notifications of the live queries are sent to the
+/// connection of the session, so the session must be a registered one.
+pub async fn subscribe(
+	query: &str,
+	vars: BTreeMap<String, Value>,
+	session: &Session,
+) -> Result<Subscription, Error> {
+	// Parse the GraphQL document
+	let doc = parser::parse(query, &vars)?;
+	// Check that this is a subscription
+	if doc.kind != Operation::Subscription {
+		return Err(Error::Graphql(String::from("Expected a subscription operation")));
+	}
+	// Convert the selections into statements
+	let ast = translate(&doc)?;
+	// Get a database reference
+	let kvs = DB.get().unwrap();
+	// Get local copy of options
+	let opt = CF.get().unwrap();
+	// Start the live queries on the database
+	let res = kvs.process(ast, session, None, opt.strict).await?;
+	// Keep the selection of each live query
+	let mut out = Subscription {
+		lives: Vec::new(),
+	};
+	let mut err = None;
+	for (sel, res) in doc.fields.into_iter().zip(res.into_iter()) {
+		match res.result {
+			Ok(Value::Uuid(id)) => out.lives.push((id, sel)),
+			Ok(_) => (),
+			Err(e) => err = err.or(Some(e)),
+		}
+	}
+	// Kill the live queries which were started if any failed
+	if let Some(e) = err {
+		out.kill(session).await?;
+		return Err(e.into());
+	}
+	Ok(out)
+}
+
+/// Convert each root field selection into a statement
+fn translate(doc: &Document) -> Result<Query, Error> {
+	let mut out = Vec::new();
+	for sel in doc.fields.iter() {
+		let stm = match doc.kind {
+			Operation::Query => Statement::Select(SelectStatement {
+				expr: Fields::all(),
+				what: Values(vec![what(&sel.name, sel.args.get("id"))]),
+				cond: cond(sel.args.get("filter")),
+				order: sel.args.get("order").map(order),
+				limit: sel.args.get("limit").cloned().map(Limit),
+				start: sel.args.get("start").cloned().map(Start),
+				fetch: fetch(&sel.fields),
+				..Default::default()
+			}),
+			Operation::Mutation => match sel.name.split_once('_') {
+				Some(("create", tb)) => Statement::Create(CreateStatement {
+					what: Values(vec![what(tb, sel.args.get("id"))]),
+					data: sel.args.get("data").cloned().map(Data::ContentExpression),
+					output: Some(Output::After),
+					..Default::default()
+				}),
+				Some(("update", tb)) => Statement::Update(UpdateStatement {
+					what: Values(vec![what(tb, required(sel, "id")?)]),
+					data: sel.args.get("data").cloned().map(Data::MergeExpression),
+					output: Some(Output::After),
+					..Default::default()
+				}),
+				Some(("delete", tb)) => Statement::Delete(DeleteStatement {
+					what: Values(vec![what(tb, required(sel, "id")?)]),
+					output: Some(Output::Before),
+					..Default::default()
+				}),
+				_ => return Err(Error::Graphql(format!("Unknown mutation '{}'", sel.name))),
+			},
+			Operation::Subscription => Statement::Live(LiveStatement {
+				id: Uuid::new_v4(),
+				expr: Fields::all(),
+				what: Value::Table(Table::from(sel.name.as_str())),
+				cond: cond(sel.args.get("filter")),
+				fetch: fetch(&sel.fields),
+				since: None,
+			}),
+		};
+		out.push(stm);
+	}
+	Ok(Query(Statements(out)))
+}
+
+/// Fetch a required argument from a field selection
+fn required<'a>(sel: &'a Selection, name: &str) -> Result<Option<&'a Value>, Error> {
+	match sel.args.get(name) {
+		Some(v) => Ok(Some(v)),
+		None => Err(Error::Graphql(format!("Missing argument '{name}' for '{}'", sel.name))),
+	}
+}
+
+/// Convert a table name and optional id into a statement target
+fn what(tb: &str, id: Option<&Value>) -> Value {
+	match id {
+		// The id is a full record id
+		Some(Value::Strand(v)) if v.starts_with(&format!("{tb}:")) => {
+			match surrealdb::sql::thing(v.as_str()) {
+				Ok(v) => Value::Thing(v),
+				Err(_) => Value::Thing(Thing::from((tb, v.as_str()))),
+			}
+		}
+		// The id is a record id on this table
+		Some(Value::Strand(v)) => Value::Thing(Thing::from((tb, v.as_str()))),
+		Some(Value::Number(v)) => Value::Thing(Thing::from((tb.to_owned(), v.to_int().into()))),
+		// No id was specified
+		_ => Value::Table(Table::from(tb)),
+	}
+}
+
+/// Convert a filter object into an equality condition
+fn cond(filter: Option<&Value>) -> Option<Cond> {
+	match filter {
+		Some(Value::Object(v)) => v
+			.iter()
+			.map(|(k, v)| {
+				Value::Expression(Box::new(Expression {
+					l: Value::Idiom(Idiom::from(k.to_owned())),
+					o: Operator::Equal,
+					r: v.to_owned(),
+				}))
+			})
+			.reduce(|l, r| {
+				Value::Expression(Box::new(Expression {
+					l,
+					o: Operator::And,
+					r,
+				}))
+			})
+			.map(Cond),
+		_ => None,
+	}
+}
+
+/// Convert an order argument into an ordering clause. A
+/// field name prefixed with a `-` is sorted descending.
+fn order(v: &Value) -> Orders {
+	let v = v.to_owned().as_raw_string();
+	let (field, direction) = match v.strip_prefix('-') {
+		Some(v) => (v, false),
+		None => (v.as_str(), true),
+	};
+	Orders(vec![Order {
+		order: Idiom::from(field.to_owned()),
+		direction,
+		..Default::default()
+	}])
+}
+
+/// Fetch any record links which have sub-selections
+fn fetch(fields: &[Selection]) -> Option<Fetchs> {
+	let mut out = Vec::new();
+	paths(fields, &mut Vec::new(), &mut out);
+	match out.is_empty() {
+		true => None,
+		false => Some(Fetchs(out)),
+	}
+}
+
+fn paths(fields: &[Selection], path: &mut Vec<String>, out: &mut Vec<Fetch>) {
+	for sel in fields.iter().filter(|sel| !sel.fields.is_empty()) {
+		path.push(sel.name.to_owned());
+		out.push(Fetch(Idiom::from(
+			path.iter().map(|v| Part::from(v.as_str())).collect::<Vec<_>>(),
+		)));
+		paths(&sel.fields, path, out);
+		path.pop();
+	}
+}
+
+/// Restrict a result to the selected fields
+fn project(v: Value, fields: &[Selection]) -> Value {
+	if fields.is_empty() {
+		return v;
+	}
+	match v {
+		Value::Array(v) => v.0.into_iter().map(|v| project(v, fields)).collect::<Vec<_>>().into(),
+		Value::Object(mut v) => {
+			let mut out = BTreeMap::new();
+			for sel in fields.iter() {
+				let val = match sel.name.as_str() {
+					"__typename" => match v.get("id") {
+						Some(Value::Thing(t)) => Value::from(t.tb.to_owned()),
+						_ => Value::Null,
+					},
+					name => v.remove(name).unwrap_or(Value::Null),
+				};
+				out.insert(sel.key().to_owned(), project(val, &sel.fields));
+			}
+			out.into()
+		}
+		v => v,
+	}
+}
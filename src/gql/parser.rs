@@ -0,0 +1,348 @@
+use crate::err::Error;
+use std::collections::BTreeMap;
+use std::iter::Peekable;
+use std::str::Chars;
+use surrealdb::sql::Value;
+
+/// The type of a GraphQL operation
+#[derive(Clone, Copy, Debug, Eq, PartialEq)]
+pub enum Operation {
+	Query,
+	Mutation,
+	Subscription,
+}
+
+/// A single field selection, with its arguments and sub-selections
+#[derive(Clone, Debug, PartialEq)]
+pub struct Selection {
+	pub alias: Option<String>,
+	pub name: String,
+	pub args: BTreeMap<String, Value>,
+	pub fields: Vec<Selection>,
+}
+
+impl Selection {
+	/// The key under which this field is returned
+	pub fn key(&self) -> &str {
+		self.alias.as_deref().unwrap_or(&self.name)
+	}
+}
+
+/// A parsed GraphQL operation
+#[derive(Clone, Debug, PartialEq)]
+pub struct Document {
+	pub kind: Operation,
+	pub fields: Vec<Selection>,
+}
+
+/// Parse a GraphQL document containing a single operation. Any
+/// variables referenced in the document are replaced with the
+/// values which were passed in alongside the request.
+pub fn parse(txt: &str, vars: &BTreeMap<String, Value>) -> Result<Document, Error> {
+	let mut p = Parser {
+		chars: txt.chars().peekable(),
+		vars,
+	};
+	// Parse the operation type
+	p.skip();
+	let kind = match p.peek() {
+		Some('{') => Operation::Query,
+		_ => {
+			let kind = match p.name()?.as_str() {
+				"query" => Operation::Query,
+				"mutation" => Operation::Mutation,
+				"subscription" => Operation::Subscription,
+				v => return Err(Error::Graphql(format!("Unsupported operation '{v}'"))),
+			};
+			// Skip the optional operation name
+			p.skip();
+			if let Some(c) = p.peek() {
+				if c.is_alphabetic() || c == '_' {
+					p.name()?;
+				}
+			}
+			// Skip any variable definitions
+			p.skip();
+			if p.peek() == Some('(') {
+				p.definitions()?;
+			}
+			kind
+		}
+	};
+	// Parse the selection set
+	let fields = p.selections()?;
+	// Check for any trailing input
+	p.skip();
+	if p.peek().is_some() {
+		return Err(Error::Graphql("Only a single operation is supported".to_owned()));
+	}
+	Ok(Document {
+		kind,
+		fields,
+	})
+}
+
+struct Parser<'a> {
+	chars: Peekable<Chars<'a>>,
+	vars: &'a BTreeMap<String, Value>,
+}
+
+impl<'a> Parser<'a> {
+	/// Peek at the next character
+	fn peek(&mut self) -> Option<char> {
+		self.chars.peek().copied()
+	}
+	/// Skip any whitespace, commas, and comments
+	fn skip(&mut self) {
+		while let Some(c) = self.peek() {
+			match c {
+				c if c.is_whitespace() || c == ',' => {
+					self.chars.next();
+				}
+				'#' => {
+					while let Some(c) = self.chars.next() {
+						if c == '\n' {
+							break;
+						}
+					}
+				}
+				_ => break,
+			}
+		}
+	}
+	/// Expect the specified character
+	fn expect(&mut self, c: char) -> Result<(), Error> {
+		self.skip();
+		match self.chars.next() {
+			Some(v) if v == c => Ok(()),
+			Some(v) => Err(Error::Graphql(format!("Expected '{c}' but found '{v}'"))),
+			None => Err(Error::Graphql(format!("Expected '{c}' but found the end of the query"))),
+		}
+	}
+	/// Parse a GraphQL name
+	fn name(&mut self) -> Result<String, Error> {
+		self.skip();
+		let mut out = String::new();
+		while let Some(c) = self.peek() {
+			if c.is_alphanumeric() || c == '_' {
+				out.push(c);
+				self.chars.next();
+			} else {
+				break;
+			}
+		}
+		match out.is_empty() {
+			true => Err(Error::Graphql("Expected a name".to_owned())),
+			false => Ok(out),
+		}
+	}
+	/// Skip over any variable definitions
+	fn definitions(&mut self) -> Result<(), Error> {
+		self.expect('(')?;
+		let mut depth = 1;
+		while depth > 0 {
+			match self.chars.next() {
+				Some('(') => depth += 1,
+				Some(')') => depth -= 1,
+				Some(_) => continue,
+				None => return Err(Error::Graphql("Unterminated variable definitions".to_owned())),
+			}
+		}
+		Ok(())
+	}
+	/// Parse a selection set
+	fn selections(&mut self) -> Result<Vec<Selection>, Error> {
+		self.expect('{')?;
+		let mut out = Vec::new();
+		loop {
+			self.skip();
+			match self.peek() {
+				Some('}') => {
+					self.chars.next();
+					break;
+				}
+				Some('.') => {
+					return Err(Error::Graphql("Fragments are not supported".to_owned()));
+				}
+				Some(_) => out.push(self.selection()?),
+				None => return Err(Error::Graphql("Unterminated selection set".to_owned())),
+			}
+		}
+		Ok(out)
+	}
+	/// Parse a single field selection
+	fn selection(&mut self) -> Result<Selection, Error> {
+		let mut alias = None;
+		let mut name = self.name()?;
+		// Check for a field alias
+		self.skip();
+		if self.peek() == Some(':') {
+			self.chars.next();
+			alias = Some(name);
+			name = self.name()?;
+		}
+		// Parse any field arguments
+		let mut args = BTreeMap::new();
+		self.skip();
+		if self.peek() == Some('(') {
+			self.chars.next();
+			loop {
+				self.skip();
+				if self.peek() == Some(')') {
+					self.chars.next();
+					break;
+				}
+				let key = self.name()?;
+				self.expect(':')?;
+				let val = self.value()?;
+				args.insert(key, val);
+			}
+		}
+		// Parse any sub-selections
+		let mut fields = Vec::new();
+		self.skip();
+		if self.peek() == Some('{') {
+			fields = self.selections()?;
+		}
+		Ok(Selection {
+			alias,
+			name,
+			args,
+			fields,
+		})
+	}
+	/// Parse an input value
+	fn value(&mut self) -> Result<Value, Error> {
+		self.skip();
+		match self.peek() {
+			Some('$') => {
+				self.chars.next();
+				let name = self.name()?;
+				Ok(self.vars.get(&name).cloned().unwrap_or(Value::Null))
+			}
+			Some('"') => self.string(),
+			Some('[') => {
+				self.chars.next();
+				let mut out = Vec::new();
+				loop {
+					self.skip();
+					if self.peek() == Some(']') {
+						self.chars.next();
+						break;
+					}
+					out.push(self.value()?);
+				}
+				Ok(out.into())
+			}
+			Some('{') => {
+				self.chars.next();
+				let mut out = BTreeMap::new();
+				loop {
+					self.skip();
+					if self.peek() == Some('}') {
+						self.chars.next();
+						break;
+					}
+					let key = self.name()?;
+					self.expect(':')?;
+					out.insert(key, self.value()?);
+				}
+				Ok(out.into())
+			}
+			Some(c) if c == '-' || c.is_ascii_digit() => self.number(),
+			Some(_) => Ok(match self.name()?.as_str() {
+				"true" => Value::Bool(true),
+				"false" => Value::Bool(false),
+				"null" => Value::Null,
+				v => Value::from(v),
+			}),
+			None => Err(Error::Graphql("Expected a value".to_owned())),
+		}
+	}
+	/// Parse a string value
+	fn string(&mut self) -> Result<Value, Error> {
+		self.expect('"')?;
+		let mut out = String::new();
+		loop {
+			match self.chars.next() {
+				Some('"') => break,
+				Some('\\') => match self.chars.next() {
+					Some('n') => out.push('\n'),
+					Some('r') => out.push('\r'),
+					Some('t') => out.push('\t'),
+					Some(c) => out.push(c),
+					None => return Err(Error::Graphql("Unterminated string".to_owned())),
+				},
+				Some(c) => out.push(c),
+				None => return Err(Error::Graphql("Unterminated string".to_owned())),
+			}
+		}
+		Ok(out.into())
+	}
+	/// Parse a number value
+	fn number(&mut self) -> Result<Value, Error> {
+		let mut out = String::new();
+		while let Some(c) = self.peek() {
+			if c == '-' || c == '+' || c == '.' || c == 'e' || c == 'E' || c.is_ascii_digit() {
+				out.push(c);
+				self.chars.next();
+			} else {
+				break;
+			}
+		}
+		if let Ok(v) = out.parse::<i64>() {
+			return Ok(v.into());
+		}
+		match out.parse::<f64>() {
+			Ok(v) => Ok(v.into()),
+			Err(_) => Err(Error::Graphql(format!("Invalid number '{out}'"))),
+		}
+	}
+}
+
+#[cfg(test)]
+mod tests {
+
+	use super::*;
+
+	#[test]
+	fn parse_query() {
+		let vars = BTreeMap::from([("min".to_owned(), Value::from(18))]);
+		let res = parse(
+			r#"query People($min: Int) {
+				adults: person(filter: { age: $min }, limit: 10) {
+					name
+					friends { name }
+				}
+			}"#,
+			&vars,
+		);
+		assert!(res.is_ok());
+		let out = res.unwrap();
+		assert_eq!(out.kind, Operation::Query);
+		assert_eq!(out.fields.len(), 1);
+		let sel = &out.fields[0];
+		assert_eq!(sel.key(), "adults");
+		assert_eq!(sel.name, "person");
+		assert_eq!(sel.args.get("limit"), Some(&Value::from(10)));
+		assert_eq!(sel.fields.len(), 2);
+		assert_eq!(sel.fields[1].fields[0].name, "name");
+	}
+
+	#[test]
+	fn parse_mutation() {
+		let vars = BTreeMap::new();
+		let res = parse(r#"mutation { create_person(data: { name: "Tobie" }) { id } }"#, &vars);
+		assert!(res.is_ok());
+		let out = res.unwrap();
+		assert_eq!(out.kind, Operation::Mutation);
+		assert_eq!(out.fields[0].name, "create_person");
+	}
+
+	#[test]
+	fn parse_fragment_fails() {
+		let vars = BTreeMap::new();
+		let res = parse("{ person { ...fields } }", &vars);
+		assert!(res.is_err());
+	}
+}
@@ -0,0 +1,86 @@
+use crate::dbs::DB;
+use crate::err::Error;
+use std::fmt::Write;
+use surrealdb::sql::statements::DefineFieldStatement;
+use surrealdb::sql::Kind;
+
+/// Generate a GraphQL schema definition from the tables
+/// and fields which are defined on the selected database.
+pub async fn generate(ns: &str, db: &str) -> Result<String, Error> {
+	// Get a database reference
+	let kvs = DB.get().unwrap();
+	// Start a new read transaction
+	let mut txn = kvs.transaction(false, false).await?;
+	// Fetch the table definitions
+	let tbs = txn.all_tb(ns, db).await?;
+	// Output the schema definition
+	let mut out = String::from("scalar JSON\n\n");
+	let mut query = String::new();
+	let mut mutation = String::new();
+	let mut subscription = String::new();
+	for tb in tbs.iter() {
+		let name = tb.name.to_raw();
+		// Fetch the field definitions
+		let fds = txn.all_fd(ns, db, &name).await?;
+		// Only top-level fields can be mapped to the schema
+		let fds: Vec<&DefineFieldStatement> =
+			fds.iter().filter(|fd| fd.name.0.len() == 1 && fd.name.to_string() != "id").collect();
+		// Output the output type
+		let _ = writeln!(out, "type {name} {{");
+		let _ = writeln!(out, "\tid: ID!");
+		for fd in fds.iter() {
+			let _ = writeln!(out, "\t{}: {}", fd.name, kind(fd.kind.as_ref()));
+		}
+		let _ = writeln!(out, "}}\n");
+		// Output the input type
+		let _ = writeln!(out, "input {name}_data {{");
+		for fd in fds.iter() {
+			let _ = writeln!(out, "\t{}: {}", fd.name, input(fd.kind.as_ref()));
+		}
+		if fds.is_empty() || !tb.full {
+			let _ = writeln!(out, "\t_: JSON");
+		}
+		let _ = writeln!(out, "}}\n");
+		// Output the root fields
+		let _ = writeln!(
+			query,
+			"\t{name}(id: ID, filter: {name}_data, order: String, limit: Int, start: Int): [{name}!]!"
+		);
+		let _ = writeln!(mutation, "\tcreate_{name}(id: ID, data: {name}_data!): {name}");
+		let _ = writeln!(mutation, "\tupdate_{name}(id: ID!, data: {name}_data!): {name}");
+		let _ = writeln!(mutation, "\tdelete_{name}(id: ID!): {name}");
+		let _ = writeln!(subscription, "\t{name}(filter: {name}_data): ID!");
+	}
+	// Cancel the transaction
+	txn.cancel().await?;
+	// Output the root types
+	if !query.is_empty() {
+		let _ = writeln!(out, "type Query {{\n{query}}}\n");
+		let _ = writeln!(out, "type Mutation {{\n{mutation}}}\n");
+		let _ = writeln!(out, "type Subscription {{\n{subscription}}}");
+	}
+	Ok(out)
+}
+
+/// Convert a field type into a GraphQL output type
+fn kind(kind: Option<&Kind>) -> String {
+	match kind {
+		Some(Kind::Option(v)) => kind(Some(v)).trim_end_matches('!').to_owned(),
+		Some(v) => format!("{}!", input(Some(v))),
+		None => "JSON".to_owned(),
+	}
+}
+
+/// Convert a field type into a GraphQL input type
+fn input(kind: Option<&Kind>) -> String {
+	match kind {
+		Some(Kind::Bool) => "Boolean".to_owned(),
+		Some(Kind::Int) => "Int".to_owned(),
+		Some(Kind::Float | Kind::Decimal | Kind::Number) => "Float".to_owned(),
+		Some(Kind::String | Kind::Datetime | Kind::Duration | Kind::Uuid) => "String".to_owned(),
+		Some(Kind::Record(_)) => "ID".to_owned(),
+		Some(Kind::Option(v)) => input(Some(v)),
+		Some(Kind::Array(v, _) | Kind::Set(v, _)) => format!("[{}]", input(Some(v))),
+		_ => "JSON".to_owned(),
+	}
+}
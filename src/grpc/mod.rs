@@ -0,0 +1,474 @@
+//! The gRPC service, which is defined in `proto/surrealdb.proto`. Each
+//! method is converted into a SurrealQL query which is run using the
+//! same datastore, session, and authentication as the HTTP endpoints.
+mod proto {
+	tonic::include_proto!("surrealdb.v1");
+}
+
+use crate::cli::CF;
+use crate::cnf::MAX_CONCURRENT_CALLS;
+use crate::dbs::DB;
+use crate::err::Error;
+use crate::iam::verify::basic;
+use crate::iam::BASIC;
+use crate::net::client_ip::ClientIp;
+use crate::net::signals;
+use futures::{Stream, StreamExt};
+use proto::surreal_server::{Surreal, SurrealServer};
+use proto::{
+	CreateRequest, DeleteRequest, LiveRequest, LiveResponse, QueryRequest, QueryResponse,
+	QueryResult, RecordsResponse, SelectRequest, UpdateRequest,
+};
+use std::collections::BTreeMap;
+use std::pin::Pin;
+use std::task::{Context, Poll};
+use surrealdb::channel;
+use surrealdb::channel::Receiver;
+use surrealdb::dbs::Session;
+use surrealdb::iam::verify::token;
+use surrealdb::iam::TOKEN;
+use surrealdb::sql::Value;
+use tonic::metadata::MetadataMap;
+use tonic::{Request, Response, Status};
+use uuid::Uuid;
+
+const LOG: &str = "surrealdb::grpc";
+
+pub async fn init() -> Result<(), Error> {
+	// Get local copy of options
+	let opt = CF.get().unwrap();
+	// Check if the gRPC server is enabled
+	let bind = match opt.grpc {
+		Some(bind) => bind,
+		None => return Ok(()),
+	};
+	info!(target: LOG, "Starting gRPC server on {}", &bind);
+	// Run the server in the background
+	tokio::spawn(async move {
+		let res = tonic::transport::Server::builder()
+			.add_service(SurrealServer::new(Service))
+			.serve_with_shutdown(bind, async move {
+				let _ = signals::listen().await;
+			})
+			.await;
+		if let Err(e) = res {
+			error!(target: LOG, "The gRPC server failed: {}", e);
+		}
+	});
+	Ok(())
+}
+
+struct Service;
+
+#[tonic::async_trait]
+impl Surreal for Service {
+	type LiveStream = Live;
+
+	async fn query(&self, req: Request<QueryRequest>) -> Result<Response<QueryResponse>, Status> {
+		let session = session(&req).await?;
+		let req = req.into_inner();
+		// Parse the query parameters
+		let mut vars = BTreeMap::new();
+		for (k, v) in req.vars {
+			vars.insert(k, json(&v)?);
+		}
+		// Get a database reference
+		let kvs = DB.get().unwrap();
+		// Get local copy of options
+		let opt = CF.get().unwrap();
+		// Execute the query on the database
+		let res = kvs.execute(&req.sql, &session, Some(vars), opt.strict).await.map_err(status)?;
+		// Convert the query results
+		let results = res
+			.into_iter()
+			.map(|res| {
+				let time = res.speed();
+				match res.result {
+					Ok(v) => QueryResult {
+						status: String::from("OK"),
+						time,
+						result: v.into_json().to_string(),
+						detail: String::new(),
+					},
+					Err(e) => QueryResult {
+						status: String::from("ERR"),
+						time,
+						result: String::new(),
+						detail: e.to_string(),
+					},
+				}
+			})
+			.collect();
+		Ok(Response::new(QueryResponse {
+			results,
+		}))
+	}
+
+	async fn select(
+		&self,
+		req: Request<SelectRequest>,
+	) -> Result<Response<RecordsResponse>, Status> {
+		let session = session(&req).await?;
+		let req = req.into_inner();
+		let sql = "SELECT * FROM $what";
+		let vars = map! {
+			String::from("what") => what(&req.what),
+		};
+		records(&session, sql, vars).await
+	}
+
+	async fn create(
+		&self,
+		req: Request<CreateRequest>,
+	) -> Result<Response<RecordsResponse>, Status> {
+		let session = session(&req).await?;
+		let req = req.into_inner();
+		let sql = "CREATE $what CONTENT $data RETURN AFTER";
+		let vars = map! {
+			String::from("what") => what(&req.what),
+			String::from("data") => data(&req.data)?,
+		};
+		records(&session, sql, vars).await
+	}
+
+	async fn update(
+		&self,
+		req: Request<UpdateRequest>,
+	) -> Result<Response<RecordsResponse>, Status> {
+		let session = session(&req).await?;
+		let req = req.into_inner();
+		let sql = match req.merge {
+			true => "UPDATE $what MERGE $data RETURN AFTER",
+			false => "UPDATE $what CONTENT $data RETURN AFTER",
+		};
+		let vars = map! {
+			String::from("what") => what(&req.what),
+			String::from("data") => data(&req.data)?,
+		};
+		records(&session, sql, vars).await
+	}
+
+	async fn delete(
+		&self,
+		req: Request<DeleteRequest>,
+	) -> Result<Response<RecordsResponse>, Status> {
+		let session = session(&req).await?;
+		let req = req.into_inner();
+		let sql = "DELETE $what RETURN BEFORE";
+		let vars = map! {
+			String::from("what") => what(&req.what),
+		};
+		records(&session, sql, vars).await
+	}
+
+	async fn live(&self, req: Request<LiveRequest>) -> Result<Response<Self::LiveStream>, Status> {
+		let mut session = session(&req).await?;
+		let req = req.into_inner();
+		// Get a database reference
+		let kvs = DB.get().unwrap();
+		// Get local copy of options
+		let opt = CF.get().unwrap();
+		// Use a unique id for the stream as the session id
+		let conn = Uuid::new_v4();
+		session.id = Some(conn.to_string());
+		// Enable real-time live queries
+		session.rt = true;
+		// Register the stream as a session of the datastore, which
+		// receives the notifications of its live query
+		let exit = kvs.registry().connect(conn, "grpc", &session);
+		let (tx, rx) = channel::new(MAX_CONCURRENT_CALLS);
+		kvs.registry().notify(&conn, Some(tx));
+		// The stream is unregistered if the live query fails
+		let mut live = Live {
+			id: None,
+			conn,
+			session,
+			exit,
+			notifications: rx,
+			sent: false,
+		};
+		// Register the live query
+		let sql = "LIVE SELECT * FROM $tb";
+		let vars = map! {
+			String::from("tb") => Value::from(req.table).could_be_table(),
+		};
+		let mut res =
+			kvs.execute(sql, &live.session, Some(vars), opt.strict).await.map_err(status)?;
+		live.id = Some(res.remove(0).result.map_err(status)?);
+		// Stream the live query to the client
+		Ok(Response::new(live))
+	}
+}
+
+/// A live query stream, which kills the
+/// live query when the client disconnects
+pub struct Live {
+	id: Option<Value>,
+	conn: Uuid,
+	session: Session,
+	// Closed when the session is killed
+	exit: Receiver<()>,
+	// Receives the notifications of the live query
+	notifications: Receiver<Value>,
+	sent: bool,
+}
+
+impl Stream for Live {
+	type Item = Result<LiveResponse, Status>;
+
+	fn poll_next(mut self: Pin<&mut Self>, cx: &mut Context<'_>) -> Poll<Option<Self::Item>> {
+		// Send the live query id to the client first
+		if !self.sent {
+			self.sent = true;
+			let id = self.id.clone().unwrap_or_default().as_raw_string();
+			return Poll::Ready(Some(Ok(LiveResponse {
+				id,
+				..Default::default()
+			})));
+		}
+		// End the stream if the session was killed
+		if let Poll::Ready(None) = self.exit.poll_next_unpin(cx) {
+			return Poll::Ready(None);
+		}
+		// Send each notification of the live query, ending
+		// the stream once the session is unregistered
+		match self.notifications.poll_next_unpin(cx) {
+			Poll::Ready(Some(v)) => Poll::Ready(Some(Ok(LiveResponse {
+				id: v.pick(&["id".into()]).as_raw_string(),
+				action: v.pick(&["action".into()]).as_raw_string(),
+				result: v.pick(&["result".into()]).into_json().to_string(),
+			}))),
+			Poll::Ready(None) => Poll::Ready(None),
+			Poll::Pending => Poll::Pending,
+		}
+	}
+}
+
+impl Drop for Live {
+	fn drop(&mut self) {
+		let id = self.id.take();
+		let conn = self.conn;
+		let session = self.session.clone();
+		tokio::spawn(async move {
+			// Get a database reference
+			let kvs = DB.get().unwrap();
+			// Get local copy of options
+			let opt = CF.get().unwrap();
+			// Kill the live query
+			if let Some(id) = id {
+				let vars = map! {
+					String::from("id") => id,
+				};
+				if let Err(e) = kvs.execute("KILL $id", &session, Some(vars), opt.strict).await {
+					warn!(target: LOG, "Unable to kill the live query: {}", e);
+				}
+			}
+			// Remove the stream from the sessions of the datastore
+			kvs.registry().disconnect(&conn);
+		});
+	}
+}
+
+/// Execute a query, returning the records from the first statement
+async fn records(
+	session: &Session,
+	sql: &str,
+	vars: BTreeMap<String, Value>,
+) -> Result<Response<RecordsResponse>, Status> {
+	// Get a database reference
+	let kvs = DB.get().unwrap();
+	// Get local copy of options
+	let opt = CF.get().unwrap();
+	// Execute the query on the database
+	let mut res = kvs.execute(sql, session, Some(vars), opt.strict).await.map_err(status)?;
+	// Extract the first query result
+	let records = match res.remove(0).result.map_err(status)? {
+		Value::Array(v) => v.0.into_iter().map(|v| v.into_json().to_string()).collect(),
+		Value::None | Value::Null => vec![],
+		v => vec![v.into_json().to_string()],
+	};
+	Ok(Response::new(RecordsResponse {
+		records,
+	}))
+}
+
+/// Build and authenticate a session from the request metadata
+async fn session<T>(req: &Request<T>) -> Result<Session, Status> {
+	let kvs = DB.get().unwrap();
+	let opt = CF.get().unwrap();
+	let md = req.metadata();
+	// Add remote ip address
+	let ip = match opt.client_ip {
+		ClientIp::None => None,
+		_ => req.remote_addr().map(|v| v.ip().to_string()),
+	};
+	// Create session
+	#[rustfmt::skip]
+	let mut session = Session { ip, id: meta(md, "id"), ns: meta(md, "ns"), db: meta(md, "db"), ..Default::default() };
+	// Parse the authentication metadata
+	match meta(md, "authorization") {
+		// Basic authentication data was supplied
+		Some(auth) if auth.starts_with(BASIC) => basic(&mut session, auth).await,
+		// Token authentication data was supplied
+		Some(auth) if auth.starts_with(TOKEN) => {
+			token(kvs, &mut session, auth).await.map_err(Error::from)
+		}
+		// Wrong authentication data was supplied
+		Some(_) => Err(Error::InvalidAuth),
+		// No authentication data was supplied
+		None => Ok(()),
+	}
+	.map_err(|e| Status::unauthenticated(e.to_string()))?;
+	// Pass the authenticated session through
+	Ok(session)
+}
+
+/// Fetch a metadata value as a string
+fn meta(md: &MetadataMap, key: &str) -> Option<String> {
+	md.get(key).and_then(|v| v.to_str().ok()).map(String::from)
+}
+
+/// Convert a table name or record id into a query value
+fn what(v: &str) -> Value {
+	Value::from(v).could_be_table()
+}
+
+/// Parse JSON-encoded record data, where empty data is no data
+fn data(v: &str) -> Result<Value, Status> {
+	match v.is_empty() {
+		true => Ok(Value::None),
+		false => json(v),
+	}
+}
+
+/// Parse a JSON-encoded value
+fn json(v: &str) -> Result<Value, Status> {
+	surrealdb::sql::json(v).map_err(|e| Status::invalid_argument(e.to_string()))
+}
+
+/// Convert a database error into a gRPC status
+fn status(e: surrealdb::error::Db) -> Status {
+	match e {
+		surrealdb::error::Db::QuotaExceeded {
+			..
+		} => Status::resource_exhausted(e.to_string()),
+		_ => Status::internal(e.to_string()),
+	}
+}
+
+#[cfg(test)]
+mod tests {
+	use super::proto::surreal_client::SurrealClient;
+	use super::*;
+	use tokio_stream::wrappers::TcpListenerStream;
+	use tonic::transport::Channel;
+
+	/// Add the session metadata to a request
+	fn request<T>(msg: T) -> Request<T> {
+		let mut req = Request::new(msg);
+		let md = req.metadata_mut();
+		md.insert("authorization", "Basic cm9vdDpyb290".parse().unwrap());
+		md.insert("ns", "grpc".parse().unwrap());
+		md.insert("db", "grpc".parse().unwrap());
+		req
+	}
+
+	async fn client() -> SurrealClient<Channel> {
+		crate::dbs::test().await;
+		let listener = tokio::net::TcpListener::bind("127.0.0.1:0").await.unwrap();
+		let addr = listener.local_addr().unwrap();
+		tokio::spawn(
+			tonic::transport::Server::builder()
+				.add_service(SurrealServer::new(Service))
+				.serve_with_incoming(TcpListenerStream::new(listener)),
+		);
+		SurrealClient::connect(format!("http://{addr}")).await.unwrap()
+	}
+
+	#[tokio::test]
+	async fn round_trip() {
+		let mut client = client().await;
+		// Create a record
+		let res = client
+			.create(request(CreateRequest {
+				what: String::from("person:tobie"),
+				data: String::from(r#"{ "name": "Tobie" }"#),
+			}))
+			.await
+			.unwrap()
+			.into_inner();
+		assert_eq!(res.records.len(), 1);
+		let rec: serde_json::Value = serde_json::from_str(&res.records[0]).unwrap();
+		assert_eq!(rec["name"], "Tobie");
+		// Select the record
+		let res = client
+			.select(request(SelectRequest {
+				what: String::from("person"),
+			}))
+			.await
+			.unwrap()
+			.into_inner();
+		assert_eq!(res.records.len(), 1);
+		// Query the record with a parameter
+		let res = client
+			.query(request(QueryRequest {
+				sql: String::from("SELECT VALUE name FROM person WHERE name = $name"),
+				vars: [(String::from("name"), String::from(r#""Tobie""#))].into(),
+			}))
+			.await
+			.unwrap()
+			.into_inner();
+		assert_eq!(res.results.len(), 1);
+		assert_eq!(res.results[0].status, "OK");
+		assert_eq!(res.results[0].result, r#"["Tobie"]"#);
+		// Delete the record
+		let res = client
+			.delete(request(DeleteRequest {
+				what: String::from("person:tobie"),
+			}))
+			.await
+			.unwrap()
+			.into_inner();
+		assert_eq!(res.records.len(), 1);
+	}
+
+	#[tokio::test]
+	async fn live_notifications() {
+		let mut client = client().await;
+		let mut live = client
+			.live(request(LiveRequest {
+				table: String::from("animal"),
+			}))
+			.await
+			.unwrap()
+			.into_inner();
+		// The id of the live query is sent first
+		let id = live.message().await.unwrap().unwrap().id;
+		assert!(!id.is_empty());
+		// Create a record in the table
+		client
+			.create(request(CreateRequest {
+				what: String::from("animal:koala"),
+				data: String::from(r#"{ "name": "Koala" }"#),
+			}))
+			.await
+			.unwrap();
+		// The change is sent to the client
+		let res = live.message().await.unwrap().unwrap();
+		assert_eq!(res.id, id);
+		assert_eq!(res.action, "CREATE");
+		let rec: serde_json::Value = serde_json::from_str(&res.result).unwrap();
+		assert_eq!(rec["name"], "Koala");
+	}
+
+	#[tokio::test]
+	async fn unauthenticated() {
+		let mut client = client().await;
+		let mut req = Request::new(SelectRequest {
+			what: String::from("person"),
+		});
+		req.metadata_mut().insert("authorization", "Basic cm9vdDp3cm9uZw==".parse().unwrap());
+		let err = client.select(req).await.unwrap_err();
+		assert_eq!(err.code(), tonic::Code::Unauthenticated);
+	}
+}
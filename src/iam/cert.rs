@@ -0,0 +1,142 @@
+//! Authentication with TLS client certificates.
+//!
+//! When the web server is started with a CA file, clients may present a
+//! certificate which is signed by that CA. The common name of a verified
+//! certificate is mapped to an authentication level with the
+//! `--client-cert-auth` option, so that services which already have a
+//! certificate do not need separate credentials.
+use std::fmt;
+use std::str::FromStr;
+use surrealdb::dbs::Auth;
+
+/// The OID of the common name attribute of a distinguished name
+const COMMON_NAME: [u8; 3] = [0x55, 0x04, 0x03];
+
+/// The authentication level which is granted to a certificate common name
+#[derive(Clone, Debug, PartialEq)]
+pub struct CertAuth {
+	pub cn: String,
+	pub auth: Auth,
+}
+
+impl FromStr for CertAuth {
+	type Err = String;
+	/// Parse a mapping in the form `cn=root`, `cn=ns`, or `cn=ns/db`
+	fn from_str(s: &str) -> Result<Self, Self::Err> {
+		let err = || {
+			format!("Invalid client certificate '{s}', expected 'cn=root', 'cn=ns', or 'cn=ns/db'")
+		};
+		let (cn, level) = s.split_once('=').ok_or_else(err)?;
+		let cn = cn.trim().to_owned();
+		if cn.is_empty() {
+			return Err(err());
+		}
+		let auth = match level.trim().split_once('/') {
+			_ if level.trim() == "root" => Auth::Kv,
+			Some((ns, db)) if !ns.is_empty() && !db.is_empty() => {
+				Auth::Db(ns.to_owned(), db.to_owned())
+			}
+			None if !level.trim().is_empty() => Auth::Ns(level.trim().to_owned()),
+			_ => return Err(err()),
+		};
+		Ok(CertAuth {
+			cn,
+			auth,
+		})
+	}
+}
+
+impl fmt::Display for CertAuth {
+	fn fmt(&self, f: &mut fmt::Formatter) -> fmt::Result {
+		match &self.auth {
+			Auth::Ns(ns) => write!(f, "{}={ns}", self.cn),
+			Auth::Db(ns, db) => write!(f, "{}={ns}/{db}", self.cn),
+			_ => write!(f, "{}=root", self.cn),
+		}
+	}
+}
+
+/// Read a DER element, returning its tag, its contents, and the remaining input
+fn element(i: &[u8]) -> Option<(u8, &[u8], &[u8])> {
+	let (&tag, i) = i.split_first()?;
+	let (&len, i) = i.split_first()?;
+	let (len, i) = match len {
+		len if len & 0x80 == 0 => (len as usize, i),
+		len => {
+			let n = (len & 0x7f) as usize;
+			if n == 0 || n > 4 || i.len() < n {
+				return None;
+			}
+			let (v, i) = i.split_at(n);
+			(v.iter().fold(0, |a, b| (a << 8) | *b as usize), i)
+		}
+	};
+	if i.len() < len {
+		return None;
+	}
+	let (v, i) = i.split_at(len);
+	Some((tag, v, i))
+}
+
+/// Get the subject common name of a DER encoded X.509 certificate
+pub fn common_name(der: &[u8]) -> Option<String> {
+	// Certificate ::= SEQUENCE { tbsCertificate, signatureAlgorithm, signature }
+	let (_, cert, _) = element(der)?;
+	let (_, tbs, _) = element(cert)?;
+	// Skip the optional version, and the serial number
+	let (tag, _, mut i) = element(tbs)?;
+	if tag == 0xa0 {
+		i = element(i)?.2;
+	}
+	// Skip the signature algorithm, the issuer, and the validity
+	for _ in 0..3 {
+		i = element(i)?.2;
+	}
+	// Name ::= SEQUENCE OF SET OF SEQUENCE { type, value }
+	let (_, mut rdns, _) = element(i)?;
+	while !rdns.is_empty() {
+		let (_, mut set, rest) = element(rdns)?;
+		rdns = rest;
+		while !set.is_empty() {
+			let (_, atv, rest) = element(set)?;
+			set = rest;
+			let (_, oid, value) = element(atv)?;
+			if oid == COMMON_NAME {
+				let (_, v, _) = element(value)?;
+				return String::from_utf8(v.to_vec()).ok();
+			}
+		}
+	}
+	None
+}
+
+#[cfg(test)]
+mod tests {
+	use super::*;
+	use rcgen::{Certificate, CertificateParams, DnType};
+
+	#[test]
+	fn parse_cert_auth() {
+		let v = "backup=root".parse::<CertAuth>().unwrap();
+		assert_eq!(v.auth, Auth::Kv);
+		let v = "app=test".parse::<CertAuth>().unwrap();
+		assert_eq!(v.auth, Auth::Ns("test".to_owned()));
+		let v = "app.example.com=test/test".parse::<CertAuth>().unwrap();
+		assert_eq!(v.cn, "app.example.com");
+		assert_eq!(v.auth, Auth::Db("test".to_owned(), "test".to_owned()));
+		assert_eq!(v.to_string(), "app.example.com=test/test");
+		assert!("app".parse::<CertAuth>().is_err());
+		assert!("=test".parse::<CertAuth>().is_err());
+		assert!("app=test/".parse::<CertAuth>().is_err());
+	}
+
+	#[test]
+	fn certificate_common_name() {
+		let mut params = CertificateParams::new(vec!["localhost".to_owned()]);
+		params.distinguished_name.push(DnType::OrganizationName, "SurrealDB");
+		params.distinguished_name.push(DnType::CommonName, "app.example.com");
+		let der = Certificate::from_params(params).unwrap().serialize_der().unwrap();
+		assert_eq!(common_name(&der), Some("app.example.com".to_owned()));
+		assert_eq!(common_name(&der[..der.len() / 2]), None);
+	}
+}
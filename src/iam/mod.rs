@@ -1,3 +1,4 @@
+pub mod cert;
 pub mod verify;
 
 use crate::cli::CF;
@@ -17,6 +18,10 @@ pub async fn init() -> Result<(), Error> {
 		}
 		None => info!(target: LOG, "Root authentication is disabled"),
 	};
+	// Log any client certificate authentication
+	for v in opt.certs.iter() {
+		info!(target: LOG, "Client certificate authentication is enabled for '{}'", v);
+	}
 	// All ok
 	Ok(())
 }
@@ -15,63 +15,97 @@ pub async fn basic(session: &mut Session, auth: String) -> Result<(), Error> {
 	trace!(target: LOG, "Attempting basic authentication");
 	// Retrieve just the auth data
 	let auth = auth.trim_start_matches(BASIC).trim();
-	// Get a database reference
-	let kvs = DB.get().unwrap();
-	// Get the config options
-	let opts = CF.get().unwrap();
 	// Decode the encoded auth data
 	let auth = BASE64.decode(auth)?;
 	// Convert the auth data to String
 	let auth = String::from_utf8(auth)?;
 	// Split the auth data into user and pass
-	if let Some((user, pass)) = auth.split_once(':') {
-		// Check that the details are not empty
-		if user.is_empty() || pass.is_empty() {
-			return Err(Error::InvalidAuth);
+	match auth.split_once(':') {
+		Some((user, pass)) => credentials(session, user, pass).await,
+		None => Err(Error::InvalidAuth),
+	}
+}
+
+pub async fn credentials(session: &mut Session, user: &str, pass: &str) -> Result<(), Error> {
+	// Get a database reference
+	let kvs = DB.get().unwrap();
+	// Get the config options
+	let opts = CF.get().unwrap();
+	// Check that the details are not empty
+	if user.is_empty() || pass.is_empty() {
+		return Err(Error::InvalidAuth);
+	}
+	// Check if this is root authentication
+	if let Some(root) = &opts.pass {
+		if user == opts.user && pass == root {
+			// Log the authentication type
+			debug!(target: LOG, "Authenticated as super user");
+			// Store the authentication data
+			session.au = Arc::new(Auth::Kv);
+			return Ok(());
 		}
-		// Check if this is root authentication
-		if let Some(root) = &opts.pass {
-			if user == opts.user && pass == root {
-				// Log the authentication type
-				debug!(target: LOG, "Authenticated as super user");
+	}
+	// Check if this is NS authentication
+	if let Some(ns) = &session.ns {
+		// Create a new readonly transaction
+		let mut tx = kvs.transaction(false, false).await?;
+		// Check if the supplied NS Login exists
+		if let Ok(nl) = tx.get_nl(ns, user).await {
+			// Compute the hash and verify the password
+			let hash = PasswordHash::new(&nl.hash).unwrap();
+			if Argon2::default().verify_password(pass.as_ref(), &hash).is_ok() {
+				// Log the successful namespace authentication
+				debug!(target: LOG, "Authenticated as namespace user: {}", user);
 				// Store the authentication data
-				session.au = Arc::new(Auth::Kv);
+				session.au = Arc::new(Auth::Ns(ns.to_owned()));
 				return Ok(());
 			}
-		}
-		// Check if this is NS authentication
-		if let Some(ns) = &session.ns {
-			// Create a new readonly transaction
-			let mut tx = kvs.transaction(false, false).await?;
-			// Check if the supplied NS Login exists
-			if let Ok(nl) = tx.get_nl(ns, user).await {
+		};
+		// Check if this is DB authentication
+		if let Some(db) = &session.db {
+			// Check if the supplied DB Login exists
+			if let Ok(dl) = tx.get_dl(ns, db, user).await {
 				// Compute the hash and verify the password
-				let hash = PasswordHash::new(&nl.hash).unwrap();
+				let hash = PasswordHash::new(&dl.hash).unwrap();
 				if Argon2::default().verify_password(pass.as_ref(), &hash).is_ok() {
 					// Log the successful namespace authentication
-					debug!(target: LOG, "Authenticated as namespace user: {}", user);
+					debug!(target: LOG, "Authenticated as database user: {}", user);
 					// Store the authentication data
-					session.au = Arc::new(Auth::Ns(ns.to_owned()));
+					session.au = Arc::new(Auth::Db(ns.to_owned(), db.to_owned()));
 					return Ok(());
 				}
 			};
-			// Check if this is DB authentication
-			if let Some(db) = &session.db {
-				// Check if the supplied DB Login exists
-				if let Ok(dl) = tx.get_dl(ns, db, user).await {
-					// Compute the hash and verify the password
-					let hash = PasswordHash::new(&dl.hash).unwrap();
-					if Argon2::default().verify_password(pass.as_ref(), &hash).is_ok() {
-						// Log the successful namespace authentication
-						debug!(target: LOG, "Authenticated as database user: {}", user);
-						// Store the authentication data
-						session.au = Arc::new(Auth::Db(ns.to_owned(), db.to_owned()));
-						return Ok(());
-					}
-				};
-			}
 		}
 	}
+	// Check if the external authenticator accepts the credentials
+	if kvs.authenticator().is_some() {
+		return surrealdb::iam::external::credentials(kvs, session, user, pass)
+			.await
+			.map_err(|_| Error::InvalidAuth);
+	}
 	// There was an auth error
 	Err(Error::InvalidAuth)
 }
+
+pub fn certificate(session: &mut Session, cn: &str) {
+	// Get the config options
+	let opts = CF.get().unwrap();
+	// Check if the common name is mapped to an authentication level
+	match opts.certs.iter().find(|v| v.cn == cn) {
+		Some(v) => {
+			// Log the successful certificate authentication
+			debug!(target: LOG, "Authenticated with client certificate: {}", cn);
+			// Store the authentication data
+			match &v.auth {
+				Auth::Ns(ns) => session.ns = Some(ns.to_owned()),
+				Auth::Db(ns, db) => {
+					session.ns = Some(ns.to_owned());
+					session.db = Some(db.to_owned());
+				}
+				_ => (),
+			}
+			session.au = Arc::new(v.auth.clone());
+		}
+		None => trace!(target: LOG, "The client certificate '{}' has no authentication level", cn),
+	}
+}
@@ -21,9 +21,13 @@ mod cnf;
 mod dbs;
 mod env;
 mod err;
+mod gql;
+#[cfg(feature = "grpc")]
+mod grpc;
 mod iam;
 mod net;
 mod o11y;
+mod pgwire;
 mod rpc;
 
 use std::future::Future;
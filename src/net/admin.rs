@@ -0,0 +1,23 @@
+use warp::Filter;
+
+/// The admin console, which runs its queries using the HTTP and WebSocket
+/// endpoints, with the credentials which are entered into the console
+const CONSOLE: &str = include_str!("../../app/admin.html");
+
+#[allow(opaque_hidden_inferred_bound)]
+pub fn config() -> impl Filter<Extract = impl warp::Reply, Error = warp::Rejection> + Clone {
+	warp::path("admin").and(warp::path::end()).and(warp::get()).map(|| warp::reply::html(CONSOLE))
+}
+
+#[cfg(test)]
+mod tests {
+	use super::*;
+
+	#[tokio::test]
+	async fn serves_the_console() {
+		let res = warp::test::request().path("/admin").reply(&config()).await;
+		assert_eq!(res.status(), 200);
+		assert_eq!(res.headers()["content-type"], "text/html; charset=utf-8");
+		assert_eq!(res.body(), CONSOLE.as_bytes());
+	}
+}
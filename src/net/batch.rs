@@ -0,0 +1,68 @@
+use crate::cli::CF;
+use crate::dbs::DB;
+use crate::err::Error;
+use crate::net::output;
+use crate::net::params::Params;
+use crate::net::session;
+use serde::Deserialize;
+use surrealdb::dbs::BatchPolicy;
+use surrealdb::dbs::Session;
+use warp::Filter;
+
+const MAX: u64 = 1024 * 1024; // 1 MiB
+
+/// A batch of statements, and how statements which fail are handled
+#[derive(Deserialize)]
+struct Batch {
+	statements: Vec<String>,
+	#[serde(default)]
+	policy: BatchPolicy,
+}
+
+#[allow(opaque_hidden_inferred_bound)]
+pub fn config() -> impl Filter<Extract = impl warp::Reply, Error = warp::Rejection> + Clone {
+	// Set base path
+	let base = warp::path("batch").and(warp::path::end());
+	// Set opts method
+	let opts = base.and(warp::options()).map(warp::reply);
+	// Set post method
+	let post = base
+		.and(warp::post())
+		.and(warp::header::<String>(http::header::ACCEPT.as_str()))
+		.and(warp::body::content_length_limit(MAX))
+		.and(warp::body::json())
+		.and(warp::query())
+		.and(session::build())
+		.and_then(handler);
+	// Specify route
+	opts.or(post)
+}
+
+async fn handler(
+	output: String,
+	batch: Batch,
+	params: Params,
+	session: Session,
+) -> Result<impl warp::Reply, warp::Rejection> {
+	// Get a database reference
+	let db = DB.get().unwrap();
+	// Get local copy of options
+	let opt = CF.get().unwrap();
+	// Execute the received statements
+	let vars = params.parse().into();
+	match db.batch(&batch.statements, batch.policy, &session, vars, opt.strict).await {
+		// Convert the response to JSON
+		Ok(res) => match output.as_ref() {
+			// Simple serialization
+			"application/json" => Ok(output::json(&output::simplify(res))),
+			"application/cbor" => Ok(output::cbor(&output::binary(res))),
+			"application/pack" => Ok(output::pack(&output::binary(res))),
+			// Internal serialization
+			"application/bung" => Ok(output::full(&res)),
+			// An incorrect content-type was requested
+			_ => Err(warp::reject::custom(Error::InvalidType)),
+		},
+		// There was an error when executing the statements
+		Err(err) => Err(warp::reject::custom(Error::from(err))),
+	}
+}
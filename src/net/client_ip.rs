@@ -1,4 +1,5 @@
 use crate::cli::CF;
+use crate::net::tls::Peer;
 use clap::ValueEnum;
 use std::net::IpAddr;
 use std::net::SocketAddr;
@@ -31,16 +32,17 @@ pub fn build() -> impl Filter<Extract = (Option<String>,), Error = warp::Rejecti
 	let client_ip = CF.get().unwrap().client_ip;
 	// Enable on any path
 	let conf = warp::any();
-	// Add raw remote IP address
-	let conf =
-		conf.and(warp::filters::addr::remote().and_then(move |s: Option<SocketAddr>| async move {
+	// Add raw remote IP address, or the address of a verified TLS client
+	let conf = conf.and(warp::filters::addr::remote().and(warp::ext::optional::<Peer>()).and_then(
+		move |s: Option<SocketAddr>, p: Option<Peer>| async move {
 			match client_ip {
 				ClientIp::None => Ok(None),
-				ClientIp::Socket => Ok(s.map(|s| s.ip())),
+				ClientIp::Socket => Ok(s.or(p.map(|p| p.addr)).map(|s| s.ip())),
 				// Move on to parsing selected IP header.
 				_ => Err(warp::reject::reject()),
 			}
-		}));
+		},
+	));
 	// Add selected IP header
 	let conf = conf.or(warp::header::optional::<IpAddr>(match client_ip {
 		ClientIp::CfConectingIp => "Cf-Connecting-IP",
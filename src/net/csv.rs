@@ -0,0 +1,114 @@
+use surrealdb::sql::Value;
+
+/// Encode a list of cells as a CSV row
+pub fn row<I, S>(cells: I) -> String
+where
+	I: IntoIterator<Item = S>,
+	S: AsRef<str>,
+{
+	let mut out = String::new();
+	for (i, v) in cells.into_iter().enumerate() {
+		if i > 0 {
+			out.push(',');
+		}
+		let v = v.as_ref();
+		if v.contains([',', '"', '\n', '\r']) {
+			out.push('"');
+			out.push_str(&v.replace('"', "\"\""));
+			out.push('"');
+		} else {
+			out.push_str(v);
+		}
+	}
+	out.push('\n');
+	out
+}
+
+/// Convert a value into a CSV cell. Strings are output as-is,
+/// missing values are empty, and other values are output as JSON.
+pub fn cell(v: &Value) -> String {
+	match v {
+		Value::None | Value::Null => String::new(),
+		Value::Strand(v) => v.as_str().to_owned(),
+		v => v.clone().into_json().to_string(),
+	}
+}
+
+/// Convert a CSV cell into a value. Cells containing valid JSON
+/// numbers, booleans, arrays, or objects are parsed as such, and
+/// empty cells are treated as missing values.
+pub fn value(v: &str) -> Value {
+	match v {
+		"" => Value::None,
+		v if v.starts_with(['[', '{', '-', '0', '1', '2', '3', '4', '5', '6', '7', '8', '9'])
+			|| v == "true"
+			|| v == "false"
+			|| v == "null" =>
+		{
+			surrealdb::sql::json(v).unwrap_or_else(|_| Value::from(v))
+		}
+		v => Value::from(v),
+	}
+}
+
+/// Parse a CSV record into a list of cells. If the record contains
+/// an unterminated quoted cell, then `None` is returned, as the record
+/// continues onto the next line.
+pub fn parse(line: &str) -> Option<Vec<String>> {
+	let line = line.strip_suffix('\n').unwrap_or(line);
+	let line = line.strip_suffix('\r').unwrap_or(line);
+	let mut out = vec![];
+	let mut cur = String::new();
+	let mut quoted = false;
+	let mut chars = line.chars().peekable();
+	while let Some(c) = chars.next() {
+		match c {
+			'"' if quoted && chars.peek() == Some(&'"') => {
+				chars.next();
+				cur.push('"');
+			}
+			'"' => quoted = !quoted,
+			',' if !quoted => out.push(std::mem::take(&mut cur)),
+			c => cur.push(c),
+		}
+	}
+	if quoted {
+		return None;
+	}
+	out.push(cur);
+	Some(out)
+}
+
+#[cfg(test)]
+mod tests {
+
+	use super::*;
+
+	#[test]
+	fn encode_row() {
+		let out = row(["tobie", "a,b", "say \"hi\""]);
+		assert_eq!(out, "tobie,\"a,b\",\"say \"\"hi\"\"\"\n");
+	}
+
+	#[test]
+	fn parse_row() {
+		let out = parse("tobie,\"a,b\",\"say \"\"hi\"\"\"\n");
+		assert_eq!(out, Some(vec!["tobie".into(), "a,b".into(), "say \"hi\"".into()]));
+	}
+
+	#[test]
+	fn parse_row_multiline() {
+		assert_eq!(parse("tobie,\"first line\n"), None);
+		let out = parse("tobie,\"first line\nsecond line\"\n");
+		assert_eq!(out, Some(vec!["tobie".into(), "first line\nsecond line".into()]));
+	}
+
+	#[test]
+	fn parse_values() {
+		assert_eq!(value(""), Value::None);
+		assert_eq!(value("18"), Value::from(18));
+		assert_eq!(value("true"), Value::Bool(true));
+		assert_eq!(value("2023-01-01"), Value::from("2023-01-01"));
+		assert_eq!(value("tobie"), Value::from("tobie"));
+	}
+}
@@ -1,21 +1,66 @@
 use crate::dbs::DB;
 use crate::err::Error;
+use crate::net::csv;
+use crate::net::params::Param;
 use crate::net::session;
 use bytes::Bytes;
-use hyper::body::Body;
+use http::header::{HeaderValue, CONTENT_TYPE};
+use hyper::body::{Body, Sender};
+use serde::Deserialize;
+use serde_json::Value as Json;
+use std::collections::HashSet;
+use std::io::SeekFrom;
+use surrealdb::channel::Receiver;
 use surrealdb::dbs::Session;
+use surrealdb::sql::Value;
+use tokio::fs::File;
+use tokio::io::{AsyncBufReadExt, AsyncSeekExt, AsyncWriteExt, BufReader, BufWriter};
+use tokio::task::JoinHandle;
 use warp::Filter;
 
+const LOG: &str = "surrealdb::net::export";
+
+/// A running table export
+type Export = JoinHandle<Result<(), surrealdb::error::Db>>;
+
+#[derive(Default, Deserialize, Debug, Clone)]
+struct Query {
+	/// Read each batch of records from a new snapshot
+	#[serde(default)]
+	pub committed: bool,
+	/// Only export the definitions, without any table data
+	#[serde(default)]
+	pub schema: bool,
+	/// Anonymize the fields which are defined as sensitive
+	#[serde(default)]
+	pub anonymize: bool,
+}
+
 #[allow(opaque_hidden_inferred_bound)]
 pub fn config() -> impl Filter<Extract = impl warp::Reply, Error = warp::Rejection> + Clone {
-	warp::path("export")
+	// Set base path
+	let base = warp::path("export");
+	// Set database export method
+	let full = base
 		.and(warp::path::end())
 		.and(warp::get())
+		.and(warp::query())
 		.and(session::build())
-		.and_then(handler)
+		.and_then(handler);
+	// Set table export method
+	let table = base
+		.and(warp::path::param())
+		.and(warp::path::end())
+		.and(warp::get())
+		.and(warp::header::<String>(http::header::ACCEPT.as_str()))
+		.and(warp::query())
+		.and(session::build())
+		.and_then(table);
+	// Specify route
+	full.or(table)
 }
 
-async fn handler(session: Session) -> Result<impl warp::Reply, warp::Rejection> {
+async fn handler(query: Query, session: Session) -> Result<impl warp::Reply, warp::Rejection> {
 	// Check the permissions
 	match session.au.is_db() {
 		true => {
@@ -36,7 +81,12 @@ async fn handler(session: Session) -> Result<impl warp::Reply, warp::Rejection>
 			// Create a new bounded channel
 			let (snd, rcv) = surrealdb::channel::new(1);
 			// Spawn a new database export
-			tokio::spawn(db.export(nsv, dbv, snd));
+			match (query.schema, query.anonymize, query.committed) {
+				(true, _, _) => tokio::spawn(db.export_schema(nsv, dbv, snd)),
+				(false, true, _) => tokio::spawn(db.export_anonymized(nsv, dbv, snd)),
+				(false, false, true) => tokio::spawn(db.export_committed(nsv, dbv, snd)),
+				(false, false, false) => tokio::spawn(db.export(nsv, dbv, snd)),
+			};
 			// Process all processed values
 			tokio::spawn(async move {
 				while let Ok(v) = rcv.recv().await {
@@ -50,3 +100,159 @@ async fn handler(session: Session) -> Result<impl warp::Reply, warp::Rejection>
 		_ => Err(warp::reject::custom(Error::InvalidAuth)),
 	}
 }
+
+async fn table(
+	table: Param,
+	output: String,
+	query: Query,
+	session: Session,
+) -> Result<impl warp::Reply, warp::Rejection> {
+	// Check the permissions
+	if !session.au.is_db() {
+		return Err(warp::reject::custom(Error::InvalidAuth));
+	}
+	// Check the requested output format
+	let is_csv = match output.as_ref() {
+		"application/x-ndjson" => false,
+		"text/csv" => true,
+		_ => return Err(warp::reject::custom(Error::InvalidType)),
+	};
+	// Get the datastore reference
+	let db = DB.get().unwrap();
+	// Extract the NS header value
+	let nsv = match session.ns {
+		Some(ns) => ns,
+		None => return Err(warp::reject::custom(Error::NoNsHeader)),
+	};
+	// Extract the DB header value
+	let dbv = match session.db {
+		Some(db) => db,
+		None => return Err(warp::reject::custom(Error::NoDbHeader)),
+	};
+	// Create a chunked response
+	let (mut chn, bdy) = Body::channel();
+	// Create a new bounded channel
+	let (snd, rcv) = surrealdb::channel::new(1000);
+	// Spawn a new table export
+	let tb = table.0.clone();
+	let export = tokio::spawn(async move {
+		match (query.anonymize, query.committed) {
+			(true, _) => db.export_table_anonymized(nsv, dbv, tb, snd).await,
+			(false, true) => db.export_table_committed(nsv, dbv, tb, snd).await,
+			(false, false) => db.export_table(nsv, dbv, tb, snd).await,
+		}
+	});
+	// Process all processed records
+	tokio::spawn(async move {
+		let res = match is_csv {
+			// Output each record as a line of JSON
+			false => to_ndjson(&mut chn, rcv, export, &table.0).await,
+			// Output each record as a line of CSV
+			true => to_csv(&mut chn, rcv, export, &table.0).await,
+		};
+		match res {
+			Ok(num) => info!(target: LOG, "Exported {} records from table {}", num, table.0),
+			// Abort the response, so that the client does not see a complete file
+			Err(e) => {
+				warn!(target: LOG, "The table export failed: {}", e);
+				chn.abort();
+			}
+		}
+	});
+	// Return the chunked body
+	let mut res = warp::reply::Response::new(bdy);
+	let con = match is_csv {
+		true => HeaderValue::from_static("text/csv"),
+		false => HeaderValue::from_static("application/x-ndjson"),
+	};
+	res.headers_mut().insert(CONTENT_TYPE, con);
+	Ok(res)
+}
+
+/// Wait for a table export to complete
+async fn finished(export: Export) -> Result<(), Error> {
+	match export.await {
+		Ok(res) => Ok(res?),
+		Err(e) => Err(Error::from(std::io::Error::from(e))),
+	}
+}
+
+/// Report the progress of a table export
+fn progress(num: usize, tb: &str) {
+	if num % 10000 == 0 {
+		info!(target: LOG, "Exported {} records from table {}", num, tb);
+	}
+}
+
+/// Output each record of a table export as a line of JSON
+async fn to_ndjson(
+	chn: &mut Sender,
+	rcv: Receiver<Value>,
+	export: Export,
+	tb: &str,
+) -> Result<usize, Error> {
+	let mut num = 0;
+	while let Ok(v) = rcv.recv().await {
+		// Stop once the client disconnects
+		if chn.send_data(Bytes::from(format!("{}\n", v.into_json()))).await.is_err() {
+			return Ok(num);
+		}
+		num += 1;
+		progress(num, tb);
+	}
+	finished(export).await?;
+	Ok(num)
+}
+
+/// Output each record of a table export as a line of CSV. The records are
+/// spooled to a temporary file, so that the header row contains the columns
+/// of every record, and so that no records are sent if the export fails.
+async fn to_csv(
+	chn: &mut Sender,
+	rcv: Receiver<Value>,
+	export: Export,
+	tb: &str,
+) -> Result<usize, Error> {
+	// The columns of every record, with the id first
+	let mut cols = vec![String::from("id")];
+	let mut seen = HashSet::from([String::from("id")]);
+	// Spool the cells of each record as a line of JSON
+	let mut file = BufWriter::new(File::from_std(tempfile::tempfile()?));
+	let mut num = 0;
+	while let Ok(v) = rcv.recv().await {
+		let mut cells = serde_json::Map::new();
+		if let Value::Object(v) = v {
+			for (k, v) in v.iter() {
+				if seen.insert(k.clone()) {
+					cols.push(k.clone());
+				}
+				cells.insert(k.clone(), Json::from(csv::cell(v)));
+			}
+		}
+		file.write_all(format!("{}\n", Json::Object(cells)).as_bytes()).await?;
+		num += 1;
+		progress(num, tb);
+	}
+	finished(export).await?;
+	// Read the spooled records from the start
+	file.flush().await?;
+	let mut file = file.into_inner();
+	file.seek(SeekFrom::Start(0)).await?;
+	let mut lines = BufReader::new(file).lines();
+	// Output the header row, and then each record
+	if chn.send_data(Bytes::from(csv::row(&cols))).await.is_err() {
+		return Ok(num);
+	}
+	while let Some(line) = lines.next_line().await? {
+		let cells: serde_json::Map<String, Json> = serde_json::from_str(&line)?;
+		let row = csv::row(cols.iter().map(|c| match cells.get(c) {
+			Some(Json::String(v)) => v.as_str(),
+			_ => "",
+		}));
+		// Stop once the client disconnects
+		if chn.send_data(Bytes::from(row)).await.is_err() {
+			break;
+		}
+	}
+	Ok(num)
+}
@@ -1,5 +1,6 @@
 use crate::err::Error;
 use serde::Serialize;
+use surrealdb::err::{ErrorKind, Retry};
 use warp::http::StatusCode;
 
 #[derive(Serialize)]
@@ -11,6 +12,26 @@ struct Message {
 	description: Option<String>,
 	#[serde(skip_serializing_if = "Option::is_none")]
 	information: Option<String>,
+	#[serde(flatten)]
+	kind: Kind,
+	#[serde(skip_serializing_if = "Option::is_none")]
+	retry: Option<Retry>,
+}
+
+/// The category of the error, with its stable string name and numeric code
+struct Kind(ErrorKind);
+
+impl Serialize for Kind {
+	fn serialize<S>(&self, serializer: S) -> Result<S::Ok, S::Error>
+	where
+		S: serde::Serializer,
+	{
+		use serde::ser::SerializeStruct;
+		let mut val = serializer.serialize_struct("Kind", 2)?;
+		val.serialize_field("kind", &self.0)?;
+		val.serialize_field("error_code", &self.0.code())?;
+		val.end()
+	}
 }
 
 pub async fn recover(err: warp::Rejection) -> Result<impl warp::Reply, warp::Rejection> {
@@ -22,6 +43,8 @@ pub async fn recover(err: warp::Rejection) -> Result<impl warp::Reply, warp::Rej
 					details: Some("Authentication failed".to_string()),
 					description: Some("Your authentication details are invalid. Reauthenticate using valid authentication parameters.".to_string()),
 					information: Some(err.to_string()),
+					kind: Kind(ErrorKind::PermissionDenied),
+					retry: None,
 				}),
 				StatusCode::FORBIDDEN,
 			)),
@@ -31,17 +54,36 @@ pub async fn recover(err: warp::Rejection) -> Result<impl warp::Reply, warp::Rej
 					details: Some("Unsupported media type".to_string()),
 					description: Some("The request needs to adhere to certain constraints. Refer to the documentation for supported content types.".to_string()),
 					information: None,
+					kind: Kind(ErrorKind::Invalid),
+					retry: None,
 				}),
 				StatusCode::UNSUPPORTED_MEDIA_TYPE,
 			)),
-			Error::InvalidStorage => Ok(warp::reply::with_status(
+			Error::Db(surrealdb::Error::Db(surrealdb::error::Db::QuotaExceeded {
+				..
+			})) => Ok(warp::reply::with_status(
+				warp::reply::json(&Message {
+					code: 429,
+					details: Some("Too many requests".to_string()),
+					description: Some("The rate limits or quotas for your authenticated identity have been exceeded. Reduce the rate of your requests, and retry the request later.".to_string()),
+					information: Some(err.to_string()),
+					kind: Kind(err.kind()),
+					retry: err.retry(),
+				}),
+				StatusCode::TOO_MANY_REQUESTS,
+			)),
+			Error::Db(surrealdb::Error::Db(surrealdb::error::Db::TxNotFound {
+				..
+			})) => Ok(warp::reply::with_status(
 				warp::reply::json(&Message {
-					code: 500,
-					details: Some("Health check failed".to_string()),
-					description: Some("The database health check for this instance failed. There was an issue with the underlying storage engine.".to_string()),
+					code: 404,
+					details: Some("Transaction not found".to_string()),
+					description: Some("The transaction does not exist, has already been committed or cancelled, or was cancelled after being idle. Begin a new transaction, and retry the request.".to_string()),
 					information: Some(err.to_string()),
+					kind: Kind(err.kind()),
+					retry: None,
 				}),
-				StatusCode::INTERNAL_SERVER_ERROR,
+				StatusCode::NOT_FOUND,
 			)),
 			_ => Ok(warp::reply::with_status(
 				warp::reply::json(&Message {
@@ -49,6 +91,8 @@ pub async fn recover(err: warp::Rejection) -> Result<impl warp::Reply, warp::Rej
 					details: Some("Request problems detected".to_string()),
 					description: Some("There is a problem with your request. Refer to the documentation for further information.".to_string()),
 					information: Some(err.to_string()),
+					kind: Kind(err.kind()),
+					retry: err.retry(),
 				}),
 				StatusCode::BAD_REQUEST,
 			))
@@ -60,6 +104,8 @@ pub async fn recover(err: warp::Rejection) -> Result<impl warp::Reply, warp::Rej
 				details: Some("Requested resource not found".to_string()),
 				description: Some("The requested resource does not exist. Check that you have entered the url correctly.".to_string()),
 				information: None,
+				kind: Kind(ErrorKind::NotFound),
+				retry: None,
 			}),
 			StatusCode::NOT_FOUND,
 		))
@@ -70,6 +116,8 @@ pub async fn recover(err: warp::Rejection) -> Result<impl warp::Reply, warp::Rej
 				details: Some("Request problems detected".to_string()),
 				description: Some("The request appears to be missing a required header. Refer to the documentation for request requirements.".to_string()),
 				information: None,
+				kind: Kind(ErrorKind::Invalid),
+				retry: None,
 			}),
 			StatusCode::PRECONDITION_FAILED,
 		))
@@ -80,6 +128,8 @@ pub async fn recover(err: warp::Rejection) -> Result<impl warp::Reply, warp::Rej
 				details: Some("Payload too large".to_string()),
 				description: Some("The request has exceeded the maximum payload size. Refer to the documentation for the request limitations.".to_string()),
 				information: None,
+				kind: Kind(ErrorKind::Quota),
+				retry: None,
 			}),
 			StatusCode::PAYLOAD_TOO_LARGE,
 		))
@@ -90,6 +140,8 @@ pub async fn recover(err: warp::Rejection) -> Result<impl warp::Reply, warp::Rej
 				details: Some("Not implemented".to_string()),
 				description: Some("The server either does not recognize the query, or it lacks the ability to fulfill the request.".to_string()),
 				information: None,
+				kind: Kind(ErrorKind::Invalid),
+				retry: None,
 			}),
 			StatusCode::NOT_IMPLEMENTED,
 		))
@@ -100,6 +152,8 @@ pub async fn recover(err: warp::Rejection) -> Result<impl warp::Reply, warp::Rej
 				details: Some("Not implemented".to_string()),
 				description: Some("The server either does not recognize a request header, or it lacks the ability to fulfill the request.".to_string()),
 				information: None,
+				kind: Kind(ErrorKind::Invalid),
+				retry: None,
 			}),
 			StatusCode::NOT_IMPLEMENTED,
 		))
@@ -110,6 +164,8 @@ pub async fn recover(err: warp::Rejection) -> Result<impl warp::Reply, warp::Rej
 				details: Some("Requested method not allowed".to_string()),
 				description: Some("The requested http method is not allowed for this resource. Refer to the documentation for allowed methods.".to_string()),
 				information: None,
+				kind: Kind(ErrorKind::Invalid),
+				retry: None,
 			}),
 			StatusCode::METHOD_NOT_ALLOWED,
 		))
@@ -120,6 +176,8 @@ pub async fn recover(err: warp::Rejection) -> Result<impl warp::Reply, warp::Rej
 				details: Some("Internal server error".to_string()),
 				description: Some("There was a problem with our servers, and we have been notified. Refer to the documentation for further information".to_string()),
 				information: None,
+				kind: Kind(ErrorKind::Internal),
+				retry: None,
 			}),
 			StatusCode::INTERNAL_SERVER_ERROR,
 		))
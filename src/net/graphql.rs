@@ -0,0 +1,379 @@
+use crate::cnf::MAX_CONCURRENT_CALLS;
+use crate::dbs::DB;
+use crate::err::Error;
+use crate::gql;
+use crate::net::output;
+use crate::net::session;
+use crate::net::LOG;
+use futures::{SinkExt, StreamExt};
+use serde::Deserialize;
+use serde_json::json;
+use std::collections::BTreeMap;
+use std::collections::HashMap;
+use std::sync::Arc;
+use surrealdb::channel;
+use surrealdb::dbs::Session;
+use surrealdb::sql::Value;
+use tokio::sync::Mutex;
+use uuid::Uuid;
+use warp::ws::{Message, WebSocket, Ws};
+use warp::Filter;
+
+const MAX: u64 = 1024 * 1024; // 1 MiB
+
+// The subprotocol of the GraphQL over WebSocket protocol
+const PROTOCOL: &str = "graphql-transport-ws";
+
+#[derive(Deserialize)]
+#[serde(rename_all = "camelCase")]
+struct Request {
+	query: String,
+	#[serde(default)]
+	variables: Option<serde_json::Value>,
+}
+
+/// A message of the GraphQL over WebSocket protocol
+#[derive(Deserialize)]
+struct Frame {
+	#[serde(rename = "type")]
+	kind: String,
+	#[serde(default)]
+	id: Option<String>,
+	#[serde(default)]
+	payload: Option<serde_json::Value>,
+}
+
+#[allow(opaque_hidden_inferred_bound)]
+pub fn config() -> impl Filter<Extract = impl warp::Reply, Error = warp::Rejection> + Clone {
+	// Set base path
+	let base = warp::path("graphql").and(warp::path::end());
+	// Set opts method
+	let opts = base.and(warp::options()).map(warp::reply);
+	// Set get method
+	let get = base.and(warp::get()).and(session::build()).and_then(schema);
+	// Set post method
+	let post = base
+		.and(warp::post())
+		.and(warp::body::content_length_limit(MAX))
+		.and(warp::body::json())
+		.and(session::build())
+		.and_then(handler);
+	// Set websocket method
+	let ws = base
+		.and(warp::ws())
+		.and(warp::header::optional::<String>("sec-websocket-protocol"))
+		.and(session::build())
+		.map(|ws: Ws, proto: Option<String>, session: Session| {
+			let reply = ws.on_upgrade(move |ws| socket(ws, session));
+			// Accept the subprotocol if the client asked for it
+			match proto {
+				Some(v) if v.split(',').any(|v| v.trim() == PROTOCOL) => {
+					Box::new(warp::reply::with_header(reply, "sec-websocket-protocol", PROTOCOL))
+						as Box<dyn warp::Reply>
+				}
+				_ => Box::new(reply),
+			}
+		});
+	// Specify route
+	opts.or(ws).or(get).or(post)
+}
+
+async fn schema(session: Session) -> Result<impl warp::Reply, warp::Rejection> {
+	// Check the permissions
+	match session.au.is_db() {
+		true => {
+			// Extract the NS header value
+			let nsv = match session.ns {
+				Some(ns) => ns,
+				None => return Err(warp::reject::custom(Error::NoNsHeader)),
+			};
+			// Extract the DB header value
+			let dbv = match session.db {
+				Some(db) => db,
+				None => return Err(warp::reject::custom(Error::NoDbHeader)),
+			};
+			// Generate the schema definition
+			match gql::schema::generate(&nsv, &dbv).await {
+				Ok(v) => Ok(output::text(v)),
+				Err(err) => Err(warp::reject::custom(err)),
+			}
+		}
+		// There was an error with permissions
+		_ => Err(warp::reject::custom(Error::InvalidAuth)),
+	}
+}
+
+async fn handler(req: Request, session: Session) -> Result<impl warp::Reply, warp::Rejection> {
+	// Convert the request variables
+	let vars = variables(req.variables).map_err(warp::reject::custom)?;
+	// Execute the GraphQL request
+	match gql::execute(&req.query, vars, &session).await {
+		Ok(v) => Ok(output::json(&v.into_json())),
+		Err(err) => Err(warp::reject::custom(err)),
+	}
+}
+
+/// Convert the variables of a GraphQL request
+fn variables(v: Option<serde_json::Value>) -> Result<BTreeMap<String, Value>, Error> {
+	match v {
+		Some(v) if !v.is_null() => match surrealdb::sql::json(&v.to_string()) {
+			Ok(Value::Object(v)) => Ok(v.0),
+			_ => Err(Error::Request),
+		},
+		_ => Ok(BTreeMap::new()),
+	}
+}
+
+/// Create a message of the GraphQL over WebSocket protocol
+fn message(kind: &str, id: Option<&str>, payload: Option<serde_json::Value>) -> Message {
+	let mut msg = json!({ "type": kind });
+	if let Some(id) = id {
+		msg["id"] = json!(id);
+	}
+	if let Some(payload) = payload {
+		msg["payload"] = payload;
+	}
+	Message::text(msg.to_string())
+}
+
+/// Serve GraphQL requests over a WebSocket, using the GraphQL over WebSocket
+/// protocol. Queries and mutations return a single result, and each event of
+/// a subscription is delivered from the notifications of its live queries.
+async fn socket(ws: WebSocket, mut session: Session) {
+	// Get a database reference
+	let kvs = DB.get().unwrap();
+	// Use the WebSocket id as the session id
+	let id = Uuid::new_v4();
+	session.id = Some(id.to_string());
+	// Enable real-time live queries
+	session.rt = true;
+	// Register this WebSocket as a session of the datastore
+	let exit = kvs.registry().connect(id, "graphql", &session);
+	// Create a channel for receiving live query notifications
+	let (tx, rx) = channel::new(MAX_CONCURRENT_CALLS);
+	kvs.registry().notify(&id, Some(tx));
+	// Create a channel for sending messages
+	let (chn, mut rcv) = channel::new(MAX_CONCURRENT_CALLS);
+	// Split the socket into send and recv
+	let (mut wtx, wrx) = ws.split();
+	// Stop receiving messages if the session is killed
+	let mut wrx = wrx.take_until(Box::pin(exit.recv()));
+	// The subscriptions which were started, by operation id
+	let subs = Arc::new(Mutex::new(HashMap::<String, gql::Subscription>::new()));
+	// Send messages to the client
+	tokio::task::spawn(async move {
+		while let Some(msg) = rcv.next().await {
+			if let Err(err) = wtx.send(msg).await {
+				trace!(target: LOG, "WebSocket error: {:?}", err);
+				let _ = wtx.close().await;
+				break;
+			}
+		}
+	});
+	// Send the events of the subscriptions to the client
+	tokio::task::spawn({
+		let (subs, chn, session) = (subs.clone(), chn.clone(), session.clone());
+		async move {
+			while let Ok(v) = rx.recv().await {
+				// Notifications are dropped while the channel is full, so
+				// end every subscription which might have missed an event
+				if rx.len() + 1 >= MAX_CONCURRENT_CALLS {
+					let msg = "Events were dropped, as the client is not keeping up";
+					let lives = std::mem::take(&mut *subs.lock().await);
+					for (op, sub) in lives {
+						let err = json!([{ "message": msg }]);
+						let _ = chn.send(message("error", Some(&op), Some(err))).await;
+						if let Err(e) = sub.kill(&session).await {
+							trace!(target: LOG, "Unable to kill the subscription {}: {}", op, e);
+						}
+					}
+					continue;
+				}
+				// Release the subscriptions before sending the events
+				let events = subs
+					.lock()
+					.await
+					.iter()
+					.filter_map(|(op, sub)| sub.response(&v).map(|res| (op.clone(), res)))
+					.collect::<Vec<_>>();
+				for (op, res) in events {
+					let _ = chn.send(message("next", Some(&op), Some(res.into_json()))).await;
+				}
+			}
+		}
+	});
+	// Whether the connection has been initialised
+	let mut init = false;
+	// Get messages from the client
+	while let Some(Ok(msg)) = wrx.next().await {
+		if msg.is_close() {
+			break;
+		}
+		// Control frames are answered by the socket
+		let txt = match msg.to_str() {
+			Ok(v) => v,
+			Err(_) => continue,
+		};
+		// Close the connection if the message is invalid
+		let req = match serde_json::from_str::<Frame>(txt) {
+			Ok(v) => v,
+			Err(_) => {
+				let _ = chn.send(Message::close_with(4400u16, "Invalid message")).await;
+				break;
+			}
+		};
+		match (req.kind.as_str(), req.id) {
+			("connection_init", _) if init => {
+				let _ = chn
+					.send(Message::close_with(4429u16, "Too many initialisation requests"))
+					.await;
+				break;
+			}
+			("connection_init", _) => {
+				init = true;
+				let _ = chn.send(message("connection_ack", None, None)).await;
+			}
+			("ping", _) => {
+				let _ = chn.send(message("pong", None, None)).await;
+			}
+			("pong", _) => (),
+			("subscribe", _) if !init => {
+				let _ = chn.send(Message::close_with(4401u16, "Unauthorized")).await;
+				break;
+			}
+			("subscribe", Some(op)) => {
+				// Parse the request of the operation
+				let res = match req.payload.map(serde_json::from_value::<Request>) {
+					Some(Ok(req)) => variables(req.variables).map(|vars| (req.query, vars)),
+					_ => Err(Error::Request),
+				};
+				let (query, vars) = match res {
+					Ok(v) => v,
+					Err(e) => {
+						let err = json!([{ "message": e.to_string() }]);
+						let _ = chn.send(message("error", Some(&op), Some(err))).await;
+						continue;
+					}
+				};
+				if subs.lock().await.contains_key(&op) {
+					let msg = format!("Subscriber for {op} already exists");
+					let _ = chn.send(Message::close_with(4409u16, msg)).await;
+					break;
+				}
+				let res = match gql::is_subscription(&query, &vars) {
+					// Subscriptions deliver an event for each notification
+					Ok(true) => {
+						// Hold the subscriptions so that no event is missed
+						let mut lock = subs.lock().await;
+						match gql::subscribe(&query, vars, &session).await {
+							Ok(sub) => {
+								lock.insert(op, sub);
+								continue;
+							}
+							Err(e) => Err(e),
+						}
+					}
+					// Queries and mutations deliver a single result
+					Ok(false) => gql::execute(&query, vars, &session).await,
+					Err(e) => Err(e),
+				};
+				match res {
+					Ok(v) => {
+						let _ = chn.send(message("next", Some(&op), Some(v.into_json()))).await;
+						let _ = chn.send(message("complete", Some(&op), None)).await;
+					}
+					Err(e) => {
+						let err = json!([{ "message": e.to_string() }]);
+						let _ = chn.send(message("error", Some(&op), Some(err))).await;
+					}
+				}
+			}
+			("complete", Some(op)) => {
+				let sub = subs.lock().await.remove(&op);
+				if let Some(sub) = sub {
+					if let Err(e) = sub.kill(&session).await {
+						trace!(target: LOG, "Unable to kill the subscription {}: {}", op, e);
+					}
+				}
+			}
+			_ => {
+				let _ = chn.send(Message::close_with(4400u16, "Invalid message")).await;
+				break;
+			}
+		}
+	}
+	// Cancel the running queries, as their results can not be sent
+	kvs.registry().interrupt(&id);
+	// Kill the live queries of the subscriptions
+	let lives = std::mem::take(&mut *subs.lock().await);
+	for (op, sub) in lives {
+		if let Err(e) = sub.kill(&session).await {
+			trace!(target: LOG, "Unable to kill the subscription {}: {}", op, e);
+		}
+	}
+	// Remove this WebSocket from the sessions of the datastore
+	kvs.registry().disconnect(&id);
+}
+
+#[cfg(test)]
+mod tests {
+	use super::*;
+
+	async fn recv(client: &mut warp::test::WsClient) -> serde_json::Value {
+		let msg = client.recv().await.unwrap();
+		serde_json::from_str(msg.to_str().unwrap()).unwrap()
+	}
+
+	#[tokio::test]
+	async fn subscription_over_websocket() {
+		crate::dbs::test().await;
+		let mut client = warp::test::ws()
+			.path("/graphql")
+			.header("sec-websocket-protocol", PROTOCOL)
+			.header("authorization", "Basic cm9vdDpyb290")
+			.header("ns", "test")
+			.header("db", "test")
+			.handshake(config())
+			.await
+			.unwrap();
+		// The connection is acknowledged once initialised
+		client.send_text(json!({ "type": "connection_init" }).to_string()).await;
+		assert_eq!(recv(&mut client).await["type"], "connection_ack");
+		// Start a subscription, and wait until it has started
+		let sub = json!({
+			"type": "subscribe",
+			"id": "1",
+			"payload": { "query": "subscription { person { name } }" },
+		});
+		client.send_text(sub.to_string()).await;
+		client.send_text(json!({ "type": "ping" }).to_string()).await;
+		assert_eq!(recv(&mut client).await["type"], "pong");
+		// Each change is delivered as an event of the subscription
+		let ses = Session::for_kv().with_ns("test").with_db("test");
+		let kvs = DB.get().unwrap();
+		kvs.execute("CREATE person:tobie SET name = 'Tobie'", &ses, None, false).await.unwrap();
+		let msg = recv(&mut client).await;
+		assert_eq!(msg["type"], "next");
+		assert_eq!(msg["id"], "1");
+		assert_eq!(msg["payload"], json!({ "data": { "person": { "name": "Tobie" } } }));
+		// Queries return a single result, and complete
+		let req = json!({
+			"type": "subscribe",
+			"id": "2",
+			"payload": { "query": "{ person { name } }" },
+		});
+		client.send_text(req.to_string()).await;
+		let msg = recv(&mut client).await;
+		assert_eq!(msg["type"], "next");
+		assert_eq!(msg["id"], "2");
+		assert_eq!(msg["payload"], json!({ "data": { "person": [{ "name": "Tobie" }] } }));
+		assert_eq!(recv(&mut client).await["type"], "complete");
+		// No events are delivered once the subscription completes
+		client.send_text(json!({ "type": "complete", "id": "1" }).to_string()).await;
+		client.send_text(json!({ "type": "ping" }).to_string()).await;
+		assert_eq!(recv(&mut client).await["type"], "pong");
+		kvs.execute("CREATE person:jaime SET name = 'Jaime'", &ses, None, false).await.unwrap();
+		client.send_text(json!({ "type": "ping" }).to_string()).await;
+		assert_eq!(recv(&mut client).await["type"], "pong");
+	}
+}
@@ -5,6 +5,7 @@ use surrealdb::cnf::SERVER_NAME;
 const ID: &str = "ID";
 const NS: &str = "NS";
 const DB: &str = "DB";
+const DRY_RUN: &str = "X-Dry-Run";
 const SERVER: &str = "Server";
 const VERSION: &str = "Version";
 
@@ -37,5 +38,6 @@ pub fn cors() -> warp::filters::cors::Builder {
 			NS.parse().unwrap(),
 			DB.parse().unwrap(),
 			ID.parse().unwrap(),
+			DRY_RUN.parse().unwrap(),
 		])
 }
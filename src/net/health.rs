@@ -1,25 +1,179 @@
+use crate::cnf::{PKG_NAME, PKG_VERSION, REPLICA_READY_LAG};
+use crate::dbs::replica::{Role, NODE};
 use crate::dbs::DB;
-use crate::err::Error;
+use serde::Serialize;
+use std::time::Instant;
+use warp::http::StatusCode;
 use warp::Filter;
 
+/// The status of the server and the services which it depends on
+#[derive(Serialize)]
+struct Health {
+	status: Status,
+	version: String,
+	storage: Storage,
+	#[serde(skip_serializing_if = "Option::is_none")]
+	replication: Option<Replication>,
+	jobs: Jobs,
+}
+
+#[derive(Clone, Copy, Eq, PartialEq, Serialize)]
+#[serde(rename_all = "lowercase")]
+enum Status {
+	Pass,
+	Fail,
+}
+
+/// The connectivity of the storage engine
+#[derive(Serialize)]
+struct Storage {
+	status: Status,
+	// How long a transaction took to start, in milliseconds
+	latency: f64,
+	#[serde(skip_serializing_if = "Option::is_none")]
+	error: Option<String>,
+}
+
+/// The replication state of this node, if replication is enabled
+#[derive(Serialize)]
+struct Replication {
+	status: Status,
+	role: &'static str,
+	#[serde(skip_serializing_if = "Option::is_none")]
+	leader: Option<String>,
+	lag: u64,
+}
+
+/// The work which is waiting to be done in the background
+#[derive(Serialize)]
+struct Jobs {
+	indexes: usize,
+	webhooks: usize,
+	transactions: usize,
+}
+
 #[allow(opaque_hidden_inferred_bound)]
 pub fn config() -> impl Filter<Extract = impl warp::Reply, Error = warp::Rejection> + Clone {
-	warp::path("health").and(warp::path::end()).and(warp::get()).and_then(handler)
+	// Set health method
+	let health = warp::path("health").and(warp::path::end()).and(warp::get()).and_then(health);
+	// Set ready method
+	let ready = warp::path("ready").and(warp::path::end()).and(warp::get()).and_then(ready);
+	// Specify route
+	health.or(ready)
+}
+
+/// Check that the server is running, and can reach its storage engine
+async fn health() -> Result<impl warp::Reply, warp::Rejection> {
+	let res = check().await;
+	let status = res.storage.status;
+	Ok(reply(res, status))
 }
 
-async fn handler() -> Result<impl warp::Reply, warp::Rejection> {
+/// Check that the server is able to serve queries, so that traffic is
+/// only routed to a node which is not shutting down, which can reach its
+/// storage engine, and which is not too far behind its replication leader
+async fn ready() -> Result<impl warp::Reply, warp::Rejection> {
+	let res = check().await;
+	let status = res.status;
+	Ok(reply(res, status))
+}
+
+async fn check() -> Health {
 	// Get the datastore reference
 	let db = DB.get().unwrap();
 	// Attempt to open a transaction
-	match db.transaction(false, false).await {
-		// The transaction failed to start
-		Err(_) => Err(warp::reject::custom(Error::InvalidStorage)),
-		// The transaction was successful
+	let now = Instant::now();
+	let storage = match db.transaction(false, false).await {
 		Ok(mut tx) => {
 			// Cancel the transaction
 			let _ = tx.cancel().await;
-			// Return the response
-			Ok(warp::reply())
+			Storage {
+				status: Status::Pass,
+				latency: now.elapsed().as_secs_f64() * 1000.0,
+				error: None,
+			}
+		}
+		Err(e) => Storage {
+			status: Status::Fail,
+			latency: now.elapsed().as_secs_f64() * 1000.0,
+			error: Some(e.to_string()),
+		},
+	};
+	// Check the replication state
+	let replication = NODE.get().map(|node| {
+		let (role, lag, leader) = (node.role(), node.lag(), node.leader());
+		Replication {
+			// A follower is ready once it is following a leader, and has caught up
+			status: match role {
+				Role::Leader => Status::Pass,
+				Role::Follower if leader.is_some() && lag <= REPLICA_READY_LAG => Status::Pass,
+				_ => Status::Fail,
+			},
+			role: match role {
+				Role::Follower => "follower",
+				Role::Candidate => "candidate",
+				Role::Leader => "leader",
+			},
+			leader,
+			lag,
 		}
+	});
+	// Count the pending background jobs
+	let jobs = Jobs {
+		indexes: db.pending_indexes().await.unwrap_or_default(),
+		webhooks: db.webhooks().pending(),
+		transactions: db.transactions().len(),
+	};
+	// Check if every dependency passed
+	let status = match (storage.status, replication.as_ref().map(|v| v.status), db.is_closing()) {
+		(Status::Pass, None | Some(Status::Pass), false) => Status::Pass,
+		_ => Status::Fail,
+	};
+	Health {
+		status,
+		version: format!("{PKG_NAME}-{}", *PKG_VERSION),
+		storage,
+		replication,
+		jobs,
+	}
+}
+
+fn reply(res: Health, status: Status) -> impl warp::Reply {
+	let code = match status {
+		Status::Pass => StatusCode::OK,
+		Status::Fail => StatusCode::SERVICE_UNAVAILABLE,
+	};
+	warp::reply::with_status(warp::reply::json(&res), code)
+}
+
+#[cfg(test)]
+mod tests {
+	use super::*;
+
+	#[tokio::test]
+	async fn health_reports_storage() {
+		crate::dbs::test().await;
+		let res = warp::test::request().path("/health").reply(&config()).await;
+		assert_eq!(res.status(), StatusCode::OK);
+		let res: serde_json::Value = serde_json::from_slice(res.body()).unwrap();
+		assert_eq!(res["status"], "pass");
+		assert_eq!(res["storage"]["status"], "pass");
+		assert!(res["storage"]["latency"].is_number());
+		assert!(res["storage"].get("error").is_none());
+		assert!(res.get("replication").is_none());
+		assert!(res["jobs"]["transactions"].is_number());
+	}
+
+	#[tokio::test]
+	async fn ready_reports_status() {
+		crate::dbs::test().await;
+		let res = warp::test::request().path("/ready").reply(&config()).await;
+		assert_eq!(res.status(), StatusCode::OK);
+		let res: serde_json::Value = serde_json::from_slice(res.body()).unwrap();
+		assert_eq!(res["status"], "pass");
+		assert_eq!(res["version"], format!("{PKG_NAME}-{}", *PKG_VERSION));
+		// Only the GET method is routed
+		let res = warp::test::request().method("POST").path("/ready").reply(&config()).await;
+		assert_eq!(res.status(), StatusCode::METHOD_NOT_ALLOWED);
 	}
 }
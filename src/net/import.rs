@@ -1,26 +1,49 @@
 use crate::cli::CF;
 use crate::dbs::DB;
 use crate::err::Error;
+use crate::net::csv;
 use crate::net::input::bytes_to_utf8;
 use crate::net::output;
+use crate::net::params::Param;
 use crate::net::session;
-use bytes::Bytes;
+use bytes::{Buf, BufMut, Bytes};
+use futures::{Stream, StreamExt};
 use surrealdb::dbs::Session;
+use surrealdb::sql::statements::InsertStatement;
+use surrealdb::sql::{Data, Query, Statement, Statements, Table, Value};
 use warp::http;
 use warp::Filter;
 
+const LOG: &str = "surrealdb::net::import";
+
 const MAX: u64 = 1024 * 1024 * 1024 * 4; // 4 GiB
 
+const BATCH: usize = 1000;
+
 #[allow(opaque_hidden_inferred_bound)]
 pub fn config() -> impl Filter<Extract = impl warp::Reply, Error = warp::Rejection> + Clone {
-	warp::path("import")
+	// Set base path
+	let base = warp::path("import");
+	// Set database import method
+	let full = base
 		.and(warp::path::end())
 		.and(warp::post())
 		.and(warp::header::<String>(http::header::ACCEPT.as_str()))
 		.and(warp::body::content_length_limit(MAX))
 		.and(warp::body::bytes())
 		.and(session::build())
-		.and_then(handler)
+		.and_then(handler);
+	// Set table import method
+	let table = base
+		.and(warp::path::param())
+		.and(warp::path::end())
+		.and(warp::post())
+		.and(warp::header::<String>(http::header::CONTENT_TYPE.as_str()))
+		.and(session::build())
+		.and(warp::body::stream())
+		.and_then(table);
+	// Specify route
+	full.or(table)
 }
 
 async fn handler(
@@ -42,8 +65,8 @@ async fn handler(
 				Ok(res) => match output.as_ref() {
 					// Simple serialization
 					"application/json" => Ok(output::json(&output::simplify(res))),
-					"application/cbor" => Ok(output::cbor(&output::simplify(res))),
-					"application/pack" => Ok(output::pack(&output::simplify(res))),
+					"application/cbor" => Ok(output::cbor(&output::binary(res))),
+					"application/pack" => Ok(output::pack(&output::binary(res))),
 					// Internal serialization
 					"application/bung" => Ok(output::full(&res)),
 					// Return nothing
@@ -58,3 +81,161 @@ async fn handler(
 		_ => Err(warp::reject::custom(Error::InvalidAuth)),
 	}
 }
+
+async fn table<S, B>(
+	table: Param,
+	input: String,
+	session: Session,
+	mut body: S,
+) -> Result<impl warp::Reply, warp::Rejection>
+where
+	S: Stream<Item = Result<B, warp::Error>> + Unpin,
+	B: Buf,
+{
+	// Check the permissions
+	if !session.au.is_db() {
+		return Err(warp::reject::custom(Error::InvalidAuth));
+	}
+	// Check the submitted input format
+	let mut import = match input.as_ref() {
+		"application/x-ndjson" => Import::new(&table, false),
+		"text/csv" => Import::new(&table, true),
+		_ => return Err(warp::reject::custom(Error::InvalidType)),
+	};
+	// Process the request body line by line
+	let mut buf: Vec<u8> = vec![];
+	while let Some(chunk) = body.next().await {
+		let chunk = chunk.map_err(|_| warp::reject::custom(Error::Request))?;
+		buf.put(chunk);
+		while let Some(pos) = buf.iter().position(|&v| v == b'\n') {
+			let line: Vec<u8> = buf.drain(..=pos).collect();
+			import.line(&session, &line).await?;
+		}
+	}
+	// Process any remaining data
+	if !buf.is_empty() {
+		buf.push(b'\n');
+		import.line(&session, &buf).await?;
+	}
+	import.flush(&session).await?;
+	// Return the import summary
+	Ok(output::json(&output::simplify(map! {
+		String::from("table") => Value::from(table.0),
+		String::from("records") => Value::from(import.records),
+		String::from("batches") => Value::from(import.batches),
+	})))
+}
+
+/// The state of a table import
+struct Import {
+	table: String,
+	csv: bool,
+	cols: Option<Vec<String>>,
+	line: String,
+	batch: Vec<Value>,
+	records: usize,
+	batches: usize,
+}
+
+impl Import {
+	fn new(table: &str, csv: bool) -> Self {
+		Import {
+			table: table.to_owned(),
+			csv,
+			cols: None,
+			line: String::new(),
+			batch: Vec::with_capacity(BATCH),
+			records: 0,
+			batches: 0,
+		}
+	}
+	/// Process a single line of input
+	async fn line(&mut self, session: &Session, line: &[u8]) -> Result<(), warp::Rejection> {
+		let line = std::str::from_utf8(line).map_err(|_| warp::reject::custom(Error::Request))?;
+		let record = match self.csv {
+			// Each line is a JSON object
+			false => {
+				if line.trim().is_empty() {
+					return Ok(());
+				}
+				match surrealdb::sql::json(line) {
+					Ok(v) if v.is_object() => v,
+					_ => return Err(warp::reject::custom(Error::Request)),
+				}
+			}
+			// Each record is a CSV row, which might span lines
+			true => {
+				self.line.push_str(line);
+				let cells = match csv::parse(&self.line) {
+					Some(v) => v,
+					None => return Ok(()),
+				};
+				self.line.clear();
+				// The first row contains the column names
+				let cols = match &self.cols {
+					Some(v) => v,
+					None => {
+						self.cols = Some(cells);
+						return Ok(());
+					}
+				};
+				Value::from(
+					cols.iter()
+						.zip(cells.iter())
+						.map(|(k, v)| (k.to_owned(), csv::value(v)))
+						.filter(|(_, v)| !v.is_none())
+						.collect::<std::collections::BTreeMap<_, _>>(),
+				)
+			}
+		};
+		let record = self.record(record);
+		self.batch.push(record);
+		// Import the batch when full
+		if self.batch.len() >= BATCH {
+			self.flush(session).await?;
+		}
+		Ok(())
+	}
+	/// Convert any exported record id into a record id
+	fn record(&self, mut v: Value) -> Value {
+		if let Value::Object(o) = &mut v {
+			if let Some(Value::Strand(id)) = o.get("id") {
+				if id.starts_with(&format!("{}:", self.table)) {
+					if let Ok(id) = surrealdb::sql::thing(id.as_str()) {
+						o.insert(String::from("id"), Value::Thing(id));
+					}
+				}
+			}
+		}
+		v
+	}
+	/// Import the current batch in a single transaction
+	async fn flush(&mut self, session: &Session) -> Result<(), warp::Rejection> {
+		if self.batch.is_empty() {
+			return Ok(());
+		}
+		// Get the datastore reference
+		let db = DB.get().unwrap();
+		// Get local copy of options
+		let opt = CF.get().unwrap();
+		// Insert the records into the table
+		let num = self.batch.len();
+		let stm = Statement::Insert(InsertStatement {
+			into: Table::from(self.table.as_str()),
+			data: Data::SingleExpression(Value::from(std::mem::take(&mut self.batch))),
+			output: Some(surrealdb::sql::Output::None),
+			..Default::default()
+		});
+		let ast = Query(Statements(vec![stm]));
+		let mut res = db
+			.process(ast, session, None, opt.strict)
+			.await
+			.map_err(|e| warp::reject::custom(Error::from(e)))?;
+		res.remove(0).result.map_err(|e| warp::reject::custom(Error::from(e)))?;
+		// Report the import progress
+		self.records += num;
+		self.batches += 1;
+		info!(target: LOG, "Imported {} records into table {}", self.records, self.table);
+		Ok(())
+	}
+}
@@ -1,6 +1,134 @@
 use crate::err::Error;
 use bytes::Bytes;
+use serde::de::{self, Deserialize, Deserializer, MapAccess, SeqAccess, Visitor};
+use std::collections::BTreeMap;
+use std::fmt;
+use surrealdb::sql::Value;
 
 pub(crate) fn bytes_to_utf8(bytes: &Bytes) -> Result<&str, warp::Rejection> {
 	std::str::from_utf8(bytes).map_err(|_| warp::reject::custom(Error::Request))
 }
+
+/// Parse a request body into a value, using the format of its content type.
+/// CBOR and MessagePack bodies are decoded, and anything else is parsed as
+/// a SurrealQL value, which is a superset of JSON.
+pub(crate) fn bytes_to_value(kind: Option<&str>, bytes: &Bytes) -> Result<Value, Error> {
+	match kind.and_then(|v| v.split(';').next()).map(str::trim) {
+		Some("application/cbor") => from_cbor(bytes),
+		Some("application/pack") => from_pack(bytes),
+		_ => match std::str::from_utf8(bytes) {
+			Ok(v) => surrealdb::sql::value(v).map_err(|_| Error::Request),
+			Err(_) => Err(Error::Request),
+		},
+	}
+}
+
+/// Decode a CBOR value
+pub(crate) fn from_cbor(bytes: &[u8]) -> Result<Value, Error> {
+	serde_cbor::from_slice::<Binary>(bytes).map(|v| v.0).map_err(|_| Error::Request)
+}
+
+/// Decode a MessagePack value
+pub(crate) fn from_pack(bytes: &[u8]) -> Result<Value, Error> {
+	serde_pack::from_slice::<Binary>(bytes).map(|v| v.0).map_err(|_| Error::Request)
+}
+
+/// A value which is decoded from a self-describing binary format
+struct Binary(Value);
+
+impl<'de> Deserialize<'de> for Binary {
+	fn deserialize<D: Deserializer<'de>>(deserializer: D) -> Result<Self, D::Error> {
+		deserializer.deserialize_any(BinaryVisitor)
+	}
+}
+
+struct BinaryVisitor;
+
+impl<'de> Visitor<'de> for BinaryVisitor {
+	type Value = Binary;
+
+	fn expecting(&self, formatter: &mut fmt::Formatter) -> fmt::Result {
+		formatter.write_str("a value")
+	}
+
+	fn visit_unit<E: de::Error>(self) -> Result<Self::Value, E> {
+		Ok(Binary(Value::Null))
+	}
+
+	fn visit_none<E: de::Error>(self) -> Result<Self::Value, E> {
+		Ok(Binary(Value::Null))
+	}
+
+	fn visit_some<D: Deserializer<'de>>(self, deserializer: D) -> Result<Self::Value, D::Error> {
+		Binary::deserialize(deserializer)
+	}
+
+	fn visit_bool<E: de::Error>(self, v: bool) -> Result<Self::Value, E> {
+		Ok(Binary(Value::from(v)))
+	}
+
+	fn visit_i64<E: de::Error>(self, v: i64) -> Result<Self::Value, E> {
+		Ok(Binary(Value::from(v)))
+	}
+
+	fn visit_u64<E: de::Error>(self, v: u64) -> Result<Self::Value, E> {
+		Ok(Binary(Value::from(v)))
+	}
+
+	fn visit_f64<E: de::Error>(self, v: f64) -> Result<Self::Value, E> {
+		Ok(Binary(Value::from(v)))
+	}
+
+	fn visit_str<E: de::Error>(self, v: &str) -> Result<Self::Value, E> {
+		Ok(Binary(Value::from(v)))
+	}
+
+	fn visit_string<E: de::Error>(self, v: String) -> Result<Self::Value, E> {
+		Ok(Binary(Value::from(v)))
+	}
+
+	fn visit_bytes<E: de::Error>(self, v: &[u8]) -> Result<Self::Value, E> {
+		Ok(Binary(Value::Bytes(v.to_vec().into())))
+	}
+
+	fn visit_byte_buf<E: de::Error>(self, v: Vec<u8>) -> Result<Self::Value, E> {
+		Ok(Binary(Value::Bytes(v.into())))
+	}
+
+	fn visit_seq<A: SeqAccess<'de>>(self, mut seq: A) -> Result<Self::Value, A::Error> {
+		let mut out = Vec::with_capacity(seq.size_hint().unwrap_or_default());
+		while let Some(Binary(v)) = seq.next_element()? {
+			out.push(v);
+		}
+		Ok(Binary(Value::from(out)))
+	}
+
+	fn visit_map<A: MapAccess<'de>>(self, mut map: A) -> Result<Self::Value, A::Error> {
+		let mut out = BTreeMap::new();
+		while let Some((k, Binary(v))) = map.next_entry::<String, Binary>()? {
+			out.insert(k, v);
+		}
+		Ok(Binary(Value::from(out)))
+	}
+}
+
+#[cfg(test)]
+mod tests {
+	use super::*;
+	use crate::net::output;
+
+	#[test]
+	fn binary_round_trip() {
+		let val = Value::from(map! {
+			String::from("age") => Value::from(13),
+			String::from("data") => Value::Bytes(vec![1, 2, 3].into()),
+			String::from("name") => Value::from("Tobie"),
+			String::from("tags") => Value::from(vec![Value::from(1.5), Value::from(true), Value::Null]),
+		});
+		let cbor = serde_cbor::to_vec(&output::binary(&val)).unwrap();
+		assert_eq!(from_cbor(&cbor).unwrap(), val);
+		let pack = serde_pack::to_vec(&output::binary(&val)).unwrap();
+		assert_eq!(from_pack(&pack).unwrap(), val);
+		assert!(from_cbor(b"invalid").is_err());
+	}
+}
@@ -1,7 +1,7 @@
 use crate::cli::CF;
 use crate::dbs::DB;
 use crate::err::Error;
-use crate::net::input::bytes_to_utf8;
+use crate::net::input::bytes_to_value;
 use crate::net::output;
 use crate::net::params::{Param, Params};
 use crate::net::session;
@@ -48,6 +48,7 @@ pub fn config() -> impl Filter<Extract = impl warp::Reply, Error = warp::Rejecti
 		.and(warp::post())
 		.and(warp::header::<String>(http::header::ACCEPT.as_str()))
 		.and(path!("key" / Param).and(warp::path::end()))
+		.and(warp::header::optional::<String>(http::header::CONTENT_TYPE.as_str()))
 		.and(warp::body::content_length_limit(MAX))
 		.and(warp::body::bytes())
 		.and(warp::query())
@@ -58,6 +59,7 @@ pub fn config() -> impl Filter<Extract = impl warp::Reply, Error = warp::Rejecti
 		.and(warp::put())
 		.and(warp::header::<String>(http::header::ACCEPT.as_str()))
 		.and(path!("key" / Param).and(warp::path::end()))
+		.and(warp::header::optional::<String>(http::header::CONTENT_TYPE.as_str()))
 		.and(warp::body::content_length_limit(MAX))
 		.and(warp::body::bytes())
 		.and(warp::query())
@@ -68,6 +70,7 @@ pub fn config() -> impl Filter<Extract = impl warp::Reply, Error = warp::Rejecti
 		.and(warp::patch())
 		.and(warp::header::<String>(http::header::ACCEPT.as_str()))
 		.and(path!("key" / Param).and(warp::path::end()))
+		.and(warp::header::optional::<String>(http::header::CONTENT_TYPE.as_str()))
 		.and(warp::body::content_length_limit(MAX))
 		.and(warp::body::bytes())
 		.and(warp::query())
@@ -100,6 +103,7 @@ pub fn config() -> impl Filter<Extract = impl warp::Reply, Error = warp::Rejecti
 		.and(warp::post())
 		.and(warp::header::<String>(http::header::ACCEPT.as_str()))
 		.and(path!("key" / Param / Param).and(warp::path::end()))
+		.and(warp::header::optional::<String>(http::header::CONTENT_TYPE.as_str()))
 		.and(warp::body::content_length_limit(MAX))
 		.and(warp::body::bytes())
 		.and(warp::query())
@@ -110,6 +114,7 @@ pub fn config() -> impl Filter<Extract = impl warp::Reply, Error = warp::Rejecti
 		.and(warp::put())
 		.and(warp::header::<String>(http::header::ACCEPT.as_str()))
 		.and(path!("key" / Param / Param).and(warp::path::end()))
+		.and(warp::header::optional::<String>(http::header::CONTENT_TYPE.as_str()))
 		.and(warp::body::content_length_limit(MAX))
 		.and(warp::body::bytes())
 		.and(warp::query())
@@ -120,6 +125,7 @@ pub fn config() -> impl Filter<Extract = impl warp::Reply, Error = warp::Rejecti
 		.and(warp::patch())
 		.and(warp::header::<String>(http::header::ACCEPT.as_str()))
 		.and(path!("key" / Param / Param).and(warp::path::end()))
+		.and(warp::header::optional::<String>(http::header::CONTENT_TYPE.as_str()))
 		.and(warp::body::content_length_limit(MAX))
 		.and(warp::body::bytes())
 		.and(warp::query())
@@ -173,8 +179,8 @@ async fn select_all(
 		Ok(ref res) => match output.as_ref() {
 			// Simple serialization
 			"application/json" => Ok(output::json(&output::simplify(res))),
-			"application/cbor" => Ok(output::cbor(&output::simplify(res))),
-			"application/pack" => Ok(output::pack(&output::simplify(res))),
+			"application/cbor" => Ok(output::cbor(&output::binary(res))),
+			"application/pack" => Ok(output::pack(&output::binary(res))),
 			// Internal serialization
 			"application/bung" => Ok(output::full(&res)),
 			// An incorrect content-type was requested
@@ -188,6 +194,7 @@ async fn select_all(
 async fn create_all(
 	output: String,
 	table: Param,
+	input: Option<String>,
 	body: Bytes,
 	params: Params,
 	session: Session,
@@ -196,10 +203,8 @@ async fn create_all(
 	let db = DB.get().unwrap();
 	// Get local copy of options
 	let opt = CF.get().unwrap();
-	// Convert the HTTP request body
-	let data = bytes_to_utf8(&body)?;
-	// Parse the request body as JSON
-	match surrealdb::sql::value(data) {
+	// Parse the request body using its content type
+	match bytes_to_value(input.as_deref(), &body) {
 		Ok(data) => {
 			// Specify the request statement
 			let sql = "CREATE type::table($table) CONTENT $data";
@@ -214,8 +219,8 @@ async fn create_all(
 				Ok(res) => match output.as_ref() {
 					// Simple serialization
 					"application/json" => Ok(output::json(&output::simplify(res))),
-					"application/cbor" => Ok(output::cbor(&output::simplify(res))),
-					"application/pack" => Ok(output::pack(&output::simplify(res))),
+					"application/cbor" => Ok(output::cbor(&output::binary(res))),
+					"application/pack" => Ok(output::pack(&output::binary(res))),
 					// Internal serialization
 					"application/bung" => Ok(output::full(&res)),
 					// An incorrect content-type was requested
@@ -232,6 +237,7 @@ async fn create_all(
 async fn update_all(
 	output: String,
 	table: Param,
+	input: Option<String>,
 	body: Bytes,
 	params: Params,
 	session: Session,
@@ -240,10 +246,8 @@ async fn update_all(
 	let db = DB.get().unwrap();
 	// Get local copy of options
 	let opt = CF.get().unwrap();
-	// Convert the HTTP request body
-	let data = bytes_to_utf8(&body)?;
-	// Parse the request body as JSON
-	match surrealdb::sql::value(data) {
+	// Parse the request body using its content type
+	match bytes_to_value(input.as_deref(), &body) {
 		Ok(data) => {
 			// Specify the request statement
 			let sql = "UPDATE type::table($table) CONTENT $data";
@@ -258,8 +262,8 @@ async fn update_all(
 				Ok(res) => match output.as_ref() {
 					// Simple serialization
 					"application/json" => Ok(output::json(&output::simplify(res))),
-					"application/cbor" => Ok(output::cbor(&output::simplify(res))),
-					"application/pack" => Ok(output::pack(&output::simplify(res))),
+					"application/cbor" => Ok(output::cbor(&output::binary(res))),
+					"application/pack" => Ok(output::pack(&output::binary(res))),
 					// Internal serialization
 					"application/bung" => Ok(output::full(&res)),
 					// An incorrect content-type was requested
@@ -276,6 +280,7 @@ async fn update_all(
 async fn modify_all(
 	output: String,
 	table: Param,
+	input: Option<String>,
 	body: Bytes,
 	params: Params,
 	session: Session,
@@ -284,10 +289,8 @@ async fn modify_all(
 	let db = DB.get().unwrap();
 	// Get local copy of options
 	let opt = CF.get().unwrap();
-	// Convert the HTTP request body
-	let data = bytes_to_utf8(&body)?;
-	// Parse the request body as JSON
-	match surrealdb::sql::value(data) {
+	// Parse the request body using its content type
+	match bytes_to_value(input.as_deref(), &body) {
 		Ok(data) => {
 			// Specify the request statement
 			let sql = "UPDATE type::table($table) MERGE $data";
@@ -302,8 +305,8 @@ async fn modify_all(
 				Ok(res) => match output.as_ref() {
 					// Simple serialization
 					"application/json" => Ok(output::json(&output::simplify(res))),
-					"application/cbor" => Ok(output::cbor(&output::simplify(res))),
-					"application/pack" => Ok(output::pack(&output::simplify(res))),
+					"application/cbor" => Ok(output::cbor(&output::binary(res))),
+					"application/pack" => Ok(output::pack(&output::binary(res))),
 					// Internal serialization
 					"application/bung" => Ok(output::full(&res)),
 					// An incorrect content-type was requested
@@ -339,8 +342,8 @@ async fn delete_all(
 		Ok(res) => match output.as_ref() {
 			// Simple serialization
 			"application/json" => Ok(output::json(&output::simplify(res))),
-			"application/cbor" => Ok(output::cbor(&output::simplify(res))),
-			"application/pack" => Ok(output::pack(&output::simplify(res))),
+			"application/cbor" => Ok(output::cbor(&output::binary(res))),
+			"application/pack" => Ok(output::pack(&output::binary(res))),
 			// Internal serialization
 			"application/bung" => Ok(output::full(&res)),
 			// An incorrect content-type was requested
@@ -382,8 +385,8 @@ async fn select_one(
 		Ok(res) => match output.as_ref() {
 			// Simple serialization
 			"application/json" => Ok(output::json(&output::simplify(res))),
-			"application/cbor" => Ok(output::cbor(&output::simplify(res))),
-			"application/pack" => Ok(output::pack(&output::simplify(res))),
+			"application/cbor" => Ok(output::cbor(&output::binary(res))),
+			"application/pack" => Ok(output::pack(&output::binary(res))),
 			// Internal serialization
 			"application/bung" => Ok(output::full(&res)),
 			// An incorrect content-type was requested
@@ -398,6 +401,7 @@ async fn create_one(
 	output: String,
 	table: Param,
 	id: Param,
+	input: Option<String>,
 	body: Bytes,
 	params: Params,
 	session: Session,
@@ -406,15 +410,13 @@ async fn create_one(
 	let db = DB.get().unwrap();
 	// Get local copy of options
 	let opt = CF.get().unwrap();
-	// Convert the HTTP request body
-	let data = bytes_to_utf8(&body)?;
 	// Parse the Record ID as a SurrealQL value
 	let rid = match surrealdb::sql::json(&id) {
 		Ok(id) => id,
 		Err(_) => Value::from(id),
 	};
-	// Parse the request body as JSON
-	match surrealdb::sql::value(data) {
+	// Parse the request body using its content type
+	match bytes_to_value(input.as_deref(), &body) {
 		Ok(data) => {
 			// Specify the request statement
 			let sql = "CREATE type::thing($table, $id) CONTENT $data";
@@ -430,8 +432,8 @@ async fn create_one(
 				Ok(res) => match output.as_ref() {
 					// Simple serialization
 					"application/json" => Ok(output::json(&output::simplify(res))),
-					"application/cbor" => Ok(output::cbor(&output::simplify(res))),
-					"application/pack" => Ok(output::pack(&output::simplify(res))),
+					"application/cbor" => Ok(output::cbor(&output::binary(res))),
+					"application/pack" => Ok(output::pack(&output::binary(res))),
 					// Internal serialization
 					"application/bung" => Ok(output::full(&res)),
 					// An incorrect content-type was requested
@@ -449,6 +451,7 @@ async fn update_one(
 	output: String,
 	table: Param,
 	id: Param,
+	input: Option<String>,
 	body: Bytes,
 	params: Params,
 	session: Session,
@@ -457,15 +460,13 @@ async fn update_one(
 	let db = DB.get().unwrap();
 	// Get local copy of options
 	let opt = CF.get().unwrap();
-	// Convert the HTTP request body
-	let data = bytes_to_utf8(&body)?;
 	// Parse the Record ID as a SurrealQL value
 	let rid = match surrealdb::sql::json(&id) {
 		Ok(id) => id,
 		Err(_) => Value::from(id),
 	};
-	// Parse the request body as JSON
-	match surrealdb::sql::value(data) {
+	// Parse the request body using its content type
+	match bytes_to_value(input.as_deref(), &body) {
 		Ok(data) => {
 			// Specify the request statement
 			let sql = "UPDATE type::thing($table, $id) CONTENT $data";
@@ -481,8 +482,8 @@ async fn update_one(
 				Ok(res) => match output.as_ref() {
 					// Simple serialization
 					"application/json" => Ok(output::json(&output::simplify(res))),
-					"application/cbor" => Ok(output::cbor(&output::simplify(res))),
-					"application/pack" => Ok(output::pack(&output::simplify(res))),
+					"application/cbor" => Ok(output::cbor(&output::binary(res))),
+					"application/pack" => Ok(output::pack(&output::binary(res))),
 					// Internal serialization
 					"application/bung" => Ok(output::full(&res)),
 					// An incorrect content-type was requested
@@ -500,6 +501,7 @@ async fn modify_one(
 	output: String,
 	table: Param,
 	id: Param,
+	input: Option<String>,
 	body: Bytes,
 	params: Params,
 	session: Session,
@@ -508,15 +510,13 @@ async fn modify_one(
 	let db = DB.get().unwrap();
 	// Get local copy of options
 	let opt = CF.get().unwrap();
-	// Convert the HTTP request body
-	let data = bytes_to_utf8(&body)?;
 	// Parse the Record ID as a SurrealQL value
 	let rid = match surrealdb::sql::json(&id) {
 		Ok(id) => id,
 		Err(_) => Value::from(id),
 	};
-	// Parse the request body as JSON
-	match surrealdb::sql::value(data) {
+	// Parse the request body using its content type
+	match bytes_to_value(input.as_deref(), &body) {
 		Ok(data) => {
 			// Specify the request statement
 			let sql = "UPDATE type::thing($table, $id) MERGE $data";
@@ -532,8 +532,8 @@ async fn modify_one(
 				Ok(res) => match output.as_ref() {
 					// Simple serialization
 					"application/json" => Ok(output::json(&output::simplify(res))),
-					"application/cbor" => Ok(output::cbor(&output::simplify(res))),
-					"application/pack" => Ok(output::pack(&output::simplify(res))),
+					"application/cbor" => Ok(output::cbor(&output::binary(res))),
+					"application/pack" => Ok(output::pack(&output::binary(res))),
 					// Internal serialization
 					"application/bung" => Ok(output::full(&res)),
 					// An incorrect content-type was requested
@@ -576,8 +576,8 @@ async fn delete_one(
 		Ok(res) => match output.as_ref() {
 			// Simple serialization
 			"application/json" => Ok(output::json(&output::simplify(res))),
-			"application/cbor" => Ok(output::cbor(&output::simplify(res))),
-			"application/pack" => Ok(output::pack(&output::simplify(res))),
+			"application/cbor" => Ok(output::cbor(&output::binary(res))),
+			"application/pack" => Ok(output::pack(&output::binary(res))),
 			// Internal serialization
 			"application/bung" => Ok(output::full(&res)),
 			// An incorrect content-type was requested
@@ -0,0 +1,132 @@
+use crate::dbs::DB;
+use crate::err::Error;
+use crate::net::params::Param;
+use crate::net::session;
+use futures::stream::{self, StreamExt};
+use serde::Deserialize;
+use serde_json::Value as Json;
+use std::convert::Infallible;
+use std::sync::Arc;
+use surrealdb::changes::Change;
+use surrealdb::dbs::Session;
+use surrealdb::iam::verify::token;
+use surrealdb::sql::Cond;
+use warp::sse::Event;
+use warp::Filter;
+
+const LOG: &str = "surrealdb::net::live";
+
+#[derive(Default, Deserialize, Debug, Clone)]
+struct Query {
+	/// The namespace, for clients which can not set headers
+	pub ns: Option<String>,
+	/// The database, for clients which can not set headers
+	pub db: Option<String>,
+	/// The authentication token, for clients which can not set headers
+	pub token: Option<String>,
+	/// The condition which changed records must match
+	#[serde(rename = "where")]
+	pub cond: Option<String>,
+}
+
+#[allow(opaque_hidden_inferred_bound)]
+pub fn config() -> impl Filter<Extract = impl warp::Reply, Error = warp::Rejection> + Clone {
+	warp::path("live")
+		.and(warp::path::param())
+		.and(warp::path::end())
+		.and(warp::get())
+		.and(warp::header::optional::<u64>("last-event-id"))
+		.and(warp::query())
+		.and(session::build())
+		.and_then(handler)
+}
+
+async fn handler(
+	table: Param,
+	last: Option<u64>,
+	query: Query,
+	mut session: Session,
+) -> Result<impl warp::Reply, warp::Rejection> {
+	// Get the datastore reference
+	let db = DB.get().unwrap();
+	// Browsers can not set headers on an EventSource
+	if session.ns.is_none() {
+		session.ns = query.ns;
+	}
+	if session.db.is_none() {
+		session.db = query.db;
+	}
+	if let Some(v) = query.token {
+		token(db, &mut session, v).await.map_err(Error::from)?;
+	}
+	// Check that a database is selected
+	match (&session.ns, &session.db) {
+		(None, _) => return Err(warp::reject::custom(Error::NoNsHeader)),
+		(_, None) => return Err(warp::reject::custom(Error::NoDbHeader)),
+		_ => (),
+	}
+	// Parse the condition which changed records must match
+	let cond = match query.cond {
+		Some(v) => Some(Cond(surrealdb::sql::value(&v).map_err(Error::from)?)),
+		None => None,
+	};
+	// Resume from the last event which the client received
+	let (replay, changes) = match last {
+		Some(seq) => db.changes_since(seq).await,
+		None => (Some(vec![]), db.changes().await),
+	};
+	// Tell the client to resynchronise if some events were missed
+	let reset = match replay {
+		Some(_) => None,
+		None => Some(Ok(Event::default().event("reset").data(""))),
+	};
+	// Send the replayed changes, and then any subsequent changes
+	let session = Arc::new(session);
+	let cond = Arc::new(cond);
+	let events = stream::iter(replay.unwrap_or_default()).chain(changes).filter_map(move |v| {
+		let session = session.clone();
+		let table = table.clone();
+		let cond = cond.clone();
+		async move {
+			let cond = cond.as_ref().as_ref();
+			event(&session, &table, cond, v).await.map(Ok::<_, Infallible>)
+		}
+	});
+	// Tell the client once the server is shutting down, so that it resumes
+	// from the last event which it received once the server restarts
+	let shutdown = stream::once(async move {
+		db.is_closing().then(|| Ok(Event::default().event("shutdown").data("")))
+	})
+	.filter_map(futures::future::ready);
+	let events = stream::iter(reset).chain(events).chain(shutdown);
+	// Keep the connection open through any proxies
+	Ok(warp::sse::reply(warp::sse::keep_alive().stream(events)))
+}
+
+/// Convert a change into an event, if the change is to the table, and
+/// the record can be selected by the session and matches the condition
+async fn event(
+	session: &Session,
+	table: &str,
+	cond: Option<&Cond>,
+	change: Change,
+) -> Option<Event> {
+	// Check that the change is to the table
+	if change.tb != table {
+		return None;
+	}
+	// Get the datastore reference
+	let db = DB.get().unwrap();
+	// Select the record with the permissions of the session
+	let result = match db.select_change(session, &change, cond).await {
+		Ok(Some(v)) => v,
+		Ok(None) => return None,
+		Err(e) => {
+			warn!(target: LOG, "Unable to select live change {}: {}", change.seq, e);
+			return None;
+		}
+	};
+	// Each event is identified by its position in the change log
+	let data = serde_json::to_string(&Json::from(result)).ok()?;
+	Some(Event::default().id(change.seq.to_string()).event(change.action().to_string()).data(data))
+}
@@ -0,0 +1,26 @@
+use crate::dbs::DB;
+use crate::net::rpc;
+use warp::http::header::CONTENT_TYPE;
+use warp::Filter;
+
+#[allow(opaque_hidden_inferred_bound)]
+pub fn config() -> impl Filter<Extract = impl warp::Reply, Error = warp::Rejection> + Clone {
+	warp::path("metrics").and(warp::path::end()).and(warp::get()).and_then(handler)
+}
+
+async fn handler() -> Result<impl warp::Reply, warp::Rejection> {
+	// Get the datastore reference
+	let db = DB.get().unwrap();
+	// Output the datastore metrics
+	let mut out = db.metrics().output();
+	// Output the latency objective metrics
+	out.push_str(&db.slo().metrics());
+	// Output the rate limit and quota metrics
+	out.push_str(&db.quotas().metrics());
+	// Output the WebSocket connection gauge
+	out.push_str("# HELP surrealdb_websocket_connections WebSocket connections which are open\n");
+	out.push_str("# TYPE surrealdb_websocket_connections gauge\n");
+	out.push_str(&format!("surrealdb_websocket_connections {}\n", rpc::connections().await));
+	// Output the metrics in the Prometheus text format
+	Ok(warp::reply::with_header(out, CONTENT_TYPE, "text/plain; version=0.0.4"))
+}
@@ -1,23 +1,33 @@
+mod admin;
+mod batch;
 pub mod client_ip;
+pub(crate) mod csv;
 mod export;
 mod fail;
+mod graphql;
 mod head;
 mod health;
 mod import;
 mod index;
 mod input;
 mod key;
+mod live;
 mod log;
-mod output;
+mod metrics;
+pub mod output;
 mod params;
+mod replica;
 mod rpc;
 mod session;
-mod signals;
+mod share;
+pub mod signals;
 mod signin;
 mod signup;
 mod sql;
 mod status;
 mod sync;
+pub mod tls;
+mod transaction;
 mod version;
 
 use crate::cli::CF;
@@ -29,26 +39,42 @@ const LOG: &str = "surrealdb::net";
 pub async fn init() -> Result<(), Error> {
 	// Setup web routes
 	let net = index::config()
+		// Admin console endpoint
+		.or(admin::config())
 		// Version endpoint
 		.or(version::config())
 		// Status endpoint
 		.or(status::config())
 		// Health endpoint
 		.or(health::config())
+		// Metrics endpoint
+		.or(metrics::config())
 		// Signup endpoint
 		.or(signup::config())
 		// Signin endpoint
 		.or(signin::config())
+		// Share link endpoint
+		.or(share::config())
 		// Export endpoint
 		.or(export::config())
 		// Import endpoint
 		.or(import::config())
 		// Backup endpoint
 		.or(sync::config())
+		// Replication endpoint
+		.or(replica::config())
 		// RPC query endpoint
 		.or(rpc::config())
+		// Live query event stream endpoint
+		.or(live::config())
 		// SQL query endpoint
 		.or(sql::config())
+		// Batch statement endpoint
+		.or(batch::config())
+		// Transaction endpoint
+		.or(transaction::config())
+		// GraphQL query endpoint
+		.or(graphql::config())
 		// API query endpoint
 		.or(key::config())
 		// Catch all errors
@@ -71,7 +97,12 @@ pub async fn init() -> Result<(), Error> {
 
 	info!(target: LOG, "Starting web server on {}", &opt.bind);
 
-	if let (Some(c), Some(k)) = (&opt.crt, &opt.key) {
+	if let (Some(c), Some(k), Some(ca)) = (&opt.crt, &opt.key, &opt.ca) {
+		// Verify the certificates of clients
+		tls::serve(net, opt.bind, c, k, ca).await?;
+		// Log the server shutdown event
+		info!(target: LOG, "Shutdown complete. Bye!")
+	} else if let (Some(c), Some(k)) = (&opt.crt, &opt.key) {
 		// Bind the server to the desired port
 		let (adr, srv) = warp::serve(net)
 			.tls()
@@ -81,6 +112,8 @@ pub async fn init() -> Result<(), Error> {
 				// Capture the shutdown signals and log that the graceful shutdown has started
 				let result = signals::listen().await.expect("Failed to listen to shutdown signal");
 				info!(target: LOG, "{} received. Start graceful shutdown...", result);
+				// Close the connections which the server is waiting for
+				tokio::spawn(crate::dbs::shutdown());
 			});
 		// Log the server startup status
 		info!(target: LOG, "Started web server on {}", &adr);
@@ -94,6 +127,8 @@ pub async fn init() -> Result<(), Error> {
 			// Capture the shutdown signals and log that the graceful shutdown has started
 			let result = signals::listen().await.expect("Failed to listen to shutdown signal");
 			info!(target: LOG, "{} received. Start graceful shutdown...", result);
+			// Close the connections which the server is waiting for
+			tokio::spawn(crate::dbs::shutdown());
 		});
 		// Log the server startup status
 		info!(target: LOG, "Started web server on {}", &adr);
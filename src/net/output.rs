@@ -1,8 +1,10 @@
 use http::header::{HeaderValue, CONTENT_TYPE};
 use http::StatusCode;
-use serde::Serialize;
+use serde::ser::{SerializeMap, SerializeSeq};
+use serde::{Serialize, Serializer};
 use serde_json::Value as Json;
 use surrealdb::sql;
+use surrealdb::sql::{Number, Value};
 
 pub enum Output {
 	None,
@@ -67,6 +69,52 @@ pub fn simplify<T: Serialize>(v: T) -> Json {
 	sql::to_value(v).unwrap().into()
 }
 
+/// Convert and simplify the value for a binary format
+pub fn binary<T: Serialize>(v: T) -> Binary {
+	Binary(sql::to_value(v).unwrap())
+}
+
+/// A value which is simplified in the same way as JSON, except that numbers
+/// and bytes are output with their native types in CBOR and MessagePack
+pub struct Binary(Value);
+
+struct Simple<'a>(&'a Value);
+
+impl Serialize for Binary {
+	fn serialize<S: Serializer>(&self, serializer: S) -> Result<S::Ok, S::Error> {
+		Simple(&self.0).serialize(serializer)
+	}
+}
+
+impl<'a> Serialize for Simple<'a> {
+	fn serialize<S: Serializer>(&self, serializer: S) -> Result<S::Ok, S::Error> {
+		match self.0 {
+			Value::None | Value::Null => serializer.serialize_none(),
+			Value::Bool(v) => serializer.serialize_bool(*v),
+			Value::Number(Number::Int(v)) => serializer.serialize_i64(*v),
+			Value::Number(Number::Float(v)) => serializer.serialize_f64(*v),
+			Value::Strand(v) => serializer.serialize_str(v.as_str()),
+			Value::Bytes(v) => serializer.serialize_bytes(v),
+			Value::Array(v) => {
+				let mut seq = serializer.serialize_seq(Some(v.len()))?;
+				for v in v.iter() {
+					seq.serialize_element(&Simple(v))?;
+				}
+				seq.end()
+			}
+			Value::Object(v) => {
+				let mut map = serializer.serialize_map(Some(v.len()))?;
+				for (k, v) in v.iter() {
+					map.serialize_entry(k, &Simple(v))?;
+				}
+				map.end()
+			}
+			// Everything else is output as it is in JSON
+			v => Json::from(v.clone()).serialize(serializer),
+		}
+	}
+}
+
 impl warp::Reply for Output {
 	fn into_response(self) -> warp::reply::Response {
 		match self {
@@ -0,0 +1,99 @@
+use crate::dbs::replica::{HeartbeatRequest, Role, VoteRequest, NODE};
+use crate::dbs::DB;
+use crate::err::Error;
+use crate::net::output;
+use crate::net::session;
+use bytes::Bytes;
+use hyper::body::Body;
+use surrealdb::dbs::Session;
+use warp::Filter;
+
+const MAX: u64 = 1024 * 16; // 16 KiB
+
+#[allow(opaque_hidden_inferred_bound)]
+pub fn config() -> impl Filter<Extract = impl warp::Reply, Error = warp::Rejection> + Clone {
+	// Set base path
+	let base = warp::path("replica");
+	// Set vote method
+	let vote = base
+		.and(warp::path("vote"))
+		.and(warp::path::end())
+		.and(warp::post())
+		.and(warp::body::content_length_limit(MAX))
+		.and(warp::body::json())
+		.and(session::build())
+		.and_then(vote);
+	// Set heartbeat method
+	let heartbeat = base
+		.and(warp::path("heartbeat"))
+		.and(warp::path::end())
+		.and(warp::post())
+		.and(warp::body::content_length_limit(MAX))
+		.and(warp::body::json())
+		.and(session::build())
+		.and_then(heartbeat);
+	// Set stream method
+	let stream = base
+		.and(warp::path("stream"))
+		.and(warp::path::end())
+		.and(warp::get())
+		.and(session::build())
+		.and_then(stream);
+	// Specify route
+	vote.or(heartbeat).or(stream)
+}
+
+async fn vote(req: VoteRequest, session: Session) -> Result<impl warp::Reply, warp::Rejection> {
+	// Check the permissions
+	check(&session)?;
+	// Get the replication state
+	let node = NODE.get().ok_or_else(|| warp::reject::custom(Error::OperationUnsupported))?;
+	// Respond to the candidate
+	Ok(output::json(&node.vote(req)))
+}
+
+async fn heartbeat(
+	req: HeartbeatRequest,
+	session: Session,
+) -> Result<impl warp::Reply, warp::Rejection> {
+	// Check the permissions
+	check(&session)?;
+	// Get the replication state
+	let node = NODE.get().ok_or_else(|| warp::reject::custom(Error::OperationUnsupported))?;
+	// Respond to the leader
+	Ok(output::json(&node.heartbeat(req)))
+}
+
+async fn stream(session: Session) -> Result<impl warp::Reply, warp::Rejection> {
+	// Check the permissions
+	check(&session)?;
+	// Only the leader streams its writes
+	match NODE.get() {
+		Some(node) if node.role() == Role::Leader => (),
+		_ => return Err(warp::reject::custom(Error::OperationUnsupported)),
+	}
+	// Get the datastore reference
+	let db = DB.get().unwrap();
+	// Create a chunked response
+	let (mut chn, bdy) = Body::channel();
+	// Subscribe to the write stream
+	let rcv = db.writes().await;
+	// Process all committed batches
+	tokio::spawn(async move {
+		while let Ok(v) = rcv.recv().await {
+			if chn.send_data(Bytes::from(v.encode())).await.is_err() {
+				break;
+			}
+		}
+	});
+	// Return the chunked body
+	Ok(warp::reply::Response::new(bdy))
+}
+
+/// Check that the session is authenticated as the root user
+fn check(session: &Session) -> Result<(), warp::Rejection> {
+	match session.au.is_kv() {
+		true => Ok(()),
+		false => Err(warp::reject::custom(Error::InvalidAuth)),
+	}
+}
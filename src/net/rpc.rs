@@ -2,9 +2,11 @@ use crate::cli::CF;
 use crate::cnf::MAX_CONCURRENT_CALLS;
 use crate::cnf::PKG_NAME;
 use crate::cnf::PKG_VERSION;
+use crate::cnf::WEBSOCKET_NOTIFICATION_WINDOW;
 use crate::cnf::WEBSOCKET_PING_FREQUENCY;
 use crate::dbs::DB;
 use crate::err::Error;
+use crate::net::input;
 use crate::net::session;
 use crate::net::LOG;
 use crate::rpc::args::Take;
@@ -17,6 +19,7 @@ use once_cell::sync::Lazy;
 use serde::Serialize;
 use std::collections::BTreeMap;
 use std::collections::HashMap;
+use std::collections::HashSet;
 use std::sync::Arc;
 use surrealdb::channel;
 use surrealdb::channel::Sender;
@@ -26,7 +29,9 @@ use surrealdb::sql::Array;
 use surrealdb::sql::Object;
 use surrealdb::sql::Strand;
 use surrealdb::sql::Value;
+use tokio::sync::watch;
 use tokio::sync::RwLock;
+use tokio::sync::Semaphore;
 use tracing::instrument;
 use uuid::Uuid;
 use warp::ws::{Message, WebSocket, Ws};
@@ -45,6 +50,11 @@ pub fn config() -> impl Filter<Extract = impl warp::Reply, Error = warp::Rejecti
 		.map(|ws: Ws, session: Session| ws.on_upgrade(move |ws| socket(ws, session)))
 }
 
+/// Get the number of WebSocket connections which are open
+pub async fn connections() -> usize {
+	WEBSOCKETS.read().await.len()
+}
+
 async fn socket(ws: WebSocket, session: Session) {
 	let rpc = Rpc::new(session);
 	Rpc::serve(rpc, ws).await
@@ -55,6 +65,10 @@ pub struct Rpc {
 	format: Output,
 	uuid: Uuid,
 	vars: BTreeMap<String, Value>,
+	// The live queries which were subscribed to on this connection
+	lives: HashSet<Uuid>,
+	// The last live query notification which was acknowledged
+	acked: watch::Sender<Option<u64>>,
 }
 
 impl Rpc {
@@ -66,14 +80,20 @@ impl Rpc {
 		let format = Output::Json;
 		// Create a unique WebSocket id
 		let uuid = Uuid::new_v4();
+		// Use the WebSocket id as the session id
+		session.id = Some(uuid.to_string());
 		// Enable real-time live queries
 		session.rt = true;
+		// Notifications are not acknowledged until the client does so
+		let (acked, _) = watch::channel(None);
 		// Create and store the Rpc connection
 		Arc::new(RwLock::new(Rpc {
 			session,
 			format,
 			uuid,
 			vars,
+			lives: HashSet::new(),
+			acked,
 		}))
 	}
 
@@ -82,11 +102,17 @@ impl Rpc {
 		// Create a channel for sending messages
 		let (chn, mut rcv) = channel::new(MAX_CONCURRENT_CALLS);
 		// Split the socket into send and recv
-		let (mut wtx, mut wrx) = ws.split();
+		let (mut wtx, wrx) = ws.split();
 		// Clone the channel for sending pings
 		let png = chn.clone();
 		// The WebSocket has connected
-		Rpc::connected(rpc.clone(), chn.clone()).await;
+		let exit = Rpc::connected(rpc.clone(), chn.clone()).await;
+		// Stop receiving messages if the session is killed
+		let mut wrx = wrx.take_until(Box::pin(exit.recv()));
+		// Limit the number of requests which are processed concurrently
+		let limiter = Arc::new(Semaphore::new(MAX_CONCURRENT_CALLS));
+		// Send live query notifications to the client
+		tokio::task::spawn(Rpc::notifications(rpc.clone(), chn.clone()));
 		// Send messages to the client
 		tokio::task::spawn(async move {
 			// Create the interval ticker
@@ -127,11 +153,15 @@ impl Rpc {
 					msg if msg.is_ping() => {
 						let _ = chn.send(Message::pong(vec![])).await;
 					}
-					msg if msg.is_text() => {
-						tokio::task::spawn(Rpc::call(rpc.clone(), msg, chn.clone()));
-					}
-					msg if msg.is_binary() => {
-						tokio::task::spawn(Rpc::call(rpc.clone(), msg, chn.clone()));
+					msg if msg.is_text() || msg.is_binary() => {
+						// Wait until another request can be processed
+						let permit = limiter.clone().acquire_owned().await.unwrap();
+						// Process the request without blocking other requests
+						let (rpc, chn) = (rpc.clone(), chn.clone());
+						tokio::task::spawn(async move {
+							Rpc::call(rpc, msg, chn).await;
+							drop(permit);
+						});
 					}
 					msg if msg.is_close() => {
 						break;
@@ -156,13 +186,16 @@ impl Rpc {
 		Rpc::disconnected(rpc.clone()).await;
 	}
 
-	async fn connected(rpc: Arc<RwLock<Rpc>>, chn: Sender<Message>) {
+	async fn connected(rpc: Arc<RwLock<Rpc>>, chn: Sender<Message>) -> channel::Receiver<()> {
+		let rpc = rpc.read().await;
 		// Fetch the unique id of the WebSocket
-		let id = rpc.read().await.uuid;
+		let id = rpc.uuid;
 		// Log that the WebSocket has connected
 		trace!(target: LOG, "WebSocket {} connected", id);
 		// Store this WebSocket in the list of WebSockets
 		WEBSOCKETS.write().await.insert(id, chn);
+		// Register this WebSocket as a session of the datastore
+		DB.get().unwrap().registry().connect(id, "websocket", &rpc.session)
 	}
 
 	async fn disconnected(rpc: Arc<RwLock<Rpc>>) {
@@ -170,8 +203,58 @@ impl Rpc {
 		let id = rpc.read().await.uuid;
 		// Log that the WebSocket has disconnected
 		trace!(target: LOG, "WebSocket {} disconnected", id);
+		// Cancel the running queries, as their results can not be sent
+		DB.get().unwrap().registry().interrupt(&id);
+		// Kill the live queries which were subscribed to
+		let lives = std::mem::take(&mut rpc.write().await.lives);
+		for lv in lives {
+			if let Err(e) = rpc.read().await.kill(surrealdb::sql::Uuid::from(lv).into()).await {
+				trace!(target: LOG, "Unable to kill the live query {}: {}", lv, e);
+			}
+		}
 		// Remove this WebSocket from the list of WebSockets
 		WEBSOCKETS.write().await.remove(&id);
+		// Remove this WebSocket from the sessions of the datastore
+		DB.get().unwrap().registry().disconnect(&id);
+	}
+
+	/// Send the live query notifications of the WebSocket to the client. Once
+	/// the client acknowledges a notification, no more than a window of
+	/// notifications are sent until the client acknowledges further ones.
+	async fn notifications(rpc: Arc<RwLock<Rpc>>, chn: Sender<Message>) {
+		// Create a channel for receiving live query notifications
+		let (tx, rx) = channel::new(MAX_CONCURRENT_CALLS);
+		// Fetch the unique id of the WebSocket, and the acknowledgements
+		let (id, mut acked) = {
+			let rpc = rpc.read().await;
+			(rpc.uuid, rpc.acked.subscribe())
+		};
+		// The channel is closed when the WebSocket disconnects
+		DB.get().unwrap().registry().notify(&id, Some(tx));
+		// Number each notification which is sent
+		let mut seq: u64 = 0;
+		while let Ok(mut v) = rx.recv().await {
+			seq += 1;
+			// Wait until enough notifications have been acknowledged
+			loop {
+				let ack = *acked.borrow_and_update();
+				match ack {
+					Some(n) if seq.saturating_sub(n) > WEBSOCKET_NOTIFICATION_WINDOW => {
+						if acked.changed().await.is_err() {
+							return;
+						}
+					}
+					_ => break,
+				}
+			}
+			// Add the sequence number to the notification
+			if let Value::Object(v) = &mut v {
+				v.insert("seq".to_owned(), seq.into());
+			}
+			// Send the notification in the current output format
+			let out = { rpc.read().await.format.clone() };
+			res::notification(v).send(out, chn.clone()).await;
+		}
 	}
 
 	/// Call RPC methods from the WebSocket
@@ -183,12 +266,25 @@ impl Rpc {
 		// Parse the request
 		let req = match msg {
 			// This is a binary message
-			m if m.is_binary() => {
-				// Use binary output
-				out = Output::Full;
-				// Deserialize the input
-				Value::from(m.into_bytes())
-			}
+			m if m.is_binary() => match out {
+				// Decode the input as CBOR if it was selected
+				Output::Cbor => match input::from_cbor(m.as_bytes()) {
+					Ok(v) => v,
+					_ => return res::failure(None, Failure::PARSE_ERROR).send(out, chn).await,
+				},
+				// Decode the input as MessagePack if it was selected
+				Output::Pack => match input::from_pack(m.as_bytes()) {
+					Ok(v) => v,
+					_ => return res::failure(None, Failure::PARSE_ERROR).send(out, chn).await,
+				},
+				// Otherwise use the internal serialization
+				_ => {
+					// Use binary output
+					out = Output::Full;
+					// Deserialize the input
+					Value::from(m.into_bytes())
+				}
+			},
 			// This is a text message
 			m if m.is_text() => {
 				// This won't panic due to the check above
@@ -261,14 +357,30 @@ impl Rpc {
 				_ => return res::failure(id, Failure::INVALID_PARAMS).send(out, chn).await,
 			},
 			// Kill a live query using a query id
-			"kill" => match params.needs_one() {
-				Ok(v) if v.is_uuid() => rpc.read().await.kill(v).await,
+			"kill" | "unsubscribe" => match params.needs_one() {
+				Ok(v) if v.is_uuid() => rpc.write().await.unsubscribe(v).await,
 				_ => return res::failure(id, Failure::INVALID_PARAMS).send(out, chn).await,
 			},
 			// Setup a live query on a specific table
-			"live" => match params.needs_one() {
-				Ok(v) if v.is_table() => rpc.read().await.live(v).await,
-				Ok(v) if v.is_strand() => rpc.read().await.live(v).await,
+			"live" | "subscribe" => match params.needs_one_or_two() {
+				Ok((v, s)) if v.is_table() && (s.is_none() || s.is_number()) => {
+					rpc.write().await.subscribe(v, s).await
+				}
+				Ok((v, s)) if v.is_strand() && (s.is_none() || s.is_number()) => {
+					rpc.write().await.subscribe(v, s).await
+				}
+				_ => return res::failure(id, Failure::INVALID_PARAMS).send(out, chn).await,
+			},
+			// Acknowledge the live query notifications up to a sequence number
+			"ack" => match params.needs_one() {
+				Ok(Value::Number(v)) if v.is_integer() && v.to_int() >= 0 => {
+					rpc.read().await.ack(v.to_int() as u64).await
+				}
+				_ => return res::failure(id, Failure::INVALID_PARAMS).send(out, chn).await,
+			},
+			// Cancel the queries which are running on this connection
+			"cancel" => match params.len() {
+				0 => rpc.read().await.cancel().await,
 				_ => return res::failure(id, Failure::INVALID_PARAMS).send(out, chn).await,
 			},
 			// Specify a connection-wide parameter
@@ -321,6 +433,11 @@ impl Rpc {
 				Ok(Value::Strand(v)) => rpc.write().await.format(v).await,
 				_ => return res::failure(id, Failure::INVALID_PARAMS).send(out, chn).await,
 			},
+			// Enable or disable statement tracing for this connection
+			"trace" => match params.needs_one() {
+				Ok(Value::Bool(v)) => rpc.read().await.trace(v, out.clone(), chn.clone()).await,
+				_ => return res::failure(id, Failure::INVALID_PARAMS).send(out, chn).await,
+			},
 			// Get the current server version
 			"version" => match params.len() {
 				0 => Ok(format!("{PKG_NAME}-{}", *PKG_VERSION).into()),
@@ -329,19 +446,23 @@ impl Rpc {
 			// Run a full SurrealQL query against the database
 			"query" => match params.needs_one_or_two() {
 				Ok((Value::Strand(s), o)) if o.is_none_or_null() => {
-					return match rpc.read().await.query(s).await {
-						Ok(v) => res::success(id, v).send(out, chn).await,
-						Err(e) => {
-							res::failure(id, Failure::custom(e.to_string())).send(out, chn).await
+					let res = rpc.read().await.query(s).await;
+					return match res {
+						Ok((v, vars)) => {
+							rpc.write().await.keep(vars);
+							res::success(id, v).send(out, chn).await
 						}
+						Err(e) => res::failure(id, Failure::from(e)).send(out, chn).await,
 					};
 				}
 				Ok((Value::Strand(s), Value::Object(o))) => {
-					return match rpc.read().await.query_with(s, o).await {
-						Ok(v) => res::success(id, v).send(out, chn).await,
-						Err(e) => {
-							res::failure(id, Failure::custom(e.to_string())).send(out, chn).await
+					let res = rpc.read().await.query_with(s, o).await;
+					return match res {
+						Ok((v, vars)) => {
+							rpc.write().await.keep(vars);
+							res::success(id, v).send(out, chn).await
 						}
+						Err(e) => res::failure(id, Failure::from(e)).send(out, chn).await,
 					};
 				}
 				_ => return res::failure(id, Failure::INVALID_PARAMS).send(out, chn).await,
@@ -351,7 +472,7 @@ impl Rpc {
 		// Return the final response
 		match res {
 			Ok(v) => res::success(id, v).send(out, chn).await,
-			Err(e) => res::failure(id, Failure::custom(e.to_string())).send(out, chn).await,
+			Err(e) => res::failure(id, Failure::from(e)).send(out, chn).await,
 		}
 	}
 
@@ -369,6 +490,28 @@ impl Rpc {
 		Ok(Value::None)
 	}
 
+	#[instrument(skip_all, name = "rpc trace", fields(websocket=self.uuid.to_string()))]
+	async fn trace(&self, enable: bool, out: Output, chn: Sender<Message>) -> Result<Value, Error> {
+		// Get a database reference
+		let kvs = DB.get().unwrap();
+		// Check if tracing is being enabled
+		if enable {
+			// Create a channel for receiving statement traces
+			let (tx, rx) = channel::new(MAX_CONCURRENT_CALLS);
+			// Send the statement traces to the client, until
+			// tracing is disabled or the connection is closed
+			tokio::task::spawn(async move {
+				while let Ok(v) = rx.recv().await {
+					res::trace(v).send(out.clone(), chn.clone()).await;
+				}
+			});
+			kvs.registry().trace(&self.uuid, Some(tx));
+		} else {
+			kvs.registry().trace(&self.uuid, None);
+		}
+		Ok(Value::None)
+	}
+
 	#[instrument(skip_all, name = "rpc use", fields(websocket=self.uuid.to_string()))]
 	async fn yuse(&mut self, ns: Value, db: Value) -> Result<Value, Error> {
 		if let Value::Strand(ns) = ns {
@@ -457,10 +600,57 @@ impl Rpc {
 		Ok(Value::Null)
 	}
 
+	/// Keep the variables which were set with LET statements in a query
+	fn keep(&mut self, vars: BTreeMap<String, Value>) {
+		for (key, val) in vars {
+			match val {
+				// Remove the variable if undefined
+				Value::None => self.vars.remove(&key),
+				// Store the variable if defined
+				v => self.vars.insert(key, v),
+			};
+		}
+	}
+
 	// ------------------------------
 	// Methods for live queries
 	// ------------------------------
 
+	#[instrument(skip_all, name = "rpc subscribe", fields(websocket=self.uuid.to_string()))]
+	async fn subscribe(&mut self, tb: Value, since: Value) -> Result<Value, Error> {
+		// Setup the live query
+		let res = self.live(tb, since).await?;
+		// Kill the live query when the WebSocket disconnects
+		if let Value::Uuid(v) = &res {
+			self.lives.insert(v.0);
+		}
+		Ok(res)
+	}
+
+	#[instrument(skip_all, name = "rpc unsubscribe", fields(websocket=self.uuid.to_string()))]
+	async fn unsubscribe(&mut self, id: Value) -> Result<Value, Error> {
+		// Forget the live query
+		if let Value::Uuid(v) = &id {
+			self.lives.remove(&v.0);
+		}
+		// Kill the live query
+		self.kill(id).await
+	}
+
+	#[instrument(skip_all, name = "rpc ack", fields(websocket=self.uuid.to_string()))]
+	async fn ack(&self, seq: u64) -> Result<Value, Error> {
+		self.acked.send_modify(|v| *v = Some(v.map_or(seq, |v| v.max(seq))));
+		Ok(Value::None)
+	}
+
+	#[instrument(skip_all, name = "rpc cancel", fields(websocket=self.uuid.to_string()))]
+	async fn cancel(&self) -> Result<Value, Error> {
+		// Cancel the running queries, which fail with an error
+		let count = DB.get().unwrap().registry().interrupt(&self.uuid);
+		// Return the number of cancelled queries
+		Ok(Value::from(count))
+	}
+
 	#[instrument(skip_all, name = "rpc kill", fields(websocket=self.uuid.to_string()))]
 	async fn kill(&self, id: Value) -> Result<Value, Error> {
 		// Get a database reference
@@ -483,16 +673,20 @@ impl Rpc {
 	}
 
 	#[instrument(skip_all, name = "rpc live", fields(websocket=self.uuid.to_string()))]
-	async fn live(&self, tb: Value) -> Result<Value, Error> {
+	async fn live(&self, tb: Value, since: Value) -> Result<Value, Error> {
 		// Get a database reference
 		let kvs = DB.get().unwrap();
 		// Get local copy of options
 		let opt = CF.get().unwrap();
-		// Specify the SQL query string
-		let sql = "LIVE SELECT * FROM $tb";
+		// Specify the SQL query string, resuming from a token if specified
+		let sql = match since.is_none() {
+			true => "LIVE SELECT * FROM $tb",
+			false => "LIVE SELECT * FROM $tb SINCE $since",
+		};
 		// Specify the query parameters
 		let var = Some(map! {
 			String::from("tb") => tb.could_be_table(),
+			String::from("since") => since,
 			=> &self.vars
 		});
 		// Execute the query on the database
@@ -692,7 +886,7 @@ impl Rpc {
 	// ------------------------------
 
 	#[instrument(skip_all, name = "rpc query", fields(websocket=self.uuid.to_string()))]
-	async fn query(&self, sql: Strand) -> Result<impl Serialize, Error> {
+	async fn query(&self, sql: Strand) -> Result<(impl Serialize, BTreeMap<String, Value>), Error> {
 		// Get a database reference
 		let kvs = DB.get().unwrap();
 		// Get local copy of options
@@ -700,13 +894,17 @@ impl Rpc {
 		// Specify the query parameters
 		let var = Some(self.vars.clone());
 		// Execute the query on the database
-		let res = kvs.execute(&sql, &self.session, var, opt.strict).await?;
-		// Return the result to the client
+		let res = kvs.execute_with_lets(&sql, &self.session, var, opt.strict).await?;
+		// Return the result to the client, and the variables to keep
 		Ok(res)
 	}
 
 	#[instrument(skip_all, name = "rpc query_with", fields(websocket=self.uuid.to_string()))]
-	async fn query_with(&self, sql: Strand, mut vars: Object) -> Result<impl Serialize, Error> {
+	async fn query_with(
+		&self,
+		sql: Strand,
+		mut vars: Object,
+	) -> Result<(impl Serialize, BTreeMap<String, Value>), Error> {
 		// Get a database reference
 		let kvs = DB.get().unwrap();
 		// Get local copy of options
@@ -714,8 +912,8 @@ impl Rpc {
 		// Specify the query parameters
 		let var = Some(mrg! { vars.0, &self.vars });
 		// Execute the query on the database
-		let res = kvs.execute(&sql, &self.session, var, opt.strict).await?;
-		// Return the result to the client
+		let res = kvs.execute_with_lets(&sql, &self.session, var, opt.strict).await?;
+		// Return the result to the client, and the variables to keep
 		Ok(res)
 	}
 }
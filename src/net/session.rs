@@ -1,8 +1,10 @@
 use crate::dbs::DB;
 use crate::err::Error;
 use crate::iam::verify::basic;
+use crate::iam::verify::certificate;
 use crate::iam::BASIC;
 use crate::net::client_ip;
+use crate::net::tls::Peer;
 use surrealdb::dbs::Session;
 use surrealdb::iam::verify::token;
 use surrealdb::iam::TOKEN;
@@ -23,6 +25,10 @@ pub fn build() -> impl Filter<Extract = (Session,), Error = warp::Rejection> + C
 	let conf = conf.and(warp::header::optional::<String>("ns"));
 	// Add database header
 	let conf = conf.and(warp::header::optional::<String>("db"));
+	// Add dry-run header
+	let conf = conf.and(warp::header::optional::<String>("x-dry-run"));
+	// Add any verified TLS client
+	let conf = conf.and(warp::ext::optional::<Peer>());
 	// Process all headers
 	conf.and_then(process)
 }
@@ -34,11 +40,15 @@ async fn process(
 	id: Option<String>,
 	ns: Option<String>,
 	db: Option<String>,
+	dr: Option<String>,
+	peer: Option<Peer>,
 ) -> Result<Session, warp::Rejection> {
 	let kvs = DB.get().unwrap();
 	// Create session
 	#[rustfmt::skip]
 	let mut session = Session { ip, or, id, ns, db, ..Default::default() };
+	// Roll back the changes of a dry-run
+	session.dr = matches!(dr.as_deref(), Some("true" | "1"));
 	// Parse the authentication header
 	match au {
 		// Basic authentication data was supplied
@@ -50,7 +60,13 @@ async fn process(
 		// Wrong authentication data was supplied
 		Some(_) => Err(Error::InvalidAuth),
 		// No authentication data was supplied
-		None => Ok(()),
+		None => {
+			// Authenticate with any verified client certificate
+			if let Some(cn) = peer.and_then(|p| p.cn) {
+				certificate(&mut session, &cn);
+			}
+			Ok(())
+		}
 	}?;
 	// Pass the authenticated session through
 	Ok(session)
@@ -0,0 +1,134 @@
+use crate::dbs::DB;
+use crate::err::Error;
+use crate::net::input::bytes_to_utf8;
+use crate::net::output;
+use crate::net::session;
+use bytes::Bytes;
+use serde::Serialize;
+use std::time::Duration;
+use surrealdb::dbs::Session;
+use surrealdb::iam::share::Share;
+use surrealdb::sql::Value;
+use warp::path;
+use warp::Filter;
+
+const MAX: u64 = 1024 * 16; // 16 KiB
+
+/// The default time for which a share link is valid
+const EXPIRY: Duration = Duration::from_secs(60 * 60);
+
+#[derive(Serialize)]
+struct Success {
+	code: u16,
+	details: String,
+	token: String,
+	url: String,
+}
+
+impl Success {
+	fn new(token: String) -> Success {
+		Success {
+			url: format!("/share/{token}"),
+			token,
+			code: 200,
+			details: String::from("Share link created"),
+		}
+	}
+}
+
+#[allow(opaque_hidden_inferred_bound)]
+pub fn config() -> impl Filter<Extract = impl warp::Reply, Error = warp::Rejection> + Clone {
+	// Set base path
+	let base = warp::path("share");
+	// Set opts method
+	let opts = base.and(warp::path::end()).and(warp::options()).map(warp::reply);
+	// Set post method
+	let post = base
+		.and(warp::path::end())
+		.and(warp::post())
+		.and(warp::header::optional::<String>(http::header::ACCEPT.as_str()))
+		.and(warp::body::content_length_limit(MAX))
+		.and(warp::body::bytes())
+		.and(session::build())
+		.and_then(mint);
+	// Set get method
+	let get = warp::any()
+		.and(warp::get())
+		.and(warp::header::optional::<String>(http::header::ACCEPT.as_str()))
+		.and(path!("share" / String).and(warp::path::end()))
+		.and_then(open);
+	// Specify route
+	opts.or(post).or(get)
+}
+
+async fn mint(
+	output: Option<String>,
+	body: Bytes,
+	session: Session,
+) -> Result<impl warp::Reply, warp::Rejection> {
+	// Get a database reference
+	let kvs = DB.get().unwrap();
+	// Convert the HTTP body into text
+	let data = bytes_to_utf8(&body)?;
+	// Parse the provided data as JSON
+	let vars = match surrealdb::sql::json(data) {
+		Ok(Value::Object(vars)) => vars,
+		_ => return Err(warp::reject::custom(Error::Request)),
+	};
+	// Get the record or query to share
+	let share = match (vars.get("record"), vars.get("query")) {
+		(Some(Value::Strand(v)), None) => Share::Record(v.as_str().to_owned()),
+		(None, Some(Value::Strand(v))) => Share::Query(v.as_str().to_owned()),
+		_ => return Err(warp::reject::custom(Error::Request)),
+	};
+	// Get the expiry of the share link
+	let expiry = match vars.get("expires") {
+		Some(Value::Strand(v)) => match surrealdb::sql::Duration::try_from(v.as_str()) {
+			Ok(v) => *v,
+			Err(_) => return Err(warp::reject::custom(Error::Request)),
+		},
+		Some(_) => return Err(warp::reject::custom(Error::Request)),
+		None => EXPIRY,
+	};
+	// Create the share link
+	match surrealdb::iam::share::mint(kvs, &session, share, expiry).await.map_err(Error::from) {
+		Ok(v) => match output.as_deref() {
+			// Simple serialization
+			Some("application/json") => Ok(output::json(&Success::new(v))),
+			Some("application/cbor") => Ok(output::cbor(&Success::new(v))),
+			Some("application/pack") => Ok(output::pack(&Success::new(v))),
+			// Internal serialization
+			Some("application/bung") => Ok(output::full(&Success::new(v))),
+			// Text serialization
+			Some("text/plain") | None => Ok(output::text(v)),
+			// An incorrect content-type was requested
+			_ => Err(warp::reject::custom(Error::InvalidType)),
+		},
+		// There was an error creating the share link
+		Err(e) => Err(warp::reject::custom(e)),
+	}
+}
+
+async fn open(output: Option<String>, token: String) -> Result<impl warp::Reply, warp::Rejection> {
+	// Get a database reference
+	let kvs = DB.get().unwrap();
+	// Verify the share link and read the shared data
+	match surrealdb::iam::share::open(kvs, &token).await {
+		// The shared record does not exist
+		Ok(Value::None) => Err(warp::reject::not_found()),
+		// The shared data was read successfully
+		Ok(ref res) => match output.as_deref() {
+			// Simple serialization
+			Some("application/cbor") => Ok(output::cbor(&output::binary(res))),
+			Some("application/pack") => Ok(output::pack(&output::binary(res))),
+			// Internal serialization
+			Some("application/bung") => Ok(output::full(&res)),
+			// Share links are usually opened in a browser, so default to JSON
+			_ => Ok(output::json(&output::simplify(res))),
+		},
+		// The share link is invalid or has expired
+		Err(surrealdb::err::Error::InvalidAuth) => Err(warp::reject::custom(Error::InvalidAuth)),
+		// There was an error when reading the shared data
+		Err(e) => Err(warp::reject::custom(Error::from(e))),
+	}
+}
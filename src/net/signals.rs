@@ -4,17 +4,13 @@ use crate::err::Error;
 pub async fn listen() -> Result<String, Error> {
 	// Import the OS signals
 	use tokio::signal::unix::{signal, SignalKind};
-	// Get the operating system signal types
-	let mut sighup = signal(SignalKind::hangup())?;
+	// Get the operating system signal types, leaving
+	// SIGHUP to reload the runtime settings
 	let mut sigint = signal(SignalKind::interrupt())?;
 	let mut sigquit = signal(SignalKind::quit())?;
 	let mut sigterm = signal(SignalKind::terminate())?;
 	// Listen and wait for the system signals
 	tokio::select! {
-		// Wait for a SIGQUIT signal
-		_ = sighup.recv() => {
-			Ok(String::from("SIGHUP"))
-		}
 		// Wait for a SIGINT signal
 		_ = sigint.recv() => {
 			Ok(String::from("SIGINT"))
@@ -55,8 +55,8 @@ async fn handler(
 		Ok(res) => match output.as_ref() {
 			// Simple serialization
 			"application/json" => Ok(output::json(&output::simplify(res))),
-			"application/cbor" => Ok(output::cbor(&output::simplify(res))),
-			"application/pack" => Ok(output::pack(&output::simplify(res))),
+			"application/cbor" => Ok(output::cbor(&output::binary(res))),
+			"application/pack" => Ok(output::pack(&output::binary(res))),
 			// Internal serialization
 			"application/bung" => Ok(output::full(&res)),
 			// An incorrect content-type was requested
@@ -1,22 +1,91 @@
-use warp::http;
+use crate::dbs::DB;
+use crate::err::Error;
+use crate::net::output;
+use crate::net::session;
+use bytes::Bytes;
+use hyper::body::Body;
+use surrealdb::dbs::Session;
 use warp::Filter;
 
+const LOG: &str = "surrealdb::net::sync";
+
+const MAX: u64 = 1024 * 1024 * 1024 * 4; // 4 GiB
+
 #[allow(opaque_hidden_inferred_bound)]
 pub fn config() -> impl Filter<Extract = impl warp::Reply, Error = warp::Rejection> + Clone {
 	// Set base path
 	let base = warp::path("sync").and(warp::path::end());
 	// Set save method
-	let save = base.and(warp::get()).and_then(save);
+	let save = base.and(warp::get()).and(session::build()).and_then(save);
 	// Set load method
-	let load = base.and(warp::post()).and_then(load);
+	let load = base
+		.and(warp::post())
+		.and(warp::body::content_length_limit(MAX))
+		.and(warp::body::bytes())
+		.and(session::build())
+		.and_then(load);
 	// Specify route
 	save.or(load)
 }
 
-pub async fn load() -> Result<impl warp::Reply, warp::Rejection> {
-	Ok(warp::reply::with_status("Load", http::StatusCode::OK))
+pub async fn load(body: Bytes, session: Session) -> Result<impl warp::Reply, warp::Rejection> {
+	// Check the permissions
+	check(&session)?;
+	// Get the datastore reference
+	let db = DB.get().unwrap();
+	// Replicas are restored from the leader
+	if db.is_read_only() {
+		return Err(warp::reject::custom(Error::from(surrealdb::Error::ReplicaReadOnly)));
+	}
+	// Restore the snapshot into the datastore
+	match db.restore(session.ns, session.db, &body).await {
+		Ok(at) => Ok(output::json(&map! {
+			String::from("time") => at.to_raw(),
+		})),
+		// There was an error when restoring the snapshot
+		Err(err) => Err(warp::reject::custom(Error::from(err))),
+	}
+}
+
+pub async fn save(session: Session) -> Result<impl warp::Reply, warp::Rejection> {
+	// Check the permissions
+	check(&session)?;
+	// Get the datastore reference
+	let db = DB.get().unwrap();
+	// Create a chunked response
+	let (mut chn, bdy) = Body::channel();
+	// Create a new bounded channel
+	let (snd, rcv) = surrealdb::channel::new(1);
+	// Spawn a new datastore snapshot
+	tokio::spawn(async move {
+		if let Err(e) = db.backup(session.ns, session.db, snd).await {
+			warn!(target: LOG, "The backup failed: {}", e);
+		}
+	});
+	// Process all snapshot chunks
+	tokio::spawn(async move {
+		while let Ok(v) = rcv.recv().await {
+			if chn.send_data(Bytes::from(v)).await.is_err() {
+				break;
+			}
+		}
+	});
+	// Return the chunked body
+	Ok(warp::reply::Response::new(bdy))
 }
 
-pub async fn save() -> Result<impl warp::Reply, warp::Rejection> {
-	Ok(warp::reply::with_status("Save", http::StatusCode::OK))
+/// Check that the session can access the selected
+/// database, the selected namespace, or the whole
+/// datastore if no namespace is selected.
+fn check(session: &Session) -> Result<(), warp::Rejection> {
+	let ok = match (&session.ns, &session.db) {
+		(Some(_), Some(_)) => session.au.is_db(),
+		(Some(_), None) => session.au.is_ns(),
+		(None, Some(_)) => return Err(warp::reject::custom(Error::NoNsHeader)),
+		(None, None) => session.au.is_kv(),
+	};
+	match ok {
+		true => Ok(()),
+		false => Err(warp::reject::custom(Error::InvalidAuth)),
+	}
 }
@@ -0,0 +1,163 @@
+//! A web server which verifies the certificates of clients.
+//!
+//! The TLS server which is built into warp does not expose the certificate
+//! which a client presented, so when a CA file is configured for client
+//! certificates, connections are accepted and decrypted here instead. The
+//! common name of any verified client certificate, and the address of the
+//! client, are added to each request of the connection as a [`Peer`], which
+//! the session filter uses to authenticate the request.
+use crate::err::Error;
+use crate::iam::cert;
+use crate::net::signals;
+use crate::net::LOG;
+use hyper::server::conn::Http;
+use hyper::service::{service_fn, Service};
+use std::fs::File;
+use std::io::BufReader;
+use std::net::SocketAddr;
+use std::path::Path;
+use std::sync::Arc;
+use tokio::net::TcpListener;
+use tokio_rustls::rustls::server::AllowAnyAnonymousOrAuthenticatedClient;
+use tokio_rustls::rustls::{Certificate, PrivateKey, RootCertStore, ServerConfig};
+use tokio_rustls::TlsAcceptor;
+use warp::Filter;
+
+/// The client of a connection which was accepted by this server
+#[derive(Clone, Debug)]
+pub struct Peer {
+	/// The socket address of the client
+	pub addr: SocketAddr,
+	/// The common name of the verified client certificate, if one was presented
+	pub cn: Option<String>,
+}
+
+/// Read the certificates from a PEM file
+fn certs(path: &Path) -> Result<Vec<Certificate>, Error> {
+	let mut file = BufReader::new(File::open(path)?);
+	let certs = rustls_pemfile::certs(&mut file)?;
+	match certs.is_empty() {
+		true => Err(Error::Tls(format!("No certificates were found in {}", path.display()))),
+		false => Ok(certs.into_iter().map(Certificate).collect()),
+	}
+}
+
+/// Read the first private key from a PEM file
+fn key(path: &Path) -> Result<PrivateKey, Error> {
+	let mut file = BufReader::new(File::open(path)?);
+	loop {
+		match rustls_pemfile::read_one(&mut file)? {
+			Some(rustls_pemfile::Item::PKCS8Key(v)) => return Ok(PrivateKey(v)),
+			Some(rustls_pemfile::Item::RSAKey(v)) => return Ok(PrivateKey(v)),
+			Some(rustls_pemfile::Item::ECKey(v)) => return Ok(PrivateKey(v)),
+			Some(_) => continue,
+			None => {
+				return Err(Error::Tls(format!("No private key was found in {}", path.display())))
+			}
+		}
+	}
+}
+
+/// Create a TLS acceptor for other protocols, which does not request a
+/// certificate from clients
+pub fn acceptor(crt: &Path, key_path: &Path) -> Result<TlsAcceptor, Error> {
+	let config = ServerConfig::builder()
+		.with_safe_defaults()
+		.with_no_client_auth()
+		.with_single_cert(certs(crt)?, key(key_path)?)
+		.map_err(|e| Error::Tls(e.to_string()))?;
+	Ok(TlsAcceptor::from(Arc::new(config)))
+}
+
+/// Create the TLS configuration, which requests a certificate from each
+/// client, and verifies any certificate which is presented against the CA
+fn config(crt: &Path, key_path: &Path, ca: &Path) -> Result<ServerConfig, Error> {
+	let mut roots = RootCertStore::empty();
+	for v in certs(ca)? {
+		roots.add(&v).map_err(|e| Error::Tls(e.to_string()))?;
+	}
+	let mut config = ServerConfig::builder()
+		.with_safe_defaults()
+		.with_client_cert_verifier(AllowAnyAnonymousOrAuthenticatedClient::new(roots))
+		.with_single_cert(certs(crt)?, key(key_path)?)
+		.map_err(|e| Error::Tls(e.to_string()))?;
+	config.alpn_protocols = vec![b"h2".to_vec(), b"http/1.1".to_vec()];
+	Ok(config)
+}
+
+/// Serve the routes over TLS, verifying the certificates of clients
+pub async fn serve<F>(
+	net: F,
+	bind: SocketAddr,
+	crt: &Path,
+	key: &Path,
+	ca: &Path,
+) -> Result<(), Error>
+where
+	F: Filter + Clone + Send + Sync + 'static,
+	F::Extract: warp::Reply,
+	F::Error: warp::reject::IsReject,
+{
+	// Load the server certificate and the client CA
+	let acceptor = TlsAcceptor::from(Arc::new(config(crt, key, ca)?));
+	// Convert the routes into a service
+	let svc = warp::service(net);
+	// Bind the server to the desired port
+	let listener = TcpListener::bind(bind).await?;
+	// Log the server startup status
+	info!(target: LOG, "Started web server on {} with client certificate verification", bind);
+	// Capture the shutdown signals
+	let shutdown = signals::listen();
+	tokio::pin!(shutdown);
+	// Accept connections until the server is shut down
+	loop {
+		let (tcp, addr) = tokio::select! {
+			res = listener.accept() => match res {
+				Ok(v) => v,
+				Err(e) => {
+					warn!(target: LOG, "Failed to accept a connection: {}", e);
+					continue;
+				}
+			},
+			res = &mut shutdown => {
+				let result = res.expect("Failed to listen to shutdown signal");
+				info!(target: LOG, "{} received. Start graceful shutdown...", result);
+				break;
+			}
+		};
+		let acceptor = acceptor.clone();
+		let svc = svc.clone();
+		tokio::spawn(async move {
+			// Complete the TLS handshake
+			let tls = match acceptor.accept(tcp).await {
+				Ok(v) => v,
+				Err(e) => {
+					debug!(target: LOG, "The TLS handshake with {} failed: {}", addr, e);
+					return;
+				}
+			};
+			// Get the common name of any verified client certificate
+			let cn = tls.get_ref().1.peer_certificates().and_then(|v| v.first()).and_then(|v| {
+				let cn = cert::common_name(&v.0);
+				if cn.is_none() {
+					debug!(target: LOG, "The client certificate of {} has no common name", addr);
+				}
+				cn
+			});
+			// Add the client to each request of the connection
+			let peer = Peer {
+				addr,
+				cn,
+			};
+			let svc = service_fn(move |mut req| {
+				req.extensions_mut().insert(peer.clone());
+				svc.clone().call(req)
+			});
+			// Serve the requests of the connection
+			if let Err(e) = Http::new().serve_connection(tls, svc).with_upgrades().await {
+				debug!(target: LOG, "The connection with {} failed: {}", addr, e);
+			}
+		});
+	}
+	Ok(())
+}
@@ -0,0 +1,143 @@
+use crate::cli::CF;
+use crate::dbs::DB;
+use crate::err::Error;
+use crate::net::input::bytes_to_utf8;
+use crate::net::output;
+use crate::net::params::Params;
+use crate::net::session;
+use bytes::Bytes;
+use serde::Serialize;
+use surrealdb::dbs::Session;
+use uuid::Uuid;
+use warp::Filter;
+
+const MAX: u64 = 1024 * 1024; // 1 MiB
+
+/// A transaction which was started, and is kept open between requests
+#[derive(Serialize)]
+struct Begun {
+	id: String,
+}
+
+#[allow(opaque_hidden_inferred_bound)]
+pub fn config() -> impl Filter<Extract = impl warp::Reply, Error = warp::Rejection> + Clone {
+	// Set base path
+	let base = warp::path("transaction");
+	// Set opts method
+	let opts = base.and(warp::options()).map(warp::reply);
+	// Set begin method
+	let begin = base
+		.and(warp::path::end())
+		.and(warp::post())
+		.and(warp::header::<String>(http::header::ACCEPT.as_str()))
+		.and(session::build())
+		.and_then(begin);
+	// Set query method
+	let query = base
+		.and(warp::path::param::<Uuid>())
+		.and(warp::path::end())
+		.and(warp::post())
+		.and(warp::header::<String>(http::header::ACCEPT.as_str()))
+		.and(warp::body::content_length_limit(MAX))
+		.and(warp::body::bytes())
+		.and(warp::query())
+		.and(session::build())
+		.and_then(query);
+	// Set commit method
+	let commit = base
+		.and(warp::path::param::<Uuid>())
+		.and(warp::path("commit"))
+		.and(warp::path::end())
+		.and(warp::post())
+		.and(session::build())
+		.and_then(commit);
+	// Set cancel method
+	let cancel = base
+		.and(warp::path::param::<Uuid>())
+		.and(warp::path("cancel"))
+		.and(warp::path::end())
+		.and(warp::post())
+		.and(session::build())
+		.and_then(cancel);
+	// Specify route
+	opts.or(begin).or(query).or(commit).or(cancel)
+}
+
+async fn begin(output: String, session: Session) -> Result<impl warp::Reply, warp::Rejection> {
+	// Get a database reference
+	let db = DB.get().unwrap();
+	// Start a transaction which is kept open
+	match db.begin(&session).await {
+		// Return the id of the transaction
+		Ok(id) => {
+			let res = Begun {
+				id: id.to_string(),
+			};
+			match output.as_ref() {
+				// Simple serialization
+				"application/json" => Ok(output::json(&res)),
+				"application/cbor" => Ok(output::cbor(&res)),
+				"application/pack" => Ok(output::pack(&res)),
+				// Internal serialization
+				"application/bung" => Ok(output::full(&res)),
+				// An incorrect content-type was requested
+				_ => Err(warp::reject::custom(Error::InvalidType)),
+			}
+		}
+		// There was an error when starting the transaction
+		Err(err) => Err(warp::reject::custom(Error::from(err))),
+	}
+}
+
+async fn query(
+	id: Uuid,
+	output: String,
+	sql: Bytes,
+	params: Params,
+	session: Session,
+) -> Result<impl warp::Reply, warp::Rejection> {
+	// Get a database reference
+	let db = DB.get().unwrap();
+	// Get local copy of options
+	let opt = CF.get().unwrap();
+	// Convert the received sql query
+	let sql = bytes_to_utf8(&sql)?;
+	// Execute the received sql query in the transaction
+	match db.execute_in(&id, sql, &session, params.parse().into(), opt.strict).await {
+		// Convert the response to JSON
+		Ok(res) => match output.as_ref() {
+			// Simple serialization
+			"application/json" => Ok(output::json(&output::simplify(res))),
+			"application/cbor" => Ok(output::cbor(&output::binary(res))),
+			"application/pack" => Ok(output::pack(&output::binary(res))),
+			// Internal serialization
+			"application/bung" => Ok(output::full(&res)),
+			// An incorrect content-type was requested
+			_ => Err(warp::reject::custom(Error::InvalidType)),
+		},
+		// There was an error when executing the query
+		Err(err) => Err(warp::reject::custom(Error::from(err))),
+	}
+}
+
+async fn commit(id: Uuid, session: Session) -> Result<impl warp::Reply, warp::Rejection> {
+	// Get a database reference
+	let db = DB.get().unwrap();
+	// Commit the transaction
+	match db.commit(&id, &session).await {
+		Ok(_) => Ok(output::none()),
+		// There was an error when committing the transaction
+		Err(err) => Err(warp::reject::custom(Error::from(err))),
+	}
+}
+
+async fn cancel(id: Uuid, session: Session) -> Result<impl warp::Reply, warp::Rejection> {
+	// Get a database reference
+	let db = DB.get().unwrap();
+	// Cancel the transaction
+	match db.cancel(&id, &session).await {
+		Ok(_) => Ok(output::none()),
+		// There was an error when cancelling the transaction
+		Err(err) => Err(warp::reject::custom(Error::from(err))),
+	}
+}
@@ -1,10 +1,14 @@
 mod logger;
 mod tracers;
 
-use crate::cli::validator::parser::env_filter::CustomEnvFilter;
+use crate::cli::validator::parser::env_filter::{filter, CustomEnvFilter};
+use once_cell::sync::OnceCell;
 use tracing::Subscriber;
 use tracing_subscriber::fmt::format::FmtSpan;
-use tracing_subscriber::{prelude::*, util::SubscriberInitExt, EnvFilter};
+use tracing_subscriber::{prelude::*, reload, util::SubscriberInitExt, EnvFilter, Registry};
+
+/// The handle with which the log filter is changed while the server is running
+static FILTER: OnceCell<reload::Handle<EnvFilter, Registry>> = OnceCell::new();
 
 #[derive(Default, Debug, Clone)]
 pub struct Builder {
@@ -32,12 +36,14 @@ impl Builder {
 	pub fn build(self) -> Box<dyn Subscriber + Send + Sync + 'static> {
 		let registry = tracing_subscriber::registry();
 		let registry = registry.with(self.filter.map(|filter| {
+			let (filter, handle) = reload::Layer::new(filter.0);
+			let _ = FILTER.set(handle);
 			tracing_subscriber::fmt::layer()
 				.compact()
 				.with_ansi(true)
 				.with_span_events(FmtSpan::NONE)
 				.with_writer(std::io::stderr)
-				.with_filter(filter.0)
+				.with_filter(filter)
 				.boxed()
 		}));
 		let registry = registry.with(self.log_level.map(logger::new));
@@ -50,6 +56,15 @@ impl Builder {
 	}
 }
 
+/// Change the log level, or the custom log filter, of the running server
+pub fn reload(level: &str) -> Result<(), String> {
+	let filter = filter(level).map_err(|e| e.to_string())?;
+	match FILTER.get() {
+		Some(handle) => handle.reload(filter).map_err(|e| e.to_string()),
+		None => Err("The log level can not be changed by this server".to_owned()),
+	}
+}
+
 #[cfg(test)]
 mod tests {
 	use opentelemetry::global::shutdown_tracer_provider;
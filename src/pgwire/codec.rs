@@ -0,0 +1,212 @@
+use crate::err::Error;
+use std::collections::BTreeMap;
+use tokio::io::{AsyncRead, AsyncReadExt, AsyncWrite, AsyncWriteExt};
+
+/// The maximum size of a client message
+const MAX: usize = 1024 * 1024; // 1 MiB
+
+/// The protocol version 3.0 startup code
+const PROTOCOL: i32 = 196608;
+/// The code used to request an SSL connection
+const SSL: i32 = 80877103;
+/// The code used to request a GSSAPI encrypted connection
+const GSS: i32 = 80877104;
+/// The code used to request query cancellation
+const CANCEL: i32 = 80877102;
+
+/// A startup message sent by the client
+pub enum Startup {
+	Ssl,
+	Gss,
+	Cancel,
+	Params(BTreeMap<String, String>),
+}
+
+/// A message sent by the server
+pub enum Message<'a> {
+	AuthenticationOk,
+	AuthenticationCleartextPassword,
+	ParameterStatus(&'a str, &'a str),
+	// The status of the transaction of the connection
+	ReadyForQuery(u8),
+	RowDescription(&'a [(String, i32)]),
+	DataRow(&'a [Option<String>]),
+	CommandComplete(&'a str),
+	EmptyQueryResponse,
+	Error(&'a str, &'a str),
+}
+
+/// Read a startup message, which has no type identifier
+pub async fn startup<R>(rx: &mut R) -> Result<Startup, Error>
+where
+	R: AsyncRead + Unpin,
+{
+	let len = rx.read_i32().await? as usize;
+	if !(8..=MAX).contains(&len) {
+		return Err(Error::Request);
+	}
+	let code = rx.read_i32().await?;
+	let mut body = vec![0; len - 8];
+	rx.read_exact(&mut body).await?;
+	match code {
+		SSL => Ok(Startup::Ssl),
+		GSS => Ok(Startup::Gss),
+		CANCEL => Ok(Startup::Cancel),
+		PROTOCOL => {
+			let mut out = BTreeMap::new();
+			let mut it = body.split(|&v| v == 0).map(|v| String::from_utf8_lossy(v).to_string());
+			while let (Some(k), Some(v)) = (it.next(), it.next()) {
+				if k.is_empty() {
+					break;
+				}
+				out.insert(k, v);
+			}
+			Ok(Startup::Params(out))
+		}
+		_ => Err(Error::Request),
+	}
+}
+
+/// Read a message, returning the type identifier and body
+pub async fn read<R>(rx: &mut R) -> Result<Option<(u8, Vec<u8>)>, Error>
+where
+	R: AsyncRead + Unpin,
+{
+	let tag = match rx.read_u8().await {
+		Ok(v) => v,
+		Err(e) if e.kind() == std::io::ErrorKind::UnexpectedEof => return Ok(None),
+		Err(e) => return Err(e.into()),
+	};
+	let len = rx.read_i32().await? as usize;
+	if !(4..=MAX).contains(&len) {
+		return Err(Error::Request);
+	}
+	let mut body = vec![0; len - 4];
+	rx.read_exact(&mut body).await?;
+	Ok(Some((tag, body)))
+}
+
+/// Read a null-terminated string from a message body
+pub fn cstring(v: &[u8]) -> String {
+	let end = v.iter().position(|&v| v == 0).unwrap_or(v.len());
+	String::from_utf8_lossy(&v[..end]).to_string()
+}
+
+/// Write a message to the client
+pub async fn write<W>(tx: &mut W, msg: Message<'_>) -> Result<(), Error>
+where
+	W: AsyncWrite + Unpin,
+{
+	tx.write_all(&encode(msg)).await?;
+	Ok(())
+}
+
+/// Encode a message, with its type identifier and length
+fn encode(msg: Message<'_>) -> Vec<u8> {
+	let mut body = Vec::new();
+	let tag = match msg {
+		Message::AuthenticationOk => {
+			body.extend(0i32.to_be_bytes());
+			b'R'
+		}
+		Message::AuthenticationCleartextPassword => {
+			body.extend(3i32.to_be_bytes());
+			b'R'
+		}
+		Message::ParameterStatus(k, v) => {
+			put(&mut body, k);
+			put(&mut body, v);
+			b'S'
+		}
+		Message::ReadyForQuery(status) => {
+			body.push(status);
+			b'Z'
+		}
+		Message::RowDescription(cols) => {
+			body.extend((cols.len() as i16).to_be_bytes());
+			for (col, oid) in cols.iter() {
+				put(&mut body, col);
+				body.extend(0i32.to_be_bytes()); // Table id
+				body.extend(0i16.to_be_bytes()); // Column number
+				body.extend(oid.to_be_bytes()); // Type id
+				body.extend((-1i16).to_be_bytes()); // Type size
+				body.extend((-1i32).to_be_bytes()); // Type modifier
+				body.extend(0i16.to_be_bytes()); // Text format
+			}
+			b'T'
+		}
+		Message::DataRow(vals) => {
+			body.extend((vals.len() as i16).to_be_bytes());
+			for val in vals.iter() {
+				match val {
+					Some(v) => {
+						body.extend((v.len() as i32).to_be_bytes());
+						body.extend(v.as_bytes());
+					}
+					None => body.extend((-1i32).to_be_bytes()),
+				}
+			}
+			b'D'
+		}
+		Message::CommandComplete(v) => {
+			put(&mut body, v);
+			b'C'
+		}
+		Message::EmptyQueryResponse => b'I',
+		Message::Error(code, msg) => {
+			body.push(b'S');
+			put(&mut body, "ERROR");
+			body.push(b'V');
+			put(&mut body, "ERROR");
+			body.push(b'C');
+			put(&mut body, code);
+			body.push(b'M');
+			put(&mut body, msg);
+			body.push(0);
+			b'E'
+		}
+	};
+	let mut out = Vec::with_capacity(body.len() + 5);
+	out.push(tag);
+	out.extend((body.len() as i32 + 4).to_be_bytes());
+	out.extend(body);
+	out
+}
+
+/// Append a null-terminated string to a message body
+fn put(body: &mut Vec<u8>, v: &str) {
+	body.extend(v.as_bytes());
+	body.push(0);
+}
+
+#[cfg(test)]
+mod tests {
+
+	use super::*;
+
+	#[test]
+	fn encode_ready_for_query() {
+		let out = encode(Message::ReadyForQuery(b'T'));
+		assert_eq!(out, vec![b'Z', 0, 0, 0, 5, b'T']);
+	}
+
+	#[test]
+	fn encode_data_row() {
+		let out = encode(Message::DataRow(&[Some(String::from("ab")), None]));
+		assert_eq!(out, vec![b'D', 0, 0, 0, 16, 0, 2, 0, 0, 0, 2, b'a', b'b', 255, 255, 255, 255]);
+	}
+
+	#[tokio::test]
+	async fn read_startup_params() {
+		let mut msg = vec![];
+		let body = b"user\0tobie\0database\0test/test\0\0";
+		msg.extend((body.len() as i32 + 8).to_be_bytes());
+		msg.extend(PROTOCOL.to_be_bytes());
+		msg.extend(body);
+		let res = startup(&mut msg.as_slice()).await;
+		assert!(matches!(
+			res,
+			Ok(Startup::Params(v)) if v.get("database") == Some(&String::from("test/test"))
+		));
+	}
+}
@@ -0,0 +1,240 @@
+//! Maps between PostgreSQL and SurrealQL. Queries are rewritten so that the
+//! PostgreSQL spelling of common statements and operators can be used, the
+//! introspection queries which drivers send when connecting are answered
+//! directly, and the values of each result are sent with the PostgreSQL type
+//! and text representation which most closely matches them.
+use surrealdb::sql::{Number, Value};
+
+/// The `bool` type identifier
+pub const BOOL: i32 = 16;
+/// The `int8` type identifier
+pub const INT8: i32 = 20;
+/// The `text` type identifier
+pub const TEXT: i32 = 25;
+/// The `float8` type identifier
+pub const FLOAT8: i32 = 701;
+/// The `timestamptz` type identifier
+pub const TIMESTAMPTZ: i32 = 1184;
+/// The `numeric` type identifier
+pub const NUMERIC: i32 = 1700;
+/// The `uuid` type identifier
+pub const UUID: i32 = 2950;
+/// The `jsonb` type identifier
+pub const JSONB: i32 = 3802;
+
+/// The version which is reported to clients
+pub const SERVER_VERSION: &str = "14.0";
+
+/// The server parameters which are reported to clients, and which can be
+/// retrieved with `SHOW`
+pub const PARAMETERS: [(&str, &str); 7] = [
+	("server_version", SERVER_VERSION),
+	("server_encoding", "UTF8"),
+	("client_encoding", "UTF8"),
+	("DateStyle", "ISO, MDY"),
+	("TimeZone", "UTC"),
+	("integer_datetimes", "on"),
+	("standard_conforming_strings", "on"),
+];
+
+/// A PostgreSQL query, mapped onto SurrealQL
+#[derive(Debug, PartialEq)]
+pub enum Query {
+	/// A query which is run as SurrealQL
+	Sql(String),
+	/// A session setting, which is accepted but ignored
+	Set,
+	/// A query which is answered with a single text value
+	Value(&'static str, String),
+	/// A statement which controls the transaction of the connection
+	Transaction(Control),
+}
+
+/// A transaction control statement, which applies to a transaction kept
+/// open across the queries of a connection
+#[derive(Debug, PartialEq)]
+pub enum Control {
+	Begin,
+	Commit,
+	Rollback,
+}
+
+/// Map a PostgreSQL query onto SurrealQL
+pub fn query(sql: &str, session: (Option<&str>, Option<&str>)) -> Query {
+	let words: Vec<String> = sql.split_whitespace().map(|v| v.to_ascii_lowercase()).collect();
+	let words: Vec<&str> = words.iter().map(String::as_str).collect();
+	match words.as_slice() {
+		["set", ..] => Query::Set,
+		["show", name] => {
+			let name = name.trim_matches('"');
+			match PARAMETERS.iter().find(|(k, _)| k.eq_ignore_ascii_case(name)) {
+				Some((k, v)) => Query::Value(k, v.to_string()),
+				None => Query::Sql(rewrite(sql)),
+			}
+		}
+		["select", "version()"] => Query::Value(
+			"version",
+			format!("PostgreSQL {SERVER_VERSION} (SurrealDB {})", crate::cnf::PKG_VERSION.as_str()),
+		),
+		["select", "current_database()"] => {
+			Query::Value("current_database", session.1.unwrap_or_default().to_owned())
+		}
+		["select", "current_schema()"] => {
+			Query::Value("current_schema", session.0.unwrap_or_default().to_owned())
+		}
+		["begin"] | ["begin", "transaction" | "work"] | ["start", "transaction"] => {
+			Query::Transaction(Control::Begin)
+		}
+		["commit"] | ["commit", "transaction" | "work"] | ["end"] => {
+			Query::Transaction(Control::Commit)
+		}
+		["rollback" | "abort"] | ["rollback" | "abort", "transaction" | "work"] => {
+			Query::Transaction(Control::Rollback)
+		}
+		["cancel", "transaction"] => Query::Transaction(Control::Rollback),
+		_ => Query::Sql(rewrite(sql)),
+	}
+}
+
+/// Rewrite the PostgreSQL spelling of identifiers and operators, outside of
+/// any string literals. Quoted identifiers use backticks, `<>` is written as
+/// `!=`, and `OFFSET` is written as `START`.
+fn rewrite(sql: &str) -> String {
+	let mut out = String::with_capacity(sql.len());
+	let mut chars = sql.chars().peekable();
+	while let Some(c) = chars.next() {
+		match c {
+			// String literals are copied as they are
+			'\'' => {
+				out.push(c);
+				for c in chars.by_ref() {
+					out.push(c);
+					if c == '\'' {
+						break;
+					}
+				}
+			}
+			// Quoted identifiers use backticks
+			'"' => {
+				out.push('`');
+				for c in chars.by_ref() {
+					if c == '"' {
+						break;
+					}
+					out.push(c);
+				}
+				out.push('`');
+			}
+			'<' if chars.peek() == Some(&'>') => {
+				chars.next();
+				out.push_str("!=");
+			}
+			c if c.is_alphabetic() || c == '_' => {
+				let mut word = String::from(c);
+				while let Some(&c) = chars.peek() {
+					if !(c.is_alphanumeric() || c == '_') {
+						break;
+					}
+					word.push(c);
+					chars.next();
+				}
+				match word.eq_ignore_ascii_case("offset") {
+					true => out.push_str("START"),
+					false => out.push_str(&word),
+				}
+			}
+			c => out.push(c),
+		}
+	}
+	out
+}
+
+/// Get the type identifier of a column, from the values in the column. If
+/// the values have different types, the column is sent as text.
+pub fn column<'a>(vals: impl Iterator<Item = &'a Value>) -> i32 {
+	let mut oids = vals.filter(|v| !v.is_none_or_null()).map(oid);
+	match oids.next() {
+		Some(first) if oids.all(|v| v == first) => first,
+		Some(_) => TEXT,
+		None => TEXT,
+	}
+}
+
+/// Get the type identifier of a value
+fn oid(v: &Value) -> i32 {
+	match v {
+		Value::Bool(_) => BOOL,
+		Value::Number(Number::Int(_)) => INT8,
+		Value::Number(Number::Float(_)) => FLOAT8,
+		Value::Number(Number::Decimal(_)) => NUMERIC,
+		Value::Datetime(_) => TIMESTAMPTZ,
+		Value::Uuid(_) => UUID,
+		Value::Array(_) | Value::Object(_) => JSONB,
+		_ => TEXT,
+	}
+}
+
+/// Convert a value into the text representation of a column type
+pub fn text(v: &Value, oid: i32) -> Option<String> {
+	match (v, oid) {
+		(Value::None | Value::Null, _) => None,
+		(Value::Bool(true), BOOL) => Some(String::from("t")),
+		(Value::Bool(false), BOOL) => Some(String::from("f")),
+		(Value::Number(Number::Float(v)), FLOAT8) => Some(match v {
+			v if v.is_nan() => String::from("NaN"),
+			v if v.is_infinite() && v.is_sign_positive() => String::from("Infinity"),
+			v if v.is_infinite() => String::from("-Infinity"),
+			v => v.to_string(),
+		}),
+		(Value::Number(Number::Decimal(v)), NUMERIC) => Some(v.to_string()),
+		(Value::Datetime(v), TIMESTAMPTZ) => {
+			Some(v.0.format("%Y-%m-%d %H:%M:%S%.f+00").to_string())
+		}
+		(Value::Array(_) | Value::Object(_), _) => Some(v.clone().into_json().to_string()),
+		(v, _) => Some(v.clone().as_raw_string()),
+	}
+}
+
+#[cfg(test)]
+mod tests {
+
+	use super::*;
+
+	#[test]
+	fn map_statements() {
+		let ses = (Some("test"), Some("app"));
+		assert_eq!(query("SET client_encoding TO 'UTF8'", ses), Query::Set);
+		assert_eq!(query("show TimeZone", ses), Query::Value("TimeZone", String::from("UTC")));
+		assert_eq!(
+			query("SELECT current_database()", ses),
+			Query::Value("current_database", String::from("app"))
+		);
+		assert_eq!(query("START TRANSACTION", ses), Query::Transaction(Control::Begin));
+		assert_eq!(query("end", ses), Query::Transaction(Control::Commit));
+		assert_eq!(query("ROLLBACK", ses), Query::Transaction(Control::Rollback));
+	}
+
+	#[test]
+	fn rewrite_queries() {
+		assert_eq!(
+			rewrite(r#"SELECT "name" FROM person WHERE age <> 30 LIMIT 10 OFFSET 20"#),
+			"SELECT `name` FROM person WHERE age != 30 LIMIT 10 START 20",
+		);
+		assert_eq!(
+			rewrite(r#"SELECT * FROM person WHERE name = 'Tobie <> "offset"'"#),
+			r#"SELECT * FROM person WHERE name = 'Tobie <> "offset"'"#,
+		);
+		assert_eq!(rewrite("SELECT * FROM offsets"), "SELECT * FROM offsets");
+	}
+
+	#[test]
+	fn column_types() {
+		let vals = vec![Value::from(1), Value::Null, Value::from(2)];
+		assert_eq!(column(vals.iter()), INT8);
+		let vals = vec![Value::from(1), Value::from("one")];
+		assert_eq!(column(vals.iter()), TEXT);
+		assert_eq!(text(&Value::from(true), BOOL), Some(String::from("t")));
+		assert_eq!(text(&Value::from(1.5), FLOAT8), Some(String::from("1.5")));
+		assert_eq!(text(&Value::from(1.5), TEXT), Some(String::from("1.5f")));
+	}
+}
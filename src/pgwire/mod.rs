@@ -0,0 +1,584 @@
+//! A listener which speaks the PostgreSQL frontend/backend protocol, so that
+//! existing tools and drivers can connect to the database. Only the simple
+//! query protocol is supported. Each query is mapped onto SurrealQL, and every
+//! returned record is sent to the client as a row, with a column type which
+//! matches the values of each column.
+//!
+//! Transactions which are started with `BEGIN` are kept open across the
+//! queries of the connection, until they are committed or rolled back. Once
+//! a statement in the transaction fails, every further query is rejected
+//! until the transaction is rolled back, and committing it rolls it back.
+//!
+//! The PostgreSQL database name selects the namespace and database, and is
+//! specified as `{ns}/{db}`. Clients authenticate using a password, which is
+//! checked in the same way as HTTP basic authentication. When the server has
+//! a certificate, connections must be encrypted with TLS before the password
+//! is sent, and unencrypted connections are refused.
+mod codec;
+mod mapping;
+
+use crate::cli::CF;
+use crate::dbs::DB;
+use crate::err::Error;
+use crate::iam::verify::credentials;
+use crate::net::client_ip::ClientIp;
+use crate::net::signals;
+use crate::net::tls;
+use codec::{Message, Startup};
+use mapping::{Control, Query};
+use std::collections::BTreeMap;
+use surrealdb::dbs::Session;
+use surrealdb::sql::{Statement, Value};
+use tokio::io::{AsyncRead, AsyncWrite, AsyncWriteExt, BufReader, BufWriter};
+use tokio::net::{TcpListener, TcpStream};
+use tokio_rustls::TlsAcceptor;
+use uuid::Uuid;
+
+const LOG: &str = "surrealdb::pgwire";
+
+pub async fn init() -> Result<(), Error> {
+	// Get local copy of options
+	let opt = CF.get().unwrap();
+	// Check if the listener is enabled
+	let bind = match opt.pg {
+		Some(bind) => bind,
+		None => return Ok(()),
+	};
+	// Encrypt connections with the certificate of the web server
+	let tls = match (&opt.crt, &opt.key) {
+		(Some(crt), Some(key)) => Some(tls::acceptor(crt, key)?),
+		_ => {
+			warn!(target: LOG, "The PostgreSQL listener has no certificate, so passwords are sent unencrypted");
+			None
+		}
+	};
+	info!(target: LOG, "Starting PostgreSQL listener on {}", &bind);
+	// Bind the listener to the desired port
+	let listener = TcpListener::bind(bind).await?;
+	// Accept connections in the background
+	tokio::spawn(async move {
+		let accept = async {
+			loop {
+				match listener.accept().await {
+					Ok((socket, addr)) => {
+						let ip = match opt.client_ip {
+							ClientIp::None => None,
+							_ => Some(addr.ip().to_string()),
+						};
+						let tls = tls.clone();
+						tokio::spawn(async move {
+							if let Err(e) = connection(socket, ip, tls).await {
+								debug!(target: LOG, "PostgreSQL connection closed: {}", e);
+							}
+						});
+					}
+					Err(e) => warn!(target: LOG, "Unable to accept connection: {}", e),
+				}
+			}
+		};
+		tokio::select! {
+			_ = accept => {},
+			_ = signals::listen() => {},
+		}
+	});
+	Ok(())
+}
+
+async fn connection(
+	mut socket: TcpStream,
+	ip: Option<String>,
+	tls: Option<TlsAcceptor>,
+) -> Result<(), Error> {
+	// Negotiate the encryption of the connection
+	loop {
+		match (codec::startup(&mut socket).await?, &tls) {
+			// Encrypt the connection, and start up again
+			(Startup::Ssl, Some(tls)) => {
+				socket.write_all(b"S").await?;
+				let socket = tls.accept(socket).await?;
+				return serve(socket, ip, None).await;
+			}
+			// Encryption is not available on this listener
+			(Startup::Ssl | Startup::Gss, _) => socket.write_all(b"N").await?,
+			(Startup::Cancel, _) => return Ok(()),
+			// Passwords are not accepted without encryption
+			(Startup::Params(_), Some(_)) => {
+				let msg = "The connection must be encrypted with SSL";
+				codec::write(&mut socket, Message::Error("28000", msg)).await?;
+				return Ok(());
+			}
+			(Startup::Params(v), None) => return serve(socket, ip, Some(v)).await,
+		}
+	}
+}
+
+async fn serve<S>(
+	socket: S,
+	ip: Option<String>,
+	params: Option<BTreeMap<String, String>>,
+) -> Result<(), Error>
+where
+	S: AsyncRead + AsyncWrite + Unpin,
+{
+	let (rx, tx) = tokio::io::split(socket);
+	let mut rx = BufReader::new(rx);
+	let mut tx = BufWriter::new(tx);
+	// Read the connection startup, once encrypted
+	let params = match params {
+		Some(v) => v,
+		None => match codec::startup(&mut rx).await? {
+			Startup::Params(v) => v,
+			_ => return Ok(()),
+		},
+	};
+	// Select the namespace and database
+	let mut session = Session {
+		ip,
+		..Default::default()
+	};
+	if let Some(v) = params.get("database") {
+		match v.split_once('/') {
+			Some((ns, db)) => {
+				session.ns = Some(ns.to_owned());
+				session.db = Some(db.to_owned());
+			}
+			None => session.ns = Some(v.to_owned()),
+		}
+	}
+	// Request and check the password
+	codec::write(&mut tx, Message::AuthenticationCleartextPassword).await?;
+	tx.flush().await?;
+	let user = params.get("user").cloned().unwrap_or_default();
+	let pass = match codec::read(&mut rx).await? {
+		Some((b'p', v)) => codec::cstring(&v),
+		_ => return Ok(()),
+	};
+	if let Err(e) = credentials(&mut session, &user, &pass).await {
+		codec::write(&mut tx, Message::Error("28P01", &e.to_string())).await?;
+		tx.flush().await?;
+		return Ok(());
+	}
+	// Complete the connection startup
+	codec::write(&mut tx, Message::AuthenticationOk).await?;
+	for (k, v) in mapping::PARAMETERS {
+		codec::write(&mut tx, Message::ParameterStatus(k, v)).await?;
+	}
+	codec::write(&mut tx, Message::ReadyForQuery(b'I')).await?;
+	tx.flush().await?;
+	// Process the client messages
+	let mut txn = Txn::default();
+	let res = messages(&mut rx, &mut tx, &session, &mut txn).await;
+	// Roll back a transaction which was left open
+	if let Some(id) = txn.id {
+		let _ = DB.get().unwrap().cancel(&id, &session).await;
+	}
+	res
+}
+
+/// The transaction which a connection keeps open across queries
+#[derive(Default)]
+struct Txn {
+	id: Option<Uuid>,
+	// Whether a statement in the transaction failed
+	failed: bool,
+}
+
+impl Txn {
+	/// The transaction status which is sent when ready for a query
+	fn status(&self) -> u8 {
+		match (self.id, self.failed) {
+			(None, _) => b'I',
+			(Some(_), false) => b'T',
+			(Some(_), true) => b'E',
+		}
+	}
+}
+
+async fn messages<R, W>(
+	rx: &mut R,
+	tx: &mut W,
+	session: &Session,
+	txn: &mut Txn,
+) -> Result<(), Error>
+where
+	R: AsyncRead + Unpin,
+	W: AsyncWriteExt + Unpin,
+{
+	let mut failed = false;
+	while let Some((tag, body)) = codec::read(rx).await? {
+		match tag {
+			// Simple query
+			b'Q' => {
+				query(tx, session, txn, &codec::cstring(&body)).await?;
+				codec::write(tx, Message::ReadyForQuery(txn.status())).await?;
+				tx.flush().await?;
+			}
+			// Extended query sync
+			b'S' => {
+				failed = false;
+				codec::write(tx, Message::ReadyForQuery(txn.status())).await?;
+				tx.flush().await?;
+			}
+			// Terminate
+			b'X' => break,
+			// Flush
+			b'H' => tx.flush().await?,
+			// The extended query protocol is unsupported, so
+			// report an error once for each message sequence
+			_ => {
+				if !failed {
+					failed = true;
+					let msg = "The extended query protocol is not supported";
+					codec::write(tx, Message::Error("0A000", msg)).await?;
+					tx.flush().await?;
+				}
+			}
+		}
+	}
+	Ok(())
+}
+
+async fn query<W>(tx: &mut W, session: &Session, txn: &mut Txn, sql: &str) -> Result<(), Error>
+where
+	W: AsyncWriteExt + Unpin,
+{
+	// Check for an empty query
+	let sql = sql.trim().trim_end_matches(';').trim();
+	if sql.is_empty() {
+		return codec::write(tx, Message::EmptyQueryResponse).await;
+	}
+	// Map the query onto SurrealQL
+	let sql = match mapping::query(sql, (session.ns.as_deref(), session.db.as_deref())) {
+		Query::Sql(v) => v,
+		// Session settings are accepted but ignored
+		Query::Set => return codec::write(tx, Message::CommandComplete("SET")).await,
+		// Server details are answered directly
+		Query::Value(col, v) => {
+			codec::write(tx, Message::RowDescription(&[(col.to_owned(), mapping::TEXT)])).await?;
+			codec::write(tx, Message::DataRow(&[Some(v)])).await?;
+			return codec::write(tx, Message::CommandComplete("SELECT 1")).await;
+		}
+		// Transactions are kept open across queries
+		Query::Transaction(v) => return control(tx, session, txn, v).await,
+	};
+	// Reject queries once the transaction has failed
+	if txn.failed {
+		let msg = "The transaction has failed, so queries are ignored until it is rolled back";
+		return codec::write(tx, Message::Error("25P02", msg)).await;
+	}
+	// Parse the SurrealQL query
+	let ast = match surrealdb::sql::parse(&sql) {
+		Ok(v) => v,
+		Err(e) => return codec::write(tx, Message::Error("42601", &e.to_string())).await,
+	};
+	let stms = ast.0 .0.clone();
+	// Get a database reference
+	let kvs = DB.get().unwrap();
+	// Get local copy of options
+	let opt = CF.get().unwrap();
+	// Execute the query on the database, in the transaction of the connection
+	let res = match txn.id {
+		Some(id) => kvs.process_in(&id, ast, session, None, opt.strict).await,
+		None => kvs.process(ast, session, None, opt.strict).await,
+	};
+	let res = match res {
+		Ok(v) => v,
+		Err(e) => {
+			txn.failed = txn.id.is_some();
+			return codec::write(tx, Message::Error("XX000", &e.to_string())).await;
+		}
+	};
+	// Output the statement results
+	for (stm, res) in stms.iter().zip(res.into_iter()) {
+		let val = match res.result {
+			Ok(v) => v,
+			Err(e) => {
+				txn.failed = txn.id.is_some();
+				return codec::write(tx, Message::Error("XX000", &e.to_string())).await;
+			}
+		};
+		let rows = match val {
+			Value::Array(v) => v.0,
+			Value::None => vec![],
+			v => vec![v],
+		};
+		let tag = match stm {
+			Statement::Create(_) | Statement::Insert(_) | Statement::Relate(_) => {
+				format!("INSERT 0 {}", rows.len())
+			}
+			Statement::Update(_) => format!("UPDATE {}", rows.len()),
+			Statement::Delete(_) => format!("DELETE {}", rows.len()),
+			Statement::Select(_) | Statement::Output(_) | Statement::Info(_) => {
+				self::rows(tx, rows).await?
+			}
+			Statement::Begin(_) => String::from("BEGIN"),
+			Statement::Commit(_) => String::from("COMMIT"),
+			Statement::Cancel(_) => String::from("ROLLBACK"),
+			_ => String::from("OK"),
+		};
+		codec::write(tx, Message::CommandComplete(&tag)).await?;
+	}
+	Ok(())
+}
+
+/// Begin, commit, or roll back the transaction of the connection
+async fn control<W>(tx: &mut W, session: &Session, txn: &mut Txn, ctl: Control) -> Result<(), Error>
+where
+	W: AsyncWriteExt + Unpin,
+{
+	// Get a database reference
+	let kvs = DB.get().unwrap();
+	match (ctl, txn.id.take()) {
+		(Control::Begin, None) => match kvs.begin(session).await {
+			Ok(id) => {
+				txn.id = Some(id);
+				txn.failed = false;
+				codec::write(tx, Message::CommandComplete("BEGIN")).await
+			}
+			Err(e) => codec::write(tx, Message::Error("XX000", &e.to_string())).await,
+		},
+		(Control::Begin, Some(id)) => {
+			txn.id = Some(id);
+			let msg = "There is already a transaction in progress";
+			codec::write(tx, Message::Error("25001", msg)).await
+		}
+		(Control::Commit, Some(id)) => {
+			let failed = std::mem::take(&mut txn.failed);
+			match kvs.commit(&id, session).await {
+				Ok(_) => codec::write(tx, Message::CommandComplete("COMMIT")).await,
+				// A failed transaction is rolled back instead
+				Err(_) if failed => codec::write(tx, Message::CommandComplete("ROLLBACK")).await,
+				Err(e) => codec::write(tx, Message::Error("40000", &e.to_string())).await,
+			}
+		}
+		(Control::Rollback, Some(id)) => {
+			txn.failed = false;
+			match kvs.cancel(&id, session).await {
+				Ok(_) => codec::write(tx, Message::CommandComplete("ROLLBACK")).await,
+				Err(e) => codec::write(tx, Message::Error("XX000", &e.to_string())).await,
+			}
+		}
+		(Control::Commit | Control::Rollback, None) => {
+			let msg = "There is no transaction in progress";
+			codec::write(tx, Message::Error("25P01", msg)).await
+		}
+	}
+}
+
+/// Output the rows in a result set, returning the command tag
+async fn rows<W>(tx: &mut W, rows: Vec<Value>) -> Result<String, Error>
+where
+	W: AsyncWriteExt + Unpin,
+{
+	// Get the columns from every record
+	let mut cols: Vec<String> = vec![];
+	for row in rows.iter() {
+		match row {
+			Value::Object(v) => {
+				for k in v.keys() {
+					if !cols.contains(k) {
+						cols.push(k.to_owned());
+					}
+				}
+			}
+			_ => {
+				if !cols.iter().any(|c| c == "value") {
+					cols.push(String::from("value"));
+				}
+			}
+		}
+	}
+	// Get the value of a column in a record
+	let cell = |row: &Value, col: &str| match row {
+		Value::Object(v) => v.get(col).cloned().unwrap_or_default(),
+		v if col == "value" => v.clone(),
+		_ => Value::None,
+	};
+	// Get the type of each column from its values
+	let cols: Vec<(String, i32)> = cols
+		.into_iter()
+		.map(|c| {
+			let vals: Vec<Value> = rows.iter().map(|row| cell(row, &c)).collect();
+			let oid = mapping::column(vals.iter());
+			(c, oid)
+		})
+		.collect();
+	codec::write(tx, Message::RowDescription(&cols)).await?;
+	// Output each record as a row
+	for row in rows.iter() {
+		let vals: Vec<Option<String>> =
+			cols.iter().map(|(c, oid)| mapping::text(&cell(row, c), *oid)).collect();
+		codec::write(tx, Message::DataRow(&vals)).await?;
+	}
+	Ok(format!("SELECT {}", rows.len()))
+}
+
+#[cfg(test)]
+mod tests {
+	use super::*;
+	use std::sync::Arc;
+	use tokio::io::AsyncReadExt;
+	use tokio_rustls::rustls::{Certificate, ClientConfig, RootCertStore, ServerName};
+	use tokio_rustls::TlsConnector;
+
+	/// Accept a single connection on a local port
+	async fn listen(tls: Option<TlsAcceptor>) -> std::net::SocketAddr {
+		crate::dbs::test().await;
+		let listener = TcpListener::bind("127.0.0.1:0").await.unwrap();
+		let addr = listener.local_addr().unwrap();
+		tokio::spawn(async move {
+			let (socket, _) = listener.accept().await.unwrap();
+			let _ = connection(socket, None, tls).await;
+		});
+		addr
+	}
+
+	/// Send a startup message with the given code and body
+	async fn startup<S: AsyncWrite + Unpin>(tx: &mut S, code: i32, body: &[u8]) {
+		let mut msg = vec![];
+		msg.extend((body.len() as i32 + 8).to_be_bytes());
+		msg.extend(code.to_be_bytes());
+		msg.extend(body);
+		tx.write_all(&msg).await.unwrap();
+	}
+
+	/// Send a message with a null-terminated string body
+	async fn send<S: AsyncWrite + Unpin>(tx: &mut S, tag: u8, body: &str) {
+		let mut msg = vec![tag];
+		msg.extend((body.len() as i32 + 5).to_be_bytes());
+		msg.extend(body.as_bytes());
+		msg.push(0);
+		tx.write_all(&msg).await.unwrap();
+	}
+
+	/// Receive the messages until the server is ready, or sends an error
+	async fn recv<S: AsyncRead + Unpin>(rx: &mut S) -> Vec<(u8, Vec<u8>)> {
+		let mut out = vec![];
+		while let Some((tag, body)) = codec::read(rx).await.unwrap() {
+			out.push((tag, body));
+			if tag == b'Z' || tag == b'E' {
+				break;
+			}
+		}
+		out
+	}
+
+	/// Start up and authenticate a connection
+	async fn login<S: AsyncRead + AsyncWrite + Unpin>(stream: &mut S, pass: &str) -> Vec<u8> {
+		startup(stream, 196608, b"user\0root\0database\0pgwire/pgwire\0\0").await;
+		let res = codec::read(stream).await.unwrap();
+		assert_eq!(res, Some((b'R', 3i32.to_be_bytes().to_vec())));
+		send(stream, b'p', pass).await;
+		recv(stream).await.into_iter().map(|(tag, _)| tag).collect()
+	}
+
+	#[tokio::test]
+	async fn startup_and_query() {
+		let addr = listen(None).await;
+		let mut stream = TcpStream::connect(addr).await.unwrap();
+		// Encryption is declined without a certificate
+		startup(&mut stream, 80877103, b"").await;
+		assert_eq!(stream.read_u8().await.unwrap(), b'N');
+		// Authenticate with the root user
+		let tags = login(&mut stream, "root").await;
+		assert_eq!(tags.first(), Some(&b'R'));
+		assert_eq!(tags.last(), Some(&b'Z'));
+		// Run a query, and receive typed columns
+		let sql = "CREATE person:tobie SET name = 'Tobie', age = 30; SELECT age, name FROM person";
+		send(&mut stream, b'Q', sql).await;
+		let res = recv(&mut stream).await;
+		let tags: Vec<u8> = res.iter().map(|(tag, _)| *tag).collect();
+		assert_eq!(tags, vec![b'C', b'T', b'D', b'C', b'Z']);
+		assert_eq!(codec::cstring(&res[0].1), "INSERT 0 1");
+		let desc = &res[1].1;
+		assert_eq!(&desc[..2], &2i16.to_be_bytes());
+		assert!(desc.windows(4).any(|v| v == b"age\0"));
+		assert!(desc.windows(4).any(|v| v == mapping::INT8.to_be_bytes()));
+		assert!(res[2].1.windows(5).any(|v| v == b"Tobie"));
+		assert_eq!(codec::cstring(&res[3].1), "SELECT 1");
+	}
+
+	#[tokio::test]
+	async fn transaction_across_queries() {
+		let addr = listen(None).await;
+		let mut stream = TcpStream::connect(addr).await.unwrap();
+		login(&mut stream, "root").await;
+		// The transaction is kept open after each query
+		send(&mut stream, b'Q', "BEGIN").await;
+		let res = recv(&mut stream).await;
+		assert_eq!(codec::cstring(&res[0].1), "BEGIN");
+		assert_eq!(res[1], (b'Z', vec![b'T']));
+		send(&mut stream, b'Q', "CREATE account:one").await;
+		let res = recv(&mut stream).await;
+		assert_eq!(res[1], (b'Z', vec![b'T']));
+		// Rolling back undoes the earlier queries
+		send(&mut stream, b'Q', "ROLLBACK").await;
+		let res = recv(&mut stream).await;
+		assert_eq!(codec::cstring(&res[0].1), "ROLLBACK");
+		assert_eq!(res[1], (b'Z', vec![b'I']));
+		send(&mut stream, b'Q', "SELECT * FROM account").await;
+		let res = recv(&mut stream).await;
+		assert_eq!(codec::cstring(&res[1].1), "SELECT 0");
+		// A failed transaction rejects queries until it is finished
+		send(&mut stream, b'Q', "BEGIN").await;
+		recv(&mut stream).await;
+		send(&mut stream, b'Q', "CREATE account:one; CREATE account:one").await;
+		let res = recv(&mut stream).await;
+		assert_eq!(res.last().unwrap().0, b'E');
+		assert_eq!(recv(&mut stream).await, vec![(b'Z', vec![b'E'])]);
+		send(&mut stream, b'Q', "SELECT * FROM account").await;
+		let res = recv(&mut stream).await;
+		assert_eq!(res[0].0, b'E');
+		assert!(res[0].1.windows(5).any(|v| v == b"25P02"));
+		recv(&mut stream).await;
+		send(&mut stream, b'Q', "COMMIT").await;
+		let res = recv(&mut stream).await;
+		assert_eq!(codec::cstring(&res[0].1), "ROLLBACK");
+		assert_eq!(res[1], (b'Z', vec![b'I']));
+		// Transaction control which can not be honoured is rejected
+		send(&mut stream, b'Q', "COMMIT").await;
+		let res = recv(&mut stream).await;
+		assert!(res[0].1.windows(5).any(|v| v == b"25P01"));
+	}
+
+	#[tokio::test]
+	async fn startup_with_wrong_password() {
+		let addr = listen(None).await;
+		let mut stream = TcpStream::connect(addr).await.unwrap();
+		let tags = login(&mut stream, "wrong").await;
+		assert_eq!(tags, vec![b'E']);
+	}
+
+	#[tokio::test]
+	async fn startup_with_tls() {
+		let dir = tempfile::tempdir().unwrap();
+		let (crt, key) = (dir.path().join("crt.pem"), dir.path().join("key.pem"));
+		let cert = rcgen::generate_simple_self_signed(vec![String::from("localhost")]).unwrap();
+		std::fs::write(&crt, cert.serialize_pem().unwrap()).unwrap();
+		std::fs::write(&key, cert.serialize_private_key_pem()).unwrap();
+		// Unencrypted connections are refused
+		let addr = listen(Some(tls::acceptor(&crt, &key).unwrap())).await;
+		let mut stream = TcpStream::connect(addr).await.unwrap();
+		startup(&mut stream, 196608, b"user\0root\0\0").await;
+		let res = recv(&mut stream).await;
+		assert_eq!(res[0].0, b'E');
+		// Encrypted connections are accepted
+		let addr = listen(Some(tls::acceptor(&crt, &key).unwrap())).await;
+		let mut stream = TcpStream::connect(addr).await.unwrap();
+		startup(&mut stream, 80877103, b"").await;
+		assert_eq!(stream.read_u8().await.unwrap(), b'S');
+		let mut roots = RootCertStore::empty();
+		roots.add(&Certificate(cert.serialize_der().unwrap())).unwrap();
+		let config = ClientConfig::builder()
+			.with_safe_defaults()
+			.with_root_certificates(roots)
+			.with_no_client_auth();
+		let name = ServerName::try_from("localhost").unwrap();
+		let mut stream = TlsConnector::from(Arc::new(config)).connect(name, stream).await.unwrap();
+		let tags = login(&mut stream, "root").await;
+		assert_eq!(tags.last(), Some(&b'Z'));
+		send(&mut stream, b'Q', "SELECT version()").await;
+		let res = recv(&mut stream).await;
+		assert!(res[1].1.windows(10).any(|v| v == b"PostgreSQL"));
+	}
+}
@@ -1,7 +1,10 @@
+use crate::err::Error;
+use crate::net::output;
 use serde::Serialize;
 use serde_json::Value as Json;
 use std::borrow::Cow;
 use surrealdb::channel::Sender;
+use surrealdb::err::{ErrorKind, Retry};
 use surrealdb::sql;
 use surrealdb::sql::Value;
 use warp::ws::Message;
@@ -28,6 +31,10 @@ enum Content<T> {
 	Success(T),
 	#[serde(rename = "error")]
 	Failure(Failure),
+	#[serde(rename = "trace")]
+	Trace(T),
+	#[serde(rename = "notification")]
+	Notification(T),
 }
 
 impl<T: Serialize> Response<T> {
@@ -45,12 +52,12 @@ impl<T: Serialize> Response<T> {
 				let _ = chn.send(res).await;
 			}
 			Output::Cbor => {
-				let res = serde_cbor::to_vec(&self.simplify()).unwrap();
+				let res = serde_cbor::to_vec(&output::binary(self)).unwrap();
 				let res = Message::binary(res);
 				let _ = chn.send(res).await;
 			}
 			Output::Pack => {
-				let res = serde_pack::to_vec(&self.simplify()).unwrap();
+				let res = serde_pack::to_vec(&output::binary(self)).unwrap();
 				let res = Message::binary(res);
 				let _ = chn.send(res).await;
 			}
@@ -67,41 +74,62 @@ impl<T: Serialize> Response<T> {
 pub struct Failure {
 	code: i64,
 	message: Cow<'static, str>,
+	#[serde(skip_serializing_if = "Option::is_none")]
+	data: Option<Data>,
+}
+
+/// The additional information included with a database error, with the
+/// category of the error, and any hint as to how it can be retried
+#[derive(Clone, Debug, Serialize)]
+struct Data {
+	kind: ErrorKind,
+	code: u16,
+	#[serde(flatten, skip_serializing_if = "Option::is_none")]
+	retry: Option<Retry>,
 }
 
 impl Failure {
 	pub const PARSE_ERROR: Failure = Failure {
 		code: -32700,
 		message: Cow::Borrowed("Parse error"),
+		data: None,
 	};
 
 	pub const INVALID_REQUEST: Failure = Failure {
 		code: -32600,
 		message: Cow::Borrowed("Invalid Request"),
+		data: None,
 	};
 
 	pub const METHOD_NOT_FOUND: Failure = Failure {
 		code: -32601,
 		message: Cow::Borrowed("Method not found"),
+		data: None,
 	};
 
 	pub const INVALID_PARAMS: Failure = Failure {
 		code: -32602,
 		message: Cow::Borrowed("Invalid params"),
+		data: None,
 	};
 
 	pub const INTERNAL_ERROR: Failure = Failure {
 		code: -32603,
 		message: Cow::Borrowed("Internal error"),
+		data: None,
 	};
+}
 
-	pub fn custom<S>(message: S) -> Failure
-	where
-		Cow<'static, str>: From<S>,
-	{
+impl From<Error> for Failure {
+	fn from(e: Error) -> Self {
 		Failure {
 			code: -32000,
-			message: message.into(),
+			data: Some(Data {
+				kind: e.kind(),
+				code: e.kind().code(),
+				retry: e.retry(),
+			}),
+			message: e.to_string().into(),
 		}
 	}
 }
@@ -121,3 +149,19 @@ pub fn failure(id: Option<Value>, err: Failure) -> Response<Value> {
 		content: Content::Failure(err),
 	}
 }
+
+/// Create a JSON RPC notification for a statement trace
+pub fn trace(val: Value) -> Response<Value> {
+	Response {
+		id: None,
+		content: Content::Trace(val),
+	}
+}
+
+/// Create a JSON RPC notification for a live query
+pub fn notification(val: Value) -> Response<Value> {
+	Response {
+		id: None,
+		content: Content::Notification(val),
+	}
+}
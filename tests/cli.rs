@@ -171,6 +171,26 @@ mod cli_integration {
 			);
 		}
 
+		// Export and import a table as JSON Lines and CSV
+		for format in ["jsonl", "csv"] {
+			let exported = tmp_file(&format!("exported.{format}"));
+			let args = format!(
+				"export --conn http://{addr} --user root --pass {pass} --ns N --db D --format {format} --table thing {exported}"
+			);
+			run(&args).output().expect("failed to run table export: {args}");
+			let args = format!(
+				"import --conn http://{addr} --user root --pass {pass} --ns N --db {format} --format {format} --table thing {exported}"
+			);
+			run(&args).output().expect("failed to run table import: {args}");
+			let args =
+				format!("sql --conn http://{addr} --user root --pass {pass} --ns N --db {format}");
+			assert_eq!(
+				run(&args).input("SELECT * FROM thing;\n").output(),
+				Ok("[{ id: thing:one }]\n\n".to_owned()),
+				"failed to send sql: {args}"
+			);
+		}
+
 		// Unfinished backup CLI
 		{
 			let file = tmp_file("backup.db");